@@ -30,16 +30,31 @@ func newCmdLonghornctlLocal() *cobra.Command {
 		Short: "Command-line interface for Longhorn.",
 		Long:  "A CLI tool (local) for troubleshooting and managing Longhorn operations.",
 		PersistentPreRun: func(cmd *cobra.Command, args []string) {
-			err := utils.SetLog(globalOpts.LogLevel)
+			err := utils.SetLog(globalOpts)
 			if err != nil {
-				logrus.WithError(err).Warn("Failed to set log level")
+				logrus.WithError(err).Warn("Failed to set logger")
 			}
 		},
 	}
 
 	cmd.CompletionOptions.HiddenDefaultCmd = true
 
-	cmd.PersistentFlags().StringVarP(&globalOpts.LogLevel, consts.CmdOptLogLevel, "l", "info", "log level (trace, debug, info, warn, error, fatal, panic)")
+	logLevel := os.Getenv(consts.EnvLogLevel)
+	if logLevel == "" {
+		logLevel = "info"
+	}
+
+	logFormat := os.Getenv(consts.EnvLogFormat)
+	if logFormat == "" {
+		logFormat = consts.LogFormatText
+	}
+
+	cmd.PersistentFlags().StringVarP(&globalOpts.LogLevel, consts.CmdOptLogLevel, "l", logLevel, "log level (trace, debug, info, warn, error, fatal, panic)")
+	cmd.PersistentFlags().CountVarP(&globalOpts.Verbosity, consts.CmdOptVerbosity, "v", "verbosity level, -v for debug and -vv for trace. Overrides the log level")
+	cmd.PersistentFlags().BoolVar(&globalOpts.Quiet, consts.CmdOptQuiet, false, "only output the final result to stdout, and errors to stderr")
+	cmd.PersistentFlags().BoolVar(&globalOpts.NoColor, consts.CmdOptNoColor, false, "disable colored output. Also disabled by the "+consts.EnvNoColor+" environment variable")
+	cmd.PersistentFlags().StringVar(&globalOpts.LogFormat, consts.CmdOptLogFormat, logFormat, "log format (text, json)")
+	cmd.PersistentFlags().StringVar(&globalOpts.LogFile, consts.CmdOptLogFile, "", "write the logs to the file in addition to stderr")
 
 	groups := templates.CommandGroups{
 		{
@@ -48,10 +63,18 @@ func newCmdLonghornctlLocal() *cobra.Command {
 				localsubcmd.NewCmdInstall(globalOpts),
 			},
 		},
+		{
+			Message: "Host Commands:",
+			Commands: []*cobra.Command{
+				localsubcmd.NewCmdPreflight(globalOpts),
+			},
+		},
 		{
 			Message: "Operation Commands:",
 			Commands: []*cobra.Command{
 				localsubcmd.NewCmdTrim(globalOpts),
+				localsubcmd.NewCmdVolume(globalOpts),
+				localsubcmd.NewCmdCleanup(globalOpts),
 			},
 		},
 		{
@@ -59,11 +82,14 @@ func newCmdLonghornctlLocal() *cobra.Command {
 			Commands: []*cobra.Command{
 				localsubcmd.NewCmdCheck(globalOpts),
 				localsubcmd.NewCmdGet(globalOpts),
+				localsubcmd.NewCmdCollect(globalOpts),
+				localsubcmd.NewCmdInspect(globalOpts),
 			},
 		},
 	}
 	groups.Add(cmd)
 
+	cmd.AddCommand(localsubcmd.NewCmdAgent(globalOpts))
 	cmd.AddCommand(localsubcmd.NewCmdVersion())
 	cmd.AddCommand(remotesubcmd.NewCmdGlobalOptions())
 
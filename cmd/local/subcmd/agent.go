@@ -0,0 +1,64 @@
+package subcmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/local/agent"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+)
+
+func NewCmdAgent(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var localAgent = agent.Agent{}
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdAgent + " -- COMMAND [ARGS...]",
+		Short: "Run a command as the node agent of the CLI",
+		Long: `This command runs the given command on the node, and serves its status, progress and result over HTTP on the localhost of the pod, until it is terminated.
+The CLI reaches the node agent through a port-forward to follow the progress of the command and fetch its result, instead of reading the logs of the pod.
+
+The command runs with JSON logs, each line becoming a progress event. Its result is read from the output file when it completes.
+
+Endpoints:
+- ` + consts.AgentPathStatus + `: the phase, exit code and error of the command.
+- ` + consts.AgentPathProgress + `?after=SEQUENCE&follow=true: the progress events as JSON lines, followed until the command completes.
+- ` + consts.AgentPathResult + `: the result of the command.`,
+		Example: `$ longhornctl-local agent --output-file=/shared/output.json -- longhornctl-local check preflight`,
+		Args:    cobra.MinimumNArgs(1),
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			localAgent.LogLevel = globalOpts.LogLevel
+			localAgent.Command = args
+
+			utils.CheckErr(localAgent.Validate())
+
+			if err := localAgent.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize agent"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			if err := localAgent.Run(ctx); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to run agent"))
+			}
+		},
+	}
+
+	utils.SetGlobalOptionsLocal(cmd, globalOpts)
+
+	cmd.Flags().StringVar(&localAgent.NodeName, consts.CmdOptNodeId, os.Getenv(consts.EnvCurrentNodeID), "Current node ID.")
+	cmd.Flags().IntVar(&localAgent.Port, consts.CmdOptPort, utils.ConvertStringToTypeOrDefault(os.Getenv(consts.EnvAgentPort), consts.AgentPort), "Port to serve the agent on the localhost of the pod.")
+	cmd.Flags().StringVarP(&localAgent.OutputFilePath, consts.CmdOptOutputFile, "o", os.Getenv(consts.EnvOutputFilePath), "Output file the command writes its result to.")
+
+	return cmd
+}
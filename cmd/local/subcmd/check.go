@@ -1,6 +1,7 @@
 package subcmd
 
 import (
+	"fmt"
 	"os"
 
 	"github.com/pkg/errors"
@@ -8,7 +9,9 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/local/device"
 	local "github.com/longhorn/cli/pkg/local/preflight"
+	localvolume "github.com/longhorn/cli/pkg/local/volume"
 	"github.com/longhorn/cli/pkg/types"
 	"github.com/longhorn/cli/pkg/utils"
 )
@@ -21,16 +24,22 @@ func NewCmdCheck(globalOpts *types.GlobalCmdOptions) *cobra.Command {
 
 	utils.SetGlobalOptionsLocal(cmd, globalOpts)
 
-	cmd.AddCommand(newCmdCheckPreflight(globalOpts))
+	cmd.AddCommand(newCmdCheckPreflight(globalOpts, consts.SubCmdPreflight, consts.VolumeMountHostDirectory))
+	cmd.AddCommand(newCmdCheckMounts(globalOpts))
+	cmd.AddCommand(newCmdCheckPciBindings(globalOpts))
+	cmd.AddCommand(newCmdCheckRwx(globalOpts))
+	cmd.AddCommand(newCmdCheckTuning(globalOpts))
 
 	return cmd
 }
 
-func newCmdCheckPreflight(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+// newCmdCheckPreflight returns the command running the preflight check with the name. The host root
+// directory defaults to the one mounted in the pod, or to / when running directly on the host.
+func newCmdCheckPreflight(globalOpts *types.GlobalCmdOptions, use, hostRootDirectory string) *cobra.Command {
 	var localChecker = local.Checker{}
 
 	cmd := &cobra.Command{
-		Use:   consts.SubCmdPreflight,
+		Use:   use,
 		Short: "Run a preflight check for Longhorn",
 		Long:  `This command verifies your Kubernetes cluster environment to ensure it meets Longhorn's requirements. It performs a series of checks that can help identify potential issues that may prevent Longhorn from functioning correctly.`,
 
@@ -62,9 +71,194 @@ func newCmdCheckPreflight(globalOpts *types.GlobalCmdOptions) *cobra.Command {
 	utils.SetGlobalOptionsLocal(cmd, globalOpts)
 
 	cmd.Flags().StringVarP(&localChecker.OutputFilePath, consts.CmdOptOutputFile, "o", os.Getenv(consts.EnvOutputFilePath), "Output the result to a file, default to stdout.")
+	cmd.Flags().StringVar(&localChecker.HostRootDirectory, consts.CmdOptHostRoot, hostRootDirectory, "Directory where the root filesystem of the host is mounted. Set to / to run directly on the host.")
+	cmd.Flags().StringVar(&localChecker.Namespace, consts.CmdOptNamespace, os.Getenv(consts.EnvNamespace), "Namespace where the node agent DaemonSet for Container-Optimized OS is deployed.")
+	cmd.Flags().StringVar(&localChecker.CustomChecksFile, consts.CmdOptCustomChecks, os.Getenv(consts.EnvCustomChecks), "Path to a YAML file defining custom checks to run on the node.")
 	cmd.Flags().BoolVar(&localChecker.EnableSpdk, consts.CmdOptEnableSpdk, utils.ConvertStringToTypeOrDefault(os.Getenv(consts.EnvEnableSpdk), false), "Enable checking of SPDK required packages, modules, and setup.")
 	cmd.Flags().IntVar(&localChecker.HugePageSize, consts.CmdOptHugePageSize, utils.ConvertStringToTypeOrDefault(os.Getenv(consts.EnvHugePageSize), 2048), "Specify the huge page size in MiB for SPDK.")
+	cmd.Flags().StringVar(&localChecker.HugePageNodes, consts.CmdOptHugePageNodes, os.Getenv(consts.EnvHugePageNodes), fmt.Sprintf("Specify a comma-separated (%s) list of huge page sizes in MiB per NUMA node, for example 0=1024,1=1024. Overrides --%s.", consts.CmdOptSeperator, consts.CmdOptHugePageSize))
 	cmd.Flags().StringVar(&localChecker.UserspaceDriver, consts.CmdOptUserspaceDriver, os.Getenv(consts.EnvUserspaceDriver), "Userspace I/O driver for SPDK.")
+	cmd.Flags().StringVar(&localChecker.Profile, consts.CmdOptProfile, os.Getenv(consts.EnvPreflightProfile), "Managed platform or Kubernetes distribution of the cluster, enabling its specific checks and skipping the ones that do not apply.")
+	cmd.Flags().StringSliceVar(&localChecker.LonghornDataDirectories, consts.CmdOptLonghornDataDirectory, utils.SplitList(os.Getenv(consts.EnvLonghornDataDirectory)), "Data paths of the Longhorn disks on the node. Can be repeated or comma-separated. Defaults to "+consts.LonghornDefaultDataDirectory+".")
+	cmd.Flags().BoolVar(&localChecker.CryptoBenchmark, consts.CmdOptCryptoBenchmark, utils.ConvertStringToTypeOrDefault(os.Getenv(consts.EnvCryptoBenchmark), false), "Benchmark the default cipher of the Longhorn encrypted volumes (aes-xts, 256-bit key) with cryptsetup.")
+	cmd.Flags().StringVar(&localChecker.KnownIssues, consts.CmdOptKnownIssues, os.Getenv(consts.EnvKnownIssues), "Known issues database in JSON or YAML to match the node against. Defaults to the one embedded in longhornctl.")
+	cmd.Flags().StringVar(&localChecker.RegistryCheckImages, consts.CmdOptRegistryCheckImages, os.Getenv(consts.EnvRegistryCheckImages), fmt.Sprintf("Specify a comma-separated (%s) list of images whose manifests are fetched through the registry mirrors configured for containerd on the node.", consts.CmdOptSeperator))
+
+	return cmd
+}
+
+func newCmdCheckPciBindings(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var localPciBindingsChecker = local.PciBindingsChecker{}
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdPciBindings,
+		Short: "Inspect the driver bindings of the NVMe PCI devices for SPDK",
+		Long:  `This command lists the NVMe PCI devices of the node with their current kernel driver and IOMMU group, and checks the devices intended for SPDK are bound to the userspace driver.`,
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			localPciBindingsChecker.LogLevel = globalOpts.LogLevel
+
+			if err := localPciBindingsChecker.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize PCI bindings checker"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := localPciBindingsChecker.Run(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to run PCI bindings checker"))
+			}
+
+			logrus.Info("Successfully checked PCI bindings")
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			if err := localPciBindingsChecker.Output(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to output PCI bindings checker collection"))
+			}
+
+			logrus.Info("Successfully output PCI bindings checker collection")
+		},
+	}
+
+	utils.SetGlobalOptionsLocal(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&localPciBindingsChecker.OutputFilePath, consts.CmdOptOutputFile, "o", os.Getenv(consts.EnvOutputFilePath), "Output the result to a file, default to stdout.")
+	cmd.Flags().StringVar(&localPciBindingsChecker.HostRootDirectory, consts.CmdOptHostRoot, consts.VolumeMountHostDirectory, "Directory where the root filesystem of the host is mounted. Set to / to run directly on the host.")
+	cmd.Flags().StringVar(&localPciBindingsChecker.AllowPci, consts.CmdOptAllowPci, os.Getenv(consts.EnvPciAllowed), fmt.Sprintf("Specify a comma-separated (%s) list of the PCI devices intended for SPDK.", consts.CmdOptSeperator))
+	cmd.Flags().StringVar(&localPciBindingsChecker.DriverOverride, consts.CmdOptDriverOverride, os.Getenv(consts.EnvDriverOverride), "Userspace driver intended for the PCI devices. Defaults to vfio-pci when IOMMU is enabled, and uio_pci_generic otherwise.")
+
+	return cmd
+}
+
+func newCmdCheckMounts(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var localMountChecker = device.MountChecker{}
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdMounts,
+		Short: "Check the mounts of the Longhorn volumes not attached to the node",
+		Long: `This command finds the mounts of the Longhorn block devices and dm-crypt mappings whose volumes are not attached to this node, and unmounts them with --` + consts.CmdOptRepair + `.
+It must run in the host PID namespace.`,
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			localMountChecker.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(localMountChecker.Validate())
+
+			if err := localMountChecker.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize mount checker"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := localMountChecker.Run(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to run mount checker"))
+			}
+
+			logrus.Info("Successfully checked mounts")
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			if err := localMountChecker.Output(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to output mount checker collection"))
+			}
+
+			logrus.Info("Successfully output mount checker collection")
+		},
+	}
+
+	utils.SetGlobalOptionsLocal(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&localMountChecker.OutputFilePath, consts.CmdOptOutputFile, "o", os.Getenv(consts.EnvOutputFilePath), "Output the result to a file, default to stdout.")
+	cmd.Flags().StringVar(&localMountChecker.CurrentNodeID, consts.CmdOptNodeId, os.Getenv(consts.EnvCurrentNodeID), "Current node ID.")
+	cmd.Flags().BoolVar(&localMountChecker.Repair, consts.CmdOptRepair, utils.ConvertStringToTypeOrDefault(os.Getenv(consts.EnvRepair), false), "Unmount the stale mounts.")
+	cmd.Flags().StringVar(&localMountChecker.VolumeNames, consts.CmdOptLonghornVolumeNames, os.Getenv(consts.EnvLonghornVolumeNames), "Comma-separated names of all the Longhorn volumes.")
+	cmd.Flags().StringVar(&localMountChecker.VolumeAttachments, consts.CmdOptLonghornVolumeAttachments, os.Getenv(consts.EnvLonghornVolumeAttachments), "Comma-separated attachments of the Longhorn volumes, as <node>:<volume>. The mounts of the volumes attached to this node are kept.")
+
+	return cmd
+}
+
+func newCmdCheckRwx(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var localRwxChecker = localvolume.RwxChecker{}
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdRwx,
+		Short: "Check the NFS client mounts of a ReadWriteMany volume",
+		Long:  `This command checks the NFS mounts of the ReadWriteMany volume on the node use NFSv4 and the share manager endpoint, and respond without stale file handle. It also reports the kernel release and the version of the NFS client kernel modules.`,
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			localRwxChecker.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(localRwxChecker.Validate())
+
+			if err := localRwxChecker.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize RWX checker"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := localRwxChecker.Run(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to run RWX checker"))
+			}
+
+			logrus.Info("Successfully checked RWX volume")
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			if err := localRwxChecker.Output(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to output RWX checker collection"))
+			}
+
+			logrus.Info("Successfully output RWX checker collection")
+		},
+	}
+
+	utils.SetGlobalOptionsLocal(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&localRwxChecker.OutputFilePath, consts.CmdOptOutputFile, "o", os.Getenv(consts.EnvOutputFilePath), "Output the result to a file, default to stdout.")
+	cmd.Flags().StringVar(&localRwxChecker.HostRootDirectory, consts.CmdOptHostRoot, consts.VolumeMountHostDirectory, "Directory where the root filesystem of the host is mounted. Set to / to run directly on the host.")
+	cmd.Flags().StringVar(&localRwxChecker.VolumeName, consts.CmdOptLonghornVolumeName, os.Getenv(consts.EnvLonghornVolumeName), "Name of the Longhorn volume to check.")
+	cmd.Flags().StringVar(&localRwxChecker.ShareEndpoint, consts.CmdOptLonghornShareEndpoint, os.Getenv(consts.EnvLonghornShareEndpoint), "NFS endpoint of the share manager, for example nfs://10.43.0.10/pvc-1234.")
+
+	return cmd
+}
+
+func newCmdCheckTuning(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var localTuningChecker = local.TuningChecker{}
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdTuning,
+		Short: "Check the node against the tuning profile for storage nodes",
+		Long:  `This command reports the drift of the live and persisted sysctl parameters, and of the open files limit of the instance-manager processes, from the tuning profile.`,
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			localTuningChecker.LogLevel = globalOpts.LogLevel
+
+			if err := localTuningChecker.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize tuning checker"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := localTuningChecker.Run(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to run tuning checker"))
+			}
+
+			logrus.Info("Successfully checked tuning")
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			if err := localTuningChecker.Output(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to output tuning checker collection"))
+			}
+
+			logrus.Info("Successfully output tuning checker collection")
+		},
+	}
+
+	utils.SetGlobalOptionsLocal(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&localTuningChecker.OutputFilePath, consts.CmdOptOutputFile, "o", os.Getenv(consts.EnvOutputFilePath), "Output the result to a file, default to stdout.")
+	cmd.Flags().StringVar(&localTuningChecker.HostRootDirectory, consts.CmdOptHostRoot, consts.VolumeMountHostDirectory, "Directory where the root filesystem of the host is mounted. Set to / to run directly on the host.")
 
 	return cmd
 }
@@ -0,0 +1,73 @@
+package subcmd
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/local/device"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+)
+
+func NewCmdCleanup(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdCleanup,
+		Short: "Longhorn node cleanup operations",
+	}
+
+	utils.SetGlobalOptionsLocal(cmd, globalOpts)
+
+	cmd.AddCommand(newCmdCleanupNodeDevices(globalOpts))
+
+	return cmd
+}
+
+func newCmdCleanupNodeDevices(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var localCleaner = device.Cleaner{}
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdNodeDevices,
+		Short: "Clean up the leftover iSCSI sessions, dm-crypt mappings and Longhorn block devices",
+		Long: `This command removes the dm-crypt mappings, the iSCSI sessions and the block devices in /dev/longhorn of the Longhorn volumes that are not active on this node. The devices in use are kept.
+It must run in the host PID namespace.`,
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			localCleaner.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(localCleaner.Validate())
+
+			if err := localCleaner.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize node device cleaner"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := localCleaner.Run(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to run node device cleaner"))
+			}
+
+			logrus.Info("Successfully cleaned up node devices")
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			if err := localCleaner.Output(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to output node device cleaner collection"))
+			}
+
+			logrus.Info("Successfully output node device cleaner collection")
+		},
+	}
+
+	utils.SetGlobalOptionsLocal(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&localCleaner.OutputFilePath, consts.CmdOptOutputFile, "o", os.Getenv(consts.EnvOutputFilePath), "Output the result to a file, default to stdout.")
+	cmd.Flags().BoolVar(&localCleaner.DryRun, consts.CmdOptDryRun, utils.ConvertStringToTypeOrDefault(os.Getenv(consts.EnvDryRun), false), "Only report the devices that would be removed.")
+	cmd.Flags().StringVar(&localCleaner.VolumeNames, consts.CmdOptLonghornVolumeNames, os.Getenv(consts.EnvLonghornVolumeNames), "Comma-separated names of all the Longhorn volumes.")
+	cmd.Flags().StringVar(&localCleaner.ActiveVolumes, consts.CmdOptLonghornActiveVolumes, os.Getenv(consts.EnvLonghornActiveVolumes), "Comma-separated names of the Longhorn volumes attached to this node, whose devices are kept.")
+
+	return cmd
+}
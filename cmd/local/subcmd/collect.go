@@ -0,0 +1,111 @@
+package subcmd
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/longhorn/cli/pkg/consts"
+	localbaseline "github.com/longhorn/cli/pkg/local/baseline"
+	local "github.com/longhorn/cli/pkg/local/nodefacts"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+)
+
+func NewCmdCollect(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdCollect,
+		Short: "Longhorn node inventory operations",
+	}
+
+	utils.SetGlobalOptionsLocal(cmd, globalOpts)
+
+	cmd.AddCommand(newCmdCollectNodeFacts(globalOpts))
+	cmd.AddCommand(newCmdCollectPrerequisites(globalOpts))
+
+	return cmd
+}
+
+func newCmdCollectNodeFacts(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var localCollector = local.Collector{}
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdNodeFacts,
+		Short: "Collect the hardware and operating system inventory of the node",
+		Long:  `This command collects the hardware and operating system inventory of the node relevant to storage: kernel, operating system, CPU, NUMA nodes, memory, disks, network interfaces and loaded kernel modules.`,
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			localCollector.LogLevel = globalOpts.LogLevel
+
+			if err := localCollector.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize node facts collector"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := localCollector.Run(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to run node facts collector"))
+			}
+
+			logrus.Info("Successfully collected node facts")
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			if err := localCollector.Output(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to output node facts"))
+			}
+
+			logrus.Info("Successfully output node facts")
+		},
+	}
+
+	utils.SetGlobalOptionsLocal(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&localCollector.OutputFilePath, consts.CmdOptOutputFile, "o", os.Getenv(consts.EnvOutputFilePath), "Output the result to a file, default to stdout.")
+	cmd.Flags().StringVar(&localCollector.HostRootDirectory, consts.CmdOptHostRoot, consts.VolumeMountHostDirectory, "Directory where the root filesystem of the host is mounted. Set to / to run directly on the host.")
+
+	return cmd
+}
+
+func newCmdCollectPrerequisites(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var localCollector = localbaseline.Collector{}
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdPrerequisites,
+		Short: "Collect the prerequisite state of the node",
+		Long:  `This command collects the prerequisite state of the node recorded in the baseline: operating system, kernel, packages, kernel modules, services, sysctl parameters and multipath configuration.`,
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			localCollector.LogLevel = globalOpts.LogLevel
+
+			if err := localCollector.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize prerequisites collector"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := localCollector.Run(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to run prerequisites collector"))
+			}
+
+			logrus.Info("Successfully collected prerequisites")
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			if err := localCollector.Output(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to output prerequisites"))
+			}
+
+			logrus.Info("Successfully output prerequisites")
+		},
+	}
+
+	utils.SetGlobalOptionsLocal(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&localCollector.OutputFilePath, consts.CmdOptOutputFile, "o", os.Getenv(consts.EnvOutputFilePath), "Output the result to a file, default to stdout.")
+	cmd.Flags().StringVar(&localCollector.HostRootDirectory, consts.CmdOptHostRoot, consts.VolumeMountHostDirectory, "Directory where the root filesystem of the host is mounted. Set to / to run directly on the host.")
+
+	return cmd
+}
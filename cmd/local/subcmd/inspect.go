@@ -0,0 +1,79 @@
+package subcmd
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/longhorn/cli/pkg/consts"
+	local "github.com/longhorn/cli/pkg/local/replica"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+)
+
+func NewCmdInspect(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdInspect,
+		Short: "Longhorn on-disk data inspection operations",
+	}
+
+	utils.SetGlobalOptionsLocal(cmd, globalOpts)
+
+	cmd.AddCommand(newCmdInspectReplicaMeta(globalOpts))
+
+	return cmd
+}
+
+func newCmdInspectReplicaMeta(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var localInspector = local.Inspector{}
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdReplicaMeta,
+		Short: "Inspect the metadata files of the replicas of a volume",
+		Long: `This command reads the volume.meta file and the snapshot metadata files in the data directories of the replicas of a volume on this node, and reports their inconsistencies.
+With --` + consts.CmdOptRepair + `, the known-safe fixes are applied. The volume must be detached.`,
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			localInspector.LogLevel = globalOpts.LogLevel
+
+			if localInspector.VolumeName == "" {
+				utils.CheckErr(errors.Errorf("Volume name (--%s) is required", consts.CmdOptLonghornVolumeName))
+			}
+
+			err := localInspector.Init()
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize replica metadata inspector"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			err := localInspector.Run()
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to run replica metadata inspector"))
+			}
+
+			logrus.Info("Successfully inspected replica metadata")
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			err := localInspector.Output()
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to output replica metadata inspector collection"))
+			}
+
+			logrus.Info("Successfully output replica metadata inspector collection")
+		},
+	}
+
+	utils.SetGlobalOptionsLocal(cmd, globalOpts)
+
+	cmd.Flags().StringVar(&localInspector.CurrentNodeID, consts.CmdOptNodeId, os.Getenv(consts.EnvCurrentNodeID), "Current node ID.")
+	cmd.Flags().StringVarP(&localInspector.OutputFilePath, consts.CmdOptOutputFile, "o", os.Getenv(consts.EnvOutputFilePath), "Output the result to a file, default to stdout.")
+	cmd.Flags().StringVar(&localInspector.VolumeName, consts.CmdOptLonghornVolumeName, os.Getenv(consts.EnvLonghornVolumeName), "Specify the name of the volume whose replicas are inspected.")
+	cmd.Flags().StringVar(&localInspector.LonghornDataDirectory, consts.CmdOptLonghornDataDirectory, os.Getenv(consts.EnvLonghornDataDirectory), "Specify the Longhorn data directory. If not provided, the default will be attempted, or it will fall back to the directory of longhorn-disk.cfg.")
+	cmd.Flags().BoolVar(&localInspector.Repair, consts.CmdOptRepair, utils.ConvertStringToTypeOrDefault(os.Getenv(consts.EnvRepair), false), "Apply the known-safe fixes.")
+
+	return cmd
+}
@@ -23,16 +23,19 @@ func NewCmdInstall(globalOpts *types.GlobalCmdOptions) *cobra.Command {
 
 	utils.SetGlobalOptionsLocal(cmd, globalOpts)
 
-	cmd.AddCommand(newCmdInstallPreflight(globalOpts))
+	cmd.AddCommand(newCmdInstallPreflight(globalOpts, consts.SubCmdPreflight, consts.VolumeMountHostDirectory))
+	cmd.AddCommand(newCmdInstallTuning(globalOpts))
 
 	return cmd
 }
 
-func newCmdInstallPreflight(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+// newCmdInstallPreflight returns the command running the preflight install with the name. The host root
+// directory defaults to the one mounted in the pod, or to / when running directly on the host.
+func newCmdInstallPreflight(globalOpts *types.GlobalCmdOptions, use, hostRootDirectory string) *cobra.Command {
 	var localInstaller = local.Installer{}
 
 	cmd := &cobra.Command{
-		Use:   consts.SubCmdPreflight,
+		Use:   use,
 		Short: "Install and configure prerequisites",
 		Long: `This command prepares your system for Longhorn deployment by installing the necessary dependencies.
 These dependencies ensure your Kubernetes cluster meets the requirements for successful Longhorn operation.`,
@@ -65,12 +68,58 @@ These dependencies ensure your Kubernetes cluster meets the requirements for suc
 	utils.SetGlobalOptionsLocal(cmd, globalOpts)
 
 	cmd.Flags().StringVarP(&localInstaller.OutputFilePath, consts.CmdOptOutputFile, "o", os.Getenv(consts.EnvOutputFilePath), "Output the result to a file, default to stdout.")
+	cmd.Flags().StringVar(&localInstaller.HostRootDirectory, consts.CmdOptHostRoot, hostRootDirectory, "Directory where the root filesystem of the host is mounted. Set to / to run directly on the host.")
 	cmd.Flags().BoolVar(&localInstaller.UpdatePackages, consts.CmdOptUpdatePackages, utils.ConvertStringToTypeOrDefault(os.Getenv(consts.EnvUpdatePackageList), true), "Update packages before installing required dependencies.")
+	cmd.Flags().StringVar(&localInstaller.PackageSource, consts.CmdOptPackageSource, os.Getenv(consts.EnvPackageSource), "Directory on the host holding the package files to install, instead of the package repositories. The package lists are not updated.")
+	cmd.Flags().BoolVar(&localInstaller.ResetCheckpoint, consts.CmdOptResetCheckpoint, utils.ConvertStringToTypeOrDefault(os.Getenv(consts.EnvResetCheckpoint), false), "Ignore the steps recorded as completed by the previous installs, and run all the steps again.")
+	cmd.Flags().BoolVar(&localInstaller.TuneIscsid, consts.CmdOptTuneIscsid, utils.ConvertStringToTypeOrDefault(os.Getenv(consts.EnvTuneIscsid), false), "Apply the recommended iscsid configuration, and disable its CHAP parameters.")
 	cmd.Flags().BoolVar(&localInstaller.EnableSpdk, consts.CmdOptEnableSpdk, utils.ConvertStringToTypeOrDefault(os.Getenv(consts.EnvEnableSpdk), false), "Enable installation of SPDK required packages, modules, and setup.")
 	cmd.Flags().StringVar(&localInstaller.SpdkOptions, consts.CmdOptSpdkOptions, os.Getenv(consts.EnvSpdkOptions), fmt.Sprintf("Specify a comma-separated (%s) list of custom options for configuring SPDK environment.", consts.CmdOptSeperator))
 	cmd.Flags().IntVar(&localInstaller.HugePageSize, consts.CmdOptHugePageSize, utils.ConvertStringToTypeOrDefault(os.Getenv(consts.EnvHugePageSize), 2048), "Specify the huge page size in MiB for SPDK.")
+	cmd.Flags().StringVar(&localInstaller.HugePageNodes, consts.CmdOptHugePageNodes, os.Getenv(consts.EnvHugePageNodes), fmt.Sprintf("Specify a comma-separated (%s) list of huge page sizes in MiB per NUMA node, for example 0=1024,1=1024. Overrides --%s.", consts.CmdOptSeperator, consts.CmdOptHugePageSize))
 	cmd.Flags().StringVar(&localInstaller.AllowPci, consts.CmdOptAllowPci, os.Getenv(consts.EnvPciAllowed), fmt.Sprintf("Specify a comma-separated (%s) list of allowed PCI devices. By default, all PCI devices are blocked by a non-valid address.", consts.CmdOptSeperator))
 	cmd.Flags().StringVar(&localInstaller.DriverOverride, consts.CmdOptDriverOverride, os.Getenv(consts.EnvDriverOverride), "Userspace driver for device bindings. Override default driver for PCI devices.")
 
 	return cmd
 }
+
+func newCmdInstallTuning(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var localTuningInstaller = local.TuningInstaller{}
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdTuning,
+		Short: "Apply the tuning profile for storage nodes",
+		Long:  `This command persists and applies the sysctl parameters of the tuning profile, and raises the open files limit of the container runtime with a systemd drop-in.`,
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			localTuningInstaller.LogLevel = globalOpts.LogLevel
+
+			if err := localTuningInstaller.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize tuning installer"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := localTuningInstaller.Run(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to run tuning installer"))
+			}
+
+			logrus.Info("Successfully applied tuning profile")
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			if err := localTuningInstaller.Output(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to output tuning installer collection"))
+			}
+
+			logrus.Info("Successfully output tuning installer collection")
+		},
+	}
+
+	utils.SetGlobalOptionsLocal(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&localTuningInstaller.OutputFilePath, consts.CmdOptOutputFile, "o", os.Getenv(consts.EnvOutputFilePath), "Output the result to a file, default to stdout.")
+	cmd.Flags().StringVar(&localTuningInstaller.HostRootDirectory, consts.CmdOptHostRoot, consts.VolumeMountHostDirectory, "Directory where the root filesystem of the host is mounted. Set to / to run directly on the host.")
+
+	return cmd
+}
@@ -0,0 +1,28 @@
+package subcmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+)
+
+func NewCmdPreflight(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdPreflight,
+		Short: "Longhorn preflight operations on the host",
+		Long: `These commands run the preflight check and install directly on the host, without a pod or access to the Kubernetes API.
+They can be baked into golden images or run by configuration management, and output the same result as the "` + consts.SubCmdCheck + ` ` + consts.SubCmdPreflight + `" and "` + consts.SubCmdInstall + ` ` + consts.SubCmdPreflight + `" commands.
+The checks requiring the Kubernetes API are skipped.`,
+		Example: `$ sudo longhornctl-local preflight check --output-file=/var/log/longhorn-preflight.json
+$ sudo longhornctl-local preflight install --enable-spdk`,
+	}
+
+	utils.SetGlobalOptionsLocal(cmd, globalOpts)
+
+	cmd.AddCommand(newCmdCheckPreflight(globalOpts, consts.SubCmdCheck, "/"))
+	cmd.AddCommand(newCmdInstallPreflight(globalOpts, consts.SubCmdInstall, "/"))
+
+	return cmd
+}
@@ -0,0 +1,66 @@
+package subcmd
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/longhorn/cli/pkg/consts"
+	local "github.com/longhorn/cli/pkg/local/volume"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+)
+
+func NewCmdVolume(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdVolume,
+		Short: "Longhorn volume maintenance operations",
+	}
+
+	utils.SetGlobalOptionsLocal(cmd, globalOpts)
+
+	cmd.AddCommand(newCmdVolumeRekey(globalOpts))
+
+	return cmd
+}
+
+func newCmdVolumeRekey(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var localRekeyer = local.Rekeyer{}
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdRekey,
+		Short: "Rotate the encryption key of a Longhorn volume",
+		Long: `This command rotates the encryption key of the Longhorn volume attached to this node with cryptsetup in the host namespaces.
+The current and new keys are read from the ` + consts.EnvCryptoKeyValue + ` and ` + consts.EnvNewCryptoKeyValue + ` environment variables.
+The data is re-encrypted with a new volume key unless --` + consts.CmdOptKeyOnly + ` is set, then the new key replaces the current one. An interrupted rotation is resumed by running the command again.`,
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			localRekeyer.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(localRekeyer.Validate())
+
+			err := localRekeyer.Init()
+			if err != nil {
+				utils.CheckErr(errors.Wrapf(err, "Failed to initialize rekeyer for volume %s", localRekeyer.VolumeName))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			err := localRekeyer.Run()
+			if err != nil {
+				utils.CheckErr(errors.Wrapf(err, "Failed to run rekeyer for volume %s", localRekeyer.VolumeName))
+			}
+
+			logrus.Infof("Successfully rekeyed volume %s", localRekeyer.VolumeName)
+		},
+	}
+
+	utils.SetGlobalOptionsLocal(cmd, globalOpts)
+
+	cmd.Flags().StringVar(&localRekeyer.VolumeName, consts.CmdOptLonghornVolumeName, os.Getenv(consts.EnvLonghornVolumeName), "Name of the Longhorn volume to rekey.")
+	cmd.Flags().BoolVar(&localRekeyer.KeyOnly, consts.CmdOptKeyOnly, utils.ConvertStringToTypeOrDefault(os.Getenv(consts.EnvKeyOnly), false), "Only replace the passphrase, without re-encrypting the data.")
+
+	return cmd
+}
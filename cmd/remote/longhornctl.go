@@ -12,6 +12,10 @@ import (
 	"github.com/longhorn/cli/pkg/consts"
 	"github.com/longhorn/cli/pkg/types"
 	"github.com/longhorn/cli/pkg/utils"
+	"github.com/longhorn/cli/pkg/utils/i18n"
+	"github.com/longhorn/cli/pkg/utils/output"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
 )
 
 func main() {
@@ -27,52 +31,143 @@ func newCmdLonghornctl() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   consts.CmdLonghornctlRemote,
 		Short: "Command-line interface for Longhorn.",
-		Long:  "A CLI tool for troubleshooting and managing Longhorn operations.",
+		Long: `A CLI tool for troubleshooting and managing Longhorn operations.
+
+The commands exit with a code telling the category of their failure, to branch on in scripts:
+  1  other errors
+  3  a node is unreachable, through the port-forward to its node agent or over SSH
+  4  a pod created by the CLI cannot be scheduled
+  5  the package manager of a node failed
+  6  an operation timed out`,
 		PersistentPreRun: func(cmd *cobra.Command, args []string) {
-			err := utils.SetLog(globalOpts.LogLevel)
+			err := utils.SetLog(globalOpts)
 			if err != nil {
-				logrus.WithError(err).Warn("Failed to set log level")
+				logrus.WithError(err).Warn("Failed to set logger")
 			}
+
+			utils.CheckErr(i18n.SetLanguage(globalOpts.Lang))
+
+			utils.CheckErr(utils.SetProxyEnv(globalOpts))
+
+			utils.CheckErr(kubeutils.SetClientRateLimits(globalOpts.KubeApiQps, globalOpts.KubeApiBurst))
+
+			utils.CheckErr(output.SetOutputTargets(globalOpts))
+
+			subcmd.StartAudit(cmd, args, globalOpts)
+
+			subcmd.StartTelemetry(cmd, globalOpts)
+
+			subcmd.AcquireOperationLock(cmd, globalOpts)
+
+			subcmd.StartRun(cmd)
+
+			subcmd.SubmitAsync(cmd, args, globalOpts)
+		},
+		PersistentPostRun: func(cmd *cobra.Command, args []string) {
+			subcmd.CompleteRun()
+
+			subcmd.ReleaseOperationLock()
+
+			subcmd.CompleteAudit(cmd, args, globalOpts)
+
+			subcmd.CompleteTelemetry(cmd, globalOpts)
 		},
 	}
 
 	cmd.CompletionOptions.HiddenDefaultCmd = true
 
 	cmd.PersistentFlags().StringVarP(&globalOpts.LogLevel, consts.CmdOptLogLevel, "l", "info", "log level (trace, debug, info, warn, error, fatal, panic)")
+	cmd.PersistentFlags().CountVarP(&globalOpts.Verbosity, consts.CmdOptVerbosity, "v", "verbosity level, -v for debug and -vv for trace. Overrides the log level")
+	cmd.PersistentFlags().BoolVar(&globalOpts.Quiet, consts.CmdOptQuiet, false, "only output the final result to stdout, and errors to stderr")
+	cmd.PersistentFlags().BoolVar(&globalOpts.NoColor, consts.CmdOptNoColor, false, "disable colored output. Also disabled by the "+consts.EnvNoColor+" environment variable")
+	cmd.PersistentFlags().StringVar(&globalOpts.Lang, consts.CmdOptLang, i18n.LanguageEnglish, utils.LangUsage)
+	cmd.PersistentFlags().StringVar(&globalOpts.LogFormat, consts.CmdOptLogFormat, consts.LogFormatText, "log format (text, json)")
+	cmd.PersistentFlags().StringVar(&globalOpts.LogFile, consts.CmdOptLogFile, "", "write the logs to the file in addition to stderr")
+	cmd.PersistentFlags().BoolVarP(&globalOpts.AssumeYes, consts.CmdOptYes, "y", false, "skip the confirmation prompts of operations modifying the nodes or volumes")
+	cmd.PersistentFlags().BoolVar(&globalOpts.ForceUnlock, consts.CmdOptForceUnlock, false, "take over the lock of another operation of the same kind in progress, when it is no longer running")
 	cmd.PersistentFlags().StringVar(&globalOpts.KubeConfigPath, consts.CmdOptKubeConfigPath, os.Getenv(consts.EnvKubeConfigPath), "Kubernetes config (kubeconfig) path")
+	cmd.PersistentFlags().Float32Var(&globalOpts.KubeApiQps, consts.CmdOptKubeApiQps, kubeutils.DefaultClientQPS, "Maximum number of requests per second to the Kubernetes API server, shared by all the requests of the CLI")
+	cmd.PersistentFlags().IntVar(&globalOpts.KubeApiBurst, consts.CmdOptKubeApiBurst, kubeutils.DefaultClientBurst, "Maximum burst of requests to the Kubernetes API server above the --"+consts.CmdOptKubeApiQps+" rate")
 	cmd.PersistentFlags().StringVar(&globalOpts.Image, consts.CmdOptImage, consts.ImageLonghornCli, "Image containing longhornctl-local")
+	cmd.PersistentFlags().StringVar(&globalOpts.Namespace, consts.CmdOptNamespace, consts.LonghornNamespace, "Namespace to deploy the resources (DaemonSets, ConfigMaps, RBAC) created by the CLI")
 	cmd.PersistentFlags().StringVar(&globalOpts.NodeSelector, consts.CmdOptNodeSelector, "", "Comma-separated list of key=value pairs to match against node labels, selecting the nodes the DaemonSet will run on (e.g. env=prod,zone=us-west).")
+	cmd.PersistentFlags().IntVar(&globalOpts.MinNodes, consts.CmdOptRequireMinNodes, 0, "Fail before creating a DaemonSet when fewer schedulable Linux nodes match --"+consts.CmdOptNodeSelector+", to enforce the coverage expected by automation")
+	cmd.PersistentFlags().StringVar(&globalOpts.PodCpu, consts.CmdOptPodCpu, "", "CPU requests and limits of the containers of the pods created by the CLI (e.g. 500m)")
+	cmd.PersistentFlags().StringVar(&globalOpts.PodMemory, consts.CmdOptPodMemory, "", "Memory requests and limits of the containers of the pods created by the CLI (e.g. 256Mi)")
+	cmd.PersistentFlags().StringVar(&globalOpts.PriorityClass, consts.CmdOptPriorityClass, "", "PriorityClass of the pods created by the CLI")
+	cmd.PersistentFlags().StringVar(&globalOpts.Proxy, consts.CmdOptProxy, "", "HTTP(S) proxy URL for the CLI and the pods created by the CLI, for example to install packages behind a proxy, without credentials. Overrides the HTTP_PROXY and HTTPS_PROXY environment variables")
+	cmd.PersistentFlags().StringVar(&globalOpts.NoProxy, consts.CmdOptNoProxy, "", "Comma-separated list of hosts excluded from the proxy. Overrides the NO_PROXY environment variable")
+	cmd.PersistentFlags().StringVar(&globalOpts.OutputTo, consts.CmdOptOutputTo, "", utils.OutputToUsage)
+	cmd.PersistentFlags().BoolVar(&globalOpts.Telemetry, consts.CmdOptTelemetry, false, "Opt in to sending anonymized usage statistics to --"+consts.CmdOptTelemetryURL+". The opt-in is persisted, see '"+consts.CmdLonghornctlRemote+" "+consts.SubCmdTelemetry+" --help'")
+	cmd.PersistentFlags().StringVar(&globalOpts.TelemetryURL, consts.CmdOptTelemetryURL, "", "HTTP or HTTPS URL the telemetry reports are sent to. Required on the first opt-in with --"+consts.CmdOptTelemetry)
+	cmd.PersistentFlags().BoolVar(&globalOpts.Privileged, consts.CmdOptPrivileged, true, "Run the pods created by the CLI as privileged. When false, the pods only get the capabilities and host mounts each operation needs. The replica exporter and the Container-Optimized OS installer always run privileged")
 
 	groups := templates.CommandGroups{
 		{
 			Message: "Install And Uninstall Commands:",
 			Commands: []*cobra.Command{
+				subcmd.NewCmdInit(globalOpts),
 				subcmd.NewCmdInstall(globalOpts),
+				subcmd.NewCmdPreload(globalOpts),
+				subcmd.NewCmdVerify(globalOpts),
 			},
 		},
 		{
 			Message: "Operation Commands:",
 			Commands: []*cobra.Command{
 				subcmd.NewCmdTrim(globalOpts),
+				subcmd.NewCmdVolume(globalOpts),
+				subcmd.NewCmdDr(globalOpts),
+				subcmd.NewCmdMigrate(globalOpts),
+				subcmd.NewCmdBackup(globalOpts),
+				subcmd.NewCmdSnapshot(globalOpts),
+				subcmd.NewCmdRestart(globalOpts),
+				subcmd.NewCmdRebuild(globalOpts),
+				subcmd.NewCmdCleanup(globalOpts),
 				subcmd.NewCmdExport(globalOpts),
+				subcmd.NewCmdImport(globalOpts),
+				subcmd.NewCmdGenerate(globalOpts),
+				subcmd.NewCmdApi(globalOpts),
+				subcmd.NewCmdValidate(globalOpts),
+				subcmd.NewCmdWait(globalOpts),
+				subcmd.NewCmdResult(globalOpts),
 			},
 		},
 		{
 			Message: "Troubleshoot Commands:",
 			Commands: []*cobra.Command{
 				subcmd.NewCmdCheck(globalOpts),
+				subcmd.NewCmdBaseline(globalOpts),
 				subcmd.NewCmdGet(globalOpts),
+				subcmd.NewCmdStatus(globalOpts),
+				subcmd.NewCmdCollect(globalOpts),
+				subcmd.NewCmdInspect(globalOpts),
+				subcmd.NewCmdReport(globalOpts),
+				subcmd.NewCmdEvents(globalOpts),
+				subcmd.NewCmdTop(globalOpts),
+				subcmd.NewCmdLogs(globalOpts),
+				subcmd.NewCmdNode(globalOpts),
+				subcmd.NewCmdServe(globalOpts),
+				subcmd.NewCmdBenchmark(globalOpts),
+				subcmd.NewCmdTest(globalOpts),
 			},
 		},
 	}
 	groups.Add(cmd)
 
-	cmd.AddCommand(subcmd.NewCmdVersion())
+	cmd.AddCommand(subcmd.NewCmdVersion(globalOpts))
+	cmd.AddCommand(subcmd.NewCmdSelfUpdate(globalOpts))
+	cmd.AddCommand(subcmd.NewCmdTelemetry(globalOpts))
+	cmd.AddCommand(subcmd.NewCmdSchema())
 	cmd.AddCommand(subcmd.NewCmdGlobalOptions())
 	cmd.AddCommand(subcmd.NewCmdDoc())
 
 	filters := []string{"options"}
 	templates.ActsAsRootCommand(cmd, filters, groups...)
 
+	subcmd.RegisterAsyncFlags(cmd, globalOpts)
+
+	subcmd.RegisterCompletions(cmd, globalOpts)
+
 	return cmd
 }
@@ -0,0 +1,90 @@
+package subcmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/remote/api"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+)
+
+func NewCmdApi(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var apiServer = api.Server{}
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdApi,
+		Short: "Serve the CLI operations over an HTTP API",
+		Long: `This command starts an HTTP server that accepts CLI operations as asynchronous jobs, so other tools and automation can drive them without shelling out.
+Every request except /healthz must carry the token in the "Authorization: Bearer <token>" header.
+
+Endpoints:
+  POST /v1/jobs              Submit a job: {"operation": "<operation>", "options": {...}}
+  GET  /v1/jobs              List the jobs
+  GET  /v1/jobs/{id}         Get the status of a job
+  GET  /v1/jobs/{id}/result  Get the result of a completed job
+
+Operations: ` + api.OperationCheckPreflight + `, ` + api.OperationInstallPreflight + `, ` + api.OperationTrimVolume + `, ` + api.OperationExportReplica + `.
+The options use the field names of the corresponding command options, for example {"VolumeName": "pvc-xxx"}.
+Options holding paths of files, such as CustomChecksFile, are rejected, as the files would be read on the server, and so are the options holding URLs the server would fetch, such as RulesURL.
+Jobs run one at a time, with the global options of this command. Completed jobs are kept for 24 hours, and at most 100 jobs are kept.
+The API listens on 127.0.0.1 by default. Serve it over HTTPS with --tls-cert and --tls-key before listening on other interfaces, otherwise the token is sent in plain text.`,
+		Example: `$ export LONGHORNCTL_API_TOKEN=secret
+$ longhornctl api --listen=:8443 --tls-cert=/etc/longhornctl/tls.crt --tls-key=/etc/longhornctl/tls.key
+INFO[2024-07-16T17:17:38+08:00] Serving API on :8443 over HTTPS
+
+$ curl -H "Authorization: Bearer secret" -d '{"operation": "check-preflight"}' https://longhornctl.example.com:8443/v1/jobs
+{"id":"7d2c1b1e-...","operation":"check-preflight","status":"pending","createdAt":"2024-07-16T09:17:40Z"}`,
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			if apiServer.Token == "" {
+				apiServer.Token = os.Getenv(consts.EnvApiToken)
+			}
+
+			apiServer.Image = globalOpts.Image
+			apiServer.KubeConfigPath = globalOpts.KubeConfigPath
+			apiServer.Namespace = globalOpts.Namespace
+			apiServer.NodeSelector = globalOpts.NodeSelector
+			apiServer.MinNodes = globalOpts.MinNodes
+			apiServer.PodCpu = globalOpts.PodCpu
+			apiServer.PodMemory = globalOpts.PodMemory
+			apiServer.PriorityClass = globalOpts.PriorityClass
+			apiServer.Proxy = globalOpts.Proxy
+			apiServer.NoProxy = globalOpts.NoProxy
+			apiServer.Privileged = globalOpts.Privileged
+			apiServer.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(apiServer.Validate())
+
+			logrus.Info("Initializing API server")
+			if err := apiServer.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize API server"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			if err := apiServer.Run(ctx); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to run API server"))
+			}
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVar(&apiServer.ListenAddress, consts.CmdOptListenAddress, "127.0.0.1:8080", "Address to serve the API on. Without --tls-cert and --tls-key, the bearer token is sent in plain text, so only listen on other interfaces behind a TLS-terminating proxy.")
+	cmd.Flags().StringVar(&apiServer.Token, consts.CmdOptToken, "", "Bearer token required to access the API. Defaults to the "+consts.EnvApiToken+" environment variable.")
+	cmd.Flags().StringVar(&apiServer.TLSCertFile, consts.CmdOptTLSCert, "", "Path of the TLS certificate file to serve the API over HTTPS. Requires --tls-key.")
+	cmd.Flags().StringVar(&apiServer.TLSKeyFile, consts.CmdOptTLSKey, "", "Path of the TLS private key file to serve the API over HTTPS. Requires --tls-cert.")
+
+	return cmd
+}
@@ -0,0 +1,97 @@
+package subcmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/remote/async"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// RegisterAsyncFlags registers the --no-wait option on the audited commands of the command and all
+// its subcommands, except the ones marked with consts.CmdAnnotationSync.
+func RegisterAsyncFlags(cmd *cobra.Command, globalOpts *types.GlobalCmdOptions) {
+	if cmd.Runnable() && cmd.Annotations[consts.CmdAnnotationAudit] != "" && cmd.Annotations[consts.CmdAnnotationSync] == "" {
+		cmd.Flags().BoolVar(&globalOpts.NoWait, consts.CmdOptNoWait, false, fmt.Sprintf("Run the command in a Job in the cluster with --%s after confirming it, and return immediately with its run ID. Get its status with '%s %s <run-id>' and its result with '%s %s <run-id>'. Paths of the options are resolved in the container of the Job.", consts.CmdOptYes, consts.CmdLonghornctlRemote, consts.SubCmdWait, consts.CmdLonghornctlRemote, consts.SubCmdResult))
+	}
+
+	for _, subcmd := range cmd.Commands() {
+		RegisterAsyncFlags(subcmd, globalOpts)
+	}
+}
+
+// SubmitAsync submits the command to a Job in the cluster when --no-wait is set, prints the ID of
+// its run to stdout, and skips running the command locally. The run ID is the ID of the run started
+// by StartRun, so the record and the Job of the run are labeled with it.
+func SubmitAsync(cmd *cobra.Command, args []string, globalOpts *types.GlobalCmdOptions) {
+	if !globalOpts.NoWait {
+		return
+	}
+
+	run := kubeutils.GetCurrentRun()
+	if run == nil {
+		utils.CheckErr(errors.Errorf("cannot submit the command with --%s without a run ID", consts.CmdOptNoWait))
+	}
+
+	submitter := async.Submitter{
+		SubmitterCmdOptions: async.SubmitterCmdOptions{
+			GlobalCmdOptions: *globalOpts,
+			RunID:            run.ID,
+			Args:             getAsyncCommandArgs(cmd, args),
+		},
+	}
+	utils.CheckErr(submitter.Validate())
+	utils.CheckErr(utils.Confirm(globalOpts, fmt.Sprintf("This will run '%s %s' in Job %s without waiting for it, skipping its confirmation prompts.", consts.CmdLonghornctlRemote, strings.Join(submitter.Args, " "), async.GetRecordName(run.ID))))
+
+	logrus.Info("Initializing async submitter")
+	if err := submitter.Init(); err != nil {
+		utils.CheckErr(errors.Wrap(err, "Failed to initialize async submitter"))
+	}
+
+	logrus.WithField("run", run.ID).Info("Running async submitter")
+	asyncRun, err := submitter.Run()
+	if err != nil {
+		utils.CheckErr(errors.Wrapf(err, "Failed to submit run %s", run.ID))
+	}
+
+	fmt.Fprintln(os.Stdout, asyncRun.ID)
+	logrus.Infof("Submitted run %v to Job %v/%v, run '%s %s %v' to wait for it and '%s %s %v' to get its result", asyncRun.ID, asyncRun.Namespace, asyncRun.Job, consts.CmdLonghornctlRemote, consts.SubCmdWait, asyncRun.ID, consts.CmdLonghornctlRemote, consts.SubCmdResult, asyncRun.ID)
+	logrus.WithField("run", run.ID).Info("Completed async submitter")
+
+	// The command runs in the Job instead.
+	cmd.PreRun = nil
+	cmd.Run = func(cmd *cobra.Command, args []string) {}
+	cmd.PostRun = nil
+}
+
+// getAsyncCommandArgs returns the subcommand, the options set on the command line and the
+// arguments, to run the command in a Job. The global options are forwarded by the Job generator.
+func getAsyncCommandArgs(cmd *cobra.Command, args []string) []string {
+	asyncArgs := strings.Fields(cmd.CommandPath())[1:]
+
+	cmd.LocalNonPersistentFlags().VisitAll(func(flag *pflag.Flag) {
+		if !flag.Changed || flag.Name == consts.CmdOptNoWait {
+			return
+		}
+
+		if sliceValue, ok := flag.Value.(pflag.SliceValue); ok {
+			for _, value := range sliceValue.GetSlice() {
+				asyncArgs = append(asyncArgs, fmt.Sprintf("--%s=%s", flag.Name, value))
+			}
+			return
+		}
+		asyncArgs = append(asyncArgs, fmt.Sprintf("--%s=%s", flag.Name, flag.Value.String()))
+	})
+
+	return append(asyncArgs, args...)
+}
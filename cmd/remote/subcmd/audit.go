@@ -0,0 +1,222 @@
+package subcmd
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// auditRecordedFlagTypes are the types of the flags whose values cannot hold secrets, so they
+// are always recorded in the audit log.
+var auditRecordedFlagTypes = map[string]bool{
+	"bool":     true,
+	"count":    true,
+	"duration": true,
+	"float64":  true,
+	"int":      true,
+	"int32":    true,
+	"int64":    true,
+	"uint":     true,
+}
+
+// auditRecordedFlags are the other flags whose values are recorded in the audit log, holding
+// names, paths and formats. The flags referring to Secrets hold their names, never their data. The
+// values of the flags not listed are masked, so a flag holding a token, a password or a URL with
+// credentials is not recorded until it is reviewed and listed here.
+var auditRecordedFlags = map[string]bool{
+	consts.CmdOptAllowPci:                true,
+	consts.CmdOptBackend:                 true,
+	consts.CmdOptBackup:                  true,
+	consts.CmdOptBackupTarget:            true,
+	consts.CmdOptBackupVolume:            true,
+	consts.CmdOptComponent:               true,
+	consts.CmdOptCredentialSecret:        true, // Name of a Secret, not its data.
+	consts.CmdOptCustomChecks:            true,
+	consts.CmdOptCustomChecksConfigMap:   true,
+	consts.CmdOptDataPath:                true,
+	consts.CmdOptDeletionPolicy:          true,
+	consts.CmdOptDriverOverride:          true,
+	consts.CmdOptFioImage:                true,
+	consts.CmdOptFilename:                true,
+	consts.CmdOptFormat:                  true,
+	consts.CmdOptFrontend:                true,
+	consts.CmdOptHugePageNodes:           true,
+	consts.CmdOptImage:                   true,
+	consts.CmdOptImagesFile:              true,
+	consts.CmdOptInput:                   true,
+	consts.CmdOptIperfImage:              true,
+	consts.CmdOptKeyFile:                 true,
+	consts.CmdOptKinds:                   true,
+	consts.CmdOptKubeConfigPath:          true,
+	consts.CmdOptLabels:                  true,
+	consts.CmdOptLang:                    true,
+	consts.CmdOptListenAddress:           true,
+	consts.CmdOptLogFile:                 true,
+	consts.CmdOptLogFormat:               true,
+	consts.CmdOptLogLevel:                true,
+	consts.CmdOptLonghornDataDirectory:   true,
+	consts.CmdOptLonghornEngineImage:     true,
+	consts.CmdOptLonghornNamespace:       true,
+	consts.CmdOptLonghornVersion:         true,
+	consts.CmdOptLonghornVolumeName:      true,
+	consts.CmdOptManifestFile:            true,
+	consts.CmdOptName:                    true,
+	consts.CmdOptNamespace:               true,
+	consts.CmdOptNamespaceLabel:          true,
+	consts.CmdOptNetworks:                true,
+	consts.CmdOptNewSecret:               true, // Name of a Secret, not its data.
+	consts.CmdOptNoProxy:                 true,
+	consts.CmdOptNode:                    true,
+	consts.CmdOptNodeId:                  true,
+	consts.CmdOptNodeSelector:            true,
+	consts.CmdOptNodes:                   true,
+	consts.CmdOptOS:                      true,
+	consts.CmdOptOperatingSystem:         true,
+	consts.CmdOptOutput:                  true,
+	consts.CmdOptOutputFile:              true,
+	consts.CmdOptOutputTo:                true,
+	consts.CmdOptPackageSource:           true,
+	consts.CmdOptPlatform:                true,
+	consts.CmdOptPodCpu:                  true,
+	consts.CmdOptPodMemory:               true,
+	consts.CmdOptPriorityClass:           true,
+	consts.CmdOptProfile:                 true,
+	consts.CmdOptProfiles:                true,
+	consts.CmdOptRebootStrategy:          true,
+	consts.CmdOptRegistryCheckImagesFile: true,
+	consts.CmdOptRegistryCheckVersion:    true,
+	consts.CmdOptReplica:                 true,
+	consts.CmdOptRuntime:                 true,
+	consts.CmdOptSSHHosts:                true,
+	consts.CmdOptSSHLocalBinary:          true,
+	consts.CmdOptSeverityPolicy:          true,
+	consts.CmdOptShare:                   true,
+	consts.CmdOptShareAllowedCIDRs:       true,
+	consts.CmdOptShareImage:              true,
+	consts.CmdOptShareSecret:             true, // Name of a Secret, not its data.
+	consts.CmdOptSnapshot:                true,
+	consts.CmdOptSnapshotType:            true,
+	consts.CmdOptStorageClass:            true,
+	consts.CmdOptSummarizeBy:             true,
+	consts.CmdOptTargetDirectory:         true,
+	consts.CmdOptTargetKubeConfig:        true,
+	consts.CmdOptTargetPVC:               true,
+	consts.CmdOptTargetVolume:            true,
+	consts.CmdOptUserspaceDriver:         true,
+	consts.CmdOptVeleroNamespace:         true,
+	consts.CmdOptVersion:                 true,
+	consts.CmdOptVolume:                  true,
+	consts.CmdOptVolumeSnapshot:          true,
+	consts.CmdOptVolumeSnapshotClass:     true,
+	consts.CmdOptVolumes:                 true,
+	consts.CmdOptWorkload:                true,
+}
+
+var auditStartTime time.Time
+
+// StartAudit prepares recording the command in the audit log if the command is audited.
+// Failures are recorded when the command exits through utils.CheckErr.
+func StartAudit(cmd *cobra.Command, args []string, globalOpts *types.GlobalCmdOptions) {
+	if cmd.Annotations[consts.CmdAnnotationAudit] == "" {
+		return
+	}
+
+	auditStartTime = time.Now()
+	utils.RegisterErrorHandler(func(err error) {
+		recordAudit(cmd, args, globalOpts, err)
+	})
+}
+
+// CompleteAudit records the successful command in the audit log if the command is audited.
+func CompleteAudit(cmd *cobra.Command, args []string, globalOpts *types.GlobalCmdOptions) {
+	if cmd.Annotations[consts.CmdAnnotationAudit] == "" {
+		return
+	}
+
+	recordAudit(cmd, args, globalOpts, nil)
+}
+
+// recordAudit records the command as an Event in the namespace. Failing to record
+// does not fail the command.
+func recordAudit(cmd *cobra.Command, args []string, globalOpts *types.GlobalCmdOptions, cmdErr error) {
+	kubeClient, err := kubeutils.NewKubeClient("", globalOpts.KubeConfigPath)
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to record audit event")
+		return
+	}
+
+	namespace := globalOpts.Namespace
+	if namespace == "" {
+		namespace = consts.LonghornNamespace
+	}
+
+	kubeUser, err := kubeutils.GetCurrentUser(kubeClient)
+	if err != nil {
+		logrus.WithError(err).Debug("Failed to get current Kubernetes user")
+		kubeUser = "unknown"
+	}
+
+	var flags []string
+	cmd.Flags().Visit(func(flag *pflag.Flag) {
+		flags = append(flags, fmt.Sprintf("--%s=%s", flag.Name, getAuditFlagValue(flag)))
+	})
+
+	record := &types.AuditRecord{
+		User:      kubeUser,
+		HostUser:  getHostUser(),
+		Command:   cmd.CommandPath(),
+		Flags:     flags,
+		Args:      getAuditArgs(args, cmd.ArgsLenAtDash()),
+		StartTime: auditStartTime,
+		Error:     cmdErr,
+	}
+
+	if _, err := kubeutils.RecordAuditEvent(kubeClient, namespace, record); err != nil {
+		logrus.WithError(err).Warn("Failed to record audit event")
+	}
+}
+
+// getAuditFlagValue returns the value of the flag to record in the audit log, masked unless the
+// flag is known not to hold secrets.
+func getAuditFlagValue(flag *pflag.Flag) string {
+	if auditRecordedFlagTypes[flag.Value.Type()] || auditRecordedFlags[flag.Name] {
+		return flag.Value.String()
+	}
+	return "***"
+}
+
+// getAuditArgs returns the positional arguments to record in the audit log, with the "--"
+// separator restored before the arguments following it, such as the command of node exec.
+func getAuditArgs(args []string, argsLenAtDash int) []string {
+	if argsLenAtDash < 0 || argsLenAtDash > len(args) {
+		return args
+	}
+
+	auditArgs := append([]string{}, args[:argsLenAtDash]...)
+	auditArgs = append(auditArgs, "--")
+	return append(auditArgs, args[argsLenAtDash:]...)
+}
+
+func getHostUser() string {
+	username := "unknown"
+	if currentUser, err := user.Current(); err == nil {
+		username = currentUser.Username
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return username
+	}
+	return username + "@" + hostname
+}
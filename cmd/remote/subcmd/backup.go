@@ -0,0 +1,250 @@
+package subcmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/remote/backup"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+)
+
+func NewCmdBackup(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdBackup,
+		Short: "Longhorn backup operations",
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.AddCommand(newCmdBackupVerify(globalOpts))
+	cmd.AddCommand(newCmdBackupFsck(globalOpts))
+
+	return cmd
+}
+
+func newCmdBackupVerify(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var backupVerifier = backup.Verifier{}
+	var outputFormat string
+	var report *types.VerifyReport
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdVerify + " <backup-name>",
+		Short: "Verify a backup by restoring it to a temporary volume",
+		Long: `This command restores a backup to a temporary volume, and reports whether each step passed.
+
+Steps:
+  ` + backup.StepRestore + `   Restore the backup to a temporary volume with a single replica. Longhorn verifies each restored block against the checksum recorded in the backup, and fails the restore on a mismatch
+  ` + backup.StepChecksum + `  Compute the SHA256 checksum of the block device of the restored volume from a pod, and compare it with --` + consts.CmdOptSHA256 + ` when given
+
+The backup name is the name of the Backup in the Longhorn namespace, as listed by "kubectl -n longhorn-system get backups.longhorn.io". The temporary volume, its PV and PVC, and the pod are deleted afterwards, and the command exits with an error when a step failed.`,
+		Example: `$ longhornctl backup verify backup-5f6e9b1a2c3d4e5f --sha256=9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08
+INFO[2024-07-16T17:17:38+08:00] Initializing backup verifier
+INFO[2024-07-16T17:17:38+08:00] Cleaning up backup verifier
+INFO[2024-07-16T17:17:38+08:00] Running backup verifier
+INFO[2024-07-16T17:17:38+08:00] Running step restore
+INFO[2024-07-16T17:17:38+08:00] Restoring backup s3://backupbucket@us-east-1/?backup=backup-5f6e9b1a2c3d4e5f&volume=pvc-48a6457d-585e-423b-b530-bbc68a5f948a to volume longhorn-backup-verifier
+INFO[2024-07-16T17:18:51+08:00] Running step checksum
+STEP      STATUS  DURATION  MESSAGE
+restore   pass    1m13s     Restored backup backup-5f6e9b1a2c3d4e5f of volume pvc-48a6457d-585e-423b-b530-bbc68a5f948a to volume longhorn-backup-verifier
+checksum  pass    42s       Restored data matches SHA256 checksum 9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08
+
+PASSED
+INFO[2024-07-16T17:19:33+08:00] Cleaning up backup verifier
+INFO[2024-07-16T17:19:38+08:00] Completed backup verifier`,
+		Args: cobra.ExactArgs(1),
+
+		Annotations: map[string]string{consts.CmdAnnotationAudit: "true"},
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			backupVerifier.Image = globalOpts.Image
+			backupVerifier.KubeConfigPath = globalOpts.KubeConfigPath
+			backupVerifier.Namespace = globalOpts.Namespace
+			backupVerifier.NodeSelector = globalOpts.NodeSelector
+			backupVerifier.MinNodes = globalOpts.MinNodes
+			backupVerifier.PodCpu = globalOpts.PodCpu
+			backupVerifier.PodMemory = globalOpts.PodMemory
+			backupVerifier.PriorityClass = globalOpts.PriorityClass
+			backupVerifier.Proxy = globalOpts.Proxy
+			backupVerifier.NoProxy = globalOpts.NoProxy
+			backupVerifier.Privileged = globalOpts.Privileged
+			backupVerifier.LogLevel = globalOpts.LogLevel
+			backupVerifier.BackupName = args[0]
+
+			utils.CheckErr(utils.ValidateOutputFormat(outputFormat, consts.OutputFormatJSON, consts.OutputFormatYAML))
+			utils.CheckErr(backupVerifier.Validate())
+
+			logrus.Info("Initializing backup verifier")
+			if err := backupVerifier.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize backup verifier"))
+			}
+
+			logrus.Info("Cleaning up backup verifier")
+			if err := backupVerifier.Cleanup(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to cleanup backup verifier"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Running backup verifier")
+			var err error
+			report, err = backupVerifier.Run()
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to run backup verifier"))
+			}
+
+			utils.CheckErr(printVerifyReport(report, outputFormat))
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Cleaning up backup verifier")
+			if err := backupVerifier.Cleanup(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to cleanup backup verifier"))
+			}
+
+			logrus.Info("Completed backup verifier")
+
+			if report != nil && !report.Passed {
+				utils.CheckErr(errors.Errorf("Verification of backup %v failed", backupVerifier.BackupName))
+			}
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&outputFormat, consts.CmdOptOutput, "o", "", fmt.Sprintf("Output format of the report (%s, %s). Defaults to a table.", consts.OutputFormatJSON, consts.OutputFormatYAML))
+	cmd.Flags().StringVar(&backupVerifier.LonghornNamespace, consts.CmdOptLonghornNamespace, consts.LonghornNamespace, "Namespace where Longhorn is deployed.")
+	cmd.Flags().StringVar(&backupVerifier.SHA256, consts.CmdOptSHA256, "", "Expected SHA256 checksum of the block device of the restored volume, for example computed with sha256sum on the block device of the source volume when the backup was taken.")
+	cmd.Flags().DurationVar(&backupVerifier.Timeout, consts.CmdOptTimeout, 30*time.Minute, "Maximum time to wait for each step.")
+
+	return cmd
+}
+
+func newCmdBackupFsck(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var backupChecker = backup.Checker{}
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdFsck,
+		Short: "Check a backupstore for orphaned blocks, dangling backups and stale locks",
+		Long: `This command checks the consistency of the backed up volumes of a backupstore, and reports the space pruning them reclaims:
+- Orphaned blocks: blocks not referenced by any completed backup, left behind by interrupted backup deletions.
+- Missing blocks: blocks referenced by completed backups but not found. These backups cannot be restored.
+- Dangling backups: backup metadata that cannot be read, or left incomplete for over an hour by a crashed backup.
+- Stale locks: locks not refreshed by their holder for over an hour.
+
+The backupstore is an S3 URL, such as s3://backupbucket@us-east-1/path, or the path or file:// URL of a directory holding the backupstore directory, such as a mounted NFS or CIFS share. The credentials of an S3 backupstore are read from --` + consts.CmdOptCredentialSecret + ` in the Longhorn namespace, defaulting to the credential secret of the backup target with the same URL.
+
+With --` + consts.CmdOptPrune + `, the command deletes the orphaned blocks, dangling backups and stale locks after confirmation. The orphaned blocks of a volume with a backup in progress or a lock held are kept. Missing blocks cannot be repaired, delete the affected backups or take new ones.`,
+		Example: `$ longhornctl backup fsck --target=s3://backupbucket@us-east-1/
+INFO[2024-07-16T17:17:38+08:00] Initializing backupstore checker
+INFO[2024-07-16T17:17:38+08:00] Running backupstore checker
+VOLUME                                    BACKUPS  BLOCKS  ORPHANED BLOCKS  MISSING BLOCKS  DANGLING BACKUPS  STALE LOCKS  BUSY   RECLAIMABLE
+pvc-48a6457d-585e-423b-b530-bbc68a5f948a  4        1024    12               0               1                 1            false  24.0MiB
+pvc-9c2d4e1f-7a3b-4c5d-8e6f-0a1b2c3d4e5f  2        256     0                0               0                 0            false  0B
+
+VOLUME                                    ISSUE
+pvc-48a6457d-585e-423b-b530-bbc68a5f948a  Backup backup-2b8c9d0e1f2a3b4c was left incomplete since 2024-07-15T02:00:12Z
+
+Reclaimable: 24.0MiB
+INFO[2024-07-16T17:17:41+08:00] Completed backupstore checker`,
+		Args: cobra.NoArgs,
+
+		Annotations: map[string]string{consts.CmdAnnotationAudit: "true"},
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			backupChecker.KubeConfigPath = globalOpts.KubeConfigPath
+			backupChecker.Namespace = globalOpts.Namespace
+			backupChecker.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(utils.ValidateOutputFormat(outputFormat, consts.OutputFormatJSON, consts.OutputFormatYAML))
+			utils.CheckErr(backupChecker.Validate())
+
+			logrus.Info("Initializing backupstore checker")
+			if err := backupChecker.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize backupstore checker"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Running backupstore checker")
+			report, err := backupChecker.Run()
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to run backupstore checker"))
+			}
+
+			if backupChecker.Prune && report.ReclaimableBytes > 0 {
+				utils.CheckErr(printBackupStoreReport(report, ""))
+				utils.CheckErr(utils.Confirm(globalOpts, fmt.Sprintf("This will delete %s of orphaned blocks, dangling backups and stale locks from %s.", utils.FormatBytes(report.ReclaimableBytes), backupChecker.Target)))
+
+				logrus.Info("Pruning backupstore")
+				if err := backupChecker.Reclaim(report); err != nil {
+					utils.CheckErr(errors.Wrap(err, "Failed to prune backupstore"))
+				}
+				if outputFormat == "" {
+					logrus.Infof("Pruned %s from %s", utils.FormatBytes(report.ReclaimableBytes), backupChecker.Target)
+					return
+				}
+			}
+
+			utils.CheckErr(printBackupStoreReport(report, outputFormat))
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Completed backupstore checker")
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&outputFormat, consts.CmdOptOutput, "o", "", fmt.Sprintf("Output format of the report (%s, %s). Defaults to a table.", consts.OutputFormatJSON, consts.OutputFormatYAML))
+	cmd.Flags().StringVar(&backupChecker.LonghornNamespace, consts.CmdOptLonghornNamespace, consts.LonghornNamespace, "Namespace where Longhorn is deployed.")
+	cmd.Flags().StringVar(&backupChecker.Target, consts.CmdOptTarget, "", "URL of the backupstore, such as s3://backupbucket@us-east-1/ or the path of a mounted NFS or CIFS backupstore.")
+	cmd.Flags().StringVar(&backupChecker.CredentialSecret, consts.CmdOptCredentialSecret, "", "Secret in the Longhorn namespace with the credentials of the S3 backupstore. Defaults to the credential secret of the backup target with the same URL.")
+	cmd.Flags().BoolVar(&backupChecker.Prune, consts.CmdOptPrune, false, "Delete the orphaned blocks, dangling backups and stale locks after confirmation.")
+
+	return cmd
+}
+
+func printBackupStoreReport(report *types.BackupStoreReport, outputFormat string) error {
+	if printed, err := utils.PrintStructuredResult(outputFormat, types.ResultKindBackupStoreReport, report); printed || err != nil {
+		return err
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "VOLUME\tBACKUPS\tBLOCKS\tORPHANED BLOCKS\tMISSING BLOCKS\tDANGLING BACKUPS\tSTALE LOCKS\tBUSY\tRECLAIMABLE")
+	for _, volume := range report.Volumes {
+		fmt.Fprintf(writer, "%s\t%d\t%d\t%d\t%d\t%d\t%d\t%v\t%s\n", volume.Name, volume.Backups, volume.Blocks, volume.OrphanedBlocks, volume.MissingBlocks, len(volume.DanglingBackups), len(volume.StaleLocks), volume.Busy, utils.FormatBytes(volume.ReclaimableBytes))
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+
+	issues := []string{}
+	for _, volume := range report.Volumes {
+		for _, issue := range volume.Issues {
+			issues = append(issues, fmt.Sprintf("%s\t%s", volume.Name, issue))
+		}
+	}
+	if len(issues) > 0 {
+		fmt.Println()
+		writer = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, "VOLUME\tISSUE")
+		fmt.Fprintln(writer, strings.Join(issues, "\n"))
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+	}
+
+	fmt.Println()
+	fmt.Printf("Reclaimable: %s\n", utils.FormatBytes(report.ReclaimableBytes))
+	return nil
+}
@@ -0,0 +1,272 @@
+package subcmd
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/remote/baseline"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+)
+
+func NewCmdBaseline(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdBaseline,
+		Short: "Longhorn node prerequisites baseline operations",
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.AddCommand(newCmdBaselineCreate(globalOpts))
+	cmd.AddCommand(newCmdBaselineCheck(globalOpts))
+
+	return cmd
+}
+
+func newCmdBaselineCreate(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var baselineCreator = baseline.Creator{}
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdCreate,
+		Short: "Record the prerequisite state of the nodes in a signed baseline",
+		Long: `This command records the prerequisite state of each node in a baseline document, to later report the drift of the nodes from it with 'longhornctl baseline check':
+- operatingSystem and kernel: ID from /etc/os-release and kernel release.
+- packages: whether the packages Longhorn depends on are installed, for the package manager of the node.
+- modules: whether the kernel modules of the v1 and v2 data engines are loaded.
+- services: active and unit file states of iscsid and multipathd.
+- sysctls: values of the sysctl parameters of the tuning profile, and of fs.file-max and fs.inotify.max_user_instances.
+- multipathConfig: SHA-256 checksum of /etc/multipath.conf, or absent.
+
+The baseline is signed with HMAC-SHA256 and the key of --` + consts.CmdOptKeyFile + `, so any change to the document fails the check. Keep the key apart from the baseline, for example generated with: openssl rand -hex 32 > baseline.key
+
+The baseline is written as YAML to --` + consts.CmdOptOutputFile + `, or stdout. Use --node-selector to record a subset of the nodes.`,
+		Example: `$ longhornctl baseline create --key-file=baseline.key --output-file=baseline.yaml
+INFO[2024-07-16T17:40:12+08:00] Initializing baseline creator
+INFO[2024-07-16T17:40:12+08:00] Cleaning up baseline creator
+INFO[2024-07-16T17:40:12+08:00] Running baseline creator
+INFO[2024-07-16T17:40:31+08:00] Recorded the prerequisites of 3 nodes in baseline.yaml
+INFO[2024-07-16T17:40:31+08:00] Cleaning up baseline creator
+INFO[2024-07-16T17:40:31+08:00] Completed baseline creator`,
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			baselineCreator.Image = globalOpts.Image
+			baselineCreator.KubeConfigPath = globalOpts.KubeConfigPath
+			baselineCreator.Namespace = globalOpts.Namespace
+			baselineCreator.NodeSelector = globalOpts.NodeSelector
+			baselineCreator.MinNodes = globalOpts.MinNodes
+			baselineCreator.PodCpu = globalOpts.PodCpu
+			baselineCreator.PodMemory = globalOpts.PodMemory
+			baselineCreator.PriorityClass = globalOpts.PriorityClass
+			baselineCreator.Proxy = globalOpts.Proxy
+			baselineCreator.NoProxy = globalOpts.NoProxy
+			baselineCreator.Privileged = globalOpts.Privileged
+			baselineCreator.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(baselineCreator.Validate())
+
+			logrus.Info("Initializing baseline creator")
+			if err := baselineCreator.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize baseline creator"))
+			}
+
+			logrus.Info("Cleaning up baseline creator")
+			if err := baselineCreator.Cleanup(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to cleanup baseline creator"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Running baseline creator")
+			result, err := baselineCreator.Run()
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to run baseline creator"))
+			}
+
+			for node, prerequisites := range result.Nodes {
+				for _, message := range prerequisites.Errors {
+					logrus.WithField("node", node).Warn(message)
+				}
+			}
+
+			if baselineCreator.OutputFile != "" {
+				logrus.Infof("Recorded the prerequisites of %d nodes in %v", len(result.Nodes), baselineCreator.OutputFile)
+				return
+			}
+
+			data, err := baseline.EncodeBaseline(result)
+			utils.CheckErr(err)
+			fmt.Print(string(data))
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Cleaning up baseline creator")
+			if err := baselineCreator.Cleanup(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to cleanup baseline creator"))
+			}
+
+			logrus.Info("Completed baseline creator")
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVar(&baselineCreator.KeyFile, consts.CmdOptKeyFile, "", "File of the key signing the baseline, at least 16 bytes.")
+	cmd.Flags().StringVar(&baselineCreator.OutputFile, consts.CmdOptOutputFile, "", "Write the baseline to the file instead of stdout.")
+
+	return cmd
+}
+
+func newCmdBaselineCheck(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var baselineChecker = baseline.Checker{}
+	var outputFormat string
+	var result *types.BaselineDriftCollection
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdCheck,
+		Short: "Report the drift of the nodes from a signed baseline",
+		Long: `This command verifies the signature of a baseline recorded with 'longhornctl baseline create', collects the prerequisite state of the nodes again, and reports per node the prerequisites with a state different from the baseline, with the expected and actual states. The nodes missing from the baseline, and the nodes of the baseline no longer in the cluster or not matching --node-selector, drift as a whole.
+
+The command fails when the signature does not match the key of --` + consts.CmdOptKeyFile + `, or when a node drifts from the baseline, so it can gate compliance checks.`,
+		Example: `$ longhornctl baseline check --key-file=baseline.key -f baseline.yaml
+INFO[2024-07-16T18:02:40+08:00] Initializing baseline checker
+INFO[2024-07-16T18:02:40+08:00] Cleaning up baseline checker
+INFO[2024-07-16T18:02:40+08:00] Running baseline checker
+ip-10-0-2-123: no drift
+ip-10-0-2-142:
+  package open-iscsi: expected installed, actual not installed
+  service multipathd.service: expected inactive (disabled), actual active (enabled)
+ip-10-0-2-217:
+  sysctl vm.dirty_ratio: expected 10, actual 20
+INFO[2024-07-16T18:02:58+08:00] Cleaning up baseline checker
+ERRO[2024-07-16T18:02:58+08:00] 2 nodes drift from the baseline recorded at 2024-07-16T09:40:31Z
+
+$ longhornctl baseline check --key-file=baseline.key -f baseline.yaml -o json`,
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			baselineChecker.Image = globalOpts.Image
+			baselineChecker.KubeConfigPath = globalOpts.KubeConfigPath
+			baselineChecker.Namespace = globalOpts.Namespace
+			baselineChecker.NodeSelector = globalOpts.NodeSelector
+			baselineChecker.MinNodes = globalOpts.MinNodes
+			baselineChecker.PodCpu = globalOpts.PodCpu
+			baselineChecker.PodMemory = globalOpts.PodMemory
+			baselineChecker.PriorityClass = globalOpts.PriorityClass
+			baselineChecker.Proxy = globalOpts.Proxy
+			baselineChecker.NoProxy = globalOpts.NoProxy
+			baselineChecker.Privileged = globalOpts.Privileged
+			baselineChecker.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(utils.ValidateOutputFormat(outputFormat, consts.OutputFormatJSON, consts.OutputFormatYAML))
+			utils.CheckErr(baselineChecker.Validate())
+
+			logrus.Info("Initializing baseline checker")
+			if err := baselineChecker.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize baseline checker"))
+			}
+
+			logrus.Info("Cleaning up baseline checker")
+			if err := baselineChecker.Cleanup(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to cleanup baseline checker"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Running baseline checker")
+			var err error
+			result, err = baselineChecker.Run()
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to run baseline checker"))
+			}
+
+			for node, drift := range result.Nodes {
+				for _, message := range drift.Errors {
+					logrus.WithField("node", node).Warn(message)
+				}
+			}
+
+			utils.CheckErr(printBaselineDrift(result, outputFormat))
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Cleaning up baseline checker")
+			if err := baselineChecker.Cleanup(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to cleanup baseline checker"))
+			}
+
+			if result != nil && baseline.HasDrift(result) {
+				driftingNodes := 0
+				for _, drift := range result.Nodes {
+					if len(drift.Drifts) > 0 {
+						driftingNodes++
+					}
+				}
+				utils.CheckErr(errors.Errorf("%d nodes drift from the baseline recorded at %v", driftingNodes, result.CreatedAt))
+			}
+
+			logrus.Info("Completed baseline checker")
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&outputFormat, consts.CmdOptOutput, "o", "", fmt.Sprintf("Output format of the result (%s, %s).", consts.OutputFormatJSON, consts.OutputFormatYAML))
+	cmd.Flags().StringVarP(&baselineChecker.BaselineFile, consts.CmdOptFilename, "f", "", "Baseline recorded with 'longhornctl baseline create'.")
+	cmd.Flags().StringVar(&baselineChecker.KeyFile, consts.CmdOptKeyFile, "", "File of the key the baseline is signed with.")
+
+	return cmd
+}
+
+// printBaselineDrift prints the drift of each node, sorted by the node name, unless a structured
+// output format is requested.
+func printBaselineDrift(result *types.BaselineDriftCollection, outputFormat string) error {
+	if printed, err := utils.PrintStructuredResult(outputFormat, types.ResultKindBaselineDriftCollection, result); printed || err != nil {
+		return err
+	}
+
+	nodes := make([]string, 0, len(result.Nodes))
+	for node := range result.Nodes {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+
+	for _, node := range nodes {
+		drifts := result.Nodes[node].Drifts
+		if len(drifts) == 0 {
+			fmt.Printf("%s: no drift\n", node)
+			continue
+		}
+
+		fmt.Printf("%s:\n", node)
+		for _, drift := range drifts {
+			fmt.Printf("  %s\n", formatBaselineDrift(drift))
+		}
+	}
+	return nil
+}
+
+func formatBaselineDrift(drift types.BaselineDrift) string {
+	switch {
+	case drift.Category == types.BaselineDriftCategoryNode && drift.Actual == "":
+		return "node: missing from the cluster"
+	case drift.Category == types.BaselineDriftCategoryNode:
+		return "node: missing from the baseline"
+	}
+
+	subject := string(drift.Category)
+	if drift.Name != "" {
+		subject += " " + drift.Name
+	}
+	return fmt.Sprintf("%s: expected %s, actual %s", subject, orAbsent(drift.Expected), orAbsent(drift.Actual))
+}
+
+func orAbsent(state string) string {
+	if state == "" {
+		return "<none>"
+	}
+	return state
+}
@@ -0,0 +1,468 @@
+package subcmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/remote/benchmark"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+)
+
+func NewCmdBenchmark(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdBenchmark,
+		Short: "Longhorn benchmarking operations",
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.AddCommand(newCmdBenchmarkDisk(globalOpts))
+	cmd.AddCommand(newCmdBenchmarkNetwork(globalOpts))
+	cmd.AddCommand(newCmdBenchmarkVolume(globalOpts))
+
+	return cmd
+}
+
+func newCmdBenchmarkDisk(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var diskBenchmark = benchmark.DiskBenchmark{}
+	var outputFormat string
+	var report *types.DiskBenchmarkReport
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdDisk,
+		Short: "Benchmark the latency of the data path on each node",
+		Long: `This command runs a short fio test of 4KiB random reads and writes, one at a time, directly on the data path of each node, and reports the mean latency of the reads and writes.
+
+A node fails when its read or write latency exceeds the threshold (--` + consts.CmdOptMaxReadLatency + `, --` + consts.CmdOptMaxWriteLatency + `). Longhorn recommends SSD or NVMe disks, and the default thresholds flag spinning or overloaded disks.
+
+The fio file and the DaemonSet are deleted afterwards, and the command exits with an error when a node failed.`,
+		Example: `$ longhornctl benchmark disk --data-path=/var/lib/longhorn
+INFO[2024-07-16T17:17:38+08:00] Initializing disk benchmark
+INFO[2024-07-16T17:17:38+08:00] Cleaning up disk benchmark
+INFO[2024-07-16T17:17:38+08:00] Running disk benchmark
+NODE           STATUS  READ LATENCY (usec)  WRITE LATENCY (usec)  MESSAGE
+ip-10-0-2-123  pass    132.4                48.9
+ip-10-0-2-124  pass    129.8                51.2
+ip-10-0-2-125  fail    8412.7               9035.1                read latency 8413us exceeds 1000us; write latency 9035us exceeds 2000us
+
+FAILED
+INFO[2024-07-16T17:18:05+08:00] Cleaning up disk benchmark
+INFO[2024-07-16T17:18:08+08:00] Completed disk benchmark`,
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			diskBenchmark.Image = globalOpts.Image
+			diskBenchmark.KubeConfigPath = globalOpts.KubeConfigPath
+			diskBenchmark.Namespace = globalOpts.Namespace
+			diskBenchmark.NodeSelector = globalOpts.NodeSelector
+			diskBenchmark.MinNodes = globalOpts.MinNodes
+			diskBenchmark.PodCpu = globalOpts.PodCpu
+			diskBenchmark.PodMemory = globalOpts.PodMemory
+			diskBenchmark.PriorityClass = globalOpts.PriorityClass
+			diskBenchmark.Proxy = globalOpts.Proxy
+			diskBenchmark.NoProxy = globalOpts.NoProxy
+			diskBenchmark.Privileged = globalOpts.Privileged
+			diskBenchmark.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(utils.ValidateOutputFormat(outputFormat, consts.OutputFormatJSON, consts.OutputFormatYAML))
+			utils.CheckErr(diskBenchmark.Validate())
+
+			logrus.Info("Initializing disk benchmark")
+			if err := diskBenchmark.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize disk benchmark"))
+			}
+
+			logrus.Info("Cleaning up disk benchmark")
+			if err := diskBenchmark.Cleanup(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to cleanup disk benchmark"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Running disk benchmark")
+			var err error
+			report, err = diskBenchmark.Run()
+			if err != nil {
+				logrus.Info("Cleaning up disk benchmark")
+				if _err := diskBenchmark.Cleanup(); _err != nil {
+					logrus.WithError(_err).Warn("Failed to cleanup disk benchmark")
+				}
+				utils.CheckErr(errors.Wrap(err, "Failed to run disk benchmark"))
+			}
+
+			utils.CheckErr(printDiskBenchmarkReport(report, outputFormat))
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Cleaning up disk benchmark")
+			if err := diskBenchmark.Cleanup(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to cleanup disk benchmark"))
+			}
+
+			logrus.Info("Completed disk benchmark")
+
+			if report != nil && !report.Passed {
+				utils.CheckErr(errors.New("disk latency exceeds the thresholds on some nodes"))
+			}
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&outputFormat, consts.CmdOptOutput, "o", "", fmt.Sprintf("Output format of the report (%s, %s). Defaults to a table.", consts.OutputFormatJSON, consts.OutputFormatYAML))
+	cmd.Flags().StringVar(&diskBenchmark.DataPath, consts.CmdOptDataPath, "/var/lib/longhorn", "Candidate data path on the nodes, where fio runs.")
+	cmd.Flags().DurationVar(&diskBenchmark.Runtime, consts.CmdOptRuntime, 10*time.Second, "Duration of the fio run.")
+	cmd.Flags().StringVar(&diskBenchmark.FioImage, consts.CmdOptFioImage, consts.ImageFio, "Image containing fio.")
+	cmd.Flags().DurationVar(&diskBenchmark.MaxReadLatency, consts.CmdOptMaxReadLatency, consts.BenchmarkDiskMaxReadLatencyUsec*time.Microsecond, "Mean read latency above which a node fails.")
+	cmd.Flags().DurationVar(&diskBenchmark.MaxWriteLatency, consts.CmdOptMaxWriteLatency, consts.BenchmarkDiskMaxWriteLatencyUsec*time.Microsecond, "Mean write latency above which a node fails.")
+
+	return cmd
+}
+
+func newCmdBenchmarkNetwork(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var networkBenchmark = benchmark.NetworkBenchmark{}
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdNetwork,
+		Short: "Benchmark the network between the nodes",
+		Long: `This command starts an iperf3 server on each node, then runs an iperf3 client from each node to each other node, and reports the TCP throughput and the mean round-trip latency between each pair of nodes.
+
+Networks:
+  host  Pods in the host network of the nodes
+  pod   Pods in the pod network of the cluster
+
+The pairs run one at a time so they do not compete for the bandwidth, which takes about (nodes * (nodes - 1) * networks * runtime). The benchmark pods are deleted afterwards.`,
+		Example: `$ longhornctl benchmark network --nodes=ip-10-0-2-123,ip-10-0-2-124,ip-10-0-2-125 --networks=host
+INFO[2024-07-16T17:17:38+08:00] Initializing network benchmark
+INFO[2024-07-16T17:17:38+08:00] Cleaning up network benchmark
+INFO[2024-07-16T17:17:38+08:00] Running network benchmark
+INFO[2024-07-16T17:17:45+08:00] Running iperf3 on the host network from node ip-10-0-2-123 to node ip-10-0-2-124
+...
+HOST NETWORK THROUGHPUT (Gbit/s)
+SOURCE \ DESTINATION  ip-10-0-2-123  ip-10-0-2-124  ip-10-0-2-125
+ip-10-0-2-123         -              9.41           9.38
+ip-10-0-2-124         9.40           -              9.37
+ip-10-0-2-125         9.39           9.36           -
+
+HOST NETWORK LATENCY (usec)
+SOURCE \ DESTINATION  ip-10-0-2-123  ip-10-0-2-124  ip-10-0-2-125
+ip-10-0-2-123         -              245            251
+ip-10-0-2-124         238            -              249
+ip-10-0-2-125         242            247            -
+INFO[2024-07-16T17:19:02+08:00] Cleaning up network benchmark
+INFO[2024-07-16T17:19:05+08:00] Completed network benchmark`,
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			networkBenchmark.Image = globalOpts.Image
+			networkBenchmark.KubeConfigPath = globalOpts.KubeConfigPath
+			networkBenchmark.Namespace = globalOpts.Namespace
+			networkBenchmark.NodeSelector = globalOpts.NodeSelector
+			networkBenchmark.MinNodes = globalOpts.MinNodes
+			networkBenchmark.PodCpu = globalOpts.PodCpu
+			networkBenchmark.PodMemory = globalOpts.PodMemory
+			networkBenchmark.PriorityClass = globalOpts.PriorityClass
+			networkBenchmark.Proxy = globalOpts.Proxy
+			networkBenchmark.NoProxy = globalOpts.NoProxy
+			networkBenchmark.Privileged = globalOpts.Privileged
+			networkBenchmark.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(utils.ValidateOutputFormat(outputFormat, consts.OutputFormatJSON, consts.OutputFormatYAML))
+			utils.CheckErr(networkBenchmark.Validate())
+
+			logrus.Info("Initializing network benchmark")
+			if err := networkBenchmark.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize network benchmark"))
+			}
+
+			logrus.Info("Cleaning up network benchmark")
+			if err := networkBenchmark.Cleanup(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to cleanup network benchmark"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Running network benchmark")
+			report, err := networkBenchmark.Run()
+			if err != nil {
+				logrus.Info("Cleaning up network benchmark")
+				if _err := networkBenchmark.Cleanup(); _err != nil {
+					logrus.WithError(_err).Warn("Failed to cleanup network benchmark")
+				}
+				utils.CheckErr(errors.Wrap(err, "Failed to run network benchmark"))
+			}
+
+			utils.CheckErr(printNetworkBenchmarkReport(report, outputFormat))
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Cleaning up network benchmark")
+			if err := networkBenchmark.Cleanup(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to cleanup network benchmark"))
+			}
+
+			logrus.Info("Completed network benchmark")
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&outputFormat, consts.CmdOptOutput, "o", "", fmt.Sprintf("Output format of the report (%s, %s). Defaults to tables.", consts.OutputFormatJSON, consts.OutputFormatYAML))
+	cmd.Flags().StringVar(&networkBenchmark.Nodes, consts.CmdOptNodes, "", fmt.Sprintf("Specify a comma-separated (%s) list of nodes to benchmark. Defaults to the nodes matching --%s.", consts.CmdOptSeperator, consts.CmdOptNodeSelector))
+	cmd.Flags().StringVar(&networkBenchmark.Networks, consts.CmdOptNetworks, consts.BenchmarkNetworkHost+consts.CmdOptSeperator+consts.BenchmarkNetworkPod, fmt.Sprintf("Specify a comma-separated (%s) list of networks to benchmark (%s, %s).", consts.CmdOptSeperator, consts.BenchmarkNetworkHost, consts.BenchmarkNetworkPod))
+	cmd.Flags().IntVar(&networkBenchmark.Port, consts.CmdOptPort, 5201, "Port of the iperf3 servers. It must be free on the nodes for the host network.")
+	cmd.Flags().DurationVar(&networkBenchmark.Runtime, consts.CmdOptRuntime, 10*time.Second, "Duration of each iperf3 run.")
+	cmd.Flags().StringVar(&networkBenchmark.IperfImage, consts.CmdOptIperfImage, consts.ImageIperf, "Image containing iperf3.")
+	cmd.Flags().DurationVar(&networkBenchmark.Timeout, consts.CmdOptTimeout, 5*time.Minute, "Maximum time to wait for the iperf3 servers and each iperf3 run, including the image pull.")
+
+	return cmd
+}
+
+func newCmdBenchmarkVolume(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var volumeBenchmark = benchmark.VolumeBenchmark{}
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdVolume,
+		Short: "Benchmark a Longhorn volume against the local disk of a node",
+		Long: `This command runs fio on the local disk of a node, in the Longhorn data directory, then on a Longhorn volume attached to the same node, and reports the IOPS, bandwidth and latency of both runs with the overhead of the volume.
+
+Profiles:
+  latency    4KiB random reads and writes, one at a time
+  randrw     4KiB random reads and writes (70% reads)
+  randread   4KiB random reads
+  randwrite  4KiB random writes
+  seqread    1MiB sequential reads
+  seqwrite   1MiB sequential writes
+
+The benchmark PVC and pods are deleted afterwards.`,
+		Example: `$ longhornctl benchmark volume --size=10Gi --profile=randrw --node-id=ip-10-0-2-123
+INFO[2024-07-16T17:17:38+08:00] Initializing volume benchmark
+INFO[2024-07-16T17:17:38+08:00] Cleaning up volume benchmark
+INFO[2024-07-16T17:17:38+08:00] Running volume benchmark
+INFO[2024-07-16T17:17:38+08:00] Running fio on the local disk of node ip-10-0-2-123
+INFO[2024-07-16T17:18:45+08:00] Running fio on a Longhorn volume on node ip-10-0-2-123
+METRIC                LOCAL DISK  LONGHORN   OVERHEAD
+Read IOPS             41203       9876       -76.0%
+Write IOPS            17658       4231       -76.0%
+Read bandwidth        160.9MiB/s  38.6MiB/s  -76.0%
+Write bandwidth       69.0MiB/s   16.5MiB/s  -76.1%
+Read latency (usec)   1087.2      4532.8     +316.9%
+Write latency (usec)  1095.4      4601.3     +320.1%
+INFO[2024-07-16T17:20:02+08:00] Cleaning up volume benchmark
+INFO[2024-07-16T17:20:05+08:00] Completed volume benchmark`,
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			volumeBenchmark.Image = globalOpts.Image
+			volumeBenchmark.KubeConfigPath = globalOpts.KubeConfigPath
+			volumeBenchmark.Namespace = globalOpts.Namespace
+			volumeBenchmark.NodeSelector = globalOpts.NodeSelector
+			volumeBenchmark.MinNodes = globalOpts.MinNodes
+			volumeBenchmark.PodCpu = globalOpts.PodCpu
+			volumeBenchmark.PodMemory = globalOpts.PodMemory
+			volumeBenchmark.PriorityClass = globalOpts.PriorityClass
+			volumeBenchmark.Proxy = globalOpts.Proxy
+			volumeBenchmark.NoProxy = globalOpts.NoProxy
+			volumeBenchmark.Privileged = globalOpts.Privileged
+			volumeBenchmark.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(utils.ValidateOutputFormat(outputFormat, consts.OutputFormatJSON, consts.OutputFormatYAML))
+			utils.CheckErr(volumeBenchmark.Validate())
+
+			logrus.Info("Initializing volume benchmark")
+			if err := volumeBenchmark.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize volume benchmark"))
+			}
+
+			logrus.Info("Cleaning up volume benchmark")
+			if err := volumeBenchmark.Cleanup(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to cleanup volume benchmark"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Running volume benchmark")
+			report, err := volumeBenchmark.Run()
+			if err != nil {
+				logrus.Info("Cleaning up volume benchmark")
+				if _err := volumeBenchmark.Cleanup(); _err != nil {
+					logrus.WithError(_err).Warn("Failed to cleanup volume benchmark")
+				}
+				utils.CheckErr(errors.Wrap(err, "Failed to run volume benchmark"))
+			}
+
+			utils.CheckErr(printVolumeBenchmarkReport(report, outputFormat))
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Cleaning up volume benchmark")
+			if err := volumeBenchmark.Cleanup(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to cleanup volume benchmark"))
+			}
+
+			logrus.Info("Completed volume benchmark")
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&outputFormat, consts.CmdOptOutput, "o", "", fmt.Sprintf("Output format of the report (%s, %s). Defaults to a table.", consts.OutputFormatJSON, consts.OutputFormatYAML))
+	cmd.Flags().StringVar(&volumeBenchmark.NodeName, consts.CmdOptNodeId, "", "Node to run the benchmark on. Defaults to the first node matching --"+consts.CmdOptNodeSelector+".")
+	cmd.Flags().StringVar(&volumeBenchmark.StorageClass, consts.CmdOptStorageClass, consts.LonghornStorageClass, "StorageClass of the benchmark PVC.")
+	cmd.Flags().StringVar(&volumeBenchmark.Size, consts.CmdOptSize, "10Gi", "Size of the benchmark PVC. fio uses 80% of it, on both the volume and the local disk.")
+	cmd.Flags().StringVar(&volumeBenchmark.Profile, consts.CmdOptProfile, consts.BenchmarkProfileRandRW, "fio profile (latency, randrw, randread, randwrite, seqread, seqwrite).")
+	cmd.Flags().DurationVar(&volumeBenchmark.Runtime, consts.CmdOptRuntime, time.Minute, "Duration of each fio run.")
+	cmd.Flags().StringVar(&volumeBenchmark.FioImage, consts.CmdOptFioImage, consts.ImageFio, "Image containing fio.")
+	cmd.Flags().StringVar(&volumeBenchmark.LonghornDataDirectory, consts.CmdOptLonghornDataDirectory, "/var/lib/longhorn", "Longhorn data directory on the node, where the baseline runs.")
+	cmd.Flags().DurationVar(&volumeBenchmark.Timeout, consts.CmdOptTimeout, 15*time.Minute, "Maximum time to wait for each fio run, including the image pull and the volume attachment.")
+
+	return cmd
+}
+
+func printVolumeBenchmarkReport(report *types.VolumeBenchmarkReport, outputFormat string) error {
+	if printed, err := utils.PrintStructuredResult(outputFormat, types.ResultKindVolumeBenchmarkReport, report); printed || err != nil {
+		return err
+	}
+
+	bandwidth := func(kibps float64) string {
+		return fmt.Sprintf("%.1fMiB/s", kibps/1024)
+	}
+	iops := func(value float64) string {
+		return fmt.Sprintf("%.0f", value)
+	}
+	latency := func(usec float64) string {
+		return fmt.Sprintf("%.1f", usec)
+	}
+	overhead := func(baseline, longhorn float64) string {
+		if baseline == 0 {
+			return "-"
+		}
+		return fmt.Sprintf("%+.1f%%", (longhorn-baseline)/baseline*100)
+	}
+
+	rows := []struct {
+		metric             string
+		baseline, longhorn float64
+		format             func(float64) string
+	}{
+		{"Read IOPS", report.Baseline.ReadIOPS, report.Longhorn.ReadIOPS, iops},
+		{"Write IOPS", report.Baseline.WriteIOPS, report.Longhorn.WriteIOPS, iops},
+		{"Read bandwidth", report.Baseline.ReadBandwidth, report.Longhorn.ReadBandwidth, bandwidth},
+		{"Write bandwidth", report.Baseline.WriteBandwidth, report.Longhorn.WriteBandwidth, bandwidth},
+		{"Read latency (usec)", report.Baseline.ReadLatencyUsec, report.Longhorn.ReadLatencyUsec, latency},
+		{"Write latency (usec)", report.Baseline.WriteLatencyUsec, report.Longhorn.WriteLatencyUsec, latency},
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "METRIC\tLOCAL DISK\tLONGHORN\tOVERHEAD")
+	for _, row := range rows {
+		if row.baseline == 0 && row.longhorn == 0 {
+			continue // The profile does not read or write.
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", row.metric, row.format(row.baseline), row.format(row.longhorn), overhead(row.baseline, row.longhorn))
+	}
+	return writer.Flush()
+}
+
+func printNetworkBenchmarkReport(report *types.NetworkBenchmarkReport, outputFormat string) error {
+	if printed, err := utils.PrintStructuredResult(outputFormat, types.ResultKindNetworkBenchmarkReport, report); printed || err != nil {
+		return err
+	}
+
+	results := map[string]types.NetworkBenchmarkResult{}
+	for _, result := range report.Results {
+		results[result.Network+"/"+result.Source+"/"+result.Destination] = result
+	}
+
+	matrices := []struct {
+		title  string
+		format func(types.NetworkBenchmarkResult) string
+	}{
+		{"THROUGHPUT (Gbit/s)", func(result types.NetworkBenchmarkResult) string {
+			return fmt.Sprintf("%.2f", result.Throughput/1e9)
+		}},
+		{"LATENCY (usec)", func(result types.NetworkBenchmarkResult) string {
+			return fmt.Sprintf("%.0f", result.LatencyUsec)
+		}},
+	}
+
+	var failures []types.NetworkBenchmarkResult
+	for i, network := range report.Networks {
+		for j, matrix := range matrices {
+			if i > 0 || j > 0 {
+				fmt.Println()
+			}
+			fmt.Printf("%s NETWORK %s\n", strings.ToUpper(network), matrix.title)
+
+			writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintf(writer, "SOURCE \\ DESTINATION\t%s\n", strings.Join(report.Nodes, "\t"))
+			for _, source := range report.Nodes {
+				cells := []string{source}
+				for _, destination := range report.Nodes {
+					result, ok := results[network+"/"+source+"/"+destination]
+					switch {
+					case !ok:
+						cells = append(cells, "-")
+					case result.Error != "":
+						cells = append(cells, "error")
+					default:
+						cells = append(cells, matrix.format(result))
+					}
+				}
+				fmt.Fprintln(writer, strings.Join(cells, "\t"))
+			}
+			if err := writer.Flush(); err != nil {
+				return err
+			}
+		}
+
+		for _, result := range report.Results {
+			if result.Network == network && result.Error != "" {
+				failures = append(failures, result)
+			}
+		}
+	}
+
+	if len(failures) > 0 {
+		fmt.Println("\nERRORS")
+		for _, result := range failures {
+			fmt.Printf("%s network from %s to %s: %s\n", result.Network, result.Source, result.Destination, result.Error)
+		}
+	}
+	return nil
+}
+
+func printDiskBenchmarkReport(report *types.DiskBenchmarkReport, outputFormat string) error {
+	if printed, err := utils.PrintStructuredResult(outputFormat, types.ResultKindDiskBenchmarkReport, report); printed || err != nil {
+		return err
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "NODE\tSTATUS\tREAD LATENCY (usec)\tWRITE LATENCY (usec)\tMESSAGE")
+	for _, node := range report.Nodes {
+		readLatency, writeLatency := "-", "-"
+		if node.Result != nil {
+			readLatency = fmt.Sprintf("%.1f", node.Result.ReadLatencyUsec)
+			writeLatency = fmt.Sprintf("%.1f", node.Result.WriteLatencyUsec)
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\n", node.Node, node.Status, readLatency, writeLatency, strings.ReplaceAll(node.Message, "\n", " "))
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+
+	if report.Passed {
+		fmt.Println("\nPASSED")
+	} else {
+		fmt.Println("\nFAILED")
+	}
+	return nil
+}
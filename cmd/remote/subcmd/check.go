@@ -1,12 +1,23 @@
 package subcmd
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
 	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/remote/autoscaler"
+	"github.com/longhorn/cli/pkg/remote/crd"
+	"github.com/longhorn/cli/pkg/remote/device"
 	"github.com/longhorn/cli/pkg/remote/preflight"
+	"github.com/longhorn/cli/pkg/remote/release"
+	"github.com/longhorn/cli/pkg/remote/storageclass"
+	"github.com/longhorn/cli/pkg/remote/velero"
+	"github.com/longhorn/cli/pkg/remote/volume"
+	"github.com/longhorn/cli/pkg/remote/webhook"
 	"github.com/longhorn/cli/pkg/types"
 	"github.com/longhorn/cli/pkg/utils"
 )
@@ -20,17 +31,85 @@ func NewCmdCheck(globalOpts *types.GlobalCmdOptions) *cobra.Command {
 	utils.SetGlobalOptionsRemote(cmd, globalOpts)
 
 	cmd.AddCommand(newCmdCheckPreflight(globalOpts))
+	cmd.AddCommand(newCmdCheckPciBindings(globalOpts))
+	cmd.AddCommand(newCmdCheckWebhooks(globalOpts))
+	cmd.AddCommand(newCmdCheckCrds(globalOpts))
+	cmd.AddCommand(newCmdCheckMounts(globalOpts))
+	cmd.AddCommand(newCmdCheckRwx(globalOpts))
+	cmd.AddCommand(newCmdCheckTuning(globalOpts))
+	cmd.AddCommand(newCmdCheckVelero(globalOpts))
+	cmd.AddCommand(newCmdCheckVersion(globalOpts))
+	cmd.AddCommand(newCmdCheckAutoscaler(globalOpts))
+	cmd.AddCommand(newCmdCheckStorageClasses(globalOpts))
 
 	return cmd
 }
 
 func newCmdCheckPreflight(globalOpts *types.GlobalCmdOptions) *cobra.Command {
 	var preflightChecker = preflight.Checker{}
+	var outputFormat string
+	var hasErrors bool
 
 	cmd := &cobra.Command{
 		Use:   consts.SubCmdPreflight,
 		Short: "Run a preflight check for Longhorn",
-		Long:  `This command verifies your Kubernetes cluster environment to ensure it meets Longhorn's requirements. It performs a series of checks that can help identify potential issues that may prevent Longhorn from functioning correctly.`,
+		Long: `This command verifies your Kubernetes cluster environment to ensure it meets Longhorn's requirements. It performs a series of checks that can help identify potential issues that may prevent Longhorn from functioning correctly.
+
+The mount propagation check ensures the kubelet root directory (the --root-dir of the kubelet process, or /var/lib/kubelet), and the pods and CSI plugin directories under it, are on shared mounts. Otherwise the volumes the Longhorn CSI plugin mounts do not reach the workload pods, and mounts fail long after the installation.
+
+The iscsid configuration check compares /etc/iscsi/iscsid.conf with the values Longhorn recommends (node.startup, node.session.timeo.replacement_timeout, node.session.queue_depth and node.session.cmds_max), and reports CHAP parameters left from other iSCSI setups. "longhornctl install preflight --tune-iscsid" applies the recommendations.
+
+With --profile, the checks specific to a managed platform or Kubernetes distribution run, and the checks that do not apply to it are skipped and listed in the result:
+- gke: the Longhorn data path is not on a noexec mount, as on Container-Optimized OS.
+- eks: the kernel of the AMI ships the iscsi_tcp, dm_crypt and nfs modules.
+- aks: the Longhorn data path is on neither the ephemeral OS disk nor the temporary disk.
+- rke2, k3s: the kubelet root directory is read from the --kubelet-arg of the server or agent, or from /etc/rancher/<distribution>/config.yaml.
+
+The NFS checks report the NFS 4 versions the kernel supports, with 4.1 required by the RWX volumes, the nfs-utils version of mount.nfs (1.3.0 or later), and the rpcbind and rpc-statd services left in a failed state, since the RWX volumes and NFS backup targets otherwise fail when mounting.
+
+The encryption check verifies cryptsetup is 2.0.0 or later, to format the encrypted volumes with LUKS2 and argon2i, and the kernel provides the aes and xts crypto algorithms of the default cipher aes-xts-plain64, warning when AES is not hardware accelerated. With --crypto-benchmark, each node runs "cryptsetup benchmark" with the default cipher, and the nodes encrypting or decrypting under 500 MiB/s are reported.
+
+The known issues check matches the kernel release, operating system, loaded kernel modules with their versions, and NVMe controller models with their firmware revisions of each node against a database of known bad kernel, driver and firmware combinations affecting Longhorn, and reports the matching issues at their severity. The database is embedded in longhornctl; with --rules-url, an up-to-date one is downloaded instead, in the format:
+  issues:
+  - id: samsung-980-pro-read-only
+    description: Samsung 980 PRO drives with firmware 3B2QGXA7 may turn read-only.
+    link: <URL of the details>  # optional
+    severity: error  # error, warn (default) or info
+    match:  # regular expressions, all the ones set must match
+      nvmeModel: Samsung SSD 980 PRO
+      nvmeFirmware: ^3B2QGXA7$
+
+The conflicting storage agents check warns about the storage and data plane agents found on the nodes that conflict with Longhorn, with the names of their processes and kernel modules: OpenEBS cStor iSCSI targets (istgt), SCSI target daemons (tgtd), the OpenEBS Mayastor data plane (io-engine), Rook/Ceph OSDs (ceph-osd), Ceph RBD kernel clients (rbd, rbd-nbd), ZFS (zfs), and device-mapper multipath maps in use (dm_multipath).
+
+The architecture check verifies the CPU architecture of each node (amd64, arm64 or s390x) is supported by the Longhorn version given by --longhorn-version, and that the --image manifest list provides a matching linux platform. A single-architecture custom image fails the check before the DaemonSet is rolled out.
+
+Additional checks can be added in two ways:
+- Custom checks defined in a YAML file (--custom-checks) or ConfigMap (--custom-checks-configmap). Each check runs a shell command on the node:
+    checks:
+    - name: ntp-synchronized
+      command: timedatectl show -p NTPSynchronized --value
+      expectedOutput: "^yes"
+      severity: warn
+- Executables named ` + consts.PreflightCheckPluginPrefix + `* on the PATH of the image (--image). Each plugin prints either a JSON object with "error", "warn" and "info" lists, or plain text reported by its exit code.
+
+With --registry-check-version or --registry-check-images-file, each node fetches the manifests of the images through the registry mirrors configured for containerd (/etc/containerd/certs.d, and the K3s and RKE2 registries.yaml) from the network of the node, to find the nodes that cannot reach the registries before installing or upgrading.
+
+With --summarize-by, the table of the result groups the nodes by zone (the topology.kubernetes.io/zone label), nodepool (the node pool label of GKE, EKS, eksctl, Karpenter, AKS or DigitalOcean) or os (the OS image the kubelet reports), with the number of nodes passing, with warnings, with errors and skipped in each group, and only lists the errors and warnings of the nodes with errors. The json, junit and yaml output formats keep the result of each node.
+
+With --severity-policy, the messages matching the regular expression of a rule are reported at its level instead, in both the result and the exit code. The first matching rule applies, and the command exits with an error when a node reports errors with the policy applied:
+    rules:
+    - match: ^nfs-utils .* is older than
+      severity: warn
+    - match: ^multipathd.service is running
+      severity: error  # error, warn or info
+
+With --backend=ssh, the check runs ` + consts.CmdLonghornctlLocal + ` over SSH on the hosts listed in --ssh-hosts instead of in a DaemonSet, for hosts without a Kubernetes cluster yet:
+    hosts:
+    - name: node-1
+      address: 10.0.2.123
+      user: ubuntu
+      identityFile: ~/.ssh/id_ed25519
+      sudo: true`,
 		Example: `$ longhornctl check preflight
 INFO[2024-07-16T17:17:38+08:00] Initializing preflight checker
 INFO[2024-07-16T17:17:38+08:00] Cleaning up preflight checker
@@ -60,7 +139,18 @@ INFO[2024-07-16T17:17:42+08:00] Completed preflight checker`,
 		PreRun: func(cmd *cobra.Command, args []string) {
 			preflightChecker.Image = globalOpts.Image
 			preflightChecker.KubeConfigPath = globalOpts.KubeConfigPath
+			preflightChecker.Namespace = globalOpts.Namespace
 			preflightChecker.NodeSelector = globalOpts.NodeSelector
+			preflightChecker.MinNodes = globalOpts.MinNodes
+			preflightChecker.PodCpu = globalOpts.PodCpu
+			preflightChecker.PodMemory = globalOpts.PodMemory
+			preflightChecker.PriorityClass = globalOpts.PriorityClass
+			preflightChecker.Proxy = globalOpts.Proxy
+			preflightChecker.NoProxy = globalOpts.NoProxy
+			preflightChecker.Privileged = globalOpts.Privileged
+			preflightChecker.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(utils.ValidateOutputFormat(outputFormat, consts.OutputFormatHTML, consts.OutputFormatJSON, consts.OutputFormatJUnit, consts.OutputFormatMarkdown, consts.OutputFormatYAML))
 
 			logrus.Info("Initializing preflight checker")
 			if err := preflightChecker.Init(); err != nil {
@@ -75,12 +165,30 @@ INFO[2024-07-16T17:17:42+08:00] Completed preflight checker`,
 
 		Run: func(cmd *cobra.Command, args []string) {
 			logrus.Info("Running preflight checker")
-			output, err := preflightChecker.Run()
+			nodeCollections, err := preflightChecker.Collect()
 			if err != nil {
 				utils.CheckErr(errors.Wrap(err, "Failed to run preflight checker"))
 			}
 
-			logrus.Infof("Retrieved preflight checker result:\n%v", output)
+			if preflightChecker.HasSeverityPolicy() {
+				for _, collection := range nodeCollections {
+					if len(collection.Error) > 0 {
+						hasErrors = true
+					}
+				}
+			}
+
+			if preflightChecker.SummarizeBy != "" {
+				nodeGroups, err := preflightChecker.GetNodeGroups()
+				if err != nil {
+					utils.CheckErr(errors.Wrap(err, "Failed to group nodes of preflight checker result"))
+				}
+
+				utils.CheckErr(utils.PrintSummarizedNodeCollections(globalOpts, strings.ToUpper(preflightChecker.SummarizeBy), "Retrieved preflight checker result", outputFormat, nodeGroups, nodeCollections))
+				return
+			}
+
+			utils.CheckErr(utils.PrintNodeCollections(globalOpts, "Retrieved preflight checker result", outputFormat, nodeCollections))
 		},
 
 		PostRun: func(cmd *cobra.Command, args []string) {
@@ -90,14 +198,810 @@ INFO[2024-07-16T17:17:42+08:00] Completed preflight checker`,
 			}
 
 			logrus.Info("Completed preflight checker")
+
+			if hasErrors {
+				utils.CheckErr(errors.New("preflight check failed with the severity policy"))
+			}
 		},
 	}
 
 	utils.SetGlobalOptionsRemote(cmd, globalOpts)
 
+	cmd.Flags().StringVarP(&outputFormat, consts.CmdOptOutput, "o", "", "Output format of the result (html, json, junit, markdown, yaml). Defaults to a table on terminals, and YAML otherwise.")
 	cmd.Flags().BoolVar(&preflightChecker.EnableSpdk, consts.CmdOptEnableSpdk, false, "Enable checking of SPDK required packages, modules, and setup.")
 	cmd.Flags().IntVar(&preflightChecker.HugePageSize, consts.CmdOptHugePageSize, 2048, "Specify the huge page size in MiB for SPDK.")
+	cmd.Flags().StringVar(&preflightChecker.HugePageNodes, consts.CmdOptHugePageNodes, "", fmt.Sprintf("Specify a comma-separated (%s) list of huge page sizes in MiB per NUMA node, for example 0=1024,1=1024. Overrides --%s.", consts.CmdOptSeperator, consts.CmdOptHugePageSize))
 	cmd.Flags().StringVar(&preflightChecker.UserspaceDriver, consts.CmdOptUserspaceDriver, "", "Userspace I/O driver for SPDK.")
+	cmd.Flags().StringVar(&preflightChecker.Profile, consts.CmdOptProfile, "", fmt.Sprintf("Managed platform or Kubernetes distribution of the cluster (%s, %s, %s, %s, %s), enabling its specific checks and skipping the ones that do not apply.", consts.PreflightProfileGKE, consts.PreflightProfileEKS, consts.PreflightProfileAKS, consts.PreflightProfileRKE2, consts.PreflightProfileK3s))
+	cmd.Flags().StringSliceVar(&preflightChecker.LonghornDataDirectories, consts.CmdOptLonghornDataDirectory, []string{consts.LonghornDefaultDataDirectory}, "Data paths of the Longhorn disks on the nodes, for the clusters with custom or multiple disks. Can be repeated or comma-separated.")
+	cmd.Flags().StringVar(&preflightChecker.LonghornVersion, consts.CmdOptLonghornVersion, "", "Longhorn version to check the CPU architecture of each node is supported by, for example v1.7.2. Defaults to --"+consts.CmdOptRegistryCheckVersion+".")
+	cmd.Flags().StringVar(&preflightChecker.CustomChecksFile, consts.CmdOptCustomChecks, "", "Path to a YAML file defining custom checks to run on each node.")
+	cmd.Flags().StringVar(&preflightChecker.CustomChecksConfigMap, consts.CmdOptCustomChecksConfigMap, "", "Name of an existing ConfigMap in the namespace defining custom checks in the "+consts.FileNameCustomChecks+" key.")
+	cmd.Flags().StringVar(&preflightChecker.RegistryCheckVersion, consts.CmdOptRegistryCheckVersion, "", "Check each node can fetch the images of this Longhorn version through its containerd registry mirrors, for example v1.7.2.")
+	cmd.Flags().StringVar(&preflightChecker.RegistryCheckImagesFile, consts.CmdOptRegistryCheckImagesFile, "", "Path to a file listing the images to check through the containerd registry mirrors of each node, one per line. Overrides --"+consts.CmdOptRegistryCheckVersion+".")
+	cmd.Flags().BoolVar(&preflightChecker.CryptoBenchmark, consts.CmdOptCryptoBenchmark, false, "Benchmark the default cipher of the Longhorn encrypted volumes (aes-xts, 256-bit key) with cryptsetup on each node, and warn about the nodes where the encrypted volumes would underperform.")
+	cmd.Flags().StringVar(&preflightChecker.RulesURL, consts.CmdOptRulesURL, "", "HTTP or HTTPS URL of a known issues database in YAML, replacing the one embedded in longhornctl.")
+	cmd.Flags().StringVar(&preflightChecker.SummarizeBy, consts.CmdOptSummarizeBy, "", fmt.Sprintf("Group the nodes of the result table by %s, %s or %s, and only list the messages of the nodes with errors.", consts.SummarizeByZone, consts.SummarizeByNodePool, consts.SummarizeByOS))
+	cmd.Flags().IntVar(&preflightChecker.MaxParallel, consts.CmdOptMaxParallel, 0, "Maximum number of nodes to check at the same time. The nodes are checked in batches of this size. 0 checks all nodes at once.")
+	cmd.Flags().StringVar(&preflightChecker.SeverityPolicyFile, consts.CmdOptSeverityPolicy, "", "Path to a YAML file changing the level of the messages matching its rules. The command fails when errors remain with the policy applied.")
+	cmd.Flags().StringVar(&preflightChecker.Backend, consts.CmdOptBackend, consts.BackendDaemonSet, "Backend running the operation on the nodes (daemonset, ssh). The ssh backend runs "+consts.CmdLonghornctlLocal+" on the hosts listed in --"+consts.CmdOptSSHHosts+" without the Kubernetes API.")
+	cmd.Flags().StringVar(&preflightChecker.SSHHostsFile, consts.CmdOptSSHHosts, "", "Path to a YAML file listing the hosts to check with the ssh backend.")
+	cmd.Flags().StringVar(&preflightChecker.SSHLocalBinary, consts.CmdOptSSHLocalBinary, "", "Path to the "+consts.CmdLonghornctlLocal+" binary to upload to the hosts with the ssh backend. Defaults to the one on the PATH of the hosts.")
+
+	return cmd
+}
+
+func newCmdCheckPciBindings(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var pciBindingsChecker = preflight.PciBindingsChecker{}
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdPciBindings,
+		Short: "Inspect the driver bindings of the NVMe PCI devices for SPDK",
+		Long: `This command lists the NVMe PCI devices of each node with their current kernel driver and IOMMU group, so SPDK device binding problems are visible before enabling v2 volumes.
+
+The devices in --allow-pci are expected to be bound to the userspace driver in --driver-override, or to the one SPDK picks by default: vfio-pci when IOMMU is enabled, and uio_pci_generic otherwise.
+With vfio-pci, the other devices in the IOMMU group of a device must be bound to vfio-pci or to no driver.`,
+		Example: `$ longhornctl check pci-bindings --allow-pci=0000:01:00.0
+INFO[2024-07-16T17:17:38+08:00] Initializing PCI bindings checker
+INFO[2024-07-16T17:17:38+08:00] Cleaning up PCI bindings checker
+INFO[2024-07-16T17:17:38+08:00] Running PCI bindings checker
+INFO[2024-07-16T17:17:42+08:00] Retrieved PCI bindings checker result:
+ip-10-0-2-123:
+  info:
+  - NVMe device 0000:01:00.0 (8086:0a54) is bound to nvme, IOMMU group 12
+  warn:
+  - NVMe device 0000:01:00.0 is bound to nvme instead of the userspace driver vfio-pci
+INFO[2024-07-16T17:17:42+08:00] Cleaning up PCI bindings checker
+INFO[2024-07-16T17:17:42+08:00] Completed PCI bindings checker`,
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			pciBindingsChecker.Image = globalOpts.Image
+			pciBindingsChecker.KubeConfigPath = globalOpts.KubeConfigPath
+			pciBindingsChecker.Namespace = globalOpts.Namespace
+			pciBindingsChecker.NodeSelector = globalOpts.NodeSelector
+			pciBindingsChecker.MinNodes = globalOpts.MinNodes
+			pciBindingsChecker.PodCpu = globalOpts.PodCpu
+			pciBindingsChecker.PodMemory = globalOpts.PodMemory
+			pciBindingsChecker.PriorityClass = globalOpts.PriorityClass
+			pciBindingsChecker.Proxy = globalOpts.Proxy
+			pciBindingsChecker.NoProxy = globalOpts.NoProxy
+			pciBindingsChecker.Privileged = globalOpts.Privileged
+			pciBindingsChecker.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(utils.ValidateOutputFormat(outputFormat, consts.OutputFormatHTML, consts.OutputFormatJSON, consts.OutputFormatJUnit, consts.OutputFormatMarkdown, consts.OutputFormatYAML))
+
+			logrus.Info("Initializing PCI bindings checker")
+			if err := pciBindingsChecker.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize PCI bindings checker"))
+			}
+
+			logrus.Info("Cleaning up PCI bindings checker")
+			if err := pciBindingsChecker.Cleanup(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to cleanup PCI bindings checker"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Running PCI bindings checker")
+			nodeCollections, err := pciBindingsChecker.Collect()
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to run PCI bindings checker"))
+			}
+
+			utils.CheckErr(utils.PrintNodeCollections(globalOpts, "Retrieved PCI bindings checker result", outputFormat, nodeCollections))
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Cleaning up PCI bindings checker")
+			if err := pciBindingsChecker.Cleanup(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to cleanup PCI bindings checker"))
+			}
+
+			logrus.Info("Completed PCI bindings checker")
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&outputFormat, consts.CmdOptOutput, "o", "", "Output format of the result (html, json, junit, markdown, yaml). Defaults to a table on terminals, and YAML otherwise.")
+	cmd.Flags().StringVar(&pciBindingsChecker.AllowPci, consts.CmdOptAllowPci, "", fmt.Sprintf("Specify a comma-separated (%s) list of the PCI devices intended for SPDK.", consts.CmdOptSeperator))
+	cmd.Flags().StringVar(&pciBindingsChecker.DriverOverride, consts.CmdOptDriverOverride, "", "Userspace driver intended for the PCI devices. Defaults to vfio-pci when IOMMU is enabled, and uio_pci_generic otherwise.")
+
+	return cmd
+}
+
+func newCmdCheckWebhooks(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var webhookChecker = webhook.Checker{}
+	var outputFormat string
+	var deleteStale bool
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdWebhooks,
+		Short: "Check the admission and conversion webhooks of Longhorn",
+		Long: `This command checks the webhooks Longhorn registers in the cluster, which often make upgrades fail when they are stale:
+- The validating and mutating webhook configurations of Longhorn.
+- The conversion webhook of the Longhorn CustomResourceDefinitions.
+- The serving certificate of the webhooks, in the ` + "`longhorn-webhook-tls`" + ` secret.
+
+For each webhook, the service must exist and have ready endpoints, and the certificates of the CA bundle must be valid. Certificates expiring within 30 days are reported as warnings.
+
+A webhook configuration whose service no longer exists is stale, and usually left by a failed uninstallation. With --` + consts.CmdOptDeleteStale + `, the stale webhook configurations are deleted after confirmation. The CustomResourceDefinitions are never modified.`,
+		Example: `$ longhornctl check webhooks --delete-stale
+INFO[2024-07-16T17:17:38+08:00] Initializing webhook checker
+INFO[2024-07-16T17:17:38+08:00] Running webhook checker
+OBJECT                                                     STATUS  MESSAGE
+CustomResourceDefinition/*.longhorn.io                     PASS    22 Longhorn CustomResourceDefinitions use a conversion webhook
+                                                           PASS    Certificate "longhorn-webhook-ca" of the CA bundle is valid until 2034-07-14T09:17:38Z
+                                                           PASS    Service longhorn-system/longhorn-conversion-webhook has 3 ready endpoints
+MutatingWebhookConfiguration/longhorn-webhook-mutator      ERROR   Service longhorn-system/longhorn-admission-webhook does not exist, the configuration is stale
+                                                           PASS    Certificate "longhorn-webhook-ca" of the CA bundle is valid until 2034-07-14T09:17:38Z
+ValidatingWebhookConfiguration/longhorn-webhook-validator  ERROR   Service longhorn-system/longhorn-admission-webhook does not exist, the configuration is stale
+                                                           PASS    Certificate "longhorn-webhook-ca" of the CA bundle is valid until 2034-07-14T09:17:38Z
+
+3 objects, 2 errors, 0 warnings
+This will delete the stale webhook configurations: ValidatingWebhookConfiguration/longhorn-webhook-validator, MutatingWebhookConfiguration/longhorn-webhook-mutator.
+Do you want to continue? [y/N]: y
+INFO[2024-07-16T17:17:45+08:00] Deleted stale webhook configurations
+INFO[2024-07-16T17:17:45+08:00] Completed webhook checker`,
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			webhookChecker.KubeConfigPath = globalOpts.KubeConfigPath
+			webhookChecker.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(utils.ValidateOutputFormat(outputFormat, consts.OutputFormatHTML, consts.OutputFormatJSON, consts.OutputFormatJUnit, consts.OutputFormatMarkdown, consts.OutputFormatYAML))
+
+			logrus.Info("Initializing webhook checker")
+			if err := webhookChecker.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize webhook checker"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Running webhook checker")
+			collections, err := webhookChecker.Collect()
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to run webhook checker"))
+			}
+
+			utils.CheckErr(utils.PrintCollections(globalOpts, "OBJECT", "objects", "Retrieved webhook checker result", outputFormat, collections))
+
+			staleConfigurations := webhookChecker.StaleConfigurations()
+			if !deleteStale || len(staleConfigurations) == 0 {
+				return
+			}
+
+			utils.CheckErr(utils.Confirm(globalOpts, fmt.Sprintf("This will delete the stale webhook configurations: %s.", strings.Join(staleConfigurations, ", "))))
+			if err := webhookChecker.DeleteStaleConfigurations(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to delete stale webhook configurations"))
+			}
+			logrus.Info("Deleted stale webhook configurations")
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Completed webhook checker")
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&outputFormat, consts.CmdOptOutput, "o", "", "Output format of the result (html, json, junit, markdown, yaml). Defaults to a table on terminals, and YAML otherwise.")
+	cmd.Flags().StringVar(&webhookChecker.LonghornNamespace, consts.CmdOptLonghornNamespace, consts.LonghornNamespace, "Namespace where Longhorn is deployed.")
+	cmd.Flags().BoolVar(&deleteStale, consts.CmdOptDeleteStale, false, "Delete the webhook configurations whose service no longer exists, after confirmation.")
+
+	return cmd
+}
+
+func newCmdCheckCrds(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var crdChecker = crd.Checker{}
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdCrds,
+		Short: "Check the Longhorn CustomResourceDefinitions against a Longhorn version",
+		Long: `This command compares the installed Longhorn CustomResourceDefinitions against the ones of a Longhorn version, usually the version to upgrade to:
+- CustomResourceDefinitions of the version that are not installed.
+- Stored versions the version no longer serves. The upgrade is blocked until the objects are migrated.
+- Stored or served versions left beside the storage version, such as v1beta1.
+- Installed CustomResourceDefinitions that are not part of the version.
+
+The CustomResourceDefinitions of the version are read from its deployment manifest, downloaded from GitHub unless --` + consts.CmdOptManifestFile + ` is given. The required migrations are listed after the result. The CustomResourceDefinitions are never modified.`,
+		Example: `$ longhornctl check crds --version v1.8.0
+INFO[2024-07-16T17:17:38+08:00] Initializing CRD checker
+INFO[2024-07-16T17:17:39+08:00] Running CRD checker
+CRD                                  STATUS  MESSAGE
+backingimagedatasources.longhorn.io  PASS    Stored as v1beta2, compatible with Longhorn v1.8.0
+engineimages.longhorn.io             ERROR   Stored versions include v1beta1, which Longhorn v1.8.0 no longer serves, the upgrade is blocked
+                                     WARN    Served version v1beta1 is removed in Longhorn v1.8.0
+systembackups.longhorn.io            WARN    Not installed, it is introduced by Longhorn v1.8.0 and created when upgrading
+
+3 CRDs, 1 errors, 2 warnings
+WARN[2024-07-16T17:17:39+08:00] Required migration: engineimages.longhorn.io: rewrite all engineimages to store them as v1beta2, then remove v1beta1 from status.storedVersions
+INFO[2024-07-16T17:17:39+08:00] Completed CRD checker`,
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			crdChecker.KubeConfigPath = globalOpts.KubeConfigPath
+			crdChecker.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(utils.ValidateOutputFormat(outputFormat, consts.OutputFormatHTML, consts.OutputFormatJSON, consts.OutputFormatJUnit, consts.OutputFormatMarkdown, consts.OutputFormatYAML))
+			utils.CheckErr(crdChecker.Validate())
+
+			logrus.Info("Initializing CRD checker")
+			if err := crdChecker.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize CRD checker"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Running CRD checker")
+			collections, err := crdChecker.Collect()
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to run CRD checker"))
+			}
+
+			utils.CheckErr(utils.PrintCollections(globalOpts, "CRD", "CRDs", "Retrieved CRD checker result", outputFormat, collections))
+
+			for _, migration := range crdChecker.RequiredMigrations() {
+				logrus.Warnf("Required migration: %s", migration)
+			}
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Completed CRD checker")
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&outputFormat, consts.CmdOptOutput, "o", "", "Output format of the result (html, json, junit, markdown, yaml). Defaults to a table on terminals, and YAML otherwise.")
+	cmd.Flags().StringVar(&crdChecker.Version, consts.CmdOptVersion, "", "Longhorn version to compare against, for example v1.8.0.")
+	cmd.Flags().StringVar(&crdChecker.ManifestFile, consts.CmdOptManifestFile, "", "Path to the deployment manifest of the Longhorn version. Overrides the manifest of --"+consts.CmdOptVersion+".")
+
+	return cmd
+}
+
+func newCmdCheckMounts(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var mountChecker = device.MountChecker{}
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdMounts,
+		Short: "Find the stale mounts of the Longhorn volumes, and the pods stuck on them",
+		Long: `This command finds the mounts a crash left behind on the nodes for the Longhorn volumes that are no longer attached to them. A stale mount keeps the kubelet from setting up the volume again, which leaves the pods using it in ContainerCreating:
+- On each node, a DaemonSet finds the mounts of the Longhorn block devices in /dev/longhorn, and of the dm-crypt mappings of the encrypted volumes, whose volumes are not attached to the node or requested to be. This covers both the staging mount of the CSI driver and the mounts published to the pods.
+- The pods the stale mounts are published to, and the pods in ContainerCreating on a node with a stale mount of their volume, are reported.
+
+With --` + consts.CmdOptRepair + `, the stale mounts are unmounted after confirmation, the mounts of the pods before the staging mounts. The mounts still in use fail to unmount and are reported as errors. Then run '` + consts.CmdLonghornctlRemote + ` ` + consts.SubCmdCleanup + ` ` + consts.SubCmdNodeDevices + `' to remove the leftover devices of the node.`,
+		Example: `$ longhornctl check mounts
+INFO[2024-07-16T17:40:12+08:00] Initializing mount checker
+INFO[2024-07-16T17:40:12+08:00] Cleaning up mount checker
+INFO[2024-07-16T17:40:12+08:00] Running mount checker
+OBJECT                                      STATUS  MESSAGE
+Node/ip-10-0-2-123                          WARN    Stale mount /var/lib/kubelet/pods/5f1c2a7e-0b6d-4e55-9a31-6d2f8c0b9e41/volumes/kubernetes.io~csi/pvc-48a6457d/mount of volume pvc-48a6457d from /dev/longhorn/pvc-48a6457d, the volume is not attached to the node
+                                            WARN    Stale mount /var/lib/kubelet/plugins/kubernetes.io/csi/driver.longhorn.io/3c0e.../globalmount of volume pvc-48a6457d from /dev/longhorn/pvc-48a6457d, the volume is not attached to the node
+Node/ip-10-0-2-142                          PASS    No stale mount of Longhorn volume found
+Pod/default/postgres-0                      WARN    Pod is stuck in ContainerCreating on volume pvc-48a6457d, which has a stale mount on node ip-10-0-2-123
+
+3 objects, 0 errors, 3 warnings
+INFO[2024-07-16T17:40:19+08:00] Cleaning up mount checker
+INFO[2024-07-16T17:40:19+08:00] Completed mount checker
+
+$ longhornctl check mounts --repair`,
+
+		Annotations: map[string]string{consts.CmdAnnotationAudit: "true"},
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			mountChecker.Image = globalOpts.Image
+			mountChecker.KubeConfigPath = globalOpts.KubeConfigPath
+			mountChecker.Namespace = globalOpts.Namespace
+			mountChecker.NodeSelector = globalOpts.NodeSelector
+			mountChecker.MinNodes = globalOpts.MinNodes
+			mountChecker.PodCpu = globalOpts.PodCpu
+			mountChecker.PodMemory = globalOpts.PodMemory
+			mountChecker.PriorityClass = globalOpts.PriorityClass
+			mountChecker.Proxy = globalOpts.Proxy
+			mountChecker.NoProxy = globalOpts.NoProxy
+			mountChecker.Privileged = globalOpts.Privileged
+			mountChecker.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(utils.ValidateOutputFormat(outputFormat, consts.OutputFormatHTML, consts.OutputFormatJSON, consts.OutputFormatJUnit, consts.OutputFormatMarkdown, consts.OutputFormatYAML))
+			utils.CheckErr(mountChecker.Validate())
+			if mountChecker.Repair {
+				utils.CheckErr(utils.Confirm(globalOpts, "This will unmount the mounts of the Longhorn volumes not attached to their nodes, including those of the pods using them."))
+			}
+
+			logrus.Info("Initializing mount checker")
+			if err := mountChecker.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize mount checker"))
+			}
+
+			logrus.Info("Cleaning up mount checker")
+			if err := mountChecker.Cleanup(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to cleanup mount checker"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Running mount checker")
+			collections, err := mountChecker.Run()
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to run mount checker"))
+			}
+
+			utils.CheckErr(utils.PrintCollections(globalOpts, "OBJECT", "objects", "Retrieved mount checker result", outputFormat, collections))
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Cleaning up mount checker")
+			if err := mountChecker.Cleanup(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to cleanup mount checker"))
+			}
+
+			logrus.Info("Completed mount checker")
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&outputFormat, consts.CmdOptOutput, "o", "", "Output format of the result (html, json, junit, markdown, yaml). Defaults to a table on terminals, and YAML otherwise.")
+	cmd.Flags().BoolVar(&mountChecker.Repair, consts.CmdOptRepair, false, "Unmount the stale mounts after confirmation.")
+	cmd.Flags().StringVar(&mountChecker.LonghornNamespace, consts.CmdOptLonghornNamespace, consts.LonghornNamespace, "Namespace where Longhorn is deployed.")
+
+	return cmd
+}
+
+func newCmdCheckRwx(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var rwxChecker = volume.RwxChecker{}
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdRwx,
+		Short: "Diagnose the share manager and NFS client mounts of a ReadWriteMany volume",
+		Long: `This command checks the path from a ReadWriteMany volume to the workload pods using it:
+- The share manager of the volume is running with a NFS endpoint.
+- The share manager pod is running and ready. Container restarts are reported as warnings.
+- The service of the share manager routes to the share manager pod.
+- The volume is mounted read-write in the export directory of the share manager pod.
+
+On each node running a workload pod of the volume, a DaemonSet checks the NFS mounts of the volume use NFSv4 and the share manager endpoint, and respond without stale file handle. The kernel release and the version of the NFS client kernel modules are reported, to compare the nodes.`,
+		Example: `$ longhornctl check rwx --volume pvc-9f9ac4b0-5d3e-4b6c-9f3a-2d1b8f3c4e5a
+INFO[2024-07-16T17:17:38+08:00] Initializing RWX checker
+INFO[2024-07-16T17:17:38+08:00] Cleaning up RWX checker
+INFO[2024-07-16T17:17:38+08:00] Running RWX checker
+OBJECT                                                  STATUS  MESSAGE
+Node/ip-10-0-2-123                                      PASS    Kernel release is 5.14.21-150500.55.65-default
+                                                        PASS    Kernel module nfs is loaded (6D1F5E1A8C3C8E5B2C0F2D1)
+                                                        PASS    Kernel module nfsv4 is loaded (C4A1B2E9F0D7A3B6E8C5D21)
+                                                        PASS    Mount /var/lib/kubelet/pods/3d6b.../mount from 10.43.12.7:/pvc-9f9ac4b0-5d3e-4b6c-9f3a-2d1b8f3c4e5a uses NFS version 4.1
+                                                        ERROR   Mount /var/lib/kubelet/pods/3d6b.../mount is not usable: stale file handle, the share manager was restarted without the mount being recovered
+ShareManager/pvc-9f9ac4b0-5d3e-4b6c-9f3a-2d1b8f3c4e5a  PASS    Share manager is running with endpoint nfs://10.43.12.7/pvc-9f9ac4b0-5d3e-4b6c-9f3a-2d1b8f3c4e5a on node ip-10-0-2-142
+                                                        PASS    Share manager pod share-manager-pvc-9f9ac4b0-5d3e-4b6c-9f3a-2d1b8f3c4e5a is running on node ip-10-0-2-142
+                                                        PASS    Service longhorn-system/pvc-9f9ac4b0-5d3e-4b6c-9f3a-2d1b8f3c4e5a routes to share manager pod IP 10.42.1.23
+                                                        PASS    Export /export/pvc-9f9ac4b0-5d3e-4b6c-9f3a-2d1b8f3c4e5a is mounted from /dev/longhorn/pvc-9f9ac4b0-5d3e-4b6c-9f3a-2d1b8f3c4e5a (ext4)
+
+2 objects, 1 errors, 0 warnings
+INFO[2024-07-16T17:17:45+08:00] Cleaning up RWX checker
+INFO[2024-07-16T17:17:45+08:00] Completed RWX checker`,
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			rwxChecker.Image = globalOpts.Image
+			rwxChecker.KubeConfigPath = globalOpts.KubeConfigPath
+			rwxChecker.Namespace = globalOpts.Namespace
+			rwxChecker.NodeSelector = globalOpts.NodeSelector
+			rwxChecker.MinNodes = globalOpts.MinNodes
+			rwxChecker.PodCpu = globalOpts.PodCpu
+			rwxChecker.PodMemory = globalOpts.PodMemory
+			rwxChecker.PriorityClass = globalOpts.PriorityClass
+			rwxChecker.Proxy = globalOpts.Proxy
+			rwxChecker.NoProxy = globalOpts.NoProxy
+			rwxChecker.Privileged = globalOpts.Privileged
+			rwxChecker.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(utils.ValidateOutputFormat(outputFormat, consts.OutputFormatHTML, consts.OutputFormatJSON, consts.OutputFormatJUnit, consts.OutputFormatMarkdown, consts.OutputFormatYAML))
+			utils.CheckErr(rwxChecker.Validate())
+
+			logrus.Info("Initializing RWX checker")
+			if err := rwxChecker.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize RWX checker"))
+			}
+
+			logrus.Info("Cleaning up RWX checker")
+			if err := rwxChecker.Cleanup(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to cleanup RWX checker"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Running RWX checker")
+			collections, err := rwxChecker.Collect()
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to run RWX checker"))
+			}
+
+			utils.CheckErr(utils.PrintCollections(globalOpts, "OBJECT", "objects", "Retrieved RWX checker result", outputFormat, collections))
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Cleaning up RWX checker")
+			if err := rwxChecker.Cleanup(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to cleanup RWX checker"))
+			}
+
+			logrus.Info("Completed RWX checker")
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&outputFormat, consts.CmdOptOutput, "o", "", "Output format of the result (html, json, junit, markdown, yaml). Defaults to a table on terminals, and YAML otherwise.")
+	cmd.Flags().StringVar(&rwxChecker.VolumeName, consts.CmdOptVolume, "", "Name of the ReadWriteMany Longhorn volume to check.")
+	cmd.Flags().StringVar(&rwxChecker.LonghornNamespace, consts.CmdOptLonghornNamespace, consts.LonghornNamespace, "Namespace where Longhorn is deployed.")
+
+	return cmd
+}
+
+func newCmdCheckTuning(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var tuningChecker = preflight.TuningChecker{}
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdTuning,
+		Short: "Check the nodes against the tuning profile for storage nodes",
+		Long: `This command reports the drift of each node from the sysctl and open files limit profile applied by "longhornctl install tuning":
+- The live values of fs.aio-max-nr, vm.dirty_background_ratio and vm.dirty_ratio.
+- The sysctl parameters persisted in /etc/sysctl.d/90-longhorn.conf.
+- The open files limit of the running instance-manager processes, and the systemd drop-in of the container runtime raising it.`,
+		Example: `$ longhornctl check tuning
+INFO[2024-07-16T17:17:38+08:00] Initializing tuning checker
+INFO[2024-07-16T17:17:38+08:00] Cleaning up tuning checker
+INFO[2024-07-16T17:17:38+08:00] Running tuning checker
+INFO[2024-07-16T17:17:42+08:00] Retrieved tuning checker result:
+ip-10-0-2-123:
+  info:
+  - sysctl fs.aio-max-nr is 1048576
+  - sysctl vm.dirty_background_ratio is 5
+  - sysctl vm.dirty_ratio is 10
+  - Open files limit of instance-manager process 2301 is 1048576
+  - The open files limit of containerd.service is persisted in /etc/systemd/system/containerd.service.d/90-longhorn.conf
+  warn:
+  - sysctl vm.dirty_ratio is not persisted in /etc/sysctl.d/90-longhorn.conf
+INFO[2024-07-16T17:17:42+08:00] Cleaning up tuning checker
+INFO[2024-07-16T17:17:42+08:00] Completed tuning checker`,
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			tuningChecker.Image = globalOpts.Image
+			tuningChecker.KubeConfigPath = globalOpts.KubeConfigPath
+			tuningChecker.Namespace = globalOpts.Namespace
+			tuningChecker.NodeSelector = globalOpts.NodeSelector
+			tuningChecker.MinNodes = globalOpts.MinNodes
+			tuningChecker.PodCpu = globalOpts.PodCpu
+			tuningChecker.PodMemory = globalOpts.PodMemory
+			tuningChecker.PriorityClass = globalOpts.PriorityClass
+			tuningChecker.Proxy = globalOpts.Proxy
+			tuningChecker.NoProxy = globalOpts.NoProxy
+			tuningChecker.Privileged = globalOpts.Privileged
+			tuningChecker.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(utils.ValidateOutputFormat(outputFormat, consts.OutputFormatHTML, consts.OutputFormatJSON, consts.OutputFormatJUnit, consts.OutputFormatMarkdown, consts.OutputFormatYAML))
+
+			logrus.Info("Initializing tuning checker")
+			if err := tuningChecker.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize tuning checker"))
+			}
+
+			logrus.Info("Cleaning up tuning checker")
+			if err := tuningChecker.Cleanup(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to cleanup tuning checker"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Running tuning checker")
+			nodeCollections, err := tuningChecker.Collect()
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to run tuning checker"))
+			}
+
+			utils.CheckErr(utils.PrintNodeCollections(globalOpts, "Retrieved tuning checker result", outputFormat, nodeCollections))
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Cleaning up tuning checker")
+			if err := tuningChecker.Cleanup(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to cleanup tuning checker"))
+			}
+
+			logrus.Info("Completed tuning checker")
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&outputFormat, consts.CmdOptOutput, "o", "", "Output format of the result (html, json, junit, markdown, yaml). Defaults to a table on terminals, and YAML otherwise.")
+
+	return cmd
+}
+
+func newCmdCheckVelero(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var veleroChecker = velero.Checker{}
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdVelero,
+		Short: "Check that Velero snapshots the Longhorn volumes",
+		Long: `This command checks that an existing Velero installation backs up the Longhorn PVCs with CSI snapshots of the Longhorn CSI driver:
+- The Velero server is ready, has the ` + consts.VeleroFeatureCSI + ` feature enabled, and has the CSI plugin, built in from Velero 1.14 and the velero-plugin-for-csi init container before.
+- Velero has an available backup storage location, and a default one.
+- The CSI snapshot custom resources are installed, and the CSI snapshot controller is running.
+- Exactly one VolumeSnapshotClass of the Longhorn CSI driver has the ` + consts.VeleroLabelCSIVolumeSnapshotClass + `=true label Velero selects it with. Snapshots of type ` + consts.CSISnapshotTypeSnapshot + ` are reported as warnings, since they stay on the volumes.
+
+Use "longhornctl generate ` + consts.SubCmdVeleroConfig + `" to generate the missing configuration.`,
+		Example: `$ longhornctl check velero
+INFO[2024-07-16T17:17:38+08:00] Initializing Velero checker
+INFO[2024-07-16T17:17:38+08:00] Running Velero checker
+OBJECT                                                   STATUS  MESSAGE
+BackupStorageLocation/default                            PASS    The backup storage location is available
+CustomResourceDefinition/*.snapshot.storage.k8s.io       PASS    The CSI snapshot custom resources are served as snapshot.storage.k8s.io/v1
+Deployment/kube-system/snapshot-controller               PASS    The CSI snapshot controller has 2 ready replicas
+Deployment/velero/velero                                 ERROR   The feature EnableCSI is not enabled, add --features=EnableCSI to the arguments of the Velero server
+                                                         PASS    The Velero server has 1 ready replicas
+                                                         PASS    Velero 1.14.1 has the CSI plugin built in
+VolumeSnapshotClass/*                                    ERROR   No VolumeSnapshotClass of driver.longhorn.io has the label velero.io/csi-volumesnapshot-class=true, Velero cannot snapshot the Longhorn volumes
+
+5 objects, 2 errors, 0 warnings
+INFO[2024-07-16T17:17:38+08:00] Completed Velero checker`,
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			veleroChecker.KubeConfigPath = globalOpts.KubeConfigPath
+			veleroChecker.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(utils.ValidateOutputFormat(outputFormat, consts.OutputFormatHTML, consts.OutputFormatJSON, consts.OutputFormatJUnit, consts.OutputFormatMarkdown, consts.OutputFormatYAML))
+
+			logrus.Info("Initializing Velero checker")
+			if err := veleroChecker.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize Velero checker"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Running Velero checker")
+			collections, err := veleroChecker.Collect()
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to run Velero checker"))
+			}
+
+			utils.CheckErr(utils.PrintCollections(globalOpts, "OBJECT", "objects", "Retrieved Velero checker result", outputFormat, collections))
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Completed Velero checker")
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&outputFormat, consts.CmdOptOutput, "o", "", "Output format of the result (html, json, junit, markdown, yaml). Defaults to a table on terminals, and YAML otherwise.")
+	cmd.Flags().StringVar(&veleroChecker.VeleroNamespace, consts.CmdOptVeleroNamespace, consts.VeleroNamespace, "Namespace where Velero is deployed.")
+
+	return cmd
+}
+
+func newCmdCheckVersion(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var versionChecker = release.Checker{}
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdVersion,
+		Short: "Check the installed Longhorn version against a release catalog",
+		Long: `This command compares the installed Longhorn version, or the one given by --` + consts.CmdOptVersion + `, against a catalog of the Longhorn releases:
+- The vulnerabilities affecting the version, and the releases fixing them. The critical and high ones are reported as errors.
+- The end of life of its minor version.
+- The patch upgrade available for its minor version, and the latest release.
+
+The catalog shipped with longhornctl is used by default, and lists no vulnerability. Use --` + consts.CmdOptOfflineCatalog + ` to read an up to date catalog from a YAML or JSON file, for example in air-gapped clusters, or --` + consts.CmdOptCatalogURL + ` to download it. The command accesses no network other than the cluster unless --` + consts.CmdOptCatalogURL + ` is given.
+
+The catalog lists the releases with their version, endOfLife, and the cves affecting them, each with an id, description, link and severity (critical, high, medium or low).`,
+		Example: `$ cat catalog.json
+{"releases": [
+  {"version": "v1.7.1", "cves": [{"id": "CVE-2025-0001", "severity": "high", "description": "Example vulnerability"}]},
+  {"version": "v1.7.2"},
+  {"version": "v1.8.0"}
+]}
+$ longhornctl check version --offline-catalog catalog.json
+INFO[2024-07-16T17:17:38+08:00] Initializing version checker
+INFO[2024-07-16T17:17:38+08:00] Read 3 releases from catalog.json
+INFO[2024-07-16T17:17:38+08:00] Running version checker
+OBJECT           STATUS  MESSAGE
+Longhorn/v1.7.1  ERROR   Affected by CVE-2025-0001 (high): Example vulnerability, fixed in v1.7.2
+                 WARN    Patch upgrade available to v1.7.2
+                 PASS    Latest release is v1.8.0
+
+1 objects, 1 errors, 1 warnings
+INFO[2024-07-16T17:17:38+08:00] Completed version checker`,
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			versionChecker.KubeConfigPath = globalOpts.KubeConfigPath
+			versionChecker.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(utils.ValidateOutputFormat(outputFormat, consts.OutputFormatHTML, consts.OutputFormatJSON, consts.OutputFormatJUnit, consts.OutputFormatMarkdown, consts.OutputFormatYAML))
+			utils.CheckErr(versionChecker.Validate())
+
+			logrus.Info("Initializing version checker")
+			if err := versionChecker.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize version checker"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Running version checker")
+			collections, err := versionChecker.Collect()
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to run version checker"))
+			}
+
+			utils.CheckErr(utils.PrintCollections(globalOpts, "OBJECT", "objects", "Retrieved version checker result", outputFormat, collections))
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Completed version checker")
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&outputFormat, consts.CmdOptOutput, "o", "", "Output format of the result (html, json, junit, markdown, yaml). Defaults to a table on terminals, and YAML otherwise.")
+	cmd.Flags().StringVar(&versionChecker.LonghornNamespace, consts.CmdOptLonghornNamespace, consts.LonghornNamespace, "Namespace where Longhorn is deployed.")
+	cmd.Flags().StringVar(&versionChecker.Version, consts.CmdOptVersion, "", "Longhorn version to check instead of the installed one, for example v1.7.1. The cluster is not accessed.")
+	cmd.Flags().StringVar(&versionChecker.OfflineCatalog, consts.CmdOptOfflineCatalog, "", "Path to the release catalog, as YAML or JSON. Defaults to the catalog shipped with "+consts.CmdLonghornctlRemote+".")
+	cmd.Flags().StringVar(&versionChecker.CatalogURL, consts.CmdOptCatalogURL, "", "HTTP or HTTPS URL to download the release catalog from.")
+
+	return cmd
+}
+
+func newCmdCheckAutoscaler(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var autoscalerChecker = autoscaler.Checker{}
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdAutoscaler,
+		Short: "Check which nodes the Cluster Autoscaler could remove without losing Longhorn redundancy",
+		Long: `This command evaluates the scale-down of the Cluster Autoscaler against the Longhorn volumes:
+- The kubernetes-cluster-autoscaler-enabled setting lets the Cluster Autoscaler evict the Longhorn pods, the replica-auto-balance setting moves the replicas to the nodes it adds, and the node-drain-policy setting protects the last healthy replica of each volume.
+- A node is kept when it has the ` + consts.AutoscalerAnnotationScaleDownDisabled + `=true annotation, when it runs a pod with the ` + consts.AutoscalerAnnotationSafeToEvict + `=false annotation, or when Longhorn blocks its removal: a volume attached to it, the last healthy replica of a volume, or a share manager or backing image pod.
+- For each node the Cluster Autoscaler could remove, the volumes left with a single healthy replica are reported as warnings, and the ones left with none as errors, with the annotation or setting to fix them.
+
+The command only reads the cluster. It cannot tell which nodes belong to an autoscaled node group, nor whether they are underutilized.`,
+		Example: `$ longhornctl check autoscaler
+INFO[2024-07-16T17:17:38+08:00] Initializing autoscaler checker
+INFO[2024-07-16T17:17:38+08:00] Running autoscaler checker
+OBJECT                                          STATUS  MESSAGE
+Node/ip-10-0-2-123                              PASS    Longhorn blocks scale-down while volume pvc-2ad6c4f6-6a6f-4c1e-9d0f-5f3c2b1a9e87 is attached to the node
+Node/ip-10-0-2-142                              WARN    The Cluster Autoscaler can remove the node, rebuilding the replicas of volumes pvc-48a6457d-585e-423b-b530-bbc68a5f948a on the other nodes
+Node/ip-10-0-2-161                              PASS    Scale-down is disabled with annotation cluster-autoscaler.kubernetes.io/scale-down-disabled=true
+Setting/kubernetes-cluster-autoscaler-enabled   PASS    Longhorn lets the Cluster Autoscaler remove the nodes without attached volumes or last healthy replicas
+Setting/node-drain-policy                       PASS    Node drain policy block-if-contains-last-replica blocks the removal of the node with the last healthy replica of a volume
+Setting/replica-auto-balance                    WARN    The replicas are not rebalanced to the nodes the Cluster Autoscaler adds. Set replica-auto-balance to best-effort
+Volume/pvc-48a6457d-585e-423b-b530-bbc68a5f948a  WARN    Removing node ip-10-0-2-142 leaves the volume with a single healthy replica until the rebuild completes. Annotate node ip-10-0-2-142 with cluster-autoscaler.kubernetes.io/scale-down-disabled=true, or increase the number of replicas of the volume
+
+7 objects, 0 errors, 3 warnings
+INFO[2024-07-16T17:17:38+08:00] Completed autoscaler checker`,
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			autoscalerChecker.KubeConfigPath = globalOpts.KubeConfigPath
+			autoscalerChecker.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(utils.ValidateOutputFormat(outputFormat, consts.OutputFormatHTML, consts.OutputFormatJSON, consts.OutputFormatJUnit, consts.OutputFormatMarkdown, consts.OutputFormatYAML))
+
+			logrus.Info("Initializing autoscaler checker")
+			if err := autoscalerChecker.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize autoscaler checker"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Running autoscaler checker")
+			collections, err := autoscalerChecker.Collect()
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to run autoscaler checker"))
+			}
+
+			utils.CheckErr(utils.PrintCollections(globalOpts, "OBJECT", "objects", "Retrieved autoscaler checker result", outputFormat, collections))
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Completed autoscaler checker")
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&outputFormat, consts.CmdOptOutput, "o", "", "Output format of the result (html, json, junit, markdown, yaml). Defaults to a table on terminals, and YAML otherwise.")
+	cmd.Flags().StringVar(&autoscalerChecker.LonghornNamespace, consts.CmdOptLonghornNamespace, consts.LonghornNamespace, "Namespace where Longhorn is deployed.")
+
+	return cmd
+}
+
+func newCmdCheckStorageClasses(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var storageClassChecker = storageclass.Checker{}
+	var outputFormat string
+	var fix bool
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdStorageClasses,
+		Short: "Check the default StorageClass and the Longhorn StorageClass",
+		Long: `This command checks the StorageClasses behind most PVCs pending after an installation:
+- Multiple default StorageClasses, which make the PVCs without a StorageClass get an unexpected one, or be rejected by Kubernetes before v1.26.
+- No default StorageClass, which leaves the PVCs without a StorageClass pending.
+- The StorageClass of --` + consts.CmdOptStorageClass + ` missing, or not provisioned by Longhorn.
+- Pending PVCs requesting a StorageClass that does not exist.
+
+With --` + consts.CmdOptFix + `, the StorageClass of --` + consts.CmdOptStorageClass + ` is set as the only default StorageClass after confirmation, by patching the ` + storageclass.AnnotationIsDefaultClass + ` annotation of the StorageClasses. The PVCs are never modified.`,
+		Example: `$ longhornctl check storageclasses --fix
+INFO[2024-07-16T17:17:38+08:00] Initializing StorageClass checker
+INFO[2024-07-16T17:17:38+08:00] Running StorageClass checker
+OBJECT                                   STATUS  MESSAGE
+PersistentVolumeClaim/default/data-db-0  ERROR   Pending, StorageClass longhorn-nvme does not exist
+StorageClass/local-path                  PASS    Provisioned by rancher.io/local-path
+                                         ERROR   One of the 2 default StorageClasses (local-path, longhorn), the PVCs without a StorageClass get the most recently created one, or are rejected by Kubernetes before v1.26
+StorageClass/longhorn                    PASS    Provisioned by driver.longhorn.io
+                                         ERROR   One of the 2 default StorageClasses (local-path, longhorn), the PVCs without a StorageClass get the most recently created one, or are rejected by Kubernetes before v1.26
+
+3 objects, 3 errors, 0 warnings
+This will change the default StorageClass: StorageClass/local-path: unset as default.
+Do you want to continue? [y/N]: y
+INFO[2024-07-16T17:17:45+08:00] Set StorageClass longhorn as the default StorageClass
+INFO[2024-07-16T17:17:45+08:00] Completed StorageClass checker`,
+
+		Annotations: map[string]string{consts.CmdAnnotationAudit: "true"},
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			storageClassChecker.KubeConfigPath = globalOpts.KubeConfigPath
+			storageClassChecker.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(utils.ValidateOutputFormat(outputFormat, consts.OutputFormatHTML, consts.OutputFormatJSON, consts.OutputFormatJUnit, consts.OutputFormatMarkdown, consts.OutputFormatYAML))
+			utils.CheckErr(storageClassChecker.Validate())
+
+			logrus.Info("Initializing StorageClass checker")
+			if err := storageClassChecker.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize StorageClass checker"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Running StorageClass checker")
+			collections, err := storageClassChecker.Collect()
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to run StorageClass checker"))
+			}
+
+			utils.CheckErr(utils.PrintCollections(globalOpts, "OBJECT", "objects", "Retrieved StorageClass checker result", outputFormat, collections))
+
+			if !fix {
+				return
+			}
+			changes, err := storageClassChecker.DefaultChanges()
+			utils.CheckErr(err)
+			if len(changes) == 0 {
+				return
+			}
+
+			utils.CheckErr(utils.Confirm(globalOpts, fmt.Sprintf("This will change the default StorageClass: %s.", strings.Join(changes, ", "))))
+			if err := storageClassChecker.SetDefault(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to set the default StorageClass"))
+			}
+			logrus.Infof("Set StorageClass %s as the default StorageClass", storageClassChecker.StorageClass)
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Completed StorageClass checker")
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&outputFormat, consts.CmdOptOutput, "o", "", "Output format of the result (html, json, junit, markdown, yaml). Defaults to a table on terminals, and YAML otherwise.")
+	cmd.Flags().StringVar(&storageClassChecker.StorageClass, consts.CmdOptStorageClass, consts.LonghornStorageClass, "StorageClass expected by the workloads, and set as the default StorageClass by --"+consts.CmdOptFix+".")
+	cmd.Flags().BoolVar(&fix, consts.CmdOptFix, false, "Set the StorageClass of --"+consts.CmdOptStorageClass+" as the only default StorageClass, after confirmation.")
 
 	return cmd
 }
@@ -0,0 +1,199 @@
+package subcmd
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/remote/cleanup"
+	"github.com/longhorn/cli/pkg/remote/device"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+)
+
+func NewCmdCleanup(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdCleanup,
+		Short: "Longhorn node and longhornctl resource cleanup operations",
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.AddCommand(newCmdCleanupAll(globalOpts))
+	cmd.AddCommand(newCmdCleanupNodeDevices(globalOpts))
+
+	return cmd
+}
+
+func newCmdCleanupAll(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var resourceCleaner = cleanup.Cleaner{}
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdAll,
+		Short: "Remove all the resources longhornctl created in the cluster",
+		Long: `This command removes the resources longhornctl created in the cluster, in all the namespaces, to clean up after interrupted commands and forgotten exports in one shot. They are found by their ` + consts.LabelManagedBy + `=` + consts.LabelValueManagedBy + ` label:
+- DaemonSets, with their pods, such as the replica exporters and the preflight checkers.
+- Jobs, with their pods, such as those of the runs submitted with --` + consts.CmdOptNoWait + `.
+- Pods, PersistentVolumeClaims, PersistentVolumes, ConfigMaps and Secrets, such as those of the benchmarks, the installation verification and the backup verification.
+- ClusterRoleBindings, ClusterRoles and ServiceAccounts of the preflight checker.
+
+The resources of the commands still running are removed too, which interrupts them. The replica exporters unmount the exported volumes before they stop.
+
+The resources created by versions of longhornctl without the label, and the resources of the manifests generated by '` + consts.CmdLonghornctlRemote + ` ` + consts.SubCmdGenerate + `', are not removed.
+
+Each run of a command labels its resources with ` + consts.LabelOperation + ` and ` + consts.LabelRunID + `, and the resources in a namespace are owned by the ` + consts.RunAnchorNamePrefix + `<run ID> ConfigMap of the run, its anchor. The anchor is removed when the command completes, leaving the resources it keeps on purpose, and kept when the command fails or is interrupted. With --` + consts.CmdOptTTL + `, only the resources of the runs started longer ago are removed, for example from a CronJob with --` + consts.CmdOptYes + ` to remove the abandoned runs without interrupting the running commands. The start of a run is the creation of its anchor, or of the resource once the anchor is removed.
+
+The ConfigMaps holding the state or the records of operations, labeled with ` + consts.LabelState + `=` + consts.LabelValueState + `, are kept so a cluster migration can be resumed and the results of the runs submitted with --` + consts.CmdOptNoWait + ` fetched afterwards. Use --` + consts.CmdOptIncludeState + ` to remove them too.
+
+With --` + consts.CmdOptDryRun + `, the resources are only listed.`,
+		Example: `$ longhornctl cleanup all --dry-run
+INFO[2024-07-16T17:40:12+08:00] Initializing longhornctl resource cleaner
+INFO[2024-07-16T17:40:12+08:00] Running longhornctl resource cleaner
+OBJECT                                                  STATUS  MESSAGE
+ConfigMap/longhorn-system/longhorn-replica-exporter     INFO    Would be removed
+DaemonSet/longhorn-system/longhorn-replica-exporter     INFO    Would be removed
+
+2 objects, 0 errors, 0 warnings
+INFO[2024-07-16T17:40:13+08:00] Completed longhornctl resource cleaner
+
+$ longhornctl cleanup all --ttl=2h`,
+
+		Annotations: map[string]string{consts.CmdAnnotationAudit: "true"},
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			resourceCleaner.KubeConfigPath = globalOpts.KubeConfigPath
+			resourceCleaner.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(utils.ValidateOutputFormat(outputFormat, consts.OutputFormatJSON, consts.OutputFormatJUnit, consts.OutputFormatYAML))
+			if resourceCleaner.TTL < 0 {
+				utils.CheckErr(errors.Errorf("TTL (--%s) must not be negative", consts.CmdOptTTL))
+			}
+			if !resourceCleaner.DryRun && resourceCleaner.TTL > 0 {
+				utils.CheckErr(utils.Confirm(globalOpts, fmt.Sprintf("This will remove the resources of the longhornctl runs started more than %v ago. Use --%s to list them first.", resourceCleaner.TTL, consts.CmdOptDryRun)))
+			} else if !resourceCleaner.DryRun {
+				utils.CheckErr(utils.Confirm(globalOpts, fmt.Sprintf("This will remove all the resources longhornctl created in the cluster, including those of the commands still running. Use --%s to list them first.", consts.CmdOptDryRun)))
+			}
+
+			logrus.Info("Initializing longhornctl resource cleaner")
+			if err := resourceCleaner.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize longhornctl resource cleaner"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Running longhornctl resource cleaner")
+			collections, err := resourceCleaner.Run()
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to run longhornctl resource cleaner"))
+			}
+
+			utils.CheckErr(utils.PrintCollections(globalOpts, "OBJECT", "objects", "Retrieved longhornctl resource cleaner result", outputFormat, collections))
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Completed longhornctl resource cleaner")
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&outputFormat, consts.CmdOptOutput, "o", "", "Output format of the result (json, junit, yaml). Defaults to a table on terminals, and YAML otherwise.")
+	cmd.Flags().BoolVar(&resourceCleaner.DryRun, consts.CmdOptDryRun, false, "Only list the resources that would be removed.")
+	cmd.Flags().DurationVar(&resourceCleaner.TTL, consts.CmdOptTTL, 0, "Only remove the resources of the runs started longer ago, for example 2h. Removes all the resources when not set.")
+	cmd.Flags().BoolVar(&resourceCleaner.IncludeState, consts.CmdOptIncludeState, false, "Also remove the ConfigMaps holding the state of the cluster migrations and the records and the results of the runs submitted with --"+consts.CmdOptNoWait+".")
+
+	return cmd
+}
+
+func newCmdCleanupNodeDevices(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var deviceCleaner = device.Cleaner{}
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdNodeDevices,
+		Short: "Clean up the leftover iSCSI sessions, dm-crypt mappings and Longhorn block devices of a node",
+		Long: `This command removes the devices a crash left behind on a node for the Longhorn volumes that are no longer attached to it. They can prevent the volumes from attaching again, or hold stale data paths:
+- The dm-crypt mappings of the encrypted volumes, named after the volume or backed by a Longhorn disk, are removed with dmsetup.
+- The iSCSI sessions to Longhorn targets are logged out, and their node records deleted with iscsiadm.
+- The block devices in /dev/longhorn are deleted.
+
+The devices of the volumes attached to the node, or requested to be, are kept. The devices that are mounted, or held by another device, are reported as errors and kept; unmount them first.
+
+With --` + consts.CmdOptDryRun + `, the devices are only listed.`,
+		Example: `$ longhornctl cleanup node-devices --node=ip-10-0-2-123 --dry-run
+INFO[2024-07-16T17:40:12+08:00] Initializing node device cleaner
+INFO[2024-07-16T17:40:12+08:00] Cleaning up node device cleaner
+INFO[2024-07-16T17:40:12+08:00] Running node device cleaner
+OBJECT              STATUS  MESSAGE
+Node/ip-10-0-2-123  WARN    Leftover dm-crypt mapping pvc-48a6457d-585e-423b-b530-bbc68a5f948a of volume pvc-48a6457d-585e-423b-b530-bbc68a5f948a would be removed
+                    WARN    Leftover iSCSI session iqn.2019-10.io.longhorn:pvc-48a6457d-585e-423b-b530-bbc68a5f948a of volume pvc-48a6457d-585e-423b-b530-bbc68a5f948a would be removed
+                    WARN    Leftover block device /dev/longhorn/pvc-48a6457d-585e-423b-b530-bbc68a5f948a of volume pvc-48a6457d-585e-423b-b530-bbc68a5f948a would be removed
+
+1 objects, 0 errors, 3 warnings
+INFO[2024-07-16T17:40:19+08:00] Cleaning up node device cleaner
+INFO[2024-07-16T17:40:19+08:00] Completed node device cleaner`,
+
+		Annotations: map[string]string{consts.CmdAnnotationAudit: "true"},
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			deviceCleaner.Image = globalOpts.Image
+			deviceCleaner.KubeConfigPath = globalOpts.KubeConfigPath
+			deviceCleaner.Namespace = globalOpts.Namespace
+			deviceCleaner.PodCpu = globalOpts.PodCpu
+			deviceCleaner.PodMemory = globalOpts.PodMemory
+			deviceCleaner.PriorityClass = globalOpts.PriorityClass
+			deviceCleaner.Proxy = globalOpts.Proxy
+			deviceCleaner.NoProxy = globalOpts.NoProxy
+			deviceCleaner.Privileged = globalOpts.Privileged
+			deviceCleaner.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(utils.ValidateOutputFormat(outputFormat, consts.OutputFormatJSON, consts.OutputFormatJUnit, consts.OutputFormatYAML))
+			utils.CheckErr(deviceCleaner.Validate())
+			if !deviceCleaner.DryRun {
+				utils.CheckErr(utils.Confirm(globalOpts, fmt.Sprintf("This will remove the leftover iSCSI sessions, dm-crypt mappings and Longhorn block devices of the volumes not attached to node %s. Use --%s to list them first.", deviceCleaner.NodeID, consts.CmdOptDryRun)))
+			}
+
+			logrus.Info("Initializing node device cleaner")
+			if err := deviceCleaner.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize node device cleaner"))
+			}
+
+			logrus.Info("Cleaning up node device cleaner")
+			if err := deviceCleaner.Cleanup(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to cleanup node device cleaner"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Running node device cleaner")
+			collections, err := deviceCleaner.Run()
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to run node device cleaner"))
+			}
+
+			utils.CheckErr(utils.PrintCollections(globalOpts, "OBJECT", "objects", "Retrieved node device cleaner result", outputFormat, collections))
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Cleaning up node device cleaner")
+			if err := deviceCleaner.Cleanup(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to cleanup node device cleaner"))
+			}
+
+			logrus.Info("Completed node device cleaner")
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&outputFormat, consts.CmdOptOutput, "o", "", "Output format of the result (json, junit, yaml). Defaults to a table on terminals, and YAML otherwise.")
+	cmd.Flags().StringVar(&deviceCleaner.NodeID, consts.CmdOptNode, "", "Name of the node to clean up.")
+	cmd.Flags().BoolVar(&deviceCleaner.DryRun, consts.CmdOptDryRun, false, "Only list the devices that would be removed.")
+	cmd.Flags().StringVar(&deviceCleaner.LonghornNamespace, consts.CmdOptLonghornNamespace, consts.LonghornNamespace, "Namespace where Longhorn is deployed.")
+
+	return cmd
+}
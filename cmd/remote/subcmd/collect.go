@@ -0,0 +1,110 @@
+package subcmd
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/remote/nodefacts"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+)
+
+func NewCmdCollect(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdCollect,
+		Short: "Longhorn node inventory operations",
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.AddCommand(newCmdCollectNodeFacts(globalOpts))
+
+	return cmd
+}
+
+func newCmdCollectNodeFacts(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var nodeFactsCollector = nodefacts.Collector{}
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdNodeFacts,
+		Short: "Collect the hardware and operating system inventory of the nodes",
+		Long: `This command collects the hardware and operating system inventory of each node relevant to storage, and outputs it as structured data keyed by the node name:
+- kernel: kernel release.
+- operatingSystem: ID, version and name from /etc/os-release.
+- cpu: architecture, model, logical CPUs and sockets.
+- numaNodes: CPUs and memory of each NUMA node.
+- memory: total and available memory, and HugePages.
+- disks: physical disks with their transport (nvme, scsi, virtio, mmc, xen), model, serial, firmware, size, and whether they are rotational or removable.
+- networkInterfaces: network interfaces with their MAC address, state, link speed, MTU, and whether they are virtual.
+- modules: loaded kernel modules with their version.
+- errors: facts that could not be read on the node.
+
+Use --node-selector to collect the facts of a subset of the nodes.`,
+		Example: `$ longhornctl collect node-facts --quiet > node-facts.json
+$ longhornctl collect node-facts -o yaml --node-selector=node-role.kubernetes.io/worker=true`,
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			nodeFactsCollector.Image = globalOpts.Image
+			nodeFactsCollector.KubeConfigPath = globalOpts.KubeConfigPath
+			nodeFactsCollector.Namespace = globalOpts.Namespace
+			nodeFactsCollector.NodeSelector = globalOpts.NodeSelector
+			nodeFactsCollector.MinNodes = globalOpts.MinNodes
+			nodeFactsCollector.PodCpu = globalOpts.PodCpu
+			nodeFactsCollector.PodMemory = globalOpts.PodMemory
+			nodeFactsCollector.PriorityClass = globalOpts.PriorityClass
+			nodeFactsCollector.Proxy = globalOpts.Proxy
+			nodeFactsCollector.NoProxy = globalOpts.NoProxy
+			nodeFactsCollector.Privileged = globalOpts.Privileged
+			nodeFactsCollector.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(utils.ValidateOutputFormat(outputFormat, consts.OutputFormatJSON, consts.OutputFormatYAML))
+
+			logrus.Info("Initializing node facts collector")
+			if err := nodeFactsCollector.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize node facts collector"))
+			}
+
+			logrus.Info("Cleaning up node facts collector")
+			if err := nodeFactsCollector.Cleanup(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to cleanup node facts collector"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Running node facts collector")
+			collection, err := nodeFactsCollector.Run()
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to run node facts collector"))
+			}
+
+			for node, facts := range collection.Nodes {
+				for _, message := range facts.Errors {
+					logrus.WithField("node", node).Warn(message)
+				}
+			}
+
+			_, err = utils.PrintStructuredResult(outputFormat, types.ResultKindNodeFactsCollection, collection)
+			utils.CheckErr(err)
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Cleaning up node facts collector")
+			if err := nodeFactsCollector.Cleanup(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to cleanup node facts collector"))
+			}
+
+			logrus.Info("Completed node facts collector")
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&outputFormat, consts.CmdOptOutput, "o", consts.OutputFormatJSON, fmt.Sprintf("Output format (%s, %s).", consts.OutputFormatJSON, consts.OutputFormatYAML))
+
+	return cmd
+}
@@ -0,0 +1,140 @@
+package subcmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+const (
+	completionTimeout  = 3 * time.Second
+	completionCacheTTL = 30 * time.Second
+)
+
+type completionFunc func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective)
+
+// RegisterCompletions registers the completion of the global options on the command and all its subcommands.
+func RegisterCompletions(cmd *cobra.Command, globalOpts *types.GlobalCmdOptions) {
+	if cmd.PersistentFlags().Lookup(consts.CmdOptNodeSelector) != nil {
+		// Ignore the error of a flag already registered by another command sharing it.
+		_ = cmd.RegisterFlagCompletionFunc(consts.CmdOptNodeSelector, completeNodeSelector(globalOpts))
+	}
+
+	for _, subcmd := range cmd.Commands() {
+		RegisterCompletions(subcmd, globalOpts)
+	}
+}
+
+// completeVolumeNames completes the names of the Longhorn volumes in the namespace.
+func completeVolumeNames(globalOpts *types.GlobalCmdOptions, namespace *string) completionFunc {
+	return newCompletionFunc(func(ctx context.Context) ([]string, error) {
+		longhornClient, err := kubeutils.NewLonghornClient("", globalOpts.KubeConfigPath)
+		if err != nil {
+			return nil, err
+		}
+
+		volumes, err := longhornClient.LonghornV1beta2().Volumes(*namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+
+		names := []string{}
+		for _, volume := range volumes.Items {
+			names = append(names, volume.Name)
+		}
+		return names, nil
+	}, func() string {
+		return fmt.Sprintf("volumes/%s/%s", globalOpts.KubeConfigPath, *namespace)
+	})
+}
+
+// completeReplicaDirectoryNames completes the data directory names of the Longhorn replicas in the namespace.
+func completeReplicaDirectoryNames(globalOpts *types.GlobalCmdOptions, namespace *string) completionFunc {
+	return newCompletionFunc(func(ctx context.Context) ([]string, error) {
+		longhornClient, err := kubeutils.NewLonghornClient("", globalOpts.KubeConfigPath)
+		if err != nil {
+			return nil, err
+		}
+
+		replicas, err := longhornClient.LonghornV1beta2().Replicas(*namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+
+		names := []string{}
+		for _, replica := range replicas.Items {
+			if replica.Spec.DataDirectoryName != "" {
+				names = append(names, replica.Spec.DataDirectoryName)
+			}
+		}
+		return names, nil
+	}, func() string {
+		return fmt.Sprintf("replicas/%s/%s", globalOpts.KubeConfigPath, *namespace)
+	})
+}
+
+// completeNodeSelector completes node selectors matching a single node by its hostname label.
+func completeNodeSelector(globalOpts *types.GlobalCmdOptions) completionFunc {
+	return newCompletionFunc(func(ctx context.Context) ([]string, error) {
+		kubeClient, err := kubeutils.NewKubeClient("", globalOpts.KubeConfigPath)
+		if err != nil {
+			return nil, err
+		}
+
+		nodes, err := kubeClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+
+		selectors := []string{}
+		for _, node := range nodes.Items {
+			hostname := node.Labels[corev1.LabelHostname]
+			if hostname == "" {
+				continue
+			}
+			selectors = append(selectors, corev1.LabelHostname+"="+hostname)
+		}
+		return selectors, nil
+	}, func() string {
+		return fmt.Sprintf("nodes/%s", globalOpts.KubeConfigPath)
+	})
+}
+
+// newCompletionFunc returns a completion function listing the candidates with a
+// timeout, and caching them briefly so repeated completions stay responsive.
+func newCompletionFunc(list func(ctx context.Context) ([]string, error), cacheKey func() string) completionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		candidates, err := utils.GetCachedStrings(cacheKey(), completionCacheTTL, func() ([]string, error) {
+			ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+			defer cancel()
+			return list(ctx)
+		})
+		if err != nil {
+			logrus.WithError(err).Debug("Failed to list completion candidates")
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		var completions []string
+		for _, candidate := range candidates {
+			if strings.HasPrefix(candidate, toComplete) {
+				completions = append(completions, candidate)
+			}
+		}
+		sort.Strings(completions)
+		return completions, cobra.ShellCompDirectiveNoFileComp
+	}
+}
@@ -0,0 +1,240 @@
+package subcmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	lhmgrtypes "github.com/longhorn/longhorn-manager/types"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/remote/dr"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+)
+
+func NewCmdDr(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdDr,
+		Short: "Longhorn disaster recovery volume operations",
+		Long: `These commands manage disaster recovery (DR) volumes in a secondary cluster sharing the backup target of the primary cluster.
+A DR volume is a standby volume restoring the latest backup of a volume, then each newer backup incrementally. It cannot be used until it is activated, usually during a failover.`,
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.AddCommand(newCmdDrCreate(globalOpts))
+	cmd.AddCommand(newCmdDrStatus(globalOpts))
+	cmd.AddCommand(newCmdDrActivate(globalOpts))
+
+	return cmd
+}
+
+func newCmdDrCreate(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var drCreator = dr.Creator{}
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdCreate + " <volume-name>",
+		Short: "Create a DR volume restoring the backups of a volume from the backup target",
+		Long: `This command creates a standby volume restoring the latest backup of --` + consts.CmdOptBackupVolume + ` from the backup target, then each newer backup incrementally.
+
+The backup volume is the name of the backed up volume in the primary cluster. The size, access mode and backing image of the DR volume are the ones of its latest backup.`,
+		Example: `$ longhornctl dr create pvc-48a6457d-dr --backup-volume=pvc-48a6457d-585e-423b-b530-bbc68a5f948a
+INFO[2024-07-16T17:40:12+08:00] Initializing DR volume creator
+INFO[2024-07-16T17:40:12+08:00] Running DR volume creator
+INFO[2024-07-16T17:40:12+08:00] Creating DR volume pvc-48a6457d-dr from backup s3://backupbucket@us-east-1/?backup=backup-5f6e9b1a2c3d4e5f&volume=pvc-48a6457d-585e-423b-b530-bbc68a5f948a
+INFO[2024-07-16T17:40:12+08:00] Completed DR volume creator`,
+		Args: cobra.ExactArgs(1),
+
+		Annotations: map[string]string{consts.CmdAnnotationAudit: "true"},
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			drCreator.KubeConfigPath = globalOpts.KubeConfigPath
+			drCreator.LogLevel = globalOpts.LogLevel
+			drCreator.VolumeName = args[0]
+
+			utils.CheckErr(drCreator.Validate())
+
+			logrus.Info("Initializing DR volume creator")
+			if err := drCreator.Init(); err != nil {
+				utils.CheckErr(errors.Wrapf(err, "Failed to initialize DR volume creator for volume %s", drCreator.VolumeName))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Running DR volume creator")
+			if err := drCreator.Run(); err != nil {
+				utils.CheckErr(errors.Wrapf(err, "Failed to run DR volume creator for volume %s", drCreator.VolumeName))
+			}
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Completed DR volume creator")
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVar(&drCreator.BackupVolume, consts.CmdOptBackupVolume, "", "Name of the backed up volume in the backup target.")
+	cmd.Flags().StringVar(&drCreator.BackupTarget, consts.CmdOptBackupTarget, lhmgrtypes.DefaultBackupTargetName, "Name of the backup target holding the backups.")
+	cmd.Flags().IntVar(&drCreator.NumberOfReplicas, consts.CmdOptNumberOfReplicas, 0, "Number of replicas of the DR volume. Defaults to the default replica count setting of Longhorn.")
+	cmd.Flags().StringVar(&drCreator.LonghornNamespace, consts.CmdOptLonghornNamespace, consts.LonghornNamespace, "Namespace where Longhorn is deployed.")
+
+	return cmd
+}
+
+func newCmdDrStatus(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var drStatusReporter = dr.StatusReporter{}
+	var outputFormat string
+	var laggingVolumes []string
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdStatus + " [volume-name...]",
+		Short: "Report the restore lag of the DR volumes",
+		Long: `This command reports, for each DR volume, the last restored backup against the latest backup of its backup volume. The lag is the time between the creation of the two backups. Without volume names, all the standby volumes are reported.
+
+With --` + consts.CmdOptMaxLag + `, the command fails when the lag of a DR volume exceeds the maximum, or when no backup is restored yet, for monitoring and runbook automation.`,
+		Example: `$ longhornctl dr status --max-lag=2h
+INFO[2024-07-16T17:40:12+08:00] Initializing DR status reporter
+INFO[2024-07-16T17:40:12+08:00] Running DR status reporter
+VOLUME           STATE     RESTORED BACKUP           LATEST BACKUP             LAG      MESSAGE
+pvc-48a6457d-dr  attached  backup-5f6e9b1a2c3d4e5f   backup-5f6e9b1a2c3d4e5f   0s
+pvc-9c1d2e3f-dr  attached  backup-0a1b2c3d4e5f6a7b   backup-7b6a5f4e3d2c1b0a   3h0m0s   Restoring a newer backup
+ERRO[2024-07-16T17:40:12+08:00] Lag of DR volumes exceeds 2h0m0s: pvc-9c1d2e3f-dr`,
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			drStatusReporter.KubeConfigPath = globalOpts.KubeConfigPath
+			drStatusReporter.LogLevel = globalOpts.LogLevel
+			drStatusReporter.VolumeNames = args
+
+			utils.CheckErr(utils.ValidateOutputFormat(outputFormat, consts.OutputFormatJSON, consts.OutputFormatYAML))
+
+			logrus.Info("Initializing DR status reporter")
+			if err := drStatusReporter.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize DR status reporter"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Running DR status reporter")
+			statuses, err := drStatusReporter.Collect()
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to run DR status reporter"))
+			}
+
+			utils.CheckErr(printDrVolumeStatuses(statuses, outputFormat))
+			laggingVolumes = drStatusReporter.LaggingVolumes(statuses)
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			if len(laggingVolumes) > 0 {
+				utils.CheckErr(errors.Errorf("Lag of DR volumes exceeds %v: %s", drStatusReporter.MaxLag, strings.Join(laggingVolumes, ", ")))
+			}
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&outputFormat, consts.CmdOptOutput, "o", "", fmt.Sprintf("Output format (%s, %s). Defaults to a table.", consts.OutputFormatJSON, consts.OutputFormatYAML))
+	cmd.Flags().DurationVar(&drStatusReporter.MaxLag, consts.CmdOptMaxLag, 0, "Fail when the lag of a DR volume exceeds this duration, for example 2h. 0 disables the check.")
+	cmd.Flags().StringVar(&drStatusReporter.LonghornNamespace, consts.CmdOptLonghornNamespace, consts.LonghornNamespace, "Namespace where Longhorn is deployed.")
+
+	cmd.ValidArgsFunction = completeVolumeNames(globalOpts, &drStatusReporter.LonghornNamespace)
+
+	return cmd
+}
+
+func newCmdDrActivate(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var drActivator = dr.Activator{}
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdActivate + " <volume-name>",
+		Short: "Activate a DR volume during a failover",
+		Long: `This command activates a DR volume, so it can be used by workloads of the secondary cluster:
+1. The backup volume is synchronized with the backup target, to find the latest backup of the primary cluster.
+2. The DR volume leaves standby with the frontend of --` + consts.CmdOptFrontend + `. Longhorn restores the latest backup first.
+3. The command waits for the volume to leave standby.
+
+The data written in the primary cluster after its latest backup is lost. Stop the workloads of the primary cluster and back up their volumes first when it is still reachable.
+
+Create a PV and PVC for the activated volume to use it in workloads.`,
+		Example: `$ longhornctl dr activate pvc-48a6457d-dr
+This will activate DR volume pvc-48a6457d-dr. It stops restoring the backups of the backup target.
+Do you want to continue? [y/N]: y
+INFO[2024-07-16T17:40:12+08:00] Initializing DR volume activator
+INFO[2024-07-16T17:40:12+08:00] Running DR volume activator                   volume=pvc-48a6457d-dr
+INFO[2024-07-16T17:40:12+08:00] Synchronizing backup volume pvc-48a6457d-585e-423b-b530-bbc68a5f948a with backup target default
+INFO[2024-07-16T17:40:15+08:00] DR volume pvc-48a6457d-dr restored backup backup-0a1b2c3d4e5f6a7b, latest backup is backup-5f6e9b1a2c3d4e5f, lag is 30m0s
+INFO[2024-07-16T17:40:15+08:00] Activating DR volume pvc-48a6457d-dr with frontend blockdev
+INFO[2024-07-16T17:40:15+08:00] Waiting for DR volume pvc-48a6457d-dr to restore the latest backup and leave standby
+INFO[2024-07-16T17:41:02+08:00] Activated DR volume pvc-48a6457d-dr with backup backup-5f6e9b1a2c3d4e5f  volume=pvc-48a6457d-dr
+INFO[2024-07-16T17:41:02+08:00] Completed DR volume activator                 volume=pvc-48a6457d-dr`,
+		Args: cobra.ExactArgs(1),
+
+		Annotations: map[string]string{consts.CmdAnnotationAudit: "true"},
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			drActivator.KubeConfigPath = globalOpts.KubeConfigPath
+			drActivator.LogLevel = globalOpts.LogLevel
+			drActivator.VolumeName = args[0]
+
+			utils.CheckErr(drActivator.Validate())
+			utils.CheckErr(utils.Confirm(globalOpts, fmt.Sprintf("This will activate DR volume %s. It stops restoring the backups of the backup target.", drActivator.VolumeName)))
+
+			logrus.Info("Initializing DR volume activator")
+			if err := drActivator.Init(); err != nil {
+				utils.CheckErr(errors.Wrapf(err, "Failed to initialize DR volume activator for volume %s", drActivator.VolumeName))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			log := logrus.WithField("volume", drActivator.VolumeName)
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			log.Info("Running DR volume activator")
+			status, err := drActivator.Run(ctx)
+			if err != nil {
+				utils.CheckErr(errors.Wrapf(err, "Failed to run DR volume activator for volume %s", drActivator.VolumeName))
+			}
+			log.Infof("Activated DR volume %s with backup %s", drActivator.VolumeName, status.LastRestoredBackup)
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logrus.WithField("volume", drActivator.VolumeName).Info("Completed DR volume activator")
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVar(&drActivator.Frontend, consts.CmdOptFrontend, "blockdev", "Frontend of the volume once activated (blockdev, iscsi, nvmf, ublk).")
+	cmd.Flags().DurationVar(&drActivator.Timeout, consts.CmdOptTimeout, 10*time.Minute, "Maximum time to wait for the synchronization of the backup volume, and for the volume to leave standby.")
+	cmd.Flags().StringVar(&drActivator.LonghornNamespace, consts.CmdOptLonghornNamespace, consts.LonghornNamespace, "Namespace where Longhorn is deployed.")
+
+	cmd.ValidArgsFunction = completeVolumeNames(globalOpts, &drActivator.LonghornNamespace)
+
+	return cmd
+}
+
+func printDrVolumeStatuses(statuses []types.DrVolumeStatus, outputFormat string) error {
+	if printed, err := utils.PrintStructuredResult(outputFormat, types.ResultKindDrVolumeStatusList, statuses); printed || err != nil {
+		return err
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "VOLUME\tSTATE\tRESTORED BACKUP\tLATEST BACKUP\tLAG\tMESSAGE")
+	for _, status := range statuses {
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\n", status.Name, status.State, status.LastRestoredBackup, status.LatestBackup, status.Lag, status.Message)
+	}
+	return writer.Flush()
+}
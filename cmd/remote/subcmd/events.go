@@ -0,0 +1,91 @@
+package subcmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/remote/event"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+)
+
+func NewCmdEvents(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var eventStreamer = event.Streamer{}
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdEvents,
+		Short: "Stream the events of the Longhorn objects",
+		Long: `This command prints the Kubernetes events of the Longhorn volumes, engines, replicas and nodes in chronological order, one per line, with warnings highlighted.
+
+With --` + consts.CmdOptVolume + `, only the events of the volume and of its engines and replicas are printed. With --` + consts.CmdOptFollow + `, the command keeps printing the new events until it is interrupted, for example to watch a replica rebuild or a volume attachment live.
+
+With --` + consts.CmdOptOutput + `=` + consts.OutputFormatJSON + `, each event is printed as a JSON object on its own line, wrapped with the schema version like the other structured outputs.`,
+		Example: `$ longhornctl events --follow --volume=pvc-0b1c6f4a-7e0a-4a7b-9d2e-5f1f3f0c2e11
+2024-07-16T17:17:38+08:00  Normal   Volume/pvc-0b1c6f4a-7e0a-4a7b-9d2e-5f1f3f0c2e11  Attached: volume pvc-0b1c6f4a-7e0a-4a7b-9d2e-5f1f3f0c2e11 has been attached to ip-10-0-2-123
+2024-07-16T17:20:02+08:00  Warning  Volume/pvc-0b1c6f4a-7e0a-4a7b-9d2e-5f1f3f0c2e11  Degraded: volume pvc-0b1c6f4a-7e0a-4a7b-9d2e-5f1f3f0c2e11 became degraded
+2024-07-16T17:20:05+08:00  Normal   Replica/pvc-0b1c6f4a-7e0a-4a7b-9d2e-5f1f3f0c2e11-r-8a2b3c4d  Start: Starts pvc-0b1c6f4a-7e0a-4a7b-9d2e-5f1f3f0c2e11-r-8a2b3c4d
+2024-07-16T17:23:41+08:00  Normal   Volume/pvc-0b1c6f4a-7e0a-4a7b-9d2e-5f1f3f0c2e11  Healthy: volume pvc-0b1c6f4a-7e0a-4a7b-9d2e-5f1f3f0c2e11 became healthy`,
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			eventStreamer.KubeConfigPath = globalOpts.KubeConfigPath
+			eventStreamer.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(utils.ValidateOutputFormat(outputFormat, consts.OutputFormatJSON))
+			utils.CheckErr(eventStreamer.Validate())
+
+			logrus.Debug("Initializing event streamer")
+			if err := eventStreamer.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize event streamer"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			color := utils.IsColorEnabled(globalOpts, os.Stdout)
+			var printErr error
+			err := eventStreamer.Run(ctx, func(event *types.Event) {
+				if printErr != nil {
+					return
+				}
+
+				if outputFormat == consts.OutputFormatJSON {
+					var jsonData []byte
+					jsonData, printErr = json.Marshal(utils.NewResult(types.ResultKindEvent, event))
+					fmt.Println(string(jsonData))
+					return
+				}
+				fmt.Println(utils.RenderEvent(event, color))
+			})
+			if err == nil {
+				err = printErr
+			}
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to stream events"))
+			}
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&outputFormat, consts.CmdOptOutput, "o", "", fmt.Sprintf("Output format of the events (%s). Defaults to one line of text per event.", consts.OutputFormatJSON))
+	cmd.Flags().StringVar(&eventStreamer.LonghornNamespace, consts.CmdOptLonghornNamespace, consts.LonghornNamespace, "Namespace where Longhorn is deployed.")
+	cmd.Flags().StringVar(&eventStreamer.Volume, consts.CmdOptVolume, "", "Only print the events of the volume, and of its engines and replicas.")
+	cmd.Flags().StringVar(&eventStreamer.Kinds, consts.CmdOptKinds, strings.Join([]string{event.KindVolume, event.KindEngine, event.KindReplica, event.KindNode}, consts.CmdOptSeperator), fmt.Sprintf("Specify a comma-separated (%s) list of the kinds of Longhorn objects to print the events of.", consts.CmdOptSeperator))
+	cmd.Flags().DurationVar(&eventStreamer.Since, consts.CmdOptSince, 0, "Only print the existing events newer than this duration, for example 1h. Defaults to all the events retained by Kubernetes.")
+	cmd.Flags().BoolVarP(&eventStreamer.Follow, consts.CmdOptFollow, "f", false, "Keep printing the new events until interrupted.")
+
+	return cmd
+}
@@ -28,6 +28,7 @@ func NewCmdExport(globalOpts *types.GlobalCmdOptions) *cobra.Command {
 
 func newCmdExportReplica(globalOpts *types.GlobalCmdOptions) *cobra.Command {
 	var replicaExporter = replica.Exporter{}
+	var replicaArchiver = replica.Archiver{}
 
 	cmd := &cobra.Command{
 		Use:   consts.SubCmdReplica,
@@ -42,8 +43,21 @@ To find available replica data directory names, run:
 
 After the export, you can access the exported data at the location specified in the output.
 
+To export many volumes at once, for example to recover the data of all the volumes of an application, provide the volume names using the --volumes option instead of --name. A healthy replica of each volume that is not in use is picked, and exported by its own replica exporter, at most --concurrency volumes at the same time. The output shows the export of every volume, and the error of the volumes that failed to export, without stopping the export of the others.
+
+The filesystem is mounted read-only, but the replica still records its revision, and mounting may replay the filesystem journal. With --read-only, the replica data directory is mounted read-only under an overlay, so these writes are discarded when the export stops and the replica data cannot be modified. With --snapshot, the volume is exported as it was when the snapshot was taken, instead of its current data; it implies --read-only.
+
+To copy the data off without shelling into the node, use --share to also serve the exported directory read-only as a network share on the node:
+- nfs: served over NFSv4 on port 2049 by nfs-ganesha, from the Longhorn share-manager image. Mount it with: mount -t nfs4 -o ro <exportedShare> <dir>
+- smb: served over SMB on port 445 by Samba, from the image given by --share-image, to the user given by --share-username, with the password in the SHARE_PASSWORD key of the Secret given by --share-secret in the namespace of --namespace. Mount it with: mount -t cifs -o ro,username=<user> <exportedShare> <dir>
+Only the clients in --share-allowed-cidrs can access the share. The share is served on the host network of the node, so the port must be free and reachable from the clients.
+
 To terminate the replica exporter and stop the replica export process, use the 'stop' subcommand with the original command. For example:
-  $ longhornctl export replica <options> stop`,
+  $ longhornctl export replica <options> stop
+With --volumes, the replica exporter of each of the volumes is stopped, so a subset of the volumes can be stopped independently.
+
+To recover the replica off the cluster or in another cluster later, use --archive with --node and --output instead of --target-dir. The whole replica data directory of the node, with its snapshots and metadata files, is packaged in a local archive (tar.zst or tar.gz), with a SHA256SUMS manifest of its files. The checksum of the archive is written next to it, in <output>.sha256. The replica must not be in use, so detach its volume first. There is nothing to stop afterwards. To restore the archive, use:
+  $ longhornctl import replica --input=<output> --node=<node>`,
 		Example: `$ longhornctl export replica --name=pvc-48a6457d-585e-423b-b530-bbc68a5f948a-0e2603a7 --target-dir=/tmp/export
 INFO[2024-07-16T17:26:53+08:00] Initializing replica exporter
 INFO[2024-07-16T17:26:53+08:00] Running replica exporter
@@ -61,12 +75,53 @@ INFO[2024-07-16T17:27:15+08:00] Completed replica exporter. Use 'longhornctl exp
 
 $ ssh user@10.0.2.123
 $ ls /tmp/export/pvc-48a6457d-585e-423b-b530-bbc68a5f948a
-lost+found`,
+lost+found
+
+$ longhornctl export replica --volumes=pvc-48a6457d-585e-423b-b530-bbc68a5f948a,pvc-7b3a1c2e-9f4d-4e0a-8c6b-2d1e5f7a9b3c --concurrency=2 --target-dir=/tmp/export
+
+$ longhornctl export replica --name=pvc-48a6457d-585e-423b-b530-bbc68a5f948a-0e2603a7 --node=ip-10-0-2-123 --archive=tar.zst --output=./vol-backup.tar.zst
+INFO[2024-07-16T17:30:02+08:00] Initializing replica archiver
+INFO[2024-07-16T17:30:02+08:00] Cleaning up replica archiver
+INFO[2024-07-16T17:30:02+08:00] Running replica archiver
+INFO[2024-07-16T17:30:09+08:00] Archiving replica pvc-48a6457d-585e-423b-b530-bbc68a5f948a-0e2603a7 to ./vol-backup.tar.zst  node=ip-10-0-2-123
+Archived replica pvc-48a6457d-585e-423b-b530-bbc68a5f948a-0e2603a7 of node ip-10-0-2-123 to ./vol-backup.tar.zst (52428912 bytes, sha256 9c1e7a0f3b52d8e46a1f0c7b2e3d4a5f6b7c8d9e0f1a2b3c4d5e6f7a8b9c0d1e)
+INFO[2024-07-16T17:30:41+08:00] Cleaning up replica archiver
+INFO[2024-07-16T17:30:41+08:00] Completed replica archiver`,
+
+		Annotations: map[string]string{consts.CmdAnnotationAudit: "true"},
 
 		PreRun: func(cmd *cobra.Command, args []string) {
 			replicaExporter.Image = globalOpts.Image
 			replicaExporter.KubeConfigPath = globalOpts.KubeConfigPath
+			replicaExporter.Namespace = globalOpts.Namespace
 			replicaExporter.NodeSelector = globalOpts.NodeSelector
+			replicaExporter.MinNodes = globalOpts.MinNodes
+			replicaExporter.PodCpu = globalOpts.PodCpu
+			replicaExporter.PodMemory = globalOpts.PodMemory
+			replicaExporter.PriorityClass = globalOpts.PriorityClass
+			replicaExporter.Proxy = globalOpts.Proxy
+			replicaExporter.NoProxy = globalOpts.NoProxy
+			replicaExporter.Privileged = globalOpts.Privileged
+			replicaExporter.LogLevel = globalOpts.LogLevel
+
+			if replicaArchiver.Format != "" {
+				replicaArchiver.GlobalCmdOptions = replicaExporter.GlobalCmdOptions
+				replicaArchiver.ReplicaName = replicaExporter.ReplicaName
+				replicaArchiver.LonghornDataDirectory = replicaExporter.LonghornDataDirectory
+
+				utils.CheckErr(replicaArchiver.Validate())
+
+				logrus.Info("Initializing replica archiver")
+				if err := replicaArchiver.Init(); err != nil {
+					utils.CheckErr(errors.Wrap(err, "Failed to initialize replica archiver"))
+				}
+
+				logrus.Info("Cleaning up replica archiver")
+				if err := replicaArchiver.Cleanup(); err != nil {
+					utils.CheckErr(errors.Wrap(err, "Failed to cleanup replica archiver"))
+				}
+				return
+			}
 
 			utils.CheckErr(replicaExporter.Validate())
 
@@ -77,16 +132,41 @@ lost+found`,
 		},
 
 		Run: func(cmd *cobra.Command, args []string) {
+			if replicaArchiver.Format != "" {
+				logrus.Info("Running replica archiver")
+				result, err := replicaArchiver.Run()
+				if err != nil {
+					utils.CheckErr(errors.Wrap(err, "Failed to run replica archiver"))
+				}
+
+				fmt.Printf("Archived replica %s of node %s to %s (%d bytes, sha256 %s)\n", result.Replica, result.Node, result.File, result.Size, result.SHA256)
+				return
+			}
+
 			logrus.Info("Running replica exporter")
 			result, err := replicaExporter.Run()
 			if err != nil {
 				utils.CheckErr(errors.Wrapf(err, "Failed to run replica exporter"))
 			}
 
-			logrus.Infof("Exported replica:\n %v", result)
+			utils.PrintResult(globalOpts, "Exported replica", result)
 		},
 
 		PostRun: func(cmd *cobra.Command, args []string) {
+			if replicaArchiver.Format != "" {
+				logrus.Info("Cleaning up replica archiver")
+				if err := replicaArchiver.Cleanup(); err != nil {
+					utils.CheckErr(errors.Wrap(err, "Failed to cleanup replica archiver"))
+				}
+
+				logrus.Info("Completed replica archiver")
+				return
+			}
+
+			if replicaExporter.Volumes != "" {
+				logrus.Infof("Completed replica exporter. Use '%s %s %s --%s=%s %s' to stop exporting replicas.", consts.CmdLonghornctlRemote, consts.SubCmdExport, consts.SubCmdReplica, consts.CmdOptVolumes, replicaExporter.Volumes, consts.SubCmdStop)
+				return
+			}
 			logrus.Infof("Completed replica exporter. Use '%s %s %s %s' to stop exporting replica.", consts.CmdLonghornctlRemote, consts.SubCmdExport, consts.SubCmdReplica, consts.SubCmdStop)
 		},
 	}
@@ -104,6 +184,21 @@ lost+found`,
 	cmd.Flags().StringVar(&replicaExporter.ReplicaName, consts.CmdOptName, "", fmt.Sprintf("Specify the replica directory name to export. The replica data directory name is not the same as the Kubernetes Replica custom resource (CR) object name. To retrieve the replica directory name, use '%s %s %s'.", consts.CmdLonghornctlRemote, consts.SubCmdGet, consts.SubCmdReplica))
 	cmd.Flags().StringVar(&replicaExporter.LonghornDataDirectory, consts.CmdOptLonghornDataDirectory, "/var/lib/longhorn", "Specify the Longhorn data directory. If not provided, the default will be attempted, or it will fall back to the directory of longhorn-disk.cfg.")
 	cmd.Flags().StringVar(&replicaExporter.HostTargetDirectory, consts.CmdOptTargetDirectory, "", "Target directory on the host machine where the exported data will be mounted.")
+	cmd.Flags().StringVar(&replicaExporter.Volumes, consts.CmdOptVolumes, "", fmt.Sprintf("Comma-separated (%s) list of the volumes to export a replica of, instead of --%s.", consts.CmdOptSeperator, consts.CmdOptName))
+	cmd.Flags().IntVar(&replicaExporter.Concurrency, consts.CmdOptConcurrency, 1, fmt.Sprintf("Maximum number of volumes exported at the same time with --%s.", consts.CmdOptVolumes))
+	cmd.Flags().BoolVar(&replicaExporter.ReadOnly, consts.CmdOptReadOnly, false, "Export through an overlay, so the replica data cannot be modified.")
+	cmd.Flags().StringVar(&replicaExporter.SnapshotName, consts.CmdOptSnapshot, "", "Name of the snapshot to export instead of the current data of the volume. Implies --"+consts.CmdOptReadOnly+".")
+	cmd.Flags().StringVar(&replicaExporter.Share, consts.CmdOptShare, "", fmt.Sprintf("Also serve the exported data as a read-only network share on the node (%s, %s).", consts.ShareProtocolNFS, consts.ShareProtocolSMB))
+	cmd.Flags().StringVar(&replicaExporter.ShareAllowedCIDRs, consts.CmdOptShareAllowedCIDRs, "", fmt.Sprintf("Comma-separated (%s) list of the CIDRs of the clients allowed to access the share, for example 10.0.0.0/8. Required with --%s.", consts.CmdOptSeperator, consts.CmdOptShare))
+	cmd.Flags().StringVar(&replicaExporter.ShareImage, consts.CmdOptShareImage, "", fmt.Sprintf("Image serving the share. Defaults to %s for nfs. Required with --%s=%s, an image providing smbd and smbpasswd, pinned to a tag or digest.", consts.ImageShareManager, consts.CmdOptShare, consts.ShareProtocolSMB))
+	cmd.Flags().StringVar(&replicaExporter.ShareUsername, consts.CmdOptShareUsername, consts.ShareUsernameDefault, "User allowed to access the SMB share.")
+	cmd.Flags().StringVar(&replicaExporter.ShareSecret, consts.CmdOptShareSecret, "", fmt.Sprintf("Secret in the namespace of --%s holding the password of the user of the SMB share in its %s key. Required with --%s=%s.", consts.CmdOptNamespace, consts.EnvSharePassword, consts.CmdOptShare, consts.ShareProtocolSMB))
+	cmd.Flags().StringVar(&replicaArchiver.Format, consts.CmdOptArchive, "", fmt.Sprintf("Package the replica data directory in a local archive instead of exporting its data (%s, %s).", replica.ArchiveFormatTarZstd, replica.ArchiveFormatTarGzip))
+	cmd.Flags().StringVar(&replicaArchiver.NodeID, consts.CmdOptNode, "", fmt.Sprintf("Name of the node of the replica data directory to archive. Required with --%s.", consts.CmdOptArchive))
+	cmd.Flags().StringVar(&replicaArchiver.Output, consts.CmdOptOutput, "", fmt.Sprintf("Local path of the archive. Required with --%s.", consts.CmdOptArchive))
+
+	longhornNamespace := consts.LonghornNamespace
+	_ = cmd.RegisterFlagCompletionFunc(consts.CmdOptName, completeReplicaDirectoryNames(globalOpts, &longhornNamespace))
 
 	return cmd
 }
@@ -114,13 +209,18 @@ func newCmdExportReplicaStop(globalOpts *types.GlobalCmdOptions) *cobra.Command
 	cmd := &cobra.Command{
 		Use:   consts.SubCmdStop,
 		Short: "Stop the replica export process",
-		Long:  `This command terminates the ongoing replica export process and stops the replica exporter.`,
+		Long:  `This command terminates the ongoing replica export process and stops the replica exporter. With --volumes, the replica exporter of each of the volumes is stopped.`,
 		Example: `$ longhornctl export replica --name=pvc-48a6457d-585e-423b-b530-bbc68a5f948a-0e2603a7 --target-dir=/tmp/export stop
 INFO[2024-07-16T17:29:14+08:00] Stopping replica exporter
-INFO[2024-07-16T17:29:14+08:00] Successfully stopped exporting replica`,
+INFO[2024-07-16T17:29:14+08:00] Successfully stopped exporting replica
+
+$ longhornctl export replica --volumes=pvc-7b3a1c2e-9f4d-4e0a-8c6b-2d1e5f7a9b3c stop`,
+
+		Annotations: map[string]string{consts.CmdAnnotationAudit: "true"},
 
 		PreRun: func(cmd *cobra.Command, args []string) {
 			replicaExporter.KubeConfigPath = globalOpts.KubeConfigPath
+			replicaExporter.Namespace = globalOpts.Namespace
 
 			if err := replicaExporter.Init(); err != nil {
 				utils.CheckErr(errors.Wrap(err, "Failed to initialize replica exporter"))
@@ -148,6 +248,19 @@ INFO[2024-07-16T17:29:14+08:00] Successfully stopped exporting replica`,
 	utils.SetFlagHidden(cmd, consts.CmdOptName)
 	utils.SetFlagHidden(cmd, consts.CmdOptLonghornDataDirectory)
 	utils.SetFlagHidden(cmd, consts.CmdOptTargetDirectory)
+	utils.SetFlagHidden(cmd, consts.CmdOptConcurrency)
+	utils.SetFlagHidden(cmd, consts.CmdOptReadOnly)
+	utils.SetFlagHidden(cmd, consts.CmdOptSnapshot)
+	utils.SetFlagHidden(cmd, consts.CmdOptShare)
+	utils.SetFlagHidden(cmd, consts.CmdOptShareAllowedCIDRs)
+	utils.SetFlagHidden(cmd, consts.CmdOptShareImage)
+	utils.SetFlagHidden(cmd, consts.CmdOptShareUsername)
+	utils.SetFlagHidden(cmd, consts.CmdOptShareSecret)
+	utils.SetFlagHidden(cmd, consts.CmdOptArchive)
+	utils.SetFlagHidden(cmd, consts.CmdOptNode)
+	utils.SetFlagHidden(cmd, consts.CmdOptOutput)
+
+	cmd.Flags().StringVar(&replicaExporter.Volumes, consts.CmdOptVolumes, "", fmt.Sprintf("Comma-separated (%s) list of the volumes to stop exporting a replica of.", consts.CmdOptSeperator))
 
 	return cmd
 }
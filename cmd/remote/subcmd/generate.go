@@ -0,0 +1,292 @@
+package subcmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	lhmgrtypes "github.com/longhorn/longhorn-manager/types"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/remote/airgap"
+	"github.com/longhorn/cli/pkg/remote/job"
+	"github.com/longhorn/cli/pkg/remote/monitoring"
+	"github.com/longhorn/cli/pkg/remote/velero"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+)
+
+func NewCmdGenerate(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdGenerate,
+		Short: "Generate manifests for Longhorn operations",
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.AddCommand(newCmdGenerateAirgapBundle(globalOpts))
+	cmd.AddCommand(newCmdGenerateJob(globalOpts))
+	cmd.AddCommand(newCmdGenerateMonitoring(globalOpts))
+	cmd.AddCommand(newCmdGenerateServiceMonitor(globalOpts))
+	cmd.AddCommand(newCmdGenerateVeleroConfig(globalOpts))
+
+	return cmd
+}
+
+func newCmdGenerateAirgapBundle(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var bundleGenerator = airgap.BundleGenerator{}
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdAirgapBundle,
+		Short: "Generate the offline bundle of the preflight install for nodes without internet access",
+		Long: `This command generates a tar.gz bundle for the preflight install of nodes without internet access, containing:
+- ` + consts.FileNameAirgapManifest + `: the packages, kernel modules and services of the preflight install for the operating system.
+- ` + consts.AirgapPackagesDirectory + `/` + consts.FileNameAirgapDownloadScript + `: a script downloading the packages with their dependencies with the package manager of the operating system. Run it on a host with internet access and the same release of the operating system as the nodes, such as a fresh container.
+- ` + consts.AirgapImagesDirectory + `/` + consts.FileNameAirgapImageArchive + `: the utility image of --` + consts.CmdOptImage + ` for --` + consts.CmdOptPlatform + `, in the docker save format, unless --` + consts.CmdOptSkipImage + ` is set.
+- ` + consts.FileNameAirgapReadme + `: the steps of the install.
+
+Once the packages are downloaded, copy the ` + consts.AirgapPackagesDirectory + ` directory to the same path on the nodes, load the image to the private registry or the container runtime of the nodes, and run "longhornctl install preflight --` + consts.CmdOptPackageSource + `=<path>".
+The kernel modules are provided by the kernel of the nodes, so they are not in the bundle.`,
+		Example: `$ longhornctl generate airgap-bundle --os=ubuntu --platform=linux/amd64
+$ tar -xzf longhorn-airgap-bundle-ubuntu.tar.gz
+$ docker run --rm -v "$PWD/longhorn-airgap-bundle:/bundle" ubuntu:22.04 /bundle/packages/download-packages.sh
+$ longhornctl install preflight --image=registry.local/longhornio/longhorn-cli:v1.8.0 --package-source=/opt/longhorn/packages`,
+		Args: cobra.NoArgs,
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			bundleGenerator.Image = globalOpts.Image
+			bundleGenerator.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(bundleGenerator.Validate())
+
+			if err := bundleGenerator.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize air-gap bundle generator"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			manifest, err := bundleGenerator.Run()
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to generate air-gap bundle"))
+			}
+
+			logrus.Infof("Generated air-gap bundle %v for %v with packages %v", bundleGenerator.OutputFilePath, manifest.OperatingSystem, strings.Join(manifest.Packages, ", "))
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVar(&bundleGenerator.OperatingSystem, consts.CmdOptOS, "", fmt.Sprintf("Operating system of the nodes (%s).", strings.Join(airgap.SupportedOperatingSystems, ", ")))
+	cmd.Flags().StringVar(&bundleGenerator.OutputFilePath, consts.CmdOptOutputFile, "", "Path of the bundle. Defaults to "+consts.AirgapBundleDirectory+"-<os>.tar.gz in the current directory.")
+	cmd.Flags().StringVar(&bundleGenerator.Platform, consts.CmdOptPlatform, "linux/amd64", "Platform of the utility image in the bundle.")
+	cmd.Flags().BoolVar(&bundleGenerator.EnableSpdk, consts.CmdOptEnableSpdk, false, "Include the kernel modules of SPDK in the requirements of the bundle.")
+	cmd.Flags().BoolVar(&bundleGenerator.SkipImage, consts.CmdOptSkipImage, false, "Do not include the utility image, such as when it is already mirrored to the private registry.")
+
+	return cmd
+}
+
+func newCmdGenerateJob(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var jobGenerator = job.Generator{}
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdJob + " -- <subcommand> [options]",
+		Short: "Generate a Job manifest running a longhornctl subcommand in the cluster",
+		Long: `This command generates a Kubernetes Job manifest, along with the ServiceAccount and RBAC it requires, that runs the given longhornctl subcommand inside the cluster.
+The Job uses the in-cluster config, so no kubeconfig is needed. This allows operations to be triggered by GitOps pipelines.
+
+The global options of this command (--image, --namespace, --log-level, --log-format, --node-selector) are forwarded to the subcommand.`,
+		Example: `$ longhornctl generate job -- check preflight --enable-spdk | kubectl apply -f -
+$ kubectl -n longhorn-system logs -f job/longhornctl-job-check-preflight`,
+		Args: cobra.MinimumNArgs(1),
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			jobGenerator.Image = globalOpts.Image
+			jobGenerator.LogLevel = globalOpts.LogLevel
+			jobGenerator.LogFormat = globalOpts.LogFormat
+			jobGenerator.Namespace = globalOpts.Namespace
+			jobGenerator.NodeSelector = globalOpts.NodeSelector
+			jobGenerator.MinNodes = globalOpts.MinNodes
+			jobGenerator.PodCpu = globalOpts.PodCpu
+			jobGenerator.PodMemory = globalOpts.PodMemory
+			jobGenerator.PriorityClass = globalOpts.PriorityClass
+			jobGenerator.Proxy = globalOpts.Proxy
+			jobGenerator.NoProxy = globalOpts.NoProxy
+			jobGenerator.Privileged = globalOpts.Privileged
+			jobGenerator.Args = args
+
+			utils.CheckErr(jobGenerator.Validate())
+
+			if err := jobGenerator.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize job generator"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			output, err := jobGenerator.Run()
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to generate job"))
+			}
+
+			fmt.Print(output)
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVar(&jobGenerator.Name, consts.CmdOptName, "", "Name of the Job and its RBAC resources. Defaults to the subcommand prefixed with "+consts.AppNameJob+".")
+
+	return cmd
+}
+
+func newCmdGenerateMonitoring(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var monitoringGenerator = monitoring.Generator{}
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdMonitoring,
+		Short: "Generate Prometheus alerting rules or a Grafana dashboard for the Longhorn metrics",
+		Long: `This command generates curated monitoring definitions for the metrics of the installed Longhorn version:
+- ` + consts.MonitoringFormatPrometheusRules + `: a PrometheusRule of the Prometheus Operator alerting on degraded and faulted volumes, nodes down, node and disk storage pressure, and failed backups.
+- ` + consts.MonitoringFormatGrafanaJSON + `: a Grafana dashboard of the volume robustness, size and performance, the node and disk usage, and the failed backups.
+
+The rules and panels are limited to the metrics exposed by the Longhorn version, read from the cluster unless --` + consts.CmdOptLonghornVersion + ` is given. The metrics are selected by the label carrying the Longhorn namespace, which depends on the relabeling of the Prometheus scrape configuration.`,
+		Example: `$ longhornctl generate monitoring --format=prometheus-rules --labels=release=kube-prometheus-stack | kubectl apply -f -
+$ longhornctl generate monitoring --format=grafana-json --longhorn-version=v1.7.2 > longhorn-dashboard.json`,
+		Args: cobra.NoArgs,
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			monitoringGenerator.KubeConfigPath = globalOpts.KubeConfigPath
+			monitoringGenerator.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(monitoringGenerator.Validate())
+
+			if err := monitoringGenerator.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize monitoring generator"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			output, err := monitoringGenerator.Run()
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to generate monitoring definitions"))
+			}
+
+			fmt.Print(output)
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVar(&monitoringGenerator.Format, consts.CmdOptFormat, consts.MonitoringFormatPrometheusRules, fmt.Sprintf("Format of the definitions (%s, %s).", consts.MonitoringFormatPrometheusRules, consts.MonitoringFormatGrafanaJSON))
+	cmd.Flags().StringVar(&monitoringGenerator.Name, consts.CmdOptName, consts.MonitoringName, "Name of the PrometheusRule and its rule group, or UID of the dashboard.")
+	cmd.Flags().StringVar(&monitoringGenerator.LonghornNamespace, consts.CmdOptLonghornNamespace, consts.LonghornNamespace, "Namespace where Longhorn is deployed. The PrometheusRule is created in it.")
+	cmd.Flags().StringVar(&monitoringGenerator.LonghornVersion, consts.CmdOptLonghornVersion, "", "Longhorn version to generate the definitions for. Defaults to the version installed in the cluster.")
+	cmd.Flags().StringVar(&monitoringGenerator.NamespaceLabel, consts.CmdOptNamespaceLabel, consts.MonitoringNamespaceLabel, "Label carrying the Longhorn namespace in the metrics series.")
+	cmd.Flags().StringVar(&monitoringGenerator.Labels, consts.CmdOptLabels, "", "Labels of the PrometheusRule, for example to match the rule selector of Prometheus (e.g. \"release=kube-prometheus-stack\").")
+
+	return cmd
+}
+
+func newCmdGenerateServiceMonitor(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var serviceMonitorGenerator = monitoring.ServiceMonitorGenerator{}
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdServiceMonitor,
+		Short: "Generate the ServiceMonitor and RBAC for the Prometheus Operator to scrape the Longhorn managers",
+		Long: `This command generates the ServiceMonitor scraping the metrics of the Longhorn managers through the longhorn-backend Service, or a PodMonitor scraping the Longhorn manager pods with --` + consts.CmdOptPodMonitor + `, along with the Role and RoleBinding allowing Prometheus to discover them in the Longhorn namespace.
+
+The command checks the Prometheus Operator custom resources are installed in the cluster. With --` + consts.CmdOptApply + `, the resources are also applied to the cluster with server-side apply.
+
+Prometheus selects the monitors matching its serviceMonitorSelector or podMonitorSelector, for example the label release=kube-prometheus-stack for a Helm release of kube-prometheus-stack. Use --` + consts.CmdOptLabels + ` to add the labels it selects.`,
+		Example: `$ longhornctl generate servicemonitor --labels=release=kube-prometheus-stack --prometheus-service-account=monitoring/kube-prometheus-stack-prometheus | kubectl apply -f -
+$ longhornctl generate servicemonitor --pod-monitor --apply`,
+		Args: cobra.NoArgs,
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			serviceMonitorGenerator.KubeConfigPath = globalOpts.KubeConfigPath
+			serviceMonitorGenerator.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(serviceMonitorGenerator.Validate())
+
+			if err := serviceMonitorGenerator.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize ServiceMonitor generator"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			output, err := serviceMonitorGenerator.Run()
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to generate ServiceMonitor"))
+			}
+
+			if !serviceMonitorGenerator.Apply {
+				fmt.Print(output)
+			}
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVar(&serviceMonitorGenerator.LonghornNamespace, consts.CmdOptLonghornNamespace, consts.LonghornNamespace, "Namespace where Longhorn is deployed. The resources are created in it.")
+	cmd.Flags().BoolVar(&serviceMonitorGenerator.PodMonitor, consts.CmdOptPodMonitor, false, "Generate a PodMonitor scraping the Longhorn manager pods instead of a ServiceMonitor.")
+	cmd.Flags().StringVar(&serviceMonitorGenerator.PrometheusServiceAccount, consts.CmdOptPrometheusAccount, consts.MonitoringPrometheusServiceAccount, "Service account of Prometheus bound to the Role, as <namespace>/<name>.")
+	cmd.Flags().StringVar(&serviceMonitorGenerator.Labels, consts.CmdOptLabels, "", "Labels of the ServiceMonitor or PodMonitor, for example to match the monitor selector of Prometheus (e.g. \"release=kube-prometheus-stack\").")
+	cmd.Flags().BoolVar(&serviceMonitorGenerator.Apply, consts.CmdOptApply, false, "Apply the resources to the cluster instead of printing them.")
+
+	return cmd
+}
+
+func newCmdGenerateVeleroConfig(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var veleroConfigGenerator = velero.ConfigGenerator{}
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdVeleroConfig,
+		Short: "Generate the Velero configuration backing up the Longhorn volumes",
+		Long: `This command inspects the Longhorn backup target and generates the Velero configuration backing up the Longhorn PVCs with CSI snapshots:
+- A BackupStorageLocation named ` + consts.VeleroBackupStorageLocation + ` for the AWS plugin of Velero, storing the Velero backups in the velero directory beside the backupstore of an S3 backup target, with its endpoint and CA certificate. It reads the credentials from the secret ` + consts.VeleroCredentialSecret + `, generated from the credential secret of the backup target with --` + consts.CmdOptIncludeCredentials + `. The output then contains the credentials.
+- A VolumeSnapshotClass named ` + consts.VeleroVolumeSnapshotClass + ` of the Longhorn CSI driver, with the ` + consts.VeleroLabelCSIVolumeSnapshotClass + `=true label Velero selects it with. With the default --` + consts.CmdOptSnapshotType + ` ` + consts.CSISnapshotTypeBackup + `, Velero backs up the volumes to the Longhorn backup target. With ` + consts.CSISnapshotTypeSnapshot + `, it takes Longhorn snapshots, lost with the volumes.
+
+No VolumeSnapshotLocation is generated, since Velero does not use one for CSI snapshots. The Velero server requires --features=` + consts.VeleroFeatureCSI + `, use "longhornctl check ` + consts.SubCmdVelero + `" to validate the installation.
+
+With --` + consts.CmdOptApply + `, the resources are also applied to the cluster with server-side apply.`,
+		Example: `$ longhornctl generate velero-config --include-credentials | kubectl apply -f -
+$ longhornctl generate velero-config --apply
+$ velero backup create nightly --include-namespaces db --storage-location longhorn`,
+		Args: cobra.NoArgs,
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			veleroConfigGenerator.KubeConfigPath = globalOpts.KubeConfigPath
+			veleroConfigGenerator.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(veleroConfigGenerator.Validate())
+
+			if err := veleroConfigGenerator.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize Velero configuration generator"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			output, err := veleroConfigGenerator.Run()
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to generate Velero configuration"))
+			}
+
+			if !veleroConfigGenerator.Apply {
+				fmt.Print(output)
+			}
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVar(&veleroConfigGenerator.LonghornNamespace, consts.CmdOptLonghornNamespace, consts.LonghornNamespace, "Namespace where Longhorn is deployed.")
+	cmd.Flags().StringVar(&veleroConfigGenerator.VeleroNamespace, consts.CmdOptVeleroNamespace, consts.VeleroNamespace, "Namespace where Velero is deployed. The BackupStorageLocation and its credential secret are created in it.")
+	cmd.Flags().StringVar(&veleroConfigGenerator.BackupTarget, consts.CmdOptBackupTarget, lhmgrtypes.DefaultBackupTargetName, "Name of the Longhorn backup target to store the Velero backups beside.")
+	cmd.Flags().StringVar(&veleroConfigGenerator.SnapshotType, consts.CmdOptSnapshotType, consts.CSISnapshotTypeBackup, fmt.Sprintf("Type of the CSI snapshots Velero takes (%s for Longhorn backups, %s for Longhorn snapshots).", consts.CSISnapshotTypeBackup, consts.CSISnapshotTypeSnapshot))
+	cmd.Flags().BoolVar(&veleroConfigGenerator.IncludeCredentials, consts.CmdOptIncludeCredentials, false, "Generate the credential secret of the BackupStorageLocation from the credential secret of the backup target.")
+	cmd.Flags().BoolVar(&veleroConfigGenerator.Apply, consts.CmdOptApply, false, "Apply the resources to the cluster instead of printing them.")
+
+	return cmd
+}
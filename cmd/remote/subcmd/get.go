@@ -1,11 +1,17 @@
 package subcmd
 
 import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
 	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/remote/instancemanager"
 	"github.com/longhorn/cli/pkg/remote/replica"
 	"github.com/longhorn/cli/pkg/types"
 	"github.com/longhorn/cli/pkg/utils"
@@ -19,6 +25,7 @@ func NewCmdGet(globalOpts *types.GlobalCmdOptions) *cobra.Command {
 
 	utils.SetGlobalOptionsRemote(cmd, globalOpts)
 
+	cmd.AddCommand(newCmdGetInstanceManager(globalOpts))
 	cmd.AddCommand(newCmdGetReplica(globalOpts))
 
 	return cmd
@@ -64,7 +71,16 @@ INFO[2024-07-16T17:23:51+08:00] Completed replica getter`,
 		PreRun: func(cmd *cobra.Command, args []string) {
 			replicaGetter.Image = globalOpts.Image
 			replicaGetter.KubeConfigPath = globalOpts.KubeConfigPath
+			replicaGetter.Namespace = globalOpts.Namespace
 			replicaGetter.NodeSelector = globalOpts.NodeSelector
+			replicaGetter.MinNodes = globalOpts.MinNodes
+			replicaGetter.PodCpu = globalOpts.PodCpu
+			replicaGetter.PodMemory = globalOpts.PodMemory
+			replicaGetter.PriorityClass = globalOpts.PriorityClass
+			replicaGetter.Proxy = globalOpts.Proxy
+			replicaGetter.NoProxy = globalOpts.NoProxy
+			replicaGetter.Privileged = globalOpts.Privileged
+			replicaGetter.LogLevel = globalOpts.LogLevel
 
 			logrus.Info("Initializing replica getter")
 			if err := replicaGetter.Init(); err != nil {
@@ -84,7 +100,7 @@ INFO[2024-07-16T17:23:51+08:00] Completed replica getter`,
 				utils.CheckErr(errors.Wrap(err, "Failed to run replica getter"))
 			}
 
-			logrus.Infof("Retrieved replica information:\n %v", output)
+			utils.PrintResult(globalOpts, "Retrieved replica information", output)
 		},
 
 		PostRun: func(cmd *cobra.Command, args []string) {
@@ -103,5 +119,136 @@ INFO[2024-07-16T17:23:51+08:00] Completed replica getter`,
 	cmd.Flags().StringVar(&replicaGetter.VolumeName, consts.CmdOptLonghornVolumeName, "", "Specify the name of the volume to retrieve replica information.")
 	cmd.Flags().StringVar(&replicaGetter.LonghornDataDirectory, consts.CmdOptLonghornDataDirectory, "/var/lib/longhorn", "Specify the Longhorn data directory. If not provided, the default will be attempted, or it will fall back to the directory of longhorn-disk.cfg.")
 
+	longhornNamespace := consts.LonghornNamespace
+	_ = cmd.RegisterFlagCompletionFunc(consts.CmdOptName, completeReplicaDirectoryNames(globalOpts, &longhornNamespace))
+	_ = cmd.RegisterFlagCompletionFunc(consts.CmdOptLonghornVolumeName, completeVolumeNames(globalOpts, &longhornNamespace))
+
+	return cmd
+}
+
+func newCmdGetInstanceManager(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var instanceManagerGetter = instancemanager.Getter{}
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdInstanceManager,
+		Short: "Retrieve Longhorn instance manager information",
+		Long: `This command retrieves the instance managers with their type, data engine, state and API version, the CPU request of their pods against the CPU guaranteed by the settings, and the engine and replica instances they host.
+
+The guaranteed CPU is the guaranteed-instance-manager-cpu setting, as a percentage of the allocatable CPU of the node, or the instance manager CPU request of the Longhorn node when set. For the V2 data engine, it is the v2-data-engine-guaranteed-instance-manager-cpu setting. A pod whose CPU request differs is flagged, usually because it has not been recreated since the setting changed.
+
+With --` + consts.CmdOptInspect + `, the processes running in each instance manager pod are listed with their state.`,
+		Example: `$ longhornctl get instance-manager --node-id=ip-10-0-2-123
+INFO[2024-07-16T17:23:47+08:00] Initializing instance manager getter
+INFO[2024-07-16T17:23:47+08:00] Running instance manager getter
+NAME                                                NODE           DATA ENGINE  STATE    API  CPU REQUEST  GUARANTEED CPU  ENGINES  REPLICAS
+instance-manager-3b5c4f9a6d2e8f7c1a0b9e8d7c6f5a4b  ip-10-0-2-123  v1           running  5    480m         480m            1        2
+
+INSTANCE MANAGER                                    INSTANCE                                             TYPE     STATE    PORTS        ERROR
+instance-manager-3b5c4f9a6d2e8f7c1a0b9e8d7c6f5a4b  pvc-0b1c6f4a-7e0a-4a7b-9d2e-5f1f3f0c2e11-e-0         engine   running  10000-10000
+instance-manager-3b5c4f9a6d2e8f7c1a0b9e8d7c6f5a4b  pvc-0b1c6f4a-7e0a-4a7b-9d2e-5f1f3f0c2e11-r-8a2b3c4d  replica  running  10010-10019
+instance-manager-3b5c4f9a6d2e8f7c1a0b9e8d7c6f5a4b  pvc-6d1e2f3a-4b5c-4d6e-8f7a-9b0c1d2e3f4a-r-1f2e3d4c  replica  running  10020-10029
+INFO[2024-07-16T17:23:48+08:00] Completed instance manager getter`,
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			instanceManagerGetter.KubeConfigPath = globalOpts.KubeConfigPath
+			instanceManagerGetter.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(utils.ValidateOutputFormat(outputFormat, consts.OutputFormatJSON, consts.OutputFormatYAML))
+
+			logrus.Info("Initializing instance manager getter")
+			if err := instanceManagerGetter.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize instance manager getter"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Running instance manager getter")
+			infos, err := instanceManagerGetter.Run()
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to run instance manager getter"))
+			}
+
+			utils.CheckErr(printInstanceManagerInfos(infos, outputFormat))
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Completed instance manager getter")
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&outputFormat, consts.CmdOptOutput, "o", "", fmt.Sprintf("Output format (%s, %s). Defaults to tables.", consts.OutputFormatJSON, consts.OutputFormatYAML))
+	cmd.Flags().StringVar(&instanceManagerGetter.LonghornNamespace, consts.CmdOptLonghornNamespace, consts.LonghornNamespace, "Namespace where Longhorn is deployed.")
+	cmd.Flags().StringVar(&instanceManagerGetter.Name, consts.CmdOptName, "", "Specify the name of the instance manager to retrieve information.")
+	cmd.Flags().StringVar(&instanceManagerGetter.NodeID, consts.CmdOptNodeId, "", "Specify the node to retrieve the instance managers of.")
+	cmd.Flags().BoolVar(&instanceManagerGetter.Inspect, consts.CmdOptInspect, false, "List the processes running in each instance manager pod.")
+
 	return cmd
 }
+
+func printInstanceManagerInfos(infos []types.InstanceManagerInfo, outputFormat string) error {
+	if printed, err := utils.PrintStructuredResult(outputFormat, types.ResultKindInstanceManagerList, infos); printed || err != nil {
+		return err
+	}
+
+	orNone := func(value string) string {
+		if value == "" {
+			return "-"
+		}
+		return value
+	}
+
+	mismatch := false
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "NAME\tNODE\tDATA ENGINE\tSTATE\tAPI\tCPU REQUEST\tGUARANTEED CPU\tENGINES\tREPLICAS")
+	for _, info := range infos {
+		engines, replicas := 0, 0
+		for _, instance := range info.Instances {
+			switch instance.Type {
+			case "engine":
+				engines++
+			case "replica":
+				replicas++
+			}
+		}
+
+		cpuRequest := orNone(info.CPURequest)
+		if info.CPURequest != info.GuaranteedCPU {
+			cpuRequest += " (*)"
+			mismatch = true
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%d\t%s\t%s\t%d\t%d\n", info.Name, info.Node, info.DataEngine, info.State, info.APIVersion, cpuRequest, orNone(info.GuaranteedCPU), engines, replicas)
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	if mismatch {
+		fmt.Println("(*) The CPU request differs from the guaranteed CPU. The pod is recreated when it no longer hosts any instance.")
+	}
+
+	fmt.Println()
+	writer = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "INSTANCE MANAGER\tINSTANCE\tTYPE\tSTATE\tPORTS\tERROR")
+	for _, info := range infos {
+		for _, instance := range info.Instances {
+			ports := "-"
+			if instance.PortStart != 0 {
+				ports = fmt.Sprintf("%d-%d", instance.PortStart, instance.PortEnd)
+			}
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\n", info.Name, instance.Name, instance.Type, instance.State, ports, strings.ReplaceAll(instance.Error, "\n", " "))
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+
+	for _, info := range infos {
+		if info.Processes == "" {
+			continue
+		}
+		fmt.Printf("\nPROCESSES OF %s\n%s", info.Name, info.Processes)
+	}
+	return nil
+}
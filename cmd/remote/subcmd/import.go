@@ -0,0 +1,110 @@
+package subcmd
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/remote/replica"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+)
+
+func NewCmdImport(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdImport,
+		Short: "Import Longhorn resources",
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.AddCommand(newCmdImportReplica(globalOpts))
+
+	return cmd
+}
+
+func newCmdImportReplica(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var replicaImporter = replica.Importer{}
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdReplica,
+		Short: "Import a Longhorn replica data directory from an archive",
+		Long: `This command restores a replica data directory archived with 'longhornctl export replica --archive' to the Longhorn data directory of a node, possibly of another cluster, for recovering its volume.
+
+The archive is verified against its checksum file (<input>.sha256) when there is one, before anything is written to the node. It is then streamed to a pod on the node, and extracted to a staging directory under the replicas directory of the node. The replica data directory is only moved in place once all its files match the SHA256SUMS manifest of the archive, and never overwrites an existing directory.
+
+The replica data directory keeps its name in the archive unless --name is set. The format of the archive is inferred from its extension unless --archive is set.
+
+The imported directory is not known to Longhorn. Export its data with 'longhornctl export replica', or use it to recover the volume.`,
+		Example: `$ longhornctl import replica --input=./vol-backup.tar.zst --node=ip-10-0-3-45
+INFO[2024-07-16T17:45:02+08:00] Initializing replica importer
+INFO[2024-07-16T17:45:02+08:00] Cleaning up replica importer
+INFO[2024-07-16T17:45:02+08:00] Running replica importer
+INFO[2024-07-16T17:45:09+08:00] Importing ./vol-backup.tar.zst to /var/lib/longhorn/replicas  node=ip-10-0-3-45
+Imported ./vol-backup.tar.zst to replica pvc-48a6457d-585e-423b-b530-bbc68a5f948a-0e2603a7 of node ip-10-0-3-45
+INFO[2024-07-16T17:45:40+08:00] Cleaning up replica importer
+INFO[2024-07-16T17:45:40+08:00] Completed replica importer`,
+
+		Annotations: map[string]string{consts.CmdAnnotationAudit: "true"},
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			replicaImporter.Image = globalOpts.Image
+			replicaImporter.KubeConfigPath = globalOpts.KubeConfigPath
+			replicaImporter.Namespace = globalOpts.Namespace
+			replicaImporter.NodeSelector = globalOpts.NodeSelector
+			replicaImporter.MinNodes = globalOpts.MinNodes
+			replicaImporter.PodCpu = globalOpts.PodCpu
+			replicaImporter.PodMemory = globalOpts.PodMemory
+			replicaImporter.PriorityClass = globalOpts.PriorityClass
+			replicaImporter.Proxy = globalOpts.Proxy
+			replicaImporter.NoProxy = globalOpts.NoProxy
+			replicaImporter.Privileged = globalOpts.Privileged
+			replicaImporter.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(replicaImporter.Validate())
+			utils.CheckErr(utils.Confirm(globalOpts, fmt.Sprintf("This will write the replica data directory of %s to node %s.", replicaImporter.Input, replicaImporter.NodeID)))
+
+			logrus.Info("Initializing replica importer")
+			if err := replicaImporter.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize replica importer"))
+			}
+
+			logrus.Info("Cleaning up replica importer")
+			if err := replicaImporter.Cleanup(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to cleanup replica importer"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Running replica importer")
+			result, err := replicaImporter.Run()
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to run replica importer"))
+			}
+
+			fmt.Printf("Imported %s to replica %s of node %s\n", result.File, result.Replica, result.Node)
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Cleaning up replica importer")
+			if err := replicaImporter.Cleanup(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to cleanup replica importer"))
+			}
+
+			logrus.Info("Completed replica importer")
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVar(&replicaImporter.Input, consts.CmdOptInput, "", "Local path of the archive to import.")
+	cmd.Flags().StringVar(&replicaImporter.NodeID, consts.CmdOptNode, "", "Name of the node to import the replica data directory to.")
+	cmd.Flags().StringVar(&replicaImporter.Format, consts.CmdOptArchive, "", fmt.Sprintf("Format of the archive (%s, %s). Defaults to the extension of --%s.", replica.ArchiveFormatTarZstd, replica.ArchiveFormatTarGzip, consts.CmdOptInput))
+	cmd.Flags().StringVar(&replicaImporter.ReplicaName, consts.CmdOptName, "", "Name of the imported replica data directory. Defaults to the name in the archive.")
+	cmd.Flags().StringVar(&replicaImporter.LonghornDataDirectory, consts.CmdOptLonghornDataDirectory, "/var/lib/longhorn", "Longhorn data directory of the node.")
+
+	return cmd
+}
@@ -0,0 +1,88 @@
+package subcmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/remote/setup"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+)
+
+func NewCmdInit(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var setupWizard = setup.Wizard{}
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdInit,
+		Short: "Set up Longhorn interactively for the first time",
+		Long: `This command walks you through the first installation of Longhorn on the cluster:
+1. It runs the preflight check of "` + consts.CmdLonghornctlRemote + ` ` + consts.SubCmdCheck + ` ` + consts.SubCmdPreflight + `" on the nodes. When nodes fail the check, it offers to install the missing packages, kernel modules and services as "` + consts.CmdLonghornctlRemote + ` ` + consts.SubCmdInstall + ` ` + consts.SubCmdPreflight + `" does, and checks again. Use --` + consts.CmdOptSkipPreflight + ` to skip this step.
+2. It asks for the default number of replicas of the volumes, defaulting to the number of nodes up to 3, the backup target with its credential secret, and the storage network, a Multus NetworkAttachmentDefinition as <namespace>/<name>.
+3. It prints the values of the Longhorn Helm chart for the answers, or writes them to --` + consts.CmdOptOutputFile + `. With --` + consts.CmdOptLonghornVersion + ` older than v1.8.0, the backup target is set in defaultSettings instead of defaultBackupStore.
+
+With --` + consts.CmdOptApply + `, Longhorn is then installed, or upgraded, by running:
+  ` + consts.HelmBinary + ` upgrade --install ` + consts.HelmReleaseLonghorn + ` ` + consts.HelmChartLonghorn + ` --repo ` + consts.HelmRepoLonghorn + ` --namespace <longhorn-namespace> --create-namespace --values <values>
+
+The command requires an interactive terminal. The questions are asked on stderr, so the values printed on stdout can be redirected to a file.`,
+		Example: `$ longhornctl init --output-file=longhorn-values.yaml
+$ longhornctl init --longhorn-version=v1.7.2 --apply`,
+		Args: cobra.NoArgs,
+
+		Annotations: map[string]string{consts.CmdAnnotationAudit: "true", consts.CmdAnnotationSync: "true"},
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			setupWizard.Image = globalOpts.Image
+			setupWizard.KubeConfigPath = globalOpts.KubeConfigPath
+			setupWizard.Namespace = globalOpts.Namespace
+			setupWizard.NodeSelector = globalOpts.NodeSelector
+			setupWizard.MinNodes = globalOpts.MinNodes
+			setupWizard.PodCpu = globalOpts.PodCpu
+			setupWizard.PodMemory = globalOpts.PodMemory
+			setupWizard.PriorityClass = globalOpts.PriorityClass
+			setupWizard.Proxy = globalOpts.Proxy
+			setupWizard.NoProxy = globalOpts.NoProxy
+			setupWizard.Privileged = globalOpts.Privileged
+			setupWizard.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(setupWizard.Validate())
+			if !utils.IsTerminal(os.Stdin) {
+				utils.CheckErr(errors.Errorf("%s requires an interactive terminal", consts.SubCmdInit))
+			}
+
+			logrus.Info("Initializing setup wizard")
+			if err := setupWizard.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize setup wizard"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			values, err := setupWizard.Run()
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to run setup wizard"))
+			}
+
+			if setupWizard.OutputFile == "" {
+				fmt.Print(values)
+			}
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Completed setup wizard")
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVar(&setupWizard.LonghornNamespace, consts.CmdOptLonghornNamespace, consts.LonghornNamespace, "Namespace to install Longhorn in.")
+	cmd.Flags().StringVar(&setupWizard.LonghornVersion, consts.CmdOptLonghornVersion, "", "Longhorn version to install, for example v1.7.2. Defaults to the latest one.")
+	cmd.Flags().StringVar(&setupWizard.OutputFile, consts.CmdOptOutputFile, "", "Write the Helm values to the file instead of stdout.")
+	cmd.Flags().BoolVar(&setupWizard.Apply, consts.CmdOptApply, false, "Install or upgrade Longhorn with "+consts.HelmBinary+" and the generated values.")
+	cmd.Flags().BoolVar(&setupWizard.SkipPreflight, consts.CmdOptSkipPreflight, false, "Skip the preflight check of the nodes.")
+
+	return cmd
+}
@@ -0,0 +1,183 @@
+package subcmd
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/remote/replica"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+)
+
+func NewCmdInspect(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdInspect,
+		Short: "Longhorn on-disk data inspection operations",
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.AddCommand(newCmdInspectReplicaMeta(globalOpts))
+
+	return cmd
+}
+
+func newCmdInspectReplicaMeta(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var replicaInspector = replica.Inspector{}
+	var outputFormat string
+	var hasErrors bool
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdReplicaMeta,
+		Short: "Inspect the metadata files of the replicas of a volume on a node",
+		Long: `This command reads the metadata files in the data directories of the replicas of a volume on a node, and reports their inconsistencies:
+- volume.meta cannot be read, or marks the replica as failed or rebuilding.
+- The volume head image is missing, or its size differs from the volume size.
+- The parent of the volume head differs between volume.meta and the head metadata.
+- A snapshot image or metadata file of the chain of the volume head is missing.
+- Snapshot files that are not part of the chain, and temporary files left by an interrupted metadata update.
+
+With --` + consts.CmdOptRepair + `, the known-safe fixes are applied after confirmation: the temporary files are removed, and a volume head image smaller than the volume size is extended to it. The volume must be detached. The other issues are only reported, since fixing them may lose data.`,
+		Example: `$ longhornctl inspect replica-meta --volume=pvc-48a6457d-585e-423b-b530-bbc68a5f948a --node=ip-10-0-2-123
+INFO[2024-07-16T17:23:47+08:00] Initializing replica metadata inspector
+INFO[2024-07-16T17:23:47+08:00] Cleaning up replica metadata inspector
+INFO[2024-07-16T17:23:47+08:00] Running replica metadata inspector
+REPLICA                                              STATUS  MESSAGE
+pvc-48a6457d-585e-423b-b530-bbc68a5f948a-0e2603a7    ERROR   volume-snap-backup-1.img: Snapshot image file is missing, the snapshot chain is broken
+                                                     WARN    volume.meta.tmp: Temporary file left by an interrupted metadata update (repairable: remove the file)
+                                                     PASS    Head volume-head-002.img, 2 snapshots, volume size 10737418240
+
+1 replicas, 1 errors, 1 warnings
+INFO[2024-07-16T17:23:51+08:00] Cleaning up replica metadata inspector
+INFO[2024-07-16T17:23:51+08:00] Completed replica metadata inspector
+ERRO[2024-07-16T17:23:51+08:00] inconsistent replica metadata`,
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			replicaInspector.Image = globalOpts.Image
+			replicaInspector.KubeConfigPath = globalOpts.KubeConfigPath
+			replicaInspector.Namespace = globalOpts.Namespace
+			replicaInspector.PodCpu = globalOpts.PodCpu
+			replicaInspector.PodMemory = globalOpts.PodMemory
+			replicaInspector.PriorityClass = globalOpts.PriorityClass
+			replicaInspector.Proxy = globalOpts.Proxy
+			replicaInspector.NoProxy = globalOpts.NoProxy
+			replicaInspector.Privileged = globalOpts.Privileged
+			replicaInspector.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(utils.ValidateOutputFormat(outputFormat, consts.OutputFormatJSON, consts.OutputFormatYAML))
+			utils.CheckErr(replicaInspector.Validate())
+			if replicaInspector.Repair {
+				utils.CheckErr(utils.Confirm(globalOpts, fmt.Sprintf("This will repair the known-safe metadata issues of the replicas of volume %s on node %s.", replicaInspector.VolumeName, replicaInspector.NodeID)))
+			}
+
+			logrus.Info("Initializing replica metadata inspector")
+			if err := replicaInspector.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize replica metadata inspector"))
+			}
+
+			logrus.Info("Cleaning up replica metadata inspector")
+			if err := replicaInspector.Cleanup(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to cleanup replica metadata inspector"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Running replica metadata inspector")
+			collection, err := replicaInspector.Run()
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to run replica metadata inspector"))
+			}
+
+			if len(collection.Replicas) == 0 {
+				logrus.Warnf("No replica of volume %s found on node %s", replicaInspector.VolumeName, replicaInspector.NodeID)
+				return
+			}
+
+			collections := replicaMetaLogCollections(collection)
+			for _, logCollection := range collections {
+				if len(logCollection.Error) > 0 {
+					hasErrors = true
+				}
+			}
+
+			utils.CheckErr(printReplicaMetaCollection(globalOpts, collection, collections, outputFormat))
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Cleaning up replica metadata inspector")
+			if err := replicaInspector.Cleanup(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to cleanup replica metadata inspector"))
+			}
+
+			logrus.Info("Completed replica metadata inspector")
+
+			if hasErrors {
+				utils.CheckErr(errors.New("inconsistent replica metadata"))
+			}
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&outputFormat, consts.CmdOptOutput, "o", "", "Output format of the result (json, yaml). Defaults to a table on terminals, and YAML otherwise.")
+	cmd.Flags().StringVar(&replicaInspector.VolumeName, consts.CmdOptVolume, "", "Name of the Longhorn volume whose replicas are inspected.")
+	cmd.Flags().StringVar(&replicaInspector.NodeID, consts.CmdOptNode, "", "Name of the node where the replicas are inspected.")
+	cmd.Flags().BoolVar(&replicaInspector.Repair, consts.CmdOptRepair, false, "Apply the known-safe fixes after confirmation. The volume must be detached.")
+	cmd.Flags().StringVar(&replicaInspector.LonghornDataDirectory, consts.CmdOptLonghornDataDirectory, "", "Specify the Longhorn data directory. If not provided, the default will be attempted, or it will fall back to the directory of longhorn-disk.cfg.")
+	cmd.Flags().StringVar(&replicaInspector.LonghornNamespace, consts.CmdOptLonghornNamespace, consts.LonghornNamespace, "Namespace where Longhorn is deployed.")
+
+	return cmd
+}
+
+// replicaMetaLogCollections converts the replica metadata inspections to results keyed by the replica
+// data directory name. Repaired issues are reported as information.
+func replicaMetaLogCollections(collection *types.ReplicaMetaCollection) map[string]*types.LogCollection {
+	collections := map[string]*types.LogCollection{}
+	for replicaName, inspection := range collection.Replicas {
+		logCollection := &types.LogCollection{}
+		collections[replicaName] = logCollection
+
+		if inspection.Error != "" {
+			logCollection.Error = append(logCollection.Error, inspection.Error)
+			continue
+		}
+
+		for _, issue := range inspection.Issues {
+			message := issue.Message
+			if issue.File != "" {
+				message = issue.File + ": " + message
+			}
+
+			switch {
+			case issue.Repaired:
+				logCollection.Info = append(logCollection.Info, fmt.Sprintf("%s (repaired: %s)", message, issue.Repair))
+				continue
+			case issue.Repair != "":
+				message = fmt.Sprintf("%s (repairable: %s)", message, issue.Repair)
+			}
+
+			if issue.Severity == types.ReplicaMetaIssueSeverityError {
+				logCollection.Error = append(logCollection.Error, message)
+			} else {
+				logCollection.Warn = append(logCollection.Warn, message)
+			}
+		}
+
+		if inspection.Head != "" {
+			logCollection.Info = append(logCollection.Info, fmt.Sprintf("Head %s, %d snapshots, volume size %d", inspection.Head, len(inspection.Chain), inspection.Size))
+		}
+	}
+	return collections
+}
+
+func printReplicaMetaCollection(globalOpts *types.GlobalCmdOptions, collection *types.ReplicaMetaCollection, collections map[string]*types.LogCollection, outputFormat string) error {
+	if printed, err := utils.PrintStructuredResult(outputFormat, types.ResultKindReplicaMetaCollection, collection); printed || err != nil {
+		return err
+	}
+
+	return utils.PrintCollections(globalOpts, "REPLICA", "replicas", "Retrieved replica metadata inspection", "", collections)
+}
@@ -22,12 +22,14 @@ func NewCmdInstall(globalOpts *types.GlobalCmdOptions) *cobra.Command {
 	utils.SetGlobalOptionsRemote(cmd, globalOpts)
 
 	cmd.AddCommand(newCmdInstallPreflight(globalOpts))
+	cmd.AddCommand(newCmdInstallTuning(globalOpts))
 
 	return cmd
 }
 
 func newCmdInstallPreflight(globalOpts *types.GlobalCmdOptions) *cobra.Command {
 	var preflightInstaller = preflight.Installer{}
+	var outputFormat string
 
 	cmd := &cobra.Command{
 		Use:   consts.SubCmdPreflight,
@@ -36,7 +38,39 @@ func newCmdInstallPreflight(globalOpts *types.GlobalCmdOptions) *cobra.Command {
 These dependencies ensure your Kubernetes cluster meets the requirements for successful Longhorn operation.
 
 On some OS, like for example SLE Micro, after having installed the needed packages, ` + "`longhornctl`" + ` asks to the user to reboot the machine and
-to execute the install command again. During the first execution ` + "`longhornctl`" + ` install needed packages, during the second one it probes modules, start services and configure tools.`,
+to execute the install command again. During the first execution ` + "`longhornctl`" + ` install needed packages, during the second one it probes modules, start services and configure tools.
+
+Each node records the completed steps of the install in /var/lib/longhornctl/install-preflight.json on the host. Rerunning the command resumes from the first step not completed yet,
+and reports the nodes where all the steps are completed as already completed. The steps lost on reboot, like loading the kernel modules, run again after a reboot.
+A step also runs again when its inputs change, for example the packages with --enable-spdk. Use --reset-checkpoint to run all the steps again.
+
+With --reboot-strategy=rolling, the nodes needing a reboot are rebooted and the install resumed on them, --max-unavailable nodes at a time. Each node is cordoned and drained with the Eviction API,
+so the drain waits while the PodDisruptionBudgets of Longhorn protect the last healthy replica of a volume on the node. The node is then rebooted by a pod entering the namespaces of the host,
+uncordoned once it is ready with a new boot ID, and the second phase of the install runs on it before moving on to the next nodes. A node that was already cordoned is left cordoned.
+The pods of longhornctl are not evicted, and the node running longhornctl, such as the Job of "longhornctl generate job", is not rebooted, so it has to be rebooted manually.
+Like "kubectl drain", the reboot fails on a node running pods not managed by a controller, as nothing would recreate them, unless --force-drain is set to evict them anyway.
+
+With --tune-iscsid, the parameters of /etc/iscsi/iscsid.conf reported by "longhornctl check preflight" are set to their recommended values, and the CHAP parameters are commented out. The new values apply to the iSCSI sessions logged in afterwards.
+
+With --profiles, the nodes are installed with the options of the first profile of the file whose node selector matches them, such as SPDK enabled only on the nodes with NVMe disks,
+and the nodes matching no profile with the options of the command. The groups of nodes are installed in turn, and their results are merged in a single report. The options left unset in a profile keep the value of the command:
+
+profiles:
+- name: nvme
+  nodeSelector: storage=nvme
+  enableSpdk: true
+  hugePageSize: 4096
+- name: nvme-numa
+  nodeSelector: storage=nvme-numa
+  enableSpdk: true
+  hugePageNodes: 0=2048,1=2048
+
+The options of a profile are updatePackages, packageSource, tuneIscsid, enableSpdk, spdkOptions, hugePageSize, hugePageNodes, allowPci and driverOverride.
+
+With --package-source, the packages are installed from the package files in the directory on the nodes, instead of the package repositories, for nodes without internet access.
+Generate the bundle of the packages and the utility image with "longhornctl generate airgap-bundle", and copy its packages directory to the nodes.
+
+With --backend=ssh, the dependencies are installed by running ` + consts.CmdLonghornctlLocal + ` over SSH on the hosts listed in --ssh-hosts instead of in a DaemonSet. See "longhornctl check preflight --help" for the hosts file format.`,
 
 		Example: `$ longhornctl install preflight
 INFO[2024-07-16T17:06:55+08:00] Initializing preflight installer
@@ -63,10 +97,28 @@ If a reboot is required, the following message will be displayed:
   warn:
   - Need to reboot the system and execute longhornctl install preflight again`,
 
+		Annotations: map[string]string{consts.CmdAnnotationAudit: "true"},
+
 		PreRun: func(cmd *cobra.Command, args []string) {
 			preflightInstaller.Image = globalOpts.Image
 			preflightInstaller.KubeConfigPath = globalOpts.KubeConfigPath
+			preflightInstaller.Namespace = globalOpts.Namespace
 			preflightInstaller.NodeSelector = globalOpts.NodeSelector
+			preflightInstaller.MinNodes = globalOpts.MinNodes
+			preflightInstaller.PodCpu = globalOpts.PodCpu
+			preflightInstaller.PodMemory = globalOpts.PodMemory
+			preflightInstaller.PriorityClass = globalOpts.PriorityClass
+			preflightInstaller.Proxy = globalOpts.Proxy
+			preflightInstaller.NoProxy = globalOpts.NoProxy
+			preflightInstaller.Privileged = globalOpts.Privileged
+			preflightInstaller.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(utils.ValidateOutputFormat(outputFormat, consts.OutputFormatJSON, consts.OutputFormatJUnit, consts.OutputFormatYAML))
+			confirmMessage := fmt.Sprintf("This will install packages, load kernel modules and start services on %s.", utils.DescribeNodeSelector(globalOpts.NodeSelector))
+			if preflightInstaller.RebootStrategy == consts.RebootStrategyRolling {
+				confirmMessage += fmt.Sprintf(" The nodes needing a reboot will be drained and rebooted, %d at a time.", preflightInstaller.MaxUnavailable)
+			}
+			utils.CheckErr(utils.Confirm(globalOpts, confirmMessage))
 
 			logrus.Info("Initializing preflight installer")
 			err := preflightInstaller.Init()
@@ -82,12 +134,12 @@ If a reboot is required, the following message will be displayed:
 
 		Run: func(cmd *cobra.Command, args []string) {
 			logrus.Info("Running preflight installer")
-			output, err := preflightInstaller.Run()
+			nodeCollections, err := preflightInstaller.Collect()
 			if err != nil {
 				utils.CheckErr(errors.Wrap(err, "Failed to run preflight installer"))
 			}
 
-			logrus.Infof("Retrieved preflight installer result:\n%v", output)
+			utils.CheckErr(utils.PrintNodeCollections(globalOpts, "Retrieved preflight installer result", outputFormat, nodeCollections))
 		},
 
 		PostRun: func(cmd *cobra.Command, args []string) {
@@ -106,13 +158,27 @@ If a reboot is required, the following message will be displayed:
 
 	utils.SetGlobalOptionsRemote(cmd, globalOpts)
 
+	cmd.Flags().StringVarP(&outputFormat, consts.CmdOptOutput, "o", "", "Output format of the result (json, junit, yaml). Defaults to a table on terminals, and YAML otherwise.")
 	cmd.Flags().StringVar(&preflightInstaller.OperatingSystem, consts.CmdOptOperatingSystem, "", "Specify the operating system (\"\", cos). Leave this empty to use the package manager for installation.")
 	cmd.Flags().BoolVar(&preflightInstaller.UpdatePackages, consts.CmdOptUpdatePackages, true, "Update packages before installing required dependencies.")
+	cmd.Flags().StringVar(&preflightInstaller.PackageSource, consts.CmdOptPackageSource, "", fmt.Sprintf("Directory on the nodes holding the package files to install, such as the packages of an air-gap bundle generated with '%s %s %s', instead of the package repositories. The package lists are not updated.", consts.CmdLonghornctlRemote, consts.SubCmdGenerate, consts.SubCmdAirgapBundle))
+	cmd.Flags().BoolVar(&preflightInstaller.ResetCheckpoint, consts.CmdOptResetCheckpoint, false, "Ignore the steps recorded as completed by the previous installs on the nodes, and run all the steps again.")
+	cmd.Flags().BoolVar(&preflightInstaller.TuneIscsid, consts.CmdOptTuneIscsid, false, "Apply the recommended iscsid configuration, and disable its CHAP parameters. The original configuration is kept as /etc/iscsi/iscsid.conf.longhornctl.bak.")
 	cmd.Flags().BoolVar(&preflightInstaller.EnableSpdk, consts.CmdOptEnableSpdk, false, "Enable installation of SPDK required packages, modules, and setup.")
 	cmd.Flags().StringVar(&preflightInstaller.SpdkOptions, consts.CmdOptSpdkOptions, "", fmt.Sprintf("Specify a comma-separated (%s) list of custom options for configuring SPDK environment.", consts.CmdOptSeperator))
 	cmd.Flags().IntVar(&preflightInstaller.HugePageSize, consts.CmdOptHugePageSize, 2048, "Specify the huge page size in MiB for SPDK.")
+	cmd.Flags().StringVar(&preflightInstaller.HugePageNodes, consts.CmdOptHugePageNodes, "", fmt.Sprintf("Specify a comma-separated (%s) list of huge page sizes in MiB per NUMA node, for example 0=1024,1=1024. Overrides --%s.", consts.CmdOptSeperator, consts.CmdOptHugePageSize))
 	cmd.Flags().StringVar(&preflightInstaller.AllowPci, consts.CmdOptAllowPci, "none", fmt.Sprintf("Specify a comma-separated (%s) list of allowed PCI devices. By default, all PCI devices are blocked by a non-valid address.", consts.CmdOptSeperator))
 	cmd.Flags().StringVar(&preflightInstaller.DriverOverride, consts.CmdOptDriverOverride, "", "Userspace driver for device bindings. Override default driver for PCI devices.")
+	cmd.Flags().StringSliceVar(&preflightInstaller.LonghornDataDirectories, consts.CmdOptLonghornDataDirectory, []string{consts.LonghornDefaultDataDirectory}, "Data paths of the Longhorn disks on the nodes, mounted with exec by the node agent on Container-Optimized OS. Can be repeated or comma-separated.")
+	cmd.Flags().IntVar(&preflightInstaller.MaxParallel, consts.CmdOptMaxParallel, 0, "Maximum number of nodes to install on at the same time with the package manager. The nodes are installed in batches of this size. 0 installs on all nodes at once.")
+	cmd.Flags().StringVar(&preflightInstaller.ProfilesFile, consts.CmdOptProfiles, "", "Path to a YAML file mapping node selectors to the options of the install, applied to the nodes matching them.")
+	cmd.Flags().StringVar(&preflightInstaller.RebootStrategy, consts.CmdOptRebootStrategy, consts.RebootStrategyNone, "Strategy rebooting the nodes needing a reboot after installing the packages (none, rolling). The rolling strategy drains and reboots the nodes, then resumes the install on them.")
+	cmd.Flags().IntVar(&preflightInstaller.MaxUnavailable, consts.CmdOptMaxUnavailable, 1, "Maximum number of nodes drained and rebooted at the same time with the rolling reboot strategy.")
+	cmd.Flags().BoolVar(&preflightInstaller.ForceDrain, consts.CmdOptForceDrain, false, "Evict the pods not managed by a controller when draining the nodes with the rolling reboot strategy. They are not recreated. Without it, the reboot fails on the nodes running such pods.")
+	cmd.Flags().StringVar(&preflightInstaller.Backend, consts.CmdOptBackend, consts.BackendDaemonSet, "Backend running the operation on the nodes (daemonset, ssh). The ssh backend runs "+consts.CmdLonghornctlLocal+" on the hosts listed in --"+consts.CmdOptSSHHosts+" without the Kubernetes API.")
+	cmd.Flags().StringVar(&preflightInstaller.SSHHostsFile, consts.CmdOptSSHHosts, "", "Path to a YAML file listing the hosts to install on with the ssh backend.")
+	cmd.Flags().StringVar(&preflightInstaller.SSHLocalBinary, consts.CmdOptSSHLocalBinary, "", "Path to the "+consts.CmdLonghornctlLocal+" binary to upload to the hosts with the ssh backend. Defaults to the one on the PATH of the hosts.")
 
 	return cmd
 }
@@ -130,6 +196,7 @@ INFO[2024-07-16T17:21:32+08:00] Successfully stopped preflight installer`,
 
 		PreRun: func(cmd *cobra.Command, args []string) {
 			preflightInstaller.KubeConfigPath = globalOpts.KubeConfigPath
+			preflightInstaller.Namespace = globalOpts.Namespace
 
 			if err := preflightInstaller.Init(); err != nil {
 				utils.CheckErr(errors.Wrap(err, "Failed to initialize preflight installer"))
@@ -156,10 +223,100 @@ INFO[2024-07-16T17:21:32+08:00] Successfully stopped preflight installer`,
 	// the `stop` subcommand to be appended directly to the `export replica` command
 	// without having to remove the irrelevant option flags.	utils.SetFlagHidden(cmd, consts.CmdOptUpdatePackages)
 	utils.SetFlagHidden(cmd, consts.CmdOptEnableSpdk)
+	utils.SetFlagHidden(cmd, consts.CmdOptResetCheckpoint)
+	utils.SetFlagHidden(cmd, consts.CmdOptProfiles)
+	utils.SetFlagHidden(cmd, consts.CmdOptRebootStrategy)
+	utils.SetFlagHidden(cmd, consts.CmdOptMaxUnavailable)
+	utils.SetFlagHidden(cmd, consts.CmdOptForceDrain)
 	utils.SetFlagHidden(cmd, consts.CmdOptSpdkOptions)
 	utils.SetFlagHidden(cmd, consts.CmdOptHugePageSize)
+	utils.SetFlagHidden(cmd, consts.CmdOptHugePageNodes)
 	utils.SetFlagHidden(cmd, consts.CmdOptAllowPci)
 	utils.SetFlagHidden(cmd, consts.CmdOptDriverOverride)
 
 	return cmd
 }
+
+func newCmdInstallTuning(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var tuningInstaller = preflight.TuningInstaller{}
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdTuning,
+		Short: "Apply the tuning profile for storage nodes",
+		Long: `This command applies a sysctl and open files limit profile suited to the Longhorn storage nodes:
+- fs.aio-max-nr = 1048576, vm.dirty_background_ratio = 5 and vm.dirty_ratio = 10, persisted in /etc/sysctl.d/90-longhorn.conf and applied immediately.
+- LimitNOFILE=1048576 for the container runtime (containerd, CRI-O, Docker, K3s or RKE2), persisted in a systemd drop-in. The instance-manager processes inherit it once the container runtime and the instance-manager pods are restarted.
+
+Use "longhornctl check tuning" to report the drift of the nodes from the profile.`,
+		Example: `$ longhornctl install tuning
+INFO[2024-07-16T17:06:55+08:00] Initializing tuning installer
+INFO[2024-07-16T17:06:55+08:00] Cleaning up tuning installer
+INFO[2024-07-16T17:06:55+08:00] Running tuning installer
+INFO[2024-07-16T17:07:03+08:00] Retrieved tuning installer result:
+ip-10-0-2-123:
+  info:
+  - Wrote /etc/sysctl.d/90-longhorn.conf
+  - Applied the sysctl parameters persisted in /etc/sysctl.d/90-longhorn.conf
+  - Wrote /etc/systemd/system/containerd.service.d/90-longhorn.conf
+  warn:
+  - Raised the open files limit of containerd.service to 1048576, restart it and the instance-manager pods of the node to apply
+INFO[2024-07-16T17:07:03+08:00] Cleaning up tuning installer
+INFO[2024-07-16T17:07:03+08:00] Completed tuning installer`,
+
+		Annotations: map[string]string{consts.CmdAnnotationAudit: "true"},
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			tuningInstaller.Image = globalOpts.Image
+			tuningInstaller.KubeConfigPath = globalOpts.KubeConfigPath
+			tuningInstaller.Namespace = globalOpts.Namespace
+			tuningInstaller.NodeSelector = globalOpts.NodeSelector
+			tuningInstaller.MinNodes = globalOpts.MinNodes
+			tuningInstaller.PodCpu = globalOpts.PodCpu
+			tuningInstaller.PodMemory = globalOpts.PodMemory
+			tuningInstaller.PriorityClass = globalOpts.PriorityClass
+			tuningInstaller.Proxy = globalOpts.Proxy
+			tuningInstaller.NoProxy = globalOpts.NoProxy
+			tuningInstaller.Privileged = globalOpts.Privileged
+			tuningInstaller.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(utils.ValidateOutputFormat(outputFormat, consts.OutputFormatJSON, consts.OutputFormatJUnit, consts.OutputFormatYAML))
+			utils.CheckErr(utils.Confirm(globalOpts, fmt.Sprintf("This will change the sysctl parameters and the container runtime open files limit on %s.", utils.DescribeNodeSelector(globalOpts.NodeSelector))))
+
+			logrus.Info("Initializing tuning installer")
+			if err := tuningInstaller.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize tuning installer"))
+			}
+
+			logrus.Info("Cleaning up tuning installer")
+			if err := tuningInstaller.Cleanup(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to cleanup tuning installer"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Running tuning installer")
+			nodeCollections, err := tuningInstaller.Collect()
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to run tuning installer"))
+			}
+
+			utils.CheckErr(utils.PrintNodeCollections(globalOpts, "Retrieved tuning installer result", outputFormat, nodeCollections))
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Cleaning up tuning installer")
+			if err := tuningInstaller.Cleanup(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to cleanup tuning installer"))
+			}
+
+			logrus.Info("Completed tuning installer")
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&outputFormat, consts.CmdOptOutput, "o", "", "Output format of the result (json, junit, yaml). Defaults to a table on terminals, and YAML otherwise.")
+
+	return cmd
+}
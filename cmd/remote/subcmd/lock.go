@@ -0,0 +1,96 @@
+package subcmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// operationLock is the lock held by the running operation, if any.
+var operationLock *kubeutils.OperationLock
+
+// AcquireOperationLock acquires the lock of the operation if the command is audited, so two
+// operations of the same kind do not run at the same time. The 'stop' subcommand shares the lock
+// of its operation. The lock is released when the command exits through utils.CheckErr.
+//
+// The operation fails when the lock cannot be acquired, for example when the ServiceAccount of a
+// Job submitted with --no-wait is not allowed to manage Leases. Operations with the ssh backend
+// do not use the Kubernetes API, so they run without the lock.
+func AcquireOperationLock(cmd *cobra.Command, globalOpts *types.GlobalCmdOptions) {
+	if cmd.Annotations[consts.CmdAnnotationAudit] == "" {
+		return
+	}
+	// The Job of a run submitted with --no-wait acquires the lock when it runs the command.
+	if globalOpts.NoWait {
+		return
+	}
+	if backend := cmd.Flags().Lookup(consts.CmdOptBackend); backend != nil && backend.Value.String() == consts.BackendSSH {
+		return
+	}
+
+	kubeClient, err := kubeutils.NewKubeClient("", globalOpts.KubeConfigPath)
+	if err != nil {
+		utils.CheckErr(errors.Wrap(err, "Failed to acquire operation lock"))
+	}
+
+	namespace := globalOpts.Namespace
+	if namespace == "" {
+		namespace = consts.LonghornNamespace
+	}
+
+	kubeUser, err := kubeutils.GetCurrentUser(kubeClient)
+	if err != nil {
+		logrus.WithError(err).Debug("Failed to get current Kubernetes user")
+		kubeUser = "unknown"
+	}
+	holder := fmt.Sprintf("%s (%s, pid %d)", kubeUser, getHostUser(), os.Getpid())
+
+	lock := kubeutils.NewOperationLock(kubeClient, namespace, getOperationLockName(cmd), holder, cmd.CommandPath())
+	if err := lock.Acquire(globalOpts.ForceUnlock); err != nil {
+		if kubeutils.IsOperationInProgress(err) {
+			utils.CheckErr(err)
+		}
+		utils.CheckErr(errors.Wrap(err, "Failed to acquire operation lock"))
+	}
+
+	operationLock = lock
+	utils.RegisterErrorHandler(func(error) {
+		ReleaseOperationLock()
+	})
+}
+
+// ReleaseOperationLock releases the lock of the operation, if it holds one. Failing to release
+// does not fail the command, the lock expires.
+func ReleaseOperationLock() {
+	if operationLock == nil {
+		return
+	}
+
+	if err := operationLock.Release(); err != nil {
+		logrus.WithError(err).Warn("Failed to release operation lock")
+	}
+	operationLock = nil
+}
+
+// getOperationLockName returns the name of the lock of the operation, after its command path
+// without the 'stop' subcommand. For example, longhornctl-export-replica.
+func getOperationLockName(cmd *cobra.Command) string {
+	names := []string{}
+	for command := cmd; command != nil; command = command.Parent() {
+		names = append([]string{command.Name()}, names...)
+	}
+	if len(names) > 1 && names[len(names)-1] == consts.SubCmdStop {
+		names = names[:len(names)-1]
+	}
+	return strings.Join(names, "-")
+}
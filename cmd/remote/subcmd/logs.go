@@ -0,0 +1,73 @@
+package subcmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/remote/logs"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+)
+
+func NewCmdLogs(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var logStreamer = logs.Streamer{}
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdLogs,
+		Short: "Stream the logs of the Longhorn components",
+		Long: `This command prints the logs of all the containers of the pods of the Longhorn components, prefixed with the pod, and the container for multi-container pods.
+
+Components:
+  ` + logs.ComponentManager + `           longhorn-manager pods
+  ` + logs.ComponentInstanceManager + `  instance-manager pods, running the engines and replicas
+  ` + logs.ComponentCSI + `               CSI plugin and sidecar pods
+
+With --` + consts.CmdOptFollow + `, the command keeps printing the new lines until it is interrupted. The pods created afterwards, for example after a restart, are not followed.`,
+		Example: `$ longhornctl logs --component=manager,instance-manager --node=ip-10-0-2-123 --since=1h --grep=pvc-0b1c6f4a --follow
+[instance-manager-2f3c5d0e6b1a] [pvc-0b1c6f4a-7e0a-4a7b-9d2e-5f1f3f0c2e11-r-8a2b3c4d] time="2024-07-16T09:20:05Z" level=info msg="Starting replica rebuilding"
+[longhorn-manager-x7k2p] time="2024-07-16T09:20:05Z" level=info msg="Volume pvc-0b1c6f4a-7e0a-4a7b-9d2e-5f1f3f0c2e11 became degraded"`,
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			logStreamer.KubeConfigPath = globalOpts.KubeConfigPath
+			logStreamer.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(logStreamer.Validate())
+
+			logrus.Debug("Initializing log streamer")
+			if err := logStreamer.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize log streamer"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			err := logStreamer.Run(ctx, func(line string) {
+				fmt.Println(line)
+			})
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to stream logs"))
+			}
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVar(&logStreamer.LonghornNamespace, consts.CmdOptLonghornNamespace, consts.LonghornNamespace, "Namespace where Longhorn is deployed.")
+	cmd.Flags().StringVar(&logStreamer.Components, consts.CmdOptComponent, logs.ComponentManager, fmt.Sprintf("Specify a comma-separated (%s) list of components to print the logs of (%s, %s, %s).", consts.CmdOptSeperator, logs.ComponentManager, logs.ComponentInstanceManager, logs.ComponentCSI))
+	cmd.Flags().StringVar(&logStreamer.Node, consts.CmdOptNode, "", "Only print the logs of the pods on the node.")
+	cmd.Flags().DurationVar(&logStreamer.Since, consts.CmdOptSince, 0, "Only print the lines newer than this duration, for example 1h. Defaults to the whole logs.")
+	cmd.Flags().BoolVarP(&logStreamer.Follow, consts.CmdOptFollow, "f", false, "Keep printing the new lines until interrupted.")
+	cmd.Flags().StringVar(&logStreamer.Grep, consts.CmdOptGrep, "", "Only print the lines matching the regular expression.")
+
+	return cmd
+}
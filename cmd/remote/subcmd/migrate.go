@@ -0,0 +1,116 @@
+package subcmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/remote/dr"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+)
+
+func NewCmdMigrate(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdMigrate,
+		Short: "Longhorn migration operations",
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.AddCommand(newCmdMigrateCluster(globalOpts))
+
+	return cmd
+}
+
+func newCmdMigrateCluster(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var migrator = dr.Migrator{}
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdCluster + " --" + consts.CmdOptVolume + "=<volume> --" + consts.CmdOptTargetKubeConfig + "=<kubeconfig>",
+		Short: "Migrate a volume to another cluster sharing the backup store",
+		Long: `This command migrates a volume of the cluster of --` + consts.CmdOptKubeConfigPath + ` to the cluster of --` + consts.CmdOptTargetKubeConfig + `. The backup target of the target cluster must be the backup store of the source cluster. The migration runs these steps:
+1. ` + types.VolumeClusterMigrationStepBackup + `: the final backup of the volume is taken on the source cluster.
+2. ` + types.VolumeClusterMigrationStepRestore + `: the backup target of the target cluster is synchronized. When the target volume is a DR volume of the volume, created with "longhornctl dr create", it is activated once it restored the final backup, which only transfers the data written since its last restore. Otherwise, a new volume restores the final backup, with the replica count, access mode, frontend and data engine of the volume.
+3. ` + types.VolumeClusterMigrationStepPVC + `: the PV and the PVC of the volume are recreated on the target cluster, bound to the target volume.
+
+The target volume and PVC keep the names of the volume and of its PVC, unless mapped with --` + consts.CmdOptTargetVolume + ` and --` + consts.CmdOptTargetPVC + `. The PVC keeps its StorageClass, access modes and size; the StorageClass does not have to exist in the target cluster for the PVC to bind.
+
+Stop the workloads of the volume first, so the final backup has all the data: the migration refuses an attached volume without --` + consts.CmdOptForce + `. The source volume is left as it is, delete it once the workloads run on the target cluster.
+
+The state of the migration is saved in the ` + consts.AppNameClusterMigrator + `-<volume> ConfigMap of the source cluster after each step. Restoring a large volume takes long: when the command is interrupted, or exceeds --` + consts.CmdOptTimeout + `, run it again to resume the migration with the step it stopped at, while Longhorn keeps backing up or restoring. --` + consts.CmdOptReset + ` discards the state and starts over.`,
+		Example: `$ longhornctl migrate cluster --volume=pvc-48a6457d-585e-423b-b530-bbc68a5f948a --target-kube-config=dr-cluster.yaml
+This will back up volume pvc-48a6457d-585e-423b-b530-bbc68a5f948a and restore it in the cluster of dr-cluster.yaml.
+Do you want to continue? [y/N]: y
+INFO[2024-07-16T17:40:12+08:00] Initializing cluster migrator
+INFO[2024-07-16T17:40:12+08:00] Running cluster migrator                      mode=restore volume=pvc-48a6457d-585e-423b-b530-bbc68a5f948a
+INFO[2024-07-16T17:40:12+08:00] Creating snapshot                             snapshot=longhornctl-migrate-20240716-094012-pvc-48a6457d-585e-423b-b530-bbc68a5f948a volume=pvc-48a6457d-585e-423b-b530-bbc68a5f948a
+INFO[2024-07-16T17:40:14+08:00] Backing up snapshot to s3://backupbucket@us-east-1/  backup=longhornctl-migrate-20240716-094012-pvc-48a6457d-585e-423b-b530-bbc68a5f948a volume=pvc-48a6457d-585e-423b-b530-bbc68a5f948a
+INFO[2024-07-16T17:42:31+08:00] Waiting for backup longhornctl-migrate-20240716-094012-pvc-48a6457d-585e-423b-b530-bbc68a5f948a to be synchronized to the target cluster
+INFO[2024-07-16T17:42:45+08:00] Creating volume pvc-48a6457d-585e-423b-b530-bbc68a5f948a in the target cluster from backup s3://backupbucket@us-east-1/?backup=longhornctl-migrate-20240716-094012-pvc-48a6457d-585e-423b-b530-bbc68a5f948a&volume=pvc-48a6457d-585e-423b-b530-bbc68a5f948a
+INFO[2024-07-16T17:42:45+08:00] Waiting for volume pvc-48a6457d-585e-423b-b530-bbc68a5f948a to restore backup longhornctl-migrate-20240716-094012-pvc-48a6457d-585e-423b-b530-bbc68a5f948a
+INFO[2024-07-16T17:51:02+08:00] Creating PV pvc-48a6457d-585e-423b-b530-bbc68a5f948a and PVC default/data-mysql-0 in the target cluster
+INFO[2024-07-16T17:51:02+08:00] Migrated volume pvc-48a6457d-585e-423b-b530-bbc68a5f948a to volume pvc-48a6457d-585e-423b-b530-bbc68a5f948a and PVC default/data-mysql-0 of the target cluster
+INFO[2024-07-16T17:51:02+08:00] Completed cluster migrator                    volume=pvc-48a6457d-585e-423b-b530-bbc68a5f948a
+
+$ longhornctl migrate cluster --volume=pvc-48a6457d-585e-423b-b530-bbc68a5f948a --target-kube-config=dr-cluster.yaml --target-volume=pvc-48a6457d-dr --target-pvc=mysql/data-mysql-0`,
+
+		Annotations: map[string]string{consts.CmdAnnotationAudit: "true", consts.CmdAnnotationSync: "true"},
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			migrator.KubeConfigPath = globalOpts.KubeConfigPath
+			migrator.Namespace = globalOpts.Namespace
+			migrator.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(utils.ValidateOutputFormat(outputFormat, consts.OutputFormatJSON, consts.OutputFormatYAML))
+			utils.CheckErr(migrator.Validate())
+			utils.CheckErr(utils.Confirm(globalOpts, fmt.Sprintf("This will back up volume %s and restore it in the cluster of %s.", migrator.VolumeName, migrator.TargetKubeConfigPath)))
+
+			logrus.Info("Initializing cluster migrator")
+			if err := migrator.Init(); err != nil {
+				utils.CheckErr(errors.Wrapf(err, "Failed to initialize cluster migrator for volume %s", migrator.VolumeName))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			logrus.WithFields(logrus.Fields{"volume": migrator.VolumeName, "mode": migrator.Mode()}).Info("Running cluster migrator")
+			state, err := migrator.Run()
+			if err != nil {
+				utils.CheckErr(errors.Wrapf(err, "Failed to run cluster migrator for volume %s", migrator.VolumeName))
+			}
+
+			printed, err := utils.PrintStructuredResult(outputFormat, types.ResultKindVolumeClusterMigration, state)
+			utils.CheckErr(err)
+			if !printed {
+				pvc := "no PVC"
+				if state.TargetPVC != "" {
+					pvc = "PVC " + state.TargetPVC
+				}
+				logrus.Infof("Migrated volume %v to volume %v and %v of the target cluster", state.Volume, state.TargetVolume, pvc)
+			}
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logrus.WithField("volume", migrator.VolumeName).Info("Completed cluster migrator")
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&outputFormat, consts.CmdOptOutput, "o", "", fmt.Sprintf("Output format of the migration state (%s, %s).", consts.OutputFormatJSON, consts.OutputFormatYAML))
+	cmd.Flags().StringVar(&migrator.VolumeName, consts.CmdOptVolume, "", "Name of the volume to migrate.")
+	cmd.Flags().StringVar(&migrator.TargetKubeConfigPath, consts.CmdOptTargetKubeConfig, "", "Kubernetes config (kubeconfig) path of the target cluster.")
+	cmd.Flags().StringVar(&migrator.TargetVolumeName, consts.CmdOptTargetVolume, "", "Name of the volume in the target cluster, or of its DR volume. Defaults to the name of the volume.")
+	cmd.Flags().StringVar(&migrator.TargetPVC, consts.CmdOptTargetPVC, "", "PVC of the volume in the target cluster, as namespace/name. Defaults to the PVC of the volume.")
+	cmd.Flags().BoolVar(&migrator.Force, consts.CmdOptForce, false, "Migrate the volume even if it is attached. The data written after the final backup is not migrated.")
+	cmd.Flags().BoolVar(&migrator.Reset, consts.CmdOptReset, false, "Discard the state of the previous migration of the volume, and start over.")
+	cmd.Flags().DurationVar(&migrator.Timeout, consts.CmdOptTimeout, 6*time.Hour, "Maximum time of the run. Run the command again to resume the migration.")
+	cmd.Flags().StringVar(&migrator.LonghornNamespace, consts.CmdOptLonghornNamespace, consts.LonghornNamespace, "Namespace where Longhorn is deployed, in both clusters.")
+
+	return cmd
+}
@@ -0,0 +1,360 @@
+package subcmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	lhmgrtypes "github.com/longhorn/longhorn-manager/types"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/remote/node"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+)
+
+func NewCmdNode(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdNode,
+		Short: "Longhorn node operations",
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.AddCommand(newCmdNodeExec(globalOpts))
+	cmd.AddCommand(newCmdNodeCp(globalOpts))
+	cmd.AddCommand(newCmdNodeGate(globalOpts))
+
+	return cmd
+}
+
+func newCmdNodeExec(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var nodeExecutor = node.Executor{}
+	var outputFormat string
+	var result *types.NodeExecResult
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdExec + " --" + consts.CmdOptNode + "=<node> -- <command> [args...]",
+		Short: "Run a command in the host namespaces of a node",
+		Long: `This command runs a command on a node without SSH access to it, for one-off diagnostics. It creates a DaemonSet pod on the node, like the other commands running on the nodes, and runs the command from the pod in the mount, UTS, IPC, network and PID namespaces of the host. The command therefore runs with the binaries and files of the host, not of the longhornctl image.
+
+The pod tolerates all the taints of the node, and must match --` + consts.CmdOptNodeSelector + ` when it is set. With --` + consts.CmdOptPrivileged + `=false, the command only gets the capabilities to enter the host namespaces.
+
+The stdout and stderr of the command are printed once it exits, and longhornctl fails when the command exits with a non-zero code. The command has no stdin and no terminal, so interactive commands are not supported.`,
+		Example: `$ longhornctl node exec --node=ip-10-0-2-123 -- iscsiadm -m session
+INFO[2024-07-16T17:40:12+08:00] Initializing node executor
+INFO[2024-07-16T17:40:12+08:00] Cleaning up node executor
+INFO[2024-07-16T17:40:12+08:00] Running node executor
+tcp: [1] 10.42.1.12:3260,1 iqn.2019-10.io.longhorn:pvc-48a6457d-585e-423b-b530-bbc68a5f948a (non-flash)
+INFO[2024-07-16T17:40:19+08:00] Cleaning up node executor
+INFO[2024-07-16T17:40:19+08:00] Completed node executor
+
+$ longhornctl node exec --node=ip-10-0-2-123 -o yaml -- sh -c 'df -h /var/lib/longhorn'`,
+		Args: cobra.MinimumNArgs(1),
+
+		Annotations: map[string]string{consts.CmdAnnotationAudit: "true", consts.CmdAnnotationSync: "true"},
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			nodeExecutor.Image = globalOpts.Image
+			nodeExecutor.KubeConfigPath = globalOpts.KubeConfigPath
+			nodeExecutor.Namespace = globalOpts.Namespace
+			nodeExecutor.NodeSelector = globalOpts.NodeSelector
+			nodeExecutor.MinNodes = globalOpts.MinNodes
+			nodeExecutor.PodCpu = globalOpts.PodCpu
+			nodeExecutor.PodMemory = globalOpts.PodMemory
+			nodeExecutor.PriorityClass = globalOpts.PriorityClass
+			nodeExecutor.Proxy = globalOpts.Proxy
+			nodeExecutor.NoProxy = globalOpts.NoProxy
+			nodeExecutor.Privileged = globalOpts.Privileged
+			nodeExecutor.LogLevel = globalOpts.LogLevel
+			nodeExecutor.Command = args
+
+			utils.CheckErr(utils.ValidateOutputFormat(outputFormat, consts.OutputFormatJSON, consts.OutputFormatYAML))
+			utils.CheckErr(nodeExecutor.Validate())
+
+			logrus.Info("Initializing node executor")
+			if err := nodeExecutor.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize node executor"))
+			}
+
+			logrus.Info("Cleaning up node executor")
+			if err := nodeExecutor.Cleanup(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to cleanup node executor"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Running node executor")
+			var err error
+			result, err = nodeExecutor.Run()
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to run node executor"))
+			}
+
+			utils.CheckErr(printNodeExecResult(result, outputFormat))
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Cleaning up node executor")
+			if err := nodeExecutor.Cleanup(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to cleanup node executor"))
+			}
+
+			if result != nil && result.ExitCode != 0 {
+				utils.CheckErr(errors.Errorf("command %q exited with code %d on node %v", strings.Join(result.Command, " "), result.ExitCode, result.Node))
+			}
+
+			logrus.Info("Completed node executor")
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&outputFormat, consts.CmdOptOutput, "o", "", fmt.Sprintf("Output format of the result (%s, %s). Defaults to the stdout and stderr of the command.", consts.OutputFormatJSON, consts.OutputFormatYAML))
+	cmd.Flags().StringVar(&nodeExecutor.NodeName, consts.CmdOptNode, "", "Name of the node to run the command on.")
+	cmd.Flags().DurationVar(&nodeExecutor.Timeout, consts.CmdOptTimeout, 10*time.Minute, "Maximum time to wait for the command.")
+
+	return cmd
+}
+
+// printNodeExecResult prints the stdout and stderr of the command as they are, unless a structured
+// output format is requested.
+func printNodeExecResult(result *types.NodeExecResult, outputFormat string) error {
+	if printed, err := utils.PrintStructuredResult(outputFormat, types.ResultKindNodeExecResult, result); printed || err != nil {
+		return err
+	}
+
+	fmt.Fprint(os.Stdout, result.Stdout)
+	fmt.Fprint(os.Stderr, result.Stderr)
+	return nil
+}
+
+func newCmdNodeCp(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var nodeCopier = node.Copier{}
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdCp + " <node>:<path> <local-path> | <local-path> <node>:<path>",
+		Short: "Copy a file between the local machine and a node",
+		Long: `This command copies a file from a node to the local machine, or from the local machine to a node, without SSH or scp access to the node, for example to save or restore the metadata of a replica during a recovery. It creates a DaemonSet pod on the node, like the other commands running on the nodes, and reads or writes the file in the mount namespace of the host with cat and sha256sum of the host.
+
+Only regular files can be copied, up to --` + consts.CmdOptMaxSize + `. The SHA-256 checksum of the file is compared on both sides, and the destination is only written once it matches: the copy goes to a temporary file next to the destination, renamed over it. When the destination is a directory, the file keeps its name in it.
+
+Copying to a node overwrites the destination, so it asks for confirmation.`,
+		Example: `$ longhornctl node cp ip-10-0-2-123:/var/lib/longhorn/replicas/pvc-48a6457d-585e-423b-b530-bbc68a5f948a-c7b1f54e/volume.meta ./
+INFO[2024-07-16T17:40:12+08:00] Initializing node copier
+INFO[2024-07-16T17:40:12+08:00] Cleaning up node copier
+INFO[2024-07-16T17:40:12+08:00] Running node copier
+INFO[2024-07-16T17:40:19+08:00] Copying /var/lib/longhorn/replicas/pvc-48a6457d-585e-423b-b530-bbc68a5f948a-c7b1f54e/volume.meta (169 bytes) to volume.meta  node=ip-10-0-2-123
+Copied ip-10-0-2-123:/var/lib/longhorn/replicas/pvc-48a6457d-585e-423b-b530-bbc68a5f948a-c7b1f54e/volume.meta to volume.meta (169 bytes, sha256 4f0a4c8e3b2d5f61a9c7e0d18b6f3a25c94e7d0b1a2f3e4d5c6b7a8f9e0d1c2b)
+INFO[2024-07-16T17:40:20+08:00] Cleaning up node copier
+INFO[2024-07-16T17:40:20+08:00] Completed node copier
+
+$ longhornctl node cp ./volume.meta ip-10-0-2-123:/var/lib/longhorn/replicas/pvc-48a6457d-585e-423b-b530-bbc68a5f948a-c7b1f54e/`,
+		Args: cobra.ExactArgs(2),
+
+		Annotations: map[string]string{consts.CmdAnnotationAudit: "true", consts.CmdAnnotationSync: "true"},
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			nodeCopier.Image = globalOpts.Image
+			nodeCopier.KubeConfigPath = globalOpts.KubeConfigPath
+			nodeCopier.Namespace = globalOpts.Namespace
+			nodeCopier.NodeSelector = globalOpts.NodeSelector
+			nodeCopier.MinNodes = globalOpts.MinNodes
+			nodeCopier.PodCpu = globalOpts.PodCpu
+			nodeCopier.PodMemory = globalOpts.PodMemory
+			nodeCopier.PriorityClass = globalOpts.PriorityClass
+			nodeCopier.Proxy = globalOpts.Proxy
+			nodeCopier.NoProxy = globalOpts.NoProxy
+			nodeCopier.Privileged = globalOpts.Privileged
+			nodeCopier.LogLevel = globalOpts.LogLevel
+			nodeCopier.Source = args[0]
+			nodeCopier.Destination = args[1]
+
+			utils.CheckErr(utils.ValidateOutputFormat(outputFormat, consts.OutputFormatJSON, consts.OutputFormatYAML))
+			utils.CheckErr(nodeCopier.Validate())
+			if nodeCopier.IsPush() {
+				utils.CheckErr(utils.Confirm(globalOpts, fmt.Sprintf("This will write %s with the content of %s.", nodeCopier.Destination, nodeCopier.Source)))
+			}
+
+			logrus.Info("Initializing node copier")
+			if err := nodeCopier.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize node copier"))
+			}
+
+			logrus.Info("Cleaning up node copier")
+			if err := nodeCopier.Cleanup(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to cleanup node copier"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Running node copier")
+			result, err := nodeCopier.Run()
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to run node copier"))
+			}
+
+			printed, err := utils.PrintStructuredResult(outputFormat, types.ResultKindNodeCopyResult, result)
+			utils.CheckErr(err)
+			if !printed {
+				fmt.Printf("Copied %s to %s (%d bytes, sha256 %s)\n", result.Source, result.Destination, result.Size, result.SHA256)
+			}
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Cleaning up node copier")
+			if err := nodeCopier.Cleanup(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to cleanup node copier"))
+			}
+
+			logrus.Info("Completed node copier")
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&outputFormat, consts.CmdOptOutput, "o", "", fmt.Sprintf("Output format of the result (%s, %s).", consts.OutputFormatJSON, consts.OutputFormatYAML))
+	cmd.Flags().StringVar(&nodeCopier.MaxSize, consts.CmdOptMaxSize, "64Mi", "Maximum size of the copied file (e.g. 512Ki, 1Gi).")
+	cmd.Flags().DurationVar(&nodeCopier.Timeout, consts.CmdOptTimeout, 10*time.Minute, "Maximum time to wait for the copy.")
+
+	return cmd
+}
+
+func newCmdNodeGate(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdGate,
+		Short: "Keep the replicas off the new nodes until the preflight check has passed on them",
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.AddCommand(newCmdNodeGateEnable(globalOpts))
+	cmd.AddCommand(newCmdNodeGateDisable(globalOpts))
+
+	return cmd
+}
+
+func newCmdNodeGateEnable(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var nodeGate = node.Gate{}
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdEnable,
+		Short: "Enable the node gate",
+		Long: `This command keeps the replicas off the new nodes until the preflight check has passed on them. It enables the Longhorn setting ` + string(lhmgrtypes.SettingNameCreateDefaultDiskLabeledNodes) + `, so Longhorn only creates the default disk of the nodes labeled with ` + lhmgrtypes.NodeCreateDefaultDiskLabelKey + `, and a node without disk gets no replica.
+
+The gate labels a node with ` + lhmgrtypes.NodeCreateDefaultDiskLabelKey + `=` + lhmgrtypes.NodeCreateDefaultDiskLabelValueTrue + ` once its preflight check result, recorded by "longhornctl serve", has no error. While the preflight server runs, it passes the new nodes after each check. The state of the gate on each node (` + consts.NodeGateStatePending + `, ` + consts.NodeGateStateFailed + ` or ` + consts.NodeGateStatePassed + `) is kept in the ` + consts.AnnotationNodeGate + ` annotation of the node.
+
+The nodes with Longhorn disks, and the nodes already labeled, are not gated. The nodes not matching the node selector of the preflight server get no result, so they stay pending until they are labeled by hand.
+
+The value of the setting before the gate is kept in the ` + consts.AnnotationNodeGatePrevious + ` annotation of the setting, and "longhornctl node gate disable" restores it. Running the command again passes the nodes with the latest recorded results.`,
+		Example: `$ longhornctl serve &
+$ longhornctl node gate enable
+INFO[2024-07-16T17:40:12+08:00] Initializing node gate
+INFO[2024-07-16T17:40:12+08:00] Running node gate
+INFO[2024-07-16T17:40:12+08:00] Node gate is passed                           node=ip-10-0-2-124
+INFO[2024-07-16T17:40:12+08:00] Node gate is failed                           node=ip-10-0-2-125
+INFO[2024-07-16T17:40:12+08:00] Enabled setting create-default-disk-labeled-nodes
+INFO[2024-07-16T17:40:12+08:00] Retrieved node gate result:
+ip-10-0-2-123:
+  info:
+  - Has Longhorn disks, not gated
+ip-10-0-2-124:
+  info:
+  - Preflight check passed, labeled with node.longhorn.io/create-default-disk=true for Longhorn to create the default disk
+ip-10-0-2-125:
+  error:
+  - 'Preflight check failed, the node gets no default disk until the check passes: Service iscsid is not running'
+INFO[2024-07-16T17:40:12+08:00] Completed node gate`,
+		Annotations: map[string]string{consts.CmdAnnotationAudit: "true"},
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			nodeGate.KubeConfigPath = globalOpts.KubeConfigPath
+			nodeGate.Namespace = globalOpts.Namespace
+			nodeGate.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(utils.ValidateOutputFormat(outputFormat, consts.OutputFormatJSON, consts.OutputFormatYAML))
+			utils.CheckErr(utils.Confirm(globalOpts, fmt.Sprintf("This will enable setting %s, so the nodes without disk get no default disk until the preflight check has passed on them.", lhmgrtypes.SettingNameCreateDefaultDiskLabeledNodes)))
+
+			logrus.Info("Initializing node gate")
+			if err := nodeGate.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize node gate"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Running node gate")
+			collections, err := nodeGate.Enable()
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to enable node gate"))
+			}
+
+			utils.CheckErr(utils.PrintCollections(globalOpts, "NODE", "nodes", "Retrieved node gate result", outputFormat, collections))
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Completed node gate")
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&outputFormat, consts.CmdOptOutput, "o", "", fmt.Sprintf("Output format of the result (%s, %s).", consts.OutputFormatJSON, consts.OutputFormatYAML))
+	cmd.Flags().StringVar(&nodeGate.LonghornNamespace, consts.CmdOptLonghornNamespace, consts.LonghornNamespace, "Namespace where Longhorn is deployed.")
+
+	return cmd
+}
+
+func newCmdNodeGateDisable(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var nodeGate = node.Gate{}
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdDisable,
+		Short: "Disable the node gate",
+		Long:  `This command restores the Longhorn setting ` + string(lhmgrtypes.SettingNameCreateDefaultDiskLabeledNodes) + ` to its value before "longhornctl node gate enable", and removes the ` + consts.AnnotationNodeGate + ` annotation of the nodes. The ` + lhmgrtypes.NodeCreateDefaultDiskLabelKey + ` labels added by the gate are kept.`,
+		Example: `$ longhornctl node gate disable
+INFO[2024-07-16T17:40:12+08:00] Initializing node gate
+INFO[2024-07-16T17:40:12+08:00] Running node gate
+INFO[2024-07-16T17:40:12+08:00] Restored setting create-default-disk-labeled-nodes to false
+INFO[2024-07-16T17:40:12+08:00] Completed node gate`,
+		Annotations: map[string]string{consts.CmdAnnotationAudit: "true"},
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			nodeGate.KubeConfigPath = globalOpts.KubeConfigPath
+			nodeGate.Namespace = globalOpts.Namespace
+			nodeGate.LogLevel = globalOpts.LogLevel
+
+			logrus.Info("Initializing node gate")
+			if err := nodeGate.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize node gate"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Running node gate")
+			enabled, err := nodeGate.Disable()
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to disable node gate"))
+			}
+			if !enabled {
+				logrus.Info("Node gate is not enabled, nothing to restore")
+			}
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Completed node gate")
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVar(&nodeGate.LonghornNamespace, consts.CmdOptLonghornNamespace, consts.LonghornNamespace, "Namespace where Longhorn is deployed.")
+
+	return cmd
+}
@@ -0,0 +1,110 @@
+package subcmd
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/remote/preload"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+)
+
+func NewCmdPreload(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdPreload,
+		Short: "Longhorn preloading operations",
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.AddCommand(newCmdPreloadImages(globalOpts))
+
+	return cmd
+}
+
+func newCmdPreloadImages(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var imagePreloader = preload.Preloader{}
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdImages,
+		Short: "Pre-pull the Longhorn images on the nodes",
+		Long: `This command pulls every Longhorn component image on the selected nodes ahead of an upgrade, so the upgrade does not wait for the image pulls.
+It deploys a DaemonSet pulling the images of the Longhorn version, reports the pull duration and failures of each image per node, then cleans up.
+
+The images are listed in the longhorn-images.txt file published with the Longhorn release. For a private registry or an air-gapped cluster, list the images in a file, one per line, and use --images-file instead.`,
+		Example: `$ longhornctl preload images --version=v1.7.2
+INFO[2024-07-16T17:17:38+08:00] Initializing image preloader
+INFO[2024-07-16T17:17:38+08:00] Cleaning up image preloader
+INFO[2024-07-16T17:17:38+08:00] Running image preloader
+INFO[2024-07-16T17:18:42+08:00] Retrieved image preloader result:
+ip-10-0-2-123:
+  info:
+  - Pulled image longhornio/csi-attacher:v4.6.1 in 3s
+  - Pulled image longhornio/longhorn-engine:v1.7.2 in 21s
+  ...
+INFO[2024-07-16T17:18:42+08:00] Cleaning up image preloader
+INFO[2024-07-16T17:18:42+08:00] Completed image preloader`,
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			imagePreloader.Image = globalOpts.Image
+			imagePreloader.KubeConfigPath = globalOpts.KubeConfigPath
+			imagePreloader.Namespace = globalOpts.Namespace
+			imagePreloader.NodeSelector = globalOpts.NodeSelector
+			imagePreloader.MinNodes = globalOpts.MinNodes
+			imagePreloader.PodCpu = globalOpts.PodCpu
+			imagePreloader.PodMemory = globalOpts.PodMemory
+			imagePreloader.PriorityClass = globalOpts.PriorityClass
+			imagePreloader.Proxy = globalOpts.Proxy
+			imagePreloader.NoProxy = globalOpts.NoProxy
+			imagePreloader.Privileged = globalOpts.Privileged
+			imagePreloader.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(utils.ValidateOutputFormat(outputFormat, consts.OutputFormatJSON, consts.OutputFormatJUnit, consts.OutputFormatYAML))
+			utils.CheckErr(imagePreloader.Validate())
+
+			logrus.Info("Initializing image preloader")
+			if err := imagePreloader.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize image preloader"))
+			}
+
+			logrus.Info("Cleaning up image preloader")
+			if err := imagePreloader.Cleanup(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to cleanup image preloader"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Running image preloader")
+			nodeCollections, err := imagePreloader.Collect()
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to run image preloader"))
+			}
+
+			utils.CheckErr(utils.PrintNodeCollections(globalOpts, "Retrieved image preloader result", outputFormat, nodeCollections))
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Cleaning up image preloader")
+			if err := imagePreloader.Cleanup(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to cleanup image preloader"))
+			}
+
+			logrus.Info("Completed image preloader")
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&outputFormat, consts.CmdOptOutput, "o", "", "Output format of the result (json, junit, yaml). Defaults to a table on terminals, and YAML otherwise.")
+	cmd.Flags().StringVar(&imagePreloader.Version, consts.CmdOptVersion, "", "Longhorn version to pull the images of, for example v1.7.2.")
+	cmd.Flags().StringVar(&imagePreloader.ImagesFile, consts.CmdOptImagesFile, "", "Path to a file listing the images to pull, one per line. Overrides the images of --"+consts.CmdOptVersion+".")
+	cmd.Flags().DurationVar(&imagePreloader.Timeout, consts.CmdOptTimeout, 30*time.Minute, "Maximum time to wait for the images to be pulled on a batch of nodes. The nodes still pulling are reported with an error.")
+	cmd.Flags().IntVar(&imagePreloader.MaxParallel, consts.CmdOptMaxParallel, 0, "Maximum number of nodes to pull on at the same time. The nodes pull in batches of this size. 0 pulls on all nodes at once.")
+
+	return cmd
+}
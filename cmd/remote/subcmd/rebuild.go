@@ -0,0 +1,208 @@
+package subcmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/remote/rebuild"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+)
+
+func NewCmdRebuild(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdRebuild,
+		Short: "Longhorn replica rebuild operations",
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.AddCommand(newCmdRebuildStatus(globalOpts))
+	cmd.AddCommand(newCmdRebuildThrottle(globalOpts))
+
+	return cmd
+}
+
+func newCmdRebuildStatus(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var statusGetter = rebuild.StatusGetter{}
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdStatus,
+		Short: "Show the in-progress replica rebuilds with their throughput and ETA",
+		Long: `This command shows the in-progress replica rebuilds of all the volumes, with the replica being rebuilt, its node, the replica it is rebuilt from, and the progress reported by the engine.
+
+The progress is sampled twice, --` + consts.CmdOptInterval + ` apart, to estimate the throughput of each rebuild and the remaining time at that throughput. The progress is reported in whole percents, so the interval must be long enough for the progress to change; the throughput is unknown for the rebuilds starting between the samples. Use --` + consts.CmdOptInterval + `=0 to only show the progress.`,
+		Example: `$ longhornctl rebuild status
+INFO[2024-07-16T20:05:11+08:00] Initializing rebuild status getter
+INFO[2024-07-16T20:05:11+08:00] Running rebuild status getter
+VOLUME        REPLICA                  NODE           SOURCE                   PROGRESS  THROUGHPUT  ETA
+pvc-3b9d3a5e  pvc-3b9d3a5e-r-5c1e9a20  ip-10-0-2-142  pvc-3b9d3a5e-r-0a7f1b3c  42%       118.0MiB/s  4m12s
+pvc-9f0c2d41  pvc-9f0c2d41-r-7e2b8c19  ip-10-0-2-217  pvc-9f0c2d41-r-1d4e6f80  3%        -           -
+INFO[2024-07-16T20:05:21+08:00] Completed rebuild status getter
+
+$ longhornctl rebuild status --node=ip-10-0-2-142 -o json`,
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			statusGetter.KubeConfigPath = globalOpts.KubeConfigPath
+			statusGetter.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(utils.ValidateOutputFormat(outputFormat, consts.OutputFormatJSON, consts.OutputFormatYAML))
+			utils.CheckErr(statusGetter.Validate())
+
+			logrus.Info("Initializing rebuild status getter")
+			if err := statusGetter.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize rebuild status getter"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			logrus.Info("Running rebuild status getter")
+			rebuilds, err := statusGetter.Run(ctx)
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to run rebuild status getter"))
+			}
+
+			utils.CheckErr(printReplicaRebuilds(rebuilds, outputFormat))
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Completed rebuild status getter")
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&outputFormat, consts.CmdOptOutput, "o", "", fmt.Sprintf("Output format of the result (%s, %s).", consts.OutputFormatJSON, consts.OutputFormatYAML))
+	cmd.Flags().StringVar(&statusGetter.NodeName, consts.CmdOptNode, "", "Only show the rebuilds of the replicas on the node.")
+	cmd.Flags().DurationVar(&statusGetter.Interval, consts.CmdOptInterval, 10*time.Second, "Interval between the two samples of the progress estimating the throughput.")
+	cmd.Flags().StringVar(&statusGetter.LonghornNamespace, consts.CmdOptLonghornNamespace, consts.LonghornNamespace, "Namespace where Longhorn is deployed.")
+
+	return cmd
+}
+
+func newCmdRebuildThrottle(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var throttler = rebuild.Throttler{}
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdThrottle,
+		Short: "Throttle the replica rebuilds, or restore the settings",
+		Long: `This command throttles the replica rebuilds by updating the Longhorn settings:
+- --` + consts.CmdOptConcurrency + `: concurrent-replica-rebuild-per-node-limit, the number of replicas rebuilt at the same time on each node. 0 stops starting new rebuilds, which also blocks the eviction and the data locality.
+- --` + consts.CmdOptLimit + `: ` + consts.SettingNameReplicaRebuildingBandwidthLimit + `, the write bandwidth of each rebuild per second, in MiB and at least 1Mi, or 0 for unlimited. The setting is only available in the Longhorn versions supporting it, and applies to the v2 data engine volumes.
+
+The settings are cluster-wide; the concurrency limit applies to each node. The value of each setting before the first throttle is kept in the ` + consts.AnnotationRebuildThrottlePrevious + ` annotation of the setting, and --` + consts.CmdOptReset + ` restores it. Schedule the throttle and the reset around the business hours, for example from a CronJob, to rebuild at full speed outside of them.`,
+		Example: `$ longhornctl rebuild throttle --limit=100Mi --concurrency=1
+INFO[2024-07-16T09:00:02+08:00] Initializing rebuild throttler
+INFO[2024-07-16T09:00:02+08:00] Running rebuild throttler
+SETTING                                    PREVIOUS  VALUE
+concurrent-replica-rebuild-per-node-limit  5         1
+replica-rebuilding-bandwidth-limit         0         100
+INFO[2024-07-16T09:00:02+08:00] Completed rebuild throttler
+
+$ longhornctl rebuild throttle --reset`,
+		Annotations: map[string]string{consts.CmdAnnotationAudit: "true"},
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			throttler.KubeConfigPath = globalOpts.KubeConfigPath
+			throttler.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(utils.ValidateOutputFormat(outputFormat, consts.OutputFormatJSON, consts.OutputFormatYAML))
+			utils.CheckErr(throttler.Validate())
+
+			logrus.Info("Initializing rebuild throttler")
+			if err := throttler.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize rebuild throttler"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Running rebuild throttler")
+			changes, err := throttler.Run()
+			if len(changes) > 0 {
+				utils.CheckErr(printRebuildSettingChanges(changes, outputFormat))
+			}
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to run rebuild throttler"))
+			}
+			if len(changes) == 0 {
+				logrus.Info("No setting was throttled, nothing to restore")
+			}
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Completed rebuild throttler")
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&outputFormat, consts.CmdOptOutput, "o", "", fmt.Sprintf("Output format of the result (%s, %s).", consts.OutputFormatJSON, consts.OutputFormatYAML))
+	cmd.Flags().IntVar(&throttler.Concurrency, consts.CmdOptConcurrency, -1, "Number of replicas rebuilt at the same time on each node. Unchanged when not set.")
+	cmd.Flags().StringVar(&throttler.Limit, consts.CmdOptLimit, "", "Write bandwidth of each rebuild per second, for example 100Mi, or 0 for unlimited. Unchanged when not set.")
+	cmd.Flags().BoolVar(&throttler.Reset, consts.CmdOptReset, false, "Restore the settings to their values before the throttle.")
+	cmd.Flags().StringVar(&throttler.LonghornNamespace, consts.CmdOptLonghornNamespace, consts.LonghornNamespace, "Namespace where Longhorn is deployed.")
+
+	return cmd
+}
+
+func printReplicaRebuilds(rebuilds []types.ReplicaRebuild, outputFormat string) error {
+	if printed, err := utils.PrintStructuredResult(outputFormat, types.ResultKindReplicaRebuildList, rebuilds); printed || err != nil {
+		return err
+	}
+
+	if len(rebuilds) == 0 {
+		fmt.Println("No replica is being rebuilt")
+		return nil
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "VOLUME\tREPLICA\tNODE\tSOURCE\tPROGRESS\tTHROUGHPUT\tETA")
+	for _, rebuild := range rebuilds {
+		throughput, eta := "-", "-"
+		if rebuild.ThroughputBytesPerSecond > 0 {
+			throughput = utils.FormatBytes(rebuild.ThroughputBytesPerSecond) + "/s"
+		}
+		if rebuild.ETASeconds > 0 {
+			eta = (time.Duration(rebuild.ETASeconds) * time.Second).String()
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%d%%\t%s\t%s\n", rebuild.Volume, rebuild.Replica, rebuild.Node, rebuild.Source, rebuild.Progress, throughput, eta)
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+
+	for _, rebuild := range rebuilds {
+		if rebuild.Error != "" {
+			logrus.WithFields(logrus.Fields{"volume": rebuild.Volume, "replica": rebuild.Replica}).Warnf("Rebuild error: %v", rebuild.Error)
+		}
+	}
+	return nil
+}
+
+func printRebuildSettingChanges(changes []types.RebuildSettingChange, outputFormat string) error {
+	if printed, err := utils.PrintStructuredResult(outputFormat, types.ResultKindRebuildSettingChangeList, changes); printed || err != nil {
+		return err
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "SETTING\tPREVIOUS\tVALUE")
+	for _, change := range changes {
+		fmt.Fprintf(writer, "%s\t%s\t%s\n", change.Name, change.Previous, change.Value)
+	}
+	return writer.Flush()
+}
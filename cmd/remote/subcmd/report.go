@@ -0,0 +1,395 @@
+package subcmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/remote/capacity"
+	"github.com/longhorn/cli/pkg/remote/protection"
+	"github.com/longhorn/cli/pkg/remote/topology"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+)
+
+func NewCmdReport(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdReport,
+		Short: "Longhorn reporting operations",
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.AddCommand(newCmdReportCapacity(globalOpts))
+	cmd.AddCommand(newCmdReportProtection(globalOpts))
+	cmd.AddCommand(newCmdReportTopology(globalOpts))
+
+	return cmd
+}
+
+func newCmdReportCapacity(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var forecaster = capacity.Forecaster{}
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdCapacity,
+		Short: "Report the capacity of the disks and nodes, and forecast when they reach the storage threshold",
+		Long: `This command reports the maximum, reserved, available, used and scheduled storage of each disk, as reported by the Longhorn nodes, and of each node as the total of its disks.
+
+The threshold of a disk is its reserved storage, or the storage-minimal-available-percentage setting of its maximum storage when it is larger. Longhorn stops scheduling replicas to a disk once its available storage is below the threshold. A disk is flagged as over-provisioned when its scheduled storage exceeds the storage-over-provisioning-percentage setting of its maximum storage without the reserved storage.
+
+When --prometheus-url is given, the usage growth of each disk is queried from the longhorn_disk_usage_bytes metric over the --growth-window, and the time until the available storage reaches the threshold is estimated at that rate.`,
+		Example: `$ longhornctl report capacity --prometheus-url http://prometheus.monitoring:9090 --growth-window 30d
+INFO[2024-07-16T17:23:47+08:00] Initializing capacity forecaster
+INFO[2024-07-16T17:23:47+08:00] Running capacity forecaster
+NODE           DISK                                       MAXIMUM   AVAILABLE  USED      SCHEDULED  THRESHOLD  GROWTH/DAY  DAYS LEFT  THRESHOLD REACHED AT  MESSAGE
+ip-10-0-1-12   default-disk-8a2c7d1b9e0f4a3c5d6e7f8a9b0c  97.9GiB   41.2GiB    56.7GiB   60.0GiB    24.5GiB    1.1GiB      15.2       2024-07-31T22:11:02Z
+ip-10-0-2-123  default-disk-1f2e3d4c5b6a7f8e9d0c1b2a3f4e  97.9GiB   20.1GiB    77.8GiB   140.0GiB*  24.5GiB    0B          -          -                     Usage is not growing
+
+NODE           MAXIMUM   AVAILABLE  USED      SCHEDULED  THRESHOLD  GROWTH/DAY  DAYS LEFT  THRESHOLD REACHED AT  MESSAGE
+ip-10-0-1-12   97.9GiB   41.2GiB    56.7GiB   60.0GiB    24.5GiB    1.1GiB      15.2       2024-07-31T22:11:02Z
+ip-10-0-2-123  97.9GiB   20.1GiB    77.8GiB   140.0GiB*  24.5GiB    0B          -          -                     Usage is not growing
+
+* Scheduled beyond the storage-over-provisioning-percentage setting.
+INFO[2024-07-16T17:23:48+08:00] Completed capacity forecaster`,
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			forecaster.KubeConfigPath = globalOpts.KubeConfigPath
+			forecaster.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(utils.ValidateOutputFormat(outputFormat, consts.OutputFormatHTML, consts.OutputFormatJSON, consts.OutputFormatMarkdown, consts.OutputFormatYAML))
+
+			if err := forecaster.Validate(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to validate capacity forecaster options"))
+			}
+
+			logrus.Info("Initializing capacity forecaster")
+			if err := forecaster.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize capacity forecaster"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Running capacity forecaster")
+			report, err := forecaster.Run()
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to run capacity forecaster"))
+			}
+
+			utils.CheckErr(printCapacityReport(report, outputFormat))
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Completed capacity forecaster")
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&outputFormat, consts.CmdOptOutput, "o", "", fmt.Sprintf("Output format (%s, %s, %s, %s). Defaults to tables.", consts.OutputFormatHTML, consts.OutputFormatJSON, consts.OutputFormatMarkdown, consts.OutputFormatYAML))
+	cmd.Flags().StringVar(&forecaster.LonghornNamespace, consts.CmdOptLonghornNamespace, consts.LonghornNamespace, "Namespace where Longhorn is deployed.")
+	cmd.Flags().StringVar(&forecaster.GrowthWindow, consts.CmdOptGrowthWindow, "30d", "Window of the historical usage growth, as a Prometheus duration such as 30d, 2w or 12h.")
+	cmd.Flags().StringVar(&forecaster.PrometheusURL, consts.CmdOptPrometheusURL, "", "URL of the Prometheus scraping the Longhorn metrics, to forecast the usage growth.")
+
+	return cmd
+}
+
+func newCmdReportProtection(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var protectionReporter = protection.Reporter{}
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdProtection + " [volume-name...]",
+		Short: "Report the volumes not protected by recurring jobs and recent backups",
+		Long: `This command reports the recurring snapshot and backup jobs applying to each volume, and its latest completed backup. Without volume names, all the volumes are reported.
+
+The recurring jobs apply to a volume by name or by group, with the recurring-job.longhorn.io/<job> and recurring-job-group.longhorn.io/<group> labels of the volume. The jobs of the default group apply to the volumes without these labels. A volume is flagged when:
+- No recurring snapshot or backup job applies to it.
+- It has no completed backup, or its latest completed backup is older than --` + consts.CmdOptMaxBackupAge + `.
+- At least --` + consts.CmdOptMaxBackupFailures + ` backups failed since its latest completed backup.
+
+Use --output json to feed the report to a monitoring system.`,
+		Example: `$ longhornctl report protection --max-backup-age 24h
+INFO[2024-07-16T17:23:47+08:00] Initializing protection reporter
+INFO[2024-07-16T17:23:47+08:00] Running protection reporter
+VOLUME                                    STATE     SNAPSHOT JOBS    BACKUP JOBS   LAST BACKUP AT        FAILED BACKUPS  ISSUES
+pvc-0b1c6f4a-7e0a-4a7b-9d2e-5f1f3f0c2e11  attached  snapshot-hourly  backup-daily  2024-07-16T02:00:11Z  0               0
+pvc-6d1e2f3a-4b5c-4d6e-8f7a-9b0c1d2e3f4a  detached  -                -             -                     0               2
+pvc-9c8b7a6f-5e4d-4c3b-8a2f-1e0d9c8b7a6f  attached  -                backup-daily  2024-07-13T02:00:09Z  3               2
+
+VOLUME                                    ISSUE
+pvc-6d1e2f3a-4b5c-4d6e-8f7a-9b0c1d2e3f4a  No recurring snapshot or backup job
+pvc-6d1e2f3a-4b5c-4d6e-8f7a-9b0c1d2e3f4a  No completed backup
+pvc-9c8b7a6f-5e4d-4c3b-8a2f-1e0d9c8b7a6f  Latest completed backup backup-1a2b3c4d5e6f4a7b is older than 24h0m0s
+pvc-9c8b7a6f-5e4d-4c3b-8a2f-1e0d9c8b7a6f  3 backups failed since the latest completed backup: backup target is unavailable
+INFO[2024-07-16T17:23:48+08:00] Completed protection reporter`,
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			protectionReporter.KubeConfigPath = globalOpts.KubeConfigPath
+			protectionReporter.LogLevel = globalOpts.LogLevel
+			protectionReporter.VolumeNames = args
+
+			utils.CheckErr(utils.ValidateOutputFormat(outputFormat, consts.OutputFormatHTML, consts.OutputFormatJSON, consts.OutputFormatMarkdown, consts.OutputFormatYAML))
+
+			if err := protectionReporter.Validate(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to validate protection reporter options"))
+			}
+
+			logrus.Info("Initializing protection reporter")
+			if err := protectionReporter.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize protection reporter"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Running protection reporter")
+			volumes, err := protectionReporter.Run()
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to run protection reporter"))
+			}
+
+			utils.CheckErr(printProtectionVolumes(volumes, outputFormat))
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Completed protection reporter")
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&outputFormat, consts.CmdOptOutput, "o", "", fmt.Sprintf("Output format (%s, %s, %s, %s). Defaults to tables.", consts.OutputFormatHTML, consts.OutputFormatJSON, consts.OutputFormatMarkdown, consts.OutputFormatYAML))
+	cmd.Flags().StringVar(&protectionReporter.LonghornNamespace, consts.CmdOptLonghornNamespace, consts.LonghornNamespace, "Namespace where Longhorn is deployed.")
+	cmd.Flags().DurationVar(&protectionReporter.MaxBackupAge, consts.CmdOptMaxBackupAge, 24*time.Hour, "Flag the volumes whose latest completed backup is older than this duration, for example 48h. 0 disables the check.")
+	cmd.Flags().IntVar(&protectionReporter.MaxBackupFailures, consts.CmdOptMaxBackupFailures, 2, "Flag the volumes with at least this number of failed backups since their latest completed backup. 0 disables the check.")
+
+	cmd.ValidArgsFunction = completeVolumeNames(globalOpts, &protectionReporter.LonghornNamespace)
+
+	return cmd
+}
+
+func newCmdReportTopology(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var topologyReporter = topology.Reporter{}
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdTopology + " [volume-name...]",
+		Short: "Report the nodes, zones and regions of the replicas of the volumes",
+		Long: `This command maps each volume to its replicas, and the replicas to their nodes, zones and regions, as reported by the Longhorn nodes. Without volume names, all the volumes are reported.
+
+The effective node and zone anti-affinity of each volume is the one of the volume, or the replica-soft-anti-affinity and replica-zone-soft-anti-affinity settings when the volume does not override them. Only the usable replicas, which are scheduled, not failed and not being deleted, are counted. A volume is flagged when:
+- Its usable replicas share a node or a zone while the anti-affinity is hard, which Longhorn does not schedule, for example after nodes were relabeled.
+- A single failure domain holds all its usable replicas: a single replica, a single node, or a single zone while the cluster has several zones.
+
+Use --output json to feed the report to a policy engine.`,
+		Example: `$ longhornctl report topology
+INFO[2024-07-16T17:23:47+08:00] Initializing topology reporter
+INFO[2024-07-16T17:23:47+08:00] Running topology reporter
+VOLUME                                    STATE     REPLICAS  NODES                        ZONES                     NODE/ZONE ANTI-AFFINITY  ISSUES
+pvc-0b1c6f4a-7e0a-4a7b-9d2e-5f1f3f0c2e11  attached  3/3       ip-10-0-1-12,ip-10-0-2-123   us-east-1a,us-east-1b     hard/soft                1
+pvc-6d1e2f3a-4b5c-4d6e-8f7a-9b0c1d2e3f4a  detached  2/2       ip-10-0-1-12,ip-10-0-1-57    us-east-1a                hard/soft                1
+
+VOLUME                                    ISSUE
+pvc-0b1c6f4a-7e0a-4a7b-9d2e-5f1f3f0c2e11  2 replicas share node ip-10-0-2-123 while node anti-affinity is hard
+pvc-6d1e2f3a-4b5c-4d6e-8f7a-9b0c1d2e3f4a  All 2 usable replicas are in zone "us-east-1a"
+INFO[2024-07-16T17:23:48+08:00] Completed topology reporter`,
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			topologyReporter.KubeConfigPath = globalOpts.KubeConfigPath
+			topologyReporter.LogLevel = globalOpts.LogLevel
+			topologyReporter.VolumeNames = args
+
+			utils.CheckErr(utils.ValidateOutputFormat(outputFormat, consts.OutputFormatHTML, consts.OutputFormatJSON, consts.OutputFormatMarkdown, consts.OutputFormatYAML))
+
+			logrus.Info("Initializing topology reporter")
+			if err := topologyReporter.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize topology reporter"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Running topology reporter")
+			volumes, err := topologyReporter.Run()
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to run topology reporter"))
+			}
+
+			utils.CheckErr(printTopologyVolumes(volumes, outputFormat))
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Completed topology reporter")
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&outputFormat, consts.CmdOptOutput, "o", "", fmt.Sprintf("Output format (%s, %s, %s, %s). Defaults to tables.", consts.OutputFormatHTML, consts.OutputFormatJSON, consts.OutputFormatMarkdown, consts.OutputFormatYAML))
+	cmd.Flags().StringVar(&topologyReporter.LonghornNamespace, consts.CmdOptLonghornNamespace, consts.LonghornNamespace, "Namespace where Longhorn is deployed.")
+
+	cmd.ValidArgsFunction = completeVolumeNames(globalOpts, &topologyReporter.LonghornNamespace)
+
+	return cmd
+}
+
+func printTopologyVolumes(volumes []types.TopologyVolume, outputFormat string) error {
+	if printed, err := utils.PrintStructuredResult(outputFormat, types.ResultKindTopologyVolumeList, volumes); printed || err != nil {
+		return err
+	}
+
+	join := func(values []string) string {
+		if len(values) == 0 {
+			return "-"
+		}
+		quoted := make([]string, 0, len(values))
+		for _, value := range values {
+			if value == "" {
+				value = `""`
+			}
+			quoted = append(quoted, value)
+		}
+		return strings.Join(quoted, ",")
+	}
+
+	summary := utils.ReportSection{
+		Title:   "Volumes",
+		Empty:   "No volumes.",
+		Headers: []string{"VOLUME", "STATE", "REPLICAS", "NODES", "ZONES", "NODE/ZONE ANTI-AFFINITY", "ISSUES"},
+	}
+	issues := utils.ReportSection{
+		Title:   "Remediation",
+		Empty:   "No anti-affinity violations or single failure domains.",
+		Headers: []string{"VOLUME", "ISSUE"},
+	}
+	for _, volume := range volumes {
+		usableReplicas := 0
+		for _, replica := range volume.Replicas {
+			if replica.Usable {
+				usableReplicas++
+			}
+		}
+		summary.Rows = append(summary.Rows, []string{
+			volume.Name, volume.State, fmt.Sprintf("%d/%d", usableReplicas, volume.NumberOfReplicas), join(volume.Nodes), join(volume.Zones),
+			volume.NodeAntiAffinity + "/" + volume.ZoneAntiAffinity, fmt.Sprint(len(volume.Violations) + len(volume.SingleFailureDomains)),
+		})
+
+		for _, issue := range volume.Violations {
+			issues.Rows = append(issues.Rows, []string{volume.Name, issue})
+		}
+		for _, issue := range volume.SingleFailureDomains {
+			issues.Rows = append(issues.Rows, []string{volume.Name, issue})
+		}
+	}
+
+	return utils.PrintReportDocument(outputFormat, newReportDocument("Replica topology report", summary, issues))
+}
+
+func printProtectionVolumes(volumes []types.ProtectionVolume, outputFormat string) error {
+	if printed, err := utils.PrintStructuredResult(outputFormat, types.ResultKindProtectionVolumeList, volumes); printed || err != nil {
+		return err
+	}
+
+	join := func(values []string) string {
+		if len(values) == 0 {
+			return "-"
+		}
+		return strings.Join(values, ",")
+	}
+
+	summary := utils.ReportSection{
+		Title:   "Volumes",
+		Empty:   "No volumes.",
+		Headers: []string{"VOLUME", "STATE", "SNAPSHOT JOBS", "BACKUP JOBS", "LAST BACKUP AT", "FAILED BACKUPS", "ISSUES"},
+	}
+	issues := utils.ReportSection{
+		Title:   "Remediation",
+		Empty:   "All volumes are protected.",
+		Headers: []string{"VOLUME", "ISSUE"},
+	}
+	for _, volume := range volumes {
+		lastBackupAt := volume.LastBackupAt
+		if lastBackupAt == "" {
+			lastBackupAt = "-"
+		}
+		summary.Rows = append(summary.Rows, []string{
+			volume.Name, volume.State, join(volume.SnapshotJobs), join(volume.BackupJobs), lastBackupAt, fmt.Sprint(volume.FailedBackups), fmt.Sprint(len(volume.Issues)),
+		})
+
+		for _, issue := range volume.Issues {
+			issues.Rows = append(issues.Rows, []string{volume.Name, issue})
+		}
+	}
+
+	return utils.PrintReportDocument(outputFormat, newReportDocument("Data protection report", summary, issues))
+}
+
+func printCapacityReport(report *types.CapacityReport, outputFormat string) error {
+	if printed, err := utils.PrintStructuredResult(outputFormat, types.ResultKindCapacityReport, report); printed || err != nil {
+		return err
+	}
+
+	overProvisioned := false
+	columns := func(forecast types.CapacityForecast) []string {
+		scheduled := utils.FormatBytes(forecast.Scheduled)
+		if forecast.OverProvisioned {
+			scheduled += "*"
+			overProvisioned = true
+		}
+
+		growth, days, reachedAt := "-", "-", "-"
+		if forecast.GrowthPerDay != nil {
+			growth = utils.FormatBytes(*forecast.GrowthPerDay)
+		}
+		if forecast.DaysUntilThreshold != nil {
+			days = fmt.Sprintf("%.1f", *forecast.DaysUntilThreshold)
+		}
+		if forecast.ThresholdReachedAt != "" {
+			reachedAt = forecast.ThresholdReachedAt
+		}
+
+		return []string{
+			utils.FormatBytes(forecast.Maximum), utils.FormatBytes(forecast.Available), utils.FormatBytes(forecast.Used), scheduled,
+			utils.FormatBytes(forecast.Threshold), growth, days, reachedAt, forecast.Message,
+		}
+	}
+
+	headers := []string{"MAXIMUM", "AVAILABLE", "USED", "SCHEDULED", "THRESHOLD", "GROWTH/DAY", "DAYS LEFT", "THRESHOLD REACHED AT", "MESSAGE"}
+	disks := utils.ReportSection{
+		Title:   "Disks",
+		Empty:   "No disks.",
+		Headers: append([]string{"NODE", "DISK"}, headers...),
+	}
+	for _, disk := range report.Disks {
+		disks.Rows = append(disks.Rows, append([]string{disk.Node, disk.Disk}, columns(disk)...))
+	}
+	nodes := utils.ReportSection{
+		Title:   "Nodes",
+		Empty:   "No nodes.",
+		Headers: append([]string{"NODE"}, headers...),
+	}
+	for _, node := range report.Nodes {
+		nodes.Rows = append(nodes.Rows, append([]string{node.Node}, columns(node)...))
+	}
+
+	document := newReportDocument("Capacity report", disks, nodes)
+	if overProvisioned {
+		document.Notes = append(document.Notes, "* Scheduled beyond the storage-over-provisioning-percentage setting.")
+	}
+	return utils.PrintReportDocument(outputFormat, document)
+}
+
+// newReportDocument returns the document of a report command, rendered as tables without
+// output format, or as a shareable document with the html and markdown output formats.
+func newReportDocument(description string, sections ...utils.ReportSection) *utils.ReportDocument {
+	return &utils.ReportDocument{
+		Title:       consts.CmdLonghornctlRemote + " report",
+		Description: description,
+		GeneratedAt: time.Now(),
+		Sections:    sections,
+	}
+}
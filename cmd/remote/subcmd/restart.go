@@ -0,0 +1,109 @@
+package subcmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/remote/restart"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+)
+
+func NewCmdRestart(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var restarter = restart.Restarter{}
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdRestart,
+		Short: "Rolling restart of the pods of a Longhorn component",
+		Long: `This command restarts the pods of a Longhorn component one at a time, instead of deleting them all with kubectl. Each replacement pod must be ready before the next pod is restarted.
+
+The pods are restarted node by node. For the CSI component, the CSI plugin pods are restarted before the CSI sidecar pods, so the sidecars reconnect to the restarted plugins.
+
+Restarting an instance manager stops the engines and replicas running in it, so before each instance manager:
+- The restart is refused when an attached volume has its engine on the node. Move its workload to another node, or detach it first.
+- The volumes with a replica on the node must be healthy, so no volume depends on the replica being restarted. A volume being rebuilt is waited for.
+After each instance manager, the volumes with a replica on the node are waited for to be rebuilt and healthy again.
+
+With --` + consts.CmdOptDryRun + `, the pods are only printed in the order of the restart.`,
+		Example: `$ longhornctl restart --component=instance-manager --node=ip-10-0-2-123
+INFO[2024-07-16T17:40:12+08:00] Initializing component restarter
+NODE           POD                                                 OWNER
+ip-10-0-2-123  instance-manager-b87f10b867a1a8f2ce1c8ac0b8ba0b26   InstanceManager/instance-manager-b87f10b867a1a8f2ce1c8ac0b8ba0b26
+This will restart 1 pod(s) of component instance-manager in the order above.
+Do you want to continue? [y/N]: y
+INFO[2024-07-16T17:40:14+08:00] Running component restarter
+INFO[2024-07-16T17:40:14+08:00] Restarting pod                                node=ip-10-0-2-123 pod=instance-manager-b87f10b867a1a8f2ce1c8ac0b8ba0b26
+INFO[2024-07-16T17:40:14+08:00] Waiting for the replacement pod to be ready   node=ip-10-0-2-123 pod=instance-manager-b87f10b867a1a8f2ce1c8ac0b8ba0b26
+INFO[2024-07-16T17:40:31+08:00] Waiting for volumes pvc-48a6457d-585e-423b-b530-bbc68a5f948a with a replica on the node to be healthy  node=ip-10-0-2-123
+INFO[2024-07-16T17:41:52+08:00] Completed component restarter`,
+		Args: cobra.NoArgs,
+
+		Annotations: map[string]string{consts.CmdAnnotationAudit: "true"},
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			restarter.KubeConfigPath = globalOpts.KubeConfigPath
+			restarter.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(restarter.Validate())
+
+			logrus.Info("Initializing component restarter")
+			if err := restarter.Init(); err != nil {
+				utils.CheckErr(errors.Wrapf(err, "Failed to initialize component restarter for component %s", restarter.Component))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			plan := restarter.Plan()
+			utils.CheckErr(printRestartSteps(plan))
+
+			if dryRun {
+				logrus.Info("Dry run, no pod is restarted")
+				return
+			}
+
+			utils.CheckErr(utils.Confirm(globalOpts, fmt.Sprintf("This will restart %d pod(s) of component %s in the order above.", len(plan), restarter.Component)))
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			logrus.Info("Running component restarter")
+			if err := restarter.Run(ctx); err != nil {
+				utils.CheckErr(errors.Wrapf(err, "Failed to run component restarter for component %s", restarter.Component))
+			}
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Completed component restarter")
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVar(&restarter.Component, consts.CmdOptComponent, "", fmt.Sprintf("Component to restart (%s, %s, %s).", restart.ComponentManager, restart.ComponentCSI, restart.ComponentInstanceManager))
+	cmd.Flags().StringVar(&restarter.NodeName, consts.CmdOptNode, "", "Only restart the pods on the node. Defaults to all the nodes.")
+	cmd.Flags().BoolVar(&dryRun, consts.CmdOptDryRun, false, "Only print the pods in the order of the restart.")
+	cmd.Flags().DurationVar(&restarter.Timeout, consts.CmdOptTimeout, 10*time.Minute, "Maximum time to wait for each replacement pod, and for the volumes before and after each instance manager restart.")
+	cmd.Flags().StringVar(&restarter.LonghornNamespace, consts.CmdOptLonghornNamespace, consts.LonghornNamespace, "Namespace where Longhorn is deployed.")
+
+	return cmd
+}
+
+func printRestartSteps(steps []types.RestartStep) error {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "NODE\tPOD\tOWNER")
+	for _, step := range steps {
+		fmt.Fprintf(writer, "%s\t%s\t%s\n", step.Node, step.Pod, step.Owner)
+	}
+	return writer.Flush()
+}
@@ -0,0 +1,114 @@
+package subcmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/remote/async"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+)
+
+func NewCmdResult(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var getter = async.Getter{}
+	var outputFormat string
+	var wait bool
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdResult + " <run-id>",
+		Short: "Get the result of a command submitted with --" + consts.CmdOptNoWait,
+		Long: `This command prints the structured result of a command submitted with --` + consts.CmdOptNoWait + `, as written by its Job to the ` + consts.AppNameAsyncRun + `-<run-id>` + consts.AsyncRunResultNameSuffix + ` ConfigMap, in the namespace of --` + consts.CmdOptNamespace + `. The result is the one printed by the command with -o json, wrapped with its schema version and kind.
+
+The command fails when the run is not completed, unless --` + consts.CmdOptWait + ` waits for it, or when the run failed. Commands without a structured result only log, see the logs of the Job of the run.`,
+		Example: `$ longhornctl result 5f2a9c1e
+INFO[2024-07-16T17:41:10+08:00] Initializing async getter
+INFO[2024-07-16T17:41:10+08:00] Running async getter                          run=5f2a9c1e
+kind: LogCollections
+result:
+  ip-10-0-2-123:
+    info:
+    - Service iscsid is running
+...
+schemaVersion: v1
+INFO[2024-07-16T17:41:10+08:00] Completed async getter                        run=5f2a9c1e
+
+$ longhornctl result 5f2a9c1e --wait --timeout=30m -o json`,
+		Args: cobra.ExactArgs(1),
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			getter.KubeConfigPath = globalOpts.KubeConfigPath
+			getter.Namespace = globalOpts.Namespace
+			getter.LogLevel = globalOpts.LogLevel
+			getter.RunID = args[0]
+
+			utils.CheckErr(utils.ValidateOutputFormat(outputFormat, consts.OutputFormatJSON, consts.OutputFormatYAML))
+			utils.CheckErr(getter.Validate())
+			if wait && getter.Timeout <= 0 {
+				utils.CheckErr(errors.Errorf("--%s must be positive", consts.CmdOptTimeout))
+			}
+
+			logrus.Info("Initializing async getter")
+			if err := getter.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize async getter"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			logrus.WithField("run", getter.RunID).Info("Running async getter")
+			var run *types.AsyncRun
+			var err error
+			if wait {
+				run, err = getter.Wait()
+			} else {
+				run, err = getter.Get()
+			}
+			if err != nil {
+				utils.CheckErr(errors.Wrapf(err, "Failed to get run %s", getter.RunID))
+			}
+
+			switch run.Status {
+			case consts.AsyncRunStatusFailed:
+				utils.CheckErr(errors.Errorf("run %s failed: %s, see the logs of Job %s/%s", run.ID, run.Message, run.Namespace, run.Job))
+			case consts.AsyncRunStatusSucceeded:
+			default:
+				utils.CheckErr(errors.Errorf("run %s is %s, use --%s to wait for it to complete", run.ID, run.Status, consts.CmdOptWait))
+			}
+
+			result, err := getter.GetResult(run)
+			utils.CheckErr(err)
+			if len(result) == 0 {
+				logrus.Infof("Run %s has no structured result, see the logs of Job %s/%s", run.ID, run.Namespace, run.Job)
+				return
+			}
+
+			if outputFormat == consts.OutputFormatJSON {
+				fmt.Println(string(result))
+				return
+			}
+			yamlResult, err := yaml.JSONToYAML(result)
+			if err != nil {
+				utils.CheckErr(errors.Wrapf(err, "Failed to convert result of run %s to YAML", run.ID))
+			}
+			fmt.Print(string(yamlResult))
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logrus.WithField("run", getter.RunID).Info("Completed async getter")
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&outputFormat, consts.CmdOptOutput, "o", "", fmt.Sprintf("Output format of the result (%s, %s). Defaults to %s.", consts.OutputFormatJSON, consts.OutputFormatYAML, consts.OutputFormatYAML))
+	cmd.Flags().BoolVar(&wait, consts.CmdOptWait, false, "Wait for the run to complete before getting its result.")
+	cmd.Flags().DurationVar(&getter.Timeout, consts.CmdOptTimeout, time.Hour, "Maximum time to wait for the run to complete, with --"+consts.CmdOptWait+".")
+
+	return cmd
+}
@@ -0,0 +1,33 @@
+package subcmd
+
+import (
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/longhorn/cli/pkg/consts"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// StartRun starts the run of the command, so the resources it creates in the cluster are labeled
+// with the operation and the run ID, and owned by the anchor of the run. For example, the
+// operation of 'longhornctl export replica' is export-replica.
+func StartRun(cmd *cobra.Command) {
+	operation := strings.TrimPrefix(getOperationLockName(cmd), consts.CmdLonghornctlRemote+"-")
+
+	run, err := kubeutils.StartRun(operation)
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to start run, the resources it creates are not labeled with it")
+		return
+	}
+	logrus.Debugf("Started run %v of operation %v", run.ID, run.Operation)
+}
+
+// CompleteRun releases the anchors of the run once the command completes. The anchors of a failed
+// run, exiting through utils.CheckErr, are kept for 'longhornctl cleanup all' to remove the
+// resources it left behind.
+func CompleteRun() {
+	kubeutils.CompleteRun()
+}
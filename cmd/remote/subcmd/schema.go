@@ -0,0 +1,79 @@
+package subcmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+)
+
+func NewCmdSchema() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdSchema,
+		Short: "Print the schemas of the structured outputs",
+	}
+
+	cmd.AddCommand(newCmdSchemaResults())
+
+	return cmd
+}
+
+func newCmdSchemaResults() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdResults + " [kind]",
+		Short: "Print the JSON Schema of the structured outputs",
+		Long: fmt.Sprintf(`This command prints the JSON Schema of the structured outputs of %[1]s, such as the preflight and check results and the reports, so downstream tooling can validate them.
+
+Every JSON and YAML output is wrapped with its schema version and kind:
+  schemaVersion: %[2]s
+  kind: LogCollections
+  result: ...
+
+The schema version changes when a field is removed or changes meaning, not when a field is added. Without a kind, the schemas of all the kinds are printed, keyed by the kind.
+
+Supported kinds: %[3]s.`, consts.CmdLonghornctlRemote, types.ResultSchemaVersion, strings.Join(utils.GetResultKinds(), ", ")),
+		Example: `$ longhornctl schema results LogCollections > preflight-result.schema.json
+$ longhornctl check preflight --output json > preflight-result.json`,
+		Args:      cobra.MaximumNArgs(1),
+		ValidArgs: utils.GetResultKinds(),
+
+		Run: func(cmd *cobra.Command, args []string) {
+			schema, err := getResultSchemas(args)
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to generate the result schema"))
+			}
+
+			jsonData, err := json.MarshalIndent(schema, "", "  ")
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to marshal the result schema"))
+			}
+			fmt.Println(string(jsonData))
+		},
+	}
+
+	return cmd
+}
+
+// getResultSchemas returns the schema of the kind given in the arguments, or the schemas of all
+// the kinds keyed by the kind.
+func getResultSchemas(args []string) (any, error) {
+	if len(args) == 1 {
+		return utils.GenerateResultSchema(args[0])
+	}
+
+	schemas := map[string]any{}
+	for _, kind := range utils.GetResultKinds() {
+		schema, err := utils.GenerateResultSchema(kind)
+		if err != nil {
+			return nil, err
+		}
+		schemas[kind] = schema
+	}
+	return schemas, nil
+}
@@ -0,0 +1,59 @@
+package subcmd
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/remote/update"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+)
+
+func NewCmdSelfUpdate(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var updater = update.Updater{}
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdSelfUpdate,
+		Short: fmt.Sprintf("Update %s to the latest or a specific release", consts.CmdLonghornctlRemote),
+		Long: fmt.Sprintf(`This command downloads the %[1]s release binary for the current operating system and architecture from the GitHub releases, verifies its published SHA256 checksum, and atomically replaces the running binary.
+Without --%[2]s, it updates to the latest release.`, consts.CmdLonghornctlRemote, consts.CmdOptVersion),
+		Example: `$ longhornctl self-update --check-only
+INFO[2024-07-16T17:17:38+08:00] Update available: v1.7.0 -> v1.7.1
+
+$ longhornctl self-update --version v1.7.1
+INFO[2024-07-16T17:17:40+08:00] Downloading longhornctl-linux-amd64 v1.7.1
+INFO[2024-07-16T17:17:45+08:00] Updated /usr/local/bin/longhornctl from v1.7.0 to v1.7.1`,
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			updater.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(updater.Validate())
+
+			if err := updater.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize updater"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			result, err := updater.Run()
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to update"))
+			}
+
+			if globalOpts.Quiet {
+				fmt.Println(result)
+				return
+			}
+			logrus.Info(result)
+		},
+	}
+
+	cmd.Flags().StringVar(&updater.Version, consts.CmdOptVersion, "", "Release version to update to, for example v1.7.1. Defaults to the latest release.")
+	cmd.Flags().BoolVar(&updater.CheckOnly, consts.CmdOptCheckOnly, false, "Only report whether an update is available, without downloading it.")
+
+	return cmd
+}
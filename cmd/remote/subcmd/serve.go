@@ -0,0 +1,82 @@
+package subcmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/remote/preflight"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+)
+
+func NewCmdServe(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var preflightServer = preflight.Server{}
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdServe,
+		Short: "Continuously run the preflight check in the cluster",
+		Long: `This command runs as a small controller that periodically re-runs the preflight check on all selected nodes, and on new nodes as they join the cluster.
+The result of each node is written to the ` + consts.AppNamePreflightServer + ` ConfigMap in the namespace, and exposed as Prometheus metrics on the /metrics endpoint.
+
+This command is intended to run inside the cluster, for example:
+  $ longhornctl generate job --name=` + consts.AppNamePreflightServer + ` -- serve`,
+		Example: `$ longhornctl serve --interval=1h --listen=:8080
+INFO[2024-07-16T17:17:38+08:00] Initializing preflight server
+INFO[2024-07-16T17:17:38+08:00] Serving metrics on :8080
+INFO[2024-07-16T17:17:38+08:00] Running preflight checker
+INFO[2024-07-16T17:17:42+08:00] Completed preflight checker on 3 nodes`,
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			preflightServer.Image = globalOpts.Image
+			preflightServer.KubeConfigPath = globalOpts.KubeConfigPath
+			preflightServer.Namespace = globalOpts.Namespace
+			preflightServer.NodeSelector = globalOpts.NodeSelector
+			preflightServer.MinNodes = globalOpts.MinNodes
+			preflightServer.PodCpu = globalOpts.PodCpu
+			preflightServer.PodMemory = globalOpts.PodMemory
+			preflightServer.PriorityClass = globalOpts.PriorityClass
+			preflightServer.Proxy = globalOpts.Proxy
+			preflightServer.NoProxy = globalOpts.NoProxy
+			preflightServer.Privileged = globalOpts.Privileged
+			preflightServer.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(preflightServer.Validate())
+
+			logrus.Info("Initializing preflight server")
+			if err := preflightServer.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize preflight server"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			if err := preflightServer.Run(ctx); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to run preflight server"))
+			}
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().DurationVar(&preflightServer.Interval, consts.CmdOptInterval, time.Hour, "Interval between preflight check runs.")
+	cmd.Flags().StringVar(&preflightServer.ListenAddress, consts.CmdOptListenAddress, "127.0.0.1:8080", "Address to serve the metrics endpoint on. Listen on all interfaces, for example :8080, for Prometheus to scrape it from other hosts.")
+	cmd.Flags().BoolVar(&preflightServer.EnableSpdk, consts.CmdOptEnableSpdk, false, "Enable checking of SPDK required packages, modules, and setup.")
+	cmd.Flags().IntVar(&preflightServer.HugePageSize, consts.CmdOptHugePageSize, 2048, "Specify the huge page size in MiB for SPDK.")
+	cmd.Flags().StringVar(&preflightServer.HugePageNodes, consts.CmdOptHugePageNodes, "", fmt.Sprintf("Specify a comma-separated (%s) list of huge page sizes in MiB per NUMA node, for example 0=1024,1=1024. Overrides --%s.", consts.CmdOptSeperator, consts.CmdOptHugePageSize))
+	cmd.Flags().StringVar(&preflightServer.UserspaceDriver, consts.CmdOptUserspaceDriver, "", "Userspace I/O driver for SPDK.")
+	cmd.Flags().StringVar(&preflightServer.CustomChecksFile, consts.CmdOptCustomChecks, "", "Path to a YAML file defining custom checks to run on each node.")
+	cmd.Flags().StringVar(&preflightServer.CustomChecksConfigMap, consts.CmdOptCustomChecksConfigMap, "", "Name of an existing ConfigMap in the namespace defining custom checks in the "+consts.FileNameCustomChecks+" key.")
+
+	return cmd
+}
@@ -0,0 +1,263 @@
+package subcmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/remote/snapshot"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+)
+
+func NewCmdSnapshot(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdSnapshot,
+		Short: "Longhorn snapshot operations",
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.AddCommand(newCmdSnapshotCreate(globalOpts))
+	cmd.AddCommand(newCmdSnapshotPromoteToCSI(globalOpts))
+	cmd.AddCommand(newCmdSnapshotImportFromCSI(globalOpts))
+
+	return cmd
+}
+
+func newCmdSnapshotCreate(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var snapshotCreator = snapshot.Creator{}
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdCreate,
+		Short: "Create snapshots of a volume, or of the volumes of a workload",
+		Long: `This command takes a snapshot of the volume given by --` + consts.CmdOptVolume + `, or of each Longhorn volume mounted by the running pods of the workload given by --` + consts.CmdOptWorkload + ` as <kind>/<namespace>/<name>, with the kinds deploy, sts, ds and pod.
+
+With --` + consts.CmdOptQuiesce + `, the snapshots are application-consistent:
+1. The pre-snapshot hook of each pod of the workload runs, the command of its ` + consts.AnnotationSnapshotPreHook + ` annotation, for example to flush and lock the tables of a database.
+2. The filesystem of each volume is frozen with fsfreeze on the node of the pods, from the Longhorn instance manager, blocking the writes until it is thawed.
+3. The snapshots are taken.
+4. The filesystems are thawed, and the post-snapshot hook of each pod runs, the command of its ` + consts.AnnotationSnapshotPostHook + ` annotation.
+The filesystems are thawed and the post-snapshot hooks run even when a previous step fails. The hooks run with /bin/sh -c in the container named by the ` + consts.AnnotationSnapshotHookContainer + ` annotation, defaulting to the first container of the pod. Set the annotations in the pod template of the workload to keep them on its new pods. The volumes used as block devices and the ReadWriteMany volumes are not frozen, and rely on the hooks only.
+
+The snapshot is named --` + consts.CmdOptName + `, defaulting to ` + consts.SnapshotNamePrefix + `<timestamp>, suffixed with -<volume> when the workload uses several volumes.`,
+		Example: `$ kubectl -n db annotate pod postgres-0 longhorn.io/snapshot-pre-hook='psql -U postgres -c CHECKPOINT'
+$ longhornctl snapshot create --quiesce --workload sts/db/postgres
+INFO[2024-07-16T17:17:38+08:00] Initializing snapshot creator
+INFO[2024-07-16T17:17:38+08:00] Running snapshot creator
+INFO[2024-07-16T17:17:38+08:00] Running longhorn.io/snapshot-pre-hook          container=postgres pod=db/postgres-0
+INFO[2024-07-16T17:17:39+08:00] Freezing the filesystem of the volume          volume=pvc-48a6457d-585e-423b-b530-bbc68a5f948a
+INFO[2024-07-16T17:17:39+08:00] Creating snapshot                              snapshot=longhornctl-20240716-091738 volume=pvc-48a6457d-585e-423b-b530-bbc68a5f948a
+INFO[2024-07-16T17:17:40+08:00] Thawing the filesystem of the volume           volume=pvc-48a6457d-585e-423b-b530-bbc68a5f948a
+VOLUME                                    SNAPSHOT                     PVC                 QUIESCE
+pvc-48a6457d-585e-423b-b530-bbc68a5f948a  longhornctl-20240716-091738  db/data-postgres-0  pre-hook,fsfreeze
+INFO[2024-07-16T17:17:40+08:00] Completed snapshot creator`,
+		Args: cobra.NoArgs,
+
+		Annotations: map[string]string{consts.CmdAnnotationAudit: "true"},
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			snapshotCreator.KubeConfigPath = globalOpts.KubeConfigPath
+			snapshotCreator.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(utils.ValidateOutputFormat(outputFormat, consts.OutputFormatJSON, consts.OutputFormatYAML))
+			utils.CheckErr(snapshotCreator.Validate())
+
+			logrus.Info("Initializing snapshot creator")
+			if err := snapshotCreator.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize snapshot creator"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Running snapshot creator")
+			snapshots, err := snapshotCreator.Run(context.Background())
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to run snapshot creator"))
+			}
+
+			utils.CheckErr(printCreatedSnapshots(snapshots, outputFormat))
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Completed snapshot creator")
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&outputFormat, consts.CmdOptOutput, "o", "", fmt.Sprintf("Output format of the result (%s, %s). Defaults to a table.", consts.OutputFormatJSON, consts.OutputFormatYAML))
+	cmd.Flags().StringVar(&snapshotCreator.LonghornNamespace, consts.CmdOptLonghornNamespace, consts.LonghornNamespace, "Namespace where Longhorn is deployed.")
+	cmd.Flags().StringVar(&snapshotCreator.Volume, consts.CmdOptVolume, "", "Name of the Longhorn volume to snapshot.")
+	cmd.Flags().StringVar(&snapshotCreator.Workload, consts.CmdOptWorkload, "", "Workload whose volumes to snapshot, as <kind>/<namespace>/<name>, for example deploy/default/nginx.")
+	cmd.Flags().StringVar(&snapshotCreator.Name, consts.CmdOptName, "", "Name of the snapshot. Defaults to "+consts.SnapshotNamePrefix+"<timestamp>.")
+	cmd.Flags().BoolVar(&snapshotCreator.Quiesce, consts.CmdOptQuiesce, false, "Run the snapshot hooks of the pods of the workload and freeze the filesystems of the volumes around the snapshots.")
+	cmd.Flags().DurationVar(&snapshotCreator.Timeout, consts.CmdOptTimeout, time.Minute, "Maximum time to wait for each hook, filesystem freeze and for the snapshots. The filesystems stay frozen while waiting for the snapshots.")
+
+	return cmd
+}
+
+func printCreatedSnapshots(snapshots []types.CreatedSnapshot, outputFormat string) error {
+	if printed, err := utils.PrintStructuredResult(outputFormat, types.ResultKindSnapshotList, snapshots); printed || err != nil {
+		return err
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "VOLUME\tSNAPSHOT\tPVC\tQUIESCE")
+	for _, snapshot := range snapshots {
+		pvc := snapshot.PVC
+		if pvc == "" {
+			pvc = "-"
+		}
+		quiesce := strings.Join(snapshot.Quiesce, ",")
+		if quiesce == "" {
+			quiesce = "-"
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", snapshot.Volume, snapshot.Snapshot, pvc, quiesce)
+	}
+	return writer.Flush()
+}
+
+func newCmdSnapshotPromoteToCSI(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var snapshotPromoter = snapshot.Promoter{}
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdPromoteToCSI + " <snapshot|backup>",
+		Short: "Expose a Longhorn snapshot or backup as a CSI VolumeSnapshot",
+		Long: `This command makes a Longhorn snapshot, or a backup with --` + consts.CmdOptBackup + `, usable by the CSI snapshot tooling, for example to restore it into a new PVC with a dataSource.
+
+It creates a pre-provisioned VolumeSnapshotContent of the Longhorn CSI driver referring to the snapshot or backup by its snapshot handle, and a VolumeSnapshot bound to it. The VolumeSnapshot is named --` + consts.CmdOptVolumeSnapshot + ` as <namespace>/<name>, defaulting to the name of the snapshot or backup in the namespace of the PVC of its volume. The Longhorn object is annotated with ` + consts.AnnotationVolumeSnapshot + `.
+
+With the default --` + consts.CmdOptDeletionPolicy + ` Retain, deleting the VolumeSnapshot keeps the Longhorn snapshot or backup. With Delete, it deletes it.`,
+		Example: `$ longhornctl snapshot promote-to-csi longhornctl-20240716-091738 --volume-snapshot-class longhorn-snapshot
+INFO[2024-07-16T17:20:02+08:00] Initializing snapshot promoter
+INFO[2024-07-16T17:20:02+08:00] Running snapshot promoter
+INFO[2024-07-16T17:20:02+08:00] Creating VolumeSnapshotContent                handle="snap://pvc-48a6457d-585e-423b-b530-bbc68a5f948a/longhornctl-20240716-091738" name=longhorn-snap-longhornctl-20240716-091738
+INFO[2024-07-16T17:20:02+08:00] Creating VolumeSnapshot                       name=db/longhornctl-20240716-091738
+SOURCE    NAME                         VOLUME SNAPSHOT                 VOLUME SNAPSHOT CONTENT                    DELETION POLICY
+snapshot  longhornctl-20240716-091738  db/longhornctl-20240716-091738  longhorn-snap-longhornctl-20240716-091738  Retain
+INFO[2024-07-16T17:20:02+08:00] Completed snapshot promoter`,
+		Args: cobra.ExactArgs(1),
+
+		Annotations: map[string]string{consts.CmdAnnotationAudit: "true"},
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			snapshotPromoter.KubeConfigPath = globalOpts.KubeConfigPath
+			snapshotPromoter.LogLevel = globalOpts.LogLevel
+			snapshotPromoter.Name = args[0]
+
+			utils.CheckErr(utils.ValidateOutputFormat(outputFormat, consts.OutputFormatJSON, consts.OutputFormatYAML))
+			utils.CheckErr(snapshotPromoter.Validate())
+
+			logrus.Info("Initializing snapshot promoter")
+			if err := snapshotPromoter.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize snapshot promoter"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Running snapshot promoter")
+			link, err := snapshotPromoter.Run(context.Background())
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to run snapshot promoter"))
+			}
+
+			utils.CheckErr(printCSISnapshotLink(link, outputFormat))
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Completed snapshot promoter")
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&outputFormat, consts.CmdOptOutput, "o", "", fmt.Sprintf("Output format of the result (%s, %s). Defaults to a table.", consts.OutputFormatJSON, consts.OutputFormatYAML))
+	cmd.Flags().StringVar(&snapshotPromoter.LonghornNamespace, consts.CmdOptLonghornNamespace, consts.LonghornNamespace, "Namespace where Longhorn is deployed.")
+	cmd.Flags().BoolVar(&snapshotPromoter.Backup, consts.CmdOptBackup, false, "Promote the backup of the given name instead of a snapshot.")
+	cmd.Flags().StringVar(&snapshotPromoter.VolumeSnapshot, consts.CmdOptVolumeSnapshot, "", "VolumeSnapshot to create, as <namespace>/<name>. Defaults to the name of the snapshot or backup in the namespace of the PVC of its volume.")
+	cmd.Flags().StringVar(&snapshotPromoter.VolumeSnapshotClass, consts.CmdOptVolumeSnapshotClass, "", "VolumeSnapshotClass of the VolumeSnapshot and VolumeSnapshotContent.")
+	cmd.Flags().StringVar(&snapshotPromoter.DeletionPolicy, consts.CmdOptDeletionPolicy, snapshot.DeletionPolicyRetain, fmt.Sprintf("Deletion policy of the VolumeSnapshotContent (%s, %s).", snapshot.DeletionPolicyRetain, snapshot.DeletionPolicyDelete))
+
+	return cmd
+}
+
+func newCmdSnapshotImportFromCSI(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var snapshotImporter = snapshot.Importer{}
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdImportFromCSI + " <namespace>/<volume-snapshot>",
+		Short: "Link a CSI VolumeSnapshot to its Longhorn snapshot or backup",
+		Long: `This command resolves the Longhorn snapshot or backup behind a VolumeSnapshot of the Longhorn CSI driver, from the snapshot handle of its VolumeSnapshotContent, and annotates it with ` + consts.AnnotationVolumeSnapshot + ` so it can be managed with the Longhorn tooling.
+
+With --` + consts.CmdOptRetain + `, the deletion policy of the VolumeSnapshotContent is set to Retain, so deleting the VolumeSnapshot keeps the Longhorn snapshot or backup.`,
+		Example: `$ longhornctl snapshot import-from-csi db/nightly --retain
+INFO[2024-07-16T17:22:10+08:00] Initializing snapshot importer
+INFO[2024-07-16T17:22:10+08:00] Running snapshot importer
+INFO[2024-07-16T17:22:10+08:00] Retaining the Longhorn object on deletion of VolumeSnapshotContent  name=snapcontent-1f0b6c1e-4d5a-4c1b-9a8e-0c6f2f1d7e3a
+SOURCE  NAME                     VOLUME SNAPSHOT  VOLUME SNAPSHOT CONTENT                           DELETION POLICY
+backup  backup-3c2d1e0f9a8b4c7d  db/nightly       snapcontent-1f0b6c1e-4d5a-4c1b-9a8e-0c6f2f1d7e3a  Retain
+INFO[2024-07-16T17:22:10+08:00] Completed snapshot importer`,
+		Args: cobra.ExactArgs(1),
+
+		Annotations: map[string]string{consts.CmdAnnotationAudit: "true"},
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			snapshotImporter.KubeConfigPath = globalOpts.KubeConfigPath
+			snapshotImporter.LogLevel = globalOpts.LogLevel
+			snapshotImporter.VolumeSnapshot = args[0]
+
+			utils.CheckErr(utils.ValidateOutputFormat(outputFormat, consts.OutputFormatJSON, consts.OutputFormatYAML))
+			utils.CheckErr(snapshotImporter.Validate())
+
+			logrus.Info("Initializing snapshot importer")
+			if err := snapshotImporter.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize snapshot importer"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Running snapshot importer")
+			link, err := snapshotImporter.Run(context.Background())
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to run snapshot importer"))
+			}
+
+			utils.CheckErr(printCSISnapshotLink(link, outputFormat))
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Completed snapshot importer")
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&outputFormat, consts.CmdOptOutput, "o", "", fmt.Sprintf("Output format of the result (%s, %s). Defaults to a table.", consts.OutputFormatJSON, consts.OutputFormatYAML))
+	cmd.Flags().StringVar(&snapshotImporter.LonghornNamespace, consts.CmdOptLonghornNamespace, consts.LonghornNamespace, "Namespace where Longhorn is deployed.")
+	cmd.Flags().BoolVar(&snapshotImporter.Retain, consts.CmdOptRetain, false, "Set the deletion policy of the VolumeSnapshotContent to Retain.")
+
+	return cmd
+}
+
+func printCSISnapshotLink(link *types.CSISnapshotLink, outputFormat string) error {
+	if printed, err := utils.PrintStructuredResult(outputFormat, types.ResultKindCSISnapshotLink, link); printed || err != nil {
+		return err
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "SOURCE\tNAME\tVOLUME SNAPSHOT\tVOLUME SNAPSHOT CONTENT\tDELETION POLICY")
+	fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\n", link.Source, link.Name, link.VolumeSnapshot, link.VolumeSnapshotContent, link.DeletionPolicy)
+	return writer.Flush()
+}
@@ -0,0 +1,124 @@
+package subcmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"k8s.io/apimachinery/pkg/util/duration"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/remote/operation"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+)
+
+func NewCmdStatus(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var operationGetter = operation.Getter{}
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdStatus,
+		Short: "List the longhornctl operations running in the cluster",
+		Long: `This command lists the longhornctl operations running in the cluster, in all the namespaces, so you can see what is still running before starting another operation, for example a replica export or a preflight install left behind by someone else.
+
+The operations are the DaemonSets and pods longhornctl created, found by their ` + consts.LabelManagedBy + `=` + consts.LabelValueManagedBy + ` label, with their age and the state of their pod on each node:
+- Init:Running: the init container is running the operation on the node, such as installing the packages.
+- Running: the pod is running. Most operations stay running until they are stopped, such as the replica exporter until 'export replica stop'.
+- A waiting reason, such as ImagePullBackOff or CrashLoopBackOff: the pod cannot run the operation on the node.
+
+To stop an operation, use the 'stop' subcommand of its command, or '` + consts.CmdLonghornctlRemote + ` ` + consts.SubCmdCleanup + ` ` + consts.SubCmdAll + `' to remove all of them.`,
+		Example: `$ longhornctl status
+INFO[2024-07-16T17:40:12+08:00] Initializing operation getter
+INFO[2024-07-16T17:40:12+08:00] Running operation getter
+NAME                       NAMESPACE        KIND       AGE  READY
+longhorn-replica-exporter  longhorn-system  DaemonSet  2d   3/3
+
+OPERATION                  NODE           POD                              STATE    RESTARTS  AGE
+longhorn-replica-exporter  ip-10-0-2-123  longhorn-replica-exporter-7kx2p  Running  0         2d
+longhorn-replica-exporter  ip-10-0-2-142  longhorn-replica-exporter-b9q4d  Running  0         2d
+longhorn-replica-exporter  ip-10-0-2-217  longhorn-replica-exporter-m3v8z  Running  0         2d
+INFO[2024-07-16T17:40:12+08:00] Completed operation getter`,
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			operationGetter.KubeConfigPath = globalOpts.KubeConfigPath
+			operationGetter.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(utils.ValidateOutputFormat(outputFormat, consts.OutputFormatJSON, consts.OutputFormatYAML))
+
+			logrus.Info("Initializing operation getter")
+			if err := operationGetter.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize operation getter"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Running operation getter")
+			operations, err := operationGetter.Run()
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to run operation getter"))
+			}
+
+			utils.CheckErr(printOperations(operations, outputFormat))
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Completed operation getter")
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&outputFormat, consts.CmdOptOutput, "o", "", fmt.Sprintf("Output format (%s, %s). Defaults to tables.", consts.OutputFormatJSON, consts.OutputFormatYAML))
+
+	return cmd
+}
+
+func printOperations(operations []types.Operation, outputFormat string) error {
+	if printed, err := utils.PrintStructuredResult(outputFormat, types.ResultKindOperationList, operations); printed || err != nil {
+		return err
+	}
+
+	if len(operations) == 0 {
+		fmt.Println("No longhornctl operation is running in the cluster.")
+		return nil
+	}
+
+	age := func(startTime time.Time) string {
+		return duration.HumanDuration(time.Since(startTime))
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "NAME\tNAMESPACE\tKIND\tAGE\tREADY")
+	for _, operation := range operations {
+		ready := 0
+		for _, node := range operation.Nodes {
+			if node.Ready {
+				ready++
+			}
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%d/%d\n", operation.Name, operation.Namespace, operation.Kind, age(operation.StartTime), ready, len(operation.Nodes))
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	writer = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "OPERATION\tNODE\tPOD\tSTATE\tRESTARTS\tAGE")
+	for _, operation := range operations {
+		for _, node := range operation.Nodes {
+			nodeName := node.Node
+			if nodeName == "" {
+				nodeName = "-"
+			}
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%d\t%s\n", operation.Name, nodeName, node.Pod, node.State, node.Restarts, age(node.StartTime))
+		}
+	}
+	return writer.Flush()
+}
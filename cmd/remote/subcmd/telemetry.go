@@ -0,0 +1,253 @@
+package subcmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/longhorn/cli/meta"
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+const telemetryNodeCountTimeout = 5 * time.Second
+
+// telemetryRun is the run reported when the telemetry is enabled.
+var telemetryRun *struct {
+	configFile string
+	config     *types.TelemetryConfig
+	startTime  time.Time
+}
+
+// StartTelemetry records the opt-in of --telemetry, and prepares reporting the command when the
+// telemetry is enabled. Failures are reported when the command exits through utils.CheckErr.
+// Failing to report never fails the command.
+func StartTelemetry(cmd *cobra.Command, globalOpts *types.GlobalCmdOptions) {
+	if globalOpts.TelemetryURL != "" && !globalOpts.Telemetry {
+		utils.CheckErr(fmt.Errorf("--%s requires --%s", consts.CmdOptTelemetryURL, consts.CmdOptTelemetry))
+	}
+
+	configFile, err := utils.GetTelemetryConfigFile()
+	if err != nil {
+		utils.CheckErr(err)
+	}
+	config, err := utils.ReadTelemetryConfig(configFile)
+	if err != nil {
+		if !globalOpts.Telemetry {
+			logrus.WithError(err).Debug("Failed to read the telemetry config")
+			return
+		}
+		config = &types.TelemetryConfig{}
+	}
+
+	if globalOpts.Telemetry {
+		wasEnabled := config.Enabled
+		utils.CheckErr(utils.EnableTelemetry(config, globalOpts.TelemetryURL))
+		utils.CheckErr(utils.WriteTelemetryConfig(configFile, config))
+		if !wasEnabled {
+			logrus.Infof("Enabled the telemetry: anonymized usage statistics of each command are sent to %v. Run '%s %s %s' to see them, and '%s %s %s' to opt out", config.Endpoint,
+				consts.CmdLonghornctlRemote, consts.SubCmdTelemetry, consts.SubCmdStatus, consts.CmdLonghornctlRemote, consts.SubCmdTelemetry, consts.SubCmdDisable)
+		}
+	}
+
+	if !config.Enabled || utils.IsDoNotTrack() || isTelemetryCommand(cmd) {
+		return
+	}
+
+	telemetryRun = &struct {
+		configFile string
+		config     *types.TelemetryConfig
+		startTime  time.Time
+	}{configFile: configFile, config: config, startTime: time.Now()}
+
+	utils.RegisterErrorHandler(func(err error) {
+		reportTelemetry(cmd, globalOpts, err)
+	})
+}
+
+// CompleteTelemetry reports the successful command when the telemetry is enabled.
+func CompleteTelemetry(cmd *cobra.Command, globalOpts *types.GlobalCmdOptions) {
+	reportTelemetry(cmd, globalOpts, nil)
+}
+
+// reportTelemetry sends the anonymized statistics of the run, and keeps them as the last report.
+func reportTelemetry(cmd *cobra.Command, globalOpts *types.GlobalCmdOptions, cmdErr error) {
+	if telemetryRun == nil {
+		return
+	}
+	run := telemetryRun
+	telemetryRun = nil
+
+	report := &types.TelemetryReport{
+		ID:              run.config.ID,
+		Version:         meta.Version,
+		OS:              runtime.GOOS,
+		Arch:            runtime.GOARCH,
+		Command:         cmd.CommandPath(),
+		DurationSeconds: time.Since(run.startTime).Round(time.Millisecond).Seconds(),
+		NodeCount:       getTelemetryNodeCount(globalOpts),
+		FailureCategory: utils.GetTelemetryFailureCategory(cmdErr),
+	}
+
+	if err := utils.SendTelemetryReport(&http.Client{}, run.config.Endpoint, report); err != nil {
+		logrus.WithError(err).Debug("Failed to send the telemetry report")
+		return
+	}
+
+	run.config.LastReport = report
+	if err := utils.WriteTelemetryConfig(run.configFile, run.config); err != nil {
+		logrus.WithError(err).Debug("Failed to record the telemetry report")
+	}
+}
+
+// getTelemetryNodeCount returns the number of nodes of the cluster, or 0 when it is not reachable.
+func getTelemetryNodeCount(globalOpts *types.GlobalCmdOptions) int {
+	kubeClient, err := kubeutils.NewKubeClient("", globalOpts.KubeConfigPath)
+	if err != nil {
+		return 0
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), telemetryNodeCountTimeout)
+	defer cancel()
+
+	nodeList, err := kubeClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return 0
+	}
+	return len(nodeList.Items)
+}
+
+// isTelemetryCommand returns whether the command manages the telemetry, which is not reported.
+func isTelemetryCommand(cmd *cobra.Command) bool {
+	for c := cmd; c != nil; c = c.Parent() {
+		if c.Name() == consts.SubCmdTelemetry && c.Parent() != nil && !c.Parent().HasParent() {
+			return true
+		}
+	}
+	return false
+}
+
+func NewCmdTelemetry(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdTelemetry,
+		Short: "Show or disable the opt-in telemetry",
+		Long: `The telemetry is disabled unless you opt in by running a command with --` + consts.CmdOptTelemetry + ` --` + consts.CmdOptTelemetryURL + `=<url>. The opt-in is persisted in the user config directory, and the following commands are then reported to the URL, as a JSON POST request with:
+- A random identifier generated on opt-in, not derived from the user, host or cluster.
+- The versions of ` + consts.CmdLonghornctlRemote + `, the operating system and the architecture.
+- The command path, without its arguments or flags, and its duration.
+- The number of nodes of the cluster.
+- The category of the failure of a failed command, without its message.
+
+The ` + consts.EnvDoNotTrack + ` environment variable disables the telemetry regardless of the opt-in.`,
+	}
+
+	cmd.AddCommand(newCmdTelemetryStatus())
+	cmd.AddCommand(newCmdTelemetryDisable())
+
+	return cmd
+}
+
+func newCmdTelemetryStatus() *cobra.Command {
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdStatus,
+		Short: "Show the telemetry opt-in and the last report sent",
+		Example: `$ longhornctl telemetry status
+Telemetry: enabled
+Endpoint: https://telemetry.example.com/longhornctl
+Config File: /home/user/.config/longhornctl/telemetry.json
+Last Report:
+  ID: 3f2b8c1d9e7a4b6c8d0e1f2a3b4c5d6e
+  Version: v1.9.0 (linux/amd64)
+  Command: longhornctl check preflight
+  Duration: 12.481s
+  Node Count: 3`,
+		Args: cobra.NoArgs,
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			utils.CheckErr(utils.ValidateOutputFormat(outputFormat, consts.OutputFormatJSON, consts.OutputFormatYAML))
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			configFile, err := utils.GetTelemetryConfigFile()
+			utils.CheckErr(err)
+			config, err := utils.ReadTelemetryConfig(configFile)
+			utils.CheckErr(err)
+
+			utils.CheckErr(printTelemetryStatus(&types.TelemetryStatus{
+				TelemetryConfig: *config,
+				DoNotTrack:      utils.IsDoNotTrack(),
+				ConfigFile:      configFile,
+			}, outputFormat))
+		},
+	}
+
+	cmd.Flags().StringVarP(&outputFormat, consts.CmdOptOutput, "o", "", fmt.Sprintf("Output format (%s, %s).", consts.OutputFormatJSON, consts.OutputFormatYAML))
+
+	return cmd
+}
+
+func newCmdTelemetryDisable() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdDisable,
+		Short: "Opt out of the telemetry",
+		Long:  `This command disables the telemetry, and discards the random identifier and the last report. Opting in again generates a new identifier.`,
+		Args:  cobra.NoArgs,
+
+		Run: func(cmd *cobra.Command, args []string) {
+			configFile, err := utils.GetTelemetryConfigFile()
+			utils.CheckErr(err)
+			config, err := utils.ReadTelemetryConfig(configFile)
+			utils.CheckErr(err)
+
+			utils.DisableTelemetry(config)
+			utils.CheckErr(utils.WriteTelemetryConfig(configFile, config))
+			logrus.Info("Disabled the telemetry")
+		},
+	}
+
+	return cmd
+}
+
+func printTelemetryStatus(status *types.TelemetryStatus, outputFormat string) error {
+	if printed, err := utils.PrintStructuredResult(outputFormat, types.ResultKindTelemetryStatus, status); printed || err != nil {
+		return err
+	}
+
+	state := "disabled"
+	switch {
+	case status.Enabled && status.DoNotTrack:
+		state = "disabled by " + consts.EnvDoNotTrack
+	case status.Enabled:
+		state = "enabled"
+	}
+	fmt.Printf("Telemetry: %s\n", state)
+	if status.Endpoint != "" {
+		fmt.Printf("Endpoint: %s\n", status.Endpoint)
+	}
+	fmt.Printf("Config File: %s\n", status.ConfigFile)
+
+	if report := status.LastReport; report != nil {
+		fmt.Println("Last Report:")
+		fmt.Printf("  ID: %s\n", report.ID)
+		fmt.Printf("  Version: %s (%s/%s)\n", report.Version, report.OS, report.Arch)
+		fmt.Printf("  Command: %s\n", report.Command)
+		fmt.Printf("  Duration: %gs\n", report.DurationSeconds)
+		fmt.Printf("  Node Count: %d\n", report.NodeCount)
+		if report.FailureCategory != "" {
+			fmt.Printf("  Failure Category: %s\n", report.FailureCategory)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package subcmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/remote/chaos"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+)
+
+func NewCmdTest(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdTest,
+		Short: "Longhorn resilience test operations",
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.AddCommand(newCmdTestFailover(globalOpts))
+
+	return cmd
+}
+
+func newCmdTestFailover(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var failoverTester = chaos.FailoverTester{}
+	var outputFormat string
+	var report *types.FailoverReport
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdFailover,
+		Short: "Inject a failure into a volume and verify it recovers within the SLO",
+		Long: `This command deliberately injects a controlled failure into an attached and healthy volume, watches the volume until it is healthy again, and reports the time for Longhorn to detect the failure and to recover from it. It is meant for validating the resilience of a cluster before production, not for production volumes.
+
+The failures (--` + consts.CmdOptKill + `):
+- replica: kills a replica process with SIGKILL in its instance manager. A replica on another node than the engine is picked, unless --` + consts.CmdOptReplica + ` is set. The volume is degraded until a replica is rebuilt.
+- engine: kills the engine process with SIGKILL in its instance manager. The I/O of the workload is interrupted until the engine is restarted, and the workload may see I/O errors.
+- node-network: drops with iptables the traffic between the node of a replica and the instance manager of the engine for --` + consts.CmdOptPartitionDuration + `, from a DaemonSet pod in the host network of the node. The partition is removed when the duration ends or the command is interrupted.
+
+The killed processes are v1 data engine processes; node-network supports both data engines. Volumes with a single replica are refused for replica and node-network.
+
+The test passes when the volume is attached and healthy again within --` + consts.CmdOptSLO + ` from the injection. When the volume never leaves the healthy state, the failure was tolerated without detection, which is reported. The command fails when the test does not pass.`,
+		Example: `$ longhornctl test failover --volume=pvc-3b9d3a5e --kill=replica
+INFO[2024-07-16T19:10:02+08:00] Initializing failover tester
+INFO[2024-07-16T19:10:02+08:00] Cleaning up failover tester
+This will kill the replica process pvc-3b9d3a5e-r-5c1e9a20 of volume pvc-3b9d3a5e on node ip-10-0-2-142, and rebuild a replica.
+Continue? [y/N]: y
+INFO[2024-07-16T19:10:05+08:00] Running failover tester
+INFO[2024-07-16T19:10:05+08:00] Injected replica failure into pvc-3b9d3a5e-r-5c1e9a20  node=ip-10-0-2-142 volume=pvc-3b9d3a5e
+INFO[2024-07-16T19:10:07+08:00] Volume is attached and degraded, waiting for it to recover  volume=pvc-3b9d3a5e
+ELAPSED  STATE     ROBUSTNESS  REPLICAS
+0s       attached  healthy     3
+2s       attached  degraded    2
+6s       attached  degraded    3
+48s      attached  healthy     3
+Volume pvc-3b9d3a5e: detected in 2s, recovered in 48s, SLO 5m0s: PASSED
+INFO[2024-07-16T19:10:53+08:00] Cleaning up failover tester
+INFO[2024-07-16T19:10:53+08:00] Completed failover tester
+
+$ longhornctl test failover --volume=pvc-3b9d3a5e --kill=node-network --partition-duration=2m --slo=10m -o json`,
+		Annotations: map[string]string{consts.CmdAnnotationAudit: "true"},
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			failoverTester.Image = globalOpts.Image
+			failoverTester.KubeConfigPath = globalOpts.KubeConfigPath
+			failoverTester.Namespace = globalOpts.Namespace
+			failoverTester.NodeSelector = globalOpts.NodeSelector
+			failoverTester.MinNodes = globalOpts.MinNodes
+			failoverTester.PodCpu = globalOpts.PodCpu
+			failoverTester.PodMemory = globalOpts.PodMemory
+			failoverTester.PriorityClass = globalOpts.PriorityClass
+			failoverTester.Proxy = globalOpts.Proxy
+			failoverTester.NoProxy = globalOpts.NoProxy
+			failoverTester.Privileged = globalOpts.Privileged
+			failoverTester.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(utils.ValidateOutputFormat(outputFormat, consts.OutputFormatJSON, consts.OutputFormatYAML))
+			utils.CheckErr(failoverTester.Validate())
+
+			logrus.Info("Initializing failover tester")
+			if err := failoverTester.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize failover tester"))
+			}
+
+			logrus.Info("Cleaning up failover tester")
+			if err := failoverTester.Cleanup(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to cleanup failover tester"))
+			}
+
+			utils.CheckErr(utils.Confirm(globalOpts, failoverTester.Describe()))
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			logrus.Info("Running failover tester")
+			var err error
+			report, err = failoverTester.Run(ctx)
+			if err != nil {
+				if err := failoverTester.Cleanup(); err != nil {
+					logrus.WithError(err).Warn("Failed to cleanup failover tester")
+				}
+				utils.CheckErr(errors.Wrap(err, "Failed to run failover tester"))
+			}
+
+			utils.CheckErr(printFailoverReport(report, outputFormat))
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Cleaning up failover tester")
+			if err := failoverTester.Cleanup(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to cleanup failover tester"))
+			}
+
+			if report != nil && !report.Passed {
+				utils.CheckErr(errors.Errorf("volume %v failed the %v failover test: %v", report.Volume, report.Failure, report.Message))
+			}
+
+			logrus.Info("Completed failover tester")
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&outputFormat, consts.CmdOptOutput, "o", "", fmt.Sprintf("Output format of the report (%s, %s).", consts.OutputFormatJSON, consts.OutputFormatYAML))
+	cmd.Flags().StringVar(&failoverTester.VolumeName, consts.CmdOptVolume, "", "Volume to inject the failure into.")
+	cmd.Flags().StringVar(&failoverTester.Failure, consts.CmdOptKill, "", fmt.Sprintf("Failure to inject (%s, %s, %s).", chaos.FailureReplica, chaos.FailureEngine, chaos.FailureNodeNetwork))
+	cmd.Flags().StringVar(&failoverTester.ReplicaName, consts.CmdOptReplica, "", "Replica to kill, or to partition the node of from the engine. Defaults to a replica on another node than the engine.")
+	cmd.Flags().DurationVar(&failoverTester.SLO, consts.CmdOptSLO, 5*time.Minute, "Maximum time from the injection for the volume to be attached and healthy again.")
+	cmd.Flags().DurationVar(&failoverTester.PartitionDuration, consts.CmdOptPartitionDuration, time.Minute, "Duration of the node-network partition.")
+	cmd.Flags().StringVar(&failoverTester.LonghornNamespace, consts.CmdOptLonghornNamespace, consts.LonghornNamespace, "Namespace where Longhorn is deployed.")
+
+	return cmd
+}
+
+// printFailoverReport prints the timeline of the volume and the verdict, unless a structured output
+// format is requested.
+func printFailoverReport(report *types.FailoverReport, outputFormat string) error {
+	if printed, err := utils.PrintStructuredResult(outputFormat, types.ResultKindFailoverReport, report); printed || err != nil {
+		return err
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "ELAPSED\tSTATE\tROBUSTNESS\tREPLICAS")
+	for _, event := range report.Timeline {
+		fmt.Fprintf(writer, "%v\t%s\t%s\t%d\n", secondsDuration(event.ElapsedSeconds), event.State, event.Robustness, event.Replicas)
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+
+	verdict := "FAILED"
+	if report.Passed {
+		verdict = "PASSED"
+	}
+	detection := "not detected"
+	if report.Detected {
+		detection = fmt.Sprintf("detected in %v", secondsDuration(report.DetectionSeconds))
+	}
+	recovery := "not recovered"
+	if report.Recovered && report.Detected {
+		recovery = fmt.Sprintf("recovered in %v", secondsDuration(report.RecoverySeconds))
+	} else if report.Recovered {
+		recovery = "stayed healthy"
+	}
+	fmt.Printf("Volume %s: %s, %s, SLO %v: %s\n", report.Volume, detection, recovery, secondsDuration(report.SLOSeconds), verdict)
+	if report.Message != "" {
+		fmt.Println(report.Message)
+	}
+	return nil
+}
+
+func secondsDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
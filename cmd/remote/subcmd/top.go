@@ -0,0 +1,74 @@
+package subcmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/remote/top"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+)
+
+func NewCmdTop(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var longhornTop = top.Top{}
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdTop,
+		Short: "Show the live state of the Longhorn volumes, nodes, rebuilds and events",
+		Long: `This command shows a terminal UI refreshed at --` + consts.CmdOptInterval + `, with a tab for each of:
+- Volumes: the state, robustness, healthy replicas, size and attached node of each volume.
+- Nodes: the readiness, schedulability and storage of each node.
+- Rebuilds: the progress of the replicas being rebuilt.
+- Events: the latest events of the Longhorn volumes, engines, replicas and nodes of the last hour.
+
+Keys:
+  up/down, j/k  Select a row
+  enter         Show the replicas of the selected volume, or of the volume of the selected rebuild
+  esc           Go back to the tab
+  tab, 1-4      Switch tab
+  q, ctrl-c     Quit
+
+The command requires an interactive terminal. Use '` + consts.CmdLonghornctlRemote + ` ` + consts.SubCmdEvents + ` --` + consts.CmdOptFollow + `' to watch the events in a script.`,
+		Example: `$ longhornctl top --interval=5s`,
+		Args:    cobra.NoArgs,
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			longhornTop.KubeConfigPath = globalOpts.KubeConfigPath
+			longhornTop.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(longhornTop.Validate())
+			if !utils.IsTerminal(os.Stdin) || !utils.IsTerminal(os.Stdout) {
+				utils.CheckErr(errors.Errorf("%s requires an interactive terminal", consts.SubCmdTop))
+			}
+
+			logrus.Debug("Initializing top")
+			if err := longhornTop.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize top"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			if err := longhornTop.Run(ctx); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to run top"))
+			}
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVar(&longhornTop.LonghornNamespace, consts.CmdOptLonghornNamespace, consts.LonghornNamespace, "Namespace where Longhorn is deployed.")
+	cmd.Flags().DurationVar(&longhornTop.Interval, consts.CmdOptInterval, 2*time.Second, "Interval between refreshes.")
+
+	return cmd
+}
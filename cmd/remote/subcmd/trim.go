@@ -1,6 +1,8 @@
 package subcmd
 
 import (
+	"fmt"
+
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -26,6 +28,7 @@ func NewCmdTrim(globalOpts *types.GlobalCmdOptions) *cobra.Command {
 
 func newCmdTrimVolume(globalOpts *types.GlobalCmdOptions) *cobra.Command {
 	var volumeTrimmer = volume.Trimmer{}
+	var outputFormat string
 
 	cmd := &cobra.Command{
 		Use:   consts.SubCmdVolume,
@@ -36,20 +39,39 @@ It is particularly useful when you have deleted files or applications from the v
 To use this command, specify the following option:
 - --name: The name of the Longhorn volume you wish to trim.
 
+The volumes that are detached or faulted are skipped. The result reports the actual size of the volume before and after the trim, the space reclaimed, and the duration of the trim. The actual size is reported by Longhorn from the replicas periodically, so the command waits up to 30 seconds for it to be updated.
+With --` + consts.CmdOptOutput + `, the result is printed in the given format.
+
 Regularly trimming your Longhorn volumes ensures better storage efficiency and management within your system.`,
 		Example: `$ longhornctl trim volume --name="pvc-48a6457d-585e-423b-b530-bbc68a5f948a"
 INFO[2024-07-16T17:31:59+08:00] Initializing volume trimmer
 INFO[2024-07-16T17:31:59+08:00] Cleaning volume trimmer
 INFO[2024-07-16T17:31:59+08:00] Running volume trimmer                        volume=pvc-48a6457d-585e-423b-b530-bbc68a5f948a
-INFO[2024-07-16T17:32:01+08:00] Cleaning volume trimmer                       volume=pvc-48a6457d-585e-423b-b530-bbc68a5f948a
-INFO[2024-07-16T17:32:01+08:00] Completed volume trimmer                      volume=pvc-48a6457d-585e-423b-b530-bbc68a5f948a`,
+INFO[2024-07-16T17:32:05+08:00] Trimmed volume in 2.104s, reclaimed 1.2GiB (actual size 3.5GiB to 2.3GiB)  volume=pvc-48a6457d-585e-423b-b530-bbc68a5f948a
+INFO[2024-07-16T17:32:05+08:00] Cleaning volume trimmer                       volume=pvc-48a6457d-585e-423b-b530-bbc68a5f948a
+INFO[2024-07-16T17:32:05+08:00] Completed volume trimmer                      volume=pvc-48a6457d-585e-423b-b530-bbc68a5f948a
+
+$ longhornctl trim volume --name="pvc-48a6457d-585e-423b-b530-bbc68a5f948a" -o json`,
+
+		Annotations: map[string]string{consts.CmdAnnotationAudit: "true"},
 
 		PreRun: func(cmd *cobra.Command, args []string) {
 			volumeTrimmer.Image = globalOpts.Image
 			volumeTrimmer.KubeConfigPath = globalOpts.KubeConfigPath
+			volumeTrimmer.Namespace = globalOpts.Namespace
 			volumeTrimmer.NodeSelector = globalOpts.NodeSelector
-
+			volumeTrimmer.MinNodes = globalOpts.MinNodes
+			volumeTrimmer.PodCpu = globalOpts.PodCpu
+			volumeTrimmer.PodMemory = globalOpts.PodMemory
+			volumeTrimmer.PriorityClass = globalOpts.PriorityClass
+			volumeTrimmer.Proxy = globalOpts.Proxy
+			volumeTrimmer.NoProxy = globalOpts.NoProxy
+			volumeTrimmer.Privileged = globalOpts.Privileged
+			volumeTrimmer.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(utils.ValidateOutputFormat(outputFormat, consts.OutputFormatJSON, consts.OutputFormatYAML))
 			utils.CheckErr(volumeTrimmer.Validate())
+			utils.CheckErr(utils.Confirm(globalOpts, fmt.Sprintf("This will trim the filesystem of volume %s, and discard the blocks of deleted data.", volumeTrimmer.VolumeName)))
 
 			logrus.Info("Initializing volume trimmer")
 			if err := volumeTrimmer.Init(); err != nil {
@@ -66,9 +88,21 @@ INFO[2024-07-16T17:32:01+08:00] Completed volume trimmer                      vo
 			log := logrus.WithField("volume", volumeTrimmer.VolumeName)
 
 			log.Info("Running volume trimmer")
-			if err := volumeTrimmer.Run(); err != nil {
+			result, err := volumeTrimmer.Run()
+			if err != nil {
 				utils.CheckErr(errors.Wrapf(err, "Failed to run volume trimmer for volume %s", volumeTrimmer.VolumeName))
 			}
+
+			if printed, err := utils.PrintStructuredResult(outputFormat, types.ResultKindVolumeTrimResult, result); printed || err != nil {
+				utils.CheckErr(err)
+				return
+			}
+			if !result.Trimmed {
+				log.Infof("Skipped volume, it is %v", result.SkippedReason)
+				return
+			}
+			log.Infof("Trimmed volume in %v, reclaimed %v (actual size %v to %v)", result.Duration, utils.FormatBytes(result.ReclaimedBytes),
+				utils.FormatBytes(result.ActualSizeBefore), utils.FormatBytes(result.ActualSizeAfter))
 		},
 
 		PostRun: func(cmd *cobra.Command, args []string) {
@@ -86,6 +120,9 @@ INFO[2024-07-16T17:32:01+08:00] Completed volume trimmer                      vo
 
 	cmd.Flags().StringVar(&volumeTrimmer.LonghornNamespace, consts.CmdOptLonghornNamespace, "longhorn-system", "Namespace where Longhorn is deployed within the Kubernetes cluster.")
 	cmd.Flags().StringVar(&volumeTrimmer.VolumeName, consts.CmdOptName, "", "Name of the Longhorn volum to be trimmed.")
+	cmd.Flags().StringVarP(&outputFormat, consts.CmdOptOutput, "o", "", fmt.Sprintf("Output format of the result (%s, %s).", consts.OutputFormatJSON, consts.OutputFormatYAML))
+
+	_ = cmd.RegisterFlagCompletionFunc(consts.CmdOptName, completeVolumeNames(globalOpts, &volumeTrimmer.LonghornNamespace))
 
 	return cmd
 }
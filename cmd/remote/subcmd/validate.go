@@ -0,0 +1,88 @@
+package subcmd
+
+import (
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/remote/manifest"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+)
+
+func NewCmdValidate(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var manifestValidator = manifest.Validator{}
+	var outputFormat string
+	var hasErrors bool
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdValidate,
+		Short: "Validate Longhorn-related manifests offline",
+		Long: `This command validates the Longhorn-related objects of manifest files without accessing the cluster, for example in CI before a GitOps tool applies them:
+- StorageClasses of the Longhorn CSI driver: the known parameters and their values, strict-local data locality with a single replica, and the secrets of encrypted volumes.
+- RecurringJobs: the task, cron, retain count, concurrency and parameters.
+- Volumes: the fields and their values, strict-local data locality with a single replica, and the access mode of migratable volumes.
+- Settings, and the ` + "`longhorn-default-setting`" + ` ConfigMap: the value of each setting, and the combinations of settings Longhorn rejects or cannot honor.
+
+The Longhorn objects are decoded against the Longhorn API types the CLI is built with, so unknown fields and objects of API versions that are no longer served are reported. The other objects are skipped.
+
+Directories are searched recursively for .yaml, .yml and .json files. The command fails when any object is invalid.`,
+		Example: `$ longhornctl validate -f deploy/longhorn/
+INFO[2024-07-16T17:17:38+08:00] Initializing manifest validator
+INFO[2024-07-16T17:17:38+08:00] Running manifest validator
+OBJECT                                                               STATUS  MESSAGE
+deploy/longhorn/recurringjobs.yaml: RecurringJob/backup-daily        ERROR   Invalid cron "0 2 * *": Expected exactly 5 fields, found 4: 0 2 * *
+deploy/longhorn/settings.yaml: ConfigMap/longhorn-default-setting    ERROR   Settings v1-data-engine and v2-data-engine disable both data engines
+                                                                     ERROR   Longhorn ignores all the default settings when any of them is invalid
+deploy/longhorn/storageclass.yaml: StorageClass/longhorn-strict      ERROR   Data locality strict-local requires numberOfReplicas 1
+                                                                     WARN    Parameter replicaCount is unknown to Longhorn and ignored
+
+3 objects, 3 errors, 1 warnings
+INFO[2024-07-16T17:17:38+08:00] Completed manifest validator
+ERRO[2024-07-16T17:17:38+08:00] invalid Longhorn manifests`,
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			manifestValidator.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(utils.ValidateOutputFormat(outputFormat, consts.OutputFormatJSON, consts.OutputFormatJUnit, consts.OutputFormatYAML))
+			utils.CheckErr(manifestValidator.Validate())
+
+			logrus.Info("Initializing manifest validator")
+			if err := manifestValidator.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize manifest validator"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Running manifest validator")
+			collections, err := manifestValidator.Collect()
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to run manifest validator"))
+			}
+
+			for _, collection := range collections {
+				if len(collection.Error) > 0 {
+					hasErrors = true
+				}
+			}
+
+			utils.CheckErr(utils.PrintCollections(globalOpts, "OBJECT", "objects", "Retrieved manifest validator result", outputFormat, collections))
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Completed manifest validator")
+
+			if hasErrors {
+				utils.CheckErr(errors.New("invalid Longhorn manifests"))
+			}
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&outputFormat, consts.CmdOptOutput, "o", "", "Output format of the result (json, junit, yaml). Defaults to a table on terminals, and YAML otherwise.")
+	cmd.Flags().StringSliceVarP(&manifestValidator.Paths, consts.CmdOptFilename, "f", nil, "Manifest files, or directories searched recursively for manifest files. Can be repeated or comma-separated.")
+
+	return cmd
+}
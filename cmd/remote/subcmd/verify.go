@@ -0,0 +1,153 @@
+package subcmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/remote/verify"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+)
+
+func NewCmdVerify(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdVerify,
+		Short: "Longhorn verification operations",
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.AddCommand(newCmdVerifyInstall(globalOpts))
+
+	return cmd
+}
+
+func newCmdVerifyInstall(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var installationVerifier = verify.InstallationVerifier{}
+	var outputFormat string
+	var report *types.VerifyReport
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdInstall,
+		Short: "Verify a Longhorn installation with a test volume",
+		Long: `This command runs a smoke test on a freshly deployed Longhorn, and reports whether each step passed.
+
+Steps:
+  ` + verify.StepWriteData + `       Create a test PVC, and write data and its checksum from a pod
+  ` + verify.StepCreateSnapshot + `  Take a snapshot of the attached volume
+  ` + verify.StepCreateBackup + `    Back up the snapshot to the backup target of the volume (--` + consts.CmdOptBackup + `)
+  ` + verify.StepReadData + `        Reattach the volume to a new pod and verify the checksum of the data
+
+The steps after a failed step are skipped. The test PVC, pods, snapshot and backup are deleted afterwards, and the command exits with an error when a step failed.`,
+		Example: `$ longhornctl verify install --backup
+INFO[2024-07-16T17:17:38+08:00] Initializing installation verifier
+INFO[2024-07-16T17:17:38+08:00] Cleaning up installation verifier
+INFO[2024-07-16T17:17:38+08:00] Running installation verifier
+INFO[2024-07-16T17:17:38+08:00] Running step write-data
+INFO[2024-07-16T17:18:02+08:00] Running step create-snapshot
+INFO[2024-07-16T17:18:05+08:00] Running step create-backup
+INFO[2024-07-16T17:18:21+08:00] Running step read-data
+STEP             STATUS  DURATION  MESSAGE
+write-data       pass    24s       Wrote 16MiB to volume pvc-0b1c6f4a-7e0a-4a7b-9d2e-5f1f3f0c2e11
+create-snapshot  pass    3s        Created snapshot longhorn-installation-verifier
+create-backup    pass    16s       Backed up snapshot longhorn-installation-verifier to s3://backups@us-east-1/
+read-data        pass    19s       Verified the checksum of the data after reattaching the volume
+
+PASSED
+INFO[2024-07-16T17:18:40+08:00] Cleaning up installation verifier
+INFO[2024-07-16T17:18:45+08:00] Completed installation verifier`,
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			installationVerifier.Image = globalOpts.Image
+			installationVerifier.KubeConfigPath = globalOpts.KubeConfigPath
+			installationVerifier.Namespace = globalOpts.Namespace
+			installationVerifier.NodeSelector = globalOpts.NodeSelector
+			installationVerifier.MinNodes = globalOpts.MinNodes
+			installationVerifier.PodCpu = globalOpts.PodCpu
+			installationVerifier.PodMemory = globalOpts.PodMemory
+			installationVerifier.PriorityClass = globalOpts.PriorityClass
+			installationVerifier.Proxy = globalOpts.Proxy
+			installationVerifier.NoProxy = globalOpts.NoProxy
+			installationVerifier.Privileged = globalOpts.Privileged
+			installationVerifier.LogLevel = globalOpts.LogLevel
+
+			utils.CheckErr(utils.ValidateOutputFormat(outputFormat, consts.OutputFormatJSON, consts.OutputFormatYAML))
+			utils.CheckErr(installationVerifier.Validate())
+
+			logrus.Info("Initializing installation verifier")
+			if err := installationVerifier.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize installation verifier"))
+			}
+
+			logrus.Info("Cleaning up installation verifier")
+			if err := installationVerifier.Cleanup(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to cleanup installation verifier"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Running installation verifier")
+			var err error
+			report, err = installationVerifier.Run()
+			if err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to run installation verifier"))
+			}
+
+			utils.CheckErr(printVerifyReport(report, outputFormat))
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logrus.Info("Cleaning up installation verifier")
+			if err := installationVerifier.Cleanup(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to cleanup installation verifier"))
+			}
+
+			logrus.Info("Completed installation verifier")
+
+			if report != nil && !report.Passed {
+				utils.CheckErr(errors.New("Longhorn installation verification failed"))
+			}
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&outputFormat, consts.CmdOptOutput, "o", "", fmt.Sprintf("Output format of the report (%s, %s). Defaults to a table.", consts.OutputFormatJSON, consts.OutputFormatYAML))
+	cmd.Flags().StringVar(&installationVerifier.LonghornNamespace, consts.CmdOptLonghornNamespace, consts.LonghornNamespace, "Namespace where Longhorn is deployed.")
+	cmd.Flags().StringVar(&installationVerifier.StorageClass, consts.CmdOptStorageClass, consts.LonghornStorageClass, "StorageClass of the test PVC.")
+	cmd.Flags().StringVar(&installationVerifier.Size, consts.CmdOptSize, "1Gi", "Size of the test PVC.")
+	cmd.Flags().BoolVar(&installationVerifier.Backup, consts.CmdOptBackup, false, "Back up the snapshot to the backup target of the test volume.")
+	cmd.Flags().DurationVar(&installationVerifier.Timeout, consts.CmdOptTimeout, 5*time.Minute, "Maximum time to wait for each step.")
+
+	return cmd
+}
+
+func printVerifyReport(report *types.VerifyReport, outputFormat string) error {
+	if printed, err := utils.PrintStructuredResult(outputFormat, types.ResultKindVerifyReport, report); printed || err != nil {
+		return err
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "STEP\tSTATUS\tDURATION\tMESSAGE")
+	for _, step := range report.Steps {
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", step.Name, step.Status, step.Duration, strings.ReplaceAll(step.Message, "\n", " "))
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+
+	if report.Passed {
+		fmt.Println("\nPASSED")
+	} else {
+		fmt.Println("\nFAILED")
+	}
+	return nil
+}
@@ -1,23 +1,129 @@
 package subcmd
 
 import (
+	"context"
 	"fmt"
+	"time"
 
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
 	"github.com/longhorn/cli/meta"
 	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
 )
 
-func NewCmdVersion() *cobra.Command {
+const serverVersionTimeout = 10 * time.Second
+
+func NewCmdVersion(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var outputFormat string
+	var clientOnly bool
+	var longhornNamespace string
+
 	cmd := &cobra.Command{
 		Use:   consts.SubCmdVersion,
 		Short: fmt.Sprintf("Print %s version", consts.CmdLonghornctlRemote),
+		Long: fmt.Sprintf(`This command prints the %[1]s version.
+
+With -o %[2]s, %[3]s or %[4]s, it also prints the git commit and build date of the CLI, and the version of the Longhorn manager detected in the cluster, in the namespace of --%[5]s.
+It then warns when the major and minor versions of the CLI and Longhorn do not match.`, consts.CmdLonghornctlRemote, consts.OutputFormatWide, consts.OutputFormatJSON, consts.OutputFormatYAML, consts.CmdOptLonghornNamespace),
+		Example: `$ longhornctl version
+v1.9.0
+
+$ longhornctl version -o wide
+Client Version: v1.9.0
+Git Commit: 1d3b8a2
+Build Date: 2025-06-25T08:00:00Z
+Server Version: v1.9.0
+
+$ longhornctl version --output json`,
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			utils.CheckErr(utils.ValidateOutputFormat(outputFormat, consts.OutputFormatWide, consts.OutputFormatJSON, consts.OutputFormatYAML))
+		},
 
 		Run: func(cmd *cobra.Command, args []string) {
-			fmt.Println(meta.Version)
+			if outputFormat == "" {
+				fmt.Println(meta.Version)
+				return
+			}
+
+			versionInfo := types.VersionInfo{
+				ClientVersion: types.ClientVersion{
+					Version:   meta.Version,
+					GitCommit: meta.GitCommit,
+					BuildDate: meta.BuildDate,
+				},
+			}
+
+			if !clientOnly {
+				serverVersion, err := getServerVersion(globalOpts, longhornNamespace)
+				if err != nil {
+					logrus.WithError(err).Warn("Failed to detect the Longhorn version in the cluster")
+				} else {
+					versionInfo.ServerVersion = serverVersion
+					versionInfo.Warnings = checkVersionCompatibility(meta.Version, serverVersion.LonghornVersion)
+				}
+			}
+
+			utils.CheckErr(printVersionInfo(&versionInfo, outputFormat))
 		},
 	}
 
+	cmd.Flags().StringVarP(&outputFormat, consts.CmdOptOutput, "o", "", fmt.Sprintf("Output format of the version report with the Longhorn version (%s, %s, %s). Only the client version is printed by default.", consts.OutputFormatWide, consts.OutputFormatJSON, consts.OutputFormatYAML))
+	cmd.Flags().BoolVar(&clientOnly, consts.CmdOptClient, false, "Only report the client version, without connecting to the cluster.")
+	cmd.Flags().StringVar(&longhornNamespace, consts.CmdOptLonghornNamespace, consts.LonghornNamespace, "Namespace where Longhorn is deployed.")
+
 	return cmd
 }
+
+func getServerVersion(globalOpts *types.GlobalCmdOptions, longhornNamespace string) (*types.ServerVersion, error) {
+	longhornClient, err := kubeutils.NewLonghornClient("", globalOpts.KubeConfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), serverVersionTimeout)
+	defer cancel()
+
+	longhornVersion, err := kubeutils.GetLonghornVersion(ctx, longhornClient, longhornNamespace)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.ServerVersion{LonghornVersion: longhornVersion}, nil
+}
+
+// checkVersionCompatibility returns the warnings about the CLI and Longhorn versions.
+func checkVersionCompatibility(clientVersion, serverVersion string) []string {
+	compatible, err := utils.IsMinorVersionCompatible(clientVersion, serverVersion)
+	if err != nil {
+		return []string{fmt.Sprintf("Unable to compare the versions: %v", err)}
+	}
+
+	if !compatible {
+		return []string{fmt.Sprintf("%s %s does not match the Longhorn version %s, use a CLI with the same major and minor version", consts.CmdLonghornctlRemote, clientVersion, serverVersion)}
+	}
+	return nil
+}
+
+func printVersionInfo(versionInfo *types.VersionInfo, outputFormat string) error {
+	if printed, err := utils.PrintStructuredResult(outputFormat, types.ResultKindVersionInfo, versionInfo); printed || err != nil {
+		return err
+	}
+
+	fmt.Printf("Client Version: %s\n", versionInfo.ClientVersion.Version)
+	fmt.Printf("Git Commit: %s\n", versionInfo.ClientVersion.GitCommit)
+	fmt.Printf("Build Date: %s\n", versionInfo.ClientVersion.BuildDate)
+	if versionInfo.ServerVersion != nil {
+		fmt.Printf("Server Version: %s\n", versionInfo.ServerVersion.LonghornVersion)
+	}
+
+	for _, warning := range versionInfo.Warnings {
+		logrus.Warn(warning)
+	}
+	return nil
+}
@@ -0,0 +1,471 @@
+package subcmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	lhmgrtypes "github.com/longhorn/longhorn-manager/types"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/remote/volume"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+)
+
+func NewCmdVolume(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdVolume,
+		Short: "Longhorn volume maintenance operations",
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.AddCommand(newCmdVolumeDelete(globalOpts))
+	cmd.AddCommand(newCmdVolumeRekey(globalOpts))
+	cmd.AddCommand(newCmdVolumeSalvage(globalOpts))
+	cmd.AddCommand(newCmdVolumeStats(globalOpts))
+
+	return cmd
+}
+
+func newCmdVolumeDelete(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var volumeDeleter = volume.Deleter{}
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdDelete + " <volume-name>",
+		Short: "Delete a Longhorn volume that is no longer used",
+		Long: `This command deletes a Longhorn volume after checking that it is no longer used. The volume is protected from deletion when:
+- It is bound to a PVC.
+- It is attached to a node, or used by pods.
+- It was used by a pod or bound to a PVC within --` + consts.CmdOptIdleTime + `.
+
+A protected volume is only deleted with --` + consts.CmdOptForce + `, which leaves its PV and PVC, if any, unusable.
+
+With --` + consts.CmdOptBackupBeforeDelete + `, a final snapshot of the volume is taken and backed up to the backup target of the volume first. The volume is not deleted when the backup fails.
+
+The snapshots of the volume are deleted with its replicas. Its backups are kept on the backup targets, unless --` + consts.CmdOptDeleteBackups + ` is given.`,
+		Example: `$ longhornctl volume delete pvc-48a6457d-585e-423b-b530-bbc68a5f948a
+INFO[2024-07-16T17:45:02+08:00] Initializing volume deleter
+FATA[2024-07-16T17:45:02+08:00] Failed to initialize volume deleter for volume pvc-48a6457d-585e-423b-b530-bbc68a5f948a: volume pvc-48a6457d-585e-423b-b530-bbc68a5f948a is protected from deletion, it is used by a pod at 2024-07-16T08:12:40Z, within the idle time of 24h0m0s. Use --force to delete it anyway
+
+$ longhornctl volume delete pvc-48a6457d-585e-423b-b530-bbc68a5f948a --backup-before-delete
+INFO[2024-07-16T17:45:02+08:00] Initializing volume deleter
+This will delete volume pvc-48a6457d-585e-423b-b530-bbc68a5f948a and its snapshots, after backing it up.
+Do you want to continue? [y/N]: y
+INFO[2024-07-16T17:45:05+08:00] Creating snapshot                             snapshot=longhornctl-final-20240716-094505-pvc-48a6457d-585e-423b-b530-bbc68a5f948a volume=pvc-48a6457d-585e-423b-b530-bbc68a5f948a
+INFO[2024-07-16T17:45:09+08:00] Backing up snapshot to s3://backups@us-east-1/  backup=longhornctl-final-20240716-094505-pvc-48a6457d-585e-423b-b530-bbc68a5f948a volume=pvc-48a6457d-585e-423b-b530-bbc68a5f948a
+INFO[2024-07-16T17:46:31+08:00] Deleting volume pvc-48a6457d-585e-423b-b530-bbc68a5f948a
+INFO[2024-07-16T17:46:35+08:00] Completed volume deleter                      volume=pvc-48a6457d-585e-423b-b530-bbc68a5f948a`,
+		Args: cobra.ExactArgs(1),
+
+		Annotations: map[string]string{consts.CmdAnnotationAudit: "true"},
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			volumeDeleter.KubeConfigPath = globalOpts.KubeConfigPath
+			volumeDeleter.LogLevel = globalOpts.LogLevel
+			volumeDeleter.VolumeName = args[0]
+
+			utils.CheckErr(volumeDeleter.Validate())
+
+			logrus.Info("Initializing volume deleter")
+			if err := volumeDeleter.Init(); err != nil {
+				utils.CheckErr(errors.Wrapf(err, "Failed to initialize volume deleter for volume %s", volumeDeleter.VolumeName))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			for _, reason := range volumeDeleter.ProtectionReasons() {
+				logrus.Warnf("Deleting volume %s with --%s, it is %s", volumeDeleter.VolumeName, consts.CmdOptForce, reason)
+			}
+
+			summary := fmt.Sprintf("This will delete volume %s and its snapshots", volumeDeleter.VolumeName)
+			switch {
+			case volumeDeleter.BackupBeforeDelete:
+				summary += ", after backing it up."
+			case volumeDeleter.DeleteBackups:
+				summary += ", and all its backups."
+			default:
+				summary += "."
+			}
+			utils.CheckErr(utils.Confirm(globalOpts, summary))
+
+			if err := volumeDeleter.Run(); err != nil {
+				utils.CheckErr(errors.Wrapf(err, "Failed to run volume deleter for volume %s", volumeDeleter.VolumeName))
+			}
+			if volumeDeleter.BackupBeforeDelete {
+				logrus.Infof("The volume can be restored from backup %s", volumeDeleter.BackupName())
+			}
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logrus.WithField("volume", volumeDeleter.VolumeName).Info("Completed volume deleter")
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().BoolVar(&volumeDeleter.Force, consts.CmdOptForce, false, "Delete the volume even if it is bound to a PVC, attached, or used within the idle time.")
+	cmd.Flags().DurationVar(&volumeDeleter.IdleTime, consts.CmdOptIdleTime, 24*time.Hour, "Time since the volume was last used by a pod or bound to a PVC, below which it is protected from deletion.")
+	cmd.Flags().BoolVar(&volumeDeleter.BackupBeforeDelete, consts.CmdOptBackupBeforeDelete, false, "Back up a final snapshot of the volume to its backup target before deleting it.")
+	cmd.Flags().BoolVar(&volumeDeleter.DeleteBackups, consts.CmdOptDeleteBackups, false, "Delete the backups of the volume from the backup targets.")
+	cmd.Flags().DurationVar(&volumeDeleter.Timeout, consts.CmdOptTimeout, time.Hour, "Maximum time to wait for the final backup and for the volume to be deleted.")
+	cmd.Flags().StringVar(&volumeDeleter.LonghornNamespace, consts.CmdOptLonghornNamespace, consts.LonghornNamespace, "Namespace where Longhorn is deployed.")
+
+	cmd.ValidArgsFunction = completeVolumeNames(globalOpts, &volumeDeleter.LonghornNamespace)
+
+	return cmd
+}
+
+func newCmdVolumeRekey(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var volumeRekeyer = volume.Rekeyer{}
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdRekey + " <volume-name>",
+		Short: "Rotate the encryption key of an encrypted Longhorn volume",
+		Long: `This command rotates the encryption key of an encrypted volume to the key of another secret, for example after the key leaked or to follow a rotation policy:
+1. The volume is attached to a node for maintenance, without workload. It defaults to the node of a healthy replica.
+2. A pod on the node re-encrypts the data with a new volume key using cryptsetup reencrypt, and reports its progress.
+3. The passphrase of the new secret is added, verified, and the current passphrase is removed.
+4. The ` + "`" + lhmgrtypes.CryptoKeyValue + "`" + ` of the secret referenced by the PV is replaced by the new key, since the secret of a PV cannot be changed. The volume is then detached.
+
+With --` + consts.CmdOptKeyOnly + `, the data is not re-encrypted, and only the passphrase is replaced. This is fast, but the volume key that encrypts the data is unchanged.
+
+The volume must be detached, use the block device frontend, and be the only volume using the secret of its PV. cryptsetup must be installed on the node, as for any encrypted volume.
+
+When the rotation is interrupted, the maintenance attachment is removed. The re-encryption is crash-safe, and running the command again resumes it. When a later step fails, the new passphrase is removed, so the current key still opens the volume.`,
+		Example: `$ longhornctl volume rekey pvc-48a6457d-585e-423b-b530-bbc68a5f948a --new-secret=default/pvc-48a6457d-crypto-2024q3
+This will re-encrypt volume pvc-48a6457d-585e-423b-b530-bbc68a5f948a, and replace its key with the key of secret default/pvc-48a6457d-crypto-2024q3.
+Do you want to continue? [y/N]: y
+INFO[2024-07-16T17:45:02+08:00] Initializing volume rekeyer
+INFO[2024-07-16T17:45:02+08:00] Cleaning up volume rekeyer
+INFO[2024-07-16T17:45:02+08:00] Running volume rekeyer                        volume=pvc-48a6457d-585e-423b-b530-bbc68a5f948a
+INFO[2024-07-16T17:45:02+08:00] Attaching volume pvc-48a6457d-585e-423b-b530-bbc68a5f948a to node ip-10-0-2-123 for maintenance
+time="2024-07-16T09:45:11Z" level=info msg="Re-encrypting volume with a new volume key" volume=pvc-48a6457d-585e-423b-b530-bbc68a5f948a
+time="2024-07-16T09:45:21Z" level=info msg="Progress:  18.4%, ETA 00:44,  377 MiB written, speed  37.7 MiB/s" volume=pvc-48a6457d-585e-423b-b530-bbc68a5f948a
+...
+time="2024-07-16T09:46:06Z" level=info msg="Adding new passphrase" volume=pvc-48a6457d-585e-423b-b530-bbc68a5f948a
+time="2024-07-16T09:46:08Z" level=info msg="Removing current passphrase" volume=pvc-48a6457d-585e-423b-b530-bbc68a5f948a
+INFO[2024-07-16T17:46:10+08:00] Updating secret default/pvc-48a6457d-crypto with the new key
+INFO[2024-07-16T17:46:10+08:00] Cleaning up volume rekeyer                    volume=pvc-48a6457d-585e-423b-b530-bbc68a5f948a
+INFO[2024-07-16T17:46:11+08:00] Completed volume rekeyer                      volume=pvc-48a6457d-585e-423b-b530-bbc68a5f948a`,
+		Args: cobra.ExactArgs(1),
+
+		Annotations: map[string]string{consts.CmdAnnotationAudit: "true"},
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			volumeRekeyer.Image = globalOpts.Image
+			volumeRekeyer.KubeConfigPath = globalOpts.KubeConfigPath
+			volumeRekeyer.Namespace = globalOpts.Namespace
+			volumeRekeyer.PodCpu = globalOpts.PodCpu
+			volumeRekeyer.PodMemory = globalOpts.PodMemory
+			volumeRekeyer.PriorityClass = globalOpts.PriorityClass
+			volumeRekeyer.Proxy = globalOpts.Proxy
+			volumeRekeyer.NoProxy = globalOpts.NoProxy
+			volumeRekeyer.Privileged = globalOpts.Privileged
+			volumeRekeyer.LogLevel = globalOpts.LogLevel
+			volumeRekeyer.VolumeName = args[0]
+
+			utils.CheckErr(volumeRekeyer.Validate())
+
+			summary := fmt.Sprintf("This will re-encrypt volume %s, and replace its key with the key of secret %s.", volumeRekeyer.VolumeName, volumeRekeyer.NewSecret)
+			if volumeRekeyer.KeyOnly {
+				summary = fmt.Sprintf("This will replace the key of volume %s with the key of secret %s.", volumeRekeyer.VolumeName, volumeRekeyer.NewSecret)
+			}
+			utils.CheckErr(utils.Confirm(globalOpts, summary))
+
+			logrus.Info("Initializing volume rekeyer")
+			if err := volumeRekeyer.Init(); err != nil {
+				utils.CheckErr(errors.Wrapf(err, "Failed to initialize volume rekeyer for volume %s", volumeRekeyer.VolumeName))
+			}
+
+			logrus.Info("Cleaning up volume rekeyer")
+			if err := volumeRekeyer.Cleanup(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to cleanup volume rekeyer"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			log := logrus.WithField("volume", volumeRekeyer.VolumeName)
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			log.Info("Running volume rekeyer")
+			err := volumeRekeyer.Run(ctx, func(line string) {
+				fmt.Fprintln(os.Stderr, line)
+			})
+			if err != nil {
+				log.Info("Cleaning up volume rekeyer")
+				if cleanupErr := volumeRekeyer.Cleanup(); cleanupErr != nil {
+					log.WithError(cleanupErr).Warn("Failed to cleanup volume rekeyer")
+				}
+				utils.CheckErr(errors.Wrapf(err, "Failed to run volume rekeyer for volume %s, run the command again to resume", volumeRekeyer.VolumeName))
+			}
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			log := logrus.WithField("volume", volumeRekeyer.VolumeName)
+			log.Info("Cleaning up volume rekeyer")
+			if err := volumeRekeyer.Cleanup(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to cleanup volume rekeyer"))
+			}
+
+			log.Info("Completed volume rekeyer")
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVar(&volumeRekeyer.NewSecret, consts.CmdOptNewSecret, "", "Secret holding the new key in "+lhmgrtypes.CryptoKeyValue+", as namespace/name.")
+	cmd.Flags().StringVar(&volumeRekeyer.NodeID, consts.CmdOptNode, "", "Node to attach the volume to during the rotation. Defaults to the node of a healthy replica.")
+	cmd.Flags().BoolVar(&volumeRekeyer.KeyOnly, consts.CmdOptKeyOnly, false, "Only replace the passphrase, without re-encrypting the data.")
+	cmd.Flags().StringVar(&volumeRekeyer.LonghornNamespace, consts.CmdOptLonghornNamespace, consts.LonghornNamespace, "Namespace where Longhorn is deployed.")
+
+	cmd.ValidArgsFunction = completeVolumeNames(globalOpts, &volumeRekeyer.LonghornNamespace)
+
+	return cmd
+}
+
+func newCmdVolumeSalvage(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var volumeSalvager = volume.Salvager{}
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdSalvage + " <volume-name>",
+		Short: "Salvage a faulted Longhorn volume from a chosen failed replica",
+		Long: `This command applies the documented manual salvage procedure to a faulted volume whose replicas all failed, when automatic salvage is disabled or chose the wrong replica:
+- The chosen replica is marked as usable by clearing its spec.failedAt.
+- The active engine is stopped when it is still desired running, so the volume controller starts it again with the replica at the next attachment.
+
+The salvage is refused unless the volume is detached and faulted, the replica belongs to the volume, is failed and completed a rebuild once, and its node and disk are ready. The other replicas that are not failed, or were healthy more recently than the chosen one, are reported.
+
+The fields to modify are printed before confirmation. With --` + consts.CmdOptDryRun + `, they are only printed.
+
+Attach the volume afterwards, and verify its data before using it. The other failed replicas are rebuilt from the salvaged one.`,
+		Example: `$ longhornctl volume salvage pvc-48a6457d-585e-423b-b530-bbc68a5f948a --replica=pvc-48a6457d-585e-423b-b530-bbc68a5f948a-r-0e2603a7 --dry-run
+INFO[2024-07-16T17:40:12+08:00] Initializing volume salvager
+INFO[2024-07-16T17:40:12+08:00] Running volume salvager                       volume=pvc-48a6457d-585e-423b-b530-bbc68a5f948a
+WARN[2024-07-16T17:40:12+08:00] Replica pvc-48a6457d-585e-423b-b530-bbc68a5f948a-r-5d8a2c11 was healthy more recently (2024-07-16T08:53:04Z) than replica pvc-48a6457d-585e-423b-b530-bbc68a5f948a-r-0e2603a7 (2024-07-16T08:41:37Z), it may hold more recent data
+KIND/NAME                                                       FIELD           FROM                  TO
+Replica/pvc-48a6457d-585e-423b-b530-bbc68a5f948a-r-0e2603a7    spec.failedAt   2024-07-16T09:02:11Z  ""
+INFO[2024-07-16T17:40:12+08:00] Dry run, no change is applied                 volume=pvc-48a6457d-585e-423b-b530-bbc68a5f948a
+INFO[2024-07-16T17:40:12+08:00] Completed volume salvager                     volume=pvc-48a6457d-585e-423b-b530-bbc68a5f948a`,
+		Args: cobra.ExactArgs(1),
+
+		Annotations: map[string]string{consts.CmdAnnotationAudit: "true"},
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			volumeSalvager.KubeConfigPath = globalOpts.KubeConfigPath
+			volumeSalvager.LogLevel = globalOpts.LogLevel
+			volumeSalvager.VolumeName = args[0]
+
+			utils.CheckErr(volumeSalvager.Validate())
+
+			logrus.Info("Initializing volume salvager")
+			if err := volumeSalvager.Init(); err != nil {
+				utils.CheckErr(errors.Wrapf(err, "Failed to initialize volume salvager for volume %s", volumeSalvager.VolumeName))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			log := logrus.WithField("volume", volumeSalvager.VolumeName)
+
+			log.Info("Running volume salvager")
+			for _, warning := range volumeSalvager.Warnings() {
+				logrus.Warn(warning)
+			}
+			utils.CheckErr(printVolumeFieldChanges(volumeSalvager.Changes()))
+
+			if dryRun {
+				log.Info("Dry run, no change is applied")
+				return
+			}
+
+			utils.CheckErr(utils.Confirm(globalOpts, fmt.Sprintf("This will salvage volume %s from replica %s, and apply the changes above.", volumeSalvager.VolumeName, volumeSalvager.ReplicaName)))
+			if err := volumeSalvager.Run(); err != nil {
+				utils.CheckErr(errors.Wrapf(err, "Failed to run volume salvager for volume %s", volumeSalvager.VolumeName))
+			}
+			log.Info("Attach the volume, and verify its data before using it")
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logrus.WithField("volume", volumeSalvager.VolumeName).Info("Completed volume salvager")
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVar(&volumeSalvager.ReplicaName, consts.CmdOptReplica, "", "Name of the failed replica to salvage the volume from.")
+	cmd.Flags().BoolVar(&dryRun, consts.CmdOptDryRun, false, "Only print the fields of the custom resources the salvage would modify.")
+	cmd.Flags().StringVar(&volumeSalvager.LonghornNamespace, consts.CmdOptLonghornNamespace, consts.LonghornNamespace, "Namespace where Longhorn is deployed.")
+
+	cmd.ValidArgsFunction = completeVolumeNames(globalOpts, &volumeSalvager.LonghornNamespace)
+
+	return cmd
+}
+
+func newCmdVolumeStats(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var statsSampler = volume.StatsSampler{}
+	var outputFormat string
+	var summaryOnly bool
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdStats + " <volume-name>",
+		Short: "Sample the IO of a Longhorn volume reported by its engine",
+		Long: `This command samples the IO of an attached volume --` + consts.CmdOptCount + ` times, --` + consts.CmdOptInterval + ` apart, to triage performance complaints without Prometheus. The engine of the volume reports, per second:
+- IOPS: read and write operations.
+- Throughput: read and written bytes.
+- Latency: average latency of the read and write operations.
+
+The metrics are read from the Longhorn manager on the node of the engine, which gets them from the engine in the instance manager, through the Kubernetes API server proxy.
+
+Each sample is printed as it is taken, followed by the minimum, average and maximum of each metric. With --` + consts.CmdOptSummary + `, only the summary is printed. Interrupt the command to stop sampling early and print the summary of the samples taken.`,
+		Example: `$ longhornctl volume stats pvc-48a6457d-585e-423b-b530-bbc68a5f948a --interval=5s --count=3
+INFO[2024-07-16T21:30:02+08:00] Initializing volume stats sampler
+INFO[2024-07-16T21:30:02+08:00] Running volume stats sampler                  volume=pvc-48a6457d-585e-423b-b530-bbc68a5f948a
+TIME                  READ IOPS  WRITE IOPS  READ        WRITE       READ LATENCY  WRITE LATENCY
+2024-07-16T13:30:02Z  120        340         4.7MiB/s    1.3MiB/s    512µs         1.2ms
+2024-07-16T13:30:07Z  98         412         3.8MiB/s    1.6MiB/s    488µs         1.4ms
+2024-07-16T13:30:12Z  143        388         5.6MiB/s    1.5MiB/s    530µs         1.3ms
+
+METRIC            MINIMUM   AVERAGE   MAXIMUM
+read_iops         98        120       143
+write_iops        340       380       412
+read_throughput   3.8MiB/s  4.7MiB/s  5.6MiB/s
+write_throughput  1.3MiB/s  1.5MiB/s  1.6MiB/s
+read_latency      488µs     510µs     530µs
+write_latency     1.2ms     1.3ms     1.4ms
+INFO[2024-07-16T21:30:12+08:00] Completed volume stats sampler                volume=pvc-48a6457d-585e-423b-b530-bbc68a5f948a
+
+$ longhornctl volume stats pvc-48a6457d-585e-423b-b530-bbc68a5f948a --count=60 -o json`,
+		Args: cobra.ExactArgs(1),
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			statsSampler.KubeConfigPath = globalOpts.KubeConfigPath
+			statsSampler.LogLevel = globalOpts.LogLevel
+			statsSampler.VolumeName = args[0]
+
+			utils.CheckErr(utils.ValidateOutputFormat(outputFormat, consts.OutputFormatJSON, consts.OutputFormatYAML))
+			utils.CheckErr(statsSampler.Validate())
+
+			logrus.Info("Initializing volume stats sampler")
+			if err := statsSampler.Init(); err != nil {
+				utils.CheckErr(errors.Wrapf(err, "Failed to initialize volume stats sampler for volume %s", statsSampler.VolumeName))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			printSamples := outputFormat == "" && !summaryOnly
+			onSample := func(sample types.VolumeIOSample) {
+				if printSamples {
+					printVolumeIOSample(sample)
+				}
+			}
+			if printSamples {
+				printVolumeIOSampleHeader()
+			}
+
+			logrus.WithField("volume", statsSampler.VolumeName).Info("Running volume stats sampler")
+			stats, err := statsSampler.Run(ctx, onSample)
+			if err != nil {
+				utils.CheckErr(errors.Wrapf(err, "Failed to run volume stats sampler for volume %s", statsSampler.VolumeName))
+			}
+
+			if printed, err := utils.PrintStructuredResult(outputFormat, types.ResultKindVolumeIOStats, stats); printed || err != nil {
+				utils.CheckErr(err)
+				return
+			}
+			if printSamples {
+				fmt.Println()
+			}
+			utils.CheckErr(printVolumeIOSummary(stats.Summary))
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logrus.WithField("volume", statsSampler.VolumeName).Info("Completed volume stats sampler")
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&outputFormat, consts.CmdOptOutput, "o", "", fmt.Sprintf("Output format of the result (%s, %s).", consts.OutputFormatJSON, consts.OutputFormatYAML))
+	cmd.Flags().DurationVar(&statsSampler.Interval, consts.CmdOptInterval, 5*time.Second, "Interval between the samples.")
+	cmd.Flags().IntVar(&statsSampler.Count, consts.CmdOptCount, 12, "Number of samples.")
+	cmd.Flags().BoolVar(&summaryOnly, consts.CmdOptSummary, false, "Only print the summary of the samples.")
+	cmd.Flags().StringVar(&statsSampler.LonghornNamespace, consts.CmdOptLonghornNamespace, consts.LonghornNamespace, "Namespace where Longhorn is deployed.")
+
+	cmd.ValidArgsFunction = completeVolumeNames(globalOpts, &statsSampler.LonghornNamespace)
+
+	return cmd
+}
+
+// volumeIOSampleFormat is the format of the rows of the samples, printed as they are taken so they
+// are aligned without a tabwriter.
+const volumeIOSampleFormat = "%-20s  %-9v  %-10v  %-10s  %-10s  %-12s  %s\n"
+
+func printVolumeIOSampleHeader() {
+	fmt.Printf(volumeIOSampleFormat, "TIME", "READ IOPS", "WRITE IOPS", "READ", "WRITE", "READ LATENCY", "WRITE LATENCY")
+}
+
+func printVolumeIOSample(sample types.VolumeIOSample) {
+	fmt.Printf(volumeIOSampleFormat, sample.Time, sample.ReadIOPS, sample.WriteIOPS,
+		formatThroughput(sample.ReadThroughput), formatThroughput(sample.WriteThroughput),
+		formatLatency(sample.ReadLatency), formatLatency(sample.WriteLatency))
+}
+
+func printVolumeIOSummary(summary []types.VolumeIOStatsRange) error {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "METRIC\tMINIMUM\tAVERAGE\tMAXIMUM")
+	for _, statsRange := range summary {
+		format := func(value int64) string { return fmt.Sprint(value) }
+		switch {
+		case strings.HasSuffix(statsRange.Metric, "_throughput"):
+			format = formatThroughput
+		case strings.HasSuffix(statsRange.Metric, "_latency"):
+			format = formatLatency
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", statsRange.Metric, format(statsRange.Minimum), format(statsRange.Average), format(statsRange.Maximum))
+	}
+	return writer.Flush()
+}
+
+func formatThroughput(bytesPerSecond int64) string {
+	return utils.FormatBytes(bytesPerSecond) + "/s"
+}
+
+func formatLatency(nanoseconds int64) string {
+	latency := time.Duration(nanoseconds)
+	if latency >= time.Millisecond {
+		return latency.Round(100 * time.Microsecond).String()
+	}
+	return latency.Round(time.Microsecond).String()
+}
+
+func printVolumeFieldChanges(changes []types.VolumeFieldChange) error {
+	orEmpty := func(value string) string {
+		if value == "" {
+			return `""`
+		}
+		return value
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "KIND/NAME\tFIELD\tFROM\tTO")
+	for _, change := range changes {
+		fmt.Fprintf(writer, "%s/%s\t%s\t%s\t%s\n", change.Kind, change.Name, change.Field, orEmpty(change.From), orEmpty(change.To))
+	}
+	return writer.Flush()
+}
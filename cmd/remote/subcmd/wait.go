@@ -0,0 +1,105 @@
+package subcmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/remote/async"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+)
+
+func NewCmdWait(globalOpts *types.GlobalCmdOptions) *cobra.Command {
+	var getter = async.Getter{}
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   consts.SubCmdWait + " <run-id>",
+		Short: "Wait for a command submitted with --" + consts.CmdOptNoWait + " to complete",
+		Long: `This command waits for the Job of a command submitted with --` + consts.CmdOptNoWait + ` to complete, and prints the status of the run. The run is read from the ` + consts.AppNameAsyncRun + `-<run-id> ConfigMap recording it, in the namespace of --` + consts.CmdOptNamespace + `.
+
+The command fails when the run failed, or does not complete within --` + consts.CmdOptTimeout + `. Get the result of a succeeded run with '` + consts.CmdLonghornctlRemote + ` ` + consts.SubCmdResult + ` <run-id>'.`,
+		Example: `$ longhornctl check preflight --no-wait
+INFO[2024-07-16T17:40:12+08:00] Initializing async submitter
+INFO[2024-07-16T17:40:12+08:00] Running async submitter                       run=5f2a9c1e
+5f2a9c1e
+INFO[2024-07-16T17:40:12+08:00] Submitted run 5f2a9c1e to Job longhorn-system/longhornctl-async-5f2a9c1e, run 'longhornctl wait 5f2a9c1e' to wait for it and 'longhornctl result 5f2a9c1e' to get its result
+INFO[2024-07-16T17:40:12+08:00] Completed async submitter                     run=5f2a9c1e
+
+$ longhornctl wait 5f2a9c1e
+INFO[2024-07-16T17:40:20+08:00] Initializing async getter
+INFO[2024-07-16T17:40:20+08:00] Running async getter                          run=5f2a9c1e
+INFO[2024-07-16T17:40:20+08:00] Run 5f2a9c1e is Running
+INFO[2024-07-16T17:41:04+08:00] Run 5f2a9c1e is Succeeded
+RUN       STATUS     SUBMITTED                  COMPLETED                  COMMAND
+5f2a9c1e  Succeeded  2024-07-16T09:40:12Z       2024-07-16T09:41:03Z       longhornctl check preflight
+INFO[2024-07-16T17:41:04+08:00] Completed async getter                        run=5f2a9c1e`,
+		Args: cobra.ExactArgs(1),
+
+		PreRun: func(cmd *cobra.Command, args []string) {
+			getter.KubeConfigPath = globalOpts.KubeConfigPath
+			getter.Namespace = globalOpts.Namespace
+			getter.LogLevel = globalOpts.LogLevel
+			getter.RunID = args[0]
+
+			utils.CheckErr(utils.ValidateOutputFormat(outputFormat, consts.OutputFormatJSON, consts.OutputFormatYAML))
+			utils.CheckErr(getter.Validate())
+			if getter.Timeout <= 0 {
+				utils.CheckErr(errors.Errorf("--%s must be positive", consts.CmdOptTimeout))
+			}
+
+			logrus.Info("Initializing async getter")
+			if err := getter.Init(); err != nil {
+				utils.CheckErr(errors.Wrap(err, "Failed to initialize async getter"))
+			}
+		},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			logrus.WithField("run", getter.RunID).Info("Running async getter")
+			run, err := getter.Wait()
+			if err != nil {
+				utils.CheckErr(errors.Wrapf(err, "Failed to wait for run %s", getter.RunID))
+			}
+
+			printed, err := utils.PrintStructuredResult(outputFormat, types.ResultKindAsyncRun, run)
+			utils.CheckErr(err)
+			if !printed {
+				utils.CheckErr(printAsyncRun(run))
+			}
+
+			if run.Status == consts.AsyncRunStatusFailed {
+				utils.CheckErr(errors.Errorf("run %s failed: %s, see the logs of Job %s/%s", run.ID, run.Message, run.Namespace, run.Job))
+			}
+		},
+
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logrus.WithField("run", getter.RunID).Info("Completed async getter")
+		},
+	}
+
+	utils.SetGlobalOptionsRemote(cmd, globalOpts)
+
+	cmd.Flags().StringVarP(&outputFormat, consts.CmdOptOutput, "o", "", fmt.Sprintf("Output format of the run (%s, %s).", consts.OutputFormatJSON, consts.OutputFormatYAML))
+	cmd.Flags().DurationVar(&getter.Timeout, consts.CmdOptTimeout, time.Hour, "Maximum time to wait for the run to complete.")
+
+	return cmd
+}
+
+func printAsyncRun(run *types.AsyncRun) error {
+	completedAt := "-"
+	if run.CompletedAt != nil {
+		completedAt = run.CompletedAt.UTC().Format(time.RFC3339)
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "RUN\tSTATUS\tSUBMITTED\tCOMPLETED\tCOMMAND")
+	fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\n", run.ID, run.Status, run.SubmittedAt.UTC().Format(time.RFC3339), completedAt, run.Command)
+	return writer.Flush()
+}
@@ -0,0 +1,18 @@
+package consts
+
+const (
+	// ContainerNameAgent is the container running the node agent, which runs the local command and
+	// serves its progress and result.
+	ContainerNameAgent = "agent-longhornctl"
+
+	// AgentPort is the port the node agent listens on the localhost of the pod. The CLI reaches it
+	// through a port-forward.
+	AgentPort = 9508
+
+	AgentPathStatus   = "/v1/status"
+	AgentPathProgress = "/v1/progress"
+	AgentPathResult   = "/v1/result"
+
+	// AgentMaxProgressEvents is the number of latest progress events the node agent keeps.
+	AgentMaxProgressEvents = 1000
+)
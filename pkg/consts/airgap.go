@@ -0,0 +1,23 @@
+package consts
+
+// Operating systems of the air-gap bundle.
+const (
+	AirgapOSRHEL   = "rhel"
+	AirgapOSSLES   = "sles"
+	AirgapOSUbuntu = "ubuntu"
+)
+
+// Layout of the air-gap bundle archive.
+const (
+	AirgapBundleDirectory        = "longhorn-airgap-bundle"
+	AirgapImagesDirectory        = "images"
+	AirgapPackagesDirectory      = "packages"
+	FileNameAirgapDownloadScript = "download-packages.sh"
+	FileNameAirgapImageArchive   = "longhorn-cli.tar"
+	FileNameAirgapManifest       = "manifest.yaml"
+	FileNameAirgapReadme         = "README.md"
+)
+
+// PackageFileExtensions are the extensions of the package files installed from the package source
+// of the preflight install.
+var PackageFileExtensions = []string{".deb", ".rpm", ".pkg.tar.zst", ".pkg.tar.xz"}
@@ -0,0 +1,22 @@
+package consts
+
+const (
+	// CmdAnnotationAudit marks the commands recorded in the audit log.
+	CmdAnnotationAudit = "longhornctl/audit"
+
+	// CmdAnnotationSync marks the audited commands that cannot be submitted to a Job with
+	// --no-wait, since they interact with the local terminal or files, or another cluster.
+	CmdAnnotationSync = "longhornctl/sync"
+)
+
+const (
+	EventReasonOperationSucceeded = "LonghornctlOperationSucceeded"
+	EventReasonOperationFailed    = "LonghornctlOperationFailed"
+)
+
+const (
+	AnnotationAuditUser      = "longhorn.io/longhornctl-user"
+	AnnotationAuditHostUser  = "longhorn.io/longhornctl-host-user"
+	AnnotationAuditCommand   = "longhorn.io/longhornctl-command"
+	AnnotationAuditStartTime = "longhorn.io/longhornctl-start-time"
+)
@@ -0,0 +1,10 @@
+package consts
+
+const (
+	// AutoscalerAnnotationScaleDownDisabled is the node annotation preventing the Cluster
+	// Autoscaler from removing the node.
+	AutoscalerAnnotationScaleDownDisabled = "cluster-autoscaler.kubernetes.io/scale-down-disabled"
+	// AutoscalerAnnotationSafeToEvict is the pod annotation telling the Cluster Autoscaler whether it
+	// can evict the pod to remove its node.
+	AutoscalerAnnotationSafeToEvict = "cluster-autoscaler.kubernetes.io/safe-to-evict"
+)
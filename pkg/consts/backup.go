@@ -0,0 +1,12 @@
+package consts
+
+const (
+	AppNameBackupVerifier = "longhorn-backup-verifier"
+
+	ContainerNameChecksum = "checksum"
+
+	// VolumeDeviceBackupVerifyPath is where the backup verifier pod gets the block device of the
+	// restored volume.
+	VolumeDeviceBackupVerifyPath = "/dev/longhorn-restored"
+	VolumeDeviceBackupVerifyName = "restored"
+)
@@ -0,0 +1,5 @@
+package consts
+
+const (
+	AppNameBaselineCollector = "longhorn-baseline-collector"
+)
@@ -0,0 +1,41 @@
+package consts
+
+const (
+	AppNameDiskBenchmark    = "longhorn-disk-benchmark"
+	AppNameNetworkBenchmark = "longhorn-network-benchmark"
+	AppNameVolumeBenchmark  = "longhorn-volume-benchmark"
+
+	// ImageFio is the image running fio for the volume benchmark.
+	ImageFio = "ghcr.io/kastenhq/kubestr:latest"
+	// ImageIperf is the image running iperf3 for the network benchmark.
+	ImageIperf = "networkstatic/iperf3:latest"
+
+	ContainerNameFio   = "fio"
+	ContainerNameIperf = "iperf3"
+
+	VolumeMountBenchmarkName      = "benchmark"
+	VolumeMountBenchmarkDirectory = "/benchmark"
+)
+
+// Profiles of the volume benchmark.
+const (
+	BenchmarkProfileLatency   = "latency"
+	BenchmarkProfileRandRW    = "randrw"
+	BenchmarkProfileRandRead  = "randread"
+	BenchmarkProfileRandWrite = "randwrite"
+	BenchmarkProfileSeqRead   = "seqread"
+	BenchmarkProfileSeqWrite  = "seqwrite"
+)
+
+// Networks of the network benchmark.
+const (
+	BenchmarkNetworkHost = "host"
+	BenchmarkNetworkPod  = "pod"
+)
+
+// Default latency thresholds of the disk benchmark, in microseconds. Longhorn recommends SSD or
+// NVMe disks, and a 4KiB random I/O slower than these usually comes from a spinning or overloaded disk.
+const (
+	BenchmarkDiskMaxReadLatencyUsec  = 1000
+	BenchmarkDiskMaxWriteLatencyUsec = 2000
+)
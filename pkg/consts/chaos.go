@@ -0,0 +1,5 @@
+package consts
+
+const (
+	AppNameNetworkPartitioner = "longhorn-network-partitioner"
+)
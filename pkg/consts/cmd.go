@@ -8,52 +8,302 @@ const (
 
 const (
 	// The first layer of subcommands (verb)
-	SubCmdCheck   = "check"
-	SubCmdExport  = "export"
-	SubCmdGet     = "get"
-	SubCmdInstall = "install"
-	SubCmdTrim    = "trim"
+	SubCmdAgent     = "agent"
+	SubCmdApi       = "api"
+	SubCmdBackup    = "backup"
+	SubCmdBaseline  = "baseline"
+	SubCmdBenchmark = "benchmark"
+	SubCmdCheck     = "check"
+	SubCmdCleanup   = "cleanup"
+	SubCmdCollect   = "collect"
+	SubCmdDr        = "dr"
+	SubCmdEvents    = "events"
+	SubCmdExport    = "export"
+	SubCmdGenerate  = "generate"
+	SubCmdGet       = "get"
+	SubCmdImport    = "import"
+	SubCmdInit      = "init"
+	SubCmdInspect   = "inspect"
+	SubCmdInstall   = "install"
+	SubCmdLogs      = "logs"
+	SubCmdMigrate   = "migrate"
+	SubCmdNode      = "node"
+	SubCmdPreload   = "preload"
+	SubCmdRebuild   = "rebuild"
+	SubCmdReport    = "report"
+	SubCmdRestart   = "restart"
+	SubCmdResult    = "result"
+	SubCmdSchema    = "schema"
+	SubCmdServe     = "serve"
+	SubCmdSnapshot  = "snapshot"
+	SubCmdTelemetry = "telemetry"
+	SubCmdTest      = "test"
+	SubCmdTop       = "top"
+	SubCmdTrim      = "trim"
+	SubCmdValidate  = "validate"
+	SubCmdVerify    = "verify"
+	SubCmdWait      = "wait"
 
 	// The second layer of subcommands (noun)
-	SubCmdPreflight = "preflight"
-	SubCmdReplica   = "replica"
-	SubCmdVolume    = "volume"
+	SubCmdAirgapBundle    = "airgap-bundle"
+	SubCmdAll             = "all"
+	SubCmdAutoscaler      = "autoscaler"
+	SubCmdCapacity        = "capacity"
+	SubCmdCluster         = "cluster"
+	SubCmdCp              = "cp"
+	SubCmdCrds            = "crds"
+	SubCmdDisk            = "disk"
+	SubCmdExec            = "exec"
+	SubCmdFailover        = "failover"
+	SubCmdGate            = "gate"
+	SubCmdImages          = "images"
+	SubCmdInstanceManager = "instance-manager"
+	SubCmdJob             = "job"
+	SubCmdMonitoring      = "monitoring"
+	SubCmdMounts          = "mounts"
+	SubCmdNetwork         = "network"
+	SubCmdNodeDevices     = "node-devices"
+	SubCmdNodeFacts       = "node-facts"
+	SubCmdPciBindings     = "pci-bindings"
+	SubCmdPreflight       = "preflight"
+	SubCmdPrerequisites   = "prerequisites"
+	SubCmdProtection      = "protection"
+	SubCmdReplica         = "replica"
+	SubCmdReplicaMeta     = "replica-meta"
+	SubCmdResults         = "results"
+	SubCmdRwx             = "rwx"
+	SubCmdServiceMonitor  = "servicemonitor"
+	SubCmdStorageClasses  = "storageclasses"
+	SubCmdTopology        = "topology"
+	SubCmdTuning          = "tuning"
+	SubCmdVelero          = "velero"
+	SubCmdVeleroConfig    = "velero-config"
+	SubCmdVolume          = "volume"
+	SubCmdWebhooks        = "webhooks"
 
 	// The third layer of subcommands (action to the previous layers)
-	SubCmdStop = "stop"
+	SubCmdActivate      = "activate"
+	SubCmdCreate        = "create"
+	SubCmdDelete        = "delete"
+	SubCmdDisable       = "disable"
+	SubCmdEnable        = "enable"
+	SubCmdFsck          = "fsck"
+	SubCmdImportFromCSI = "import-from-csi"
+	SubCmdPromoteToCSI  = "promote-to-csi"
+	SubCmdRekey         = "rekey"
+	SubCmdSalvage       = "salvage"
+	SubCmdStats         = "stats"
+	SubCmdStatus        = "status"
+	SubCmdStop          = "stop"
+	SubCmdThrottle      = "throttle"
 
 	// Other subcommands
-	SubCmdVersion = "version"
+	SubCmdSelfUpdate = "self-update"
+	SubCmdVersion    = "version"
 )
 
 const (
 	// Global options
 	CmdOptKubeConfigPath = "kube-config"
+	CmdOptKubeApiQps     = "kube-api-qps"
+	CmdOptKubeApiBurst   = "kube-api-burst"
 	CmdOptLogLevel       = "log-level"
+	CmdOptLogFormat      = "log-format"
+	CmdOptLogFile        = "log-file"
+	CmdOptVerbosity      = "verbosity"
+	CmdOptQuiet          = "quiet"
+	CmdOptNoColor        = "no-color"
+	CmdOptLang           = "lang"
+	CmdOptYes            = "yes"
+	CmdOptForceUnlock    = "force-unlock"
 	CmdOptImage          = "image"
+	CmdOptNamespace      = "namespace"
+	CmdOptPodCpu         = "pod-cpu"
+	CmdOptPodMemory      = "pod-memory"
+	CmdOptPrivileged     = "privileged"
+	CmdOptPriorityClass  = "priority-class"
+	CmdOptProxy          = "proxy"
+	CmdOptNoProxy        = "no-proxy"
+	CmdOptNoWait         = "no-wait"
+	CmdOptOutputTo       = "output-to"
+	CmdOptTelemetry      = "telemetry"
+	CmdOptTelemetryURL   = "telemetry-url"
 
 	// General options
-	CmdOptName            = "name"
-	CmdOptNodeId          = "node-id"
-	CmdOptOperatingSystem = "operating-system"
-	CmdOptOutputFile      = "output-file"
-	CmdOptTargetDirectory = "target-dir"
-	CmdOptUpdatePackages  = "update-packages"
-	CmdOptNodeSelector    = "node-selector"
+	CmdOptApply                   = "apply"
+	CmdOptArchive                 = "archive"
+	CmdOptBackend                 = "backend"
+	CmdOptBackup                  = "backup"
+	CmdOptBackupBeforeDelete      = "backup-before-delete"
+	CmdOptBackupTarget            = "backup-target"
+	CmdOptBackupVolume            = "backup-volume"
+	CmdOptCatalogURL              = "catalog-url"
+	CmdOptClient                  = "client"
+	CmdOptCheckOnly               = "check-only"
+	CmdOptComponent               = "component"
+	CmdOptConcurrency             = "concurrency"
+	CmdOptCount                   = "count"
+	CmdOptCustomChecks            = "custom-checks"
+	CmdOptCustomChecksConfigMap   = "custom-checks-configmap"
+	CmdOptDataPath                = "data-path"
+	CmdOptCredentialSecret        = "credential-secret"
+	CmdOptDryRun                  = "dry-run"
+	CmdOptCryptoBenchmark         = "crypto-benchmark"
+	CmdOptDeleteBackups           = "delete-backups"
+	CmdOptDeleteStale             = "delete-stale"
+	CmdOptDeletionPolicy          = "deletion-policy"
+	CmdOptFilename                = "filename"
+	CmdOptFix                     = "fix"
+	CmdOptFioImage                = "fio-image"
+	CmdOptFollow                  = "follow"
+	CmdOptForce                   = "force"
+	CmdOptForceDrain              = "force-drain"
+	CmdOptFormat                  = "format"
+	CmdOptFrontend                = "frontend"
+	CmdOptGrep                    = "grep"
+	CmdOptHostRoot                = "host-root"
+	CmdOptIdleTime                = "idle-time"
+	CmdOptGrowthWindow            = "growth-window"
+	CmdOptImagesFile              = "images-file"
+	CmdOptIncludeCredentials      = "include-credentials"
+	CmdOptIncludeState            = "include-state"
+	CmdOptInput                   = "input"
+	CmdOptInspect                 = "inspect"
+	CmdOptInterval                = "interval"
+	CmdOptIperfImage              = "iperf-image"
+	CmdOptKeyFile                 = "key-file"
+	CmdOptKeyOnly                 = "key-only"
+	CmdOptKill                    = "kill"
+	CmdOptKinds                   = "kinds"
+	CmdOptKnownIssues             = "known-issues"
+	CmdOptLabels                  = "labels"
+	CmdOptLimit                   = "limit"
+	CmdOptListenAddress           = "listen"
+	CmdOptManifestFile            = "manifest-file"
+	CmdOptMaxBackupAge            = "max-backup-age"
+	CmdOptMaxBackupFailures       = "max-backup-failures"
+	CmdOptMaxLag                  = "max-lag"
+	CmdOptMaxParallel             = "max-parallel"
+	CmdOptMaxSize                 = "max-size"
+	CmdOptMaxUnavailable          = "max-unavailable"
+	CmdOptMaxReadLatency          = "max-read-latency"
+	CmdOptMaxWriteLatency         = "max-write-latency"
+	CmdOptName                    = "name"
+	CmdOptNamespaceLabel          = "namespace-label"
+	CmdOptNetworks                = "networks"
+	CmdOptNewSecret               = "new-secret"
+	CmdOptNode                    = "node"
+	CmdOptNodeId                  = "node-id"
+	CmdOptNodes                   = "nodes"
+	CmdOptNumberOfReplicas        = "number-of-replicas"
+	CmdOptOfflineCatalog          = "offline-catalog"
+	CmdOptOutput                  = "output"
+	CmdOptOperatingSystem         = "operating-system"
+	CmdOptOS                      = "os"
+	CmdOptPackageSource           = "package-source"
+	CmdOptPartitionDuration       = "partition-duration"
+	CmdOptPlatform                = "platform"
+	CmdOptPort                    = "port"
+	CmdOptPodMonitor              = "pod-monitor"
+	CmdOptPrometheusURL           = "prometheus-url"
+	CmdOptPrometheusAccount       = "prometheus-service-account"
+	CmdOptPrune                   = "prune"
+	CmdOptQuiesce                 = "quiesce"
+	CmdOptProfile                 = "profile"
+	CmdOptProfiles                = "profiles"
+	CmdOptRebootStrategy          = "reboot-strategy"
+	CmdOptRegistryCheckImages     = "registry-check-images"
+	CmdOptRegistryCheckImagesFile = "registry-check-images-file"
+	CmdOptRegistryCheckVersion    = "registry-check-version"
+	CmdOptReadOnly                = "read-only"
+	CmdOptRepair                  = "repair"
+	CmdOptReset                   = "reset"
+	CmdOptResetCheckpoint         = "reset-checkpoint"
+	CmdOptRetain                  = "retain"
+	CmdOptReplica                 = "replica"
+	CmdOptRequireMinNodes         = "require-min-nodes"
+	CmdOptRulesURL                = "rules-url"
+	CmdOptRuntime                 = "runtime"
+	CmdOptSeverityPolicy          = "severity-policy"
+	CmdOptSHA256                  = "sha256"
+	CmdOptShare                   = "share"
+	CmdOptShareAllowedCIDRs       = "share-allowed-cidrs"
+	CmdOptShareImage              = "share-image"
+	CmdOptShareSecret             = "share-secret"
+	CmdOptShareUsername           = "share-username"
+	CmdOptSince                   = "since"
+	CmdOptOutputFile              = "output-file"
+	CmdOptSize                    = "size"
+	CmdOptSLO                     = "slo"
+	CmdOptSkipImage               = "skip-image"
+	CmdOptSkipPreflight           = "skip-preflight"
+	CmdOptSnapshot                = "snapshot"
+	CmdOptSnapshotType            = "snapshot-type"
+	CmdOptSSHHosts                = "ssh-hosts"
+	CmdOptSSHLocalBinary          = "ssh-local-binary"
+	CmdOptStorageClass            = "storage-class"
+	CmdOptSummarizeBy             = "summarize-by"
+	CmdOptSummary                 = "summary"
+	CmdOptTarget                  = "target"
+	CmdOptTargetDirectory         = "target-dir"
+	CmdOptTargetKubeConfig        = "target-kube-config"
+	CmdOptTargetPVC               = "target-pvc"
+	CmdOptTargetVolume            = "target-volume"
+	CmdOptTimeout                 = "timeout"
+	CmdOptTLSCert                 = "tls-cert"
+	CmdOptTLSKey                  = "tls-key"
+	CmdOptToken                   = "token"
+	CmdOptTTL                     = "ttl"
+	CmdOptTuneIscsid              = "tune-iscsid"
+	CmdOptUpdatePackages          = "update-packages"
+	CmdOptVeleroNamespace         = "velero-namespace"
+	CmdOptVersion                 = "version"
+	CmdOptVolume                  = "volume"
+	CmdOptVolumeSnapshot          = "volume-snapshot"
+	CmdOptVolumeSnapshotClass     = "volume-snapshot-class"
+	CmdOptVolumes                 = "volumes"
+	CmdOptWait                    = "wait"
+	CmdOptWorkload                = "workload"
+	CmdOptNodeSelector            = "node-selector"
 
 	// SPDK options
 	CmdOptAllowPci        = "allow-pci"
 	CmdOptDriverOverride  = "driver-override"
 	CmdOptEnableSpdk      = "enable-spdk"
+	CmdOptHugePageNodes   = "huge-page-nodes"
 	CmdOptHugePageSize    = "huge-page-size"
 	CmdOptSpdkOptions     = "spdk-options"
 	CmdOptUserspaceDriver = "userspace-driver"
 
 	// Longhorn options
-	CmdOptLonghornDataDirectory = "data-dir"
-	CmdOptLonghornEngineImage   = "engine-image"
-	CmdOptLonghornNamespace     = "longhorn-namespace"
-	CmdOptLonghornVolumeName    = "volume-name"
+	CmdOptLonghornActiveVolumes     = "active-volumes"
+	CmdOptLonghornDataDirectory     = "data-dir"
+	CmdOptLonghornEngineImage       = "engine-image"
+	CmdOptLonghornNamespace         = "longhorn-namespace"
+	CmdOptLonghornShareEndpoint     = "share-endpoint"
+	CmdOptLonghornVersion           = "longhorn-version"
+	CmdOptLonghornVolumeAttachments = "volume-attachments"
+	CmdOptLonghornVolumeName        = "volume-name"
+	CmdOptLonghornVolumeNames       = "volume-names"
 )
 
 const CmdOptSeperator = ","
+
+const (
+	// Backends running the operations on the nodes
+	BackendDaemonSet = "daemonset"
+	BackendSSH       = "ssh"
+)
+
+// LocalResultHeader precedes the result printed by longhornctl-local when it is not written to a file.
+const LocalResultHeader = "Result: \n"
+
+const (
+	// Exit codes of the commands, by the category of their error. The node agents report the
+	// exit code of longhornctl-local, so the category of a failure on a node is not lost.
+	ExitCodeError               = 1
+	ExitCodeNodeUnreachable     = 3
+	ExitCodePodSchedulingFailed = 4
+	ExitCodePackageManagerError = 5
+	ExitCodeTimeout             = 6
+)
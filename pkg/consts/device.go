@@ -0,0 +1,6 @@
+package consts
+
+const (
+	AppNameMountChecker      = "longhorn-mount-checker"
+	AppNameNodeDeviceCleaner = "longhorn-node-device-cleaner"
+)
@@ -7,24 +7,80 @@ const (
 )
 
 const (
-	EnvCurrentNodeID  = "CURRENT_NODE_ID"
-	EnvKubeConfigPath = "KUBECONFIG"
-	EnvLogLevel       = "LOG_LEVEL"
-	EnvOutputFilePath = "OUTPUT_FILE_PATH"
+	EnvAgentPort             = "AGENT_PORT"
+	EnvApiToken              = "LONGHORNCTL_API_TOKEN"
+	EnvCryptoKeyValue        = "CRYPTO_KEY_VALUE"
+	EnvCurrentNodeID         = "CURRENT_NODE_ID"
+	EnvDryRun                = "DRY_RUN"
+	EnvCustomChecks          = "CUSTOM_CHECKS_FILE"
+	EnvDoNotTrack            = "DO_NOT_TRACK"
+	EnvHttpProxy             = "HTTP_PROXY"
+	EnvHttpsProxy            = "HTTPS_PROXY"
+	EnvKubeConfigPath        = "KUBECONFIG"
+	EnvKubernetesServiceHost = "KUBERNETES_SERVICE_HOST"
+	EnvKeyOnly               = "KEY_ONLY"
+	EnvKnownIssues           = "KNOWN_ISSUES"
+	EnvLogFormat             = "LOG_FORMAT"
+	EnvLogLevel              = "LOG_LEVEL"
+	EnvNamespace             = "NAMESPACE"
+	EnvNewCryptoKeyValue     = "NEW_CRYPTO_KEY_VALUE"
+	EnvNoColor               = "NO_COLOR"
+	EnvNoProxy               = "NO_PROXY"
+	EnvOutputFilePath        = "OUTPUT_FILE_PATH"
+	EnvPackageSource         = "PACKAGE_SOURCE"
+	EnvPodName               = "POD_NAME"
+	EnvPodNamespace          = "POD_NAMESPACE"
+	EnvPreflightProfile      = "PREFLIGHT_PROFILE"
+	EnvReadOnly              = "READ_ONLY"
+	EnvResetCheckpoint       = "RESET_CHECKPOINT"
+	EnvRepair                = "REPAIR"
+	EnvRegistryCheckImages   = "REGISTRY_CHECK_IMAGES"
+	EnvShareAllowedCIDRs     = "SHARE_ALLOWED_CIDRS"
+	EnvSharePassword         = "SHARE_PASSWORD"
+	EnvSharePort             = "SHARE_PORT"
+	EnvShareProtocol         = "SHARE_PROTOCOL"
+	EnvShareUsername         = "SHARE_USERNAME"
+	EnvSnapshotName          = "SNAPSHOT_NAME"
 
-	EnvLonghornDataDirectory = "LONGHORN_DATA_DIRECTORY"
-	EnvLonghornNamespace     = "LONGHORN_NAMESPACE"
-	EnvLonghornReplicaName   = "REPLICA_NAME"
-	EnvLonghornVolumeName    = "VOLUME_NAME"
+	EnvLonghornActiveVolumes     = "ACTIVE_VOLUMES"
+	EnvLonghornDataDirectory     = "LONGHORN_DATA_DIRECTORY"
+	EnvLonghornNamespace         = "LONGHORN_NAMESPACE"
+	EnvLonghornReplicaName       = "REPLICA_NAME"
+	EnvLonghornShareEndpoint     = "SHARE_ENDPOINT"
+	EnvLonghornVolumeAttachments = "VOLUME_ATTACHMENTS"
+	EnvLonghornVolumeName        = "VOLUME_NAME"
+	EnvLonghornVolumeNames       = "VOLUME_NAMES"
 )
 
 // SPDK related environment variables
 const (
 	EnvDriverOverride    = "DRIVER_OVERRIDE"
+	EnvCryptoBenchmark   = "CRYPTO_BENCHMARK"
 	EnvEnableSpdk        = "ENABLE_SPDK"
+	EnvHugePageNodes     = "HUGE_PAGE_NODES"
 	EnvHugePageSize      = "HUGEMEM"
+	EnvSpdkHugeNode      = "HUGENODE"
 	EnvPciAllowed        = "PCI_ALLOWED"
 	EnvUserspaceDriver   = "USERSPACE_DRIVER"
 	EnvUpdatePackageList = "UPDATE_PACKAGE_LIST"
+	EnvTuneIscsid        = "TUNE_ISCSID"
 	EnvSpdkOptions       = "SPDK_OPTIONS"
 )
+
+// AWS environment variables of the S3 output destination
+const (
+	EnvAwsAccessKeyID     = "AWS_ACCESS_KEY_ID"
+	EnvAwsDefaultRegion   = "AWS_DEFAULT_REGION"
+	EnvAwsEndpointURL     = "AWS_ENDPOINT_URL"
+	EnvAwsEndpointURLS3   = "AWS_ENDPOINT_URL_S3"
+	EnvAwsRegion          = "AWS_REGION"
+	EnvAwsSecretAccessKey = "AWS_SECRET_ACCESS_KEY"
+	EnvAwsSessionToken    = "AWS_SESSION_TOKEN"
+)
+
+// Keys of the credential secret of an S3 backup target, besides the AWS credentials
+const (
+	EnvAwsCert            = "AWS_CERT"
+	EnvAwsEndpoints       = "AWS_ENDPOINTS"
+	EnvVirtualHostedStyle = "VIRTUAL_HOSTED_STYLE"
+)
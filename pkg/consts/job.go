@@ -0,0 +1,17 @@
+package consts
+
+const (
+	AppNameJob      = "longhornctl-job"
+	AppNameAsyncRun = "longhornctl-async"
+)
+
+const (
+	// AsyncRunResultNameSuffix is the suffix of the ConfigMap the Job of an async run writes its
+	// result to, following the name of the run record.
+	AsyncRunResultNameSuffix = "-result"
+
+	AsyncRunStatusPending   = "Pending"
+	AsyncRunStatusRunning   = "Running"
+	AsyncRunStatusSucceeded = "Succeeded"
+	AsyncRunStatusFailed    = "Failed"
+)
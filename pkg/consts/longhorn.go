@@ -2,4 +2,27 @@ package consts
 
 const LonghornDiskConfigFile = "longhorn-disk.cfg"
 
+// LonghornDefaultDataDirectory is the data path of the default Longhorn disk of the nodes.
+const LonghornDefaultDataDirectory = "/var/lib/longhorn"
+
+const LonghornNamespace = "longhorn-system"
+
 const LonghornServiceAccountName = "longhorn-service-account"
+
+// Label selectors of the pods of the Longhorn components.
+const (
+	LonghornLabelSelectorCSI             = "app in (csi-attacher,csi-provisioner,csi-resizer,csi-snapshotter,longhorn-csi-plugin)"
+	LonghornLabelSelectorInstanceManager = "longhorn.io/component=instance-manager"
+	LonghornLabelSelectorManager         = "app=longhorn-manager"
+)
+
+// LonghornManifestURL is the URL format of the deployment manifest published with each Longhorn release.
+const LonghornManifestURL = "https://raw.githubusercontent.com/longhorn/longhorn/%s/deploy/longhorn.yaml"
+
+// Helm chart of Longhorn, installed by the setup wizard.
+const (
+	HelmBinary          = "helm"
+	HelmChartLonghorn   = "longhorn"
+	HelmReleaseLonghorn = "longhorn"
+	HelmRepoLonghorn    = "https://charts.longhorn.io"
+)
@@ -12,8 +12,34 @@ const (
 )
 
 var (
-	ImageEngine      = fmt.Sprintf("longhornio/longhorn-engine:%s", meta.Version)
-	ImageLonghornCli = fmt.Sprintf("longhornio/longhorn-cli:%s", meta.Version)
+	ImageEngine       = fmt.Sprintf("longhornio/longhorn-engine:%s", meta.Version)
+	ImageLonghornCli  = fmt.Sprintf("longhornio/longhorn-cli:%s", meta.Version)
+	ImageShareManager = fmt.Sprintf("longhornio/longhorn-share-manager:%s", meta.Version)
+)
+
+const (
+	ReleaseApiURL      = "https://api.github.com/repos/longhorn/cli/releases"
+	ReleaseDownloadURL = "https://github.com/longhorn/cli/releases/download"
+)
+
+const (
+	// LabelManagedBy marks the resources created in the cluster by longhornctl, so
+	// they can be found and removed by 'longhornctl cleanup all'.
+	LabelManagedBy      = "app.kubernetes.io/managed-by"
+	LabelValueManagedBy = CmdLonghornctlRemote
+
+	// LabelOperation and LabelRunID identify the command and the run of longhornctl that created
+	// the resource. The namespaced resources of a run are owned by the anchor ConfigMap of the run
+	// in their namespace, named RunAnchorNamePrefix followed by the run ID.
+	LabelOperation      = "longhorn.io/longhornctl-operation"
+	LabelRunID          = "longhorn.io/longhornctl-run-id"
+	RunAnchorNamePrefix = "longhornctl-run-"
+
+	// LabelState marks the resources holding the state or the records of operations, such as the
+	// state of a cluster migration and the records and results of the runs submitted with
+	// --no-wait. They are kept by 'longhornctl cleanup all' unless --include-state is set.
+	LabelState      = "longhorn.io/longhornctl-state"
+	LabelValueState = "true"
 )
 
 const (
@@ -22,6 +48,7 @@ const (
 	ContainerNameInit   = "init-longhornctl"
 	ContainerNameOutput = "output-longhornctl"
 	ContainerNamePause  = "pause"
+	ContainerNameShare  = "share"
 )
 
 const (
@@ -43,8 +70,19 @@ const (
 	VolumeMountEntrypointName      = "entrypoint"
 	VolumeMountEntrypointDirectory = "/scripts"
 
+	VolumeMountCustomChecksName      = "custom-checks"
+	VolumeMountCustomChecksDirectory = "/custom-checks"
+
 	VolumeMountVolumeName      = "volume"
 	VolumeMountVolumeDirectory = "/volume"
+
+	VolumeMountVolumeSourceDirectory = "/volume-source"
+
+	VolumeMountOverlayName      = "overlay"
+	VolumeMountOverlayDirectory = "/overlay"
+
+	VolumeMountReplicasName      = "replicas"
+	VolumeMountReplicasDirectory = "/replicas"
 )
 
 const (
@@ -52,6 +90,20 @@ const (
 	FileNameOutputJSON    = "output.json"
 )
 
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+const (
+	OutputFormatHTML     = "html"
+	OutputFormatJSON     = "json"
+	OutputFormatJUnit    = "junit"
+	OutputFormatMarkdown = "markdown"
+	OutputFormatWide     = "wide"
+	OutputFormatYAML     = "yaml"
+)
+
 const (
 	LogPrefixError = "ERROR: "
 	LogPrefixWarn  = "WARN: "
@@ -0,0 +1,25 @@
+package consts
+
+const (
+	// Formats of the generated monitoring definitions
+	MonitoringFormatGrafanaJSON     = "grafana-json"
+	MonitoringFormatPrometheusRules = "prometheus-rules"
+
+	// MonitoringName is the default name of the generated PrometheusRule and dashboard.
+	MonitoringName = "longhorn"
+
+	// MonitoringNamespaceLabel is the default label carrying the namespace of the scraped Longhorn
+	// managers in the metrics series.
+	MonitoringNamespaceLabel = "namespace"
+
+	// AppNameLonghornPrometheus is the name of the ServiceMonitor or PodMonitor scraping the
+	// Longhorn managers, and of the Role allowing Prometheus to discover them.
+	AppNameLonghornPrometheus = "longhorn-prometheus"
+
+	// MonitoringPrometheusServiceAccount is the default service account of Prometheus, as deployed
+	// by kube-prometheus.
+	MonitoringPrometheusServiceAccount = "monitoring/prometheus-k8s"
+
+	// LonghornManagerMetricsPort is the name of the port of the Longhorn manager serving the metrics.
+	LonghornManagerMetricsPort = "manager"
+)
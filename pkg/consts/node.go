@@ -0,0 +1,20 @@
+package consts
+
+const (
+	AppNameNodeCopier   = "longhorn-node-copier"
+	AppNameNodeExecutor = "longhorn-node-executor"
+)
+
+const (
+	// AnnotationNodeGate is the annotation of the nodes gated by "node gate", holding the state of
+	// the gate on the node.
+	AnnotationNodeGate = "longhorn.io/longhornctl-node-gate"
+	// AnnotationNodeGatePrevious is the annotation of the Longhorn setting changed by
+	// "node gate enable", holding its value before the gate for restoring it.
+	AnnotationNodeGatePrevious = "longhorn.io/longhornctl-node-gate-previous"
+
+	// States of the node gate on a node
+	NodeGateStatePending = "pending" // The preflight check has not run on the node yet.
+	NodeGateStateFailed  = "failed"  // The preflight check failed on the node.
+	NodeGateStatePassed  = "passed"  // The preflight check passed, the node is labeled for its default disk.
+)
@@ -0,0 +1,5 @@
+package consts
+
+const (
+	AppNameNodeFactsCollector = "longhorn-node-facts-collector"
+)
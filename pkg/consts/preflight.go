@@ -1,9 +1,30 @@
 package consts
 
 const (
+	AppNamePciBindingsChecker            = "longhorn-pci-bindings-checker"
 	AppNamePreflightChecker              = "longhorn-preflight-checker"
 	AppNamePreflightContainerOptimizedOS = "longhorn-gke-cos-node-agent"
 	AppNamePreflightInstaller            = "longhorn-preflight-installer"
+	AppNamePreflightRebooter             = "longhorn-preflight-rebooter"
+	AppNamePreflightServer               = "longhorn-preflight-server"
+	AppNameTuningChecker                 = "longhorn-tuning-checker"
+	AppNameTuningInstaller               = "longhorn-tuning-installer"
+)
+
+// Profiles of the managed platforms and Kubernetes distributions of the preflight check.
+const (
+	PreflightProfileAKS  = "aks"
+	PreflightProfileEKS  = "eks"
+	PreflightProfileGKE  = "gke"
+	PreflightProfileK3s  = "k3s"
+	PreflightProfileRKE2 = "rke2"
+)
+
+// Categories the nodes are grouped by in the summary of the preflight check.
+const (
+	SummarizeByNodePool = "nodepool"
+	SummarizeByOS       = "os"
+	SummarizeByZone     = "zone"
 )
 
 const (
@@ -17,3 +38,25 @@ const (
 	DependencyModuleDefault DependencyModuleType = iota
 	DependencyModuleSpdk
 )
+
+const (
+	// PreflightCheckPluginPrefix is the file name prefix of the executables
+	// on PATH that are run as additional preflight checks.
+	PreflightCheckPluginPrefix = "longhornctl-check-"
+
+	FileNameCustomChecks = "custom-checks.yaml"
+)
+
+// PreflightInstallRebootRequired is the warning of the preflight install on the nodes needing a
+// reboot before the second phase of the install.
+const PreflightInstallRebootRequired = "Need to reboot the system and execute longhornctl install preflight again"
+
+const (
+	// Strategies rebooting the nodes needing a reboot after the preflight install
+	RebootStrategyNone    = "none"
+	RebootStrategyRolling = "rolling"
+)
+
+const (
+	AnnotationPreflightLastRunTime = "longhorn.io/preflight-last-run-time"
+)
@@ -0,0 +1,11 @@
+package consts
+
+const (
+	AppNameImagePreloader = "longhorn-image-preloader"
+
+	// ContainerNamePullPrefix prefixes the name of the init container pulling each image.
+	ContainerNamePullPrefix = "pull-"
+
+	// LonghornImagesURL is the URL format of the image list published with each Longhorn release.
+	LonghornImagesURL = "https://raw.githubusercontent.com/longhorn/longhorn/%s/deploy/longhorn-images.txt"
+)
@@ -0,0 +1,12 @@
+package consts
+
+const (
+	// AnnotationRebuildThrottlePrevious is the annotation of the Longhorn settings changed by
+	// "rebuild throttle", holding their value before the throttle for restoring it.
+	AnnotationRebuildThrottlePrevious = "longhorn.io/longhornctl-rebuild-throttle-previous"
+
+	// SettingNameReplicaRebuildingBandwidthLimit is the Longhorn setting limiting the write
+	// bandwidth of the replica rebuilds in megabytes per second, 0 for unlimited. It is only
+	// available in the Longhorn versions supporting it.
+	SettingNameReplicaRebuildingBandwidthLimit = "replica-rebuilding-bandwidth-limit"
+)
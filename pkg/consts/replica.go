@@ -1,6 +1,20 @@
 package consts
 
 const (
-	AppNameReplicaExporter = "longhorn-replica-exporter"
-	AppNameReplicaGetter   = "longhorn-replica-getter"
+	AppNameReplicaArchiver      = "longhorn-replica-archiver"
+	AppNameReplicaExporter      = "longhorn-replica-exporter"
+	AppNameReplicaImporter      = "longhorn-replica-importer"
+	AppNameReplicaGetter        = "longhorn-replica-getter"
+	AppNameReplicaMetaInspector = "longhorn-replica-meta-inspector"
+)
+
+const (
+	ShareProtocolNFS = "nfs"
+	ShareProtocolSMB = "smb"
+
+	SharePortNFS = 2049
+	SharePortSMB = 445
+
+	// ShareUsernameDefault is the default user allowed to access the exported replicas over SMB.
+	ShareUsernameDefault = "longhorn"
 )
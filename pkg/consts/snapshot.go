@@ -0,0 +1,23 @@
+package consts
+
+const (
+	// Annotations of the pods of a workload running commands around its quiesced snapshots. The
+	// commands run with /bin/sh -c in the hook container, defaulting to the first container.
+	AnnotationSnapshotPreHook       = "longhorn.io/snapshot-pre-hook"
+	AnnotationSnapshotPostHook      = "longhorn.io/snapshot-post-hook"
+	AnnotationSnapshotHookContainer = "longhorn.io/snapshot-hook-container"
+
+	// SnapshotNamePrefix is the prefix of the names of the snapshots created by the CLI.
+	SnapshotNamePrefix = "longhornctl-"
+)
+
+const (
+	// AnnotationVolumeSnapshot is the annotation of the Longhorn snapshots and backups linked to a
+	// CSI VolumeSnapshot, as <namespace>/<name>.
+	AnnotationVolumeSnapshot = "longhorn.io/volume-snapshot"
+
+	// Types of the snapshot handles of the Longhorn CSI driver, as <type>://<volume>/<name>.
+	CSISnapshotTypeSnapshot     = "snap"
+	CSISnapshotTypeBackup       = "bak"
+	CSISnapshotTypeLegacyBackup = "bs"
+)
@@ -0,0 +1,18 @@
+package consts
+
+const (
+	// VeleroNamespace is the default namespace of Velero.
+	VeleroNamespace = "velero"
+	// VeleroDeployment is the name of the Velero server deployment.
+	VeleroDeployment = "velero"
+	// VeleroFeatureCSI is the feature flag of the Velero server enabling the CSI snapshots.
+	VeleroFeatureCSI = "EnableCSI"
+	// VeleroLabelCSIVolumeSnapshotClass is the label selecting the VolumeSnapshotClass Velero uses
+	// for the volumes of a CSI driver.
+	VeleroLabelCSIVolumeSnapshotClass = "velero.io/csi-volumesnapshot-class"
+
+	// Names of the resources generated for Velero.
+	VeleroBackupStorageLocation = "longhorn"
+	VeleroCredentialSecret      = "longhorn-velero-credentials"
+	VeleroVolumeSnapshotClass   = "longhorn-velero"
+)
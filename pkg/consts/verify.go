@@ -0,0 +1,12 @@
+package consts
+
+const (
+	AppNameInstallationVerifier = "longhorn-installation-verifier"
+
+	// LonghornStorageClass is the name of the default StorageClass deployed with Longhorn.
+	LonghornStorageClass = "longhorn"
+
+	// VolumeMountVerifyDataDirectory is where the installation verifier pods mount the test volume.
+	VolumeMountVerifyDataDirectory = "/data"
+	VolumeMountVerifyDataName      = "data"
+)
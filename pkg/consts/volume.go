@@ -1,5 +1,8 @@
 package consts
 
 const (
-	AppNameVolumeTrimmer = "longhorn-volume-trimmer"
+	AppNameClusterMigrator = "longhorn-cluster-migrator"
+	AppNameRwxChecker      = "longhorn-rwx-checker"
+	AppNameVolumeRekeyer   = "longhorn-volume-rekeyer"
+	AppNameVolumeTrimmer   = "longhorn-volume-trimmer"
 )
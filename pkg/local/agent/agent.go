@@ -0,0 +1,337 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+)
+
+const (
+	agentShutdownTimeout = 5 * time.Second
+	maxProgressLineSize  = 1024 * 1024
+)
+
+// Agent runs a local command on the node, and serves its status, progress and result over HTTP
+// on the localhost of the pod. The CLI reaches it through a port-forward, instead of inferring
+// the state of the node from the pod logs.
+type Agent struct {
+	AgentCmdOptions
+
+	logger *logrus.Entry
+
+	mutex    sync.Mutex
+	status   types.AgentStatus
+	progress []types.AgentProgress // Latest progress events, up to AgentMaxProgressEvents.
+	result   []byte
+	updateCh chan struct{} // Closed and replaced on each update, waking up the progress followers.
+}
+
+// AgentCmdOptions holds the options for the command.
+type AgentCmdOptions struct {
+	LogLevel string
+
+	NodeName       string
+	Port           int
+	OutputFilePath string   // File the command writes its result to.
+	Command        []string // Command to run, usually longhornctl-local with its subcommands.
+}
+
+// Validate validates the command options.
+func (local *Agent) Validate() error {
+	if len(local.Command) == 0 {
+		return errors.New("missing command to run")
+	}
+
+	if local.Port <= 0 || local.Port > 65535 {
+		return errors.Errorf("invalid port %d", local.Port)
+	}
+
+	return nil
+}
+
+// Init initializes the Agent.
+func (local *Agent) Init() error {
+	local.logger = logrus.WithField("component", "agent")
+	local.updateCh = make(chan struct{})
+	local.status = types.AgentStatus{
+		Node:    local.NodeName,
+		Phase:   types.AgentPhaseRunning,
+		Command: local.Command,
+	}
+	return nil
+}
+
+// Run serves the agent and runs the command, until the context is done. It keeps serving the
+// result after the command completes, so the CLI can fetch it.
+func (local *Agent) Run(ctx context.Context) error {
+	listener, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(local.Port)))
+	if err != nil {
+		return errors.Wrapf(err, "failed to listen on port %d", local.Port)
+	}
+
+	server := &http.Server{
+		Handler:           local.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	serverErrCh := make(chan error, 1)
+	go func() {
+		serverErrCh <- server.Serve(listener)
+	}()
+	local.logger.Infof("Serving agent on %v", listener.Addr())
+
+	go local.RunCommand(ctx)
+
+	select {
+	case <-ctx.Done():
+	case err := <-serverErrCh:
+		return errors.Wrap(err, "failed to serve agent")
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), agentShutdownTimeout)
+	defer cancel()
+	return server.Shutdown(shutdownCtx)
+}
+
+// RunCommand runs the command with JSON logs, recording each log line as a progress event, then
+// records the result from the output file.
+func (local *Agent) RunCommand(ctx context.Context) {
+	cmd := exec.CommandContext(ctx, local.Command[0], local.Command[1:]...)
+	cmd.Env = append(os.Environ(), fmt.Sprintf("%s=%s", consts.EnvLogFormat, consts.LogFormatJSON))
+	cmd.Stdout = os.Stdout
+
+	local.mutex.Lock()
+	local.status.StartTime = time.Now().UTC()
+	local.mutex.Unlock()
+
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		local.complete(-1, nil, errors.Wrap(err, "failed to get stderr of the command"))
+		return
+	}
+
+	local.logger.Infof("Running command %v", local.Command)
+	if err := cmd.Start(); err != nil {
+		local.complete(-1, nil, errors.Wrap(err, "failed to start the command"))
+		return
+	}
+
+	scanner := bufio.NewScanner(stderr)
+	scanner.Buffer(make([]byte, 64*1024), maxProgressLineSize)
+	for scanner.Scan() {
+		line := scanner.Text()
+		// Keep the logs of the command in the logs of the pod.
+		fmt.Fprintln(os.Stderr, line)
+		local.addProgress(parseProgress(line))
+	}
+	if err := scanner.Err(); err != nil {
+		local.logger.WithError(err).Warn("Failed to read the logs of the command")
+	}
+
+	err = cmd.Wait()
+	exitCode := cmd.ProcessState.ExitCode()
+	if err != nil {
+		local.complete(exitCode, nil, errors.Wrap(err, "command failed"))
+		return
+	}
+
+	if local.OutputFilePath == "" {
+		local.complete(exitCode, nil, nil)
+		return
+	}
+
+	result, err := os.ReadFile(local.OutputFilePath)
+	if err != nil {
+		local.complete(exitCode, nil, errors.Wrap(err, "failed to read the result of the command"))
+		return
+	}
+	local.complete(exitCode, result, nil)
+}
+
+// parseProgress parses a JSON log line of logrus into a progress event. Other lines only have the
+// message.
+func parseProgress(line string) types.AgentProgress {
+	progress := types.AgentProgress{
+		Time:    time.Now().UTC(),
+		Message: line,
+	}
+
+	fields := map[string]any{}
+	if err := json.Unmarshal([]byte(line), &fields); err != nil {
+		return progress
+	}
+
+	if message, ok := fields[logrus.FieldKeyMsg].(string); ok {
+		progress.Message = message
+		delete(fields, logrus.FieldKeyMsg)
+	}
+	if level, ok := fields[logrus.FieldKeyLevel].(string); ok {
+		progress.Level = level
+		delete(fields, logrus.FieldKeyLevel)
+	}
+	if timestamp, ok := fields[logrus.FieldKeyTime].(string); ok {
+		if t, err := time.Parse(time.RFC3339, timestamp); err == nil {
+			progress.Time = t.UTC()
+		}
+		delete(fields, logrus.FieldKeyTime)
+	}
+	if len(fields) > 0 {
+		progress.Fields = fields
+	}
+	return progress
+}
+
+func (local *Agent) addProgress(progress types.AgentProgress) {
+	local.mutex.Lock()
+	defer local.mutex.Unlock()
+
+	local.status.LastSequence++
+	progress.Sequence = local.status.LastSequence
+
+	local.progress = append(local.progress, progress)
+	if len(local.progress) > consts.AgentMaxProgressEvents {
+		local.progress = local.progress[len(local.progress)-consts.AgentMaxProgressEvents:]
+	}
+	local.notify()
+}
+
+func (local *Agent) complete(exitCode int, result []byte, err error) {
+	local.mutex.Lock()
+	defer local.mutex.Unlock()
+
+	now := time.Now().UTC()
+	local.status.CompletionTime = &now
+	local.status.ExitCode = exitCode
+	local.status.Phase = types.AgentPhaseSucceeded
+	if err != nil {
+		local.status.Phase = types.AgentPhaseFailed
+		local.status.Error = err.Error()
+		local.logger.WithError(err).Error("Failed to run command")
+	} else {
+		local.logger.Info("Completed command")
+	}
+	local.result = result
+	local.notify()
+}
+
+// notify wakes up the progress followers. It must be called with the mutex held.
+func (local *Agent) notify() {
+	close(local.updateCh)
+	local.updateCh = make(chan struct{})
+}
+
+// Handler returns the HTTP handler serving the status, progress and result of the command.
+func (local *Agent) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(consts.AgentPathStatus, local.handleStatus)
+	mux.HandleFunc(consts.AgentPathProgress, local.handleProgress)
+	mux.HandleFunc(consts.AgentPathResult, local.handleResult)
+	return mux
+}
+
+func (local *Agent) handleStatus(w http.ResponseWriter, r *http.Request) {
+	local.mutex.Lock()
+	status := local.status
+	local.mutex.Unlock()
+
+	writeJSON(w, http.StatusOK, status)
+}
+
+// handleProgress writes the progress events after the "after" sequence as JSON lines. With
+// "follow", it keeps writing the new events until the command completes.
+func (local *Agent) handleProgress(w http.ResponseWriter, r *http.Request) {
+	var after int64
+	if value := r.URL.Query().Get("after"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid after %q", value), http.StatusBadRequest)
+			return
+		}
+		after = parsed
+	}
+	follow := r.URL.Query().Get("follow") == "true"
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	encoder := json.NewEncoder(w)
+
+	for {
+		local.mutex.Lock()
+		events := local.progressAfter(after)
+		running := local.status.Phase == types.AgentPhaseRunning
+		updateCh := local.updateCh
+		local.mutex.Unlock()
+
+		for _, event := range events {
+			if err := encoder.Encode(event); err != nil {
+				return
+			}
+			after = event.Sequence
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+
+		if !follow || !running {
+			return
+		}
+
+		select {
+		case <-updateCh:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// progressAfter returns the kept progress events after the sequence. It must be called with the
+// mutex held.
+func (local *Agent) progressAfter(after int64) []types.AgentProgress {
+	for i, event := range local.progress {
+		if event.Sequence > after {
+			return append([]types.AgentProgress{}, local.progress[i:]...)
+		}
+	}
+	return nil
+}
+
+func (local *Agent) handleResult(w http.ResponseWriter, r *http.Request) {
+	local.mutex.Lock()
+	status := local.status
+	result := local.result
+	local.mutex.Unlock()
+
+	switch {
+	case status.Phase == types.AgentPhaseRunning:
+		http.Error(w, "command is still running", http.StatusConflict)
+	case result == nil:
+		http.Error(w, "no result", http.StatusNotFound)
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(result)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		logrus.WithError(err).Debug("Failed to write response")
+	}
+}
@@ -0,0 +1,154 @@
+package agent
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func TestParseProgress(t *testing.T) {
+	for name, tc := range map[string]struct {
+		line     string
+		expected types.AgentProgress
+	}{
+		"json log": {
+			line: `{"level":"info","msg":"Checking packages","time":"2024-07-16T17:17:38+08:00","component":"preflight"}`,
+			expected: types.AgentProgress{
+				Time:    time.Date(2024, 7, 16, 9, 17, 38, 0, time.UTC),
+				Level:   "info",
+				Message: "Checking packages",
+				Fields:  map[string]any{"component": "preflight"},
+			},
+		},
+		"json log without fields": {
+			line: `{"level":"error","msg":"Failed to check packages","time":"2024-07-16T17:17:38Z"}`,
+			expected: types.AgentProgress{
+				Time:    time.Date(2024, 7, 16, 17, 17, 38, 0, time.UTC),
+				Level:   "error",
+				Message: "Failed to check packages",
+			},
+		},
+		"plain line": {
+			line:     "panic: runtime error",
+			expected: types.AgentProgress{Message: "panic: runtime error"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			progress := parseProgress(tc.line)
+			if tc.expected.Time.IsZero() {
+				progress.Time = time.Time{}
+			}
+			if !reflect.DeepEqual(progress, tc.expected) {
+				t.Errorf("expected %+v, got %+v", tc.expected, progress)
+			}
+		})
+	}
+}
+
+func TestAgent(t *testing.T) {
+	outputFilePath := filepath.Join(t.TempDir(), "output.json")
+
+	for name, tc := range map[string]struct {
+		script         string
+		expectedPhase  types.AgentPhase
+		expectedResult string
+		expectedEvents []string
+	}{
+		"succeeded": {
+			script:         `echo '{"level":"info","msg":"Checking"}' >&2; echo '{"result":true}' > "$1"`,
+			expectedPhase:  types.AgentPhaseSucceeded,
+			expectedResult: `{"result":true}` + "\n",
+			expectedEvents: []string{"Checking"},
+		},
+		"failed": {
+			script:         `echo '{"level":"info","msg":"Checking"}' >&2; echo '{"level":"fatal","msg":"Failed"}' >&2; exit 1`,
+			expectedPhase:  types.AgentPhaseFailed,
+			expectedEvents: []string{"Checking", "Failed"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_ = os.Remove(outputFilePath)
+
+			agent := &Agent{
+				AgentCmdOptions: AgentCmdOptions{
+					Port:           1,
+					OutputFilePath: outputFilePath,
+					Command:        []string{"sh", "-c", tc.script, "sh", outputFilePath},
+				},
+			}
+			if err := agent.Validate(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := agent.Init(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			server := httptest.NewServer(agent.Handler())
+			defer server.Close()
+
+			go agent.RunCommand(t.Context())
+
+			resp, err := http.Get(server.URL + "/v1/progress?follow=true")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var events []string
+			scanner := bufio.NewScanner(resp.Body)
+			for scanner.Scan() {
+				var progress types.AgentProgress
+				if err := json.Unmarshal(scanner.Bytes(), &progress); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				events = append(events, progress.Message)
+			}
+			_ = resp.Body.Close()
+			if !reflect.DeepEqual(events, tc.expectedEvents) {
+				t.Errorf("expected progress %v, got %v", tc.expectedEvents, events)
+			}
+
+			resp, err = http.Get(server.URL + "/v1/status")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var status types.AgentStatus
+			if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			_ = resp.Body.Close()
+			if status.Phase != tc.expectedPhase {
+				t.Errorf("expected phase %v, got %v (%v)", tc.expectedPhase, status.Phase, status.Error)
+			}
+			if status.LastSequence != int64(len(tc.expectedEvents)) {
+				t.Errorf("expected last sequence %v, got %v", len(tc.expectedEvents), status.LastSequence)
+			}
+
+			resp, err = http.Get(server.URL + "/v1/result")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			result, err := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.expectedResult == "" {
+				if resp.StatusCode != http.StatusNotFound {
+					t.Errorf("expected status %v, got %v", http.StatusNotFound, resp.StatusCode)
+				}
+				return
+			}
+			if string(result) != tc.expectedResult {
+				t.Errorf("expected result %q, got %q", tc.expectedResult, result)
+			}
+		})
+	}
+}
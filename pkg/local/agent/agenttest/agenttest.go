@@ -0,0 +1,114 @@
+// Package agenttest runs node agents in-process, so the remote operations collecting the results
+// of a DaemonSet can be exercised in the tests without a cluster or real nodes.
+package agenttest
+
+import (
+	"context"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/longhorn/cli/pkg/local/agent"
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// OutputFileArg is replaced with the output file of the node agent in the command of a node.
+const OutputFileArg = "{{output}}"
+
+// Node is a fake node running a node agent in-process, in place of the agent container of a
+// DaemonSet pod.
+type Node struct {
+	Name string
+	Pod  *corev1.Pod
+
+	server *httptest.Server
+	cancel context.CancelFunc
+}
+
+// Cluster is a set of fake nodes, keyed by their node name.
+type Cluster struct {
+	Nodes map[string]*Node
+}
+
+// NewCluster returns a cluster without nodes.
+func NewCluster() *Cluster {
+	return &Cluster{Nodes: map[string]*Node{}}
+}
+
+// AddNode starts a node agent running the command for the node. The OutputFileArg arguments of
+// the command are replaced with the output file the command writes its result to. The agent is
+// stopped with the test.
+func (cluster *Cluster) AddNode(t testing.TB, name string, command ...string) *Node {
+	t.Helper()
+
+	outputFilePath := filepath.Join(t.TempDir(), "output.json")
+	args := make([]string, 0, len(command))
+	for _, arg := range command {
+		args = append(args, strings.ReplaceAll(arg, OutputFileArg, outputFilePath))
+	}
+
+	nodeAgent := &agent.Agent{
+		AgentCmdOptions: agent.AgentCmdOptions{
+			NodeName:       name,
+			Port:           1, // Not listened on, the agent is served by the test server.
+			OutputFilePath: outputFilePath,
+			Command:        args,
+		},
+	}
+	if err := nodeAgent.Validate(); err != nil {
+		t.Fatalf("invalid node agent of node %v: %v", name, err)
+	}
+	if err := nodeAgent.Init(); err != nil {
+		t.Fatalf("failed to initialize the node agent of node %v: %v", name, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	node := &Node{
+		Name: name,
+		Pod: &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "agent-" + name,
+				Namespace: metav1.NamespaceDefault,
+			},
+			Spec: corev1.PodSpec{NodeName: name},
+		},
+		server: httptest.NewServer(nodeAgent.Handler()),
+		cancel: cancel,
+	}
+	t.Cleanup(node.Stop)
+
+	go nodeAgent.RunCommand(ctx)
+
+	cluster.Nodes[name] = node
+	return node
+}
+
+// Stop stops the node agent, making the node unreachable.
+func (node *Node) Stop() {
+	node.cancel()
+	node.server.Close()
+}
+
+// Pods returns the agent pods of the nodes.
+func (cluster *Cluster) Pods() []*corev1.Pod {
+	pods := make([]*corev1.Pod, 0, len(cluster.Nodes))
+	for _, node := range cluster.Nodes {
+		pods = append(pods, node.Pod)
+	}
+	return pods
+}
+
+// Connect connects to the node agent of the pod, in place of the port-forward to the pod.
+func (cluster *Cluster) Connect(pod *corev1.Pod) (*kubeutils.AgentClient, error) {
+	node, ok := cluster.Nodes[pod.Spec.NodeName]
+	if !ok {
+		return nil, errors.Errorf("node %v is not in the cluster", pod.Spec.NodeName)
+	}
+	return kubeutils.NewAgentClientForAddress(node.server.Listener.Addr().String()), nil
+}
@@ -0,0 +1,237 @@
+package baseline
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	commonns "github.com/longhorn/go-common-libs/ns"
+	commontypes "github.com/longhorn/go-common-libs/types"
+
+	"github.com/longhorn/cli/pkg/consts"
+	pkgmgr "github.com/longhorn/cli/pkg/local/preflight/packagemanager"
+	remote "github.com/longhorn/cli/pkg/remote/baseline"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+)
+
+// States of the prerequisites recorded in the baseline.
+const (
+	stateInstalled    = "installed"
+	stateNotInstalled = "not installed"
+	stateLoaded       = "loaded"
+	stateNotLoaded    = "not loaded"
+	stateAbsent       = "absent"
+)
+
+// multipathConfigPath is the configuration of multipathd, which must blacklist the Longhorn devices.
+const multipathConfigPath = "/etc/multipath.conf"
+
+// packages are the packages Longhorn depends on, by package manager, as installed by the preflight
+// installer.
+var packages = map[pkgmgr.PackageManagerType][]string{
+	pkgmgr.PackageManagerApt:                 {"nfs-common", "open-iscsi", "cryptsetup", "dmsetup"},
+	pkgmgr.PackageManagerYum:                 {"nfs-utils", "iscsi-initiator-utils", "cryptsetup", "device-mapper"},
+	pkgmgr.PackageManagerZypper:              {"nfs-client", "open-iscsi", "cryptsetup", "device-mapper"},
+	pkgmgr.PackageManagerTransactionalUpdate: {"nfs-client", "open-iscsi", "cryptsetup", "device-mapper"},
+	pkgmgr.PackageManagerPacman:              {"nfs-utils", "open-iscsi", "cryptsetup", "device-mapper"},
+}
+
+// modules are the kernel modules of the v1 and v2 data engines.
+var modules = []string{"dm_crypt", "iscsi_tcp", "nfs", "nvme_tcp", "uio_pci_generic", "vfio_pci"}
+
+// services are the systemd units the preflight checks look at.
+var services = []string{"iscsid.service", "iscsid.socket", "multipathd.service", "multipathd.socket"}
+
+// sysctls are the sysctl parameters of the tuning profile, and the ones the nodes commonly differ
+// on.
+var sysctls = []string{"fs.aio-max-nr", "fs.file-max", "fs.inotify.max_user_instances", "vm.dirty_background_ratio", "vm.dirty_ratio"}
+
+// Collector provide functions for collecting the prerequisite state of the node recorded in the
+// baseline.
+type Collector struct {
+	remote.CollectorCmdOptions
+
+	logger *logrus.Entry
+
+	OutputFilePath string
+
+	// HostRootDirectory is the directory of the host root filesystem.
+	// It is "/" when running directly on the host instead of in a DaemonSet pod.
+	HostRootDirectory string
+
+	prerequisites *types.NodePrerequisites
+}
+
+// Init initializes the Collector.
+func (local *Collector) Init() error {
+	if len(local.OutputFilePath) != 0 {
+		local.logger = logrus.WithField("output", local.OutputFilePath)
+	} else {
+		local.logger = logrus.WithField("output", "stdout")
+	}
+
+	if local.HostRootDirectory == "" {
+		local.HostRootDirectory = consts.VolumeMountHostDirectory
+	}
+
+	return nil
+}
+
+// Run collects the prerequisite state of the node. The prerequisites that cannot be read are
+// listed in the errors, so the others are still recorded.
+func (local *Collector) Run() error {
+	local.logger.Infof("Collecting prerequisites from %v", local.HostRootDirectory)
+
+	prerequisites := &types.NodePrerequisites{
+		Modules: collectModules(filepath.Join(local.HostRootDirectory, "sys")),
+		Sysctls: map[string]string{},
+	}
+	addError := func(format string, args ...any) {
+		prerequisites.Errors = append(prerequisites.Errors, fmt.Sprintf(format, args...))
+	}
+
+	if data, err := os.ReadFile(filepath.Join(local.HostRootDirectory, "proc/sys/kernel/osrelease")); err != nil {
+		addError("Failed to read the kernel release: %v", err)
+	} else {
+		prerequisites.Kernel = strings.TrimSpace(string(data))
+	}
+
+	for _, key := range sysctls {
+		data, err := os.ReadFile(filepath.Join(local.HostRootDirectory, "proc/sys", strings.ReplaceAll(key, ".", "/")))
+		if err != nil {
+			addError("Failed to read sysctl %v: %v", key, err)
+			continue
+		}
+		prerequisites.Sysctls[key] = strings.Join(strings.Fields(string(data)), " ")
+	}
+
+	multipathConfig, err := getFileChecksum(filepath.Join(local.HostRootDirectory, multipathConfigPath))
+	if err != nil {
+		addError("Failed to read %v: %v", multipathConfigPath, err)
+	}
+	prerequisites.MultipathConfig = multipathConfig
+
+	osRelease, err := utils.GetOSRelease(local.HostRootDirectory)
+	if err != nil {
+		addError("Failed to get OS release: %v", err)
+		local.prerequisites = prerequisites
+		return nil
+	}
+	prerequisites.OperatingSystem = osRelease
+
+	executor, err := commonns.NewNamespaceExecutor(commontypes.ProcessNone, filepath.Join(local.HostRootDirectory, "proc"), []commontypes.Namespace{commontypes.NamespaceMnt})
+	if err != nil {
+		return errors.Wrap(err, "failed to create executor in the host mount namespace")
+	}
+
+	prerequisites.Services = collectServices(executor)
+
+	// The container-optimized OS has no package manager, the prerequisites are built in.
+	if osRelease != fmt.Sprint(consts.OperatingSystemContainerOptimizedOS) {
+		packageManagerType, err := utils.GetPackageManagerType(osRelease)
+		if err != nil {
+			addError("Failed to get package manager: %v", err)
+		} else if packageManager, err := pkgmgr.New(packageManagerType, executor); err != nil {
+			addError("Failed to get package manager: %v", err)
+		} else {
+			prerequisites.Packages = collectPackages(packageManager, packages[packageManagerType])
+		}
+	}
+
+	local.prerequisites = prerequisites
+	return nil
+}
+
+// Output outputs the prerequisite state of the node to stdout or a file.
+func (local *Collector) Output() error {
+	local.logger.Tracef("Outputting prerequisites")
+
+	jsonBytes, err := json.Marshal(local.prerequisites)
+	if err != nil {
+		return errors.Wrap(err, "failed to convert prerequisites to JSON")
+	}
+
+	return utils.HandleResult(jsonBytes, local.OutputFilePath, local.logger)
+}
+
+// collectModules returns whether the kernel modules are loaded or built in, from /sys/module.
+func collectModules(sysDirectory string) map[string]string {
+	states := map[string]string{}
+	for _, module := range modules {
+		states[module] = stateNotLoaded
+		if _, err := os.Stat(filepath.Join(sysDirectory, "module", module)); err == nil {
+			states[module] = stateLoaded
+		}
+	}
+	return states
+}
+
+// collectServices returns the active and unit file states of the systemd units, as active
+// (enabled). The units not installed are inactive (not-found).
+func collectServices(executor *commonns.Executor) map[string]string {
+	states := map[string]string{}
+	for _, service := range services {
+		output, err := executor.Execute([]string{}, "systemctl", []string{"show", "--property=ActiveState,UnitFileState", service}, commontypes.ExecuteDefaultTimeout)
+		if err != nil {
+			states[service] = "unknown"
+			continue
+		}
+		states[service] = parseServiceState(output)
+	}
+	return states
+}
+
+// parseServiceState parses the ActiveState and UnitFileState properties of systemctl show.
+func parseServiceState(output string) string {
+	properties := map[string]string{}
+	for _, line := range strings.Split(output, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if ok {
+			properties[key] = value
+		}
+	}
+
+	state := properties["ActiveState"]
+	if state == "" {
+		state = "unknown"
+	}
+	unitFileState := properties["UnitFileState"]
+	if unitFileState == "" {
+		unitFileState = "not-found"
+	}
+	return fmt.Sprintf("%s (%s)", state, unitFileState)
+}
+
+// collectPackages returns whether the packages are installed.
+func collectPackages(packageManager pkgmgr.PackageManager, names []string) map[string]string {
+	states := map[string]string{}
+	for _, name := range names {
+		states[name] = stateInstalled
+		if _, err := packageManager.CheckPackageInstalled(name); err != nil {
+			states[name] = stateNotInstalled
+		}
+	}
+	return states
+}
+
+// getFileChecksum returns the SHA-256 checksum of the file, or absent when it does not exist.
+func getFileChecksum(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return stateAbsent, nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	checksum := sha256.Sum256(data)
+	return hex.EncodeToString(checksum[:]), nil
+}
@@ -0,0 +1,77 @@
+package baseline
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseServiceState(t *testing.T) {
+	tests := map[string]struct {
+		output   string
+		expected string
+	}{
+		"active": {
+			output:   "ActiveState=active\nUnitFileState=enabled\n",
+			expected: "active (enabled)",
+		},
+		"not found": {
+			output:   "ActiveState=inactive\nUnitFileState=\n",
+			expected: "inactive (not-found)",
+		},
+		"empty": {
+			expected: "unknown (not-found)",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if state := parseServiceState(test.output); state != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, state)
+			}
+		})
+	}
+}
+
+func TestCollectModules(t *testing.T) {
+	sysDirectory := t.TempDir()
+	for _, module := range []string{"dm_crypt", "iscsi_tcp"} {
+		if err := os.MkdirAll(filepath.Join(sysDirectory, "module", module), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	states := collectModules(sysDirectory)
+	if len(states) != len(modules) {
+		t.Fatalf("expected %d modules, got %v", len(modules), states)
+	}
+	for module, expected := range map[string]string{"dm_crypt": stateLoaded, "iscsi_tcp": stateLoaded, "nvme_tcp": stateNotLoaded} {
+		if states[module] != expected {
+			t.Errorf("expected module %v %v, got %v", module, expected, states[module])
+		}
+	}
+}
+
+func TestGetFileChecksum(t *testing.T) {
+	directory := t.TempDir()
+	path := filepath.Join(directory, "multipath.conf")
+	if err := os.WriteFile(path, []byte("blacklist {\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	checksum, err := getFileChecksum(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(checksum) != 64 {
+		t.Errorf("expected a SHA-256 checksum, got %v", checksum)
+	}
+
+	checksum, err = getFileChecksum(filepath.Join(directory, "missing.conf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checksum != stateAbsent {
+		t.Errorf("expected %v, got %v", stateAbsent, checksum)
+	}
+}
@@ -0,0 +1,416 @@
+package device
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+
+	remote "github.com/longhorn/cli/pkg/remote/device"
+)
+
+// hostRootDirectory is the root directory of the host, seen through the init process of the
+// host PID namespace.
+const hostRootDirectory = "/proc/1/root"
+
+// longhornIscsiTargetPrefix is the prefix of the iSCSI targets of the Longhorn engines, followed
+// by the volume name.
+const longhornIscsiTargetPrefix = "iqn.2019-10.io.longhorn:"
+
+// longhornDeviceDirectory is the directory of the block devices of the Longhorn volumes.
+const longhornDeviceDirectory = "/dev/longhorn"
+
+// Kinds of the devices cleaned up, in the order of the cleanup. The dm-crypt mappings are removed
+// before the iSCSI sessions holding their backing disks.
+const (
+	deviceKindCryptMapping = "dm-crypt mapping"
+	deviceKindIscsiSession = "iSCSI session"
+	deviceKindBlockDevice  = "block device"
+)
+
+// Cleaner provide functions for cleaning up the leftover iSCSI sessions, dm-crypt mappings and
+// Longhorn block devices on the node.
+type Cleaner struct {
+	remote.CleanerCmdOptions
+
+	logger *logrus.Entry
+
+	OutputFilePath string
+	VolumeNames    string // Comma-separated names of all the Longhorn volumes.
+	ActiveVolumes  string // Comma-separated names of the Longhorn volumes attached to the node.
+
+	volumeNames   map[string]bool
+	activeVolumes map[string]bool
+
+	collection types.NodeCollection
+}
+
+// hostDevices are the devices of the host the leftovers are looked for in. The disks are the
+// kernel names of the block devices, such as sdb.
+type hostDevices struct {
+	sessions     []iscsiSession
+	mappings     []cryptMapping
+	blockDevices []blockDevice
+	diskHolders  map[string][]string // Holders of each disk, such as dm-0.
+	mountSources map[string]bool     // Sources of the mounts of the host, such as /dev/sdb.
+}
+
+// iscsiSession is an iSCSI session of the host and the disks of its LUNs.
+type iscsiSession struct {
+	ID     string
+	Target string
+	Disks  []string
+}
+
+// cryptMapping is a dm-crypt mapping of the host, backed by its disks.
+type cryptMapping struct {
+	Name    string
+	Device  string // Kernel name, such as dm-0.
+	Disks   []string
+	Holders []string
+}
+
+// blockDevice is a block device in the Longhorn device directory.
+type blockDevice struct {
+	Name string
+	Disk string // Kernel name of the disk of the device number, empty when no disk has it.
+}
+
+// staleDevice is a device of a volume that is not attached to the node.
+type staleDevice struct {
+	Kind   string
+	Name   string
+	Volume string
+	InUse  string // Reason the device cannot be cleaned up, empty when it can.
+
+	session *iscsiSession
+}
+
+// Validate validates the command options.
+func (local *Cleaner) Validate() error {
+	return nil
+}
+
+// Init initializes the Cleaner.
+func (local *Cleaner) Init() error {
+	local.collection.Log = &types.LogCollection{}
+	local.logger = logrus.WithField("component", "node-devices")
+
+	local.volumeNames = parseVolumeNames(local.VolumeNames)
+	local.activeVolumes = parseVolumeNames(local.ActiveVolumes)
+
+	return nil
+}
+
+// Run finds the leftover devices of the volumes that are not attached to the node, and removes the
+// ones that are not in use unless DryRun is set.
+func (local *Cleaner) Run() error {
+	log := local.collection.Log
+
+	devices, err := readHostDevices(hostRootDirectory)
+	if err != nil {
+		return err
+	}
+
+	staleDevices := findStaleDevices(devices, local.volumeNames, local.activeVolumes)
+	if len(staleDevices) == 0 {
+		log.Info = append(log.Info, "No leftover iSCSI session, dm-crypt mapping or Longhorn block device found")
+		return nil
+	}
+
+	for _, device := range staleDevices {
+		description := fmt.Sprintf("Leftover %v %v of volume %v", device.Kind, device.Name, device.Volume)
+		switch {
+		case device.InUse != "":
+			log.Error = append(log.Error, fmt.Sprintf("%v is %v, it is kept", description, device.InUse))
+		case local.DryRun:
+			log.Warn = append(log.Warn, fmt.Sprintf("%v would be removed", description))
+		default:
+			if err := local.cleanup(&device); err != nil {
+				log.Error = append(log.Error, fmt.Sprintf("Failed to remove %v %v of volume %v: %v", device.Kind, device.Name, device.Volume, err))
+				continue
+			}
+			log.Info = append(log.Info, fmt.Sprintf("%v is removed", description))
+		}
+	}
+
+	return nil
+}
+
+// Output converts the collection to JSON and output to stdout or the output file.
+func (local *Cleaner) Output() error {
+	local.logger.Trace("Outputting node device cleaner results")
+
+	jsonBytes, err := json.Marshal(local.collection)
+	if err != nil {
+		return errors.Wrap(err, "failed to convert collection to JSON")
+	}
+
+	return utils.HandleResult(jsonBytes, local.OutputFilePath, local.logger)
+}
+
+// cleanup removes the device in the host namespaces.
+func (local *Cleaner) cleanup(device *staleDevice) error {
+	local.logger.Infof("Removing %v %v", device.Kind, device.Name)
+
+	switch device.Kind {
+	case deviceKindCryptMapping:
+		return nsenter([]string{"--mount", "--ipc"}, "dmsetup", "remove", device.Name)
+
+	case deviceKindIscsiSession:
+		if err := nsenter([]string{"--mount", "--net"}, "iscsiadm", "-m", "session", "-r", device.session.ID, "-u"); err != nil {
+			return err
+		}
+		if err := nsenter([]string{"--mount", "--net"}, "iscsiadm", "-m", "node", "-T", device.session.Target, "-o", "delete"); err != nil {
+			local.logger.WithError(err).Warnf("Failed to delete iSCSI node record of target %v", device.session.Target)
+		}
+		return nil
+
+	case deviceKindBlockDevice:
+		return os.Remove(filepath.Join(hostRootDirectory, device.Name))
+	}
+
+	return errors.Errorf("unknown device kind %v", device.Kind)
+}
+
+// nsenter runs the command in the namespaces of the host init process.
+func nsenter(namespaces []string, command string, args ...string) error {
+	nsenterArgs := append([]string{"--target", "1"}, namespaces...)
+	nsenterArgs = append(nsenterArgs, "--", command)
+
+	cmd := exec.Command("nsenter", append(nsenterArgs, args...)...)
+	output := &bytes.Buffer{}
+	cmd.Stdout = output
+	cmd.Stderr = output
+	if err := cmd.Run(); err != nil {
+		return errors.Wrapf(err, "%v %v failed: %v", command, strings.Join(args, " "), strings.TrimSpace(output.String()))
+	}
+	return nil
+}
+
+// readHostDevices reads the iSCSI sessions, the dm-crypt mappings, the Longhorn block devices and
+// the mounts of the host from its root directory.
+func readHostDevices(rootDirectory string) (*hostDevices, error) {
+	devices := &hostDevices{
+		diskHolders:  map[string][]string{},
+		mountSources: map[string]bool{},
+	}
+	sysDirectory := filepath.Join(rootDirectory, "sys")
+
+	sessionDirectories, err := filepath.Glob(filepath.Join(sysDirectory, "class/iscsi_session/session*"))
+	if err != nil {
+		return nil, err
+	}
+	for _, sessionDirectory := range sessionDirectories {
+		target, err := os.ReadFile(filepath.Join(sessionDirectory, "targetname"))
+		if err != nil {
+			continue
+		}
+		diskDirectories, _ := filepath.Glob(filepath.Join(sessionDirectory, "device/target*/*/block/*"))
+		devices.sessions = append(devices.sessions, iscsiSession{
+			ID:     strings.TrimPrefix(filepath.Base(sessionDirectory), "session"),
+			Target: strings.TrimSpace(string(target)),
+			Disks:  baseNames(diskDirectories),
+		})
+	}
+
+	dmDirectories, err := filepath.Glob(filepath.Join(sysDirectory, "block/dm-*"))
+	if err != nil {
+		return nil, err
+	}
+	for _, dmDirectory := range dmDirectories {
+		uuid, err := os.ReadFile(filepath.Join(dmDirectory, "dm/uuid"))
+		if err != nil || !strings.HasPrefix(string(uuid), "CRYPT-") {
+			continue
+		}
+		name, err := os.ReadFile(filepath.Join(dmDirectory, "dm/name"))
+		if err != nil {
+			continue
+		}
+		devices.mappings = append(devices.mappings, cryptMapping{
+			Name:    strings.TrimSpace(string(name)),
+			Device:  filepath.Base(dmDirectory),
+			Disks:   readDirectoryNames(filepath.Join(dmDirectory, "slaves")),
+			Holders: readDirectoryNames(filepath.Join(dmDirectory, "holders")),
+		})
+	}
+
+	entries, err := os.ReadDir(filepath.Join(rootDirectory, longhornDeviceDirectory))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrapf(err, "failed to read %v", longhornDeviceDirectory)
+	}
+	for _, entry := range entries {
+		var stat unix.Stat_t
+		if err := unix.Stat(filepath.Join(rootDirectory, longhornDeviceDirectory, entry.Name()), &stat); err != nil || stat.Mode&unix.S_IFMT != unix.S_IFBLK {
+			continue
+		}
+
+		disk := ""
+		devicePath := filepath.Join(sysDirectory, "dev/block", fmt.Sprintf("%d:%d", unix.Major(uint64(stat.Rdev)), unix.Minor(uint64(stat.Rdev))))
+		if target, err := os.Readlink(devicePath); err == nil {
+			disk = filepath.Base(target)
+		}
+		devices.blockDevices = append(devices.blockDevices, blockDevice{Name: entry.Name(), Disk: disk})
+	}
+
+	for _, session := range devices.sessions {
+		for _, disk := range session.Disks {
+			devices.diskHolders[disk] = readDirectoryNames(filepath.Join(sysDirectory, "block", disk, "holders"))
+		}
+	}
+
+	mountTable, err := os.ReadFile("/proc/1/mounts")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read mounts of the host")
+	}
+	for _, line := range strings.Split(string(mountTable), "\n") {
+		if fields := strings.Fields(line); len(fields) > 1 {
+			devices.mountSources[fields[0]] = true
+		}
+	}
+
+	return devices, nil
+}
+
+// findStaleDevices returns the devices of the Longhorn volumes that are not active on the node,
+// in the order of the cleanup. A dm-crypt mapping belongs to Longhorn when it is named after a
+// volume, or backed by the disk of a Longhorn iSCSI session or block device. The devices that are
+// mounted, or held by a device that is not cleaned up, are reported as in use.
+func findStaleDevices(devices *hostDevices, volumeNames, activeVolumes map[string]bool) []staleDevice {
+	diskVolumes := map[string]string{}
+	for _, session := range devices.sessions {
+		if volume, ok := strings.CutPrefix(session.Target, longhornIscsiTargetPrefix); ok {
+			for _, disk := range session.Disks {
+				diskVolumes[disk] = volume
+			}
+		}
+	}
+	for _, device := range devices.blockDevices {
+		if device.Disk != "" {
+			diskVolumes[device.Disk] = device.Name
+		}
+	}
+
+	staleDevices := []staleDevice{}
+	removedHolders := map[string]bool{}
+
+	mappings := append([]cryptMapping{}, devices.mappings...)
+	sort.Slice(mappings, func(i, j int) bool { return mappings[i].Name < mappings[j].Name })
+	for _, mapping := range mappings {
+		volume := ""
+		if volumeNames[mapping.Name] {
+			volume = mapping.Name
+		}
+		for _, disk := range mapping.Disks {
+			if diskVolumes[disk] != "" {
+				volume = diskVolumes[disk]
+			}
+		}
+		if volume == "" || activeVolumes[volume] || activeVolumes[mapping.Name] {
+			continue
+		}
+
+		stale := staleDevice{Kind: deviceKindCryptMapping, Name: mapping.Name, Volume: volume}
+		switch {
+		case devices.mountSources["/dev/mapper/"+mapping.Name] || devices.mountSources["/dev/"+mapping.Device]:
+			stale.InUse = "mounted"
+		case len(mapping.Holders) > 0:
+			stale.InUse = "held by " + strings.Join(mapping.Holders, ", ")
+		default:
+			removedHolders[mapping.Device] = true
+		}
+		staleDevices = append(staleDevices, stale)
+	}
+
+	sessions := append([]iscsiSession{}, devices.sessions...)
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].Target < sessions[j].Target })
+	for i := range sessions {
+		session := &sessions[i]
+		volume, ok := strings.CutPrefix(session.Target, longhornIscsiTargetPrefix)
+		if !ok || activeVolumes[volume] {
+			continue
+		}
+
+		stale := staleDevice{Kind: deviceKindIscsiSession, Name: session.Target, Volume: volume, session: session}
+		stale.InUse = getDisksInUse(devices, session.Disks, removedHolders)
+		staleDevices = append(staleDevices, stale)
+	}
+
+	blockDevices := append([]blockDevice{}, devices.blockDevices...)
+	sort.Slice(blockDevices, func(i, j int) bool { return blockDevices[i].Name < blockDevices[j].Name })
+	for _, device := range blockDevices {
+		if activeVolumes[device.Name] {
+			continue
+		}
+
+		stale := staleDevice{Kind: deviceKindBlockDevice, Name: filepath.Join(longhornDeviceDirectory, device.Name), Volume: device.Name}
+		if devices.mountSources[stale.Name] {
+			stale.InUse = "mounted"
+		} else if device.Disk != "" {
+			stale.InUse = getDisksInUse(devices, []string{device.Disk}, removedHolders)
+		}
+		staleDevices = append(staleDevices, stale)
+	}
+
+	return staleDevices
+}
+
+// getDisksInUse returns why the disks are in use, or an empty string when they are neither mounted
+// nor held by a device other than the removed ones.
+func getDisksInUse(devices *hostDevices, disks []string, removedHolders map[string]bool) string {
+	for _, disk := range disks {
+		if devices.mountSources["/dev/"+disk] {
+			return fmt.Sprintf("mounted from disk %v", disk)
+		}
+		for _, holder := range devices.diskHolders[disk] {
+			if !removedHolders[holder] {
+				return fmt.Sprintf("held by %v through disk %v", holder, disk)
+			}
+		}
+	}
+	return ""
+}
+
+// parseVolumeNames parses the comma-separated volume names.
+func parseVolumeNames(value string) map[string]bool {
+	volumeNames := map[string]bool{}
+	for _, name := range strings.Split(value, consts.CmdOptSeperator) {
+		if name = strings.TrimSpace(name); name != "" {
+			volumeNames[name] = true
+		}
+	}
+	return volumeNames
+}
+
+func baseNames(paths []string) []string {
+	names := make([]string, 0, len(paths))
+	for _, path := range paths {
+		names = append(names, filepath.Base(path))
+	}
+	return names
+}
+
+func readDirectoryNames(directory string) []string {
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		return []string{}
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
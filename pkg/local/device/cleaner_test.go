@@ -0,0 +1,94 @@
+package device
+
+import (
+	"testing"
+)
+
+func TestFindStaleDevices(t *testing.T) {
+	volumeNames := map[string]bool{"vol-1": true, "vol-2": true, "vol-3": true}
+
+	tests := map[string]struct {
+		devices       *hostDevices
+		activeVolumes map[string]bool
+		expected      []staleDevice
+	}{
+		"active volume kept": {
+			devices: &hostDevices{
+				sessions:     []iscsiSession{{ID: "1", Target: longhornIscsiTargetPrefix + "vol-1", Disks: []string{"sdb"}}},
+				mappings:     []cryptMapping{{Name: "vol-1", Device: "dm-0", Disks: []string{"sdb"}}},
+				blockDevices: []blockDevice{{Name: "vol-1", Disk: "sdb"}},
+				diskHolders:  map[string][]string{"sdb": {"dm-0"}},
+				mountSources: map[string]bool{"/dev/mapper/vol-1": true},
+			},
+			activeVolumes: map[string]bool{"vol-1": true},
+			expected:      []staleDevice{},
+		},
+		"leftovers of a crashed attachment": {
+			devices: &hostDevices{
+				sessions:     []iscsiSession{{ID: "1", Target: longhornIscsiTargetPrefix + "vol-1", Disks: []string{"sdb"}}},
+				mappings:     []cryptMapping{{Name: "vol-1", Device: "dm-0", Disks: []string{"sdb"}}},
+				blockDevices: []blockDevice{{Name: "vol-1", Disk: "sdb"}},
+				diskHolders:  map[string][]string{"sdb": {"dm-0"}},
+				mountSources: map[string]bool{},
+			},
+			expected: []staleDevice{
+				{Kind: deviceKindCryptMapping, Name: "vol-1", Volume: "vol-1"},
+				{Kind: deviceKindIscsiSession, Name: longhornIscsiTargetPrefix + "vol-1", Volume: "vol-1"},
+				{Kind: deviceKindBlockDevice, Name: "/dev/longhorn/vol-1", Volume: "vol-1"},
+			},
+		},
+		"mounted mapping keeps its disk": {
+			devices: &hostDevices{
+				sessions:     []iscsiSession{{ID: "1", Target: longhornIscsiTargetPrefix + "vol-2", Disks: []string{"sdc"}}},
+				mappings:     []cryptMapping{{Name: "vol-2", Device: "dm-1", Disks: []string{"sdc"}}},
+				diskHolders:  map[string][]string{"sdc": {"dm-1"}},
+				mountSources: map[string]bool{"/dev/mapper/vol-2": true},
+			},
+			expected: []staleDevice{
+				{Kind: deviceKindCryptMapping, Name: "vol-2", Volume: "vol-2", InUse: "mounted"},
+				{Kind: deviceKindIscsiSession, Name: longhornIscsiTargetPrefix + "vol-2", Volume: "vol-2", InUse: "held by dm-1 through disk sdc"},
+			},
+		},
+		"mapping of a deleted volume backed by a Longhorn disk": {
+			devices: &hostDevices{
+				sessions:     []iscsiSession{{ID: "2", Target: longhornIscsiTargetPrefix + "vol-deleted", Disks: []string{"sdd"}}},
+				mappings:     []cryptMapping{{Name: "vol-deleted", Device: "dm-2", Disks: []string{"sdd"}}},
+				diskHolders:  map[string][]string{"sdd": {"dm-2"}},
+				mountSources: map[string]bool{},
+			},
+			expected: []staleDevice{
+				{Kind: deviceKindCryptMapping, Name: "vol-deleted", Volume: "vol-deleted"},
+				{Kind: deviceKindIscsiSession, Name: longhornIscsiTargetPrefix + "vol-deleted", Volume: "vol-deleted"},
+			},
+		},
+		"other devices ignored": {
+			devices: &hostDevices{
+				sessions:     []iscsiSession{{ID: "3", Target: "iqn.2001-05.com.example:storage", Disks: []string{"sde"}}},
+				mappings:     []cryptMapping{{Name: "luks-root", Device: "dm-3", Disks: []string{"nvme0n1p3"}}},
+				diskHolders:  map[string][]string{},
+				mountSources: map[string]bool{"/dev/mapper/luks-root": true},
+			},
+			expected: []staleDevice{},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			activeVolumes := test.activeVolumes
+			if activeVolumes == nil {
+				activeVolumes = map[string]bool{}
+			}
+
+			staleDevices := findStaleDevices(test.devices, volumeNames, activeVolumes)
+			if len(staleDevices) != len(test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, staleDevices)
+			}
+			for i, expected := range test.expected {
+				actual := staleDevices[i]
+				if actual.Kind != expected.Kind || actual.Name != expected.Name || actual.Volume != expected.Volume || actual.InUse != expected.InUse {
+					t.Errorf("expected device %d to be %+v, got %+v", i, expected, actual)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package device
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+
+	remote "github.com/longhorn/cli/pkg/remote/device"
+)
+
+// podVolumeMountRegex matches the mount point of a volume published to a pod by the kubelet, and
+// captures the pod UID.
+var podVolumeMountRegex = regexp.MustCompile(`/pods/([0-9a-f-]+)/volumes/`)
+
+// mountEntry is a mount read from the mount table of the host.
+type mountEntry struct {
+	Source     string
+	MountPoint string
+}
+
+// MountChecker provide functions for finding, and unmounting, the mounts of the Longhorn volume
+// devices on the node whose volumes are no longer attached to it.
+type MountChecker struct {
+	remote.MountCheckerCmdOptions
+
+	logger *logrus.Entry
+
+	OutputFilePath    string
+	CurrentNodeID     string
+	VolumeNames       string // Comma-separated names of all the Longhorn volumes.
+	VolumeAttachments string // Comma-separated attachments of the Longhorn volumes, as <node>:<volume>.
+
+	volumeNames   map[string]bool
+	activeVolumes map[string]bool
+
+	collection types.NodeCollection
+}
+
+// Validate validates the command options.
+func (local *MountChecker) Validate() error {
+	if local.CurrentNodeID == "" {
+		return errors.Errorf("current node ID (--%s) is required", consts.CmdOptNodeId)
+	}
+
+	return nil
+}
+
+// Init initializes the MountChecker.
+func (local *MountChecker) Init() error {
+	local.collection.Log = &types.LogCollection{}
+	local.collection.StaleMounts = []types.StaleMount{}
+	local.logger = logrus.WithField("component", "mounts")
+
+	local.volumeNames = parseVolumeNames(local.VolumeNames)
+	local.activeVolumes = parseVolumeAttachments(local.VolumeAttachments, local.CurrentNodeID)
+
+	return nil
+}
+
+// Run finds the stale mounts in the mount table of the host, and unmounts them when Repair is set.
+func (local *MountChecker) Run() error {
+	log := local.collection.Log
+
+	mountTable, err := os.ReadFile("/proc/1/mounts")
+	if err != nil {
+		return errors.Wrap(err, "failed to read mounts of the host")
+	}
+
+	staleMounts := findStaleMounts(parseMountTable(string(mountTable)), local.volumeNames, local.activeVolumes)
+	if len(staleMounts) == 0 {
+		log.Info = append(log.Info, "No stale mount of Longhorn volume found")
+		return nil
+	}
+
+	for i := range staleMounts {
+		mount := &staleMounts[i]
+		description := fmt.Sprintf("Stale mount %v of volume %v from %v", mount.MountPoint, mount.Volume, mount.Source)
+		if !local.Repair {
+			log.Warn = append(log.Warn, fmt.Sprintf("%v, the volume is not attached to the node", description))
+			continue
+		}
+
+		local.logger.Infof("Unmounting %v", mount.MountPoint)
+		if err := nsenter([]string{"--mount"}, "umount", mount.MountPoint); err != nil {
+			log.Error = append(log.Error, fmt.Sprintf("Failed to unmount %v of volume %v: %v", mount.MountPoint, mount.Volume, err))
+			continue
+		}
+		mount.Unmounted = true
+		log.Info = append(log.Info, fmt.Sprintf("%v is unmounted", description))
+	}
+	local.collection.StaleMounts = staleMounts
+
+	return nil
+}
+
+// Output converts the collection to JSON and output to stdout or the output file.
+func (local *MountChecker) Output() error {
+	local.logger.Trace("Outputting mount checker results")
+
+	jsonBytes, err := json.Marshal(local.collection)
+	if err != nil {
+		return errors.Wrap(err, "failed to convert collection to JSON")
+	}
+
+	return utils.HandleResult(jsonBytes, local.OutputFilePath, local.logger)
+}
+
+// parseMountTable parses the mount table in the /proc/mounts format. The spaces and the other
+// special characters in the paths are octal escaped.
+func parseMountTable(mountTable string) []mountEntry {
+	mounts := []mountEntry{}
+	for _, line := range strings.Split(mountTable, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		mounts = append(mounts, mountEntry{
+			Source:     unescapeMountPath(fields[0]),
+			MountPoint: unescapeMountPath(fields[1]),
+		})
+	}
+	return mounts
+}
+
+// unescapeMountPath decodes the octal escapes, such as \040 for a space, of a path in the mount table.
+func unescapeMountPath(path string) string {
+	if !strings.Contains(path, `\`) {
+		return path
+	}
+
+	var builder strings.Builder
+	for i := 0; i < len(path); i++ {
+		if path[i] == '\\' && i+3 < len(path) {
+			if value, err := strconv.ParseUint(path[i+1:i+4], 8, 8); err == nil {
+				builder.WriteByte(byte(value))
+				i += 3
+				continue
+			}
+		}
+		builder.WriteByte(path[i])
+	}
+	return builder.String()
+}
+
+// findStaleMounts returns the mounts of the Longhorn volumes that are not active on the node, in the
+// order of the unmount: the mounts published to the pods before the staging mounts of the CSI
+// driver, and the nested mount points before their parents. A mount belongs to Longhorn when its
+// source is a Longhorn block device, or the dm-crypt mapping named after a volume.
+func findStaleMounts(mounts []mountEntry, volumeNames, activeVolumes map[string]bool) []types.StaleMount {
+	staleMounts := []types.StaleMount{}
+	for _, mount := range mounts {
+		volume := ""
+		if name, ok := strings.CutPrefix(mount.Source, longhornDeviceDirectory+"/"); ok {
+			volume = name
+		} else if name, ok := strings.CutPrefix(mount.Source, "/dev/mapper/"); ok && volumeNames[name] {
+			volume = name
+		}
+		if volume == "" || activeVolumes[volume] {
+			continue
+		}
+
+		stale := types.StaleMount{Volume: volume, Source: mount.Source, MountPoint: mount.MountPoint}
+		if match := podVolumeMountRegex.FindStringSubmatch(mount.MountPoint); match != nil {
+			stale.PodUID = match[1]
+		}
+		staleMounts = append(staleMounts, stale)
+	}
+
+	sort.SliceStable(staleMounts, func(i, j int) bool {
+		if (staleMounts[i].PodUID != "") != (staleMounts[j].PodUID != "") {
+			return staleMounts[i].PodUID != ""
+		}
+		return staleMounts[i].MountPoint > staleMounts[j].MountPoint
+	})
+	return staleMounts
+}
+
+// parseVolumeAttachments returns the volumes active on the node from the comma-separated
+// attachments, as <node>:<volume>.
+func parseVolumeAttachments(value, nodeName string) map[string]bool {
+	activeVolumes := map[string]bool{}
+	for _, attachment := range strings.Split(value, consts.CmdOptSeperator) {
+		node, volume, ok := strings.Cut(strings.TrimSpace(attachment), ":")
+		if ok && node == nodeName && volume != "" {
+			activeVolumes[volume] = true
+		}
+	}
+	return activeVolumes
+}
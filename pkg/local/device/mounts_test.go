@@ -0,0 +1,86 @@
+package device
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func TestParseMountTable(t *testing.T) {
+	mountTable := `/dev/sda1 / ext4 rw,relatime 0 0
+/dev/longhorn/vol-1 /var/lib/kubelet/pods/5f1c2a7e-0b6d-4e55-9a31-6d2f8c0b9e41/volumes/kubernetes.io~csi/vol-1/mount ext4 rw,relatime 0 0
+/dev/mapper/vol-2 /mnt/with\040space xfs rw 0 0
+
+`
+
+	expected := []mountEntry{
+		{Source: "/dev/sda1", MountPoint: "/"},
+		{Source: "/dev/longhorn/vol-1", MountPoint: "/var/lib/kubelet/pods/5f1c2a7e-0b6d-4e55-9a31-6d2f8c0b9e41/volumes/kubernetes.io~csi/vol-1/mount"},
+		{Source: "/dev/mapper/vol-2", MountPoint: "/mnt/with space"},
+	}
+	if mounts := parseMountTable(mountTable); !reflect.DeepEqual(mounts, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, mounts)
+	}
+}
+
+func TestFindStaleMounts(t *testing.T) {
+	const (
+		podMount     = "/var/lib/kubelet/pods/5f1c2a7e-0b6d-4e55-9a31-6d2f8c0b9e41/volumes/kubernetes.io~csi/vol-1/mount"
+		stagingMount = "/var/lib/kubelet/plugins/kubernetes.io/csi/driver.longhorn.io/3c0e/globalmount"
+	)
+	volumeNames := map[string]bool{"vol-1": true, "vol-2": true, "vol-3": true}
+
+	tests := map[string]struct {
+		mounts        []mountEntry
+		activeVolumes map[string]bool
+		expected      []types.StaleMount
+	}{
+		"active volume kept": {
+			mounts:        []mountEntry{{Source: "/dev/longhorn/vol-1", MountPoint: stagingMount}, {Source: "/dev/longhorn/vol-1", MountPoint: podMount}},
+			activeVolumes: map[string]bool{"vol-1": true},
+			expected:      []types.StaleMount{},
+		},
+		"pod mount before staging mount": {
+			mounts: []mountEntry{{Source: "/dev/longhorn/vol-1", MountPoint: stagingMount}, {Source: "/dev/longhorn/vol-1", MountPoint: podMount}},
+			expected: []types.StaleMount{
+				{Volume: "vol-1", Source: "/dev/longhorn/vol-1", MountPoint: podMount, PodUID: "5f1c2a7e-0b6d-4e55-9a31-6d2f8c0b9e41"},
+				{Volume: "vol-1", Source: "/dev/longhorn/vol-1", MountPoint: stagingMount},
+			},
+		},
+		"encrypted volume": {
+			mounts:   []mountEntry{{Source: "/dev/mapper/vol-2", MountPoint: stagingMount}},
+			expected: []types.StaleMount{{Volume: "vol-2", Source: "/dev/mapper/vol-2", MountPoint: stagingMount}},
+		},
+		"deleted volume": {
+			mounts:   []mountEntry{{Source: "/dev/longhorn/vol-deleted", MountPoint: stagingMount}},
+			expected: []types.StaleMount{{Volume: "vol-deleted", Source: "/dev/longhorn/vol-deleted", MountPoint: stagingMount}},
+		},
+		"other mounts ignored": {
+			mounts:   []mountEntry{{Source: "/dev/sda1", MountPoint: "/"}, {Source: "/dev/mapper/luks-root", MountPoint: "/home"}},
+			expected: []types.StaleMount{},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			activeVolumes := test.activeVolumes
+			if activeVolumes == nil {
+				activeVolumes = map[string]bool{}
+			}
+
+			staleMounts := findStaleMounts(test.mounts, volumeNames, activeVolumes)
+			if !reflect.DeepEqual(staleMounts, test.expected) {
+				t.Fatalf("expected %+v, got %+v", test.expected, staleMounts)
+			}
+		})
+	}
+}
+
+func TestParseVolumeAttachments(t *testing.T) {
+	activeVolumes := parseVolumeAttachments("node-1:vol-1, node-2:vol-2,node-1:vol-3,invalid,node-1:", "node-1")
+	expected := map[string]bool{"vol-1": true, "vol-3": true}
+	if !reflect.DeepEqual(activeVolumes, expected) {
+		t.Fatalf("expected %v, got %v", expected, activeVolumes)
+	}
+}
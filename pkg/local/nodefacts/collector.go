@@ -0,0 +1,355 @@
+package nodefacts
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	remote "github.com/longhorn/cli/pkg/remote/nodefacts"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+)
+
+// sectorSize is the unit of the size of the block devices in /sys/block.
+const sectorSize = 512
+
+// diskTransports are the transports of the disks, by the prefix of their names.
+var diskTransports = []struct {
+	prefix    string
+	transport string
+}{
+	{prefix: "nvme", transport: "nvme"},
+	{prefix: "sd", transport: "scsi"},
+	{prefix: "vd", transport: "virtio"},
+	{prefix: "xvd", transport: "xen"},
+	{prefix: "mmcblk", transport: "mmc"},
+}
+
+// Collector provide functions for collecting the hardware and operating system inventory of the
+// node.
+type Collector struct {
+	remote.CollectorCmdOptions
+
+	logger *logrus.Entry
+
+	OutputFilePath string
+
+	// HostRootDirectory is the directory of the host root filesystem.
+	// It is "/" when running directly on the host instead of in a DaemonSet pod.
+	HostRootDirectory string
+
+	facts *types.NodeFacts
+}
+
+// Init initializes the Collector.
+func (local *Collector) Init() error {
+	if len(local.OutputFilePath) != 0 {
+		local.logger = logrus.WithField("output", local.OutputFilePath)
+	} else {
+		local.logger = logrus.WithField("output", "stdout")
+	}
+
+	return nil
+}
+
+// Run collects the facts of the node.
+func (local *Collector) Run() error {
+	local.logger.Infof("Collecting node facts from %v", local.HostRootDirectory)
+	local.facts = CollectNodeFacts(local.HostRootDirectory)
+	return nil
+}
+
+// Output outputs the facts of the node to stdout or a file.
+func (local *Collector) Output() error {
+	local.logger.Tracef("Outputting node facts")
+
+	jsonBytes, err := json.Marshal(local.facts)
+	if err != nil {
+		return errors.Wrap(err, "failed to convert node facts to JSON")
+	}
+
+	return utils.HandleResult(jsonBytes, local.OutputFilePath, local.logger)
+}
+
+// CollectNodeFacts collects the hardware and operating system inventory of the node from the proc,
+// sys and etc directories of the host root filesystem. The facts that cannot be read are listed in
+// the errors, so the others are still reported.
+func CollectNodeFacts(hostRootDirectory string) *types.NodeFacts {
+	procDirectory := filepath.Join(hostRootDirectory, "proc")
+	sysDirectory := filepath.Join(hostRootDirectory, "sys")
+
+	facts := &types.NodeFacts{}
+	readFile := func(path string) (string, bool) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			facts.Errors = append(facts.Errors, fmt.Sprintf("Failed to read %v: %v", strings.TrimPrefix(path, hostRootDirectory), err))
+			return "", false
+		}
+		return string(data), true
+	}
+
+	if data, ok := readFile(filepath.Join(procDirectory, "sys/kernel/osrelease")); ok {
+		facts.Kernel = strings.TrimSpace(data)
+	}
+
+	osReleasePath := filepath.Join(hostRootDirectory, "etc/os-release")
+	if _, err := os.Stat(osReleasePath); err != nil {
+		osReleasePath = filepath.Join(hostRootDirectory, "usr/lib/os-release")
+	}
+	if data, ok := readFile(osReleasePath); ok {
+		osRelease := parseKeyValues(data)
+		facts.OperatingSystem = types.NodeOperatingSystem{
+			ID:         osRelease["ID"],
+			VersionID:  osRelease["VERSION_ID"],
+			PrettyName: osRelease["PRETTY_NAME"],
+		}
+	}
+
+	if data, ok := readFile(filepath.Join(procDirectory, "cpuinfo")); ok {
+		facts.CPU = parseCPUInfo(data)
+	}
+	facts.CPU.Architecture = runtime.GOARCH
+
+	if data, ok := readFile(filepath.Join(procDirectory, "meminfo")); ok {
+		memInfo := parseMemInfo(data)
+		facts.Memory = types.NodeMemory{
+			TotalBytes:        memInfo["MemTotal"],
+			AvailableBytes:    memInfo["MemAvailable"],
+			HugePagesTotal:    memInfo["HugePages_Total"],
+			HugePageSizeBytes: memInfo["Hugepagesize"],
+		}
+	}
+
+	facts.NUMANodes = collectNUMANodes(sysDirectory)
+	facts.Disks = collectDisks(sysDirectory)
+	facts.NetworkInterfaces = collectNetworkInterfaces(sysDirectory)
+
+	if data, ok := readFile(filepath.Join(procDirectory, "modules")); ok {
+		facts.Modules = collectModules(sysDirectory, data)
+	}
+
+	return facts
+}
+
+// parseKeyValues parses the KEY=value lines of the os-release format, unquoting the values.
+func parseKeyValues(data string) map[string]string {
+	values := map[string]string{}
+	for _, line := range strings.Split(data, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok || strings.HasPrefix(key, "#") {
+			continue
+		}
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		} else {
+			value = strings.Trim(value, `'"`)
+		}
+		values[key] = value
+	}
+	return values
+}
+
+// parseCPUInfo parses the /proc/cpuinfo format, where each logical CPU is listed with its
+// processor number. The model is read from "model name" on x86 and from "Model" on ARM, where
+// the sockets are not listed.
+func parseCPUInfo(data string) types.NodeCPU {
+	cpu := types.NodeCPU{}
+	sockets := map[string]bool{}
+	for _, line := range strings.Split(data, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+
+		switch strings.TrimSpace(key) {
+		case "processor":
+			cpu.LogicalCPUs++
+		case "model name", "Model":
+			if cpu.Model == "" {
+				cpu.Model = value
+			}
+		case "physical id":
+			sockets[value] = true
+		}
+	}
+	cpu.Sockets = len(sockets)
+	return cpu
+}
+
+// parseMemInfo parses the /proc/meminfo format, or the one of the meminfo of a NUMA node whose lines
+// start with "Node <id>". The sizes in kB are converted to bytes, and the counts are kept as is.
+func parseMemInfo(data string) map[string]int64 {
+	memInfo := map[string]int64{}
+	for _, line := range strings.Split(data, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(key)
+		if len(fields) == 0 {
+			continue
+		}
+		key = fields[len(fields)-1]
+
+		fields = strings.Fields(value)
+		if len(fields) == 0 {
+			continue
+		}
+		size, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		if len(fields) > 1 && fields[1] == "kB" {
+			size *= 1024
+		}
+		memInfo[key] = size
+	}
+	return memInfo
+}
+
+// collectNUMANodes returns the NUMA nodes with their CPUs and memory, sorted by their ID.
+func collectNUMANodes(sysDirectory string) []types.NodeNUMANode {
+	nodePaths, _ := filepath.Glob(filepath.Join(sysDirectory, "devices/system/node/node[0-9]*"))
+
+	numaNodes := []types.NodeNUMANode{}
+	for _, nodePath := range nodePaths {
+		id, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(nodePath), "node"))
+		if err != nil {
+			continue
+		}
+
+		numaNode := types.NodeNUMANode{
+			ID:   id,
+			CPUs: readTrimmedFile(filepath.Join(nodePath, "cpulist")),
+		}
+		numaNode.TotalBytes = parseMemInfo(readTrimmedFile(filepath.Join(nodePath, "meminfo")))["MemTotal"]
+		numaNodes = append(numaNodes, numaNode)
+	}
+
+	sort.Slice(numaNodes, func(i, j int) bool {
+		return numaNodes[i].ID < numaNodes[j].ID
+	})
+	return numaNodes
+}
+
+// collectDisks returns the physical disks sorted by their name. The virtual block devices, such as
+// the loop, device-mapper and Longhorn devices, have no device directory and are skipped, as are
+// the disks without media.
+func collectDisks(sysDirectory string) []types.NodeDisk {
+	diskPaths, _ := filepath.Glob(filepath.Join(sysDirectory, "block/*"))
+
+	disks := []types.NodeDisk{}
+	for _, diskPath := range diskPaths {
+		devicePath := filepath.Join(diskPath, "device")
+		if _, err := os.Stat(devicePath); err != nil {
+			continue
+		}
+
+		sectors, _ := strconv.ParseInt(readTrimmedFile(filepath.Join(diskPath, "size")), 10, 64)
+		if sectors == 0 {
+			continue
+		}
+
+		disk := types.NodeDisk{
+			Name:       filepath.Base(diskPath),
+			SizeBytes:  sectors * sectorSize,
+			Rotational: readTrimmedFile(filepath.Join(diskPath, "queue/rotational")) == "1",
+			Removable:  readTrimmedFile(filepath.Join(diskPath, "removable")) == "1",
+		}
+		disk.Transport = getDiskTransport(disk.Name)
+
+		// The device of an NVMe namespace is its controller, where the firmware revision is.
+		// The SCSI disks have their vendor apart from their model, and their firmware in rev.
+		disk.Model = strings.TrimSpace(readTrimmedFile(filepath.Join(devicePath, "vendor")) + " " + readTrimmedFile(filepath.Join(devicePath, "model")))
+		disk.Serial = readTrimmedFile(filepath.Join(devicePath, "serial"))
+		disk.Firmware = readTrimmedFile(filepath.Join(devicePath, "firmware_rev"))
+		if disk.Firmware == "" {
+			disk.Firmware = readTrimmedFile(filepath.Join(devicePath, "rev"))
+		}
+
+		disks = append(disks, disk)
+	}
+	return disks
+}
+
+// getDiskTransport returns the transport of the disk from the prefix of its name.
+func getDiskTransport(name string) string {
+	for _, diskTransport := range diskTransports {
+		if strings.HasPrefix(name, diskTransport.prefix) {
+			return diskTransport.transport
+		}
+	}
+	return ""
+}
+
+// collectNetworkInterfaces returns the network interfaces other than the loopback one, sorted by
+// their name. The interfaces without device directory, such as bridges, veths and VLANs, are
+// virtual.
+func collectNetworkInterfaces(sysDirectory string) []types.NodeNetworkInterface {
+	interfacePaths, _ := filepath.Glob(filepath.Join(sysDirectory, "class/net/*"))
+
+	networkInterfaces := []types.NodeNetworkInterface{}
+	for _, interfacePath := range interfacePaths {
+		name := filepath.Base(interfacePath)
+		if name == "lo" {
+			continue
+		}
+
+		networkInterface := types.NodeNetworkInterface{
+			Name:       name,
+			MACAddress: readTrimmedFile(filepath.Join(interfacePath, "address")),
+			State:      readTrimmedFile(filepath.Join(interfacePath, "operstate")),
+		}
+		// Reading the speed fails for the interfaces down, and gives -1 when it is unknown.
+		if speed, err := strconv.Atoi(readTrimmedFile(filepath.Join(interfacePath, "speed"))); err == nil && speed > 0 {
+			networkInterface.SpeedMbps = speed
+		}
+		networkInterface.MTU, _ = strconv.Atoi(readTrimmedFile(filepath.Join(interfacePath, "mtu")))
+		if _, err := os.Stat(filepath.Join(interfacePath, "device")); err != nil {
+			networkInterface.Virtual = true
+		}
+
+		networkInterfaces = append(networkInterfaces, networkInterface)
+	}
+	return networkInterfaces
+}
+
+// collectModules returns the loaded kernel modules in the /proc/modules format, with their version
+// from /sys/module, sorted by their name.
+func collectModules(sysDirectory, data string) []types.NodeModule {
+	modules := []types.NodeModule{}
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		modules = append(modules, types.NodeModule{
+			Name:    fields[0],
+			Version: readTrimmedFile(filepath.Join(sysDirectory, "module", fields[0], "version")),
+		})
+	}
+
+	sort.Slice(modules, func(i, j int) bool {
+		return modules[i].Name < modules[j].Name
+	})
+	return modules
+}
+
+// readTrimmedFile returns the content of the file without the surrounding spaces, or an empty
+// string when it cannot be read.
+func readTrimmedFile(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
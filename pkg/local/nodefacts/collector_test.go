@@ -0,0 +1,139 @@
+package nodefacts
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func TestParseKeyValues(t *testing.T) {
+	data := "# comment\nNAME=\"SLES\"\nID=sles\nVERSION_ID='15.5'\nPRETTY_NAME=\"SUSE Linux Enterprise Server 15 SP5\"\n"
+	expected := map[string]string{
+		"NAME":        "SLES",
+		"ID":          "sles",
+		"VERSION_ID":  "15.5",
+		"PRETTY_NAME": "SUSE Linux Enterprise Server 15 SP5",
+	}
+	if values := parseKeyValues(data); !reflect.DeepEqual(values, expected) {
+		t.Errorf("expected %v, got %v", expected, values)
+	}
+}
+
+func TestParseCPUInfo(t *testing.T) {
+	tests := map[string]struct {
+		data     string
+		expected types.NodeCPU
+	}{
+		"x86": {
+			data: "processor\t: 0\nmodel name\t: AMD EPYC 7R13 Processor\nphysical id\t: 0\n\n" +
+				"processor\t: 1\nmodel name\t: AMD EPYC 7R13 Processor\nphysical id\t: 0\n\n" +
+				"processor\t: 2\nmodel name\t: AMD EPYC 7R13 Processor\nphysical id\t: 1\n",
+			expected: types.NodeCPU{Model: "AMD EPYC 7R13 Processor", LogicalCPUs: 3, Sockets: 2},
+		},
+		"arm": {
+			data:     "processor\t: 0\nBogoMIPS\t: 108.00\n\nprocessor\t: 1\nBogoMIPS\t: 108.00\n\nModel\t\t: Raspberry Pi 4 Model B Rev 1.4\n",
+			expected: types.NodeCPU{Model: "Raspberry Pi 4 Model B Rev 1.4", LogicalCPUs: 2},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if cpu := parseCPUInfo(test.data); !reflect.DeepEqual(cpu, test.expected) {
+				t.Errorf("expected %+v, got %+v", test.expected, cpu)
+			}
+		})
+	}
+}
+
+func TestParseMemInfo(t *testing.T) {
+	tests := map[string]struct {
+		data     string
+		expected map[string]int64
+	}{
+		"node": {
+			data:     "MemTotal:       16303716 kB\nMemAvailable:   12001380 kB\nHugePages_Total:    1024\nHugepagesize:       2048 kB\n",
+			expected: map[string]int64{"MemTotal": 16303716 * 1024, "MemAvailable": 12001380 * 1024, "HugePages_Total": 1024, "Hugepagesize": 2048 * 1024},
+		},
+		"NUMA node": {
+			data:     "Node 1 MemTotal:       8151858 kB\nNode 1 MemFree:        6020168 kB\n",
+			expected: map[string]int64{"MemTotal": 8151858 * 1024, "MemFree": 6020168 * 1024},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if memInfo := parseMemInfo(test.data); !reflect.DeepEqual(memInfo, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, memInfo)
+			}
+		})
+	}
+}
+
+func TestCollectDisks(t *testing.T) {
+	sysDirectory := t.TempDir()
+	writeFiles(t, sysDirectory, map[string]string{
+		"block/nvme0n1/size":                    "1953525168\n",
+		"block/nvme0n1/queue/rotational":        "0\n",
+		"block/nvme0n1/removable":               "0\n",
+		"block/nvme0n1/device/model":            "Samsung SSD 980 PRO 1TB                 \n",
+		"block/nvme0n1/device/serial":           "S5GXNF0R123456\n",
+		"block/nvme0n1/device/firmware_rev":     "5B2QGXA7\n",
+		"block/sda/size":                        "7814037168\n",
+		"block/sda/queue/rotational":            "1\n",
+		"block/sda/device/vendor":               "ATA     \n",
+		"block/sda/device/model":                "ST4000NM000A-2HZ\n",
+		"block/sda/device/rev":                  "TN02\n",
+		"block/sr0/size":                        "0\n",
+		"block/sr0/device/model":                "DVD-ROM\n",
+		"block/loop0/size":                      "2097152\n",
+		"block/longhorn-pvc-a/size":             "2097152\n",
+		"block/longhorn-pvc-a/queue/rotational": "0\n",
+	})
+
+	expected := []types.NodeDisk{
+		{Name: "nvme0n1", Transport: "nvme", Model: "Samsung SSD 980 PRO 1TB", Serial: "S5GXNF0R123456", Firmware: "5B2QGXA7", SizeBytes: 1953525168 * 512},
+		{Name: "sda", Transport: "scsi", Model: "ATA ST4000NM000A-2HZ", Firmware: "TN02", SizeBytes: 7814037168 * 512, Rotational: true},
+	}
+	if disks := collectDisks(sysDirectory); !reflect.DeepEqual(disks, expected) {
+		t.Errorf("expected %+v, got %+v", expected, disks)
+	}
+}
+
+func TestCollectNetworkInterfaces(t *testing.T) {
+	sysDirectory := t.TempDir()
+	writeFiles(t, sysDirectory, map[string]string{
+		"class/net/lo/mtu":             "65536\n",
+		"class/net/eth0/address":       "0a:1b:2c:3d:4e:5f\n",
+		"class/net/eth0/operstate":     "up\n",
+		"class/net/eth0/speed":         "25000\n",
+		"class/net/eth0/mtu":           "9001\n",
+		"class/net/eth0/device/vendor": "0x1d0f\n",
+		"class/net/cni0/operstate":     "up\n",
+		"class/net/cni0/speed":         "-1\n",
+		"class/net/cni0/mtu":           "1450\n",
+	})
+
+	expected := []types.NodeNetworkInterface{
+		{Name: "cni0", State: "up", MTU: 1450, Virtual: true},
+		{Name: "eth0", MACAddress: "0a:1b:2c:3d:4e:5f", State: "up", SpeedMbps: 25000, MTU: 9001},
+	}
+	if networkInterfaces := collectNetworkInterfaces(sysDirectory); !reflect.DeepEqual(networkInterfaces, expected) {
+		t.Errorf("expected %+v, got %+v", expected, networkInterfaces)
+	}
+}
+
+func writeFiles(t *testing.T, directory string, files map[string]string) {
+	t.Helper()
+	for path, content := range files {
+		path = filepath.Join(directory, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
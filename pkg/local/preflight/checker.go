@@ -3,8 +3,10 @@ package preflight
 import (
 	"encoding/json"
 	"fmt"
+	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -25,6 +27,8 @@ import (
 	remote "github.com/longhorn/cli/pkg/remote/preflight"
 	"github.com/longhorn/cli/pkg/types"
 	"github.com/longhorn/cli/pkg/utils"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
 )
 
 // Checker provide functions for the preflight checker.
@@ -35,6 +39,10 @@ type Checker struct {
 
 	OutputFilePath string
 
+	// HostRootDirectory is the directory of the host root filesystem.
+	// It is "/" when running directly on the host instead of in a DaemonSet pod.
+	HostRootDirectory string
+
 	kubeClient *kubeclient.Clientset
 
 	osRelease      string
@@ -53,17 +61,35 @@ type Checker struct {
 func (local *Checker) Init() error {
 	local.collection.Log = &types.LogCollection{}
 
-	config, err := commonkube.GetInClusterConfig()
-	if err != nil {
-		return errors.Wrap(err, "failed to get client config")
+	if local.HostRootDirectory == "" {
+		local.HostRootDirectory = consts.VolumeMountHostDirectory
 	}
 
-	local.kubeClient, err = kubeclient.NewForConfig(config)
-	if err != nil {
-		return errors.Wrap(err, "failed to get Kubernetes clientset")
+	if err := remote.ValidateProfile(local.Profile); err != nil {
+		return err
+	}
+
+	if len(local.LonghornDataDirectories) == 0 {
+		local.LonghornDataDirectories = []string{consts.LonghornDefaultDataDirectory}
+	}
+	if err := remote.ValidateDataDirectories(local.LonghornDataDirectories); err != nil {
+		return err
 	}
 
-	osRelease, err := utils.GetOSRelease()
+	// The Kubernetes checks are skipped when running on a host outside of the cluster.
+	if kubeutils.IsInCluster() {
+		config, err := commonkube.GetInClusterConfig()
+		if err != nil {
+			return errors.Wrap(err, "failed to get client config")
+		}
+
+		local.kubeClient, err = kubeclient.NewForConfig(config)
+		if err != nil {
+			return errors.Wrap(err, "failed to get Kubernetes clientset")
+		}
+	}
+
+	osRelease, err := utils.GetOSRelease(local.HostRootDirectory)
 	if err != nil {
 		return errors.Wrap(err, "failed to get OS release")
 	}
@@ -85,7 +111,7 @@ func (local *Checker) Init() error {
 		commontypes.NamespaceNet,
 	}
 
-	executor, err := commonns.NewNamespaceExecutor(commontypes.ProcessSelf, commontypes.HostProcDirectory, namespaces)
+	executor, err := commonns.NewNamespaceExecutor(commontypes.ProcessSelf, hostProcDirectory(local.HostRootDirectory), namespaces)
 	if err != nil {
 		return err
 	}
@@ -177,7 +203,11 @@ func (local *Checker) Init() error {
 
 // Run executes the preflight checks.
 func (local *Checker) Run() error {
-	local.checkKubeDNS()
+	if !local.isCheckSkipped(checkNameKubeDNS) {
+		local.checkKubeDNS()
+	}
+	local.checkMountPropagation()
+	local.checkDataDirectories()
 
 	switch local.osRelease {
 	case fmt.Sprint(consts.OperatingSystemContainerOptimizedOS):
@@ -190,14 +220,24 @@ func (local *Checker) Run() error {
 			return err
 		}
 
+		if !local.isCheckSkipped(checkNameIscsidConfig) {
+			local.checkIscsidConfig()
+		}
+
 		if err := local.checkMultipathService(); err != nil {
 			return err
 		}
 
+		local.checkConflictingStorageAgents()
+
 		if err := local.checkNFSv4Support(); err != nil {
 			return err
 		}
 
+		local.checkNFSClient()
+
+		local.checkCrypto()
+
 		if err := local.checkPackagesInstalled(false); err != nil {
 			return err
 		}
@@ -229,6 +269,21 @@ func (local *Checker) Run() error {
 		}
 	}
 
+	local.checkKnownIssues()
+
+	local.runProfileChecks()
+
+	if local.RegistryCheckImages != "" {
+		logrus.Info("Checking registries of the images")
+		local.checkRegistries()
+	}
+
+	if err := local.runCustomChecks(); err != nil {
+		return err
+	}
+
+	local.runPlugins()
+
 	return nil
 }
 
@@ -246,7 +301,17 @@ func (local *Checker) Output() error {
 
 // checkContainerOptimizedOS checks if the node-agent DaemonSet is running.
 func (local *Checker) checkContainerOptimizedOS() error {
-	daemonSet, err := commonkube.GetDaemonSet(local.kubeClient, metav1.NamespaceDefault, consts.AppNamePreflightContainerOptimizedOS)
+	if local.kubeClient == nil {
+		local.collection.Log.Warn = append(local.collection.Log.Warn, fmt.Sprintf("Skipped checking DaemonSet %v outside of the Kubernetes cluster", consts.AppNamePreflightContainerOptimizedOS))
+		return nil
+	}
+
+	namespace := local.Namespace
+	if namespace == "" {
+		namespace = consts.LonghornNamespace
+	}
+
+	daemonSet, err := commonkube.GetDaemonSet(local.kubeClient, namespace, consts.AppNamePreflightContainerOptimizedOS)
 	if err != nil {
 		return errors.Wrapf(err, "failed to get DaemonSet %v", consts.AppNamePreflightContainerOptimizedOS)
 	}
@@ -305,7 +370,12 @@ func (local *Checker) checkHugePages() error {
 		return nil
 	}
 
-	pages := local.HugePageSize >> 1
+	hugePageNodes, err := remote.ParseHugePageNodes(local.HugePageNodes)
+	if err != nil {
+		return err
+	}
+
+	pages := remote.GetRequiredHugePageSize(local.HugePageSize, hugePageNodes) >> 1
 
 	ok, hugePagesTotalNum, requiredHugePages, err := local.isHugePagesTotalEqualOrLargerThan(pages)
 	if err != nil {
@@ -317,10 +387,41 @@ func (local *Checker) checkHugePages() error {
 		return nil
 	}
 
+	if len(hugePageNodes) > 0 {
+		inspectNumaHugePages(local.collection.Log, filepath.Join(local.HostRootDirectory, "sys"), hugePageNodes)
+	}
+
 	local.collection.Log.Info = append(local.collection.Log.Info, "HugePages is enabled")
 	return nil
 }
 
+// inspectNumaHugePages compares the 2MiB huge pages allocated on each NUMA node with the required size in MiB.
+func inspectNumaHugePages(log *types.LogCollection, sysDirectory string, hugePageNodes map[int]int) {
+	for _, numaNode := range remote.SortedNumaNodes(hugePageNodes) {
+		requiredPages := hugePageNodes[numaNode] >> 1
+
+		path := filepath.Join(sysDirectory, fmt.Sprintf("devices/system/node/node%d/hugepages/hugepages-2048kB/nr_hugepages", numaNode))
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Error = append(log.Error, fmt.Sprintf("Failed to read the 2MiB HugePages of NUMA node %v: %v", numaNode, err))
+			continue
+		}
+
+		allocatedPages, err := strconv.Atoi(strings.TrimSpace(string(data)))
+		if err != nil {
+			log.Error = append(log.Error, fmt.Sprintf("Failed to parse the 2MiB HugePages of NUMA node %v: %v", numaNode, err))
+			continue
+		}
+
+		if allocatedPages < requiredPages {
+			log.Error = append(log.Error, fmt.Sprintf("HugePages is insufficient on NUMA node %v. Required 2MiB HugePages: %v pages, Allocated 2MiB HugePages: %v pages", numaNode, requiredPages, allocatedPages))
+			continue
+		}
+
+		log.Info = append(log.Info, fmt.Sprintf("NUMA node %v has %v 2MiB HugePages allocated", numaNode, allocatedPages))
+	}
+}
+
 func (local *Checker) isHugePagesTotalEqualOrLargerThan(requiredHugePages int) (bool, int, int, error) {
 	output, err := local.packageManager.Execute([]string{}, "grep", []string{"HugePages_Total", "/proc/meminfo"}, commontypes.ExecuteNoTimeout)
 	if err != nil {
@@ -421,45 +522,32 @@ func (local *Checker) checkNFSv4Support() error {
 	logrus.Info("Checking if NFS4 (either 4.0, 4.1 or 4.2) is supported")
 
 	// check kernel capability
-	var isKernelSupport = false
-
 	kernelVersion, err := utils.GetKernelVersion()
 	if err != nil {
 		return err
 	}
-	hostBootDir := filepath.Join(consts.VolumeMountHostDirectory, commontypes.SysBootDirectory)
+	hostBootDir := filepath.Join(local.HostRootDirectory, commontypes.SysBootDirectory)
 	kernelConfigMap, err := commonsys.GetBootKernelConfigMap(hostBootDir, kernelVersion)
 	if err != nil {
 		return err
 	}
-	for configItem, module := range map[string]string{"CONFIG_NFS_V4_2": "nfs", "CONFIG_NFS_V4_1": "nfs", "CONFIG_NFS_V4": "nfs"} {
-		if configVal, exist := kernelConfigMap[configItem]; !exist {
-			continue
-		} else if configVal == "y" {
-			isKernelSupport = true
-			break
-		} else if configVal == "m" {
-			// Check if the module is loaded
-			moduleLoaded, err := utils.IsModuleLoaded(module)
-			if err != nil {
-				continue
-			}
-			if moduleLoaded {
-				isKernelSupport = true
-				break
-			}
-		}
-	}
+	// The versions built as modules are only supported once the module is loaded.
+	moduleLoaded, _ := utils.IsModuleLoaded("nfs")
 
-	if !isKernelSupport {
+	nfsVersions := getKernelNFSVersions(kernelConfigMap, moduleLoaded)
+	if len(nfsVersions) == 0 {
 		local.collection.Log.Error = append(local.collection.Log.Error, "NFS4 is not supported")
 		return nil
 	}
+	local.collection.Log.Info = append(local.collection.Log.Info, fmt.Sprintf("NFS versions supported by the kernel: %v", strings.Join(nfsVersions, ", ")))
+	if !slices.Contains(nfsVersions, "4.1") {
+		local.collection.Log.Error = append(local.collection.Log.Error, "NFS 4.1 is not supported by the kernel, the RWX volumes mount with it")
+	}
 
 	// check default NFS protocol version
 	var isSupportedNFSVersion bool
 
-	hostEtcDir := filepath.Join(consts.VolumeMountHostDirectory, commontypes.SysEtcDirectory)
+	hostEtcDir := filepath.Join(local.HostRootDirectory, commontypes.SysEtcDirectory)
 	nfsMajor, nfsMinor, err := commonnfs.GetSystemDefaultNFSVersion(hostEtcDir)
 	if err == nil {
 		isSupportedNFSVersion = nfsMajor == 4 && (nfsMinor == 0 || nfsMinor == 1 || nfsMinor == 2)
@@ -491,6 +579,11 @@ func (local *Checker) checkNFSv4Support() error {
 //
 // https://github.com/longhorn/longhorn/issues/9752
 func (local *Checker) checkKubeDNS() {
+	if local.kubeClient == nil {
+		logrus.Info("Skipping CoreDNS check outside of the Kubernetes cluster")
+		return
+	}
+
 	logrus.Info("Checking if CoreDNS has multiple replicas")
 
 	deployments, err := commonkube.ListDeployments(local.kubeClient, metav1.NamespaceSystem, map[string]string{consts.KubeAppLabel: consts.KubeAppValueDNS})
@@ -0,0 +1,29 @@
+package preflight
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func TestInspectNumaHugePages(t *testing.T) {
+	sysDirectory := t.TempDir()
+
+	for numaNode, pages := range map[string]string{"node0": "512\n", "node1": "128\n"} {
+		directory := filepath.Join(sysDirectory, "devices/system/node", numaNode, "hugepages/hugepages-2048kB")
+		if err := os.MkdirAll(directory, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(directory, "nr_hugepages"), []byte(pages), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	log := &types.LogCollection{}
+	inspectNumaHugePages(log, sysDirectory, map[int]int{0: 1024, 1: 1024, 2: 1024})
+
+	assertMessages(t, "info", log.Info, []string{"NUMA node 0 has 512"})
+	assertMessages(t, "error", log.Error, []string{"insufficient on NUMA node 1", "NUMA node 2"})
+}
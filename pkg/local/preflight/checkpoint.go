@@ -0,0 +1,130 @@
+package preflight
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// installCheckpointPath is the file on the host recording the completed steps of the preflight
+// install, so a rerun after a reboot resumes where the previous one left off.
+const installCheckpointPath = "/var/lib/longhornctl/install-preflight.json"
+
+// installCheckpoint records the completed steps of the preflight install on the host.
+type installCheckpoint struct {
+	Steps map[string]*installCheckpointStep `json:"steps"`
+
+	// RebootRequiredBootID is the boot ID of the host when the installed packages required a
+	// reboot. The install does not go further until the host boots with another ID.
+	RebootRequiredBootID string `json:"rebootRequiredBootID,omitempty"`
+}
+
+// installCheckpointStep records a completed step of the preflight install.
+type installCheckpointStep struct {
+	CompletedAt time.Time `json:"completedAt"`
+	Fingerprint string    `json:"fingerprint"`      // Fingerprint of the inputs of the step, such as the packages.
+	BootID      string    `json:"bootID,omitempty"` // Boot ID for the steps that do not persist across reboots.
+}
+
+// installStep is a step of the preflight install.
+type installStep struct {
+	name string
+
+	// inputs change the fingerprint of the step, so the step runs again when they differ from
+	// the completed one.
+	inputs []string
+
+	// perBoot is set for the steps that do not persist across reboots, such as loading the
+	// kernel modules.
+	perBoot bool
+
+	// run runs the step. It returns true when the host must reboot before the next steps.
+	run func() (bool, error)
+}
+
+func newInstallCheckpoint() *installCheckpoint {
+	return &installCheckpoint{Steps: map[string]*installCheckpointStep{}}
+}
+
+// readInstallCheckpoint reads the checkpoint from the host. It returns an empty checkpoint when the
+// host has none, or an unreadable one.
+func readInstallCheckpoint(hostRootDirectory string) (*installCheckpoint, error) {
+	data, err := os.ReadFile(filepath.Join(hostRootDirectory, installCheckpointPath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return newInstallCheckpoint(), nil
+		}
+		return nil, errors.Wrapf(err, "failed to read %v", installCheckpointPath)
+	}
+
+	checkpoint := newInstallCheckpoint()
+	if err := json.Unmarshal(data, checkpoint); err != nil {
+		return newInstallCheckpoint(), nil
+	}
+	if checkpoint.Steps == nil {
+		checkpoint.Steps = map[string]*installCheckpointStep{}
+	}
+	return checkpoint, nil
+}
+
+// write writes the checkpoint to the host through a temporary file, so an interrupted write does not
+// leave a partial checkpoint.
+func (checkpoint *installCheckpoint) write(hostRootDirectory string) error {
+	data, err := json.MarshalIndent(checkpoint, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to convert checkpoint to JSON")
+	}
+
+	path := filepath.Join(hostRootDirectory, installCheckpointPath)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.Wrapf(err, "failed to create the directory of %v", installCheckpointPath)
+	}
+
+	temporaryPath := path + ".tmp"
+	if err := os.WriteFile(temporaryPath, append(data, '\n'), 0644); err != nil {
+		return errors.Wrapf(err, "failed to write %v", installCheckpointPath)
+	}
+	return errors.Wrapf(os.Rename(temporaryPath, path), "failed to write %v", installCheckpointPath)
+}
+
+// isCompleted returns whether the step was completed with the same inputs, and in the current boot
+// when it does not persist across reboots.
+func (checkpoint *installCheckpoint) isCompleted(step *installStep, bootID string) bool {
+	completed, ok := checkpoint.Steps[step.name]
+	if !ok || completed.Fingerprint != step.fingerprint() {
+		return false
+	}
+	return !step.perBoot || completed.BootID == bootID
+}
+
+// complete records the step as completed.
+func (checkpoint *installCheckpoint) complete(step *installStep, bootID string, now time.Time) {
+	completed := &installCheckpointStep{
+		CompletedAt: now.UTC(),
+		Fingerprint: step.fingerprint(),
+	}
+	if step.perBoot {
+		completed.BootID = bootID
+	}
+	checkpoint.Steps[step.name] = completed
+}
+
+func (step *installStep) fingerprint() string {
+	sum := sha256.Sum256([]byte(strings.Join(step.inputs, "\n")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// getBootID returns the boot ID of the host, which changes on each boot.
+func getBootID(hostRootDirectory string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(hostProcDirectory(hostRootDirectory), "sys/kernel/random/boot_id"))
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read boot ID")
+	}
+	return strings.TrimSpace(string(data)), nil
+}
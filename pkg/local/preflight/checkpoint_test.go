@@ -0,0 +1,97 @@
+package preflight
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestInstallCheckpoint(t *testing.T) {
+	hostRootDirectory := t.TempDir()
+
+	checkpoint, err := readInstallCheckpoint(hostRootDirectory)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(checkpoint.Steps) != 0 {
+		t.Fatalf("expected empty checkpoint, got %+v", checkpoint)
+	}
+
+	packages := &installStep{name: "packages", inputs: []string{"nfs-common", "open-iscsi"}}
+	modules := &installStep{name: "modules", inputs: []string{"nfs"}, perBoot: true}
+
+	checkpoint.complete(packages, "boot-1", time.Now())
+	checkpoint.complete(modules, "boot-1", time.Now())
+	checkpoint.RebootRequiredBootID = "boot-1"
+	if err := checkpoint.write(hostRootDirectory); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	checkpoint, err = readInstallCheckpoint(hostRootDirectory)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if checkpoint.RebootRequiredBootID != "boot-1" {
+		t.Errorf("expected reboot required in boot-1, got %q", checkpoint.RebootRequiredBootID)
+	}
+
+	for name, tc := range map[string]struct {
+		step     *installStep
+		bootID   string
+		expected bool
+	}{
+		"completed":                      {packages, "boot-1", true},
+		"completed before reboot":        {packages, "boot-2", true},
+		"per boot completed":             {modules, "boot-1", true},
+		"per boot completed before boot": {modules, "boot-2", false},
+		"changed inputs":                 {&installStep{name: "packages", inputs: []string{"nfs-common"}}, "boot-1", false},
+		"not completed":                  {&installStep{name: "services"}, "boot-1", false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if completed := checkpoint.isCompleted(tc.step, tc.bootID); completed != tc.expected {
+				t.Errorf("expected completed %v, got %v", tc.expected, completed)
+			}
+		})
+	}
+}
+
+func TestReadInstallCheckpointCorrupted(t *testing.T) {
+	hostRootDirectory := t.TempDir()
+
+	path := filepath.Join(hostRootDirectory, installCheckpointPath)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.WriteFile(path, []byte("{"), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	checkpoint, err := readInstallCheckpoint(hostRootDirectory)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(checkpoint.Steps) != 0 {
+		t.Errorf("expected empty checkpoint, got %+v", checkpoint)
+	}
+}
+
+func TestGetBootID(t *testing.T) {
+	hostRootDirectory := t.TempDir()
+
+	path := filepath.Join(hostRootDirectory, "proc/sys/kernel/random/boot_id")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.WriteFile(path, []byte("0f8c6a4e-2b1d-4c3e-9a7f-5d6e8b9c0a1b\n"), 0444); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	bootID, err := getBootID(hostRootDirectory)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := "0f8c6a4e-2b1d-4c3e-9a7f-5d6e8b9c0a1b"; bootID != expected {
+		t.Errorf("expected %q, got %q", expected, bootID)
+	}
+}
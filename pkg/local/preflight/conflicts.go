@@ -0,0 +1,170 @@
+package preflight
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+// storageAgent is a storage or data plane agent known to conflict with Longhorn on the same node.
+type storageAgent struct {
+	name string
+
+	processes []string // Process names of the agent.
+	modules   []string // Kernel modules of the agent, reported when they are loaded.
+
+	// usedModules are kernel modules of the agent, reported only when they are in use, since
+	// some distributions load them by default.
+	usedModules []string
+
+	reason string
+}
+
+var conflictingStorageAgents = []storageAgent{
+	{
+		name:      "OpenEBS cStor iSCSI target",
+		processes: []string{"istgt"},
+		reason:    "it serves iSCSI targets next to the Longhorn engines, and may claim the Longhorn volumes logged in on the node",
+	},
+	{
+		name:      "SCSI target daemon",
+		processes: []string{"tgtd"},
+		reason:    "it serves iSCSI targets next to the Longhorn engines, and may claim the Longhorn volumes logged in on the node",
+	},
+	{
+		name:      "OpenEBS Mayastor data plane",
+		processes: []string{"io-engine", "mayastor"},
+		reason:    "it claims the huge pages and the NVMe devices of the node, which the Longhorn V2 data engine needs as well",
+	},
+	{
+		name:      "Rook/Ceph OSD",
+		processes: []string{"ceph-osd"},
+		reason:    "it claims raw block devices of the node, which must not be used as Longhorn disks",
+	},
+	{
+		name:      "Ceph RBD client",
+		processes: []string{"rbd-nbd"},
+		modules:   []string{"rbd"},
+		reason:    "it maps RBD block devices on the node, which udev rules and multipathd may claim along with the Longhorn volumes",
+	},
+	{
+		name:    "ZFS",
+		modules: []string{"zfs"},
+		reason:  "its pools hold the disks they are created on, which must not be used as Longhorn disks",
+	},
+	{
+		name:        "Device-mapper multipath",
+		usedModules: []string{"dm_multipath"},
+		reason:      "its maps may claim the block devices of the Longhorn volumes. Please refer to https://longhorn.io/kb/troubleshooting-volume-with-multipath/ for more information",
+	},
+}
+
+// checkConflictingStorageAgents checks the host runs none of the storage agents known to conflict
+// with Longhorn, from the processes and the kernel modules of the host.
+func (local *Checker) checkConflictingStorageAgents() {
+	logrus.Info("Checking conflicting storage agents")
+
+	procDirectory := hostProcDirectory(local.HostRootDirectory)
+
+	modulesData, err := os.ReadFile(filepath.Join(procDirectory, "modules"))
+	if err != nil {
+		local.collection.Log.Warn = append(local.collection.Log.Warn, fmt.Sprintf("Failed to read kernel modules of the host: %v", err))
+	}
+
+	inspectStorageAgents(local.collection.Log, listProcesses(procDirectory), parseModules(string(modulesData)))
+}
+
+// inspectStorageAgents warns about the conflicting storage agents found in the processes, keyed by
+// their name, and the kernel modules, keyed by their name with their use count.
+func inspectStorageAgents(log *types.LogCollection, processes map[string][]int, modules map[string]int) {
+	found := false
+	for _, agent := range conflictingStorageAgents {
+		var evidences []string
+		for _, process := range agent.processes {
+			if pids, ok := processes[process]; ok {
+				evidences = append(evidences, fmt.Sprintf("process %v (PID %v)", process, joinInts(pids)))
+			}
+		}
+		for _, module := range agent.modules {
+			if _, ok := modules[module]; ok {
+				evidences = append(evidences, fmt.Sprintf("module %v", module))
+			}
+		}
+		for _, module := range agent.usedModules {
+			if useCount := modules[module]; useCount > 0 {
+				evidences = append(evidences, fmt.Sprintf("module %v (used %d times)", module, useCount))
+			}
+		}
+
+		if len(evidences) == 0 {
+			continue
+		}
+		found = true
+		log.Warn = append(log.Warn, fmt.Sprintf("Found conflicting storage agent %v (%v): %v", agent.name, strings.Join(evidences, ", "), agent.reason))
+	}
+
+	if !found {
+		log.Info = append(log.Info, "No conflicting storage agent is running")
+	}
+}
+
+// listProcesses returns the PIDs of the processes in the proc directory, keyed by their name.
+func listProcesses(procDirectory string) map[string][]int {
+	processes := map[string][]int{}
+
+	commPaths, _ := filepath.Glob(filepath.Join(procDirectory, "[0-9]*/comm"))
+	for _, commPath := range commPaths {
+		pid, err := strconv.Atoi(filepath.Base(filepath.Dir(commPath)))
+		if err != nil {
+			continue
+		}
+
+		comm, err := os.ReadFile(commPath)
+		if err != nil {
+			continue
+		}
+
+		name := strings.TrimSpace(string(comm))
+		processes[name] = append(processes[name], pid)
+	}
+
+	for _, pids := range processes {
+		sort.Ints(pids)
+	}
+	return processes
+}
+
+// parseModules parses the modules file format of proc(5), and returns the use count of each
+// module keyed by its name:
+// dm_multipath 45056 2 dm_round_robin, Live 0x0000000000000000
+func parseModules(data string) map[string]int {
+	modules := map[string]int{}
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+
+		useCount, err := strconv.Atoi(fields[2])
+		if err != nil {
+			continue
+		}
+		modules[fields[0]] = useCount
+	}
+	return modules
+}
+
+func joinInts(values []int) string {
+	strs := make([]string, len(values))
+	for i, value := range values {
+		strs[i] = strconv.Itoa(value)
+	}
+	return strings.Join(strs, ", ")
+}
@@ -0,0 +1,67 @@
+package preflight
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func TestInspectStorageAgents(t *testing.T) {
+	tests := map[string]struct {
+		processes    map[string][]int
+		modules      string
+		expectedInfo []string
+		expectedWarn []string
+	}{
+		"none": {
+			processes:    map[string][]int{"systemd": {1}, "kubelet": {812}},
+			modules:      "iscsi_tcp 24576 2 - Live 0x0000000000000000\ndm_multipath 45056 0 - Live 0x0000000000000000\n",
+			expectedInfo: []string{"No conflicting storage agent"},
+		},
+		"OpenEBS iSCSI target": {
+			processes:    map[string][]int{"istgt": {2301, 2298}},
+			expectedWarn: []string{"OpenEBS cStor iSCSI target (process istgt (PID 2301, 2298))"},
+		},
+		"Ceph and ZFS": {
+			processes: map[string][]int{"ceph-osd": {4001}},
+			modules: "rbd 122880 3 - Live 0x0000000000000000\n" +
+				"libceph 548864 1 rbd, Live 0x0000000000000000\n" +
+				"zfs 4632576 6 - Live 0x0000000000000000\n",
+			expectedWarn: []string{"Rook/Ceph OSD (process ceph-osd", "Ceph RBD client (module rbd)", "ZFS (module zfs)"},
+		},
+		"multipath in use": {
+			modules:      "dm_multipath 45056 2 dm_round_robin, Live 0x0000000000000000\n",
+			expectedWarn: []string{"Device-mapper multipath (module dm_multipath (used 2 times))"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			log := &types.LogCollection{}
+			inspectStorageAgents(log, test.processes, parseModules(test.modules))
+
+			assertMessages(t, "info", log.Info, test.expectedInfo)
+			assertMessages(t, "warn", log.Warn, test.expectedWarn)
+		})
+	}
+}
+
+func TestListProcesses(t *testing.T) {
+	procDirectory := t.TempDir()
+	for pid, comm := range map[string]string{"1": "systemd\n", "4001": "ceph-osd\n", "377": "ceph-osd\n", "self": "longhornctl\n"} {
+		if err := os.MkdirAll(filepath.Join(procDirectory, pid), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(procDirectory, pid, "comm"), []byte(comm), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := map[string][]int{"systemd": {1}, "ceph-osd": {377, 4001}}
+	if processes := listProcesses(procDirectory); !reflect.DeepEqual(processes, expected) {
+		t.Errorf("expected %v, got %v", expected, processes)
+	}
+}
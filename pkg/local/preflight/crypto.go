@@ -0,0 +1,216 @@
+package preflight
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"github.com/sirupsen/logrus"
+
+	commonsys "github.com/longhorn/go-common-libs/sys"
+	commontypes "github.com/longhorn/go-common-libs/types"
+
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+)
+
+const (
+	// minCryptsetupVersion is the first cryptsetup version formatting LUKS2 with the argon2i key
+	// derivation, which the Longhorn encrypted volumes use by default.
+	minCryptsetupVersion = "2.0.0"
+
+	// cryptoBenchmarkCipher and cryptoBenchmarkKeySize are the default cipher and key size of the
+	// Longhorn encrypted volumes.
+	cryptoBenchmarkCipher  = "aes-xts"
+	cryptoBenchmarkKeySize = 256
+
+	// minCryptoThroughputMiB is the encryption and decryption throughput under which the encrypted
+	// volumes are likely bound by the CPU, usually without AES instructions.
+	minCryptoThroughputMiB = 500
+)
+
+// cryptoAlgorithms are the kernel crypto algorithms of the default cipher of the Longhorn encrypted
+// volumes, with the kernel configurations providing them.
+var cryptoAlgorithms = []struct {
+	name    string
+	configs []string
+}{
+	{name: "aes", configs: []string{"CONFIG_CRYPTO_AES", "CONFIG_CRYPTO_AES_NI_INTEL", "CONFIG_CRYPTO_AES_ARM64_CE", "CONFIG_CRYPTO_AES_S390"}},
+	{name: "xts", configs: []string{"CONFIG_CRYPTO_XTS"}},
+}
+
+var (
+	cryptsetupVersionRegex   = regexp.MustCompile(`cryptsetup ([0-9]+\.[0-9]+(\.[0-9]+)?)`)
+	cryptsetupBenchmarkRegex = regexp.MustCompile(`^\s*(\S+)\s+([0-9]+)b\s+([0-9.]+) MiB/s\s+([0-9.]+) MiB/s`)
+)
+
+// cryptoDriver is an algorithm registered in the kernel crypto API, from /proc/crypto.
+type cryptoDriver struct {
+	Name   string
+	Driver string
+}
+
+// checkCrypto checks cryptsetup supports the LUKS2 format of the Longhorn encrypted volumes, and the
+// kernel provides the algorithms of their default cipher. With CryptoBenchmark, it also benchmarks
+// the cipher to find the nodes where the encrypted volumes would underperform.
+func (local *Checker) checkCrypto() {
+	logrus.Info("Checking cryptsetup and kernel crypto algorithms")
+
+	output, err := local.packageManager.Execute([]string{}, "cryptsetup", []string{"--version"}, commontypes.ExecuteDefaultTimeout)
+	if err != nil {
+		local.collection.Log.Error = append(local.collection.Log.Error, fmt.Sprintf("Failed to get the version of cryptsetup, it may not be installed: %v", err))
+	} else {
+		inspectCryptsetupVersion(local.collection.Log, output)
+	}
+
+	cryptoData, err := os.ReadFile(filepath.Join(hostProcDirectory(local.HostRootDirectory), "crypto"))
+	if err != nil {
+		local.collection.Log.Warn = append(local.collection.Log.Warn, fmt.Sprintf("Failed to read the kernel crypto algorithms: %v", err))
+	}
+
+	kernelConfig := map[string]string{}
+	if kernelVersion, err := utils.GetKernelVersion(); err == nil {
+		hostBootDir := filepath.Join(local.HostRootDirectory, commontypes.SysBootDirectory)
+		if configMap, err := commonsys.GetBootKernelConfigMap(hostBootDir, kernelVersion); err == nil {
+			kernelConfig = configMap
+		}
+	}
+
+	inspectCryptoAlgorithms(local.collection.Log, parseCryptoDrivers(string(cryptoData)), kernelConfig)
+
+	if !local.CryptoBenchmark {
+		return
+	}
+
+	logrus.Infof("Benchmarking cipher %v", cryptoBenchmarkCipher)
+	output, err = local.packageManager.Execute([]string{}, "cryptsetup", []string{"benchmark", "--cipher", cryptoBenchmarkCipher, "--key-size", strconv.Itoa(cryptoBenchmarkKeySize)}, commontypes.ExecuteDefaultTimeout)
+	if err != nil {
+		local.collection.Log.Warn = append(local.collection.Log.Warn, fmt.Sprintf("Failed to benchmark cipher %v: %v", cryptoBenchmarkCipher, err))
+		return
+	}
+	inspectCryptoBenchmark(local.collection.Log, output)
+}
+
+// inspectCryptsetupVersion checks the version in the output of cryptsetup --version, for example
+// "cryptsetup 2.4.3" or "cryptsetup 2.7.0 flags: UDEV BLKID KEYRING".
+func inspectCryptsetupVersion(log *types.LogCollection, output string) {
+	match := cryptsetupVersionRegex.FindStringSubmatch(output)
+	if match == nil {
+		log.Warn = append(log.Warn, fmt.Sprintf("Failed to parse the cryptsetup version from %q", strings.TrimSpace(output)))
+		return
+	}
+
+	version, err := semver.ParseTolerant(match[1])
+	if err != nil {
+		log.Warn = append(log.Warn, fmt.Sprintf("Failed to parse cryptsetup version %v: %v", match[1], err))
+		return
+	}
+
+	if version.LT(semver.MustParse(minCryptsetupVersion)) {
+		log.Error = append(log.Error, fmt.Sprintf("cryptsetup %v is older than %v, it cannot format the encrypted volumes with LUKS2 and argon2i", match[1], minCryptsetupVersion))
+		return
+	}
+	log.Info = append(log.Info, fmt.Sprintf("cryptsetup %v is installed", match[1]))
+}
+
+// parseCryptoDrivers parses the /proc/crypto format, where the algorithms are separated by empty
+// lines:
+// name         : xts(aes)
+// driver       : xts-aes-aesni
+func parseCryptoDrivers(data string) []cryptoDriver {
+	drivers := []cryptoDriver{}
+	driver := cryptoDriver{}
+	flush := func() {
+		if driver.Name != "" {
+			drivers = append(drivers, driver)
+		}
+		driver = cryptoDriver{}
+	}
+
+	for _, line := range strings.Split(data, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			flush()
+			continue
+		}
+
+		switch strings.TrimSpace(key) {
+		case "name":
+			flush()
+			driver.Name = strings.TrimSpace(value)
+		case "driver":
+			driver.Driver = strings.TrimSpace(value)
+		}
+	}
+	flush()
+	return drivers
+}
+
+// inspectCryptoAlgorithms checks the kernel provides the algorithms of the default cipher, either
+// registered already or built in the kernel configuration, so the kernel loads them on demand. It
+// warns when AES has no hardware accelerated driver, since the generic one is much slower.
+func inspectCryptoAlgorithms(log *types.LogCollection, drivers []cryptoDriver, kernelConfig map[string]string) {
+	for _, algorithm := range cryptoAlgorithms {
+		registered := false
+		for _, driver := range drivers {
+			if driver.Name == algorithm.name || strings.HasPrefix(driver.Name, algorithm.name+"(") {
+				registered = true
+				break
+			}
+		}
+
+		configured := false
+		for _, config := range algorithm.configs {
+			if value := kernelConfig[config]; value == "y" || value == "m" {
+				configured = true
+				break
+			}
+		}
+
+		switch {
+		case registered:
+			log.Info = append(log.Info, fmt.Sprintf("Kernel crypto algorithm %v is available", algorithm.name))
+		case configured:
+			log.Info = append(log.Info, fmt.Sprintf("Kernel crypto algorithm %v is available in the kernel configuration", algorithm.name))
+		default:
+			log.Error = append(log.Error, fmt.Sprintf("Kernel crypto algorithm %v is not available, the encrypted volumes cannot use cipher aes-xts-plain64", algorithm.name))
+		}
+	}
+
+	if len(drivers) == 0 {
+		return
+	}
+	for _, driver := range drivers {
+		if driver.Name == "aes" && driver.Driver != "aes-generic" {
+			log.Info = append(log.Info, fmt.Sprintf("AES is hardware accelerated by driver %v", driver.Driver))
+			return
+		}
+	}
+	log.Warn = append(log.Warn, "AES is not hardware accelerated, the encrypted volumes may be bound by the CPU")
+}
+
+// inspectCryptoBenchmark checks the throughput in the output of cryptsetup benchmark with a cipher,
+// from its result line, for example "aes-xts 256b 2455.7 MiB/s 2453.1 MiB/s".
+func inspectCryptoBenchmark(log *types.LogCollection, output string) {
+	for _, line := range strings.Split(output, "\n") {
+		match := cryptsetupBenchmarkRegex.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+
+		encryption, _ := strconv.ParseFloat(match[3], 64)
+		decryption, _ := strconv.ParseFloat(match[4], 64)
+		result := fmt.Sprintf("Cipher %v with %v-bit key encrypts at %.1f MiB/s and decrypts at %.1f MiB/s", match[1], match[2], encryption, decryption)
+		if encryption < minCryptoThroughputMiB || decryption < minCryptoThroughputMiB {
+			log.Warn = append(log.Warn, fmt.Sprintf("%v, under %d MiB/s, the encrypted volumes would underperform", result, minCryptoThroughputMiB))
+			return
+		}
+		log.Info = append(log.Info, result)
+		return
+	}
+	log.Warn = append(log.Warn, fmt.Sprintf("Failed to parse the benchmark of cipher %v from %q", cryptoBenchmarkCipher, strings.TrimSpace(output)))
+}
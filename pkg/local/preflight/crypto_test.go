@@ -0,0 +1,116 @@
+package preflight
+
+import (
+	"testing"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func TestInspectCryptsetupVersion(t *testing.T) {
+	tests := map[string]struct {
+		output        string
+		expectedInfo  []string
+		expectedWarn  []string
+		expectedError []string
+	}{
+		"recent": {
+			output:       "cryptsetup 2.4.3\n",
+			expectedInfo: []string{"cryptsetup 2.4.3 is installed"},
+		},
+		"with flags": {
+			output:       "cryptsetup 2.7.0 flags: UDEV BLKID KEYRING KERNEL_CAPI\n",
+			expectedInfo: []string{"cryptsetup 2.7.0 is installed"},
+		},
+		"LUKS1 only": {
+			output:        "cryptsetup 1.7.4\n",
+			expectedError: []string{"cryptsetup 1.7.4 is older than 2.0.0"},
+		},
+		"unknown output": {
+			output:       "command not found\n",
+			expectedWarn: []string{"Failed to parse the cryptsetup version"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			log := &types.LogCollection{}
+			inspectCryptsetupVersion(log, test.output)
+
+			assertMessages(t, "info", log.Info, test.expectedInfo)
+			assertMessages(t, "warn", log.Warn, test.expectedWarn)
+			assertMessages(t, "error", log.Error, test.expectedError)
+		})
+	}
+}
+
+func TestInspectCryptoAlgorithms(t *testing.T) {
+	tests := map[string]struct {
+		crypto        string
+		kernelConfig  map[string]string
+		expectedInfo  []string
+		expectedWarn  []string
+		expectedError []string
+	}{
+		"accelerated": {
+			crypto: "name         : xts(aes)\ndriver       : xts-aes-aesni\nmodule       : aesni_intel\npriority     : 401\n\n" +
+				"name         : aes\ndriver       : aes-aesni\nmodule       : aesni_intel\n\n" +
+				"name         : aes\ndriver       : aes-generic\nmodule       : kernel\n",
+			expectedInfo: []string{"aes is available", "xts is available", "hardware accelerated by driver aes-aesni"},
+		},
+		"generic with xts module": {
+			crypto:       "name         : aes\ndriver       : aes-generic\nmodule       : kernel\n",
+			kernelConfig: map[string]string{"CONFIG_CRYPTO_XTS": "m"},
+			expectedInfo: []string{"aes is available", "xts is available in the kernel configuration"},
+			expectedWarn: []string{"AES is not hardware accelerated"},
+		},
+		"missing xts": {
+			crypto:        "name         : aes\ndriver       : aes-ce\nmodule       : aes_ce_cipher\n",
+			expectedInfo:  []string{"aes is available", "hardware accelerated by driver aes-ce"},
+			expectedError: []string{"xts is not available"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			log := &types.LogCollection{}
+			inspectCryptoAlgorithms(log, parseCryptoDrivers(test.crypto), test.kernelConfig)
+
+			assertMessages(t, "info", log.Info, test.expectedInfo)
+			assertMessages(t, "warn", log.Warn, test.expectedWarn)
+			assertMessages(t, "error", log.Error, test.expectedError)
+		})
+	}
+}
+
+func TestInspectCryptoBenchmark(t *testing.T) {
+	header := "# Tests are approximate using memory only (no storage IO).\n#     Algorithm |       Key |      Encryption |      Decryption\n"
+
+	tests := map[string]struct {
+		output       string
+		expectedInfo []string
+		expectedWarn []string
+	}{
+		"fast": {
+			output:       header + "        aes-xts        256b      2455.7 MiB/s      2453.1 MiB/s\n",
+			expectedInfo: []string{"Cipher aes-xts with 256-bit key encrypts at 2455.7 MiB/s and decrypts at 2453.1 MiB/s"},
+		},
+		"slow": {
+			output:       header + "        aes-xts        256b       180.2 MiB/s       176.9 MiB/s\n",
+			expectedWarn: []string{"under 500 MiB/s"},
+		},
+		"no result": {
+			output:       header,
+			expectedWarn: []string{"Failed to parse the benchmark"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			log := &types.LogCollection{}
+			inspectCryptoBenchmark(log, test.output)
+
+			assertMessages(t, "info", log.Info, test.expectedInfo)
+			assertMessages(t, "warn", log.Warn, test.expectedWarn)
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package preflight
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	commonns "github.com/longhorn/go-common-libs/ns"
+	commontypes "github.com/longhorn/go-common-libs/types"
+
+	"github.com/longhorn/cli/pkg/consts"
+	remote "github.com/longhorn/cli/pkg/remote/preflight"
+	"github.com/longhorn/cli/pkg/types"
+)
+
+const customCheckTimeout = time.Minute
+
+// runCustomChecks runs the user defined checks from the custom checks file in the host namespaces.
+func (local *Checker) runCustomChecks() error {
+	if local.CustomChecksFile == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(local.CustomChecksFile)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read custom checks file %v", local.CustomChecksFile)
+	}
+
+	checkList, err := remote.ParseCustomChecks(data)
+	if err != nil {
+		return err
+	}
+
+	if len(checkList.Checks) == 0 {
+		return nil
+	}
+
+	namespaces := []commontypes.Namespace{
+		commontypes.NamespaceMnt,
+		commontypes.NamespaceNet,
+	}
+	executor, err := commonns.NewNamespaceExecutor(commontypes.ProcessSelf, hostProcDirectory(local.HostRootDirectory), namespaces)
+	if err != nil {
+		return err
+	}
+
+	for _, check := range checkList.Checks {
+		logrus.Infof("Running custom check %v", check.Name)
+		logrus.Debugf("Executing command: %v", check.Command)
+
+		output, err := executor.Execute([]string{}, "sh", []string{"-c", check.Command}, customCheckTimeout)
+		if err != nil {
+			local.appendCustomCheckFailure(check, fmt.Sprintf("Custom check %v failed: %v", check.Name, err))
+			continue
+		}
+
+		if check.ExpectedOutput != "" && !regexp.MustCompile(check.ExpectedOutput).MatchString(output) {
+			local.appendCustomCheckFailure(check, fmt.Sprintf("Custom check %v failed: output %q does not match %q", check.Name, strings.TrimSpace(output), check.ExpectedOutput))
+			continue
+		}
+
+		local.collection.Log.Info = append(local.collection.Log.Info, fmt.Sprintf("Custom check %v passed", check.Name))
+	}
+
+	return nil
+}
+
+func (local *Checker) appendCustomCheckFailure(check types.CustomCheck, message string) {
+	switch check.Severity {
+	case types.CustomCheckSeverityInfo:
+		local.collection.Log.Info = append(local.collection.Log.Info, message)
+	case types.CustomCheckSeverityWarn:
+		local.collection.Log.Warn = append(local.collection.Log.Warn, message)
+	default:
+		local.collection.Log.Error = append(local.collection.Log.Error, message)
+	}
+}
+
+// runPlugins runs the executables named with the PreflightCheckPluginPrefix on PATH.
+//
+// A plugin either prints a JSON object with "error", "warn" and "info" lists,
+// which are merged into the result, or plain text, which is reported as info
+// when the plugin exits successfully and as error otherwise.
+func (local *Checker) runPlugins() {
+	for _, pluginPath := range findPlugins(filepath.SplitList(os.Getenv("PATH"))) {
+		name := strings.TrimPrefix(filepath.Base(pluginPath), consts.PreflightCheckPluginPrefix)
+		logrus.Infof("Running check plugin %v", name)
+		logrus.Debugf("Executing command: %v", pluginPath)
+
+		ctx, cancel := context.WithTimeout(context.Background(), customCheckTimeout)
+		output, err := exec.CommandContext(ctx, pluginPath).Output()
+		cancel()
+
+		var pluginCollection types.LogCollection
+		if jsonErr := json.Unmarshal(output, &pluginCollection); jsonErr == nil {
+			local.collection.Log.Error = append(local.collection.Log.Error, pluginCollection.Error...)
+			local.collection.Log.Warn = append(local.collection.Log.Warn, pluginCollection.Warn...)
+			local.collection.Log.Info = append(local.collection.Log.Info, pluginCollection.Info...)
+			if err == nil {
+				continue
+			}
+		}
+
+		if err != nil {
+			local.collection.Log.Error = append(local.collection.Log.Error, fmt.Sprintf("Check plugin %v failed: %v %s", name, err, strings.TrimSpace(string(output))))
+			continue
+		}
+
+		local.collection.Log.Info = append(local.collection.Log.Info, fmt.Sprintf("Check plugin %v: %s", name, strings.TrimSpace(string(output))))
+	}
+}
+
+// findPlugins returns the executables with the plugin prefix in the given directories.
+// Like kubectl plugins, the first one found wins when the same name appears in several directories.
+func findPlugins(dirs []string) []string {
+	found := map[string]string{}
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.HasPrefix(entry.Name(), consts.PreflightCheckPluginPrefix) {
+				continue
+			}
+
+			if _, exists := found[entry.Name()]; exists {
+				continue
+			}
+
+			info, err := entry.Info()
+			if err != nil || info.Mode().Perm()&0111 == 0 {
+				continue
+			}
+
+			found[entry.Name()] = filepath.Join(dir, entry.Name())
+		}
+	}
+
+	plugins := make([]string, 0, len(found))
+	for _, path := range found {
+		plugins = append(plugins, path)
+	}
+	sort.Strings(plugins)
+	return plugins
+}
@@ -0,0 +1,82 @@
+package preflight
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func writePlugin(t *testing.T, dir, name, script string, mode os.FileMode) string {
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), mode); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFindPlugins(t *testing.T) {
+	firstDir := t.TempDir()
+	secondDir := t.TempDir()
+
+	kernel := writePlugin(t, firstDir, consts.PreflightCheckPluginPrefix+"kernel", "exit 0", 0755)
+	writePlugin(t, firstDir, consts.PreflightCheckPluginPrefix+"not-executable", "exit 0", 0644)
+	writePlugin(t, firstDir, "longhornctl-other", "exit 0", 0755)
+	if err := os.Mkdir(filepath.Join(firstDir, consts.PreflightCheckPluginPrefix+"directory"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// The plugin of the first directory wins the name collision, like the PATH lookup.
+	writePlugin(t, secondDir, consts.PreflightCheckPluginPrefix+"kernel", "exit 1", 0755)
+	firmware := writePlugin(t, secondDir, consts.PreflightCheckPluginPrefix+"firmware", "exit 0", 0755)
+
+	plugins := findPlugins([]string{firstDir, filepath.Join(firstDir, "missing"), secondDir})
+	expected := []string{kernel, firmware}
+	sort.Strings(expected)
+	if !reflect.DeepEqual(plugins, expected) {
+		t.Errorf("expected plugins %v, got %v", expected, plugins)
+	}
+
+	if plugins := findPlugins(nil); len(plugins) != 0 {
+		t.Errorf("expected no plugin, got %v", plugins)
+	}
+}
+
+func TestRunPlugins(t *testing.T) {
+	dir := t.TempDir()
+	writePlugin(t, dir, consts.PreflightCheckPluginPrefix+"a-json", `echo '{"error":["disk is full"],"warn":["disk is slow"],"info":["disk is present"]}'`, 0755)
+	writePlugin(t, dir, consts.PreflightCheckPluginPrefix+"b-text", `echo "firmware is supported"`, 0755)
+	writePlugin(t, dir, consts.PreflightCheckPluginPrefix+"c-failing", `echo "firmware is outdated"; exit 3`, 0755)
+	writePlugin(t, dir, consts.PreflightCheckPluginPrefix+"d-failing-json", `echo '{"warn":["bios is old"]}'; exit 1`, 0755)
+	t.Setenv("PATH", dir)
+
+	local := &Checker{}
+	local.collection.Log = &types.LogCollection{}
+	local.runPlugins()
+
+	assertMessages(t, "error", local.collection.Log.Error, []string{
+		"disk is full",
+		"Check plugin c-failing failed: exit status 3 firmware is outdated",
+		"Check plugin d-failing-json failed: exit status 1",
+	})
+	assertMessages(t, "warn", local.collection.Log.Warn, []string{"disk is slow", "bios is old"})
+	assertMessages(t, "info", local.collection.Log.Info, []string{"disk is present", "Check plugin b-text: firmware is supported"})
+}
+
+func TestAppendCustomCheckFailure(t *testing.T) {
+	local := &Checker{}
+	local.collection.Log = &types.LogCollection{}
+
+	local.appendCustomCheckFailure(types.CustomCheck{Name: "default"}, "default failed")
+	local.appendCustomCheckFailure(types.CustomCheck{Name: "error", Severity: types.CustomCheckSeverityError}, "error failed")
+	local.appendCustomCheckFailure(types.CustomCheck{Name: "warn", Severity: types.CustomCheckSeverityWarn}, "warn failed")
+	local.appendCustomCheckFailure(types.CustomCheck{Name: "info", Severity: types.CustomCheckSeverityInfo}, "info failed")
+
+	assertMessages(t, "error", local.collection.Log.Error, []string{"default failed", "error failed"})
+	assertMessages(t, "warn", local.collection.Log.Warn, []string{"warn failed"})
+	assertMessages(t, "info", local.collection.Log.Info, []string{"info failed"})
+}
@@ -0,0 +1,97 @@
+package preflight
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+)
+
+// dataDirectoryUsage is the filesystem usage of a Longhorn data directory of the host.
+type dataDirectoryUsage struct {
+	Directory  string
+	MountPoint string // Mount point of the filesystem holding the directory, or its nearest existing parent.
+	Exists     bool
+	Available  int64
+	Total      int64
+}
+
+// checkDataDirectories reports the filesystem usage of the Longhorn data directories, and warns
+// about the directories sharing a filesystem.
+func (local *Checker) checkDataDirectories() {
+	logrus.Info("Checking Longhorn data directories")
+
+	mounts, err := readHostMounts(hostProcDirectory(local.HostRootDirectory))
+	if err != nil {
+		local.collection.Log.Error = append(local.collection.Log.Error, fmt.Sprintf("Failed to read mounts of the host: %v", err))
+		return
+	}
+
+	usages := []dataDirectoryUsage{}
+	for _, dataDirectory := range local.LonghornDataDirectories {
+		usage, err := getDataDirectoryUsage(local.HostRootDirectory, mounts, dataDirectory)
+		if err != nil {
+			local.collection.Log.Error = append(local.collection.Log.Error, fmt.Sprintf("Failed to get usage of Longhorn data directory %v: %v", dataDirectory, err))
+			continue
+		}
+		usages = append(usages, *usage)
+	}
+
+	inspectDataDirectories(local.collection.Log, usages)
+}
+
+// getDataDirectoryUsage returns the usage of the filesystem holding the data directory. The
+// filesystem of its nearest existing parent is used when the directory is not created yet.
+func getDataDirectoryUsage(hostRootDirectory string, mounts []mountInfo, dataDirectory string) (*dataDirectoryUsage, error) {
+	usage := &dataDirectoryUsage{Directory: dataDirectory}
+
+	existing := filepath.Clean(dataDirectory)
+	for {
+		if _, err := os.Stat(filepath.Join(hostRootDirectory, existing)); err == nil {
+			break
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+		existing = filepath.Dir(existing)
+	}
+	usage.Exists = existing == filepath.Clean(dataDirectory)
+
+	if mount := findContainingMount(mounts, existing); mount != nil {
+		usage.MountPoint = mount.MountPoint
+	}
+
+	var stat unix.Statfs_t
+	if err := unix.Statfs(filepath.Join(hostRootDirectory, existing), &stat); err != nil {
+		return nil, err
+	}
+	usage.Available = int64(stat.Bavail) * stat.Bsize
+	usage.Total = int64(stat.Blocks) * stat.Bsize
+	return usage, nil
+}
+
+// inspectDataDirectories reports the usage of the data directories, and warns about the ones on the
+// same filesystem, since Longhorn counts the capacity of the filesystem once for each of its disks.
+func inspectDataDirectories(log *types.LogCollection, usages []dataDirectoryUsage) {
+	directoriesByMount := map[string]string{}
+	for _, usage := range usages {
+		if usage.Exists {
+			log.Info = append(log.Info, fmt.Sprintf("Longhorn data directory %v on mount %v has %v available of %v", usage.Directory, usage.MountPoint, utils.FormatBytes(usage.Available), utils.FormatBytes(usage.Total)))
+		} else {
+			log.Info = append(log.Info, fmt.Sprintf("Longhorn data directory %v does not exist yet, Longhorn creates it on mount %v with %v available of %v", usage.Directory, usage.MountPoint, utils.FormatBytes(usage.Available), utils.FormatBytes(usage.Total)))
+		}
+
+		if usage.MountPoint == "" {
+			continue
+		}
+		if other, ok := directoriesByMount[usage.MountPoint]; ok {
+			log.Warn = append(log.Warn, fmt.Sprintf("Longhorn data directories %v and %v are on the same filesystem mounted on %v. Longhorn counts its capacity for each disk, and may schedule more replicas than it holds", other, usage.Directory, usage.MountPoint))
+			continue
+		}
+		directoriesByMount[usage.MountPoint] = usage.Directory
+	}
+}
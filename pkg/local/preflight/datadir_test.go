@@ -0,0 +1,92 @@
+package preflight
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func TestGetDataDirectoryUsage(t *testing.T) {
+	hostRootDirectory := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(hostRootDirectory, "var/lib/longhorn"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	mounts := []mountInfo{{MountPoint: "/"}, {MountPoint: "/mnt/disk1"}}
+
+	tests := []struct {
+		name               string
+		dataDirectory      string
+		expectedExists     bool
+		expectedMountPoint string
+	}{
+		{
+			name:               "existing directory",
+			dataDirectory:      "/var/lib/longhorn",
+			expectedExists:     true,
+			expectedMountPoint: "/",
+		},
+		{
+			name:               "directory not created yet",
+			dataDirectory:      "/var/lib/longhorn-ssd/",
+			expectedExists:     false,
+			expectedMountPoint: "/",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			usage, err := getDataDirectoryUsage(hostRootDirectory, mounts, test.dataDirectory)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if usage.Exists != test.expectedExists || usage.MountPoint != test.expectedMountPoint {
+				t.Errorf("expected exists %v on mount %v, got %+v", test.expectedExists, test.expectedMountPoint, usage)
+			}
+			if usage.Total <= 0 {
+				t.Errorf("expected the filesystem size, got %+v", usage)
+			}
+		})
+	}
+}
+
+func TestInspectDataDirectories(t *testing.T) {
+	tests := []struct {
+		name         string
+		usages       []dataDirectoryUsage
+		expectedInfo []string
+		expectedWarn []string
+	}{
+		{
+			name: "dedicated disks",
+			usages: []dataDirectoryUsage{
+				{Directory: "/var/lib/longhorn", MountPoint: "/var/lib/longhorn", Exists: true, Available: 50 << 30, Total: 100 << 30},
+				{Directory: "/mnt/disk1/longhorn", MountPoint: "/mnt/disk1", Available: 200 << 30, Total: 200 << 30},
+			},
+			expectedInfo: []string{
+				"Longhorn data directory /var/lib/longhorn on mount /var/lib/longhorn has 50.0GiB available of 100.0GiB",
+				"Longhorn data directory /mnt/disk1/longhorn does not exist yet, Longhorn creates it on mount /mnt/disk1",
+			},
+		},
+		{
+			name: "disks on the same filesystem",
+			usages: []dataDirectoryUsage{
+				{Directory: "/var/lib/longhorn", MountPoint: "/", Exists: true},
+				{Directory: "/data/longhorn", MountPoint: "/", Exists: true},
+			},
+			expectedInfo: []string{"/var/lib/longhorn on mount /", "/data/longhorn on mount /"},
+			expectedWarn: []string{"Longhorn data directories /var/lib/longhorn and /data/longhorn are on the same filesystem mounted on /"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			log := &types.LogCollection{}
+			inspectDataDirectories(log, test.usages)
+
+			assertMessages(t, "info", log.Info, test.expectedInfo)
+			assertMessages(t, "warn", log.Warn, test.expectedWarn)
+		})
+	}
+}
@@ -0,0 +1,11 @@
+package preflight
+
+import (
+	"path/filepath"
+)
+
+// hostProcDirectory returns the proc directory in the host root filesystem, used
+// to find the host namespaces to run the commands in.
+func hostProcDirectory(hostRootDirectory string) string {
+	return filepath.Join(hostRootDirectory, "proc")
+}
@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -31,6 +33,10 @@ type Installer struct {
 
 	OutputFilePath string
 
+	// HostRootDirectory is the directory of the host root filesystem.
+	// It is "/" when running directly on the host instead of in a DaemonSet pod.
+	HostRootDirectory string
+
 	osRelease      string
 	packageManager pkgmgr.PackageManager
 
@@ -47,7 +53,11 @@ type Installer struct {
 func (local *Installer) Init() error {
 	local.collection.Log = &types.LogCollection{}
 
-	osRelease, err := utils.GetOSRelease()
+	if local.HostRootDirectory == "" {
+		local.HostRootDirectory = consts.VolumeMountHostDirectory
+	}
+
+	osRelease, err := utils.GetOSRelease(local.HostRootDirectory)
 	if err != nil {
 		return errors.Wrap(err, "failed to get OS release")
 	}
@@ -65,7 +75,7 @@ func (local *Installer) Init() error {
 		commontypes.NamespaceNet,
 	}
 
-	executor, err := commonns.NewNamespaceExecutor(commontypes.ProcessSelf, commontypes.HostProcDirectory, namespaces)
+	executor, err := commonns.NewNamespaceExecutor(commontypes.ProcessSelf, hostProcDirectory(local.HostRootDirectory), namespaces)
 	if err != nil {
 		return err
 	}
@@ -81,130 +91,173 @@ func (local *Installer) Init() error {
 		return err
 	}
 
-	switch packageManagerType {
-	case pkgmgr.PackageManagerApt:
-		local.packageManager = pkgMgr
-		local.packages = []string{
-			"nfs-common", "open-iscsi", "cryptsetup",
-		}
-		local.modules = []string{
-			"nfs", "dm_crypt",
-		}
-		local.services = []string{
-			"iscsid",
-		}
-		local.spdkDepPackages = []string{
-			"linux-modules-extra-" + kernelRelease,
-		}
-		local.spdkDepModules = []string{
-			"nvme_tcp",
-			"uio_pci_generic",
-			"vfio_pci",
-		}
-		return nil
+	requirements, err := pkgmgr.GetRequirements(packageManagerType, kernelRelease)
+	if err != nil {
+		return errors.Wrapf(err, "Operating system (%v) package manager (%s) is not supported", osRelease, packageManagerType)
+	}
 
-	case pkgmgr.PackageManagerYum:
-		local.packageManager = pkgMgr
-		local.packages = []string{
-			"nfs-utils", "iscsi-initiator-utils", "cryptsetup",
-		}
-		local.modules = []string{
-			"nfs", "iscsi_tcp", "dm_crypt",
-		}
-		local.services = []string{
-			"iscsid",
-		}
-		local.spdkDepPackages = []string{}
-		local.spdkDepModules = []string{
-			"nvme_tcp",
-			"uio_pci_generic",
-			"vfio_pci",
-		}
-		return nil
+	local.packageManager = pkgMgr
+	local.packages = requirements.Packages
+	local.modules = requirements.Modules
+	local.services = requirements.Services
+	local.spdkDepPackages = requirements.SpdkDepPackages
+	local.spdkDepModules = requirements.SpdkDepModules
+	return nil
+}
 
-	case pkgmgr.PackageManagerZypper, pkgmgr.PackageManagerTransactionalUpdate:
-		local.packageManager = pkgMgr
-		local.packages = []string{
-			"nfs-client", "open-iscsi", "cryptsetup",
-		}
-		local.modules = []string{
-			"nfs", "iscsi_tcp", "dm_crypt",
-		}
-		local.services = []string{
-			"iscsid",
-		}
-		local.spdkDepPackages = []string{}
-		local.spdkDepModules = []string{
-			"nvme_tcp",
-			"uio_pci_generic",
-			"vfio_pci",
-		}
-		return nil
+// Run runs the steps of the preflight install that are not completed yet according to the
+// checkpoint on the host, and records each step once completed. It stops when the installed
+// packages require a reboot, and resumes after the reboot on the next run.
+func (local *Installer) Run() error {
+	bootID, err := getBootID(local.HostRootDirectory)
+	if err != nil {
+		return err
+	}
 
-	case pkgmgr.PackageManagerPacman:
-		local.packageManager = pkgMgr
-		local.packages = []string{
-			"nfs-utils", "open-iscsi", "cryptsetup",
-		}
-		local.modules = []string{
-			"nfs", "iscsi_tcp", "dm_crypt",
-		}
-		local.services = []string{
-			"iscsid",
+	checkpoint := newInstallCheckpoint()
+	if !local.ResetCheckpoint {
+		checkpoint, err = readInstallCheckpoint(local.HostRootDirectory)
+		if err != nil {
+			return err
 		}
-		local.spdkDepPackages = []string{}
-		local.spdkDepModules = []string{
-			"nvme_tcp",
-			"uio_pci_generic",
-			"vfio_pci",
+	}
+
+	if checkpoint.RebootRequiredBootID != "" {
+		if checkpoint.RebootRequiredBootID == bootID {
+			local.warnRebootRequired()
+			return nil
 		}
-		return nil
 
-	default:
-		return errors.Errorf("Operating system (%v) package manager (%s) is not supported", osRelease, packageManagerType)
+		logrus.Info("Resuming preflight install after reboot")
+		checkpoint.RebootRequiredBootID = ""
 	}
-}
 
-func (local *Installer) Run() error {
-	var rebootRequired bool
-	var err error
+	steps := local.newInstallSteps()
+	var completed []string
+	for _, step := range steps {
+		if checkpoint.isCompleted(step, bootID) {
+			logrus.Infof("Step %s already completed at %v", step.name, checkpoint.Steps[step.name].CompletedAt.Format(time.RFC3339))
+			completed = append(completed, step.name)
+			continue
+		}
+
+		logrus.Infof("Running step %s", step.name)
+		rebootRequired, err := step.run()
+		if err != nil {
+			return errors.Wrapf(err, "failed step %s", step.name)
+		}
 
-	if local.UpdatePackages {
-		if err := local.updatePackageList(); err != nil {
+		checkpoint.complete(step, bootID, time.Now())
+		if rebootRequired {
+			checkpoint.RebootRequiredBootID = bootID
+		}
+		if err := checkpoint.write(local.HostRootDirectory); err != nil {
 			return err
 		}
+
+		if rebootRequired {
+			local.warnRebootRequired()
+			return nil
+		}
 	}
 
-	rebootRequired, err = local.checkAndinstallPackages(local.EnableSpdk)
-	if err != nil {
-		return err
+	switch {
+	case len(completed) == len(steps):
+		local.collection.Log.Info = append(local.collection.Log.Info, fmt.Sprintf("Preflight install already completed, according to %v", installCheckpointPath))
+	case len(completed) > 0:
+		local.collection.Log.Info = append(local.collection.Log.Info, fmt.Sprintf("Resumed preflight install, skipping the completed steps: %v", strings.Join(completed, ", ")))
 	}
 
-	if rebootRequired {
-		logrus.Warn("Need to reboot the system and execute longhornctl install preflight again")
-		local.collection.Log.Warn = append(local.collection.Log.Warn, "Need to reboot the system and execute longhornctl install preflight again")
-		return nil
+	return nil
+}
+
+// newInstallSteps returns the steps of the preflight install for the options, in order.
+func (local *Installer) newInstallSteps() []*installStep {
+	packages := local.packages
+	if local.EnableSpdk {
+		packages = append(append([]string{}, packages...), local.spdkDepPackages...)
 	}
 
-	if err := local.probeModules(consts.DependencyModuleDefault); err != nil {
-		return err
+	var steps []*installStep
+	if local.UpdatePackages && local.PackageSource == "" {
+		steps = append(steps, &installStep{
+			name:   "update-package-list",
+			inputs: packages,
+			run: func() (bool, error) {
+				return false, local.updatePackageList()
+			},
+		})
 	}
 
-	if err := local.startServices(); err != nil {
-		return err
+	steps = append(steps,
+		&installStep{
+			name:   "packages",
+			inputs: packages,
+			run: func() (bool, error) {
+				return local.checkAndinstallPackages(local.EnableSpdk)
+			},
+		},
+		&installStep{
+			name:    "modules",
+			inputs:  local.modules,
+			perBoot: true,
+			run: func() (bool, error) {
+				return false, local.probeModules(consts.DependencyModuleDefault)
+			},
+		},
+		&installStep{
+			name:    "services",
+			inputs:  local.services,
+			perBoot: true,
+			run: func() (bool, error) {
+				return false, local.startServices()
+			},
+		},
+	)
+
+	if local.TuneIscsid {
+		steps = append(steps, &installStep{
+			name: "iscsid",
+			run: func() (bool, error) {
+				return false, local.tuneIscsidConfig()
+			},
+		})
 	}
 
 	if local.EnableSpdk {
-		if err := local.probeModules(consts.DependencyModuleSpdk); err != nil {
-			return err
-		}
-
-		if err := local.configureSPDKEnv(); err != nil {
-			return err
-		}
+		steps = append(steps,
+			&installStep{
+				name:    "spdk-modules",
+				inputs:  local.spdkDepModules,
+				perBoot: true,
+				run: func() (bool, error) {
+					return false, local.probeModules(consts.DependencyModuleSpdk)
+				},
+			},
+			&installStep{
+				name: "spdk-environment",
+				inputs: []string{
+					strconv.Itoa(local.HugePageSize),
+					local.HugePageNodes,
+					local.SpdkOptions,
+					local.AllowPci,
+					local.DriverOverride,
+				},
+				// The huge pages and the device bindings are reset on reboot.
+				perBoot: true,
+				run: func() (bool, error) {
+					return false, local.configureSPDKEnv()
+				},
+			},
+		)
 	}
 
-	return nil
+	return steps
+}
+
+func (local *Installer) warnRebootRequired() {
+	logrus.Warn(consts.PreflightInstallRebootRequired)
+	local.collection.Log.Warn = append(local.collection.Log.Warn, consts.PreflightInstallRebootRequired)
 }
 
 // Output converts the collection to JSON and output to stdout or the output file.
@@ -268,13 +321,16 @@ func (local *Installer) checkAndinstallPackages(spdkDependent bool) (bool, error
 
 	_, err := local.packageManager.StartPackageSession()
 	if err != nil {
-		return false, errors.Wrap(err, "failed to start package session")
+		return false, &types.PackageManagerError{Operation: "start package session", Err: err}
 	}
 
 	packages := local.packages
 	if spdkDependent {
 		packages = append(packages, local.spdkDepPackages...)
 	}
+	if local.PackageSource != "" {
+		return local.installPackagesFromSource(packages)
+	}
 	for _, pkg := range packages {
 		logrus.Infof("Checking package %s", pkg)
 
@@ -284,7 +340,7 @@ func (local *Installer) checkAndinstallPackages(spdkDependent bool) (bool, error
 
 			_, err := local.packageManager.InstallPackage(pkg)
 			if err != nil {
-				return false, errors.Wrapf(err, "failed to install package %s", pkg)
+				return false, &types.PackageManagerError{Operation: "install package " + pkg, Err: err}
 			} else {
 				logrus.Infof("Successfully installed package %s", pkg)
 				local.collection.Log.Info = append(local.collection.Log.Info, fmt.Sprintf("Successfully installed package %s", pkg))
@@ -301,12 +357,85 @@ func (local *Installer) checkAndinstallPackages(spdkDependent bool) (bool, error
 	return rebootRequired, nil
 }
 
+// installPackagesFromSource installs the package files of the package source when any of the
+// packages is missing, such as the packages of an air-gap bundle copied to the node, then checks
+// the packages are installed.
+func (local *Installer) installPackagesFromSource(packages []string) (bool, error) {
+	var missing []string
+	for _, pkg := range packages {
+		logrus.Infof("Checking package %s", pkg)
+
+		if _, err := local.packageManager.CheckPackageInstalled(pkg); err != nil {
+			missing = append(missing, pkg)
+			continue
+		}
+		logrus.Infof("Package %s already installed", pkg)
+	}
+	if len(missing) == 0 {
+		return false, nil
+	}
+
+	files, err := listPackageFiles(filepath.Join(local.HostRootDirectory, local.PackageSource))
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to list the packages of package source %v", local.PackageSource)
+	}
+	if len(files) == 0 {
+		return false, errors.Errorf("package source %v has no package files, while packages %v are missing", local.PackageSource, strings.Join(missing, ", "))
+	}
+
+	paths := make([]string, 0, len(files))
+	for _, file := range files {
+		paths = append(paths, filepath.Join(local.PackageSource, file))
+	}
+
+	logrus.Infof("Installing %d package files from package source %s", len(paths), local.PackageSource)
+	if _, err := local.packageManager.InstallPackageFiles(paths); err != nil {
+		return false, &types.PackageManagerError{Operation: "install the packages of package source " + local.PackageSource, Err: err}
+	}
+
+	for _, pkg := range missing {
+		if _, err := local.packageManager.CheckPackageInstalled(pkg); err != nil {
+			return false, &types.PackageManagerError{
+				Operation: "install package " + pkg,
+				Err:       errors.Errorf("package is not in package source %v", local.PackageSource),
+			}
+		}
+
+		logrus.Infof("Successfully installed package %s", pkg)
+		local.collection.Log.Info = append(local.collection.Log.Info, fmt.Sprintf("Successfully installed package %s", pkg))
+	}
+
+	return local.packageManager.NeedReboot(), nil
+}
+
+// listPackageFiles returns the sorted names of the package files in the directory.
+func listPackageFiles(directory string) ([]string, error) {
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		for _, extension := range consts.PackageFileExtensions {
+			if strings.HasSuffix(entry.Name(), extension) {
+				files = append(files, entry.Name())
+				break
+			}
+		}
+	}
+	return files, nil
+}
+
 // updatePackageList updates list of available packages.
 func (local *Installer) updatePackageList() error {
 	logrus.Info("Updating package list")
 	_, err := local.packageManager.UpdatePackageList()
 	if err != nil {
-		return errors.Wrapf(err, "failed to update package list")
+		return &types.PackageManagerError{Operation: "update package list", Err: err}
 	}
 
 	logrus.Info("Successfully updated package list")
@@ -316,7 +445,7 @@ func (local *Installer) updatePackageList() error {
 // configureSPDKEnv configures SPDK environment.
 func (local *Installer) configureSPDKEnv() error {
 	// Blindly remove the SPDK source code directory if it exists.
-	spdkPath := filepath.Join(consts.VolumeMountHostDirectory, consts.SpdkPath)
+	spdkPath := filepath.Join(local.HostRootDirectory, consts.SpdkPath)
 	if err := os.RemoveAll(spdkPath); err != nil {
 		return err
 	}
@@ -327,10 +456,16 @@ func (local *Installer) configureSPDKEnv() error {
 		_ = os.RemoveAll(spdkPath)
 	}()
 
+	hugePageNodes, err := remote.ParseHugePageNodes(local.HugePageNodes)
+	if err != nil {
+		return err
+	}
+
 	// Configure SPDK environment
 	logrus.Info("Configuring SPDK environment")
+	envs := getEnvsForConfiguringSPDKEnv(local.HugePageSize, hugePageNodes)
 	args := getArgsForConfiguringSPDKEnv(local.SpdkOptions)
-	if _, err := local.packageManager.Execute([]string{}, "bash", args, commontypes.ExecuteNoTimeout); err != nil {
+	if _, err := local.packageManager.Execute(envs, "bash", args, commontypes.ExecuteNoTimeout); err != nil {
 		logrus.WithError(err).Error("Failed to configure SPDK environment")
 	} else {
 		logrus.Info("Successfully configured SPDK environment")
@@ -340,6 +475,26 @@ func (local *Installer) configureSPDKEnv() error {
 	return nil
 }
 
+// getEnvsForConfiguringSPDKEnv returns the environment variables of the SPDK setup script for
+// allocating the huge pages. The per-NUMA-node sizes are converted to the HUGENODE format of the
+// script, for example "nodes_hp[0]=512,nodes_hp[1]=512", which counts 2MiB pages.
+func getEnvsForConfiguringSPDKEnv(hugePageSize int, hugePageNodes map[int]int) []string {
+	envs := []string{
+		fmt.Sprintf("%s=%d", consts.EnvHugePageSize, hugePageSize),
+	}
+
+	if len(hugePageNodes) == 0 {
+		return envs
+	}
+
+	nodes := []string{}
+	for _, numaNode := range remote.SortedNumaNodes(hugePageNodes) {
+		nodes = append(nodes, fmt.Sprintf("nodes_hp[%d]=%d", numaNode, hugePageNodes[numaNode]>>1))
+	}
+	logrus.Infof("Allocating huge pages per NUMA node: %v", strings.Join(nodes, consts.CmdOptSeperator))
+	return append(envs, fmt.Sprintf("%s=%s", consts.EnvSpdkHugeNode, strings.Join(nodes, consts.CmdOptSeperator)))
+}
+
 func getArgsForConfiguringSPDKEnv(options string) []string {
 	args := []string{filepath.Join(consts.SpdkPath, "scripts/setup.sh")}
 	if options != "" {
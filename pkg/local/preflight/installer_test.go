@@ -0,0 +1,40 @@
+package preflight
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGetEnvsForConfiguringSPDKEnv(t *testing.T) {
+	envs := getEnvsForConfiguringSPDKEnv(2048, nil)
+	if expected := []string{"HUGEMEM=2048"}; !reflect.DeepEqual(envs, expected) {
+		t.Errorf("expected %v, got %v", expected, envs)
+	}
+
+	envs = getEnvsForConfiguringSPDKEnv(2048, map[int]int{1: 512, 0: 1024})
+	if expected := []string{"HUGEMEM=2048", "HUGENODE=nodes_hp[0]=512,nodes_hp[1]=256"}; !reflect.DeepEqual(envs, expected) {
+		t.Errorf("expected %v, got %v", expected, envs)
+	}
+}
+
+func TestListPackageFiles(t *testing.T) {
+	directory := t.TempDir()
+	for _, name := range []string{"open-iscsi_2.1.5_amd64.deb", "nfs-common_2.6.1_amd64.deb", "download-packages.sh", "nfs-client-2.6.4.x86_64.rpm"} {
+		if err := os.WriteFile(filepath.Join(directory, name), nil, 0644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(directory, "partial.deb"), 0755); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	files, err := listPackageFiles(directory)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []string{"nfs-client-2.6.4.x86_64.rpm", "nfs-common_2.6.1_amd64.deb", "open-iscsi_2.1.5_amd64.deb"}; !reflect.DeepEqual(files, expected) {
+		t.Errorf("expected %v, got %v", expected, files)
+	}
+}
@@ -0,0 +1,250 @@
+package preflight
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+// iscsidConfigPath is the configuration of open-iscsi, read by iscsiadm when the Longhorn engines
+// log in to their targets.
+const iscsidConfigPath = "/etc/iscsi/iscsid.conf"
+
+// iscsidConfigBackupSuffix is the suffix of the copy of the configuration kept before tuning it.
+const iscsidConfigBackupSuffix = ".longhornctl.bak"
+
+// iscsidRecommendation is a recommended value of a parameter of the open-iscsi configuration.
+type iscsidRecommendation struct {
+	Key         string
+	Default     string // Value of open-iscsi when the parameter is not set.
+	Recommended string
+	Minimum     bool // The recommended value is a minimum instead of an exact value.
+	Reason      string
+}
+
+// iscsidRecommendations are the parameters of the open-iscsi configuration that affect Longhorn.
+var iscsidRecommendations = []iscsidRecommendation{
+	{
+		Key:         "node.startup",
+		Default:     "manual",
+		Recommended: "manual",
+		Reason:      "Longhorn logs in to its targets itself, automatic logins at boot leave sessions to stale targets",
+	},
+	{
+		Key:         "node.session.timeo.replacement_timeout",
+		Default:     "120",
+		Recommended: "120",
+		Reason:      "lower values fail the I/O of the workloads while an engine restarts, higher values hang them longer when the engine is gone",
+	},
+	{
+		Key:         "node.session.queue_depth",
+		Default:     "32",
+		Recommended: "32",
+		Minimum:     true,
+		Reason:      "lower values limit the outstanding I/O to the volumes",
+	},
+	{
+		Key:         "node.session.cmds_max",
+		Default:     "128",
+		Recommended: "128",
+		Minimum:     true,
+		Reason:      "lower values limit the outstanding I/O to the volumes",
+	},
+}
+
+// iscsidAuthKeys are the CHAP parameters. The Longhorn targets do not use authentication.
+var iscsidAuthKeys = []string{
+	"node.session.auth.authmethod",
+	"node.session.auth.username",
+	"node.session.auth.password",
+	"node.session.auth.username_in",
+	"node.session.auth.password_in",
+	"discovery.sendtargets.auth.authmethod",
+	"discovery.sendtargets.auth.username",
+	"discovery.sendtargets.auth.password",
+	"discovery.sendtargets.auth.username_in",
+	"discovery.sendtargets.auth.password_in",
+}
+
+// checkIscsidConfig checks the parameters of the open-iscsi configuration against the recommendations.
+func (local *Checker) checkIscsidConfig() {
+	logrus.Info("Checking iscsid configuration")
+
+	data, err := os.ReadFile(filepath.Join(local.HostRootDirectory, iscsidConfigPath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			local.collection.Log.Warn = append(local.collection.Log.Warn, fmt.Sprintf("%v is not found, the open-iscsi defaults apply", iscsidConfigPath))
+			return
+		}
+		local.collection.Log.Error = append(local.collection.Log.Error, fmt.Sprintf("Failed to read %v: %v", iscsidConfigPath, err))
+		return
+	}
+
+	auditIscsidConfig(local.collection.Log, parseIscsidConfig(string(data)))
+}
+
+// tuneIscsidConfig applies the recommendations to the open-iscsi configuration, and disables the
+// CHAP parameters. The original configuration is kept once next to it. The new values apply to the
+// sessions the Longhorn engines log in to afterwards.
+func (local *Installer) tuneIscsidConfig() error {
+	logrus.Info("Tuning iscsid configuration")
+
+	configPath := filepath.Join(local.HostRootDirectory, iscsidConfigPath)
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read %v", iscsidConfigPath)
+	}
+
+	tuned, changes := tuneIscsidConfig(string(data))
+	if len(changes) == 0 {
+		local.collection.Log.Info = append(local.collection.Log.Info, fmt.Sprintf("%v already follows the recommendations", iscsidConfigPath))
+		return nil
+	}
+
+	info, err := os.Stat(configPath)
+	if err != nil {
+		return err
+	}
+	backupPath := configPath + iscsidConfigBackupSuffix
+	if _, err := os.Stat(backupPath); os.IsNotExist(err) {
+		if err := os.WriteFile(backupPath, data, info.Mode().Perm()); err != nil {
+			return errors.Wrapf(err, "failed to back up %v", iscsidConfigPath)
+		}
+	}
+	if err := os.WriteFile(configPath, []byte(tuned), info.Mode().Perm()); err != nil {
+		return errors.Wrapf(err, "failed to write %v", iscsidConfigPath)
+	}
+
+	for _, change := range changes {
+		local.collection.Log.Info = append(local.collection.Log.Info, fmt.Sprintf("Tuned %v: %v", iscsidConfigPath, change))
+	}
+	return nil
+}
+
+// auditIscsidConfig reports the parameters deviating from the recommendations, and the CHAP
+// parameters. The credentials are not reported.
+func auditIscsidConfig(log *types.LogCollection, config map[string]string) {
+	for _, recommendation := range iscsidRecommendations {
+		value, ok := config[recommendation.Key]
+		if !ok {
+			value = recommendation.Default
+		}
+
+		if recommendation.follows(value) {
+			log.Info = append(log.Info, fmt.Sprintf("iscsid parameter %v is %v", recommendation.Key, value))
+			continue
+		}
+
+		expected := recommendation.Recommended
+		if recommendation.Minimum {
+			expected = "at least " + expected
+		}
+		log.Warn = append(log.Warn, fmt.Sprintf("iscsid parameter %v is %v instead of %v: %v", recommendation.Key, value, expected, recommendation.Reason))
+	}
+
+	for _, key := range iscsidAuthKeys {
+		value, ok := config[key]
+		if !ok || value == "" || (strings.HasSuffix(key, ".authmethod") && strings.EqualFold(value, "None")) {
+			continue
+		}
+		log.Warn = append(log.Warn, fmt.Sprintf("iscsid parameter %v is set, the Longhorn targets do not use CHAP authentication", key))
+	}
+}
+
+// follows returns whether the value follows the recommendation.
+func (recommendation *iscsidRecommendation) follows(value string) bool {
+	if !recommendation.Minimum {
+		return value == recommendation.Recommended
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return false
+	}
+	minimum, _ := strconv.Atoi(recommendation.Recommended)
+	return number >= minimum
+}
+
+// parseIscsidConfig returns the parameters of the open-iscsi configuration. The comments are
+// skipped, and the last value of a parameter wins.
+func parseIscsidConfig(data string) map[string]string {
+	config := map[string]string{}
+	for _, line := range strings.Split(data, "\n") {
+		key, value, ok := parseIscsidConfigLine(line)
+		if ok {
+			config[key] = value
+		}
+	}
+	return config
+}
+
+func parseIscsidConfigLine(line string) (string, string, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", "", false
+	}
+
+	key, value, found := strings.Cut(line, "=")
+	if !found {
+		return "", "", false
+	}
+	return strings.TrimSpace(key), strings.TrimSpace(value), true
+}
+
+// tuneIscsidConfig returns the configuration with the deviating parameters set to their
+// recommended values, and the CHAP parameters commented out, with the list of the changes. The
+// missing parameters are appended when their default deviates from the recommendation.
+func tuneIscsidConfig(data string) (string, []string) {
+	recommendations := map[string]*iscsidRecommendation{}
+	for i := range iscsidRecommendations {
+		recommendations[iscsidRecommendations[i].Key] = &iscsidRecommendations[i]
+	}
+	authKeys := map[string]bool{}
+	for _, key := range iscsidAuthKeys {
+		authKeys[key] = true
+	}
+
+	changes := []string{}
+	seen := map[string]bool{}
+	lines := strings.Split(strings.TrimSuffix(data, "\n"), "\n")
+	for i, line := range lines {
+		key, value, ok := parseIscsidConfigLine(line)
+		if !ok {
+			continue
+		}
+
+		if authKeys[key] {
+			lines[i] = "# " + line
+			changes = append(changes, fmt.Sprintf("disabled %v", key))
+			continue
+		}
+
+		recommendation, ok := recommendations[key]
+		if !ok {
+			continue
+		}
+		seen[key] = true
+		if recommendation.follows(value) {
+			continue
+		}
+		lines[i] = fmt.Sprintf("%v = %v", key, recommendation.Recommended)
+		changes = append(changes, fmt.Sprintf("set %v from %v to %v", key, value, recommendation.Recommended))
+	}
+
+	for _, recommendation := range iscsidRecommendations {
+		if seen[recommendation.Key] || recommendation.follows(recommendation.Default) {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%v = %v", recommendation.Key, recommendation.Recommended))
+		changes = append(changes, fmt.Sprintf("set %v to %v", recommendation.Key, recommendation.Recommended))
+	}
+
+	return strings.Join(lines, "\n") + "\n", changes
+}
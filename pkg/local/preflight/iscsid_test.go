@@ -0,0 +1,90 @@
+package preflight
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func TestAuditIscsidConfig(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       string
+		expectedWarn []string
+		expectedInfo []string
+	}{
+		{
+			name:         "defaults",
+			config:       "# node.startup = automatic\n",
+			expectedInfo: []string{"node.startup is manual", "replacement_timeout is 120", "queue_depth is 32", "cmds_max is 128"},
+		},
+		{
+			name:         "deviations",
+			config:       "node.startup = automatic\nnode.session.timeo.replacement_timeout = 5\nnode.session.queue_depth = 16\nnode.session.cmds_max = 1024\n",
+			expectedWarn: []string{"node.startup is automatic instead of manual", "replacement_timeout is 5 instead of 120", "queue_depth is 16 instead of at least 32"},
+			expectedInfo: []string{"cmds_max is 1024"},
+		},
+		{
+			name:         "chap remnants",
+			config:       "node.session.auth.authmethod = CHAP\nnode.session.auth.password = secret\ndiscovery.sendtargets.auth.authmethod = None\n",
+			expectedWarn: []string{"node.session.auth.authmethod is set", "node.session.auth.password is set"},
+			expectedInfo: []string{"node.startup", "replacement_timeout", "queue_depth", "cmds_max"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			log := &types.LogCollection{}
+			auditIscsidConfig(log, parseIscsidConfig(test.config))
+
+			assertMessages(t, "warn", log.Warn, test.expectedWarn)
+			assertMessages(t, "info", log.Info, test.expectedInfo)
+			for _, message := range log.Warn {
+				if strings.Contains(message, "secret") {
+					t.Errorf("expected credentials not to be reported, got %q", message)
+				}
+			}
+		})
+	}
+}
+
+func TestTuneIscsidConfig(t *testing.T) {
+	tests := []struct {
+		name            string
+		config          string
+		expectedConfig  string
+		expectedChanges int
+	}{
+		{
+			name:            "recommended",
+			config:          "node.startup = manual\nnode.session.queue_depth = 64\n",
+			expectedConfig:  "node.startup = manual\nnode.session.queue_depth = 64\n",
+			expectedChanges: 0,
+		},
+		{
+			name:            "deviations",
+			config:          "# comment\nnode.startup = automatic\nnode.session.timeo.replacement_timeout=5\n",
+			expectedConfig:  "# comment\nnode.startup = manual\nnode.session.timeo.replacement_timeout = 120\n",
+			expectedChanges: 2,
+		},
+		{
+			name:            "chap remnants",
+			config:          "node.session.auth.authmethod = CHAP\nnode.session.auth.username = user",
+			expectedConfig:  "# node.session.auth.authmethod = CHAP\n# node.session.auth.username = user\n",
+			expectedChanges: 2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config, changes := tuneIscsidConfig(test.config)
+			if config != test.expectedConfig {
+				t.Errorf("expected config %q, got %q", test.expectedConfig, config)
+			}
+			if len(changes) != test.expectedChanges {
+				t.Errorf("expected %v changes, got %v", test.expectedChanges, changes)
+			}
+		})
+	}
+}
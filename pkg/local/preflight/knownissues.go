@@ -0,0 +1,182 @@
+package preflight
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	remote "github.com/longhorn/cli/pkg/remote/preflight"
+	"github.com/longhorn/cli/pkg/types"
+)
+
+// nodeFacts are the facts of the node matched against the known issues.
+type nodeFacts struct {
+	KernelRelease   string
+	OperatingSystem string
+	Modules         map[string]string // Versions of the loaded kernel modules, empty for the modules without one.
+	NVMeControllers []nvmeController
+}
+
+// nvmeController is an NVMe controller of the node, from /sys/class/nvme.
+type nvmeController struct {
+	Name     string
+	Model    string
+	Firmware string
+}
+
+// checkKnownIssues matches the facts of the node against the known kernel, driver and firmware
+// issues, downloaded by the CLI from the rules URL or embedded in longhornctl.
+func (local *Checker) checkKnownIssues() {
+	logrus.Info("Checking known kernel, driver and firmware issues")
+
+	var (
+		issueList *types.KnownIssueList
+		err       error
+	)
+	if local.KnownIssues != "" {
+		issueList, err = remote.ParseKnownIssues([]byte(local.KnownIssues))
+	} else {
+		issueList, err = remote.GetEmbeddedKnownIssues()
+	}
+	if err != nil {
+		local.collection.Log.Warn = append(local.collection.Log.Warn, fmt.Sprintf("Failed to load known issues: %v", err))
+		return
+	}
+
+	inspectKnownIssues(local.collection.Log, issueList, local.getNodeFacts())
+}
+
+// getNodeFacts collects the facts of the node from the proc and sys directories of the host.
+func (local *Checker) getNodeFacts() *nodeFacts {
+	procDirectory := hostProcDirectory(local.HostRootDirectory)
+	sysDirectory := filepath.Join(local.HostRootDirectory, "sys")
+
+	facts := &nodeFacts{
+		OperatingSystem: local.osRelease,
+		Modules:         map[string]string{},
+	}
+
+	if kernelRelease, err := os.ReadFile(filepath.Join(procDirectory, "sys/kernel/osrelease")); err == nil {
+		facts.KernelRelease = strings.TrimSpace(string(kernelRelease))
+	}
+
+	if modulesData, err := os.ReadFile(filepath.Join(procDirectory, "modules")); err == nil {
+		for module := range parseModules(string(modulesData)) {
+			version, _ := os.ReadFile(filepath.Join(sysDirectory, "module", module, "version"))
+			facts.Modules[module] = strings.TrimSpace(string(version))
+		}
+	}
+
+	controllerPaths, _ := filepath.Glob(filepath.Join(sysDirectory, "class/nvme/nvme*"))
+	for _, controllerPath := range controllerPaths {
+		model, err := os.ReadFile(filepath.Join(controllerPath, "model"))
+		if err != nil {
+			continue
+		}
+		firmware, _ := os.ReadFile(filepath.Join(controllerPath, "firmware_rev"))
+		facts.NVMeControllers = append(facts.NVMeControllers, nvmeController{
+			Name:     filepath.Base(controllerPath),
+			Model:    strings.TrimSpace(string(model)),
+			Firmware: strings.TrimSpace(string(firmware)),
+		})
+	}
+
+	return facts
+}
+
+// inspectKnownIssues reports the known issues matching the facts of the node, at their severity.
+func inspectKnownIssues(log *types.LogCollection, issueList *types.KnownIssueList, facts *nodeFacts) {
+	matched := false
+	for _, issue := range issueList.Issues {
+		evidences, ok := matchKnownIssue(&issue.Match, facts)
+		if !ok {
+			continue
+		}
+		matched = true
+
+		message := fmt.Sprintf("Known issue %v (%v): %v", issue.ID, strings.Join(evidences, ", "), issue.Description)
+		if issue.Link != "" {
+			message = fmt.Sprintf("%v See %v", message, issue.Link)
+		}
+
+		switch issue.Severity {
+		case types.CustomCheckSeverityError:
+			log.Error = append(log.Error, message)
+		case types.CustomCheckSeverityInfo:
+			log.Info = append(log.Info, message)
+		default:
+			log.Warn = append(log.Warn, message)
+		}
+	}
+
+	if !matched {
+		log.Info = append(log.Info, fmt.Sprintf("No known issue matches the node, out of %d known issues", len(issueList.Issues)))
+	}
+}
+
+// matchKnownIssue returns whether all the non-empty regular expressions of the match match the
+// facts, with the matching facts.
+func matchKnownIssue(match *types.KnownIssueMatch, facts *nodeFacts) ([]string, bool) {
+	evidences := []string{}
+
+	if match.Kernel != "" {
+		if !regexp.MustCompile(match.Kernel).MatchString(facts.KernelRelease) {
+			return nil, false
+		}
+		evidences = append(evidences, fmt.Sprintf("kernel %v", facts.KernelRelease))
+	}
+
+	if match.OperatingSystem != "" {
+		if !regexp.MustCompile(match.OperatingSystem).MatchString(facts.OperatingSystem) {
+			return nil, false
+		}
+		evidences = append(evidences, fmt.Sprintf("operating system %v", facts.OperatingSystem))
+	}
+
+	if match.Module != "" || match.ModuleVersion != "" {
+		modules := make([]string, 0, len(facts.Modules))
+		for module := range facts.Modules {
+			modules = append(modules, module)
+		}
+		sort.Strings(modules)
+
+		found := false
+		for _, module := range modules {
+			version := facts.Modules[module]
+			if !regexp.MustCompile(match.Module).MatchString(module) || !regexp.MustCompile(match.ModuleVersion).MatchString(version) {
+				continue
+			}
+			found = true
+			if version != "" {
+				evidences = append(evidences, fmt.Sprintf("module %v %v", module, version))
+			} else {
+				evidences = append(evidences, fmt.Sprintf("module %v", module))
+			}
+			break
+		}
+		if !found {
+			return nil, false
+		}
+	}
+
+	if match.NVMeModel != "" || match.NVMeFirmware != "" {
+		found := false
+		for _, controller := range facts.NVMeControllers {
+			if !regexp.MustCompile(match.NVMeModel).MatchString(controller.Model) || !regexp.MustCompile(match.NVMeFirmware).MatchString(controller.Firmware) {
+				continue
+			}
+			found = true
+			evidences = append(evidences, fmt.Sprintf("%v %v firmware %v", controller.Name, controller.Model, controller.Firmware))
+		}
+		if !found {
+			return nil, false
+		}
+	}
+
+	return evidences, true
+}
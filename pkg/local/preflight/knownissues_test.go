@@ -0,0 +1,87 @@
+package preflight
+
+import (
+	"testing"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func TestInspectKnownIssues(t *testing.T) {
+	issueList := &types.KnownIssueList{
+		Issues: []types.KnownIssue{
+			{
+				ID:          "kernel-regression",
+				Description: "Kernel regression.",
+				Link:        "https://example.com/kernel",
+				Severity:    types.CustomCheckSeverityError,
+				Match:       types.KnownIssueMatch{Kernel: `^6\.1\.64([^0-9]|$)`},
+			},
+			{
+				ID:          "iscsi-regression",
+				Description: "iscsi_tcp regression.",
+				Match:       types.KnownIssueMatch{Module: `^iscsi_tcp$`, OperatingSystem: `^ubuntu$`},
+			},
+			{
+				ID:          "nvme-firmware",
+				Description: "NVMe firmware bug.",
+				Match:       types.KnownIssueMatch{NVMeModel: "Samsung SSD 980 PRO", NVMeFirmware: `^3B2QGXA7$`},
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		facts         *nodeFacts
+		expectedInfo  []string
+		expectedWarn  []string
+		expectedError []string
+	}{
+		"none": {
+			facts: &nodeFacts{
+				KernelRelease:   "6.1.640-generic",
+				OperatingSystem: "sles",
+				Modules:         map[string]string{"iscsi_tcp": ""},
+				NVMeControllers: []nvmeController{{Name: "nvme0", Model: "Samsung SSD 980 PRO 1TB", Firmware: "5B2QGXA7"}},
+			},
+			expectedInfo: []string{"No known issue matches the node, out of 3 known issues"},
+		},
+		"kernel": {
+			facts: &nodeFacts{KernelRelease: "6.1.64-1-amd64"},
+			expectedError: []string{
+				"Known issue kernel-regression (kernel 6.1.64-1-amd64): Kernel regression. See https://example.com/kernel",
+			},
+		},
+		"module and operating system": {
+			facts: &nodeFacts{
+				OperatingSystem: "ubuntu",
+				Modules:         map[string]string{"iscsi_tcp": "2.0-873"},
+			},
+			expectedWarn: []string{"Known issue iscsi-regression (operating system ubuntu, module iscsi_tcp 2.0-873): iscsi_tcp regression."},
+		},
+		"model and firmware of different controllers": {
+			facts: &nodeFacts{
+				NVMeControllers: []nvmeController{
+					{Name: "nvme0", Model: "Samsung SSD 980 PRO 1TB", Firmware: "5B2QGXA7"},
+					{Name: "nvme1", Model: "Samsung SSD 990 PRO 2TB", Firmware: "3B2QGXA7"},
+				},
+			},
+			expectedInfo: []string{"No known issue matches the node"},
+		},
+		"firmware": {
+			facts: &nodeFacts{
+				NVMeControllers: []nvmeController{{Name: "nvme1", Model: "Samsung SSD 980 PRO 1TB", Firmware: "3B2QGXA7"}},
+			},
+			expectedWarn: []string{"Known issue nvme-firmware (nvme1 Samsung SSD 980 PRO 1TB firmware 3B2QGXA7)"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			log := &types.LogCollection{}
+			inspectKnownIssues(log, issueList, test.facts)
+
+			assertMessages(t, "info", log.Info, test.expectedInfo)
+			assertMessages(t, "warn", log.Warn, test.expectedWarn)
+			assertMessages(t, "error", log.Error, test.expectedError)
+		})
+	}
+}
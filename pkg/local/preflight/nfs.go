@@ -0,0 +1,162 @@
+package preflight
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"github.com/sirupsen/logrus"
+
+	commontypes "github.com/longhorn/go-common-libs/types"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+// minNFSUtilsVersion is the first nfs-utils version mounting with the NFS 4.1 and 4.2 options the
+// Longhorn RWX volumes and NFS backup targets use.
+const minNFSUtilsVersion = "1.3.0"
+
+// nfsKernelConfigs are the kernel configurations of the NFS client protocol versions, in order.
+var nfsKernelConfigs = []struct {
+	version string
+	config  string
+}{
+	{version: "4.0", config: "CONFIG_NFS_V4"},
+	{version: "4.1", config: "CONFIG_NFS_V4_1"},
+	{version: "4.2", config: "CONFIG_NFS_V4_2"},
+}
+
+// nfsServices are the systemd units of the NFS client, which break the NFS mounts relying on them
+// when they fail.
+var nfsServices = []string{"rpcbind.socket", "rpcbind.service", "rpc-statd.service"}
+
+var nfsUtilsVersionRegex = regexp.MustCompile(`nfs-utils ([0-9][0-9.]*)`)
+
+// checkNFSClient checks the version of the NFS client tools, and the state of the rpcbind and statd
+// services of the host. RWX volumes and NFS backup targets otherwise fail when mounting, long after
+// the installation.
+func (local *Checker) checkNFSClient() {
+	logrus.Info("Checking NFS client version and services")
+
+	output, err := local.packageManager.Execute([]string{}, "mount.nfs", []string{"-V"}, commontypes.ExecuteDefaultTimeout)
+	if err != nil {
+		local.collection.Log.Error = append(local.collection.Log.Error, fmt.Sprintf("Failed to get the version of mount.nfs, the NFS client tools may not be installed: %v", err))
+	} else {
+		inspectNFSUtilsVersion(local.collection.Log, output)
+	}
+
+	args := append([]string{"show", "--property=Id,LoadState,ActiveState,SubState"}, nfsServices...)
+	output, err = local.packageManager.Execute([]string{}, "systemctl", args, commontypes.ExecuteDefaultTimeout)
+	if err != nil {
+		local.collection.Log.Warn = append(local.collection.Log.Warn, fmt.Sprintf("Failed to get the state of %v: %v", strings.Join(nfsServices, ", "), err))
+		return
+	}
+	inspectNFSServices(local.collection.Log, parseSystemdUnits(output))
+}
+
+// inspectNFSUtilsVersion checks the nfs-utils version in the output of mount.nfs -V, for example
+// "mount.nfs: (linux nfs-utils 2.6.1)".
+func inspectNFSUtilsVersion(log *types.LogCollection, output string) {
+	match := nfsUtilsVersionRegex.FindStringSubmatch(output)
+	if match == nil {
+		log.Warn = append(log.Warn, fmt.Sprintf("Failed to parse the nfs-utils version from %q", strings.TrimSpace(output)))
+		return
+	}
+
+	version, err := semver.ParseTolerant(strings.TrimSuffix(match[1], "."))
+	if err != nil {
+		log.Warn = append(log.Warn, fmt.Sprintf("Failed to parse nfs-utils version %v: %v", match[1], err))
+		return
+	}
+
+	if version.LT(semver.MustParse(minNFSUtilsVersion)) {
+		log.Error = append(log.Error, fmt.Sprintf("nfs-utils %v is older than %v, it cannot mount the RWX volumes and NFS backup targets with NFS 4.1 or 4.2", match[1], minNFSUtilsVersion))
+		return
+	}
+	log.Info = append(log.Info, fmt.Sprintf("nfs-utils %v is installed", match[1]))
+}
+
+// systemdUnit is the state of a systemd unit, from systemctl show.
+type systemdUnit struct {
+	ID          string
+	LoadState   string
+	ActiveState string
+	SubState    string
+}
+
+// parseSystemdUnits parses the output of systemctl show with the Id, LoadState, ActiveState and
+// SubState properties, where the units are separated by empty lines.
+func parseSystemdUnits(output string) []systemdUnit {
+	units := []systemdUnit{}
+	unit := systemdUnit{}
+	flush := func() {
+		if unit.ID != "" {
+			units = append(units, unit)
+		}
+		unit = systemdUnit{}
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			flush()
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "Id":
+			unit.ID = value
+		case "LoadState":
+			unit.LoadState = value
+		case "ActiveState":
+			unit.ActiveState = value
+		case "SubState":
+			unit.SubState = value
+		}
+	}
+	flush()
+	return units
+}
+
+// inspectNFSServices checks none of the NFS client services failed. The services not installed are
+// skipped, since the NFS 4 mounts do not need them.
+func inspectNFSServices(log *types.LogCollection, units []systemdUnit) {
+	for _, unit := range units {
+		switch {
+		case unit.LoadState == "not-found":
+			continue
+		case unit.ActiveState == "failed":
+			log.Warn = append(log.Warn, fmt.Sprintf("%v is in failed state (%v), the NFS mounts relying on it fail. Check it with: systemctl status %v", unit.ID, unit.SubState, unit.ID))
+		default:
+			log.Info = append(log.Info, fmt.Sprintf("%v is %v (%v)", unit.ID, unit.ActiveState, unit.SubState))
+		}
+	}
+}
+
+// getKernelNFSVersions returns the NFS 4 protocol versions the kernel supports, from its
+// configuration. With NFS 4 built as a module, the versions are only supported once the module is
+// loaded.
+func getKernelNFSVersions(kernelConfig map[string]string, moduleLoaded bool) []string {
+	switch kernelConfig[nfsKernelConfigs[0].config] {
+	case "y":
+	case "m":
+		if !moduleLoaded {
+			return nil
+		}
+	default:
+		return nil
+	}
+
+	versions := []string{}
+	for _, nfsKernelConfig := range nfsKernelConfigs {
+		if value := kernelConfig[nfsKernelConfig.config]; value == "y" || value == "m" {
+			versions = append(versions, nfsKernelConfig.version)
+		}
+	}
+	return versions
+}
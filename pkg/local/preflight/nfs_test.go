@@ -0,0 +1,103 @@
+package preflight
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func TestInspectNFSUtilsVersion(t *testing.T) {
+	tests := map[string]struct {
+		output        string
+		expectedInfo  []string
+		expectedWarn  []string
+		expectedError []string
+	}{
+		"recent": {
+			output:       "mount.nfs: (linux nfs-utils 2.6.1)\n",
+			expectedInfo: []string{"nfs-utils 2.6.1 is installed"},
+		},
+		"minimum": {
+			output:       "mount.nfs: (linux nfs-utils 1.3.0)\n",
+			expectedInfo: []string{"nfs-utils 1.3.0 is installed"},
+		},
+		"too old": {
+			output:        "mount.nfs: (linux nfs-utils 1.2.8)\n",
+			expectedError: []string{"nfs-utils 1.2.8 is older than 1.3.0"},
+		},
+		"two components": {
+			output:       "mount.nfs: (linux nfs-utils 2.5)\n",
+			expectedInfo: []string{"nfs-utils 2.5 is installed"},
+		},
+		"unknown output": {
+			output:       "mount.nfs: unknown\n",
+			expectedWarn: []string{"Failed to parse the nfs-utils version"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			log := &types.LogCollection{}
+			inspectNFSUtilsVersion(log, test.output)
+
+			assertMessages(t, "info", log.Info, test.expectedInfo)
+			assertMessages(t, "warn", log.Warn, test.expectedWarn)
+			assertMessages(t, "error", log.Error, test.expectedError)
+		})
+	}
+}
+
+func TestInspectNFSServices(t *testing.T) {
+	output := "Id=rpcbind.socket\nLoadState=loaded\nActiveState=active\nSubState=listening\n\n" +
+		"Id=rpcbind.service\nLoadState=loaded\nActiveState=failed\nSubState=failed\n\n" +
+		"Id=rpc-statd.service\nLoadState=not-found\nActiveState=inactive\nSubState=dead\n"
+
+	units := parseSystemdUnits(output)
+	if len(units) != 3 {
+		t.Fatalf("expected 3 units, got %v", units)
+	}
+
+	log := &types.LogCollection{}
+	inspectNFSServices(log, units)
+
+	assertMessages(t, "info", log.Info, []string{"rpcbind.socket is active (listening)"})
+	assertMessages(t, "warn", log.Warn, []string{"rpcbind.service is in failed state (failed)"})
+}
+
+func TestGetKernelNFSVersions(t *testing.T) {
+	tests := map[string]struct {
+		kernelConfig map[string]string
+		moduleLoaded bool
+		expected     []string
+	}{
+		"built in": {
+			kernelConfig: map[string]string{"CONFIG_NFS_V4": "y", "CONFIG_NFS_V4_1": "y", "CONFIG_NFS_V4_2": "y"},
+			expected:     []string{"4.0", "4.1", "4.2"},
+		},
+		"module loaded": {
+			kernelConfig: map[string]string{"CONFIG_NFS_V4": "m", "CONFIG_NFS_V4_1": "y"},
+			moduleLoaded: true,
+			expected:     []string{"4.0", "4.1"},
+		},
+		"module not loaded": {
+			kernelConfig: map[string]string{"CONFIG_NFS_V4": "m", "CONFIG_NFS_V4_1": "y", "CONFIG_NFS_V4_2": "y"},
+		},
+		"not configured": {
+			kernelConfig: map[string]string{"CONFIG_NFS_FS": "y"},
+			moduleLoaded: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			versions := getKernelNFSVersions(test.kernelConfig, test.moduleLoaded)
+			if len(versions) == 0 && len(test.expected) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(versions, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, versions)
+			}
+		})
+	}
+}
@@ -33,6 +33,11 @@ func (c *AptPackageManager) InstallPackage(name string) (string, error) {
 	return c.executor.Execute([]string{}, "apt", []string{"install", name, "-y"}, commontypes.ExecuteNoTimeout)
 }
 
+// InstallPackageFiles executes the installation command of the local package files
+func (c *AptPackageManager) InstallPackageFiles(paths []string) (string, error) {
+	return c.executor.Execute([]string{}, "apt", append([]string{"install", "-y"}, paths...), commontypes.ExecuteNoTimeout)
+}
+
 // UninstallPackage executes the uninstallation command
 func (c *AptPackageManager) UninstallPackage(name string) (string, error) {
 	return c.executor.Execute([]string{}, "apt", []string{"remove", name, "-y"}, commontypes.ExecuteNoTimeout)
@@ -26,6 +26,7 @@ type PackageManager interface {
 	UpdatePackageList() (string, error)
 	StartPackageSession() (string, error)
 	InstallPackage(name string) (string, error)
+	InstallPackageFiles(paths []string) (string, error)
 	UninstallPackage(name string) (string, error)
 	Modprobe(module string) (string, error)
 	CheckModLoaded(module string) error
@@ -32,6 +32,11 @@ func (c *PacmanPackageManager) InstallPackage(name string) (string, error) {
 	return c.executor.Execute([]string{}, "pacman", []string{"-S", "--noconfirm", name}, commontypes.ExecuteNoTimeout)
 }
 
+// InstallPackageFiles executes the installation command of the local package files
+func (c *PacmanPackageManager) InstallPackageFiles(paths []string) (string, error) {
+	return c.executor.Execute([]string{}, "pacman", append([]string{"-U", "--noconfirm"}, paths...), commontypes.ExecuteNoTimeout)
+}
+
 // UninstallPackage executes the uninstallation command
 func (c *PacmanPackageManager) UninstallPackage(name string) (string, error) {
 	return c.executor.Execute([]string{}, "pacman", []string{"-R", "--noconfirm", name}, commontypes.ExecuteNoTimeout)
@@ -0,0 +1,71 @@
+package packagemanager
+
+import (
+	"fmt"
+)
+
+// Requirements are the packages, kernel modules and services the preflight install sets up on
+// the nodes using a package manager.
+type Requirements struct {
+	Packages        []string
+	Modules         []string
+	Services        []string
+	SpdkDepPackages []string
+	SpdkDepModules  []string
+}
+
+// GetRequirements returns the requirements of the package manager. The kernel release selects the
+// kernel module packages of SPDK, and is left empty when the kernel of the nodes is unknown, such
+// as when generating an air-gap bundle.
+func GetRequirements(pkgMgrType PackageManagerType, kernelRelease string) (*Requirements, error) {
+	spdkDepModules := []string{
+		"nvme_tcp",
+		"uio_pci_generic",
+		"vfio_pci",
+	}
+
+	switch pkgMgrType {
+	case PackageManagerApt:
+		requirements := &Requirements{
+			Packages:        []string{"nfs-common", "open-iscsi", "cryptsetup"},
+			Modules:         []string{"nfs", "dm_crypt"},
+			Services:        []string{"iscsid"},
+			SpdkDepPackages: []string{},
+			SpdkDepModules:  spdkDepModules,
+		}
+		if kernelRelease != "" {
+			requirements.SpdkDepPackages = append(requirements.SpdkDepPackages, "linux-modules-extra-"+kernelRelease)
+		}
+		return requirements, nil
+
+	case PackageManagerYum:
+		return &Requirements{
+			Packages:        []string{"nfs-utils", "iscsi-initiator-utils", "cryptsetup"},
+			Modules:         []string{"nfs", "iscsi_tcp", "dm_crypt"},
+			Services:        []string{"iscsid"},
+			SpdkDepPackages: []string{},
+			SpdkDepModules:  spdkDepModules,
+		}, nil
+
+	case PackageManagerZypper, PackageManagerTransactionalUpdate:
+		return &Requirements{
+			Packages:        []string{"nfs-client", "open-iscsi", "cryptsetup"},
+			Modules:         []string{"nfs", "iscsi_tcp", "dm_crypt"},
+			Services:        []string{"iscsid"},
+			SpdkDepPackages: []string{},
+			SpdkDepModules:  spdkDepModules,
+		}, nil
+
+	case PackageManagerPacman:
+		return &Requirements{
+			Packages:        []string{"nfs-utils", "open-iscsi", "cryptsetup"},
+			Modules:         []string{"nfs", "iscsi_tcp", "dm_crypt"},
+			Services:        []string{"iscsid"},
+			SpdkDepPackages: []string{},
+			SpdkDepModules:  spdkDepModules,
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown package manager type: %s", pkgMgrType)
+	}
+}
@@ -37,6 +37,11 @@ func (c *TransactionalUpdatePackageManager) InstallPackage(name string) (string,
 	return c.executor.Execute([]string{}, packageCommand, []string{"--continue", "--non-interactive", "pkg", "install", name}, commontypes.ExecuteNoTimeout)
 }
 
+// InstallPackageFiles executes the installation command of the local package files
+func (c *TransactionalUpdatePackageManager) InstallPackageFiles(paths []string) (string, error) {
+	return c.executor.Execute([]string{}, packageCommand, append([]string{"--continue", "--non-interactive", "pkg", "install", "--allow-unsigned-rpm"}, paths...), commontypes.ExecuteNoTimeout)
+}
+
 // UninstallPackage executes the uninstallation command
 func (c *TransactionalUpdatePackageManager) UninstallPackage(name string) (string, error) {
 	return c.executor.Execute([]string{}, packageCommand, []string{"--continue", "--non-interactive", "pkg", "remove", name}, commontypes.ExecuteNoTimeout)
@@ -32,6 +32,11 @@ func (c *YumPackageManager) InstallPackage(name string) (string, error) {
 	return c.executor.Execute([]string{}, "yum", []string{"install", name, "-y"}, commontypes.ExecuteNoTimeout)
 }
 
+// InstallPackageFiles executes the installation command of the local package files
+func (c *YumPackageManager) InstallPackageFiles(paths []string) (string, error) {
+	return c.executor.Execute([]string{}, "yum", append([]string{"install", "-y", "--disablerepo=*"}, paths...), commontypes.ExecuteNoTimeout)
+}
+
 // UninstallPackage executes the uninstallation command
 func (c *YumPackageManager) UninstallPackage(name string) (string, error) {
 	return c.executor.Execute([]string{}, "yum", []string{"remove", name, "-y"}, commontypes.ExecuteNoTimeout)
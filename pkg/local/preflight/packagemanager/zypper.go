@@ -32,6 +32,11 @@ func (c *ZypperPackageManager) InstallPackage(name string) (string, error) {
 	return c.executor.Execute([]string{}, "zypper", []string{"--non-interactive", "install", name}, commontypes.ExecuteNoTimeout)
 }
 
+// InstallPackageFiles executes the installation command of the local package files
+func (c *ZypperPackageManager) InstallPackageFiles(paths []string) (string, error) {
+	return c.executor.Execute([]string{}, "zypper", append([]string{"--non-interactive", "--no-refresh", "install", "--allow-unsigned-rpm"}, paths...), commontypes.ExecuteNoTimeout)
+}
+
 // UninstallPackage executes the uninstallation command
 func (c *ZypperPackageManager) UninstallPackage(name string) (string, error) {
 	return c.executor.Execute([]string{}, "zypper", []string{"--non-interactive", "remove", name}, commontypes.ExecuteNoTimeout)
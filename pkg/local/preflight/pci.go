@@ -0,0 +1,231 @@
+package preflight
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+
+	remote "github.com/longhorn/cli/pkg/remote/preflight"
+)
+
+const (
+	pciClassNvme   = "0x010802"
+	pciClassBridge = "0x0604" // Prefix of the PCI bridge classes, which do not need to be bound in an IOMMU group.
+
+	driverVfioPci       = "vfio-pci"
+	driverUioPciGeneric = "uio_pci_generic"
+)
+
+// PciBindingsChecker provide functions for inspecting the driver bindings of the NVMe PCI devices.
+type PciBindingsChecker struct {
+	remote.PciBindingsCheckerCmdOptions
+
+	logger *logrus.Entry
+
+	OutputFilePath string
+
+	// HostRootDirectory is the directory of the host root filesystem.
+	// It is "/" when running directly on the host instead of in a DaemonSet pod.
+	HostRootDirectory string
+
+	collection types.NodeCollection
+}
+
+// pciDevice is a PCI device read from sysfs.
+type pciDevice struct {
+	Address    string
+	Class      string
+	Vendor     string
+	Device     string
+	Driver     string // Empty when the device is not bound to a driver.
+	IommuGroup string // Empty when IOMMU is disabled.
+}
+
+// Init initializes the PciBindingsChecker.
+func (local *PciBindingsChecker) Init() error {
+	local.collection.Log = &types.LogCollection{}
+	local.logger = logrus.WithField("component", "pci-bindings")
+
+	if local.HostRootDirectory == "" {
+		local.HostRootDirectory = consts.VolumeMountHostDirectory
+	}
+
+	return nil
+}
+
+// Run inspects the NVMe PCI devices against the intended userspace driver.
+func (local *PciBindingsChecker) Run() error {
+	sysDirectory := filepath.Join(local.HostRootDirectory, "sys")
+
+	devices, err := readPciDevices(sysDirectory)
+	if err != nil {
+		return err
+	}
+
+	inspectPciBindings(local.collection.Log, devices, getIntendedDriver(sysDirectory, local.DriverOverride), parseAllowedPci(local.AllowPci))
+	return nil
+}
+
+// Output converts the collection to JSON and output to stdout or the output file.
+func (local *PciBindingsChecker) Output() error {
+	local.logger.Trace("Outputting PCI bindings results")
+
+	jsonBytes, err := json.Marshal(local.collection)
+	if err != nil {
+		return errors.Wrap(err, "failed to convert collection to JSON")
+	}
+
+	return utils.HandleResult(jsonBytes, local.OutputFilePath, local.logger)
+}
+
+// getIntendedDriver returns the userspace driver the devices are bound to for SPDK. Like the
+// SPDK setup script, it defaults to vfio-pci when IOMMU is enabled, and uio_pci_generic otherwise.
+func getIntendedDriver(sysDirectory, driverOverride string) string {
+	if driverOverride != "" {
+		return driverOverride
+	}
+
+	groups, err := os.ReadDir(filepath.Join(sysDirectory, "kernel/iommu_groups"))
+	if err == nil && len(groups) > 0 {
+		return driverVfioPci
+	}
+	return driverUioPciGeneric
+}
+
+// parseAllowedPci returns the PCI addresses of the comma-separated list. The SPDK "none"
+// placeholder, which blocks all devices, results in no address.
+func parseAllowedPci(allowPci string) []string {
+	addresses := []string{}
+	for _, address := range strings.Split(allowPci, consts.CmdOptSeperator) {
+		address = strings.TrimSpace(address)
+		if address == "" || address == "none" {
+			continue
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses
+}
+
+// readPciDevices returns the PCI devices in sysfs, sorted by address.
+func readPciDevices(sysDirectory string) ([]pciDevice, error) {
+	devicesDirectory := filepath.Join(sysDirectory, "bus/pci/devices")
+	entries, err := os.ReadDir(devicesDirectory)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read PCI devices in %v", devicesDirectory)
+	}
+
+	readValue := func(deviceDirectory, name string) string {
+		data, err := os.ReadFile(filepath.Join(deviceDirectory, name))
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(string(data))
+	}
+
+	readLinkBase := func(deviceDirectory, name string) string {
+		target, err := os.Readlink(filepath.Join(deviceDirectory, name))
+		if err != nil {
+			return ""
+		}
+		return filepath.Base(target)
+	}
+
+	devices := []pciDevice{}
+	for _, entry := range entries {
+		deviceDirectory := filepath.Join(devicesDirectory, entry.Name())
+		devices = append(devices, pciDevice{
+			Address:    entry.Name(),
+			Class:      readValue(deviceDirectory, "class"),
+			Vendor:     strings.TrimPrefix(readValue(deviceDirectory, "vendor"), "0x"),
+			Device:     strings.TrimPrefix(readValue(deviceDirectory, "device"), "0x"),
+			Driver:     readLinkBase(deviceDirectory, "driver"),
+			IommuGroup: readLinkBase(deviceDirectory, "iommu_group"),
+		})
+	}
+
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].Address < devices[j].Address
+	})
+	return devices, nil
+}
+
+// inspectPciBindings reports the driver and IOMMU group of each NVMe device. The devices allowed
+// for SPDK must be bound to the intended userspace driver, and with vfio-pci, every other device
+// in their IOMMU group must be bound to vfio-pci or to no driver.
+func inspectPciBindings(log *types.LogCollection, devices []pciDevice, intendedDriver string, allowedAddresses []string) {
+	groupDevices := map[string][]pciDevice{}
+	for _, device := range devices {
+		if device.IommuGroup != "" {
+			groupDevices[device.IommuGroup] = append(groupDevices[device.IommuGroup], device)
+		}
+	}
+
+	foundAddresses := map[string]bool{}
+	for _, device := range devices {
+		if device.Class != pciClassNvme {
+			continue
+		}
+		foundAddresses[device.Address] = true
+
+		driver := device.Driver
+		if driver == "" {
+			driver = "no driver"
+		}
+		iommuGroup := device.IommuGroup
+		if iommuGroup == "" {
+			iommuGroup = "none"
+		}
+		log.Info = append(log.Info, fmt.Sprintf("NVMe device %s (%s:%s) is bound to %s, IOMMU group %s", device.Address, device.Vendor, device.Device, driver, iommuGroup))
+
+		if !slices.Contains(allowedAddresses, device.Address) {
+			continue
+		}
+
+		if device.Driver != intendedDriver {
+			log.Warn = append(log.Warn, fmt.Sprintf("NVMe device %s is bound to %s instead of the userspace driver %s", device.Address, driver, intendedDriver))
+		}
+
+		if intendedDriver != driverVfioPci {
+			continue
+		}
+
+		if device.IommuGroup == "" {
+			log.Error = append(log.Error, fmt.Sprintf("NVMe device %s has no IOMMU group, enable IOMMU (intel_iommu=on or amd_iommu=on) to use %s", device.Address, driverVfioPci))
+			continue
+		}
+
+		var conflicts []string
+		for _, other := range groupDevices[device.IommuGroup] {
+			if other.Address == device.Address || strings.HasPrefix(other.Class, pciClassBridge) {
+				continue
+			}
+			if other.Driver != "" && other.Driver != driverVfioPci {
+				conflicts = append(conflicts, fmt.Sprintf("%s (%s)", other.Address, other.Driver))
+			}
+		}
+		if len(conflicts) > 0 {
+			log.Error = append(log.Error, fmt.Sprintf("IOMMU group %s of NVMe device %s has devices not bound to %s: %s", device.IommuGroup, device.Address, driverVfioPci, strings.Join(conflicts, ", ")))
+		}
+	}
+
+	for _, address := range allowedAddresses {
+		if !foundAddresses[address] {
+			log.Warn = append(log.Warn, fmt.Sprintf("PCI device %s in --%s is not an NVMe device on the node", address, consts.CmdOptAllowPci))
+		}
+	}
+
+	if len(foundAddresses) == 0 {
+		log.Info = append(log.Info, "No NVMe PCI devices found")
+	}
+}
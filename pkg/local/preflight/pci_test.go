@@ -0,0 +1,152 @@
+package preflight
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func TestReadPciDevices(t *testing.T) {
+	sysDirectory := t.TempDir()
+
+	writeDevice := func(address, class, driver, iommuGroup string) {
+		deviceDirectory := filepath.Join(sysDirectory, "bus/pci/devices", address)
+		if err := os.MkdirAll(deviceDirectory, 0755); err != nil {
+			t.Fatal(err)
+		}
+		for name, value := range map[string]string{"class": class, "vendor": "0x8086", "device": "0x0a54"} {
+			if err := os.WriteFile(filepath.Join(deviceDirectory, name), []byte(value+"\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if driver != "" {
+			if err := os.Symlink("../../../bus/pci/drivers/"+driver, filepath.Join(deviceDirectory, "driver")); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if iommuGroup != "" {
+			if err := os.Symlink("../../../kernel/iommu_groups/"+iommuGroup, filepath.Join(deviceDirectory, "iommu_group")); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	writeDevice("0000:02:00.0", pciClassNvme, "", "")
+	writeDevice("0000:01:00.0", pciClassNvme, "nvme", "12")
+
+	devices, err := readPciDevices(sysDirectory)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []pciDevice{
+		{Address: "0000:01:00.0", Class: pciClassNvme, Vendor: "8086", Device: "0a54", Driver: "nvme", IommuGroup: "12"},
+		{Address: "0000:02:00.0", Class: pciClassNvme, Vendor: "8086", Device: "0a54"},
+	}
+	if !reflect.DeepEqual(devices, expected) {
+		t.Errorf("expected %+v, got %+v", expected, devices)
+	}
+}
+
+func TestGetIntendedDriver(t *testing.T) {
+	sysDirectory := t.TempDir()
+
+	if driver := getIntendedDriver(sysDirectory, ""); driver != driverUioPciGeneric {
+		t.Errorf("expected %v without IOMMU groups, got %v", driverUioPciGeneric, driver)
+	}
+
+	if err := os.MkdirAll(filepath.Join(sysDirectory, "kernel/iommu_groups/0"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if driver := getIntendedDriver(sysDirectory, ""); driver != driverVfioPci {
+		t.Errorf("expected %v with IOMMU groups, got %v", driverVfioPci, driver)
+	}
+
+	if driver := getIntendedDriver(sysDirectory, driverUioPciGeneric); driver != driverUioPciGeneric {
+		t.Errorf("expected override %v, got %v", driverUioPciGeneric, driver)
+	}
+}
+
+func TestParseAllowedPci(t *testing.T) {
+	tests := map[string][]string{
+		"":                           {},
+		"none":                       {},
+		"0000:01:00.0":               {"0000:01:00.0"},
+		"0000:01:00.0, 0000:02:00.0": {"0000:01:00.0", "0000:02:00.0"},
+	}
+
+	for allowPci, expected := range tests {
+		if addresses := parseAllowedPci(allowPci); !reflect.DeepEqual(addresses, expected) {
+			t.Errorf("parseAllowedPci(%q) = %v, expected %v", allowPci, addresses, expected)
+		}
+	}
+}
+
+func TestInspectPciBindings(t *testing.T) {
+	devices := []pciDevice{
+		{Address: "0000:00:01.0", Class: "0x060400", Driver: "pcieport", IommuGroup: "1"},
+		{Address: "0000:01:00.0", Class: pciClassNvme, Driver: "vfio-pci", IommuGroup: "1"},
+		{Address: "0000:02:00.0", Class: pciClassNvme, Driver: "nvme", IommuGroup: "2"},
+		{Address: "0000:02:00.1", Class: "0x020000", Driver: "ixgbe", IommuGroup: "2"},
+		{Address: "0000:03:00.0", Class: pciClassNvme, Driver: "nvme", IommuGroup: "3"},
+	}
+
+	tests := []struct {
+		name           string
+		intendedDriver string
+		allowed        []string
+		expectedWarn   []string
+		expectedError  []string
+	}{
+		{
+			name:           "bound device with a bridge in the group",
+			intendedDriver: driverVfioPci,
+			allowed:        []string{"0000:01:00.0"},
+		},
+		{
+			name:           "kernel driver and conflicting group",
+			intendedDriver: driverVfioPci,
+			allowed:        []string{"0000:02:00.0"},
+			expectedWarn:   []string{"bound to nvme instead"},
+			expectedError:  []string{"IOMMU group 2"},
+		},
+		{
+			name:           "unknown device",
+			intendedDriver: driverUioPciGeneric,
+			allowed:        []string{"0000:09:00.0"},
+			expectedWarn:   []string{"0000:09:00.0"},
+		},
+		{
+			name:           "no allowed devices",
+			intendedDriver: driverVfioPci,
+		},
+	}
+
+	for _, test := range tests {
+		log := &types.LogCollection{}
+		inspectPciBindings(log, devices, test.intendedDriver, test.allowed)
+
+		if len(log.Info) != 3 {
+			t.Errorf("%s: expected an info message per NVMe device, got %v", test.name, log.Info)
+		}
+		assertMessages(t, test.name+" warn", log.Warn, test.expectedWarn)
+		assertMessages(t, test.name+" error", log.Error, test.expectedError)
+	}
+}
+
+func assertMessages(t *testing.T, name string, messages, expectedSubstrings []string) {
+	if len(messages) != len(expectedSubstrings) {
+		t.Errorf("%s: expected %d messages, got %v", name, len(expectedSubstrings), messages)
+		return
+	}
+	for i, substring := range expectedSubstrings {
+		if !strings.Contains(messages[i], substring) {
+			t.Errorf("%s: expected %q to contain %q", name, messages[i], substring)
+		}
+	}
+}
@@ -0,0 +1,322 @@
+package preflight
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	sigsyaml "sigs.k8s.io/yaml"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+)
+
+// Names of the checks a profile can skip.
+const (
+	checkNameIscsidConfig = "iscsid configuration"
+	checkNameKubeDNS      = "Kube DNS replicas"
+)
+
+// preflightProfile holds the checks specific to a managed platform or Kubernetes distribution.
+type preflightProfile struct {
+	skippedChecks map[string]string // Reasons of the checks that do not apply, keyed by the check name.
+	check         func(local *Checker)
+}
+
+// preflightProfiles are the profiles of the managed platforms and Kubernetes distributions.
+var preflightProfiles = map[string]preflightProfile{
+	consts.PreflightProfileGKE: {
+		skippedChecks: map[string]string{
+			checkNameKubeDNS:      "GKE scales kube-dns with its autoscaler",
+			checkNameIscsidConfig: "GKE manages the iscsid configuration of its node images, and resets it on node upgrades",
+		},
+		check: (*Checker).checkGKE,
+	},
+	consts.PreflightProfileEKS: {
+		skippedChecks: map[string]string{
+			checkNameKubeDNS: "EKS manages the CoreDNS replicas with its add-on",
+		},
+		check: (*Checker).checkEKS,
+	},
+	consts.PreflightProfileAKS: {
+		skippedChecks: map[string]string{
+			checkNameKubeDNS: "AKS scales CoreDNS with its autoscaler",
+		},
+		check: (*Checker).checkAKS,
+	},
+	consts.PreflightProfileK3s: {
+		skippedChecks: map[string]string{
+			checkNameKubeDNS: "K3s redeploys CoreDNS from its packaged manifest, which resets the replicas",
+		},
+	},
+	consts.PreflightProfileRKE2: {
+		skippedChecks: map[string]string{
+			checkNameKubeDNS: "RKE2 scales CoreDNS with its autoscaler",
+		},
+	},
+}
+
+// isCheckSkipped returns whether the profile skips the check, and reports the reason.
+func (local *Checker) isCheckSkipped(name string) bool {
+	reason, ok := preflightProfiles[local.Profile].skippedChecks[name]
+	if !ok {
+		return false
+	}
+
+	local.collection.Log.Info = append(local.collection.Log.Info, fmt.Sprintf("Skipped the %v check for the %v profile: %v", name, local.Profile, reason))
+	return true
+}
+
+// runProfileChecks runs the checks specific to the profile.
+func (local *Checker) runProfileChecks() {
+	profile, ok := preflightProfiles[local.Profile]
+	if !ok || profile.check == nil {
+		return
+	}
+
+	logrus.Infof("Checking preflight for the %v profile", local.Profile)
+	profile.check(local)
+}
+
+// checkGKE checks the Longhorn data paths are not on noexec mounts, as the stateful partition of
+// Container-Optimized OS is. The Longhorn node agent remounts them with exec.
+func (local *Checker) checkGKE() {
+	mounts, err := readHostMounts(hostProcDirectory(local.HostRootDirectory))
+	if err != nil {
+		local.collection.Log.Error = append(local.collection.Log.Error, fmt.Sprintf("Failed to read mounts of the host: %v", err))
+		return
+	}
+
+	for _, dataDirectory := range local.LonghornDataDirectories {
+		inspectDataDirectoryExec(local.collection.Log, mounts, dataDirectory)
+	}
+}
+
+// checkEKS checks the running kernel of the EKS AMI ships the kernel modules Longhorn loads.
+func (local *Checker) checkEKS() {
+	procDirectory := hostProcDirectory(local.HostRootDirectory)
+	kernelRelease, err := os.ReadFile(filepath.Join(procDirectory, "sys/kernel/osrelease"))
+	if err != nil {
+		local.collection.Log.Error = append(local.collection.Log.Error, fmt.Sprintf("Failed to read the kernel release: %v", err))
+		return
+	}
+	release := strings.TrimSpace(string(kernelRelease))
+
+	modules, err := readKernelModules(local.HostRootDirectory, release)
+	if err != nil {
+		local.collection.Log.Error = append(local.collection.Log.Error, fmt.Sprintf("Failed to read the kernel modules of kernel %v: %v", release, err))
+		return
+	}
+
+	inspectKernelModules(local.collection.Log, modules, release, []string{"iscsi_tcp", "dm_crypt", "nfs"})
+}
+
+// checkAKS checks the Longhorn data paths are on neither the OS disk nor the temporary disk.
+// AKS reimages ephemeral OS disks on node image upgrades, and the temporary disk mounted on /mnt
+// is wiped when the VM is deallocated.
+func (local *Checker) checkAKS() {
+	mounts, err := readHostMounts(hostProcDirectory(local.HostRootDirectory))
+	if err != nil {
+		local.collection.Log.Error = append(local.collection.Log.Error, fmt.Sprintf("Failed to read mounts of the host: %v", err))
+		return
+	}
+
+	for _, dataDirectory := range local.LonghornDataDirectories {
+		inspectAKSDataDirectory(local.collection.Log, mounts, dataDirectory)
+	}
+}
+
+// getProfileKubeletRootDirectory returns the kubelet root directory configured for K3s and RKE2,
+// through the --kubelet-arg flag of the server or agent process, or the kubelet-arg of the
+// configuration file. It returns an empty string when it is not configured.
+func (local *Checker) getProfileKubeletRootDirectory() string {
+	var distribution string
+	switch local.Profile {
+	case consts.PreflightProfileK3s:
+		distribution = "k3s"
+	case consts.PreflightProfileRKE2:
+		distribution = "rke2"
+	default:
+		return ""
+	}
+
+	cmdlinePaths, _ := filepath.Glob(filepath.Join(hostProcDirectory(local.HostRootDirectory), "[0-9]*/cmdline"))
+	for _, cmdlinePath := range cmdlinePaths {
+		cmdline, err := os.ReadFile(cmdlinePath)
+		if err != nil || len(cmdline) == 0 {
+			continue
+		}
+
+		args := strings.Split(strings.TrimRight(string(cmdline), "\x00"), "\x00")
+		if filepath.Base(args[0]) != distribution {
+			continue
+		}
+		if rootDirectory := getKubeletRootDirectory(getKubeletArgs(args[1:])); rootDirectory != "" {
+			return rootDirectory
+		}
+	}
+
+	configPath := filepath.Join(local.HostRootDirectory, "etc/rancher", distribution, "config.yaml")
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return ""
+	}
+	return getKubeletRootDirectory(parseDistributionKubeletArgs(data))
+}
+
+// getKubeletArgs returns the kubelet flags passed with --kubelet-arg to K3s or RKE2.
+func getKubeletArgs(args []string) []string {
+	kubeletArgs := []string{}
+	for i, arg := range args {
+		value, ok := strings.CutPrefix(arg, "--kubelet-arg=")
+		if !ok {
+			if arg != "--kubelet-arg" || i+1 >= len(args) {
+				continue
+			}
+			value = args[i+1]
+		}
+		kubeletArgs = append(kubeletArgs, "--"+strings.TrimPrefix(value, "--"))
+	}
+	return kubeletArgs
+}
+
+// parseDistributionKubeletArgs returns the kubelet flags of the kubelet-arg of the K3s or RKE2
+// configuration file, which is either a string or a list of strings.
+func parseDistributionKubeletArgs(data []byte) []string {
+	config := struct {
+		KubeletArg any `json:"kubelet-arg"`
+	}{}
+	if err := sigsyaml.Unmarshal(data, &config); err != nil {
+		return []string{}
+	}
+
+	values := []string{}
+	switch kubeletArg := config.KubeletArg.(type) {
+	case string:
+		values = append(values, kubeletArg)
+	case []any:
+		for _, value := range kubeletArg {
+			if value, ok := value.(string); ok {
+				values = append(values, value)
+			}
+		}
+	}
+
+	kubeletArgs := []string{}
+	for _, value := range values {
+		kubeletArgs = append(kubeletArgs, "--"+strings.TrimPrefix(value, "--"))
+	}
+	return kubeletArgs
+}
+
+// readHostMounts returns the mounts of the host with their options.
+func readHostMounts(procDirectory string) ([]mountInfo, error) {
+	data, err := os.ReadFile(filepath.Join(procDirectory, "1/mountinfo"))
+	if err != nil {
+		return nil, err
+	}
+	return parseMountInfo(string(data)), nil
+}
+
+// inspectDataDirectoryExec checks the data directory is not on a noexec mount.
+func inspectDataDirectoryExec(log *types.LogCollection, mounts []mountInfo, dataDirectory string) {
+	mount := findContainingMount(mounts, dataDirectory)
+	if mount == nil {
+		log.Error = append(log.Error, fmt.Sprintf("No mount contains %v", dataDirectory))
+		return
+	}
+
+	for _, option := range mount.Options {
+		if option == "noexec" {
+			log.Error = append(log.Error, fmt.Sprintf("%v is on noexec mount %v, the Longhorn engines cannot run from it. Deploy the Longhorn node agent with longhornctl install preflight --operating-system=%v on Container-Optimized OS", dataDirectory, mount.MountPoint, consts.OperatingSystemContainerOptimizedOS))
+			return
+		}
+	}
+	log.Info = append(log.Info, fmt.Sprintf("%v is on exec mount %v", dataDirectory, mount.MountPoint))
+}
+
+// inspectAKSDataDirectory checks the data directory is on a dedicated data disk.
+func inspectAKSDataDirectory(log *types.LogCollection, mounts []mountInfo, dataDirectory string) {
+	mount := findContainingMount(mounts, dataDirectory)
+	if mount == nil {
+		log.Error = append(log.Error, fmt.Sprintf("No mount contains %v", dataDirectory))
+		return
+	}
+
+	switch {
+	case mount.MountPoint == "/":
+		log.Warn = append(log.Warn, fmt.Sprintf("%v is on the OS disk. Node image upgrades and reimages wipe ephemeral OS disks, and the replicas on them with it. Use a dedicated data disk", dataDirectory))
+	case mount.MountPoint == "/mnt" || strings.HasPrefix(mount.MountPoint, "/mnt/"):
+		log.Warn = append(log.Warn, fmt.Sprintf("%v is on the temporary disk mounted on %v, which is wiped when the VM is deallocated. Use a dedicated data disk", dataDirectory, mount.MountPoint))
+	default:
+		log.Info = append(log.Info, fmt.Sprintf("%v is on data disk mount %v", dataDirectory, mount.MountPoint))
+	}
+}
+
+// readKernelModules returns the names of the loadable and built-in kernel modules of the kernel
+// release, read from modules.dep and modules.builtin.
+func readKernelModules(hostRootDirectory, release string) (map[string]bool, error) {
+	var modulesDirectory string
+	for _, directory := range []string{"lib/modules", "usr/lib/modules"} {
+		path := filepath.Join(hostRootDirectory, directory, release)
+		if _, err := os.Stat(path); err == nil {
+			modulesDirectory = path
+			break
+		}
+	}
+	if modulesDirectory == "" {
+		return nil, errors.Errorf("modules directory of kernel %v is not found", release)
+	}
+
+	modules := map[string]bool{}
+	for _, file := range []string{"modules.dep", "modules.builtin"} {
+		f, err := os.Open(filepath.Join(modulesDirectory, file))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			path, _, _ := strings.Cut(scanner.Text(), ":")
+			if name := getKernelModuleName(path); name != "" {
+				modules[name] = true
+			}
+		}
+		err = scanner.Err()
+		_ = f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return modules, nil
+}
+
+// getKernelModuleName returns the module name of the path in modules.dep or modules.builtin, such
+// as iscsi_tcp for kernel/drivers/scsi/iscsi_tcp.ko.xz. Dashes are normalized to underscores.
+func getKernelModuleName(path string) string {
+	name := filepath.Base(strings.TrimSpace(path))
+	index := strings.Index(name, ".ko")
+	if index <= 0 {
+		return ""
+	}
+	return strings.ReplaceAll(name[:index], "-", "_")
+}
+
+// inspectKernelModules checks the kernel ships the modules.
+func inspectKernelModules(log *types.LogCollection, modules map[string]bool, release string, required []string) {
+	for _, module := range required {
+		if modules[module] {
+			log.Info = append(log.Info, fmt.Sprintf("Kernel %v ships module %v", release, module))
+			continue
+		}
+		log.Error = append(log.Error, fmt.Sprintf("Kernel %v does not ship module %v, use an EKS AMI with a kernel providing it", release, module))
+	}
+}
@@ -0,0 +1,153 @@
+package preflight
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func TestGetKubeletArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected []string
+	}{
+		{
+			name:     "equal sign",
+			args:     []string{"server", "--kubelet-arg=root-dir=/data/kubelet", "--disable=traefik"},
+			expected: []string{"--root-dir=/data/kubelet"},
+		},
+		{
+			name:     "separate value",
+			args:     []string{"agent", "--kubelet-arg", "--max-pods=250", "--kubelet-arg", "root-dir=/data/kubelet"},
+			expected: []string{"--max-pods=250", "--root-dir=/data/kubelet"},
+		},
+		{
+			name:     "none",
+			args:     []string{"server"},
+			expected: []string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			kubeletArgs := getKubeletArgs(test.args)
+			if !reflect.DeepEqual(kubeletArgs, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, kubeletArgs)
+			}
+		})
+	}
+}
+
+func TestParseDistributionKubeletArgs(t *testing.T) {
+	tests := []struct {
+		name                  string
+		config                string
+		expectedRootDirectory string
+	}{
+		{
+			name:                  "list",
+			config:                "token: secret\nkubelet-arg:\n- max-pods=250\n- root-dir=/data/kubelet\n",
+			expectedRootDirectory: "/data/kubelet",
+		},
+		{
+			name:                  "string",
+			config:                "kubelet-arg: --root-dir=/data/kubelet/\n",
+			expectedRootDirectory: "/data/kubelet",
+		},
+		{
+			name:   "not configured",
+			config: "write-kubeconfig-mode: \"0644\"\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rootDirectory := getKubeletRootDirectory(parseDistributionKubeletArgs([]byte(test.config)))
+			if rootDirectory != test.expectedRootDirectory {
+				t.Errorf("expected %q, got %q", test.expectedRootDirectory, rootDirectory)
+			}
+		})
+	}
+}
+
+func TestInspectDataDirectoryExec(t *testing.T) {
+	tests := []struct {
+		name          string
+		mounts        []mountInfo
+		expectedError []string
+		expectedInfo  []string
+	}{
+		{
+			name:          "noexec stateful partition",
+			mounts:        []mountInfo{{MountPoint: "/", Options: []string{"ro"}}, {MountPoint: "/var", Options: []string{"rw", "nosuid", "noexec"}}},
+			expectedError: []string{"/var/lib/longhorn is on noexec mount /var"},
+		},
+		{
+			name:         "remounted with exec",
+			mounts:       []mountInfo{{MountPoint: "/var", Options: []string{"rw", "noexec"}}, {MountPoint: "/var/lib/longhorn", Options: []string{"rw"}}},
+			expectedInfo: []string{"/var/lib/longhorn is on exec mount /var/lib/longhorn"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			log := &types.LogCollection{}
+			inspectDataDirectoryExec(log, test.mounts, consts.LonghornDefaultDataDirectory)
+
+			assertMessages(t, "error", log.Error, test.expectedError)
+			assertMessages(t, "info", log.Info, test.expectedInfo)
+		})
+	}
+}
+
+func TestInspectAKSDataDirectory(t *testing.T) {
+	tests := []struct {
+		name         string
+		mounts       []mountInfo
+		expectedWarn []string
+		expectedInfo []string
+	}{
+		{
+			name:         "os disk",
+			mounts:       []mountInfo{{MountPoint: "/"}, {MountPoint: "/mnt"}},
+			expectedWarn: []string{"is on the OS disk"},
+		},
+		{
+			name:         "temporary disk",
+			mounts:       []mountInfo{{MountPoint: "/"}, {MountPoint: "/var/lib/longhorn"}, {MountPoint: "/mnt"}},
+			expectedInfo: []string{"is on data disk mount /var/lib/longhorn"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			log := &types.LogCollection{}
+			inspectAKSDataDirectory(log, test.mounts, consts.LonghornDefaultDataDirectory)
+
+			assertMessages(t, "warn", log.Warn, test.expectedWarn)
+			assertMessages(t, "info", log.Info, test.expectedInfo)
+		})
+	}
+
+	log := &types.LogCollection{}
+	inspectAKSDataDirectory(log, []mountInfo{{MountPoint: "/"}, {MountPoint: "/mnt"}}, "/mnt/longhorn")
+	assertMessages(t, "warn", log.Warn, []string{"is on the temporary disk mounted on /mnt"})
+}
+
+func TestGetKernelModuleName(t *testing.T) {
+	tests := map[string]string{
+		"kernel/drivers/scsi/iscsi_tcp.ko.xz": "iscsi_tcp",
+		"kernel/drivers/md/dm-crypt.ko":       "dm_crypt",
+		"kernel/fs/nfs/nfs.ko.zst":            "nfs",
+		"":                                    "",
+	}
+
+	for path, expected := range tests {
+		if name := getKernelModuleName(path); name != expected {
+			t.Errorf("expected %q for %q, got %q", expected, path, name)
+		}
+	}
+}
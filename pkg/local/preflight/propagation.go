@@ -0,0 +1,170 @@
+package preflight
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+// defaultKubeletRootDirectory is the root directory of kubelet without the --root-dir flag.
+const defaultKubeletRootDirectory = "/var/lib/kubelet"
+
+// mountInfo is a mount read from the mountinfo file of a process.
+type mountInfo struct {
+	MountPoint  string
+	Options     []string // Per-mount options, such as rw or noexec.
+	Propagation []string // Optional fields, such as shared:1 or master:2.
+}
+
+// checkMountPropagation checks the kubelet root directory, and the directories where the Longhorn
+// CSI plugin mounts the volumes, are on shared mounts. The CSI plugin mounts them with
+// bidirectional propagation, and its mounts only reach kubelet and the workload pods through
+// shared mounts.
+func (local *Checker) checkMountPropagation() {
+	logrus.Info("Checking mount propagation of the kubelet root directory")
+
+	procDirectory := hostProcDirectory(local.HostRootDirectory)
+	kubeletRootDirectory := local.getProfileKubeletRootDirectory()
+	if kubeletRootDirectory == "" {
+		kubeletRootDirectory = findKubeletRootDirectory(procDirectory)
+	}
+
+	mountInfoData, err := os.ReadFile(filepath.Join(procDirectory, "1/mountinfo"))
+	if err != nil {
+		local.collection.Log.Error = append(local.collection.Log.Error, fmt.Sprintf("Failed to read mounts of the host: %v", err))
+		return
+	}
+
+	inspectMountPropagation(local.collection.Log, parseMountInfo(string(mountInfoData)), []string{
+		kubeletRootDirectory,
+		filepath.Join(kubeletRootDirectory, "pods"),
+		filepath.Join(kubeletRootDirectory, "plugins/kubernetes.io/csi"),
+	})
+}
+
+// inspectMountPropagation checks each path is on a shared mount. A slave mount receives the mounts
+// of the host, but the mounts of the CSI plugin under it do not propagate back to the host.
+func inspectMountPropagation(log *types.LogCollection, mounts []mountInfo, paths []string) {
+	for _, path := range paths {
+		mount := findContainingMount(mounts, path)
+		if mount == nil {
+			log.Error = append(log.Error, fmt.Sprintf("No mount contains %v", path))
+			continue
+		}
+
+		shared, slave := false, false
+		for _, field := range mount.Propagation {
+			shared = shared || strings.HasPrefix(field, "shared:")
+			slave = slave || strings.HasPrefix(field, "master:")
+		}
+
+		switch {
+		case shared:
+			log.Info = append(log.Info, fmt.Sprintf("%v is on shared mount %v", path, mount.MountPoint))
+		case slave:
+			log.Error = append(log.Error, fmt.Sprintf("%v is on slave mount %v, the mounts of the Longhorn CSI plugin do not propagate to kubelet. Make it shared with: mount --make-rshared %v", path, mount.MountPoint, mount.MountPoint))
+		default:
+			log.Error = append(log.Error, fmt.Sprintf("%v is on private mount %v, the mounts of the Longhorn CSI plugin do not propagate to kubelet. Make it shared with: mount --make-rshared %v", path, mount.MountPoint, mount.MountPoint))
+		}
+	}
+}
+
+// findContainingMount returns the mount the path is on, the last mount on the longest mount point
+// containing the path, since it hides the mounts below it on the same mount point.
+func findContainingMount(mounts []mountInfo, path string) *mountInfo {
+	var containing *mountInfo
+	for i := range mounts {
+		mountPoint := mounts[i].MountPoint
+		if mountPoint != "/" && path != mountPoint && !strings.HasPrefix(path, mountPoint+"/") {
+			continue
+		}
+		if containing == nil || len(mountPoint) >= len(containing.MountPoint) {
+			containing = &mounts[i]
+		}
+	}
+	return containing
+}
+
+// parseMountInfo parses the mountinfo file format of proc(5):
+// 36 35 98:0 /mnt1 /mnt2 rw,noatime master:1 - ext3 /dev/root rw,errors=continue
+func parseMountInfo(data string) []mountInfo {
+	mounts := []mountInfo{}
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 7 {
+			continue
+		}
+
+		mount := mountInfo{MountPoint: unescapeMountPath(fields[4]), Options: strings.Split(fields[5], ","), Propagation: []string{}}
+		for _, field := range fields[6:] {
+			if field == "-" {
+				break
+			}
+			mount.Propagation = append(mount.Propagation, field)
+		}
+		mounts = append(mounts, mount)
+	}
+	return mounts
+}
+
+// unescapeMountPath decodes the octal escapes of the spaces, tabs, line feeds and backslashes in
+// the paths of the mountinfo file.
+func unescapeMountPath(path string) string {
+	if !strings.Contains(path, `\`) {
+		return path
+	}
+
+	unescaped := strings.Builder{}
+	for i := 0; i < len(path); i++ {
+		if path[i] == '\\' && i+3 < len(path) {
+			if value, err := strconv.ParseUint(path[i+1:i+4], 8, 8); err == nil {
+				unescaped.WriteByte(byte(value))
+				i += 3
+				continue
+			}
+		}
+		unescaped.WriteByte(path[i])
+	}
+	return unescaped.String()
+}
+
+// findKubeletRootDirectory returns the --root-dir of the kubelet process of the host, or the default
+// kubelet root directory.
+func findKubeletRootDirectory(procDirectory string) string {
+	cmdlinePaths, _ := filepath.Glob(filepath.Join(procDirectory, "[0-9]*/cmdline"))
+	for _, cmdlinePath := range cmdlinePaths {
+		cmdline, err := os.ReadFile(cmdlinePath)
+		if err != nil || len(cmdline) == 0 {
+			continue
+		}
+
+		args := strings.Split(strings.TrimRight(string(cmdline), "\x00"), "\x00")
+		if filepath.Base(args[0]) != "kubelet" {
+			continue
+		}
+		if rootDirectory := getKubeletRootDirectory(args[1:]); rootDirectory != "" {
+			return rootDirectory
+		}
+		return defaultKubeletRootDirectory
+	}
+	return defaultKubeletRootDirectory
+}
+
+// getKubeletRootDirectory returns the value of the --root-dir flag in the kubelet arguments.
+func getKubeletRootDirectory(args []string) string {
+	for i, arg := range args {
+		if value, ok := strings.CutPrefix(arg, "--root-dir="); ok {
+			return filepath.Clean(value)
+		}
+		if arg == "--root-dir" && i+1 < len(args) {
+			return filepath.Clean(args[i+1])
+		}
+	}
+	return ""
+}
@@ -0,0 +1,115 @@
+package preflight
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func TestInspectMountPropagation(t *testing.T) {
+	paths := []string{"/var/lib/kubelet", "/var/lib/kubelet/pods", "/var/lib/kubelet/plugins/kubernetes.io/csi"}
+
+	tests := map[string]struct {
+		mountInfo     string
+		expectedInfo  []string
+		expectedError []string
+	}{
+		"shared root": {
+			mountInfo:    "22 1 8:1 / / rw,relatime shared:1 - ext4 /dev/sda1 rw\n",
+			expectedInfo: []string{"shared mount /", "shared mount /", "shared mount /"},
+		},
+		"private kubelet mount": {
+			mountInfo: "22 1 8:1 / / rw,relatime shared:1 - ext4 /dev/sda1 rw\n" +
+				"40 22 8:2 / /var/lib/kubelet rw,relatime - xfs /dev/sdb1 rw\n",
+			expectedError: []string{"private mount /var/lib/kubelet", "private mount /var/lib/kubelet", "private mount /var/lib/kubelet"},
+		},
+		"slave pods mount": {
+			mountInfo: "22 1 8:1 / / rw,relatime shared:1 - ext4 /dev/sda1 rw\n" +
+				"41 22 8:2 /pods /var/lib/kubelet/pods rw,relatime master:1 - ext4 /dev/sda1 rw\n",
+			expectedInfo:  []string{"shared mount /", "shared mount /"},
+			expectedError: []string{"slave mount /var/lib/kubelet/pods"},
+		},
+		"remounted shared on top of private": {
+			mountInfo: "22 1 8:1 / / rw,relatime - ext4 /dev/sda1 rw\n" +
+				"40 22 8:2 / /var/lib/kubelet rw,relatime - xfs /dev/sdb1 rw\n" +
+				"45 40 8:2 / /var/lib/kubelet rw,relatime shared:7 - xfs /dev/sdb1 rw\n",
+			expectedInfo: []string{"shared mount /var/lib/kubelet", "shared mount /var/lib/kubelet", "shared mount /var/lib/kubelet"},
+		},
+		"similar prefix": {
+			mountInfo: "22 1 8:1 / / rw,relatime shared:1 - ext4 /dev/sda1 rw\n" +
+				"40 22 8:2 / /var/lib/kubelet-data rw,relatime - xfs /dev/sdb1 rw\n",
+			expectedInfo: []string{"shared mount /", "shared mount /", "shared mount /"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			log := &types.LogCollection{}
+			inspectMountPropagation(log, parseMountInfo(test.mountInfo), paths)
+
+			assertMessages(t, "info", log.Info, test.expectedInfo)
+			assertMessages(t, "error", log.Error, test.expectedError)
+		})
+	}
+}
+
+func TestUnescapeMountPath(t *testing.T) {
+	for path, expected := range map[string]string{
+		"/var/lib/kubelet":          "/var/lib/kubelet",
+		`/mnt/with\040space`:        "/mnt/with space",
+		`/mnt/back\134slash`:        `/mnt/back\slash`,
+		`/mnt/trailing\04`:          `/mnt/trailing\04`,
+		`/mnt/with\040two\040space`: "/mnt/with two space",
+	} {
+		if actual := unescapeMountPath(path); actual != expected {
+			t.Errorf("expected %q for %q, got %q", expected, path, actual)
+		}
+	}
+}
+
+func TestFindKubeletRootDirectory(t *testing.T) {
+	tests := map[string]struct {
+		cmdlines map[string]string
+		expected string
+	}{
+		"root dir flag": {
+			cmdlines: map[string]string{
+				"1":   "/sbin/init\x00",
+				"812": "/usr/bin/kubelet\x00--config=/etc/kubernetes/kubelet.conf\x00--root-dir=/data/kubelet/\x00",
+			},
+			expected: "/data/kubelet",
+		},
+		"separate root dir value": {
+			cmdlines: map[string]string{"812": "kubelet\x00--root-dir\x00/opt/kubelet\x00"},
+			expected: "/opt/kubelet",
+		},
+		"default": {
+			cmdlines: map[string]string{"812": "/usr/bin/kubelet\x00--config=/etc/kubernetes/kubelet.conf\x00"},
+			expected: defaultKubeletRootDirectory,
+		},
+		"no kubelet": {
+			cmdlines: map[string]string{"1": "/sbin/init\x00"},
+			expected: defaultKubeletRootDirectory,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			procDirectory := t.TempDir()
+			for pid, cmdline := range test.cmdlines {
+				if err := os.MkdirAll(filepath.Join(procDirectory, pid), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(procDirectory, pid, "cmdline"), []byte(cmdline), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if actual := findKubeletRootDirectory(procDirectory); actual != test.expected {
+				t.Errorf("expected %v, got %v", test.expected, actual)
+			}
+		})
+	}
+}
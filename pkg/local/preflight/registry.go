@@ -0,0 +1,146 @@
+package preflight
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	sigsyaml "sigs.k8s.io/yaml"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/utils/registry"
+)
+
+const registryCheckTimeout = 10 * time.Second
+
+var (
+	containerdHostRegexp   = regexp.MustCompile(`(?m)^\s*\[host\."([^"]+)"\]`)
+	containerdServerRegexp = regexp.MustCompile(`(?m)^\s*server\s*=\s*"([^"]+)"`)
+)
+
+// rancherRegistriesFiles are the registry configuration files of K3s and RKE2, relative to the host root.
+var rancherRegistriesFiles = []string{
+	"etc/rancher/k3s/registries.yaml",
+	"etc/rancher/rke2/registries.yaml",
+}
+
+// rancherRegistries is the part of the K3s and RKE2 registries.yaml defining the mirrors.
+type rancherRegistries struct {
+	Mirrors map[string]struct {
+		Endpoint []string `json:"endpoint"`
+	} `json:"mirrors"`
+}
+
+// checkRegistries checks the manifest of each image can be fetched through the registry
+// mirrors configured for containerd on the host, falling back to the upstream registry
+// like containerd does.
+func (local *Checker) checkRegistries() {
+	httpClient := &http.Client{Timeout: registryCheckTimeout}
+
+	registryEndpoints := map[string][]string{}
+	for _, image := range strings.Split(local.RegistryCheckImages, consts.CmdOptSeperator) {
+		image = strings.TrimSpace(image)
+		if image == "" {
+			continue
+		}
+
+		ref := registry.ParseImageReference(image)
+		endpoints, ok := registryEndpoints[ref.Registry]
+		if !ok {
+			endpoints = local.getRegistryEndpoints(ref.Registry)
+			registryEndpoints[ref.Registry] = endpoints
+			logrus.Debugf("Registry %v endpoints: %v", ref.Registry, endpoints)
+		}
+
+		var failures []string
+		for _, endpoint := range endpoints {
+			err := registry.CheckManifest(httpClient, endpoint, ref)
+			if err == nil {
+				local.collection.Log.Info = append(local.collection.Log.Info, fmt.Sprintf("Image %s is reachable through %s", image, endpoint))
+				failures = nil
+				break
+			}
+			failures = append(failures, fmt.Sprintf("%s: %v", endpoint, err))
+		}
+
+		if len(failures) > 0 {
+			local.collection.Log.Error = append(local.collection.Log.Error, fmt.Sprintf("Image %s is not reachable through any registry endpoint (%s)", image, strings.Join(failures, "; ")))
+		}
+	}
+}
+
+// getRegistryEndpoints returns the mirrors configured for the registry in the containerd hosts
+// directory and the K3s and RKE2 registries.yaml, followed by the upstream registry.
+func (local *Checker) getRegistryEndpoints(registryName string) []string {
+	upstream := registry.GetUpstreamEndpoint(registryName)
+
+	var endpoints []string
+	hostsFile := filepath.Join(local.HostRootDirectory, "etc/containerd/certs.d", registryName, "hosts.toml")
+	if data, err := os.ReadFile(hostsFile); err == nil {
+		mirrors, server := parseContainerdHosts(data)
+		endpoints = append(endpoints, mirrors...)
+		if server != "" {
+			upstream = server
+		}
+	}
+
+	for _, file := range rancherRegistriesFiles {
+		data, err := os.ReadFile(filepath.Join(local.HostRootDirectory, file))
+		if err != nil {
+			continue
+		}
+
+		mirrors, err := parseRancherRegistries(data, registryName)
+		if err != nil {
+			logrus.WithError(err).Warnf("Failed to parse %v", file)
+			continue
+		}
+		endpoints = append(endpoints, mirrors...)
+	}
+
+	for i, endpoint := range endpoints {
+		if !strings.Contains(endpoint, "://") {
+			endpoints[i] = "https://" + endpoint
+		}
+		endpoints[i] = strings.TrimSuffix(strings.TrimSuffix(endpoints[i], "/"), "/v2")
+	}
+
+	if !slices.Contains(endpoints, upstream) {
+		endpoints = append(endpoints, upstream)
+	}
+	return endpoints
+}
+
+// parseContainerdHosts returns the mirror hosts and the upstream server in a containerd hosts.toml.
+func parseContainerdHosts(data []byte) (mirrors []string, server string) {
+	for _, match := range containerdHostRegexp.FindAllSubmatch(data, -1) {
+		mirrors = append(mirrors, string(match[1]))
+	}
+
+	if match := containerdServerRegexp.FindSubmatch(data); match != nil {
+		server = string(match[1])
+	}
+	return mirrors, server
+}
+
+// parseRancherRegistries returns the mirror endpoints of the registry in a K3s or RKE2 registries.yaml,
+// including the ones of the "*" wildcard mirror.
+func parseRancherRegistries(data []byte, registry string) ([]string, error) {
+	var registries rancherRegistries
+	if err := sigsyaml.Unmarshal(data, &registries); err != nil {
+		return nil, err
+	}
+
+	endpoints := registries.Mirrors[registry].Endpoint
+	if wildcard, ok := registries.Mirrors["*"]; ok && registry != "*" {
+		endpoints = append(endpoints, wildcard.Endpoint...)
+	}
+	return endpoints, nil
+}
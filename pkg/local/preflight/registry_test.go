@@ -0,0 +1,56 @@
+package preflight
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseContainerdHosts(t *testing.T) {
+	data := []byte(`server = "https://registry-1.docker.io"
+
+[host."https://mirror-a.example.com"]
+  capabilities = ["pull", "resolve"]
+
+[host."http://mirror-b.example.com:5000"]
+  capabilities = ["pull"]
+`)
+
+	mirrors, server := parseContainerdHosts(data)
+	expectedMirrors := []string{"https://mirror-a.example.com", "http://mirror-b.example.com:5000"}
+	if !reflect.DeepEqual(mirrors, expectedMirrors) {
+		t.Errorf("expected mirrors %v, got %v", expectedMirrors, mirrors)
+	}
+	if server != "https://registry-1.docker.io" {
+		t.Errorf("unexpected server %q", server)
+	}
+}
+
+func TestParseRancherRegistries(t *testing.T) {
+	data := []byte(`mirrors:
+  docker.io:
+    endpoint:
+    - "https://mirror.example.com"
+  "*":
+    endpoint:
+    - "https://proxy.example.com"
+configs:
+  mirror.example.com:
+    auth:
+      username: user
+`)
+
+	tests := map[string][]string{
+		"docker.io":   {"https://mirror.example.com", "https://proxy.example.com"},
+		"registry.io": {"https://proxy.example.com"},
+	}
+
+	for registry, expected := range tests {
+		endpoints, err := parseRancherRegistries(data, registry)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(endpoints, expected) {
+			t.Errorf("registry %v: expected %v, got %v", registry, expected, endpoints)
+		}
+	}
+}
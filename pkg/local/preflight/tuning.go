@@ -0,0 +1,470 @@
+package preflight
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	commonns "github.com/longhorn/go-common-libs/ns"
+	commontypes "github.com/longhorn/go-common-libs/types"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+
+	remote "github.com/longhorn/cli/pkg/remote/preflight"
+)
+
+const (
+	// tuningSysctlPath is the file persisting the sysctl parameters of the tuning profile.
+	tuningSysctlPath = "/etc/sysctl.d/90-longhorn.conf"
+
+	// tuningDropInName is the systemd drop-in raising the open files limit of the container
+	// runtime, which the instance-manager processes inherit.
+	tuningDropInName = "90-longhorn.conf"
+
+	// tuningNofileLimit is the open files limit recommended for the instance-manager processes,
+	// which hold the replica files and the frontend connections of all the volumes of the node.
+	tuningNofileLimit = 1048576
+
+	instanceManagerProcessName = "longhorn-instance-manager"
+)
+
+// sysctlRecommendation is a sysctl parameter of the tuning profile.
+type sysctlRecommendation struct {
+	Key     string
+	Value   string
+	Minimum bool // The value is a minimum instead of an exact value.
+	Maximum bool // The value is a maximum instead of an exact value.
+	Reason  string
+}
+
+// tuningSysctls are the sysctl parameters of the tuning profile for the storage nodes.
+var tuningSysctls = []sysctlRecommendation{
+	{
+		Key:     "fs.aio-max-nr",
+		Value:   "1048576",
+		Minimum: true,
+		Reason:  "the asynchronous I/O of the v2 data engine exhausts the default of 65536",
+	},
+	{
+		Key:     "vm.dirty_background_ratio",
+		Value:   "5",
+		Maximum: true,
+		Reason:  "larger amounts of dirty pages delay the writeback of the replica files",
+	},
+	{
+		Key:     "vm.dirty_ratio",
+		Value:   "10",
+		Maximum: true,
+		Reason:  "larger amounts of dirty pages stall the I/O of the volumes on writeback bursts",
+	},
+}
+
+// containerRuntimeUnits are the systemd units of the container runtimes the instance-manager
+// processes inherit the open files limit from.
+var containerRuntimeUnits = []string{
+	"containerd.service",
+	"crio.service",
+	"docker.service",
+	"k3s.service",
+	"k3s-agent.service",
+	"rke2-server.service",
+	"rke2-agent.service",
+}
+
+// systemdUnitDirectories are the directories of the systemd unit files, by precedence.
+var systemdUnitDirectories = []string{
+	"/etc/systemd/system",
+	"/usr/lib/systemd/system",
+	"/lib/systemd/system",
+}
+
+// TuningChecker provide functions for checking the node against the tuning profile.
+type TuningChecker struct {
+	remote.TuningCmdOptions
+
+	logger *logrus.Entry
+
+	OutputFilePath string
+
+	// HostRootDirectory is the directory of the host root filesystem.
+	// It is "/" when running directly on the host instead of in a DaemonSet pod.
+	HostRootDirectory string
+
+	collection types.NodeCollection
+}
+
+// Init initializes the TuningChecker.
+func (local *TuningChecker) Init() error {
+	local.collection.Log = &types.LogCollection{}
+	local.logger = logrus.WithField("component", "tuning")
+
+	if local.HostRootDirectory == "" {
+		local.HostRootDirectory = consts.VolumeMountHostDirectory
+	}
+
+	return nil
+}
+
+// Run reports the drift of the live and the persisted settings from the tuning profile.
+func (local *TuningChecker) Run() error {
+	procDirectory := hostProcDirectory(local.HostRootDirectory)
+
+	live := map[string]string{}
+	for _, recommendation := range tuningSysctls {
+		value, err := readSysctl(procDirectory, recommendation.Key)
+		if err != nil {
+			return err
+		}
+		live[recommendation.Key] = value
+	}
+	inspectSysctls(local.collection.Log, live)
+
+	persisted, err := os.ReadFile(filepath.Join(local.HostRootDirectory, tuningSysctlPath))
+	switch {
+	case os.IsNotExist(err):
+		local.collection.Log.Warn = append(local.collection.Log.Warn, fmt.Sprintf("The tuning profile is not persisted in %v, the sysctl parameters are reset on reboot", tuningSysctlPath))
+	case err != nil:
+		return errors.Wrapf(err, "failed to read %v", tuningSysctlPath)
+	default:
+		inspectPersistedSysctls(local.collection.Log, parseSysctlConfig(string(persisted)))
+	}
+
+	limits, err := readInstanceManagerNofileLimits(procDirectory)
+	if err != nil {
+		return err
+	}
+	inspectNofileLimits(local.collection.Log, limits)
+
+	for _, unit := range findContainerRuntimeUnits(local.HostRootDirectory) {
+		dropInPath := filepath.Join(systemdUnitDirectories[0], unit+".d", tuningDropInName)
+		data, err := os.ReadFile(filepath.Join(local.HostRootDirectory, dropInPath))
+		if err != nil || string(data) != renderNofileDropIn() {
+			local.collection.Log.Warn = append(local.collection.Log.Warn, fmt.Sprintf("The open files limit of %v is not persisted in %v", unit, dropInPath))
+			continue
+		}
+		local.collection.Log.Info = append(local.collection.Log.Info, fmt.Sprintf("The open files limit of %v is persisted in %v", unit, dropInPath))
+	}
+
+	return nil
+}
+
+// Output converts the collection to JSON and output to stdout or the output file.
+func (local *TuningChecker) Output() error {
+	local.logger.Trace("Outputting tuning checker results")
+
+	jsonBytes, err := json.Marshal(local.collection)
+	if err != nil {
+		return errors.Wrap(err, "failed to convert collection to JSON")
+	}
+
+	return utils.HandleResult(jsonBytes, local.OutputFilePath, local.logger)
+}
+
+// TuningInstaller provide functions for applying the tuning profile to the node.
+type TuningInstaller struct {
+	remote.TuningCmdOptions
+
+	logger *logrus.Entry
+
+	OutputFilePath string
+
+	// HostRootDirectory is the directory of the host root filesystem.
+	// It is "/" when running directly on the host instead of in a DaemonSet pod.
+	HostRootDirectory string
+
+	executor *commonns.Executor
+
+	collection types.NodeCollection
+}
+
+// Init initializes the TuningInstaller.
+func (local *TuningInstaller) Init() error {
+	local.collection.Log = &types.LogCollection{}
+	local.logger = logrus.WithField("component", "tuning")
+
+	if local.HostRootDirectory == "" {
+		local.HostRootDirectory = consts.VolumeMountHostDirectory
+	}
+
+	executor, err := commonns.NewNamespaceExecutor(commontypes.ProcessSelf, hostProcDirectory(local.HostRootDirectory), []commontypes.Namespace{commontypes.NamespaceMnt})
+	if err != nil {
+		return err
+	}
+	local.executor = executor
+
+	return nil
+}
+
+// Run persists the sysctl parameters of the tuning profile and applies them, and raises the open
+// files limit of the container runtimes. The container runtimes and the instance-manager pods
+// pick up the new limit when they are restarted.
+func (local *TuningInstaller) Run() error {
+	if err := local.writeHostFile(tuningSysctlPath, renderSysctlProfile()); err != nil {
+		return err
+	}
+
+	if _, err := local.executor.Execute([]string{}, "sysctl", []string{"-p", tuningSysctlPath}, commontypes.ExecuteDefaultTimeout); err != nil {
+		return errors.Wrapf(err, "failed to apply %v", tuningSysctlPath)
+	}
+	local.collection.Log.Info = append(local.collection.Log.Info, fmt.Sprintf("Applied the sysctl parameters persisted in %v", tuningSysctlPath))
+
+	updatedUnits := []string{}
+	for _, unit := range findContainerRuntimeUnits(local.HostRootDirectory) {
+		dropInPath := filepath.Join(systemdUnitDirectories[0], unit+".d", tuningDropInName)
+		data, err := os.ReadFile(filepath.Join(local.HostRootDirectory, dropInPath))
+		if err == nil && string(data) == renderNofileDropIn() {
+			local.collection.Log.Info = append(local.collection.Log.Info, fmt.Sprintf("The open files limit of %v is already persisted in %v", unit, dropInPath))
+			continue
+		}
+
+		if err := local.writeHostFile(dropInPath, renderNofileDropIn()); err != nil {
+			return err
+		}
+		updatedUnits = append(updatedUnits, unit)
+	}
+
+	if len(updatedUnits) == 0 {
+		return nil
+	}
+
+	if _, err := local.executor.Execute([]string{}, "systemctl", []string{"daemon-reload"}, commontypes.ExecuteDefaultTimeout); err != nil {
+		return errors.Wrap(err, "failed to reload systemd")
+	}
+	for _, unit := range updatedUnits {
+		local.collection.Log.Warn = append(local.collection.Log.Warn, fmt.Sprintf("Raised the open files limit of %v to %v, restart it and the instance-manager pods of the node to apply", unit, tuningNofileLimit))
+	}
+
+	return nil
+}
+
+// Output converts the collection to JSON and output to stdout or the output file.
+func (local *TuningInstaller) Output() error {
+	local.logger.Trace("Outputting tuning installer results")
+
+	jsonBytes, err := json.Marshal(local.collection)
+	if err != nil {
+		return errors.Wrap(err, "failed to convert collection to JSON")
+	}
+
+	return utils.HandleResult(jsonBytes, local.OutputFilePath, local.logger)
+}
+
+// writeHostFile writes the file in the host root filesystem, creating its directory.
+func (local *TuningInstaller) writeHostFile(path, content string) error {
+	hostPath := filepath.Join(local.HostRootDirectory, path)
+	if err := os.MkdirAll(filepath.Dir(hostPath), 0755); err != nil {
+		return errors.Wrapf(err, "failed to create the directory of %v", path)
+	}
+	if err := os.WriteFile(hostPath, []byte(content), 0644); err != nil {
+		return errors.Wrapf(err, "failed to write %v", path)
+	}
+
+	local.collection.Log.Info = append(local.collection.Log.Info, fmt.Sprintf("Wrote %v", path))
+	return nil
+}
+
+// follows returns whether the value follows the recommendation.
+func (recommendation *sysctlRecommendation) follows(value string) bool {
+	if !recommendation.Minimum && !recommendation.Maximum {
+		return value == recommendation.Value
+	}
+
+	number, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return false
+	}
+	limit, _ := strconv.ParseInt(recommendation.Value, 10, 64)
+	if recommendation.Minimum {
+		return number >= limit
+	}
+	return number <= limit
+}
+
+// expected returns the description of the recommended value.
+func (recommendation *sysctlRecommendation) expected() string {
+	switch {
+	case recommendation.Minimum:
+		return "at least " + recommendation.Value
+	case recommendation.Maximum:
+		return "at most " + recommendation.Value
+	default:
+		return recommendation.Value
+	}
+}
+
+// inspectSysctls reports the live sysctl parameters deviating from the tuning profile.
+func inspectSysctls(log *types.LogCollection, live map[string]string) {
+	for _, recommendation := range tuningSysctls {
+		value := live[recommendation.Key]
+		if recommendation.follows(value) {
+			log.Info = append(log.Info, fmt.Sprintf("sysctl %v is %v", recommendation.Key, value))
+			continue
+		}
+		log.Warn = append(log.Warn, fmt.Sprintf("sysctl %v is %v instead of %v: %v", recommendation.Key, value, recommendation.expected(), recommendation.Reason))
+	}
+}
+
+// inspectPersistedSysctls reports the sysctl parameters of the tuning profile that are missing
+// from, or deviating in, the persisted profile.
+func inspectPersistedSysctls(log *types.LogCollection, persisted map[string]string) {
+	for _, recommendation := range tuningSysctls {
+		value, ok := persisted[recommendation.Key]
+		switch {
+		case !ok:
+			log.Warn = append(log.Warn, fmt.Sprintf("sysctl %v is not persisted in %v", recommendation.Key, tuningSysctlPath))
+		case !recommendation.follows(value):
+			log.Warn = append(log.Warn, fmt.Sprintf("sysctl %v is persisted as %v in %v instead of %v", recommendation.Key, value, tuningSysctlPath, recommendation.expected()))
+		}
+	}
+}
+
+// inspectNofileLimits reports the instance-manager processes with an open files limit lower
+// than the recommended one. The limits are keyed by the process ID.
+func inspectNofileLimits(log *types.LogCollection, limits map[string]uint64) {
+	if len(limits) == 0 {
+		log.Info = append(log.Info, "No instance-manager process is running, skipped checking its open files limit")
+		return
+	}
+
+	pids := make([]string, 0, len(limits))
+	for pid := range limits {
+		pids = append(pids, pid)
+	}
+	sort.Slice(pids, func(i, j int) bool {
+		a, _ := strconv.Atoi(pids[i])
+		b, _ := strconv.Atoi(pids[j])
+		return a < b
+	})
+
+	for _, pid := range pids {
+		limit := limits[pid]
+		if limit < tuningNofileLimit {
+			log.Warn = append(log.Warn, fmt.Sprintf("Open files limit of instance-manager process %v is %v instead of at least %v", pid, limit, tuningNofileLimit))
+			continue
+		}
+		log.Info = append(log.Info, fmt.Sprintf("Open files limit of instance-manager process %v is %v", pid, limit))
+	}
+}
+
+// renderSysctlProfile returns the content of the file persisting the sysctl parameters.
+func renderSysctlProfile() string {
+	var builder strings.Builder
+	builder.WriteString("# Managed by longhornctl install tuning.\n")
+	for _, recommendation := range tuningSysctls {
+		fmt.Fprintf(&builder, "%v = %v\n", recommendation.Key, recommendation.Value)
+	}
+	return builder.String()
+}
+
+// renderNofileDropIn returns the content of the systemd drop-in raising the open files limit.
+func renderNofileDropIn() string {
+	return fmt.Sprintf("# Managed by longhornctl install tuning.\n[Service]\nLimitNOFILE=%v\n", tuningNofileLimit)
+}
+
+// parseSysctlConfig returns the parameters of the sysctl configuration file. The comments are
+// skipped, and the last value of a parameter wins.
+func parseSysctlConfig(data string) map[string]string {
+	config := map[string]string{}
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			continue
+		}
+		key = strings.ReplaceAll(strings.TrimPrefix(strings.TrimSpace(key), "-"), "/", ".")
+		config[key] = strings.TrimSpace(value)
+	}
+	return config
+}
+
+// readSysctl returns the live value of the sysctl parameter.
+func readSysctl(procDirectory, key string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(procDirectory, "sys", strings.ReplaceAll(key, ".", "/")))
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to read sysctl %v", key)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// readInstanceManagerNofileLimits returns the soft open files limit of the instance-manager
+// processes, keyed by the process ID.
+func readInstanceManagerNofileLimits(procDirectory string) (map[string]uint64, error) {
+	entries, err := os.ReadDir(procDirectory)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %v", procDirectory)
+	}
+
+	limits := map[string]uint64{}
+	for _, entry := range entries {
+		if _, err := strconv.Atoi(entry.Name()); err != nil {
+			continue
+		}
+
+		cmdline, err := os.ReadFile(filepath.Join(procDirectory, entry.Name(), "cmdline"))
+		if err != nil {
+			continue // The process has exited.
+		}
+		command, _, _ := strings.Cut(string(cmdline), "\x00")
+		if filepath.Base(command) != instanceManagerProcessName {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(procDirectory, entry.Name(), "limits"))
+		if err != nil {
+			continue
+		}
+		limit, err := parseNofileLimit(string(data))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse the limits of process %v", entry.Name())
+		}
+		limits[entry.Name()] = limit
+	}
+	return limits, nil
+}
+
+// parseNofileLimit returns the soft open files limit in the content of /proc/<pid>/limits.
+// An unlimited limit is returned as the maximum value.
+func parseNofileLimit(limits string) (uint64, error) {
+	for _, line := range strings.Split(limits, "\n") {
+		if !strings.HasPrefix(line, "Max open files") {
+			continue
+		}
+
+		fields := strings.Fields(strings.TrimPrefix(line, "Max open files"))
+		if len(fields) == 0 {
+			break
+		}
+		if fields[0] == "unlimited" {
+			return ^uint64(0), nil
+		}
+		return strconv.ParseUint(fields[0], 10, 64)
+	}
+	return 0, errors.New("missing Max open files limit")
+}
+
+// findContainerRuntimeUnits returns the container runtime units installed on the host.
+func findContainerRuntimeUnits(hostRootDirectory string) []string {
+	units := []string{}
+	for _, unit := range containerRuntimeUnits {
+		for _, directory := range systemdUnitDirectories {
+			if _, err := os.Stat(filepath.Join(hostRootDirectory, directory, unit)); err == nil {
+				units = append(units, unit)
+				break
+			}
+		}
+	}
+	return units
+}
@@ -0,0 +1,115 @@
+package preflight
+
+import (
+	"testing"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func TestInspectSysctls(t *testing.T) {
+	tests := []struct {
+		name         string
+		live         map[string]string
+		expectedWarn []string
+		expectedInfo []string
+	}{
+		{
+			name:         "tuned",
+			live:         map[string]string{"fs.aio-max-nr": "2097152", "vm.dirty_background_ratio": "5", "vm.dirty_ratio": "3"},
+			expectedInfo: []string{"fs.aio-max-nr is 2097152", "vm.dirty_background_ratio is 5", "vm.dirty_ratio is 3"},
+		},
+		{
+			name:         "defaults",
+			live:         map[string]string{"fs.aio-max-nr": "65536", "vm.dirty_background_ratio": "10", "vm.dirty_ratio": "20"},
+			expectedWarn: []string{"fs.aio-max-nr is 65536 instead of at least 1048576", "vm.dirty_background_ratio is 10 instead of at most 5", "vm.dirty_ratio is 20 instead of at most 10"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			log := &types.LogCollection{}
+			inspectSysctls(log, test.live)
+
+			assertMessages(t, "warn", log.Warn, test.expectedWarn)
+			assertMessages(t, "info", log.Info, test.expectedInfo)
+		})
+	}
+}
+
+func TestInspectPersistedSysctls(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       string
+		expectedWarn []string
+	}{
+		{
+			name:   "rendered profile",
+			config: renderSysctlProfile(),
+		},
+		{
+			name:         "drift",
+			config:       "# comment\nfs/aio-max-nr=1048576\n-vm.dirty_ratio = 40\n",
+			expectedWarn: []string{"vm.dirty_background_ratio is not persisted", "vm.dirty_ratio is persisted as 40"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			log := &types.LogCollection{}
+			inspectPersistedSysctls(log, parseSysctlConfig(test.config))
+
+			assertMessages(t, "warn", log.Warn, test.expectedWarn)
+		})
+	}
+}
+
+func TestInspectNofileLimits(t *testing.T) {
+	log := &types.LogCollection{}
+	inspectNofileLimits(log, map[string]uint64{"120": 1048576, "13": 65536})
+
+	assertMessages(t, "warn", log.Warn, []string{"process 13 is 65536 instead of at least 1048576"})
+	assertMessages(t, "info", log.Info, []string{"process 120 is 1048576"})
+}
+
+func TestParseNofileLimit(t *testing.T) {
+	tests := []struct {
+		name          string
+		limits        string
+		expectedLimit uint64
+		expectedError bool
+	}{
+		{
+			name:          "limited",
+			limits:        "Limit                     Soft Limit           Hard Limit           Units\nMax open files            65536                524288               files\n",
+			expectedLimit: 65536,
+		},
+		{
+			name:          "unlimited",
+			limits:        "Max open files            unlimited            unlimited            files\n",
+			expectedLimit: ^uint64(0),
+		},
+		{
+			name:          "missing",
+			limits:        "Max processes             unlimited            unlimited            processes\n",
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			limit, err := parseNofileLimit(test.limits)
+			if test.expectedError {
+				if err == nil {
+					t.Errorf("expected error, got limit %v", limit)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if limit != test.expectedLimit {
+				t.Errorf("expected limit %v, got %v", test.expectedLimit, limit)
+			}
+		})
+	}
+}
@@ -100,14 +100,17 @@ func (local *Getter) Output() error {
 // getReplicaNamesInDirectory returns a list of replica names in the given directory that match the given volume name.
 // If the volume name is empty, it returns all replica names in the given directory.
 func (local *Getter) getReplicaNamesInDirectory() ([]string, error) {
-	log := local.logger
-	replicasDirectory := local.replicasDirectory
+	return findReplicaNames(local.logger, local.replicasDirectory, local.VolumeName, local.ReplicaName)
+}
 
-	if local.VolumeName != "" {
-		log = log.WithField("volume", local.VolumeName)
+// findReplicaNames returns the names of the replica directories in the replicas directory,
+// filtered by the replica name and the volume name when they are not empty.
+func findReplicaNames(log *logrus.Entry, replicasDirectory, volumeName, replicaName string) ([]string, error) {
+	if volumeName != "" {
+		log = log.WithField("volume", volumeName)
 	}
-	if local.ReplicaName != "" {
-		log = log.WithField("replica", local.ReplicaName)
+	if replicaName != "" {
+		log = log.WithField("replica", replicaName)
 	}
 	log.Infof("Searching for replicas in %s", replicasDirectory)
 
@@ -128,23 +131,22 @@ func (local *Getter) getReplicaNamesInDirectory() ([]string, error) {
 			continue
 		}
 
-		replicaName := filepath.Base(filePath)
+		name := filepath.Base(filePath)
 
 		// Skip the replicas directory itself.
 		if filePath == replicasDirectory {
 			continue
 		}
 
-		if local.ReplicaName != "" && local.ReplicaName != replicaName {
+		if replicaName != "" && replicaName != name {
 			continue
 		}
 
-		volumeName := replicaName[:strings.LastIndex(replicaName, "-")]
-		if local.VolumeName != "" && local.VolumeName != volumeName {
+		if volumeName != "" && volumeName != name[:strings.LastIndex(name, "-")] {
 			continue
 		}
 
-		replicaNames = append(replicaNames, replicaName)
+		replicaNames = append(replicaNames, name)
 	}
 
 	return replicaNames, nil
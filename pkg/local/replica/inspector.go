@@ -0,0 +1,310 @@
+package replica
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	lhmgrutil "github.com/longhorn/longhorn-manager/util"
+
+	"github.com/longhorn/cli/pkg/consts"
+	remote "github.com/longhorn/cli/pkg/remote/replica"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+	utilslonghorn "github.com/longhorn/cli/pkg/utils/longhorn"
+)
+
+// Files of a replica data directory.
+const (
+	volumeMetaFileName     = "volume.meta"
+	imageFileSuffix        = ".img"
+	imageMetaFileSuffix    = ".img.meta"
+	snapshotFilePrefix     = "volume-snap-"
+	headFilePrefix         = "volume-head-"
+	temporaryFileExtension = ".tmp"
+)
+
+// diskMeta is the metadata file of a snapshot or volume head image.
+type diskMeta struct {
+	Name        string
+	Parent      string
+	Removed     bool
+	UserCreated bool
+	Created     string
+}
+
+// Inspector provide functions for the replica metadata inspector.
+type Inspector struct {
+	remote.InspectorCmdOptions
+
+	logger *logrus.Entry
+
+	OutputFilePath string
+	CurrentNodeID  string
+
+	replicasDirectory string
+
+	collection types.ReplicaMetaCollection
+}
+
+// Init initializes the Inspector.
+func (local *Inspector) Init() error {
+	var err error
+
+	if len(local.OutputFilePath) != 0 {
+		local.logger = logrus.WithField("output", local.OutputFilePath)
+	} else {
+		local.logger = logrus.WithField("output", "stdout")
+	}
+
+	local.LonghornDataDirectory, err = utilslonghorn.GetDataDirectory(local.logger, consts.VolumeMountHostDirectory, local.LonghornDataDirectory)
+	if err != nil {
+		return errors.Wrap(err, "failed to get Longhorn data directory")
+	}
+
+	local.logger = local.logger.WithField("data-dir", local.LonghornDataDirectory)
+
+	local.replicasDirectory = filepath.Join(local.LonghornDataDirectory, "replicas")
+
+	local.collection.Replicas = make(map[string]*types.ReplicaMetaInspection)
+
+	return nil
+}
+
+// Run inspects the metadata files of the replicas of the volume, and repairs the
+// known-safe issues when Repair is set.
+func (local *Inspector) Run() error {
+	replicaNames, err := findReplicaNames(local.logger, local.replicasDirectory, local.VolumeName, "")
+	if err != nil {
+		return err
+	}
+
+	for _, replicaName := range replicaNames {
+		log := local.logger.WithField("replica", replicaName)
+		log.Info("Inspecting replica metadata")
+
+		replicaDirectory := filepath.Join(local.replicasDirectory, replicaName)
+		inspection := inspectReplicaDirectory(replicaDirectory)
+		inspection.Node = local.CurrentNodeID
+		inspection.Directory = strings.TrimPrefix(replicaDirectory, consts.VolumeMountHostDirectory)
+		inspection.VolumeName = replicaName[:strings.LastIndex(replicaName, "-")]
+
+		if local.Repair {
+			repairReplicaDirectory(log, replicaDirectory, inspection)
+		}
+
+		local.collection.Replicas[replicaName] = inspection
+	}
+
+	return nil
+}
+
+// Output converts the collection to JSON and output to stdout or the output file.
+func (local *Inspector) Output() error {
+	local.logger.Tracef("Outputting replica metadata inspector results")
+
+	jsonBytes, err := json.Marshal(local.collection)
+	if err != nil {
+		return errors.Wrap(err, "failed to convert replica metadata collections to JSON")
+	}
+
+	return utils.HandleResult(jsonBytes, local.OutputFilePath, local.logger)
+}
+
+// inspectReplicaDirectory checks the volume.meta file of a replica, and the chain of image and
+// metadata files from the volume head to the oldest snapshot.
+func inspectReplicaDirectory(replicaDirectory string) *types.ReplicaMetaInspection {
+	inspection := &types.ReplicaMetaInspection{}
+
+	addIssue := func(severity types.ReplicaMetaIssueSeverity, file, repair, format string, args ...interface{}) {
+		inspection.Issues = append(inspection.Issues, &types.ReplicaMetaIssue{
+			Severity: severity,
+			File:     file,
+			Message:  fmt.Sprintf(format, args...),
+			Repair:   repair,
+		})
+	}
+
+	entries, err := os.ReadDir(replicaDirectory)
+	if err != nil {
+		inspection.Error = errors.Wrapf(err, "failed to read replica directory %v", replicaDirectory).Error()
+		return inspection
+	}
+
+	files := map[string]os.FileInfo{}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			inspection.Error = errors.Wrapf(err, "failed to get file %v", entry.Name()).Error()
+			return inspection
+		}
+		files[entry.Name()] = info
+	}
+
+	for _, name := range sortedFileNames(files) {
+		if strings.HasSuffix(name, temporaryFileExtension) {
+			addIssue(types.ReplicaMetaIssueSeverityWarn, name, "remove the file", "Temporary file left by an interrupted metadata update")
+		}
+	}
+
+	volumeMeta, err := readVolumeMeta(filepath.Join(replicaDirectory, volumeMetaFileName))
+	if err != nil {
+		addIssue(types.ReplicaMetaIssueSeverityError, volumeMetaFileName, "", "%v", err)
+		return inspection
+	}
+	inspection.Size = volumeMeta.Size
+	inspection.Head = volumeMeta.Head
+
+	if volumeMeta.Error != "" {
+		addIssue(types.ReplicaMetaIssueSeverityError, volumeMetaFileName, "", "Replica is marked as failed: %v", volumeMeta.Error)
+	}
+	if volumeMeta.Rebuilding {
+		addIssue(types.ReplicaMetaIssueSeverityWarn, volumeMetaFileName, "", "Replica is marked as rebuilding, its data is incomplete unless a rebuild is running")
+	}
+	if volumeMeta.BackingFilePath != "" {
+		if _, err := os.Stat(volumeMeta.BackingFilePath); err != nil {
+			addIssue(types.ReplicaMetaIssueSeverityError, volumeMetaFileName, "", "Backing file %v is missing", volumeMeta.BackingFilePath)
+		}
+	}
+
+	inChain := map[string]bool{}
+	head := volumeMeta.Head
+	if head == "" {
+		addIssue(types.ReplicaMetaIssueSeverityError, volumeMetaFileName, "", "Volume head is not set")
+		return inspection
+	}
+
+	headInfo, ok := files[head]
+	if !ok {
+		addIssue(types.ReplicaMetaIssueSeverityError, head, "", "Volume head image file is missing")
+	} else {
+		inChain[head] = true
+		switch {
+		case headInfo.Size() < volumeMeta.Size:
+			addIssue(types.ReplicaMetaIssueSeverityError, head, fmt.Sprintf("extend the sparse file to %d bytes", volumeMeta.Size),
+				"Size %d is smaller than the volume size %d", headInfo.Size(), volumeMeta.Size)
+		case headInfo.Size() > volumeMeta.Size:
+			addIssue(types.ReplicaMetaIssueSeverityError, head, "", "Size %d is larger than the volume size %d", headInfo.Size(), volumeMeta.Size)
+		}
+	}
+
+	headMeta, err := readDiskMeta(filepath.Join(replicaDirectory, head+".meta"))
+	if err != nil {
+		addIssue(types.ReplicaMetaIssueSeverityError, head+".meta", "", "%v", err)
+		return inspection
+	}
+	inChain[head+".meta"] = true
+
+	if headMeta.Parent != volumeMeta.Parent {
+		addIssue(types.ReplicaMetaIssueSeverityError, head+".meta", "", "Parent %q differs from the parent %q in %v", headMeta.Parent, volumeMeta.Parent, volumeMetaFileName)
+	}
+
+	for parent := headMeta.Parent; parent != ""; {
+		if inChain[parent] {
+			addIssue(types.ReplicaMetaIssueSeverityError, parent, "", "Snapshot chain contains a loop")
+			break
+		}
+		inChain[parent] = true
+		inChain[parent+".meta"] = true
+		inspection.Chain = append(inspection.Chain, parent)
+
+		info, ok := files[parent]
+		if !ok {
+			addIssue(types.ReplicaMetaIssueSeverityError, parent, "", "Snapshot image file is missing, the snapshot chain is broken")
+		} else if info.Size() > volumeMeta.Size {
+			addIssue(types.ReplicaMetaIssueSeverityError, parent, "", "Size %d is larger than the volume size %d", info.Size(), volumeMeta.Size)
+		}
+
+		meta, err := readDiskMeta(filepath.Join(replicaDirectory, parent+".meta"))
+		if err != nil {
+			addIssue(types.ReplicaMetaIssueSeverityError, parent+".meta", "", "%v", err)
+			break
+		}
+		if meta.Name != "" && meta.Name != parent {
+			addIssue(types.ReplicaMetaIssueSeverityWarn, parent+".meta", "", "Name %q differs from the file name", meta.Name)
+		}
+		parent = meta.Parent
+	}
+
+	for _, name := range sortedFileNames(files) {
+		if inChain[name] || !(strings.HasPrefix(name, snapshotFilePrefix) || strings.HasPrefix(name, headFilePrefix)) {
+			continue
+		}
+		if strings.HasSuffix(name, imageFileSuffix) || strings.HasSuffix(name, imageMetaFileSuffix) {
+			addIssue(types.ReplicaMetaIssueSeverityWarn, name, "", "File is not part of the snapshot chain of the volume head")
+		}
+	}
+
+	return inspection
+}
+
+// repairReplicaDirectory applies the known-safe repairs of the issues of a replica: it removes the
+// temporary files, and extends the volume head image to the volume size. The other issues are left
+// untouched, since fixing them may lose data.
+func repairReplicaDirectory(log *logrus.Entry, replicaDirectory string, inspection *types.ReplicaMetaInspection) {
+	for _, issue := range inspection.Issues {
+		if issue.Repair == "" {
+			continue
+		}
+
+		path := filepath.Join(replicaDirectory, issue.File)
+
+		var err error
+		switch {
+		case strings.HasSuffix(issue.File, temporaryFileExtension):
+			err = os.Remove(path)
+		case issue.File == inspection.Head:
+			err = os.Truncate(path, inspection.Size)
+		default:
+			continue
+		}
+		if err != nil {
+			log.WithError(err).Warnf("Failed to repair %v", issue.File)
+			continue
+		}
+
+		log.Infof("Repaired %v: %v", issue.File, issue.Repair)
+		issue.Repaired = true
+	}
+}
+
+func readVolumeMeta(path string) (*lhmgrutil.VolumeMeta, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %v", filepath.Base(path))
+	}
+
+	meta := &lhmgrutil.VolumeMeta{}
+	if err := json.Unmarshal(data, meta); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %v", filepath.Base(path))
+	}
+	return meta, nil
+}
+
+func readDiskMeta(path string) (*diskMeta, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %v", filepath.Base(path))
+	}
+
+	meta := &diskMeta{}
+	if err := json.Unmarshal(data, meta); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %v", filepath.Base(path))
+	}
+	return meta, nil
+}
+
+func sortedFileNames(files map[string]os.FileInfo) []string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,149 @@
+package replica
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+const testVolumeSize = 4096
+
+func writeReplicaFiles(t *testing.T, files map[string]string, sizes map[string]int64) string {
+	directory := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(directory, name), []byte(content), 0600); err != nil {
+			t.Fatalf("failed to write %v: %v", name, err)
+		}
+	}
+	for name, size := range sizes {
+		if err := os.Truncate(filepath.Join(directory, name), size); err != nil {
+			t.Fatalf("failed to truncate %v: %v", name, err)
+		}
+	}
+	return directory
+}
+
+func countIssues(inspection *types.ReplicaMetaInspection, severity types.ReplicaMetaIssueSeverity) int {
+	count := 0
+	for _, issue := range inspection.Issues {
+		if issue.Severity == severity {
+			count++
+		}
+	}
+	return count
+}
+
+func TestInspectReplicaDirectory(t *testing.T) {
+	consistentFiles := map[string]string{
+		"volume.meta":                 `{"Size":4096,"Head":"volume-head-001.img","Parent":"volume-snap-s1.img","SectorSize":512}`,
+		"volume-head-001.img":         "",
+		"volume-head-001.img.meta":    `{"Name":"volume-head-001.img","Parent":"volume-snap-s1.img"}`,
+		"volume-snap-s1.img":          "",
+		"volume-snap-s1.img.meta":     `{"Name":"volume-snap-s1.img","Parent":""}`,
+		"revision.counter":            "1",
+		"volume-snap-s1.img.checksum": "",
+	}
+	consistentSizes := map[string]int64{"volume-head-001.img": testVolumeSize, "volume-snap-s1.img": testVolumeSize}
+
+	tests := map[string]struct {
+		remove         []string
+		add            map[string]string
+		sizes          map[string]int64
+		expectedErrors int
+		expectedWarns  int
+		expectedChain  int
+	}{
+		"consistent": {
+			expectedChain: 1,
+		},
+		"missing snapshot file": {
+			remove:         []string{"volume-snap-s1.img"},
+			expectedErrors: 1,
+			expectedChain:  1,
+		},
+		"missing volume meta": {
+			remove:         []string{"volume.meta"},
+			expectedErrors: 1,
+		},
+		"head smaller than the volume": {
+			sizes:          map[string]int64{"volume-head-001.img": 1024},
+			expectedErrors: 1,
+			expectedChain:  1,
+		},
+		"orphan snapshot and temporary file": {
+			add:           map[string]string{"volume-snap-s0.img": "", "volume.meta.tmp": "{}"},
+			expectedWarns: 2,
+			expectedChain: 1,
+		},
+		"parent mismatch": {
+			add:            map[string]string{"volume-head-001.img.meta": `{"Name":"volume-head-001.img","Parent":""}`},
+			expectedErrors: 1,
+			expectedWarns:  2,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			files := map[string]string{}
+			for file, content := range consistentFiles {
+				files[file] = content
+			}
+			for file, content := range test.add {
+				files[file] = content
+			}
+			for _, file := range test.remove {
+				delete(files, file)
+			}
+			sizes := map[string]int64{}
+			for file, size := range consistentSizes {
+				if _, ok := files[file]; ok {
+					sizes[file] = size
+				}
+			}
+			for file, size := range test.sizes {
+				sizes[file] = size
+			}
+
+			inspection := inspectReplicaDirectory(writeReplicaFiles(t, files, sizes))
+			if inspection.Error != "" {
+				t.Fatalf("unexpected error: %v", inspection.Error)
+			}
+			if count := countIssues(inspection, types.ReplicaMetaIssueSeverityError); count != test.expectedErrors {
+				t.Errorf("expected %d errors, got %d", test.expectedErrors, count)
+			}
+			if count := countIssues(inspection, types.ReplicaMetaIssueSeverityWarn); count != test.expectedWarns {
+				t.Errorf("expected %d warnings, got %d", test.expectedWarns, count)
+			}
+			if len(inspection.Chain) != test.expectedChain {
+				t.Errorf("expected chain of %d snapshots, got %v", test.expectedChain, inspection.Chain)
+			}
+		})
+	}
+}
+
+func TestRepairReplicaDirectory(t *testing.T) {
+	directory := writeReplicaFiles(t, map[string]string{
+		"volume.meta":              `{"Size":4096,"Head":"volume-head-000.img","Parent":""}`,
+		"volume.meta.tmp":          "{}",
+		"volume-head-000.img":      "",
+		"volume-head-000.img.meta": `{"Name":"volume-head-000.img","Parent":""}`,
+	}, nil)
+
+	inspection := inspectReplicaDirectory(directory)
+	repairReplicaDirectory(logrus.NewEntry(logrus.StandardLogger()), directory, inspection)
+
+	for _, issue := range inspection.Issues {
+		if !issue.Repaired {
+			t.Errorf("expected issue of %v to be repaired: %v", issue.File, issue.Message)
+		}
+	}
+
+	reinspection := inspectReplicaDirectory(directory)
+	if len(reinspection.Issues) != 0 {
+		t.Errorf("expected no issues after repair, got %d", len(reinspection.Issues))
+	}
+}
@@ -0,0 +1,243 @@
+package volume
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/longhorn/cli/pkg/consts"
+	remote "github.com/longhorn/cli/pkg/remote/volume"
+)
+
+// hostRootDirectory is the root directory of the host, seen through the init process of the
+// host PID namespace.
+const hostRootDirectory = "/proc/1/root"
+
+// cryptsetupExitCodeBadPassphrase is the exit code of cryptsetup when no keyslot matches the passphrase.
+const cryptsetupExitCodeBadPassphrase = 2
+
+// luksKeyslotRegex matches the keyslots listed by cryptsetup luksDump for LUKS2 devices.
+var luksKeyslotRegex = regexp.MustCompile(`(?m)^\s+\d+: luks2$`)
+
+// Rekeyer provide functions for rotating the encryption key of an encrypted volume.
+type Rekeyer struct {
+	remote.RekeyerCmdOptions
+
+	logger *logrus.Entry
+
+	devicePath string
+	currentKey string
+	newKey     string
+}
+
+// Validate validates the command options.
+func (local *Rekeyer) Validate() error {
+	if local.VolumeName == "" {
+		return errors.Errorf("Longhorn volume name (--%s) is required", consts.CmdOptLonghornVolumeName)
+	}
+
+	return nil
+}
+
+// Init initializes the Rekeyer. The keys are only read from the environment, so they do not
+// appear in the arguments of the process.
+func (local *Rekeyer) Init() error {
+	local.logger = logrus.WithField("volume", local.VolumeName)
+
+	local.currentKey = os.Getenv(consts.EnvCryptoKeyValue)
+	local.newKey = os.Getenv(consts.EnvNewCryptoKeyValue)
+	if local.currentKey == "" || local.newKey == "" {
+		return errors.Errorf("both %v and %v are required", consts.EnvCryptoKeyValue, consts.EnvNewCryptoKeyValue)
+	}
+
+	local.devicePath = filepath.Join("/dev/longhorn", local.VolumeName)
+	if _, err := os.Stat(filepath.Join(hostRootDirectory, local.devicePath)); err != nil {
+		return errors.Wrapf(err, "failed to find the device of volume %v", local.VolumeName)
+	}
+
+	mapperPath := filepath.Join(hostRootDirectory, "/dev/mapper", local.VolumeName)
+	if _, err := os.Stat(mapperPath); err == nil {
+		return errors.Errorf("encrypted device of volume %v is open, the volume must not be used during the rotation", local.VolumeName)
+	}
+
+	return nil
+}
+
+// Run rotates the key of the volume. The data is re-encrypted with a new volume key using the
+// current passphrase, unless KeyOnly is set, then the new passphrase is added and the current one
+// removed. Each step is skipped when a previous interrupted run already completed it, and an
+// interrupted re-encryption is resumed. When a step fails after the new passphrase is added, the
+// new passphrase is removed again, so the volume is only opened with the current one.
+func (local *Rekeyer) Run() error {
+	currentKeyValid, err := local.testPassphrase(local.currentKey)
+	if err != nil {
+		return err
+	}
+	newKeyValid, err := local.testPassphrase(local.newKey)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case !currentKeyValid && newKeyValid:
+		local.logger.Info("Volume is already rekeyed, only the new key opens it")
+		return nil
+	case !currentKeyValid:
+		return errors.New("neither the current key nor the new key opens the volume")
+	}
+
+	sameKey := local.currentKey == local.newKey
+	dump, err := local.cryptsetup("", nil, "luksDump", local.devicePath)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case isReencryptionInProgress(dump):
+		local.logger.Info("Resuming interrupted re-encryption")
+		if _, err := local.cryptsetup(local.currentKey, local.logProgress, "reencrypt", "--resume-only", "--batch-mode", "--progress-frequency", "10", "--key-file", "-", local.devicePath); err != nil {
+			return errors.Wrap(err, "failed to resume re-encryption, run the command again to resume it")
+		}
+	case local.KeyOnly:
+		local.logger.Info("Skipping re-encryption, only the passphrase is replaced")
+	case newKeyValid && !sameKey:
+		local.logger.Info("Skipping re-encryption, completed by a previous run")
+	default:
+		if keyslots := countKeyslots(dump); keyslots != 1 {
+			return errors.Errorf("volume has %d keyslots, re-encryption is only supported with a single keyslot", keyslots)
+		}
+
+		local.logger.Info("Re-encrypting volume with a new volume key")
+		if _, err := local.cryptsetup(local.currentKey, local.logProgress, "reencrypt", "--batch-mode", "--progress-frequency", "10", "--key-file", "-", local.devicePath); err != nil {
+			return errors.Wrap(err, "failed to re-encrypt volume, run the command again to resume it")
+		}
+	}
+
+	if sameKey {
+		return nil
+	}
+
+	if !newKeyValid {
+		local.logger.Info("Adding new passphrase")
+		if _, err := local.cryptsetup(local.currentKey+"\n"+local.newKey+"\n", nil, "luksAddKey", "--batch-mode", local.devicePath); err != nil {
+			return errors.Wrap(err, "failed to add new passphrase")
+		}
+	}
+
+	if err := local.removeCurrentKey(); err != nil {
+		local.logger.WithError(err).Warn("Rolling back new passphrase")
+		if _, rollbackErr := local.cryptsetup(local.newKey, nil, "luksRemoveKey", "--batch-mode", "--key-file", "-", local.devicePath); rollbackErr != nil {
+			local.logger.WithError(rollbackErr).Warn("Failed to roll back new passphrase, both passphrases open the volume")
+		}
+		return err
+	}
+
+	return nil
+}
+
+// removeCurrentKey verifies that the new passphrase opens the volume, and removes the current one.
+func (local *Rekeyer) removeCurrentKey() error {
+	newKeyValid, err := local.testPassphrase(local.newKey)
+	if err != nil {
+		return err
+	}
+	if !newKeyValid {
+		return errors.New("new passphrase does not open the volume")
+	}
+
+	local.logger.Info("Removing current passphrase")
+	if _, err := local.cryptsetup(local.currentKey, nil, "luksRemoveKey", "--batch-mode", "--key-file", "-", local.devicePath); err != nil {
+		return errors.Wrap(err, "failed to remove current passphrase")
+	}
+	return nil
+}
+
+// testPassphrase returns whether the passphrase opens a keyslot of the device.
+func (local *Rekeyer) testPassphrase(passphrase string) (bool, error) {
+	_, err := local.cryptsetup(passphrase, nil, "open", "--test-passphrase", "--key-file", "-", local.devicePath)
+	if err == nil {
+		return true, nil
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == cryptsetupExitCodeBadPassphrase {
+		return false, nil
+	}
+	return false, err
+}
+
+func (local *Rekeyer) logProgress(line string) {
+	local.logger.Info(line)
+}
+
+// cryptsetup runs cryptsetup in the mount and IPC namespaces of the host with the input, passes
+// each line of the output to the handler, and returns the output.
+func (local *Rekeyer) cryptsetup(input string, handler func(line string), args ...string) (string, error) {
+	cmd := exec.Command("nsenter", append([]string{"--target", "1", "--mount", "--ipc", "--", "cryptsetup"}, args...)...)
+	cmd.Stdin = strings.NewReader(input)
+
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return "", err
+	}
+	if err := cmd.Start(); err != nil {
+		return "", errors.Wrapf(err, "failed to run cryptsetup %v", args[0])
+	}
+
+	output := &strings.Builder{}
+	scanner := bufio.NewScanner(stdout)
+	scanner.Split(scanProgressLines)
+	for scanner.Scan() {
+		output.WriteString(scanner.Text() + "\n")
+		if handler != nil {
+			handler(scanner.Text())
+		}
+	}
+
+	if err := cmd.Wait(); err != nil {
+		return output.String(), errors.Wrapf(err, "cryptsetup %v failed: %v", args[0], strings.TrimSpace(stderr.String()))
+	}
+	return output.String(), nil
+}
+
+// scanProgressLines is a bufio.SplitFunc splitting the output at line feeds and carriage returns,
+// since cryptsetup rewrites the progress line with carriage returns. Empty lines are skipped.
+func scanProgressLines(data []byte, atEOF bool) (int, []byte, error) {
+	start := 0
+	for start < len(data) && (data[start] == '\n' || data[start] == '\r') {
+		start++
+	}
+
+	if i := bytes.IndexAny(data[start:], "\r\n"); i >= 0 {
+		return start + i + 1, data[start : start+i], nil
+	}
+	if atEOF && start < len(data) {
+		return len(data), data[start:], nil
+	}
+	return start, nil, nil
+}
+
+// isReencryptionInProgress returns whether the luksDump output shows an unfinished re-encryption.
+func isReencryptionInProgress(dump string) bool {
+	for _, line := range strings.Split(dump, "\n") {
+		if strings.HasPrefix(line, "Requirements:") && strings.Contains(line, "reencrypt") {
+			return true
+		}
+	}
+	return false
+}
+
+// countKeyslots returns the number of keyslots in the luksDump output.
+func countKeyslots(dump string) int {
+	return len(luksKeyslotRegex.FindAllString(dump, -1))
+}
@@ -0,0 +1,82 @@
+package volume
+
+import (
+	"bufio"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const luksDumpReencrypting = `LUKS header information
+Version:       	2
+Epoch:         	9
+Metadata area: 	16384 [bytes]
+Keyslots area: 	16744448 [bytes]
+UUID:          	3f1b7a2c-5a8e-4c7b-9a41-2d0e6f1c9b55
+Label:         	(no label)
+Subsystem:     	(no subsystem)
+Flags:       	(no flags)
+Requirements:	online-reencrypt-v2
+
+Data segments:
+  0: crypt
+	offset: 16777216 [bytes]
+	length: 536870912 [bytes]
+	cipher: aes-xts-plain64
+
+Keyslots:
+  0: luks2
+	Key:        512 bits
+  1: luks2
+	Key:        512 bits
+  2: reencrypt (unbound)
+	Key:        8 bits
+`
+
+func TestScanProgressLines(t *testing.T) {
+	tests := map[string]struct {
+		output   string
+		expected []string
+	}{
+		"line feeds": {
+			output:   "Finished, time 00:55\nDone\n",
+			expected: []string{"Finished, time 00:55", "Done"},
+		},
+		"carriage returns": {
+			output:   "Progress:  10.0%\rProgress:  20.0%\r\nFinished",
+			expected: []string{"Progress:  10.0%", "Progress:  20.0%", "Finished"},
+		},
+		"empty": {},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var lines []string
+			scanner := bufio.NewScanner(strings.NewReader(test.output))
+			scanner.Split(scanProgressLines)
+			for scanner.Scan() {
+				lines = append(lines, scanner.Text())
+			}
+			if err := scanner.Err(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(lines, test.expected) {
+				t.Errorf("expected %q, got %q", test.expected, lines)
+			}
+		})
+	}
+}
+
+func TestLuksDump(t *testing.T) {
+	if !isReencryptionInProgress(luksDumpReencrypting) {
+		t.Errorf("expected re-encryption in progress")
+	}
+	if count := countKeyslots(luksDumpReencrypting); count != 2 {
+		t.Errorf("expected 2 keyslots, got %d", count)
+	}
+
+	idle := strings.Replace(luksDumpReencrypting, "Requirements:\tonline-reencrypt-v2", "Requirements:\t(no requirements)", 1)
+	if isReencryptionInProgress(idle) {
+		t.Errorf("expected no re-encryption in progress")
+	}
+}
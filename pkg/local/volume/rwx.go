@@ -0,0 +1,233 @@
+package volume
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+
+	remote "github.com/longhorn/cli/pkg/remote/volume"
+)
+
+// mountResponseTimeout is the maximum time to wait for a NFS mount to respond before it is reported
+// as hung.
+const mountResponseTimeout = 10 * time.Second
+
+// nfsKernelModules are the kernel modules of the NFS client used by the share manager mounts.
+var nfsKernelModules = []string{"nfs", "nfsv4"}
+
+// RwxChecker provide functions for checking the NFS client mounts of a ReadWriteMany volume on the node.
+type RwxChecker struct {
+	remote.RwxCheckerCmdOptions
+
+	logger *logrus.Entry
+
+	OutputFilePath string
+	ShareEndpoint  string // NFS endpoint of the share manager, as nfs://<server>/<volume>.
+
+	// HostRootDirectory is the directory of the host root filesystem.
+	// It is "/" when running directly on the host instead of in a DaemonSet pod.
+	HostRootDirectory string
+
+	collection types.NodeCollection
+}
+
+// nfsMount is a NFS mount read from the mount table of the host.
+type nfsMount struct {
+	Server     string
+	Export     string
+	MountPoint string
+	FsType     string
+	Options    []string
+}
+
+// Validate validates the command options.
+func (local *RwxChecker) Validate() error {
+	if local.VolumeName == "" {
+		return errors.Errorf("Longhorn volume name (--%s) is required", consts.CmdOptLonghornVolumeName)
+	}
+
+	return nil
+}
+
+// Init initializes the RwxChecker.
+func (local *RwxChecker) Init() error {
+	local.collection.Log = &types.LogCollection{}
+	local.logger = logrus.WithFields(logrus.Fields{"component": "rwx", "volume": local.VolumeName})
+
+	if local.HostRootDirectory == "" {
+		local.HostRootDirectory = consts.VolumeMountHostDirectory
+	}
+
+	return nil
+}
+
+// Run checks the NFS mounts of the volume on the host, and the NFS client kernel modules.
+func (local *RwxChecker) Run() error {
+	log := local.collection.Log
+
+	if release, err := os.ReadFile(filepath.Join(local.HostRootDirectory, "proc/sys/kernel/osrelease")); err == nil {
+		log.Info = append(log.Info, fmt.Sprintf("Kernel release is %v", strings.TrimSpace(string(release))))
+	}
+	checkNfsKernelModules(log, filepath.Join(local.HostRootDirectory, "sys"))
+
+	server := ""
+	if local.ShareEndpoint != "" {
+		var err error
+		if server, err = parseShareEndpoint(local.ShareEndpoint); err != nil {
+			log.Warn = append(log.Warn, err.Error())
+		}
+	}
+
+	mountTable, err := os.ReadFile(filepath.Join(local.HostRootDirectory, "proc/1/mounts"))
+	if err != nil {
+		return errors.Wrap(err, "failed to read mounts of the host")
+	}
+
+	mounts := findNfsMounts(string(mountTable), local.VolumeName)
+	checkNfsMounts(log, mounts, server)
+	for _, mount := range mounts {
+		if err := local.checkMountResponsive(mount.MountPoint); err != nil {
+			log.Error = append(log.Error, fmt.Sprintf("Mount %v is not usable: %v", mount.MountPoint, err))
+		}
+	}
+
+	return nil
+}
+
+// Output converts the collection to JSON and output to stdout or the output file.
+func (local *RwxChecker) Output() error {
+	local.logger.Trace("Outputting RWX checker results")
+
+	jsonBytes, err := json.Marshal(local.collection)
+	if err != nil {
+		return errors.Wrap(err, "failed to convert collection to JSON")
+	}
+
+	return utils.HandleResult(jsonBytes, local.OutputFilePath, local.logger)
+}
+
+// checkMountResponsive stats the mount point, and returns an error when the file handle is stale
+// or the server does not respond in time. A hung stat is left behind, since it cannot be interrupted.
+func (local *RwxChecker) checkMountResponsive(mountPoint string) error {
+	result := make(chan error, 1)
+	go func() {
+		_, err := os.Stat(filepath.Join(local.HostRootDirectory, mountPoint))
+		result <- err
+	}()
+
+	select {
+	case err := <-result:
+		if errors.Is(err, syscall.ESTALE) {
+			return errors.New("stale file handle, the share manager was restarted without the mount being recovered")
+		}
+		return err
+	case <-time.After(mountResponseTimeout):
+		return errors.Errorf("no response within %v", mountResponseTimeout)
+	}
+}
+
+// parseShareEndpoint returns the server of the share manager endpoint.
+func parseShareEndpoint(endpoint string) (string, error) {
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil || endpointURL.Scheme != "nfs" || endpointURL.Hostname() == "" {
+		return "", errors.Errorf("share manager endpoint %q is not a NFS endpoint", endpoint)
+	}
+	return endpointURL.Hostname(), nil
+}
+
+// findNfsMounts returns the NFS mounts of the volume in the mount table, in the /proc/mounts format.
+// The share manager exports the volume as /<volume>.
+func findNfsMounts(mountTable, volumeName string) []nfsMount {
+	mounts := []nfsMount{}
+	for _, line := range strings.Split(mountTable, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 || (fields[2] != "nfs" && fields[2] != "nfs4") {
+			continue
+		}
+
+		index := strings.LastIndex(fields[0], ":")
+		if index < 0 {
+			continue
+		}
+		export := strings.TrimSuffix(fields[0][index+1:], "/")
+		if export != "/"+volumeName {
+			continue
+		}
+
+		mounts = append(mounts, nfsMount{
+			Server:     strings.Trim(fields[0][:index], "[]"),
+			Export:     export,
+			MountPoint: fields[1],
+			FsType:     fields[2],
+			Options:    strings.Split(fields[3], ","),
+		})
+	}
+	return mounts
+}
+
+// checkNfsMounts checks the NFS mounts of the volume use the server of the share manager endpoint,
+// NFSv4, and are writable. The server is not checked when it is empty.
+func checkNfsMounts(log *types.LogCollection, mounts []nfsMount, server string) {
+	if len(mounts) == 0 {
+		log.Error = append(log.Error, "No NFS mount of the volume was found, the workload pods on the node cannot access it")
+		return
+	}
+
+	for _, mount := range mounts {
+		version := ""
+		readOnly := false
+		for _, option := range mount.Options {
+			switch {
+			case strings.HasPrefix(option, "vers="):
+				version = strings.TrimPrefix(option, "vers=")
+			case option == "ro":
+				readOnly = true
+			}
+		}
+
+		log.Info = append(log.Info, fmt.Sprintf("Mount %v from %v:%v uses NFS version %v", mount.MountPoint, mount.Server, mount.Export, version))
+
+		if server != "" && mount.Server != server {
+			log.Warn = append(log.Warn, fmt.Sprintf("Mount %v uses server %v instead of the share manager endpoint %v, it may be left from a previous share manager", mount.MountPoint, mount.Server, server))
+		}
+		if !strings.HasPrefix(version, "4") {
+			log.Error = append(log.Error, fmt.Sprintf("Mount %v uses NFS version %v, the share manager only serves NFSv4", mount.MountPoint, version))
+		}
+		if readOnly {
+			log.Warn = append(log.Warn, fmt.Sprintf("Mount %v is read-only", mount.MountPoint))
+		}
+	}
+}
+
+// checkNfsKernelModules checks the NFS client kernel modules are loaded, and reports their version.
+// The modules without version report the source checksum instead.
+func checkNfsKernelModules(log *types.LogCollection, sysDirectory string) {
+	for _, module := range nfsKernelModules {
+		moduleDirectory := filepath.Join(sysDirectory, "module", module)
+		if _, err := os.Stat(moduleDirectory); err != nil {
+			log.Error = append(log.Error, fmt.Sprintf("Kernel module %v is not loaded", module))
+			continue
+		}
+
+		version := "built-in"
+		for _, name := range []string{"version", "srcversion"} {
+			if data, err := os.ReadFile(filepath.Join(moduleDirectory, name)); err == nil {
+				version = strings.TrimSpace(string(data))
+				break
+			}
+		}
+		log.Info = append(log.Info, fmt.Sprintf("Kernel module %v is loaded (%v)", module, version))
+	}
+}
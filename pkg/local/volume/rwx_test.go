@@ -0,0 +1,87 @@
+package volume
+
+import (
+	"testing"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func TestParseShareEndpoint(t *testing.T) {
+	tests := map[string]struct {
+		endpoint       string
+		expectedServer string
+		expectedError  bool
+	}{
+		"ip":       {endpoint: "nfs://10.43.12.7/vol", expectedServer: "10.43.12.7"},
+		"hostname": {endpoint: "nfs://vol.longhorn-system.svc.cluster.local/vol", expectedServer: "vol.longhorn-system.svc.cluster.local"},
+		"ipv6":     {endpoint: "nfs://[fd00::7]/vol", expectedServer: "fd00::7"},
+		"scheme":   {endpoint: "http://10.43.12.7/vol", expectedError: true},
+		"empty":    {endpoint: "", expectedError: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			server, err := parseShareEndpoint(test.endpoint)
+			if test.expectedError != (err != nil) {
+				t.Fatalf("expected error %v, got %v", test.expectedError, err)
+			}
+			if server != test.expectedServer {
+				t.Errorf("expected server %q, got %q", test.expectedServer, server)
+			}
+		})
+	}
+}
+
+func TestCheckNfsMounts(t *testing.T) {
+	const mountPoint = "/var/lib/kubelet/pods/uid/volumes/kubernetes.io~csi/vol/mount"
+
+	tests := map[string]struct {
+		mountTable       string
+		server           string
+		expectedMounts   int
+		expectedErrors   int
+		expectedWarnings int
+	}{
+		"nfs4 mount": {
+			mountTable: "/dev/sda1 / ext4 rw 0 0\n" +
+				"10.43.12.7:/vol " + mountPoint + " nfs4 rw,relatime,vers=4.1,softerr 0 0\n",
+			server:         "10.43.12.7",
+			expectedMounts: 1,
+		},
+		"other volume": {
+			mountTable:     "10.43.12.7:/vol-2 " + mountPoint + " nfs4 rw,vers=4.1 0 0\n",
+			server:         "10.43.12.7",
+			expectedErrors: 1,
+		},
+		"previous server": {
+			mountTable:       "10.43.12.8:/vol/ " + mountPoint + " nfs4 rw,vers=4.1 0 0\n",
+			server:           "10.43.12.7",
+			expectedMounts:   1,
+			expectedWarnings: 1,
+		},
+		"nfsv3 read-only": {
+			mountTable:       "10.43.12.7:/vol " + mountPoint + " nfs ro,vers=3 0 0\n",
+			expectedMounts:   1,
+			expectedErrors:   1,
+			expectedWarnings: 1,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mounts := findNfsMounts(test.mountTable, "vol")
+			if len(mounts) != test.expectedMounts {
+				t.Fatalf("expected %d mounts, got %v", test.expectedMounts, mounts)
+			}
+
+			log := &types.LogCollection{}
+			checkNfsMounts(log, mounts, test.server)
+			if len(log.Error) != test.expectedErrors {
+				t.Errorf("expected %d errors, got %v", test.expectedErrors, log.Error)
+			}
+			if len(log.Warn) != test.expectedWarnings {
+				t.Errorf("expected %d warnings, got %v", test.expectedWarnings, log.Warn)
+			}
+		})
+	}
+}
@@ -0,0 +1,299 @@
+package airgap
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/longhorn/cli/meta"
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+	"github.com/longhorn/cli/pkg/utils/registry"
+
+	pkgmgr "github.com/longhorn/cli/pkg/local/preflight/packagemanager"
+)
+
+// SupportedOperatingSystems are the operating systems of the --os option.
+var SupportedOperatingSystems = []string{consts.AirgapOSRHEL, consts.AirgapOSSLES, consts.AirgapOSUbuntu}
+
+const imageDownloadTimeout = 30 * time.Minute
+
+// BundleGenerator provide functions for generating the air-gap bundle of the preflight install.
+type BundleGenerator struct {
+	BundleGeneratorCmdOptions
+
+	packageManager pkgmgr.PackageManagerType
+	requirements   *pkgmgr.Requirements
+}
+
+// BundleGeneratorCmdOptions holds the options for the command.
+type BundleGeneratorCmdOptions struct {
+	types.GlobalCmdOptions
+
+	OperatingSystem string // Operating system of the nodes (rhel, sles, ubuntu).
+	OutputFilePath  string // Path of the bundle archive. Defaults to longhorn-airgap-bundle-<os>.tar.gz.
+	Platform        string // Platform of the utility image, such as linux/amd64.
+	EnableSpdk      bool   // Include the kernel modules of SPDK in the expectations of the bundle.
+	SkipImage       bool   // Do not include the utility image, such as when it is mirrored to a private registry.
+}
+
+// Validate validates the command options.
+func (remote *BundleGenerator) Validate() error {
+	supported := false
+	for _, operatingSystem := range SupportedOperatingSystems {
+		if remote.OperatingSystem == operatingSystem {
+			supported = true
+			break
+		}
+	}
+	if !supported {
+		return errors.Errorf("unsupported operating system %q (--%s), supported operating systems: %s", remote.OperatingSystem, consts.CmdOptOS, strings.Join(SupportedOperatingSystems, ", "))
+	}
+
+	if !remote.SkipImage && len(strings.Split(remote.Platform, "/")) < 2 {
+		return errors.Errorf("invalid platform %q (--%s), expected os/architecture[/variant], such as linux/amd64", remote.Platform, consts.CmdOptPlatform)
+	}
+
+	return nil
+}
+
+// Init initializes the BundleGenerator.
+func (remote *BundleGenerator) Init() error {
+	if remote.OutputFilePath == "" {
+		remote.OutputFilePath = fmt.Sprintf("%s-%s.tar.gz", consts.AirgapBundleDirectory, remote.OperatingSystem)
+	}
+
+	packageManager, err := utils.GetPackageManagerType(remote.OperatingSystem)
+	if err != nil {
+		return err
+	}
+	remote.packageManager = packageManager
+
+	requirements, err := pkgmgr.GetRequirements(packageManager, "")
+	if err != nil {
+		return err
+	}
+	remote.requirements = requirements
+
+	return nil
+}
+
+// Run writes the bundle archive, and returns the manifest of the bundle.
+func (remote *BundleGenerator) Run() (*types.AirgapBundleManifest, error) {
+	manifest := remote.newManifest()
+
+	var imageArchive *os.File
+	if !remote.SkipImage {
+		var err error
+		imageArchive, err = remote.saveImage()
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			_ = imageArchive.Close()
+			_ = os.Remove(imageArchive.Name())
+		}()
+		manifest.ImageArchive = path.Join(consts.AirgapImagesDirectory, consts.FileNameAirgapImageArchive)
+	}
+
+	manifestData, err := yaml.Marshal(manifest)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to convert the bundle manifest to YAML")
+	}
+
+	file, err := os.Create(remote.OutputFilePath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create bundle %v", remote.OutputFilePath)
+	}
+	defer file.Close()
+
+	gzipWriter := gzip.NewWriter(file)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	files := []struct {
+		name string
+		mode int64
+		data []byte
+	}{
+		{consts.FileNameAirgapManifest, 0644, manifestData},
+		{consts.FileNameAirgapReadme, 0644, []byte(remote.newReadme(manifest))},
+		{path.Join(consts.AirgapPackagesDirectory, consts.FileNameAirgapDownloadScript), 0755, []byte(remote.newDownloadScript())},
+	}
+	for _, bundleFile := range files {
+		if err := writeBundleFile(tarWriter, bundleFile.name, bundleFile.mode, int64(len(bundleFile.data)), bytes.NewReader(bundleFile.data)); err != nil {
+			return nil, err
+		}
+	}
+
+	if imageArchive != nil {
+		info, err := imageArchive.Stat()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := imageArchive.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		if err := writeBundleFile(tarWriter, manifest.ImageArchive, 0644, info.Size(), imageArchive); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return nil, errors.Wrapf(err, "failed to write bundle %v", remote.OutputFilePath)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return nil, errors.Wrapf(err, "failed to write bundle %v", remote.OutputFilePath)
+	}
+	return manifest, file.Close()
+}
+
+func (remote *BundleGenerator) newManifest() *types.AirgapBundleManifest {
+	modules := remote.requirements.Modules
+	if remote.EnableSpdk {
+		modules = append(append([]string{}, modules...), remote.requirements.SpdkDepModules...)
+	}
+
+	return &types.AirgapBundleManifest{
+		Version:         meta.Version,
+		OperatingSystem: remote.OperatingSystem,
+		PackageManager:  string(remote.packageManager),
+		Platform:        remote.Platform,
+		Packages:        remote.requirements.Packages,
+		Modules:         modules,
+		Services:        remote.requirements.Services,
+		Image:           remote.Image,
+	}
+}
+
+// saveImage downloads the utility image of the platform to a temporary docker-archive tarball.
+func (remote *BundleGenerator) saveImage() (*os.File, error) {
+	logrus.Infof("Downloading image %v for platform %v", remote.Image, remote.Platform)
+
+	file, err := os.CreateTemp("", consts.AirgapBundleDirectory+"-*.tar")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create the image archive")
+	}
+
+	ref := registry.ParseImageReference(remote.Image)
+	httpClient := &http.Client{Timeout: imageDownloadTimeout}
+	if err := registry.SaveImage(httpClient, registry.GetUpstreamEndpoint(ref.Registry), ref, remote.Image, remote.Platform, file); err != nil {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+		return nil, errors.Wrapf(err, "failed to download image %v", remote.Image)
+	}
+	return file, nil
+}
+
+// newDownloadScript returns the script downloading the packages and their dependencies next to
+// it. It runs on a host with internet access and the same release of the operating system as the
+// nodes, ideally a fresh container, so that the dependencies installed on the nodes are
+// downloaded too.
+func (remote *BundleGenerator) newDownloadScript() string {
+	packages := strings.Join(remote.requirements.Packages, " ")
+
+	var download string
+	switch remote.packageManager {
+	case pkgmgr.PackageManagerApt:
+		download = `apt-get update
+apt-get install -y --download-only --reinstall -o Dir::Cache::archives="$dir" ` + packages + `
+rm -rf "$dir/partial" "$dir/lock"`
+	case pkgmgr.PackageManagerYum:
+		download = `yum install -y --downloadonly --downloaddir="$dir" ` + packages
+	default:
+		download = `zypper --non-interactive --pkg-cache-dir "$dir/.cache" install --download-only --force ` + packages + `
+find "$dir/.cache" -name '*.rpm' -exec mv {} "$dir" \;
+rm -rf "$dir/.cache"`
+	}
+
+	return `#!/bin/sh
+# Downloads the packages of the Longhorn preflight install for ` + remote.OperatingSystem + `, with their dependencies,
+# to the directory of this script. Run it as root on a host with internet access and the same
+# release of the operating system as the nodes, such as a fresh container of the release.
+set -eu
+dir="$(cd "$(dirname "$0")" && pwd)"
+` + download + `
+echo "Downloaded the packages to $dir"
+`
+}
+
+func (remote *BundleGenerator) newReadme(manifest *types.AirgapBundleManifest) string {
+	var image string
+	if manifest.ImageArchive != "" {
+		image = fmt.Sprintf("Load the utility image from %v to the private registry of the cluster, or to the container runtime of each node:\n\n"+
+			"    docker load -i %v\n"+
+			"    ctr -n k8s.io images import %v\n", manifest.ImageArchive, manifest.ImageArchive, manifest.ImageArchive)
+	} else {
+		image = fmt.Sprintf("Mirror the utility image %v to the private registry of the cluster.\n", manifest.Image)
+	}
+
+	return fmt.Sprintf(`# Longhorn air-gap bundle for %[1]v
+
+Generated by longhornctl %[2]v for the preflight install of nodes without internet access.
+
+## 1. Download the packages
+
+The bundle lists the packages of the %[3]v package manager. Download them with their dependencies
+on a host with internet access and the same release of %[1]v as the nodes:
+
+    ./%[4]v/%[5]v
+
+## 2. Copy the packages to the nodes
+
+Copy the %[4]v directory to the same path on each node, such as /opt/longhorn/%[4]v.
+
+## 3. Provide the utility image
+
+%[6]v
+## 4. Run the preflight install
+
+    longhornctl install preflight --image=<registry>/%[7]v --package-source=/opt/longhorn/%[4]v
+
+The packages are installed from the package source instead of the package repositories, which are
+not updated.
+
+## Requirements not in the bundle
+
+- Kernel modules, loaded by the preflight install: %[8]v. They are provided by the kernel of the
+  nodes, with linux-modules-extra on Ubuntu for the SPDK modules.
+- Services, started by the preflight install: %[9]v.
+`, manifest.OperatingSystem, manifest.Version, manifest.PackageManager, consts.AirgapPackagesDirectory, consts.FileNameAirgapDownloadScript,
+		image, imageName(manifest.Image), strings.Join(manifest.Modules, ", "), strings.Join(manifest.Services, ", "))
+}
+
+// imageName returns the image without its registry, to be prefixed with the private registry.
+func imageName(image string) string {
+	ref := registry.ParseImageReference(image)
+	separator := ":"
+	if strings.Contains(ref.Reference, ":") {
+		separator = "@"
+	}
+	return strings.TrimPrefix(ref.Repository, "library/") + separator + ref.Reference
+}
+
+func writeBundleFile(tarWriter *tar.Writer, name string, mode, size int64, reader io.Reader) error {
+	if err := tarWriter.WriteHeader(&tar.Header{
+		Name:    path.Join(consts.AirgapBundleDirectory, name),
+		Mode:    mode,
+		Size:    size,
+		ModTime: time.Now(),
+	}); err != nil {
+		return errors.Wrapf(err, "failed to write %v to the bundle", name)
+	}
+	if _, err := io.Copy(tarWriter, reader); err != nil {
+		return errors.Wrapf(err, "failed to write %v to the bundle", name)
+	}
+	return nil
+}
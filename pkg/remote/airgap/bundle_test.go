@@ -0,0 +1,112 @@
+package airgap
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func TestBundleGeneratorValidate(t *testing.T) {
+	tests := map[string]BundleGeneratorCmdOptions{
+		"unsupported os":   {OperatingSystem: "windows", Platform: "linux/amd64"},
+		"invalid platform": {OperatingSystem: consts.AirgapOSUbuntu, Platform: "amd64"},
+	}
+	for name, options := range tests {
+		generator := &BundleGenerator{BundleGeneratorCmdOptions: options}
+		if err := generator.Validate(); err == nil {
+			t.Errorf("%v: expected an error", name)
+		}
+	}
+}
+
+func TestBundleGeneratorRun(t *testing.T) {
+	tests := map[string]struct {
+		packages       []string
+		downloadScript string
+	}{
+		consts.AirgapOSUbuntu: {[]string{"nfs-common", "open-iscsi", "cryptsetup"}, "apt-get install -y --download-only"},
+		consts.AirgapOSRHEL:   {[]string{"nfs-utils", "iscsi-initiator-utils", "cryptsetup"}, "yum install -y --downloadonly"},
+		consts.AirgapOSSLES:   {[]string{"nfs-client", "open-iscsi", "cryptsetup"}, "install --download-only"},
+	}
+
+	for operatingSystem, test := range tests {
+		t.Run(operatingSystem, func(t *testing.T) {
+			generator := &BundleGenerator{BundleGeneratorCmdOptions: BundleGeneratorCmdOptions{
+				GlobalCmdOptions: types.GlobalCmdOptions{Image: "longhornio/longhorn-cli:v1.8.0"},
+				OperatingSystem:  operatingSystem,
+				OutputFilePath:   filepath.Join(t.TempDir(), "bundle.tar.gz"),
+				SkipImage:        true,
+			}}
+			if err := generator.Validate(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := generator.Init(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, err := generator.Run(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			files := readBundle(t, generator.OutputFilePath)
+
+			var manifest types.AirgapBundleManifest
+			if err := yaml.Unmarshal([]byte(files[consts.FileNameAirgapManifest]), &manifest); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(manifest.Packages, test.packages) || manifest.ImageArchive != "" {
+				t.Errorf("unexpected manifest %+v", manifest)
+			}
+
+			script := files[filepath.Join(consts.AirgapPackagesDirectory, consts.FileNameAirgapDownloadScript)]
+			if !strings.Contains(script, test.downloadScript) || !strings.Contains(script, strings.Join(test.packages, " ")) {
+				t.Errorf("unexpected download script:\n%s", script)
+			}
+
+			if readme := files[consts.FileNameAirgapReadme]; !strings.Contains(readme, "--package-source=/opt/longhorn/packages") {
+				t.Errorf("unexpected readme:\n%s", readme)
+			}
+		})
+	}
+}
+
+// readBundle returns the files of the bundle by their path in the bundle directory.
+func readBundle(t *testing.T, path string) map[string]string {
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer file.Close()
+
+	gzipReader, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	files := map[string]string{}
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		data, err := io.ReadAll(tarReader)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		files[strings.TrimPrefix(header.Name, consts.AirgapBundleDirectory+"/")] = string(data)
+	}
+	return files
+}
@@ -0,0 +1,504 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/remote/preflight"
+	"github.com/longhorn/cli/pkg/remote/replica"
+	"github.com/longhorn/cli/pkg/remote/volume"
+	"github.com/longhorn/cli/pkg/types"
+)
+
+// Operations supported by the API server.
+const (
+	OperationCheckPreflight   = "check-preflight"
+	OperationInstallPreflight = "install-preflight"
+	OperationTrimVolume       = "trim-volume"
+	OperationExportReplica    = "export-replica"
+)
+
+const (
+	// jobRetention is how long a completed job and its result are kept.
+	jobRetention = 24 * time.Hour
+	// maxJobs is the maximum number of jobs kept. Submissions are rejected when all of them are
+	// pending or running.
+	maxJobs = 100
+)
+
+// operationFunc runs an operation with the JSON encoded options and returns its result.
+type operationFunc func(globalOpts types.GlobalCmdOptions, options json.RawMessage) (string, error)
+
+// Server provide functions for serving the CLI operations over an authenticated HTTP API.
+type Server struct {
+	ServerCmdOptions
+
+	operations map[string]operationFunc
+
+	// runLock ensures only one operation runs at a time, because operations
+	// of the same kind share the names of the resources they deploy.
+	runLock sync.Mutex
+
+	lock    sync.RWMutex
+	jobs    map[string]*types.ApiJob
+	results map[string]string
+
+	jobRetention time.Duration
+	maxJobs      int
+}
+
+// ServerCmdOptions holds the options for the command.
+type ServerCmdOptions struct {
+	types.GlobalCmdOptions
+
+	ListenAddress string
+	Token         string
+	TLSCertFile   string
+	TLSKeyFile    string
+}
+
+// Validate validates the command options.
+func (remote *Server) Validate() error {
+	if remote.Token == "" {
+		return errors.Errorf("API token (--%s or %s) is required", consts.CmdOptToken, consts.EnvApiToken)
+	}
+
+	if (remote.TLSCertFile == "") != (remote.TLSKeyFile == "") {
+		return errors.Errorf("--%s and --%s must be set together", consts.CmdOptTLSCert, consts.CmdOptTLSKey)
+	}
+
+	return nil
+}
+
+// Init initializes the Server.
+func (remote *Server) Init() error {
+	remote.jobs = map[string]*types.ApiJob{}
+	remote.results = map[string]string{}
+	remote.jobRetention = jobRetention
+	remote.maxJobs = maxJobs
+	remote.operations = map[string]operationFunc{
+		OperationCheckPreflight:   runCheckPreflight,
+		OperationInstallPreflight: runInstallPreflight,
+		OperationTrimVolume:       runTrimVolume,
+		OperationExportReplica:    runExportReplica,
+	}
+
+	return nil
+}
+
+// Run serves the API until the context is done.
+func (remote *Server) Run(ctx context.Context) error {
+	server := &http.Server{
+		Addr:              remote.ListenAddress,
+		Handler:           remote.newHandler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		var err error
+		if remote.TLSCertFile != "" {
+			logrus.Infof("Serving API on %s over HTTPS", remote.ListenAddress)
+			err = server.ListenAndServeTLS(remote.TLSCertFile, remote.TLSKeyFile)
+		} else {
+			logrus.Infof("Serving API on %s", remote.ListenAddress)
+			err = server.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errCh <- err
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		logrus.Info("Stopping API server")
+		return server.Shutdown(context.Background())
+	case err := <-errCh:
+		return errors.Wrap(err, "failed to serve API")
+	}
+}
+
+// newHandler returns the handler serving the API endpoints.
+func (remote *Server) newHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.Handle("POST /v1/jobs", remote.authenticate(remote.handleSubmitJob))
+	mux.Handle("GET /v1/jobs", remote.authenticate(remote.handleListJobs))
+	mux.Handle("GET /v1/jobs/{id}", remote.authenticate(remote.handleGetJob))
+	mux.Handle("GET /v1/jobs/{id}/result", remote.authenticate(remote.handleGetJobResult))
+	return mux
+}
+
+// authenticate rejects requests without the expected bearer token.
+func (remote *Server) authenticate(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(remote.Token)) != 1 {
+			writeError(w, http.StatusUnauthorized, errors.New("invalid or missing bearer token"))
+			return
+		}
+
+		next(w, r)
+	})
+}
+
+func (remote *Server) handleSubmitJob(w http.ResponseWriter, r *http.Request) {
+	var request types.ApiJobRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, errors.Wrap(err, "failed to decode request"))
+		return
+	}
+
+	operation, ok := remote.operations[request.Operation]
+	if !ok {
+		writeError(w, http.StatusBadRequest, errors.Errorf("unsupported operation %q", request.Operation))
+		return
+	}
+
+	job := &types.ApiJob{
+		ID:        uuid.NewString(),
+		Operation: request.Operation,
+		Status:    types.ApiJobStatusPending,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	remote.lock.Lock()
+	remote.pruneJobs(job.CreatedAt)
+	if len(remote.jobs) >= remote.maxJobs {
+		remote.lock.Unlock()
+		writeError(w, http.StatusTooManyRequests, errors.Errorf("too many jobs pending or running, the maximum is %d", remote.maxJobs))
+		return
+	}
+	remote.jobs[job.ID] = job
+	remote.lock.Unlock()
+
+	logrus.WithFields(logrus.Fields{
+		"job":       job.ID,
+		"operation": job.Operation,
+	}).Info("Submitted job")
+
+	go remote.runJob(job.ID, operation, request.Options)
+
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+func (remote *Server) handleListJobs(w http.ResponseWriter, r *http.Request) {
+	remote.lock.RLock()
+	jobs := make([]types.ApiJob, 0, len(remote.jobs))
+	for _, job := range remote.jobs {
+		jobs = append(jobs, *job)
+	}
+	remote.lock.RUnlock()
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+
+	writeJSON(w, http.StatusOK, jobs)
+}
+
+func (remote *Server) handleGetJob(w http.ResponseWriter, r *http.Request) {
+	remote.lock.RLock()
+	job, ok := remote.jobs[r.PathValue("id")]
+	var jobCopy types.ApiJob
+	if ok {
+		jobCopy = *job
+	}
+	remote.lock.RUnlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, errors.Errorf("job %s not found", r.PathValue("id")))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, jobCopy)
+}
+
+func (remote *Server) handleGetJobResult(w http.ResponseWriter, r *http.Request) {
+	remote.lock.RLock()
+	job, ok := remote.jobs[r.PathValue("id")]
+	var status types.ApiJobStatus
+	if ok {
+		status = job.Status
+	}
+	result := remote.results[r.PathValue("id")]
+	remote.lock.RUnlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, errors.Errorf("job %s not found", r.PathValue("id")))
+		return
+	}
+
+	if status != types.ApiJobStatusSucceeded && status != types.ApiJobStatusFailed {
+		writeError(w, http.StatusConflict, errors.Errorf("job %s is %s", r.PathValue("id"), status))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, types.ApiJobResult{
+		ID:     r.PathValue("id"),
+		Status: status,
+		Result: result,
+	})
+}
+
+// pruneJobs deletes the completed jobs past the retention, then the oldest completed jobs while
+// the number of jobs is at the maximum. The caller must hold the lock.
+func (remote *Server) pruneJobs(now time.Time) {
+	var completed []*types.ApiJob
+	for id, job := range remote.jobs {
+		if job.CompletedAt == nil {
+			continue
+		}
+		if now.Sub(*job.CompletedAt) > remote.jobRetention {
+			delete(remote.jobs, id)
+			delete(remote.results, id)
+			continue
+		}
+		completed = append(completed, job)
+	}
+
+	sort.Slice(completed, func(i, j int) bool {
+		return completed[i].CompletedAt.Before(*completed[j].CompletedAt)
+	})
+	for _, job := range completed {
+		if len(remote.jobs) < remote.maxJobs {
+			return
+		}
+		delete(remote.jobs, job.ID)
+		delete(remote.results, job.ID)
+	}
+}
+
+// runJob waits for the previous operation to complete, then runs the operation and records its result.
+func (remote *Server) runJob(id string, operation operationFunc, options json.RawMessage) {
+	remote.runLock.Lock()
+	defer remote.runLock.Unlock()
+
+	log := logrus.WithField("job", id)
+
+	remote.lock.Lock()
+	job := remote.jobs[id]
+	job.Status = types.ApiJobStatusRunning
+	job.StartedAt = ptrToNow()
+	remote.lock.Unlock()
+
+	log.Info("Running job")
+	result, err := operation(remote.GlobalCmdOptions, options)
+
+	remote.lock.Lock()
+	defer remote.lock.Unlock()
+
+	job.CompletedAt = ptrToNow()
+	remote.results[id] = result
+	if err != nil {
+		log.WithError(err).Warn("Failed job")
+		job.Status = types.ApiJobStatusFailed
+		job.Error = err.Error()
+		return
+	}
+
+	log.Info("Completed job")
+	job.Status = types.ApiJobStatusSucceeded
+}
+
+// decodeOptions decodes the operation options. Callers override the global options
+// with the ones of the server afterwards, so API clients cannot change the image or namespace.
+func decodeOptions(options json.RawMessage, out any) error {
+	if len(options) == 0 {
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(options))
+	decoder.DisallowUnknownFields()
+	return errors.Wrap(decoder.Decode(out), "failed to decode options")
+}
+
+// validateNoFileOptions rejects the options holding paths of files, keyed by the option name, as
+// the files would be read from the filesystem of the server.
+func validateNoFileOptions(fileOptions map[string]string) error {
+	names := make([]string, 0, len(fileOptions))
+	for name, path := range fileOptions {
+		if path != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	sort.Strings(names)
+	return errors.Errorf("options %v are not supported over the API, they are paths of files on the server", strings.Join(names, ", "))
+}
+
+// validateNoURLOptions rejects the options holding URLs, keyed by the option name, as the server
+// would fetch them from inside the cluster.
+func validateNoURLOptions(urlOptions map[string]string) error {
+	names := make([]string, 0, len(urlOptions))
+	for name, url := range urlOptions {
+		if url != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	sort.Strings(names)
+	return errors.Errorf("options %v are not supported over the API, they are URLs fetched by the server", strings.Join(names, ", "))
+}
+
+func runCheckPreflight(globalOpts types.GlobalCmdOptions, options json.RawMessage) (string, error) {
+	checker := preflight.Checker{}
+	if err := decodeOptions(options, &checker.CheckerCmdOptions); err != nil {
+		return "", err
+	}
+	if err := validateNoFileOptions(map[string]string{
+		"CustomChecksFile":        checker.CustomChecksFile,
+		"RegistryCheckImagesFile": checker.RegistryCheckImagesFile,
+		"SeverityPolicyFile":      checker.SeverityPolicyFile,
+	}); err != nil {
+		return "", err
+	}
+	if err := validateNoURLOptions(map[string]string{
+		"RulesURL": checker.RulesURL,
+	}); err != nil {
+		return "", err
+	}
+	checker.GlobalCmdOptions = globalOpts
+	checker.SSHCmdOptions = types.SSHCmdOptions{} // The API runs on the cluster only.
+
+	if err := checker.Init(); err != nil {
+		return "", errors.Wrap(err, "failed to initialize preflight checker")
+	}
+
+	if err := checker.Cleanup(); err != nil {
+		return "", errors.Wrap(err, "failed to cleanup preflight checker")
+	}
+
+	output, err := checker.Run()
+	if _err := checker.Cleanup(); _err != nil {
+		logrus.WithError(_err).Warn("Failed to cleanup preflight checker")
+	}
+	return output, err
+}
+
+func runInstallPreflight(globalOpts types.GlobalCmdOptions, options json.RawMessage) (string, error) {
+	installer := preflight.Installer{}
+	installer.UpdatePackages = true
+	installer.HugePageSize = 2048
+	installer.AllowPci = "none"
+	if err := decodeOptions(options, &installer.InstallerCmdOptions); err != nil {
+		return "", err
+	}
+	if err := validateNoFileOptions(map[string]string{
+		"ProfilesFile": installer.ProfilesFile,
+	}); err != nil {
+		return "", err
+	}
+	installer.GlobalCmdOptions = globalOpts
+	installer.SSHCmdOptions = types.SSHCmdOptions{} // The API runs on the cluster only.
+
+	if err := installer.Init(); err != nil {
+		return "", errors.Wrap(err, "failed to initialize preflight installer")
+	}
+
+	if err := installer.Cleanup(); err != nil {
+		return "", errors.Wrap(err, "failed to cleanup preflight installer")
+	}
+
+	output, err := installer.Run()
+	if installer.OperatingSystem != string(consts.OperatingSystemContainerOptimizedOS) {
+		if _err := installer.Cleanup(); _err != nil {
+			logrus.WithError(_err).Warn("Failed to cleanup preflight installer")
+		}
+	}
+	return output, err
+}
+
+func runTrimVolume(globalOpts types.GlobalCmdOptions, options json.RawMessage) (string, error) {
+	trimmer := volume.Trimmer{}
+	trimmer.LonghornNamespace = consts.LonghornNamespace
+	if err := decodeOptions(options, &trimmer.TrimmerCmdOptions); err != nil {
+		return "", err
+	}
+	trimmer.GlobalCmdOptions = globalOpts
+
+	if err := trimmer.Validate(); err != nil {
+		return "", err
+	}
+
+	if err := trimmer.Init(); err != nil {
+		return "", errors.Wrap(err, "failed to initialize volume trimmer")
+	}
+
+	if err := trimmer.Cleanup(); err != nil {
+		return "", errors.Wrap(err, "failed to cleanup volume trimmer")
+	}
+
+	result, err := trimmer.Run()
+	if _err := trimmer.Cleanup(); _err != nil {
+		logrus.WithError(_err).Warn("Failed to cleanup volume trimmer")
+	}
+	if err != nil {
+		return "", err
+	}
+
+	yamlData, err := yaml.Marshal(result)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to convert the trim result to YAML")
+	}
+	return string(yamlData), nil
+}
+
+func runExportReplica(globalOpts types.GlobalCmdOptions, options json.RawMessage) (string, error) {
+	exporter := replica.Exporter{}
+	exporter.EngineImage = consts.ImageEngine
+	exporter.LonghornDataDirectory = "/var/lib/longhorn"
+	if err := decodeOptions(options, &exporter.ExporterCmdOptions); err != nil {
+		return "", err
+	}
+	exporter.GlobalCmdOptions = globalOpts
+
+	if err := exporter.Validate(); err != nil {
+		return "", err
+	}
+
+	if err := exporter.Init(); err != nil {
+		return "", errors.Wrap(err, "failed to initialize replica exporter")
+	}
+
+	return exporter.Run()
+}
+
+func ptrToNow() *time.Time {
+	now := time.Now().UTC()
+	return &now
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, obj any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(obj); err != nil {
+		logrus.WithError(err).Warn("Failed to write response")
+	}
+}
+
+func writeError(w http.ResponseWriter, statusCode int, err error) {
+	writeJSON(w, statusCode, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,282 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func newTestServer(t *testing.T, operations map[string]operationFunc) *Server {
+	server := &Server{ServerCmdOptions: ServerCmdOptions{Token: "secret"}}
+	if err := server.Init(); err != nil {
+		t.Fatalf("failed to initialize server: %v", err)
+	}
+	server.operations = operations
+	return server
+}
+
+func serve(handler http.Handler, method, path, token, body string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestHandlers(t *testing.T) {
+	release := make(chan struct{})
+	server := newTestServer(t, map[string]operationFunc{
+		"succeed": func(globalOpts types.GlobalCmdOptions, options json.RawMessage) (string, error) {
+			<-release
+			return "result", nil
+		},
+	})
+	handler := server.newHandler()
+
+	for name, tc := range map[string]struct {
+		method         string
+		path           string
+		token          string
+		body           string
+		expectedStatus int
+	}{
+		"healthz without token": {method: http.MethodGet, path: "/healthz", expectedStatus: http.StatusOK},
+		"missing token":         {method: http.MethodGet, path: "/v1/jobs", expectedStatus: http.StatusUnauthorized},
+		"invalid token":         {method: http.MethodGet, path: "/v1/jobs", token: "wrong", expectedStatus: http.StatusUnauthorized},
+		"invalid body":          {method: http.MethodPost, path: "/v1/jobs", token: "secret", body: "{", expectedStatus: http.StatusBadRequest},
+		"unsupported operation": {method: http.MethodPost, path: "/v1/jobs", token: "secret", body: `{"operation":"unknown"}`, expectedStatus: http.StatusBadRequest},
+		"unknown job":           {method: http.MethodGet, path: "/v1/jobs/unknown", token: "secret", expectedStatus: http.StatusNotFound},
+		"unknown job result":    {method: http.MethodGet, path: "/v1/jobs/unknown/result", token: "secret", expectedStatus: http.StatusNotFound},
+	} {
+		if recorder := serve(handler, tc.method, tc.path, tc.token, tc.body); recorder.Code != tc.expectedStatus {
+			t.Errorf("%v: expected status %v, got %v: %v", name, tc.expectedStatus, recorder.Code, recorder.Body.String())
+		}
+	}
+
+	recorder := serve(handler, http.MethodPost, "/v1/jobs", "secret", `{"operation":"succeed"}`)
+	if recorder.Code != http.StatusAccepted {
+		t.Fatalf("expected status %v submitting the job, got %v: %v", http.StatusAccepted, recorder.Code, recorder.Body.String())
+	}
+	var job types.ApiJob
+	if err := json.Unmarshal(recorder.Body.Bytes(), &job); err != nil {
+		t.Fatalf("failed to decode job: %v", err)
+	}
+
+	if recorder := serve(handler, http.MethodGet, "/v1/jobs/"+job.ID+"/result", "secret", ""); recorder.Code != http.StatusConflict {
+		t.Errorf("expected status %v getting the result of an incomplete job, got %v", http.StatusConflict, recorder.Code)
+	}
+
+	close(release)
+	var result types.ApiJobResult
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		recorder := serve(handler, http.MethodGet, "/v1/jobs/"+job.ID+"/result", "secret", "")
+		if recorder.Code == http.StatusOK {
+			if err := json.Unmarshal(recorder.Body.Bytes(), &result); err != nil {
+				t.Fatalf("failed to decode result: %v", err)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job did not complete, got status %v", recorder.Code)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if result.Status != types.ApiJobStatusSucceeded || result.Result != "result" {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	recorder = serve(handler, http.MethodGet, "/v1/jobs", "secret", "")
+	var jobs []types.ApiJob
+	if err := json.Unmarshal(recorder.Body.Bytes(), &jobs); err != nil {
+		t.Fatalf("failed to decode jobs: %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].ID != job.ID || jobs[0].Status != types.ApiJobStatusSucceeded {
+		t.Errorf("unexpected jobs %+v", jobs)
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	handler := newTestServer(t, nil).newHandler()
+
+	for name, tc := range map[string]struct {
+		authorization  string
+		expectedStatus int
+	}{
+		"bearer token":    {authorization: "Bearer secret", expectedStatus: http.StatusOK},
+		"bare token":      {authorization: "secret", expectedStatus: http.StatusUnauthorized},
+		"empty scheme":    {authorization: " secret", expectedStatus: http.StatusUnauthorized},
+		"basic scheme":    {authorization: "Basic secret", expectedStatus: http.StatusUnauthorized},
+		"no token":        {authorization: "Bearer ", expectedStatus: http.StatusUnauthorized},
+		"no header value": {expectedStatus: http.StatusUnauthorized},
+	} {
+		request := httptest.NewRequest(http.MethodGet, "/v1/jobs", nil)
+		if tc.authorization != "" {
+			request.Header.Set("Authorization", tc.authorization)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		if recorder.Code != tc.expectedStatus {
+			t.Errorf("%v: expected status %v, got %v", name, tc.expectedStatus, recorder.Code)
+		}
+	}
+}
+
+func TestRunJobFailed(t *testing.T) {
+	server := newTestServer(t, nil)
+	server.jobs["failed"] = &types.ApiJob{ID: "failed", Status: types.ApiJobStatusPending}
+
+	server.runJob("failed", func(globalOpts types.GlobalCmdOptions, options json.RawMessage) (string, error) {
+		return "", errors.New("failed to check")
+	}, nil)
+
+	job := server.jobs["failed"]
+	if job.Status != types.ApiJobStatusFailed || job.Error != "failed to check" || job.CompletedAt == nil {
+		t.Errorf("unexpected job %+v", job)
+	}
+}
+
+func TestPruneJobs(t *testing.T) {
+	now := time.Date(2024, 7, 16, 9, 0, 0, 0, time.UTC)
+	completedAt := func(age time.Duration) *time.Time {
+		completed := now.Add(-age)
+		return &completed
+	}
+
+	for name, tc := range map[string]struct {
+		jobs     map[string]*types.ApiJob
+		maxJobs  int
+		expected []string
+	}{
+		"expired": {
+			jobs: map[string]*types.ApiJob{
+				"expired": {CompletedAt: completedAt(25 * time.Hour)},
+				"recent":  {CompletedAt: completedAt(time.Hour)},
+				"running": {},
+			},
+			maxJobs:  10,
+			expected: []string{"recent", "running"},
+		},
+		"oldest completed until below maximum": {
+			jobs: map[string]*types.ApiJob{
+				"oldest":  {CompletedAt: completedAt(3 * time.Hour)},
+				"older":   {CompletedAt: completedAt(2 * time.Hour)},
+				"recent":  {CompletedAt: completedAt(time.Hour)},
+				"running": {},
+			},
+			maxJobs:  3,
+			expected: []string{"recent", "running"},
+		},
+		"only running at maximum": {
+			jobs: map[string]*types.ApiJob{
+				"pending": {},
+				"running": {},
+			},
+			maxJobs:  2,
+			expected: []string{"pending", "running"},
+		},
+	} {
+		server := newTestServer(t, nil)
+		server.maxJobs = tc.maxJobs
+		for id, job := range tc.jobs {
+			job.ID = id
+			server.jobs[id] = job
+			server.results[id] = "result"
+		}
+
+		server.pruneJobs(now)
+
+		if len(server.jobs) != len(tc.expected) || len(server.results) != len(tc.expected) {
+			t.Errorf("%v: expected jobs %v, got %v jobs and %v results", name, tc.expected, len(server.jobs), len(server.results))
+		}
+		for _, id := range tc.expected {
+			if _, ok := server.jobs[id]; !ok {
+				t.Errorf("%v: expected job %v to be kept", name, id)
+			}
+		}
+	}
+}
+
+func TestSubmitJobAtMaximum(t *testing.T) {
+	server := newTestServer(t, map[string]operationFunc{
+		"noop": func(globalOpts types.GlobalCmdOptions, options json.RawMessage) (string, error) {
+			return "", nil
+		},
+	})
+	server.maxJobs = 1
+	server.jobs["running"] = &types.ApiJob{ID: "running", Status: types.ApiJobStatusRunning}
+
+	recorder := serve(server.newHandler(), http.MethodPost, "/v1/jobs", "secret", `{"operation":"noop"}`)
+	if recorder.Code != http.StatusTooManyRequests {
+		t.Errorf("expected status %v, got %v: %v", http.StatusTooManyRequests, recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestFileOptionsRejected(t *testing.T) {
+	for name, tc := range map[string]struct {
+		operation operationFunc
+		options   string
+	}{
+		"custom checks file":         {operation: runCheckPreflight, options: `{"CustomChecksFile":"/etc/shadow"}`},
+		"registry check images file": {operation: runCheckPreflight, options: `{"RegistryCheckImagesFile":"/etc/shadow"}`},
+		"severity policy file":       {operation: runCheckPreflight, options: `{"SeverityPolicyFile":"/etc/shadow"}`},
+		"profiles file":              {operation: runInstallPreflight, options: `{"ProfilesFile":"/etc/shadow"}`},
+		"rules URL":                  {operation: runCheckPreflight, options: `{"RulesURL":"http://169.254.169.254/latest/meta-data/"}`},
+		"unknown option":             {operation: runTrimVolume, options: `{"Unknown":"value"}`},
+	} {
+		if _, err := tc.operation(types.GlobalCmdOptions{}, json.RawMessage(tc.options)); err == nil {
+			t.Errorf("%v: expected an error", name)
+		}
+	}
+}
+
+func TestValidateNoFileOptions(t *testing.T) {
+	err := validateNoFileOptions(map[string]string{"SeverityPolicyFile": "a", "CustomChecksFile": "b", "ProfilesFile": ""})
+	expected := "options CustomChecksFile, SeverityPolicyFile are not supported over the API, they are paths of files on the server"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected error %q, got %v", expected, err)
+	}
+
+	if err := validateNoFileOptions(map[string]string{"ProfilesFile": ""}); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for name, tc := range map[string]struct {
+		options     ServerCmdOptions
+		expectError bool
+	}{
+		"token":                {options: ServerCmdOptions{Token: "secret"}},
+		"tls":                  {options: ServerCmdOptions{Token: "secret", TLSCertFile: "tls.crt", TLSKeyFile: "tls.key"}},
+		"no token":             {options: ServerCmdOptions{}, expectError: true},
+		"tls cert without key": {options: ServerCmdOptions{Token: "secret", TLSCertFile: "tls.crt"}, expectError: true},
+		"tls key without cert": {options: ServerCmdOptions{Token: "secret", TLSKeyFile: "tls.key"}, expectError: true},
+	} {
+		server := &Server{ServerCmdOptions: tc.options}
+		if err := server.Validate(); (err != nil) != tc.expectError {
+			t.Errorf("%v: expected error %v, got %v", name, tc.expectError, err)
+		}
+	}
+}
+
+func TestValidateNoURLOptions(t *testing.T) {
+	err := validateNoURLOptions(map[string]string{"RulesURL": "http://example.com/rules.json"})
+	expected := "options RulesURL are not supported over the API, they are URLs fetched by the server"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected error %q, got %v", expected, err)
+	}
+
+	if err := validateNoURLOptions(map[string]string{"RulesURL": ""}); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
@@ -0,0 +1,137 @@
+package async
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	kubeclient "k8s.io/client-go/kubernetes"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils/output"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// Getter provide functions for getting the status and the result of a run submitted with
+// --no-wait, from its record and its Job.
+type Getter struct {
+	GetterCmdOptions
+
+	kubeClient *kubeclient.Clientset
+	namespace  string
+}
+
+// GetterCmdOptions holds the options for the command.
+type GetterCmdOptions struct {
+	types.GlobalCmdOptions
+
+	RunID   string
+	Timeout time.Duration // Maximum time to wait for the run to complete.
+}
+
+// Validate validates the command options.
+func (remote *Getter) Validate() error {
+	if remote.RunID == "" {
+		return errors.New("run ID is required")
+	}
+	return nil
+}
+
+// Init initializes the Getter.
+func (remote *Getter) Init() error {
+	kubeClient, err := kubeutils.NewKubeClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.kubeClient = kubeClient
+
+	remote.namespace = remote.Namespace
+	if remote.namespace == "" {
+		remote.namespace = consts.LonghornNamespace
+	}
+
+	return nil
+}
+
+// Get returns the run with the status of its Job.
+func (remote *Getter) Get() (*types.AsyncRun, error) {
+	name := GetRecordName(remote.RunID)
+	record, err := remote.kubeClient.CoreV1().ConfigMaps(remote.namespace).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, errors.Errorf("run %v not found in namespace %v, check --%s", remote.RunID, remote.namespace, consts.CmdOptNamespace)
+		}
+		return nil, errors.Wrapf(err, "failed to get record of run %v", remote.RunID)
+	}
+
+	var job *batchv1.Job
+	if job, err = remote.kubeClient.BatchV1().Jobs(remote.namespace).Get(context.Background(), record.Data[recordKeyJob], metav1.GetOptions{}); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, errors.Wrapf(err, "failed to get Job of run %v", remote.RunID)
+		}
+		job = nil
+	}
+
+	return newAsyncRun(record, job), nil
+}
+
+// Wait waits for the run to complete, and returns it. It returns a TimeoutError when the run does
+// not complete within the timeout.
+func (remote *Getter) Wait() (*types.AsyncRun, error) {
+	ctx, cancel := context.WithTimeoutCause(context.Background(), remote.Timeout, &types.TimeoutError{
+		Operation: "waiting for run " + remote.RunID,
+		Timeout:   remote.Timeout,
+	})
+	defer cancel()
+
+	var run *types.AsyncRun
+	status := ""
+	err := wait.PollUntilContextCancel(ctx, 2*time.Second, true, func(ctx context.Context) (bool, error) {
+		var err error
+		if run, err = remote.Get(); err != nil {
+			return false, err
+		}
+		if run.Status != status {
+			logrus.Infof("Run %v is %v", run.ID, run.Status)
+			status = run.Status
+		}
+		return IsCompleted(run), nil
+	})
+	if err != nil && ctx.Err() != nil {
+		return run, context.Cause(ctx)
+	}
+	return run, err
+}
+
+// GetResult returns the structured result the Job of the run wrote, wrapped with its schema
+// version and kind, in JSON. It returns an empty result when the command has none, or the Job
+// did not write it.
+func (remote *Getter) GetResult(run *types.AsyncRun) ([]byte, error) {
+	resultConfigMap, err := remote.kubeClient.CoreV1().ConfigMaps(remote.namespace).Get(context.Background(), run.Result, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "failed to get result of run %v", run.ID)
+	}
+	return getResultData(resultConfigMap), nil
+}
+
+// getResultData returns the JSON result in the ConfigMap written by the configmap:// destination
+// of --output-to.
+func getResultData(configMap *corev1.ConfigMap) []byte {
+	data, ok := configMap.Data[output.ConfigMapDataKeyPrefix+consts.OutputFormatJSON]
+	if !ok {
+		return nil
+	}
+	return []byte(data)
+}
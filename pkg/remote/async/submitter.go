@@ -0,0 +1,213 @@
+package async
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeclient "k8s.io/client-go/kubernetes"
+
+	commonkube "github.com/longhorn/go-common-libs/kubernetes"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/remote/job"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+const (
+	recordKeyCommand     = "command"
+	recordKeyJob         = "job"
+	recordKeySubmittedAt = "submittedAt"
+)
+
+// Submitter provide functions for submitting a longhornctl command to a Job in the cluster, and
+// recording the run in a ConfigMap, so its status and result are retrieved later by its run ID.
+type Submitter struct {
+	SubmitterCmdOptions
+
+	kubeClient *kubeclient.Clientset
+	namespace  string
+}
+
+// SubmitterCmdOptions holds the options for the command.
+type SubmitterCmdOptions struct {
+	types.GlobalCmdOptions
+
+	RunID string   // ID of the run, naming its record and its Job.
+	Args  []string // The longhornctl subcommand and options to run in the Job.
+}
+
+// Validate validates the command options.
+func (remote *Submitter) Validate() error {
+	if remote.RunID == "" {
+		return errors.New("run ID is required")
+	}
+	if len(remote.Args) == 0 {
+		return errors.New("subcommand to run in the Job is required")
+	}
+	return nil
+}
+
+// Init initializes the Submitter.
+func (remote *Submitter) Init() error {
+	kubeClient, err := kubeutils.NewKubeClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.kubeClient = kubeClient
+
+	remote.namespace = remote.Namespace
+	if remote.namespace == "" {
+		remote.namespace = consts.LonghornNamespace
+	}
+
+	return nil
+}
+
+// Run creates the record of the run, and the Job running the command with its RBAC resources.
+// The Job writes the structured result of the command to the result ConfigMap of the run.
+func (remote *Submitter) Run() (*types.AsyncRun, error) {
+	name := GetRecordName(remote.RunID)
+	command := strings.Join(append([]string{consts.CmdLonghornctlRemote}, remote.Args...), " ")
+
+	generator := &job.Generator{
+		GeneratorCmdOptions: job.GeneratorCmdOptions{
+			GlobalCmdOptions: remote.GlobalCmdOptions,
+			Name:             name,
+			Args:             append(append([]string{}, remote.Args...), newOutputToArg(remote.namespace, name)),
+		},
+	}
+	if err := generator.Validate(); err != nil {
+		return nil, err
+	}
+	if err := generator.Init(); err != nil {
+		return nil, err
+	}
+
+	if _, err := kubeutils.CreateNamespace(remote.kubeClient, remote.namespace); err != nil {
+		return nil, err
+	}
+
+	record := newRecord(remote.namespace, name, command, time.Now())
+	kubeutils.SetRunMetadata(remote.kubeClient, record)
+	record, err := commonkube.CreateConfigMap(remote.kubeClient, record)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to record run %v", remote.RunID)
+	}
+
+	for _, object := range generator.Objects() {
+		metaObject, ok := object.(metav1.Object)
+		if !ok {
+			continue
+		}
+		metaObject.GetLabels()[consts.LabelManagedBy] = consts.LabelValueManagedBy
+		kubeutils.SetRunMetadata(remote.kubeClient, metaObject)
+
+		switch object := object.(type) {
+		case *corev1.ServiceAccount:
+			_, err = commonkube.CreateServiceAccount(remote.kubeClient, object)
+		case *rbacv1.ClusterRole:
+			_, err = commonkube.CreateClusterRole(remote.kubeClient, object)
+		case *rbacv1.ClusterRoleBinding:
+			_, err = commonkube.CreateClusterRoleBinding(remote.kubeClient, object)
+		case *batchv1.Job:
+			_, err = remote.kubeClient.BatchV1().Jobs(object.Namespace).Create(context.Background(), object, metav1.CreateOptions{})
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create %T %v of run %v", object, metaObject.GetName(), remote.RunID)
+		}
+	}
+
+	return newAsyncRun(record, nil), nil
+}
+
+// GetRecordName returns the name of the ConfigMap recording the run, also naming its Job.
+func GetRecordName(runID string) string {
+	return consts.AppNameAsyncRun + "-" + runID
+}
+
+// newOutputToArg returns the --output-to option writing the structured result of the command to
+// the result ConfigMap of the run in JSON, and to the logs of the Job.
+func newOutputToArg(namespace, name string) string {
+	return fmt.Sprintf("--%s=stdout,%s=configmap://%s/%s%s", consts.CmdOptOutputTo, consts.OutputFormatJSON, namespace, name, consts.AsyncRunResultNameSuffix)
+}
+
+// newRecord returns the ConfigMap recording the command of the run and its Job.
+func newRecord(namespace, name, command string, submittedAt time.Time) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels: map[string]string{
+				"app":                 consts.AppNameAsyncRun,
+				consts.LabelManagedBy: consts.LabelValueManagedBy,
+				consts.LabelState:     consts.LabelValueState,
+			},
+		},
+		Data: map[string]string{
+			recordKeyCommand:     command,
+			recordKeyJob:         name,
+			recordKeySubmittedAt: submittedAt.UTC().Format(time.RFC3339),
+		},
+	}
+}
+
+// newAsyncRun returns the run of the record, with the status of its Job. Without the Job, the run
+// is pending.
+func newAsyncRun(record *corev1.ConfigMap, job *batchv1.Job) *types.AsyncRun {
+	run := &types.AsyncRun{
+		ID:        strings.TrimPrefix(record.Name, consts.AppNameAsyncRun+"-"),
+		Command:   record.Data[recordKeyCommand],
+		Namespace: record.Namespace,
+		Job:       record.Data[recordKeyJob],
+		Status:    consts.AsyncRunStatusPending,
+		Result:    record.Name + consts.AsyncRunResultNameSuffix,
+	}
+	if submittedAt, err := time.Parse(time.RFC3339, record.Data[recordKeySubmittedAt]); err == nil {
+		run.SubmittedAt = submittedAt
+	}
+
+	if job == nil {
+		return run
+	}
+
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobComplete:
+			run.Status = consts.AsyncRunStatusSucceeded
+		case batchv1.JobFailed:
+			run.Status = consts.AsyncRunStatusFailed
+			run.Message = condition.Message
+		default:
+			continue
+		}
+		completedAt := condition.LastTransitionTime.Time
+		if job.Status.CompletionTime != nil {
+			completedAt = job.Status.CompletionTime.Time
+		}
+		run.CompletedAt = &completedAt
+		return run
+	}
+
+	if job.Status.Active > 0 {
+		run.Status = consts.AsyncRunStatusRunning
+	}
+	return run
+}
+
+// IsCompleted returns true if the run succeeded or failed.
+func IsCompleted(run *types.AsyncRun) bool {
+	return run.Status == consts.AsyncRunStatusSucceeded || run.Status == consts.AsyncRunStatusFailed
+}
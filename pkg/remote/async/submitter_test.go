@@ -0,0 +1,98 @@
+package async
+
+import (
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/longhorn/cli/pkg/consts"
+)
+
+func TestNewAsyncRun(t *testing.T) {
+	submittedAt := time.Date(2024, 7, 16, 9, 40, 12, 0, time.UTC)
+	completedAt := metav1.NewTime(submittedAt.Add(time.Minute))
+	record := newRecord("longhorn-system", GetRecordName("5f2a9c1e"), "longhornctl check preflight", submittedAt)
+	if record.Labels[consts.LabelState] != consts.LabelValueState {
+		t.Errorf("expected the record to be labeled %v=%v, so it is kept by the cleanup", consts.LabelState, consts.LabelValueState)
+	}
+
+	tests := map[string]struct {
+		job               *batchv1.Job
+		expectedStatus    string
+		expectedCompleted bool
+	}{
+		"no Job": {expectedStatus: consts.AsyncRunStatusPending},
+		"Job not started": {
+			job:            &batchv1.Job{},
+			expectedStatus: consts.AsyncRunStatusPending,
+		},
+		"Job active": {
+			job:            &batchv1.Job{Status: batchv1.JobStatus{Active: 1}},
+			expectedStatus: consts.AsyncRunStatusRunning,
+		},
+		"Job complete": {
+			job: &batchv1.Job{Status: batchv1.JobStatus{
+				Conditions:     []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}},
+				CompletionTime: &completedAt,
+			}},
+			expectedStatus:    consts.AsyncRunStatusSucceeded,
+			expectedCompleted: true,
+		},
+		"Job failed": {
+			job: &batchv1.Job{Status: batchv1.JobStatus{
+				Conditions: []batchv1.JobCondition{
+					{Type: batchv1.JobFailureTarget, Status: corev1.ConditionTrue},
+					{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Message: "Job has reached the specified backoff limit", LastTransitionTime: completedAt},
+				},
+			}},
+			expectedStatus:    consts.AsyncRunStatusFailed,
+			expectedCompleted: true,
+		},
+		"Job complete condition false": {
+			job: &batchv1.Job{Status: batchv1.JobStatus{
+				Active:     1,
+				Conditions: []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionFalse}},
+			}},
+			expectedStatus: consts.AsyncRunStatusRunning,
+		},
+	}
+
+	for name, test := range tests {
+		run := newAsyncRun(record, test.job)
+		if run.Status != test.expectedStatus {
+			t.Errorf("%v: expected status %v, got %v", name, test.expectedStatus, run.Status)
+		}
+		if IsCompleted(run) != test.expectedCompleted {
+			t.Errorf("%v: expected completed %v, got %v", name, test.expectedCompleted, IsCompleted(run))
+		}
+		if test.expectedCompleted && (run.CompletedAt == nil || !run.CompletedAt.Equal(completedAt.Time)) {
+			t.Errorf("%v: expected completion time %v, got %v", name, completedAt.Time, run.CompletedAt)
+		}
+		if run.ID != "5f2a9c1e" || run.Job != "longhornctl-async-5f2a9c1e" || run.Result != "longhornctl-async-5f2a9c1e-result" {
+			t.Errorf("%v: unexpected run %+v", name, run)
+		}
+		if !run.SubmittedAt.Equal(submittedAt) || run.Command != "longhornctl check preflight" {
+			t.Errorf("%v: unexpected submission of run %+v", name, run)
+		}
+	}
+}
+
+func TestNewOutputToArg(t *testing.T) {
+	expected := "--output-to=stdout,json=configmap://longhorn-system/longhornctl-async-5f2a9c1e-result"
+	if arg := newOutputToArg("longhorn-system", GetRecordName("5f2a9c1e")); arg != expected {
+		t.Errorf("expected %v, got %v", expected, arg)
+	}
+}
+
+func TestGetResultData(t *testing.T) {
+	configMap := &corev1.ConfigMap{Data: map[string]string{"result.json": `{"schemaVersion":"v1"}`}}
+	if data := getResultData(configMap); string(data) != `{"schemaVersion":"v1"}` {
+		t.Errorf("expected the JSON result, got %q", data)
+	}
+	if data := getResultData(&corev1.ConfigMap{}); data != nil {
+		t.Errorf("expected no result, got %q", data)
+	}
+}
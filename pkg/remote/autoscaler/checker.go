@@ -0,0 +1,312 @@
+package autoscaler
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeclient "k8s.io/client-go/kubernetes"
+
+	longhorn "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+	lhclient "github.com/longhorn/longhorn-manager/k8s/pkg/client/clientset/versioned"
+	lhmgrtypes "github.com/longhorn/longhorn-manager/types"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// Kinds of the checked objects, used in the keys of the result.
+const (
+	KindNode    = "Node"
+	KindSetting = "Setting"
+	KindVolume  = "Volume"
+)
+
+// Checker provide functions for evaluating which nodes the Cluster Autoscaler could remove, and
+// whether the Longhorn volumes tolerate it.
+type Checker struct {
+	CheckerCmdOptions
+
+	kubeClient     *kubeclient.Clientset
+	longhornClient *lhclient.Clientset
+}
+
+// CheckerCmdOptions holds the options for the command.
+type CheckerCmdOptions struct {
+	types.GlobalCmdOptions
+
+	LonghornNamespace string
+}
+
+// scaleDownState holds the state of the cluster the scale-down is evaluated on.
+type scaleDownState struct {
+	longhornNamespace string
+
+	autoscalerEnabled bool // Longhorn lets the Cluster Autoscaler evict its pods.
+	lastReplicaBlocks bool // The node drain policy blocks the removal of the node with the last healthy replica of a volume.
+
+	nodes    []corev1.Node
+	pods     []corev1.Pod
+	volumes  []longhorn.Volume
+	replicas []longhorn.Replica
+}
+
+// Init initializes the Checker.
+func (remote *Checker) Init() error {
+	kubeClient, err := kubeutils.NewKubeClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.kubeClient = kubeClient
+
+	longhornClient, err := kubeutils.NewLonghornClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.longhornClient = longhornClient
+
+	return nil
+}
+
+// Collect checks the Longhorn settings the Cluster Autoscaler depends on, then evaluates each node
+// the Cluster Autoscaler could remove, and the volumes that would lose redundancy with it. It
+// returns the result of each object keyed by its kind and name.
+func (remote *Checker) Collect() (map[string]*types.LogCollection, error) {
+	ctx := context.Background()
+	collections := map[string]*types.LogCollection{}
+	state := &scaleDownState{longhornNamespace: remote.LonghornNamespace}
+
+	autoscalerEnabled, err := remote.getSetting(ctx, lhmgrtypes.SettingNameKubernetesClusterAutoscalerEnabled, lhmgrtypes.SettingDefinitionKubernetesClusterAutoscalerEnabled)
+	if err != nil {
+		return nil, err
+	}
+	if state.autoscalerEnabled, err = strconv.ParseBool(autoscalerEnabled); err != nil {
+		return nil, errors.Wrapf(err, "invalid value %q of setting %v", autoscalerEnabled, lhmgrtypes.SettingNameKubernetesClusterAutoscalerEnabled)
+	}
+	replicaAutoBalance, err := remote.getSetting(ctx, lhmgrtypes.SettingNameReplicaAutoBalance, lhmgrtypes.SettingDefinitionReplicaAutoBalance)
+	if err != nil {
+		return nil, err
+	}
+	nodeDrainPolicy, err := remote.getSetting(ctx, lhmgrtypes.SettingNameNodeDrainPolicy, lhmgrtypes.SettingDefinitionNodeDrainPolicy)
+	if err != nil {
+		return nil, err
+	}
+	state.lastReplicaBlocks = inspectSettings(collections, state.autoscalerEnabled, replicaAutoBalance, nodeDrainPolicy)
+
+	nodeList, err := remote.kubeClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list nodes")
+	}
+	state.nodes = nodeList.Items
+
+	podList, err := remote.kubeClient.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list pods")
+	}
+	state.pods = podList.Items
+
+	volumeList, err := remote.longhornClient.LonghornV1beta2().Volumes(remote.LonghornNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list volumes")
+	}
+	state.volumes = volumeList.Items
+
+	replicaList, err := remote.longhornClient.LonghornV1beta2().Replicas(remote.LonghornNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list replicas")
+	}
+	state.replicas = replicaList.Items
+
+	inspectScaleDown(collections, state)
+	return collections, nil
+}
+
+// Cleanup does nothing, since the Checker does not create any resource.
+func (remote *Checker) Cleanup() error {
+	return nil
+}
+
+// getSetting returns the value of the setting, or its default when it is not set.
+func (remote *Checker) getSetting(ctx context.Context, name lhmgrtypes.SettingName, definition lhmgrtypes.SettingDefinition) (string, error) {
+	setting, err := remote.longhornClient.LonghornV1beta2().Settings(remote.LonghornNamespace).Get(ctx, string(name), metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return "", errors.Wrapf(err, "failed to get setting %v", name)
+	}
+	if err == nil && setting.Value != "" {
+		return setting.Value, nil
+	}
+	return definition.Default, nil
+}
+
+// inspectSettings checks the settings of Longhorn for the Cluster Autoscaler, and returns whether
+// the node drain policy blocks the removal of the nodes with the last healthy replica of a volume.
+func inspectSettings(collections map[string]*types.LogCollection, autoscalerEnabled bool, replicaAutoBalance, nodeDrainPolicy string) bool {
+	collection := getCollection(collections, KindSetting, string(lhmgrtypes.SettingNameKubernetesClusterAutoscalerEnabled))
+	if autoscalerEnabled {
+		collection.Info = append(collection.Info, "Longhorn lets the Cluster Autoscaler remove the nodes without attached volumes or last healthy replicas")
+	} else {
+		collection.Warn = append(collection.Warn, fmt.Sprintf("The Longhorn pods block the Cluster Autoscaler from removing any node running them. Set %v to true if the cluster uses the Cluster Autoscaler", lhmgrtypes.SettingNameKubernetesClusterAutoscalerEnabled))
+	}
+
+	collection = getCollection(collections, KindSetting, string(lhmgrtypes.SettingNameReplicaAutoBalance))
+	if replicaAutoBalance == string(longhorn.ReplicaAutoBalanceDisabled) {
+		collection.Warn = append(collection.Warn, fmt.Sprintf("The replicas are not rebalanced to the nodes the Cluster Autoscaler adds. Set %v to %v", lhmgrtypes.SettingNameReplicaAutoBalance, longhorn.ReplicaAutoBalanceBestEffort))
+	} else {
+		collection.Info = append(collection.Info, fmt.Sprintf("The replicas are rebalanced to the nodes the Cluster Autoscaler adds with %v", replicaAutoBalance))
+	}
+
+	collection = getCollection(collections, KindSetting, string(lhmgrtypes.SettingNameNodeDrainPolicy))
+	switch lhmgrtypes.NodeDrainPolicy(nodeDrainPolicy) {
+	case lhmgrtypes.NodeDrainPolicyAlwaysAllow, lhmgrtypes.NodeDrainPolicyAllowIfReplicaIsStopped:
+		collection.Error = append(collection.Error, fmt.Sprintf("Node drain policy %v lets the Cluster Autoscaler remove the node with the last healthy replica of a volume, losing its data. Set %v to %v", nodeDrainPolicy, lhmgrtypes.SettingNameNodeDrainPolicy, lhmgrtypes.NodeDrainPolicyBlockIfContainsLastReplica))
+		return false
+	default:
+		collection.Info = append(collection.Info, fmt.Sprintf("Node drain policy %v blocks the removal of the node with the last healthy replica of a volume", nodeDrainPolicy))
+		return true
+	}
+}
+
+// inspectScaleDown evaluates each node the Cluster Autoscaler could remove. A node is kept when it
+// has the scale-down-disabled annotation, when it runs a pod that is not safe to evict, or when
+// Longhorn blocks its removal: with a volume attached to it, the last healthy replica of a volume,
+// or a share manager or backing image pod. The volumes of the removable nodes are reported when
+// they would be left with a single healthy replica or none.
+func inspectScaleDown(collections map[string]*types.LogCollection, state *scaleDownState) {
+	podsPerNode := map[string][]corev1.Pod{}
+	for _, pod := range state.pods {
+		if pod.Spec.NodeName == "" || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		podsPerNode[pod.Spec.NodeName] = append(podsPerNode[pod.Spec.NodeName], pod)
+	}
+
+	healthyReplicas := map[string]map[string]bool{} // Nodes of the healthy replicas, keyed by their volume.
+	for _, replica := range state.replicas {
+		if !isReplicaHealthy(&replica) {
+			continue
+		}
+		if healthyReplicas[replica.Spec.VolumeName] == nil {
+			healthyReplicas[replica.Spec.VolumeName] = map[string]bool{}
+		}
+		healthyReplicas[replica.Spec.VolumeName][replica.Spec.NodeID] = true
+	}
+
+	volumes := append([]longhorn.Volume{}, state.volumes...)
+	sort.Slice(volumes, func(i, j int) bool { return volumes[i].Name < volumes[j].Name })
+
+	for _, node := range state.nodes {
+		collection := getCollection(collections, KindNode, node.Name)
+
+		blockers := getScaleDownBlockers(&node, podsPerNode[node.Name], volumes, healthyReplicas, state)
+		if len(blockers) > 0 {
+			collection.Info = append(collection.Info, blockers...)
+			continue
+		}
+
+		var replicaVolumes []string
+		for _, volume := range volumes {
+			if !healthyReplicas[volume.Name][node.Name] {
+				continue
+			}
+			replicaVolumes = append(replicaVolumes, volume.Name)
+			inspectVolumeRedundancy(getCollection(collections, KindVolume, volume.Name), &volume, node.Name, len(healthyReplicas[volume.Name])-1)
+		}
+
+		if len(replicaVolumes) == 0 {
+			collection.Info = append(collection.Info, "The Cluster Autoscaler can remove the node, which holds no healthy replica")
+		} else {
+			collection.Warn = append(collection.Warn, fmt.Sprintf("The Cluster Autoscaler can remove the node, rebuilding the replicas of volumes %v on the other nodes", strings.Join(replicaVolumes, ", ")))
+		}
+	}
+}
+
+// getScaleDownBlockers returns the reasons the Cluster Autoscaler cannot remove the node.
+func getScaleDownBlockers(node *corev1.Node, pods []corev1.Pod, volumes []longhorn.Volume, healthyReplicas map[string]map[string]bool, state *scaleDownState) []string {
+	if node.Annotations[consts.AutoscalerAnnotationScaleDownDisabled] == "true" {
+		return []string{fmt.Sprintf("Scale-down is disabled with annotation %v=true", consts.AutoscalerAnnotationScaleDownDisabled)}
+	}
+
+	var blockers []string
+	for _, pod := range pods {
+		if pod.Annotations[consts.AutoscalerAnnotationSafeToEvict] == "false" && !isDaemonSetPod(&pod) {
+			blockers = append(blockers, fmt.Sprintf("Pod %v/%v blocks scale-down with annotation %v=false", pod.Namespace, pod.Name, consts.AutoscalerAnnotationSafeToEvict))
+		}
+	}
+
+	longhornPods := false
+	for _, pod := range pods {
+		if pod.Namespace != state.longhornNamespace {
+			continue
+		}
+		longhornPods = true
+
+		switch pod.Labels[lhmgrtypes.GetLonghornLabelComponentKey()] {
+		case lhmgrtypes.LonghornLabelShareManager:
+			blockers = append(blockers, fmt.Sprintf("Longhorn blocks scale-down while share manager pod %v runs on the node", pod.Name))
+		case lhmgrtypes.LonghornLabelBackingImageManager, lhmgrtypes.LonghornLabelBackingImageDataSource:
+			blockers = append(blockers, fmt.Sprintf("Longhorn blocks scale-down while backing image pod %v runs on the node", pod.Name))
+		}
+	}
+	if longhornPods && !state.autoscalerEnabled {
+		blockers = append(blockers, fmt.Sprintf("The Longhorn pods on the node are not safe to evict while %v is false", lhmgrtypes.SettingNameKubernetesClusterAutoscalerEnabled))
+	}
+
+	for _, volume := range volumes {
+		if volume.Status.CurrentNodeID == node.Name {
+			blockers = append(blockers, fmt.Sprintf("Longhorn blocks scale-down while volume %v is attached to the node", volume.Name))
+		}
+
+		nodes := healthyReplicas[volume.Name]
+		if state.lastReplicaBlocks && len(nodes) == 1 && nodes[node.Name] {
+			blockers = append(blockers, fmt.Sprintf("Longhorn blocks scale-down while the node holds the last healthy replica of volume %v", volume.Name))
+		}
+	}
+	return blockers
+}
+
+// inspectVolumeRedundancy reports the redundancy the volume would keep with the remaining healthy
+// replicas after the removal of the node.
+func inspectVolumeRedundancy(collection *types.LogCollection, volume *longhorn.Volume, nodeName string, remainingReplicas int) {
+	fix := fmt.Sprintf("Annotate node %v with %v=true, or increase the number of replicas of the volume", nodeName, consts.AutoscalerAnnotationScaleDownDisabled)
+
+	switch {
+	case remainingReplicas == 0:
+		collection.Error = append(collection.Error, fmt.Sprintf("Removing node %v loses the last healthy replica of the volume. %v", nodeName, fix))
+	case remainingReplicas == 1 && volume.Spec.NumberOfReplicas > 1:
+		collection.Warn = append(collection.Warn, fmt.Sprintf("Removing node %v leaves the volume with a single healthy replica until the rebuild completes. %v", nodeName, fix))
+	default:
+		collection.Info = append(collection.Info, fmt.Sprintf("Removing node %v leaves the volume with %d healthy replicas", nodeName, remainingReplicas))
+	}
+}
+
+// isReplicaHealthy returns whether the replica has been rebuilt and has not failed since.
+func isReplicaHealthy(replica *longhorn.Replica) bool {
+	return replica.Spec.NodeID != "" && replica.Spec.HealthyAt != "" && replica.Spec.FailedAt == "" && replica.DeletionTimestamp == nil
+}
+
+// isDaemonSetPod returns whether the pod is controlled by a DaemonSet, which the Cluster
+// Autoscaler does not evict.
+func isDaemonSetPod(pod *corev1.Pod) bool {
+	controller := metav1.GetControllerOf(pod)
+	return controller != nil && controller.Kind == "DaemonSet"
+}
+
+// getCollection returns the collection of the object, creating it if needed.
+func getCollection(collections map[string]*types.LogCollection, kind, name string) *types.LogCollection {
+	key := kind + "/" + name
+	collection, ok := collections[key]
+	if !ok {
+		collection = &types.LogCollection{}
+		collections[key] = collection
+	}
+	return collection
+}
@@ -0,0 +1,153 @@
+package autoscaler
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	longhorn "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func TestInspectSettings(t *testing.T) {
+	tests := map[string]struct {
+		autoscalerEnabled  bool
+		replicaAutoBalance string
+		nodeDrainPolicy    string
+		expectedBlocks     bool
+		expectedWarnings   []string
+		expectedErrors     []string
+	}{
+		"recommended": {
+			autoscalerEnabled:  true,
+			replicaAutoBalance: "best-effort",
+			nodeDrainPolicy:    "block-if-contains-last-replica",
+			expectedBlocks:     true,
+		},
+		"defaults": {
+			replicaAutoBalance: "disabled",
+			nodeDrainPolicy:    "block-if-contains-last-replica",
+			expectedBlocks:     true,
+			expectedWarnings:   []string{"Setting/kubernetes-cluster-autoscaler-enabled", "Setting/replica-auto-balance"},
+		},
+		"always allow": {
+			autoscalerEnabled:  true,
+			replicaAutoBalance: "least-effort",
+			nodeDrainPolicy:    "always-allow",
+			expectedErrors:     []string{"Setting/node-drain-policy"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			collections := map[string]*types.LogCollection{}
+			if blocks := inspectSettings(collections, test.autoscalerEnabled, test.replicaAutoBalance, test.nodeDrainPolicy); blocks != test.expectedBlocks {
+				t.Errorf("expected last replica blocks %v, got %v", test.expectedBlocks, blocks)
+			}
+
+			var warnings, errors []string
+			for _, key := range []string{"Setting/kubernetes-cluster-autoscaler-enabled", "Setting/node-drain-policy", "Setting/replica-auto-balance"} {
+				if len(collections[key].Warn) > 0 {
+					warnings = append(warnings, key)
+				}
+				if len(collections[key].Error) > 0 {
+					errors = append(errors, key)
+				}
+			}
+			if !reflect.DeepEqual(warnings, test.expectedWarnings) || !reflect.DeepEqual(errors, test.expectedErrors) {
+				t.Errorf("expected warnings %v and errors %v, got %v and %v", test.expectedWarnings, test.expectedErrors, warnings, errors)
+			}
+		})
+	}
+}
+
+func TestInspectScaleDown(t *testing.T) {
+	newNode := func(name string, annotations map[string]string) corev1.Node {
+		return corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: annotations}}
+	}
+	newPod := func(namespace, name, nodeName string, labels, annotations map[string]string) corev1.Pod {
+		pod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: labels, Annotations: annotations}}
+		pod.Spec.NodeName = nodeName
+		pod.Status.Phase = corev1.PodRunning
+		return pod
+	}
+	newVolume := func(name string, numberOfReplicas int, currentNodeID string) longhorn.Volume {
+		volume := longhorn.Volume{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		volume.Spec.NumberOfReplicas = numberOfReplicas
+		volume.Status.CurrentNodeID = currentNodeID
+		return volume
+	}
+	newReplica := func(volumeName, nodeID string, healthy bool) longhorn.Replica {
+		replica := longhorn.Replica{ObjectMeta: metav1.ObjectMeta{Name: volumeName + "-r-" + nodeID}}
+		replica.Spec.VolumeName = volumeName
+		replica.Spec.NodeID = nodeID
+		if healthy {
+			replica.Spec.HealthyAt = "2024-07-16T09:00:00Z"
+		}
+		return replica
+	}
+
+	state := &scaleDownState{
+		longhornNamespace: consts.LonghornNamespace,
+		autoscalerEnabled: true,
+		lastReplicaBlocks: true,
+		nodes: []corev1.Node{
+			newNode("node-1", nil),
+			newNode("node-2", nil),
+			newNode("node-3", map[string]string{consts.AutoscalerAnnotationScaleDownDisabled: "true"}),
+			newNode("node-4", nil),
+			newNode("node-5", nil),
+			newNode("node-6", nil),
+		},
+		pods: []corev1.Pod{
+			newPod("default", "cache", "node-5", nil, map[string]string{consts.AutoscalerAnnotationSafeToEvict: "false"}),
+			newPod(consts.LonghornNamespace, "share-manager-vol-3", "node-6", map[string]string{"longhorn.io/component": "share-manager"}, nil),
+		},
+		volumes: []longhorn.Volume{
+			newVolume("vol-1", 2, "node-1"),
+			newVolume("vol-2", 3, ""),
+			newVolume("vol-3", 1, ""),
+		},
+		replicas: []longhorn.Replica{
+			newReplica("vol-1", "node-1", true),
+			newReplica("vol-1", "node-2", true),
+			newReplica("vol-2", "node-2", true),
+			newReplica("vol-2", "node-3", true),
+			newReplica("vol-2", "node-5", false),
+			newReplica("vol-3", "node-4", true),
+		},
+	}
+
+	collections := map[string]*types.LogCollection{}
+	inspectScaleDown(collections, state)
+
+	expected := map[string]*types.LogCollection{
+		"Node/node-1":  {Info: []string{"Longhorn blocks scale-down while volume vol-1 is attached to the node"}},
+		"Node/node-2":  {Warn: []string{"The Cluster Autoscaler can remove the node, rebuilding the replicas of volumes vol-1, vol-2 on the other nodes"}},
+		"Node/node-3":  {Info: []string{"Scale-down is disabled with annotation cluster-autoscaler.kubernetes.io/scale-down-disabled=true"}},
+		"Node/node-4":  {Info: []string{"Longhorn blocks scale-down while the node holds the last healthy replica of volume vol-3"}},
+		"Node/node-5":  {Info: []string{"Pod default/cache blocks scale-down with annotation cluster-autoscaler.kubernetes.io/safe-to-evict=false"}},
+		"Node/node-6":  {Info: []string{"Longhorn blocks scale-down while share manager pod share-manager-vol-3 runs on the node"}},
+		"Volume/vol-1": {Warn: []string{"Removing node node-2 leaves the volume with a single healthy replica until the rebuild completes. Annotate node node-2 with cluster-autoscaler.kubernetes.io/scale-down-disabled=true, or increase the number of replicas of the volume"}},
+		"Volume/vol-2": {Warn: []string{"Removing node node-2 leaves the volume with a single healthy replica until the rebuild completes. Annotate node node-2 with cluster-autoscaler.kubernetes.io/scale-down-disabled=true, or increase the number of replicas of the volume"}},
+	}
+	if !reflect.DeepEqual(collections, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, collections)
+	}
+
+	state.autoscalerEnabled = false
+	state.lastReplicaBlocks = false
+	collections = map[string]*types.LogCollection{}
+	inspectScaleDown(collections, state)
+
+	if expected := []string{"Longhorn blocks scale-down while share manager pod share-manager-vol-3 runs on the node", "The Longhorn pods on the node are not safe to evict while kubernetes-cluster-autoscaler-enabled is false"}; !reflect.DeepEqual(collections["Node/node-6"].Info, expected) {
+		t.Errorf("expected node-6 to be kept with %v, got %+v", expected, collections["Node/node-6"])
+	}
+	if expected := []string{"Removing node node-4 loses the last healthy replica of the volume. Annotate node node-4 with cluster-autoscaler.kubernetes.io/scale-down-disabled=true, or increase the number of replicas of the volume"}; !reflect.DeepEqual(collections["Volume/vol-3"].Error, expected) {
+		t.Errorf("expected vol-3 to lose its last replica, got %+v", collections["Volume/vol-3"])
+	}
+}
@@ -0,0 +1,375 @@
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// Layout of the backupstore: backupstore/volumes/<xx>/<yy>/<volume>/ holds the volume.cfg of the
+// volume, the backups/backup_<name>.cfg of its backups, the blocks/<xx>/<yy>/<checksum>.blk of its
+// blocks and the locks/<name>.lck of the locks taken by the backup, restore and deletion.
+const (
+	backupStoreVolumesDirectory = "backupstore/volumes"
+	volumeConfigFile            = "volume.cfg"
+	backupsDirectory            = "backups"
+	backupConfigPrefix          = "backup_"
+	configSuffix                = ".cfg"
+	blocksDirectory             = "blocks"
+	blockSuffix                 = ".blk"
+	locksDirectory              = "locks"
+	lockSuffix                  = ".lck"
+)
+
+// staleAge is the age after which a lock not refreshed by its holder, and a backup left
+// incomplete, are considered abandoned. Longhorn refreshes the locks it holds every few seconds.
+const staleAge = time.Hour
+
+// backupConfig is the part of the backup.cfg of a backup the checker uses. The created time is
+// only set when the backup completed.
+type backupConfig struct {
+	Name        string
+	CreatedTime string
+	Blocks      []struct {
+		Offset        int64
+		BlockChecksum string
+	}
+}
+
+// volumeFiles holds the files of a backed up volume, with their paths relative to the
+// backupstore root, and the files the checker found to be prunable.
+type volumeFiles struct {
+	directory string
+
+	config  *storeObject
+	backups []storeObject
+	blocks  map[string]storeObject // Keyed by checksum.
+	locks   []storeObject
+
+	orphanedBlocks  []storeObject
+	danglingBackups []storeObject
+	staleLocks      []storeObject
+}
+
+// Checker provide functions for checking the consistency of a backupstore, and pruning its orphaned
+// blocks, dangling backups and stale locks.
+type Checker struct {
+	CheckerCmdOptions
+
+	storeURL *backupStoreURL
+	store    store
+
+	volumes map[string]*volumeFiles
+}
+
+// CheckerCmdOptions holds the options for the command.
+type CheckerCmdOptions struct {
+	types.GlobalCmdOptions
+
+	LonghornNamespace string
+	Target            string // URL of the backupstore.
+	CredentialSecret  string // Secret with the credentials of an S3 backupstore. Defaults to the one of the matching BackupTarget.
+	Prune             bool
+}
+
+// Validate validates the command options.
+func (remote *Checker) Validate() error {
+	if remote.Target == "" {
+		return errors.Errorf("backupstore URL (--%s) is required", consts.CmdOptTarget)
+	}
+
+	storeURL, err := parseBackupStoreURL(remote.Target)
+	if err != nil {
+		return err
+	}
+	remote.storeURL = storeURL
+
+	return nil
+}
+
+// Init initializes the Checker. The credentials of an S3 backupstore are read from the credential
+// secret, defaulting to the one of the BackupTarget with the same URL.
+func (remote *Checker) Init() error {
+	if remote.storeURL.scheme == schemeFile {
+		remote.store = &fileStore{root: remote.storeURL.path}
+		return nil
+	}
+
+	ctx := context.Background()
+
+	if remote.CredentialSecret == "" {
+		longhornClient, err := kubeutils.NewLonghornClient("", remote.KubeConfigPath)
+		if err != nil {
+			return err
+		}
+
+		backupTargetList, err := longhornClient.LonghornV1beta2().BackupTargets(remote.LonghornNamespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return errors.Wrap(err, "failed to list backup targets")
+		}
+		for _, backupTarget := range backupTargetList.Items {
+			if strings.TrimSuffix(backupTarget.Spec.BackupTargetURL, "/") == strings.TrimSuffix(remote.Target, "/") {
+				remote.CredentialSecret = backupTarget.Spec.CredentialSecret
+				break
+			}
+		}
+		if remote.CredentialSecret == "" {
+			return errors.Errorf("no backup target with a credential secret found for %v, use --%s", remote.Target, consts.CmdOptCredentialSecret)
+		}
+	}
+
+	kubeClient, err := kubeutils.NewKubeClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	secret, err := kubeClient.CoreV1().Secrets(remote.LonghornNamespace).Get(ctx, remote.CredentialSecret, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to get credential secret %v", remote.CredentialSecret)
+	}
+
+	remote.store, err = newS3Store(remote.storeURL, secret.Data)
+	if err != nil {
+		return errors.Wrapf(err, "invalid credential secret %v", remote.CredentialSecret)
+	}
+
+	return nil
+}
+
+// Run checks the backed up volumes of the backupstore, sorted by name.
+func (remote *Checker) Run() (*types.BackupStoreReport, error) {
+	ctx := context.Background()
+
+	objects, err := remote.store.List(ctx, backupStoreVolumesDirectory)
+	if err != nil {
+		return nil, err
+	}
+
+	remote.volumes = groupVolumeFiles(objects)
+
+	names := make([]string, 0, len(remote.volumes))
+	for name := range remote.volumes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	report := &types.BackupStoreReport{
+		Target:  remote.Target,
+		Volumes: make([]types.BackupStoreVolume, 0, len(names)),
+	}
+	now := time.Now()
+	for _, name := range names {
+		logrus.Debugf("Checking volume %v", name)
+
+		volume := remote.checkVolume(ctx, name, remote.volumes[name], now)
+		report.ReclaimableBytes += volume.ReclaimableBytes
+		report.Volumes = append(report.Volumes, volume)
+	}
+	return report, nil
+}
+
+// Reclaim deletes the orphaned blocks, dangling backups and stale locks of the volumes checked by
+// Run. The locks of each volume are listed again right before pruning it, and its orphaned blocks
+// are kept when a backup started in the meantime.
+func (remote *Checker) Reclaim(report *types.BackupStoreReport) error {
+	ctx := context.Background()
+
+	for i := range report.Volumes {
+		volume := &report.Volumes[i]
+		files := remote.volumes[volume.Name]
+		if files == nil || volume.ReclaimableBytes == 0 {
+			continue
+		}
+
+		locks, err := remote.store.List(ctx, path.Join(files.directory, locksDirectory))
+		if err != nil {
+			return err
+		}
+		staleLocks := map[string]struct{}{}
+		for _, lock := range files.staleLocks {
+			staleLocks[lock.Path] = struct{}{}
+		}
+		for _, lock := range locks {
+			if _, ok := staleLocks[lock.Path]; !ok && !volume.Busy {
+				volume.Busy = true
+				volume.ReclaimableBytes -= volume.OrphanedBytes
+				report.ReclaimableBytes -= volume.OrphanedBytes
+			}
+		}
+
+		toDelete := append([]storeObject{}, files.danglingBackups...)
+		if !volume.Busy {
+			toDelete = append(toDelete, files.orphanedBlocks...)
+		}
+		toDelete = append(toDelete, files.staleLocks...)
+
+		logrus.Infof("Pruning %d files of volume %v", len(toDelete), volume.Name)
+		for _, object := range toDelete {
+			if err := remote.store.Delete(ctx, object.Path); err != nil {
+				return errors.Wrapf(err, "failed to prune volume %v", volume.Name)
+			}
+		}
+	}
+
+	report.Pruned = true
+	return nil
+}
+
+// groupVolumeFiles groups the files of the backupstore by volume.
+func groupVolumeFiles(objects []storeObject) map[string]*volumeFiles {
+	volumes := map[string]*volumeFiles{}
+	for i := range objects {
+		object := objects[i]
+
+		// <xx>/<yy>/<volume>/<file>...
+		parts := strings.Split(strings.TrimPrefix(object.Path, backupStoreVolumesDirectory+"/"), "/")
+		if len(parts) < 4 {
+			continue
+		}
+		name := parts[2]
+
+		files, ok := volumes[name]
+		if !ok {
+			files = &volumeFiles{
+				directory: path.Join(backupStoreVolumesDirectory, parts[0], parts[1], name),
+				blocks:    map[string]storeObject{},
+			}
+			volumes[name] = files
+		}
+
+		fileName := parts[len(parts)-1]
+		switch {
+		case len(parts) == 4 && fileName == volumeConfigFile:
+			files.config = &object
+		case parts[3] == backupsDirectory && strings.HasPrefix(fileName, backupConfigPrefix) && strings.HasSuffix(fileName, configSuffix):
+			files.backups = append(files.backups, object)
+		case parts[3] == blocksDirectory && strings.HasSuffix(fileName, blockSuffix):
+			files.blocks[strings.TrimSuffix(fileName, blockSuffix)] = object
+		case parts[3] == locksDirectory && strings.HasSuffix(fileName, lockSuffix):
+			files.locks = append(files.locks, object)
+		}
+	}
+	return volumes
+}
+
+// checkVolume checks the backups, blocks and locks of a volume. The blocks not referenced by any
+// completed backup are orphaned. A backup is dangling when its config is unreadable, or when it was
+// left incomplete for longer than the stale age, and a lock is stale when it was not refreshed for
+// longer than the stale age. A volume with a recent incomplete backup or lock is busy, and its
+// orphaned blocks may belong to a backup in progress.
+func (remote *Checker) checkVolume(ctx context.Context, name string, files *volumeFiles, now time.Time) types.BackupStoreVolume {
+	volume := types.BackupStoreVolume{
+		Name:   name,
+		Blocks: len(files.blocks),
+	}
+
+	if files.config == nil {
+		volume.Issues = append(volume.Issues, fmt.Sprintf("%v is missing", volumeConfigFile))
+	}
+
+	referenced := map[string][]string{} // Backups referencing each block.
+	for _, object := range files.backups {
+		backupName := strings.TrimSuffix(strings.TrimPrefix(path.Base(object.Path), backupConfigPrefix), configSuffix)
+
+		config, err := readBackupConfig(ctx, remote.store, object.Path)
+		switch {
+		case err != nil:
+			files.danglingBackups = append(files.danglingBackups, object)
+			volume.DanglingBackups = append(volume.DanglingBackups, backupName)
+			volume.Issues = append(volume.Issues, fmt.Sprintf("Backup %v is unreadable: %v", backupName, err))
+		case config.CreatedTime == "" && now.Sub(object.ModTime) > staleAge:
+			files.danglingBackups = append(files.danglingBackups, object)
+			volume.DanglingBackups = append(volume.DanglingBackups, backupName)
+			volume.Issues = append(volume.Issues, fmt.Sprintf("Backup %v was left incomplete since %v", backupName, object.ModTime.UTC().Format(time.RFC3339)))
+		case config.CreatedTime == "":
+			volume.Busy = true
+		default:
+			volume.Backups++
+			for _, block := range config.Blocks {
+				referenced[block.BlockChecksum] = append(referenced[block.BlockChecksum], backupName)
+			}
+		}
+	}
+
+	for _, object := range files.locks {
+		if now.Sub(object.ModTime) > staleAge {
+			files.staleLocks = append(files.staleLocks, object)
+			volume.StaleLocks = append(volume.StaleLocks, path.Base(object.Path))
+		} else {
+			volume.Busy = true
+		}
+	}
+
+	for checksum, object := range files.blocks {
+		if _, ok := referenced[checksum]; !ok {
+			files.orphanedBlocks = append(files.orphanedBlocks, object)
+			volume.OrphanedBlocks++
+			volume.OrphanedBytes += object.Size
+		}
+	}
+	sort.Slice(files.orphanedBlocks, func(i, j int) bool { return files.orphanedBlocks[i].Path < files.orphanedBlocks[j].Path })
+
+	corruptedBackups := map[string]struct{}{}
+	for checksum, backupNames := range referenced {
+		if _, ok := files.blocks[checksum]; ok {
+			continue
+		}
+		volume.MissingBlocks++
+		for _, backupName := range backupNames {
+			corruptedBackups[backupName] = struct{}{}
+		}
+	}
+	if volume.MissingBlocks > 0 {
+		volume.Issues = append(volume.Issues, fmt.Sprintf("%d blocks are missing, backups %v cannot be restored", volume.MissingBlocks, strings.Join(sortedKeys(corruptedBackups), ", ")))
+	}
+
+	for _, object := range files.danglingBackups {
+		volume.ReclaimableBytes += object.Size
+	}
+	for _, object := range files.staleLocks {
+		volume.ReclaimableBytes += object.Size
+	}
+	if !volume.Busy {
+		volume.ReclaimableBytes += volume.OrphanedBytes
+	} else if volume.OrphanedBlocks > 0 {
+		volume.Issues = append(volume.Issues, fmt.Sprintf("%d orphaned blocks are kept, a backup is in progress or a lock is held", volume.OrphanedBlocks))
+	}
+
+	return volume
+}
+
+// readBackupConfig reads and parses the config of a backup.
+func readBackupConfig(ctx context.Context, store store, name string) (*backupConfig, error) {
+	data, err := store.Read(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &backupConfig{}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %v", path.Base(name))
+	}
+	return config, nil
+}
+
+func sortedKeys(values map[string]struct{}) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,151 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseBackupStoreURL(t *testing.T) {
+	tests := map[string]struct {
+		target        string
+		expected      *backupStoreURL
+		expectedError bool
+	}{
+		"path": {
+			target:   "/mnt/nfs/",
+			expected: &backupStoreURL{scheme: schemeFile, path: "/mnt/nfs"},
+		},
+		"file": {
+			target:   "file:///mnt/nfs/backups",
+			expected: &backupStoreURL{scheme: schemeFile, path: "/mnt/nfs/backups"},
+		},
+		"s3": {
+			target:   "s3://backupbucket@us-east-1/longhorn/",
+			expected: &backupStoreURL{scheme: schemeS3, bucket: "backupbucket", region: "us-east-1", path: "longhorn"},
+		},
+		"s3 without path": {
+			target:   "s3://backupbucket@us-east-1/",
+			expected: &backupStoreURL{scheme: schemeS3, bucket: "backupbucket", region: "us-east-1"},
+		},
+		"s3 without region": {
+			target:        "s3://backupbucket/",
+			expectedError: true,
+		},
+		"nfs": {
+			target:        "nfs://longhorn-test-nfs-svc.default:/opt/backupstore",
+			expectedError: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			result, err := parseBackupStoreURL(test.target)
+			if test.expectedError {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Errorf("expected %+v, got %+v", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestCheckerRunAndPrune(t *testing.T) {
+	root := t.TempDir()
+	volume := "backupstore/volumes/5e/b1/pvc-1/"
+	writeFiles(t, root, map[string]string{
+		volume + "volume.cfg":                           `{"Name":"pvc-1"}`,
+		volume + "backups/backup_a.cfg":                 `{"Name":"a","CreatedTime":"2024-07-16T09:00:00Z","Blocks":[{"Offset":0,"BlockChecksum":"aaaa"},{"Offset":2097152,"BlockChecksum":"bbbb"}]}`,
+		volume + "backups/backup_b.cfg":                 `{"Name":"b","CreatedTime":"2024-07-17T09:00:00Z","Blocks":[{"Offset":0,"BlockChecksum":"aaaa"},{"Offset":2097152,"BlockChecksum":"cccc"}]}`,
+		volume + "backups/backup_incomplete.cfg":        `{"Name":"incomplete","CreatedTime":""}`,
+		volume + "backups/backup_corrupted.cfg":         `{"Name":`,
+		volume + "blocks/aa/aa/aaaa.blk":                "aaaa",
+		volume + "blocks/bb/bb/bbbb.blk":                "bbbb",
+		volume + "blocks/dd/dd/dddd.blk":                "dddddddd",
+		volume + "locks/lock-stale.lck":                 `{}`,
+		"backupstore/volumes/9a/3c/pvc-2/volume.cfg":    `{"Name":"pvc-2"}`,
+		"backupstore/volumes/9a/3c/pvc-2/locks/l.lck":   `{}`,
+		"backupstore/volumes/9a/3c/pvc-2/blocks/ee.blk": "eeee",
+	})
+
+	old := time.Now().Add(-2 * staleAge)
+	for _, name := range []string{"backups/backup_incomplete.cfg", "locks/lock-stale.lck"} {
+		if err := os.Chtimes(filepath.Join(root, volume, name), old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	checker := &Checker{CheckerCmdOptions: CheckerCmdOptions{Target: root}}
+	if err := checker.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := checker.Init(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	report, err := checker.Run()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(report.Volumes) != 2 {
+		t.Fatalf("expected 2 volumes, got %+v", report.Volumes)
+	}
+
+	result := report.Volumes[0]
+	if result.Name != "pvc-1" || result.Backups != 2 || result.Blocks != 3 || result.Busy {
+		t.Errorf("unexpected volume %+v", result)
+	}
+	if result.OrphanedBlocks != 1 || result.OrphanedBytes != 8 || result.MissingBlocks != 1 {
+		t.Errorf("expected 1 orphaned block of 8 bytes and 1 missing block, got %+v", result)
+	}
+	if !reflect.DeepEqual(result.DanglingBackups, []string{"corrupted", "incomplete"}) {
+		t.Errorf("unexpected dangling backups %v", result.DanglingBackups)
+	}
+	if !reflect.DeepEqual(result.StaleLocks, []string{"lock-stale.lck"}) {
+		t.Errorf("unexpected stale locks %v", result.StaleLocks)
+	}
+	if expected := int64(8 + len(`{"Name":`) + len(`{"Name":"incomplete","CreatedTime":""}`) + len(`{}`)); result.ReclaimableBytes != expected {
+		t.Errorf("expected %d reclaimable bytes, got %d", expected, result.ReclaimableBytes)
+	}
+
+	busy := report.Volumes[1]
+	if busy.Name != "pvc-2" || !busy.Busy || busy.OrphanedBlocks != 1 || busy.ReclaimableBytes != 0 {
+		t.Errorf("expected a busy volume with an orphaned block not reclaimable, got %+v", busy)
+	}
+
+	if err := checker.Reclaim(report); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, name := range []string{"blocks/dd/dd/dddd.blk", "backups/backup_incomplete.cfg", "backups/backup_corrupted.cfg", "locks/lock-stale.lck"} {
+		if _, err := os.Stat(filepath.Join(root, volume, name)); !os.IsNotExist(err) {
+			t.Errorf("expected %v to be pruned", name)
+		}
+	}
+	for _, name := range []string{volume + "blocks/aa/aa/aaaa.blk", volume + "backups/backup_a.cfg", "backupstore/volumes/9a/3c/pvc-2/blocks/ee.blk"} {
+		if _, err := os.Stat(filepath.Join(root, name)); err != nil {
+			t.Errorf("expected %v to be kept: %v", name, err)
+		}
+	}
+}
+
+func writeFiles(t *testing.T, directory string, files map[string]string) {
+	t.Helper()
+	for path, content := range files {
+		path = filepath.Join(directory, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package backup
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/longhorn/cli/pkg/consts"
+
+	s3utils "github.com/longhorn/cli/pkg/utils/s3"
+)
+
+const (
+	s3Timeout = time.Minute
+
+	// s3MaxObjectSize is the maximum size of the metadata files read from the backupstore.
+	s3MaxObjectSize = 64 << 20
+)
+
+// s3Store is a backupstore in an S3 or S3-compatible bucket, accessed with the credentials of the
+// credential secret of the backup target.
+type s3Store struct {
+	bucket        string
+	region        string
+	root          string // Prefix of the backupstore directory in the bucket.
+	endpoint      *url.URL
+	virtualHosted bool
+	credentials   s3utils.Credentials
+	httpClient    *http.Client
+}
+
+// s3ListResult is the result of a ListObjectsV2 request.
+type s3ListResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+}
+
+// newS3Store returns the store of the S3 backupstore with the keys of the credential secret of the
+// backup target: AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and optionally AWS_SESSION_TOKEN,
+// AWS_ENDPOINTS, AWS_CERT and VIRTUAL_HOSTED_STYLE.
+func newS3Store(storeURL *backupStoreURL, secret map[string][]byte) (*s3Store, error) {
+	store := &s3Store{
+		bucket: storeURL.bucket,
+		region: storeURL.region,
+		root:   storeURL.path,
+		credentials: s3utils.Credentials{
+			AccessKeyID:     string(secret[consts.EnvAwsAccessKeyID]),
+			SecretAccessKey: string(secret[consts.EnvAwsSecretAccessKey]),
+			SessionToken:    string(secret[consts.EnvAwsSessionToken]),
+		},
+		virtualHosted: strings.EqualFold(string(secret[consts.EnvVirtualHostedStyle]), "true"),
+	}
+	if store.credentials.AccessKeyID == "" || store.credentials.SecretAccessKey == "" {
+		return nil, errors.Errorf("%s and %s are required in the credential secret", consts.EnvAwsAccessKeyID, consts.EnvAwsSecretAccessKey)
+	}
+
+	endpoint := strings.TrimSpace(string(secret[consts.EnvAwsEndpoints]))
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", store.region)
+		store.virtualHosted = true
+	}
+	parsedEndpoint, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+	if err != nil || parsedEndpoint.Host == "" {
+		return nil, errors.Errorf("invalid %s %q", consts.EnvAwsEndpoints, endpoint)
+	}
+	store.endpoint = parsedEndpoint
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cert := secret[consts.EnvAwsCert]; len(cert) > 0 {
+		rootCAs, err := x509.SystemCertPool()
+		if err != nil {
+			rootCAs = x509.NewCertPool()
+		}
+		if !rootCAs.AppendCertsFromPEM(cert) {
+			return nil, errors.Errorf("invalid %s in the credential secret", consts.EnvAwsCert)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: rootCAs, MinVersion: tls.VersionTLS12}
+	}
+	store.httpClient = &http.Client{Timeout: s3Timeout, Transport: transport}
+
+	return store, nil
+}
+
+func (store *s3Store) List(ctx context.Context, prefix string) ([]storeObject, error) {
+	keyPrefix := store.getKey(prefix) + "/"
+
+	objects := []storeObject{}
+	continuationToken := ""
+	for {
+		query := url.Values{}
+		query.Set("list-type", "2")
+		query.Set("prefix", keyPrefix)
+		if continuationToken != "" {
+			query.Set("continuation-token", continuationToken)
+		}
+
+		resp, err := store.do(ctx, http.MethodGet, "", query)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to list %v", keyPrefix)
+		}
+		result := &s3ListResult{}
+		err = xml.NewDecoder(resp.Body).Decode(result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to decode the list of %v", keyPrefix)
+		}
+
+		for _, content := range result.Contents {
+			objects = append(objects, storeObject{
+				Path:    strings.TrimPrefix(strings.TrimPrefix(content.Key, store.root), "/"),
+				Size:    content.Size,
+				ModTime: content.LastModified,
+			})
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, nil
+		}
+		continuationToken = result.NextContinuationToken
+	}
+}
+
+func (store *s3Store) Read(ctx context.Context, name string) ([]byte, error) {
+	resp, err := store.do(ctx, http.MethodGet, store.getKey(name), nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %v", name)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, s3MaxObjectSize+1))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %v", name)
+	}
+	if len(data) > s3MaxObjectSize {
+		return nil, errors.Errorf("%v is larger than %v bytes", name, s3MaxObjectSize)
+	}
+	return data, nil
+}
+
+func (store *s3Store) Delete(ctx context.Context, name string) error {
+	resp, err := store.do(ctx, http.MethodDelete, store.getKey(name), nil)
+	if err != nil {
+		return errors.Wrapf(err, "failed to delete %v", name)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// getKey returns the key of the backupstore path in the bucket.
+func (store *s3Store) getKey(name string) string {
+	return strings.TrimPrefix(path.Join(store.root, name), "/")
+}
+
+// do sends the signed request for the key, or for the bucket when the key is empty, and returns
+// the response when it succeeded.
+func (store *s3Store) do(ctx context.Context, method, key string, query url.Values) (*http.Response, error) {
+	requestURL := *store.endpoint
+	if store.virtualHosted {
+		requestURL.Host = store.bucket + "." + requestURL.Host
+		requestURL.Path = "/" + key
+	} else {
+		requestURL.Path = path.Join("/", store.bucket, key)
+	}
+	requestURL.RawPath = s3utils.EscapeKey(requestURL.Path)
+	// The canonical query of the signature encodes the spaces as %20.
+	requestURL.RawQuery = strings.ReplaceAll(query.Encode(), "+", "%20")
+
+	req, err := http.NewRequestWithContext(ctx, method, requestURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	s3utils.SignRequest(req, nil, store.credentials, store.region, time.Now())
+
+	resp, err := store.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, errors.Errorf("%v %v returned %v: %s", method, requestURL.Path, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return resp, nil
+}
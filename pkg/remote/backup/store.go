@@ -0,0 +1,126 @@
+package backup
+
+import (
+	"context"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Schemes of the backupstore URLs the checker supports.
+const (
+	schemeFile = "file"
+	schemeS3   = "s3"
+)
+
+// storeObject is a file of the backupstore, with its path relative to the backupstore root.
+type storeObject struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+}
+
+// store lists, reads and deletes the files of a backupstore.
+type store interface {
+	// List returns the files under the prefix, recursively.
+	List(ctx context.Context, prefix string) ([]storeObject, error)
+	Read(ctx context.Context, path string) ([]byte, error)
+	Delete(ctx context.Context, path string) error
+}
+
+// backupStoreURL is a parsed backupstore URL, such as s3://bucket@us-east-1/path or
+// file:///mnt/nfs/path.
+type backupStoreURL struct {
+	scheme string
+	bucket string // Bucket of an S3 backupstore.
+	region string // Region of an S3 backupstore.
+	path   string // Path of the directory holding the backupstore directory.
+}
+
+// parseBackupStoreURL parses the URL of a backupstore. A path without scheme is a directory.
+func parseBackupStoreURL(target string) (*backupStoreURL, error) {
+	if !strings.Contains(target, "://") {
+		return &backupStoreURL{scheme: schemeFile, path: filepath.Clean(target)}, nil
+	}
+
+	parsed, err := url.Parse(target)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid backupstore URL %q", target)
+	}
+
+	switch parsed.Scheme {
+	case schemeFile:
+		if parsed.Path == "" {
+			return nil, errors.Errorf("backupstore URL %q has no path", target)
+		}
+		return &backupStoreURL{scheme: schemeFile, path: filepath.Clean(parsed.Path)}, nil
+	case schemeS3:
+		if parsed.User == nil || parsed.User.Username() == "" || parsed.Host == "" {
+			return nil, errors.Errorf("invalid S3 backupstore URL %q, expected s3://<bucket>@<region>/<path>", target)
+		}
+		return &backupStoreURL{
+			scheme: schemeS3,
+			bucket: parsed.User.Username(),
+			region: parsed.Host,
+			path:   strings.Trim(parsed.Path, "/"),
+		}, nil
+	}
+	return nil, errors.Errorf("unsupported backupstore URL %q, mount NFS and CIFS backupstores and use a file:// URL", target)
+}
+
+// fileStore is a backupstore in a local directory, such as a mounted NFS or CIFS share.
+type fileStore struct {
+	root string
+}
+
+func (store *fileStore) List(ctx context.Context, prefix string) ([]storeObject, error) {
+	objects := []storeObject{}
+	err := filepath.WalkDir(filepath.Join(store.root, filepath.FromSlash(prefix)), func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		relativePath, err := filepath.Rel(store.root, name)
+		if err != nil {
+			return err
+		}
+		objects = append(objects, storeObject{Path: filepath.ToSlash(relativePath), Size: info.Size(), ModTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list %v", path.Join(store.root, prefix))
+	}
+	return objects, nil
+}
+
+func (store *fileStore) Read(ctx context.Context, name string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(store.root, filepath.FromSlash(name)))
+	return data, errors.Wrapf(err, "failed to read %v", name)
+}
+
+func (store *fileStore) Delete(ctx context.Context, name string) error {
+	err := os.Remove(filepath.Join(store.root, filepath.FromSlash(name)))
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "failed to delete %v", name)
+	}
+	return nil
+}
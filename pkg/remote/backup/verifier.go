@@ -0,0 +1,433 @@
+package backup
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	kubeclient "k8s.io/client-go/kubernetes"
+	"k8s.io/utils/ptr"
+
+	longhorn "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+	lhclient "github.com/longhorn/longhorn-manager/k8s/pkg/client/clientset/versioned"
+	lhmgrtypes "github.com/longhorn/longhorn-manager/types"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/remote/update"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// Names of the verification steps.
+const (
+	StepRestore  = "restore"
+	StepChecksum = "checksum"
+)
+
+const pollInterval = 2 * time.Second
+
+// Verifier provide functions for verifying a backup by restoring it to a temporary volume.
+type Verifier struct {
+	VerifierCmdOptions
+
+	kubeClient     *kubeclient.Clientset
+	longhornClient *lhclient.Clientset
+
+	namespace    string
+	appName      string // Name of the temporary volume, PV, PVC and pod.
+	nodeSelector map[string]string
+
+	backup *longhorn.Backup
+}
+
+// VerifierCmdOptions holds the options for the command.
+type VerifierCmdOptions struct {
+	types.GlobalCmdOptions
+
+	LonghornNamespace string
+	BackupName        string
+	SHA256            string        // Expected SHA256 checksum of the restored block device, optional.
+	Timeout           time.Duration // Maximum time to wait for each step.
+}
+
+// Validate validates the command options.
+func (remote *Verifier) Validate() error {
+	if remote.BackupName == "" {
+		return errors.New("backup name is required")
+	}
+
+	if remote.SHA256 != "" {
+		remote.SHA256 = strings.ToLower(remote.SHA256)
+		if _, err := hex.DecodeString(remote.SHA256); err != nil || len(remote.SHA256) != 64 {
+			return errors.Errorf("invalid --%s %q, expected a SHA256 checksum in hexadecimal", consts.CmdOptSHA256, remote.SHA256)
+		}
+	}
+
+	if remote.Timeout <= 0 {
+		return errors.Errorf("timeout (--%s) must be positive", consts.CmdOptTimeout)
+	}
+
+	return nil
+}
+
+// Init initializes the Verifier. It ensures the backup is completed.
+func (remote *Verifier) Init() error {
+	kubeClient, err := kubeutils.NewKubeClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.kubeClient = kubeClient
+
+	longhornClient, err := kubeutils.NewLonghornClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.longhornClient = longhornClient
+
+	remote.namespace = remote.Namespace
+	if remote.namespace == "" {
+		remote.namespace = consts.LonghornNamespace
+	}
+	remote.appName = consts.AppNameBackupVerifier
+
+	remote.nodeSelector, err = kubeutils.ParseNodeSelector(remote.NodeSelector)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse %q argument", consts.CmdOptNodeSelector)
+	}
+
+	remote.backup, err = remote.longhornClient.LonghornV1beta2().Backups(remote.LonghornNamespace).Get(context.Background(), remote.BackupName, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to get backup %v", remote.BackupName)
+	}
+	if remote.backup.Status.State != longhorn.BackupStateCompleted || remote.backup.Status.URL == "" {
+		return errors.Errorf("backup %v is %v, only a completed backup can be verified", remote.BackupName, remote.backup.Status.State)
+	}
+
+	return nil
+}
+
+// Run runs the verification steps in order and returns the report. The steps after a failed
+// step are skipped. An error is only returned when the report cannot be produced.
+func (remote *Verifier) Run() (*types.VerifyReport, error) {
+	if _, err := kubeutils.CreateNamespace(remote.kubeClient, remote.namespace); err != nil {
+		return nil, err
+	}
+
+	steps := []struct {
+		name string
+		run  func(ctx context.Context) (string, error)
+	}{
+		{name: StepRestore, run: remote.restore},
+		{name: StepChecksum, run: remote.checksum},
+	}
+
+	report := &types.VerifyReport{Passed: true}
+	for _, step := range steps {
+		if !report.Passed {
+			report.Steps = append(report.Steps, types.VerifyStep{Name: step.name, Status: types.VerifyStepStatusSkip})
+			continue
+		}
+
+		logrus.Infof("Running step %v", step.name)
+		startTime := time.Now()
+
+		ctx, cancel := context.WithTimeout(context.Background(), remote.Timeout)
+		message, err := step.run(ctx)
+		cancel()
+
+		result := types.VerifyStep{
+			Name:     step.name,
+			Status:   types.VerifyStepStatusPass,
+			Message:  message,
+			Duration: time.Since(startTime).Round(time.Second).String(),
+		}
+		if err != nil {
+			logrus.WithError(err).Errorf("Failed step %v", step.name)
+			result.Status = types.VerifyStepStatusFail
+			result.Message = err.Error()
+			report.Passed = false
+		}
+		report.Steps = append(report.Steps, result)
+	}
+
+	return report, nil
+}
+
+// restore creates the temporary volume from the backup, and waits for Longhorn to restore it.
+// Longhorn verifies each restored block against the checksum recorded in the backup, and fails
+// the restore on a mismatch.
+func (remote *Verifier) restore(ctx context.Context) (string, error) {
+	volume, err := remote.newVolume()
+	if err != nil {
+		return "", err
+	}
+
+	logrus.Infof("Restoring backup %v to volume %v", remote.backup.Status.URL, volume.Name)
+	if _, err := remote.longhornClient.LonghornV1beta2().Volumes(remote.LonghornNamespace).Create(ctx, volume, metav1.CreateOptions{}); err != nil {
+		return "", errors.Wrapf(err, "failed to create volume %v", volume.Name)
+	}
+
+	err = wait.PollUntilContextCancel(ctx, pollInterval, true, func(ctx context.Context) (bool, error) {
+		volume, err := remote.longhornClient.LonghornV1beta2().Volumes(remote.LonghornNamespace).Get(ctx, remote.appName, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		return isVolumeRestored(volume)
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed waiting for volume %v to restore backup %v", remote.appName, remote.backup.Name)
+	}
+
+	return fmt.Sprintf("Restored backup %v of volume %v to volume %v", remote.backup.Name, remote.backup.Status.VolumeName, remote.appName), nil
+}
+
+// checksum computes the SHA256 checksum of the block device of the restored volume from a pod,
+// and compares it with the expected checksum, if any.
+func (remote *Verifier) checksum(ctx context.Context) (string, error) {
+	size, err := remote.getVolumeSize()
+	if err != nil {
+		return "", err
+	}
+
+	pv := remote.newPersistentVolume(size)
+	kubeutils.SetRunMetadata(remote.kubeClient, pv)
+	if _, err := remote.kubeClient.CoreV1().PersistentVolumes().Create(ctx, pv, metav1.CreateOptions{}); err != nil {
+		return "", errors.Wrapf(err, "failed to create PV %v", remote.appName)
+	}
+	pvc := remote.newPersistentVolumeClaim(size)
+	kubeutils.SetRunMetadata(remote.kubeClient, pvc)
+	if _, err := remote.kubeClient.CoreV1().PersistentVolumeClaims(remote.namespace).Create(ctx, pvc, metav1.CreateOptions{}); err != nil {
+		return "", errors.Wrapf(err, "failed to create PVC %v", remote.appName)
+	}
+
+	pod := remote.newPod()
+	if err := kubeutils.SetPodOptions(&pod.Spec, &remote.GlobalCmdOptions); err != nil {
+		return "", err
+	}
+	kubeutils.SetRunMetadata(remote.kubeClient, pod)
+	if _, err := remote.kubeClient.CoreV1().Pods(remote.namespace).Create(ctx, pod, metav1.CreateOptions{}); err != nil {
+		return "", errors.Wrapf(err, "failed to create pod %v", pod.Name)
+	}
+
+	waitErr := kubeutils.WaitForPodCompleted(ctx, remote.kubeClient, remote.namespace, pod.Name)
+
+	output, err := kubeutils.GetPodContainerLog(ctx, remote.kubeClient, remote.namespace, pod.Name, consts.ContainerNameChecksum)
+	if waitErr != nil {
+		if err == nil {
+			logrus.Debugf("Log of pod %v: %v", pod.Name, output)
+		}
+		return "", errors.Wrapf(waitErr, "failed waiting for pod %v to compute the checksum", pod.Name)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	checksum, err := update.ParseChecksum(strings.NewReader(output))
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to parse the output of pod %v", pod.Name)
+	}
+
+	return compareChecksum(checksum, remote.SHA256)
+}
+
+// Cleanup deletes the pod, PVC, PV and temporary volume created for the verification.
+func (remote *Verifier) Cleanup() error {
+	ctx, cancel := context.WithTimeout(context.Background(), remote.Timeout)
+	defer cancel()
+
+	if err := kubeutils.DeletePod(ctx, remote.kubeClient, remote.namespace, remote.appName); err != nil {
+		return err
+	}
+
+	err := remote.kubeClient.CoreV1().PersistentVolumeClaims(remote.namespace).Delete(ctx, remote.appName, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to delete PVC %v", remote.appName)
+	}
+
+	err = remote.kubeClient.CoreV1().PersistentVolumes().Delete(ctx, remote.appName, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to delete PV %v", remote.appName)
+	}
+
+	err = remote.longhornClient.LonghornV1beta2().Volumes(remote.LonghornNamespace).Delete(ctx, remote.appName, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to delete volume %v", remote.appName)
+	}
+
+	return nil
+}
+
+// isVolumeRestored returns true once the volume restored its backup, or an error when the restore
+// failed.
+func isVolumeRestored(volume *longhorn.Volume) (bool, error) {
+	for _, condition := range volume.Status.Conditions {
+		if condition.Type == longhorn.VolumeConditionTypeRestore && condition.Reason == longhorn.VolumeConditionReasonRestoreFailure {
+			return false, errors.Errorf("restore failed: %v", condition.Message)
+		}
+	}
+	if volume.Status.Robustness == longhorn.VolumeRobustnessFaulted {
+		return false, errors.Errorf("volume %v is faulted", volume.Name)
+	}
+	return volume.Status.RestoreInitiated && !volume.Status.RestoreRequired, nil
+}
+
+// compareChecksum returns the message of the checksum step, or an error when the checksum does
+// not match the expected checksum.
+func compareChecksum(checksum, expected string) (string, error) {
+	if expected == "" {
+		return fmt.Sprintf("Restored data has SHA256 checksum %v", checksum), nil
+	}
+	if checksum != expected {
+		return "", errors.Errorf("restored data has SHA256 checksum %v, expected %v", checksum, expected)
+	}
+	return fmt.Sprintf("Restored data matches SHA256 checksum %v", checksum), nil
+}
+
+func (remote *Verifier) getVolumeSize() (int64, error) {
+	size, err := strconv.ParseInt(remote.backup.Status.VolumeSize, 10, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid volume size %q of backup %v", remote.backup.Status.VolumeSize, remote.backup.Name)
+	}
+	return size, nil
+}
+
+// newVolume prepares the temporary volume restoring the backup, with a single replica.
+func (remote *Verifier) newVolume() (*longhorn.Volume, error) {
+	size, err := remote.getVolumeSize()
+	if err != nil {
+		return nil, err
+	}
+
+	backupTargetName := remote.backup.Status.BackupTargetName
+	if backupTargetName == "" {
+		backupTargetName = remote.backup.Labels[lhmgrtypes.LonghornLabelBackupTarget]
+	}
+
+	return &longhorn.Volume{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: remote.appName,
+			Labels: map[string]string{
+				"app":                 remote.appName,
+				consts.LabelManagedBy: consts.LabelValueManagedBy,
+			},
+		},
+		Spec: longhorn.VolumeSpec{
+			Size:             size,
+			FromBackup:       remote.backup.Status.URL,
+			BackupTargetName: backupTargetName,
+			NumberOfReplicas: 1,
+			BackingImage:     remote.backup.Status.VolumeBackingImageName,
+			Frontend:         longhorn.VolumeFrontendBlockDev,
+		},
+	}, nil
+}
+
+// newPersistentVolume prepares the PV of the temporary volume. It is retained, so the volume is
+// only deleted by the cleanup.
+func (remote *Verifier) newPersistentVolume(size int64) *corev1.PersistentVolume {
+	return &corev1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: remote.appName,
+			Labels: map[string]string{
+				"app":                 remote.appName,
+				consts.LabelManagedBy: consts.LabelValueManagedBy,
+			},
+		},
+		Spec: corev1.PersistentVolumeSpec{
+			Capacity: corev1.ResourceList{
+				corev1.ResourceStorage: *resource.NewQuantity(size, resource.BinarySI),
+			},
+			AccessModes:                   []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			PersistentVolumeReclaimPolicy: corev1.PersistentVolumeReclaimRetain,
+			StorageClassName:              "",
+			VolumeMode:                    ptr.To(corev1.PersistentVolumeBlock),
+			ClaimRef: &corev1.ObjectReference{
+				Namespace: remote.namespace,
+				Name:      remote.appName,
+			},
+			PersistentVolumeSource: corev1.PersistentVolumeSource{
+				CSI: &corev1.CSIPersistentVolumeSource{
+					Driver:       lhmgrtypes.LonghornDriverName,
+					VolumeHandle: remote.appName,
+				},
+			},
+		},
+	}
+}
+
+func (remote *Verifier) newPersistentVolumeClaim(size int64) *corev1.PersistentVolumeClaim {
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      remote.appName,
+			Namespace: remote.namespace,
+			Labels: map[string]string{
+				"app":                 remote.appName,
+				consts.LabelManagedBy: consts.LabelValueManagedBy,
+			},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			StorageClassName: ptr.To(""),
+			VolumeMode:       ptr.To(corev1.PersistentVolumeBlock),
+			VolumeName:       remote.appName,
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: *resource.NewQuantity(size, resource.BinarySI),
+				},
+			},
+		},
+	}
+}
+
+// newPod prepares the pod computing the checksum of the block device of the restored volume.
+func (remote *Verifier) newPod() *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      remote.appName,
+			Namespace: remote.namespace,
+			Labels: map[string]string{
+				"app":                 remote.appName,
+				consts.LabelManagedBy: consts.LabelValueManagedBy,
+			},
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			Containers: []corev1.Container{
+				{
+					Name:    consts.ContainerNameChecksum,
+					Image:   remote.Image,
+					Command: []string{"sha256sum", consts.VolumeDeviceBackupVerifyPath},
+					VolumeDevices: []corev1.VolumeDevice{
+						{
+							Name:       consts.VolumeDeviceBackupVerifyName,
+							DevicePath: consts.VolumeDeviceBackupVerifyPath,
+						},
+					},
+				},
+			},
+			Volumes: []corev1.Volume{
+				{
+					Name: consts.VolumeDeviceBackupVerifyName,
+					VolumeSource: corev1.VolumeSource{
+						PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+							ClaimName: remote.appName,
+						},
+					},
+				},
+			},
+			NodeSelector: remote.nodeSelector,
+		},
+	}
+}
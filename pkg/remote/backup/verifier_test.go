@@ -0,0 +1,89 @@
+package backup
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	longhorn "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+)
+
+func TestValidate(t *testing.T) {
+	checksum := strings.Repeat("A1", 32)
+	verifier := &Verifier{VerifierCmdOptions: VerifierCmdOptions{BackupName: "backup-1", SHA256: checksum, Timeout: time.Minute}}
+	if err := verifier.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if verifier.SHA256 != strings.ToLower(checksum) {
+		t.Errorf("expected the checksum in lower case, got %v", verifier.SHA256)
+	}
+
+	verifier.SHA256 = "a1b2"
+	if err := verifier.Validate(); err == nil {
+		t.Error("expected an error for a short checksum")
+	}
+}
+
+func TestIsVolumeRestored(t *testing.T) {
+	tests := map[string]struct {
+		status        longhorn.VolumeStatus
+		expected      bool
+		expectedError bool
+	}{
+		"not initiated": {
+			status: longhorn.VolumeStatus{RestoreRequired: true},
+		},
+		"restoring": {
+			status: longhorn.VolumeStatus{RestoreRequired: true, RestoreInitiated: true},
+		},
+		"restored": {
+			status:   longhorn.VolumeStatus{RestoreInitiated: true},
+			expected: true,
+		},
+		"failed": {
+			status: longhorn.VolumeStatus{
+				RestoreRequired:  true,
+				RestoreInitiated: true,
+				Conditions: []longhorn.Condition{
+					{Type: longhorn.VolumeConditionTypeRestore, Reason: longhorn.VolumeConditionReasonRestoreFailure, Message: "checksum mismatch"},
+				},
+			},
+			expectedError: true,
+		},
+		"faulted": {
+			status:        longhorn.VolumeStatus{RestoreRequired: true, RestoreInitiated: true, Robustness: longhorn.VolumeRobustnessFaulted},
+			expectedError: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			restored, err := isVolumeRestored(&longhorn.Volume{Status: test.status})
+			if test.expectedError {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if restored != test.expected {
+				t.Errorf("expected %v, got %v", test.expected, restored)
+			}
+		})
+	}
+}
+
+func TestCompareChecksum(t *testing.T) {
+	checksum := strings.Repeat("a1", 32)
+	if _, err := compareChecksum(checksum, ""); err != nil {
+		t.Errorf("unexpected error without expected checksum: %v", err)
+	}
+	if _, err := compareChecksum(checksum, checksum); err != nil {
+		t.Errorf("unexpected error for a matching checksum: %v", err)
+	}
+	if _, err := compareChecksum(checksum, strings.Repeat("b2", 32)); err == nil {
+		t.Error("expected an error for a mismatching checksum")
+	}
+}
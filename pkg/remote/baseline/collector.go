@@ -0,0 +1,362 @@
+package baseline
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/utils/ptr"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeclient "k8s.io/client-go/kubernetes"
+
+	commonkube "github.com/longhorn/go-common-libs/kubernetes"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// CollectorCmdOptions holds the options for collecting the prerequisite state of the nodes.
+type CollectorCmdOptions struct {
+	types.GlobalCmdOptions
+}
+
+// Creator provide functions for recording the prerequisite state of the nodes in a signed
+// baseline document.
+type Creator struct {
+	CreatorCmdOptions
+
+	collector *collector
+	key       []byte
+}
+
+// CreatorCmdOptions holds the options for the command.
+type CreatorCmdOptions struct {
+	CollectorCmdOptions
+
+	KeyFile    string
+	OutputFile string // Defaults to stdout.
+}
+
+// Validate validates the command options, and reads the signing key.
+func (remote *Creator) Validate() error {
+	if remote.KeyFile == "" {
+		return errors.Errorf("signing key (--%s) is required", consts.CmdOptKeyFile)
+	}
+
+	key, err := ReadKeyFile(remote.KeyFile)
+	if err != nil {
+		return err
+	}
+	remote.key = key
+
+	return nil
+}
+
+// Init initializes the Creator.
+func (remote *Creator) Init() error {
+	collector, err := newCollector(&remote.CollectorCmdOptions)
+	if err != nil {
+		return err
+	}
+	remote.collector = collector
+
+	return nil
+}
+
+// Run collects the prerequisite state of the nodes, and writes the signed baseline to the output
+// file, or returns it to be printed when there is none.
+func (remote *Creator) Run() (*types.Baseline, error) {
+	nodes, err := remote.collector.collect()
+	if err != nil {
+		return nil, err
+	}
+
+	baseline := &types.Baseline{
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+		Nodes:     nodes,
+	}
+	if err := SignBaseline(baseline, remote.key); err != nil {
+		return nil, err
+	}
+
+	if remote.OutputFile == "" {
+		return baseline, nil
+	}
+
+	data, err := EncodeBaseline(baseline)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(remote.OutputFile, data, 0644); err != nil {
+		return nil, errors.Wrapf(err, "failed to write baseline %v", remote.OutputFile)
+	}
+	return baseline, nil
+}
+
+// Cleanup deletes the DaemonSet created for collecting the prerequisites.
+func (remote *Creator) Cleanup() error {
+	return remote.collector.cleanup()
+}
+
+// Checker provide functions for reporting the drift of the nodes from a signed baseline document.
+type Checker struct {
+	CheckerCmdOptions
+
+	collector *collector
+	baseline  *types.Baseline
+}
+
+// CheckerCmdOptions holds the options for the command.
+type CheckerCmdOptions struct {
+	CollectorCmdOptions
+
+	KeyFile      string
+	BaselineFile string
+}
+
+// Validate validates the command options, and verifies the signature of the baseline.
+func (remote *Checker) Validate() error {
+	if remote.BaselineFile == "" {
+		return errors.Errorf("baseline (--%s) is required", consts.CmdOptFilename)
+	}
+	if remote.KeyFile == "" {
+		return errors.Errorf("signing key (--%s) is required", consts.CmdOptKeyFile)
+	}
+
+	key, err := ReadKeyFile(remote.KeyFile)
+	if err != nil {
+		return err
+	}
+
+	baseline, err := ReadBaseline(remote.BaselineFile)
+	if err != nil {
+		return err
+	}
+	if err := VerifyBaseline(baseline, key); err != nil {
+		return errors.Wrapf(err, "failed to verify baseline %v", remote.BaselineFile)
+	}
+	remote.baseline = baseline
+
+	return nil
+}
+
+// Init initializes the Checker.
+func (remote *Checker) Init() error {
+	collector, err := newCollector(&remote.CollectorCmdOptions)
+	if err != nil {
+		return err
+	}
+	remote.collector = collector
+
+	return nil
+}
+
+// Run collects the prerequisite state of the nodes, and compares it with the baseline.
+func (remote *Checker) Run() (*types.BaselineDriftCollection, error) {
+	nodes, err := remote.collector.collect()
+	if err != nil {
+		return nil, err
+	}
+
+	return CompareBaseline(remote.baseline, nodes), nil
+}
+
+// Cleanup deletes the DaemonSet created for collecting the prerequisites.
+func (remote *Checker) Cleanup() error {
+	return remote.collector.cleanup()
+}
+
+// collector collects the prerequisite state of the nodes with a DaemonSet running
+// "longhornctl-local collect prerequisites".
+type collector struct {
+	options *CollectorCmdOptions
+
+	kubeClient *kubeclient.Clientset
+
+	appName   string // App name of the DaemonSet.
+	namespace string
+}
+
+func newCollector(options *CollectorCmdOptions) (*collector, error) {
+	kubeClient, err := kubeutils.NewKubeClient("", options.KubeConfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	namespace := options.Namespace
+	if namespace == "" {
+		namespace = consts.LonghornNamespace
+	}
+
+	return &collector{
+		options:    options,
+		kubeClient: kubeClient,
+		appName:    consts.AppNameBaselineCollector,
+		namespace:  namespace,
+	}, nil
+}
+
+// collect creates the DaemonSet, and returns the prerequisites keyed by the node name once the init
+// and output containers of all the pods complete.
+func (c *collector) collect() (map[string]*types.NodePrerequisites, error) {
+	nodeSelector, err := kubeutils.ParseNodeSelector(c.options.NodeSelector)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %q argument", consts.CmdOptNodeSelector)
+	}
+	newDaemonSet := c.newDaemonSet(nodeSelector)
+	if err := kubeutils.SetPodOptions(&newDaemonSet.Spec.Template.Spec, &c.options.GlobalCmdOptions); err != nil {
+		return nil, err
+	}
+
+	_, err = kubeutils.CreateNamespace(c.kubeClient, c.namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	kubeutils.SetRunMetadata(c.kubeClient, newDaemonSet)
+	kubeutils.LogManifest(newDaemonSet)
+	daemonSet, err := kubeutils.CreateDaemonSet(c.kubeClient, newDaemonSet, c.options.MinNodes)
+	if err != nil {
+		return nil, err
+	}
+
+	err = kubeutils.MonitorDaemonSetContainer(c.kubeClient, daemonSet, consts.ContainerNameInit, kubeutils.WaitForDaemonSetContainersExit, ptr.To(consts.ContainerConditionMaxTolerationMedium))
+	if err != nil {
+		return nil, err
+	}
+
+	err = kubeutils.MonitorDaemonSetContainer(c.kubeClient, daemonSet, consts.ContainerNameOutput, kubeutils.WaitForDaemonSetContainersExit, ptr.To(consts.ContainerConditionMaxTolerationShort))
+	if err != nil {
+		return nil, err
+	}
+
+	podCollections, err := kubeutils.GetDaemonSetPodCollections(c.kubeClient, daemonSet, consts.ContainerNameOutput, false, false, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := map[string]*types.NodePrerequisites{}
+	for _, podCollection := range podCollections.Pods {
+		prerequisites := &types.NodePrerequisites{}
+		if err := json.Unmarshal([]byte(podCollection.Log), prerequisites); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse the prerequisites of node %v", podCollection.Node)
+		}
+		nodes[podCollection.Node] = prerequisites
+	}
+
+	return nodes, nil
+}
+
+func (c *collector) cleanup() error {
+	return commonkube.DeleteDaemonSet(c.kubeClient, c.namespace, c.appName)
+}
+
+// newDaemonSet prepares the DaemonSet collecting the prerequisites. The files are read from the
+// host root filesystem, mounted read-only, and the packages and services are queried in the host
+// mount namespace.
+func (c *collector) newDaemonSet(nodeSelector map[string]string) *appsv1.DaemonSet {
+	outputFilePath := filepath.Join(consts.VolumeMountSharedDirectory, consts.FileNameOutputJSON)
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      c.appName,
+			Namespace: c.namespace,
+			Labels: map[string]string{
+				"app":                 c.appName,
+				consts.LabelManagedBy: consts.LabelValueManagedBy,
+			},
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app": c.appName,
+				},
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						"app":                 c.appName,
+						consts.LabelManagedBy: consts.LabelValueManagedBy,
+					},
+				},
+				Spec: corev1.PodSpec{
+					HostPID: true,
+					InitContainers: []corev1.Container{
+						{
+							Name:    consts.ContainerNameInit,
+							Image:   c.options.Image,
+							Command: []string{consts.CmdLonghornctlLocal, consts.SubCmdCollect, consts.SubCmdPrerequisites},
+							Env: []corev1.EnvVar{
+								{
+									Name:  consts.EnvLogLevel,
+									Value: c.options.LogLevel,
+								},
+								{
+									Name:  consts.EnvOutputFilePath,
+									Value: outputFilePath,
+								},
+							},
+							SecurityContext: kubeutils.NewSecurityContext(c.options.Privileged, kubeutils.CapabilitiesHostRead, kubeutils.CapabilitiesHostNamespaces),
+							VolumeMounts: []corev1.VolumeMount{
+								{
+									Name:      consts.VolumeMountHostName,
+									MountPath: consts.VolumeMountHostDirectory,
+									ReadOnly:  true,
+								},
+								{
+									Name:      consts.VolumeMountSharedName,
+									MountPath: consts.VolumeMountSharedDirectory,
+								},
+							},
+						},
+						{
+							Name:    consts.ContainerNameOutput,
+							Image:   c.options.Image,
+							Command: []string{"cat", outputFilePath},
+							Env:     []corev1.EnvVar{},
+							VolumeMounts: []corev1.VolumeMount{
+								{
+									Name:      consts.VolumeMountSharedName,
+									MountPath: consts.VolumeMountSharedDirectory,
+								},
+							},
+						},
+					},
+					Containers: []corev1.Container{
+						{
+							Name:  consts.ContainerNamePause,
+							Image: consts.ImagePause,
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: consts.VolumeMountHostName,
+							VolumeSource: corev1.VolumeSource{
+								HostPath: &corev1.HostPathVolumeSource{
+									Path: "/",
+								},
+							},
+						},
+						{
+							Name: consts.VolumeMountSharedName,
+							VolumeSource: corev1.VolumeSource{
+								EmptyDir: &corev1.EmptyDirVolumeSource{},
+							},
+						},
+					},
+					NodeSelector: nodeSelector,
+				},
+			},
+			UpdateStrategy: appsv1.DaemonSetUpdateStrategy{
+				Type: appsv1.RollingUpdateDaemonSetStrategyType,
+			},
+		},
+	}
+}
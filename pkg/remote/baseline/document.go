@@ -0,0 +1,192 @@
+package baseline
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+// signaturePrefix is the prefix of the signature, naming its algorithm.
+const signaturePrefix = "hmac-sha256:"
+
+// minKeySize is the minimum size of the signing key in bytes.
+const minKeySize = 16
+
+// ReadKeyFile reads the signing key. The surrounding whitespaces are ignored, so the key can be
+// generated with, for example, openssl rand -hex 32 > baseline.key.
+func ReadKeyFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read signing key %v", path)
+	}
+
+	key := []byte(strings.TrimSpace(string(data)))
+	if len(key) < minKeySize {
+		return nil, errors.Errorf("signing key %v must be at least %d bytes", path, minKeySize)
+	}
+	return key, nil
+}
+
+// SignBaseline sets the signature of the baseline, computed over its JSON encoding without the
+// signature. The maps are encoded with sorted keys, so the encoding is stable across the YAML and
+// JSON round trips of the document.
+func SignBaseline(baseline *types.Baseline, key []byte) error {
+	signature, err := computeSignature(baseline, key)
+	if err != nil {
+		return err
+	}
+	baseline.Signature = signature
+	return nil
+}
+
+// VerifyBaseline checks the signature of the baseline.
+func VerifyBaseline(baseline *types.Baseline, key []byte) error {
+	if baseline.Signature == "" {
+		return errors.New("baseline is not signed")
+	}
+	if !strings.HasPrefix(baseline.Signature, signaturePrefix) {
+		return errors.Errorf("unsupported signature %q, it must start with %s", baseline.Signature, signaturePrefix)
+	}
+
+	signature, err := computeSignature(baseline, key)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(signature), []byte(baseline.Signature)) {
+		return errors.New("signature of the baseline does not match, the baseline was modified or signed with another key")
+	}
+	return nil
+}
+
+func computeSignature(baseline *types.Baseline, key []byte) (string, error) {
+	unsigned := *baseline
+	unsigned.Signature = ""
+	data, err := json.Marshal(&unsigned)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to encode baseline")
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// EncodeBaseline encodes the baseline document in YAML.
+func EncodeBaseline(baseline *types.Baseline) ([]byte, error) {
+	data, err := yaml.Marshal(baseline)
+	return data, errors.Wrap(err, "failed to encode baseline")
+}
+
+// ReadBaseline reads the baseline document, in YAML or JSON.
+func ReadBaseline(path string) (*types.Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read baseline %v", path)
+	}
+
+	baseline := &types.Baseline{}
+	if err := yaml.UnmarshalStrict(data, baseline); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse baseline %v", path)
+	}
+	return baseline, nil
+}
+
+// CompareBaseline returns the drift of the nodes from the baseline. The nodes of the baseline
+// missing from the nodes, and the nodes missing from the baseline, drift as a whole.
+func CompareBaseline(baseline *types.Baseline, nodes map[string]*types.NodePrerequisites) *types.BaselineDriftCollection {
+	collection := &types.BaselineDriftCollection{
+		CreatedAt: baseline.CreatedAt,
+		Nodes:     map[string]*types.NodeBaselineDrift{},
+	}
+
+	for node, expected := range baseline.Nodes {
+		actual, ok := nodes[node]
+		if !ok {
+			collection.Nodes[node] = &types.NodeBaselineDrift{
+				Drifts: []types.BaselineDrift{{Category: types.BaselineDriftCategoryNode, Expected: "present"}},
+			}
+			continue
+		}
+		collection.Nodes[node] = &types.NodeBaselineDrift{
+			Drifts: compareNodePrerequisites(expected, actual),
+			Errors: actual.Errors,
+		}
+	}
+
+	for node, actual := range nodes {
+		if _, ok := baseline.Nodes[node]; ok {
+			continue
+		}
+		collection.Nodes[node] = &types.NodeBaselineDrift{
+			Drifts: []types.BaselineDrift{{Category: types.BaselineDriftCategoryNode, Actual: "present"}},
+			Errors: actual.Errors,
+		}
+	}
+
+	return collection
+}
+
+// compareNodePrerequisites returns the prerequisites of the node with a state different from the
+// baseline, sorted by category and name.
+func compareNodePrerequisites(expected, actual *types.NodePrerequisites) []types.BaselineDrift {
+	drifts := []types.BaselineDrift{}
+	compareValue := func(category types.BaselineDriftCategory, expected, actual string) {
+		if expected != actual {
+			drifts = append(drifts, types.BaselineDrift{Category: category, Expected: expected, Actual: actual})
+		}
+	}
+	compareMap := func(category types.BaselineDriftCategory, expected, actual map[string]string) {
+		names := map[string]bool{}
+		for name := range expected {
+			names[name] = true
+		}
+		for name := range actual {
+			names[name] = true
+		}
+
+		sortedNames := make([]string, 0, len(names))
+		for name := range names {
+			sortedNames = append(sortedNames, name)
+		}
+		sort.Strings(sortedNames)
+
+		for _, name := range sortedNames {
+			if expected[name] != actual[name] {
+				drifts = append(drifts, types.BaselineDrift{Category: category, Name: name, Expected: expected[name], Actual: actual[name]})
+			}
+		}
+	}
+
+	compareValue(types.BaselineDriftCategoryOperatingSystem, expected.OperatingSystem, actual.OperatingSystem)
+	compareValue(types.BaselineDriftCategoryKernel, expected.Kernel, actual.Kernel)
+	compareMap(types.BaselineDriftCategoryPackage, expected.Packages, actual.Packages)
+	compareMap(types.BaselineDriftCategoryModule, expected.Modules, actual.Modules)
+	compareMap(types.BaselineDriftCategoryService, expected.Services, actual.Services)
+	compareMap(types.BaselineDriftCategorySysctl, expected.Sysctls, actual.Sysctls)
+	compareValue(types.BaselineDriftCategoryMultipathConfig, expected.MultipathConfig, actual.MultipathConfig)
+
+	if len(drifts) == 0 {
+		return nil
+	}
+	return drifts
+}
+
+// HasDrift returns whether a node drifts from the baseline.
+func HasDrift(collection *types.BaselineDriftCollection) bool {
+	for _, node := range collection.Nodes {
+		if len(node.Drifts) > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package baseline
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func newTestBaseline() *types.Baseline {
+	return &types.Baseline{
+		CreatedAt: "2024-07-16T09:40:31Z",
+		Nodes: map[string]*types.NodePrerequisites{
+			"node-1": {
+				OperatingSystem: "sles",
+				Kernel:          "5.14.21-150500.55.65-default",
+				Packages:        map[string]string{"open-iscsi": "installed", "nfs-client": "installed"},
+				Modules:         map[string]string{"dm_crypt": "loaded"},
+				Services:        map[string]string{"multipathd.service": "inactive (disabled)"},
+				Sysctls:         map[string]string{"vm.dirty_ratio": "10"},
+				MultipathConfig: "absent",
+			},
+		},
+	}
+}
+
+func TestSignBaseline(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	baseline := newTestBaseline()
+	if err := SignBaseline(baseline, key); err != nil {
+		t.Fatal(err)
+	}
+
+	// The signature survives the YAML round trip of the document.
+	path := filepath.Join(t.TempDir(), "baseline.yaml")
+	data, err := EncodeBaseline(baseline)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadBaseline(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyBaseline(read, key); err != nil {
+		t.Errorf("expected the signature to match, got %v", err)
+	}
+
+	if err := VerifyBaseline(read, []byte("fedcba9876543210fedcba9876543210")); err == nil {
+		t.Error("expected the signature with another key not to match")
+	}
+
+	read.Nodes["node-1"].Sysctls["vm.dirty_ratio"] = "20"
+	if err := VerifyBaseline(read, key); err == nil {
+		t.Error("expected the signature of the modified baseline not to match")
+	}
+
+	if err := VerifyBaseline(newTestBaseline(), key); err == nil {
+		t.Error("expected the unsigned baseline to fail")
+	}
+}
+
+func TestReadKeyFile(t *testing.T) {
+	directory := t.TempDir()
+	valid := filepath.Join(directory, "valid.key")
+	if err := os.WriteFile(valid, []byte("0123456789abcdef0123456789abcdef\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	short := filepath.Join(directory, "short.key")
+	if err := os.WriteFile(short, []byte("secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := ReadKeyFile(valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(key) != "0123456789abcdef0123456789abcdef" {
+		t.Errorf("expected the key without the trailing newline, got %q", key)
+	}
+
+	if _, err := ReadKeyFile(short); err == nil {
+		t.Error("expected the short key to fail")
+	}
+	if _, err := ReadKeyFile(filepath.Join(directory, "missing.key")); err == nil {
+		t.Error("expected the missing key to fail")
+	}
+}
+
+func TestCompareBaseline(t *testing.T) {
+	baseline := newTestBaseline()
+	baseline.Nodes["node-2"] = &types.NodePrerequisites{OperatingSystem: "sles"}
+
+	actual := newTestBaseline().Nodes
+	actual["node-1"].Packages["open-iscsi"] = "not installed"
+	actual["node-1"].Services["multipathd.service"] = "active (enabled)"
+	actual["node-1"].Modules["nvme_tcp"] = "loaded"
+	actual["node-1"].MultipathConfig = "9c1e7a0f3b52d8e4"
+	actual["node-3"] = &types.NodePrerequisites{OperatingSystem: "ubuntu", Errors: []string{"Failed to read sysctl fs.aio-max-nr"}}
+
+	collection := CompareBaseline(baseline, actual)
+	expected := &types.BaselineDriftCollection{
+		CreatedAt: baseline.CreatedAt,
+		Nodes: map[string]*types.NodeBaselineDrift{
+			"node-1": {
+				Drifts: []types.BaselineDrift{
+					{Category: types.BaselineDriftCategoryPackage, Name: "open-iscsi", Expected: "installed", Actual: "not installed"},
+					{Category: types.BaselineDriftCategoryModule, Name: "nvme_tcp", Actual: "loaded"},
+					{Category: types.BaselineDriftCategoryService, Name: "multipathd.service", Expected: "inactive (disabled)", Actual: "active (enabled)"},
+					{Category: types.BaselineDriftCategoryMultipathConfig, Expected: "absent", Actual: "9c1e7a0f3b52d8e4"},
+				},
+			},
+			"node-2": {
+				Drifts: []types.BaselineDrift{{Category: types.BaselineDriftCategoryNode, Expected: "present"}},
+			},
+			"node-3": {
+				Drifts: []types.BaselineDrift{{Category: types.BaselineDriftCategoryNode, Actual: "present"}},
+				Errors: []string{"Failed to read sysctl fs.aio-max-nr"},
+			},
+		},
+	}
+	if !reflect.DeepEqual(collection, expected) {
+		t.Errorf("expected %+v, got %+v", expected, collection)
+	}
+	if !HasDrift(collection) {
+		t.Error("expected drift")
+	}
+
+	collection = CompareBaseline(baseline, newTestBaseline().Nodes)
+	if drift := collection.Nodes["node-1"]; len(drift.Drifts) != 0 {
+		t.Errorf("expected no drift of node-1, got %+v", drift.Drifts)
+	}
+}
@@ -0,0 +1,274 @@
+package benchmark
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/utils/ptr"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeclient "k8s.io/client-go/kubernetes"
+
+	commonkube "github.com/longhorn/go-common-libs/kubernetes"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// diskBenchmarkFileSizeMiB is the size of the fio file on the data path, small enough to fit on
+// any candidate disk but larger than the cache of most disk controllers.
+const diskBenchmarkFileSizeMiB = 1024
+
+// DiskBenchmark provide functions for benchmarking the latency of the data path on each node.
+type DiskBenchmark struct {
+	DiskBenchmarkCmdOptions
+
+	kubeClient *kubeclient.Clientset
+
+	namespace string
+	appName   string // App name of the DaemonSet.
+}
+
+// DiskBenchmarkCmdOptions holds the options for the command.
+type DiskBenchmarkCmdOptions struct {
+	types.GlobalCmdOptions
+
+	DataPath        string        // Data path on the nodes where fio runs.
+	Runtime         time.Duration // Duration of the fio run.
+	FioImage        string        // Image containing fio.
+	MaxReadLatency  time.Duration // Read latency above which a node fails.
+	MaxWriteLatency time.Duration // Write latency above which a node fails.
+}
+
+// Validate validates the command options.
+func (remote *DiskBenchmark) Validate() error {
+	if !filepath.IsAbs(remote.DataPath) {
+		return errors.Errorf("data path (--%s) %q must be an absolute path", consts.CmdOptDataPath, remote.DataPath)
+	}
+
+	if remote.Runtime < time.Second {
+		return errors.Errorf("runtime (--%s) must be at least 1s", consts.CmdOptRuntime)
+	}
+
+	if remote.MaxReadLatency <= 0 || remote.MaxWriteLatency <= 0 {
+		return errors.Errorf("latency thresholds (--%s, --%s) must be positive", consts.CmdOptMaxReadLatency, consts.CmdOptMaxWriteLatency)
+	}
+
+	return nil
+}
+
+// Init initializes the DiskBenchmark.
+func (remote *DiskBenchmark) Init() error {
+	kubeClient, err := kubeutils.NewKubeClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.kubeClient = kubeClient
+
+	remote.namespace = remote.Namespace
+	if remote.namespace == "" {
+		remote.namespace = consts.LonghornNamespace
+	}
+	remote.appName = consts.AppNameDiskBenchmark
+
+	return nil
+}
+
+// Run creates the DaemonSet running fio on the data path of each node, waits for it to complete,
+// and compares the latency of each node with the thresholds.
+func (remote *DiskBenchmark) Run() (*types.DiskBenchmarkReport, error) {
+	if _, err := kubeutils.CreateNamespace(remote.kubeClient, remote.namespace); err != nil {
+		return nil, err
+	}
+
+	nodeSelector, err := kubeutils.ParseNodeSelector(remote.NodeSelector)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %q argument", consts.CmdOptNodeSelector)
+	}
+	newDaemonSet, err := remote.newDaemonSet(nodeSelector)
+	if err != nil {
+		return nil, err
+	}
+	if err := kubeutils.SetPodOptions(&newDaemonSet.Spec.Template.Spec, &remote.GlobalCmdOptions); err != nil {
+		return nil, err
+	}
+
+	kubeutils.SetRunMetadata(remote.kubeClient, newDaemonSet)
+	kubeutils.LogManifest(newDaemonSet)
+	daemonSet, err := kubeutils.CreateDaemonSet(remote.kubeClient, newDaemonSet, remote.MinNodes)
+	if err != nil {
+		return nil, err
+	}
+
+	err = kubeutils.MonitorDaemonSetContainer(remote.kubeClient, daemonSet, consts.ContainerNameInit, kubeutils.WaitForDaemonSetContainersExit, ptr.To(consts.ContainerConditionMaxTolerationMedium))
+	if err != nil {
+		return nil, err
+	}
+
+	err = kubeutils.MonitorDaemonSetContainer(remote.kubeClient, daemonSet, consts.ContainerNameOutput, kubeutils.WaitForDaemonSetContainersExit, ptr.To(consts.ContainerConditionMaxTolerationShort))
+	if err != nil {
+		return nil, err
+	}
+
+	podCollections, err := kubeutils.GetDaemonSetPodCollections(remote.kubeClient, daemonSet, consts.ContainerNameOutput, false, false, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &types.DiskBenchmarkReport{
+		DataPath:            remote.DataPath,
+		MaxReadLatencyUsec:  float64(remote.MaxReadLatency.Microseconds()),
+		MaxWriteLatencyUsec: float64(remote.MaxWriteLatency.Microseconds()),
+		Passed:              true,
+	}
+	for _, collection := range podCollections.Pods {
+		node := types.DiskBenchmarkNode{Node: collection.Node}
+
+		result, err := ParseFioOutput(collection.Log)
+		if err != nil {
+			node.Status = types.VerifyStepStatusFail
+			node.Message = err.Error()
+		} else {
+			node.Result = result
+			node.Status, node.Message = evaluateDiskLatency(result, report.MaxReadLatencyUsec, report.MaxWriteLatencyUsec)
+		}
+
+		if node.Status != types.VerifyStepStatusPass {
+			report.Passed = false
+		}
+		report.Nodes = append(report.Nodes, node)
+	}
+	sort.Slice(report.Nodes, func(i, j int) bool {
+		return report.Nodes[i].Node < report.Nodes[j].Node
+	})
+
+	return report, nil
+}
+
+// Cleanup deletes the DaemonSet created for the benchmark.
+func (remote *DiskBenchmark) Cleanup() error {
+	return commonkube.DeleteDaemonSet(remote.kubeClient, remote.namespace, remote.appName)
+}
+
+// evaluateDiskLatency compares the mean latencies of the fio result with the thresholds in microseconds.
+func evaluateDiskLatency(result *types.FioResult, maxReadLatencyUsec, maxWriteLatencyUsec float64) (types.VerifyStepStatus, string) {
+	var exceeded []string
+	if result.ReadLatencyUsec > maxReadLatencyUsec {
+		exceeded = append(exceeded, fmt.Sprintf("read latency %.0fus exceeds %.0fus", result.ReadLatencyUsec, maxReadLatencyUsec))
+	}
+	if result.WriteLatencyUsec > maxWriteLatencyUsec {
+		exceeded = append(exceeded, fmt.Sprintf("write latency %.0fus exceeds %.0fus", result.WriteLatencyUsec, maxWriteLatencyUsec))
+	}
+
+	if len(exceeded) > 0 {
+		return types.VerifyStepStatusFail, strings.Join(exceeded, "; ")
+	}
+	return types.VerifyStepStatusPass, ""
+}
+
+// newDaemonSet prepares a DaemonSet running the latency profile of fio in a directory of the data path.
+// The fio file is removed afterwards, and the output is printed by the output container.
+func (remote *DiskBenchmark) newDaemonSet(nodeSelector map[string]string) (*appsv1.DaemonSet, error) {
+	fileName := filepath.Join(consts.VolumeMountBenchmarkDirectory, fioFileName)
+	outputFilePath := filepath.Join(consts.VolumeMountSharedDirectory, consts.FileNameOutputJSON)
+
+	args, err := getFioArgs(consts.BenchmarkProfileLatency, fileName, diskBenchmarkFileSizeMiB, int(remote.Runtime.Seconds()))
+	if err != nil {
+		return nil, err
+	}
+	args = append(args, "--output="+outputFilePath)
+	script := fmt.Sprintf("%s; rc=$?; rm -f %s; exit $rc", strings.Join(args, " "), fileName)
+
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      remote.appName,
+			Namespace: remote.namespace,
+			Labels: map[string]string{
+				"app":                 remote.appName,
+				consts.LabelManagedBy: consts.LabelValueManagedBy,
+			},
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app": remote.appName,
+				},
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						"app":                 remote.appName,
+						consts.LabelManagedBy: consts.LabelValueManagedBy,
+					},
+				},
+				Spec: corev1.PodSpec{
+					InitContainers: []corev1.Container{
+						{
+							Name:            consts.ContainerNameInit,
+							Image:           remote.FioImage,
+							Command:         []string{"sh", "-c", script},
+							SecurityContext: kubeutils.NewSecurityContext(remote.Privileged),
+							VolumeMounts: []corev1.VolumeMount{
+								{
+									Name:      consts.VolumeMountBenchmarkName,
+									MountPath: consts.VolumeMountBenchmarkDirectory,
+								},
+								{
+									Name:      consts.VolumeMountSharedName,
+									MountPath: consts.VolumeMountSharedDirectory,
+								},
+							},
+						},
+						{
+							Name:    consts.ContainerNameOutput,
+							Image:   remote.FioImage,
+							Command: []string{"cat", outputFilePath},
+							VolumeMounts: []corev1.VolumeMount{
+								{
+									Name:      consts.VolumeMountSharedName,
+									MountPath: consts.VolumeMountSharedDirectory,
+								},
+							},
+						},
+					},
+					Containers: []corev1.Container{
+						{
+							Name:  consts.ContainerNamePause,
+							Image: consts.ImagePause,
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: consts.VolumeMountBenchmarkName,
+							VolumeSource: corev1.VolumeSource{
+								HostPath: &corev1.HostPathVolumeSource{
+									Path: filepath.Join(remote.DataPath, remote.appName),
+									Type: ptr.To(corev1.HostPathDirectoryOrCreate),
+								},
+							},
+						},
+						{
+							Name: consts.VolumeMountSharedName,
+							VolumeSource: corev1.VolumeSource{
+								EmptyDir: &corev1.EmptyDirVolumeSource{},
+							},
+						},
+					},
+					NodeSelector: nodeSelector,
+				},
+			},
+			UpdateStrategy: appsv1.DaemonSetUpdateStrategy{
+				Type: appsv1.RollingUpdateDaemonSetStrategyType,
+			},
+		},
+	}, nil
+}
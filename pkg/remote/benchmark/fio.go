@@ -0,0 +1,104 @@
+package benchmark
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+)
+
+// fioProfileArgs are the fio arguments of each benchmark profile. The random profiles use 4KiB
+// blocks with a deep queue to measure IOPS, the sequential ones 1MiB blocks to measure bandwidth,
+// and the latency one 4KiB blocks one at a time to measure the latency of a single I/O.
+var fioProfileArgs = map[string][]string{
+	consts.BenchmarkProfileLatency:   {"--rw=randrw", "--rwmixread=50", "--bs=4k", "--iodepth=1"},
+	consts.BenchmarkProfileRandRW:    {"--rw=randrw", "--rwmixread=70", "--bs=4k", "--iodepth=64"},
+	consts.BenchmarkProfileRandRead:  {"--rw=randread", "--bs=4k", "--iodepth=64"},
+	consts.BenchmarkProfileRandWrite: {"--rw=randwrite", "--bs=4k", "--iodepth=64"},
+	consts.BenchmarkProfileSeqRead:   {"--rw=read", "--bs=1M", "--iodepth=16"},
+	consts.BenchmarkProfileSeqWrite:  {"--rw=write", "--bs=1M", "--iodepth=16"},
+}
+
+// fioOutput is the part of the fio JSON output used for the result.
+type fioOutput struct {
+	Jobs []struct {
+		Read  fioJobStats `json:"read"`
+		Write fioJobStats `json:"write"`
+	} `json:"jobs"`
+}
+
+type fioJobStats struct {
+	IOPS float64 `json:"iops"`
+	BW   float64 `json:"bw"` // KiB/s
+	Clat struct {
+		Mean float64 `json:"mean"` // Nanoseconds
+	} `json:"clat_ns"`
+}
+
+// getFioArgs returns the fio command line running the profile on a file of the size in MiB.
+func getFioArgs(profile, fileName string, sizeMiB int64, runtimeSeconds int) ([]string, error) {
+	profileArgs, ok := fioProfileArgs[profile]
+	if !ok {
+		return nil, errors.Errorf("unsupported profile %q (--%s), supported profiles: %s", profile, consts.CmdOptProfile, strings.Join(getProfiles(), ", "))
+	}
+
+	args := []string{
+		"fio",
+		"--name=" + consts.AppNameVolumeBenchmark,
+		"--filename=" + fileName,
+		fmt.Sprintf("--size=%dM", sizeMiB),
+		fmt.Sprintf("--runtime=%d", runtimeSeconds),
+		"--time_based",
+		"--ramp_time=5",
+		"--ioengine=libaio",
+		"--direct=1",
+		"--numjobs=1",
+		"--group_reporting",
+		"--output-format=json",
+	}
+	return append(args, profileArgs...), nil
+}
+
+// getProfiles returns the supported profiles in a stable order.
+func getProfiles() []string {
+	return []string{
+		consts.BenchmarkProfileLatency,
+		consts.BenchmarkProfileRandRW,
+		consts.BenchmarkProfileRandRead,
+		consts.BenchmarkProfileRandWrite,
+		consts.BenchmarkProfileSeqRead,
+		consts.BenchmarkProfileSeqWrite,
+	}
+}
+
+// ParseFioOutput parses the result of a fio run from its JSON output. Anything printed
+// before the JSON object, such as warnings of fio, is ignored.
+func ParseFioOutput(output string) (*types.FioResult, error) {
+	start := strings.Index(output, "{")
+	if start < 0 {
+		return nil, errors.Errorf("failed to find the fio JSON output in %q", output)
+	}
+
+	var parsed fioOutput
+	if err := json.Unmarshal([]byte(output[start:]), &parsed); err != nil {
+		return nil, errors.Wrap(err, "failed to parse the fio JSON output")
+	}
+
+	if len(parsed.Jobs) == 0 {
+		return nil, errors.New("fio JSON output has no job")
+	}
+
+	job := parsed.Jobs[0]
+	return &types.FioResult{
+		ReadIOPS:         job.Read.IOPS,
+		WriteIOPS:        job.Write.IOPS,
+		ReadBandwidth:    job.Read.BW,
+		WriteBandwidth:   job.Write.BW,
+		ReadLatencyUsec:  job.Read.Clat.Mean / 1000,
+		WriteLatencyUsec: job.Write.Clat.Mean / 1000,
+	}, nil
+}
@@ -0,0 +1,108 @@
+package benchmark
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func TestParseFioOutput(t *testing.T) {
+	output := `fio: some warning
+{
+  "fio version": "fio-3.36",
+  "jobs": [
+    {
+      "jobname": "longhorn-volume-benchmark",
+      "read": {"iops": 1000.5, "bw": 4002, "clat_ns": {"mean": 250000.0}},
+      "write": {"iops": 500.25, "bw": 2001, "clat_ns": {"mean": 500000.0}}
+    }
+  ]
+}`
+
+	result, err := ParseFioOutput(output)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := &types.FioResult{
+		ReadIOPS:         1000.5,
+		WriteIOPS:        500.25,
+		ReadBandwidth:    4002,
+		WriteBandwidth:   2001,
+		ReadLatencyUsec:  250,
+		WriteLatencyUsec: 500,
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected %+v, got %+v", expected, result)
+	}
+
+	for _, output := range []string{"", "fio: failed", `{"jobs": []}`} {
+		if _, err := ParseFioOutput(output); err == nil {
+			t.Errorf("expected an error for output %q", output)
+		}
+	}
+}
+
+func TestGetFioArgs(t *testing.T) {
+	args, err := getFioArgs("seqwrite", "/benchmark/fio.bin", 1024, 60)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, expected := range []string{"--filename=/benchmark/fio.bin", "--size=1024M", "--runtime=60", "--rw=write", "--bs=1M"} {
+		found := false
+		for _, arg := range args {
+			if arg == expected {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected %v in %v", expected, args)
+		}
+	}
+
+	if _, err := getFioArgs("unknown", "", 0, 0); err == nil {
+		t.Error("expected an error for an unknown profile")
+	}
+}
+
+func TestEvaluateDiskLatency(t *testing.T) {
+	tests := []struct {
+		name             string
+		result           *types.FioResult
+		expectedStatus   types.VerifyStepStatus
+		expectedMessages []string
+	}{
+		{
+			name:           "within thresholds",
+			result:         &types.FioResult{ReadLatencyUsec: 120, WriteLatencyUsec: 80},
+			expectedStatus: types.VerifyStepStatusPass,
+		},
+		{
+			name:             "slow reads",
+			result:           &types.FioResult{ReadLatencyUsec: 8000, WriteLatencyUsec: 80},
+			expectedStatus:   types.VerifyStepStatusFail,
+			expectedMessages: []string{"read latency 8000us exceeds 1000us"},
+		},
+		{
+			name:             "slow reads and writes",
+			result:           &types.FioResult{ReadLatencyUsec: 8000, WriteLatencyUsec: 12000},
+			expectedStatus:   types.VerifyStepStatusFail,
+			expectedMessages: []string{"read latency 8000us", "write latency 12000us exceeds 2000us"},
+		},
+	}
+
+	for _, test := range tests {
+		status, message := evaluateDiskLatency(test.result, 1000, 2000)
+		if status != test.expectedStatus {
+			t.Errorf("%s: expected status %v, got %v", test.name, test.expectedStatus, status)
+		}
+		for _, expected := range test.expectedMessages {
+			if !strings.Contains(message, expected) {
+				t.Errorf("%s: expected message %q to contain %q", test.name, message, expected)
+			}
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package benchmark
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+// iperfOutput is the part of the iperf3 JSON output used for the result.
+type iperfOutput struct {
+	End struct {
+		Streams []struct {
+			Sender struct {
+				MeanRtt float64 `json:"mean_rtt"` // Microseconds
+			} `json:"sender"`
+		} `json:"streams"`
+		SumSent struct {
+			Retransmits int `json:"retransmits"`
+		} `json:"sum_sent"`
+		SumReceived struct {
+			BitsPerSecond float64 `json:"bits_per_second"`
+		} `json:"sum_received"`
+	} `json:"end"`
+	Error string `json:"error"`
+}
+
+// getIperfServerArgs returns the iperf3 command line of the server listening on the port.
+func getIperfServerArgs(port int) []string {
+	return []string{"iperf3", "--server", fmt.Sprintf("--port=%d", port)}
+}
+
+// getIperfClientArgs returns the iperf3 command line sending TCP traffic to the server for the runtime.
+func getIperfClientArgs(serverIP string, port int, runtimeSeconds int) []string {
+	return []string{
+		"iperf3",
+		"--client=" + serverIP,
+		fmt.Sprintf("--port=%d", port),
+		fmt.Sprintf("--time=%d", runtimeSeconds),
+		"--omit=2",
+		"--json",
+	}
+}
+
+// ParseIperfOutput parses the throughput, the mean round-trip time and the retransmits of an
+// iperf3 client run from its JSON output. Anything printed before the JSON object is ignored.
+func ParseIperfOutput(output string) (*types.NetworkBenchmarkResult, error) {
+	start := strings.Index(output, "{")
+	if start < 0 {
+		return nil, errors.Errorf("failed to find the iperf3 JSON output in %q", output)
+	}
+
+	var parsed iperfOutput
+	if err := json.Unmarshal([]byte(output[start:]), &parsed); err != nil {
+		return nil, errors.Wrap(err, "failed to parse the iperf3 JSON output")
+	}
+
+	if parsed.Error != "" {
+		return nil, errors.Errorf("iperf3 failed: %v", parsed.Error)
+	}
+
+	if len(parsed.End.Streams) == 0 {
+		return nil, errors.New("iperf3 JSON output has no stream")
+	}
+
+	var rtt float64
+	for _, stream := range parsed.End.Streams {
+		rtt += stream.Sender.MeanRtt
+	}
+
+	return &types.NetworkBenchmarkResult{
+		Throughput:  parsed.End.SumReceived.BitsPerSecond,
+		LatencyUsec: rtt / float64(len(parsed.End.Streams)),
+		Retransmits: parsed.End.SumSent.Retransmits,
+	}, nil
+}
@@ -0,0 +1,76 @@
+package benchmark
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func TestParseIperfOutput(t *testing.T) {
+	output := `{
+  "start": {"version": "iperf 3.16"},
+  "end": {
+    "streams": [
+      {"sender": {"bits_per_second": 9.5e9, "retransmits": 12, "mean_rtt": 240}}
+    ],
+    "sum_sent": {"bits_per_second": 9.5e9, "retransmits": 12},
+    "sum_received": {"bits_per_second": 9.4e9}
+  }
+}`
+
+	result, err := ParseIperfOutput(output)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := &types.NetworkBenchmarkResult{
+		Throughput:  9.4e9,
+		LatencyUsec: 240,
+		Retransmits: 12,
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected %+v, got %+v", expected, result)
+	}
+
+	for _, output := range []string{
+		"",
+		"iperf3: error",
+		`{"end": {"streams": []}}`,
+		`{"start": {}, "end": {}, "error": "unable to connect to server: Connection refused"}`,
+	} {
+		if _, err := ParseIperfOutput(output); err == nil {
+			t.Errorf("expected an error for output %q", output)
+		}
+	}
+}
+
+func TestParseNetworks(t *testing.T) {
+	tests := []struct {
+		value     string
+		expected  []string
+		expectErr bool
+	}{
+		{"host,pod", []string{"host", "pod"}, false},
+		{" pod ", []string{"pod"}, false},
+		{"", nil, true},
+		{"host,overlay", nil, true},
+	}
+
+	for _, test := range tests {
+		networks, err := parseNetworks(test.value)
+		if test.expectErr {
+			if err == nil {
+				t.Errorf("expected an error for %q", test.value)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error for %q: %v", test.value, err)
+			continue
+		}
+		if !reflect.DeepEqual(networks, test.expected) {
+			t.Errorf("expected %v for %q, got %v", test.expected, test.value, networks)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package benchmark
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeclient "k8s.io/client-go/kubernetes"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// NetworkBenchmark provide functions for benchmarking the network between each pair of nodes.
+type NetworkBenchmark struct {
+	NetworkBenchmarkCmdOptions
+
+	kubeClient *kubeclient.Clientset
+
+	namespace string
+	appName   string   // Prefix of the pod names.
+	nodeNames []string // Nodes to benchmark, in a stable order.
+	networks  []string // Networks to benchmark, host and/or pod.
+}
+
+// NetworkBenchmarkCmdOptions holds the options for the command.
+type NetworkBenchmarkCmdOptions struct {
+	types.GlobalCmdOptions
+
+	Nodes      string        // Comma-separated nodes to benchmark. Defaults to the nodes matching the node selector.
+	Networks   string        // Comma-separated networks to benchmark.
+	Port       int           // Port of the iperf3 servers.
+	Runtime    time.Duration // Duration of each iperf3 run.
+	IperfImage string        // Image containing iperf3.
+	Timeout    time.Duration // Maximum time to wait for each iperf3 run.
+}
+
+// Validate validates the command options.
+func (remote *NetworkBenchmark) Validate() error {
+	if _, err := parseNetworks(remote.Networks); err != nil {
+		return err
+	}
+
+	if remote.Port < 1 || remote.Port > 65535 {
+		return errors.Errorf("invalid port (--%s) %v", consts.CmdOptPort, remote.Port)
+	}
+
+	if remote.Runtime < 3*time.Second {
+		return errors.Errorf("runtime (--%s) must be at least 3s", consts.CmdOptRuntime)
+	}
+
+	if remote.Timeout <= remote.Runtime {
+		return errors.Errorf("timeout (--%s) must be longer than the runtime (--%s)", consts.CmdOptTimeout, consts.CmdOptRuntime)
+	}
+
+	return nil
+}
+
+// Init initializes the NetworkBenchmark.
+func (remote *NetworkBenchmark) Init() error {
+	kubeClient, err := kubeutils.NewKubeClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.kubeClient = kubeClient
+
+	remote.namespace = remote.Namespace
+	if remote.namespace == "" {
+		remote.namespace = consts.LonghornNamespace
+	}
+	remote.appName = consts.AppNameNetworkBenchmark
+
+	remote.networks, err = parseNetworks(remote.Networks)
+	if err != nil {
+		return err
+	}
+
+	if remote.Nodes != "" {
+		remote.nodeNames = splitList(remote.Nodes)
+	} else {
+		nodeSelector, err := kubeutils.ParseNodeSelector(remote.NodeSelector)
+		if err != nil {
+			return errors.Wrapf(err, "failed to parse %q argument", consts.CmdOptNodeSelector)
+		}
+
+		remote.nodeNames, err = kubeutils.ListNodeNames(remote.kubeClient, nodeSelector)
+		if err != nil {
+			return err
+		}
+	}
+	if len(remote.nodeNames) < 2 {
+		return errors.Errorf("at least 2 nodes are required, found %v", remote.nodeNames)
+	}
+
+	return nil
+}
+
+// Run starts an iperf3 server on each node, then runs an iperf3 client from each node to
+// each other node, one pair at a time so the runs do not compete for the bandwidth.
+func (remote *NetworkBenchmark) Run() (*types.NetworkBenchmarkReport, error) {
+	if _, err := kubeutils.CreateNamespace(remote.kubeClient, remote.namespace); err != nil {
+		return nil, err
+	}
+
+	report := &types.NetworkBenchmarkReport{
+		Nodes:    remote.nodeNames,
+		Networks: remote.networks,
+	}
+
+	for _, network := range remote.networks {
+		serverIPs, err := remote.startServers(network)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, source := range remote.nodeNames {
+			for j, destination := range remote.nodeNames {
+				if i == j {
+					continue
+				}
+
+				logrus.Infof("Running iperf3 on the %v network from node %v to node %v", network, source, destination)
+				result, err := remote.runClient(network, i, j, serverIPs[j])
+				if err != nil {
+					logrus.WithError(err).Warnf("Failed to run iperf3 on the %v network from node %v to node %v", network, source, destination)
+					result = &types.NetworkBenchmarkResult{Error: err.Error()}
+				}
+				result.Network = network
+				result.Source = source
+				result.Destination = destination
+				report.Results = append(report.Results, *result)
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// startServers creates an iperf3 server pod on each node, and returns their IPs in the order of the nodes.
+func (remote *NetworkBenchmark) startServers(network string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), remote.Timeout)
+	defer cancel()
+
+	for i, nodeName := range remote.nodeNames {
+		pod, err := remote.newPod(remote.serverPodName(network, i), network, nodeName, getIperfServerArgs(remote.Port))
+		if err != nil {
+			return nil, err
+		}
+
+		kubeutils.SetRunMetadata(remote.kubeClient, pod)
+		if _, err := remote.kubeClient.CoreV1().Pods(remote.namespace).Create(ctx, pod, metav1.CreateOptions{}); err != nil {
+			return nil, errors.Wrapf(err, "failed to create pod %v", pod.Name)
+		}
+	}
+
+	serverIPs := make([]string, len(remote.nodeNames))
+	for i := range remote.nodeNames {
+		pod, err := kubeutils.WaitForPodRunning(ctx, remote.kubeClient, remote.namespace, remote.serverPodName(network, i))
+		if err != nil {
+			return nil, err
+		}
+		serverIPs[i] = pod.Status.PodIP
+	}
+	return serverIPs, nil
+}
+
+// runClient runs an iperf3 client on the source node against the server on the destination node,
+// and parses the result from the pod log. The client pod is deleted afterwards.
+func (remote *NetworkBenchmark) runClient(network string, source, destination int, serverIP string) (*types.NetworkBenchmarkResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), remote.Timeout)
+	defer cancel()
+
+	podName := remote.clientPodName(network, source, destination)
+	pod, err := remote.newPod(podName, network, remote.nodeNames[source], getIperfClientArgs(serverIP, remote.Port, int(remote.Runtime.Seconds())))
+	if err != nil {
+		return nil, err
+	}
+
+	kubeutils.SetRunMetadata(remote.kubeClient, pod)
+	if _, err := remote.kubeClient.CoreV1().Pods(remote.namespace).Create(ctx, pod, metav1.CreateOptions{}); err != nil {
+		return nil, errors.Wrapf(err, "failed to create pod %v", podName)
+	}
+	defer func() {
+		deleteCtx, deleteCancel := context.WithTimeout(context.Background(), remote.Timeout)
+		defer deleteCancel()
+
+		if err := kubeutils.DeletePod(deleteCtx, remote.kubeClient, remote.namespace, podName); err != nil {
+			logrus.WithError(err).Warnf("Failed to delete pod %v", podName)
+		}
+	}()
+
+	waitErr := kubeutils.WaitForPodCompleted(ctx, remote.kubeClient, remote.namespace, podName)
+
+	output, err := kubeutils.GetPodContainerLog(ctx, remote.kubeClient, remote.namespace, podName, consts.ContainerNameIperf)
+	if err != nil {
+		if waitErr != nil {
+			return nil, errors.Wrapf(waitErr, "failed waiting for iperf3 in pod %v", podName)
+		}
+		return nil, err
+	}
+
+	// iperf3 reports its errors in the JSON output, which is more useful than the pod failure.
+	result, err := ParseIperfOutput(output)
+	if err != nil {
+		return nil, err
+	}
+	if waitErr != nil {
+		return nil, errors.Wrapf(waitErr, "failed waiting for iperf3 in pod %v", podName)
+	}
+	return result, nil
+}
+
+// Cleanup deletes the pods created for the benchmark.
+func (remote *NetworkBenchmark) Cleanup() error {
+	ctx, cancel := context.WithTimeout(context.Background(), remote.Timeout)
+	defer cancel()
+
+	pods, err := remote.kubeClient.CoreV1().Pods(remote.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: "app=" + remote.appName,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to list pods of %v", remote.appName)
+	}
+
+	for _, pod := range pods.Items {
+		if err := kubeutils.DeletePod(ctx, remote.kubeClient, remote.namespace, pod.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseNetworks parses the comma-separated networks to benchmark.
+func parseNetworks(value string) ([]string, error) {
+	networks := splitList(value)
+	if len(networks) == 0 {
+		return nil, errors.Errorf("no network (--%s) to benchmark", consts.CmdOptNetworks)
+	}
+
+	for _, network := range networks {
+		switch network {
+		case consts.BenchmarkNetworkHost, consts.BenchmarkNetworkPod:
+		default:
+			return nil, errors.Errorf("unsupported network %q (--%s), supported networks: %s, %s", network, consts.CmdOptNetworks, consts.BenchmarkNetworkHost, consts.BenchmarkNetworkPod)
+		}
+	}
+	return networks, nil
+}
+
+// splitList returns the non-empty entries of the comma-separated list.
+func splitList(value string) []string {
+	entries := []string{}
+	for _, entry := range strings.Split(value, consts.CmdOptSeperator) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// The pods are named after the index of the nodes, since node names may be too long for pod names.
+func (remote *NetworkBenchmark) serverPodName(network string, node int) string {
+	return fmt.Sprintf("%s-%s-server-%d", remote.appName, network, node)
+}
+
+func (remote *NetworkBenchmark) clientPodName(network string, source, destination int) string {
+	return fmt.Sprintf("%s-%s-client-%d-%d", remote.appName, network, source, destination)
+}
+
+// newPod prepares the pod running iperf3 on the node, in the host network or the pod network.
+func (remote *NetworkBenchmark) newPod(name, network, nodeName string, args []string) (*corev1.Pod, error) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: remote.namespace,
+			Labels: map[string]string{
+				"app":                 remote.appName,
+				consts.LabelManagedBy: consts.LabelValueManagedBy,
+			},
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			HostNetwork:   network == consts.BenchmarkNetworkHost,
+			Containers: []corev1.Container{
+				{
+					Name:    consts.ContainerNameIperf,
+					Image:   remote.IperfImage,
+					Command: []string{args[0]},
+					Args:    args[1:],
+				},
+			},
+		},
+	}
+	kubeutils.SetNodeNameAffinity(&pod.Spec, []string{nodeName})
+
+	if err := kubeutils.SetPodOptions(&pod.Spec, &remote.GlobalCmdOptions); err != nil {
+		return nil, err
+	}
+	return pod, nil
+}
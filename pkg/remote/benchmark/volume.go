@@ -0,0 +1,293 @@
+package benchmark
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeclient "k8s.io/client-go/kubernetes"
+	"k8s.io/utils/ptr"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+const fioFileName = "fio.bin"
+
+// VolumeBenchmark provide functions for benchmarking a Longhorn volume against the local disk of a node.
+type VolumeBenchmark struct {
+	VolumeBenchmarkCmdOptions
+
+	kubeClient *kubeclient.Clientset
+
+	namespace string
+	appName   string // Name of the PVC, and prefix of the pod names.
+	nodeName  string // Node the benchmark pods are pinned to.
+	sizeMiB   int64  // Size of the fio file, leaving room for the filesystem of the volume.
+}
+
+// VolumeBenchmarkCmdOptions holds the options for the command.
+type VolumeBenchmarkCmdOptions struct {
+	types.GlobalCmdOptions
+
+	NodeName              string        // Node to run the benchmark on. Defaults to the first node matching the node selector.
+	StorageClass          string        // StorageClass of the benchmark PVC.
+	Size                  string        // Size of the benchmark PVC.
+	Profile               string        // fio profile of the benchmark.
+	Runtime               time.Duration // Duration of each fio run.
+	FioImage              string        // Image containing fio.
+	LonghornDataDirectory string        // Directory on the node where the baseline runs.
+	Timeout               time.Duration // Maximum time to wait for each fio run.
+}
+
+// Validate validates the command options.
+func (remote *VolumeBenchmark) Validate() error {
+	if _, err := getFioArgs(remote.Profile, "", 0, 0); err != nil {
+		return err
+	}
+
+	size, err := resource.ParseQuantity(remote.Size)
+	if err != nil {
+		return errors.Wrapf(err, "invalid --%s %q", consts.CmdOptSize, remote.Size)
+	}
+
+	if getFioSizeMiB(size) < 1 {
+		return errors.Errorf("size (--%s) %v is too small", consts.CmdOptSize, remote.Size)
+	}
+
+	if remote.Runtime < time.Second {
+		return errors.Errorf("runtime (--%s) must be at least 1s", consts.CmdOptRuntime)
+	}
+
+	if remote.Timeout <= remote.Runtime {
+		return errors.Errorf("timeout (--%s) must be longer than the runtime (--%s)", consts.CmdOptTimeout, consts.CmdOptRuntime)
+	}
+
+	return nil
+}
+
+// Init initializes the VolumeBenchmark.
+func (remote *VolumeBenchmark) Init() error {
+	kubeClient, err := kubeutils.NewKubeClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.kubeClient = kubeClient
+
+	remote.namespace = remote.Namespace
+	if remote.namespace == "" {
+		remote.namespace = consts.LonghornNamespace
+	}
+	remote.appName = consts.AppNameVolumeBenchmark
+	remote.sizeMiB = getFioSizeMiB(resource.MustParse(remote.Size))
+
+	remote.nodeName = remote.NodeName
+	if remote.nodeName == "" {
+		nodeSelector, err := kubeutils.ParseNodeSelector(remote.NodeSelector)
+		if err != nil {
+			return errors.Wrapf(err, "failed to parse %q argument", consts.CmdOptNodeSelector)
+		}
+
+		nodeNames, err := kubeutils.ListNodeNames(remote.kubeClient, nodeSelector)
+		if err != nil {
+			return err
+		}
+		if len(nodeNames) == 0 {
+			return errors.New("no node matches the node selector")
+		}
+		remote.nodeName = nodeNames[0]
+	}
+
+	return nil
+}
+
+// Run runs fio on the local disk of the node, then on a Longhorn volume attached to the node,
+// and returns both results.
+func (remote *VolumeBenchmark) Run() (*types.VolumeBenchmarkReport, error) {
+	if _, err := kubeutils.CreateNamespace(remote.kubeClient, remote.namespace); err != nil {
+		return nil, err
+	}
+
+	report := &types.VolumeBenchmarkReport{
+		Node:    remote.nodeName,
+		Profile: remote.Profile,
+		Size:    remote.Size,
+	}
+
+	logrus.Infof("Running fio on the local disk of node %v", remote.nodeName)
+	baseline, err := remote.runFio(remote.baselinePodName(), corev1.VolumeSource{
+		HostPath: &corev1.HostPathVolumeSource{
+			Path: filepath.Join(remote.LonghornDataDirectory, remote.appName),
+			Type: ptr.To(corev1.HostPathDirectoryOrCreate),
+		},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to run fio on the local disk")
+	}
+	report.Baseline = baseline
+
+	pvc := remote.newPersistentVolumeClaim()
+	kubeutils.SetRunMetadata(remote.kubeClient, pvc)
+	if _, err := remote.kubeClient.CoreV1().PersistentVolumeClaims(remote.namespace).Create(context.Background(), pvc, metav1.CreateOptions{}); err != nil {
+		return nil, errors.Wrapf(err, "failed to create PVC %v", remote.appName)
+	}
+
+	logrus.Infof("Running fio on a Longhorn volume on node %v", remote.nodeName)
+	longhorn, err := remote.runFio(remote.volumePodName(), corev1.VolumeSource{
+		PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+			ClaimName: remote.appName,
+		},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to run fio on the Longhorn volume")
+	}
+	report.Longhorn = longhorn
+
+	return report, nil
+}
+
+// runFio runs fio in a pod with the volume mounted, and parses the result from the pod log.
+func (remote *VolumeBenchmark) runFio(podName string, volumeSource corev1.VolumeSource) (*types.FioResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), remote.Timeout)
+	defer cancel()
+
+	pod, err := remote.newPod(podName, volumeSource)
+	if err != nil {
+		return nil, err
+	}
+
+	kubeutils.SetRunMetadata(remote.kubeClient, pod)
+	if _, err := remote.kubeClient.CoreV1().Pods(remote.namespace).Create(ctx, pod, metav1.CreateOptions{}); err != nil {
+		return nil, errors.Wrapf(err, "failed to create pod %v", podName)
+	}
+
+	waitErr := kubeutils.WaitForPodCompleted(ctx, remote.kubeClient, remote.namespace, podName)
+
+	output, err := kubeutils.GetPodContainerLog(ctx, remote.kubeClient, remote.namespace, podName, consts.ContainerNameFio)
+	if waitErr != nil {
+		if err == nil {
+			logrus.Debugf("Log of pod %v: %v", podName, output)
+		}
+		return nil, errors.Wrapf(waitErr, "failed waiting for fio in pod %v", podName)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseFioOutput(output)
+}
+
+// Cleanup deletes the pods and the PVC created for the benchmark.
+func (remote *VolumeBenchmark) Cleanup() error {
+	ctx, cancel := context.WithTimeout(context.Background(), remote.Timeout)
+	defer cancel()
+
+	for _, podName := range []string{remote.baselinePodName(), remote.volumePodName()} {
+		if err := kubeutils.DeletePod(ctx, remote.kubeClient, remote.namespace, podName); err != nil {
+			return err
+		}
+	}
+
+	err := remote.kubeClient.CoreV1().PersistentVolumeClaims(remote.namespace).Delete(ctx, remote.appName, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to delete PVC %v", remote.appName)
+	}
+
+	return nil
+}
+
+// getFioSizeMiB returns the size of the fio file in MiB, keeping 20% of the volume for the filesystem.
+func getFioSizeMiB(size resource.Quantity) int64 {
+	return size.Value() * 8 / 10 >> 20
+}
+
+func (remote *VolumeBenchmark) baselinePodName() string {
+	return remote.appName + "-baseline"
+}
+
+func (remote *VolumeBenchmark) volumePodName() string {
+	return remote.appName + "-longhorn"
+}
+
+func (remote *VolumeBenchmark) newPersistentVolumeClaim() *corev1.PersistentVolumeClaim {
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      remote.appName,
+			Namespace: remote.namespace,
+			Labels: map[string]string{
+				"app":                 remote.appName,
+				consts.LabelManagedBy: consts.LabelValueManagedBy,
+			},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			StorageClassName: ptr.To(remote.StorageClass),
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: resource.MustParse(remote.Size),
+				},
+			},
+		},
+	}
+}
+
+// newPod prepares the pod running fio on the volume, pinned to the benchmark node.
+// The fio file is removed afterwards, so the baseline does not leave data on the node.
+func (remote *VolumeBenchmark) newPod(name string, volumeSource corev1.VolumeSource) (*corev1.Pod, error) {
+	fileName := filepath.Join(consts.VolumeMountBenchmarkDirectory, fioFileName)
+	args, err := getFioArgs(remote.Profile, fileName, remote.sizeMiB, int(remote.Runtime.Seconds()))
+	if err != nil {
+		return nil, err
+	}
+	script := fmt.Sprintf("%s; rc=$?; rm -f %s; exit $rc", strings.Join(args, " "), fileName)
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: remote.namespace,
+			Labels: map[string]string{
+				"app":                 remote.appName,
+				consts.LabelManagedBy: consts.LabelValueManagedBy,
+			},
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			Containers: []corev1.Container{
+				{
+					Name:    consts.ContainerNameFio,
+					Image:   remote.FioImage,
+					Command: []string{"sh", "-c", script},
+					VolumeMounts: []corev1.VolumeMount{
+						{
+							Name:      consts.VolumeMountBenchmarkName,
+							MountPath: consts.VolumeMountBenchmarkDirectory,
+						},
+					},
+				},
+			},
+			Volumes: []corev1.Volume{
+				{
+					Name:         consts.VolumeMountBenchmarkName,
+					VolumeSource: volumeSource,
+				},
+			},
+		},
+	}
+	kubeutils.SetNodeNameAffinity(&pod.Spec, []string{remote.nodeName})
+
+	if err := kubeutils.SetPodOptions(&pod.Spec, &remote.GlobalCmdOptions); err != nil {
+		return nil, err
+	}
+	return pod, nil
+}
@@ -0,0 +1,348 @@
+package capacity
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	longhorn "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+	lhclient "github.com/longhorn/longhorn-manager/k8s/pkg/client/clientset/versioned"
+	lhmgrtypes "github.com/longhorn/longhorn-manager/types"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+const httpTimeout = time.Minute
+
+// diskUsageGrowthQuery is the Prometheus query of the growth of the disk usage reported by the
+// Longhorn managers, in bytes per second over the window.
+const diskUsageGrowthQuery = "deriv(longhorn_disk_usage_bytes[%s])"
+
+// prometheusDurationRegex matches a Prometheus duration with a single unit, such as 30d.
+var prometheusDurationRegex = regexp.MustCompile(`^([0-9]+)(ms|s|m|h|d|w|y)$`)
+
+// Forecaster provide functions for reporting the capacity of the disks, and estimating when their
+// usage reaches the threshold from its historical growth.
+type Forecaster struct {
+	ForecasterCmdOptions
+
+	longhornClient *lhclient.Clientset
+	httpClient     *http.Client
+}
+
+// ForecasterCmdOptions holds the options for the command.
+type ForecasterCmdOptions struct {
+	types.GlobalCmdOptions
+
+	LonghornNamespace string
+	GrowthWindow      string // Window of the historical growth, as a Prometheus duration.
+	PrometheusURL     string // Prometheus scraping the Longhorn metrics. The growth is not estimated without it.
+}
+
+// capacitySettings holds the Longhorn settings the capacity is evaluated against.
+type capacitySettings struct {
+	overProvisioningPercentage int64
+	minimalAvailablePercentage int64
+}
+
+// Validate validates the command options.
+func (remote *Forecaster) Validate() error {
+	if _, err := parsePrometheusDuration(remote.GrowthWindow); err != nil {
+		return errors.Wrapf(err, "invalid --%s", consts.CmdOptGrowthWindow)
+	}
+
+	if remote.PrometheusURL != "" {
+		prometheusURL, err := url.Parse(remote.PrometheusURL)
+		if err != nil || (prometheusURL.Scheme != "http" && prometheusURL.Scheme != "https") || prometheusURL.Host == "" {
+			return errors.Errorf("invalid --%s %q, expected an http or https URL", consts.CmdOptPrometheusURL, remote.PrometheusURL)
+		}
+	}
+
+	return nil
+}
+
+// Init initializes the Forecaster.
+func (remote *Forecaster) Init() error {
+	longhornClient, err := kubeutils.NewLonghornClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.longhornClient = longhornClient
+
+	remote.httpClient = &http.Client{Timeout: httpTimeout}
+
+	return nil
+}
+
+// Run returns the capacity and the forecast of the disks and nodes, sorted by node and disk name.
+func (remote *Forecaster) Run() (*types.CapacityReport, error) {
+	ctx := context.Background()
+	now := time.Now()
+
+	nodeList, err := remote.longhornClient.LonghornV1beta2().Nodes(remote.LonghornNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list nodes")
+	}
+	sort.Slice(nodeList.Items, func(i, j int) bool { return nodeList.Items[i].Name < nodeList.Items[j].Name })
+
+	settings := capacitySettings{}
+	if settings.overProvisioningPercentage, err = remote.getIntSetting(ctx, lhmgrtypes.SettingNameStorageOverProvisioningPercentage, lhmgrtypes.SettingDefinitionStorageOverProvisioningPercentage); err != nil {
+		return nil, err
+	}
+	if settings.minimalAvailablePercentage, err = remote.getIntSetting(ctx, lhmgrtypes.SettingNameStorageMinimalAvailablePercentage, lhmgrtypes.SettingDefinitionStorageMinimalAvailablePercentage); err != nil {
+		return nil, err
+	}
+
+	var growth map[string]float64
+	report := &types.CapacityReport{Disks: []types.CapacityForecast{}, Nodes: []types.CapacityForecast{}}
+	if remote.PrometheusURL != "" {
+		report.GrowthWindow = remote.GrowthWindow
+		if growth, err = remote.queryGrowth(ctx, now); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, node := range nodeList.Items {
+		disks := newDiskForecasts(&node, settings, growth, now)
+		report.Disks = append(report.Disks, disks...)
+		report.Nodes = append(report.Nodes, newNodeForecast(node.Name, disks, settings, growth != nil, now))
+	}
+	return report, nil
+}
+
+// getIntSetting returns the value of the integer setting, or its default when it is not set.
+func (remote *Forecaster) getIntSetting(ctx context.Context, name lhmgrtypes.SettingName, definition lhmgrtypes.SettingDefinition) (int64, error) {
+	value := definition.Default
+
+	setting, err := remote.longhornClient.LonghornV1beta2().Settings(remote.LonghornNamespace).Get(ctx, string(name), metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return 0, errors.Wrapf(err, "failed to get setting %v", name)
+	}
+	if err == nil && setting.Value != "" {
+		value = setting.Value
+	}
+
+	result, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid value %q of setting %v", value, name)
+	}
+	return result, nil
+}
+
+// queryGrowth returns the growth of the usage of each disk in bytes per second, keyed by
+// <node>/<disk>.
+func (remote *Forecaster) queryGrowth(ctx context.Context, now time.Time) (map[string]float64, error) {
+	query := url.Values{}
+	query.Set("query", fmt.Sprintf(diskUsageGrowthQuery, remote.GrowthWindow))
+	query.Set("time", strconv.FormatInt(now.Unix(), 10))
+	queryURL := strings.TrimSuffix(remote.PrometheusURL, "/") + "/api/v1/query?" + query.Encode()
+	logrus.Debugf("Requesting %v", queryURL)
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, queryURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := remote.httpClient.Do(request)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to query Prometheus %v", remote.PrometheusURL)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read the response of Prometheus %v", remote.PrometheusURL)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("failed to query Prometheus %v: %v %v", remote.PrometheusURL, resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	return parseGrowthResponse(body)
+}
+
+// parseGrowthResponse returns the growth of each disk in the vector result of a Prometheus
+// instant query, keyed by <node>/<disk>.
+func parseGrowthResponse(body []byte) (map[string]float64, error) {
+	var response struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+		Data   struct {
+			ResultType string `json:"resultType"`
+			Result     []struct {
+				Metric map[string]string `json:"metric"`
+				Value  []interface{}     `json:"value"`
+			} `json:"result"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, errors.Wrap(err, "failed to parse the response of Prometheus")
+	}
+	if response.Status != "success" {
+		return nil, errors.Errorf("Prometheus query failed: %v", response.Error)
+	}
+	if response.Data.ResultType != "vector" {
+		return nil, errors.Errorf("unexpected result type %q of Prometheus query", response.Data.ResultType)
+	}
+
+	growth := map[string]float64{}
+	for _, sample := range response.Data.Result {
+		if len(sample.Value) != 2 {
+			continue
+		}
+		value, ok := sample.Value[1].(string)
+		if !ok {
+			continue
+		}
+		bytesPerSecond, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(bytesPerSecond) || math.IsInf(bytesPerSecond, 0) {
+			continue
+		}
+		growth[sample.Metric["node"]+"/"+sample.Metric["disk"]] = bytesPerSecond
+	}
+	return growth, nil
+}
+
+// parsePrometheusDuration returns the duration of a Prometheus duration with a single unit.
+func parsePrometheusDuration(value string) (time.Duration, error) {
+	matches := prometheusDurationRegex.FindStringSubmatch(value)
+	if matches == nil {
+		return 0, errors.Errorf("%q is not a duration such as 30d, 2w or 12h", value)
+	}
+
+	count, err := strconv.ParseInt(matches[1], 10, 64)
+	if err != nil || count == 0 {
+		return 0, errors.Errorf("%q is not a positive duration", value)
+	}
+
+	units := map[string]time.Duration{
+		"ms": time.Millisecond,
+		"s":  time.Second,
+		"m":  time.Minute,
+		"h":  time.Hour,
+		"d":  24 * time.Hour,
+		"w":  7 * 24 * time.Hour,
+		"y":  365 * 24 * time.Hour,
+	}
+	return time.Duration(count) * units[matches[2]], nil
+}
+
+// newDiskForecasts returns the forecasts of the disks of the node, sorted by disk name. The growth
+// is keyed by <node>/<disk>, and a nil growth map means no growth is known.
+func newDiskForecasts(node *longhorn.Node, settings capacitySettings, growth map[string]float64, now time.Time) []types.CapacityForecast {
+	diskNames := make([]string, 0, len(node.Status.DiskStatus))
+	for diskName := range node.Status.DiskStatus {
+		diskNames = append(diskNames, diskName)
+	}
+	sort.Strings(diskNames)
+
+	forecasts := []types.CapacityForecast{}
+	for _, diskName := range diskNames {
+		status := node.Status.DiskStatus[diskName]
+		if status == nil {
+			continue
+		}
+
+		forecast := types.CapacityForecast{
+			Node:      node.Name,
+			Disk:      diskName,
+			Path:      node.Spec.Disks[diskName].Path,
+			Maximum:   status.StorageMaximum,
+			Reserved:  node.Spec.Disks[diskName].StorageReserved,
+			Available: status.StorageAvailable,
+			Scheduled: status.StorageScheduled,
+		}
+		forecast.Threshold = forecast.Reserved
+		if minimalAvailable := forecast.Maximum * settings.minimalAvailablePercentage / 100; minimalAvailable > forecast.Threshold {
+			forecast.Threshold = minimalAvailable
+		}
+
+		var growthPerSecond *float64
+		if bytesPerSecond, ok := growth[node.Name+"/"+diskName]; ok {
+			growthPerSecond = &bytesPerSecond
+		}
+		forecastThreshold(&forecast, settings, growth != nil, growthPerSecond, now)
+		forecasts = append(forecasts, forecast)
+	}
+	return forecasts
+}
+
+// newNodeForecast returns the forecast of the node as the total of its disks.
+func newNodeForecast(nodeName string, disks []types.CapacityForecast, settings capacitySettings, growthQueried bool, now time.Time) types.CapacityForecast {
+	forecast := types.CapacityForecast{Node: nodeName}
+
+	var growthPerSecond *float64
+	for _, disk := range disks {
+		forecast.Maximum += disk.Maximum
+		forecast.Reserved += disk.Reserved
+		forecast.Available += disk.Available
+		forecast.Scheduled += disk.Scheduled
+		forecast.Threshold += disk.Threshold
+
+		if disk.GrowthPerDay != nil {
+			if growthPerSecond == nil {
+				growthPerSecond = new(float64)
+			}
+			*growthPerSecond += float64(*disk.GrowthPerDay) / (24 * 60 * 60)
+		}
+	}
+
+	forecastThreshold(&forecast, settings, growthQueried, growthPerSecond, now)
+	return forecast
+}
+
+// forecastThreshold completes the forecast with its usage and scheduling, and estimates when its
+// available storage reaches the threshold at the growth rate.
+func forecastThreshold(forecast *types.CapacityForecast, settings capacitySettings, growthQueried bool, growthPerSecond *float64, now time.Time) {
+	forecast.Used = forecast.Maximum - forecast.Available
+
+	if schedulable := forecast.Maximum - forecast.Reserved; schedulable > 0 {
+		forecast.ScheduledPercentage = math.Round(float64(forecast.Scheduled)*1000/float64(schedulable)) / 10
+		forecast.OverProvisioned = forecast.Scheduled > schedulable*settings.overProvisioningPercentage/100
+	}
+
+	headroom := forecast.Available - forecast.Threshold
+	switch {
+	case forecast.Maximum == 0:
+		forecast.Message = "Disk is not ready"
+		return
+	case headroom <= 0:
+		forecast.DaysUntilThreshold = new(float64)
+		forecast.ThresholdReachedAt = now.UTC().Format(time.RFC3339)
+		forecast.Message = "Available storage is below the threshold"
+		return
+	case !growthQueried:
+		return
+	case growthPerSecond == nil:
+		forecast.Message = "No usage history in Prometheus"
+		return
+	}
+
+	growthPerDay := *growthPerSecond * 24 * 60 * 60
+	forecast.GrowthPerDay = new(int64)
+	*forecast.GrowthPerDay = int64(math.Round(growthPerDay))
+	if growthPerDay <= 0 {
+		forecast.Message = "Usage is not growing"
+		return
+	}
+
+	days := math.Round(float64(headroom)/growthPerDay*10) / 10
+	forecast.DaysUntilThreshold = &days
+	forecast.ThresholdReachedAt = now.Add(time.Duration(float64(headroom) / *growthPerSecond * float64(time.Second))).UTC().Format(time.RFC3339)
+}
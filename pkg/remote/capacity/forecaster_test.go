@@ -0,0 +1,188 @@
+package capacity
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	longhorn "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+)
+
+const gib = int64(1024 * 1024 * 1024)
+
+func TestParsePrometheusDuration(t *testing.T) {
+	tests := map[string]struct {
+		value       string
+		expected    time.Duration
+		expectedErr bool
+	}{
+		"days":       {value: "30d", expected: 30 * 24 * time.Hour},
+		"weeks":      {value: "2w", expected: 14 * 24 * time.Hour},
+		"hours":      {value: "12h", expected: 12 * time.Hour},
+		"zero":       {value: "0d", expectedErr: true},
+		"no unit":    {value: "30", expectedErr: true},
+		"multi unit": {value: "1h30m", expectedErr: true},
+		"empty":      {value: "", expectedErr: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			duration, err := parsePrometheusDuration(test.value)
+			if test.expectedErr {
+				if err == nil {
+					t.Fatalf("expected error, got %v", duration)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if duration != test.expected {
+				t.Errorf("expected %v, got %v", test.expected, duration)
+			}
+		})
+	}
+}
+
+func TestParseGrowthResponse(t *testing.T) {
+	tests := map[string]struct {
+		body        string
+		expected    map[string]float64
+		expectedErr bool
+	}{
+		"vector": {
+			body: `{"status":"success","data":{"resultType":"vector","result":[
+				{"metric":{"node":"node-1","disk":"disk-1"},"value":[1721120627,"1024.5"]},
+				{"metric":{"node":"node-2","disk":"disk-1"},"value":[1721120627,"-2"]},
+				{"metric":{"node":"node-2","disk":"disk-2"},"value":[1721120627,"NaN"]}]}}`,
+			expected: map[string]float64{"node-1/disk-1": 1024.5, "node-2/disk-1": -2},
+		},
+		"error": {
+			body:        `{"status":"error","error":"parse error"}`,
+			expectedErr: true,
+		},
+		"matrix": {
+			body:        `{"status":"success","data":{"resultType":"matrix","result":[]}}`,
+			expectedErr: true,
+		},
+		"invalid": {
+			body:        `not json`,
+			expectedErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			growth, err := parseGrowthResponse([]byte(test.body))
+			if test.expectedErr {
+				if err == nil {
+					t.Fatalf("expected error, got %v", growth)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(growth) != len(test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, growth)
+			}
+			for key, value := range test.expected {
+				if growth[key] != value {
+					t.Errorf("expected %v for %v, got %v", value, key, growth[key])
+				}
+			}
+		})
+	}
+}
+
+func TestNewDiskForecasts(t *testing.T) {
+	now := time.Date(2024, 7, 16, 0, 0, 0, 0, time.UTC)
+	settings := capacitySettings{overProvisioningPercentage: 100, minimalAvailablePercentage: 25}
+
+	newNode := func(reserved, available, scheduled int64) *longhorn.Node {
+		node := &longhorn.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}
+		node.Spec.Disks = map[string]longhorn.DiskSpec{"disk-1": {Path: "/var/lib/longhorn", StorageReserved: reserved}}
+		node.Status.DiskStatus = map[string]*longhorn.DiskStatus{
+			"disk-1": {StorageMaximum: 100 * gib, StorageAvailable: available, StorageScheduled: scheduled},
+		}
+		return node
+	}
+
+	tests := map[string]struct {
+		node                       *longhorn.Node
+		growth                     map[string]float64
+		expectedThreshold          int64
+		expectedOverProvisioned    bool
+		expectedDaysUntilThreshold *float64
+		expectedThresholdReachedAt string
+		expectedMessage            string
+	}{
+		"minimal available threshold without growth": {
+			node:              newNode(10*gib, 60*gib, 50*gib),
+			expectedThreshold: 25 * gib,
+		},
+		"reserved threshold over-provisioned": {
+			node:                    newNode(30*gib, 60*gib, 80*gib),
+			expectedThreshold:       30 * gib,
+			expectedOverProvisioned: true,
+		},
+		"growing": {
+			node:                       newNode(10*gib, 45*gib, 50*gib),
+			growth:                     map[string]float64{"node-1/disk-1": float64(2*gib) / (24 * 60 * 60)},
+			expectedThreshold:          25 * gib,
+			expectedDaysUntilThreshold: floatPtr(10),
+			expectedThresholdReachedAt: "2024-07-26T00:00:00Z",
+		},
+		"not growing": {
+			node:              newNode(10*gib, 45*gib, 50*gib),
+			growth:            map[string]float64{"node-1/disk-1": 0},
+			expectedThreshold: 25 * gib,
+			expectedMessage:   "Usage is not growing",
+		},
+		"no history": {
+			node:              newNode(10*gib, 45*gib, 50*gib),
+			growth:            map[string]float64{},
+			expectedThreshold: 25 * gib,
+			expectedMessage:   "No usage history in Prometheus",
+		},
+		"below threshold": {
+			node:                       newNode(10*gib, 20*gib, 50*gib),
+			growth:                     map[string]float64{"node-1/disk-1": 1},
+			expectedThreshold:          25 * gib,
+			expectedDaysUntilThreshold: floatPtr(0),
+			expectedThresholdReachedAt: "2024-07-16T00:00:00Z",
+			expectedMessage:            "Available storage is below the threshold",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			forecasts := newDiskForecasts(test.node, settings, test.growth, now)
+			if len(forecasts) != 1 {
+				t.Fatalf("expected 1 forecast, got %v", forecasts)
+			}
+			forecast := forecasts[0]
+			if forecast.Threshold != test.expectedThreshold {
+				t.Errorf("expected threshold %v, got %v", test.expectedThreshold, forecast.Threshold)
+			}
+			if forecast.OverProvisioned != test.expectedOverProvisioned {
+				t.Errorf("expected over-provisioned %v, got %v", test.expectedOverProvisioned, forecast.OverProvisioned)
+			}
+			if (forecast.DaysUntilThreshold == nil) != (test.expectedDaysUntilThreshold == nil) ||
+				(forecast.DaysUntilThreshold != nil && *forecast.DaysUntilThreshold != *test.expectedDaysUntilThreshold) {
+				t.Errorf("expected days until threshold %v, got %v", test.expectedDaysUntilThreshold, forecast.DaysUntilThreshold)
+			}
+			if forecast.ThresholdReachedAt != test.expectedThresholdReachedAt {
+				t.Errorf("expected threshold reached at %q, got %q", test.expectedThresholdReachedAt, forecast.ThresholdReachedAt)
+			}
+			if forecast.Message != test.expectedMessage {
+				t.Errorf("expected message %q, got %q", test.expectedMessage, forecast.Message)
+			}
+		})
+	}
+}
+
+func floatPtr(value float64) *float64 {
+	return &value
+}
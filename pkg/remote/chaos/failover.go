@@ -0,0 +1,381 @@
+package chaos
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	kubeclient "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	longhorn "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+	lhclient "github.com/longhorn/longhorn-manager/k8s/pkg/client/clientset/versioned"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// Failures injected by the FailoverTester.
+const (
+	FailureEngine      = "engine"
+	FailureNodeNetwork = "node-network"
+	FailureReplica     = "replica"
+)
+
+// failoverPollInterval is the interval between the checks of the volume.
+const failoverPollInterval = 2 * time.Second
+
+// detectionWindow is how long the volume is watched for the failure to be detected after the
+// failure ends, before the volume is considered to have tolerated it without leaving the healthy
+// state.
+const detectionWindow = 30 * time.Second
+
+// FailoverTester provide functions for injecting a controlled failure into a volume, and
+// measuring the time for the volume to recover from it, for validating the resilience of a
+// cluster before production.
+type FailoverTester struct {
+	FailoverTesterCmdOptions
+
+	kubeClient     *kubeclient.Clientset
+	longhornClient *lhclient.Clientset
+	restConfig     *rest.Config
+
+	engine      *longhorn.Engine
+	replica     *longhorn.Replica // Replica killed, or partitioned from the engine.
+	partitioner *partitioner
+}
+
+// FailoverTesterCmdOptions holds the options for the command.
+type FailoverTesterCmdOptions struct {
+	types.GlobalCmdOptions
+
+	LonghornNamespace string
+	VolumeName        string
+	Failure           string
+	ReplicaName       string        // Replica to kill or partition, picked when empty.
+	SLO               time.Duration // Maximum time for the volume to recover.
+	PartitionDuration time.Duration // Duration of the network partition.
+}
+
+// Validate validates the command options.
+func (remote *FailoverTester) Validate() error {
+	if remote.VolumeName == "" {
+		return errors.Errorf("volume name (--%s) is required", consts.CmdOptVolume)
+	}
+
+	switch remote.Failure {
+	case FailureReplica, FailureNodeNetwork:
+	case FailureEngine:
+		if remote.ReplicaName != "" {
+			return errors.Errorf("--%s is not supported with --%s=%s", consts.CmdOptReplica, consts.CmdOptKill, FailureEngine)
+		}
+	default:
+		return errors.Errorf("unsupported failure %q (--%s), supported failures: %s", remote.Failure, consts.CmdOptKill,
+			strings.Join([]string{FailureReplica, FailureEngine, FailureNodeNetwork}, ", "))
+	}
+
+	if remote.SLO <= 0 {
+		return errors.Errorf("--%s must be positive", consts.CmdOptSLO)
+	}
+	if remote.Failure == FailureNodeNetwork && remote.PartitionDuration <= 0 {
+		return errors.Errorf("--%s must be positive", consts.CmdOptPartitionDuration)
+	}
+
+	return nil
+}
+
+// Init initializes the FailoverTester, checks the volume is attached and healthy, and picks the
+// target of the failure.
+func (remote *FailoverTester) Init() error {
+	kubeClient, err := kubeutils.NewKubeClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.kubeClient = kubeClient
+
+	longhornClient, err := kubeutils.NewLonghornClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.longhornClient = longhornClient
+
+	restConfig, err := kubeutils.NewRestConfig("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.restConfig = restConfig
+
+	ctx := context.Background()
+	volume, err := remote.longhornClient.LonghornV1beta2().Volumes(remote.LonghornNamespace).Get(ctx, remote.VolumeName, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to get volume %v", remote.VolumeName)
+	}
+	if volume.Spec.DataEngine == longhorn.DataEngineTypeV2 && remote.Failure != FailureNodeNetwork {
+		return errors.Errorf("killing the %v of volume %v is only supported with the v1 data engine", remote.Failure, remote.VolumeName)
+	}
+	if volume.Status.State != longhorn.VolumeStateAttached || volume.Status.Robustness != longhorn.VolumeRobustnessHealthy {
+		return errors.Errorf("volume %v must be attached and healthy, it is %v and %v", remote.VolumeName, volume.Status.State, volume.Status.Robustness)
+	}
+
+	engine, replicas, err := remote.getVolumeInstances(ctx)
+	if err != nil {
+		return err
+	}
+	if engine == nil {
+		return errors.Errorf("no running engine of volume %v found", remote.VolumeName)
+	}
+	remote.engine = engine
+
+	if remote.Failure == FailureEngine {
+		return nil
+	}
+
+	if len(replicas) < 2 {
+		return errors.Errorf("volume %v has %d running replica, at least 2 are required to tolerate the failure of one", remote.VolumeName, len(replicas))
+	}
+	replica, err := pickReplica(replicas, engine, remote.ReplicaName, remote.Failure == FailureNodeNetwork)
+	if err != nil {
+		return err
+	}
+	remote.replica = replica
+
+	if remote.Failure == FailureNodeNetwork {
+		engineIP, err := remote.getInstanceManagerIP(ctx, engine.Status.InstanceManagerName)
+		if err != nil {
+			return err
+		}
+		remote.partitioner = newPartitioner(&remote.GlobalCmdOptions, remote.kubeClient, replica.Spec.NodeID, engineIP, remote.PartitionDuration)
+	}
+
+	return nil
+}
+
+// Describe returns the failure to inject, for the confirmation.
+func (remote *FailoverTester) Describe() string {
+	switch remote.Failure {
+	case FailureEngine:
+		return fmt.Sprintf("This will kill the engine process %v of volume %v on node %v, interrupting its I/O until it is restarted.", remote.engine.Name, remote.VolumeName, remote.engine.Spec.NodeID)
+	case FailureReplica:
+		return fmt.Sprintf("This will kill the replica process %v of volume %v on node %v, and rebuild a replica.", remote.replica.Name, remote.VolumeName, remote.replica.Spec.NodeID)
+	default:
+		return fmt.Sprintf("This will drop the traffic between node %v and the engine of volume %v on node %v for %v, and rebuild the replicas of the node.", remote.replica.Spec.NodeID, remote.VolumeName, remote.engine.Spec.NodeID, remote.PartitionDuration)
+	}
+}
+
+// Run injects the failure, and watches the volume until it recovers or the SLO expires.
+func (remote *FailoverTester) Run(ctx context.Context) (*types.FailoverReport, error) {
+	report := &types.FailoverReport{
+		Volume:     remote.VolumeName,
+		Failure:    remote.Failure,
+		SLOSeconds: remote.SLO.Seconds(),
+		Timeline:   []types.FailoverEvent{},
+	}
+
+	injectedAt := time.Now()
+	failureEnd := injectedAt
+	switch remote.Failure {
+	case FailureEngine:
+		report.Target, report.Node = remote.engine.Name, remote.engine.Spec.NodeID
+		if err := remote.killInstance(ctx, remote.engine.Status.InstanceManagerName, getEngineProcessPattern(remote.VolumeName)); err != nil {
+			return nil, err
+		}
+	case FailureReplica:
+		report.Target, report.Node = remote.replica.Name, remote.replica.Spec.NodeID
+		if err := remote.killInstance(ctx, remote.replica.Status.InstanceManagerName, getReplicaProcessPattern(remote.replica.Spec.DataDirectoryName)); err != nil {
+			return nil, err
+		}
+	case FailureNodeNetwork:
+		report.Target, report.Node = remote.replica.Spec.NodeID, remote.replica.Spec.NodeID
+		if err := remote.partitioner.start(); err != nil {
+			return nil, err
+		}
+		injectedAt = time.Now()
+		failureEnd = injectedAt.Add(remote.PartitionDuration)
+	}
+	report.InjectedAt = injectedAt.UTC().Format(time.RFC3339)
+	logrus.WithFields(logrus.Fields{"volume": remote.VolumeName, "node": report.Node}).Infof("Injected %v failure into %v", remote.Failure, report.Target)
+
+	err := wait.PollUntilContextTimeout(ctx, failoverPollInterval, remote.SLO, true, func(ctx context.Context) (bool, error) {
+		volume, err := remote.longhornClient.LonghornV1beta2().Volumes(remote.LonghornNamespace).Get(ctx, remote.VolumeName, metav1.GetOptions{})
+		if err != nil {
+			logrus.WithError(err).Debugf("Failed to get volume %v", remote.VolumeName)
+			return false, nil
+		}
+		_, replicas, err := remote.getVolumeInstances(ctx)
+		if err != nil {
+			logrus.WithError(err).Debugf("Failed to get the instances of volume %v", remote.VolumeName)
+			return false, nil
+		}
+
+		now := time.Now()
+		recordFailoverEvent(report, types.FailoverEvent{
+			ElapsedSeconds: now.Sub(injectedAt).Round(time.Second).Seconds(),
+			State:          string(volume.Status.State),
+			Robustness:     string(volume.Status.Robustness),
+			Replicas:       len(replicas),
+		})
+
+		healthy := volume.Status.State == longhorn.VolumeStateAttached && volume.Status.Robustness == longhorn.VolumeRobustnessHealthy
+		if !healthy && !report.Detected {
+			report.Detected = true
+			report.DetectionSeconds = now.Sub(injectedAt).Round(time.Second).Seconds()
+			logrus.WithField("volume", remote.VolumeName).Infof("Volume is %v and %v, waiting for it to recover", volume.Status.State, volume.Status.Robustness)
+		}
+
+		switch {
+		case !healthy:
+			return false, nil
+		case report.Detected:
+			report.Recovered = true
+			report.RecoverySeconds = now.Sub(injectedAt).Round(time.Second).Seconds()
+			return true, nil
+		case now.After(failureEnd.Add(detectionWindow)):
+			report.Recovered = true
+			return true, nil
+		}
+		return false, nil
+	})
+	if err != nil && ctx.Err() != nil {
+		return nil, errors.Wrap(ctx.Err(), "failover test interrupted")
+	}
+
+	report.Passed = report.Recovered
+	switch {
+	case !report.Recovered:
+		report.Message = fmt.Sprintf("Volume did not recover within the SLO of %v", remote.SLO)
+	case !report.Detected:
+		report.Message = fmt.Sprintf("Volume stayed healthy, the failure was not detected within %v after it ended", detectionWindow)
+	}
+	return report, nil
+}
+
+// Cleanup deletes the DaemonSet partitioning the node, which removes its firewall rules.
+func (remote *FailoverTester) Cleanup() error {
+	if remote.partitioner == nil {
+		return nil
+	}
+	return remote.partitioner.cleanup()
+}
+
+// getVolumeInstances returns the running engine of the volume, and its running replicas sorted by
+// name.
+func (remote *FailoverTester) getVolumeInstances(ctx context.Context) (*longhorn.Engine, []longhorn.Replica, error) {
+	engineList, err := remote.longhornClient.LonghornV1beta2().Engines(remote.LonghornNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to list engines")
+	}
+	replicaList, err := remote.longhornClient.LonghornV1beta2().Replicas(remote.LonghornNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to list replicas")
+	}
+
+	var engine *longhorn.Engine
+	for i := range engineList.Items {
+		candidate := &engineList.Items[i]
+		if candidate.Spec.VolumeName == remote.VolumeName && candidate.Status.CurrentState == longhorn.InstanceStateRunning {
+			engine = candidate
+			break
+		}
+	}
+
+	replicas := []longhorn.Replica{}
+	for _, replica := range replicaList.Items {
+		if replica.Spec.VolumeName == remote.VolumeName && replica.Status.CurrentState == longhorn.InstanceStateRunning {
+			replicas = append(replicas, replica)
+		}
+	}
+	sort.Slice(replicas, func(i, j int) bool { return replicas[i].Name < replicas[j].Name })
+
+	return engine, replicas, nil
+}
+
+// getInstanceManagerIP returns the pod IP of the instance manager.
+func (remote *FailoverTester) getInstanceManagerIP(ctx context.Context, name string) (string, error) {
+	pod, err := remote.kubeClient.CoreV1().Pods(remote.LonghornNamespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get instance manager pod %v", name)
+	}
+	if pod.Status.PodIP == "" {
+		return "", errors.Errorf("instance manager pod %v has no IP", name)
+	}
+	return pod.Status.PodIP, nil
+}
+
+// killInstance kills the processes matching the pattern in the instance manager, with SIGKILL so
+// they cannot shut down cleanly.
+func (remote *FailoverTester) killInstance(ctx context.Context, instanceManagerName, pattern string) error {
+	pod, err := remote.kubeClient.CoreV1().Pods(remote.LonghornNamespace).Get(ctx, instanceManagerName, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to get instance manager pod %v", instanceManagerName)
+	}
+	if pod.Status.Phase != corev1.PodRunning {
+		return errors.Errorf("instance manager pod %v is %v", pod.Name, pod.Status.Phase)
+	}
+
+	_, stderr, err := kubeutils.ExecPodContainer(ctx, remote.restConfig, remote.kubeClient, pod.Namespace, pod.Name, pod.Spec.Containers[0].Name, []string{"pkill", "-9", "-f", "--", pattern})
+	if err != nil {
+		return errors.Wrapf(err, "failed to kill the process matching %q in instance manager %v: %s", pattern, pod.Name, strings.TrimSpace(stderr))
+	}
+	return nil
+}
+
+// pickReplica returns the replica to kill or partition. Unless it is given, a replica on another
+// node than the engine is preferred, so the engine survives the failure. The node network failure
+// requires it, since the partitioned node would also cut the engine from its own replica.
+func pickReplica(replicas []longhorn.Replica, engine *longhorn.Engine, name string, requireOtherNode bool) (*longhorn.Replica, error) {
+	if name != "" {
+		for i := range replicas {
+			if replicas[i].Name != name {
+				continue
+			}
+			if requireOtherNode && replicas[i].Spec.NodeID == engine.Spec.NodeID {
+				return nil, errors.Errorf("replica %v is on node %v of the engine, the network of the node cannot be partitioned from the engine", name, engine.Spec.NodeID)
+			}
+			return &replicas[i], nil
+		}
+		return nil, errors.Errorf("no running replica %v of volume %v found", name, engine.Spec.VolumeName)
+	}
+
+	for i := range replicas {
+		if replicas[i].Spec.NodeID != engine.Spec.NodeID {
+			return &replicas[i], nil
+		}
+	}
+	if requireOtherNode {
+		return nil, errors.Errorf("no running replica of volume %v on another node than the engine on node %v", engine.Spec.VolumeName, engine.Spec.NodeID)
+	}
+	return &replicas[0], nil
+}
+
+// getEngineProcessPattern returns the pattern of the command line of the engine process of the
+// volume in the instance manager.
+func getEngineProcessPattern(volumeName string) string {
+	return fmt.Sprintf("controller %s( |$)", volumeName)
+}
+
+// getReplicaProcessPattern returns the pattern of the command line of the replica process in the
+// instance manager, which serves the replica data directory.
+func getReplicaProcessPattern(dataDirectoryName string) string {
+	return fmt.Sprintf("replicas/%s( |$)", dataDirectoryName)
+}
+
+// recordFailoverEvent adds the event to the timeline when the volume changed.
+func recordFailoverEvent(report *types.FailoverReport, event types.FailoverEvent) {
+	if len(report.Timeline) > 0 {
+		last := report.Timeline[len(report.Timeline)-1]
+		if last.State == event.State && last.Robustness == event.Robustness && last.Replicas == event.Replicas {
+			return
+		}
+	}
+	report.Timeline = append(report.Timeline, event)
+}
@@ -0,0 +1,184 @@
+package chaos
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	longhorn "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func TestFailoverTesterValidate(t *testing.T) {
+	tests := map[string]struct {
+		options       FailoverTesterCmdOptions
+		expectedError bool
+	}{
+		"replica": {
+			options: FailoverTesterCmdOptions{VolumeName: "vol-1", Failure: FailureReplica, SLO: time.Minute},
+		},
+		"replica with name": {
+			options: FailoverTesterCmdOptions{VolumeName: "vol-1", Failure: FailureReplica, ReplicaName: "vol-1-r-1", SLO: time.Minute},
+		},
+		"engine": {
+			options: FailoverTesterCmdOptions{VolumeName: "vol-1", Failure: FailureEngine, SLO: time.Minute},
+		},
+		"engine with replica name": {
+			options:       FailoverTesterCmdOptions{VolumeName: "vol-1", Failure: FailureEngine, ReplicaName: "vol-1-r-1", SLO: time.Minute},
+			expectedError: true,
+		},
+		"node network": {
+			options: FailoverTesterCmdOptions{VolumeName: "vol-1", Failure: FailureNodeNetwork, SLO: time.Minute, PartitionDuration: time.Minute},
+		},
+		"node network without duration": {
+			options:       FailoverTesterCmdOptions{VolumeName: "vol-1", Failure: FailureNodeNetwork, SLO: time.Minute},
+			expectedError: true,
+		},
+		"no volume": {
+			options:       FailoverTesterCmdOptions{Failure: FailureReplica, SLO: time.Minute},
+			expectedError: true,
+		},
+		"unsupported failure": {
+			options:       FailoverTesterCmdOptions{VolumeName: "vol-1", Failure: "disk", SLO: time.Minute},
+			expectedError: true,
+		},
+		"no SLO": {
+			options:       FailoverTesterCmdOptions{VolumeName: "vol-1", Failure: FailureReplica},
+			expectedError: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			tester := &FailoverTester{FailoverTesterCmdOptions: test.options}
+			err := tester.Validate()
+			if test.expectedError != (err != nil) {
+				t.Fatalf("expected error %v, got %v", test.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestPickReplica(t *testing.T) {
+	newReplica := func(name, nodeID string) longhorn.Replica {
+		replica := longhorn.Replica{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		replica.Spec.NodeID = nodeID
+		return replica
+	}
+	engine := &longhorn.Engine{}
+	engine.Spec.VolumeName = "vol-1"
+	engine.Spec.NodeID = "node-1"
+
+	tests := map[string]struct {
+		replicas         []longhorn.Replica
+		name             string
+		requireOtherNode bool
+		expected         string
+		expectedError    bool
+	}{
+		"other node preferred": {
+			replicas: []longhorn.Replica{newReplica("r-1", "node-1"), newReplica("r-2", "node-2")},
+			expected: "r-2",
+		},
+		"engine node": {
+			replicas: []longhorn.Replica{newReplica("r-1", "node-1"), newReplica("r-2", "node-1")},
+			expected: "r-1",
+		},
+		"engine node with other node required": {
+			replicas:         []longhorn.Replica{newReplica("r-1", "node-1"), newReplica("r-2", "node-1")},
+			requireOtherNode: true,
+			expectedError:    true,
+		},
+		"named": {
+			replicas: []longhorn.Replica{newReplica("r-1", "node-1"), newReplica("r-2", "node-2")},
+			name:     "r-1",
+			expected: "r-1",
+		},
+		"named on engine node with other node required": {
+			replicas:         []longhorn.Replica{newReplica("r-1", "node-1"), newReplica("r-2", "node-2")},
+			name:             "r-1",
+			requireOtherNode: true,
+			expectedError:    true,
+		},
+		"named not running": {
+			replicas:      []longhorn.Replica{newReplica("r-1", "node-1"), newReplica("r-2", "node-2")},
+			name:          "r-3",
+			expectedError: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			replica, err := pickReplica(test.replicas, engine, test.name, test.requireOtherNode)
+			if test.expectedError != (err != nil) {
+				t.Fatalf("expected error %v, got %v", test.expectedError, err)
+			}
+			if err == nil && replica.Name != test.expected {
+				t.Fatalf("expected replica %v, got %v", test.expected, replica.Name)
+			}
+		})
+	}
+}
+
+func TestProcessPatterns(t *testing.T) {
+	tests := map[string]struct {
+		pattern  string
+		command  string
+		expected bool
+	}{
+		"engine": {
+			pattern:  getEngineProcessPattern("vol-1"),
+			command:  "/engine-binaries/longhorn-engine/longhorn controller vol-1 --frontend tgt-blockdev --size 1073741824",
+			expected: true,
+		},
+		"engine of other volume": {
+			pattern: getEngineProcessPattern("vol-1"),
+			command: "/engine-binaries/longhorn-engine/longhorn controller vol-10 --frontend tgt-blockdev",
+		},
+		"replica": {
+			pattern:  getReplicaProcessPattern("vol-1-8e3fa9c6"),
+			command:  "/engine-binaries/longhorn-engine/longhorn replica /host/var/lib/longhorn/replicas/vol-1-8e3fa9c6 --size 1073741824",
+			expected: true,
+		},
+		"replica of other directory": {
+			pattern: getReplicaProcessPattern("vol-1-8e3fa9c6"),
+			command: "/engine-binaries/longhorn-engine/longhorn replica /host/var/lib/longhorn/replicas/vol-1-8e3fa9c6-old --size 1073741824",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			matched := regexp.MustCompile(test.pattern).MatchString(test.command)
+			if matched != test.expected {
+				t.Fatalf("expected %q matching %q %v, got %v", test.pattern, test.command, test.expected, matched)
+			}
+		})
+	}
+}
+
+func TestRecordFailoverEvent(t *testing.T) {
+	report := &types.FailoverReport{}
+	events := []types.FailoverEvent{
+		{ElapsedSeconds: 0, State: "attached", Robustness: "healthy", Replicas: 3},
+		{ElapsedSeconds: 2, State: "attached", Robustness: "healthy", Replicas: 3},
+		{ElapsedSeconds: 4, State: "attached", Robustness: "degraded", Replicas: 2},
+		{ElapsedSeconds: 6, State: "attached", Robustness: "degraded", Replicas: 3},
+		{ElapsedSeconds: 8, State: "attached", Robustness: "healthy", Replicas: 3},
+	}
+	for _, event := range events {
+		recordFailoverEvent(report, event)
+	}
+
+	expected := []float64{0, 4, 6, 8}
+	if len(report.Timeline) != len(expected) {
+		t.Fatalf("expected %d events, got %+v", len(expected), report.Timeline)
+	}
+	for i, event := range report.Timeline {
+		if event.ElapsedSeconds != expected[i] {
+			t.Fatalf("expected event %d at %vs, got %+v", i, expected[i], event)
+		}
+	}
+}
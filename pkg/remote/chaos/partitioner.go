@@ -0,0 +1,170 @@
+package chaos
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/utils/ptr"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeclient "k8s.io/client-go/kubernetes"
+
+	commonkube "github.com/longhorn/go-common-libs/kubernetes"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// partitionScript drops the traffic between the node and the peer IP for the duration, then
+// removes the rules and idles, so the restarted container does not partition the node again. The
+// rules are also removed when the pod is deleted before the end of the partition.
+const partitionScript = `
+peer="$1"
+duration="$2"
+
+rules() {
+	iptables "$1" INPUT -s "$peer" -j DROP
+	iptables "$1" OUTPUT -d "$peer" -j DROP
+	iptables "$1" FORWARD -s "$peer" -j DROP
+	iptables "$1" FORWARD -d "$peer" -j DROP
+}
+
+heal() {
+	rules -D 2>/dev/null
+	echo "Removed the partition from $peer"
+}
+
+trap 'heal; exit 0' TERM INT
+
+rules -I || { heal; exit 1; }
+echo "Partitioned the node from $peer for ${duration}s"
+
+sleep "$duration" &
+wait $!
+heal
+
+sleep infinity &
+wait $!
+`
+
+// partitioner partitions a node from a peer IP with a DaemonSet pod in the host network namespace
+// of the node.
+type partitioner struct {
+	globalOpts *types.GlobalCmdOptions
+
+	kubeClient *kubeclient.Clientset
+
+	appName   string // App name of the DaemonSet.
+	namespace string
+	nodeName  string
+	peerIP    string
+	duration  time.Duration
+}
+
+func newPartitioner(globalOpts *types.GlobalCmdOptions, kubeClient *kubeclient.Clientset, nodeName, peerIP string, duration time.Duration) *partitioner {
+	namespace := globalOpts.Namespace
+	if namespace == "" {
+		namespace = consts.LonghornNamespace
+	}
+
+	return &partitioner{
+		globalOpts: globalOpts,
+		kubeClient: kubeClient,
+		appName:    consts.AppNameNetworkPartitioner,
+		namespace:  namespace,
+		nodeName:   nodeName,
+		peerIP:     peerIP,
+		duration:   duration,
+	}
+}
+
+// start creates the DaemonSet on the node, and waits for the partition to be in place.
+func (p *partitioner) start() error {
+	newDaemonSet := p.newDaemonSet()
+	kubeutils.SetNodeNameAffinity(&newDaemonSet.Spec.Template.Spec, []string{p.nodeName})
+	if err := kubeutils.SetPodOptions(&newDaemonSet.Spec.Template.Spec, p.globalOpts); err != nil {
+		return err
+	}
+
+	_, err := kubeutils.CreateNamespace(p.kubeClient, p.namespace)
+	if err != nil {
+		return err
+	}
+
+	kubeutils.SetRunMetadata(p.kubeClient, newDaemonSet)
+	kubeutils.LogManifest(newDaemonSet)
+	daemonSet, err := kubeutils.CreateDaemonSet(p.kubeClient, newDaemonSet, p.globalOpts.MinNodes)
+	if err != nil {
+		return err
+	}
+
+	return kubeutils.MonitorDaemonSetContainer(p.kubeClient, daemonSet, consts.ContainerName, kubeutils.WaitForDaemonSetContainersReady, ptr.To(consts.ContainerConditionMaxTolerationMedium))
+}
+
+// cleanup deletes the DaemonSet, which removes the partition if it is still in place.
+func (p *partitioner) cleanup() error {
+	return commonkube.DeleteDaemonSet(p.kubeClient, p.namespace, p.appName)
+}
+
+// newDaemonSet prepares the DaemonSet partitioning the node. The pod is ready once the rules are
+// inserted, and has a grace period for removing them when deleted. It tolerates all the taints,
+// since the node is selected explicitly.
+func (p *partitioner) newDaemonSet() *appsv1.DaemonSet {
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      p.appName,
+			Namespace: p.namespace,
+			Labels: map[string]string{
+				"app":                 p.appName,
+				consts.LabelManagedBy: consts.LabelValueManagedBy,
+			},
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app": p.appName,
+				},
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						"app":                 p.appName,
+						consts.LabelManagedBy: consts.LabelValueManagedBy,
+					},
+				},
+				Spec: corev1.PodSpec{
+					HostNetwork: true,
+					Tolerations: []corev1.Toleration{
+						{
+							Operator: corev1.TolerationOpExists,
+						},
+					},
+					Containers: []corev1.Container{
+						{
+							Name:            consts.ContainerName,
+							Image:           p.globalOpts.Image,
+							Command:         []string{"bash", "-c", partitionScript, "partition", p.peerIP, fmt.Sprint(int64(p.duration.Seconds()))},
+							SecurityContext: kubeutils.NewSecurityContext(p.globalOpts.Privileged, kubeutils.CapabilitiesNetworkAdmin),
+							ReadinessProbe: &corev1.Probe{
+								ProbeHandler: corev1.ProbeHandler{
+									Exec: &corev1.ExecAction{
+										Command: []string{"iptables", "-C", "OUTPUT", "-d", p.peerIP, "-j", "DROP"},
+									},
+								},
+								PeriodSeconds: 1,
+							},
+						},
+					},
+					TerminationGracePeriodSeconds: ptr.To(int64(30)),
+				},
+			},
+			UpdateStrategy: appsv1.DaemonSetUpdateStrategy{
+				Type: appsv1.RollingUpdateDaemonSetStrategyType,
+			},
+		},
+	}
+}
@@ -0,0 +1,347 @@
+package cleanup
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/utils/ptr"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	kubeclient "k8s.io/client-go/kubernetes"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// Cleaner provide functions for removing the resources created in the cluster by longhornctl.
+type Cleaner struct {
+	CleanerCmdOptions
+
+	kubeClient *kubeclient.Clientset
+}
+
+// CleanerCmdOptions holds the options for the command.
+type CleanerCmdOptions struct {
+	types.GlobalCmdOptions
+
+	DryRun       bool          // Only report the resources to remove.
+	TTL          time.Duration // Only remove the resources of the runs started longer ago. Removes all when not positive.
+	IncludeState bool          // Also remove the resources holding the state or the records of operations.
+}
+
+// resourceKind lists and deletes the resources of a kind.
+type resourceKind struct {
+	kind   string
+	list   func(ctx context.Context, listOptions metav1.ListOptions) ([]metav1.Object, error)
+	delete func(ctx context.Context, namespace, name string, deleteOptions metav1.DeleteOptions) error
+}
+
+// Init initializes the Cleaner.
+func (remote *Cleaner) Init() error {
+	kubeClient, err := kubeutils.NewKubeClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.kubeClient = kubeClient
+
+	return nil
+}
+
+// Run removes the resources labeled as managed by longhornctl in all the namespaces, and returns
+// the result keyed by the resource. The resources controlled by another resource, such as the pods
+// of the DaemonSets, are removed with it. A resource failing to be removed is reported as an error
+// in its result, so the other resources are still removed.
+//
+// The resources labeled with consts.LabelState, holding the state or the records of operations,
+// are kept and reported as skipped, unless IncludeState is set.
+//
+// With TTL, only the resources of the runs started longer than TTL ago are removed. The start of a
+// run is the creation of its anchor, or the creation of the resource when the anchor is gone, such
+// as for the resources of the runs that completed or of the versions of longhornctl without runs.
+func (remote *Cleaner) Run() (map[string]*types.LogCollection, error) {
+	ctx := context.Background()
+	listOptions := metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set{consts.LabelManagedBy: consts.LabelValueManagedBy}).String(),
+	}
+	deleteOptions := metav1.DeleteOptions{
+		PropagationPolicy: ptr.To(metav1.DeletePropagationBackground),
+	}
+
+	runStartTimes := map[string]time.Time{}
+	if remote.TTL > 0 {
+		configMapList, err := remote.kubeClient.CoreV1().ConfigMaps(metav1.NamespaceAll).List(ctx, listOptions)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list run anchors")
+		}
+		for i := range configMapList.Items {
+			addRunStartTime(runStartTimes, &configMapList.Items[i])
+		}
+	}
+	now := time.Now()
+
+	collections := map[string]*types.LogCollection{}
+	for _, resourceKind := range remote.getResourceKinds() {
+		objects, err := resourceKind.list(ctx, listOptions)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to list %v", resourceKind.kind)
+		}
+
+		for _, object := range objects {
+			if metav1.GetControllerOf(object) != nil {
+				continue
+			}
+			if remote.TTL > 0 && now.Sub(getRunStartTime(runStartTimes, object)) < remote.TTL {
+				continue
+			}
+
+			collection := &types.LogCollection{}
+			collections[getObjectKey(resourceKind.kind, object)] = collection
+
+			if !remote.IncludeState && isStateObject(object) {
+				collection.Skipped = append(collection.Skipped, fmt.Sprintf("Kept, it holds the state or the record of an operation. Use --%s to remove it", consts.CmdOptIncludeState))
+				continue
+			}
+
+			if remote.DryRun {
+				collection.Info = append(collection.Info, "Would be removed")
+				continue
+			}
+
+			err := resourceKind.delete(ctx, object.GetNamespace(), object.GetName(), deleteOptions)
+			if err != nil && !apierrors.IsNotFound(err) {
+				collection.Error = append(collection.Error, fmt.Sprintf("Failed to remove: %v", err))
+				continue
+			}
+			collection.Info = append(collection.Info, "Removed")
+		}
+	}
+
+	return collections, nil
+}
+
+// getResourceKinds returns the kinds of the resources longhornctl creates, in the order they are
+// removed: the workloads first, then the resources they use.
+func (remote *Cleaner) getResourceKinds() []resourceKind {
+	appsClient := remote.kubeClient.AppsV1()
+	batchClient := remote.kubeClient.BatchV1()
+	coreClient := remote.kubeClient.CoreV1()
+	rbacClient := remote.kubeClient.RbacV1()
+
+	return []resourceKind{
+		{
+			kind: "DaemonSet",
+			list: func(ctx context.Context, listOptions metav1.ListOptions) ([]metav1.Object, error) {
+				list, err := appsClient.DaemonSets(metav1.NamespaceAll).List(ctx, listOptions)
+				if err != nil {
+					return nil, err
+				}
+				objects := []metav1.Object{}
+				for i := range list.Items {
+					objects = append(objects, &list.Items[i])
+				}
+				return objects, nil
+			},
+			delete: func(ctx context.Context, namespace, name string, deleteOptions metav1.DeleteOptions) error {
+				return appsClient.DaemonSets(namespace).Delete(ctx, name, deleteOptions)
+			},
+		},
+		{
+			kind: "Job",
+			list: func(ctx context.Context, listOptions metav1.ListOptions) ([]metav1.Object, error) {
+				list, err := batchClient.Jobs(metav1.NamespaceAll).List(ctx, listOptions)
+				if err != nil {
+					return nil, err
+				}
+				objects := []metav1.Object{}
+				for i := range list.Items {
+					objects = append(objects, &list.Items[i])
+				}
+				return objects, nil
+			},
+			delete: func(ctx context.Context, namespace, name string, deleteOptions metav1.DeleteOptions) error {
+				return batchClient.Jobs(namespace).Delete(ctx, name, deleteOptions)
+			},
+		},
+		{
+			kind: "Pod",
+			list: func(ctx context.Context, listOptions metav1.ListOptions) ([]metav1.Object, error) {
+				list, err := coreClient.Pods(metav1.NamespaceAll).List(ctx, listOptions)
+				if err != nil {
+					return nil, err
+				}
+				objects := []metav1.Object{}
+				for i := range list.Items {
+					objects = append(objects, &list.Items[i])
+				}
+				return objects, nil
+			},
+			delete: func(ctx context.Context, namespace, name string, deleteOptions metav1.DeleteOptions) error {
+				return coreClient.Pods(namespace).Delete(ctx, name, deleteOptions)
+			},
+		},
+		{
+			kind: "PersistentVolumeClaim",
+			list: func(ctx context.Context, listOptions metav1.ListOptions) ([]metav1.Object, error) {
+				list, err := coreClient.PersistentVolumeClaims(metav1.NamespaceAll).List(ctx, listOptions)
+				if err != nil {
+					return nil, err
+				}
+				objects := []metav1.Object{}
+				for i := range list.Items {
+					objects = append(objects, &list.Items[i])
+				}
+				return objects, nil
+			},
+			delete: func(ctx context.Context, namespace, name string, deleteOptions metav1.DeleteOptions) error {
+				return coreClient.PersistentVolumeClaims(namespace).Delete(ctx, name, deleteOptions)
+			},
+		},
+		{
+			kind: "PersistentVolume",
+			list: func(ctx context.Context, listOptions metav1.ListOptions) ([]metav1.Object, error) {
+				list, err := coreClient.PersistentVolumes().List(ctx, listOptions)
+				if err != nil {
+					return nil, err
+				}
+				objects := []metav1.Object{}
+				for i := range list.Items {
+					objects = append(objects, &list.Items[i])
+				}
+				return objects, nil
+			},
+			delete: func(ctx context.Context, namespace, name string, deleteOptions metav1.DeleteOptions) error {
+				return coreClient.PersistentVolumes().Delete(ctx, name, deleteOptions)
+			},
+		},
+		{
+			kind: "ConfigMap",
+			list: func(ctx context.Context, listOptions metav1.ListOptions) ([]metav1.Object, error) {
+				list, err := coreClient.ConfigMaps(metav1.NamespaceAll).List(ctx, listOptions)
+				if err != nil {
+					return nil, err
+				}
+				objects := []metav1.Object{}
+				for i := range list.Items {
+					objects = append(objects, &list.Items[i])
+				}
+				return objects, nil
+			},
+			delete: func(ctx context.Context, namespace, name string, deleteOptions metav1.DeleteOptions) error {
+				return coreClient.ConfigMaps(namespace).Delete(ctx, name, deleteOptions)
+			},
+		},
+		{
+			kind: "Secret",
+			list: func(ctx context.Context, listOptions metav1.ListOptions) ([]metav1.Object, error) {
+				list, err := coreClient.Secrets(metav1.NamespaceAll).List(ctx, listOptions)
+				if err != nil {
+					return nil, err
+				}
+				objects := []metav1.Object{}
+				for i := range list.Items {
+					objects = append(objects, &list.Items[i])
+				}
+				return objects, nil
+			},
+			delete: func(ctx context.Context, namespace, name string, deleteOptions metav1.DeleteOptions) error {
+				return coreClient.Secrets(namespace).Delete(ctx, name, deleteOptions)
+			},
+		},
+		{
+			kind: "ClusterRoleBinding",
+			list: func(ctx context.Context, listOptions metav1.ListOptions) ([]metav1.Object, error) {
+				list, err := rbacClient.ClusterRoleBindings().List(ctx, listOptions)
+				if err != nil {
+					return nil, err
+				}
+				objects := []metav1.Object{}
+				for i := range list.Items {
+					objects = append(objects, &list.Items[i])
+				}
+				return objects, nil
+			},
+			delete: func(ctx context.Context, _, name string, deleteOptions metav1.DeleteOptions) error {
+				return rbacClient.ClusterRoleBindings().Delete(ctx, name, deleteOptions)
+			},
+		},
+		{
+			kind: "ClusterRole",
+			list: func(ctx context.Context, listOptions metav1.ListOptions) ([]metav1.Object, error) {
+				list, err := rbacClient.ClusterRoles().List(ctx, listOptions)
+				if err != nil {
+					return nil, err
+				}
+				objects := []metav1.Object{}
+				for i := range list.Items {
+					objects = append(objects, &list.Items[i])
+				}
+				return objects, nil
+			},
+			delete: func(ctx context.Context, _, name string, deleteOptions metav1.DeleteOptions) error {
+				return rbacClient.ClusterRoles().Delete(ctx, name, deleteOptions)
+			},
+		},
+		{
+			kind: "ServiceAccount",
+			list: func(ctx context.Context, listOptions metav1.ListOptions) ([]metav1.Object, error) {
+				list, err := coreClient.ServiceAccounts(metav1.NamespaceAll).List(ctx, listOptions)
+				if err != nil {
+					return nil, err
+				}
+				objects := []metav1.Object{}
+				for i := range list.Items {
+					objects = append(objects, &list.Items[i])
+				}
+				return objects, nil
+			},
+			delete: func(ctx context.Context, namespace, name string, deleteOptions metav1.DeleteOptions) error {
+				return coreClient.ServiceAccounts(namespace).Delete(ctx, name, deleteOptions)
+			},
+		},
+	}
+}
+
+// addRunStartTime records the creation of the ConfigMap as the start of its run when it is the
+// anchor of the run. A run has an anchor in each namespace it created resources in; the earliest
+// is the start.
+func addRunStartTime(runStartTimes map[string]time.Time, configMap metav1.Object) {
+	runID := configMap.GetLabels()[consts.LabelRunID]
+	if runID == "" || !strings.HasPrefix(configMap.GetName(), consts.RunAnchorNamePrefix) {
+		return
+	}
+
+	startTime := configMap.GetCreationTimestamp().Time
+	if previous, ok := runStartTimes[runID]; !ok || startTime.Before(previous) {
+		runStartTimes[runID] = startTime
+	}
+}
+
+// getRunStartTime returns the start of the run of the resource, or its creation when the anchor
+// of its run is gone.
+func getRunStartTime(runStartTimes map[string]time.Time, object metav1.Object) time.Time {
+	if startTime, ok := runStartTimes[object.GetLabels()[consts.LabelRunID]]; ok {
+		return startTime
+	}
+	return object.GetCreationTimestamp().Time
+}
+
+// isStateObject returns whether the resource holds the state or the record of an operation, such
+// as the state of a cluster migration, to resume or inspect after the command completed.
+func isStateObject(object metav1.Object) bool {
+	return object.GetLabels()[consts.LabelState] == consts.LabelValueState
+}
+
+// getObjectKey returns the key of the resource in the result, as "<kind>/<namespace>/<name>", or
+// "<kind>/<name>" for the cluster-scoped resources.
+func getObjectKey(kind string, object metav1.Object) string {
+	return path.Join(kind, object.GetNamespace(), object.GetName())
+}
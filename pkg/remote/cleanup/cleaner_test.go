@@ -0,0 +1,182 @@
+package cleanup
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeclient "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"github.com/longhorn/cli/pkg/consts"
+)
+
+func TestGetObjectKey(t *testing.T) {
+	tests := []struct {
+		kind     string
+		object   metav1.Object
+		expected string
+	}{
+		{
+			kind:     "ConfigMap",
+			object:   &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "longhorn-replica-exporter", Namespace: "longhorn-system"}},
+			expected: "ConfigMap/longhorn-system/longhorn-replica-exporter",
+		},
+		{
+			kind:     "ClusterRole",
+			object:   &rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "longhorn-preflight-checker"}},
+			expected: "ClusterRole/longhorn-preflight-checker",
+		},
+	}
+
+	for _, test := range tests {
+		if key := getObjectKey(test.kind, test.object); key != test.expected {
+			t.Errorf("expected %q, got %q", test.expected, key)
+		}
+	}
+}
+
+func TestGetRunStartTime(t *testing.T) {
+	runStart := time.Date(2024, 7, 16, 9, 0, 0, 0, time.UTC)
+	newObject := func(name, runID string, creationTime time.Time) *corev1.ConfigMap {
+		return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Labels:            map[string]string{consts.LabelRunID: runID},
+			CreationTimestamp: metav1.NewTime(creationTime),
+		}}
+	}
+
+	runStartTimes := map[string]time.Time{}
+	addRunStartTime(runStartTimes, newObject(consts.RunAnchorNamePrefix+"0a1b2c3d", "0a1b2c3d", runStart.Add(time.Minute)))
+	addRunStartTime(runStartTimes, newObject(consts.RunAnchorNamePrefix+"0a1b2c3d", "0a1b2c3d", runStart))
+	addRunStartTime(runStartTimes, newObject("longhorn-preflight-custom-checks", "4e5f6a7b", runStart))
+
+	tests := map[string]struct {
+		object   metav1.Object
+		expected time.Time
+	}{
+		"run with anchors": {
+			object:   newObject("longhorn-replica-exporter", "0a1b2c3d", runStart.Add(time.Hour)),
+			expected: runStart,
+		},
+		"run without anchor": {
+			object:   newObject("longhorn-replica-exporter", "4e5f6a7b", runStart.Add(time.Hour)),
+			expected: runStart.Add(time.Hour),
+		},
+		"resource without run": {
+			object:   newObject("longhorn-replica-exporter", "", runStart.Add(2*time.Hour)),
+			expected: runStart.Add(2 * time.Hour),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if startTime := getRunStartTime(runStartTimes, test.object); !startTime.Equal(test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, startTime)
+			}
+		})
+	}
+}
+
+func TestIsStateObject(t *testing.T) {
+	tests := map[string]struct {
+		object   metav1.Object
+		expected bool
+	}{
+		"migration state": {
+			object: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+				Name: consts.AppNameClusterMigrator,
+				Labels: map[string]string{
+					consts.LabelManagedBy: consts.LabelValueManagedBy,
+					consts.LabelState:     consts.LabelValueState,
+				},
+			}},
+			expected: true,
+		},
+		"custom checks": {
+			object: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+				Name:   "longhorn-preflight-custom-checks",
+				Labels: map[string]string{consts.LabelManagedBy: consts.LabelValueManagedBy},
+			}},
+		},
+		"other state label value": {
+			object: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+				Name:   "longhorn-replica-exporter",
+				Labels: map[string]string{consts.LabelState: "false"},
+			}},
+		},
+	}
+
+	for name, test := range tests {
+		if isStateObject(test.object) != test.expected {
+			t.Errorf("%v: expected %v", name, test.expected)
+		}
+	}
+}
+
+func TestRunRemovesJobs(t *testing.T) {
+	listKinds := map[string]string{
+		"/apis/apps/v1/daemonsets":       "DaemonSetList",
+		"/apis/batch/v1/jobs":            "JobList",
+		"/api/v1/pods":                   "PodList",
+		"/api/v1/persistentvolumeclaims": "PersistentVolumeClaimList",
+		"/api/v1/persistentvolumes":      "PersistentVolumeList",
+		"/api/v1/configmaps":             "ConfigMapList",
+		"/api/v1/secrets":                "SecretList",
+		"/apis/rbac.authorization.k8s.io/v1/clusterrolebindings": "ClusterRoleBindingList",
+		"/apis/rbac.authorization.k8s.io/v1/clusterroles":        "ClusterRoleList",
+		"/api/v1/serviceaccounts":                                "ServiceAccountList",
+	}
+
+	var deleteOptions *metav1.DeleteOptions
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/apis/batch/v1/jobs":
+			job := batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "longhornctl-async-5f2a9c1e", Namespace: "longhorn-system"}}
+			_ = json.NewEncoder(w).Encode(&batchv1.JobList{
+				TypeMeta: metav1.TypeMeta{APIVersion: "batch/v1", Kind: "JobList"},
+				Items:    []batchv1.Job{job},
+			})
+		case r.Method == http.MethodGet && listKinds[r.URL.Path] != "":
+			_, _ = fmt.Fprintf(w, `{"kind":%q,"items":[]}`, listKinds[r.URL.Path])
+		case r.Method == http.MethodDelete && r.URL.Path == "/apis/batch/v1/namespaces/longhorn-system/jobs/longhornctl-async-5f2a9c1e":
+			deleteOptions = &metav1.DeleteOptions{}
+			_ = json.NewDecoder(r.Body).Decode(deleteOptions)
+			_, _ = fmt.Fprint(w, `{"kind":"Status","apiVersion":"v1","status":"Success"}`)
+		default:
+			t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	kubeClient, err := kubeclient.NewForConfig(&rest.Config{
+		Host:          server.URL,
+		ContentConfig: rest.ContentConfig{ContentType: "application/json"},
+	})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	cleaner := &Cleaner{kubeClient: kubeClient}
+	collections, err := cleaner.Run()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	collection, ok := collections["Job/longhorn-system/longhornctl-async-5f2a9c1e"]
+	if !ok || len(collection.Info) != 1 || collection.Info[0] != "Removed" {
+		t.Errorf("expected the Job to be removed, got %v", collections)
+	}
+	if deleteOptions == nil || deleteOptions.PropagationPolicy == nil || *deleteOptions.PropagationPolicy != metav1.DeletePropagationBackground {
+		t.Errorf("expected the Job to be deleted with background propagation, got %+v", deleteOptions)
+	}
+}
@@ -0,0 +1,346 @@
+package crd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/blang/semver/v4"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/dynamic"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+const httpTimeout = time.Minute
+
+const (
+	longhornAPIGroup = "longhorn.io"
+
+	kindCustomResourceDefinition = "CustomResourceDefinition"
+)
+
+var customResourceDefinitionResource = schema.GroupVersionResource{
+	Group:    "apiextensions.k8s.io",
+	Version:  "v1",
+	Resource: "customresourcedefinitions",
+}
+
+// customResourceDefinition is the part of a CustomResourceDefinition used for the check.
+type customResourceDefinition struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              struct {
+		Group string `json:"group"`
+		Names struct {
+			Plural string `json:"plural"`
+		} `json:"names"`
+		Versions []customResourceDefinitionVersion `json:"versions"`
+	} `json:"spec"`
+	Status struct {
+		StoredVersions []string `json:"storedVersions"`
+	} `json:"status"`
+}
+
+type customResourceDefinitionVersion struct {
+	Name    string `json:"name"`
+	Served  bool   `json:"served"`
+	Storage bool   `json:"storage"`
+}
+
+// storageVersion returns the version the objects are stored as.
+func (crd *customResourceDefinition) storageVersion() string {
+	for _, version := range crd.Spec.Versions {
+		if version.Storage {
+			return version.Name
+		}
+	}
+	return ""
+}
+
+// servedVersions returns the versions served by the API server, in order.
+func (crd *customResourceDefinition) servedVersions() []string {
+	var versions []string
+	for _, version := range crd.Spec.Versions {
+		if version.Served {
+			versions = append(versions, version.Name)
+		}
+	}
+	return versions
+}
+
+// Checker provide functions for comparing the installed Longhorn CustomResourceDefinitions
+// against the ones of a Longhorn version.
+type Checker struct {
+	CheckerCmdOptions
+
+	dynamicClient *dynamic.DynamicClient
+
+	expected []customResourceDefinition
+
+	// Migrations required before upgrading to the version.
+	migrations []string
+}
+
+// CheckerCmdOptions holds the options for the command.
+type CheckerCmdOptions struct {
+	types.GlobalCmdOptions
+
+	Version      string // Longhorn version to compare against.
+	ManifestFile string // Path to the deployment manifest of the version, instead of downloading it.
+}
+
+// Validate validates the command options.
+func (remote *Checker) Validate() error {
+	if remote.Version == "" && remote.ManifestFile == "" {
+		return errors.Errorf("Longhorn version (--%s) or manifest file (--%s) is required", consts.CmdOptVersion, consts.CmdOptManifestFile)
+	}
+
+	if remote.Version != "" {
+		if _, err := semver.ParseTolerant(remote.Version); err != nil {
+			return errors.Wrapf(err, "invalid version %q", remote.Version)
+		}
+	}
+
+	return nil
+}
+
+// Init initializes the Checker, and loads the CustomResourceDefinitions of the version.
+func (remote *Checker) Init() error {
+	config, err := kubeutils.NewRestConfig("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+
+	remote.dynamicClient, err = dynamic.NewForConfig(config)
+	if err != nil {
+		return errors.Wrap(err, "failed to create dynamic client")
+	}
+
+	data, err := loadManifest(remote.Version, remote.ManifestFile)
+	if err != nil {
+		return err
+	}
+
+	remote.expected, err = parseCustomResourceDefinitions(data)
+	if err != nil {
+		return err
+	}
+	if len(remote.expected) == 0 {
+		return errors.New("no Longhorn CustomResourceDefinitions found in the manifest")
+	}
+	logrus.Debugf("Loaded %d Longhorn CustomResourceDefinitions", len(remote.expected))
+
+	return nil
+}
+
+// Collect compares the installed Longhorn CustomResourceDefinitions against the ones of the
+// version, and returns the result of each CustomResourceDefinition keyed by its name.
+func (remote *Checker) Collect() (map[string]*types.LogCollection, error) {
+	list, err := remote.dynamicClient.Resource(customResourceDefinitionResource).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list CustomResourceDefinitions")
+	}
+
+	var installed []customResourceDefinition
+	for _, item := range list.Items {
+		var crd customResourceDefinition
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(item.Object, &crd); err != nil {
+			return nil, errors.Wrapf(err, "failed to convert CustomResourceDefinition %v", item.GetName())
+		}
+		if crd.Spec.Group == longhornAPIGroup {
+			installed = append(installed, crd)
+		}
+	}
+
+	collections, migrations := compareCustomResourceDefinitions(remote.expected, installed, remote.versionName())
+	remote.migrations = migrations
+	return collections, nil
+}
+
+// RequiredMigrations returns the migrations required before upgrading to the version, in order.
+func (remote *Checker) RequiredMigrations() []string {
+	return remote.migrations
+}
+
+// Cleanup does nothing, since the Checker does not create any resource.
+func (remote *Checker) Cleanup() error {
+	return nil
+}
+
+// versionName returns the name of the compared version in the messages.
+func (remote *Checker) versionName() string {
+	if remote.Version == "" {
+		return "the manifest"
+	}
+	return "Longhorn " + normalizeVersion(remote.Version)
+}
+
+// loadManifest returns the manifest file, or the deployment manifest published with the
+// Longhorn version when no file is given.
+func loadManifest(version, manifestFile string) ([]byte, error) {
+	if manifestFile != "" {
+		data, err := os.ReadFile(manifestFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read manifest file %v", manifestFile)
+		}
+		return data, nil
+	}
+
+	version = normalizeVersion(version)
+	url := fmt.Sprintf(consts.LonghornManifestURL, version)
+	logrus.Debugf("Requesting %v", url)
+
+	httpClient := &http.Client{Timeout: httpTimeout}
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get the manifest of Longhorn %v", version)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("failed to get the manifest of Longhorn %v: %v returned %v", version, url, resp.Status)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read the manifest of Longhorn %v", version)
+	}
+	return data, nil
+}
+
+func normalizeVersion(version string) string {
+	if !strings.HasPrefix(version, "v") {
+		return "v" + version
+	}
+	return version
+}
+
+// parseCustomResourceDefinitions returns the Longhorn CustomResourceDefinitions of a multi-document
+// YAML manifest, skipping the other objects.
+func parseCustomResourceDefinitions(data []byte) ([]customResourceDefinition, error) {
+	var crds []customResourceDefinition
+
+	decoder := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+	for {
+		var crd customResourceDefinition
+		err := decoder.Decode(&crd)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse manifest")
+		}
+
+		if crd.Kind != kindCustomResourceDefinition || crd.Spec.Group != longhornAPIGroup {
+			continue
+		}
+		crds = append(crds, crd)
+	}
+
+	return crds, nil
+}
+
+// compareCustomResourceDefinitions compares the installed CustomResourceDefinitions against the
+// expected ones. It returns the result of each CustomResourceDefinition keyed by its name, and the
+// migrations required before upgrading, in the order of the names.
+func compareCustomResourceDefinitions(expected, installed []customResourceDefinition, versionName string) (map[string]*types.LogCollection, []string) {
+	collections := map[string]*types.LogCollection{}
+	migrationsByName := map[string]string{}
+
+	installedByName := map[string]*customResourceDefinition{}
+	for i := range installed {
+		installedByName[installed[i].Name] = &installed[i]
+	}
+
+	expectedNames := map[string]bool{}
+	for i := range expected {
+		want := &expected[i]
+		expectedNames[want.Name] = true
+
+		collection := &types.LogCollection{}
+		collections[want.Name] = collection
+
+		got, ok := installedByName[want.Name]
+		if !ok {
+			collection.Warn = append(collection.Warn, fmt.Sprintf("Not installed, it is introduced by %v and created when upgrading", versionName))
+			continue
+		}
+
+		storageVersion := want.storageVersion()
+		servedVersions := want.servedVersions()
+
+		var blocking, leftover []string
+		for _, storedVersion := range got.Status.StoredVersions {
+			switch {
+			case storedVersion == storageVersion:
+			case !slices.Contains(servedVersions, storedVersion):
+				blocking = append(blocking, storedVersion)
+			default:
+				leftover = append(leftover, storedVersion)
+			}
+		}
+
+		if len(blocking) > 0 {
+			collection.Error = append(collection.Error, fmt.Sprintf("Stored versions include %v, which %v no longer serves, the upgrade is blocked", strings.Join(blocking, ", "), versionName))
+		}
+		if len(leftover) > 0 {
+			collection.Warn = append(collection.Warn, fmt.Sprintf("Stored versions include %v beside the storage version %v", strings.Join(leftover, ", "), storageVersion))
+		}
+		if len(blocking) > 0 || len(leftover) > 0 {
+			migrationsByName[want.Name] = fmt.Sprintf("%v: rewrite all %v to store them as %v, then remove %v from status.storedVersions",
+				want.Name, want.Spec.Names.Plural, storageVersion, strings.Join(append(blocking, leftover...), ", "))
+		}
+
+		for _, servedVersion := range got.servedVersions() {
+			if !slices.Contains(servedVersions, servedVersion) {
+				collection.Warn = append(collection.Warn, fmt.Sprintf("Served version %v is removed in %v", servedVersion, versionName))
+			}
+		}
+
+		if got.storageVersion() != storageVersion {
+			collection.Info = append(collection.Info, fmt.Sprintf("Storage version changes from %v to %v", got.storageVersion(), storageVersion))
+		}
+
+		if len(collection.Error) == 0 && len(collection.Warn) == 0 {
+			collection.Info = append(collection.Info, fmt.Sprintf("Stored as %v, compatible with %v", storageVersion, versionName))
+		}
+	}
+
+	for _, got := range installed {
+		if expectedNames[got.Name] {
+			continue
+		}
+		collections[got.Name] = &types.LogCollection{
+			Warn: []string{fmt.Sprintf("Not part of %v, left by an earlier version", versionName)},
+		}
+	}
+
+	names := make([]string, 0, len(migrationsByName))
+	for name := range migrationsByName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	migrations := make([]string, 0, len(names))
+	for _, name := range names {
+		migrations = append(migrations, migrationsByName[name])
+	}
+	return collections, migrations
+}
@@ -0,0 +1,153 @@
+package crd
+
+import (
+	"testing"
+)
+
+const testManifest = `
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: longhorn-system
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: engineimages.longhorn.io
+spec:
+  group: longhorn.io
+  names:
+    plural: engineimages
+  versions:
+  - name: v1beta2
+    served: true
+    storage: true
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: volumes.longhorn.io
+spec:
+  group: longhorn.io
+  names:
+    plural: volumes
+  versions:
+  - name: v1beta1
+    served: true
+    storage: false
+  - name: v1beta2
+    served: true
+    storage: true
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  group: example.com
+`
+
+func TestParseCustomResourceDefinitions(t *testing.T) {
+	crds, err := parseCustomResourceDefinitions([]byte(testManifest))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(crds) != 2 {
+		t.Fatalf("expected 2 Longhorn CustomResourceDefinitions, got %d", len(crds))
+	}
+	if crds[1].Name != "volumes.longhorn.io" || crds[1].storageVersion() != "v1beta2" || len(crds[1].servedVersions()) != 2 {
+		t.Errorf("unexpected CustomResourceDefinition %+v", crds[1])
+	}
+}
+
+func TestCompareCustomResourceDefinitions(t *testing.T) {
+	expected, err := parseCustomResourceDefinitions([]byte(testManifest))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	newDefinition := func(name string, storedVersions []string, versions ...string) customResourceDefinition {
+		var crd customResourceDefinition
+		crd.Name = name
+		crd.Spec.Group = longhornAPIGroup
+		for i, version := range versions {
+			crd.Spec.Versions = append(crd.Spec.Versions, customResourceDefinitionVersion{Name: version, Served: true, Storage: i == len(versions)-1})
+		}
+		crd.Status.StoredVersions = storedVersions
+		return crd
+	}
+
+	tests := map[string]struct {
+		installed          []customResourceDefinition
+		expectedErrors     map[string]int
+		expectedWarns      map[string]int
+		expectedMigrations int
+	}{
+		"up to date": {
+			installed: []customResourceDefinition{
+				newDefinition("engineimages.longhorn.io", []string{"v1beta2"}, "v1beta2"),
+				newDefinition("volumes.longhorn.io", []string{"v1beta2"}, "v1beta1", "v1beta2"),
+			},
+			expectedErrors: map[string]int{"engineimages.longhorn.io": 0, "volumes.longhorn.io": 0},
+			expectedWarns:  map[string]int{"engineimages.longhorn.io": 0, "volumes.longhorn.io": 0},
+		},
+		"missing": {
+			installed: []customResourceDefinition{
+				newDefinition("volumes.longhorn.io", []string{"v1beta2"}, "v1beta1", "v1beta2"),
+			},
+			expectedErrors: map[string]int{"engineimages.longhorn.io": 0},
+			expectedWarns:  map[string]int{"engineimages.longhorn.io": 1},
+		},
+		"stored version no longer served": {
+			installed: []customResourceDefinition{
+				newDefinition("engineimages.longhorn.io", []string{"v1beta1", "v1beta2"}, "v1beta1", "v1beta2"),
+				newDefinition("volumes.longhorn.io", []string{"v1beta2"}, "v1beta1", "v1beta2"),
+			},
+			expectedErrors:     map[string]int{"engineimages.longhorn.io": 1},
+			expectedWarns:      map[string]int{"engineimages.longhorn.io": 1},
+			expectedMigrations: 1,
+		},
+		"leftover stored version": {
+			installed: []customResourceDefinition{
+				newDefinition("engineimages.longhorn.io", []string{"v1beta2"}, "v1beta2"),
+				newDefinition("volumes.longhorn.io", []string{"v1beta1", "v1beta2"}, "v1beta1", "v1beta2"),
+			},
+			expectedErrors:     map[string]int{"volumes.longhorn.io": 0},
+			expectedWarns:      map[string]int{"volumes.longhorn.io": 1},
+			expectedMigrations: 1,
+		},
+		"not part of the version": {
+			installed: []customResourceDefinition{
+				newDefinition("engineimages.longhorn.io", []string{"v1beta2"}, "v1beta2"),
+				newDefinition("volumes.longhorn.io", []string{"v1beta2"}, "v1beta1", "v1beta2"),
+				newDefinition("engineupgrades.longhorn.io", []string{"v1beta1"}, "v1beta1"),
+			},
+			expectedErrors: map[string]int{"engineupgrades.longhorn.io": 0},
+			expectedWarns:  map[string]int{"engineupgrades.longhorn.io": 1},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			collections, migrations := compareCustomResourceDefinitions(expected, test.installed, "Longhorn v1.8.0")
+
+			for crdName, count := range test.expectedErrors {
+				if collections[crdName] == nil {
+					t.Fatalf("expected result for %v", crdName)
+				}
+				if len(collections[crdName].Error) != count {
+					t.Errorf("expected %d errors for %v, got %v", count, crdName, collections[crdName].Error)
+				}
+			}
+			for crdName, count := range test.expectedWarns {
+				if len(collections[crdName].Warn) != count {
+					t.Errorf("expected %d warnings for %v, got %v", count, crdName, collections[crdName].Warn)
+				}
+			}
+			if len(migrations) != test.expectedMigrations {
+				t.Errorf("expected %d migrations, got %v", test.expectedMigrations, migrations)
+			}
+		})
+	}
+}
@@ -0,0 +1,273 @@
+package device
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/utils/ptr"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeclient "k8s.io/client-go/kubernetes"
+
+	commonkube "github.com/longhorn/go-common-libs/kubernetes"
+
+	longhorn "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+	lhclient "github.com/longhorn/longhorn-manager/k8s/pkg/client/clientset/versioned"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// Cleaner provide functions for cleaning up the leftover iSCSI sessions, dm-crypt mappings and
+// Longhorn block devices of a node.
+type Cleaner struct {
+	CleanerCmdOptions
+
+	kubeClient     *kubeclient.Clientset
+	longhornClient *lhclient.Clientset
+
+	appName   string // App name of the DaemonSet.
+	namespace string
+}
+
+// CleanerCmdOptions holds the options for the command.
+type CleanerCmdOptions struct {
+	types.GlobalCmdOptions
+
+	LonghornNamespace string
+	NodeID            string
+	DryRun            bool // Only report the devices to clean up.
+}
+
+// Validate validates the command options.
+func (remote *Cleaner) Validate() error {
+	if remote.NodeID == "" {
+		return errors.Errorf("Node name (--%s) is required", consts.CmdOptNode)
+	}
+
+	return nil
+}
+
+// Init initializes the Cleaner.
+func (remote *Cleaner) Init() error {
+	kubeClient, err := kubeutils.NewKubeClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.kubeClient = kubeClient
+
+	longhornClient, err := kubeutils.NewLonghornClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.longhornClient = longhornClient
+
+	remote.namespace = remote.Namespace
+	if remote.namespace == "" {
+		remote.namespace = consts.LonghornNamespace
+	}
+	remote.appName = consts.AppNameNodeDeviceCleaner
+
+	if _, err := remote.kubeClient.CoreV1().Nodes().Get(context.Background(), remote.NodeID, metav1.GetOptions{}); err != nil {
+		return errors.Wrapf(err, "failed to get node %v", remote.NodeID)
+	}
+
+	return nil
+}
+
+// Run creates the DaemonSet cleaning up the devices on the node, and returns its result keyed by
+// the node. The volumes attached or being attached to the node are passed to the DaemonSet, so
+// their devices are kept.
+func (remote *Cleaner) Run() (map[string]*types.LogCollection, error) {
+	volumeList, err := remote.longhornClient.LonghornV1beta2().Volumes(remote.LonghornNamespace).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list volumes")
+	}
+	volumeNames, activeVolumeNames := getNodeVolumeNames(remote.NodeID, volumeList.Items)
+
+	newDaemonSet := remote.newDaemonSet(volumeNames, activeVolumeNames)
+	kubeutils.SetNodeNameAffinity(&newDaemonSet.Spec.Template.Spec, []string{remote.NodeID})
+	if err := kubeutils.SetPodOptions(&newDaemonSet.Spec.Template.Spec, &remote.GlobalCmdOptions); err != nil {
+		return nil, err
+	}
+
+	_, err = kubeutils.CreateNamespace(remote.kubeClient, remote.namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	kubeutils.SetRunMetadata(remote.kubeClient, newDaemonSet)
+	kubeutils.LogManifest(newDaemonSet)
+	daemonSet, err := kubeutils.CreateDaemonSet(remote.kubeClient, newDaemonSet, remote.MinNodes)
+	if err != nil {
+		return nil, err
+	}
+
+	err = kubeutils.MonitorDaemonSetContainer(remote.kubeClient, daemonSet, consts.ContainerNameInit, kubeutils.WaitForDaemonSetContainersExit, ptr.To(consts.ContainerConditionMaxTolerationMedium))
+	if err != nil {
+		return nil, err
+	}
+
+	err = kubeutils.MonitorDaemonSetContainer(remote.kubeClient, daemonSet, consts.ContainerNameOutput, kubeutils.WaitForDaemonSetContainersExit, ptr.To(consts.ContainerConditionMaxTolerationShort))
+	if err != nil {
+		return nil, err
+	}
+
+	podCollections, err := kubeutils.GetDaemonSetPodCollections(remote.kubeClient, daemonSet, consts.ContainerNameOutput, false, false, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	collections := map[string]*types.LogCollection{}
+	for _, podCollection := range podCollections.Pods {
+		var nodeCollection types.NodeCollection
+		if err := json.Unmarshal([]byte(podCollection.Log), &nodeCollection); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse the result of node %v", podCollection.Node)
+		}
+		if nodeCollection.Log != nil {
+			collections["Node/"+podCollection.Node] = nodeCollection.Log
+		}
+	}
+	if _, ok := collections["Node/"+remote.NodeID]; !ok {
+		collections["Node/"+remote.NodeID] = &types.LogCollection{Error: []string{"No result was collected from the node"}}
+	}
+
+	return collections, nil
+}
+
+// Cleanup deletes the DaemonSet created for cleaning up the devices.
+func (remote *Cleaner) Cleanup() error {
+	return commonkube.DeleteDaemonSet(remote.kubeClient, remote.namespace, remote.appName)
+}
+
+// getNodeVolumeNames returns the names of all the volumes, and of the volumes whose engine is or
+// may soon be on the node. A volume that is not detached is active on the node when it is attached
+// to the node, or requested to be.
+func getNodeVolumeNames(nodeName string, volumes []longhorn.Volume) (volumeNames, activeVolumeNames []string) {
+	volumeNames = []string{}
+	activeVolumeNames = []string{}
+	for _, volume := range volumes {
+		volumeNames = append(volumeNames, volume.Name)
+
+		if volume.Status.State == longhorn.VolumeStateDetached && volume.Spec.NodeID == "" {
+			continue
+		}
+		if volume.Status.CurrentNodeID == nodeName || volume.Spec.NodeID == nodeName {
+			activeVolumeNames = append(activeVolumeNames, volume.Name)
+		}
+	}
+
+	sort.Strings(volumeNames)
+	sort.Strings(activeVolumeNames)
+	return volumeNames, activeVolumeNames
+}
+
+// newDaemonSet prepares the DaemonSet cleaning up the devices. The pod shares the PID namespace of
+// the host, so iscsiadm and dmsetup run in the host namespaces where the devices are.
+func (remote *Cleaner) newDaemonSet(volumeNames, activeVolumeNames []string) *appsv1.DaemonSet {
+	outputFilePath := filepath.Join(consts.VolumeMountSharedDirectory, consts.FileNameOutputJSON)
+
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      remote.appName,
+			Namespace: remote.namespace,
+			Labels: map[string]string{
+				"app":                 remote.appName,
+				consts.LabelManagedBy: consts.LabelValueManagedBy,
+			},
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app": remote.appName,
+				},
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						"app":                 remote.appName,
+						consts.LabelManagedBy: consts.LabelValueManagedBy,
+					},
+				},
+				Spec: corev1.PodSpec{
+					HostPID: true,
+					InitContainers: []corev1.Container{
+						{
+							Name:    consts.ContainerNameInit,
+							Image:   remote.Image,
+							Command: []string{consts.CmdLonghornctlLocal, consts.SubCmdCleanup, consts.SubCmdNodeDevices},
+							Env: []corev1.EnvVar{
+								{
+									Name:  consts.EnvLogLevel,
+									Value: remote.LogLevel,
+								},
+								{
+									Name:  consts.EnvOutputFilePath,
+									Value: outputFilePath,
+								},
+								{
+									Name:  consts.EnvDryRun,
+									Value: strconv.FormatBool(remote.DryRun),
+								},
+								{
+									Name:  consts.EnvLonghornVolumeNames,
+									Value: strings.Join(volumeNames, consts.CmdOptSeperator),
+								},
+								{
+									Name:  consts.EnvLonghornActiveVolumes,
+									Value: strings.Join(activeVolumeNames, consts.CmdOptSeperator),
+								},
+							},
+							SecurityContext: kubeutils.NewSecurityContext(remote.Privileged, kubeutils.CapabilitiesHostNamespaces),
+							VolumeMounts: []corev1.VolumeMount{
+								{
+									Name:      consts.VolumeMountSharedName,
+									MountPath: consts.VolumeMountSharedDirectory,
+								},
+							},
+						},
+						{
+							Name:    consts.ContainerNameOutput,
+							Image:   remote.Image,
+							Command: []string{"cat", outputFilePath},
+							Env:     []corev1.EnvVar{},
+							VolumeMounts: []corev1.VolumeMount{
+								{
+									Name:      consts.VolumeMountSharedName,
+									MountPath: consts.VolumeMountSharedDirectory,
+								},
+							},
+						},
+					},
+					Containers: []corev1.Container{
+						{
+							Name:  consts.ContainerNamePause,
+							Image: consts.ImagePause,
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: consts.VolumeMountSharedName,
+							VolumeSource: corev1.VolumeSource{
+								EmptyDir: &corev1.EmptyDirVolumeSource{},
+							},
+						},
+					},
+				},
+			},
+			UpdateStrategy: appsv1.DaemonSetUpdateStrategy{
+				Type: appsv1.RollingUpdateDaemonSetStrategyType,
+			},
+		},
+	}
+}
@@ -0,0 +1,356 @@
+package device
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/utils/ptr"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	kubeclient "k8s.io/client-go/kubernetes"
+
+	commonkube "github.com/longhorn/go-common-libs/kubernetes"
+
+	longhorn "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+	lhclient "github.com/longhorn/longhorn-manager/k8s/pkg/client/clientset/versioned"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// containerCreatingReason is the waiting reason of the containers of a pod whose volumes are being
+// set up.
+const containerCreatingReason = "ContainerCreating"
+
+// MountChecker provide functions for finding the mounts of the Longhorn volume devices left on the
+// nodes the volumes are no longer attached to, and the pods stuck on them.
+type MountChecker struct {
+	MountCheckerCmdOptions
+
+	kubeClient     *kubeclient.Clientset
+	longhornClient *lhclient.Clientset
+
+	appName   string // App name of the DaemonSet.
+	namespace string
+}
+
+// MountCheckerCmdOptions holds the options for the command.
+type MountCheckerCmdOptions struct {
+	types.GlobalCmdOptions
+
+	LonghornNamespace string
+	Repair            bool // Unmount the stale mounts.
+}
+
+// Validate validates the command options.
+func (remote *MountChecker) Validate() error {
+	return nil
+}
+
+// Init initializes the MountChecker.
+func (remote *MountChecker) Init() error {
+	kubeClient, err := kubeutils.NewKubeClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.kubeClient = kubeClient
+
+	longhornClient, err := kubeutils.NewLonghornClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.longhornClient = longhornClient
+
+	remote.namespace = remote.Namespace
+	if remote.namespace == "" {
+		remote.namespace = consts.LonghornNamespace
+	}
+	remote.appName = consts.AppNameMountChecker
+
+	return nil
+}
+
+// Run creates the DaemonSet finding the stale mounts on the nodes, and returns its result keyed by
+// the node, with the pods stuck on the stale mounts keyed by the pod. The attachments of the
+// volumes are passed to the DaemonSet, so each node keeps the mounts of the volumes attached to it.
+func (remote *MountChecker) Run() (map[string]*types.LogCollection, error) {
+	volumeList, err := remote.longhornClient.LonghornV1beta2().Volumes(remote.LonghornNamespace).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list volumes")
+	}
+	volumeNames, volumeAttachments := getVolumeAttachments(volumeList.Items)
+
+	nodeSelector, err := kubeutils.ParseNodeSelector(remote.NodeSelector)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %q argument", consts.CmdOptNodeSelector)
+	}
+	newDaemonSet := remote.newDaemonSet(nodeSelector, volumeNames, volumeAttachments)
+	if err := kubeutils.SetPodOptions(&newDaemonSet.Spec.Template.Spec, &remote.GlobalCmdOptions); err != nil {
+		return nil, err
+	}
+
+	_, err = kubeutils.CreateNamespace(remote.kubeClient, remote.namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	kubeutils.SetRunMetadata(remote.kubeClient, newDaemonSet)
+	kubeutils.LogManifest(newDaemonSet)
+	daemonSet, err := kubeutils.CreateDaemonSet(remote.kubeClient, newDaemonSet, remote.MinNodes)
+	if err != nil {
+		return nil, err
+	}
+
+	err = kubeutils.MonitorDaemonSetContainer(remote.kubeClient, daemonSet, consts.ContainerNameInit, kubeutils.WaitForDaemonSetContainersExit, ptr.To(consts.ContainerConditionMaxTolerationMedium))
+	if err != nil {
+		return nil, err
+	}
+
+	err = kubeutils.MonitorDaemonSetContainer(remote.kubeClient, daemonSet, consts.ContainerNameOutput, kubeutils.WaitForDaemonSetContainersExit, ptr.To(consts.ContainerConditionMaxTolerationShort))
+	if err != nil {
+		return nil, err
+	}
+
+	podCollections, err := kubeutils.GetDaemonSetPodCollections(remote.kubeClient, daemonSet, consts.ContainerNameOutput, false, false, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	collections := map[string]*types.LogCollection{}
+	for _, podCollection := range podCollections.Pods {
+		var nodeCollection types.NodeCollection
+		if err := json.Unmarshal([]byte(podCollection.Log), &nodeCollection); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse the result of node %v", podCollection.Node)
+		}
+		if nodeCollection.Log != nil {
+			collections["Node/"+podCollection.Node] = nodeCollection.Log
+		}
+		if len(nodeCollection.StaleMounts) == 0 {
+			continue
+		}
+
+		podList, err := remote.kubeClient.CoreV1().Pods(metav1.NamespaceAll).List(context.Background(), metav1.ListOptions{
+			FieldSelector: fields.OneTermEqualSelector("spec.nodeName", podCollection.Node).String(),
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to list pods on node %v", podCollection.Node)
+		}
+		for key, collection := range findStuckPods(podCollection.Node, nodeCollection.StaleMounts, podList.Items, volumeList.Items) {
+			collections[key] = collection
+		}
+	}
+
+	return collections, nil
+}
+
+// Cleanup deletes the DaemonSet created for finding the stale mounts.
+func (remote *MountChecker) Cleanup() error {
+	return commonkube.DeleteDaemonSet(remote.kubeClient, remote.namespace, remote.appName)
+}
+
+// getVolumeAttachments returns the names of all the volumes, and the nodes each volume that is not
+// detached is attached to, or requested to be, as <node>:<volume>. A migrating volume is attached
+// to both of its nodes.
+func getVolumeAttachments(volumes []longhorn.Volume) (volumeNames, volumeAttachments []string) {
+	volumeNames = []string{}
+	volumeAttachments = []string{}
+	for _, volume := range volumes {
+		volumeNames = append(volumeNames, volume.Name)
+
+		if volume.Status.State == longhorn.VolumeStateDetached && volume.Spec.NodeID == "" {
+			continue
+		}
+		nodes := map[string]bool{}
+		for _, node := range []string{volume.Status.CurrentNodeID, volume.Spec.NodeID, volume.Spec.MigrationNodeID} {
+			if node != "" && !nodes[node] {
+				nodes[node] = true
+				volumeAttachments = append(volumeAttachments, node+":"+volume.Name)
+			}
+		}
+	}
+
+	sort.Strings(volumeNames)
+	sort.Strings(volumeAttachments)
+	return volumeNames, volumeAttachments
+}
+
+// findStuckPods returns the pods on the node stuck on its stale mounts, keyed by the pod: the pods
+// the stale mounts are published to, and the pods waiting in ContainerCreating for the
+// PersistentVolumeClaim of a volume with a stale mount.
+func findStuckPods(nodeName string, staleMounts []types.StaleMount, pods []corev1.Pod, volumes []longhorn.Volume) map[string]*types.LogCollection {
+	podMounts := map[string][]types.StaleMount{}
+	staleVolumes := map[string]bool{}
+	for _, mount := range staleMounts {
+		if mount.PodUID != "" {
+			podMounts[mount.PodUID] = append(podMounts[mount.PodUID], mount)
+		}
+		staleVolumes[mount.Volume] = true
+	}
+
+	claimVolumes := map[string]string{}
+	for _, volume := range volumes {
+		status := volume.Status.KubernetesStatus
+		if staleVolumes[volume.Name] && status.PVCName != "" {
+			claimVolumes[status.Namespace+"/"+status.PVCName] = volume.Name
+		}
+	}
+
+	collections := map[string]*types.LogCollection{}
+	for _, pod := range pods {
+		messages := []string{}
+		for _, mount := range podMounts[string(pod.UID)] {
+			messages = append(messages, fmt.Sprintf("Pod has stale mount %v of volume %v on node %v", mount.MountPoint, mount.Volume, nodeName))
+		}
+		if isContainerCreating(&pod) {
+			for _, podVolume := range pod.Spec.Volumes {
+				if podVolume.PersistentVolumeClaim == nil {
+					continue
+				}
+				if volume, ok := claimVolumes[pod.Namespace+"/"+podVolume.PersistentVolumeClaim.ClaimName]; ok {
+					messages = append(messages, fmt.Sprintf("Pod is stuck in %v on volume %v, which has a stale mount on node %v", containerCreatingReason, volume, nodeName))
+				}
+			}
+		}
+		if len(messages) > 0 {
+			collections[fmt.Sprintf("Pod/%v/%v", pod.Namespace, pod.Name)] = &types.LogCollection{Warn: messages}
+		}
+	}
+	return collections
+}
+
+// isContainerCreating returns true when a container of the pod is waiting for the pod setup, which
+// includes mounting its volumes.
+func isContainerCreating(pod *corev1.Pod) bool {
+	if pod.Status.Phase != corev1.PodPending {
+		return false
+	}
+	for _, statuses := range [][]corev1.ContainerStatus{pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses} {
+		for _, status := range statuses {
+			if status.State.Waiting != nil && status.State.Waiting.Reason == containerCreatingReason {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// newDaemonSet prepares the DaemonSet finding the stale mounts. The pod shares the PID namespace of
+// the host, so the mount table and umount are those of the host mount namespace.
+func (remote *MountChecker) newDaemonSet(nodeSelector map[string]string, volumeNames, volumeAttachments []string) *appsv1.DaemonSet {
+	outputFilePath := filepath.Join(consts.VolumeMountSharedDirectory, consts.FileNameOutputJSON)
+
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      remote.appName,
+			Namespace: remote.namespace,
+			Labels: map[string]string{
+				"app":                 remote.appName,
+				consts.LabelManagedBy: consts.LabelValueManagedBy,
+			},
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app": remote.appName,
+				},
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						"app":                 remote.appName,
+						consts.LabelManagedBy: consts.LabelValueManagedBy,
+					},
+				},
+				Spec: corev1.PodSpec{
+					HostPID: true,
+					InitContainers: []corev1.Container{
+						{
+							Name:    consts.ContainerNameInit,
+							Image:   remote.Image,
+							Command: []string{consts.CmdLonghornctlLocal, consts.SubCmdCheck, consts.SubCmdMounts},
+							Env: []corev1.EnvVar{
+								{
+									Name: consts.EnvCurrentNodeID,
+									ValueFrom: &corev1.EnvVarSource{
+										FieldRef: &corev1.ObjectFieldSelector{
+											FieldPath: "spec.nodeName",
+										},
+									},
+								},
+								{
+									Name:  consts.EnvLogLevel,
+									Value: remote.LogLevel,
+								},
+								{
+									Name:  consts.EnvOutputFilePath,
+									Value: outputFilePath,
+								},
+								{
+									Name:  consts.EnvRepair,
+									Value: strconv.FormatBool(remote.Repair),
+								},
+								{
+									Name:  consts.EnvLonghornVolumeNames,
+									Value: strings.Join(volumeNames, consts.CmdOptSeperator),
+								},
+								{
+									Name:  consts.EnvLonghornVolumeAttachments,
+									Value: strings.Join(volumeAttachments, consts.CmdOptSeperator),
+								},
+							},
+							SecurityContext: kubeutils.NewSecurityContext(remote.Privileged, kubeutils.CapabilitiesHostNamespaces),
+							VolumeMounts: []corev1.VolumeMount{
+								{
+									Name:      consts.VolumeMountSharedName,
+									MountPath: consts.VolumeMountSharedDirectory,
+								},
+							},
+						},
+						{
+							Name:    consts.ContainerNameOutput,
+							Image:   remote.Image,
+							Command: []string{"cat", outputFilePath},
+							Env:     []corev1.EnvVar{},
+							VolumeMounts: []corev1.VolumeMount{
+								{
+									Name:      consts.VolumeMountSharedName,
+									MountPath: consts.VolumeMountSharedDirectory,
+								},
+							},
+						},
+					},
+					Containers: []corev1.Container{
+						{
+							Name:  consts.ContainerNamePause,
+							Image: consts.ImagePause,
+						},
+					},
+					NodeSelector: nodeSelector,
+					Volumes: []corev1.Volume{
+						{
+							Name: consts.VolumeMountSharedName,
+							VolumeSource: corev1.VolumeSource{
+								EmptyDir: &corev1.EmptyDirVolumeSource{},
+							},
+						},
+					},
+				},
+			},
+			UpdateStrategy: appsv1.DaemonSetUpdateStrategy{
+				Type: appsv1.RollingUpdateDaemonSetStrategyType,
+			},
+		},
+	}
+}
@@ -0,0 +1,75 @@
+package device
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+
+	longhorn "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func TestGetVolumeAttachments(t *testing.T) {
+	volumes := []longhorn.Volume{
+		{ObjectMeta: metav1.ObjectMeta{Name: "vol-2"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "vol-1"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "vol-3"}},
+	}
+	volumes[0].Status.State = longhorn.VolumeStateDetached
+	volumes[1].Status.State = longhorn.VolumeStateAttached
+	volumes[1].Status.CurrentNodeID = "node-1"
+	volumes[1].Spec.NodeID = "node-1"
+	volumes[1].Spec.MigrationNodeID = "node-2"
+	volumes[2].Status.State = longhorn.VolumeStateAttaching
+	volumes[2].Spec.NodeID = "node-3"
+
+	volumeNames, volumeAttachments := getVolumeAttachments(volumes)
+	if expected := []string{"vol-1", "vol-2", "vol-3"}; !reflect.DeepEqual(volumeNames, expected) {
+		t.Fatalf("expected volume names %v, got %v", expected, volumeNames)
+	}
+	if expected := []string{"node-1:vol-1", "node-2:vol-1", "node-3:vol-3"}; !reflect.DeepEqual(volumeAttachments, expected) {
+		t.Fatalf("expected volume attachments %v, got %v", expected, volumeAttachments)
+	}
+}
+
+func TestFindStuckPods(t *testing.T) {
+	staleMounts := []types.StaleMount{
+		{Volume: "vol-1", MountPoint: "/var/lib/kubelet/pods/uid-1/volumes/kubernetes.io~csi/vol-1/mount", PodUID: "uid-1"},
+		{Volume: "vol-1", MountPoint: "/var/lib/kubelet/plugins/kubernetes.io/csi/driver.longhorn.io/3c0e/globalmount"},
+	}
+
+	volume := longhorn.Volume{ObjectMeta: metav1.ObjectMeta{Name: "vol-1"}}
+	volume.Status.KubernetesStatus.Namespace = "default"
+	volume.Status.KubernetesStatus.PVCName = "data"
+
+	newPod := func(name, uid string, phase corev1.PodPhase, waitingReason, claimName string) corev1.Pod {
+		pod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, UID: k8stypes.UID("uid-" + uid)}}
+		pod.Status.Phase = phase
+		if waitingReason != "" {
+			pod.Status.ContainerStatuses = []corev1.ContainerStatus{{State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: waitingReason}}}}
+		}
+		if claimName != "" {
+			pod.Spec.Volumes = []corev1.Volume{{VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: claimName}}}}
+		}
+		return pod
+	}
+	pods := []corev1.Pod{
+		newPod("terminating", "1", corev1.PodRunning, "", "data"),
+		newPod("creating", "2", corev1.PodPending, containerCreatingReason, "data"),
+		newPod("pulling", "3", corev1.PodPending, "ImagePullBackOff", "data"),
+		newPod("other-claim", "4", corev1.PodPending, containerCreatingReason, "logs"),
+	}
+
+	collections := findStuckPods("node-1", staleMounts, pods, []longhorn.Volume{volume})
+	expected := map[string]*types.LogCollection{
+		"Pod/default/terminating": {Warn: []string{"Pod has stale mount /var/lib/kubelet/pods/uid-1/volumes/kubernetes.io~csi/vol-1/mount of volume vol-1 on node node-1"}},
+		"Pod/default/creating":    {Warn: []string{"Pod is stuck in ContainerCreating on volume vol-1, which has a stale mount on node node-1"}},
+	}
+	if !reflect.DeepEqual(collections, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, collections)
+	}
+}
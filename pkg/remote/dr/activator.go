@@ -0,0 +1,150 @@
+package dr
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	longhorn "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+	lhclient "github.com/longhorn/longhorn-manager/k8s/pkg/client/clientset/versioned"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// activatorPollInterval is the interval between the checks of the backup volume and the DR volume.
+const activatorPollInterval = 2 * time.Second
+
+// Activator provide functions for activating a DR volume during a failover.
+type Activator struct {
+	ActivatorCmdOptions
+
+	longhornClient *lhclient.Clientset
+
+	backupTargetName string
+	backupVolumeName string
+}
+
+// ActivatorCmdOptions holds the options for the command.
+type ActivatorCmdOptions struct {
+	types.GlobalCmdOptions
+
+	LonghornNamespace string
+	VolumeName        string
+	Frontend          string        // Frontend of the volume once activated.
+	Timeout           time.Duration // Maximum time to wait for each step.
+}
+
+// Validate validates the command options.
+func (remote *Activator) Validate() error {
+	if remote.VolumeName == "" {
+		return errors.New("DR volume name is required")
+	}
+
+	switch longhorn.VolumeFrontend(remote.Frontend) {
+	case longhorn.VolumeFrontendBlockDev, longhorn.VolumeFrontendISCSI, longhorn.VolumeFrontendNvmf, longhorn.VolumeFrontendUblk:
+	default:
+		return errors.Errorf("unsupported frontend %q (--%s)", remote.Frontend, consts.CmdOptFrontend)
+	}
+
+	return nil
+}
+
+// Init initializes the Activator. It ensures the volume is a DR volume.
+func (remote *Activator) Init() error {
+	longhornClient, err := kubeutils.NewLonghornClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.longhornClient = longhornClient
+
+	volume, err := remote.longhornClient.LonghornV1beta2().Volumes(remote.LonghornNamespace).Get(context.Background(), remote.VolumeName, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to get volume %v", remote.VolumeName)
+	}
+	if !volume.Spec.Standby {
+		return errors.Errorf("volume %v is not a DR volume, or is already activated", remote.VolumeName)
+	}
+
+	_, remote.backupVolumeName, err = parseBackupURL(volume.Spec.FromBackup)
+	if err != nil {
+		return errors.Wrapf(err, "failed to find the backup volume of DR volume %v", remote.VolumeName)
+	}
+	remote.backupTargetName = backupTargetNameOrDefault(volume.Spec.BackupTargetName)
+
+	return nil
+}
+
+// Run activates the DR volume. The backup volume is synchronized first, so Longhorn restores the
+// latest backup of the backup target before the volume leaves standby.
+func (remote *Activator) Run(ctx context.Context) (*types.DrVolumeStatus, error) {
+	backupVolume, err := remote.syncBackupVolume(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	volume, err := remote.longhornClient.LonghornV1beta2().Volumes(remote.LonghornNamespace).Get(ctx, remote.VolumeName, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get volume %v", remote.VolumeName)
+	}
+	status := newDrVolumeStatus(volume, remote.backupVolumeName, backupVolume)
+	logrus.Infof("DR volume %v restored backup %v, latest backup is %v, lag is %v", remote.VolumeName, status.LastRestoredBackup, status.LatestBackup, status.Lag)
+
+	logrus.Infof("Activating DR volume %v with frontend %v", remote.VolumeName, remote.Frontend)
+	volume.Spec.Standby = false
+	volume.Spec.Frontend = longhorn.VolumeFrontend(remote.Frontend)
+	if _, err := remote.longhornClient.LonghornV1beta2().Volumes(remote.LonghornNamespace).Update(ctx, volume, metav1.UpdateOptions{}); err != nil {
+		return nil, errors.Wrapf(err, "failed to activate DR volume %v", remote.VolumeName)
+	}
+
+	logrus.Infof("Waiting for DR volume %v to restore the latest backup and leave standby", remote.VolumeName)
+	err = wait.PollUntilContextTimeout(ctx, activatorPollInterval, remote.Timeout, true, func(ctx context.Context) (bool, error) {
+		volume, err = remote.longhornClient.LonghornV1beta2().Volumes(remote.LonghornNamespace).Get(ctx, remote.VolumeName, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		return !volume.Status.IsStandby, nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "DR volume %v did not leave standby within %v, Longhorn keeps restoring it", remote.VolumeName, remote.Timeout)
+	}
+
+	status = newDrVolumeStatus(volume, remote.backupVolumeName, backupVolume)
+	return &status, nil
+}
+
+// syncBackupVolume requests the synchronization of the backup volume with the backup target,
+// waits for it, and returns the synchronized backup volume.
+func (remote *Activator) syncBackupVolume(ctx context.Context) (*longhorn.BackupVolume, error) {
+	backupVolume, err := getBackupVolume(ctx, remote.longhornClient, remote.LonghornNamespace, remote.backupTargetName, remote.backupVolumeName)
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.Infof("Synchronizing backup volume %v with backup target %v", remote.backupVolumeName, remote.backupTargetName)
+	requestedAt := metav1.Now().Rfc3339Copy()
+	backupVolume.Spec.SyncRequestedAt = requestedAt
+	if _, err := remote.longhornClient.LonghornV1beta2().BackupVolumes(remote.LonghornNamespace).Update(ctx, backupVolume, metav1.UpdateOptions{}); err != nil {
+		return nil, errors.Wrapf(err, "failed to request synchronization of backup volume %v", remote.backupVolumeName)
+	}
+
+	err = wait.PollUntilContextTimeout(ctx, activatorPollInterval, remote.Timeout, true, func(ctx context.Context) (bool, error) {
+		backupVolume, err = remote.longhornClient.LonghornV1beta2().BackupVolumes(remote.LonghornNamespace).Get(ctx, backupVolume.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		return !backupVolume.Status.LastSyncedAt.Before(&requestedAt), nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "backup volume %v was not synchronized within %v", remote.backupVolumeName, remote.Timeout)
+	}
+
+	return backupVolume, nil
+}
@@ -0,0 +1,137 @@
+package dr
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	longhorn "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+	lhclient "github.com/longhorn/longhorn-manager/k8s/pkg/client/clientset/versioned"
+	lhmgrtypes "github.com/longhorn/longhorn-manager/types"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// Creator provide functions for creating a DR volume restoring the backups of a volume from a
+// backup target.
+type Creator struct {
+	CreatorCmdOptions
+
+	longhornClient *lhclient.Clientset
+
+	backup *longhorn.Backup // Latest backup of the backup volume, restored first.
+}
+
+// CreatorCmdOptions holds the options for the command.
+type CreatorCmdOptions struct {
+	types.GlobalCmdOptions
+
+	LonghornNamespace string
+	VolumeName        string // Name of the DR volume.
+	BackupTarget      string
+	BackupVolume      string // Name of the backed up volume in the backup target.
+	NumberOfReplicas  int    // Defaults to the default replica count setting of Longhorn.
+}
+
+// Validate validates the command options.
+func (remote *Creator) Validate() error {
+	if remote.VolumeName == "" {
+		return errors.New("DR volume name is required")
+	}
+	if remote.BackupVolume == "" {
+		return errors.Errorf("backup volume (--%s) is required", consts.CmdOptBackupVolume)
+	}
+	if remote.NumberOfReplicas < 0 {
+		return errors.Errorf("number of replicas (--%s) must not be negative", consts.CmdOptNumberOfReplicas)
+	}
+
+	return nil
+}
+
+// Init initializes the Creator. It ensures the volume does not exist and the backup target is
+// available, and finds the latest completed backup of the backup volume.
+func (remote *Creator) Init() error {
+	longhornClient, err := kubeutils.NewLonghornClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.longhornClient = longhornClient
+
+	ctx := context.Background()
+
+	_, err = remote.longhornClient.LonghornV1beta2().Volumes(remote.LonghornNamespace).Get(ctx, remote.VolumeName, metav1.GetOptions{})
+	if err == nil {
+		return errors.Errorf("volume %v already exists", remote.VolumeName)
+	}
+	if !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to get volume %v", remote.VolumeName)
+	}
+
+	remote.BackupTarget = backupTargetNameOrDefault(remote.BackupTarget)
+	backupTarget, err := remote.longhornClient.LonghornV1beta2().BackupTargets(remote.LonghornNamespace).Get(ctx, remote.BackupTarget, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to get backup target %v", remote.BackupTarget)
+	}
+	if !backupTarget.Status.Available {
+		return errors.Errorf("backup target %v (%v) is not available", backupTarget.Name, backupTarget.Spec.BackupTargetURL)
+	}
+
+	backupVolume, err := getBackupVolume(ctx, remote.longhornClient, remote.LonghornNamespace, remote.BackupTarget, remote.BackupVolume)
+	if err != nil {
+		return err
+	}
+	if backupVolume.Status.LastBackupName == "" {
+		return errors.Errorf("backup volume %v has no backup", remote.BackupVolume)
+	}
+
+	remote.backup, err = remote.longhornClient.LonghornV1beta2().Backups(remote.LonghornNamespace).Get(ctx, backupVolume.Status.LastBackupName, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to get latest backup %v of backup volume %v", backupVolume.Status.LastBackupName, remote.BackupVolume)
+	}
+	if remote.backup.Status.State != longhorn.BackupStateCompleted || remote.backup.Status.URL == "" {
+		return errors.Errorf("latest backup %v of backup volume %v is %v", remote.backup.Name, remote.BackupVolume, remote.backup.Status.State)
+	}
+
+	return nil
+}
+
+// Run creates the DR volume. Longhorn restores the latest backup, then each newer backup
+// incrementally, until the volume is activated.
+func (remote *Creator) Run() error {
+	size, err := strconv.ParseInt(remote.backup.Status.VolumeSize, 10, 64)
+	if err != nil {
+		return errors.Wrapf(err, "invalid volume size %q of backup %v", remote.backup.Status.VolumeSize, remote.backup.Name)
+	}
+
+	volume := &longhorn.Volume{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: remote.VolumeName,
+		},
+		Spec: longhorn.VolumeSpec{
+			Size:             size,
+			FromBackup:       remote.backup.Status.URL,
+			Standby:          true,
+			BackupTargetName: remote.BackupTarget,
+			NumberOfReplicas: remote.NumberOfReplicas,
+			BackingImage:     remote.backup.Status.VolumeBackingImageName,
+		},
+	}
+	if accessMode := remote.backup.Status.Labels[lhmgrtypes.GetLonghornLabelKey(lhmgrtypes.LonghornLabelVolumeAccessMode)]; accessMode != "" {
+		volume.Spec.AccessMode = longhorn.AccessMode(accessMode)
+	}
+
+	logrus.Infof("Creating DR volume %v from backup %v", remote.VolumeName, remote.backup.Status.URL)
+	if _, err := remote.longhornClient.LonghornV1beta2().Volumes(remote.LonghornNamespace).Create(context.Background(), volume, metav1.CreateOptions{}); err != nil {
+		return errors.Wrapf(err, "failed to create DR volume %v", remote.VolumeName)
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package dr
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	longhorn "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+	lhclient "github.com/longhorn/longhorn-manager/k8s/pkg/client/clientset/versioned"
+	lhmgrtypes "github.com/longhorn/longhorn-manager/types"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+// lagUnknown is the lag of a DR volume without restored backup.
+const lagUnknown = "unknown"
+
+// getBackupVolume returns the backup volume of the volume in the backup target.
+func getBackupVolume(ctx context.Context, longhornClient *lhclient.Clientset, namespace, backupTargetName, volumeName string) (*longhorn.BackupVolume, error) {
+	selector := labels.SelectorFromSet(lhmgrtypes.GetBackupVolumeWithBackupTargetLabels(backupTargetName, volumeName))
+	backupVolumes, err := longhornClient.LonghornV1beta2().BackupVolumes(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list backup volumes of volume %v", volumeName)
+	}
+
+	for _, backupVolume := range backupVolumes.Items {
+		if backupVolume.DeletionTimestamp == nil {
+			return &backupVolume, nil
+		}
+	}
+
+	// Backup volumes of Longhorn versions without backup target label are named after the volume.
+	backupVolume, err := longhornClient.LonghornV1beta2().BackupVolumes(namespace).Get(ctx, volumeName, metav1.GetOptions{})
+	if err == nil && (backupVolume.Spec.BackupTargetName == "" || backupVolume.Spec.BackupTargetName == backupTargetName) {
+		return backupVolume, nil
+	}
+
+	return nil, errors.Errorf("backup volume of volume %v is not found in backup target %v, the backup target may not be synchronized yet", volumeName, backupTargetName)
+}
+
+// parseBackupURL returns the names of the backup and of the backup volume in the query of a
+// backup URL, such as s3://bucket@region/?backup=backup-1&volume=vol.
+func parseBackupURL(backupURL string) (string, string, error) {
+	index := strings.Index(backupURL, "?")
+	if index < 0 {
+		return "", "", errors.Errorf("backup URL %q has no query", backupURL)
+	}
+
+	query, err := url.ParseQuery(backupURL[index+1:])
+	if err != nil {
+		return "", "", errors.Wrapf(err, "failed to parse backup URL %q", backupURL)
+	}
+	if query.Get("backup") == "" || query.Get("volume") == "" {
+		return "", "", errors.Errorf("backup URL %q has no backup or volume", backupURL)
+	}
+	return query.Get("backup"), query.Get("volume"), nil
+}
+
+// newDrVolumeStatus returns the restore status of the DR volume against the latest backup of its
+// backup volume. The lag is the time between the creation of the last restored backup and of the
+// latest backup.
+func newDrVolumeStatus(volume *longhorn.Volume, backupVolumeName string, backupVolume *longhorn.BackupVolume) types.DrVolumeStatus {
+	status := types.DrVolumeStatus{
+		Name:               volume.Name,
+		BackupVolume:       backupVolumeName,
+		BackupTarget:       volume.Spec.BackupTargetName,
+		State:              string(volume.Status.State),
+		Standby:            volume.Status.IsStandby,
+		LastRestoredBackup: volume.Status.LastBackup,
+		LastRestoredAt:     volume.Status.LastBackupAt,
+		Lag:                lagUnknown,
+		LagSeconds:         -1,
+	}
+	if backupVolume == nil {
+		status.Message = "Backup volume is not found"
+		return status
+	}
+	status.LatestBackup = backupVolume.Status.LastBackupName
+	status.LatestBackupAt = backupVolume.Status.LastBackupAt
+
+	switch {
+	case status.LastRestoredBackup == "":
+		status.Message = "No backup is restored yet"
+		return status
+	case status.LastRestoredBackup == status.LatestBackup && !volume.Status.RestoreRequired:
+		status.UpToDate = true
+		status.Lag = time.Duration(0).String()
+		status.LagSeconds = 0
+		return status
+	case volume.Status.RestoreRequired:
+		status.Message = "Restoring a newer backup"
+	}
+
+	restoredAt, err := time.Parse(time.RFC3339, status.LastRestoredAt)
+	if err != nil {
+		return status
+	}
+	latestAt, err := time.Parse(time.RFC3339, status.LatestBackupAt)
+	if err != nil {
+		return status
+	}
+
+	lag := latestAt.Sub(restoredAt)
+	if lag < 0 {
+		lag = 0
+	}
+	status.Lag = lag.Round(time.Second).String()
+	status.LagSeconds = int64(lag.Seconds())
+	return status
+}
+
+// backupTargetNameOrDefault returns the backup target name, or the default backup target of
+// Longhorn when it is empty.
+func backupTargetNameOrDefault(backupTargetName string) string {
+	if backupTargetName == "" {
+		return lhmgrtypes.DefaultBackupTargetName
+	}
+	return backupTargetName
+}
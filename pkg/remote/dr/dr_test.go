@@ -0,0 +1,108 @@
+package dr
+
+import (
+	"testing"
+
+	longhorn "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+)
+
+func TestParseBackupURL(t *testing.T) {
+	tests := map[string]struct {
+		backupURL      string
+		expectedBackup string
+		expectedVolume string
+		expectedError  bool
+	}{
+		"s3": {
+			backupURL:      "s3://backupbucket@us-east-1/?backup=backup-1&volume=vol",
+			expectedBackup: "backup-1",
+			expectedVolume: "vol",
+		},
+		"nfs": {
+			backupURL:      "nfs://nfs.default:/opt/backupstore?backup=backup-1&volume=vol",
+			expectedBackup: "backup-1",
+			expectedVolume: "vol",
+		},
+		"no query":  {backupURL: "s3://backupbucket@us-east-1/", expectedError: true},
+		"no volume": {backupURL: "s3://backupbucket@us-east-1/?backup=backup-1", expectedError: true},
+		"empty":     {backupURL: "", expectedError: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			backup, volume, err := parseBackupURL(test.backupURL)
+			if test.expectedError != (err != nil) {
+				t.Fatalf("expected error %v, got %v", test.expectedError, err)
+			}
+			if backup != test.expectedBackup || volume != test.expectedVolume {
+				t.Errorf("expected backup %q and volume %q, got %q and %q", test.expectedBackup, test.expectedVolume, backup, volume)
+			}
+		})
+	}
+}
+
+func TestNewDrVolumeStatus(t *testing.T) {
+	newVolume := func(lastBackup, lastBackupAt string, restoreRequired bool) *longhorn.Volume {
+		volume := &longhorn.Volume{}
+		volume.Name = "vol-dr"
+		volume.Status.IsStandby = true
+		volume.Status.LastBackup = lastBackup
+		volume.Status.LastBackupAt = lastBackupAt
+		volume.Status.RestoreRequired = restoreRequired
+		return volume
+	}
+	backupVolume := &longhorn.BackupVolume{}
+	backupVolume.Status.LastBackupName = "backup-2"
+	backupVolume.Status.LastBackupAt = "2024-07-16T12:00:00Z"
+
+	tests := map[string]struct {
+		volume             *longhorn.Volume
+		backupVolume       *longhorn.BackupVolume
+		expectedUpToDate   bool
+		expectedLag        string
+		expectedLagSeconds int64
+	}{
+		"up to date": {
+			volume:             newVolume("backup-2", "2024-07-16T12:00:00Z", false),
+			backupVolume:       backupVolume,
+			expectedUpToDate:   true,
+			expectedLag:        "0s",
+			expectedLagSeconds: 0,
+		},
+		"lagging": {
+			volume:             newVolume("backup-1", "2024-07-16T09:00:00Z", true),
+			backupVolume:       backupVolume,
+			expectedLag:        "3h0m0s",
+			expectedLagSeconds: 3 * 60 * 60,
+		},
+		"restoring latest backup": {
+			volume:             newVolume("backup-2", "2024-07-16T12:00:00Z", true),
+			backupVolume:       backupVolume,
+			expectedLag:        "0s",
+			expectedLagSeconds: 0,
+		},
+		"nothing restored": {
+			volume:             newVolume("", "", true),
+			backupVolume:       backupVolume,
+			expectedLag:        lagUnknown,
+			expectedLagSeconds: -1,
+		},
+		"backup volume not found": {
+			volume:             newVolume("backup-2", "2024-07-16T12:00:00Z", false),
+			expectedLag:        lagUnknown,
+			expectedLagSeconds: -1,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			status := newDrVolumeStatus(test.volume, "vol", test.backupVolume)
+			if status.UpToDate != test.expectedUpToDate {
+				t.Errorf("expected up to date %v, got %v", test.expectedUpToDate, status.UpToDate)
+			}
+			if status.Lag != test.expectedLag || status.LagSeconds != test.expectedLagSeconds {
+				t.Errorf("expected lag %v (%d seconds), got %v (%d seconds)", test.expectedLag, test.expectedLagSeconds, status.Lag, status.LagSeconds)
+			}
+		})
+	}
+}
@@ -0,0 +1,583 @@
+package dr
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	sigsyaml "sigs.k8s.io/yaml"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	kubeclient "k8s.io/client-go/kubernetes"
+
+	longhorn "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+	lhclient "github.com/longhorn/longhorn-manager/k8s/pkg/client/clientset/versioned"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// migrationStateKey is the key of the migration state in the state ConfigMap.
+const migrationStateKey = "state"
+
+// Annotations of the PVs and PVCs set by Kubernetes when binding them, not copied to the target
+// cluster.
+var bindingAnnotations = []string{
+	"pv.kubernetes.io/bind-completed",
+	"pv.kubernetes.io/bound-by-controller",
+	"volume.beta.kubernetes.io/storage-provisioner",
+	"volume.kubernetes.io/storage-provisioner",
+	"volume.kubernetes.io/selected-node",
+}
+
+// Migrator provide functions for migrating a volume to another cluster sharing the backup store,
+// by backing it up on the source cluster, and restoring the backup or activating the DR volume of
+// the volume on the target cluster.
+type Migrator struct {
+	MigratorCmdOptions
+
+	kubeClient           *kubeclient.Clientset // Clients of the source cluster.
+	longhornClient       *lhclient.Clientset
+	targetKubeClient     *kubeclient.Clientset
+	targetLonghornClient *lhclient.Clientset
+
+	namespace    string // Namespace of the state ConfigMap in the source cluster.
+	appName      string // Name of the state ConfigMap.
+	sourceVolume *longhorn.Volume
+	state        *types.VolumeClusterMigration
+}
+
+// MigratorCmdOptions holds the options for the command.
+type MigratorCmdOptions struct {
+	types.GlobalCmdOptions
+
+	LonghornNamespace    string // Namespace where Longhorn is deployed, in both clusters.
+	VolumeName           string
+	TargetKubeConfigPath string
+	TargetVolumeName     string        // Defaults to the name of the volume.
+	TargetPVC            string        // Namespace/name of the PVC in the target cluster. Defaults to the PVC of the volume.
+	Force                bool          // Migrate the volume even if it is attached.
+	Reset                bool          // Discard the state of the previous migration of the volume.
+	Timeout              time.Duration // Maximum time of the run, an interrupted migration is resumed by running it again.
+}
+
+// Validate validates the command options.
+func (remote *Migrator) Validate() error {
+	if remote.VolumeName == "" {
+		return errors.Errorf("volume (--%s) is required", consts.CmdOptVolume)
+	}
+	if remote.TargetKubeConfigPath == "" {
+		return errors.Errorf("kubeconfig of the target cluster (--%s) is required", consts.CmdOptTargetKubeConfig)
+	}
+	if remote.TargetKubeConfigPath == remote.KubeConfigPath {
+		return errors.Errorf("--%s must be the kubeconfig of another cluster than --%s", consts.CmdOptTargetKubeConfig, consts.CmdOptKubeConfigPath)
+	}
+	if remote.TargetPVC != "" {
+		if _, _, err := parsePVCName(remote.TargetPVC); err != nil {
+			return errors.Wrapf(err, "invalid --%s", consts.CmdOptTargetPVC)
+		}
+	}
+	if remote.Timeout <= 0 {
+		return errors.Errorf("timeout (--%s) must be positive", consts.CmdOptTimeout)
+	}
+	return nil
+}
+
+// Init initializes the Migrator. It loads the state of the interrupted migration of the volume, or
+// starts a new one, in the restore mode when the target volume does not exist, or in the DR mode
+// when it is a DR volume of the volume.
+func (remote *Migrator) Init() error {
+	var err error
+	if remote.kubeClient, err = kubeutils.NewKubeClient("", remote.KubeConfigPath); err != nil {
+		return err
+	}
+	if remote.longhornClient, err = kubeutils.NewLonghornClient("", remote.KubeConfigPath); err != nil {
+		return err
+	}
+	if remote.targetKubeClient, err = kubeutils.NewKubeClient("", remote.TargetKubeConfigPath); err != nil {
+		return errors.Wrap(err, "failed to create the client of the target cluster")
+	}
+	if remote.targetLonghornClient, err = kubeutils.NewLonghornClient("", remote.TargetKubeConfigPath); err != nil {
+		return errors.Wrap(err, "failed to create the client of the target cluster")
+	}
+
+	remote.namespace = remote.Namespace
+	if remote.namespace == "" {
+		remote.namespace = consts.LonghornNamespace
+	}
+	remote.appName = fmt.Sprintf("%s-%s", consts.AppNameClusterMigrator, remote.VolumeName)
+
+	ctx := context.Background()
+
+	remote.sourceVolume, err = remote.longhornClient.LonghornV1beta2().Volumes(remote.LonghornNamespace).Get(ctx, remote.VolumeName, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to get volume %v", remote.VolumeName)
+	}
+
+	if remote.Reset {
+		err := remote.kubeClient.CoreV1().ConfigMaps(remote.namespace).Delete(ctx, remote.appName, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to delete the migration state %v", remote.appName)
+		}
+	}
+
+	remote.state, err = remote.loadState(ctx)
+	if err != nil {
+		return err
+	}
+	if remote.state != nil {
+		if remote.TargetVolumeName != "" && remote.TargetVolumeName != remote.state.TargetVolume {
+			return errors.Errorf("volume %v is being migrated to volume %v, run the command again without --%v to resume, or with --%v to start over",
+				remote.VolumeName, remote.state.TargetVolume, consts.CmdOptTargetVolume, consts.CmdOptReset)
+		}
+		logrus.Infof("Resuming the migration of volume %v started at %v, completed steps: %v", remote.VolumeName, remote.state.StartedAt, getCompletedStepNames(remote.state))
+		return nil
+	}
+
+	return remote.newState(ctx)
+}
+
+// Run runs the steps of the migration not completed yet, saving the state after each one, and
+// returns the state of the completed migration. The state is deleted once the migration completes.
+func (remote *Migrator) Run() (*types.VolumeClusterMigration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), remote.Timeout)
+	defer cancel()
+
+	steps := []struct {
+		name string
+		run  func(ctx context.Context) error
+	}{
+		{types.VolumeClusterMigrationStepBackup, remote.backup},
+		{types.VolumeClusterMigrationStepRestore, remote.restore},
+		{types.VolumeClusterMigrationStepPVC, remote.recreatePVC},
+	}
+	for _, step := range steps {
+		if isStepCompleted(remote.state, step.name) {
+			continue
+		}
+
+		if err := step.run(ctx); err != nil {
+			return remote.state, errors.Wrapf(err, "failed to %v volume %v, run the command again to resume the migration", step.name, remote.VolumeName)
+		}
+
+		remote.state.CompletedSteps = append(remote.state.CompletedSteps, types.VolumeClusterMigrationStep{
+			Name:        step.name,
+			CompletedAt: time.Now().UTC().Format(time.RFC3339),
+		})
+		if err := remote.saveState(ctx); err != nil {
+			return remote.state, err
+		}
+	}
+
+	err := remote.kubeClient.CoreV1().ConfigMaps(remote.namespace).Delete(ctx, remote.appName, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		logrus.WithError(err).Warnf("Failed to delete the migration state %v", remote.appName)
+	}
+	return remote.state, nil
+}
+
+// Cleanup does nothing, since the Migrator keeps the state of an interrupted migration, and the
+// resources it creates are the result of the migration.
+func (remote *Migrator) Cleanup() error {
+	return nil
+}
+
+// Mode returns the mode of the migration.
+func (remote *Migrator) Mode() string {
+	return remote.state.Mode
+}
+
+// TargetVolume returns the name of the volume in the target cluster.
+func (remote *Migrator) TargetVolume() string {
+	return remote.state.TargetVolume
+}
+
+// newState starts a new migration state, with the name mapping of the volume and of its PVC.
+func (remote *Migrator) newState(ctx context.Context) error {
+	state := &types.VolumeClusterMigration{
+		Volume:       remote.VolumeName,
+		TargetVolume: remote.TargetVolumeName,
+		Mode:         types.VolumeClusterMigrationModeRestore,
+		StartedAt:    time.Now().UTC().Format(time.RFC3339),
+	}
+	if state.TargetVolume == "" {
+		state.TargetVolume = remote.VolumeName
+	}
+
+	kubernetesStatus := remote.sourceVolume.Status.KubernetesStatus
+	if kubernetesStatus.PVName != "" {
+		state.SourcePV = kubernetesStatus.PVName
+		state.TargetPVC = remote.TargetPVC
+		if state.TargetPVC == "" && kubernetesStatus.PVCName != "" {
+			state.TargetPVC = kubernetesStatus.Namespace + "/" + kubernetesStatus.PVCName
+		}
+	}
+
+	targetVolume, err := remote.targetLonghornClient.LonghornV1beta2().Volumes(remote.LonghornNamespace).Get(ctx, state.TargetVolume, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+	case err != nil:
+		return errors.Wrapf(err, "failed to get volume %v in the target cluster", state.TargetVolume)
+	case !targetVolume.Spec.Standby:
+		return errors.Errorf("volume %v already exists in the target cluster, choose another name with --%v", state.TargetVolume, consts.CmdOptTargetVolume)
+	default:
+		_, backupVolumeName, err := parseBackupURL(targetVolume.Spec.FromBackup)
+		if err != nil || backupVolumeName != remote.VolumeName {
+			return errors.Errorf("volume %v of the target cluster is a DR volume of another volume than %v", state.TargetVolume, remote.VolumeName)
+		}
+		state.Mode = types.VolumeClusterMigrationModeDr
+	}
+
+	if state.TargetPVC != "" {
+		namespace, name, _ := parsePVCName(state.TargetPVC)
+		_, err := remote.targetKubeClient.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err == nil {
+			return errors.Errorf("PVC %v already exists in the target cluster, choose another one with --%v", state.TargetPVC, consts.CmdOptTargetPVC)
+		}
+		if !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to get PVC %v in the target cluster", state.TargetPVC)
+		}
+	}
+
+	if remote.sourceVolume.Status.State != longhorn.VolumeStateDetached && !remote.Force {
+		return errors.Errorf("volume %v is %v, stop its workloads so the final backup has all the data, or use --%v to migrate the data of the final backup anyway",
+			remote.VolumeName, remote.sourceVolume.Status.State, consts.CmdOptForce)
+	}
+
+	remote.state = state
+	return nil
+}
+
+// backup takes the final backup of the volume on the source cluster. Its name is saved first, so
+// an interrupted backup is resumed.
+func (remote *Migrator) backup(ctx context.Context) error {
+	if remote.state.Backup == "" {
+		remote.state.Backup = fmt.Sprintf("%smigrate-%s-%s", consts.SnapshotNamePrefix, time.Now().UTC().Format("20060102-150405"), remote.VolumeName)
+		if err := remote.saveState(ctx); err != nil {
+			return err
+		}
+	}
+
+	_, err := kubeutils.CreateBackup(ctx, remote.longhornClient, remote.LonghornNamespace, remote.VolumeName, backupTargetNameOrDefault(remote.sourceVolume.Spec.BackupTargetName), remote.state.Backup)
+	return err
+}
+
+// restore restores the final backup into the target volume, or activates the DR volume, and waits
+// for the volume to be restored.
+func (remote *Migrator) restore(ctx context.Context) error {
+	backup, err := remote.waitForTargetBackup(ctx)
+	if err != nil {
+		return err
+	}
+
+	volumes := remote.targetLonghornClient.LonghornV1beta2().Volumes(remote.LonghornNamespace)
+	if remote.state.Mode == types.VolumeClusterMigrationModeDr {
+		return remote.activate(ctx)
+	}
+
+	if _, err := volumes.Get(ctx, remote.state.TargetVolume, metav1.GetOptions{}); apierrors.IsNotFound(err) {
+		volume, err := newTargetVolume(remote.sourceVolume, remote.state.TargetVolume, backup)
+		if err != nil {
+			return err
+		}
+		logrus.Infof("Creating volume %v in the target cluster from backup %v", remote.state.TargetVolume, backup.Status.URL)
+		if _, err := volumes.Create(ctx, volume, metav1.CreateOptions{}); err != nil {
+			return errors.Wrapf(err, "failed to create volume %v in the target cluster", remote.state.TargetVolume)
+		}
+	} else if err != nil {
+		return errors.Wrapf(err, "failed to get volume %v in the target cluster", remote.state.TargetVolume)
+	}
+
+	logrus.Infof("Waiting for volume %v to restore backup %v", remote.state.TargetVolume, backup.Name)
+	err = wait.PollUntilContextCancel(ctx, 2*time.Second, true, func(ctx context.Context) (bool, error) {
+		volume, err := volumes.Get(ctx, remote.state.TargetVolume, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		if volume.Status.Robustness == longhorn.VolumeRobustnessFaulted {
+			return false, errors.Errorf("volume %v is faulted", volume.Name)
+		}
+		return volume.Status.RestoreInitiated && !volume.Status.RestoreRequired && volume.Status.State == longhorn.VolumeStateDetached, nil
+	})
+	return errors.Wrapf(err, "failed waiting for volume %v to be restored", remote.state.TargetVolume)
+}
+
+// activate activates the DR volume of the target cluster, once it restored the final backup. An
+// activated DR volume is left as it is.
+func (remote *Migrator) activate(ctx context.Context) error {
+	volume, err := remote.targetLonghornClient.LonghornV1beta2().Volumes(remote.LonghornNamespace).Get(ctx, remote.state.TargetVolume, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to get volume %v in the target cluster", remote.state.TargetVolume)
+	}
+	if !volume.Spec.Standby {
+		logrus.Infof("DR volume %v is already activated", remote.state.TargetVolume)
+		return nil
+	}
+
+	frontend := remote.sourceVolume.Spec.Frontend
+	if frontend == "" {
+		frontend = longhorn.VolumeFrontendBlockDev
+	}
+	activator := &Activator{ActivatorCmdOptions: ActivatorCmdOptions{
+		GlobalCmdOptions:  types.GlobalCmdOptions{KubeConfigPath: remote.TargetKubeConfigPath, LogLevel: remote.LogLevel},
+		LonghornNamespace: remote.LonghornNamespace,
+		VolumeName:        remote.state.TargetVolume,
+		Frontend:          string(frontend),
+		Timeout:           remote.Timeout,
+	}}
+	if err := activator.Init(); err != nil {
+		return err
+	}
+	status, err := activator.Run(ctx)
+	if err != nil {
+		return err
+	}
+	if status.LastRestoredBackup != remote.state.Backup {
+		return errors.Errorf("DR volume %v restored backup %v instead of the final backup %v", remote.state.TargetVolume, status.LastRestoredBackup, remote.state.Backup)
+	}
+	return nil
+}
+
+// waitForTargetBackup requests the synchronization of the backup targets of the target cluster,
+// and waits for the final backup to be found in it.
+func (remote *Migrator) waitForTargetBackup(ctx context.Context) (*longhorn.Backup, error) {
+	backupTargets := remote.targetLonghornClient.LonghornV1beta2().BackupTargets(remote.LonghornNamespace)
+	list, err := backupTargets.List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list the backup targets of the target cluster")
+	}
+	for _, backupTarget := range list.Items {
+		backupTarget.Spec.SyncRequestedAt = metav1.Now()
+		if _, err := backupTargets.Update(ctx, &backupTarget, metav1.UpdateOptions{}); err != nil {
+			return nil, errors.Wrapf(err, "failed to request synchronization of backup target %v of the target cluster", backupTarget.Name)
+		}
+	}
+
+	logrus.Infof("Waiting for backup %v to be synchronized to the target cluster", remote.state.Backup)
+	var backup *longhorn.Backup
+	err = wait.PollUntilContextCancel(ctx, 2*time.Second, true, func(ctx context.Context) (bool, error) {
+		backup, err = remote.targetLonghornClient.LonghornV1beta2().Backups(remote.LonghornNamespace).Get(ctx, remote.state.Backup, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		return backup.Status.State == longhorn.BackupStateCompleted && backup.Status.URL != "", nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed waiting for backup %v in the target cluster, its backup target must be the backup store of the source cluster", remote.state.Backup)
+	}
+	return backup, nil
+}
+
+// recreatePVC creates the PV and the PVC of the volume in the target cluster, after the ones of the
+// source cluster. It does nothing when the volume has no PVC.
+func (remote *Migrator) recreatePVC(ctx context.Context) error {
+	if remote.state.TargetPVC == "" {
+		logrus.Infof("Volume %v has no PVC, the PV and the PVC are not recreated", remote.VolumeName)
+		return nil
+	}
+
+	sourcePV, err := remote.kubeClient.CoreV1().PersistentVolumes().Get(ctx, remote.state.SourcePV, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to get PV %v", remote.state.SourcePV)
+	}
+	if sourcePV.Spec.ClaimRef == nil {
+		return errors.Errorf("PV %v is not bound to a PVC", sourcePV.Name)
+	}
+	sourcePVC, err := remote.kubeClient.CoreV1().PersistentVolumeClaims(sourcePV.Spec.ClaimRef.Namespace).Get(ctx, sourcePV.Spec.ClaimRef.Name, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to get PVC %v/%v", sourcePV.Spec.ClaimRef.Namespace, sourcePV.Spec.ClaimRef.Name)
+	}
+
+	namespace, name, err := parsePVCName(remote.state.TargetPVC)
+	if err != nil {
+		return err
+	}
+	pv := newTargetPersistentVolume(sourcePV, remote.state.TargetVolume, namespace, name)
+	pvc := newTargetPersistentVolumeClaim(sourcePVC, pv.Name, namespace, name)
+
+	if _, err := kubeutils.CreateNamespace(remote.targetKubeClient, namespace); err != nil {
+		return err
+	}
+
+	logrus.Infof("Creating PV %v and PVC %v in the target cluster", pv.Name, remote.state.TargetPVC)
+	if _, err := remote.targetKubeClient.CoreV1().PersistentVolumes().Create(ctx, pv, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+		return errors.Wrapf(err, "failed to create PV %v in the target cluster", pv.Name)
+	}
+	if _, err := remote.targetKubeClient.CoreV1().PersistentVolumeClaims(namespace).Create(ctx, pvc, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+		return errors.Wrapf(err, "failed to create PVC %v in the target cluster", remote.state.TargetPVC)
+	}
+	return nil
+}
+
+// loadState returns the state of the interrupted migration of the volume, or nil when there is
+// none.
+func (remote *Migrator) loadState(ctx context.Context) (*types.VolumeClusterMigration, error) {
+	configMap, err := remote.kubeClient.CoreV1().ConfigMaps(remote.namespace).Get(ctx, remote.appName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get the migration state %v", remote.appName)
+	}
+
+	state := &types.VolumeClusterMigration{}
+	if err := sigsyaml.Unmarshal([]byte(configMap.Data[migrationStateKey]), state); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse the migration state %v, start over with --%v", remote.appName, consts.CmdOptReset)
+	}
+	return state, nil
+}
+
+// saveState writes the migration state into the state ConfigMap of the source cluster.
+func (remote *Migrator) saveState(ctx context.Context) error {
+	data, err := sigsyaml.Marshal(remote.state)
+	if err != nil {
+		return errors.Wrap(err, "failed to convert the migration state to YAML")
+	}
+
+	if _, err := kubeutils.CreateNamespace(remote.kubeClient, remote.namespace); err != nil {
+		return err
+	}
+	_, err = kubeutils.CreateOrUpdateConfigMap(remote.kubeClient, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      remote.appName,
+			Namespace: remote.namespace,
+			Labels: map[string]string{
+				"app":                 consts.AppNameClusterMigrator,
+				consts.LabelManagedBy: consts.LabelValueManagedBy,
+				consts.LabelState:     consts.LabelValueState,
+			},
+		},
+		Data: map[string]string{
+			migrationStateKey: string(data),
+		},
+	})
+	return errors.Wrapf(err, "failed to save the migration state %v", remote.appName)
+}
+
+// newTargetVolume returns the volume restoring the backup in the target cluster, with the
+// settings of the source volume.
+func newTargetVolume(sourceVolume *longhorn.Volume, name string, backup *longhorn.Backup) (*longhorn.Volume, error) {
+	size, err := strconv.ParseInt(backup.Status.VolumeSize, 10, 64)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid volume size %q of backup %v", backup.Status.VolumeSize, backup.Name)
+	}
+
+	return &longhorn.Volume{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Spec: longhorn.VolumeSpec{
+			Size:             size,
+			FromBackup:       backup.Status.URL,
+			BackupTargetName: backup.Status.BackupTargetName,
+			BackingImage:     backup.Status.VolumeBackingImageName,
+			NumberOfReplicas: sourceVolume.Spec.NumberOfReplicas,
+			AccessMode:       sourceVolume.Spec.AccessMode,
+			Migratable:       sourceVolume.Spec.Migratable,
+			Encrypted:        sourceVolume.Spec.Encrypted,
+			Frontend:         sourceVolume.Spec.Frontend,
+			DataEngine:       sourceVolume.Spec.DataEngine,
+			DataLocality:     sourceVolume.Spec.DataLocality,
+		},
+	}, nil
+}
+
+// newTargetPersistentVolume returns the PV of the target volume in the target cluster, after the
+// PV of the source volume, bound to the PVC.
+func newTargetPersistentVolume(sourcePV *corev1.PersistentVolume, volumeName, pvcNamespace, pvcName string) *corev1.PersistentVolume {
+	spec := *sourcePV.Spec.DeepCopy()
+	spec.ClaimRef = &corev1.ObjectReference{
+		Kind:      "PersistentVolumeClaim",
+		Namespace: pvcNamespace,
+		Name:      pvcName,
+	}
+	if spec.CSI != nil {
+		spec.CSI.VolumeHandle = volumeName
+	}
+
+	return &corev1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        volumeName,
+			Labels:      sourcePV.Labels,
+			Annotations: withoutBindingAnnotations(sourcePV.Annotations),
+		},
+		Spec: spec,
+	}
+}
+
+// newTargetPersistentVolumeClaim returns the PVC of the target cluster, after the PVC of the source
+// volume, bound to the PV.
+func newTargetPersistentVolumeClaim(sourcePVC *corev1.PersistentVolumeClaim, pvName, namespace, name string) *corev1.PersistentVolumeClaim {
+	spec := *sourcePVC.Spec.DeepCopy()
+	spec.VolumeName = pvName
+	spec.DataSource = nil
+	spec.DataSourceRef = nil
+
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   namespace,
+			Labels:      sourcePVC.Labels,
+			Annotations: withoutBindingAnnotations(sourcePVC.Annotations),
+		},
+		Spec: spec,
+	}
+}
+
+func withoutBindingAnnotations(annotations map[string]string) map[string]string {
+	if len(annotations) == 0 {
+		return nil
+	}
+
+	copied := map[string]string{}
+	for key, value := range annotations {
+		copied[key] = value
+	}
+	for _, key := range bindingAnnotations {
+		delete(copied, key)
+	}
+	return copied
+}
+
+// parsePVCName parses a PVC name as namespace/name.
+func parsePVCName(pvcName string) (string, string, error) {
+	namespace, name, ok := strings.Cut(pvcName, "/")
+	if !ok || namespace == "" || name == "" || strings.Contains(name, "/") {
+		return "", "", errors.Errorf("PVC %q must be namespace/name", pvcName)
+	}
+	return namespace, name, nil
+}
+
+func isStepCompleted(state *types.VolumeClusterMigration, name string) bool {
+	for _, step := range state.CompletedSteps {
+		if step.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func getCompletedStepNames(state *types.VolumeClusterMigration) string {
+	if len(state.CompletedSteps) == 0 {
+		return "none"
+	}
+
+	names := make([]string, 0, len(state.CompletedSteps))
+	for _, step := range state.CompletedSteps {
+		names = append(names, step.Name)
+	}
+	return strings.Join(names, ", ")
+}
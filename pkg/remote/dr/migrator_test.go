@@ -0,0 +1,153 @@
+package dr
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	longhorn "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+	lhmgrtypes "github.com/longhorn/longhorn-manager/types"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func TestMigratorValidate(t *testing.T) {
+	valid := MigratorCmdOptions{VolumeName: "vol", TargetKubeConfigPath: "target.yaml", Timeout: time.Hour}
+
+	tests := map[string]struct {
+		modify        func(options *MigratorCmdOptions)
+		expectedError bool
+	}{
+		"valid":                 {modify: func(options *MigratorCmdOptions) {}},
+		"valid target PVC":      {modify: func(options *MigratorCmdOptions) { options.TargetPVC = "default/data" }},
+		"no volume":             {modify: func(options *MigratorCmdOptions) { options.VolumeName = "" }, expectedError: true},
+		"no target kubeconfig":  {modify: func(options *MigratorCmdOptions) { options.TargetKubeConfigPath = "" }, expectedError: true},
+		"same kubeconfig":       {modify: func(options *MigratorCmdOptions) { options.KubeConfigPath = "target.yaml" }, expectedError: true},
+		"target PVC name only":  {modify: func(options *MigratorCmdOptions) { options.TargetPVC = "data" }, expectedError: true},
+		"target PVC extra part": {modify: func(options *MigratorCmdOptions) { options.TargetPVC = "default/data/0" }, expectedError: true},
+		"no timeout":            {modify: func(options *MigratorCmdOptions) { options.Timeout = 0 }, expectedError: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			migrator := &Migrator{MigratorCmdOptions: valid}
+			test.modify(&migrator.MigratorCmdOptions)
+			if err := migrator.Validate(); test.expectedError != (err != nil) {
+				t.Errorf("expected error %v, got %v", test.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestNewTargetVolume(t *testing.T) {
+	sourceVolume := &longhorn.Volume{
+		Spec: longhorn.VolumeSpec{
+			NumberOfReplicas: 2,
+			AccessMode:       longhorn.AccessModeReadWriteMany,
+			Frontend:         longhorn.VolumeFrontendBlockDev,
+			DataEngine:       longhorn.DataEngineTypeV1,
+		},
+	}
+	backup := &longhorn.Backup{
+		ObjectMeta: metav1.ObjectMeta{Name: "backup-1"},
+		Status: longhorn.BackupStatus{
+			URL:              "s3://backupbucket@us-east-1/?backup=backup-1&volume=vol",
+			VolumeSize:       "2147483648",
+			BackupTargetName: "default",
+		},
+	}
+
+	volume, err := newTargetVolume(sourceVolume, "vol-target", backup)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if volume.Name != "vol-target" || volume.Spec.Size != 2147483648 || volume.Spec.FromBackup != backup.Status.URL || volume.Spec.BackupTargetName != "default" {
+		t.Errorf("unexpected volume: %+v", volume)
+	}
+	if volume.Spec.NumberOfReplicas != 2 || volume.Spec.AccessMode != longhorn.AccessModeReadWriteMany || volume.Spec.Standby {
+		t.Errorf("expected the settings of the source volume, got %+v", volume.Spec)
+	}
+
+	backup.Status.VolumeSize = ""
+	if _, err := newTargetVolume(sourceVolume, "vol-target", backup); err == nil {
+		t.Error("expected an error for a backup without size")
+	}
+}
+
+func TestNewTargetPersistentVolumeAndClaim(t *testing.T) {
+	sourcePV := &corev1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "vol",
+			Annotations: map[string]string{
+				"pv.kubernetes.io/provisioned-by":      lhmgrtypes.LonghornDriverName,
+				"pv.kubernetes.io/bound-by-controller": "yes",
+			},
+		},
+		Spec: corev1.PersistentVolumeSpec{
+			Capacity:                      corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("2Gi")},
+			PersistentVolumeReclaimPolicy: corev1.PersistentVolumeReclaimDelete,
+			StorageClassName:              "longhorn",
+			ClaimRef:                      &corev1.ObjectReference{Namespace: "default", Name: "data", UID: "uid-1", ResourceVersion: "1"},
+			PersistentVolumeSource: corev1.PersistentVolumeSource{
+				CSI: &corev1.CSIPersistentVolumeSource{Driver: lhmgrtypes.LonghornDriverName, VolumeHandle: "vol"},
+			},
+		},
+	}
+	sourcePVC := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "data",
+			Namespace:   "default",
+			Labels:      map[string]string{"app": "mysql"},
+			Annotations: map[string]string{"pv.kubernetes.io/bind-completed": "yes", "volume.kubernetes.io/selected-node": "node-1"},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			StorageClassName: &sourcePV.Spec.StorageClassName,
+			VolumeName:       "vol",
+			DataSource:       &corev1.TypedLocalObjectReference{Kind: "VolumeSnapshot", Name: "snapshot-1"},
+		},
+	}
+
+	pv := newTargetPersistentVolume(sourcePV, "vol-target", "mysql", "data-0")
+	if pv.Name != "vol-target" || pv.Spec.CSI.VolumeHandle != "vol-target" {
+		t.Errorf("expected PV and volume handle vol-target, got %v and %v", pv.Name, pv.Spec.CSI.VolumeHandle)
+	}
+	if pv.Spec.ClaimRef.Namespace != "mysql" || pv.Spec.ClaimRef.Name != "data-0" || pv.Spec.ClaimRef.UID != "" {
+		t.Errorf("expected a claim reference to mysql/data-0 without UID, got %+v", pv.Spec.ClaimRef)
+	}
+	if _, ok := pv.Annotations["pv.kubernetes.io/bound-by-controller"]; ok || pv.Annotations["pv.kubernetes.io/provisioned-by"] != lhmgrtypes.LonghornDriverName {
+		t.Errorf("unexpected PV annotations: %v", pv.Annotations)
+	}
+	if sourcePV.Spec.CSI.VolumeHandle != "vol" || sourcePV.Annotations["pv.kubernetes.io/bound-by-controller"] != "yes" {
+		t.Error("expected the source PV to be left unchanged")
+	}
+
+	pvc := newTargetPersistentVolumeClaim(sourcePVC, pv.Name, "mysql", "data-0")
+	if pvc.Namespace != "mysql" || pvc.Name != "data-0" || pvc.Spec.VolumeName != "vol-target" || pvc.Spec.DataSource != nil {
+		t.Errorf("unexpected PVC: %+v", pvc)
+	}
+	if len(pvc.Annotations) != 0 || pvc.Labels["app"] != "mysql" || *pvc.Spec.StorageClassName != "longhorn" {
+		t.Errorf("unexpected PVC metadata or StorageClass: %+v", pvc)
+	}
+}
+
+func TestIsStepCompleted(t *testing.T) {
+	state := &types.VolumeClusterMigration{
+		CompletedSteps: []types.VolumeClusterMigrationStep{{Name: types.VolumeClusterMigrationStepBackup}},
+	}
+
+	if !isStepCompleted(state, types.VolumeClusterMigrationStepBackup) {
+		t.Error("expected the backup step to be completed")
+	}
+	if isStepCompleted(state, types.VolumeClusterMigrationStepRestore) {
+		t.Error("expected the restore step not to be completed")
+	}
+	if names := getCompletedStepNames(state); names != types.VolumeClusterMigrationStepBackup {
+		t.Errorf("expected completed steps %q, got %q", types.VolumeClusterMigrationStepBackup, names)
+	}
+	if names := getCompletedStepNames(&types.VolumeClusterMigration{}); names != "none" {
+		t.Errorf("expected no completed step, got %q", names)
+	}
+}
@@ -0,0 +1,110 @@
+package dr
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	longhorn "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+	lhclient "github.com/longhorn/longhorn-manager/k8s/pkg/client/clientset/versioned"
+
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// StatusReporter provide functions for reporting the restore status of the DR volumes.
+type StatusReporter struct {
+	StatusReporterCmdOptions
+
+	longhornClient *lhclient.Clientset
+}
+
+// StatusReporterCmdOptions holds the options for the command.
+type StatusReporterCmdOptions struct {
+	types.GlobalCmdOptions
+
+	LonghornNamespace string
+	VolumeNames       []string      // DR volumes to report. Defaults to all standby volumes.
+	MaxLag            time.Duration // Maximum lag of the DR volumes, 0 for no maximum.
+}
+
+// Init initializes the StatusReporter.
+func (remote *StatusReporter) Init() error {
+	longhornClient, err := kubeutils.NewLonghornClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.longhornClient = longhornClient
+
+	return nil
+}
+
+// Collect returns the restore status of the DR volumes, sorted by name.
+func (remote *StatusReporter) Collect() ([]types.DrVolumeStatus, error) {
+	ctx := context.Background()
+
+	volumes := []longhorn.Volume{}
+	if len(remote.VolumeNames) == 0 {
+		volumeList, err := remote.longhornClient.LonghornV1beta2().Volumes(remote.LonghornNamespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list volumes")
+		}
+		for _, volume := range volumeList.Items {
+			if volume.Spec.Standby || volume.Status.IsStandby {
+				volumes = append(volumes, volume)
+			}
+		}
+	}
+	for _, volumeName := range remote.VolumeNames {
+		volume, err := remote.longhornClient.LonghornV1beta2().Volumes(remote.LonghornNamespace).Get(ctx, volumeName, metav1.GetOptions{})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get volume %v", volumeName)
+		}
+		volumes = append(volumes, *volume)
+	}
+	sort.Slice(volumes, func(i, j int) bool { return volumes[i].Name < volumes[j].Name })
+
+	statuses := make([]types.DrVolumeStatus, 0, len(volumes))
+	for i := range volumes {
+		statuses = append(statuses, remote.getDrVolumeStatus(ctx, &volumes[i]))
+	}
+	return statuses, nil
+}
+
+// LaggingVolumes returns the names of the DR volumes whose lag exceeds the maximum lag, including
+// the ones without restored backup.
+func (remote *StatusReporter) LaggingVolumes(statuses []types.DrVolumeStatus) []string {
+	if remote.MaxLag <= 0 {
+		return nil
+	}
+
+	names := []string{}
+	for _, status := range statuses {
+		if status.LagSeconds < 0 || time.Duration(status.LagSeconds)*time.Second > remote.MaxLag {
+			names = append(names, status.Name)
+		}
+	}
+	return names
+}
+
+func (remote *StatusReporter) getDrVolumeStatus(ctx context.Context, volume *longhorn.Volume) types.DrVolumeStatus {
+	_, backupVolumeName, err := parseBackupURL(volume.Spec.FromBackup)
+	if err != nil {
+		status := newDrVolumeStatus(volume, "", nil)
+		status.Message = "Volume is not restored from a backup"
+		return status
+	}
+
+	backupVolume, err := getBackupVolume(ctx, remote.longhornClient, remote.LonghornNamespace, backupTargetNameOrDefault(volume.Spec.BackupTargetName), backupVolumeName)
+	if err != nil {
+		status := newDrVolumeStatus(volume, backupVolumeName, nil)
+		status.Message = err.Error()
+		return status
+	}
+	return newDrVolumeStatus(volume, backupVolumeName, backupVolume)
+}
@@ -0,0 +1,248 @@
+package event
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	kubeclient "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	watchtools "k8s.io/client-go/tools/watch"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// Kinds of the Longhorn objects whose events are streamed.
+const (
+	KindVolume  = "Volume"
+	KindEngine  = "Engine"
+	KindReplica = "Replica"
+	KindNode    = "Node"
+)
+
+const longhornAPIGroup = "longhorn.io"
+
+// Streamer provide functions for streaming the Kubernetes events of the Longhorn objects.
+type Streamer struct {
+	StreamerCmdOptions
+
+	kubeClient *kubeclient.Clientset
+
+	kinds map[string]bool // Kinds of the objects to stream the events of.
+}
+
+// StreamerCmdOptions holds the options for the command.
+type StreamerCmdOptions struct {
+	types.GlobalCmdOptions
+
+	LonghornNamespace string        // Namespace where Longhorn is deployed.
+	Volume            string        // Only stream the events of the volume, and of its engines and replicas.
+	Kinds             string        // Comma-separated kinds of the objects to stream the events of.
+	Since             time.Duration // Only show the existing events newer than this. Shows all existing events when zero.
+	Follow            bool          // Keep streaming the new events.
+}
+
+// Validate validates the command options.
+func (remote *Streamer) Validate() error {
+	if _, err := parseKinds(remote.Kinds); err != nil {
+		return err
+	}
+
+	if remote.Since < 0 {
+		return errors.Errorf("since (--%s) must not be negative", consts.CmdOptSince)
+	}
+
+	return nil
+}
+
+// Init initializes the Streamer.
+func (remote *Streamer) Init() error {
+	kubeClient, err := kubeutils.NewKubeClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.kubeClient = kubeClient
+
+	remote.kinds, err = parseKinds(remote.Kinds)
+	return err
+}
+
+// Run passes the existing events to the handler in chronological order. With Follow, it
+// then passes each new or repeated event to the handler until the context is cancelled.
+func (remote *Streamer) Run(ctx context.Context, handler func(*types.Event)) error {
+	eventClient := remote.kubeClient.CoreV1().Events(remote.LonghornNamespace)
+
+	eventList, err := eventClient.List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to list events in namespace %v", remote.LonghornNamespace)
+	}
+
+	var since time.Time
+	if remote.Since > 0 {
+		since = time.Now().Add(-remote.Since)
+	}
+
+	var events []*types.Event
+	for i := range eventList.Items {
+		event := &eventList.Items[i]
+		if !matchEvent(event, remote.kinds, remote.Volume) {
+			continue
+		}
+
+		normalized := normalizeEvent(event)
+		if normalized.Time.Before(since) {
+			continue
+		}
+		events = append(events, normalized)
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time.Before(events[j].Time)
+	})
+	for _, event := range events {
+		handler(event)
+	}
+
+	if !remote.Follow {
+		return nil
+	}
+
+	// The retry watcher resumes from the last seen resource version when the API server closes the watch.
+	watcher, err := watchtools.NewRetryWatcherWithContext(ctx, eventList.ResourceVersion, &cache.ListWatch{
+		WatchFuncWithContext: func(ctx context.Context, options metav1.ListOptions) (watch.Interface, error) {
+			return eventClient.Watch(ctx, options)
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to watch events")
+	}
+	defer watcher.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-watcher.Done():
+			return errors.New("stopped watching events")
+		case watchEvent, ok := <-watcher.ResultChan():
+			if !ok {
+				return nil
+			}
+
+			switch watchEvent.Type {
+			case watch.Added, watch.Modified:
+			case watch.Error:
+				logrus.Debugf("Received watch error: %v", watchEvent.Object)
+				continue
+			default:
+				continue
+			}
+
+			event, ok := watchEvent.Object.(*corev1.Event)
+			if !ok || !matchEvent(event, remote.kinds, remote.Volume) {
+				continue
+			}
+			handler(normalizeEvent(event))
+		}
+	}
+}
+
+// Cleanup does nothing, since the Streamer does not create any resource.
+func (remote *Streamer) Cleanup() error {
+	return nil
+}
+
+// parseKinds parses the comma-separated kinds, case-insensitively.
+func parseKinds(value string) (map[string]bool, error) {
+	supported := []string{KindVolume, KindEngine, KindReplica, KindNode}
+
+	kinds := map[string]bool{}
+	for _, entry := range strings.Split(value, consts.CmdOptSeperator) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		found := false
+		for _, kind := range supported {
+			if strings.EqualFold(entry, kind) {
+				kinds[kind] = true
+				found = true
+			}
+		}
+		if !found {
+			return nil, errors.Errorf("unsupported kind %q (--%s), supported kinds: %s", entry, consts.CmdOptKinds, strings.Join(supported, ", "))
+		}
+	}
+
+	if len(kinds) == 0 {
+		return nil, errors.Errorf("no kind (--%s) to stream the events of", consts.CmdOptKinds)
+	}
+	return kinds, nil
+}
+
+// matchEvent returns true if the event is about a Longhorn object of one of the kinds. With a volume,
+// only the events of the volume and of its engines and replicas match, which are named after the
+// volume with a "-e-" or "-r-" suffix.
+func matchEvent(event *corev1.Event, kinds map[string]bool, volume string) bool {
+	object := event.InvolvedObject
+	if !strings.HasPrefix(object.APIVersion, longhornAPIGroup+"/") || !kinds[object.Kind] {
+		return false
+	}
+
+	if volume == "" {
+		return true
+	}
+
+	switch object.Kind {
+	case KindVolume:
+		return object.Name == volume
+	case KindEngine:
+		return strings.HasPrefix(object.Name, volume+"-e-")
+	case KindReplica:
+		return strings.HasPrefix(object.Name, volume+"-r-")
+	}
+	return false
+}
+
+// normalizeEvent returns the event with the time of its last occurrence, from whichever
+// of the timestamps, event time and series the component recording it filled in.
+func normalizeEvent(event *corev1.Event) *types.Event {
+	eventTime := event.LastTimestamp.Time
+	if eventTime.IsZero() {
+		eventTime = event.EventTime.Time
+	}
+	if eventTime.IsZero() {
+		eventTime = event.FirstTimestamp.Time
+	}
+	if eventTime.IsZero() {
+		eventTime = event.CreationTimestamp.Time
+	}
+
+	count := event.Count
+	if event.Series != nil {
+		count = event.Series.Count
+		if !event.Series.LastObservedTime.IsZero() {
+			eventTime = event.Series.LastObservedTime.Time
+		}
+	}
+
+	return &types.Event{
+		Time:    eventTime,
+		Type:    event.Type,
+		Kind:    event.InvolvedObject.Kind,
+		Name:    event.InvolvedObject.Name,
+		Reason:  event.Reason,
+		Message: strings.TrimSpace(event.Message),
+		Count:   count,
+	}
+}
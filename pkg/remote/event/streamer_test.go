@@ -0,0 +1,101 @@
+package event
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func TestParseKinds(t *testing.T) {
+	kinds, err := parseKinds("volume, Replica,")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]bool{KindVolume: true, KindReplica: true}
+	if !reflect.DeepEqual(kinds, expected) {
+		t.Errorf("expected %v, got %v", expected, kinds)
+	}
+
+	for _, value := range []string{"", "Volume,Pod"} {
+		if _, err := parseKinds(value); err == nil {
+			t.Errorf("expected an error for %q", value)
+		}
+	}
+}
+
+func TestMatchEvent(t *testing.T) {
+	allKinds := map[string]bool{KindVolume: true, KindEngine: true, KindReplica: true, KindNode: true}
+
+	newEvent := func(apiVersion, kind, name string) *corev1.Event {
+		return &corev1.Event{
+			InvolvedObject: corev1.ObjectReference{APIVersion: apiVersion, Kind: kind, Name: name},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		event    *corev1.Event
+		kinds    map[string]bool
+		volume   string
+		expected bool
+	}{
+		{"volume", newEvent("longhorn.io/v1beta2", KindVolume, "pvc-1"), allKinds, "", true},
+		{"kubernetes node", newEvent("v1", KindNode, "node-1"), allKinds, "", false},
+		{"longhorn node", newEvent("longhorn.io/v1beta2", KindNode, "node-1"), allKinds, "", true},
+		{"excluded kind", newEvent("longhorn.io/v1beta2", KindEngine, "pvc-1-e-0"), map[string]bool{KindVolume: true}, "", false},
+		{"matching volume", newEvent("longhorn.io/v1beta2", KindVolume, "pvc-1"), allKinds, "pvc-1", true},
+		{"other volume", newEvent("longhorn.io/v1beta2", KindVolume, "pvc-10"), allKinds, "pvc-1", false},
+		{"engine of volume", newEvent("longhorn.io/v1beta2", KindEngine, "pvc-1-e-0"), allKinds, "pvc-1", true},
+		{"replica of volume", newEvent("longhorn.io/v1beta2", KindReplica, "pvc-1-r-8a2b3c4d"), allKinds, "pvc-1", true},
+		{"replica of other volume", newEvent("longhorn.io/v1beta2", KindReplica, "pvc-10-r-8a2b3c4d"), allKinds, "pvc-1", false},
+		{"node with volume", newEvent("longhorn.io/v1beta2", KindNode, "node-1"), allKinds, "pvc-1", false},
+	}
+
+	for _, test := range tests {
+		if result := matchEvent(test.event, test.kinds, test.volume); result != test.expected {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, result)
+		}
+	}
+}
+
+func TestNormalizeEvent(t *testing.T) {
+	first := time.Date(2024, 7, 16, 17, 0, 0, 0, time.UTC)
+	last := time.Date(2024, 7, 16, 17, 5, 0, 0, time.UTC)
+
+	event := &corev1.Event{
+		InvolvedObject: corev1.ObjectReference{Kind: KindVolume, Name: "pvc-1"},
+		Type:           corev1.EventTypeNormal,
+		Reason:         "Attached",
+		Message:        "volume pvc-1 has been attached to node-1\n",
+		FirstTimestamp: metav1.NewTime(first),
+		LastTimestamp:  metav1.NewTime(last),
+		Count:          2,
+	}
+
+	expected := &types.Event{
+		Time:    last,
+		Type:    corev1.EventTypeNormal,
+		Kind:    KindVolume,
+		Name:    "pvc-1",
+		Reason:  "Attached",
+		Message: "volume pvc-1 has been attached to node-1",
+		Count:   2,
+	}
+	if result := normalizeEvent(event); !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected %+v, got %+v", expected, result)
+	}
+
+	event.LastTimestamp = metav1.Time{}
+	event.FirstTimestamp = metav1.Time{}
+	event.EventTime = metav1.NewMicroTime(first)
+	event.Series = &corev1.EventSeries{Count: 5, LastObservedTime: metav1.NewMicroTime(last)}
+	result := normalizeEvent(event)
+	if !result.Time.Equal(last) || result.Count != 5 {
+		t.Errorf("expected the time and count of the series, got %+v", result)
+	}
+}
@@ -0,0 +1,264 @@
+package instancemanager
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeclient "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	longhorn "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+	lhclient "github.com/longhorn/longhorn-manager/k8s/pkg/client/clientset/versioned"
+	lhmgrtypes "github.com/longhorn/longhorn-manager/types"
+
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// inspectCommand lists the processes running in the instance manager pod, such as the engine
+// and replica processes of the V1 data engine, or the SPDK target of the V2 data engine.
+var inspectCommand = []string{"ps", "-eo", "pid,stat,etime,rss,args"}
+
+// Getter provide functions for retrieving the information of the instance managers.
+type Getter struct {
+	GetterCmdOptions
+
+	config         *rest.Config
+	kubeClient     *kubeclient.Clientset
+	longhornClient *lhclient.Clientset
+}
+
+// GetterCmdOptions holds the options for the command.
+type GetterCmdOptions struct {
+	types.GlobalCmdOptions
+
+	LonghornNamespace string // Namespace where Longhorn is deployed.
+	Name              string // Only retrieve the instance manager.
+	NodeID            string // Only retrieve the instance managers on the node.
+	Inspect           bool   // List the processes running in the instance manager pods.
+}
+
+// Init initializes the Getter.
+func (remote *Getter) Init() error {
+	config, err := kubeutils.NewRestConfig("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.config = config
+
+	remote.kubeClient, err = kubeutils.NewKubeClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+
+	remote.longhornClient, err = kubeutils.NewLonghornClient("", remote.KubeConfigPath)
+	return err
+}
+
+// Run returns the information of the instance managers, sorted by node and name.
+func (remote *Getter) Run() ([]types.InstanceManagerInfo, error) {
+	ctx := context.Background()
+
+	instanceManagers, err := remote.listInstanceManagers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	guaranteedCPUSettings := map[longhorn.DataEngineType]string{}
+	for dataEngine, settingName := range map[longhorn.DataEngineType]lhmgrtypes.SettingName{
+		longhorn.DataEngineTypeV1: lhmgrtypes.SettingNameGuaranteedInstanceManagerCPU,
+		longhorn.DataEngineTypeV2: lhmgrtypes.SettingNameV2DataEngineGuaranteedInstanceManagerCPU,
+	} {
+		setting, err := remote.longhornClient.LonghornV1beta2().Settings(remote.LonghornNamespace).Get(ctx, string(settingName), metav1.GetOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, errors.Wrapf(err, "failed to get setting %v", settingName)
+		}
+		guaranteedCPUSettings[dataEngine] = setting.Value
+	}
+
+	var infos []types.InstanceManagerInfo
+	for _, instanceManager := range instanceManagers {
+		info := types.InstanceManagerInfo{
+			Name:       instanceManager.Name,
+			Node:       instanceManager.Spec.NodeID,
+			Type:       string(instanceManager.Spec.Type),
+			DataEngine: string(instanceManager.Spec.DataEngine),
+			State:      string(instanceManager.Status.CurrentState),
+			Image:      instanceManager.Spec.Image,
+			APIVersion: instanceManager.Status.APIVersion,
+			Instances:  getInstanceProcesses(&instanceManager),
+		}
+
+		// The pod of an instance manager has the name of the instance manager.
+		pod, err := remote.kubeClient.CoreV1().Pods(remote.LonghornNamespace).Get(ctx, instanceManager.Name, metav1.GetOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return nil, errors.Wrapf(err, "failed to get pod of instance manager %v", instanceManager.Name)
+		}
+		if err != nil {
+			pod = nil
+		} else {
+			info.CPURequest = getPodCPURequest(pod)
+		}
+
+		guaranteedCPU, err := remote.getGuaranteedCPU(ctx, &instanceManager, guaranteedCPUSettings[instanceManager.Spec.DataEngine])
+		if err != nil {
+			logrus.WithError(err).Warnf("Failed to get the guaranteed CPU of instance manager %v", instanceManager.Name)
+		} else {
+			info.GuaranteedCPU = guaranteedCPU
+		}
+
+		if remote.Inspect && pod != nil && pod.Status.Phase == corev1.PodRunning {
+			stdout, stderr, err := kubeutils.ExecPodContainer(ctx, remote.config, remote.kubeClient, pod.Namespace, pod.Name, pod.Spec.Containers[0].Name, inspectCommand)
+			if err != nil {
+				logrus.WithError(err).Warnf("Failed to inspect instance manager %v: %v", instanceManager.Name, strings.TrimSpace(stderr))
+			}
+			info.Processes = stdout
+		}
+
+		infos = append(infos, info)
+	}
+
+	return infos, nil
+}
+
+// Cleanup does nothing, since the Getter does not create any resource.
+func (remote *Getter) Cleanup() error {
+	return nil
+}
+
+// listInstanceManagers returns the instance managers matching the options, sorted by node and name.
+func (remote *Getter) listInstanceManagers(ctx context.Context) ([]longhorn.InstanceManager, error) {
+	instanceManagerList, err := remote.longhornClient.LonghornV1beta2().InstanceManagers(remote.LonghornNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list instance managers")
+	}
+
+	var instanceManagers []longhorn.InstanceManager
+	for _, instanceManager := range instanceManagerList.Items {
+		if remote.Name != "" && instanceManager.Name != remote.Name {
+			continue
+		}
+		if remote.NodeID != "" && instanceManager.Spec.NodeID != remote.NodeID {
+			continue
+		}
+		instanceManagers = append(instanceManagers, instanceManager)
+	}
+
+	if remote.Name != "" && len(instanceManagers) == 0 {
+		return nil, errors.Errorf("instance manager %v not found", remote.Name)
+	}
+
+	sort.Slice(instanceManagers, func(i, j int) bool {
+		if instanceManagers[i].Spec.NodeID != instanceManagers[j].Spec.NodeID {
+			return instanceManagers[i].Spec.NodeID < instanceManagers[j].Spec.NodeID
+		}
+		return instanceManagers[i].Name < instanceManagers[j].Name
+	})
+	return instanceManagers, nil
+}
+
+// getGuaranteedCPU returns the CPU request the settings ask for the instance manager. The
+// instance manager CPU request of the Longhorn node overrides the setting of the V1 data engine.
+func (remote *Getter) getGuaranteedCPU(ctx context.Context, instanceManager *longhorn.InstanceManager, settingValue string) (string, error) {
+	var nodeCPURequest int
+	var allocatable resource.Quantity
+	if instanceManager.Spec.DataEngine != longhorn.DataEngineTypeV2 {
+		longhornNode, err := remote.longhornClient.LonghornV1beta2().Nodes(remote.LonghornNamespace).Get(ctx, instanceManager.Spec.NodeID, metav1.GetOptions{})
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to get Longhorn node %v", instanceManager.Spec.NodeID)
+		}
+		nodeCPURequest = longhornNode.Spec.InstanceManagerCPURequest
+
+		kubeNode, err := remote.kubeClient.CoreV1().Nodes().Get(ctx, instanceManager.Spec.NodeID, metav1.GetOptions{})
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to get node %v", instanceManager.Spec.NodeID)
+		}
+		allocatable = kubeNode.Status.Allocatable[corev1.ResourceCPU]
+	}
+
+	return calculateGuaranteedCPU(instanceManager.Spec.DataEngine, settingValue, nodeCPURequest, allocatable)
+}
+
+// calculateGuaranteedCPU returns the guaranteed CPU as a quantity. The setting of the V1 data
+// engine is a percentage of the allocatable CPU of the node, and the one of the V2 data engine
+// is in millicpu. An empty result means no CPU request.
+func calculateGuaranteedCPU(dataEngine longhorn.DataEngineType, settingValue string, nodeCPURequest int, allocatable resource.Quantity) (string, error) {
+	if dataEngine != longhorn.DataEngineTypeV2 && nodeCPURequest > 0 {
+		return resource.NewMilliQuantity(int64(nodeCPURequest), resource.DecimalSI).String(), nil
+	}
+
+	if settingValue == "" {
+		return "", nil
+	}
+
+	value, err := strconv.ParseFloat(strings.TrimSpace(settingValue), 64)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to parse guaranteed CPU setting %q", settingValue)
+	}
+	if value == 0 {
+		return "", nil
+	}
+
+	milliCPU := int64(value)
+	if dataEngine != longhorn.DataEngineTypeV2 {
+		milliCPU = int64(float64(allocatable.MilliValue()) * value / 100)
+	}
+	return resource.NewMilliQuantity(milliCPU, resource.DecimalSI).String(), nil
+}
+
+// getPodCPURequest returns the CPU request of the containers of the pod, or an empty string without request.
+func getPodCPURequest(pod *corev1.Pod) string {
+	var total resource.Quantity
+	for _, container := range pod.Spec.Containers {
+		if request, ok := container.Resources.Requests[corev1.ResourceCPU]; ok {
+			total.Add(request)
+		}
+	}
+
+	if total.IsZero() {
+		return ""
+	}
+	return resource.NewMilliQuantity(total.MilliValue(), resource.DecimalSI).String()
+}
+
+// getInstanceProcesses returns the engine and replica instances of the instance manager, sorted by name.
+// Instance managers of older versions only report them in the deprecated instances field.
+func getInstanceProcesses(instanceManager *longhorn.InstanceManager) []types.InstanceProcessInfo {
+	var processes []types.InstanceProcessInfo
+	add := func(instances map[string]longhorn.InstanceProcess) {
+		for name, instance := range instances {
+			processes = append(processes, types.InstanceProcessInfo{
+				Name:      name,
+				Type:      string(instance.Status.Type),
+				State:     string(instance.Status.State),
+				PortStart: instance.Status.PortStart,
+				PortEnd:   instance.Status.PortEnd,
+				Error:     instance.Status.ErrorMsg,
+			})
+		}
+	}
+
+	add(instanceManager.Status.InstanceEngines)
+	add(instanceManager.Status.InstanceReplicas)
+	if len(processes) == 0 {
+		add(instanceManager.Status.Instances)
+	}
+
+	sort.Slice(processes, func(i, j int) bool {
+		return processes[i].Name < processes[j].Name
+	})
+	return processes
+}
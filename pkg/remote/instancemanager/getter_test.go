@@ -0,0 +1,93 @@
+package instancemanager
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	longhorn "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func TestCalculateGuaranteedCPU(t *testing.T) {
+	tests := []struct {
+		name           string
+		dataEngine     longhorn.DataEngineType
+		settingValue   string
+		nodeCPURequest int
+		allocatable    string
+		expected       string
+		expectErr      bool
+	}{
+		{"v1 percentage", longhorn.DataEngineTypeV1, "12", 0, "4", "480m", false},
+		{"v1 node override", longhorn.DataEngineTypeV1, "12", 1000, "4", "1", false},
+		{"v1 unset", longhorn.DataEngineTypeV1, "0", 0, "4", "", false},
+		{"v2 millicpu", longhorn.DataEngineTypeV2, "1250", 1000, "4", "1250m", false},
+		{"no setting", longhorn.DataEngineTypeV2, "", 0, "4", "", false},
+		{"invalid setting", longhorn.DataEngineTypeV1, "{\"v1\":\"12\"}", 0, "4", "", true},
+	}
+
+	for _, test := range tests {
+		result, err := calculateGuaranteedCPU(test.dataEngine, test.settingValue, test.nodeCPURequest, resource.MustParse(test.allocatable))
+		if test.expectErr {
+			if err == nil {
+				t.Errorf("%s: expected an error", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		if result != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.name, test.expected, result)
+		}
+	}
+}
+
+func TestGetPodCPURequest(t *testing.T) {
+	pod := &corev1.Pod{}
+	if request := getPodCPURequest(pod); request != "" {
+		t.Errorf("expected no request, got %q", request)
+	}
+
+	pod.Spec.Containers = []corev1.Container{
+		{Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("480m")}}},
+	}
+	if request := getPodCPURequest(pod); request != "480m" {
+		t.Errorf("expected 480m, got %q", request)
+	}
+}
+
+func TestGetInstanceProcesses(t *testing.T) {
+	instanceManager := &longhorn.InstanceManager{
+		Status: longhorn.InstanceManagerStatus{
+			InstanceReplicas: map[string]longhorn.InstanceProcess{
+				"pvc-1-r-abc": {Status: longhorn.InstanceProcessStatus{Type: longhorn.InstanceTypeReplica, State: longhorn.InstanceStateRunning, PortStart: 10010, PortEnd: 10019}},
+			},
+			InstanceEngines: map[string]longhorn.InstanceProcess{
+				"pvc-1-e-0": {Status: longhorn.InstanceProcessStatus{Type: longhorn.InstanceTypeEngine, State: longhorn.InstanceStateError, ErrorMsg: "failed"}},
+			},
+		},
+	}
+
+	expected := []types.InstanceProcessInfo{
+		{Name: "pvc-1-e-0", Type: "engine", State: "error", Error: "failed"},
+		{Name: "pvc-1-r-abc", Type: "replica", State: "running", PortStart: 10010, PortEnd: 10019},
+	}
+	if processes := getInstanceProcesses(instanceManager); !reflect.DeepEqual(processes, expected) {
+		t.Errorf("expected %+v, got %+v", expected, processes)
+	}
+
+	instanceManager.Status = longhorn.InstanceManagerStatus{
+		Instances: map[string]longhorn.InstanceProcess{
+			"pvc-2-e-0": {Status: longhorn.InstanceProcessStatus{Type: longhorn.InstanceTypeEngine, State: longhorn.InstanceStateRunning}},
+		},
+	}
+	if processes := getInstanceProcesses(instanceManager); len(processes) != 1 || processes[0].Name != "pvc-2-e-0" {
+		t.Errorf("expected the deprecated instances, got %+v", processes)
+	}
+}
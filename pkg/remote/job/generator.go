@@ -0,0 +1,333 @@
+package job
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/yaml"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+)
+
+// Generator provide functions for generating a Job manifest that runs a longhornctl subcommand in the cluster.
+type Generator struct {
+	GeneratorCmdOptions
+
+	appName   string // Name of the Job and its RBAC resources.
+	namespace string
+}
+
+// GeneratorCmdOptions holds the options for the command.
+type GeneratorCmdOptions struct {
+	types.GlobalCmdOptions
+
+	Name string   // Name of the Job. Defaults to the subcommand joined by dashes.
+	Args []string // The longhornctl subcommand and options to run in the Job.
+}
+
+// Validate validates the command options.
+func (remote *Generator) Validate() error {
+	if len(remote.Args) == 0 {
+		return errors.Errorf("subcommand to run in the Job is required, for example: %s %s %s -- %s %s", consts.CmdLonghornctlRemote, consts.SubCmdGenerate, consts.SubCmdJob, consts.SubCmdCheck, consts.SubCmdPreflight)
+	}
+
+	if remote.Args[0] == consts.SubCmdGenerate {
+		return errors.Errorf("cannot run %q in a Job", consts.SubCmdGenerate)
+	}
+
+	return nil
+}
+
+// Init initializes the Generator.
+func (remote *Generator) Init() error {
+	remote.namespace = remote.Namespace
+	if remote.namespace == "" {
+		remote.namespace = consts.LonghornNamespace
+	}
+
+	remote.appName = remote.Name
+	if remote.appName == "" {
+		var subcommands []string
+		for _, arg := range remote.Args {
+			if strings.HasPrefix(arg, "-") {
+				break
+			}
+			subcommands = append(subcommands, arg)
+		}
+		remote.appName = strings.Join(append([]string{consts.AppNameJob}, subcommands...), "-")
+	}
+
+	return nil
+}
+
+// Run returns the ServiceAccount, ClusterRole, ClusterRoleBinding and Job as a multi-document YAML string.
+func (remote *Generator) Run() (string, error) {
+	var documents []string
+	for _, object := range remote.Objects() {
+		yamlData, err := yaml.Marshal(object)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to convert %T to YAML", object)
+		}
+		documents = append(documents, string(yamlData))
+	}
+
+	return strings.Join(documents, "---\n"), nil
+}
+
+// Objects returns the ServiceAccount, ClusterRole, ClusterRoleBinding and Job, in the order to create them.
+func (remote *Generator) Objects() []runtime.Object {
+	return []runtime.Object{
+		remote.newServiceAccount(),
+		remote.newClusterRole(),
+		remote.newClusterRoleBinding(),
+		remote.newJob(),
+	}
+}
+
+// commandArgs returns the longhornctl arguments for the Job container, with the
+// global options of this invocation forwarded to the subcommand.
+func (remote *Generator) commandArgs() []string {
+	args := append([]string{}, remote.Args...)
+	args = append(args,
+		fmt.Sprintf("--%s=%s", consts.CmdOptLogLevel, remote.LogLevel),
+		fmt.Sprintf("--%s=%s", consts.CmdOptLogFormat, remote.LogFormat),
+		fmt.Sprintf("--%s=%s", consts.CmdOptImage, remote.Image),
+		fmt.Sprintf("--%s=%s", consts.CmdOptNamespace, remote.namespace),
+		fmt.Sprintf("--%s", consts.CmdOptYes),
+	)
+
+	if remote.NodeSelector != "" {
+		args = append(args, fmt.Sprintf("--%s=%s", consts.CmdOptNodeSelector, remote.NodeSelector))
+	}
+	if remote.MinNodes > 0 {
+		args = append(args, fmt.Sprintf("--%s=%d", consts.CmdOptRequireMinNodes, remote.MinNodes))
+	}
+	if remote.PodCpu != "" {
+		args = append(args, fmt.Sprintf("--%s=%s", consts.CmdOptPodCpu, remote.PodCpu))
+	}
+	if remote.PodMemory != "" {
+		args = append(args, fmt.Sprintf("--%s=%s", consts.CmdOptPodMemory, remote.PodMemory))
+	}
+	if remote.PriorityClass != "" {
+		args = append(args, fmt.Sprintf("--%s=%s", consts.CmdOptPriorityClass, remote.PriorityClass))
+	}
+	if remote.Proxy != "" {
+		args = append(args, fmt.Sprintf("--%s=%s", consts.CmdOptProxy, remote.Proxy))
+	}
+	if remote.NoProxy != "" {
+		args = append(args, fmt.Sprintf("--%s=%s", consts.CmdOptNoProxy, remote.NoProxy))
+	}
+	if !remote.Privileged {
+		args = append(args, fmt.Sprintf("--%s=false", consts.CmdOptPrivileged))
+	}
+
+	return args
+}
+
+func (remote *Generator) newServiceAccount() *corev1.ServiceAccount {
+	return &corev1.ServiceAccount{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1.SchemeGroupVersion.String(),
+			Kind:       "ServiceAccount",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      remote.appName,
+			Namespace: remote.namespace,
+			Labels: map[string]string{
+				"app": remote.appName,
+			},
+		},
+	}
+}
+
+// newClusterRole prepares the permissions required by the CLI to deploy and
+// monitor its node agents, to read and update Longhorn resources, and to manage
+// the Kubernetes resources the subcommands operate on. The CLI only creates the
+// ClusterRoles and ClusterRoleBindings of its node agents, with permissions the
+// Job already holds, so it is not allowed to update or bind any other role.
+func (remote *Generator) newClusterRole() *rbacv1.ClusterRole {
+	return &rbacv1.ClusterRole{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: rbacv1.SchemeGroupVersion.String(),
+			Kind:       "ClusterRole",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: remote.appName,
+			Labels: map[string]string{
+				"app": remote.appName,
+			},
+		},
+		Rules: []rbacv1.PolicyRule{
+			{
+				APIGroups: []string{""},
+				Resources: []string{"namespaces", "nodes"},
+				Verbs:     []string{"get", "list", "watch", "create"},
+			},
+			{
+				APIGroups: []string{""},
+				Resources: []string{"nodes"},
+				Verbs:     []string{"patch"},
+			},
+			{
+				APIGroups: []string{""},
+				Resources: []string{"pods", "pods/log", "configmaps", "serviceaccounts", "events"},
+				Verbs:     []string{"*"},
+			},
+			{
+				APIGroups: []string{""},
+				Resources: []string{"pods/eviction", "pods/exec", "pods/portforward"},
+				Verbs:     []string{"create"},
+			},
+			{
+				APIGroups: []string{""},
+				Resources: []string{"secrets", "persistentvolumes", "persistentvolumeclaims"},
+				Verbs:     []string{"get", "list", "watch", "create", "update", "patch", "delete"},
+			},
+			{
+				APIGroups: []string{"apps"},
+				Resources: []string{"daemonsets", "deployments"},
+				Verbs:     []string{"*"},
+			},
+			{
+				APIGroups: []string{"batch"},
+				Resources: []string{"jobs"},
+				Verbs:     []string{"get", "list", "watch", "create", "delete"},
+			},
+			{
+				APIGroups: []string{"coordination.k8s.io"},
+				Resources: []string{"leases"},
+				Verbs:     []string{"get", "list", "create", "update", "delete"},
+			},
+			{
+				APIGroups: []string{"storage.k8s.io"},
+				Resources: []string{"storageclasses"},
+				Verbs:     []string{"get", "list", "update", "patch"},
+			},
+			{
+				APIGroups: []string{"snapshot.storage.k8s.io"},
+				Resources: []string{"volumesnapshots", "volumesnapshotcontents", "volumesnapshotclasses"},
+				Verbs:     []string{"get", "list", "create", "patch", "delete"},
+			},
+			{
+				// Creation cannot be restricted to resource names.
+				APIGroups: []string{rbacv1.GroupName},
+				Resources: []string{"clusterroles", "clusterrolebindings"},
+				Verbs:     []string{"create"},
+			},
+			{
+				APIGroups:     []string{rbacv1.GroupName},
+				Resources:     []string{"clusterroles", "clusterrolebindings"},
+				ResourceNames: []string{consts.AppNamePreflightChecker, consts.AppNameVolumeTrimmer},
+				Verbs:         []string{"get", "delete"},
+			},
+			{
+				APIGroups: []string{"longhorn.io"},
+				Resources: []string{"*"},
+				Verbs:     []string{"*"},
+			},
+		},
+	}
+}
+
+func (remote *Generator) newClusterRoleBinding() *rbacv1.ClusterRoleBinding {
+	return &rbacv1.ClusterRoleBinding{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: rbacv1.SchemeGroupVersion.String(),
+			Kind:       "ClusterRoleBinding",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: remote.appName,
+			Labels: map[string]string{
+				"app": remote.appName,
+			},
+		},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "ClusterRole",
+			Name:     remote.appName,
+		},
+		Subjects: []rbacv1.Subject{
+			{
+				Kind:      "ServiceAccount",
+				Name:      remote.appName,
+				Namespace: remote.namespace,
+			},
+		},
+	}
+}
+
+// newJob prepares the Job running longhornctl with the in-cluster config.
+func (remote *Generator) newJob() *batchv1.Job {
+	return &batchv1.Job{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: batchv1.SchemeGroupVersion.String(),
+			Kind:       "Job",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      remote.appName,
+			Namespace: remote.namespace,
+			Labels: map[string]string{
+				"app": remote.appName,
+			},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: ptr.To[int32](0),
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						"app": remote.appName,
+					},
+				},
+				Spec: corev1.PodSpec{
+					ServiceAccountName: remote.appName,
+					RestartPolicy:      corev1.RestartPolicyNever,
+					PriorityClassName:  remote.PriorityClass,
+					Containers: []corev1.Container{
+						{
+							Name:    consts.ContainerName,
+							Image:   remote.Image,
+							Command: append([]string{consts.CmdLonghornctlRemote}, remote.commandArgs()...),
+							// The pod and node running the CLI are not drained nor rebooted by the install.
+							Env: []corev1.EnvVar{
+								{
+									Name: consts.EnvCurrentNodeID,
+									ValueFrom: &corev1.EnvVarSource{
+										FieldRef: &corev1.ObjectFieldSelector{
+											FieldPath: "spec.nodeName",
+										},
+									},
+								},
+								{
+									Name: consts.EnvPodName,
+									ValueFrom: &corev1.EnvVarSource{
+										FieldRef: &corev1.ObjectFieldSelector{
+											FieldPath: "metadata.name",
+										},
+									},
+								},
+								{
+									Name: consts.EnvPodNamespace,
+									ValueFrom: &corev1.EnvVarSource{
+										FieldRef: &corev1.ObjectFieldSelector{
+											FieldPath: "metadata.namespace",
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
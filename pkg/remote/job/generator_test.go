@@ -0,0 +1,121 @@
+package job
+
+import (
+	"slices"
+	"testing"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils/golden"
+)
+
+func TestGeneratorRun(t *testing.T) {
+	tests := map[string]GeneratorCmdOptions{
+		"preflight": {
+			GlobalCmdOptions: types.GlobalCmdOptions{
+				LogLevel:   "info",
+				LogFormat:  "text",
+				Image:      "longhornio/longhorn-cli:v1.7.2",
+				Privileged: true,
+			},
+			Args: []string{"check", "preflight", "--enable-spdk"},
+		},
+		"install-options": {
+			GlobalCmdOptions: types.GlobalCmdOptions{
+				LogLevel:      "debug",
+				LogFormat:     "json",
+				Image:         "longhornio/longhorn-cli:v1.7.2",
+				Namespace:     "storage",
+				NodeSelector:  "storage=true",
+				PodCpu:        "100m",
+				PodMemory:     "128Mi",
+				PriorityClass: "system-node-critical",
+				Proxy:         "http://proxy:3128",
+				NoProxy:       "10.0.0.0/8",
+			},
+			Name: "install",
+			Args: []string{"install", "preflight"},
+		},
+	}
+
+	for name, options := range tests {
+		t.Run(name, func(t *testing.T) {
+			generator := &Generator{GeneratorCmdOptions: options}
+			if err := generator.Validate(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := generator.Init(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			output, err := generator.Run()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			golden.Assert(t, name, []byte(output))
+		})
+	}
+}
+
+func TestGeneratorClusterRole(t *testing.T) {
+	generator := &Generator{GeneratorCmdOptions: GeneratorCmdOptions{Args: []string{"check", "preflight"}}}
+	if err := generator.Init(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rules := generator.newClusterRole().Rules
+
+	type permission struct {
+		group, resource, verb, name string
+	}
+	allowed := []permission{
+		{group: "coordination.k8s.io", resource: "leases", verb: "create"},
+		{group: "coordination.k8s.io", resource: "leases", verb: "update"},
+		{group: "coordination.k8s.io", resource: "leases", verb: "delete"},
+		{group: "batch", resource: "jobs", verb: "create"},
+		{group: "", resource: "secrets", verb: "get"},
+		{group: "", resource: "secrets", verb: "create"},
+		{group: "", resource: "persistentvolumes", verb: "create"},
+		{group: "", resource: "persistentvolumeclaims", verb: "delete"},
+		{group: "storage.k8s.io", resource: "storageclasses", verb: "patch"},
+		{group: "snapshot.storage.k8s.io", resource: "volumesnapshots", verb: "create"},
+		{group: "snapshot.storage.k8s.io", resource: "volumesnapshotcontents", verb: "patch"},
+		{group: "rbac.authorization.k8s.io", resource: "clusterroles", verb: "create"},
+		{group: "rbac.authorization.k8s.io", resource: "clusterrolebindings", verb: "delete", name: consts.AppNamePreflightChecker},
+		{group: "rbac.authorization.k8s.io", resource: "clusterroles", verb: "get", name: consts.AppNameVolumeTrimmer},
+	}
+	denied := []permission{
+		{group: "rbac.authorization.k8s.io", resource: "clusterroles", verb: "update", name: consts.AppNamePreflightChecker},
+		{group: "rbac.authorization.k8s.io", resource: "clusterroles", verb: "escalate"},
+		{group: "rbac.authorization.k8s.io", resource: "clusterroles", verb: "bind", name: "cluster-admin"},
+		{group: "rbac.authorization.k8s.io", resource: "clusterrolebindings", verb: "delete", name: "cluster-admin"},
+		{group: "rbac.authorization.k8s.io", resource: "rolebindings", verb: "create"},
+	}
+
+	for _, p := range allowed {
+		if !isAllowed(rules, p.group, p.resource, p.verb, p.name) {
+			t.Errorf("expected %q on %q %q named %q to be allowed", p.verb, p.group, p.resource, p.name)
+		}
+	}
+	for _, p := range denied {
+		if isAllowed(rules, p.group, p.resource, p.verb, p.name) {
+			t.Errorf("expected %q on %q %q named %q to be denied", p.verb, p.group, p.resource, p.name)
+		}
+	}
+}
+
+// isAllowed returns true when a rule grants the verb on the resource, following the RBAC matching rules.
+func isAllowed(rules []rbacv1.PolicyRule, group, resource, verb, name string) bool {
+	for _, rule := range rules {
+		if matches(rule.APIGroups, group) && matches(rule.Resources, resource) && matches(rule.Verbs, verb) &&
+			(len(rule.ResourceNames) == 0 || slices.Contains(rule.ResourceNames, name)) {
+			return true
+		}
+	}
+	return false
+}
+
+func matches(values []string, value string) bool {
+	return slices.Contains(values, "*") || slices.Contains(values, value)
+}
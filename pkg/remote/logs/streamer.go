@@ -0,0 +1,226 @@
+package logs
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	kubeclient "k8s.io/client-go/kubernetes"
+	"k8s.io/utils/ptr"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// Longhorn components whose logs can be streamed.
+const (
+	ComponentCSI             = "csi"
+	ComponentInstanceManager = "instance-manager"
+	ComponentManager         = "manager"
+)
+
+// componentLabelSelectors are the label selectors of the pods of each component.
+var componentLabelSelectors = map[string]string{
+	ComponentCSI:             consts.LonghornLabelSelectorCSI,
+	ComponentInstanceManager: consts.LonghornLabelSelectorInstanceManager,
+	ComponentManager:         consts.LonghornLabelSelectorManager,
+}
+
+// maxLogLineSize is the longest log line read, since some components log whole objects on a line.
+const maxLogLineSize = 1024 * 1024
+
+// Streamer provide functions for streaming the logs of the pods of the Longhorn components.
+type Streamer struct {
+	StreamerCmdOptions
+
+	kubeClient *kubeclient.Clientset
+
+	components []string
+	grep       *regexp.Regexp
+}
+
+// StreamerCmdOptions holds the options for the command.
+type StreamerCmdOptions struct {
+	types.GlobalCmdOptions
+
+	LonghornNamespace string        // Namespace where Longhorn is deployed.
+	Components        string        // Comma-separated components to stream the logs of.
+	Node              string        // Only stream the logs of the pods on the node.
+	Since             time.Duration // Only stream the logs newer than this. Streams the whole logs when zero.
+	Follow            bool          // Keep streaming the new logs.
+	Grep              string        // Only stream the lines matching the regular expression.
+}
+
+// Validate validates the command options.
+func (remote *Streamer) Validate() error {
+	if _, err := parseComponents(remote.Components); err != nil {
+		return err
+	}
+
+	if _, err := regexp.Compile(remote.Grep); err != nil {
+		return errors.Wrapf(err, "invalid regular expression (--%s) %q", consts.CmdOptGrep, remote.Grep)
+	}
+
+	if remote.Since < 0 {
+		return errors.Errorf("since (--%s) must not be negative", consts.CmdOptSince)
+	}
+
+	return nil
+}
+
+// Init initializes the Streamer.
+func (remote *Streamer) Init() error {
+	kubeClient, err := kubeutils.NewKubeClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.kubeClient = kubeClient
+
+	remote.components, err = parseComponents(remote.Components)
+	if err != nil {
+		return err
+	}
+
+	remote.grep, err = regexp.Compile(remote.Grep)
+	return err
+}
+
+// Run streams the logs of all the containers of the pods of the components concurrently, and
+// passes each matching line to the handler with the pod and container as prefix. With Follow,
+// it streams until the context is cancelled. The pods created afterwards are not streamed.
+func (remote *Streamer) Run(ctx context.Context, handler func(line string)) error {
+	pods, err := remote.listPods(ctx)
+	if err != nil {
+		return err
+	}
+	if len(pods) == 0 {
+		return errors.Errorf("no pod of %v found", strings.Join(remote.components, ", "))
+	}
+
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for _, pod := range pods {
+		for _, container := range pod.Spec.Containers {
+			prefix := getLinePrefix(&pod, container.Name)
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				err := remote.streamContainer(ctx, &pod, container.Name, func(line string) {
+					if !remote.grep.MatchString(line) {
+						return
+					}
+
+					mutex.Lock()
+					defer mutex.Unlock()
+					handler(prefix + line)
+				})
+				if err != nil && ctx.Err() == nil {
+					logrus.WithError(err).Warnf("Failed to stream the logs of %v", strings.TrimSpace(prefix))
+				}
+			}()
+		}
+	}
+	wg.Wait()
+
+	return nil
+}
+
+// Cleanup does nothing, since the Streamer does not create any resource.
+func (remote *Streamer) Cleanup() error {
+	return nil
+}
+
+// listPods returns the pods of the components, on the node if any, sorted by name.
+func (remote *Streamer) listPods(ctx context.Context) ([]corev1.Pod, error) {
+	listOptions := metav1.ListOptions{}
+	if remote.Node != "" {
+		listOptions.FieldSelector = fields.OneTermEqualSelector("spec.nodeName", remote.Node).String()
+	}
+
+	var pods []corev1.Pod
+	for _, component := range remote.components {
+		listOptions.LabelSelector = componentLabelSelectors[component]
+
+		podList, err := remote.kubeClient.CoreV1().Pods(remote.LonghornNamespace).List(ctx, listOptions)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to list pods of %v", component)
+		}
+		pods = append(pods, podList.Items...)
+	}
+
+	sort.Slice(pods, func(i, j int) bool {
+		return pods[i].Name < pods[j].Name
+	})
+	return pods, nil
+}
+
+// streamContainer passes each line of the log of the container to the handler.
+func (remote *Streamer) streamContainer(ctx context.Context, pod *corev1.Pod, containerName string, handler func(line string)) error {
+	logOptions := &corev1.PodLogOptions{
+		Container: containerName,
+		Follow:    remote.Follow,
+	}
+	if remote.Since > 0 {
+		logOptions.SinceSeconds = ptr.To(int64(remote.Since.Seconds()))
+	}
+
+	stream, err := remote.kubeClient.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, logOptions).Stream(ctx)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	scanner := bufio.NewScanner(stream)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLogLineSize)
+	for scanner.Scan() {
+		handler(scanner.Text())
+	}
+	return scanner.Err()
+}
+
+// parseComponents parses the comma-separated components.
+func parseComponents(value string) ([]string, error) {
+	supported := []string{ComponentManager, ComponentInstanceManager, ComponentCSI}
+
+	var components []string
+	for _, component := range strings.Split(value, consts.CmdOptSeperator) {
+		component = strings.TrimSpace(component)
+		if component == "" {
+			continue
+		}
+
+		if _, ok := componentLabelSelectors[component]; !ok {
+			return nil, errors.Errorf("unsupported component %q (--%s), supported components: %s", component, consts.CmdOptComponent, strings.Join(supported, ", "))
+		}
+		components = append(components, component)
+	}
+
+	if len(components) == 0 {
+		return nil, errors.Errorf("no component (--%s) to stream the logs of", consts.CmdOptComponent)
+	}
+	return components, nil
+}
+
+// getLinePrefix returns the prefix of the lines of the container. The container is omitted for
+// single-container pods, such as the managers and the instance managers.
+func getLinePrefix(pod *corev1.Pod, containerName string) string {
+	if len(pod.Spec.Containers) == 1 {
+		return fmt.Sprintf("[%s] ", pod.Name)
+	}
+	return fmt.Sprintf("[%s/%s] ", pod.Name, containerName)
+}
@@ -0,0 +1,58 @@
+package logs
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseComponents(t *testing.T) {
+	tests := []struct {
+		value     string
+		expected  []string
+		expectErr bool
+	}{
+		{"manager", []string{"manager"}, false},
+		{"manager, csi,", []string{"manager", "csi"}, false},
+		{"", nil, true},
+		{"manager,ui", nil, true},
+	}
+
+	for _, test := range tests {
+		components, err := parseComponents(test.value)
+		if test.expectErr {
+			if err == nil {
+				t.Errorf("expected an error for %q", test.value)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error for %q: %v", test.value, err)
+			continue
+		}
+		if !reflect.DeepEqual(components, test.expected) {
+			t.Errorf("expected %v for %q, got %v", test.expected, test.value, components)
+		}
+	}
+}
+
+func TestGetLinePrefix(t *testing.T) {
+	newPod := func(name string, containers ...string) *corev1.Pod {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		for _, container := range containers {
+			pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{Name: container})
+		}
+		return pod
+	}
+
+	if prefix := getLinePrefix(newPod("longhorn-manager-abcde", "longhorn-manager"), "longhorn-manager"); prefix != "[longhorn-manager-abcde] " {
+		t.Errorf("unexpected prefix %q", prefix)
+	}
+
+	pod := newPod("longhorn-csi-plugin-abcde", "node-driver-registrar", "longhorn-csi-plugin")
+	if prefix := getLinePrefix(pod, "longhorn-csi-plugin"); prefix != "[longhorn-csi-plugin-abcde/longhorn-csi-plugin] " {
+		t.Errorf("unexpected prefix %q", prefix)
+	}
+}
@@ -0,0 +1,419 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/robfig/cron"
+	"gopkg.in/yaml.v3"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	lhmgrtypes "github.com/longhorn/longhorn-manager/types"
+
+	longhorn "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+const (
+	longhornAPIGroup   = "longhorn.io"
+	longhornAPIVersion = "v1beta2"
+
+	// csiParameterPrefix prefixes the StorageClass parameters handled by the CSI sidecars.
+	csiParameterPrefix = "csi.storage.k8s.io/"
+
+	maxReplicaCount = 20
+)
+
+// Choices of the enumerated fields, as defined by the Longhorn CustomResourceDefinitions.
+var (
+	accessModeChoices            = []string{"", string(longhorn.AccessModeReadWriteOnce), string(longhorn.AccessModeReadWriteMany)}
+	dataEngineChoices            = []string{"", string(longhorn.DataEngineTypeV1), string(longhorn.DataEngineTypeV2)}
+	dataLocalityChoices          = []string{"", string(longhorn.DataLocalityDisabled), string(longhorn.DataLocalityBestEffort), string(longhorn.DataLocalityStrictLocal)}
+	frontendChoices              = []string{"", string(longhorn.VolumeFrontendBlockDev), string(longhorn.VolumeFrontendISCSI), string(longhorn.VolumeFrontendNvmf), string(longhorn.VolumeFrontendUblk)}
+	replicaAutoBalanceChoices    = []string{"", string(longhorn.ReplicaAutoBalanceIgnored), string(longhorn.ReplicaAutoBalanceDisabled), string(longhorn.ReplicaAutoBalanceLeastEffort), string(longhorn.ReplicaAutoBalanceBestEffort)}
+	snapshotDataIntegrityChoices = []string{"", string(longhorn.SnapshotDataIntegrityIgnored), string(longhorn.SnapshotDataIntegrityDisabled), string(longhorn.SnapshotDataIntegrityEnabled), string(longhorn.SnapshotDataIntegrityFastCheck)}
+	toggleChoices                = []string{"", "ignored", "enabled", "disabled"}
+	recurringJobTaskChoices      = []string{
+		string(longhorn.RecurringJobTypeSnapshot),
+		string(longhorn.RecurringJobTypeSnapshotForceCreate),
+		string(longhorn.RecurringJobTypeSnapshotCleanup),
+		string(longhorn.RecurringJobTypeSnapshotDelete),
+		string(longhorn.RecurringJobTypeBackup),
+		string(longhorn.RecurringJobTypeBackupForceCreate),
+		string(longhorn.RecurringJobTypeFilesystemTrim),
+		string(longhorn.RecurringJobTypeSystemBackup),
+	}
+)
+
+// storageClassParameters validates the value of each StorageClass parameter of the Longhorn CSI driver.
+var storageClassParameters = map[string]func(value string) error{
+	"backingImage":                     nil,
+	"backingImageChecksum":             nil,
+	"backingImageDataSourceParameters": validateJSONObject,
+	"backingImageDataSourceType":       nil,
+	"backupTargetName":                 nil,
+	"dataEngine":                       validateChoice(dataEngineChoices),
+	"dataLocality":                     validateChoice(dataLocalityChoices),
+	"disableRevisionCounter":           validateBool,
+	"diskSelector":                     nil,
+	"encrypted":                        validateBool,
+	"freezeFilesystemForSnapshot":      validateChoice(toggleChoices),
+	"fromBackup":                       nil,
+	"fsType":                           nil,
+	"migratable":                       validateBool,
+	"mkfsParams":                       nil,
+	"nfsOptions":                       nil,
+	"nodeSelector":                     nil,
+	"numberOfReplicas":                 validateReplicaCount,
+	"recurringJobSelector":             validateRecurringJobSelector,
+	"replicaAutoBalance":               validateChoice(replicaAutoBalanceChoices),
+	"replicaDiskSoftAntiAffinity":      validateChoice(toggleChoices),
+	"replicaSoftAntiAffinity":          validateChoice(toggleChoices),
+	"replicaZoneSoftAntiAffinity":      validateChoice(toggleChoices),
+	"snapshotDataIntegrity":            validateChoice(snapshotDataIntegrityChoices),
+	"snapshotMaxCount":                 validateNonNegativeInt,
+	"snapshotMaxSize":                  validateQuantity,
+	"staleReplicaTimeout":              validateNonNegativeInt,
+	"unmapMarkSnapChainRemoved":        validateChoice(toggleChoices),
+}
+
+// encryptionSecretParameters are the StorageClass parameters required by encrypted volumes.
+var encryptionSecretParameters = []string{
+	csiParameterPrefix + "provisioner-secret-name",
+	csiParameterPrefix + "node-publish-secret-name",
+	csiParameterPrefix + "node-stage-secret-name",
+}
+
+// validateStorageClass validates the parameters of a StorageClass of the Longhorn CSI driver.
+// It returns nil for the StorageClasses of the other provisioners.
+func validateStorageClass(raw []byte) *types.LogCollection {
+	var storageClass storagev1.StorageClass
+	if err := decodeStrict(raw, &storageClass); err != nil {
+		return &types.LogCollection{Error: []string{fmt.Sprintf("Invalid StorageClass: %v", err)}}
+	}
+	if storageClass.Provisioner != lhmgrtypes.LonghornDriverName {
+		return nil
+	}
+
+	collection := &types.LogCollection{}
+	parameters := storageClass.Parameters
+
+	names := make([]string, 0, len(parameters))
+	for name := range parameters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if strings.HasPrefix(name, csiParameterPrefix) {
+			continue
+		}
+
+		validate, ok := storageClassParameters[name]
+		if !ok {
+			collection.Warn = append(collection.Warn, fmt.Sprintf("Parameter %v is unknown to Longhorn and ignored", name))
+			continue
+		}
+		if validate == nil {
+			continue
+		}
+		if err := validate(parameters[name]); err != nil {
+			collection.Error = append(collection.Error, fmt.Sprintf("Parameter %v: %v", name, err))
+		}
+	}
+
+	if parameters["dataLocality"] == string(longhorn.DataLocalityStrictLocal) && parameters["numberOfReplicas"] != "1" {
+		collection.Error = append(collection.Error, "Data locality strict-local requires numberOfReplicas 1")
+	}
+
+	if encrypted, _ := strconv.ParseBool(parameters["encrypted"]); encrypted {
+		var missing []string
+		for _, name := range encryptionSecretParameters {
+			if parameters[name] == "" {
+				missing = append(missing, name)
+			}
+		}
+		if len(missing) > 0 {
+			collection.Error = append(collection.Error, fmt.Sprintf("Encrypted volumes require parameters %v", strings.Join(missing, ", ")))
+		}
+	}
+
+	return collection
+}
+
+// validateRecurringJob validates a RecurringJob against its API type and the rules of Longhorn.
+func validateRecurringJob(raw []byte) *types.LogCollection {
+	var recurringJob longhorn.RecurringJob
+	if err := decodeStrict(raw, &recurringJob); err != nil {
+		return &types.LogCollection{Error: []string{fmt.Sprintf("Invalid RecurringJob: %v", err)}}
+	}
+
+	collection := &types.LogCollection{}
+	spec := recurringJob.Spec
+
+	if spec.Name != "" && spec.Name != recurringJob.Name {
+		collection.Error = append(collection.Error, fmt.Sprintf("Name %v differs from the object name %v", spec.Name, recurringJob.Name))
+	}
+
+	if err := validateChoice(recurringJobTaskChoices)(string(spec.Task)); err != nil {
+		collection.Error = append(collection.Error, fmt.Sprintf("Task: %v", err))
+	}
+
+	if _, err := cron.ParseStandard(spec.Cron); err != nil {
+		collection.Error = append(collection.Error, fmt.Sprintf("Invalid cron %q: %v", spec.Cron, err))
+	}
+
+	switch spec.Task {
+	case longhorn.RecurringJobTypeSnapshotCleanup, longhorn.RecurringJobTypeFilesystemTrim:
+	default:
+		if spec.Retain < 1 || spec.Retain > lhmgrtypes.MaxSnapshotNum {
+			collection.Error = append(collection.Error, fmt.Sprintf("Retain %d is out of the range [1, %d]", spec.Retain, lhmgrtypes.MaxSnapshotNum))
+		}
+	}
+
+	if spec.Concurrency < 1 {
+		collection.Error = append(collection.Error, fmt.Sprintf("Concurrency %d must be positive", spec.Concurrency))
+	}
+
+	for name, value := range spec.Parameters {
+		switch name {
+		case lhmgrtypes.RecurringJobParameterFullBackupInterval:
+			if err := validateNonNegativeInt(value); err != nil {
+				collection.Error = append(collection.Error, fmt.Sprintf("Parameter %v: %v", name, err))
+			}
+		case lhmgrtypes.RecurringJobParameterVolumeBackupPolicy:
+		default:
+			collection.Warn = append(collection.Warn, fmt.Sprintf("Parameter %v is unknown to Longhorn and ignored", name))
+		}
+	}
+
+	return collection
+}
+
+// validateVolume validates a Volume against its API type and the rules of Longhorn.
+func validateVolume(raw []byte) *types.LogCollection {
+	var volume longhorn.Volume
+	if err := decodeStrict(raw, &volume); err != nil {
+		return &types.LogCollection{Error: []string{fmt.Sprintf("Invalid Volume: %v", err)}}
+	}
+
+	collection := &types.LogCollection{}
+	spec := volume.Spec
+
+	if spec.Size <= 0 {
+		collection.Error = append(collection.Error, "Size must be positive")
+	}
+
+	if spec.NumberOfReplicas < 0 || spec.NumberOfReplicas > maxReplicaCount {
+		collection.Error = append(collection.Error, fmt.Sprintf("Number of replicas %d is out of the range [1, %d]", spec.NumberOfReplicas, maxReplicaCount))
+	}
+
+	choices := []struct {
+		field   string
+		value   string
+		choices []string
+	}{
+		{"accessMode", string(spec.AccessMode), accessModeChoices},
+		{"dataEngine", string(spec.DataEngine), dataEngineChoices},
+		{"dataLocality", string(spec.DataLocality), dataLocalityChoices},
+		{"freezeFilesystemForSnapshot", string(spec.FreezeFilesystemForSnapshot), toggleChoices},
+		{"frontend", string(spec.Frontend), frontendChoices},
+		{"replicaAutoBalance", string(spec.ReplicaAutoBalance), replicaAutoBalanceChoices},
+		{"replicaDiskSoftAntiAffinity", string(spec.ReplicaDiskSoftAntiAffinity), toggleChoices},
+		{"replicaSoftAntiAffinity", string(spec.ReplicaSoftAntiAffinity), toggleChoices},
+		{"replicaZoneSoftAntiAffinity", string(spec.ReplicaZoneSoftAntiAffinity), toggleChoices},
+		{"snapshotDataIntegrity", string(spec.SnapshotDataIntegrity), snapshotDataIntegrityChoices},
+		{"unmapMarkSnapChainRemoved", string(spec.UnmapMarkSnapChainRemoved), toggleChoices},
+	}
+	for _, choice := range choices {
+		if err := validateChoice(choice.choices)(choice.value); err != nil {
+			collection.Error = append(collection.Error, fmt.Sprintf("Field %v: %v", choice.field, err))
+		}
+	}
+
+	if spec.DataLocality == longhorn.DataLocalityStrictLocal && spec.NumberOfReplicas != 1 {
+		collection.Error = append(collection.Error, "Data locality strict-local requires numberOfReplicas 1")
+	}
+
+	if spec.Migratable && spec.AccessMode != longhorn.AccessModeReadWriteMany {
+		collection.Error = append(collection.Error, "Migratable volumes require access mode rwx")
+	}
+
+	return collection
+}
+
+// validateSetting validates the value of a Setting.
+func validateSetting(raw []byte) *types.LogCollection {
+	var setting longhorn.Setting
+	if err := decodeStrict(raw, &setting); err != nil {
+		return &types.LogCollection{Error: []string{fmt.Sprintf("Invalid Setting: %v", err)}}
+	}
+
+	collection := &types.LogCollection{}
+	appendSettingMessages(collection, setting.Name, setting.Value)
+	return collection
+}
+
+// validateDefaultSettingConfigMap validates the default settings of the Longhorn settings ConfigMap,
+// and their combinations. It returns nil for the other ConfigMaps.
+func validateDefaultSettingConfigMap(name string, raw []byte) *types.LogCollection {
+	if name != lhmgrtypes.DefaultDefaultSettingConfigMapName {
+		return nil
+	}
+
+	var configMap corev1.ConfigMap
+	if err := decodeStrict(raw, &configMap); err != nil {
+		return &types.LogCollection{Error: []string{fmt.Sprintf("Invalid ConfigMap: %v", err)}}
+	}
+
+	data, ok := configMap.Data[lhmgrtypes.DefaultSettingYAMLFileName]
+	if !ok {
+		return &types.LogCollection{Error: []string{fmt.Sprintf("Key %v is missing", lhmgrtypes.DefaultSettingYAMLFileName)}}
+	}
+
+	settings := map[string]string{}
+	if err := yaml.Unmarshal([]byte(data), &settings); err != nil {
+		return &types.LogCollection{Error: []string{fmt.Sprintf("Invalid %v: %v", lhmgrtypes.DefaultSettingYAMLFileName, err)}}
+	}
+
+	collection := &types.LogCollection{}
+
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if value := strings.TrimSpace(settings[name]); value != "" {
+			appendSettingMessages(collection, name, value)
+		}
+	}
+
+	validateSettingCombinations(collection, settings)
+
+	if len(collection.Error) > 0 {
+		collection.Error = append(collection.Error, "Longhorn ignores all the default settings when any of them is invalid")
+	}
+	return collection
+}
+
+// appendSettingMessages appends the result of the validation of a setting value.
+func appendSettingMessages(collection *types.LogCollection, name, value string) {
+	if lhmgrtypes.IsSettingReplaced(lhmgrtypes.SettingName(name)) {
+		collection.Warn = append(collection.Warn, fmt.Sprintf("Setting %v is replaced and will be removed", name))
+	}
+
+	if err := lhmgrtypes.ValidateSetting(name, value); err != nil {
+		collection.Error = append(collection.Error, err.Error())
+	}
+}
+
+// validateSettingCombinations validates the combinations of settings Longhorn rejects or
+// cannot honor. The settings that are not given take their default value.
+func validateSettingCombinations(collection *types.LogCollection, settings map[string]string) {
+	value := func(name lhmgrtypes.SettingName) string {
+		if value := strings.TrimSpace(settings[string(name)]); value != "" {
+			return value
+		}
+		definition, _ := lhmgrtypes.GetSettingDefinition(name)
+		return definition.Default
+	}
+
+	v1DataEngine, _ := strconv.ParseBool(value(lhmgrtypes.SettingNameV1DataEngine))
+	v2DataEngine, _ := strconv.ParseBool(value(lhmgrtypes.SettingNameV2DataEngine))
+	if !v1DataEngine && !v2DataEngine {
+		collection.Error = append(collection.Error, fmt.Sprintf("Settings %v and %v disable both data engines", lhmgrtypes.SettingNameV1DataEngine, lhmgrtypes.SettingNameV2DataEngine))
+	}
+
+	for _, name := range []lhmgrtypes.SettingName{lhmgrtypes.SettingNameGuaranteedInstanceManagerCPU, lhmgrtypes.SettingNameV2DataEngineGuaranteedInstanceManagerCPU} {
+		if err := lhmgrtypes.ValidateCPUReservationValues(name, value(name)); err != nil {
+			collection.Error = append(collection.Error, fmt.Sprintf("Setting %v: %v", name, err))
+		}
+	}
+
+	if value(lhmgrtypes.SettingNameDefaultDataLocality) == string(longhorn.DataLocalityStrictLocal) && value(lhmgrtypes.SettingNameDefaultReplicaCount) != "1" {
+		collection.Warn = append(collection.Warn, fmt.Sprintf("Setting %v strict-local requires %v 1, volumes created with the defaults fail to schedule",
+			lhmgrtypes.SettingNameDefaultDataLocality, lhmgrtypes.SettingNameDefaultReplicaCount))
+	}
+}
+
+func validateChoice(choices []string) func(value string) error {
+	return func(value string) error {
+		for _, choice := range choices {
+			if value == choice {
+				return nil
+			}
+		}
+
+		var nonEmpty []string
+		for _, choice := range choices {
+			if choice != "" {
+				nonEmpty = append(nonEmpty, choice)
+			}
+		}
+		return fmt.Errorf("invalid value %q, must be one of %v", value, strings.Join(nonEmpty, ", "))
+	}
+}
+
+func validateBool(value string) error {
+	if _, err := strconv.ParseBool(value); err != nil {
+		return fmt.Errorf("invalid boolean %q", value)
+	}
+	return nil
+}
+
+func validateNonNegativeInt(value string) error {
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 0 {
+		return fmt.Errorf("invalid non-negative integer %q", value)
+	}
+	return nil
+}
+
+func validateReplicaCount(value string) error {
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 1 || number > maxReplicaCount {
+		return fmt.Errorf("invalid value %q, must be an integer in the range [1, %d]", value, maxReplicaCount)
+	}
+	return nil
+}
+
+func validateQuantity(value string) error {
+	if _, err := resource.ParseQuantity(value); err != nil {
+		return fmt.Errorf("invalid size %q", value)
+	}
+	return nil
+}
+
+func validateJSONObject(value string) error {
+	var object map[string]interface{}
+	if err := json.Unmarshal([]byte(value), &object); err != nil {
+		return fmt.Errorf("invalid JSON object: %v", err)
+	}
+	return nil
+}
+
+// validateRecurringJobSelector validates the JSON list of recurring jobs and groups of a StorageClass.
+func validateRecurringJobSelector(value string) error {
+	var selectors []longhorn.VolumeRecurringJob
+	decoder := json.NewDecoder(strings.NewReader(value))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&selectors); err != nil {
+		return fmt.Errorf("invalid JSON list of recurring jobs: %v", err)
+	}
+
+	for _, selector := range selectors {
+		if selector.Name == "" {
+			return fmt.Errorf("recurring job without name")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+)
+
+// manifestExtensions are the extensions of the files read from the directories.
+var manifestExtensions = []string{".json", ".yaml", ".yml"}
+
+// Validator provide functions for validating Longhorn-related manifests offline, against the
+// Longhorn API types and the rules Longhorn enforces when the objects are applied.
+type Validator struct {
+	ValidatorCmdOptions
+
+	files []string
+}
+
+// ValidatorCmdOptions holds the options for the command.
+type ValidatorCmdOptions struct {
+	types.GlobalCmdOptions
+
+	Paths []string // Manifest files, or directories searched recursively for manifest files.
+}
+
+// Validate validates the command options.
+func (remote *Validator) Validate() error {
+	if len(remote.Paths) == 0 {
+		return errors.Errorf("manifest files or directories (--%s) are required", consts.CmdOptFilename)
+	}
+
+	for _, path := range remote.Paths {
+		if _, err := os.Stat(path); err != nil {
+			return errors.Wrapf(err, "invalid path %v", path)
+		}
+	}
+
+	return nil
+}
+
+// Init initializes the Validator, and finds the manifest files to validate.
+func (remote *Validator) Init() error {
+	files, err := findManifestFiles(remote.Paths)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return errors.Errorf("no manifest files (%s) found", strings.Join(manifestExtensions, ", "))
+	}
+	logrus.Debugf("Found %d manifest files", len(files))
+
+	remote.files = files
+	return nil
+}
+
+// Collect validates the Longhorn-related objects of the manifest files, and returns the result
+// of each object keyed by its file, kind and name. The other objects are skipped.
+func (remote *Validator) Collect() (map[string]*types.LogCollection, error) {
+	collections := map[string]*types.LogCollection{}
+
+	for _, file := range remote.files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read manifest file %v", file)
+		}
+
+		fileCollections, err := validateManifest(data)
+		if err != nil {
+			collections[file] = &types.LogCollection{Error: []string{err.Error()}}
+			continue
+		}
+
+		for object, collection := range fileCollections {
+			collections[file+": "+object] = collection
+		}
+	}
+
+	return collections, nil
+}
+
+// Cleanup does nothing, since the Validator does not create any resource.
+func (remote *Validator) Cleanup() error {
+	return nil
+}
+
+// findManifestFiles returns the given files, and the manifest files found in the given directories, in order.
+func findManifestFiles(paths []string) ([]string, error) {
+	var files []string
+	seen := map[string]bool{}
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get %v", path)
+		}
+
+		if !info.IsDir() {
+			if !seen[path] {
+				seen[path] = true
+				files = append(files, path)
+			}
+			continue
+		}
+
+		var found []string
+		err = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if entry.IsDir() || seen[file] || !isManifestFile(file) {
+				return nil
+			}
+			seen[file] = true
+			found = append(found, file)
+			return nil
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to walk directory %v", path)
+		}
+
+		sort.Strings(found)
+		files = append(files, found...)
+	}
+
+	return files, nil
+}
+
+func isManifestFile(file string) bool {
+	extension := strings.ToLower(filepath.Ext(file))
+	for _, manifestExtension := range manifestExtensions {
+		if extension == manifestExtension {
+			return true
+		}
+	}
+	return false
+}
+
+// validateManifest validates the Longhorn-related objects of a multi-document YAML or JSON
+// manifest, and returns the result of each object keyed by its kind and name.
+func validateManifest(data []byte) (map[string]*types.LogCollection, error) {
+	collections := map[string]*types.LogCollection{}
+
+	decoder := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+	for index := 0; ; index++ {
+		var raw json.RawMessage
+		err := decoder.Decode(&raw)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse manifest")
+		}
+		if len(raw) == 0 || string(raw) == "null" {
+			continue
+		}
+
+		var object metav1.PartialObjectMetadata
+		if err := json.Unmarshal(raw, &object); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse document %d", index)
+		}
+
+		collection := validateObject(&object, raw)
+		if collection == nil {
+			continue
+		}
+
+		key := fmt.Sprintf("%s/%s", object.Kind, object.Name)
+		if object.Name == "" {
+			key = fmt.Sprintf("%s/#%d", object.Kind, index)
+		}
+		if existing, ok := collections[key]; ok {
+			existing.Error = append(existing.Error, collection.Error...)
+			existing.Warn = append(existing.Warn, collection.Warn...)
+			existing.Info = append(existing.Info, collection.Info...)
+			continue
+		}
+		collections[key] = collection
+	}
+
+	return collections, nil
+}
+
+// validateObject returns the result of a Longhorn-related object, or nil for the other objects.
+func validateObject(object *metav1.PartialObjectMetadata, raw []byte) *types.LogCollection {
+	groupVersion, err := schema.ParseGroupVersion(object.APIVersion)
+	if err != nil {
+		return &types.LogCollection{Error: []string{fmt.Sprintf("Invalid apiVersion %q", object.APIVersion)}}
+	}
+
+	switch {
+	case groupVersion.Group == "storage.k8s.io" && object.Kind == "StorageClass":
+		return validateStorageClass(raw)
+
+	case groupVersion.Group == "" && object.Kind == "ConfigMap":
+		return validateDefaultSettingConfigMap(object.Name, raw)
+
+	case groupVersion.Group == longhornAPIGroup:
+		if groupVersion.Version != longhornAPIVersion {
+			return &types.LogCollection{Error: []string{fmt.Sprintf("API version %v is no longer served, use %v/%v", object.APIVersion, longhornAPIGroup, longhornAPIVersion)}}
+		}
+
+		switch object.Kind {
+		case "RecurringJob":
+			return validateRecurringJob(raw)
+		case "Setting":
+			return validateSetting(raw)
+		case "Volume":
+			return validateVolume(raw)
+		default:
+			return &types.LogCollection{Info: []string{fmt.Sprintf("Kind %v is not validated beyond its API version", object.Kind)}}
+		}
+	}
+
+	return nil
+}
+
+// decodeStrict decodes the object, failing on the fields unknown to its API type.
+func decodeStrict(raw []byte, object interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(object)
+}
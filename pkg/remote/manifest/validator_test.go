@@ -0,0 +1,163 @@
+package manifest
+
+import (
+	"testing"
+)
+
+func TestValidateManifest(t *testing.T) {
+	tests := map[string]struct {
+		manifest       string
+		expectedErrors map[string]int
+		expectedWarns  map[string]int
+	}{
+		"non-Longhorn objects are skipped": {
+			manifest: `
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+---
+apiVersion: storage.k8s.io/v1
+kind: StorageClass
+metadata:
+  name: local-path
+provisioner: rancher.io/local-path
+`,
+		},
+		"valid storage class": {
+			manifest: `
+apiVersion: storage.k8s.io/v1
+kind: StorageClass
+metadata:
+  name: longhorn
+provisioner: driver.longhorn.io
+parameters:
+  numberOfReplicas: "3"
+  staleReplicaTimeout: "30"
+  dataLocality: best-effort
+  recurringJobSelector: '[{"name":"snapshot","isGroup":true}]'
+  csi.storage.k8s.io/fstype: xfs
+`,
+			expectedErrors: map[string]int{"StorageClass/longhorn": 0},
+			expectedWarns:  map[string]int{"StorageClass/longhorn": 0},
+		},
+		"invalid storage class": {
+			manifest: `
+apiVersion: storage.k8s.io/v1
+kind: StorageClass
+metadata:
+  name: longhorn
+provisioner: driver.longhorn.io
+parameters:
+  numberOfReplicas: "3"
+  dataLocality: strict-local
+  encrypted: "true"
+  replicaCount: "2"
+`,
+			expectedErrors: map[string]int{"StorageClass/longhorn": 2},
+			expectedWarns:  map[string]int{"StorageClass/longhorn": 1},
+		},
+		"recurring job": {
+			manifest: `
+apiVersion: longhorn.io/v1beta2
+kind: RecurringJob
+metadata:
+  name: backup
+spec:
+  task: backup
+  cron: "0 2 * *"
+  retain: 0
+  concurrency: 1
+`,
+			expectedErrors: map[string]int{"RecurringJob/backup": 2},
+		},
+		"volume with unknown field": {
+			manifest: `
+apiVersion: longhorn.io/v1beta2
+kind: Volume
+metadata:
+  name: data
+spec:
+  size: "1073741824"
+  numberOfReplicas: 3
+  replicas: 3
+`,
+			expectedErrors: map[string]int{"Volume/data": 1},
+		},
+		"migratable volume": {
+			manifest: `
+apiVersion: longhorn.io/v1beta2
+kind: Volume
+metadata:
+  name: data
+spec:
+  size: "1073741824"
+  numberOfReplicas: 3
+  migratable: true
+  accessMode: rwo
+`,
+			expectedErrors: map[string]int{"Volume/data": 1},
+		},
+		"API version no longer served": {
+			manifest: `
+apiVersion: longhorn.io/v1beta1
+kind: Volume
+metadata:
+  name: data
+`,
+			expectedErrors: map[string]int{"Volume/data": 1},
+		},
+		"default settings": {
+			manifest: `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: longhorn-default-setting
+data:
+  default-setting.yaml: |-
+    default-replica-count: 2
+    default-data-locality: strict-local
+    v1-data-engine: false
+    not-a-setting: true
+`,
+			expectedErrors: map[string]int{"ConfigMap/longhorn-default-setting": 3},
+			expectedWarns:  map[string]int{"ConfigMap/longhorn-default-setting": 1},
+		},
+		"setting": {
+			manifest: `
+apiVersion: longhorn.io/v1beta2
+kind: Setting
+metadata:
+  name: default-replica-count
+value: "30"
+`,
+			expectedErrors: map[string]int{"Setting/default-replica-count": 1},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			collections, err := validateManifest([]byte(test.manifest))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(test.expectedErrors) == 0 && len(collections) != 0 {
+				t.Errorf("expected no results, got %v", collections)
+			}
+			for object, count := range test.expectedErrors {
+				if collections[object] == nil {
+					t.Fatalf("expected result for %v, got %v", object, collections)
+				}
+				if len(collections[object].Error) != count {
+					t.Errorf("expected %d errors for %v, got %v", count, object, collections[object].Error)
+				}
+			}
+			for object, count := range test.expectedWarns {
+				if len(collections[object].Warn) != count {
+					t.Errorf("expected %d warnings for %v, got %v", count, object, collections[object].Warn)
+				}
+			}
+		})
+	}
+}
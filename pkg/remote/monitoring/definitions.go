@@ -0,0 +1,159 @@
+package monitoring
+
+// alertRule is a curated Prometheus alerting rule. The expression is a format string, with %[1]s
+// replaced by the label matchers of the Longhorn namespace.
+type alertRule struct {
+	Alert       string
+	Expr        string
+	For         string
+	Severity    string
+	Summary     string
+	Description string
+	Since       string // Longhorn version exposing the metrics of the rule.
+}
+
+// Robustness of a volume in longhorn_volume_robustness: 0 unknown, 1 healthy, 2 degraded and
+// 3 faulted. State of a backup in longhorn_backup_state: 0 new, 1 pending, 2 in progress,
+// 3 completed, 4 error and 5 unknown.
+var alertRules = []alertRule{
+	{
+		Alert:       "LonghornVolumeDegraded",
+		Expr:        `longhorn_volume_robustness{%[1]s} == 2`,
+		For:         "10m",
+		Severity:    "warning",
+		Summary:     "Longhorn volume {{ $labels.volume }} is degraded",
+		Description: "Longhorn volume {{ $labels.volume }} of PVC {{ $labels.pvc_namespace }}/{{ $labels.pvc }} has been running with fewer healthy replicas than requested for more than 10 minutes.",
+		Since:       "1.1.0",
+	},
+	{
+		Alert:       "LonghornVolumeFaulted",
+		Expr:        `longhorn_volume_robustness{%[1]s} == 3`,
+		For:         "2m",
+		Severity:    "critical",
+		Summary:     "Longhorn volume {{ $labels.volume }} is faulted",
+		Description: "Longhorn volume {{ $labels.volume }} of PVC {{ $labels.pvc_namespace }}/{{ $labels.pvc }} has no healthy replica, its data is unavailable.",
+		Since:       "1.1.0",
+	},
+	{
+		Alert:       "LonghornNodeDown",
+		Expr:        `longhorn_node_status{%[1]s,condition="ready"} == 0`,
+		For:         "5m",
+		Severity:    "critical",
+		Summary:     "Longhorn node {{ $labels.node }} is down",
+		Description: "Longhorn node {{ $labels.node }} has not been ready for more than 5 minutes, its replicas are unavailable.",
+		Since:       "1.1.0",
+	},
+	{
+		Alert:       "LonghornNodeStoragePressure",
+		Expr:        `longhorn_node_storage_usage_bytes{%[1]s} / longhorn_node_storage_capacity_bytes{%[1]s} > 0.8`,
+		For:         "10m",
+		Severity:    "warning",
+		Summary:     "Longhorn node {{ $labels.node }} storage is over 80% used",
+		Description: "The disks of Longhorn node {{ $labels.node }} are {{ $value | humanizePercentage }} used. New replicas may not be scheduled on the node.",
+		Since:       "1.1.0",
+	},
+	{
+		Alert:       "LonghornDiskStoragePressure",
+		Expr:        `longhorn_disk_usage_bytes{%[1]s} / longhorn_disk_capacity_bytes{%[1]s} > 0.9`,
+		For:         "10m",
+		Severity:    "critical",
+		Summary:     "Longhorn disk {{ $labels.disk }} of node {{ $labels.node }} is over 90% used",
+		Description: "Longhorn disk {{ $labels.disk }} of node {{ $labels.node }} is {{ $value | humanizePercentage }} used. The replicas on the disk may fail to grow.",
+		Since:       "1.1.0",
+	},
+	{
+		Alert:       "LonghornBackupFailed",
+		Expr:        `longhorn_backup_state{%[1]s} == 4`,
+		For:         "1m",
+		Severity:    "warning",
+		Summary:     "Longhorn backup {{ $labels.backup }} failed",
+		Description: "Longhorn backup {{ $labels.backup }} of volume {{ $labels.volume }} is in error state.",
+		Since:       "1.5.0",
+	},
+}
+
+// dashboardPanel is a curated Grafana time series panel. The expressions are format strings, with
+// %[1]s replaced by the label matchers of the Longhorn namespace.
+type dashboardPanel struct {
+	Title   string
+	Unit    string
+	Targets []panelTarget
+	Since   string // Longhorn version exposing the metrics of the panel.
+}
+
+type panelTarget struct {
+	Expr   string
+	Legend string
+}
+
+var dashboardPanels = []dashboardPanel{
+	{
+		Title: "Volumes by robustness",
+		Unit:  "short",
+		Targets: []panelTarget{
+			{Expr: `count(longhorn_volume_robustness{%[1]s} == 1)`, Legend: "healthy"},
+			{Expr: `count(longhorn_volume_robustness{%[1]s} == 2)`, Legend: "degraded"},
+			{Expr: `count(longhorn_volume_robustness{%[1]s} == 3)`, Legend: "faulted"},
+		},
+		Since: "1.1.0",
+	},
+	{
+		Title: "Volume actual size",
+		Unit:  "bytes",
+		Targets: []panelTarget{
+			{Expr: `longhorn_volume_actual_size_bytes{%[1]s}`, Legend: "{{volume}}"},
+		},
+		Since: "1.1.0",
+	},
+	{
+		Title: "Node storage usage",
+		Unit:  "percentunit",
+		Targets: []panelTarget{
+			{Expr: `longhorn_node_storage_usage_bytes{%[1]s} / longhorn_node_storage_capacity_bytes{%[1]s}`, Legend: "{{node}}"},
+		},
+		Since: "1.1.0",
+	},
+	{
+		Title: "Disk usage",
+		Unit:  "percentunit",
+		Targets: []panelTarget{
+			{Expr: `longhorn_disk_usage_bytes{%[1]s} / longhorn_disk_capacity_bytes{%[1]s}`, Legend: "{{node}} {{disk}}"},
+		},
+		Since: "1.1.0",
+	},
+	{
+		Title: "Volume throughput",
+		Unit:  "Bps",
+		Targets: []panelTarget{
+			{Expr: `longhorn_volume_read_throughput{%[1]s}`, Legend: "{{volume}} read"},
+			{Expr: `longhorn_volume_write_throughput{%[1]s}`, Legend: "{{volume}} write"},
+		},
+		Since: "1.3.0",
+	},
+	{
+		Title: "Volume IOPS",
+		Unit:  "iops",
+		Targets: []panelTarget{
+			{Expr: `longhorn_volume_read_iops{%[1]s}`, Legend: "{{volume}} read"},
+			{Expr: `longhorn_volume_write_iops{%[1]s}`, Legend: "{{volume}} write"},
+		},
+		Since: "1.3.0",
+	},
+	{
+		Title: "Volume latency",
+		Unit:  "ns",
+		Targets: []panelTarget{
+			{Expr: `longhorn_volume_read_latency{%[1]s}`, Legend: "{{volume}} read"},
+			{Expr: `longhorn_volume_write_latency{%[1]s}`, Legend: "{{volume}} write"},
+		},
+		Since: "1.3.0",
+	},
+	{
+		Title: "Failed backups",
+		Unit:  "short",
+		Targets: []panelTarget{
+			{Expr: `count by (volume) (longhorn_backup_state{%[1]s} == 4)`, Legend: "{{volume}}"},
+		},
+		Since: "1.5.0",
+	},
+}
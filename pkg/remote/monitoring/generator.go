@@ -0,0 +1,305 @@
+package monitoring
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/yaml"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// labelNameRegex matches a valid Prometheus label name.
+var labelNameRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// Generator provide functions for generating Prometheus alerting rules and a Grafana dashboard for
+// the metrics of the installed Longhorn version.
+type Generator struct {
+	GeneratorCmdOptions
+
+	version semver.Version
+	labels  map[string]string
+}
+
+// GeneratorCmdOptions holds the options for the command.
+type GeneratorCmdOptions struct {
+	types.GlobalCmdOptions
+
+	Format            string
+	Name              string // Name of the PrometheusRule and UID of the dashboard.
+	LonghornNamespace string
+	LonghornVersion   string // Defaults to the version installed in the cluster.
+	NamespaceLabel    string // Label carrying the namespace of the Longhorn managers in the metrics series.
+	Labels            string // Labels of the PrometheusRule, for example to match the rule selector of Prometheus.
+}
+
+// prometheusRule is a PrometheusRule of the Prometheus Operator.
+type prometheusRule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              prometheusRuleSpec `json:"spec"`
+}
+
+type prometheusRuleSpec struct {
+	Groups []prometheusRuleGroup `json:"groups"`
+}
+
+type prometheusRuleGroup struct {
+	Name  string               `json:"name"`
+	Rules []prometheusRuleItem `json:"rules"`
+}
+
+type prometheusRuleItem struct {
+	Alert       string            `json:"alert"`
+	Expr        string            `json:"expr"`
+	For         string            `json:"for,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// grafanaDashboard is the JSON model of a Grafana dashboard.
+type grafanaDashboard struct {
+	UID           string            `json:"uid"`
+	Title         string            `json:"title"`
+	Description   string            `json:"description"`
+	Tags          []string          `json:"tags"`
+	SchemaVersion int               `json:"schemaVersion"`
+	Refresh       string            `json:"refresh"`
+	Time          grafanaTimeRange  `json:"time"`
+	Templating    grafanaTemplating `json:"templating"`
+	Panels        []grafanaPanel    `json:"panels"`
+}
+
+type grafanaTimeRange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type grafanaTemplating struct {
+	List []grafanaVariable `json:"list"`
+}
+
+type grafanaVariable struct {
+	Name  string `json:"name"`
+	Label string `json:"label"`
+	Type  string `json:"type"`
+	Query string `json:"query"`
+}
+
+type grafanaDatasource struct {
+	Type string `json:"type"`
+	UID  string `json:"uid"`
+}
+
+type grafanaPanel struct {
+	ID          int                `json:"id"`
+	Type        string             `json:"type"`
+	Title       string             `json:"title"`
+	Datasource  grafanaDatasource  `json:"datasource"`
+	GridPos     grafanaGridPos     `json:"gridPos"`
+	FieldConfig grafanaFieldConfig `json:"fieldConfig"`
+	Targets     []grafanaTarget    `json:"targets"`
+}
+
+type grafanaGridPos struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+type grafanaFieldConfig struct {
+	Defaults struct {
+		Unit string `json:"unit"`
+	} `json:"defaults"`
+	Overrides []interface{} `json:"overrides"`
+}
+
+type grafanaTarget struct {
+	RefID        string            `json:"refId"`
+	Datasource   grafanaDatasource `json:"datasource"`
+	Expr         string            `json:"expr"`
+	LegendFormat string            `json:"legendFormat"`
+}
+
+// Validate validates the command options.
+func (remote *Generator) Validate() error {
+	switch remote.Format {
+	case consts.MonitoringFormatPrometheusRules, consts.MonitoringFormatGrafanaJSON:
+	default:
+		return errors.Errorf("invalid --%s %q, expected %s or %s", consts.CmdOptFormat, remote.Format, consts.MonitoringFormatPrometheusRules, consts.MonitoringFormatGrafanaJSON)
+	}
+
+	if remote.Name == "" {
+		return errors.Errorf("name (--%s) is required", consts.CmdOptName)
+	}
+
+	if !labelNameRegex.MatchString(remote.NamespaceLabel) {
+		return errors.Errorf("invalid --%s %q, it must be a Prometheus label name", consts.CmdOptNamespaceLabel, remote.NamespaceLabel)
+	}
+
+	if remote.LonghornVersion != "" {
+		if _, err := semver.ParseTolerant(remote.LonghornVersion); err != nil {
+			return errors.Wrapf(err, "invalid Longhorn version %q (--%s)", remote.LonghornVersion, consts.CmdOptLonghornVersion)
+		}
+	}
+
+	labels, err := kubeutils.ParseNodeSelector(remote.Labels)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse %q argument", consts.CmdOptLabels)
+	}
+	remote.labels = labels
+
+	return nil
+}
+
+// Init initializes the Generator. The Longhorn version defaults to the one installed in the cluster.
+func (remote *Generator) Init() error {
+	version := remote.LonghornVersion
+	if version == "" {
+		longhornClient, err := kubeutils.NewLonghornClient("", remote.KubeConfigPath)
+		if err != nil {
+			return err
+		}
+
+		version, err = kubeutils.GetLonghornVersion(context.Background(), longhornClient, remote.LonghornNamespace)
+		if err != nil {
+			return errors.Wrapf(err, "failed to get the installed Longhorn version, use --%s", consts.CmdOptLonghornVersion)
+		}
+		logrus.Infof("Generating monitoring definitions for Longhorn %v", version)
+	}
+
+	parsed, err := semver.ParseTolerant(version)
+	if err != nil {
+		return errors.Wrapf(err, "invalid Longhorn version %q", version)
+	}
+	parsed.Pre = nil
+	parsed.Build = nil
+	remote.version = parsed
+
+	return nil
+}
+
+// Run returns the PrometheusRule as a YAML document, or the Grafana dashboard as a JSON document.
+func (remote *Generator) Run() (string, error) {
+	if remote.Format == consts.MonitoringFormatPrometheusRules {
+		data, err := yaml.Marshal(remote.newPrometheusRule())
+		if err != nil {
+			return "", errors.Wrap(err, "failed to convert PrometheusRule to YAML")
+		}
+		return string(data), nil
+	}
+
+	data, err := json.MarshalIndent(remote.newGrafanaDashboard(), "", "  ")
+	if err != nil {
+		return "", errors.Wrap(err, "failed to convert dashboard to JSON")
+	}
+	return string(data) + "\n", nil
+}
+
+// newPrometheusRule returns the PrometheusRule with the alerting rules supported by the version.
+func (remote *Generator) newPrometheusRule() *prometheusRule {
+	group := prometheusRuleGroup{Name: remote.Name, Rules: []prometheusRuleItem{}}
+	for _, rule := range alertRules {
+		if !remote.isSupported(rule.Since) {
+			continue
+		}
+		group.Rules = append(group.Rules, prometheusRuleItem{
+			Alert: rule.Alert,
+			Expr:  fmt.Sprintf(rule.Expr, remote.matchers()),
+			For:   rule.For,
+			Labels: map[string]string{
+				"severity": rule.Severity,
+			},
+			Annotations: map[string]string{
+				"summary":     rule.Summary,
+				"description": rule.Description,
+			},
+		})
+	}
+
+	return &prometheusRule{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "monitoring.coreos.com/v1",
+			Kind:       "PrometheusRule",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      remote.Name,
+			Namespace: remote.LonghornNamespace,
+			Labels:    remote.labels,
+		},
+		Spec: prometheusRuleSpec{Groups: []prometheusRuleGroup{group}},
+	}
+}
+
+// newGrafanaDashboard returns the dashboard with the panels supported by the version, two per row.
+func (remote *Generator) newGrafanaDashboard() *grafanaDashboard {
+	datasource := grafanaDatasource{Type: "prometheus", UID: "${datasource}"}
+
+	dashboard := &grafanaDashboard{
+		UID:           remote.Name,
+		Title:         fmt.Sprintf("Longhorn (%s)", remote.LonghornNamespace),
+		Description:   fmt.Sprintf("Longhorn v%s in namespace %s", remote.version, remote.LonghornNamespace),
+		Tags:          []string{"longhorn"},
+		SchemaVersion: 39,
+		Refresh:       "30s",
+		Time:          grafanaTimeRange{From: "now-6h", To: "now"},
+		Templating: grafanaTemplating{
+			List: []grafanaVariable{
+				{Name: "datasource", Label: "Data source", Type: "datasource", Query: "prometheus"},
+			},
+		},
+		Panels: []grafanaPanel{},
+	}
+
+	for _, definition := range dashboardPanels {
+		if !remote.isSupported(definition.Since) {
+			continue
+		}
+
+		index := len(dashboard.Panels)
+		panel := grafanaPanel{
+			ID:         index + 1,
+			Type:       "timeseries",
+			Title:      definition.Title,
+			Datasource: datasource,
+			GridPos:    grafanaGridPos{X: (index % 2) * 12, Y: (index / 2) * 8, W: 12, H: 8},
+			Targets:    []grafanaTarget{},
+		}
+		panel.FieldConfig.Defaults.Unit = definition.Unit
+		panel.FieldConfig.Overrides = []interface{}{}
+		for i, target := range definition.Targets {
+			panel.Targets = append(panel.Targets, grafanaTarget{
+				RefID:        string(rune('A' + i)),
+				Datasource:   datasource,
+				Expr:         fmt.Sprintf(target.Expr, remote.matchers()),
+				LegendFormat: target.Legend,
+			})
+		}
+		dashboard.Panels = append(dashboard.Panels, panel)
+	}
+
+	return dashboard
+}
+
+// matchers returns the label matchers selecting the metrics of the Longhorn namespace.
+func (remote *Generator) matchers() string {
+	return fmt.Sprintf("%s=%q", remote.NamespaceLabel, remote.LonghornNamespace)
+}
+
+// isSupported returns whether the version exposes the metrics introduced in the given version.
+func (remote *Generator) isSupported(since string) bool {
+	return remote.version.GTE(semver.MustParse(strings.TrimPrefix(since, "v")))
+}
@@ -0,0 +1,105 @@
+package monitoring
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/utils/golden"
+)
+
+func TestGeneratorRun(t *testing.T) {
+	tests := map[string]struct {
+		format      string
+		version     string
+		contains    []string
+		notContains []string
+	}{
+		"rules": {
+			format:   consts.MonitoringFormatPrometheusRules,
+			version:  "v1.7.2",
+			contains: []string{"kind: PrometheusRule", "release: prometheus", "LonghornVolumeDegraded", `longhorn_volume_robustness{kubernetes_namespace="storage"} == 2`, "LonghornBackupFailed"},
+		},
+		"rules without backup metrics": {
+			format:      consts.MonitoringFormatPrometheusRules,
+			version:     "v1.4.3",
+			contains:    []string{"LonghornNodeStoragePressure"},
+			notContains: []string{"LonghornBackupFailed"},
+		},
+		"dashboard": {
+			format:   consts.MonitoringFormatGrafanaJSON,
+			version:  "v1.6.0-rc1",
+			contains: []string{`"uid": "longhorn"`, `"title": "Volume IOPS"`, `longhorn_volume_read_iops{kubernetes_namespace=\"storage\"}`, `"title": "Failed backups"`},
+		},
+		"dashboard without performance metrics": {
+			format:      consts.MonitoringFormatGrafanaJSON,
+			version:     "1.2.6",
+			contains:    []string{`"title": "Volume actual size"`},
+			notContains: []string{"Volume IOPS", "Failed backups"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			generator := &Generator{GeneratorCmdOptions: GeneratorCmdOptions{
+				Format:            test.format,
+				Name:              consts.MonitoringName,
+				LonghornNamespace: "storage",
+				LonghornVersion:   test.version,
+				NamespaceLabel:    "kubernetes_namespace",
+				Labels:            "release=prometheus",
+			}}
+			if err := generator.Validate(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := generator.Init(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			output, err := generator.Run()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, expected := range test.contains {
+				if !strings.Contains(output, expected) {
+					t.Errorf("expected %q in output:\n%s", expected, output)
+				}
+			}
+			for _, unexpected := range test.notContains {
+				if strings.Contains(output, unexpected) {
+					t.Errorf("unexpected %q in output:\n%s", unexpected, output)
+				}
+			}
+		})
+	}
+}
+
+func TestGeneratorGolden(t *testing.T) {
+	for name, format := range map[string]string{
+		"prometheus-rules":  consts.MonitoringFormatPrometheusRules,
+		"grafana-dashboard": consts.MonitoringFormatGrafanaJSON,
+	} {
+		t.Run(name, func(t *testing.T) {
+			generator := &Generator{GeneratorCmdOptions: GeneratorCmdOptions{
+				Format:            format,
+				Name:              consts.MonitoringName,
+				LonghornNamespace: "storage",
+				LonghornVersion:   "v1.7.2",
+				NamespaceLabel:    "kubernetes_namespace",
+				Labels:            "release=prometheus",
+			}}
+			if err := generator.Validate(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := generator.Init(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			output, err := generator.Run()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			golden.Assert(t, name, []byte(output))
+		})
+	}
+}
@@ -0,0 +1,296 @@
+package monitoring
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/yaml"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	kubeclient "k8s.io/client-go/kubernetes"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// prometheusOperatorGroupVersion is the API of the custom resources of the Prometheus Operator.
+var prometheusOperatorGroupVersion = schema.GroupVersion{Group: "monitoring.coreos.com", Version: "v1"}
+
+// ServiceMonitorGenerator provide functions for generating, and optionally applying, the
+// ServiceMonitor or PodMonitor and the RBAC the Prometheus Operator needs to scrape the Longhorn
+// managers.
+type ServiceMonitorGenerator struct {
+	ServiceMonitorGeneratorCmdOptions
+
+	kubeClient    *kubeclient.Clientset
+	dynamicClient *dynamic.DynamicClient
+
+	prometheusNamespace      string
+	prometheusServiceAccount string
+}
+
+// ServiceMonitorGeneratorCmdOptions holds the options for the command.
+type ServiceMonitorGeneratorCmdOptions struct {
+	types.GlobalCmdOptions
+
+	LonghornNamespace        string
+	PodMonitor               bool   // Generate a PodMonitor instead of a ServiceMonitor.
+	PrometheusServiceAccount string // Service account of Prometheus, as <namespace>/<name>.
+	Labels                   string // Labels of the monitor, for example to match the monitor selector of Prometheus.
+	Apply                    bool
+}
+
+// Validate validates the command options.
+func (remote *ServiceMonitorGenerator) Validate() error {
+	parts := strings.Split(remote.PrometheusServiceAccount, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return errors.Errorf("invalid --%s %q, expected <namespace>/<name>", consts.CmdOptPrometheusAccount, remote.PrometheusServiceAccount)
+	}
+	remote.prometheusNamespace = parts[0]
+	remote.prometheusServiceAccount = parts[1]
+
+	if _, err := kubeutils.ParseNodeSelector(remote.Labels); err != nil {
+		return errors.Wrapf(err, "failed to parse %q argument", consts.CmdOptLabels)
+	}
+
+	return nil
+}
+
+// Init initializes the ServiceMonitorGenerator, and ensures the Prometheus Operator custom
+// resources are installed. Without access to the cluster, the manifests are still generated unless
+// they are applied.
+func (remote *ServiceMonitorGenerator) Init() error {
+	err := remote.initClients()
+	if err == nil {
+		err = remote.checkPrometheusOperator()
+		if err == nil || apierrors.IsNotFound(errors.Cause(err)) {
+			return err
+		}
+	}
+
+	if remote.Apply {
+		return err
+	}
+	logrus.WithError(err).Warn("Failed to check the Prometheus Operator custom resources, generating the manifests anyway")
+	return nil
+}
+
+func (remote *ServiceMonitorGenerator) initClients() error {
+	config, err := kubeutils.NewRestConfig("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+
+	remote.kubeClient, err = kubeutils.NewKubeClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+
+	remote.dynamicClient, err = dynamic.NewForConfig(config)
+	if err != nil {
+		return errors.Wrap(err, "failed to create dynamic client")
+	}
+	return nil
+}
+
+// checkPrometheusOperator returns a NotFound error when the custom resource of the monitor is not
+// served by the cluster.
+func (remote *ServiceMonitorGenerator) checkPrometheusOperator() error {
+	resources, err := remote.kubeClient.Discovery().ServerResourcesForGroupVersion(prometheusOperatorGroupVersion.String())
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "the Prometheus Operator custom resources (%v) are not installed", prometheusOperatorGroupVersion)
+		}
+		return errors.Wrapf(err, "failed to discover %v", prometheusOperatorGroupVersion)
+	}
+
+	resource := remote.monitorResource().Resource
+	for _, apiResource := range resources.APIResources {
+		if apiResource.Name == resource {
+			return nil
+		}
+	}
+	return errors.WithStack(apierrors.NewNotFound(remote.monitorResource().GroupResource(), resource))
+}
+
+// Run returns the Role, RoleBinding and monitor as a multi-document YAML string, after applying them
+// when requested.
+func (remote *ServiceMonitorGenerator) Run() (string, error) {
+	objects := []runtime.Object{
+		remote.newRole(),
+		remote.newRoleBinding(),
+		remote.newMonitor(),
+	}
+
+	var documents []string
+	for _, object := range objects {
+		if remote.Apply {
+			if err := remote.applyObject(object); err != nil {
+				return "", err
+			}
+		}
+
+		yamlData, err := yaml.Marshal(object)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to convert %T to YAML", object)
+		}
+		documents = append(documents, string(yamlData))
+	}
+
+	return strings.Join(documents, "---\n"), nil
+}
+
+// applyObject applies the object with server-side apply, taking over the fields changed by others.
+func (remote *ServiceMonitorGenerator) applyObject(object runtime.Object) error {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(object)
+	if err != nil {
+		return errors.Wrapf(err, "failed to convert %T", object)
+	}
+	obj := &unstructured.Unstructured{Object: content}
+
+	gvr := remote.monitorResource()
+	switch object.(type) {
+	case *rbacv1.Role:
+		gvr = rbacv1.SchemeGroupVersion.WithResource("roles")
+	case *rbacv1.RoleBinding:
+		gvr = rbacv1.SchemeGroupVersion.WithResource("rolebindings")
+	}
+
+	log := logrus.WithFields(logrus.Fields{
+		"kind":      obj.GetKind(),
+		"namespace": obj.GetNamespace(),
+		"name":      obj.GetName(),
+	})
+	log.Info("Applying resource")
+
+	_, err = remote.dynamicClient.Resource(gvr).Namespace(obj.GetNamespace()).Apply(context.Background(), obj.GetName(), obj, metav1.ApplyOptions{
+		FieldManager: consts.CmdLonghornctlRemote,
+		Force:        true,
+	})
+	return errors.Wrapf(err, "failed to apply %v %v", obj.GetKind(), obj.GetName())
+}
+
+func (remote *ServiceMonitorGenerator) monitorResource() schema.GroupVersionResource {
+	if remote.PodMonitor {
+		return prometheusOperatorGroupVersion.WithResource("podmonitors")
+	}
+	return prometheusOperatorGroupVersion.WithResource("servicemonitors")
+}
+
+// newMonitor prepares the ServiceMonitor scraping the metrics port of the longhorn-backend
+// Service, or the PodMonitor scraping the metrics port of the Longhorn manager pods.
+func (remote *ServiceMonitorGenerator) newMonitor() *unstructured.Unstructured {
+	selector, _ := labels.ConvertSelectorToLabelsMap(consts.LonghornLabelSelectorManager)
+	matchLabels := map[string]interface{}{}
+	for key, value := range selector {
+		matchLabels[key] = value
+	}
+
+	monitorLabels := map[string]interface{}{
+		"app": consts.AppNameLonghornPrometheus,
+	}
+	extraLabels, _ := kubeutils.ParseNodeSelector(remote.Labels)
+	for key, value := range extraLabels {
+		monitorLabels[key] = value
+	}
+
+	kind := "ServiceMonitor"
+	endpointsField := "endpoints"
+	if remote.PodMonitor {
+		kind = "PodMonitor"
+		endpointsField = "podMetricsEndpoints"
+	}
+
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": prometheusOperatorGroupVersion.String(),
+		"kind":       kind,
+		"metadata": map[string]interface{}{
+			"name":      consts.AppNameLonghornPrometheus,
+			"namespace": remote.LonghornNamespace,
+			"labels":    monitorLabels,
+		},
+		"spec": map[string]interface{}{
+			"selector": map[string]interface{}{
+				"matchLabels": matchLabels,
+			},
+			"namespaceSelector": map[string]interface{}{
+				"matchNames": []interface{}{remote.LonghornNamespace},
+			},
+			endpointsField: []interface{}{
+				map[string]interface{}{
+					"port": consts.LonghornManagerMetricsPort,
+				},
+			},
+		},
+	}}
+}
+
+// newRole prepares the permissions Prometheus requires to discover the Longhorn managers in the
+// Longhorn namespace, when it is not granted them cluster-wide.
+func (remote *ServiceMonitorGenerator) newRole() *rbacv1.Role {
+	return &rbacv1.Role{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: rbacv1.SchemeGroupVersion.String(),
+			Kind:       "Role",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      consts.AppNameLonghornPrometheus,
+			Namespace: remote.LonghornNamespace,
+			Labels: map[string]string{
+				"app": consts.AppNameLonghornPrometheus,
+			},
+		},
+		Rules: []rbacv1.PolicyRule{
+			{
+				APIGroups: []string{""},
+				Resources: []string{"services", "endpoints", "pods"},
+				Verbs:     []string{"get", "list", "watch"},
+			},
+			{
+				APIGroups: []string{"discovery.k8s.io"},
+				Resources: []string{"endpointslices"},
+				Verbs:     []string{"get", "list", "watch"},
+			},
+		},
+	}
+}
+
+func (remote *ServiceMonitorGenerator) newRoleBinding() *rbacv1.RoleBinding {
+	return &rbacv1.RoleBinding{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: rbacv1.SchemeGroupVersion.String(),
+			Kind:       "RoleBinding",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      consts.AppNameLonghornPrometheus,
+			Namespace: remote.LonghornNamespace,
+			Labels: map[string]string{
+				"app": consts.AppNameLonghornPrometheus,
+			},
+		},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "Role",
+			Name:     consts.AppNameLonghornPrometheus,
+		},
+		Subjects: []rbacv1.Subject{
+			{
+				Kind:      "ServiceAccount",
+				Name:      remote.prometheusServiceAccount,
+				Namespace: remote.prometheusNamespace,
+			},
+		},
+	}
+}
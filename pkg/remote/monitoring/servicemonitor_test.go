@@ -0,0 +1,54 @@
+package monitoring
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestServiceMonitorGeneratorValidate(t *testing.T) {
+	tests := map[string]struct {
+		serviceAccount string
+		labels         string
+		expectedError  bool
+	}{
+		"valid":                    {serviceAccount: "monitoring/prometheus-k8s", labels: "release=prometheus"},
+		"service account no name":  {serviceAccount: "monitoring/", expectedError: true},
+		"service account no slash": {serviceAccount: "prometheus-k8s", expectedError: true},
+		"invalid labels":           {serviceAccount: "monitoring/prometheus-k8s", labels: "release", expectedError: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			generator := &ServiceMonitorGenerator{ServiceMonitorGeneratorCmdOptions: ServiceMonitorGeneratorCmdOptions{
+				PrometheusServiceAccount: test.serviceAccount,
+				Labels:                   test.labels,
+			}}
+			err := generator.Validate()
+			if test.expectedError != (err != nil) {
+				t.Fatalf("expected error %v, got %v", test.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestServiceMonitorGeneratorRun(t *testing.T) {
+	generator := &ServiceMonitorGenerator{ServiceMonitorGeneratorCmdOptions: ServiceMonitorGeneratorCmdOptions{
+		LonghornNamespace:        "storage",
+		PodMonitor:               true,
+		PrometheusServiceAccount: "monitoring/prometheus-k8s",
+		Labels:                   "release=prometheus",
+	}}
+	if err := generator.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	output, err := generator.Run()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, expected := range []string{"kind: Role\n", "kind: RoleBinding\n", "name: prometheus-k8s", "kind: PodMonitor", "podMetricsEndpoints:\n  - port: manager", "release: prometheus", "app: longhorn-manager", "- storage"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %q in output:\n%s", expected, output)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package node
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+)
+
+// pushScript writes the stdin to a temporary file next to the destination, and moves it to the
+// destination once its checksum matches. A destination directory gets the file under the name of
+// the source. It prints the path of the written file.
+const pushScript = `set -e
+destination="$1"
+if [ -d "$destination" ]; then destination="${destination%/}/$2"; fi
+temporary="$destination.longhornctl-cp"
+trap 'rm -f "$temporary"' EXIT
+cat > "$temporary"
+if ! echo "$3  $temporary" | sha256sum -c - > /dev/null; then
+  echo "checksum of $temporary does not match $3" >&2
+  exit 1
+fi
+mv -f "$temporary" "$destination"
+echo "$destination"`
+
+// sizeScript prints the size of the regular file.
+const sizeScript = `test -f "$1" || { echo "$1 is not a regular file" >&2; exit 1; }
+stat -L -c %s -- "$1"`
+
+// Copier provide functions for copying a file between the local machine and a node, through a
+// DaemonSet pod on the node, without SSH or scp access to the node. The files are read and
+// written in the host mount namespace, their size is limited, and their checksum is verified.
+type Copier struct {
+	CopierCmdOptions
+
+	nodePod *nodePod
+
+	nodeName  string
+	nodePath  string
+	localPath string
+	push      bool // Copy the local file to the node.
+	maxSize   int64
+}
+
+// CopierCmdOptions holds the options for the command.
+type CopierCmdOptions struct {
+	types.GlobalCmdOptions
+
+	Source      string // <node>:<path> or local path.
+	Destination string // <node>:<path> or local path.
+	MaxSize     string
+	Timeout     time.Duration // Maximum time to wait for the copy.
+}
+
+// Validate validates the command options. Exactly one of the source and the destination must be a
+// path on a node.
+func (remote *Copier) Validate() error {
+	sourceNode, sourcePath, sourceOnNode := parseNodePath(remote.Source)
+	destinationNode, destinationPath, destinationOnNode := parseNodePath(remote.Destination)
+	switch {
+	case sourceOnNode && destinationOnNode:
+		return errors.New("copying between nodes is not supported, copy the file to the local machine first")
+	case sourceOnNode:
+		remote.nodeName, remote.nodePath, remote.localPath = sourceNode, sourcePath, remote.Destination
+	case destinationOnNode:
+		remote.nodeName, remote.nodePath, remote.localPath = destinationNode, destinationPath, remote.Source
+		remote.push = true
+	default:
+		return errors.New("one of the source and the destination must be a path on a node, as <node>:<path>")
+	}
+
+	if !path.IsAbs(remote.nodePath) {
+		return errors.Errorf("path %v on node %v must be absolute", remote.nodePath, remote.nodeName)
+	}
+	if remote.localPath == "" {
+		return errors.New("local path is required")
+	}
+
+	maxSize, err := resource.ParseQuantity(remote.MaxSize)
+	if err != nil {
+		return errors.Wrapf(err, "invalid --%s %q", consts.CmdOptMaxSize, remote.MaxSize)
+	}
+	if maxSize.Value() <= 0 {
+		return errors.Errorf("--%s must be positive", consts.CmdOptMaxSize)
+	}
+	remote.maxSize = maxSize.Value()
+
+	if remote.Timeout <= 0 {
+		return errors.Errorf("--%s must be positive", consts.CmdOptTimeout)
+	}
+
+	return nil
+}
+
+// IsPush returns whether the local file is copied to the node, once the options are validated.
+func (remote *Copier) IsPush() bool {
+	return remote.push
+}
+
+// Init initializes the Copier, and checks the node exists and matches the node selector.
+func (remote *Copier) Init() error {
+	nodePod, err := newNodePod(&remote.GlobalCmdOptions, consts.AppNameNodeCopier, remote.nodeName)
+	if err != nil {
+		return err
+	}
+	remote.nodePod = nodePod
+
+	return nil
+}
+
+// Run creates the DaemonSet on the node, and copies the file from or to the node once its pod is
+// ready.
+func (remote *Copier) Run() (*types.NodeCopyResult, error) {
+	if err := remote.nodePod.start(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), remote.Timeout)
+	defer cancel()
+
+	if remote.push {
+		return remote.pushFile(ctx)
+	}
+	return remote.fetchFile(ctx)
+}
+
+// Cleanup deletes the DaemonSet created for copying the file.
+func (remote *Copier) Cleanup() error {
+	return remote.nodePod.cleanup()
+}
+
+// fetchFile copies the file of the node to a temporary file next to the local path, and renames it
+// to the local path once its checksum matches the one of the file on the node.
+func (remote *Copier) fetchFile(ctx context.Context) (*types.NodeCopyResult, error) {
+	output, err := remote.runNodeCommand(ctx, nil, "sh", "-c", sizeScript, "sh", remote.nodePath)
+	if err != nil {
+		return nil, err
+	}
+	size, err := strconv.ParseInt(strings.TrimSpace(output), 10, 64)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse the size of %v on node %v", remote.nodePath, remote.nodeName)
+	}
+	if size > remote.maxSize {
+		return nil, errors.Errorf("%v on node %v is %d bytes, larger than --%s %v", remote.nodePath, remote.nodeName, size, consts.CmdOptMaxSize, remote.MaxSize)
+	}
+
+	localPath := remote.localPath
+	if info, err := os.Stat(localPath); (err == nil && info.IsDir()) || strings.HasSuffix(localPath, string(filepath.Separator)) {
+		localPath = filepath.Join(localPath, path.Base(remote.nodePath))
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(localPath), "."+filepath.Base(localPath)+".*")
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create temporary file for %v", localPath)
+	}
+	defer os.Remove(file.Name())
+
+	logrus.WithField("node", remote.nodeName).Infof("Copying %v (%d bytes) to %v", remote.nodePath, size, localPath)
+	hash := sha256.New()
+	writer := &limitedWriter{writer: io.MultiWriter(file, hash), remaining: remote.maxSize}
+	var stderr bytes.Buffer
+	err = remote.nodePod.exec(ctx, []string{"cat", "--", remote.nodePath}, nil, writer, &stderr)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %v on node %v: %s", remote.nodePath, remote.nodeName, strings.TrimSpace(stderr.String()))
+	}
+	checksum := hex.EncodeToString(hash.Sum(nil))
+
+	output, err = remote.runNodeCommand(ctx, nil, "sha256sum", "--", remote.nodePath)
+	if err != nil {
+		return nil, err
+	}
+	if nodeChecksum := strings.Fields(output); len(nodeChecksum) == 0 || nodeChecksum[0] != checksum {
+		return nil, errors.Errorf("checksum %v of the copy does not match the one of %v on node %v, the file may have changed during the copy", checksum, remote.nodePath, remote.nodeName)
+	}
+
+	if err := os.Chmod(file.Name(), 0644); err != nil {
+		return nil, errors.Wrapf(err, "failed to set the permissions of %v", file.Name())
+	}
+	if err := os.Rename(file.Name(), localPath); err != nil {
+		return nil, errors.Wrapf(err, "failed to write %v", localPath)
+	}
+
+	return &types.NodeCopyResult{
+		Node:        remote.nodeName,
+		Source:      remote.nodeName + ":" + remote.nodePath,
+		Destination: localPath,
+		Size:        writer.written,
+		SHA256:      checksum,
+	}, nil
+}
+
+// pushFile streams the local file to the node, where it is written once its checksum matches.
+func (remote *Copier) pushFile(ctx context.Context) (*types.NodeCopyResult, error) {
+	file, err := os.Open(remote.localPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open %v", remote.localPath)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get the size of %v", remote.localPath)
+	}
+	if !info.Mode().IsRegular() {
+		return nil, errors.Errorf("%v is not a regular file", remote.localPath)
+	}
+	if info.Size() > remote.maxSize {
+		return nil, errors.Errorf("%v is %d bytes, larger than --%s %v", remote.localPath, info.Size(), consts.CmdOptMaxSize, remote.MaxSize)
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return nil, errors.Wrapf(err, "failed to read %v", remote.localPath)
+	}
+	checksum := hex.EncodeToString(hash.Sum(nil))
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, errors.Wrapf(err, "failed to read %v", remote.localPath)
+	}
+
+	logrus.WithField("node", remote.nodeName).Infof("Copying %v (%d bytes) to %v", remote.localPath, info.Size(), remote.nodePath)
+	output, err := remote.runNodeCommand(ctx, io.LimitReader(file, info.Size()), "sh", "-c", pushScript, "sh", remote.nodePath, filepath.Base(remote.localPath), checksum)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.NodeCopyResult{
+		Node:        remote.nodeName,
+		Source:      remote.localPath,
+		Destination: remote.nodeName + ":" + strings.TrimSpace(output),
+		Size:        info.Size(),
+		SHA256:      checksum,
+	}, nil
+}
+
+// runNodeCommand runs the command in the host namespaces of the node, and returns its stdout.
+func (remote *Copier) runNodeCommand(ctx context.Context, stdin io.Reader, command ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	if err := remote.nodePod.exec(ctx, command, stdin, &stdout, &stderr); err != nil {
+		return "", errors.Wrapf(err, "failed to run %v on node %v: %s", command[0], remote.nodeName, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// parseNodePath parses a path on a node, as <node>:<path>. A local path has no colon before its
+// first slash.
+func parseNodePath(value string) (nodeName, nodePath string, ok bool) {
+	nodeName, nodePath, ok = strings.Cut(value, ":")
+	if !ok || nodeName == "" || strings.Contains(nodeName, "/") {
+		return "", "", false
+	}
+	return nodeName, nodePath, true
+}
+
+// limitedWriter fails the writes past the remaining bytes, so a file growing during the copy does
+// not exceed the size limit.
+type limitedWriter struct {
+	writer    io.Writer
+	remaining int64
+	written   int64
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	if int64(len(p)) > w.remaining {
+		return 0, errors.Errorf("file is larger than %d bytes", w.written+w.remaining)
+	}
+	n, err := w.writer.Write(p)
+	w.remaining -= int64(n)
+	w.written += int64(n)
+	return n, err
+}
@@ -0,0 +1,71 @@
+package node
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestParseNodePath(t *testing.T) {
+	for _, test := range []struct {
+		value    string
+		wantNode string
+		wantPath string
+		wantOK   bool
+	}{
+		{value: "node-1:/var/lib/longhorn/replicas/r-1/volume.meta", wantNode: "node-1", wantPath: "/var/lib/longhorn/replicas/r-1/volume.meta", wantOK: true},
+		{value: "node-1:volume.meta", wantNode: "node-1", wantPath: "volume.meta", wantOK: true},
+		{value: "./volume.meta", wantOK: false},
+		{value: "./backup:1/volume.meta", wantOK: false},
+		{value: ":/volume.meta", wantOK: false},
+	} {
+		node, path, ok := parseNodePath(test.value)
+		if node != test.wantNode || path != test.wantPath || ok != test.wantOK {
+			t.Errorf("parseNodePath(%q) = %q, %q, %v, want %q, %q, %v", test.value, node, path, ok, test.wantNode, test.wantPath, test.wantOK)
+		}
+	}
+}
+
+func TestCopierValidate(t *testing.T) {
+	for name, test := range map[string]struct {
+		source      string
+		destination string
+		maxSize     string
+		wantPush    bool
+		wantErr     bool
+	}{
+		"fetch":         {source: "node-1:/var/lib/longhorn/volume.meta", destination: "./", maxSize: "64Mi"},
+		"push":          {source: "volume.meta", destination: "node-1:/var/lib/longhorn/", maxSize: "64Mi", wantPush: true},
+		"between nodes": {source: "node-1:/volume.meta", destination: "node-2:/volume.meta", maxSize: "64Mi", wantErr: true},
+		"no node":       {source: "volume.meta", destination: "./", maxSize: "64Mi", wantErr: true},
+		"relative":      {source: "node-1:volume.meta", destination: "./", maxSize: "64Mi", wantErr: true},
+		"invalid size":  {source: "node-1:/volume.meta", destination: "./", maxSize: "big", wantErr: true},
+		"zero size":     {source: "node-1:/volume.meta", destination: "./", maxSize: "0", wantErr: true},
+		"no local path": {source: "node-1:/volume.meta", destination: "", maxSize: "64Mi", wantErr: true},
+	} {
+		copier := &Copier{CopierCmdOptions: CopierCmdOptions{Source: test.source, Destination: test.destination, MaxSize: test.maxSize, Timeout: time.Minute}}
+		err := copier.Validate()
+		if (err != nil) != test.wantErr {
+			t.Errorf("%v: Validate() error = %v, want error %v", name, err, test.wantErr)
+			continue
+		}
+		if err == nil && copier.IsPush() != test.wantPush {
+			t.Errorf("%v: IsPush() = %v, want %v", name, copier.IsPush(), test.wantPush)
+		}
+	}
+}
+
+func TestLimitedWriter(t *testing.T) {
+	var buffer bytes.Buffer
+	writer := &limitedWriter{writer: &buffer, remaining: 8}
+
+	if _, err := writer.Write([]byte("12345")); err != nil {
+		t.Fatalf("Write() within the limit error = %v", err)
+	}
+	if _, err := writer.Write([]byte("6789")); err == nil {
+		t.Fatalf("Write() past the limit succeeded")
+	}
+	if writer.written != 5 || buffer.String() != "12345" {
+		t.Fatalf("limitedWriter wrote %d bytes %q, want 5 bytes %q", writer.written, buffer.String(), "12345")
+	}
+}
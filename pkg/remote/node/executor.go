@@ -0,0 +1,98 @@
+package node
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	utilexec "k8s.io/client-go/util/exec"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+)
+
+// Executor provide functions for running a command in the host namespaces of a node, through a
+// DaemonSet pod on the node, for one-off diagnostics without SSH access to the node.
+type Executor struct {
+	ExecutorCmdOptions
+
+	nodePod *nodePod
+}
+
+// ExecutorCmdOptions holds the options for the command.
+type ExecutorCmdOptions struct {
+	types.GlobalCmdOptions
+
+	NodeName string
+	Command  []string
+	Timeout  time.Duration // Maximum time to wait for the command.
+}
+
+// Validate validates the command options.
+func (remote *Executor) Validate() error {
+	if remote.NodeName == "" {
+		return errors.Errorf("Node name (--%s) is required", consts.CmdOptNode)
+	}
+
+	if len(remote.Command) == 0 {
+		return errors.New("command is required after --")
+	}
+
+	if remote.Timeout <= 0 {
+		return errors.Errorf("--%s must be positive", consts.CmdOptTimeout)
+	}
+
+	return nil
+}
+
+// Init initializes the Executor, and checks the node exists and matches the node selector.
+func (remote *Executor) Init() error {
+	nodePod, err := newNodePod(&remote.GlobalCmdOptions, consts.AppNameNodeExecutor, remote.NodeName)
+	if err != nil {
+		return err
+	}
+	remote.nodePod = nodePod
+
+	return nil
+}
+
+// Run creates the DaemonSet on the node, and runs the command in the host namespaces from its pod
+// once it is ready. A command exiting with a non-zero code is not an error, its code is returned
+// in the result.
+func (remote *Executor) Run() (*types.NodeExecResult, error) {
+	if err := remote.nodePod.start(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), remote.Timeout)
+	defer cancel()
+
+	logrus.WithField("node", remote.NodeName).Debugf("Running %v", remote.Command)
+	var stdout, stderr bytes.Buffer
+	err := remote.nodePod.exec(ctx, remote.Command, nil, &stdout, &stderr)
+
+	result := &types.NodeExecResult{
+		Node:    remote.NodeName,
+		Command: remote.Command,
+		Stdout:  stdout.String(),
+		Stderr:  stderr.String(),
+	}
+
+	var exitErr utilexec.ExitError
+	if errors.As(err, &exitErr) {
+		result.ExitCode = exitErr.ExitStatus()
+		return result, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to run command on node %v", remote.NodeName)
+	}
+	return result, nil
+}
+
+// Cleanup deletes the DaemonSet created for running the command.
+func (remote *Executor) Cleanup() error {
+	return remote.nodePod.cleanup()
+}
@@ -0,0 +1,32 @@
+package node
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	for name, test := range map[string]struct {
+		options ExecutorCmdOptions
+		wantErr bool
+	}{
+		"valid":      {options: ExecutorCmdOptions{NodeName: "node-1", Command: []string{"uptime"}, Timeout: time.Minute}},
+		"no node":    {options: ExecutorCmdOptions{Command: []string{"uptime"}, Timeout: time.Minute}, wantErr: true},
+		"no command": {options: ExecutorCmdOptions{NodeName: "node-1", Timeout: time.Minute}, wantErr: true},
+		"no timeout": {options: ExecutorCmdOptions{NodeName: "node-1", Command: []string{"uptime"}}, wantErr: true},
+	} {
+		executor := &Executor{ExecutorCmdOptions: test.options}
+		if err := executor.Validate(); (err != nil) != test.wantErr {
+			t.Errorf("%v: Validate() error = %v, want error %v", name, err, test.wantErr)
+		}
+	}
+}
+
+func TestGetHostCommand(t *testing.T) {
+	got := getHostCommand([]string{"sh", "-c", "df -h"})
+	want := []string{"nsenter", "--target", "1", "--mount", "--uts", "--ipc", "--net", "--pid", "--", "sh", "-c", "df -h"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("getHostCommand() = %v, want %v", got, want)
+	}
+}
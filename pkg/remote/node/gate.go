@@ -0,0 +1,317 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	sigsyaml "sigs.k8s.io/yaml"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	kubeclient "k8s.io/client-go/kubernetes"
+
+	longhorn "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+	lhclient "github.com/longhorn/longhorn-manager/k8s/pkg/client/clientset/versioned"
+	lhmgrtypes "github.com/longhorn/longhorn-manager/types"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// Gate provide functions for keeping the replicas off the new nodes until the preflight check has
+// passed on them. With the Longhorn setting create-default-disk-labeled-nodes enabled, Longhorn
+// only creates the default disk of the nodes labeled with node.longhorn.io/create-default-disk, so
+// the nodes without disk get no replica. The gate labels each node once its preflight check result
+// has no error.
+type Gate struct {
+	GateCmdOptions
+
+	kubeClient     *kubeclient.Clientset
+	longhornClient *lhclient.Clientset
+
+	namespace string // Namespace of the result ConfigMap of the preflight server.
+}
+
+// GateCmdOptions holds the options for the command.
+type GateCmdOptions struct {
+	types.GlobalCmdOptions
+
+	LonghornNamespace string
+}
+
+// nodeGateDecision is what the gate does on a node.
+type nodeGateDecision struct {
+	state      string // State of the gate on the node, empty when the node is not gated.
+	label      bool   // Label the node for Longhorn to create its default disk.
+	collection *types.LogCollection
+}
+
+// Init initializes the Gate.
+func (remote *Gate) Init() error {
+	kubeClient, err := kubeutils.NewKubeClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.kubeClient = kubeClient
+
+	longhornClient, err := kubeutils.NewLonghornClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.longhornClient = longhornClient
+
+	remote.namespace = remote.Namespace
+	if remote.namespace == "" {
+		remote.namespace = consts.LonghornNamespace
+	}
+	return nil
+}
+
+// Enable enables the setting create-default-disk-labeled-nodes, then gates the nodes with the
+// results recorded by the preflight server. The value of the setting before the gate is kept in an
+// annotation of the setting, for Disable to restore it. It returns the state of the gate on each
+// node.
+func (remote *Gate) Enable() (map[string]*types.LogCollection, error) {
+	ctx := context.Background()
+
+	settings := remote.longhornClient.LonghornV1beta2().Settings(remote.LonghornNamespace)
+	setting, err := settings.Get(ctx, string(lhmgrtypes.SettingNameCreateDefaultDiskLabeledNodes), metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get setting %v", lhmgrtypes.SettingNameCreateDefaultDiskLabeledNodes)
+	}
+
+	// Gate the nodes before enabling the setting, so the passed nodes are labeled first and keep
+	// getting their default disk.
+	nodeCollections, err := remote.loadResults(ctx)
+	if err != nil {
+		return nil, err
+	}
+	gateCollections, err := remote.sync(ctx, nodeCollections)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, enabled := setting.Annotations[consts.AnnotationNodeGatePrevious]; !enabled {
+		if setting.Annotations == nil {
+			setting.Annotations = map[string]string{}
+		}
+		setting.Annotations[consts.AnnotationNodeGatePrevious] = setting.Value
+		setting.Value = "true"
+		if _, err := settings.Update(ctx, setting, metav1.UpdateOptions{}); err != nil {
+			return nil, errors.Wrapf(err, "failed to update setting %v", setting.Name)
+		}
+		logrus.Infof("Enabled setting %v", setting.Name)
+	}
+
+	return gateCollections, nil
+}
+
+// Disable restores the setting create-default-disk-labeled-nodes to its value before the gate, and
+// removes the gate annotation of the nodes. The node labels are kept, since the nodes have their
+// default disk. It returns false when the gate is not enabled.
+func (remote *Gate) Disable() (bool, error) {
+	ctx := context.Background()
+
+	settings := remote.longhornClient.LonghornV1beta2().Settings(remote.LonghornNamespace)
+	setting, err := settings.Get(ctx, string(lhmgrtypes.SettingNameCreateDefaultDiskLabeledNodes), metav1.GetOptions{})
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to get setting %v", lhmgrtypes.SettingNameCreateDefaultDiskLabeledNodes)
+	}
+
+	previous, enabled := setting.Annotations[consts.AnnotationNodeGatePrevious]
+	if enabled {
+		delete(setting.Annotations, consts.AnnotationNodeGatePrevious)
+		setting.Value = previous
+		if _, err := settings.Update(ctx, setting, metav1.UpdateOptions{}); err != nil {
+			return false, errors.Wrapf(err, "failed to update setting %v", setting.Name)
+		}
+		logrus.Infof("Restored setting %v to %v", setting.Name, previous)
+	}
+
+	nodes, err := remote.kubeClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return enabled, errors.Wrap(err, "failed to list nodes")
+	}
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:null}}}`, consts.AnnotationNodeGate)
+	for _, node := range nodes.Items {
+		if _, ok := node.Annotations[consts.AnnotationNodeGate]; !ok {
+			continue
+		}
+		if _, err := remote.kubeClient.CoreV1().Nodes().Patch(ctx, node.Name, k8stypes.MergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
+			return enabled, errors.Wrapf(err, "failed to remove the gate annotation of node %v", node.Name)
+		}
+	}
+	return enabled, nil
+}
+
+// SyncIfEnabled gates the nodes with the preflight check results when the gate is enabled, and
+// returns nil otherwise. The preflight server calls it after each check.
+func (remote *Gate) SyncIfEnabled(nodeCollections map[string]*types.LogCollection) (map[string]*types.LogCollection, error) {
+	ctx := context.Background()
+
+	setting, err := remote.longhornClient.LonghornV1beta2().Settings(remote.LonghornNamespace).Get(ctx, string(lhmgrtypes.SettingNameCreateDefaultDiskLabeledNodes), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get setting %v", lhmgrtypes.SettingNameCreateDefaultDiskLabeledNodes)
+	}
+	if _, enabled := setting.Annotations[consts.AnnotationNodeGatePrevious]; !enabled {
+		return nil, nil
+	}
+
+	return remote.sync(ctx, nodeCollections)
+}
+
+// sync labels and annotates the gated nodes according to their preflight check result, and returns
+// the state of the gate on each node.
+func (remote *Gate) sync(ctx context.Context, nodeCollections map[string]*types.LogCollection) (map[string]*types.LogCollection, error) {
+	nodes, err := remote.kubeClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list nodes")
+	}
+
+	lhNodes, err := remote.longhornClient.LonghornV1beta2().Nodes(remote.LonghornNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list Longhorn nodes")
+	}
+	lhNodeMap := map[string]*longhorn.Node{}
+	for i := range lhNodes.Items {
+		lhNodeMap[lhNodes.Items[i].Name] = &lhNodes.Items[i]
+	}
+
+	gateCollections := map[string]*types.LogCollection{}
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		decision := getNodeGateDecision(node, lhNodeMap[node.Name], nodeCollections[node.Name])
+		gateCollections[node.Name] = decision.collection
+
+		if decision.state == "" {
+			continue
+		}
+		if node.Annotations[consts.AnnotationNodeGate] == decision.state && (!decision.label || node.Labels[lhmgrtypes.NodeCreateDefaultDiskLabelKey] != "") {
+			continue
+		}
+
+		patch, err := newNodeGatePatch(decision)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := remote.kubeClient.CoreV1().Nodes().Patch(ctx, node.Name, k8stypes.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+			return nil, errors.Wrapf(err, "failed to gate node %v", node.Name)
+		}
+		logrus.WithField("node", node.Name).Infof("Node gate is %v", decision.state)
+	}
+	return gateCollections, nil
+}
+
+// loadResults returns the preflight check results recorded by the preflight server, or nil when
+// the preflight server has not recorded any.
+func (remote *Gate) loadResults(ctx context.Context) (map[string]*types.LogCollection, error) {
+	configMap, err := remote.kubeClient.CoreV1().ConfigMaps(remote.namespace).Get(ctx, consts.AppNamePreflightServer, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		logrus.Warnf("No preflight check result in ConfigMap %v/%v, run \"longhornctl serve\" for the gate to pass the nodes", remote.namespace, consts.AppNamePreflightServer)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get ConfigMap %v/%v", remote.namespace, consts.AppNamePreflightServer)
+	}
+
+	nodeCollections := map[string]*types.LogCollection{}
+	for nodeName, data := range configMap.Data {
+		collection := &types.LogCollection{}
+		if err := sigsyaml.Unmarshal([]byte(data), collection); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse the preflight check result of node %v", nodeName)
+		}
+		nodeCollections[nodeName] = collection
+	}
+	return nodeCollections, nil
+}
+
+// getNodeGateDecision returns what the gate does on the node, given its Longhorn node and its
+// preflight check result, both nil when missing. The nodes with Longhorn disks, or labeled for
+// their default disk by the user, are not gated.
+func getNodeGateDecision(node *corev1.Node, lhNode *longhorn.Node, result *types.LogCollection) *nodeGateDecision {
+	_, gated := node.Annotations[consts.AnnotationNodeGate]
+	labelValue, labeled := node.Labels[lhmgrtypes.NodeCreateDefaultDiskLabelKey]
+	if !gated {
+		if labeled {
+			return &nodeGateDecision{collection: &types.LogCollection{
+				Info: []string{fmt.Sprintf("Labeled with %v=%v, not gated", lhmgrtypes.NodeCreateDefaultDiskLabelKey, labelValue)},
+			}}
+		}
+		if lhNode != nil && len(lhNode.Spec.Disks) > 0 {
+			return &nodeGateDecision{collection: &types.LogCollection{
+				Info: []string{"Has Longhorn disks, not gated"},
+			}}
+		}
+	}
+
+	switch {
+	case result == nil:
+		return &nodeGateDecision{
+			state: consts.NodeGateStatePending,
+			collection: &types.LogCollection{
+				Warn: []string{"No preflight check result, the node gets no default disk until the check passes"},
+			},
+		}
+	case len(result.Error) > 0:
+		message := "Preflight check failed, the node gets no default disk until the check passes: "
+		if labeled {
+			message = "Preflight check failed after it passed, the node keeps its default disk: "
+		}
+		return &nodeGateDecision{
+			state: consts.NodeGateStateFailed,
+			collection: &types.LogCollection{
+				Error: []string{message + strings.Join(result.Error, "; ")},
+			},
+		}
+	case len(result.Skipped) > 0:
+		return &nodeGateDecision{
+			state: consts.NodeGateStatePending,
+			collection: &types.LogCollection{
+				Warn: []string{"Skipped by the preflight check, the node gets no default disk until the check passes: " + strings.Join(result.Skipped, "; ")},
+			},
+		}
+	}
+
+	return &nodeGateDecision{
+		state: consts.NodeGateStatePassed,
+		label: true,
+		collection: &types.LogCollection{
+			Info: []string{fmt.Sprintf("Preflight check passed, labeled with %v=%v for Longhorn to create the default disk",
+				lhmgrtypes.NodeCreateDefaultDiskLabelKey, lhmgrtypes.NodeCreateDefaultDiskLabelValueTrue)},
+		},
+	}
+}
+
+// newNodeGatePatch returns the merge patch of the gate annotation, and of the default disk label
+// when the node passed the gate. The label is never removed, since Longhorn keeps the disks it
+// created anyway.
+func newNodeGatePatch(decision *nodeGateDecision) ([]byte, error) {
+	metadata := map[string]any{
+		"annotations": map[string]any{
+			consts.AnnotationNodeGate: decision.state,
+		},
+	}
+	if decision.label {
+		metadata["labels"] = map[string]any{
+			lhmgrtypes.NodeCreateDefaultDiskLabelKey: lhmgrtypes.NodeCreateDefaultDiskLabelValueTrue,
+		}
+	}
+
+	data, err := json.Marshal(map[string]any{"metadata": metadata})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to convert the node gate patch to JSON")
+	}
+	return data, nil
+}
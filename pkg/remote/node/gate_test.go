@@ -0,0 +1,123 @@
+package node
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	longhorn "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+	lhmgrtypes "github.com/longhorn/longhorn-manager/types"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func TestGetNodeGateDecision(t *testing.T) {
+	gatedLabeled := &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+		Name:        "node-1",
+		Annotations: map[string]string{consts.AnnotationNodeGate: consts.NodeGateStatePassed},
+		Labels:      map[string]string{lhmgrtypes.NodeCreateDefaultDiskLabelKey: lhmgrtypes.NodeCreateDefaultDiskLabelValueTrue},
+	}}
+	newNode := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}
+	lhNodeWithDisk := &longhorn.Node{Spec: longhorn.NodeSpec{Disks: map[string]longhorn.DiskSpec{"default-disk": {Path: "/var/lib/longhorn"}}}}
+
+	for name, test := range map[string]struct {
+		node      *corev1.Node
+		lhNode    *longhorn.Node
+		result    *types.LogCollection
+		wantState string
+		wantLabel bool
+		wantLevel string
+	}{
+		"labeled by the user": {
+			node:      &corev1.Node{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{lhmgrtypes.NodeCreateDefaultDiskLabelKey: "config"}}},
+			wantLevel: "info",
+		},
+		"has disks": {
+			node:      newNode,
+			lhNode:    lhNodeWithDisk,
+			result:    &types.LogCollection{Error: []string{"Service iscsid is not running"}},
+			wantLevel: "info",
+		},
+		"no result": {
+			node:      newNode,
+			wantState: consts.NodeGateStatePending,
+			wantLevel: "warn",
+		},
+		"skipped": {
+			node:      newNode,
+			result:    &types.LogCollection{Skipped: []string{"Node is not ready"}},
+			wantState: consts.NodeGateStatePending,
+			wantLevel: "warn",
+		},
+		"failed": {
+			node:      newNode,
+			result:    &types.LogCollection{Error: []string{"Service iscsid is not running"}},
+			wantState: consts.NodeGateStateFailed,
+			wantLevel: "error",
+		},
+		"failed after passed": {
+			node:      gatedLabeled,
+			lhNode:    lhNodeWithDisk,
+			result:    &types.LogCollection{Error: []string{"Service iscsid is not running"}},
+			wantState: consts.NodeGateStateFailed,
+			wantLevel: "error",
+		},
+		"passed": {
+			node:      newNode,
+			result:    &types.LogCollection{Info: []string{"Service iscsid is running"}, Warn: []string{"Kernel module nfs is not loaded"}},
+			wantState: consts.NodeGateStatePassed,
+			wantLabel: true,
+			wantLevel: "info",
+		},
+		"gated node with disks passed": {
+			node:      gatedLabeled,
+			lhNode:    lhNodeWithDisk,
+			result:    &types.LogCollection{},
+			wantState: consts.NodeGateStatePassed,
+			wantLabel: true,
+			wantLevel: "info",
+		},
+	} {
+		decision := getNodeGateDecision(test.node, test.lhNode, test.result)
+		if decision.state != test.wantState {
+			t.Errorf("%v: state = %q, want %q", name, decision.state, test.wantState)
+		}
+		if decision.label != test.wantLabel {
+			t.Errorf("%v: label = %v, want %v", name, decision.label, test.wantLabel)
+		}
+
+		levels := map[string]int{"info": len(decision.collection.Info), "warn": len(decision.collection.Warn), "error": len(decision.collection.Error)}
+		for level, count := range levels {
+			if want := map[bool]int{true: 1}[level == test.wantLevel]; count != want {
+				t.Errorf("%v: %d %v messages, want %d: %+v", name, count, level, want, decision.collection)
+			}
+		}
+	}
+}
+
+func TestNewNodeGatePatch(t *testing.T) {
+	for name, test := range map[string]struct {
+		decision *nodeGateDecision
+		want     string
+	}{
+		"pending": {
+			decision: &nodeGateDecision{state: consts.NodeGateStatePending},
+			want:     `{"metadata":{"annotations":{"longhorn.io/longhornctl-node-gate":"pending"}}}`,
+		},
+		"passed": {
+			decision: &nodeGateDecision{state: consts.NodeGateStatePassed, label: true},
+			want:     `{"metadata":{"annotations":{"longhorn.io/longhornctl-node-gate":"passed"},"labels":{"node.longhorn.io/create-default-disk":"true"}}}`,
+		},
+	} {
+		patch, err := newNodeGatePatch(test.decision)
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", name, err)
+			continue
+		}
+		if string(patch) != test.want {
+			t.Errorf("%v: patch = %s, want %s", name, patch, test.want)
+		}
+	}
+}
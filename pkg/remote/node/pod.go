@@ -0,0 +1,204 @@
+package node
+
+import (
+	"context"
+	"io"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/utils/ptr"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	kubeclient "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	commonkube "github.com/longhorn/go-common-libs/kubernetes"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// nodePod is the pod of a DaemonSet on a single node, running commands in the host namespaces of
+// the node.
+type nodePod struct {
+	globalOpts *types.GlobalCmdOptions
+
+	kubeClient *kubeclient.Clientset
+	restConfig *rest.Config
+
+	appName   string // App name of the DaemonSet.
+	namespace string
+	nodeName  string
+
+	pod *corev1.Pod
+}
+
+// newNodePod initializes the clients of the pod, and checks the node exists and matches the node
+// selector.
+func newNodePod(globalOpts *types.GlobalCmdOptions, appName, nodeName string) (*nodePod, error) {
+	kubeClient, err := kubeutils.NewKubeClient("", globalOpts.KubeConfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	restConfig, err := kubeutils.NewRestConfig("", globalOpts.KubeConfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	namespace := globalOpts.Namespace
+	if namespace == "" {
+		namespace = consts.LonghornNamespace
+	}
+
+	node, err := kubeClient.CoreV1().Nodes().Get(context.Background(), nodeName, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get node %v", nodeName)
+	}
+
+	nodeSelector, err := kubeutils.ParseNodeSelector(globalOpts.NodeSelector)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %q argument", consts.CmdOptNodeSelector)
+	}
+	if !labels.SelectorFromSet(nodeSelector).Matches(labels.Set(node.Labels)) {
+		return nil, errors.Errorf("node %v does not match --%s %v", nodeName, consts.CmdOptNodeSelector, globalOpts.NodeSelector)
+	}
+
+	return &nodePod{
+		globalOpts: globalOpts,
+		kubeClient: kubeClient,
+		restConfig: restConfig,
+		appName:    appName,
+		namespace:  namespace,
+		nodeName:   nodeName,
+	}, nil
+}
+
+// start creates the DaemonSet on the node, and waits for its pod to be ready.
+func (p *nodePod) start() error {
+	nodeSelector, err := kubeutils.ParseNodeSelector(p.globalOpts.NodeSelector)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse %q argument", consts.CmdOptNodeSelector)
+	}
+	newDaemonSet := p.newDaemonSet(nodeSelector)
+	kubeutils.SetNodeNameAffinity(&newDaemonSet.Spec.Template.Spec, []string{p.nodeName})
+	if err := kubeutils.SetPodOptions(&newDaemonSet.Spec.Template.Spec, p.globalOpts); err != nil {
+		return err
+	}
+
+	_, err = kubeutils.CreateNamespace(p.kubeClient, p.namespace)
+	if err != nil {
+		return err
+	}
+
+	kubeutils.SetRunMetadata(p.kubeClient, newDaemonSet)
+	kubeutils.LogManifest(newDaemonSet)
+	daemonSet, err := kubeutils.CreateDaemonSet(p.kubeClient, newDaemonSet, p.globalOpts.MinNodes)
+	if err != nil {
+		return err
+	}
+
+	err = kubeutils.MonitorDaemonSetContainer(p.kubeClient, daemonSet, consts.ContainerName, kubeutils.WaitForDaemonSetContainersReady, ptr.To(consts.ContainerConditionMaxTolerationMedium))
+	if err != nil {
+		return err
+	}
+
+	p.pod, err = p.getPod()
+	return err
+}
+
+// exec runs the command in the host namespaces of the node, streaming the stdin to the command and
+// its stdout and stderr to the writers. The stdin is not attached when it is nil.
+func (p *nodePod) exec(ctx context.Context, command []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	if p.pod == nil {
+		return errors.Errorf("pod of %v is not started", p.appName)
+	}
+	return kubeutils.StreamPodContainer(ctx, p.restConfig, p.kubeClient, p.pod.Namespace, p.pod.Name, consts.ContainerName, getHostCommand(command), stdin, stdout, stderr)
+}
+
+// cleanup deletes the DaemonSet.
+func (p *nodePod) cleanup() error {
+	return commonkube.DeleteDaemonSet(p.kubeClient, p.namespace, p.appName)
+}
+
+// getPod returns the pod of the DaemonSet on the node.
+func (p *nodePod) getPod() (*corev1.Pod, error) {
+	podList, err := p.kubeClient.CoreV1().Pods(p.namespace).List(context.Background(), metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set{"app": p.appName}).String(),
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", p.nodeName).String(),
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list pods of %v", p.appName)
+	}
+
+	for i := range podList.Items {
+		if podList.Items[i].DeletionTimestamp == nil {
+			return &podList.Items[i], nil
+		}
+	}
+	return nil, errors.Errorf("no pod of %v is running on node %v", p.appName, p.nodeName)
+}
+
+// getHostCommand returns the command entering the namespaces of the host process 1, so the command
+// sees the filesystems, network and processes of the node.
+func getHostCommand(command []string) []string {
+	return append([]string{"nsenter", "--target", "1", "--mount", "--uts", "--ipc", "--net", "--pid", "--"}, command...)
+}
+
+// newDaemonSet prepares the DaemonSet running the commands. The pod shares the PID namespace of the
+// host, and idles until the commands are run in its container. It tolerates all the taints, since
+// the node is selected explicitly, and tainted nodes are often the ones to diagnose.
+func (p *nodePod) newDaemonSet(nodeSelector map[string]string) *appsv1.DaemonSet {
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      p.appName,
+			Namespace: p.namespace,
+			Labels: map[string]string{
+				"app":                 p.appName,
+				consts.LabelManagedBy: consts.LabelValueManagedBy,
+			},
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app": p.appName,
+				},
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						"app":                 p.appName,
+						consts.LabelManagedBy: consts.LabelValueManagedBy,
+					},
+				},
+				Spec: corev1.PodSpec{
+					HostPID: true,
+					Tolerations: []corev1.Toleration{
+						{
+							Operator: corev1.TolerationOpExists,
+						},
+					},
+					Containers: []corev1.Container{
+						{
+							Name:            consts.ContainerName,
+							Image:           p.globalOpts.Image,
+							Command:         []string{"sleep", "infinity"},
+							SecurityContext: kubeutils.NewSecurityContext(p.globalOpts.Privileged, kubeutils.CapabilitiesHostNamespaces),
+						},
+					},
+					TerminationGracePeriodSeconds: ptr.To(int64(0)),
+					NodeSelector:                  nodeSelector,
+				},
+			},
+			UpdateStrategy: appsv1.DaemonSetUpdateStrategy{
+				Type: appsv1.RollingUpdateDaemonSetStrategyType,
+			},
+		},
+	}
+}
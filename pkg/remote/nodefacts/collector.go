@@ -0,0 +1,213 @@
+package nodefacts
+
+import (
+	"encoding/json"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/utils/ptr"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeclient "k8s.io/client-go/kubernetes"
+
+	commonkube "github.com/longhorn/go-common-libs/kubernetes"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// Collector provide functions for collecting the hardware and operating system inventory of the
+// nodes.
+type Collector struct {
+	CollectorCmdOptions
+
+	kubeClient *kubeclient.Clientset
+
+	appName   string // App name of the DaemonSet.
+	namespace string
+}
+
+// CollectorCmdOptions holds the options for the command.
+type CollectorCmdOptions struct {
+	types.GlobalCmdOptions
+}
+
+// Init initializes the Collector.
+func (remote *Collector) Init() error {
+	kubeClient, err := kubeutils.NewKubeClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.kubeClient = kubeClient
+
+	remote.namespace = remote.Namespace
+	if remote.namespace == "" {
+		remote.namespace = consts.LonghornNamespace
+	}
+	remote.appName = consts.AppNameNodeFactsCollector
+
+	return nil
+}
+
+// Run creates the DaemonSet collecting the facts of the nodes, and returns them keyed by the node
+// name once the init and output containers of all the pods complete.
+func (remote *Collector) Run() (*types.NodeFactsCollection, error) {
+	nodeSelector, err := kubeutils.ParseNodeSelector(remote.NodeSelector)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %q argument", consts.CmdOptNodeSelector)
+	}
+	newDaemonSet := remote.newDaemonSet(nodeSelector)
+	if err := kubeutils.SetPodOptions(&newDaemonSet.Spec.Template.Spec, &remote.GlobalCmdOptions); err != nil {
+		return nil, err
+	}
+
+	_, err = kubeutils.CreateNamespace(remote.kubeClient, remote.namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	kubeutils.SetRunMetadata(remote.kubeClient, newDaemonSet)
+	kubeutils.LogManifest(newDaemonSet)
+	daemonSet, err := kubeutils.CreateDaemonSet(remote.kubeClient, newDaemonSet, remote.MinNodes)
+	if err != nil {
+		return nil, err
+	}
+
+	err = kubeutils.MonitorDaemonSetContainer(remote.kubeClient, daemonSet, consts.ContainerNameInit, kubeutils.WaitForDaemonSetContainersExit, ptr.To(consts.ContainerConditionMaxTolerationMedium))
+	if err != nil {
+		return nil, err
+	}
+
+	err = kubeutils.MonitorDaemonSetContainer(remote.kubeClient, daemonSet, consts.ContainerNameOutput, kubeutils.WaitForDaemonSetContainersExit, ptr.To(consts.ContainerConditionMaxTolerationShort))
+	if err != nil {
+		return nil, err
+	}
+
+	podCollections, err := kubeutils.GetDaemonSetPodCollections(remote.kubeClient, daemonSet, consts.ContainerNameOutput, false, false, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	collection := &types.NodeFactsCollection{
+		Nodes: map[string]*types.NodeFacts{},
+	}
+	for _, podCollection := range podCollections.Pods {
+		facts := &types.NodeFacts{}
+		if err := json.Unmarshal([]byte(podCollection.Log), facts); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse the facts of node %v", podCollection.Node)
+		}
+		collection.Nodes[podCollection.Node] = facts
+	}
+
+	return collection, nil
+}
+
+// Cleanup deletes the DaemonSet created for collecting the facts of the nodes.
+func (remote *Collector) Cleanup() error {
+	return commonkube.DeleteDaemonSet(remote.kubeClient, remote.namespace, remote.appName)
+}
+
+// newDaemonSet prepares the DaemonSet collecting the facts of the nodes. The facts are read from
+// the proc and sys directories of the host root filesystem, mounted read-only.
+func (remote *Collector) newDaemonSet(nodeSelector map[string]string) *appsv1.DaemonSet {
+	outputFilePath := filepath.Join(consts.VolumeMountSharedDirectory, consts.FileNameOutputJSON)
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      remote.appName,
+			Namespace: remote.namespace,
+			Labels: map[string]string{
+				"app":                 remote.appName,
+				consts.LabelManagedBy: consts.LabelValueManagedBy,
+			},
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app": remote.appName,
+				},
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						"app":                 remote.appName,
+						consts.LabelManagedBy: consts.LabelValueManagedBy,
+					},
+				},
+				Spec: corev1.PodSpec{
+					InitContainers: []corev1.Container{
+						{
+							Name:    consts.ContainerNameInit,
+							Image:   remote.Image,
+							Command: []string{consts.CmdLonghornctlLocal, consts.SubCmdCollect, consts.SubCmdNodeFacts},
+							Env: []corev1.EnvVar{
+								{
+									Name:  consts.EnvLogLevel,
+									Value: remote.LogLevel,
+								},
+								{
+									Name:  consts.EnvOutputFilePath,
+									Value: outputFilePath,
+								},
+							},
+							SecurityContext: kubeutils.NewSecurityContext(remote.Privileged, kubeutils.CapabilitiesHostRead),
+							VolumeMounts: []corev1.VolumeMount{
+								{
+									Name:      consts.VolumeMountHostName,
+									MountPath: consts.VolumeMountHostDirectory,
+									ReadOnly:  true,
+								},
+								{
+									Name:      consts.VolumeMountSharedName,
+									MountPath: consts.VolumeMountSharedDirectory,
+								},
+							},
+						},
+						{
+							Name:    consts.ContainerNameOutput,
+							Image:   remote.Image,
+							Command: []string{"cat", outputFilePath},
+							Env:     []corev1.EnvVar{},
+							VolumeMounts: []corev1.VolumeMount{
+								{
+									Name:      consts.VolumeMountSharedName,
+									MountPath: consts.VolumeMountSharedDirectory,
+								},
+							},
+						},
+					},
+					Containers: []corev1.Container{
+						{
+							Name:  consts.ContainerNamePause,
+							Image: consts.ImagePause,
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: consts.VolumeMountHostName,
+							VolumeSource: corev1.VolumeSource{
+								HostPath: &corev1.HostPathVolumeSource{
+									Path: "/",
+								},
+							},
+						},
+						{
+							Name: consts.VolumeMountSharedName,
+							VolumeSource: corev1.VolumeSource{
+								EmptyDir: &corev1.EmptyDirVolumeSource{},
+							},
+						},
+					},
+					NodeSelector: nodeSelector,
+				},
+			},
+			UpdateStrategy: appsv1.DaemonSetUpdateStrategy{
+				Type: appsv1.RollingUpdateDaemonSetStrategyType,
+			},
+		},
+	}
+}
@@ -0,0 +1,178 @@
+package operation
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	kubeclient "k8s.io/client-go/kubernetes"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// Getter provide functions for listing the longhornctl operations running in the cluster.
+type Getter struct {
+	GetterCmdOptions
+
+	kubeClient *kubeclient.Clientset
+}
+
+// GetterCmdOptions holds the options for the command.
+type GetterCmdOptions struct {
+	types.GlobalCmdOptions
+}
+
+// Init initializes the Getter.
+func (remote *Getter) Init() error {
+	kubeClient, err := kubeutils.NewKubeClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.kubeClient = kubeClient
+
+	return nil
+}
+
+// Run returns the operations running in all the namespaces, sorted by namespace and name. They are
+// the DaemonSets and the pods without controller labeled as managed by longhornctl, with the state
+// of their pods on each node.
+func (remote *Getter) Run() ([]types.Operation, error) {
+	ctx := context.Background()
+	listOptions := metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set{consts.LabelManagedBy: consts.LabelValueManagedBy}).String(),
+	}
+
+	daemonSetList, err := remote.kubeClient.AppsV1().DaemonSets(metav1.NamespaceAll).List(ctx, listOptions)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list DaemonSets")
+	}
+
+	podList, err := remote.kubeClient.CoreV1().Pods(metav1.NamespaceAll).List(ctx, listOptions)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list pods")
+	}
+
+	operations := []*types.Operation{}
+	daemonSetOperations := map[k8stypes.UID]*types.Operation{}
+	for _, daemonSet := range daemonSetList.Items {
+		operation := &types.Operation{
+			Name:      daemonSet.Name,
+			Namespace: daemonSet.Namespace,
+			Kind:      "DaemonSet",
+			StartTime: daemonSet.CreationTimestamp.Time,
+		}
+		operations = append(operations, operation)
+		daemonSetOperations[daemonSet.UID] = operation
+	}
+
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+
+		controller := metav1.GetControllerOf(pod)
+		if controller == nil {
+			operations = append(operations, &types.Operation{
+				Name:      pod.Name,
+				Namespace: pod.Namespace,
+				Kind:      "Pod",
+				StartTime: pod.CreationTimestamp.Time,
+				Nodes:     []*types.OperationNode{newOperationNode(pod)},
+			})
+			continue
+		}
+
+		if operation, ok := daemonSetOperations[controller.UID]; ok {
+			operation.Nodes = append(operation.Nodes, newOperationNode(pod))
+		}
+	}
+
+	sort.Slice(operations, func(i, j int) bool {
+		if operations[i].Namespace != operations[j].Namespace {
+			return operations[i].Namespace < operations[j].Namespace
+		}
+		return operations[i].Name < operations[j].Name
+	})
+
+	result := []types.Operation{}
+	for _, operation := range operations {
+		sort.Slice(operation.Nodes, func(i, j int) bool {
+			return operation.Nodes[i].Node < operation.Nodes[j].Node
+		})
+		result = append(result, *operation)
+	}
+	return result, nil
+}
+
+// newOperationNode returns the state of the pod of an operation on its node.
+func newOperationNode(pod *corev1.Pod) *types.OperationNode {
+	operationNode := &types.OperationNode{
+		Node:      pod.Spec.NodeName,
+		Pod:       pod.Name,
+		State:     getPodState(pod),
+		StartTime: pod.CreationTimestamp.Time,
+	}
+
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			operationNode.Ready = condition.Status == corev1.ConditionTrue
+		}
+	}
+
+	for _, status := range pod.Status.ContainerStatuses {
+		operationNode.Restarts += status.RestartCount
+	}
+
+	return operationNode
+}
+
+// getPodState returns the state of the pod as kubectl shows it: the reason its init containers or
+// containers are waiting or terminated, or its phase.
+func getPodState(pod *corev1.Pod) string {
+	if pod.DeletionTimestamp != nil {
+		return "Terminating"
+	}
+
+	for _, status := range pod.Status.InitContainerStatuses {
+		switch {
+		case status.State.Terminated != nil && status.State.Terminated.ExitCode == 0:
+			continue
+		case status.State.Terminated != nil:
+			return "Init:" + orDefault(status.State.Terminated.Reason, fmt.Sprintf("ExitCode:%d", status.State.Terminated.ExitCode))
+		case status.State.Waiting != nil && status.State.Waiting.Reason != "" && status.State.Waiting.Reason != "PodInitializing":
+			return "Init:" + status.State.Waiting.Reason
+		default:
+			return "Init:Running"
+		}
+	}
+
+	reasons := []string{}
+	for _, status := range pod.Status.ContainerStatuses {
+		switch {
+		case status.State.Waiting != nil && status.State.Waiting.Reason != "":
+			reasons = append(reasons, status.State.Waiting.Reason)
+		case status.State.Terminated != nil && pod.Status.Phase == corev1.PodRunning:
+			reasons = append(reasons, orDefault(status.State.Terminated.Reason, fmt.Sprintf("ExitCode:%d", status.State.Terminated.ExitCode)))
+		}
+	}
+	if len(reasons) != 0 {
+		return strings.Join(reasons, ",")
+	}
+
+	return string(pod.Status.Phase)
+}
+
+func orDefault(value, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
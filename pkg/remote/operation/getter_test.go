@@ -0,0 +1,65 @@
+package operation
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetPodState(t *testing.T) {
+	tests := map[string]struct {
+		pod      corev1.Pod
+		expected string
+	}{
+		"running": {
+			pod: corev1.Pod{Status: corev1.PodStatus{
+				Phase:                 corev1.PodRunning,
+				InitContainerStatuses: []corev1.ContainerStatus{{State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "Completed"}}}},
+				ContainerStatuses:     []corev1.ContainerStatus{{State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}}},
+			}},
+			expected: "Running",
+		},
+		"init running": {
+			pod: corev1.Pod{Status: corev1.PodStatus{
+				Phase:                 corev1.PodPending,
+				InitContainerStatuses: []corev1.ContainerStatus{{State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}}},
+				ContainerStatuses:     []corev1.ContainerStatus{{State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "PodInitializing"}}}},
+			}},
+			expected: "Init:Running",
+		},
+		"init failed": {
+			pod: corev1.Pod{Status: corev1.PodStatus{
+				Phase:                 corev1.PodPending,
+				InitContainerStatuses: []corev1.ContainerStatus{{State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "Error", ExitCode: 1}}}},
+			}},
+			expected: "Init:Error",
+		},
+		"image pull": {
+			pod: corev1.Pod{Status: corev1.PodStatus{
+				Phase:             corev1.PodPending,
+				ContainerStatuses: []corev1.ContainerStatus{{State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff"}}}},
+			}},
+			expected: "ImagePullBackOff",
+		},
+		"terminating": {
+			pod: corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{DeletionTimestamp: &metav1.Time{}},
+				Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+			},
+			expected: "Terminating",
+		},
+		"unscheduled": {
+			pod:      corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodPending}},
+			expected: "Pending",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if state := getPodState(&test.pod); state != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, state)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package preflight
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/blang/semver/v4"
+	"github.com/pkg/errors"
+
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils/registry"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+const imagePlatformCheckTimeout = 30 * time.Second
+
+// supportedArchitectures are the CPU architectures Longhorn supports, with the first version
+// supporting each of them.
+var supportedArchitectures = map[string]string{
+	"amd64": "v1.0.0",
+	"arm64": "v1.1.0",
+	"s390x": "v1.6.0",
+}
+
+// checkArchitectures checks the CPU architecture of each node matching the node selector is
+// supported by the target Longhorn version, and the image of the DaemonSet provides a platform
+// for it. It returns an error when the image lacks the platform of a node, since the pods could
+// not start there.
+func (remote *Checker) checkArchitectures(nodeSelector map[string]string) (map[string]*types.LogCollection, error) {
+	nodes, err := kubeutils.ListNodes(remote.kubeClient, labels.SelectorFromSet(nodeSelector))
+	if err != nil {
+		return nil, err
+	}
+
+	version := remote.LonghornVersion
+	if version == "" {
+		version = remote.RegistryCheckVersion
+	}
+
+	nodeCollections := map[string]*types.LogCollection{}
+	nodeArchitectures := map[string]string{}
+	for _, node := range nodes {
+		if kubeutils.GetUnsupportedNodeReason(node) != "" {
+			continue
+		}
+
+		collection := &types.LogCollection{}
+		nodeCollections[node.Name] = collection
+
+		architecture := node.Status.NodeInfo.Architecture
+		nodeArchitectures[node.Name] = architecture
+		inspectNodeArchitecture(collection, architecture, version)
+	}
+
+	ref := registry.ParseImageReference(remote.Image)
+	httpClient := &http.Client{Timeout: imagePlatformCheckTimeout}
+	platforms, err := registry.GetImagePlatforms(httpClient, registry.GetUpstreamEndpoint(ref.Registry), ref)
+	if err != nil {
+		for _, collection := range nodeCollections {
+			collection.Warn = append(collection.Warn, fmt.Sprintf("Failed to get the platforms of image %v: %v", remote.Image, err))
+		}
+		return nodeCollections, nil
+	}
+
+	missingNodes := map[string][]string{}
+	for nodeName, architecture := range nodeArchitectures {
+		if !hasPlatform(platforms, architecture) {
+			missingNodes[architecture] = append(missingNodes[architecture], nodeName)
+			continue
+		}
+		nodeCollections[nodeName].Info = append(nodeCollections[nodeName].Info, fmt.Sprintf("Image %v provides the linux/%v platform", remote.Image, architecture))
+	}
+
+	if len(missingNodes) > 0 {
+		var missing []string
+		for architecture, nodeNames := range missingNodes {
+			sort.Strings(nodeNames)
+			missing = append(missing, fmt.Sprintf("linux/%v (%v)", architecture, strings.Join(nodeNames, ", ")))
+		}
+		sort.Strings(missing)
+		return nil, errors.Errorf("image %v (--%s) provides platforms %v, but not the ones of the nodes: %v", remote.Image, consts.CmdOptImage, strings.Join(platforms, ", "), strings.Join(missing, "; "))
+	}
+
+	return nodeCollections, nil
+}
+
+// ValidateLonghornVersion checks the target Longhorn version is a semantic version, when it is specified.
+func ValidateLonghornVersion(version string) error {
+	if version == "" {
+		return nil
+	}
+
+	if _, err := semver.ParseTolerant(version); err != nil {
+		return errors.Wrapf(err, "invalid Longhorn version %q (--%s)", version, consts.CmdOptLonghornVersion)
+	}
+	return nil
+}
+
+// inspectNodeArchitecture checks Longhorn supports the CPU architecture of the node, from the given
+// version when it is specified.
+func inspectNodeArchitecture(collection *types.LogCollection, architecture, version string) {
+	supportedSince, ok := supportedArchitectures[architecture]
+	if !ok {
+		collection.Error = append(collection.Error, fmt.Sprintf("CPU architecture %v is not supported by Longhorn", architecture))
+		return
+	}
+
+	if version == "" {
+		collection.Info = append(collection.Info, fmt.Sprintf("CPU architecture %v is supported by Longhorn since %v", architecture, supportedSince))
+		return
+	}
+
+	if !isArchitectureSupported(supportedSince, version) {
+		collection.Error = append(collection.Error, fmt.Sprintf("CPU architecture %v is not supported by Longhorn %v, it is supported since %v", architecture, version, supportedSince))
+		return
+	}
+
+	collection.Info = append(collection.Info, fmt.Sprintf("CPU architecture %v is supported by Longhorn %v", architecture, version))
+}
+
+// isArchitectureSupported returns whether the Longhorn version is the one the architecture is
+// supported since or a later one. Pre-releases of the first supported version count as supported.
+func isArchitectureSupported(supportedSince, version string) bool {
+	target, err := semver.ParseTolerant(version)
+	if err != nil {
+		return false
+	}
+	target.Pre = nil
+	target.Build = nil
+
+	return target.GTE(semver.MustParse(strings.TrimPrefix(supportedSince, "v")))
+}
+
+// hasPlatform returns whether the image platforms include the Linux platform of the architecture,
+// with any variant.
+func hasPlatform(platforms []string, architecture string) bool {
+	platform := "linux/" + architecture
+	for _, p := range platforms {
+		if p == platform || strings.HasPrefix(p, platform+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package preflight
+
+import (
+	"testing"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func TestInspectNodeArchitecture(t *testing.T) {
+	tests := []struct {
+		architecture  string
+		version       string
+		expectedError bool
+	}{
+		{architecture: "amd64", version: "v1.7.2"},
+		{architecture: "arm64", version: ""},
+		{architecture: "arm64", version: "v1.0.2", expectedError: true},
+		{architecture: "s390x", version: "1.6.0-rc1"},
+		{architecture: "s390x", version: "v1.5.3", expectedError: true},
+		{architecture: "ppc64le", version: "v1.7.2", expectedError: true},
+	}
+
+	for _, test := range tests {
+		collection := &types.LogCollection{}
+		inspectNodeArchitecture(collection, test.architecture, test.version)
+		if hasError := len(collection.Error) > 0; hasError != test.expectedError {
+			t.Errorf("architecture %v, version %q: expected error %v, got %v", test.architecture, test.version, test.expectedError, collection)
+		}
+	}
+}
+
+func TestHasPlatform(t *testing.T) {
+	platforms := []string{"linux/amd64", "linux/arm/v7", "linux/arm64/v8"}
+
+	tests := map[string]bool{
+		"amd64": true,
+		"arm":   true,
+		"arm64": true,
+		"s390x": false,
+	}
+
+	for architecture, expected := range tests {
+		if found := hasPlatform(platforms, architecture); found != expected {
+			t.Errorf("hasPlatform(%v) = %v, expected %v", architecture, found, expected)
+		}
+	}
+}
+
+func TestValidateLonghornVersion(t *testing.T) {
+	for _, version := range []string{"", "v1.7.2", "1.8.0-rc1"} {
+		if err := ValidateLonghornVersion(version); err != nil {
+			t.Errorf("ValidateLonghornVersion(%q) unexpected error: %v", version, err)
+		}
+	}
+
+	if err := ValidateLonghornVersion("latest"); err == nil {
+		t.Errorf("expected error for invalid version")
+	}
+}
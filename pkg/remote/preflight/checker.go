@@ -1,25 +1,35 @@
 package preflight
 
 import (
+	"context"
 	"encoding/json"
+	"net/http"
+	"os"
 	"path/filepath"
 	"reflect"
+	"regexp"
+	"strings"
 
 	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 
-	"k8s.io/utils/ptr"
 	"sigs.k8s.io/kustomize/kyaml/yaml"
 
+	sigsyaml "sigs.k8s.io/yaml"
+
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	kubeclient "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 
 	commonkube "github.com/longhorn/go-common-libs/kubernetes"
 	commonutils "github.com/longhorn/go-common-libs/utils"
 
 	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/remote/preload"
+	"github.com/longhorn/cli/pkg/remote/ssh"
 	"github.com/longhorn/cli/pkg/types"
 
 	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
@@ -30,90 +40,348 @@ type Checker struct {
 	CheckerCmdOptions
 
 	kubeClient *kubeclient.Clientset
+	restConfig *rest.Config
+	sshRunner  *ssh.Runner // Runner of the SSH backend. Nil with the DaemonSet backend.
 
 	namespace string
 	appName   string // App name of the DaemonSet.
+
+	customChecksData      string // Content of the custom checks file.
+	customChecksConfigMap string // Name of the ConfigMap mounted with the custom checks.
+
+	severityPolicy *types.SeverityPolicy // Policy changing the level of the messages. Nil when not given.
 }
 
 // CheckerCmdOptions holds the options for the command.
 type CheckerCmdOptions struct {
 	types.GlobalCmdOptions
+	types.SSHCmdOptions
 
 	EnableSpdk      bool
 	HugePageSize    int
+	HugePageNodes   string // Comma-separated huge page sizes in MiB per NUMA node, for example "0=1024,1=1024".
 	UserspaceDriver string
+
+	Profile string // Managed platform or Kubernetes distribution enabling its specific checks.
+
+	LonghornDataDirectories []string // Data paths of the Longhorn disks on the nodes.
+
+	SummarizeBy string // Category the nodes are grouped by in the summary of the result (zone, nodepool, os).
+
+	CryptoBenchmark bool // Benchmark the default cipher of the encrypted volumes on each node.
+
+	RulesURL    string // URL of the known issues database overriding the embedded one.
+	KnownIssues string // Known issues JSON downloaded from the rules URL. The nodes use the embedded ones when empty.
+
+	LonghornVersion string // Longhorn version to check the CPU architecture of the nodes is supported by.
+
+	CustomChecksFile      string // Path to a YAML file defining custom checks.
+	CustomChecksConfigMap string // Name of an existing ConfigMap defining custom checks.
+
+	RegistryCheckVersion    string // Longhorn version of the images to check through the registry mirrors of the nodes.
+	RegistryCheckImagesFile string // Path to a file listing the images to check through the registry mirrors of the nodes.
+	RegistryCheckImages     string // Comma-separated images to check, resolved from the version or the images file.
+
+	MaxParallel int // Maximum number of nodes to run on at the same time. Runs on all nodes at once when not positive.
+
+	SeverityPolicyFile string // Path to a YAML file changing the level of the messages.
 }
 
 // Init initializes the Checker.
 func (remote *Checker) Init() error {
-	kubeClient, err := kubeutils.NewKubeClient("", remote.KubeConfigPath)
+	if err := validateBackend(&remote.SSHCmdOptions); err != nil {
+		return err
+	}
+
+	sshRunner, err := newSSHRunner(&remote.SSHCmdOptions, remote.MaxParallel)
 	if err != nil {
 		return err
 	}
+	remote.sshRunner = sshRunner
+
+	if remote.sshRunner != nil && remote.CustomChecksConfigMap != "" {
+		return errors.Errorf("--%s is not supported with the %s backend", consts.CmdOptCustomChecksConfigMap, consts.BackendSSH)
+	}
+
+	if err := ValidateSummarizeBy(remote.SummarizeBy); err != nil {
+		return err
+	}
+	if remote.sshRunner != nil && remote.SummarizeBy != "" {
+		return errors.Errorf("--%s is not supported with the %s backend", consts.CmdOptSummarizeBy, consts.BackendSSH)
+	}
 
-	remote.kubeClient = kubeClient
+	if remote.sshRunner == nil {
+		kubeClient, err := kubeutils.NewKubeClient("", remote.KubeConfigPath)
+		if err != nil {
+			return err
+		}
+		remote.kubeClient = kubeClient
 
-	remote.namespace = metav1.NamespaceDefault
+		restConfig, err := kubeutils.NewRestConfig("", remote.KubeConfigPath)
+		if err != nil {
+			return err
+		}
+		remote.restConfig = restConfig
+	}
+
+	remote.namespace = remote.Namespace
+	if remote.namespace == "" {
+		remote.namespace = consts.LonghornNamespace
+	}
 	remote.appName = consts.AppNamePreflightChecker
+
+	if remote.CustomChecksFile != "" && remote.CustomChecksConfigMap != "" {
+		return errors.Errorf("only one of --%s and --%s can be specified", consts.CmdOptCustomChecks, consts.CmdOptCustomChecksConfigMap)
+	}
+
+	remote.customChecksConfigMap = remote.CustomChecksConfigMap
+	if remote.CustomChecksFile != "" {
+		data, err := os.ReadFile(remote.CustomChecksFile)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read custom checks file %v", remote.CustomChecksFile)
+		}
+
+		if _, err := ParseCustomChecks(data); err != nil {
+			return err
+		}
+
+		remote.customChecksData = string(data)
+		remote.customChecksConfigMap = remote.appName + "-" + consts.VolumeMountCustomChecksName
+	}
+
+	if remote.SeverityPolicyFile != "" {
+		data, err := os.ReadFile(remote.SeverityPolicyFile)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read severity policy file %v", remote.SeverityPolicyFile)
+		}
+
+		remote.severityPolicy, err = ParseSeverityPolicy(data)
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err := ParseHugePageNodes(remote.HugePageNodes); err != nil {
+		return err
+	}
+
+	if err := ValidateProfile(remote.Profile); err != nil {
+		return err
+	}
+
+	if err := ValidateDataDirectories(remote.LonghornDataDirectories); err != nil {
+		return err
+	}
+
+	if err := ValidateLonghornVersion(remote.LonghornVersion); err != nil {
+		return err
+	}
+
+	if remote.RulesURL != "" {
+		issueList, err := FetchKnownIssues(&http.Client{Timeout: knownIssuesFetchTimeout}, remote.RulesURL)
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(issueList)
+		if err != nil {
+			return errors.Wrap(err, "failed to convert known issues to JSON")
+		}
+		remote.KnownIssues = string(data)
+		logrus.Infof("Downloaded %d known issues from %v", len(issueList.Issues), remote.RulesURL)
+	}
+
+	if remote.RegistryCheckVersion != "" || remote.RegistryCheckImagesFile != "" {
+		images, err := preload.LoadImages(remote.RegistryCheckVersion, remote.RegistryCheckImagesFile)
+		if err != nil {
+			return errors.Wrap(err, "failed to get the images for the registry check")
+		}
+		remote.RegistryCheckImages = strings.Join(images, consts.CmdOptSeperator)
+	}
+
+	return nil
+}
+
+// ValidateProfile checks the profile of the preflight check is supported. An empty profile runs
+// the checks for all the platforms.
+func ValidateProfile(profile string) error {
+	switch profile {
+	case "", consts.PreflightProfileAKS, consts.PreflightProfileEKS, consts.PreflightProfileGKE, consts.PreflightProfileK3s, consts.PreflightProfileRKE2:
+		return nil
+	default:
+		return errors.Errorf("unsupported profile %q (--%s), supported profiles: %s, %s, %s, %s, %s", profile, consts.CmdOptProfile, consts.PreflightProfileGKE, consts.PreflightProfileEKS, consts.PreflightProfileAKS, consts.PreflightProfileRKE2, consts.PreflightProfileK3s)
+	}
+}
+
+// ValidateSummarizeBy checks the nodes can be grouped by the category in the summary of the
+// result. An empty category lists each node.
+func ValidateSummarizeBy(category string) error {
+	switch category {
+	case "", consts.SummarizeByZone, consts.SummarizeByNodePool, consts.SummarizeByOS:
+		return nil
+	default:
+		return errors.Errorf("unsupported category %q (--%s), supported categories: %s, %s, %s", category, consts.CmdOptSummarizeBy, consts.SummarizeByZone, consts.SummarizeByNodePool, consts.SummarizeByOS)
+	}
+}
+
+// ValidateDataDirectories checks the Longhorn data directories are absolute paths on the host, and
+// are not listed twice.
+func ValidateDataDirectories(dataDirectories []string) error {
+	seen := map[string]bool{}
+	for _, dataDirectory := range dataDirectories {
+		if !filepath.IsAbs(dataDirectory) {
+			return errors.Errorf("data directory %q (--%s) is not an absolute path", dataDirectory, consts.CmdOptLonghornDataDirectory)
+		}
+
+		cleaned := filepath.Clean(dataDirectory)
+		if seen[cleaned] {
+			return errors.Errorf("data directory %q (--%s) is listed more than once", dataDirectory, consts.CmdOptLonghornDataDirectory)
+		}
+		seen[cleaned] = true
+	}
 	return nil
 }
 
-// Run creates the DaemonSet for the preflight check, and waits for it to complete.
+// ParseCustomChecks parses and validates the custom checks YAML.
+func ParseCustomChecks(data []byte) (*types.CustomCheckList, error) {
+	checkList := &types.CustomCheckList{}
+	if err := sigsyaml.UnmarshalStrict(data, checkList); err != nil {
+		return nil, errors.Wrap(err, "failed to parse custom checks")
+	}
+
+	for i, check := range checkList.Checks {
+		if check.Name == "" {
+			return nil, errors.Errorf("custom check #%d has no name", i)
+		}
+
+		if check.Command == "" {
+			return nil, errors.Errorf("custom check %v has no command", check.Name)
+		}
+
+		if _, err := regexp.Compile(check.ExpectedOutput); err != nil {
+			return nil, errors.Wrapf(err, "custom check %v has an invalid expected output", check.Name)
+		}
+
+		switch check.Severity {
+		case "", types.CustomCheckSeverityError, types.CustomCheckSeverityWarn, types.CustomCheckSeverityInfo:
+		default:
+			return nil, errors.Errorf("custom check %v has an invalid severity %q", check.Name, check.Severity)
+		}
+	}
+
+	return checkList, nil
+}
+
+// Run creates the DaemonSet for the preflight check, waits for it to complete,
+// and returns the per-node result as a YAML string.
 func (remote *Checker) Run() (string, error) {
-	err := remote.createRbacForNodeAgent()
+	nodeCollections, err := remote.Collect()
 	if err != nil {
 		return "", err
 	}
 
-	nodeSelector, err := kubeutils.ParseNodeSelector(remote.NodeSelector)
-	if err != nil {
-		return "", errors.Wrapf(err, "failed to parse %q argument", consts.CmdOptNodeSelector)
+	if reflect.DeepEqual(nodeCollections, map[string]types.LogCollection{}) {
+		return "", nil
 	}
-	newDaemonSet := remote.newDaemonSet(nodeSelector)
-	daemonSet, err := commonkube.CreateDaemonSet(remote.kubeClient, newDaemonSet)
+
+	yamlData, err := yaml.Marshal(nodeCollections)
 	if err != nil {
 		return "", err
 	}
 
-	err = kubeutils.MonitorDaemonSetContainer(remote.kubeClient, daemonSet, consts.ContainerNameInit, kubeutils.WaitForDaemonSetContainersExit, ptr.To(consts.ContainerConditionMaxTolerationMedium))
+	return string(yamlData), nil
+}
+
+// Collect creates the DaemonSet for the preflight check, waits for it to complete,
+// and returns the check result of each node keyed by the node name.
+// With the SSH backend, it runs the check on the SSH hosts instead, keyed by the host name.
+// The messages are reported at the levels of the severity policy, when given.
+func (remote *Checker) Collect() (map[string]*types.LogCollection, error) {
+	nodeCollections, err := remote.collect()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	err = kubeutils.MonitorDaemonSetContainer(remote.kubeClient, daemonSet, consts.ContainerNameOutput, kubeutils.WaitForDaemonSetContainersExit, ptr.To(consts.ContainerConditionMaxTolerationShort))
+	if remote.severityPolicy != nil {
+		if err := ApplySeverityPolicy(remote.severityPolicy, nodeCollections); err != nil {
+			return nil, err
+		}
+	}
+	return nodeCollections, nil
+}
+
+// HasSeverityPolicy returns true when the messages are reported at the levels of a severity policy.
+func (remote *Checker) HasSeverityPolicy() bool {
+	return remote.severityPolicy != nil
+}
+
+func (remote *Checker) collect() (map[string]*types.LogCollection, error) {
+	if remote.sshRunner != nil {
+		return remote.collectOverSSH()
+	}
+
+	_, err := kubeutils.CreateNamespace(remote.kubeClient, remote.namespace)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	podCollections, err := kubeutils.GetDaemonSetPodCollections(remote.kubeClient, daemonSet, consts.ContainerNameOutput, false, false, nil)
+	err = remote.createRbacForNodeAgent()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	nodeCollections := map[string]*types.LogCollection{}
-	for _, collection := range podCollections.Pods {
-		var resultMap types.NodeCollection
-		if err := json.Unmarshal([]byte(collection.Log), &resultMap); err != nil {
-			return "", err
+	if remote.customChecksData != "" {
+		customChecksConfigMap := remote.newCustomChecksConfigMap()
+		kubeutils.SetRunMetadata(remote.kubeClient, customChecksConfigMap)
+		_, err = kubeutils.CreateOrUpdateConfigMap(remote.kubeClient, customChecksConfigMap)
+		if err != nil {
+			return nil, err
 		}
+	}
 
-		if reflect.DeepEqual(resultMap, types.NodeCollection{}) {
-			continue
-		}
+	nodeSelector, err := kubeutils.ParseNodeSelector(remote.NodeSelector)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %q argument", consts.CmdOptNodeSelector)
+	}
 
-		nodeCollections[collection.Node] = resultMap.Log
+	architectureCollections, err := remote.checkArchitectures(nodeSelector)
+	if err != nil {
+		return nil, err
 	}
 
-	if reflect.DeepEqual(nodeCollections, map[string]types.LogCollection{}) {
-		return "", nil
+	newDaemonSet := remote.newDaemonSet(nodeSelector)
+	if err := kubeutils.SetPodOptions(&newDaemonSet.Spec.Template.Spec, &remote.GlobalCmdOptions); err != nil {
+		return nil, err
 	}
 
-	yamlData, err := yaml.Marshal(nodeCollections)
+	nodeCollections := map[string]*types.LogCollection{}
+	err = kubeutils.RunDaemonSetInBatches(remote.kubeClient, newDaemonSet, remote.MinNodes, remote.MaxParallel, func(daemonSet *appsv1.DaemonSet) error {
+		return collectNodeCollections(remote.restConfig, remote.kubeClient, daemonSet, nodeCollections)
+	})
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return string(yamlData), nil
+	if err := kubeutils.AddSkippedNodes(remote.kubeClient, newDaemonSet, nodeCollections); err != nil {
+		return nil, err
+	}
+
+	for nodeName, architectureCollection := range architectureCollections {
+		collection := nodeCollections[nodeName]
+		if collection == nil {
+			continue
+		}
+		collection.Error = append(collection.Error, architectureCollection.Error...)
+		collection.Warn = append(collection.Warn, architectureCollection.Warn...)
+		collection.Info = append(collection.Info, architectureCollection.Info...)
+	}
+
+	if remote.EnableSpdk {
+		if err := remote.checkKubeletHugePages(nodeCollections); err != nil {
+			return nil, err
+		}
+	}
+
+	return nodeCollections, nil
 }
 
 // createRbacForNodeAgent creates the RBAC for checking if node agent exists when the cluster is running on Container-Optimized OS (COS).
@@ -121,18 +389,21 @@ func (remote *Checker) Run() (string, error) {
 func (remote *Checker) createRbacForNodeAgent() error {
 	// Create the RBAC for checking if node agent exists when the cluster is running on Container-Optimized OS.
 	newServiceAccount := remote.newServiceAccount()
+	kubeutils.SetRunMetadata(remote.kubeClient, newServiceAccount)
 	_, err := commonkube.CreateServiceAccount(remote.kubeClient, newServiceAccount)
 	if err != nil {
 		return err
 	}
 
 	newClusterRole := remote.newClusterRole()
+	kubeutils.SetRunMetadata(remote.kubeClient, newClusterRole)
 	_, err = commonkube.CreateClusterRole(remote.kubeClient, newClusterRole)
 	if err != nil {
 		return err
 	}
 
 	newClusterRoleBinding := remote.newClusterRoleBinding()
+	kubeutils.SetRunMetadata(remote.kubeClient, newClusterRoleBinding)
 	_, err = commonkube.CreateClusterRoleBinding(remote.kubeClient, newClusterRoleBinding)
 	if err != nil {
 		return err
@@ -141,12 +412,37 @@ func (remote *Checker) createRbacForNodeAgent() error {
 	return nil
 }
 
+// GetNodeGroups returns the group of each node in the category of --summarize-by, keyed by the
+// node name.
+func (remote *Checker) GetNodeGroups() (map[string]string, error) {
+	nodeList, err := remote.kubeClient.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list nodes")
+	}
+
+	nodeGroups := map[string]string{}
+	for i := range nodeList.Items {
+		nodeGroups[nodeList.Items[i].Name] = kubeutils.GetNodeGroup(&nodeList.Items[i], remote.SummarizeBy)
+	}
+	return nodeGroups, nil
+}
+
 // Cleanup deletes the DaemonSet created for the preflight check.
 func (remote *Checker) Cleanup() error {
+	if remote.sshRunner != nil {
+		return nil
+	}
+
 	if err := commonkube.DeleteDaemonSet(remote.kubeClient, remote.namespace, remote.appName); err != nil {
 		return err
 	}
 
+	if remote.customChecksData != "" {
+		if err := commonkube.DeleteConfigMap(remote.kubeClient, remote.namespace, remote.customChecksConfigMap); err != nil {
+			return err
+		}
+	}
+
 	if err := commonkube.DeleteClusterRoleBinding(remote.kubeClient, remote.appName); err != nil {
 		return err
 	}
@@ -162,6 +458,10 @@ func (remote *Checker) newClusterRole() *rbacv1.ClusterRole {
 	return &rbacv1.ClusterRole{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: remote.appName,
+			Labels: map[string]string{
+				"app":                 remote.appName,
+				consts.LabelManagedBy: consts.LabelValueManagedBy,
+			},
 		},
 		Rules: []rbacv1.PolicyRule{
 			{
@@ -177,6 +477,10 @@ func (remote *Checker) newClusterRoleBinding() *rbacv1.ClusterRoleBinding {
 	return &rbacv1.ClusterRoleBinding{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: remote.appName,
+			Labels: map[string]string{
+				"app":                 remote.appName,
+				consts.LabelManagedBy: consts.LabelValueManagedBy,
+			},
 		},
 		RoleRef: rbacv1.RoleRef{
 			APIGroup: rbacv1.GroupName,
@@ -187,7 +491,7 @@ func (remote *Checker) newClusterRoleBinding() *rbacv1.ClusterRoleBinding {
 			{
 				Kind:      "ServiceAccount",
 				Name:      remote.appName,
-				Namespace: metav1.NamespaceDefault,
+				Namespace: remote.namespace,
 			},
 		},
 	}
@@ -197,7 +501,27 @@ func (remote *Checker) newServiceAccount() *corev1.ServiceAccount {
 	return &corev1.ServiceAccount{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      remote.appName,
-			Namespace: metav1.NamespaceDefault,
+			Namespace: remote.namespace,
+			Labels: map[string]string{
+				"app":                 remote.appName,
+				consts.LabelManagedBy: consts.LabelValueManagedBy,
+			},
+		},
+	}
+}
+
+func (remote *Checker) newCustomChecksConfigMap() *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      remote.customChecksConfigMap,
+			Namespace: remote.namespace,
+			Labels: map[string]string{
+				"app":                 remote.appName,
+				consts.LabelManagedBy: consts.LabelValueManagedBy,
+			},
+		},
+		Data: map[string]string{
+			consts.FileNameCustomChecks: remote.customChecksData,
 		},
 	}
 }
@@ -205,12 +529,13 @@ func (remote *Checker) newServiceAccount() *corev1.ServiceAccount {
 // NewDaemonSet prepares a DaemonSet for the preflight check.
 func (remote *Checker) newDaemonSet(nodeSelector map[string]string) *appsv1.DaemonSet {
 	outputFilePath := filepath.Join(consts.VolumeMountSharedDirectory, consts.FileNameOutputJSON)
-	return &appsv1.DaemonSet{
+	daemonSet := &appsv1.DaemonSet{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      remote.appName,
-			Namespace: metav1.NamespaceDefault,
+			Namespace: remote.namespace,
 			Labels: map[string]string{
-				"app": remote.appName,
+				"app":                 remote.appName,
+				consts.LabelManagedBy: consts.LabelValueManagedBy,
 			},
 		},
 		Spec: appsv1.DaemonSetSpec{
@@ -222,18 +547,27 @@ func (remote *Checker) newDaemonSet(nodeSelector map[string]string) *appsv1.Daem
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
 					Labels: map[string]string{
-						"app": remote.appName,
+						"app":                 remote.appName,
+						consts.LabelManagedBy: consts.LabelValueManagedBy,
 					},
 				},
 				Spec: corev1.PodSpec{
 					ServiceAccountName: remote.appName,
 					HostPID:            true,
-					InitContainers: []corev1.Container{
+					Containers: []corev1.Container{
 						{
-							Name:    consts.ContainerNameInit,
+							Name:    consts.ContainerNameAgent,
 							Image:   remote.Image,
-							Command: []string{consts.CmdLonghornctlLocal, consts.SubCmdCheck, consts.SubCmdPreflight},
+							Command: kubeutils.NewAgentCommand(consts.CmdLonghornctlLocal, consts.SubCmdCheck, consts.SubCmdPreflight),
 							Env: []corev1.EnvVar{
+								{
+									Name: consts.EnvNamespace,
+									ValueFrom: &corev1.EnvVarSource{
+										FieldRef: &corev1.ObjectFieldSelector{
+											FieldPath: "metadata.namespace",
+										},
+									},
+								},
 								{
 									Name:  consts.EnvLogLevel,
 									Value: remote.LogLevel,
@@ -250,14 +584,36 @@ func (remote *Checker) newDaemonSet(nodeSelector map[string]string) *appsv1.Daem
 									Name:  consts.EnvHugePageSize,
 									Value: commonutils.ConvertTypeToString(remote.HugePageSize),
 								},
+								{
+									Name:  consts.EnvHugePageNodes,
+									Value: remote.HugePageNodes,
+								},
 								{
 									Name:  consts.EnvUserspaceDriver,
 									Value: remote.UserspaceDriver,
 								},
+								{
+									Name:  consts.EnvPreflightProfile,
+									Value: remote.Profile,
+								},
+								{
+									Name:  consts.EnvLonghornDataDirectory,
+									Value: strings.Join(remote.LonghornDataDirectories, consts.CmdOptSeperator),
+								},
+								{
+									Name:  consts.EnvRegistryCheckImages,
+									Value: remote.RegistryCheckImages,
+								},
+								{
+									Name:  consts.EnvCryptoBenchmark,
+									Value: commonutils.ConvertTypeToString(remote.CryptoBenchmark),
+								},
+								{
+									Name:  consts.EnvKnownIssues,
+									Value: remote.KnownIssues,
+								},
 							},
-							SecurityContext: &corev1.SecurityContext{
-								Privileged: ptr.To(true),
-							},
+							SecurityContext: kubeutils.NewSecurityContext(remote.Privileged, kubeutils.CapabilitiesHostNamespaces),
 							VolumeMounts: []corev1.VolumeMount{
 								{
 									Name:      consts.VolumeMountHostName,
@@ -270,24 +626,6 @@ func (remote *Checker) newDaemonSet(nodeSelector map[string]string) *appsv1.Daem
 								},
 							},
 						},
-						{
-							Name:    consts.ContainerNameOutput,
-							Image:   remote.Image,
-							Command: []string{"cat", outputFilePath},
-							Env:     []corev1.EnvVar{},
-							VolumeMounts: []corev1.VolumeMount{
-								{
-									Name:      consts.VolumeMountSharedName,
-									MountPath: consts.VolumeMountSharedDirectory,
-								},
-							},
-						},
-					},
-					Containers: []corev1.Container{
-						{
-							Name:  consts.ContainerNamePause,
-							Image: consts.ImagePause,
-						},
 					},
 					Volumes: []corev1.Volume{
 						{
@@ -313,4 +651,36 @@ func (remote *Checker) newDaemonSet(nodeSelector map[string]string) *appsv1.Daem
 			},
 		},
 	}
+
+	// Check the registries from the network of the node, as containerd pulls the images.
+	if remote.RegistryCheckImages != "" {
+		daemonSet.Spec.Template.Spec.HostNetwork = true
+	}
+
+	if remote.customChecksConfigMap != "" {
+		podSpec := &daemonSet.Spec.Template.Spec
+		podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+			Name: consts.VolumeMountCustomChecksName,
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: remote.customChecksConfigMap,
+					},
+				},
+			},
+		})
+
+		agentContainer := &podSpec.Containers[0]
+		agentContainer.Env = append(agentContainer.Env, corev1.EnvVar{
+			Name:  consts.EnvCustomChecks,
+			Value: filepath.Join(consts.VolumeMountCustomChecksDirectory, consts.FileNameCustomChecks),
+		})
+		agentContainer.VolumeMounts = append(agentContainer.VolumeMounts, corev1.VolumeMount{
+			Name:      consts.VolumeMountCustomChecksName,
+			MountPath: consts.VolumeMountCustomChecksDirectory,
+			ReadOnly:  true,
+		})
+	}
+
+	return daemonSet
 }
@@ -0,0 +1,67 @@
+package preflight
+
+import (
+	"testing"
+)
+
+func TestValidateDataDirectories(t *testing.T) {
+	for _, test := range []struct {
+		dataDirectories []string
+		valid           bool
+	}{
+		{valid: true},
+		{dataDirectories: []string{"/var/lib/longhorn"}, valid: true},
+		{dataDirectories: []string{"/var/lib/longhorn", "/mnt/disk1/longhorn"}, valid: true},
+		{dataDirectories: []string{"var/lib/longhorn"}},
+		{dataDirectories: []string{"/var/lib/longhorn", "/var/lib/longhorn/"}},
+	} {
+		err := ValidateDataDirectories(test.dataDirectories)
+		if test.valid && err != nil {
+			t.Errorf("expected data directories %v to be valid, got %v", test.dataDirectories, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("expected data directories %v to be invalid", test.dataDirectories)
+		}
+	}
+}
+
+func TestParseCustomChecks(t *testing.T) {
+	for name, test := range map[string]struct {
+		data   string
+		checks int
+		valid  bool
+	}{
+		"no check": {data: "checks: []", valid: true},
+		"checks": {
+			data: `checks:
+- name: ntp
+  command: timedatectl show -p NTPSynchronized --value
+  expectedOutput: ^yes$
+  severity: warn
+- name: swap
+  command: test -z "$(swapon --show)"`,
+			checks: 2,
+			valid:  true,
+		},
+		"no name":          {data: "checks:\n- command: true"},
+		"no command":       {data: "checks:\n- name: ntp"},
+		"invalid regexp":   {data: "checks:\n- name: ntp\n  command: true\n  expectedOutput: '('"},
+		"invalid severity": {data: "checks:\n- name: ntp\n  command: true\n  severity: fatal"},
+		"unknown field":    {data: "checks:\n- name: ntp\n  command: true\n  timeout: 10s"},
+	} {
+		checkList, err := ParseCustomChecks([]byte(test.data))
+		if !test.valid {
+			if err == nil {
+				t.Errorf("%v: expected an error", name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", name, err)
+			continue
+		}
+		if len(checkList.Checks) != test.checks {
+			t.Errorf("%v: expected %d checks, got %d", name, test.checks, len(checkList.Checks))
+		}
+	}
+}
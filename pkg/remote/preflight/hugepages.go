@@ -0,0 +1,121 @@
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+)
+
+// ResourceHugePages2Mi is the node resource kubelet registers for the 2MiB huge pages used by SPDK.
+const ResourceHugePages2Mi = corev1.ResourceName(corev1.ResourceHugePagesPrefix + "2Mi")
+
+// ParseHugePageNodes parses the comma-separated huge page sizes in MiB per NUMA node,
+// for example "0=1024,1=1024", into a map keyed by the NUMA node ID.
+func ParseHugePageNodes(hugePageNodes string) (map[int]int, error) {
+	sizes := map[int]int{}
+	if strings.TrimSpace(hugePageNodes) == "" {
+		return sizes, nil
+	}
+
+	for _, entry := range strings.Split(hugePageNodes, consts.CmdOptSeperator) {
+		numaNode, size, found := strings.Cut(strings.TrimSpace(entry), "=")
+		if !found {
+			return nil, errors.Errorf("invalid huge page size %q (--%s), expected <NUMA node>=<MiB>", entry, consts.CmdOptHugePageNodes)
+		}
+
+		numaNodeID, err := strconv.Atoi(strings.TrimSpace(numaNode))
+		if err != nil || numaNodeID < 0 {
+			return nil, errors.Errorf("invalid NUMA node %q (--%s)", numaNode, consts.CmdOptHugePageNodes)
+		}
+
+		sizeMiB, err := strconv.Atoi(strings.TrimSpace(size))
+		if err != nil || sizeMiB <= 0 || sizeMiB%2 != 0 {
+			return nil, errors.Errorf("invalid huge page size %q of NUMA node %d (--%s), expected a positive multiple of 2 MiB", size, numaNodeID, consts.CmdOptHugePageNodes)
+		}
+
+		if _, exists := sizes[numaNodeID]; exists {
+			return nil, errors.Errorf("duplicate NUMA node %d (--%s)", numaNodeID, consts.CmdOptHugePageNodes)
+		}
+		sizes[numaNodeID] = sizeMiB
+	}
+
+	return sizes, nil
+}
+
+// SortedNumaNodes returns the NUMA node IDs of the huge page sizes in ascending order.
+func SortedNumaNodes(sizes map[int]int) []int {
+	numaNodes := make([]int, 0, len(sizes))
+	for numaNode := range sizes {
+		numaNodes = append(numaNodes, numaNode)
+	}
+	sort.Ints(numaNodes)
+	return numaNodes
+}
+
+// GetRequiredHugePageSize returns the total huge page size in MiB required on a node,
+// which is the sum of the per-NUMA-node sizes when they are specified.
+func GetRequiredHugePageSize(hugePageSize int, hugePageNodes map[int]int) int {
+	if len(hugePageNodes) == 0 {
+		return hugePageSize
+	}
+
+	total := 0
+	for _, size := range hugePageNodes {
+		total += size
+	}
+	return total
+}
+
+// checkKubeletHugePages checks kubelet has registered the allocated huge pages as an allocatable
+// resource of each node. Kubelet only discovers the huge pages at startup, so it must be
+// restarted after they are allocated for the SPDK pods to be scheduled.
+func (remote *Checker) checkKubeletHugePages(nodeCollections map[string]*types.LogCollection) error {
+	hugePageNodes, err := ParseHugePageNodes(remote.HugePageNodes)
+	if err != nil {
+		return err
+	}
+	requiredSize := GetRequiredHugePageSize(remote.HugePageSize, hugePageNodes)
+
+	for nodeName, collection := range nodeCollections {
+		if collection == nil {
+			continue
+		}
+
+		node, err := remote.kubeClient.CoreV1().Nodes().Get(context.Background(), nodeName, metav1.GetOptions{})
+		if err != nil {
+			collection.Error = append(collection.Error, fmt.Sprintf("Failed to get node %v to check the %v resource: %v", nodeName, ResourceHugePages2Mi, err))
+			continue
+		}
+
+		inspectNodeHugePages(collection, node, requiredSize)
+	}
+
+	return nil
+}
+
+// inspectNodeHugePages compares the allocatable 2MiB huge pages of the node with the required size in MiB.
+func inspectNodeHugePages(collection *types.LogCollection, node *corev1.Node, requiredSize int) {
+	allocatable, ok := node.Status.Allocatable[ResourceHugePages2Mi]
+	if !ok || allocatable.IsZero() {
+		collection.Error = append(collection.Error, fmt.Sprintf("Kubelet has not registered the %v resource, restart kubelet after allocating the huge pages", ResourceHugePages2Mi))
+		return
+	}
+
+	allocatableSize := int(allocatable.Value() >> 20)
+	if allocatableSize < requiredSize {
+		collection.Error = append(collection.Error, fmt.Sprintf("Kubelet has registered %v MiB of %v, required %v MiB. Restart kubelet if the huge pages were allocated after it started", allocatableSize, ResourceHugePages2Mi, requiredSize))
+		return
+	}
+
+	collection.Info = append(collection.Info, fmt.Sprintf("Kubelet has registered %v MiB of %v", allocatableSize, ResourceHugePages2Mi))
+}
@@ -0,0 +1,77 @@
+package preflight
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func TestParseHugePageNodes(t *testing.T) {
+	tests := map[string]map[int]int{
+		"":                 {},
+		"0=1024":           {0: 1024},
+		"0=1024, 1 = 2048": {0: 1024, 1: 2048},
+	}
+
+	for hugePageNodes, expected := range tests {
+		sizes, err := ParseHugePageNodes(hugePageNodes)
+		if err != nil {
+			t.Errorf("ParseHugePageNodes(%q) unexpected error: %v", hugePageNodes, err)
+			continue
+		}
+		if !reflect.DeepEqual(sizes, expected) {
+			t.Errorf("ParseHugePageNodes(%q) = %v, expected %v", hugePageNodes, sizes, expected)
+		}
+	}
+
+	for _, hugePageNodes := range []string{"1024", "a=1024", "-1=1024", "0=0", "0=1023", "0=1024,0=2048"} {
+		if _, err := ParseHugePageNodes(hugePageNodes); err == nil {
+			t.Errorf("ParseHugePageNodes(%q) expected an error", hugePageNodes)
+		}
+	}
+}
+
+func TestGetRequiredHugePageSize(t *testing.T) {
+	if size := GetRequiredHugePageSize(2048, nil); size != 2048 {
+		t.Errorf("expected 2048 without per-NUMA-node sizes, got %v", size)
+	}
+
+	if size := GetRequiredHugePageSize(2048, map[int]int{0: 512, 1: 1024}); size != 1536 {
+		t.Errorf("expected the sum of the per-NUMA-node sizes, got %v", size)
+	}
+}
+
+func TestInspectNodeHugePages(t *testing.T) {
+	newNode := func(allocatable string) *corev1.Node {
+		node := &corev1.Node{}
+		if allocatable != "" {
+			node.Status.Allocatable = corev1.ResourceList{
+				ResourceHugePages2Mi: resource.MustParse(allocatable),
+			}
+		}
+		return node
+	}
+
+	tests := map[string]struct {
+		node          *corev1.Node
+		expectedError bool
+	}{
+		"not registered": {node: newNode(""), expectedError: true},
+		"zero":           {node: newNode("0"), expectedError: true},
+		"insufficient":   {node: newNode("1Gi"), expectedError: true},
+		"sufficient":     {node: newNode("2Gi"), expectedError: false},
+	}
+
+	for name, test := range tests {
+		collection := &types.LogCollection{}
+		inspectNodeHugePages(collection, test.node, 2048)
+
+		if hasError := len(collection.Error) > 0; hasError != test.expectedError {
+			t.Errorf("%s: expected error %v, got %v", name, test.expectedError, collection)
+		}
+	}
+}
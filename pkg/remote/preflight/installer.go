@@ -2,8 +2,10 @@ package preflight
 
 import (
 	"encoding/json"
+	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -15,11 +17,13 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	kubeclient "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 
 	commonkube "github.com/longhorn/go-common-libs/kubernetes"
 	commonutils "github.com/longhorn/go-common-libs/utils"
 
 	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/remote/ssh"
 	"github.com/longhorn/cli/pkg/types"
 
 	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
@@ -30,31 +34,126 @@ type Installer struct {
 	InstallerCmdOptions
 
 	kubeClient *kubeclient.Clientset
+	restConfig *rest.Config
+	sshRunner  *ssh.Runner // Runner of the SSH backend. Nil with the DaemonSet backend.
 
-	appName string // App name of the DaemonSet.
+	appName   string // App name of the DaemonSet.
+	namespace string
+
+	installProfiles *types.PreflightInstallProfiles // Options of the groups of nodes. Nil when not given.
 }
 
 // InstallerCmdOptions holds the options for the command.
 type InstallerCmdOptions struct {
 	types.GlobalCmdOptions
+	types.SSHCmdOptions
 
 	OperatingSystem string
 
-	UpdatePackages bool
-	EnableSpdk     bool
-	SpdkOptions    string
-	HugePageSize   int
-	AllowPci       string
-	DriverOverride string
+	UpdatePackages  bool
+	PackageSource   string // Directory on the nodes holding the package files to install from, instead of the package repositories.
+	ResetCheckpoint bool   // Ignore the checkpoints of the previous installs on the nodes, and run all the steps again.
+	TuneIscsid      bool   // Apply the recommended iscsid configuration.
+	EnableSpdk      bool
+	SpdkOptions     string
+	HugePageSize    int
+	HugePageNodes   string // Comma-separated huge page sizes in MiB per NUMA node, for example "0=1024,1=1024".
+	AllowPci        string
+	DriverOverride  string
+
+	LonghornDataDirectories []string // Data paths of the Longhorn disks, mounted with exec on Container-Optimized OS.
+
+	MaxParallel int // Maximum number of nodes to install on at the same time. Installs on all nodes at once when not positive.
+
+	ProfilesFile string // Path to a YAML file mapping node selectors to the options of the install.
+
+	RebootStrategy string // Strategy rebooting the nodes needing a reboot after the install (none, rolling).
+	MaxUnavailable int    // Maximum number of nodes rebooted at the same time with the rolling strategy.
+	ForceDrain     bool   // Evict the pods not managed by a controller when draining the nodes to reboot.
 }
 
 // Init initializes the Installer.
 func (remote *Installer) Init() error {
-	kubeClient, err := kubeutils.NewKubeClient("", remote.KubeConfigPath)
+	if err := validateBackend(&remote.SSHCmdOptions); err != nil {
+		return err
+	}
+
+	sshRunner, err := newSSHRunner(&remote.SSHCmdOptions, remote.MaxParallel)
 	if err != nil {
 		return err
 	}
-	remote.kubeClient = kubeClient
+	remote.sshRunner = sshRunner
+
+	if remote.sshRunner != nil && consts.OperatingSystem(remote.OperatingSystem) == consts.OperatingSystemContainerOptimizedOS {
+		return errors.Errorf("--%s=%s is not supported with the %s backend", consts.CmdOptOperatingSystem, remote.OperatingSystem, consts.BackendSSH)
+	}
+
+	if remote.PackageSource != "" {
+		if !filepath.IsAbs(remote.PackageSource) {
+			return errors.Errorf("--%s must be an absolute path on the nodes, got %q", consts.CmdOptPackageSource, remote.PackageSource)
+		}
+		if consts.OperatingSystem(remote.OperatingSystem) == consts.OperatingSystemContainerOptimizedOS {
+			return errors.Errorf("--%s is not supported with --%s=%s", consts.CmdOptPackageSource, consts.CmdOptOperatingSystem, remote.OperatingSystem)
+		}
+	}
+
+	if remote.ProfilesFile != "" {
+		if remote.sshRunner != nil {
+			return errors.Errorf("--%s is not supported with the %s backend", consts.CmdOptProfiles, consts.BackendSSH)
+		}
+		if consts.OperatingSystem(remote.OperatingSystem) == consts.OperatingSystemContainerOptimizedOS {
+			return errors.Errorf("--%s is not supported with --%s=%s", consts.CmdOptProfiles, consts.CmdOptOperatingSystem, remote.OperatingSystem)
+		}
+
+		data, err := os.ReadFile(remote.ProfilesFile)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read install profiles file %v", remote.ProfilesFile)
+		}
+
+		remote.installProfiles, err = ParseInstallProfiles(data)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := ValidateRebootStrategy(remote.RebootStrategy, remote.MaxUnavailable); err != nil {
+		return err
+	}
+	if remote.RebootStrategy == consts.RebootStrategyRolling {
+		if remote.sshRunner != nil {
+			return errors.Errorf("--%s=%s is not supported with the %s backend", consts.CmdOptRebootStrategy, remote.RebootStrategy, consts.BackendSSH)
+		}
+		if consts.OperatingSystem(remote.OperatingSystem) == consts.OperatingSystemContainerOptimizedOS {
+			return errors.Errorf("--%s=%s is not supported with --%s=%s", consts.CmdOptRebootStrategy, remote.RebootStrategy, consts.CmdOptOperatingSystem, remote.OperatingSystem)
+		}
+	}
+
+	if remote.sshRunner == nil {
+		kubeClient, err := kubeutils.NewKubeClient("", remote.KubeConfigPath)
+		if err != nil {
+			return err
+		}
+		remote.kubeClient = kubeClient
+
+		restConfig, err := kubeutils.NewRestConfig("", remote.KubeConfigPath)
+		if err != nil {
+			return err
+		}
+		remote.restConfig = restConfig
+	}
+
+	if _, err := ParseHugePageNodes(remote.HugePageNodes); err != nil {
+		return err
+	}
+
+	if err := ValidateDataDirectories(remote.LonghornDataDirectories); err != nil {
+		return err
+	}
+
+	remote.namespace = remote.Namespace
+	if remote.namespace == "" {
+		remote.namespace = consts.LonghornNamespace
+	}
 
 	operatingSystem := consts.OperatingSystem(remote.OperatingSystem)
 	switch operatingSystem {
@@ -67,44 +166,85 @@ func (remote *Installer) Init() error {
 	return nil
 }
 
-// Run creates the DaemonSet for the preflight install.
-// It checks if the operating system is specified, and installs the dependencies accordingly.
-// If the operating system is not specified, it installs the dependencies with package manager.
+// Run creates the DaemonSet for the preflight install, and returns the per-node
+// result as a YAML string.
 func (remote *Installer) Run() (string, error) {
+	nodeCollections, err := remote.Collect()
+	if err != nil {
+		return "", err
+	}
+
+	if len(nodeCollections) == 0 {
+		return "", nil
+	}
+
+	yamlData, err := yaml.Marshal(nodeCollections)
+	if err != nil {
+		return "", err
+	}
+
+	return string(yamlData), nil
+}
+
+// Collect creates the DaemonSet for the preflight install.
+// It checks if the operating system is specified, and installs the dependencies accordingly.
+// If the operating system is not specified, it installs the dependencies with package manager,
+// and returns the install result of each node keyed by the node name.
+// With the SSH backend, it installs the dependencies on the SSH hosts instead, keyed by the host name.
+func (remote *Installer) Collect() (map[string]*types.LogCollection, error) {
+	if remote.sshRunner != nil {
+		logrus.Info("Installing dependencies over SSH")
+		return remote.installOverSSH()
+	}
+
+	if _, err := kubeutils.CreateNamespace(remote.kubeClient, remote.namespace); err != nil {
+		return nil, err
+	}
+
 	operatingSystem := consts.OperatingSystem(remote.OperatingSystem)
 	switch operatingSystem {
 	case consts.OperatingSystemContainerOptimizedOS:
 		logrus.Infof("Installing dependencies on Container Optimized OS (%v)", operatingSystem)
 
 		if err := remote.InstallByContainerOptimizedOS(); err != nil {
-			return "", errors.Wrapf(err, "failed to install dependencies on Container Optimized OS (%v)", operatingSystem)
+			return nil, errors.Wrapf(err, "failed to install dependencies on Container Optimized OS (%v)", operatingSystem)
 		}
 
 		logrus.Infof("Installed dependencies on Container Optimized OS (%v)", operatingSystem)
-		return "", nil
+		return nil, nil
 
 	default:
 		logrus.Info("Installing dependencies with package manager")
 
-		output, err := remote.InstallByPackageManager()
+		nodeCollections, err := remote.InstallByPackageManager()
 		if err != nil {
-			return "", errors.Wrapf(err, "failed to install dependencies with package manager")
+			return nil, errors.Wrapf(err, "failed to install dependencies with package manager")
 		}
 
 		logrus.Info("Installed dependencies with package manager")
-		return output, nil
+		return nodeCollections, nil
 	}
 }
 
-// Cleanup deletes the DaemonSet created for the preflight install when it's installed with package manager.
+// Cleanup deletes the DaemonSet created for the preflight install when it's installed with package manager,
+// and the pods left by an interrupted reboot.
 func (remote *Installer) Cleanup() error {
-	return commonkube.DeleteDaemonSet(remote.kubeClient, metav1.NamespaceDefault, remote.appName)
+	if remote.sshRunner != nil {
+		return nil
+	}
+
+	if err := remote.deleteRebootPods(); err != nil {
+		return err
+	}
+
+	return commonkube.DeleteDaemonSet(remote.kubeClient, remote.namespace, remote.appName)
 }
 
 // InstallByContainerOptimizedOS installs the dependencies on Container Optimized OS.
 // It creates a ConfigMap and a DaemonSet. Then it waits for the DaemonSet to be ready.
 func (remote *Installer) InstallByContainerOptimizedOS() error {
 	newConfigMap := remote.newConfigMapForContainerOptimizedOS()
+	kubeutils.SetRunMetadata(remote.kubeClient, newConfigMap)
 	_, err := commonkube.CreateConfigMap(remote.kubeClient, newConfigMap)
 	if err != nil {
 		return err
@@ -115,7 +255,12 @@ func (remote *Installer) InstallByContainerOptimizedOS() error {
 		return errors.Wrapf(err, "failed to parse %q argument", consts.CmdOptNodeSelector)
 	}
 	newDaemonSet := remote.newDaemonSetForContainerOptimizedOS(nodeSelector)
-	daemonSet, err := commonkube.CreateDaemonSet(remote.kubeClient, newDaemonSet)
+	if err := kubeutils.SetPodOptions(&newDaemonSet.Spec.Template.Spec, &remote.GlobalCmdOptions); err != nil {
+		return err
+	}
+	kubeutils.SetRunMetadata(remote.kubeClient, newDaemonSet)
+	kubeutils.LogManifest(newDaemonSet)
+	daemonSet, err := kubeutils.CreateDaemonSet(remote.kubeClient, newDaemonSet, remote.MinNodes)
 	if err != nil {
 		return err
 	}
@@ -124,67 +269,43 @@ func (remote *Installer) InstallByContainerOptimizedOS() error {
 }
 
 // InstallByPackageManager installs the dependencies with package manager.
-// It creates a DaemonSet. Then it waits for the DaemonSet to complete and return the logs of output container within the DaemonSet, for example:
-// ip-192-168-208-117:
-//
-//	info:
-//	- Successfully installed package nfs-client
-//	- Successfully installed package open-iscsi
-//	- Successfully installed package cryptsetup
-//	- Successfully probed module nfs
-//	- Successfully probed module iscsi_tcp
-//	- Successfully probed module dm_crypt
-//	- Successfully started service iscsid
-func (remote *Installer) InstallByPackageManager() (string, error) {
+// It creates a DaemonSet, in batches of nodes when MaxParallel is set. Then it waits for the DaemonSet
+// to complete and returns the result reported by the node agent of each node.
+// With the rolling reboot strategy, the nodes needing a reboot are rebooted and the install resumed on them.
+// With the install profiles, each group of nodes is installed in turn with the options of its profile.
+func (remote *Installer) InstallByPackageManager() (map[string]*types.LogCollection, error) {
 	nodeSelector, err := kubeutils.ParseNodeSelector(remote.NodeSelector)
 	if err != nil {
-		return "", errors.Wrapf(err, "failed to parse %q argument", consts.CmdOptNodeSelector)
+		return nil, errors.Wrapf(err, "failed to parse %q argument", consts.CmdOptNodeSelector)
 	}
-	newDaemonSet := remote.NewDaemonSetForPackageManager(nodeSelector)
-	daemonSet, err := commonkube.CreateDaemonSet(remote.kubeClient, newDaemonSet)
-	if err != nil {
-		return "", err
-	}
-
-	err = kubeutils.MonitorDaemonSetContainer(remote.kubeClient, daemonSet, consts.ContainerNameInit, kubeutils.WaitForDaemonSetContainersExit, ptr.To(consts.ContainerConditionMaxTolerationLong))
-	if err != nil {
-		return "", err
+	if remote.installProfiles != nil {
+		return remote.installByProfiles(nodeSelector)
 	}
 
-	err = kubeutils.MonitorDaemonSetContainer(remote.kubeClient, daemonSet, consts.ContainerNameOutput, kubeutils.WaitForDaemonSetContainersExit, ptr.To(consts.ContainerConditionMaxTolerationShort))
-	if err != nil {
-		return "", err
+	newDaemonSet := remote.NewDaemonSetForPackageManager(nodeSelector)
+	if err := kubeutils.SetPodOptions(&newDaemonSet.Spec.Template.Spec, &remote.GlobalCmdOptions); err != nil {
+		return nil, err
 	}
 
-	podCollections, err := kubeutils.GetDaemonSetPodCollections(remote.kubeClient, daemonSet, consts.ContainerNameOutput, false, false, nil)
+	nodeCollections := map[string]*types.LogCollection{}
+	err = kubeutils.RunDaemonSetInBatches(remote.kubeClient, newDaemonSet, remote.MinNodes, remote.MaxParallel, func(daemonSet *appsv1.DaemonSet) error {
+		return collectNodeCollections(remote.restConfig, remote.kubeClient, daemonSet, nodeCollections)
+	})
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	nodeCollections := map[string]*types.LogCollection{}
-	for _, collection := range podCollections.Pods {
-		var resultMap types.NodeCollection
-		if err := json.Unmarshal([]byte(collection.Log), &resultMap); err != nil {
-			return "", err
+	if remote.RebootStrategy == consts.RebootStrategyRolling {
+		if err := remote.rebootAndResume(newDaemonSet, nodeCollections); err != nil {
+			return nil, err
 		}
-
-		if reflect.DeepEqual(resultMap, types.NodeCollection{}) {
-			continue
-		}
-
-		nodeCollections[collection.Node] = resultMap.Log
 	}
 
-	if reflect.DeepEqual(nodeCollections, map[string]types.LogCollection{}) {
-		return "", nil
+	if err := kubeutils.AddSkippedNodes(remote.kubeClient, newDaemonSet, nodeCollections); err != nil {
+		return nil, err
 	}
 
-	yamlData, err := yaml.Marshal(nodeCollections)
-	if err != nil {
-		return "", err
-	}
-
-	return string(yamlData), nil
+	return nodeCollections, nil
 }
 
 // newConfigMapForContainerOptimizedOS prepares a ConfigMap for installing the dependencies on Container Optimized OS.
@@ -274,9 +395,10 @@ sleep infinity
 	return &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      remote.appName,
-			Namespace: metav1.NamespaceDefault,
+			Namespace: remote.namespace,
 			Labels: map[string]string{
-				"app": remote.appName,
+				"app":                 remote.appName,
+				consts.LabelManagedBy: consts.LabelValueManagedBy,
 			},
 		},
 		Data: map[string]string{
@@ -290,9 +412,10 @@ func (remote *Installer) newDaemonSetForContainerOptimizedOS(nodeSelector map[st
 	return &appsv1.DaemonSet{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      remote.appName,
-			Namespace: metav1.NamespaceDefault,
+			Namespace: remote.namespace,
 			Labels: map[string]string{
-				"app": remote.appName,
+				"app":                 remote.appName,
+				consts.LabelManagedBy: consts.LabelValueManagedBy,
 			},
 		},
 		Spec: appsv1.DaemonSetSpec{
@@ -304,7 +427,8 @@ func (remote *Installer) newDaemonSetForContainerOptimizedOS(nodeSelector map[st
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
 					Labels: map[string]string{
-						"app": remote.appName,
+						"app":                 remote.appName,
+						consts.LabelManagedBy: consts.LabelValueManagedBy,
 					},
 				},
 				Spec: corev1.PodSpec{
@@ -325,7 +449,7 @@ func (remote *Installer) newDaemonSetForContainerOptimizedOS(nodeSelector map[st
 								},
 								{
 									Name:  "LONGHORN_DATA_PATHS",
-									Value: "/var/lib/longhorn",
+									Value: strings.Join(remote.LonghornDataDirectories, consts.CmdOptSeperator),
 								},
 							},
 							SecurityContext: &corev1.SecurityContext{
@@ -418,9 +542,10 @@ func (remote *Installer) NewDaemonSetForPackageManager(nodeSelector map[string]s
 	return &appsv1.DaemonSet{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      remote.appName,
-			Namespace: metav1.NamespaceDefault,
+			Namespace: remote.namespace,
 			Labels: map[string]string{
-				"app": remote.appName,
+				"app":                 remote.appName,
+				consts.LabelManagedBy: consts.LabelValueManagedBy,
 			},
 		},
 		Spec: appsv1.DaemonSetSpec{
@@ -432,7 +557,8 @@ func (remote *Installer) NewDaemonSetForPackageManager(nodeSelector map[string]s
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
 					Labels: map[string]string{
-						"app": remote.appName,
+						"app":                 remote.appName,
+						consts.LabelManagedBy: consts.LabelValueManagedBy,
 					},
 				},
 				Spec: corev1.PodSpec{
@@ -440,11 +566,11 @@ func (remote *Installer) NewDaemonSetForPackageManager(nodeSelector map[string]s
 					HostNetwork: true,
 					HostPID:     true,
 
-					InitContainers: []corev1.Container{
+					Containers: []corev1.Container{
 						{
-							Name:    consts.ContainerNameInit,
+							Name:    consts.ContainerNameAgent,
 							Image:   remote.Image,
-							Command: []string{consts.CmdLonghornctlLocal, consts.SubCmdInstall, consts.SubCmdPreflight},
+							Command: kubeutils.NewAgentCommand(consts.CmdLonghornctlLocal, consts.SubCmdInstall, consts.SubCmdPreflight),
 							Env: []corev1.EnvVar{
 								{
 									Name:  consts.EnvLogLevel,
@@ -458,6 +584,18 @@ func (remote *Installer) NewDaemonSetForPackageManager(nodeSelector map[string]s
 									Name:  consts.EnvUpdatePackageList,
 									Value: commonutils.ConvertTypeToString(remote.UpdatePackages),
 								},
+								{
+									Name:  consts.EnvPackageSource,
+									Value: remote.PackageSource,
+								},
+								{
+									Name:  consts.EnvResetCheckpoint,
+									Value: commonutils.ConvertTypeToString(remote.ResetCheckpoint),
+								},
+								{
+									Name:  consts.EnvTuneIscsid,
+									Value: commonutils.ConvertTypeToString(remote.TuneIscsid),
+								},
 								{
 									Name:  consts.EnvEnableSpdk,
 									Value: commonutils.ConvertTypeToString(remote.EnableSpdk),
@@ -470,6 +608,10 @@ func (remote *Installer) NewDaemonSetForPackageManager(nodeSelector map[string]s
 									Name:  consts.EnvHugePageSize,
 									Value: commonutils.ConvertTypeToString(remote.HugePageSize),
 								},
+								{
+									Name:  consts.EnvHugePageNodes,
+									Value: remote.HugePageNodes,
+								},
 								{
 									Name:  consts.EnvPciAllowed,
 									Value: remote.AllowPci,
@@ -479,9 +621,7 @@ func (remote *Installer) NewDaemonSetForPackageManager(nodeSelector map[string]s
 									Value: remote.DriverOverride,
 								},
 							},
-							SecurityContext: &corev1.SecurityContext{
-								Privileged: ptr.To(true),
-							},
+							SecurityContext: kubeutils.NewSecurityContext(remote.Privileged, kubeutils.CapabilitiesHostNamespaces, kubeutils.CapabilitiesKernelModule),
 							VolumeMounts: []corev1.VolumeMount{
 								{
 									Name:      consts.VolumeMountHostName,
@@ -493,24 +633,6 @@ func (remote *Installer) NewDaemonSetForPackageManager(nodeSelector map[string]s
 								},
 							},
 						},
-						{
-							Name:    consts.ContainerNameOutput,
-							Image:   remote.Image,
-							Command: []string{"cat", outputFilePath},
-							Env:     []corev1.EnvVar{},
-							VolumeMounts: []corev1.VolumeMount{
-								{
-									Name:      consts.VolumeMountSharedName,
-									MountPath: consts.VolumeMountSharedDirectory,
-								},
-							},
-						},
-					},
-					Containers: []corev1.Container{
-						{
-							Name:  consts.ContainerNamePause,
-							Image: consts.ImagePause,
-						},
 					},
 					Volumes: []corev1.Volume{
 						{
@@ -537,3 +659,27 @@ func (remote *Installer) NewDaemonSetForPackageManager(nodeSelector map[string]s
 		},
 	}
 }
+
+// collectNodeCollections follows the node agents of the DaemonSet until their commands complete,
+// and adds the result of each node to the node collections.
+func collectNodeCollections(restConfig *rest.Config, kubeClient *kubeclient.Clientset, daemonSet *appsv1.DaemonSet, nodeCollections map[string]*types.LogCollection) error {
+	results, err := kubeutils.CollectDaemonSetAgentResults(restConfig, kubeClient, daemonSet, ptr.To(consts.ContainerConditionMaxTolerationMedium))
+	if err != nil {
+		return err
+	}
+
+	for nodeName, result := range results {
+		var resultMap types.NodeCollection
+		if err := json.Unmarshal(result, &resultMap); err != nil {
+			return errors.Wrapf(err, "failed to parse the result of node %v", nodeName)
+		}
+
+		if reflect.DeepEqual(resultMap, types.NodeCollection{}) {
+			continue
+		}
+
+		nodeCollections[nodeName] = resultMap.Log
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package preflight
+
+import (
+	_ "embed"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/pkg/errors"
+
+	sigsyaml "sigs.k8s.io/yaml"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+)
+
+const (
+	knownIssuesFetchTimeout = 30 * time.Second
+	knownIssuesMaxSize      = 1024 * 1024
+)
+
+// embeddedKnownIssues is the known issues database shipped with longhornctl.
+//
+//go:embed knownissues.yaml
+var embeddedKnownIssues []byte
+
+// GetEmbeddedKnownIssues returns the known issues shipped with longhornctl.
+func GetEmbeddedKnownIssues() (*types.KnownIssueList, error) {
+	return ParseKnownIssues(embeddedKnownIssues)
+}
+
+// ParseKnownIssues parses and validates the known issues YAML.
+func ParseKnownIssues(data []byte) (*types.KnownIssueList, error) {
+	issueList := &types.KnownIssueList{}
+	if err := sigsyaml.UnmarshalStrict(data, issueList); err != nil {
+		return nil, errors.Wrap(err, "failed to parse known issues")
+	}
+
+	ids := map[string]bool{}
+	for i, issue := range issueList.Issues {
+		if issue.ID == "" {
+			return nil, errors.Errorf("known issue #%d has no id", i)
+		}
+		if ids[issue.ID] {
+			return nil, errors.Errorf("known issue %v is defined more than once", issue.ID)
+		}
+		ids[issue.ID] = true
+
+		if issue.Description == "" {
+			return nil, errors.Errorf("known issue %v has no description", issue.ID)
+		}
+
+		switch issue.Severity {
+		case "", types.CustomCheckSeverityError, types.CustomCheckSeverityWarn, types.CustomCheckSeverityInfo:
+		default:
+			return nil, errors.Errorf("known issue %v has an invalid severity %q", issue.ID, issue.Severity)
+		}
+
+		empty := true
+		for name, pattern := range GetKnownIssueMatchPatterns(&issue.Match) {
+			if pattern == "" {
+				continue
+			}
+			empty = false
+			if _, err := regexp.Compile(pattern); err != nil {
+				return nil, errors.Wrapf(err, "known issue %v has an invalid %v", issue.ID, name)
+			}
+		}
+		if empty {
+			return nil, errors.Errorf("known issue %v matches no fact", issue.ID)
+		}
+	}
+
+	return issueList, nil
+}
+
+// GetKnownIssueMatchPatterns returns the regular expressions of the match, keyed by their field.
+func GetKnownIssueMatchPatterns(match *types.KnownIssueMatch) map[string]string {
+	return map[string]string{
+		"kernel":          match.Kernel,
+		"operatingSystem": match.OperatingSystem,
+		"module":          match.Module,
+		"moduleVersion":   match.ModuleVersion,
+		"nvmeModel":       match.NVMeModel,
+		"nvmeFirmware":    match.NVMeFirmware,
+	}
+}
+
+// FetchKnownIssues downloads and validates the known issues from the HTTP or HTTPS URL.
+func FetchKnownIssues(httpClient *http.Client, rulesURL string) (*types.KnownIssueList, error) {
+	parsedURL, err := url.Parse(rulesURL)
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") {
+		return nil, errors.Errorf("invalid --%s %q, it must be an HTTP or HTTPS URL", consts.CmdOptRulesURL, rulesURL)
+	}
+
+	resp, err := httpClient.Get(rulesURL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to download known issues from %v", rulesURL)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("failed to download known issues from %v: %v", rulesURL, resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, knownIssuesMaxSize+1))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to download known issues from %v", rulesURL)
+	}
+	if len(data) > knownIssuesMaxSize {
+		return nil, errors.Errorf("known issues from %v are larger than %d bytes", rulesURL, knownIssuesMaxSize)
+	}
+
+	issueList, err := ParseKnownIssues(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid known issues from %v", rulesURL)
+	}
+	return issueList, nil
+}
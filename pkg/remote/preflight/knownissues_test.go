@@ -0,0 +1,61 @@
+package preflight
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseKnownIssues(t *testing.T) {
+	issueList, err := GetEmbeddedKnownIssues()
+	if err != nil {
+		t.Fatalf("embedded known issues: unexpected error: %v", err)
+	}
+	if len(issueList.Issues) == 0 {
+		t.Error("embedded known issues: expected at least one issue")
+	}
+
+	tests := map[string]string{
+		"unknown field":     "issues:\n- id: a\n  description: a\n  match:\n    kernelVersion: ^6\n",
+		"no id":             "issues:\n- description: a\n  match:\n    kernel: ^6\n",
+		"duplicated id":     "issues:\n- id: a\n  description: a\n  match:\n    kernel: ^6\n- id: a\n  description: b\n  match:\n    kernel: ^5\n",
+		"no description":    "issues:\n- id: a\n  match:\n    kernel: ^6\n",
+		"invalid severity":  "issues:\n- id: a\n  description: a\n  severity: fatal\n  match:\n    kernel: ^6\n",
+		"invalid regex":     "issues:\n- id: a\n  description: a\n  match:\n    nvmeFirmware: ^(3B2\n",
+		"no match criteria": "issues:\n- id: a\n  description: a\n",
+	}
+	for name, data := range tests {
+		if _, err := ParseKnownIssues([]byte(data)); err == nil {
+			t.Errorf("%v: expected an error", name)
+		}
+	}
+}
+
+func TestFetchKnownIssues(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rules.yaml":
+			_, _ = w.Write([]byte("issues:\n- id: a\n  description: a\n  match:\n    module: ^iscsi_tcp$\n"))
+		case "/large.yaml":
+			_, _ = w.Write([]byte(strings.Repeat("#", knownIssuesMaxSize+1)))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	issueList, err := FetchKnownIssues(server.Client(), server.URL+"/rules.yaml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(issueList.Issues) != 1 || issueList.Issues[0].Match.Module != "^iscsi_tcp$" {
+		t.Errorf("unexpected known issues %+v", issueList.Issues)
+	}
+
+	for _, rulesURL := range []string{server.URL + "/missing.yaml", server.URL + "/large.yaml", "file:///etc/rules.yaml"} {
+		if _, err := FetchKnownIssues(server.Client(), rulesURL); err == nil {
+			t.Errorf("FetchKnownIssues(%q) expected an error", rulesURL)
+		}
+	}
+}
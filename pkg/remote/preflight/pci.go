@@ -0,0 +1,191 @@
+package preflight
+
+import (
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeclient "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	commonkube "github.com/longhorn/go-common-libs/kubernetes"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// PciBindingsChecker provide functions for inspecting the driver bindings of the NVMe PCI devices for SPDK.
+type PciBindingsChecker struct {
+	PciBindingsCheckerCmdOptions
+
+	kubeClient *kubeclient.Clientset
+	restConfig *rest.Config
+
+	namespace string
+	appName   string // App name of the DaemonSet.
+}
+
+// PciBindingsCheckerCmdOptions holds the options for the command.
+type PciBindingsCheckerCmdOptions struct {
+	types.GlobalCmdOptions
+
+	AllowPci       string // Comma-separated PCI addresses of the devices intended for SPDK.
+	DriverOverride string // Userspace driver intended for the devices. Defaults to the one picked by SPDK.
+}
+
+// Init initializes the PciBindingsChecker.
+func (remote *PciBindingsChecker) Init() error {
+	kubeClient, err := kubeutils.NewKubeClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.kubeClient = kubeClient
+
+	restConfig, err := kubeutils.NewRestConfig("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.restConfig = restConfig
+
+	remote.namespace = remote.Namespace
+	if remote.namespace == "" {
+		remote.namespace = consts.LonghornNamespace
+	}
+	remote.appName = consts.AppNamePciBindingsChecker
+
+	return nil
+}
+
+// Collect creates the DaemonSet inspecting the PCI devices, waits for it to complete,
+// and returns the result of each node keyed by the node name.
+func (remote *PciBindingsChecker) Collect() (map[string]*types.LogCollection, error) {
+	if _, err := kubeutils.CreateNamespace(remote.kubeClient, remote.namespace); err != nil {
+		return nil, err
+	}
+
+	nodeSelector, err := kubeutils.ParseNodeSelector(remote.NodeSelector)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %q argument", consts.CmdOptNodeSelector)
+	}
+	newDaemonSet := remote.newDaemonSet(nodeSelector)
+	if err := kubeutils.SetPodOptions(&newDaemonSet.Spec.Template.Spec, &remote.GlobalCmdOptions); err != nil {
+		return nil, err
+	}
+
+	kubeutils.SetRunMetadata(remote.kubeClient, newDaemonSet)
+	kubeutils.LogManifest(newDaemonSet)
+	daemonSet, err := kubeutils.CreateDaemonSet(remote.kubeClient, newDaemonSet, remote.MinNodes)
+	if err != nil {
+		return nil, err
+	}
+
+	nodeCollections := map[string]*types.LogCollection{}
+	if err := collectNodeCollections(remote.restConfig, remote.kubeClient, daemonSet, nodeCollections); err != nil {
+		return nil, err
+	}
+
+	if err := kubeutils.AddSkippedNodes(remote.kubeClient, daemonSet, nodeCollections); err != nil {
+		return nil, err
+	}
+
+	return nodeCollections, nil
+}
+
+// Cleanup deletes the DaemonSet created for inspecting the PCI devices.
+func (remote *PciBindingsChecker) Cleanup() error {
+	return commonkube.DeleteDaemonSet(remote.kubeClient, remote.namespace, remote.appName)
+}
+
+// newDaemonSet prepares a DaemonSet reading the PCI devices from the sysfs of the host.
+func (remote *PciBindingsChecker) newDaemonSet(nodeSelector map[string]string) *appsv1.DaemonSet {
+	outputFilePath := filepath.Join(consts.VolumeMountSharedDirectory, consts.FileNameOutputJSON)
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      remote.appName,
+			Namespace: remote.namespace,
+			Labels: map[string]string{
+				"app":                 remote.appName,
+				consts.LabelManagedBy: consts.LabelValueManagedBy,
+			},
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app": remote.appName,
+				},
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						"app":                 remote.appName,
+						consts.LabelManagedBy: consts.LabelValueManagedBy,
+					},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:    consts.ContainerNameAgent,
+							Image:   remote.Image,
+							Command: kubeutils.NewAgentCommand(consts.CmdLonghornctlLocal, consts.SubCmdCheck, consts.SubCmdPciBindings),
+							Env: []corev1.EnvVar{
+								{
+									Name:  consts.EnvLogLevel,
+									Value: remote.LogLevel,
+								},
+								{
+									Name:  consts.EnvOutputFilePath,
+									Value: outputFilePath,
+								},
+								{
+									Name:  consts.EnvPciAllowed,
+									Value: remote.AllowPci,
+								},
+								{
+									Name:  consts.EnvDriverOverride,
+									Value: remote.DriverOverride,
+								},
+							},
+							SecurityContext: kubeutils.NewSecurityContext(remote.Privileged),
+							VolumeMounts: []corev1.VolumeMount{
+								{
+									Name:      consts.VolumeMountHostName,
+									MountPath: consts.VolumeMountHostDirectory,
+									ReadOnly:  true,
+								},
+								{
+									Name:      consts.VolumeMountSharedName,
+									MountPath: consts.VolumeMountSharedDirectory,
+								},
+							},
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: consts.VolumeMountHostName,
+							VolumeSource: corev1.VolumeSource{
+								HostPath: &corev1.HostPathVolumeSource{
+									Path: "/",
+								},
+							},
+						},
+						{
+							Name: consts.VolumeMountSharedName,
+							VolumeSource: corev1.VolumeSource{
+								EmptyDir: &corev1.EmptyDirVolumeSource{},
+							},
+						},
+					},
+					NodeSelector: nodeSelector,
+				},
+			},
+			UpdateStrategy: appsv1.DaemonSetUpdateStrategy{
+				Type: appsv1.RollingUpdateDaemonSetStrategyType,
+			},
+		},
+	}
+}
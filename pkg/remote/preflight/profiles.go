@@ -0,0 +1,213 @@
+package preflight
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	sigsyaml "sigs.k8s.io/yaml"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// installProfileGroup is a group of nodes installed with the same options.
+type installProfileGroup struct {
+	profile   *types.PreflightInstallProfile // Nil for the nodes matching no profile.
+	nodeNames []string
+}
+
+// ParseInstallProfiles parses and validates the preflight install profiles YAML.
+func ParseInstallProfiles(data []byte) (*types.PreflightInstallProfiles, error) {
+	profiles := &types.PreflightInstallProfiles{}
+	if err := sigsyaml.UnmarshalStrict(data, profiles); err != nil {
+		return nil, errors.Wrap(err, "failed to parse install profiles")
+	}
+
+	names := map[string]bool{}
+	for i, profile := range profiles.Profiles {
+		if profile.Name == "" {
+			return nil, errors.Errorf("install profile #%d has no name", i)
+		}
+		if names[profile.Name] {
+			return nil, errors.Errorf("install profile %v is defined more than once", profile.Name)
+		}
+		names[profile.Name] = true
+
+		nodeSelector, err := kubeutils.ParseNodeSelector(profile.NodeSelector)
+		if err != nil {
+			return nil, errors.Wrapf(err, "install profile %v has an invalid node selector", profile.Name)
+		}
+		if len(nodeSelector) == 0 {
+			return nil, errors.Errorf("install profile %v has no node selector", profile.Name)
+		}
+
+		if profile.HugePageNodes != nil {
+			if _, err := ParseHugePageNodes(*profile.HugePageNodes); err != nil {
+				return nil, errors.Wrapf(err, "install profile %v has invalid huge page nodes", profile.Name)
+			}
+		}
+		if profile.PackageSource != nil && *profile.PackageSource != "" && !filepath.IsAbs(*profile.PackageSource) {
+			return nil, errors.Errorf("install profile %v has a package source that is not an absolute path: %q", profile.Name, *profile.PackageSource)
+		}
+	}
+	return profiles, nil
+}
+
+// groupNodesByInstallProfile assigns each node to the first profile matching it, or to the group of
+// the nodes matching no profile, last. The groups without nodes are left out.
+func groupNodesByInstallProfile(profiles *types.PreflightInstallProfiles, nodes []*corev1.Node) ([]*installProfileGroup, error) {
+	groups := make([]*installProfileGroup, 0, len(profiles.Profiles)+1)
+	selectors := make([]labels.Selector, 0, len(profiles.Profiles))
+	for i := range profiles.Profiles {
+		nodeSelector, err := kubeutils.ParseNodeSelector(profiles.Profiles[i].NodeSelector)
+		if err != nil {
+			return nil, err
+		}
+		selectors = append(selectors, labels.SelectorFromSet(nodeSelector))
+		groups = append(groups, &installProfileGroup{profile: &profiles.Profiles[i]})
+	}
+	defaultGroup := &installProfileGroup{}
+	groups = append(groups, defaultGroup)
+
+	for _, node := range nodes {
+		group := defaultGroup
+		for i, selector := range selectors {
+			if selector.Matches(labels.Set(node.Labels)) {
+				group = groups[i]
+				break
+			}
+		}
+		group.nodeNames = append(group.nodeNames, node.Name)
+	}
+
+	nonEmptyGroups := []*installProfileGroup{}
+	for _, group := range groups {
+		if len(group.nodeNames) > 0 {
+			nonEmptyGroups = append(nonEmptyGroups, group)
+		}
+	}
+	return nonEmptyGroups, nil
+}
+
+// withInstallProfile returns a copy of the installer with the options of the profile.
+func (remote *Installer) withInstallProfile(profile *types.PreflightInstallProfile) *Installer {
+	installer := *remote
+	if profile == nil {
+		return &installer
+	}
+
+	if profile.UpdatePackages != nil {
+		installer.UpdatePackages = *profile.UpdatePackages
+	}
+	if profile.PackageSource != nil {
+		installer.PackageSource = *profile.PackageSource
+	}
+	if profile.TuneIscsid != nil {
+		installer.TuneIscsid = *profile.TuneIscsid
+	}
+	if profile.EnableSpdk != nil {
+		installer.EnableSpdk = *profile.EnableSpdk
+	}
+	if profile.SpdkOptions != nil {
+		installer.SpdkOptions = *profile.SpdkOptions
+	}
+	if profile.HugePageSize != nil {
+		installer.HugePageSize = *profile.HugePageSize
+	}
+	if profile.HugePageNodes != nil {
+		installer.HugePageNodes = *profile.HugePageNodes
+	}
+	if profile.AllowPci != nil {
+		installer.AllowPci = *profile.AllowPci
+	}
+	if profile.DriverOverride != nil {
+		installer.DriverOverride = *profile.DriverOverride
+	}
+	return &installer
+}
+
+// installByProfiles installs the dependencies with the package manager on each group of nodes in
+// turn, with the options of its profile, and merges the results of the groups.
+func (remote *Installer) installByProfiles(nodeSelector map[string]string) (map[string]*types.LogCollection, error) {
+	nodes, err := kubeutils.ListNodes(remote.kubeClient, labels.SelectorFromSet(nodeSelector))
+	if err != nil {
+		return nil, err
+	}
+
+	supportedNodes := make([]*corev1.Node, 0, len(nodes))
+	for _, node := range nodes {
+		if kubeutils.GetUnsupportedNodeReason(node) == "" {
+			supportedNodes = append(supportedNodes, node)
+		}
+	}
+
+	groups, err := groupNodesByInstallProfile(remote.installProfiles, supportedNodes)
+	if err != nil {
+		return nil, err
+	}
+
+	nodeCollections := map[string]*types.LogCollection{}
+	var newDaemonSet *appsv1.DaemonSet
+	for i, group := range groups {
+		profileName := "command options"
+		if group.profile != nil {
+			profileName = "profile " + group.profile.Name
+		}
+		logrus.Infof("Installing dependencies with the %v on nodes: %s", profileName, strings.Join(group.nodeNames, consts.CmdOptSeperator))
+
+		installer := remote.withInstallProfile(group.profile)
+		newDaemonSet = installer.NewDaemonSetForPackageManager(nodeSelector)
+		if err := kubeutils.SetPodOptions(&newDaemonSet.Spec.Template.Spec, &installer.GlobalCmdOptions); err != nil {
+			return nil, err
+		}
+
+		groupCollections := map[string]*types.LogCollection{}
+		err := kubeutils.RunDaemonSetOnNodes(installer.kubeClient, newDaemonSet, group.nodeNames, installer.MaxParallel, func(daemonSet *appsv1.DaemonSet) error {
+			return collectNodeCollections(installer.restConfig, installer.kubeClient, daemonSet, groupCollections)
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to install with the %v", profileName)
+		}
+
+		if installer.RebootStrategy == consts.RebootStrategyRolling {
+			if err := installer.rebootAndResume(newDaemonSet, groupCollections); err != nil {
+				return nil, errors.Wrapf(err, "failed to reboot the nodes of the %v", profileName)
+			}
+		}
+
+		for nodeName, collection := range groupCollections {
+			if collection == nil {
+				collection = &types.LogCollection{}
+			}
+			if group.profile != nil {
+				collection.Info = append([]string{fmt.Sprintf("Installed with profile %v", group.profile.Name)}, collection.Info...)
+			}
+			nodeCollections[nodeName] = collection
+		}
+
+		if i < len(groups)-1 {
+			if err := kubeutils.DeleteDaemonSetAndWait(remote.kubeClient, newDaemonSet); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if newDaemonSet == nil {
+		newDaemonSet = remote.NewDaemonSetForPackageManager(nodeSelector)
+	}
+	if err := kubeutils.AddSkippedNodes(remote.kubeClient, newDaemonSet, nodeCollections); err != nil {
+		return nil, err
+	}
+
+	return nodeCollections, nil
+}
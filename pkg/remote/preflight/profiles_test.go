@@ -0,0 +1,84 @@
+package preflight
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseInstallProfiles(t *testing.T) {
+	tests := map[string]string{
+		"unknown field":           "profiles:\n- name: nvme\n  nodeSelector: storage=nvme\n  spdk: true\n",
+		"no name":                 "profiles:\n- nodeSelector: storage=nvme\n",
+		"duplicated name":         "profiles:\n- name: nvme\n  nodeSelector: storage=nvme\n- name: nvme\n  nodeSelector: storage=ssd\n",
+		"no node selector":        "profiles:\n- name: nvme\n",
+		"invalid node selector":   "profiles:\n- name: nvme\n  nodeSelector: storage\n",
+		"invalid huge pages":      "profiles:\n- name: nvme\n  nodeSelector: storage=nvme\n  hugePageNodes: a=1\n",
+		"relative package source": "profiles:\n- name: nvme\n  nodeSelector: storage=nvme\n  packageSource: packages\n",
+	}
+	for name, data := range tests {
+		if _, err := ParseInstallProfiles([]byte(data)); err == nil {
+			t.Errorf("%v: expected an error", name)
+		}
+	}
+}
+
+func TestGroupNodesByInstallProfile(t *testing.T) {
+	profiles, err := ParseInstallProfiles([]byte(`profiles:
+- name: nvme
+  nodeSelector: storage=nvme
+  enableSpdk: true
+  hugePageSize: 4096
+- name: gpu
+  nodeSelector: pool=gpu
+  tuneIscsid: true
+- name: empty
+  nodeSelector: pool=empty
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	newNode := func(name string, labels map[string]string) *corev1.Node {
+		return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+	}
+	nodes := []*corev1.Node{
+		newNode("node-1", map[string]string{"storage": "nvme", "pool": "gpu"}),
+		newNode("node-2", map[string]string{"pool": "gpu"}),
+		newNode("node-3", nil),
+		newNode("node-4", map[string]string{"storage": "nvme"}),
+	}
+
+	groups, err := groupNodesByInstallProfile(profiles, nodes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	actual := map[string][]string{}
+	for _, group := range groups {
+		name := ""
+		if group.profile != nil {
+			name = group.profile.Name
+		}
+		actual[name] = group.nodeNames
+	}
+	expected := map[string][]string{
+		"nvme": {"node-1", "node-4"},
+		"gpu":  {"node-2"},
+		"":     {"node-3"},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected groups %v, got %v", expected, actual)
+	}
+
+	installer := &Installer{InstallerCmdOptions: InstallerCmdOptions{HugePageSize: 2048, AllowPci: "none"}}
+	profiled := installer.withInstallProfile(&profiles.Profiles[0])
+	if !profiled.EnableSpdk || profiled.HugePageSize != 4096 || profiled.AllowPci != "none" {
+		t.Errorf("unexpected options of profile nvme: %+v", profiled.InstallerCmdOptions)
+	}
+	if installer.EnableSpdk || installer.HugePageSize != 2048 {
+		t.Errorf("expected the options of the command to be kept, got %+v", installer.InstallerCmdOptions)
+	}
+}
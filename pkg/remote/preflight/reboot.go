@@ -0,0 +1,245 @@
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	commonkube "github.com/longhorn/go-common-libs/kubernetes"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// rebootBatchTimeout bounds the drain, the reboot and the readiness of a batch of nodes. The drain
+// may wait for Longhorn to rebuild the replicas of the nodes elsewhere.
+const rebootBatchTimeout = time.Hour
+
+// ValidateRebootStrategy checks the reboot strategy and the maximum number of nodes rebooted at
+// the same time.
+func ValidateRebootStrategy(strategy string, maxUnavailable int) error {
+	switch strategy {
+	case "", consts.RebootStrategyNone:
+		return nil
+	case consts.RebootStrategyRolling:
+		if maxUnavailable < 1 {
+			return errors.Errorf("invalid --%s %d, it must be at least 1", consts.CmdOptMaxUnavailable, maxUnavailable)
+		}
+		return nil
+	default:
+		return errors.Errorf("invalid --%s %q, it must be %v or %v", consts.CmdOptRebootStrategy, strategy, consts.RebootStrategyNone, consts.RebootStrategyRolling)
+	}
+}
+
+// GetRebootRequiredNodes returns the sorted names of the nodes reporting they need a reboot before
+// the second phase of the install.
+func GetRebootRequiredNodes(nodeCollections map[string]*types.LogCollection) []string {
+	nodeNames := []string{}
+	for nodeName, collection := range nodeCollections {
+		if collection != nil && slices.Contains(collection.Warn, consts.PreflightInstallRebootRequired) {
+			nodeNames = append(nodeNames, nodeName)
+		}
+	}
+	sort.Strings(nodeNames)
+	return nodeNames
+}
+
+// rebootAndResume reboots the nodes needing a reboot after the install, at most MaxUnavailable
+// nodes at a time. Each batch is cordoned and drained, rebooted, and uncordoned once ready, unless
+// a node was cordoned before. Then the DaemonSet runs again on the batch for the second phase of
+// the install, and its results replace the ones of the first phase.
+func (remote *Installer) rebootAndResume(newDaemonSet *appsv1.DaemonSet, nodeCollections map[string]*types.LogCollection) error {
+	nodeNames := GetRebootRequiredNodes(nodeCollections)
+	if len(nodeNames) == 0 {
+		return nil
+	}
+
+	// Rebooting the node running the CLI, such as in the Job of "longhornctl generate job", would
+	// interrupt the install.
+	if currentNodeName := os.Getenv(consts.EnvCurrentNodeID); slices.Contains(nodeNames, currentNodeName) {
+		logrus.Warnf("Skipping reboot of node %v running longhornctl, reboot it and run the install again to complete it", currentNodeName)
+		nodeNames = slices.DeleteFunc(nodeNames, func(nodeName string) bool {
+			return nodeName == currentNodeName
+		})
+		if len(nodeNames) == 0 {
+			return nil
+		}
+	}
+
+	logrus.Infof("Rebooting nodes: %s", strings.Join(nodeNames, consts.CmdOptSeperator))
+
+	if err := kubeutils.DeleteDaemonSetAndWait(remote.kubeClient, newDaemonSet); err != nil {
+		return err
+	}
+
+	batches := kubeutils.SplitIntoBatches(nodeNames, remote.MaxUnavailable)
+	for i, batch := range batches {
+		logrus.Infof("Rebooting batch %d/%d of nodes: %s", i+1, len(batches), strings.Join(batch, consts.CmdOptSeperator))
+
+		if err := remote.rebootNodes(batch); err != nil {
+			return errors.Wrapf(err, "failed to reboot batch %d/%d", i+1, len(batches))
+		}
+
+		batchDaemonSet := newDaemonSet.DeepCopy()
+		kubeutils.SetNodeNameAffinity(&batchDaemonSet.Spec.Template.Spec, batch)
+
+		kubeutils.SetRunMetadata(remote.kubeClient, batchDaemonSet)
+		kubeutils.LogManifest(batchDaemonSet)
+		daemonSet, err := commonkube.CreateDaemonSet(remote.kubeClient, batchDaemonSet)
+		if err != nil {
+			return err
+		}
+
+		batchCollections := map[string]*types.LogCollection{}
+		if err := collectNodeCollections(remote.restConfig, remote.kubeClient, daemonSet, batchCollections); err != nil {
+			return errors.Wrapf(err, "failed to resume the install on batch %d/%d", i+1, len(batches))
+		}
+
+		for _, nodeName := range batch {
+			collection, ok := batchCollections[nodeName]
+			if !ok {
+				collection = &types.LogCollection{}
+			}
+			collection.Info = append([]string{"Rebooted node and resumed the install"}, collection.Info...)
+			nodeCollections[nodeName] = collection
+		}
+
+		logrus.Infof("Completed reboot of batch %d/%d", i+1, len(batches))
+
+		if err := kubeutils.DeleteDaemonSetAndWait(remote.kubeClient, daemonSet); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// rebootNodes cordons and drains the nodes, reboots them through a pod entering the namespaces of
+// the host, and waits for them to be ready again. The nodes cordoned here are uncordoned afterwards,
+// even when the reboot fails, so they are not left out of the cluster. The nodes already cordoned
+// are left cordoned.
+func (remote *Installer) rebootNodes(nodeNames []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), rebootBatchTimeout)
+	defer cancel()
+
+	cordonedNodeNames := []string{}
+	defer func() {
+		for _, nodeName := range cordonedNodeNames {
+			if err := kubeutils.CordonNode(context.Background(), remote.kubeClient, nodeName, false); err != nil {
+				logrus.WithError(err).Warn("Failed to uncordon node")
+			}
+		}
+	}()
+
+	for _, nodeName := range nodeNames {
+		node, err := remote.kubeClient.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+		if err != nil {
+			return errors.Wrapf(err, "failed to get node %v", nodeName)
+		}
+		if node.Spec.Unschedulable {
+			logrus.Infof("Node %v is already cordoned, it is left cordoned after the reboot", nodeName)
+		} else {
+			if err := kubeutils.CordonNode(ctx, remote.kubeClient, nodeName, true); err != nil {
+				return err
+			}
+			cordonedNodeNames = append(cordonedNodeNames, nodeName)
+		}
+
+		logrus.Infof("Draining node %v", nodeName)
+		if err := kubeutils.DrainNode(ctx, remote.kubeClient, nodeName, remote.ForceDrain); err != nil {
+			return err
+		}
+	}
+
+	bootIDs := map[string]string{}
+	for _, nodeName := range nodeNames {
+		node, err := remote.kubeClient.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+		if err != nil {
+			return errors.Wrapf(err, "failed to get node %v", nodeName)
+		}
+		bootIDs[nodeName] = node.Status.NodeInfo.BootID
+
+		pod := remote.newRebootPod(nodeName)
+		if err := kubeutils.SetPodOptions(&pod.Spec, &remote.GlobalCmdOptions); err != nil {
+			return err
+		}
+
+		kubeutils.SetRunMetadata(remote.kubeClient, pod)
+		logrus.Infof("Rebooting node %v", nodeName)
+		if _, err := remote.kubeClient.CoreV1().Pods(remote.namespace).Create(ctx, pod, metav1.CreateOptions{}); err != nil {
+			return errors.Wrapf(err, "failed to create pod %v", pod.Name)
+		}
+	}
+
+	for _, nodeName := range nodeNames {
+		if err := kubeutils.WaitForNodeRebooted(ctx, remote.kubeClient, nodeName, bootIDs[nodeName]); err != nil {
+			return err
+		}
+		logrus.Infof("Node %v is ready after the reboot", nodeName)
+
+		if err := kubeutils.DeletePod(ctx, remote.kubeClient, remote.namespace, remote.rebootPodName(nodeName)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (remote *Installer) rebootPodName(nodeName string) string {
+	return fmt.Sprintf("%s-%s", consts.AppNamePreflightRebooter, nodeName)
+}
+
+// newRebootPod prepares a pod rebooting the node with systemd, from the namespaces of the host.
+// It is bound to the node, so it runs on the cordoned node without being scheduled.
+func (remote *Installer) newRebootPod(nodeName string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      remote.rebootPodName(nodeName),
+			Namespace: remote.namespace,
+			Labels: map[string]string{
+				"app":                 consts.AppNamePreflightRebooter,
+				consts.LabelManagedBy: consts.LabelValueManagedBy,
+			},
+		},
+		Spec: corev1.PodSpec{
+			NodeName:      nodeName,
+			HostPID:       true,
+			RestartPolicy: corev1.RestartPolicyNever,
+			Tolerations: []corev1.Toleration{
+				{
+					Operator: corev1.TolerationOpExists,
+				},
+			},
+			Containers: []corev1.Container{
+				{
+					Name:            consts.ContainerName,
+					Image:           remote.Image,
+					Command:         []string{"nsenter", "--target", "1", "--mount", "--uts", "--ipc", "--net", "--pid", "--", "systemctl", "reboot"},
+					SecurityContext: kubeutils.NewSecurityContext(remote.Privileged, kubeutils.CapabilitiesHostNamespaces),
+				},
+			},
+		},
+	}
+}
+
+// deleteRebootPods deletes the reboot pods left by an interrupted reboot.
+func (remote *Installer) deleteRebootPods() error {
+	err := remote.kubeClient.CoreV1().Pods(remote.namespace).DeleteCollection(context.Background(), metav1.DeleteOptions{}, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set{"app": consts.AppNamePreflightRebooter}).String(),
+	})
+	return errors.Wrap(err, "failed to delete reboot pods")
+}
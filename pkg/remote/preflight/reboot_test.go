@@ -0,0 +1,50 @@
+package preflight
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func TestValidateRebootStrategy(t *testing.T) {
+	for _, test := range []struct {
+		strategy       string
+		maxUnavailable int
+		valid          bool
+	}{
+		{strategy: "", valid: true},
+		{strategy: consts.RebootStrategyNone, valid: true},
+		{strategy: consts.RebootStrategyRolling, maxUnavailable: 1, valid: true},
+		{strategy: consts.RebootStrategyRolling, maxUnavailable: 3, valid: true},
+		{strategy: consts.RebootStrategyRolling, maxUnavailable: 0},
+		{strategy: "parallel", maxUnavailable: 1},
+	} {
+		err := ValidateRebootStrategy(test.strategy, test.maxUnavailable)
+		if test.valid && err != nil {
+			t.Errorf("expected strategy %q with max unavailable %d to be valid, got %v", test.strategy, test.maxUnavailable, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("expected strategy %q with max unavailable %d to be invalid", test.strategy, test.maxUnavailable)
+		}
+	}
+}
+
+func TestGetRebootRequiredNodes(t *testing.T) {
+	nodeCollections := map[string]*types.LogCollection{
+		"node-c": {Warn: []string{consts.PreflightInstallRebootRequired}},
+		"node-a": {Warn: []string{"Failed to start service iscsid", consts.PreflightInstallRebootRequired}},
+		"node-b": {Info: []string{"Successfully installed package open-iscsi"}},
+		"node-d": nil,
+	}
+
+	expected := []string{"node-a", "node-c"}
+	if nodeNames := GetRebootRequiredNodes(nodeCollections); !reflect.DeepEqual(nodeNames, expected) {
+		t.Errorf("expected nodes %v, got %v", expected, nodeNames)
+	}
+
+	if nodeNames := GetRebootRequiredNodes(map[string]*types.LogCollection{}); len(nodeNames) != 0 {
+		t.Errorf("expected no nodes, got %v", nodeNames)
+	}
+}
@@ -0,0 +1,325 @@
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	kubeclient "k8s.io/client-go/kubernetes"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/remote/node"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+const serverNodePollInterval = time.Minute
+
+// Server provide functions for continuously running the preflight check in the cluster.
+type Server struct {
+	ServerCmdOptions
+
+	kubeClient *kubeclient.Clientset
+	checker    *Checker
+	gate       *node.Gate
+
+	appName   string // Name of the result ConfigMap.
+	namespace string
+
+	lock            sync.RWMutex
+	knownNodes      map[string]bool
+	nodeCollections map[string]*types.LogCollection
+	lastRunTime     time.Time
+	runsTotal       int
+	runsFailedTotal int
+}
+
+// ServerCmdOptions holds the options for the command.
+type ServerCmdOptions struct {
+	CheckerCmdOptions
+
+	Interval      time.Duration
+	ListenAddress string
+}
+
+// Validate validates the command options.
+func (remote *Server) Validate() error {
+	if remote.Interval <= 0 {
+		return errors.Errorf("interval (--%s) must be greater than 0", consts.CmdOptInterval)
+	}
+
+	return nil
+}
+
+// Init initializes the Server and the Checker it runs.
+func (remote *Server) Init() error {
+	kubeClient, err := kubeutils.NewKubeClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.kubeClient = kubeClient
+
+	remote.checker = &Checker{CheckerCmdOptions: remote.CheckerCmdOptions}
+	if err := remote.checker.Init(); err != nil {
+		return errors.Wrap(err, "failed to initialize preflight checker")
+	}
+
+	remote.gate = &node.Gate{GateCmdOptions: node.GateCmdOptions{
+		GlobalCmdOptions:  remote.GlobalCmdOptions,
+		LonghornNamespace: consts.LonghornNamespace,
+	}}
+	if err := remote.gate.Init(); err != nil {
+		return errors.Wrap(err, "failed to initialize node gate")
+	}
+
+	remote.namespace = remote.checker.namespace
+	remote.appName = consts.AppNamePreflightServer
+	remote.knownNodes = map[string]bool{}
+	remote.nodeCollections = map[string]*types.LogCollection{}
+
+	return nil
+}
+
+// Run serves the metrics endpoint and runs the preflight check at every interval,
+// and whenever a new node joins the cluster. It blocks until the context is done.
+func (remote *Server) Run(ctx context.Context) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", remote.handleMetrics)
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	server := &http.Server{
+		Addr:              remote.ListenAddress,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	serverErrCh := make(chan error, 1)
+	go func() {
+		logrus.Infof("Serving metrics on %s", remote.ListenAddress)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErrCh <- err
+		}
+	}()
+	defer func() {
+		_ = server.Shutdown(context.Background())
+	}()
+
+	runTicker := time.NewTicker(remote.Interval)
+	defer runTicker.Stop()
+
+	nodeTicker := time.NewTicker(serverNodePollInterval)
+	defer nodeTicker.Stop()
+
+	remote.check()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logrus.Info("Stopping preflight server")
+			return remote.checker.Cleanup()
+
+		case err := <-serverErrCh:
+			return errors.Wrap(err, "failed to serve metrics")
+
+		case <-runTicker.C:
+			remote.check()
+
+		case <-nodeTicker.C:
+			newNodes, err := remote.findNewNodes()
+			if err != nil {
+				logrus.WithError(err).Warn("Failed to list nodes")
+				continue
+			}
+
+			if len(newNodes) == 0 {
+				continue
+			}
+
+			logrus.Infof("Detected new nodes %v", newNodes)
+			remote.check()
+		}
+	}
+}
+
+// check runs the preflight check once and records the result.
+func (remote *Server) check() {
+	logrus.Info("Running preflight checker")
+
+	nodes, err := remote.listNodes()
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to list nodes")
+	}
+
+	nodeCollections, err := remote.runChecker()
+
+	remote.lock.Lock()
+	remote.runsTotal++
+	remote.lastRunTime = time.Now()
+	if err != nil {
+		remote.runsFailedTotal++
+	} else {
+		remote.nodeCollections = nodeCollections
+	}
+	for _, node := range nodes {
+		remote.knownNodes[node] = true
+	}
+	remote.lock.Unlock()
+
+	if err != nil {
+		logrus.WithError(err).Error("Failed to run preflight checker")
+		return
+	}
+
+	if err := remote.saveResult(nodeCollections); err != nil {
+		logrus.WithError(err).Error("Failed to save preflight checker result")
+		return
+	}
+
+	// Pass the nodes of the node gate, when "node gate enable" enabled it.
+	if _, err := remote.gate.SyncIfEnabled(nodeCollections); err != nil {
+		logrus.WithError(err).Error("Failed to sync node gate")
+	}
+
+	logrus.Infof("Completed preflight checker on %d nodes", len(nodeCollections))
+}
+
+func (remote *Server) runChecker() (map[string]*types.LogCollection, error) {
+	if err := remote.checker.Cleanup(); err != nil {
+		return nil, errors.Wrap(err, "failed to cleanup preflight checker")
+	}
+
+	nodeCollections, err := remote.checker.Collect()
+
+	if _err := remote.checker.Cleanup(); _err != nil {
+		logrus.WithError(_err).Warn("Failed to cleanup preflight checker")
+	}
+
+	return nodeCollections, err
+}
+
+// saveResult writes the result of each node into the result ConfigMap.
+func (remote *Server) saveResult(nodeCollections map[string]*types.LogCollection) error {
+	data := map[string]string{}
+	for node, collection := range nodeCollections {
+		yamlData, err := yaml.Marshal(collection)
+		if err != nil {
+			return err
+		}
+		data[node] = string(yamlData)
+	}
+
+	remote.lock.RLock()
+	lastRunTime := remote.lastRunTime
+	remote.lock.RUnlock()
+
+	_, err := kubeutils.CreateOrUpdateConfigMap(remote.kubeClient, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      remote.appName,
+			Namespace: remote.namespace,
+			Labels: map[string]string{
+				"app": remote.appName,
+			},
+			Annotations: map[string]string{
+				consts.AnnotationPreflightLastRunTime: lastRunTime.UTC().Format(time.RFC3339),
+			},
+		},
+		Data: data,
+	})
+	return err
+}
+
+func (remote *Server) listNodes() ([]string, error) {
+	selector, err := labels.Parse(remote.NodeSelector)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %q argument", consts.CmdOptNodeSelector)
+	}
+
+	nodeList, err := kubeutils.ListNodes(remote.kubeClient, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes []string
+	for _, node := range nodeList {
+		nodes = append(nodes, node.Name)
+	}
+	return nodes, nil
+}
+
+// findNewNodes returns the nodes that have not been checked yet.
+func (remote *Server) findNewNodes() ([]string, error) {
+	nodes, err := remote.listNodes()
+	if err != nil {
+		return nil, err
+	}
+
+	remote.lock.RLock()
+	defer remote.lock.RUnlock()
+
+	var newNodes []string
+	for _, node := range nodes {
+		if !remote.knownNodes[node] {
+			newNodes = append(newNodes, node)
+		}
+	}
+	return newNodes, nil
+}
+
+// handleMetrics writes the preflight check metrics in the Prometheus text format.
+func (remote *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	remote.lock.RLock()
+	defer remote.lock.RUnlock()
+
+	var builder strings.Builder
+
+	builder.WriteString("# HELP longhornctl_preflight_node_checks Number of preflight check results on the node by level.\n")
+	builder.WriteString("# TYPE longhornctl_preflight_node_checks gauge\n")
+
+	nodes := make([]string, 0, len(remote.nodeCollections))
+	for node := range remote.nodeCollections {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+
+	for _, node := range nodes {
+		collection := remote.nodeCollections[node]
+		if collection == nil {
+			collection = &types.LogCollection{}
+		}
+		fmt.Fprintf(&builder, "longhornctl_preflight_node_checks{node=%q,level=\"error\"} %d\n", node, len(collection.Error))
+		fmt.Fprintf(&builder, "longhornctl_preflight_node_checks{node=%q,level=\"warn\"} %d\n", node, len(collection.Warn))
+		fmt.Fprintf(&builder, "longhornctl_preflight_node_checks{node=%q,level=\"info\"} %d\n", node, len(collection.Info))
+	}
+
+	builder.WriteString("# HELP longhornctl_preflight_runs_total Number of preflight check runs.\n")
+	builder.WriteString("# TYPE longhornctl_preflight_runs_total counter\n")
+	fmt.Fprintf(&builder, "longhornctl_preflight_runs_total %d\n", remote.runsTotal)
+
+	builder.WriteString("# HELP longhornctl_preflight_runs_failed_total Number of failed preflight check runs.\n")
+	builder.WriteString("# TYPE longhornctl_preflight_runs_failed_total counter\n")
+	fmt.Fprintf(&builder, "longhornctl_preflight_runs_failed_total %d\n", remote.runsFailedTotal)
+
+	if !remote.lastRunTime.IsZero() {
+		builder.WriteString("# HELP longhornctl_preflight_last_run_timestamp_seconds Unix time of the last preflight check run.\n")
+		builder.WriteString("# TYPE longhornctl_preflight_last_run_timestamp_seconds gauge\n")
+		fmt.Fprintf(&builder, "longhornctl_preflight_last_run_timestamp_seconds %d\n", remote.lastRunTime.Unix())
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	_, _ = w.Write([]byte(builder.String()))
+}
@@ -0,0 +1,135 @@
+package preflight
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeclient "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func TestServerHandleMetrics(t *testing.T) {
+	server := &Server{
+		nodeCollections: map[string]*types.LogCollection{
+			"node-2": {Error: []string{"iscsid is not running"}, Warn: []string{"multipathd is running"}},
+			"node-1": {Info: []string{"iscsid is running", "nfs client is installed"}},
+			"node-3": nil,
+		},
+		lastRunTime:     time.Unix(1721121458, 0),
+		runsTotal:       3,
+		runsFailedTotal: 1,
+	}
+
+	recorder := httptest.NewRecorder()
+	server.handleMetrics(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	expected := `# HELP longhornctl_preflight_node_checks Number of preflight check results on the node by level.
+# TYPE longhornctl_preflight_node_checks gauge
+longhornctl_preflight_node_checks{node="node-1",level="error"} 0
+longhornctl_preflight_node_checks{node="node-1",level="warn"} 0
+longhornctl_preflight_node_checks{node="node-1",level="info"} 2
+longhornctl_preflight_node_checks{node="node-2",level="error"} 1
+longhornctl_preflight_node_checks{node="node-2",level="warn"} 1
+longhornctl_preflight_node_checks{node="node-2",level="info"} 0
+longhornctl_preflight_node_checks{node="node-3",level="error"} 0
+longhornctl_preflight_node_checks{node="node-3",level="warn"} 0
+longhornctl_preflight_node_checks{node="node-3",level="info"} 0
+# HELP longhornctl_preflight_runs_total Number of preflight check runs.
+# TYPE longhornctl_preflight_runs_total counter
+longhornctl_preflight_runs_total 3
+# HELP longhornctl_preflight_runs_failed_total Number of failed preflight check runs.
+# TYPE longhornctl_preflight_runs_failed_total counter
+longhornctl_preflight_runs_failed_total 1
+# HELP longhornctl_preflight_last_run_timestamp_seconds Unix time of the last preflight check run.
+# TYPE longhornctl_preflight_last_run_timestamp_seconds gauge
+longhornctl_preflight_last_run_timestamp_seconds 1721121458
+`
+	if body := recorder.Body.String(); body != expected {
+		t.Errorf("expected metrics:\n%s\ngot:\n%s", expected, body)
+	}
+	if contentType := recorder.Header().Get("Content-Type"); contentType != "text/plain; version=0.0.4" {
+		t.Errorf("expected the Prometheus text format, got %q", contentType)
+	}
+}
+
+func TestServerHandleMetricsBeforeFirstRun(t *testing.T) {
+	server := &Server{nodeCollections: map[string]*types.LogCollection{}}
+
+	recorder := httptest.NewRecorder()
+	server.handleMetrics(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	expected := `# HELP longhornctl_preflight_node_checks Number of preflight check results on the node by level.
+# TYPE longhornctl_preflight_node_checks gauge
+# HELP longhornctl_preflight_runs_total Number of preflight check runs.
+# TYPE longhornctl_preflight_runs_total counter
+longhornctl_preflight_runs_total 0
+# HELP longhornctl_preflight_runs_failed_total Number of failed preflight check runs.
+# TYPE longhornctl_preflight_runs_failed_total counter
+longhornctl_preflight_runs_failed_total 0
+`
+	if body := recorder.Body.String(); body != expected {
+		t.Errorf("expected metrics:\n%s\ngot:\n%s", expected, body)
+	}
+}
+
+func TestServerFindNewNodes(t *testing.T) {
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path != "/api/v1/nodes":
+			t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		case r.URL.Query().Get("watch") == "true":
+			// End the watch without events, the node informer lists again.
+		default:
+			var nodes []corev1.Node
+			for _, name := range []string{"node-1", "node-2", "node-3"} {
+				nodes = append(nodes, corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, ResourceVersion: "1"}})
+			}
+			_ = json.NewEncoder(w).Encode(&corev1.NodeList{
+				TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "NodeList"},
+				ListMeta: metav1.ListMeta{ResourceVersion: "1"},
+				Items:    nodes,
+			})
+		}
+	}))
+	defer apiServer.Close()
+
+	kubeClient, err := kubeclient.NewForConfig(&rest.Config{
+		Host:          apiServer.URL,
+		ContentConfig: rest.ContentConfig{ContentType: "application/json"},
+	})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	server := &Server{
+		kubeClient: kubeClient,
+		knownNodes: map[string]bool{"node-1": true, "node-3": true},
+	}
+
+	newNodes, err := server.findNewNodes()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(newNodes, []string{"node-2"}) {
+		t.Errorf("expected new node node-2, got %v", newNodes)
+	}
+
+	server.knownNodes["node-2"] = true
+	newNodes, err = server.findNewNodes()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(newNodes) != 0 {
+		t.Errorf("expected no new nodes, got %v", newNodes)
+	}
+}
@@ -0,0 +1,99 @@
+package preflight
+
+import (
+	"regexp"
+
+	"github.com/pkg/errors"
+
+	sigsyaml "sigs.k8s.io/yaml"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+// severityPolicyRule is a rule of the severity policy with its compiled regular expression.
+type severityPolicyRule struct {
+	match    *regexp.Regexp
+	severity types.CustomCheckSeverity
+}
+
+// ParseSeverityPolicy parses and validates the severity policy YAML.
+func ParseSeverityPolicy(data []byte) (*types.SeverityPolicy, error) {
+	policy := &types.SeverityPolicy{}
+	if err := sigsyaml.UnmarshalStrict(data, policy); err != nil {
+		return nil, errors.Wrap(err, "failed to parse severity policy")
+	}
+
+	if _, err := compileSeverityPolicy(policy); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+func compileSeverityPolicy(policy *types.SeverityPolicy) ([]severityPolicyRule, error) {
+	rules := make([]severityPolicyRule, 0, len(policy.Rules))
+	for i, rule := range policy.Rules {
+		if rule.Match == "" {
+			return nil, errors.Errorf("severity rule #%d has no match", i)
+		}
+
+		match, err := regexp.Compile(rule.Match)
+		if err != nil {
+			return nil, errors.Wrapf(err, "severity rule #%d has an invalid match", i)
+		}
+
+		switch rule.Severity {
+		case types.CustomCheckSeverityError, types.CustomCheckSeverityWarn, types.CustomCheckSeverityInfo:
+		default:
+			return nil, errors.Errorf("severity rule #%d has an invalid severity %q", i, rule.Severity)
+		}
+
+		rules = append(rules, severityPolicyRule{match: match, severity: rule.Severity})
+	}
+	return rules, nil
+}
+
+// ApplySeverityPolicy moves the error, warn and info messages of each node matching a rule of the
+// policy to the level of the rule. The first matching rule applies, and the messages matching no
+// rule keep their level. The skip reasons are left as is.
+func ApplySeverityPolicy(policy *types.SeverityPolicy, nodeCollections map[string]*types.LogCollection) error {
+	rules, err := compileSeverityPolicy(policy)
+	if err != nil {
+		return err
+	}
+
+	for _, collection := range nodeCollections {
+		if collection == nil {
+			continue
+		}
+
+		levels := map[types.CustomCheckSeverity][]string{}
+		for _, level := range []struct {
+			severity types.CustomCheckSeverity
+			messages []string
+		}{
+			{types.CustomCheckSeverityError, collection.Error},
+			{types.CustomCheckSeverityWarn, collection.Warn},
+			{types.CustomCheckSeverityInfo, collection.Info},
+		} {
+			for _, message := range level.messages {
+				severity := applySeverityRules(rules, message, level.severity)
+				levels[severity] = append(levels[severity], message)
+			}
+		}
+
+		collection.Error = levels[types.CustomCheckSeverityError]
+		collection.Warn = levels[types.CustomCheckSeverityWarn]
+		collection.Info = levels[types.CustomCheckSeverityInfo]
+	}
+	return nil
+}
+
+// applySeverityRules returns the level of the message with the rules applied.
+func applySeverityRules(rules []severityPolicyRule, message string, severity types.CustomCheckSeverity) types.CustomCheckSeverity {
+	for _, rule := range rules {
+		if rule.match.MatchString(message) {
+			return rule.severity
+		}
+	}
+	return severity
+}
@@ -0,0 +1,58 @@
+package preflight
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func TestParseSeverityPolicy(t *testing.T) {
+	tests := map[string]string{
+		"unknown field":    "rules:\n- match: a\n  level: warn\n",
+		"no match":         "rules:\n- severity: warn\n",
+		"invalid regex":    "rules:\n- match: ^(a\n  severity: warn\n",
+		"no severity":      "rules:\n- match: a\n",
+		"invalid severity": "rules:\n- match: a\n  severity: fatal\n",
+	}
+	for name, data := range tests {
+		if _, err := ParseSeverityPolicy([]byte(data)); err == nil {
+			t.Errorf("%v: expected an error", name)
+		}
+	}
+}
+
+func TestApplySeverityPolicy(t *testing.T) {
+	policy, err := ParseSeverityPolicy([]byte("rules:\n- match: ^nfs-utils .* is older than\n  severity: warn\n- match: ^multipathd\n  severity: error\n- match: multipath\n  severity: info\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	nodeCollections := map[string]*types.LogCollection{
+		"node-1": {
+			Error: []string{"nfs-utils 1.2.8 is older than 1.3.0", "Missing package nvme-cli"},
+			Warn:  []string{"multipathd.service is running", "dm_multipath is loaded"},
+			Info:  []string{"Kernel module iscsi_tcp is loaded"},
+		},
+		"node-2": {
+			Skipped: []string{"multipathd.service is running"},
+		},
+	}
+	if err := ApplySeverityPolicy(policy, nodeCollections); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]*types.LogCollection{
+		"node-1": {
+			Error: []string{"Missing package nvme-cli", "multipathd.service is running"},
+			Warn:  []string{"nfs-utils 1.2.8 is older than 1.3.0"},
+			Info:  []string{"dm_multipath is loaded", "Kernel module iscsi_tcp is loaded"},
+		},
+		"node-2": {
+			Skipped: []string{"multipathd.service is running"},
+		},
+	}
+	if !reflect.DeepEqual(nodeCollections, expected) {
+		t.Errorf("unexpected collections %+v, expected %+v", nodeCollections["node-1"], expected["node-1"])
+	}
+}
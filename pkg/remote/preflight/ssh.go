@@ -0,0 +1,80 @@
+package preflight
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+
+	commonutils "github.com/longhorn/go-common-libs/utils"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/remote/ssh"
+	"github.com/longhorn/cli/pkg/types"
+)
+
+// validateBackend validates the backend options.
+func validateBackend(options *types.SSHCmdOptions) error {
+	switch options.Backend {
+	case "", consts.BackendDaemonSet:
+		return nil
+	case consts.BackendSSH:
+		if options.SSHHostsFile == "" {
+			return errors.Errorf("--%s is required with the %s backend", consts.CmdOptSSHHosts, consts.BackendSSH)
+		}
+		return nil
+	default:
+		return errors.Errorf("unsupported backend %q (--%s), supported backends: %s, %s", options.Backend, consts.CmdOptBackend, consts.BackendDaemonSet, consts.BackendSSH)
+	}
+}
+
+// collectOverSSH runs the preflight check with longhornctl-local on the SSH hosts.
+func (remote *Checker) collectOverSSH() (map[string]*types.LogCollection, error) {
+	args := []string{
+		consts.SubCmdPreflight, consts.SubCmdCheck,
+		"--" + consts.CmdOptLogLevel + "=" + remote.LogLevel,
+		"--" + consts.CmdOptEnableSpdk + "=" + commonutils.ConvertTypeToString(remote.EnableSpdk),
+		"--" + consts.CmdOptHugePageSize + "=" + commonutils.ConvertTypeToString(remote.HugePageSize),
+		"--" + consts.CmdOptHugePageNodes + "=" + remote.HugePageNodes,
+		"--" + consts.CmdOptUserspaceDriver + "=" + remote.UserspaceDriver,
+		"--" + consts.CmdOptRegistryCheckImages + "=" + remote.RegistryCheckImages,
+		"--" + consts.CmdOptProfile + "=" + remote.Profile,
+		"--" + consts.CmdOptLonghornDataDirectory + "=" + strings.Join(remote.LonghornDataDirectories, consts.CmdOptSeperator),
+		"--" + consts.CmdOptCryptoBenchmark + "=" + commonutils.ConvertTypeToString(remote.CryptoBenchmark),
+		"--" + consts.CmdOptKnownIssues + "=" + remote.KnownIssues,
+	}
+
+	files := map[string]string{}
+	if remote.CustomChecksFile != "" {
+		files[consts.CmdOptCustomChecks] = remote.CustomChecksFile
+	}
+
+	return remote.sshRunner.Run(args, files), nil
+}
+
+// installOverSSH runs the preflight install with longhornctl-local on the SSH hosts.
+func (remote *Installer) installOverSSH() (map[string]*types.LogCollection, error) {
+	args := []string{
+		consts.SubCmdPreflight, consts.SubCmdInstall,
+		"--" + consts.CmdOptLogLevel + "=" + remote.LogLevel,
+		"--" + consts.CmdOptUpdatePackages + "=" + commonutils.ConvertTypeToString(remote.UpdatePackages),
+		"--" + consts.CmdOptPackageSource + "=" + remote.PackageSource,
+		"--" + consts.CmdOptResetCheckpoint + "=" + commonutils.ConvertTypeToString(remote.ResetCheckpoint),
+		"--" + consts.CmdOptTuneIscsid + "=" + commonutils.ConvertTypeToString(remote.TuneIscsid),
+		"--" + consts.CmdOptEnableSpdk + "=" + commonutils.ConvertTypeToString(remote.EnableSpdk),
+		"--" + consts.CmdOptSpdkOptions + "=" + remote.SpdkOptions,
+		"--" + consts.CmdOptHugePageSize + "=" + commonutils.ConvertTypeToString(remote.HugePageSize),
+		"--" + consts.CmdOptHugePageNodes + "=" + remote.HugePageNodes,
+		"--" + consts.CmdOptAllowPci + "=" + remote.AllowPci,
+		"--" + consts.CmdOptDriverOverride + "=" + remote.DriverOverride,
+	}
+
+	return remote.sshRunner.Run(args, nil), nil
+}
+
+// newSSHRunner returns the runner for the SSH backend, or nil for the DaemonSet backend.
+func newSSHRunner(options *types.SSHCmdOptions, maxParallel int) (*ssh.Runner, error) {
+	if options.Backend != consts.BackendSSH {
+		return nil, nil
+	}
+	return ssh.NewRunner(options, maxParallel)
+}
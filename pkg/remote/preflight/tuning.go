@@ -0,0 +1,247 @@
+package preflight
+
+import (
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeclient "k8s.io/client-go/kubernetes"
+
+	commonkube "github.com/longhorn/go-common-libs/kubernetes"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// TuningCmdOptions holds the options for the command.
+type TuningCmdOptions struct {
+	types.GlobalCmdOptions
+}
+
+// TuningChecker provide functions for checking the nodes against the sysctl and open files limit
+// tuning profile for the storage nodes.
+type TuningChecker struct {
+	TuningCmdOptions
+
+	kubeClient *kubeclient.Clientset
+
+	namespace string
+	appName   string // App name of the DaemonSet.
+}
+
+// Init initializes the TuningChecker.
+func (remote *TuningChecker) Init() error {
+	kubeClient, namespace, err := newTuningClient(&remote.TuningCmdOptions)
+	if err != nil {
+		return err
+	}
+	remote.kubeClient = kubeClient
+	remote.namespace = namespace
+	remote.appName = consts.AppNameTuningChecker
+
+	return nil
+}
+
+// Collect creates the DaemonSet checking the nodes, waits for it to complete,
+// and returns the result of each node keyed by the node name.
+func (remote *TuningChecker) Collect() (map[string]*types.LogCollection, error) {
+	return collectTuning(remote.kubeClient, &remote.TuningCmdOptions, remote.namespace, remote.newDaemonSet)
+}
+
+// Cleanup deletes the DaemonSet created for checking the nodes.
+func (remote *TuningChecker) Cleanup() error {
+	return commonkube.DeleteDaemonSet(remote.kubeClient, remote.namespace, remote.appName)
+}
+
+// newDaemonSet prepares a DaemonSet reading the sysctl parameters, the persisted profile and the
+// limits of the instance-manager processes from the host.
+func (remote *TuningChecker) newDaemonSet(nodeSelector map[string]string) *appsv1.DaemonSet {
+	return newTuningDaemonSet(&remote.TuningCmdOptions, remote.namespace, remote.appName, consts.SubCmdCheck, nodeSelector)
+}
+
+// TuningInstaller provide functions for applying the sysctl and open files limit tuning profile
+// to the nodes.
+type TuningInstaller struct {
+	TuningCmdOptions
+
+	kubeClient *kubeclient.Clientset
+
+	namespace string
+	appName   string // App name of the DaemonSet.
+}
+
+// Init initializes the TuningInstaller.
+func (remote *TuningInstaller) Init() error {
+	kubeClient, namespace, err := newTuningClient(&remote.TuningCmdOptions)
+	if err != nil {
+		return err
+	}
+	remote.kubeClient = kubeClient
+	remote.namespace = namespace
+	remote.appName = consts.AppNameTuningInstaller
+
+	return nil
+}
+
+// Collect creates the DaemonSet applying the profile, waits for it to complete,
+// and returns the result of each node keyed by the node name.
+func (remote *TuningInstaller) Collect() (map[string]*types.LogCollection, error) {
+	return collectTuning(remote.kubeClient, &remote.TuningCmdOptions, remote.namespace, remote.newDaemonSet)
+}
+
+// Cleanup deletes the DaemonSet created for applying the profile.
+func (remote *TuningInstaller) Cleanup() error {
+	return commonkube.DeleteDaemonSet(remote.kubeClient, remote.namespace, remote.appName)
+}
+
+// newDaemonSet prepares a DaemonSet writing the profile to the host and applying it in the host
+// mount namespace.
+func (remote *TuningInstaller) newDaemonSet(nodeSelector map[string]string) *appsv1.DaemonSet {
+	return newTuningDaemonSet(&remote.TuningCmdOptions, remote.namespace, remote.appName, consts.SubCmdInstall, nodeSelector)
+}
+
+func newTuningClient(options *TuningCmdOptions) (*kubeclient.Clientset, string, error) {
+	kubeClient, err := kubeutils.NewKubeClient("", options.KubeConfigPath)
+	if err != nil {
+		return nil, "", err
+	}
+
+	namespace := options.Namespace
+	if namespace == "" {
+		namespace = consts.LonghornNamespace
+	}
+	return kubeClient, namespace, nil
+}
+
+func collectTuning(kubeClient *kubeclient.Clientset, options *TuningCmdOptions, namespace string, newDaemonSet func(map[string]string) *appsv1.DaemonSet) (map[string]*types.LogCollection, error) {
+	restConfig, err := kubeutils.NewRestConfig("", options.KubeConfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := kubeutils.CreateNamespace(kubeClient, namespace); err != nil {
+		return nil, err
+	}
+
+	nodeSelector, err := kubeutils.ParseNodeSelector(options.NodeSelector)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %q argument", consts.CmdOptNodeSelector)
+	}
+	daemonSet := newDaemonSet(nodeSelector)
+	if err := kubeutils.SetPodOptions(&daemonSet.Spec.Template.Spec, &options.GlobalCmdOptions); err != nil {
+		return nil, err
+	}
+
+	kubeutils.SetRunMetadata(kubeClient, daemonSet)
+	kubeutils.LogManifest(daemonSet)
+	daemonSet, err = kubeutils.CreateDaemonSet(kubeClient, daemonSet, options.MinNodes)
+	if err != nil {
+		return nil, err
+	}
+
+	nodeCollections := map[string]*types.LogCollection{}
+	if err := collectNodeCollections(restConfig, kubeClient, daemonSet, nodeCollections); err != nil {
+		return nil, err
+	}
+
+	if err := kubeutils.AddSkippedNodes(kubeClient, daemonSet, nodeCollections); err != nil {
+		return nil, err
+	}
+
+	return nodeCollections, nil
+}
+
+// newTuningDaemonSet prepares a DaemonSet running "longhornctl-local <verb> tuning" on the nodes.
+// The installer mounts the host root filesystem writable and runs in the host PID namespace to
+// apply the profile.
+func newTuningDaemonSet(options *TuningCmdOptions, namespace, appName, verb string, nodeSelector map[string]string) *appsv1.DaemonSet {
+	install := verb == consts.SubCmdInstall
+	securityContext := kubeutils.NewSecurityContext(options.Privileged)
+	if install {
+		securityContext = kubeutils.NewSecurityContext(options.Privileged, kubeutils.CapabilitiesHostNamespaces)
+	}
+
+	outputFilePath := filepath.Join(consts.VolumeMountSharedDirectory, consts.FileNameOutputJSON)
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      appName,
+			Namespace: namespace,
+			Labels: map[string]string{
+				"app":                 appName,
+				consts.LabelManagedBy: consts.LabelValueManagedBy,
+			},
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app": appName,
+				},
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						"app":                 appName,
+						consts.LabelManagedBy: consts.LabelValueManagedBy,
+					},
+				},
+				Spec: corev1.PodSpec{
+					HostPID: install,
+					Containers: []corev1.Container{
+						{
+							Name:    consts.ContainerNameAgent,
+							Image:   options.Image,
+							Command: kubeutils.NewAgentCommand(consts.CmdLonghornctlLocal, verb, consts.SubCmdTuning),
+							Env: []corev1.EnvVar{
+								{
+									Name:  consts.EnvLogLevel,
+									Value: options.LogLevel,
+								},
+								{
+									Name:  consts.EnvOutputFilePath,
+									Value: outputFilePath,
+								},
+							},
+							SecurityContext: securityContext,
+							VolumeMounts: []corev1.VolumeMount{
+								{
+									Name:      consts.VolumeMountHostName,
+									MountPath: consts.VolumeMountHostDirectory,
+									ReadOnly:  !install,
+								},
+								{
+									Name:      consts.VolumeMountSharedName,
+									MountPath: consts.VolumeMountSharedDirectory,
+								},
+							},
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: consts.VolumeMountHostName,
+							VolumeSource: corev1.VolumeSource{
+								HostPath: &corev1.HostPathVolumeSource{
+									Path: "/",
+								},
+							},
+						},
+						{
+							Name: consts.VolumeMountSharedName,
+							VolumeSource: corev1.VolumeSource{
+								EmptyDir: &corev1.EmptyDirVolumeSource{},
+							},
+						},
+					},
+					NodeSelector: nodeSelector,
+				},
+			},
+			UpdateStrategy: appsv1.DaemonSetUpdateStrategy{
+				Type: appsv1.RollingUpdateDaemonSetStrategyType,
+			},
+		},
+	}
+}
@@ -0,0 +1,393 @@
+package preload
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/blang/semver/v4"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	kubeclient "k8s.io/client-go/kubernetes"
+
+	commonkube "github.com/longhorn/go-common-libs/kubernetes"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+const httpTimeout = time.Minute
+
+// imagePullFailureReasons are the reasons of a waiting container whose image cannot be pulled.
+var imagePullFailureReasons = map[string]bool{
+	"ErrImagePull":      true,
+	"ImagePullBackOff":  true,
+	"InvalidImageName":  true,
+	"ErrImageNeverPull": true,
+}
+
+// Preloader provide functions for pre-pulling the Longhorn images on the nodes.
+type Preloader struct {
+	PreloaderCmdOptions
+
+	kubeClient *kubeclient.Clientset
+
+	namespace string
+	appName   string // App name of the DaemonSet.
+
+	images []string // Images to pull on each node.
+}
+
+// PreloaderCmdOptions holds the options for the command.
+type PreloaderCmdOptions struct {
+	types.GlobalCmdOptions
+
+	Version    string        // Longhorn version to pull the images of.
+	ImagesFile string        // Path to a file listing the images to pull, one per line. Overrides the image list of the version.
+	Timeout    time.Duration // Maximum time to wait for the images to be pulled on a batch of nodes.
+
+	MaxParallel int // Maximum number of nodes to pull on at the same time. Pulls on all nodes at once when not positive.
+}
+
+// Validate validates the command options.
+func (remote *Preloader) Validate() error {
+	if remote.Version == "" && remote.ImagesFile == "" {
+		return errors.Errorf("Longhorn version (--%s) or images file (--%s) is required", consts.CmdOptVersion, consts.CmdOptImagesFile)
+	}
+
+	if remote.Version != "" {
+		if _, err := semver.ParseTolerant(remote.Version); err != nil {
+			return errors.Wrapf(err, "invalid version %q", remote.Version)
+		}
+	}
+
+	if remote.Timeout <= 0 {
+		return errors.Errorf("timeout (--%s) must be positive", consts.CmdOptTimeout)
+	}
+
+	return nil
+}
+
+// Init initializes the Preloader.
+func (remote *Preloader) Init() error {
+	kubeClient, err := kubeutils.NewKubeClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.kubeClient = kubeClient
+
+	remote.namespace = remote.Namespace
+	if remote.namespace == "" {
+		remote.namespace = consts.LonghornNamespace
+	}
+	remote.appName = consts.AppNameImagePreloader
+
+	remote.images, err = LoadImages(remote.Version, remote.ImagesFile)
+	if err != nil {
+		return err
+	}
+	logrus.Debugf("Images to pull: %v", remote.images)
+
+	return nil
+}
+
+// LoadImages returns the images listed in the images file, or in the image list
+// published with the Longhorn version when no file is given.
+func LoadImages(version, imagesFile string) ([]string, error) {
+	var data []byte
+	if imagesFile != "" {
+		fileData, err := os.ReadFile(imagesFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read images file %v", imagesFile)
+		}
+		data = fileData
+	} else {
+		if !strings.HasPrefix(version, "v") {
+			version = "v" + version
+		}
+
+		url := fmt.Sprintf(consts.LonghornImagesURL, version)
+		logrus.Debugf("Requesting %v", url)
+
+		httpClient := &http.Client{Timeout: httpTimeout}
+		resp, err := httpClient.Get(url)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get the images of Longhorn %v", version)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, errors.Errorf("failed to get the images of Longhorn %v: %v returned %v", version, url, resp.Status)
+		}
+
+		data, err = io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read the images of Longhorn %v", version)
+		}
+	}
+
+	images := ParseImageList(data)
+	if len(images) == 0 {
+		return nil, errors.New("no images found")
+	}
+	return images, nil
+}
+
+// ParseImageList returns the images listed one per line, skipping empty lines,
+// comments and duplicates.
+func ParseImageList(data []byte) []string {
+	images := []string{}
+	seen := map[string]bool{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		image := strings.TrimSpace(scanner.Text())
+		if image == "" || strings.HasPrefix(image, "#") || seen[image] {
+			continue
+		}
+
+		seen[image] = true
+		images = append(images, image)
+	}
+	return images
+}
+
+// Collect creates the DaemonSet pulling the images, in batches of nodes when MaxParallel is set.
+// It waits for the images to be pulled, and returns the pull result of each node keyed by the node name.
+func (remote *Preloader) Collect() (map[string]*types.LogCollection, error) {
+	if _, err := kubeutils.CreateNamespace(remote.kubeClient, remote.namespace); err != nil {
+		return nil, err
+	}
+
+	nodeSelector, err := kubeutils.ParseNodeSelector(remote.NodeSelector)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %q argument", consts.CmdOptNodeSelector)
+	}
+	newDaemonSet := remote.newDaemonSet(nodeSelector)
+	if err := kubeutils.SetPodOptions(&newDaemonSet.Spec.Template.Spec, &remote.GlobalCmdOptions); err != nil {
+		return nil, err
+	}
+
+	nodeCollections := map[string]*types.LogCollection{}
+	err = kubeutils.RunDaemonSetInBatches(remote.kubeClient, newDaemonSet, remote.MinNodes, remote.MaxParallel, func(daemonSet *appsv1.DaemonSet) error {
+		return remote.waitForImagePulls(daemonSet, nodeCollections)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return nodeCollections, nil
+}
+
+// waitForImagePulls waits until every pod of the DaemonSet has pulled all images or failed
+// to pull one, and adds the pull result of each node to the node collections. The nodes still
+// pulling when the timeout is reached are reported with an error.
+func (remote *Preloader) waitForImagePulls(daemonSet *appsv1.DaemonSet, nodeCollections map[string]*types.LogCollection) error {
+	ctx, cancel := context.WithTimeout(context.Background(), remote.Timeout)
+	defer cancel()
+
+	incompleteNodes := map[string]bool{}
+	err := wait.PollUntilContextCancel(ctx, 2*time.Second, true, func(ctx context.Context) (bool, error) {
+		currentDaemonSet, err := remote.kubeClient.AppsV1().DaemonSets(daemonSet.Namespace).Get(ctx, daemonSet.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+
+		pods, err := kubeutils.ListPods(remote.kubeClient, daemonSet.Namespace, labels.SelectorFromSet(daemonSet.Spec.Selector.MatchLabels))
+		if err != nil {
+			return false, err
+		}
+
+		if len(pods) < int(currentDaemonSet.Status.DesiredNumberScheduled) {
+			logrus.Trace("Waiting for DaemonSet to schedule pods")
+			return false, nil
+		}
+
+		isDone := true
+		for _, pod := range pods {
+			collection, isComplete := GetImagePullResult(pod, remote.images)
+			nodeCollections[pod.Spec.NodeName] = collection
+
+			if !isComplete {
+				incompleteNodes[pod.Spec.NodeName] = true
+				isDone = false
+				continue
+			}
+			delete(incompleteNodes, pod.Spec.NodeName)
+		}
+		return isDone, nil
+	})
+	if err == nil {
+		return nil
+	}
+
+	if ctx.Err() == nil || len(incompleteNodes) == 0 {
+		return errors.Wrapf(err, "failed waiting for DaemonSet %v to pull the images", daemonSet.Name)
+	}
+
+	for node := range incompleteNodes {
+		logrus.WithField("node", node).Warn("Timed out pulling images")
+		collection := nodeCollections[node]
+		collection.Error = append(collection.Error, fmt.Sprintf("Timed out after %v pulling the images", remote.Timeout))
+	}
+	return nil
+}
+
+// GetImagePullResult returns the pull result of the images on the node of the pod, and whether
+// the pod is done pulling. The pod is done when all images are pulled, or when an image
+// fails to be pulled, as the images are pulled in order by the init containers.
+//
+// The pull duration of an image is the time between the previous init container exiting
+// and the init container of the image starting, which the kubelet spends pulling the image.
+func GetImagePullResult(pod *corev1.Pod, images []string) (*types.LogCollection, bool) {
+	statuses := map[string]corev1.ContainerStatus{}
+	for _, status := range pod.Status.InitContainerStatuses {
+		statuses[status.Name] = status
+	}
+
+	var previousFinishedAt metav1.Time
+	if terminated := getTerminatedState(statuses[consts.ContainerNameInit]); terminated != nil {
+		previousFinishedAt = terminated.FinishedAt
+	}
+
+	collection := &types.LogCollection{}
+	for i, image := range images {
+		status, ok := statuses[getPullContainerName(i)]
+		if !ok {
+			return collection, false
+		}
+
+		if terminated := getTerminatedState(status); terminated != nil {
+			if previousFinishedAt.IsZero() {
+				collection.Info = append(collection.Info, fmt.Sprintf("Pulled image %s", image))
+			} else {
+				duration := terminated.StartedAt.Sub(previousFinishedAt.Time)
+				collection.Info = append(collection.Info, fmt.Sprintf("Pulled image %s in %v", image, duration))
+			}
+			previousFinishedAt = terminated.FinishedAt
+			continue
+		}
+
+		if status.State.Waiting != nil && imagePullFailureReasons[status.State.Waiting.Reason] {
+			collection.Error = append(collection.Error, fmt.Sprintf("Failed to pull image %s: %s", image, status.State.Waiting.Message))
+			for _, skippedImage := range images[i+1:] {
+				collection.Warn = append(collection.Warn, fmt.Sprintf("Skipped pulling image %s after the previous failure", skippedImage))
+			}
+			return collection, true
+		}
+
+		return collection, false
+	}
+
+	return collection, true
+}
+
+// getTerminatedState returns the terminated state of the container, including the last one when the
+// container is restarting. A terminated container means its image was pulled.
+func getTerminatedState(status corev1.ContainerStatus) *corev1.ContainerStateTerminated {
+	if status.State.Terminated != nil {
+		return status.State.Terminated
+	}
+	return status.LastTerminationState.Terminated
+}
+
+func getPullContainerName(index int) string {
+	return fmt.Sprintf("%s%d", consts.ContainerNamePullPrefix, index)
+}
+
+// Cleanup deletes the DaemonSet created for pulling the images.
+func (remote *Preloader) Cleanup() error {
+	return commonkube.DeleteDaemonSet(remote.kubeClient, remote.namespace, remote.appName)
+}
+
+// newDaemonSet prepares a DaemonSet pulling the images with an init container for each image.
+// The images may have no shell, so the init containers run the static longhornctl-local binary
+// copied from the CLI image, which exits right away.
+func (remote *Preloader) newDaemonSet(nodeSelector map[string]string) *appsv1.DaemonSet {
+	localBinaryPath := consts.VolumeMountSharedDirectory + "/" + consts.CmdLonghornctlLocal
+	sharedVolumeMounts := []corev1.VolumeMount{
+		{
+			Name:      consts.VolumeMountSharedName,
+			MountPath: consts.VolumeMountSharedDirectory,
+		},
+	}
+
+	initContainers := []corev1.Container{
+		{
+			Name:         consts.ContainerNameInit,
+			Image:        remote.Image,
+			Command:      []string{"sh", "-c", fmt.Sprintf(`cp "$(command -v %s)" %s`, consts.CmdLonghornctlLocal, localBinaryPath)},
+			VolumeMounts: sharedVolumeMounts,
+		},
+	}
+	for i, image := range remote.images {
+		initContainers = append(initContainers, corev1.Container{
+			Name:            getPullContainerName(i),
+			Image:           image,
+			ImagePullPolicy: corev1.PullIfNotPresent,
+			Command:         []string{localBinaryPath, consts.SubCmdVersion},
+			VolumeMounts:    sharedVolumeMounts,
+		})
+	}
+
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      remote.appName,
+			Namespace: remote.namespace,
+			Labels: map[string]string{
+				"app":                 remote.appName,
+				consts.LabelManagedBy: consts.LabelValueManagedBy,
+			},
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app": remote.appName,
+				},
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						"app":                 remote.appName,
+						consts.LabelManagedBy: consts.LabelValueManagedBy,
+					},
+				},
+				Spec: corev1.PodSpec{
+					InitContainers: initContainers,
+					Containers: []corev1.Container{
+						{
+							Name:  consts.ContainerNamePause,
+							Image: consts.ImagePause,
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: consts.VolumeMountSharedName,
+							VolumeSource: corev1.VolumeSource{
+								EmptyDir: &corev1.EmptyDirVolumeSource{},
+							},
+						},
+					},
+					NodeSelector: nodeSelector,
+				},
+			},
+			UpdateStrategy: appsv1.DaemonSetUpdateStrategy{
+				Type: appsv1.RollingUpdateDaemonSetStrategyType,
+			},
+		},
+	}
+}
@@ -0,0 +1,99 @@
+package preload
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func TestParseImageList(t *testing.T) {
+	data := []byte(`longhornio/longhorn-manager:v1.7.2
+
+# CSI sidecars
+longhornio/csi-attacher:v4.6.1
+  longhornio/longhorn-manager:v1.7.2
+`)
+
+	expected := []string{"longhornio/longhorn-manager:v1.7.2", "longhornio/csi-attacher:v4.6.1"}
+	if images := ParseImageList(data); !reflect.DeepEqual(images, expected) {
+		t.Errorf("expected %v, got %v", expected, images)
+	}
+}
+
+func TestGetImagePullResult(t *testing.T) {
+	start := time.Date(2024, 7, 16, 9, 0, 0, 0, time.UTC)
+	terminated := func(startedAt, finishedAt time.Duration) corev1.ContainerState {
+		return corev1.ContainerState{
+			Terminated: &corev1.ContainerStateTerminated{
+				StartedAt:  metav1.NewTime(start.Add(startedAt)),
+				FinishedAt: metav1.NewTime(start.Add(finishedAt)),
+			},
+		}
+	}
+
+	images := []string{"image-a", "image-b", "image-c"}
+
+	tests := []struct {
+		name             string
+		statuses         []corev1.ContainerStatus
+		expected         *types.LogCollection
+		expectedComplete bool
+	}{
+		{
+			name: "all pulled",
+			statuses: []corev1.ContainerStatus{
+				{Name: consts.ContainerNameInit, State: terminated(0, time.Second)},
+				{Name: "pull-0", State: terminated(4*time.Second, 5*time.Second)},
+				{Name: "pull-1", State: terminated(6*time.Second, 7*time.Second)},
+				{Name: "pull-2", LastTerminationState: terminated(17*time.Second, 18*time.Second)},
+			},
+			expected: &types.LogCollection{
+				Info: []string{"Pulled image image-a in 3s", "Pulled image image-b in 1s", "Pulled image image-c in 10s"},
+			},
+			expectedComplete: true,
+		},
+		{
+			name: "pulling",
+			statuses: []corev1.ContainerStatus{
+				{Name: consts.ContainerNameInit, State: terminated(0, time.Second)},
+				{Name: "pull-0", State: terminated(4*time.Second, 5*time.Second)},
+				{Name: "pull-1", State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "PodInitializing"}}},
+			},
+			expected: &types.LogCollection{
+				Info: []string{"Pulled image image-a in 3s"},
+			},
+		},
+		{
+			name: "failed",
+			statuses: []corev1.ContainerStatus{
+				{Name: consts.ContainerNameInit, State: terminated(0, time.Second)},
+				{Name: "pull-0", State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff", Message: "not found"}}},
+			},
+			expected: &types.LogCollection{
+				Error: []string{"Failed to pull image image-a: not found"},
+				Warn:  []string{"Skipped pulling image image-b after the previous failure", "Skipped pulling image image-c after the previous failure"},
+			},
+			expectedComplete: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pod := &corev1.Pod{Status: corev1.PodStatus{InitContainerStatuses: test.statuses}}
+
+			collection, isComplete := GetImagePullResult(pod, images)
+			if isComplete != test.expectedComplete {
+				t.Errorf("expected complete %v, got %v", test.expectedComplete, isComplete)
+			}
+			if !reflect.DeepEqual(collection, test.expected) {
+				t.Errorf("expected %+v, got %+v", test.expected, collection)
+			}
+		})
+	}
+}
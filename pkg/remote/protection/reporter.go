@@ -0,0 +1,232 @@
+package protection
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	longhorn "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+	lhclient "github.com/longhorn/longhorn-manager/k8s/pkg/client/clientset/versioned"
+	lhmgrtypes "github.com/longhorn/longhorn-manager/types"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// Reporter provide functions for reporting the volumes not protected by recurring jobs and recent
+// backups.
+type Reporter struct {
+	ReporterCmdOptions
+
+	longhornClient *lhclient.Clientset
+}
+
+// ReporterCmdOptions holds the options for the command.
+type ReporterCmdOptions struct {
+	types.GlobalCmdOptions
+
+	LonghornNamespace string
+	VolumeNames       []string      // Volumes to report. Defaults to all volumes.
+	MaxBackupAge      time.Duration // Maximum age of the latest completed backup, 0 for no maximum.
+	MaxBackupFailures int           // Maximum failed backups since the latest completed backup, 0 for no maximum.
+}
+
+// Validate validates the command options.
+func (remote *Reporter) Validate() error {
+	if remote.MaxBackupAge < 0 {
+		return errors.Errorf("invalid --%s %v, it must not be negative", consts.CmdOptMaxBackupAge, remote.MaxBackupAge)
+	}
+	if remote.MaxBackupFailures < 0 {
+		return errors.Errorf("invalid --%s %v, it must not be negative", consts.CmdOptMaxBackupFailures, remote.MaxBackupFailures)
+	}
+	return nil
+}
+
+// Init initializes the Reporter.
+func (remote *Reporter) Init() error {
+	longhornClient, err := kubeutils.NewLonghornClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.longhornClient = longhornClient
+
+	return nil
+}
+
+// Run returns the protection of the volumes, sorted by name.
+func (remote *Reporter) Run() ([]types.ProtectionVolume, error) {
+	ctx := context.Background()
+	client := remote.longhornClient.LonghornV1beta2()
+
+	volumeList, err := client.Volumes(remote.LonghornNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list volumes")
+	}
+	recurringJobList, err := client.RecurringJobs(remote.LonghornNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list recurring jobs")
+	}
+	backupList, err := client.Backups(remote.LonghornNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list backups")
+	}
+
+	backups := map[string][]longhorn.Backup{}
+	for _, backup := range backupList.Items {
+		volumeName := backup.Status.VolumeName
+		if volumeName == "" {
+			volumeName = backup.Labels[lhmgrtypes.LonghornLabelBackupVolume]
+		}
+		backups[volumeName] = append(backups[volumeName], backup)
+	}
+
+	volumes := map[string]*longhorn.Volume{}
+	for i := range volumeList.Items {
+		volumes[volumeList.Items[i].Name] = &volumeList.Items[i]
+	}
+
+	volumeNames := remote.VolumeNames
+	if len(volumeNames) == 0 {
+		for name := range volumes {
+			volumeNames = append(volumeNames, name)
+		}
+	}
+	sort.Strings(volumeNames)
+
+	now := time.Now()
+	result := make([]types.ProtectionVolume, 0, len(volumeNames))
+	for _, name := range volumeNames {
+		volume, ok := volumes[name]
+		if !ok {
+			return nil, errors.Errorf("volume %v is not found", name)
+		}
+		result = append(result, remote.newProtectionVolume(volume, recurringJobList.Items, backups[name], now))
+	}
+	return result, nil
+}
+
+// newProtectionVolume returns the recurring jobs and the latest backups of the volume. A volume is
+// flagged when no recurring snapshot or backup job applies to it, when its latest completed backup
+// is older than the maximum age, and when its backups failed repeatedly since then.
+func (remote *Reporter) newProtectionVolume(volume *longhorn.Volume, recurringJobs []longhorn.RecurringJob, backups []longhorn.Backup, now time.Time) types.ProtectionVolume {
+	result := types.ProtectionVolume{
+		Name:         volume.Name,
+		State:        string(volume.Status.State),
+		SnapshotJobs: []string{},
+		BackupJobs:   []string{},
+	}
+
+	for _, job := range getVolumeRecurringJobs(volume.Labels, recurringJobs) {
+		switch job.Spec.Task {
+		case longhorn.RecurringJobTypeSnapshot, longhorn.RecurringJobTypeSnapshotForceCreate:
+			result.SnapshotJobs = append(result.SnapshotJobs, job.Name)
+		case longhorn.RecurringJobTypeBackup, longhorn.RecurringJobTypeBackupForceCreate:
+			result.BackupJobs = append(result.BackupJobs, job.Name)
+		}
+	}
+	if len(result.SnapshotJobs) == 0 && len(result.BackupJobs) == 0 {
+		result.Issues = append(result.Issues, "No recurring snapshot or backup job")
+	}
+
+	var lastBackupAt time.Time
+	for _, backup := range sortBackups(backups) {
+		switch backup.Status.State {
+		case longhorn.BackupStateCompleted:
+			result.LastBackup = backup.Name
+			lastBackupAt = getBackupTime(&backup)
+			result.FailedBackups = 0
+			result.LastBackupError = ""
+		case longhorn.BackupStateError:
+			result.FailedBackups++
+			result.LastBackupError = backup.Status.Error
+		}
+	}
+	if result.LastBackup != "" {
+		result.LastBackupAt = lastBackupAt.UTC().Format(time.RFC3339)
+	}
+
+	if remote.MaxBackupAge > 0 {
+		switch {
+		case result.LastBackup == "":
+			result.Issues = append(result.Issues, "No completed backup")
+		case now.Sub(lastBackupAt) > remote.MaxBackupAge:
+			result.Issues = append(result.Issues, fmt.Sprintf("Latest completed backup %v is older than %v", result.LastBackup, remote.MaxBackupAge))
+		}
+	}
+	if remote.MaxBackupFailures > 0 && result.FailedBackups >= remote.MaxBackupFailures {
+		issue := fmt.Sprintf("%d backups failed since the latest completed backup", result.FailedBackups)
+		if result.LastBackupError != "" {
+			issue += ": " + result.LastBackupError
+		}
+		result.Issues = append(result.Issues, issue)
+	}
+
+	return result
+}
+
+// getVolumeRecurringJobs returns the recurring jobs applying to a volume with the labels, sorted
+// by name. The jobs apply to the volume by name or by group, and the jobs of the default group
+// apply to the volumes without recurring job labels.
+func getVolumeRecurringJobs(labels map[string]string, recurringJobs []longhorn.RecurringJob) []longhorn.RecurringJob {
+	jobPrefix := fmt.Sprintf(lhmgrtypes.LonghornLabelRecurringJobKeyPrefixFmt, lhmgrtypes.LonghornLabelRecurringJob) + "/"
+	groupPrefix := fmt.Sprintf(lhmgrtypes.LonghornLabelRecurringJobKeyPrefixFmt, lhmgrtypes.LonghornLabelRecurringJobGroup) + "/"
+
+	jobNames := map[string]struct{}{}
+	groups := map[string]struct{}{}
+	for key, value := range labels {
+		if !lhmgrtypes.IsRecurringJobLabel(key) || value != lhmgrtypes.LonghornLabelValueEnabled {
+			continue
+		}
+		if strings.HasPrefix(key, jobPrefix) {
+			jobNames[strings.TrimPrefix(key, jobPrefix)] = struct{}{}
+		} else if strings.HasPrefix(key, groupPrefix) {
+			groups[strings.TrimPrefix(key, groupPrefix)] = struct{}{}
+		}
+	}
+	if len(jobNames) == 0 && len(groups) == 0 {
+		groups[longhorn.RecurringJobGroupDefault] = struct{}{}
+	}
+
+	result := []longhorn.RecurringJob{}
+	for _, job := range recurringJobs {
+		_, applies := jobNames[job.Name]
+		for _, group := range job.Spec.Groups {
+			if _, ok := groups[group]; ok {
+				applies = true
+			}
+		}
+		if applies {
+			result = append(result, job)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// sortBackups returns the backups sorted from the oldest to the latest.
+func sortBackups(backups []longhorn.Backup) []longhorn.Backup {
+	sorted := append([]longhorn.Backup{}, backups...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return getBackupTime(&sorted[i]).Before(getBackupTime(&sorted[j]))
+	})
+	return sorted
+}
+
+// getBackupTime returns the time the backup was created in the backup target, or the time the
+// Backup was created when it is not known.
+func getBackupTime(backup *longhorn.Backup) time.Time {
+	for _, value := range []string{backup.Status.BackupCreatedAt, backup.Status.SnapshotCreatedAt} {
+		if createdAt, err := time.Parse(time.RFC3339, value); err == nil {
+			return createdAt
+		}
+	}
+	return backup.CreationTimestamp.Time
+}
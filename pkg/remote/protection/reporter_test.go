@@ -0,0 +1,143 @@
+package protection
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	longhorn "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+)
+
+func TestGetVolumeRecurringJobs(t *testing.T) {
+	newJob := func(name string, task longhorn.RecurringJobType, groups ...string) longhorn.RecurringJob {
+		job := longhorn.RecurringJob{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		job.Spec.Task = task
+		job.Spec.Groups = groups
+		return job
+	}
+	jobs := []longhorn.RecurringJob{
+		newJob("snapshot-hourly", longhorn.RecurringJobTypeSnapshot, "default"),
+		newJob("backup-daily", longhorn.RecurringJobTypeBackup, "critical"),
+		newJob("trim-weekly", longhorn.RecurringJobTypeFilesystemTrim),
+	}
+
+	tests := map[string]struct {
+		labels   map[string]string
+		expected []string
+	}{
+		"default group": {
+			labels:   map[string]string{"app": "db"},
+			expected: []string{"snapshot-hourly"},
+		},
+		"by group": {
+			labels:   map[string]string{"recurring-job-group.longhorn.io/critical": "enabled"},
+			expected: []string{"backup-daily"},
+		},
+		"by name": {
+			labels:   map[string]string{"recurring-job.longhorn.io/trim-weekly": "enabled", "recurring-job.longhorn.io/source": "enabled"},
+			expected: []string{"trim-weekly"},
+		},
+		"by name and group": {
+			labels: map[string]string{
+				"recurring-job.longhorn.io/snapshot-hourly": "enabled",
+				"recurring-job-group.longhorn.io/critical":  "enabled",
+			},
+			expected: []string{"backup-daily", "snapshot-hourly"},
+		},
+		"unknown job": {
+			labels:   map[string]string{"recurring-job.longhorn.io/backup-monthly": "enabled"},
+			expected: []string{},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			names := []string{}
+			for _, job := range getVolumeRecurringJobs(test.labels, jobs) {
+				names = append(names, job.Name)
+			}
+			if !reflect.DeepEqual(names, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, names)
+			}
+		})
+	}
+}
+
+func TestNewProtectionVolume(t *testing.T) {
+	now := time.Date(2024, 7, 16, 12, 0, 0, 0, time.UTC)
+	newBackup := func(name string, state longhorn.BackupState, createdAt time.Time) longhorn.Backup {
+		backup := longhorn.Backup{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		backup.Status.State = state
+		backup.Status.BackupCreatedAt = createdAt.Format(time.RFC3339)
+		if state == longhorn.BackupStateError {
+			backup.Status.Error = "backup target is unavailable"
+		}
+		return backup
+	}
+	backupJob := longhorn.RecurringJob{ObjectMeta: metav1.ObjectMeta{Name: "backup-daily"}}
+	backupJob.Spec.Task = longhorn.RecurringJobTypeBackup
+	backupJob.Spec.Groups = []string{longhorn.RecurringJobGroupDefault}
+
+	tests := map[string]struct {
+		recurringJobs         []longhorn.RecurringJob
+		backups               []longhorn.Backup
+		expectedLastBackup    string
+		expectedFailedBackups int
+		expectedIssues        int
+	}{
+		"protected": {
+			recurringJobs:      []longhorn.RecurringJob{backupJob},
+			backups:            []longhorn.Backup{newBackup("backup-1", longhorn.BackupStateCompleted, now.Add(-time.Hour))},
+			expectedLastBackup: "backup-1",
+		},
+		"no recurring job nor backup": {
+			expectedIssues: 2,
+		},
+		"outdated backup": {
+			recurringJobs:      []longhorn.RecurringJob{backupJob},
+			backups:            []longhorn.Backup{newBackup("backup-1", longhorn.BackupStateCompleted, now.Add(-48*time.Hour))},
+			expectedLastBackup: "backup-1",
+			expectedIssues:     1,
+		},
+		"failing backups": {
+			recurringJobs: []longhorn.RecurringJob{backupJob},
+			backups: []longhorn.Backup{
+				newBackup("backup-3", longhorn.BackupStateError, now.Add(-time.Hour)),
+				newBackup("backup-1", longhorn.BackupStateCompleted, now.Add(-3*time.Hour)),
+				newBackup("backup-2", longhorn.BackupStateError, now.Add(-2*time.Hour)),
+			},
+			expectedLastBackup:    "backup-1",
+			expectedFailedBackups: 2,
+			expectedIssues:        1,
+		},
+		"failure before the latest backup": {
+			recurringJobs: []longhorn.RecurringJob{backupJob},
+			backups: []longhorn.Backup{
+				newBackup("backup-1", longhorn.BackupStateError, now.Add(-3*time.Hour)),
+				newBackup("backup-2", longhorn.BackupStateError, now.Add(-2*time.Hour)),
+				newBackup("backup-3", longhorn.BackupStateCompleted, now.Add(-time.Hour)),
+				newBackup("backup-4", longhorn.BackupStateInProgress, now),
+			},
+			expectedLastBackup: "backup-3",
+		},
+	}
+
+	reporter := &Reporter{ReporterCmdOptions: ReporterCmdOptions{MaxBackupAge: 24 * time.Hour, MaxBackupFailures: 2}}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			volume := &longhorn.Volume{ObjectMeta: metav1.ObjectMeta{Name: "vol"}}
+			result := reporter.newProtectionVolume(volume, test.recurringJobs, test.backups, now)
+			if result.LastBackup != test.expectedLastBackup {
+				t.Errorf("expected last backup %q, got %q", test.expectedLastBackup, result.LastBackup)
+			}
+			if result.FailedBackups != test.expectedFailedBackups {
+				t.Errorf("expected %d failed backups, got %d", test.expectedFailedBackups, result.FailedBackups)
+			}
+			if len(result.Issues) != test.expectedIssues {
+				t.Errorf("expected %d issues, got %v", test.expectedIssues, result.Issues)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package rebuild
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	longhorn "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+	lhclient "github.com/longhorn/longhorn-manager/k8s/pkg/client/clientset/versioned"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// StatusGetter provide functions for reporting the in-progress replica rebuilds, with their
+// throughput and remaining time.
+type StatusGetter struct {
+	StatusGetterCmdOptions
+
+	longhornClient *lhclient.Clientset
+}
+
+// StatusGetterCmdOptions holds the options for the command.
+type StatusGetterCmdOptions struct {
+	types.GlobalCmdOptions
+
+	LonghornNamespace string
+	NodeName          string        // Only report the rebuilds of the replicas on the node.
+	Interval          time.Duration // Interval between the two samples of the progress.
+}
+
+// rebuildSample is the progress of a rebuild, keyed by the replica being rebuilt.
+type rebuildSample map[string]types.ReplicaRebuild
+
+// Validate validates the command options.
+func (remote *StatusGetter) Validate() error {
+	if remote.Interval < 0 {
+		return errors.Errorf("--%s must not be negative", consts.CmdOptInterval)
+	}
+	return nil
+}
+
+// Init initializes the StatusGetter.
+func (remote *StatusGetter) Init() error {
+	longhornClient, err := kubeutils.NewLonghornClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.longhornClient = longhornClient
+
+	return nil
+}
+
+// Run samples the progress of the rebuilds twice, the interval apart, and returns the rebuilds of
+// the second sample sorted by volume and replica. The throughput is computed from the progress
+// between the samples, so it is unknown for the rebuilds starting in between, and when the interval
+// is 0.
+func (remote *StatusGetter) Run(ctx context.Context) ([]types.ReplicaRebuild, error) {
+	previous := rebuildSample{}
+	start := time.Now()
+	if remote.Interval > 0 {
+		sample, err := remote.sample(ctx)
+		if err != nil {
+			return nil, err
+		}
+		previous = sample
+
+		select {
+		case <-ctx.Done():
+			return nil, errors.Wrap(ctx.Err(), "rebuild status interrupted")
+		case <-time.After(remote.Interval):
+		}
+	}
+
+	current, err := remote.sample(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return estimateRebuilds(previous, current, time.Since(start)), nil
+}
+
+// sample returns the progress of the in-progress rebuilds.
+func (remote *StatusGetter) sample(ctx context.Context) (rebuildSample, error) {
+	client := remote.longhornClient.LonghornV1beta2()
+
+	engineList, err := client.Engines(remote.LonghornNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list engines")
+	}
+	replicaList, err := client.Replicas(remote.LonghornNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list replicas")
+	}
+	volumeList, err := client.Volumes(remote.LonghornNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list volumes")
+	}
+
+	replicas := map[string]*longhorn.Replica{}
+	for i := range replicaList.Items {
+		replicas[replicaList.Items[i].Name] = &replicaList.Items[i]
+	}
+	sizes := map[string]int64{}
+	for _, volume := range volumeList.Items {
+		sizes[volume.Name] = volume.Spec.Size
+	}
+
+	sample := rebuildSample{}
+	for i := range engineList.Items {
+		for _, rebuild := range getEngineRebuilds(&engineList.Items[i], replicas, sizes) {
+			if remote.NodeName != "" && rebuild.Node != remote.NodeName {
+				continue
+			}
+			sample[rebuild.Replica] = rebuild
+		}
+	}
+	return sample, nil
+}
+
+// getEngineRebuilds returns the in-progress rebuilds of the engine. The rebuild status is keyed by
+// the address of the replica being rebuilt, which is mapped back to the replica with the replica
+// address map of the engine.
+func getEngineRebuilds(engine *longhorn.Engine, replicas map[string]*longhorn.Replica, sizes map[string]int64) []types.ReplicaRebuild {
+	replicaNames := map[string]string{}
+	for name, address := range engine.Status.CurrentReplicaAddressMap {
+		replicaNames[trimAddressScheme(address)] = name
+	}
+	for name, address := range engine.Spec.ReplicaAddressMap {
+		if _, ok := replicaNames[trimAddressScheme(address)]; !ok {
+			replicaNames[trimAddressScheme(address)] = name
+		}
+	}
+
+	rebuilds := []types.ReplicaRebuild{}
+	for address, status := range engine.Status.RebuildStatus {
+		if status == nil || !status.IsRebuilding {
+			continue
+		}
+
+		rebuild := types.ReplicaRebuild{
+			Volume:    engine.Spec.VolumeName,
+			Replica:   replicaNames[trimAddressScheme(address)],
+			Source:    replicaNames[trimAddressScheme(status.FromReplicaAddress)],
+			SizeBytes: sizes[engine.Spec.VolumeName],
+			Progress:  status.Progress,
+			Error:     status.Error,
+		}
+		if rebuild.Replica == "" {
+			rebuild.Replica = address
+		}
+		if rebuild.Source == "" {
+			rebuild.Source = status.FromReplicaAddress
+		}
+		if replica, ok := replicas[rebuild.Replica]; ok {
+			rebuild.Node = replica.Spec.NodeID
+		}
+		rebuilds = append(rebuilds, rebuild)
+	}
+	return rebuilds
+}
+
+// estimateRebuilds returns the rebuilds of the current sample, with the throughput and remaining
+// time estimated from their progress since the previous sample.
+func estimateRebuilds(previous, current rebuildSample, elapsed time.Duration) []types.ReplicaRebuild {
+	rebuilds := make([]types.ReplicaRebuild, 0, len(current))
+	for replica, rebuild := range current {
+		before, ok := previous[replica]
+		if ok && elapsed > 0 && rebuild.Progress > before.Progress {
+			rebuiltBytes := rebuild.SizeBytes * int64(rebuild.Progress-before.Progress) / 100
+			rebuild.ThroughputBytesPerSecond = int64(float64(rebuiltBytes) / elapsed.Seconds())
+		}
+		if rebuild.ThroughputBytesPerSecond > 0 {
+			remainingBytes := rebuild.SizeBytes * int64(100-rebuild.Progress) / 100
+			rebuild.ETASeconds = remainingBytes / rebuild.ThroughputBytesPerSecond
+		}
+		rebuilds = append(rebuilds, rebuild)
+	}
+
+	sort.Slice(rebuilds, func(i, j int) bool {
+		if rebuilds[i].Volume != rebuilds[j].Volume {
+			return rebuilds[i].Volume < rebuilds[j].Volume
+		}
+		return rebuilds[i].Replica < rebuilds[j].Replica
+	})
+	return rebuilds
+}
+
+// trimAddressScheme returns the replica address without the tcp:// scheme of the rebuild status.
+func trimAddressScheme(address string) string {
+	return strings.TrimPrefix(address, "tcp://")
+}
@@ -0,0 +1,81 @@
+package rebuild
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	longhorn "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func TestGetEngineRebuilds(t *testing.T) {
+	engine := &longhorn.Engine{}
+	engine.Spec.VolumeName = "vol-1"
+	engine.Spec.ReplicaAddressMap = map[string]string{
+		"vol-1-r-1": "10.42.1.5:10000",
+		"vol-1-r-2": "10.42.2.7:10000",
+	}
+	engine.Status.CurrentReplicaAddressMap = map[string]string{
+		"vol-1-r-1": "10.42.1.5:10000",
+	}
+	engine.Status.RebuildStatus = map[string]*longhorn.RebuildStatus{
+		"tcp://10.42.2.7:10000": {IsRebuilding: true, Progress: 42, FromReplicaAddress: "tcp://10.42.1.5:10000"},
+		"tcp://10.42.3.9:10000": {IsRebuilding: false, Progress: 100},
+	}
+
+	replica := &longhorn.Replica{ObjectMeta: metav1.ObjectMeta{Name: "vol-1-r-2"}}
+	replica.Spec.NodeID = "node-2"
+
+	rebuilds := getEngineRebuilds(engine, map[string]*longhorn.Replica{"vol-1-r-2": replica}, map[string]int64{"vol-1": 10 << 30})
+	expected := []types.ReplicaRebuild{
+		{Volume: "vol-1", Replica: "vol-1-r-2", Node: "node-2", Source: "vol-1-r-1", SizeBytes: 10 << 30, Progress: 42},
+	}
+	if !reflect.DeepEqual(rebuilds, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, rebuilds)
+	}
+}
+
+func TestEstimateRebuilds(t *testing.T) {
+	const size = 100 << 20
+
+	tests := map[string]struct {
+		previous           rebuildSample
+		current            rebuildSample
+		expectedThroughput int64
+		expectedETA        int64
+	}{
+		"progressing": {
+			previous:           rebuildSample{"r-1": {Replica: "r-1", SizeBytes: size, Progress: 10}},
+			current:            rebuildSample{"r-1": {Replica: "r-1", SizeBytes: size, Progress: 20}},
+			expectedThroughput: 1 << 20,
+			expectedETA:        80,
+		},
+		"stalled": {
+			previous: rebuildSample{"r-1": {Replica: "r-1", SizeBytes: size, Progress: 20}},
+			current:  rebuildSample{"r-1": {Replica: "r-1", SizeBytes: size, Progress: 20}},
+		},
+		"started between the samples": {
+			previous: rebuildSample{},
+			current:  rebuildSample{"r-1": {Replica: "r-1", SizeBytes: size, Progress: 5}},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			rebuilds := estimateRebuilds(test.previous, test.current, 10*time.Second)
+			if len(rebuilds) != 1 {
+				t.Fatalf("expected 1 rebuild, got %+v", rebuilds)
+			}
+			if rebuilds[0].ThroughputBytesPerSecond != test.expectedThroughput {
+				t.Fatalf("expected throughput %v, got %v", test.expectedThroughput, rebuilds[0].ThroughputBytesPerSecond)
+			}
+			if rebuilds[0].ETASeconds != test.expectedETA {
+				t.Fatalf("expected ETA %v, got %v", test.expectedETA, rebuilds[0].ETASeconds)
+			}
+		})
+	}
+}
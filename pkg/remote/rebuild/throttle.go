@@ -0,0 +1,163 @@
+package rebuild
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	lhclient "github.com/longhorn/longhorn-manager/k8s/pkg/client/clientset/versioned"
+	lhmgrtypes "github.com/longhorn/longhorn-manager/types"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// Throttler provide functions for throttling the replica rebuilds with the Longhorn settings, and
+// restoring the settings afterwards.
+type Throttler struct {
+	ThrottlerCmdOptions
+
+	longhornClient *lhclient.Clientset
+
+	values map[string]string // Values of the settings to change, keyed by the setting name.
+}
+
+// ThrottlerCmdOptions holds the options for the command.
+type ThrottlerCmdOptions struct {
+	types.GlobalCmdOptions
+
+	LonghornNamespace string
+	Concurrency       int    // Concurrent rebuilds per node, unchanged when negative.
+	Limit             string // Rebuild bandwidth per second, unchanged when empty.
+	Reset             bool   // Restore the settings changed by the previous throttles.
+}
+
+// Validate validates the command options, and computes the values of the settings to change.
+func (remote *Throttler) Validate() error {
+	throttle := remote.Concurrency >= 0 || remote.Limit != ""
+	if remote.Reset && throttle {
+		return errors.Errorf("--%s cannot be used with --%s or --%s", consts.CmdOptReset, consts.CmdOptConcurrency, consts.CmdOptLimit)
+	}
+	if !remote.Reset && !throttle {
+		return errors.Errorf("--%s, --%s or --%s is required", consts.CmdOptConcurrency, consts.CmdOptLimit, consts.CmdOptReset)
+	}
+
+	remote.values = map[string]string{}
+	if remote.Concurrency >= 0 {
+		remote.values[string(lhmgrtypes.SettingNameConcurrentReplicaRebuildPerNodeLimit)] = strconv.Itoa(remote.Concurrency)
+	}
+	if remote.Limit != "" {
+		limit, err := parseBandwidthLimit(remote.Limit)
+		if err != nil {
+			return err
+		}
+		remote.values[consts.SettingNameReplicaRebuildingBandwidthLimit] = strconv.FormatInt(limit, 10)
+	}
+
+	return nil
+}
+
+// Init initializes the Throttler.
+func (remote *Throttler) Init() error {
+	longhornClient, err := kubeutils.NewLonghornClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.longhornClient = longhornClient
+
+	return nil
+}
+
+// Run updates the settings, and returns their changes sorted by the setting name. The value of a
+// setting before the first throttle is kept in an annotation of the setting, so throttling again
+// does not lose it, and the reset restores it.
+func (remote *Throttler) Run() ([]types.RebuildSettingChange, error) {
+	ctx := context.Background()
+
+	names := []string{
+		string(lhmgrtypes.SettingNameConcurrentReplicaRebuildPerNodeLimit),
+		consts.SettingNameReplicaRebuildingBandwidthLimit,
+	}
+
+	changes := []types.RebuildSettingChange{}
+	for _, name := range names {
+		value, ok := remote.values[name]
+		if !remote.Reset && !ok {
+			continue
+		}
+
+		change, err := remote.updateSetting(ctx, name, value)
+		if err != nil {
+			return changes, err
+		}
+		if change != nil {
+			changes = append(changes, *change)
+		}
+	}
+	return changes, nil
+}
+
+// updateSetting sets the value of the setting, or restores its value before the throttle on reset.
+// It returns nil when the setting is left unchanged.
+func (remote *Throttler) updateSetting(ctx context.Context, name, value string) (*types.RebuildSettingChange, error) {
+	settings := remote.longhornClient.LonghornV1beta2().Settings(remote.LonghornNamespace)
+
+	setting, err := settings.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) && remote.Reset {
+			return nil, nil
+		}
+		if apierrors.IsNotFound(err) {
+			return nil, errors.Errorf("setting %v is not found, the Longhorn version does not support it", name)
+		}
+		return nil, errors.Wrapf(err, "failed to get setting %v", name)
+	}
+
+	previous, throttled := setting.Annotations[consts.AnnotationRebuildThrottlePrevious]
+	change := &types.RebuildSettingChange{Name: name, Previous: setting.Value, Value: value}
+	if remote.Reset {
+		if !throttled {
+			return nil, nil
+		}
+		change.Value = previous
+		delete(setting.Annotations, consts.AnnotationRebuildThrottlePrevious)
+	} else if !throttled {
+		if setting.Annotations == nil {
+			setting.Annotations = map[string]string{}
+		}
+		setting.Annotations[consts.AnnotationRebuildThrottlePrevious] = setting.Value
+	}
+
+	setting.Value = change.Value
+	if _, err := settings.Update(ctx, setting, metav1.UpdateOptions{}); err != nil {
+		return nil, errors.Wrapf(err, "failed to update setting %v", name)
+	}
+	return change, nil
+}
+
+// parseBandwidthLimit returns the bandwidth limit in MiB per second of the quantity, 0 for
+// unlimited.
+func parseBandwidthLimit(limit string) (int64, error) {
+	quantity, err := resource.ParseQuantity(limit)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid --%s %q", consts.CmdOptLimit, limit)
+	}
+
+	bytes := quantity.Value()
+	switch {
+	case bytes < 0:
+		return 0, errors.Errorf("--%s %q must not be negative", consts.CmdOptLimit, limit)
+	case bytes == 0:
+		return 0, nil
+	case bytes < 1<<20:
+		return 0, errors.Errorf("--%s %q must be at least 1Mi, or 0 for unlimited", consts.CmdOptLimit, limit)
+	}
+	return bytes >> 20, nil
+}
@@ -0,0 +1,69 @@
+package rebuild
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/longhorn/cli/pkg/consts"
+)
+
+func TestThrottlerValidate(t *testing.T) {
+	tests := map[string]struct {
+		options        ThrottlerCmdOptions
+		expectedValues map[string]string
+		expectedError  bool
+	}{
+		"limit": {
+			options:        ThrottlerCmdOptions{Concurrency: -1, Limit: "100Mi"},
+			expectedValues: map[string]string{consts.SettingNameReplicaRebuildingBandwidthLimit: "100"},
+		},
+		"unlimited": {
+			options:        ThrottlerCmdOptions{Concurrency: -1, Limit: "0"},
+			expectedValues: map[string]string{consts.SettingNameReplicaRebuildingBandwidthLimit: "0"},
+		},
+		"concurrency and limit": {
+			options: ThrottlerCmdOptions{Concurrency: 1, Limit: "1Gi"},
+			expectedValues: map[string]string{
+				"concurrent-replica-rebuild-per-node-limit":       "1",
+				consts.SettingNameReplicaRebuildingBandwidthLimit: "1024",
+			},
+		},
+		"reset": {
+			options:        ThrottlerCmdOptions{Concurrency: -1, Reset: true},
+			expectedValues: map[string]string{},
+		},
+		"nothing to change": {
+			options:       ThrottlerCmdOptions{Concurrency: -1},
+			expectedError: true,
+		},
+		"reset with limit": {
+			options:       ThrottlerCmdOptions{Concurrency: -1, Limit: "100Mi", Reset: true},
+			expectedError: true,
+		},
+		"limit below 1Mi": {
+			options:       ThrottlerCmdOptions{Concurrency: -1, Limit: "512Ki"},
+			expectedError: true,
+		},
+		"negative limit": {
+			options:       ThrottlerCmdOptions{Concurrency: -1, Limit: "-1Mi"},
+			expectedError: true,
+		},
+		"invalid limit": {
+			options:       ThrottlerCmdOptions{Concurrency: -1, Limit: "fast"},
+			expectedError: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			throttler := &Throttler{ThrottlerCmdOptions: test.options}
+			err := throttler.Validate()
+			if test.expectedError != (err != nil) {
+				t.Fatalf("expected error %v, got %v", test.expectedError, err)
+			}
+			if err == nil && !reflect.DeepEqual(throttler.values, test.expectedValues) {
+				t.Fatalf("expected values %v, got %v", test.expectedValues, throttler.values)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package release
+
+import (
+	_ "embed"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/blang/semver/v4"
+	"github.com/pkg/errors"
+
+	sigsyaml "sigs.k8s.io/yaml"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+)
+
+const (
+	catalogFetchTimeout = 30 * time.Second
+	catalogMaxSize      = 1024 * 1024
+)
+
+// Severities of the vulnerabilities of the catalog.
+const (
+	SeverityCritical = "critical"
+	SeverityHigh     = "high"
+	SeverityMedium   = "medium"
+	SeverityLow      = "low"
+)
+
+// embeddedCatalog is the release catalog shipped with longhornctl.
+//
+//go:embed catalog.yaml
+var embeddedCatalog []byte
+
+// catalogRelease is a release of the catalog with its parsed version.
+type catalogRelease struct {
+	*types.Release
+	version semver.Version
+}
+
+// GetEmbeddedCatalog returns the release catalog shipped with longhornctl.
+func GetEmbeddedCatalog() (*types.ReleaseCatalog, error) {
+	return ParseCatalog(embeddedCatalog)
+}
+
+// ParseCatalog parses and validates the release catalog, as YAML or JSON.
+func ParseCatalog(data []byte) (*types.ReleaseCatalog, error) {
+	catalog := &types.ReleaseCatalog{}
+	if err := sigsyaml.UnmarshalStrict(data, catalog); err != nil {
+		return nil, errors.Wrap(err, "failed to parse release catalog")
+	}
+
+	versions := map[string]bool{}
+	for i, release := range catalog.Releases {
+		if release.Version == "" {
+			return nil, errors.Errorf("release #%d has no version", i)
+		}
+		version, err := semver.ParseTolerant(release.Version)
+		if err != nil {
+			return nil, errors.Wrapf(err, "release %v has an invalid version", release.Version)
+		}
+		if versions[version.String()] {
+			return nil, errors.Errorf("release %v is defined more than once", release.Version)
+		}
+		versions[version.String()] = true
+
+		for j, cve := range release.CVEs {
+			if cve.ID == "" {
+				return nil, errors.Errorf("CVE #%d of release %v has no id", j, release.Version)
+			}
+			switch cve.Severity {
+			case "", SeverityCritical, SeverityHigh, SeverityMedium, SeverityLow:
+			default:
+				return nil, errors.Errorf("%v of release %v has an invalid severity %q", cve.ID, release.Version, cve.Severity)
+			}
+		}
+	}
+
+	return catalog, nil
+}
+
+// ReadCatalog reads and validates the release catalog from the file.
+func ReadCatalog(path string) (*types.ReleaseCatalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read release catalog %v", path)
+	}
+
+	catalog, err := ParseCatalog(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid release catalog %v", path)
+	}
+	return catalog, nil
+}
+
+// FetchCatalog downloads and validates the release catalog from the HTTP or HTTPS URL.
+func FetchCatalog(httpClient *http.Client, catalogURL string) (*types.ReleaseCatalog, error) {
+	parsedURL, err := url.Parse(catalogURL)
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") {
+		return nil, errors.Errorf("invalid --%s %q, it must be an HTTP or HTTPS URL", consts.CmdOptCatalogURL, catalogURL)
+	}
+
+	resp, err := httpClient.Get(catalogURL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to download release catalog from %v", catalogURL)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("failed to download release catalog from %v: %v", catalogURL, resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, catalogMaxSize+1))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to download release catalog from %v", catalogURL)
+	}
+	if len(data) > catalogMaxSize {
+		return nil, errors.Errorf("release catalog from %v is larger than %d bytes", catalogURL, catalogMaxSize)
+	}
+
+	catalog, err := ParseCatalog(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid release catalog from %v", catalogURL)
+	}
+	return catalog, nil
+}
+
+// getReleases returns the releases of the validated catalog, sorted by version.
+func getReleases(catalog *types.ReleaseCatalog) []catalogRelease {
+	releases := make([]catalogRelease, 0, len(catalog.Releases))
+	for i := range catalog.Releases {
+		version, err := semver.ParseTolerant(catalog.Releases[i].Version)
+		if err != nil {
+			continue
+		}
+		releases = append(releases, catalogRelease{Release: &catalog.Releases[i], version: version})
+	}
+
+	sort.Slice(releases, func(i, j int) bool { return releases[i].version.LT(releases[j].version) })
+	return releases
+}
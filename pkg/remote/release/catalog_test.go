@@ -0,0 +1,90 @@
+package release
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseCatalog(t *testing.T) {
+	catalog, err := GetEmbeddedCatalog()
+	if err != nil {
+		t.Fatalf("embedded catalog: unexpected error: %v", err)
+	}
+	if len(catalog.Releases) == 0 {
+		t.Error("embedded catalog: expected at least one release")
+	}
+
+	json := `{"releases": [{"version": "v1.7.1", "cves": [{"id": "CVE-2025-0001", "severity": "high"}]}, {"version": "v1.7.2"}]}`
+	catalog, err = ParseCatalog([]byte(json))
+	if err != nil {
+		t.Fatalf("JSON catalog: unexpected error: %v", err)
+	}
+	if len(catalog.Releases) != 2 || catalog.Releases[0].CVEs[0].ID != "CVE-2025-0001" {
+		t.Errorf("JSON catalog: unexpected releases %+v", catalog.Releases)
+	}
+
+	tests := map[string]string{
+		"unknown field":    "releases:\n- version: v1.7.1\n  eol: true\n",
+		"no version":       "releases:\n- endOfLife: true\n",
+		"invalid version":  "releases:\n- version: latest\n",
+		"duplicated":       "releases:\n- version: v1.7.1\n- version: 1.7.1\n",
+		"no CVE id":        "releases:\n- version: v1.7.1\n  cves:\n  - severity: high\n",
+		"invalid severity": "releases:\n- version: v1.7.1\n  cves:\n  - id: CVE-2025-0001\n    severity: severe\n",
+	}
+	for name, data := range tests {
+		if _, err := ParseCatalog([]byte(data)); err == nil {
+			t.Errorf("%v: expected an error", name)
+		}
+	}
+}
+
+func TestReadCatalog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "catalog.yaml")
+	if err := os.WriteFile(path, []byte("releases:\n- version: v1.7.1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	catalog, err := ReadCatalog(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(catalog.Releases) != 1 {
+		t.Errorf("unexpected releases %+v", catalog.Releases)
+	}
+
+	if _, err := ReadCatalog(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+func TestFetchCatalog(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/catalog.yaml":
+			_, _ = w.Write([]byte("releases:\n- version: v1.7.1\n- version: v1.7.2\n"))
+		case "/large.yaml":
+			_, _ = w.Write([]byte(strings.Repeat("#", catalogMaxSize+1)))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	catalog, err := FetchCatalog(server.Client(), server.URL+"/catalog.yaml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(catalog.Releases) != 2 {
+		t.Errorf("unexpected releases %+v", catalog.Releases)
+	}
+
+	for _, catalogURL := range []string{server.URL + "/missing.yaml", server.URL + "/large.yaml", "file:///etc/catalog.yaml"} {
+		if _, err := FetchCatalog(server.Client(), catalogURL); err == nil {
+			t.Errorf("FetchCatalog(%q) expected an error", catalogURL)
+		}
+	}
+}
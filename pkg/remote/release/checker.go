@@ -0,0 +1,216 @@
+package release
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/blang/semver/v4"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// KindLonghorn is the kind of the checked object, used in the key of the result.
+const KindLonghorn = "Longhorn"
+
+// Checker provide functions for comparing the installed Longhorn version against a release
+// catalog, to report the available upgrades and the vulnerabilities affecting it. The catalog is
+// the one shipped with longhornctl unless a file or URL is given, and it is only downloaded when
+// the URL is given.
+type Checker struct {
+	CheckerCmdOptions
+
+	catalog *types.ReleaseCatalog
+	version string
+}
+
+// CheckerCmdOptions holds the options for the command.
+type CheckerCmdOptions struct {
+	types.GlobalCmdOptions
+
+	LonghornNamespace string
+	Version           string // Version to check instead of the installed one.
+	OfflineCatalog    string // Path to the release catalog overriding the embedded one.
+	CatalogURL        string // URL of the release catalog overriding the embedded one.
+}
+
+// Validate validates the command options.
+func (remote *Checker) Validate() error {
+	if remote.OfflineCatalog != "" && remote.CatalogURL != "" {
+		return errors.Errorf("only one of --%s and --%s can be set", consts.CmdOptOfflineCatalog, consts.CmdOptCatalogURL)
+	}
+
+	if remote.Version != "" {
+		if _, err := semver.ParseTolerant(remote.Version); err != nil {
+			return errors.Wrapf(err, "invalid --%s %q", consts.CmdOptVersion, remote.Version)
+		}
+	}
+
+	return nil
+}
+
+// Init initializes the Checker: it loads the release catalog, and gets the installed Longhorn
+// version unless one is given.
+func (remote *Checker) Init() error {
+	var err error
+	switch {
+	case remote.OfflineCatalog != "":
+		remote.catalog, err = ReadCatalog(remote.OfflineCatalog)
+		if err != nil {
+			return err
+		}
+		logrus.Infof("Read %d releases from %v", len(remote.catalog.Releases), remote.OfflineCatalog)
+	case remote.CatalogURL != "":
+		remote.catalog, err = FetchCatalog(&http.Client{Timeout: catalogFetchTimeout}, remote.CatalogURL)
+		if err != nil {
+			return err
+		}
+		logrus.Infof("Downloaded %d releases from %v", len(remote.catalog.Releases), remote.CatalogURL)
+	default:
+		remote.catalog, err = GetEmbeddedCatalog()
+		if err != nil {
+			return err
+		}
+	}
+
+	remote.version = remote.Version
+	if remote.version != "" {
+		return nil
+	}
+
+	longhornClient, err := kubeutils.NewLonghornClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.version, err = kubeutils.GetLonghornVersion(context.Background(), longhornClient, remote.LonghornNamespace)
+	return err
+}
+
+// Collect compares the Longhorn version against the release catalog, and returns the result keyed
+// by the version.
+func (remote *Checker) Collect() (map[string]*types.LogCollection, error) {
+	return map[string]*types.LogCollection{
+		KindLonghorn + "/" + remote.version: checkVersion(remote.catalog, remote.version),
+	}, nil
+}
+
+// Cleanup does nothing, since the Checker does not create any resource.
+func (remote *Checker) Cleanup() error {
+	return nil
+}
+
+// checkVersion returns the vulnerabilities affecting the version, its end of life and the
+// available upgrades, according to the catalog.
+func checkVersion(catalog *types.ReleaseCatalog, versionValue string) *types.LogCollection {
+	collection := &types.LogCollection{}
+
+	installed, err := semver.ParseTolerant(versionValue)
+	if err != nil {
+		collection.Error = append(collection.Error, fmt.Sprintf("Cannot parse the version %v", versionValue))
+		return collection
+	}
+
+	releases := getReleases(catalog)
+
+	var current, latestPatch, latest *catalogRelease
+	for i := range releases {
+		release := &releases[i]
+		if release.version.Equals(installed) {
+			current = release
+		}
+		if len(release.version.Pre) > 0 {
+			continue
+		}
+		if latest == nil || release.version.GT(latest.version) {
+			latest = release
+		}
+		if isSameMinor(release.version, installed) && release.version.GT(installed) && (latestPatch == nil || release.version.GT(latestPatch.version)) {
+			latestPatch = release
+		}
+	}
+
+	switch {
+	case current != nil:
+		for _, cve := range current.CVEs {
+			message := fmt.Sprintf("Affected by %v", cve.ID)
+			if cve.Severity != "" {
+				message += fmt.Sprintf(" (%v)", cve.Severity)
+			}
+			if cve.Description != "" {
+				message += ": " + cve.Description
+			}
+			if fix := getFixRelease(releases, installed, cve.ID); fix != nil {
+				message += fmt.Sprintf(", fixed in %v", fix.Version)
+			} else {
+				message += ", no release fixes it yet"
+			}
+
+			switch cve.Severity {
+			case SeverityCritical, SeverityHigh:
+				collection.Error = append(collection.Error, message)
+			default:
+				collection.Warn = append(collection.Warn, message)
+			}
+		}
+		if len(current.CVEs) == 0 {
+			collection.Info = append(collection.Info, "Not affected by any vulnerability of the release catalog")
+		}
+		if current.EndOfLife {
+			collection.Warn = append(collection.Warn, fmt.Sprintf("Longhorn v%d.%d is end of life and no longer receives patch releases", installed.Major, installed.Minor))
+		}
+	case latest != nil && installed.GT(latest.version):
+		collection.Info = append(collection.Info, fmt.Sprintf("Newer than the latest release %v of the release catalog, update the catalog to check it", latest.Version))
+	default:
+		collection.Warn = append(collection.Warn, "Not a release of the release catalog, its vulnerabilities are unknown")
+	}
+
+	if latestPatch != nil {
+		collection.Warn = append(collection.Warn, fmt.Sprintf("Patch upgrade available to %v", latestPatch.Version))
+	} else if current != nil {
+		collection.Info = append(collection.Info, fmt.Sprintf("Latest patch release of Longhorn v%d.%d", installed.Major, installed.Minor))
+	}
+
+	if latest != nil && !isSameMinor(latest.version, installed) && latest.version.GT(installed) {
+		collection.Info = append(collection.Info, fmt.Sprintf("Latest release is %v", latest.Version))
+	}
+
+	return collection
+}
+
+// getFixRelease returns the first release after the version not affected by the vulnerability,
+// preferring a patch release of the same minor version.
+func getFixRelease(releases []catalogRelease, version semver.Version, cveID string) *catalogRelease {
+	var fix *catalogRelease
+	for i := range releases {
+		release := &releases[i]
+		if len(release.version.Pre) > 0 || !release.version.GT(version) || hasCVE(release.Release, cveID) {
+			continue
+		}
+
+		if isSameMinor(release.version, version) {
+			return release
+		}
+		if fix == nil {
+			fix = release
+		}
+	}
+	return fix
+}
+
+func hasCVE(release *types.Release, cveID string) bool {
+	for _, cve := range release.CVEs {
+		if cve.ID == cveID {
+			return true
+		}
+	}
+	return false
+}
+
+func isSameMinor(version, other semver.Version) bool {
+	return version.Major == other.Major && version.Minor == other.Minor
+}
@@ -0,0 +1,103 @@
+package release
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func TestCheckVersion(t *testing.T) {
+	catalog := &types.ReleaseCatalog{Releases: []types.Release{
+		{Version: "v1.6.4", EndOfLife: true},
+		{Version: "v1.7.0", CVEs: []types.ReleaseCVE{{ID: "CVE-1", Severity: SeverityHigh, Description: "Remote code execution"}, {ID: "CVE-2", Severity: SeverityLow}}},
+		{Version: "v1.7.1", CVEs: []types.ReleaseCVE{{ID: "CVE-1", Severity: SeverityHigh, Description: "Remote code execution"}}},
+		{Version: "v1.7.2"},
+		{Version: "v1.8.0-rc1"},
+		{Version: "v1.8.0", CVEs: []types.ReleaseCVE{{ID: "CVE-3", Severity: SeverityCritical}}},
+		{Version: "v1.6.3", EndOfLife: true},
+	}}
+
+	tests := map[string]struct {
+		version  string
+		expected *types.LogCollection
+	}{
+		"vulnerable": {
+			version: "v1.7.0",
+			expected: &types.LogCollection{
+				Error: []string{"Affected by CVE-1 (high): Remote code execution, fixed in v1.7.2"},
+				Warn:  []string{"Affected by CVE-2 (low), fixed in v1.7.1", "Patch upgrade available to v1.7.2"},
+				Info:  []string{"Latest release is v1.8.0"},
+			},
+		},
+		"latest patch": {
+			version: "1.7.2",
+			expected: &types.LogCollection{
+				Info: []string{"Not affected by any vulnerability of the release catalog", "Latest patch release of Longhorn v1.7", "Latest release is v1.8.0"},
+			},
+		},
+		"end of life": {
+			version: "v1.6.3",
+			expected: &types.LogCollection{
+				Warn: []string{"Longhorn v1.6 is end of life and no longer receives patch releases", "Patch upgrade available to v1.6.4"},
+				Info: []string{"Not affected by any vulnerability of the release catalog", "Latest release is v1.8.0"},
+			},
+		},
+		"unfixed": {
+			version: "v1.8.0",
+			expected: &types.LogCollection{
+				Error: []string{"Affected by CVE-3 (critical), no release fixes it yet"},
+				Info:  []string{"Latest patch release of Longhorn v1.8"},
+			},
+		},
+		"newer than catalog": {
+			version: "v1.9.0",
+			expected: &types.LogCollection{
+				Info: []string{"Newer than the latest release v1.8.0 of the release catalog, update the catalog to check it"},
+			},
+		},
+		"unknown release": {
+			version: "v1.7.5-dev",
+			expected: &types.LogCollection{
+				Warn: []string{"Not a release of the release catalog, its vulnerabilities are unknown"},
+				Info: []string{"Latest release is v1.8.0"},
+			},
+		},
+		"invalid version": {
+			version: "master-head",
+			expected: &types.LogCollection{
+				Error: []string{"Cannot parse the version master-head"},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if collection := checkVersion(catalog, test.version); !reflect.DeepEqual(collection, test.expected) {
+				t.Errorf("expected %+v, got %+v", test.expected, collection)
+			}
+		})
+	}
+}
+
+func TestCheckerValidate(t *testing.T) {
+	tests := map[string]struct {
+		options     CheckerCmdOptions
+		expectError bool
+	}{
+		"embedded catalog": {options: CheckerCmdOptions{}},
+		"offline catalog":  {options: CheckerCmdOptions{OfflineCatalog: "catalog.json", Version: "v1.7.1"}},
+		"catalog URL":      {options: CheckerCmdOptions{CatalogURL: "https://example.com/catalog.yaml"}},
+		"both catalogs":    {options: CheckerCmdOptions{OfflineCatalog: "catalog.json", CatalogURL: "https://example.com/catalog.yaml"}, expectError: true},
+		"invalid version":  {options: CheckerCmdOptions{Version: "latest"}, expectError: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			checker := &Checker{CheckerCmdOptions: test.options}
+			if err := checker.Validate(); test.expectError != (err != nil) {
+				t.Errorf("expected error %v, got %v", test.expectError, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,568 @@
+package replica
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/utils/ptr"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	kubeclient "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	commonkube "github.com/longhorn/go-common-libs/kubernetes"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// Archive formats of a replica data directory, compressed by tar in the archive pod.
+const (
+	ArchiveFormatTarGzip = "tar.gz"
+	ArchiveFormatTarZstd = "tar.zst"
+)
+
+// ArchiveChecksumSuffix is the suffix of the file holding the checksum of an archive, next to it,
+// in the format of sha256sum.
+const ArchiveChecksumSuffix = ".sha256"
+
+// archiveScript checks no process has files of the replica data directory open, and writes to
+// stdout the archive of the directory, with the SHA256SUMS manifest of its files. The sparse
+// snapshot files stay sparse in the archive.
+const archiveScript = `set -euo pipefail
+replica="$1"
+cd ` + consts.VolumeMountReplicasDirectory + `
+if [ ! -d "$replica" ]; then
+  echo "replica data directory $replica does not exist" >&2
+  exit 1
+fi
+if for fd in /proc/[0-9]*/fd/*; do readlink "$fd" 2>/dev/null || true; done | grep -F "/replicas/$replica/" > /dev/null; then
+  echo "replica $replica is in use, detach its volume first" >&2
+  exit 1
+fi
+manifest=$(mktemp -d)
+trap 'rm -rf "$manifest"' EXIT
+find "$replica" -type f -print0 | sort -z | xargs -0 -r sha256sum > "$manifest/SHA256SUMS"
+tar --create --sparse "$2" --file - -C "$manifest" SHA256SUMS -C ` + consts.VolumeMountReplicasDirectory + ` "$replica"`
+
+// importScript extracts the archive from stdin to a staging directory, verifies the files against
+// the SHA256SUMS manifest, and moves the replica data directory in place under the name, defaulting
+// to the one in the archive. It prints the name of the imported directory.
+const importScript = `set -euo pipefail
+name="$1"
+staging=$(mktemp -d ` + consts.VolumeMountReplicasDirectory + `/.longhornctl-import.XXXXXX)
+trap 'rm -rf "$staging"' EXIT
+tar --extract "$2" --no-same-owner --file - -C "$staging"
+cd "$staging"
+if [ ! -f SHA256SUMS ]; then
+  echo "archive has no SHA256SUMS manifest" >&2
+  exit 1
+fi
+replica=$(ls -A | grep -vx SHA256SUMS || true)
+if [ -z "$replica" ] || [ "$(echo "$replica" | wc -l)" -ne 1 ] || [ ! -d "$replica" ]; then
+  echo "archive must hold a single replica data directory" >&2
+  exit 1
+fi
+if [ "$(find "$replica" -type f | wc -l)" -ne "$(wc -l < SHA256SUMS)" ]; then
+  echo "files of the archive do not match the SHA256SUMS manifest" >&2
+  exit 1
+fi
+sha256sum --quiet --check SHA256SUMS >&2
+name="${name:-$replica}"
+if [ -e "` + consts.VolumeMountReplicasDirectory + `/$name" ]; then
+  echo "replica data directory $name already exists" >&2
+  exit 1
+fi
+mv "$replica" "` + consts.VolumeMountReplicasDirectory + `/$name"
+echo "$name"`
+
+// Archiver provide functions for packaging a replica data directory of a node, with its snapshots
+// and metadata files, into a local archive, for recovering the replica off the cluster or in
+// another cluster with the Importer.
+type Archiver struct {
+	ArchiverCmdOptions
+
+	archivePod *archivePod
+}
+
+// ArchiverCmdOptions holds the options for the command.
+type ArchiverCmdOptions struct {
+	types.GlobalCmdOptions
+
+	LonghornDataDirectory string
+	ReplicaName           string
+	NodeID                string
+	Format                string // tar.zst or tar.gz.
+	Output                string // Local path of the archive.
+}
+
+// Validate validates the command options.
+func (remote *Archiver) Validate() error {
+	if remote.ReplicaName == "" {
+		return errors.Errorf("Replica name (--%s) is required", consts.CmdOptName)
+	}
+	if strings.ContainsAny(remote.ReplicaName, "/ ") {
+		return errors.Errorf("invalid --%s %q", consts.CmdOptName, remote.ReplicaName)
+	}
+
+	if remote.NodeID == "" {
+		return errors.Errorf("Node name (--%s) is required with --%s", consts.CmdOptNode, consts.CmdOptArchive)
+	}
+
+	if _, err := getArchiveFormatOption(remote.Format); err != nil {
+		return err
+	}
+
+	if remote.Output == "" {
+		return errors.Errorf("Archive path (--%s) is required with --%s", consts.CmdOptOutput, consts.CmdOptArchive)
+	}
+	if _, err := os.Stat(remote.Output); err == nil {
+		return errors.Errorf("%v already exists", remote.Output)
+	}
+
+	return nil
+}
+
+// Init initializes the Archiver.
+func (remote *Archiver) Init() error {
+	archivePod, err := newArchivePod(&remote.GlobalCmdOptions, consts.AppNameReplicaArchiver, remote.NodeID, remote.LonghornDataDirectory, true)
+	if err != nil {
+		return err
+	}
+	remote.archivePod = archivePod
+
+	return nil
+}
+
+// Run creates the archive pod on the node, streams the archive of the replica data directory to a
+// temporary file next to the output, and renames it to the output with its checksum file once
+// complete.
+func (remote *Archiver) Run() (*types.ReplicaArchive, error) {
+	if err := remote.archivePod.start(); err != nil {
+		return nil, err
+	}
+
+	formatOption, _ := getArchiveFormatOption(remote.Format)
+
+	file, err := os.CreateTemp(filepath.Dir(remote.Output), "."+filepath.Base(remote.Output)+".*")
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create temporary file for %v", remote.Output)
+	}
+	defer os.Remove(file.Name())
+
+	logrus.WithField("node", remote.NodeID).Infof("Archiving replica %v to %v", remote.ReplicaName, remote.Output)
+	hash := sha256.New()
+	counter := &countWriter{}
+	var stderr bytes.Buffer
+	err = remote.archivePod.exec(context.Background(), []string{"bash", "-c", archiveScript, "bash", remote.ReplicaName, formatOption}, nil, io.MultiWriter(file, hash, counter), &stderr)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to archive replica %v on node %v: %s", remote.ReplicaName, remote.NodeID, strings.TrimSpace(stderr.String()))
+	}
+	checksum := hex.EncodeToString(hash.Sum(nil))
+
+	if err := os.Chmod(file.Name(), 0644); err != nil {
+		return nil, errors.Wrapf(err, "failed to set the permissions of %v", file.Name())
+	}
+	if err := os.Rename(file.Name(), remote.Output); err != nil {
+		return nil, errors.Wrapf(err, "failed to write %v", remote.Output)
+	}
+	if err := writeArchiveChecksum(remote.Output, checksum); err != nil {
+		return nil, err
+	}
+
+	return &types.ReplicaArchive{
+		Node:    remote.NodeID,
+		Replica: remote.ReplicaName,
+		Format:  remote.Format,
+		File:    remote.Output,
+		Size:    counter.written,
+		SHA256:  checksum,
+	}, nil
+}
+
+// Cleanup deletes the DaemonSet created for archiving the replica.
+func (remote *Archiver) Cleanup() error {
+	return remote.archivePod.cleanup()
+}
+
+// Importer provide functions for restoring a replica data directory archived by the Archiver to a
+// node, possibly of another cluster.
+type Importer struct {
+	ImporterCmdOptions
+
+	archivePod *archivePod
+	format     string
+	checksum   string
+}
+
+// ImporterCmdOptions holds the options for the command.
+type ImporterCmdOptions struct {
+	types.GlobalCmdOptions
+
+	LonghornDataDirectory string
+	ReplicaName           string // Name of the imported directory, defaulting to the one in the archive.
+	NodeID                string
+	Format                string // tar.zst or tar.gz, defaulting to the extension of the input.
+	Input                 string // Local path of the archive.
+}
+
+// Validate validates the command options, and the archive against its checksum file when it has
+// one.
+func (remote *Importer) Validate() error {
+	if remote.Input == "" {
+		return errors.Errorf("Archive path (--%s) is required", consts.CmdOptInput)
+	}
+
+	if remote.NodeID == "" {
+		return errors.Errorf("Node name (--%s) is required", consts.CmdOptNode)
+	}
+
+	if strings.ContainsAny(remote.ReplicaName, "/ ") || strings.HasPrefix(remote.ReplicaName, ".") {
+		return errors.Errorf("invalid --%s %q", consts.CmdOptName, remote.ReplicaName)
+	}
+
+	remote.format = remote.Format
+	if remote.format == "" {
+		remote.format = getArchiveFormat(remote.Input)
+	}
+	if remote.format == "" {
+		return errors.Errorf("cannot tell the format of %v from its extension, set --%s", remote.Input, consts.CmdOptArchive)
+	}
+	if _, err := getArchiveFormatOption(remote.format); err != nil {
+		return err
+	}
+
+	checksum, err := getFileChecksum(remote.Input)
+	if err != nil {
+		return err
+	}
+	remote.checksum = checksum
+
+	expectedChecksum, err := readArchiveChecksum(remote.Input)
+	switch {
+	case os.IsNotExist(errors.Cause(err)):
+		logrus.Warnf("%v has no checksum file %v, only the files of the archive are verified", remote.Input, remote.Input+ArchiveChecksumSuffix)
+	case err != nil:
+		return err
+	case expectedChecksum != checksum:
+		return errors.Errorf("checksum %v of %v does not match %v of %v", checksum, remote.Input, expectedChecksum, remote.Input+ArchiveChecksumSuffix)
+	}
+
+	return nil
+}
+
+// Init initializes the Importer.
+func (remote *Importer) Init() error {
+	archivePod, err := newArchivePod(&remote.GlobalCmdOptions, consts.AppNameReplicaImporter, remote.NodeID, remote.LonghornDataDirectory, false)
+	if err != nil {
+		return err
+	}
+	remote.archivePod = archivePod
+
+	return nil
+}
+
+// Run creates the import pod on the node, and streams the archive to it. The replica data directory
+// is only moved in place once all its files match the manifest of the archive.
+func (remote *Importer) Run() (*types.ReplicaArchive, error) {
+	if err := remote.archivePod.start(); err != nil {
+		return nil, err
+	}
+
+	formatOption, _ := getArchiveFormatOption(remote.format)
+
+	file, err := os.Open(remote.Input)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open %v", remote.Input)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get the size of %v", remote.Input)
+	}
+
+	logrus.WithField("node", remote.NodeID).Infof("Importing %v to %v", remote.Input, filepath.Join(remote.LonghornDataDirectory, "replicas"))
+	var stdout, stderr bytes.Buffer
+	err = remote.archivePod.exec(context.Background(), []string{"bash", "-c", importScript, "bash", remote.ReplicaName, formatOption}, file, &stdout, &stderr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to import %v on node %v: %s", remote.Input, remote.NodeID, strings.TrimSpace(stderr.String()))
+	}
+
+	return &types.ReplicaArchive{
+		Node:    remote.NodeID,
+		Replica: strings.TrimSpace(stdout.String()),
+		Format:  remote.format,
+		File:    remote.Input,
+		Size:    info.Size(),
+		SHA256:  remote.checksum,
+	}, nil
+}
+
+// Cleanup deletes the DaemonSet created for importing the replica.
+func (remote *Importer) Cleanup() error {
+	return remote.archivePod.cleanup()
+}
+
+// getArchiveFormatOption returns the tar option compressing the archive in the format.
+func getArchiveFormatOption(format string) (string, error) {
+	switch format {
+	case ArchiveFormatTarZstd:
+		return "--zstd", nil
+	case ArchiveFormatTarGzip:
+		return "--gzip", nil
+	}
+	return "", errors.Errorf("invalid --%s %q, it must be %s or %s", consts.CmdOptArchive, format, ArchiveFormatTarZstd, ArchiveFormatTarGzip)
+}
+
+// getArchiveFormat returns the format of the archive from its extension, or an empty string.
+func getArchiveFormat(path string) string {
+	switch {
+	case strings.HasSuffix(path, "."+ArchiveFormatTarZstd), strings.HasSuffix(path, ".tzst"):
+		return ArchiveFormatTarZstd
+	case strings.HasSuffix(path, "."+ArchiveFormatTarGzip), strings.HasSuffix(path, ".tgz"):
+		return ArchiveFormatTarGzip
+	}
+	return ""
+}
+
+// getFileChecksum returns the SHA-256 checksum of the file.
+func getFileChecksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to open %v", path)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", errors.Wrapf(err, "failed to read %v", path)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// writeArchiveChecksum writes the checksum file of the archive, in the format of sha256sum.
+func writeArchiveChecksum(path, checksum string) error {
+	content := fmt.Sprintf("%s  %s\n", checksum, filepath.Base(path))
+	return errors.Wrapf(os.WriteFile(path+ArchiveChecksumSuffix, []byte(content), 0644), "failed to write %v", path+ArchiveChecksumSuffix)
+}
+
+// readArchiveChecksum reads the checksum file of the archive.
+func readArchiveChecksum(path string) (string, error) {
+	data, err := os.ReadFile(path + ArchiveChecksumSuffix)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to read %v", path+ArchiveChecksumSuffix)
+	}
+
+	checksum := strings.Fields(string(data))
+	if len(checksum) == 0 {
+		return "", errors.Errorf("%v is empty", path+ArchiveChecksumSuffix)
+	}
+	return checksum[0], nil
+}
+
+// countWriter counts the bytes written to it.
+type countWriter struct {
+	written int64
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	w.written += int64(len(p))
+	return len(p), nil
+}
+
+// archivePod is the pod of a DaemonSet on a single node, with the replicas directory of the
+// Longhorn data directory of the node mounted, read-only for archiving.
+type archivePod struct {
+	globalOpts *types.GlobalCmdOptions
+
+	kubeClient *kubeclient.Clientset
+	restConfig *rest.Config
+
+	appName   string // App name of the DaemonSet.
+	namespace string
+	nodeName  string
+
+	replicasDirectory string // Replicas directory on the node.
+	readOnly          bool
+
+	pod *corev1.Pod
+}
+
+// newArchivePod initializes the clients of the pod, and checks the node exists.
+func newArchivePod(globalOpts *types.GlobalCmdOptions, appName, nodeName, longhornDataDirectory string, readOnly bool) (*archivePod, error) {
+	kubeClient, err := kubeutils.NewKubeClient("", globalOpts.KubeConfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	restConfig, err := kubeutils.NewRestConfig("", globalOpts.KubeConfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	namespace := globalOpts.Namespace
+	if namespace == "" {
+		namespace = consts.LonghornNamespace
+	}
+
+	if _, err := kubeClient.CoreV1().Nodes().Get(context.Background(), nodeName, metav1.GetOptions{}); err != nil {
+		return nil, errors.Wrapf(err, "failed to get node %v", nodeName)
+	}
+
+	return &archivePod{
+		globalOpts:        globalOpts,
+		kubeClient:        kubeClient,
+		restConfig:        restConfig,
+		appName:           appName,
+		namespace:         namespace,
+		nodeName:          nodeName,
+		replicasDirectory: filepath.Join(longhornDataDirectory, "replicas"),
+		readOnly:          readOnly,
+	}, nil
+}
+
+// start creates the DaemonSet on the node, and waits for its pod to be ready.
+func (p *archivePod) start() error {
+	newDaemonSet := p.newDaemonSet()
+	kubeutils.SetNodeNameAffinity(&newDaemonSet.Spec.Template.Spec, []string{p.nodeName})
+	if err := kubeutils.SetPodOptions(&newDaemonSet.Spec.Template.Spec, p.globalOpts); err != nil {
+		return err
+	}
+
+	_, err := kubeutils.CreateNamespace(p.kubeClient, p.namespace)
+	if err != nil {
+		return err
+	}
+
+	kubeutils.SetRunMetadata(p.kubeClient, newDaemonSet)
+	kubeutils.LogManifest(newDaemonSet)
+	daemonSet, err := kubeutils.CreateDaemonSet(p.kubeClient, newDaemonSet, p.globalOpts.MinNodes)
+	if err != nil {
+		return err
+	}
+
+	err = kubeutils.MonitorDaemonSetContainer(p.kubeClient, daemonSet, consts.ContainerName, kubeutils.WaitForDaemonSetContainersReady, ptr.To(consts.ContainerConditionMaxTolerationMedium))
+	if err != nil {
+		return err
+	}
+
+	podList, err := p.kubeClient.CoreV1().Pods(p.namespace).List(context.Background(), metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set{"app": p.appName}).String(),
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", p.nodeName).String(),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to list pods of %v", p.appName)
+	}
+	for i := range podList.Items {
+		if podList.Items[i].DeletionTimestamp == nil {
+			p.pod = &podList.Items[i]
+			return nil
+		}
+	}
+	return errors.Errorf("no pod of %v is running on node %v", p.appName, p.nodeName)
+}
+
+// exec runs the command in the container of the pod, streaming the stdin to the command and its
+// stdout and stderr to the writers. The stdin is not attached when it is nil.
+func (p *archivePod) exec(ctx context.Context, command []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	if p.pod == nil {
+		return errors.Errorf("pod of %v is not started", p.appName)
+	}
+	return kubeutils.StreamPodContainer(ctx, p.restConfig, p.kubeClient, p.pod.Namespace, p.pod.Name, consts.ContainerName, command, stdin, stdout, stderr)
+}
+
+// cleanup deletes the DaemonSet.
+func (p *archivePod) cleanup() error {
+	return commonkube.DeleteDaemonSet(p.kubeClient, p.namespace, p.appName)
+}
+
+// newDaemonSet prepares the DaemonSet archiving or importing the replica. The pod shares the PID
+// namespace of the host to find the processes using the replica, and idles until the archive is
+// streamed from or to its container.
+func (p *archivePod) newDaemonSet() *appsv1.DaemonSet {
+	capabilities := kubeutils.CapabilitiesHostRead
+	hostPathType := corev1.HostPathDirectory
+	if !p.readOnly {
+		capabilities = kubeutils.CapabilitiesHostWrite
+		hostPathType = corev1.HostPathDirectoryOrCreate
+	}
+
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      p.appName,
+			Namespace: p.namespace,
+			Labels: map[string]string{
+				"app":                 p.appName,
+				consts.LabelManagedBy: consts.LabelValueManagedBy,
+			},
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app": p.appName,
+				},
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						"app":                 p.appName,
+						consts.LabelManagedBy: consts.LabelValueManagedBy,
+					},
+				},
+				Spec: corev1.PodSpec{
+					HostPID: true,
+					Containers: []corev1.Container{
+						{
+							Name:            consts.ContainerName,
+							Image:           p.globalOpts.Image,
+							Command:         []string{"sleep", "infinity"},
+							SecurityContext: kubeutils.NewSecurityContext(p.globalOpts.Privileged, capabilities),
+							VolumeMounts: []corev1.VolumeMount{
+								{
+									Name:      consts.VolumeMountReplicasName,
+									MountPath: consts.VolumeMountReplicasDirectory,
+									ReadOnly:  p.readOnly,
+								},
+							},
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: consts.VolumeMountReplicasName,
+							VolumeSource: corev1.VolumeSource{
+								HostPath: &corev1.HostPathVolumeSource{
+									Path: p.replicasDirectory,
+									Type: ptr.To(hostPathType),
+								},
+							},
+						},
+					},
+					TerminationGracePeriodSeconds: ptr.To(int64(0)),
+				},
+			},
+			UpdateStrategy: appsv1.DaemonSetUpdateStrategy{
+				Type: appsv1.RollingUpdateDaemonSetStrategyType,
+			},
+		},
+	}
+}
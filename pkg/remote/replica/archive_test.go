@@ -0,0 +1,161 @@
+package replica
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestArchiverValidate(t *testing.T) {
+	directory := t.TempDir()
+	existing := filepath.Join(directory, "existing.tar.zst")
+	if err := os.WriteFile(existing, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		options       ArchiverCmdOptions
+		expectedError bool
+	}{
+		"tar.zst": {
+			options: ArchiverCmdOptions{ReplicaName: "pvc-1-0e2603a7", NodeID: "node-1", Format: ArchiveFormatTarZstd, Output: filepath.Join(directory, "vol.tar.zst")},
+		},
+		"tar.gz": {
+			options: ArchiverCmdOptions{ReplicaName: "pvc-1-0e2603a7", NodeID: "node-1", Format: ArchiveFormatTarGzip, Output: filepath.Join(directory, "vol.tar.gz")},
+		},
+		"no replica name": {
+			options:       ArchiverCmdOptions{NodeID: "node-1", Format: ArchiveFormatTarZstd, Output: filepath.Join(directory, "vol.tar.zst")},
+			expectedError: true,
+		},
+		"replica name with slash": {
+			options:       ArchiverCmdOptions{ReplicaName: "../pvc-1", NodeID: "node-1", Format: ArchiveFormatTarZstd, Output: filepath.Join(directory, "vol.tar.zst")},
+			expectedError: true,
+		},
+		"no node": {
+			options:       ArchiverCmdOptions{ReplicaName: "pvc-1-0e2603a7", Format: ArchiveFormatTarZstd, Output: filepath.Join(directory, "vol.tar.zst")},
+			expectedError: true,
+		},
+		"unsupported format": {
+			options:       ArchiverCmdOptions{ReplicaName: "pvc-1-0e2603a7", NodeID: "node-1", Format: "zip", Output: filepath.Join(directory, "vol.zip")},
+			expectedError: true,
+		},
+		"no output": {
+			options:       ArchiverCmdOptions{ReplicaName: "pvc-1-0e2603a7", NodeID: "node-1", Format: ArchiveFormatTarZstd},
+			expectedError: true,
+		},
+		"existing output": {
+			options:       ArchiverCmdOptions{ReplicaName: "pvc-1-0e2603a7", NodeID: "node-1", Format: ArchiveFormatTarZstd, Output: existing},
+			expectedError: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			archiver := &Archiver{ArchiverCmdOptions: test.options}
+			err := archiver.Validate()
+			if test.expectedError != (err != nil) {
+				t.Fatalf("expected error %v, got %v", test.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestImporterValidate(t *testing.T) {
+	directory := t.TempDir()
+	writeArchive := func(name string, content string) string {
+		path := filepath.Join(directory, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	verified := writeArchive("verified.tar.zst", "archive")
+	checksum, err := getFileChecksum(verified)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeArchiveChecksum(verified, checksum); err != nil {
+		t.Fatal(err)
+	}
+
+	corrupted := writeArchive("corrupted.tar.gz", "archive")
+	if err := writeArchiveChecksum(corrupted, checksum+"0"); err != nil {
+		t.Fatal(err)
+	}
+
+	unverified := writeArchive("unverified.tgz", "archive")
+	unknown := writeArchive("unknown.bin", "archive")
+
+	tests := map[string]struct {
+		options        ImporterCmdOptions
+		expectedError  bool
+		expectedFormat string
+	}{
+		"verified": {
+			options:        ImporterCmdOptions{Input: verified, NodeID: "node-1"},
+			expectedFormat: ArchiveFormatTarZstd,
+		},
+		"without checksum file": {
+			options:        ImporterCmdOptions{Input: unverified, NodeID: "node-1", ReplicaName: "pvc-1-restored"},
+			expectedFormat: ArchiveFormatTarGzip,
+		},
+		"format set": {
+			options:        ImporterCmdOptions{Input: unknown, NodeID: "node-1", Format: ArchiveFormatTarGzip},
+			expectedFormat: ArchiveFormatTarGzip,
+		},
+		"unknown format": {
+			options:       ImporterCmdOptions{Input: unknown, NodeID: "node-1"},
+			expectedError: true,
+		},
+		"checksum mismatch": {
+			options:       ImporterCmdOptions{Input: corrupted, NodeID: "node-1"},
+			expectedError: true,
+		},
+		"missing input": {
+			options:       ImporterCmdOptions{Input: filepath.Join(directory, "missing.tar.zst"), NodeID: "node-1"},
+			expectedError: true,
+		},
+		"no input": {
+			options:       ImporterCmdOptions{NodeID: "node-1"},
+			expectedError: true,
+		},
+		"no node": {
+			options:       ImporterCmdOptions{Input: verified},
+			expectedError: true,
+		},
+		"hidden replica name": {
+			options:       ImporterCmdOptions{Input: verified, NodeID: "node-1", ReplicaName: ".longhornctl-import"},
+			expectedError: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			importer := &Importer{ImporterCmdOptions: test.options}
+			err := importer.Validate()
+			if test.expectedError != (err != nil) {
+				t.Fatalf("expected error %v, got %v", test.expectedError, err)
+			}
+			if err == nil && importer.format != test.expectedFormat {
+				t.Fatalf("expected format %v, got %v", test.expectedFormat, importer.format)
+			}
+		})
+	}
+}
+
+func TestGetArchiveFormat(t *testing.T) {
+	tests := map[string]string{
+		"vol-backup.tar.zst": ArchiveFormatTarZstd,
+		"vol-backup.tzst":    ArchiveFormatTarZstd,
+		"vol-backup.tar.gz":  ArchiveFormatTarGzip,
+		"vol-backup.tgz":     ArchiveFormatTarGzip,
+		"vol-backup.tar":     "",
+	}
+
+	for path, expected := range tests {
+		if format := getArchiveFormat(path); format != expected {
+			t.Errorf("expected format %q of %v, got %q", expected, path, format)
+		}
+	}
+}
@@ -1,7 +1,9 @@
 package replica
 
 import (
+	"context"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -45,12 +47,28 @@ type ExporterCmdOptions struct {
 	ReplicaName           string
 	LonghornDataDirectory string
 	HostTargetDirectory   string
+
+	Volumes     string // Comma-separated volumes to export a replica of, each by its own replica exporter.
+	Concurrency int    // Maximum number of volumes exported at the same time.
+
+	ReadOnly     bool   // Export through an overlay, so the replica data cannot be modified.
+	SnapshotName string // Export the snapshot instead of the volume head. Implies ReadOnly.
+
+	Share             string // Protocol serving the exported directory as a network share, nfs or smb.
+	ShareAllowedCIDRs string // Comma-separated CIDRs of the clients allowed to access the share.
+	ShareImage        string // Image of the container serving the share.
+	ShareUsername     string // User allowed to access the SMB share.
+	ShareSecret       string // Secret in the namespace holding the password of the user of the SMB share.
 }
 
 // Validate validates the command options.
 func (remote *Exporter) Validate() error {
-	if remote.ReplicaName == "" {
-		return errors.New("Replica name (--name) is required")
+	if remote.Volumes != "" {
+		if err := remote.validateVolumes(); err != nil {
+			return err
+		}
+	} else if remote.ReplicaName == "" {
+		return errors.New("Replica name (--name) or volumes (--volumes) is required")
 	}
 
 	if remote.EngineImage == "" {
@@ -61,7 +79,14 @@ func (remote *Exporter) Validate() error {
 		return errors.New("Host target directory (--target-dir) is required")
 	}
 
-	return nil
+	if remote.SnapshotName != "" {
+		if strings.ContainsAny(remote.SnapshotName, "/ ") {
+			return errors.Errorf("invalid --%s %q", consts.CmdOptSnapshot, remote.SnapshotName)
+		}
+		remote.ReadOnly = true
+	}
+
+	return remote.validateShare()
 }
 
 // Init initializes the Exporter.
@@ -72,7 +97,10 @@ func (remote *Exporter) Init() error {
 	}
 	remote.kubeClient = kubeClient
 
-	remote.namespace = metav1.NamespaceDefault
+	remote.namespace = remote.Namespace
+	if remote.namespace == "" {
+		remote.namespace = consts.LonghornNamespace
+	}
 	remote.appName = consts.AppNameReplicaExporter
 
 	// Not required for cleanup
@@ -89,51 +117,102 @@ func (remote *Exporter) Init() error {
 // Run creates the ConfigMap and DaemonSet for the replica exporter.
 // It ensures the init container completes and the engine container is ready
 // before collecting volume information and returning it as a YAML string.
+// With volumes, a replica of each volume is exported by its own replica exporter.
 func (remote *Exporter) Run() (string, error) {
+	if err := remote.validateShareSecret(); err != nil {
+		return "", err
+	}
+
+	volumeCollections := types.VolumeCollection{
+		Volumes: make(map[string][]*types.VolumeInfo),
+	}
+	if remote.Volumes != "" {
+		volumes, err := remote.exportVolumes()
+		if err != nil {
+			return "", err
+		}
+		volumeCollections.Volumes = volumes
+	} else {
+		volumeInfo, err := remote.export()
+		if err != nil {
+			return "", err
+		}
+		volumeCollections.Volumes[remote.volumeName] = append(volumeCollections.Volumes[remote.volumeName], volumeInfo)
+	}
+
+	yamlData, err := yaml.Marshal(volumeCollections)
+	if err != nil {
+		return "", err
+	}
+
+	return string(yamlData), nil
+}
+
+// export creates the ConfigMap and DaemonSet for the replica exporter, and returns the
+// export of the replica on each node.
+func (remote *Exporter) export() (*types.VolumeInfo, error) {
 	newConfigMap := remote.newConfigMapForSimpleLonghorn()
 	nodeSelector, err := kubeutils.ParseNodeSelector(remote.NodeSelector)
 	if err != nil {
-		return "", errors.Wrapf(err, "failed to parse %q argument", consts.CmdOptNodeSelector)
+		return nil, errors.Wrapf(err, "failed to parse %q argument", consts.CmdOptNodeSelector)
 	}
 	newDaemonSet := remote.newDaemonSet(nodeSelector)
+	if err := kubeutils.SetPodOptions(&newDaemonSet.Spec.Template.Spec, &remote.GlobalCmdOptions); err != nil {
+		return nil, err
+	}
 
 	configMap, err := commonkube.GetConfigMap(remote.kubeClient, newConfigMap.Namespace, newConfigMap.Name)
 	if err == nil {
-		return "", errors.Errorf("ConfigMap %v already exists", configMap.Name)
+		return nil, errors.Errorf("ConfigMap %v already exists", configMap.Name)
 	} else if !apierrors.IsNotFound(err) {
-		return "", err
+		return nil, err
 	}
 
 	daemonSet, err := commonkube.GetDaemonSet(remote.kubeClient, newDaemonSet.Namespace, newDaemonSet.Name)
 	if err == nil {
-		return "", errors.Errorf("DaemonSet %v already exists", daemonSet.Name)
+		return nil, errors.Errorf("DaemonSet %v already exists", daemonSet.Name)
 	} else if !apierrors.IsNotFound(err) {
-		return "", err
+		return nil, err
 	}
 
+	_, err = kubeutils.CreateNamespace(remote.kubeClient, remote.namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	kubeutils.SetRunMetadata(remote.kubeClient, newConfigMap)
 	_, err = commonkube.CreateConfigMap(remote.kubeClient, newConfigMap)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	daemonSet, err = commonkube.CreateDaemonSet(remote.kubeClient, newDaemonSet)
+	kubeutils.SetRunMetadata(remote.kubeClient, newDaemonSet)
+	kubeutils.LogManifest(newDaemonSet)
+	daemonSet, err = kubeutils.CreateDaemonSet(remote.kubeClient, newDaemonSet, remote.MinNodes)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	err = kubeutils.MonitorDaemonSetContainer(remote.kubeClient, daemonSet, consts.ContainerNameInit, kubeutils.WaitForDaemonSetContainersExit, ptr.To(consts.ContainerConditionMaxTolerationMedium))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	err = kubeutils.MonitorDaemonSetContainer(remote.kubeClient, daemonSet, consts.ContainerNameEngine, kubeutils.WaitForDaemonSetContainersReady, ptr.To(consts.ContainerConditionMaxTolerationMedium))
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	if remote.Share != "" {
+		err = kubeutils.MonitorDaemonSetContainer(remote.kubeClient, daemonSet, consts.ContainerNameShare, kubeutils.WaitForDaemonSetContainersReady, ptr.To(consts.ContainerConditionMaxTolerationMedium))
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	podCollections, err := kubeutils.GetDaemonSetPodCollections(remote.kubeClient, daemonSet, consts.ContainerNameEngine, false, false, ptr.To(int64(2)))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	replicaExportedDirectory := filepath.Join(remote.HostTargetDirectory, remote.volumeName)
@@ -141,9 +220,6 @@ func (remote *Exporter) Run() (string, error) {
 	volumeInfo := &types.VolumeInfo{
 		Replicas: []*types.ReplicaInfo{},
 	}
-	volumeCollections := types.VolumeCollection{
-		Volumes: make(map[string][]*types.VolumeInfo),
-	}
 	for podName, collection := range podCollections.Pods {
 		logrus.Tracef("Collecting log from %s/%s", daemonSet.Namespace, podName)
 
@@ -165,21 +241,31 @@ func (remote *Exporter) Run() (string, error) {
 			}
 		}
 
-		volumeInfo.Replicas = append(volumeInfo.Replicas, replicaInfo)
-	}
-
-	volumeCollections.Volumes[remote.volumeName] = append(volumeCollections.Volumes[remote.volumeName], volumeInfo)
+		if remote.Share != "" && replicaInfo.ExportedDirectory != "" {
+			node, err := remote.kubeClient.CoreV1().Nodes().Get(context.Background(), collection.Node, metav1.GetOptions{})
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to get node %v", collection.Node)
+			}
+			replicaInfo.ExportedShare = getShareAddress(remote.Share, getNodeAddress(node), remote.volumeName)
+		}
 
-	yamlData, err := yaml.Marshal(volumeCollections)
-	if err != nil {
-		return "", err
+		volumeInfo.Replicas = append(volumeInfo.Replicas, replicaInfo)
 	}
 
-	return string(yamlData), nil
+	return volumeInfo, nil
 }
 
 // Cleanup deletes the ConfigMap and DaemonSet created for the replica exporter.
+// With volumes, the replica exporter of each volume is deleted.
 func (remote *Exporter) Cleanup() error {
+	if remote.Volumes != "" {
+		return remote.cleanupVolumes()
+	}
+	return remote.cleanup()
+}
+
+// cleanup deletes the ConfigMap and DaemonSet created for the replica exporter.
+func (remote *Exporter) cleanup() error {
 	if err := commonkube.DeleteConfigMap(remote.kubeClient, remote.namespace, remote.appName); err != nil {
 		return err
 	}
@@ -201,6 +287,7 @@ PAUSED=false
 # Function to pause the script.
 function pause() {
 	PAUSED=true
+	touch /shared/paused
 	echo "Paused. Attempting to export replica on another node"
 	sleep infinity
 }
@@ -307,6 +394,39 @@ function is_volume_in_use() {
 	echo "${_is_in_use}"
 }
 
+# Function to mount an overlay on /volume, with the replica data directory mounted read-only at
+# /volume-source as the lower directory. The writes of the replica, and of the filesystem journal
+# replayed when mounting, go to the upper directory and are discarded with the pod.
+function prepare_overlay() {
+	mkdir -p /overlay/upper /overlay/work /volume
+	mount -t overlay overlay -o lowerdir=/volume-source,upperdir=/overlay/upper,workdir=/overlay/work /volume
+}
+
+# Function to replace the volume head with an empty one whose parent is the snapshot, so the volume
+# is exported as it was when the snapshot was taken. It only changes the overlay.
+function pin_snapshot() {
+	local _snapshot_file="volume-snap-${SNAPSHOT_NAME}.img"
+	if [[ ! -f "/volume/${_snapshot_file}.meta" ]]; then
+		echo "ERROR: cannot find snapshot ${SNAPSHOT_NAME} of replica ${REPLICA_NAME}."
+		pause
+	fi
+
+	local _head_file=$(jq -r '.Head' /volume/volume.meta)
+	local _head_index=${_head_file#volume-head-}
+	_head_index=${_head_index%.img}
+	local _new_head_file=$(printf "volume-head-%03d.img" $((10#${_head_index} + 1)))
+
+	echo "Replacing volume head ${_head_file} with ${_new_head_file} on snapshot ${SNAPSHOT_NAME}"
+	rm -f "/volume/${_head_file}" "/volume/${_head_file}.meta"
+	truncate -s "${VOLUME_SIZE}" "/volume/${_new_head_file}"
+	jq -n --arg name "${_new_head_file}" --arg parent "${_snapshot_file}" --arg created "$(date -u +%Y-%m-%dT%H:%M:%SZ)" \
+		'{Name: $name, Parent: $parent, Removed: false, UserCreated: false, Created: $created, Labels: null}' > "/volume/${_new_head_file}.meta"
+
+	jq --arg head "${_new_head_file}" --arg parent "${_snapshot_file}" '.Head = $head | .Parent = $parent | .Dirty = false' \
+		/volume/volume.meta > /tmp/volume.meta
+	cat /tmp/volume.meta > /volume/volume.meta
+}
+
 function prepare_mount() {
 	mount --rbind "${HOST_DIR}/dev" /dev
 
@@ -356,6 +476,15 @@ fi
 echo "Preparing mount"
 prepare_mount
 
+if [ "${READ_ONLY}" == "true" ]; then
+  echo "Preparing read-only overlay of replica ${REPLICA_NAME}"
+  prepare_overlay
+fi
+
+if [ -n "${SNAPSHOT_NAME}" ]; then
+  pin_snapshot
+fi
+
 chmod +x /usr/local/bin/longhorn-instance-manager
 
 echo "Launching simple-longhorn for volume ${VOLUME_NAME} in the background"
@@ -375,26 +504,31 @@ sleep infinity
 	return &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      remote.appName,
-			Namespace: metav1.NamespaceDefault,
+			Namespace: remote.namespace,
 			Labels: map[string]string{
-				"app": remote.appName,
+				"app":                 remote.appName,
+				consts.LabelManagedBy: consts.LabelValueManagedBy,
 			},
 		},
 		Data: map[string]string{
-			"entrypoint.sh": entrypointScript,
+			"entrypoint.sh":     entrypointScript,
+			fileNameShareScript: shareScript,
 		},
 	}
 }
 
-// newDaemonSet prepares the DaemonSet for the replica exporter.
+// newDaemonSet prepares the DaemonSet for the replica exporter. With a share, a container serves the
+// exported directory on the share port of the nodes. Read-only, the replica data directory is
+// mounted read-only under an overlay.
 func (remote *Exporter) newDaemonSet(nodeSelector map[string]string) *appsv1.DaemonSet {
 	outputFilePath := filepath.Join(consts.VolumeMountSharedDirectory, consts.FileNameOutputJSON)
-	return &appsv1.DaemonSet{
+	daemonSet := &appsv1.DaemonSet{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      remote.appName,
 			Namespace: remote.namespace,
 			Labels: map[string]string{
-				"app": remote.appName,
+				"app":                 remote.appName,
+				consts.LabelManagedBy: consts.LabelValueManagedBy,
 			},
 		},
 		Spec: appsv1.DaemonSetSpec{
@@ -406,7 +540,8 @@ func (remote *Exporter) newDaemonSet(nodeSelector map[string]string) *appsv1.Dae
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
 					Labels: map[string]string{
-						"app": remote.appName,
+						"app":                 remote.appName,
+						consts.LabelManagedBy: consts.LabelValueManagedBy,
 					},
 				},
 				Spec: corev1.PodSpec{
@@ -470,6 +605,14 @@ func (remote *Exporter) newDaemonSet(nodeSelector map[string]string) *appsv1.Dae
 									Name:  consts.EnvLonghornVolumeName,
 									Value: remote.volumeName,
 								},
+								{
+									Name:  consts.EnvReadOnly,
+									Value: strconv.FormatBool(remote.ReadOnly),
+								},
+								{
+									Name:  consts.EnvSnapshotName,
+									Value: remote.SnapshotName,
+								},
 							},
 							VolumeMounts: []corev1.VolumeMount{
 								{
@@ -575,4 +718,44 @@ func (remote *Exporter) newDaemonSet(nodeSelector map[string]string) *appsv1.Dae
 			},
 		},
 	}
+
+	podSpec := &daemonSet.Spec.Template.Spec
+	if remote.ReadOnly {
+		setReadOnlyVolumeMounts(podSpec)
+	}
+	if remote.Share != "" {
+		podSpec.Containers = append(podSpec.Containers, remote.newShareContainer())
+	}
+
+	return daemonSet
+}
+
+// setReadOnlyVolumeMounts mounts the replica data directory read-only in the engine container, at
+// the source of the overlay the entrypoint script mounts on the volume directory, and adds the
+// empty directory holding the writes to the overlay.
+func setReadOnlyVolumeMounts(podSpec *corev1.PodSpec) {
+	for i := range podSpec.Containers {
+		container := &podSpec.Containers[i]
+		if container.Name != consts.ContainerNameEngine {
+			continue
+		}
+
+		for j := range container.VolumeMounts {
+			if container.VolumeMounts[j].Name == consts.VolumeMountVolumeName {
+				container.VolumeMounts[j].MountPath = consts.VolumeMountVolumeSourceDirectory
+				container.VolumeMounts[j].ReadOnly = true
+			}
+		}
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      consts.VolumeMountOverlayName,
+			MountPath: consts.VolumeMountOverlayDirectory,
+		})
+	}
+
+	podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+		Name: consts.VolumeMountOverlayName,
+		VolumeSource: corev1.VolumeSource{
+			EmptyDir: &corev1.EmptyDirVolumeSource{},
+		},
+	})
 }
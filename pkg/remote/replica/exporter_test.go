@@ -0,0 +1,70 @@
+package replica
+
+import (
+	"testing"
+
+	"github.com/longhorn/cli/pkg/consts"
+)
+
+func TestValidateSnapshot(t *testing.T) {
+	exporter := &Exporter{ExporterCmdOptions: ExporterCmdOptions{
+		ReplicaName:         "pvc-1-0e2603a7",
+		EngineImage:         consts.ImageEngine,
+		HostTargetDirectory: "/tmp/export",
+		SnapshotName:        "backup-1",
+	}}
+	if err := exporter.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !exporter.ReadOnly {
+		t.Error("expected --snapshot to imply --read-only")
+	}
+
+	exporter.SnapshotName = "../volume-head-000"
+	if err := exporter.Validate(); err == nil {
+		t.Error("expected an error for a snapshot name with a path separator")
+	}
+}
+
+func TestNewDaemonSetReadOnly(t *testing.T) {
+	exporter := &Exporter{
+		ExporterCmdOptions: ExporterCmdOptions{
+			ReplicaName:           "pvc-1-0e2603a7",
+			LonghornDataDirectory: "/var/lib/longhorn",
+			HostTargetDirectory:   "/tmp/export",
+			ReadOnly:              true,
+		},
+		appName: consts.AppNameReplicaExporter,
+	}
+
+	podSpec := exporter.newDaemonSet(nil).Spec.Template.Spec
+	for _, container := range podSpec.Containers {
+		if container.Name != consts.ContainerNameEngine {
+			continue
+		}
+
+		mountPaths := map[string]string{}
+		for _, volumeMount := range container.VolumeMounts {
+			mountPaths[volumeMount.Name] = volumeMount.MountPath
+			if volumeMount.Name == consts.VolumeMountVolumeName && !volumeMount.ReadOnly {
+				t.Error("expected the replica data directory to be mounted read-only")
+			}
+		}
+		if mountPaths[consts.VolumeMountVolumeName] != consts.VolumeMountVolumeSourceDirectory {
+			t.Errorf("expected the replica data directory at %v, got %v", consts.VolumeMountVolumeSourceDirectory, mountPaths[consts.VolumeMountVolumeName])
+		}
+		if mountPaths[consts.VolumeMountOverlayName] != consts.VolumeMountOverlayDirectory {
+			t.Errorf("expected the overlay directory at %v, got %v", consts.VolumeMountOverlayDirectory, mountPaths[consts.VolumeMountOverlayName])
+		}
+	}
+
+	found := false
+	for _, volume := range podSpec.Volumes {
+		if volume.Name == consts.VolumeMountOverlayName && volume.EmptyDir != nil {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("expected an empty directory volume for the overlay")
+	}
+}
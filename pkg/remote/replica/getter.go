@@ -49,7 +49,10 @@ func (remote *Getter) Init() error {
 	}
 	remote.kubeClient = kubeClient
 
-	remote.namespace = metav1.NamespaceDefault
+	remote.namespace = remote.Namespace
+	if remote.namespace == "" {
+		remote.namespace = consts.LonghornNamespace
+	}
 	remote.appName = consts.AppNameReplicaGetter
 
 	return nil
@@ -59,39 +62,65 @@ func (remote *Getter) Init() error {
 // init container and the output container completes before collecting the
 // replica information and returning it as a YAML string.
 func (remote *Getter) Run() (string, error) {
+	replicaCollections, err := remote.getReplicas()
+	if err != nil {
+		return "", err
+	}
+
+	yamlData, err := yaml.Marshal(replicaCollections)
+	if err != nil {
+		return "", err
+	}
+
+	return string(yamlData), nil
+}
+
+// getReplicas creates the DaemonSet for the replica getter, and collects the
+// replicas found on the nodes.
+func (remote *Getter) getReplicas() (*types.ReplicaCollection, error) {
 	nodeSelector, err := kubeutils.ParseNodeSelector(remote.NodeSelector)
 	if err != nil {
-		return "", errors.Wrapf(err, "failed to parse %q argument", consts.CmdOptNodeSelector)
+		return nil, errors.Wrapf(err, "failed to parse %q argument", consts.CmdOptNodeSelector)
 	}
 	newDaemonSet := remote.newDaemonSet(nodeSelector)
+	if err := kubeutils.SetPodOptions(&newDaemonSet.Spec.Template.Spec, &remote.GlobalCmdOptions); err != nil {
+		return nil, err
+	}
 
-	daemonSet, err := commonkube.CreateDaemonSet(remote.kubeClient, newDaemonSet)
+	_, err = kubeutils.CreateNamespace(remote.kubeClient, remote.namespace)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	kubeutils.SetRunMetadata(remote.kubeClient, newDaemonSet)
+	kubeutils.LogManifest(newDaemonSet)
+	daemonSet, err := kubeutils.CreateDaemonSet(remote.kubeClient, newDaemonSet, remote.MinNodes)
+	if err != nil {
+		return nil, err
 	}
 
 	err = kubeutils.MonitorDaemonSetContainer(remote.kubeClient, daemonSet, consts.ContainerNameInit, kubeutils.WaitForDaemonSetContainersExit, ptr.To(consts.ContainerConditionMaxTolerationMedium))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	err = kubeutils.MonitorDaemonSetContainer(remote.kubeClient, daemonSet, consts.ContainerNameOutput, kubeutils.WaitForDaemonSetContainersExit, ptr.To(consts.ContainerConditionMaxTolerationShort))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	podCollections, err := kubeutils.GetDaemonSetPodCollections(remote.kubeClient, daemonSet, consts.ContainerNameOutput, false, false, nil)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	replicaCollections := types.ReplicaCollection{
+	replicaCollections := &types.ReplicaCollection{
 		Replicas: make(map[string][]*types.ReplicaInfo),
 	}
 	for _, collection := range podCollections.Pods {
 		var resultMap types.ReplicaCollection
 		if err := json.Unmarshal([]byte(collection.Log), &resultMap); err != nil {
-			return "", err
+			return nil, err
 		}
 
 		for replicaName, replicaInfo := range resultMap.Replicas {
@@ -99,12 +128,7 @@ func (remote *Getter) Run() (string, error) {
 		}
 	}
 
-	yamlData, err := yaml.Marshal(replicaCollections)
-	if err != nil {
-		return "", err
-	}
-
-	return string(yamlData), nil
+	return replicaCollections, nil
 }
 
 // Cleanup deletes the DaemonSet created for the replica getter.
@@ -120,7 +144,8 @@ func (remote *Getter) newDaemonSet(nodeSelector map[string]string) *appsv1.Daemo
 			Name:      remote.appName,
 			Namespace: remote.namespace,
 			Labels: map[string]string{
-				"app": remote.appName,
+				"app":                 remote.appName,
+				consts.LabelManagedBy: consts.LabelValueManagedBy,
 			},
 		},
 		Spec: appsv1.DaemonSetSpec{
@@ -132,7 +157,8 @@ func (remote *Getter) newDaemonSet(nodeSelector map[string]string) *appsv1.Daemo
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
 					Labels: map[string]string{
-						"app": remote.appName,
+						"app":                 remote.appName,
+						consts.LabelManagedBy: consts.LabelValueManagedBy,
 					},
 				},
 				Spec: corev1.PodSpec{
@@ -171,9 +197,7 @@ func (remote *Getter) newDaemonSet(nodeSelector map[string]string) *appsv1.Daemo
 									Value: remote.LonghornDataDirectory,
 								},
 							},
-							SecurityContext: &corev1.SecurityContext{
-								Privileged: ptr.To(true),
-							},
+							SecurityContext: kubeutils.NewSecurityContext(remote.Privileged, kubeutils.CapabilitiesHostRead),
 							VolumeMounts: []corev1.VolumeMount{
 								{
 									Name:      consts.VolumeMountHostName,
@@ -0,0 +1,293 @@
+package replica
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/utils/ptr"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeclient "k8s.io/client-go/kubernetes"
+
+	commonkube "github.com/longhorn/go-common-libs/kubernetes"
+
+	longhorn "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+	lhclient "github.com/longhorn/longhorn-manager/k8s/pkg/client/clientset/versioned"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// Inspector provide functions for the replica metadata inspector.
+type Inspector struct {
+	InspectorCmdOptions
+
+	kubeClient     *kubeclient.Clientset
+	longhornClient *lhclient.Clientset
+
+	appName   string // App name of the DaemonSet.
+	namespace string
+}
+
+// InspectorCmdOptions holds the options for the command.
+type InspectorCmdOptions struct {
+	types.GlobalCmdOptions
+
+	LonghornDataDirectory string
+	LonghornNamespace     string
+	VolumeName            string
+	NodeID                string
+	Repair                bool
+}
+
+// Validate validates the command options.
+func (remote *Inspector) Validate() error {
+	if remote.VolumeName == "" {
+		return errors.Errorf("Volume name (--%s) is required", consts.CmdOptVolume)
+	}
+
+	if remote.NodeID == "" {
+		return errors.Errorf("Node name (--%s) is required", consts.CmdOptNode)
+	}
+
+	return nil
+}
+
+// Init initializes the Inspector. With Repair, it ensures that the volume is detached, so no
+// engine or replica process uses the replica files while they are repaired.
+func (remote *Inspector) Init() error {
+	kubeClient, err := kubeutils.NewKubeClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.kubeClient = kubeClient
+
+	longhornClient, err := kubeutils.NewLonghornClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.longhornClient = longhornClient
+
+	remote.namespace = remote.Namespace
+	if remote.namespace == "" {
+		remote.namespace = consts.LonghornNamespace
+	}
+	remote.appName = consts.AppNameReplicaMetaInspector
+
+	ctx := context.Background()
+	if _, err := remote.kubeClient.CoreV1().Nodes().Get(ctx, remote.NodeID, metav1.GetOptions{}); err != nil {
+		return errors.Wrapf(err, "failed to get node %v", remote.NodeID)
+	}
+
+	if !remote.Repair {
+		return nil
+	}
+
+	volume, err := remote.longhornClient.LonghornV1beta2().Volumes(remote.LonghornNamespace).Get(ctx, remote.VolumeName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		logrus.Warnf("Volume %v does not exist, make sure that no process uses its replica files", remote.VolumeName)
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to get volume %v", remote.VolumeName)
+	}
+	if volume.Status.State != longhorn.VolumeStateDetached {
+		return errors.Errorf("volume %v is %v, it must be detached before repairing its replicas", remote.VolumeName, volume.Status.State)
+	}
+
+	return nil
+}
+
+// Run creates the DaemonSet inspecting the replica metadata on the node. It ensures that the
+// init container and the output container complete, and returns the inspection of each replica
+// of the volume on the node, keyed by the replica data directory name.
+func (remote *Inspector) Run() (*types.ReplicaMetaCollection, error) {
+	newDaemonSet := remote.newDaemonSet()
+	kubeutils.SetNodeNameAffinity(&newDaemonSet.Spec.Template.Spec, []string{remote.NodeID})
+	if err := kubeutils.SetPodOptions(&newDaemonSet.Spec.Template.Spec, &remote.GlobalCmdOptions); err != nil {
+		return nil, err
+	}
+
+	_, err := kubeutils.CreateNamespace(remote.kubeClient, remote.namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	kubeutils.SetRunMetadata(remote.kubeClient, newDaemonSet)
+	kubeutils.LogManifest(newDaemonSet)
+	daemonSet, err := kubeutils.CreateDaemonSet(remote.kubeClient, newDaemonSet, remote.MinNodes)
+	if err != nil {
+		return nil, err
+	}
+
+	err = kubeutils.MonitorDaemonSetContainer(remote.kubeClient, daemonSet, consts.ContainerNameInit, kubeutils.WaitForDaemonSetContainersExit, ptr.To(consts.ContainerConditionMaxTolerationMedium))
+	if err != nil {
+		return nil, err
+	}
+
+	err = kubeutils.MonitorDaemonSetContainer(remote.kubeClient, daemonSet, consts.ContainerNameOutput, kubeutils.WaitForDaemonSetContainersExit, ptr.To(consts.ContainerConditionMaxTolerationShort))
+	if err != nil {
+		return nil, err
+	}
+
+	podCollections, err := kubeutils.GetDaemonSetPodCollections(remote.kubeClient, daemonSet, consts.ContainerNameOutput, false, false, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	collection := &types.ReplicaMetaCollection{
+		Replicas: make(map[string]*types.ReplicaMetaInspection),
+	}
+	for _, podCollection := range podCollections.Pods {
+		var result types.ReplicaMetaCollection
+		if err := json.Unmarshal([]byte(podCollection.Log), &result); err != nil {
+			return nil, err
+		}
+
+		for replicaName, inspection := range result.Replicas {
+			collection.Replicas[replicaName] = inspection
+		}
+	}
+
+	return collection, nil
+}
+
+// Cleanup deletes the DaemonSet created for the replica metadata inspector.
+func (remote *Inspector) Cleanup() error {
+	return commonkube.DeleteDaemonSet(remote.kubeClient, remote.namespace, remote.appName)
+}
+
+// newDaemonSet prepares the DaemonSet for the replica metadata inspector. The host directory is
+// only mounted writable with Repair.
+func (remote *Inspector) newDaemonSet() *appsv1.DaemonSet {
+	outputFilePath := filepath.Join(consts.VolumeMountSharedDirectory, consts.FileNameOutputJSON)
+
+	securityContext := kubeutils.NewSecurityContext(remote.Privileged, kubeutils.CapabilitiesHostRead)
+	if remote.Repair {
+		securityContext = kubeutils.NewSecurityContext(remote.Privileged, kubeutils.CapabilitiesHostRead, kubeutils.CapabilitiesHostWrite)
+	}
+
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      remote.appName,
+			Namespace: remote.namespace,
+			Labels: map[string]string{
+				"app":                 remote.appName,
+				consts.LabelManagedBy: consts.LabelValueManagedBy,
+			},
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app": remote.appName,
+				},
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						"app":                 remote.appName,
+						consts.LabelManagedBy: consts.LabelValueManagedBy,
+					},
+				},
+				Spec: corev1.PodSpec{
+					InitContainers: []corev1.Container{
+						{
+							Name:    consts.ContainerNameInit,
+							Image:   remote.Image,
+							Command: []string{consts.CmdLonghornctlLocal, consts.SubCmdInspect, consts.SubCmdReplicaMeta},
+							Env: []corev1.EnvVar{
+								{
+									Name: consts.EnvCurrentNodeID,
+									ValueFrom: &corev1.EnvVarSource{
+										FieldRef: &corev1.ObjectFieldSelector{
+											FieldPath: "spec.nodeName",
+										},
+									},
+								},
+								{
+									Name:  consts.EnvLogLevel,
+									Value: remote.LogLevel,
+								},
+								{
+									Name:  consts.EnvOutputFilePath,
+									Value: outputFilePath,
+								},
+								{
+									Name:  consts.EnvLonghornVolumeName,
+									Value: remote.VolumeName,
+								},
+								{
+									Name:  consts.EnvLonghornDataDirectory,
+									Value: remote.LonghornDataDirectory,
+								},
+								{
+									Name:  consts.EnvRepair,
+									Value: strconv.FormatBool(remote.Repair),
+								},
+							},
+							SecurityContext: securityContext,
+							VolumeMounts: []corev1.VolumeMount{
+								{
+									Name:      consts.VolumeMountHostName,
+									MountPath: consts.VolumeMountHostDirectory,
+									ReadOnly:  !remote.Repair,
+								},
+								{
+									Name:      consts.VolumeMountSharedName,
+									MountPath: consts.VolumeMountSharedDirectory,
+								},
+							},
+						},
+						{
+							Name:    consts.ContainerNameOutput,
+							Image:   remote.Image,
+							Command: []string{"cat", outputFilePath},
+							Env:     []corev1.EnvVar{},
+							VolumeMounts: []corev1.VolumeMount{
+								{
+									Name:      consts.VolumeMountSharedName,
+									MountPath: consts.VolumeMountSharedDirectory,
+								},
+							},
+						},
+					},
+					Containers: []corev1.Container{
+						{
+							Name:  consts.ContainerNamePause,
+							Image: consts.ImagePause,
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: consts.VolumeMountHostName,
+							VolumeSource: corev1.VolumeSource{
+								HostPath: &corev1.HostPathVolumeSource{
+									Path: "/",
+								},
+							},
+						},
+						{
+							Name: consts.VolumeMountSharedName,
+							VolumeSource: corev1.VolumeSource{
+								EmptyDir: &corev1.EmptyDirVolumeSource{},
+							},
+						},
+					},
+				},
+			},
+			UpdateStrategy: appsv1.DaemonSetUpdateStrategy{
+				Type: appsv1.RollingUpdateDaemonSetStrategyType,
+			},
+		},
+	}
+}
@@ -0,0 +1,288 @@
+package replica
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/utils/ptr"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/longhorn/cli/pkg/consts"
+)
+
+const (
+	// fileNameShareScript is the script serving the exported directory, in the ConfigMap of the
+	// replica exporter.
+	fileNameShareScript = "share.sh"
+
+	// fileNameShareReady and fileNamePaused are created in the shared directory once the share is
+	// served, or once the engine container paused since the replica cannot be exported on the node.
+	fileNameShareReady = "share-ready"
+	fileNamePaused     = "paused"
+)
+
+// shareScript serves the exported directory of the volume over NFS with nfs-ganesha, or over SMB
+// with Samba, read-only and to the allowed CIDRs only. It waits for the engine container to mount
+// the volume, and does nothing on the nodes where the engine container paused.
+const shareScript = `#!/bin/sh
+set -eu
+
+EXPORTED_DIR="/host-exporter/${VOLUME_NAME}"
+READY_FILE="/shared/share-ready"
+PAUSED_FILE="/shared/paused"
+
+echo "Waiting for ${EXPORTED_DIR} to be mounted..."
+until [ -d "${EXPORTED_DIR}/lost+found" ]; do
+	if [ -f "${PAUSED_FILE}" ]; then
+		echo "The replica is not exported on this node"
+		while true; do sleep 3600; done
+	fi
+	sleep 1
+done
+
+case "${SHARE_PROTOCOL}" in
+nfs)
+	cat > /tmp/ganesha.conf <<EOF
+NFS_CORE_PARAM {
+	Protocols = 4;
+	NFS_Port = ${SHARE_PORT};
+}
+NFSV4 {
+	Graceless = true;
+}
+EXPORT {
+	Export_Id = 1;
+	Path = "${EXPORTED_DIR}";
+	Pseudo = "/${VOLUME_NAME}";
+	Protocols = 4;
+	Transports = TCP;
+	Access_Type = None;
+	SecType = sys;
+	Squash = No_Root_Squash;
+	FSAL {
+		Name = VFS;
+	}
+	CLIENT {
+		Clients = ${SHARE_ALLOWED_CIDRS};
+		Access_Type = RO;
+	}
+}
+EOF
+	echo "Serving ${EXPORTED_DIR} over NFS to ${SHARE_ALLOWED_CIDRS}"
+	touch "${READY_FILE}"
+	exec ganesha.nfsd -F -L /dev/stdout -f /tmp/ganesha.conf
+	;;
+smb)
+	cat > /tmp/smb.conf <<EOF
+[global]
+	server role = standalone server
+	disable netbios = yes
+	smb ports = ${SHARE_PORT}
+	map to guest = never
+	hosts allow = $(echo "${SHARE_ALLOWED_CIDRS}" | tr ',' ' ')
+	hosts deny = ALL
+[${VOLUME_NAME}]
+	path = ${EXPORTED_DIR}
+	read only = yes
+	valid users = ${SHARE_USERNAME}
+	force user = root
+EOF
+	adduser -D -H -s /sbin/nologin "${SHARE_USERNAME}" 2>/dev/null || useradd -M -s /sbin/nologin "${SHARE_USERNAME}"
+	printf '%s\n%s\n' "${SHARE_PASSWORD}" "${SHARE_PASSWORD}" | smbpasswd -c /tmp/smb.conf -s -a "${SHARE_USERNAME}"
+
+	echo "Serving ${EXPORTED_DIR} over SMB to ${SHARE_ALLOWED_CIDRS}"
+	touch "${READY_FILE}"
+	exec smbd --foreground --no-process-group --debug-stdout -s /tmp/smb.conf
+	;;
+*)
+	echo "ERROR: unsupported share protocol ${SHARE_PROTOCOL}"
+	exit 1
+	;;
+esac
+`
+
+// validateShare validates the share options, and defaults the share image and username.
+func (remote *Exporter) validateShare() error {
+	switch remote.Share {
+	case "":
+		return nil
+	case consts.ShareProtocolNFS:
+		if remote.ShareImage == "" {
+			remote.ShareImage = consts.ImageShareManager
+		}
+	case consts.ShareProtocolSMB:
+		if remote.ShareImage == "" {
+			return errors.Errorf("Share image (--%s) is required with --%s=%s, Longhorn does not ship a Samba image", consts.CmdOptShareImage, consts.CmdOptShare, consts.ShareProtocolSMB)
+		}
+		if remote.ShareUsername == "" {
+			remote.ShareUsername = consts.ShareUsernameDefault
+		}
+		if remote.ShareSecret == "" {
+			return errors.Errorf("Share secret (--%s) is required with --%s=%s", consts.CmdOptShareSecret, consts.CmdOptShare, consts.ShareProtocolSMB)
+		}
+	default:
+		return errors.Errorf("invalid --%s %q, it must be %s or %s", consts.CmdOptShare, remote.Share, consts.ShareProtocolNFS, consts.ShareProtocolSMB)
+	}
+
+	if remote.ShareAllowedCIDRs == "" {
+		return errors.Errorf("Allowed CIDRs (--%s) are required with --%s, to limit the clients of the share", consts.CmdOptShareAllowedCIDRs, consts.CmdOptShare)
+	}
+	cidrs := []string{}
+	for _, cidr := range strings.Split(remote.ShareAllowedCIDRs, consts.CmdOptSeperator) {
+		cidr = strings.TrimSpace(cidr)
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return errors.Wrapf(err, "invalid --%s", consts.CmdOptShareAllowedCIDRs)
+		}
+		cidrs = append(cidrs, cidr)
+	}
+	remote.ShareAllowedCIDRs = strings.Join(cidrs, consts.CmdOptSeperator)
+
+	return nil
+}
+
+// getSharePort returns the port the share is served on, on the host network of the nodes.
+func (remote *Exporter) getSharePort() int32 {
+	if remote.Share == consts.ShareProtocolSMB {
+		return consts.SharePortSMB
+	}
+	return consts.SharePortNFS
+}
+
+// validateShareSecret validates the Secret holding the password of the user of the SMB share
+// exists in the namespace with the password, before the share containers read it.
+func (remote *Exporter) validateShareSecret() error {
+	if remote.Share != consts.ShareProtocolSMB {
+		return nil
+	}
+
+	secret, err := remote.kubeClient.CoreV1().Secrets(remote.namespace).Get(context.Background(), remote.ShareSecret, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to get share secret %v", remote.ShareSecret)
+	}
+	if len(secret.Data[consts.EnvSharePassword]) == 0 {
+		return errors.Errorf("share secret %v has no %v key", remote.ShareSecret, consts.EnvSharePassword)
+	}
+	return nil
+}
+
+// newShareContainer prepares the container serving the exported directory on the share port of
+// the node. It sees the mount of the engine container through the host exporter directory.
+func (remote *Exporter) newShareContainer() corev1.Container {
+	container := corev1.Container{
+		Name:    consts.ContainerNameShare,
+		Image:   remote.ShareImage,
+		Command: []string{filepath.Join(consts.VolumeMountEntrypointDirectory, fileNameShareScript)},
+		Env: []corev1.EnvVar{
+			{
+				Name:  consts.EnvLonghornVolumeName,
+				Value: remote.volumeName,
+			},
+			{
+				Name:  consts.EnvShareProtocol,
+				Value: remote.Share,
+			},
+			{
+				Name:  consts.EnvSharePort,
+				Value: fmt.Sprint(remote.getSharePort()),
+			},
+			{
+				Name:  consts.EnvShareAllowedCIDRs,
+				Value: remote.ShareAllowedCIDRs,
+			},
+		},
+		Ports: []corev1.ContainerPort{
+			{
+				Name:          remote.Share,
+				ContainerPort: remote.getSharePort(),
+				HostPort:      remote.getSharePort(),
+				Protocol:      corev1.ProtocolTCP,
+			},
+		},
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      consts.VolumeMountEntrypointName,
+				MountPath: consts.VolumeMountEntrypointDirectory,
+			},
+			{
+				Name:      consts.VolumeMountSharedName,
+				MountPath: consts.VolumeMountSharedDirectory,
+			},
+			{
+				Name:             consts.VolumeMountHostExporterName,
+				MountPath:        consts.VolumeMountHostExporterDirectory,
+				MountPropagation: ptr.To(corev1.MountPropagationHostToContainer),
+			},
+		},
+		SecurityContext: &corev1.SecurityContext{
+			Privileged: ptr.To(true),
+		},
+		ReadinessProbe: &corev1.Probe{
+			ProbeHandler: corev1.ProbeHandler{
+				Exec: &corev1.ExecAction{
+					Command: []string{
+						"/bin/sh", "-c",
+						fmt.Sprintf("[ -f %[1]s/%[2]s ] || [ -f %[1]s/%[3]s ]", consts.VolumeMountSharedDirectory, fileNameShareReady, fileNamePaused),
+					},
+				},
+			},
+			InitialDelaySeconds: 10,
+			PeriodSeconds:       10,
+			TimeoutSeconds:      5,
+			SuccessThreshold:    1,
+			FailureThreshold:    3,
+		},
+	}
+
+	if remote.Share == consts.ShareProtocolSMB {
+		container.Env = append(container.Env,
+			corev1.EnvVar{
+				Name:  consts.EnvShareUsername,
+				Value: remote.ShareUsername,
+			},
+			corev1.EnvVar{
+				Name: consts.EnvSharePassword,
+				ValueFrom: &corev1.EnvVarSource{
+					SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{
+							Name: remote.ShareSecret,
+						},
+						Key: consts.EnvSharePassword,
+					},
+				},
+			},
+		)
+	}
+
+	return container
+}
+
+// getNodeAddress returns the address the node is reached at, preferring its internal IP.
+func getNodeAddress(node *corev1.Node) string {
+	for _, addressType := range []corev1.NodeAddressType{corev1.NodeInternalIP, corev1.NodeExternalIP, corev1.NodeHostName} {
+		for _, address := range node.Status.Addresses {
+			if address.Type == addressType && address.Address != "" {
+				return address.Address
+			}
+		}
+	}
+	return node.Name
+}
+
+// getShareAddress returns the address clients mount the share of the volume from, as
+// "<address>:/<volume>" for NFS and "//<address>/<volume>" for SMB.
+func getShareAddress(protocol, nodeAddress, volumeName string) string {
+	if protocol == consts.ShareProtocolSMB {
+		return fmt.Sprintf("//%s/%s", nodeAddress, volumeName)
+	}
+	if strings.Contains(nodeAddress, ":") {
+		nodeAddress = "[" + nodeAddress + "]"
+	}
+	return fmt.Sprintf("%s:/%s", nodeAddress, volumeName)
+}
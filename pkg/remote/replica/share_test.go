@@ -0,0 +1,124 @@
+package replica
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/longhorn/cli/pkg/consts"
+)
+
+func TestValidateShare(t *testing.T) {
+	tests := map[string]struct {
+		options       ExporterCmdOptions
+		expectedError bool
+		expected      ExporterCmdOptions
+	}{
+		"no share": {},
+		"nfs": {
+			options:  ExporterCmdOptions{Share: "nfs", ShareAllowedCIDRs: "10.0.0.0/8, 192.168.1.0/24"},
+			expected: ExporterCmdOptions{Share: "nfs", ShareAllowedCIDRs: "10.0.0.0/8,192.168.1.0/24", ShareImage: consts.ImageShareManager},
+		},
+		"smb": {
+			options:  ExporterCmdOptions{Share: "smb", ShareAllowedCIDRs: "fd00::/8", ShareSecret: "share", ShareImage: "samba:4"},
+			expected: ExporterCmdOptions{Share: "smb", ShareAllowedCIDRs: "fd00::/8", ShareSecret: "share", ShareImage: "samba:4", ShareUsername: consts.ShareUsernameDefault},
+		},
+		"unsupported protocol": {
+			options:       ExporterCmdOptions{Share: "iscsi", ShareAllowedCIDRs: "10.0.0.0/8"},
+			expectedError: true,
+		},
+		"no allowed CIDRs": {
+			options:       ExporterCmdOptions{Share: "nfs"},
+			expectedError: true,
+		},
+		"invalid CIDR": {
+			options:       ExporterCmdOptions{Share: "nfs", ShareAllowedCIDRs: "10.0.0.1"},
+			expectedError: true,
+		},
+		"smb without secret": {
+			options:       ExporterCmdOptions{Share: "smb", ShareAllowedCIDRs: "10.0.0.0/8", ShareImage: "samba:4"},
+			expectedError: true,
+		},
+		"smb without image": {
+			options:       ExporterCmdOptions{Share: "smb", ShareAllowedCIDRs: "10.0.0.0/8", ShareSecret: "share"},
+			expectedError: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			exporter := &Exporter{ExporterCmdOptions: test.options}
+			err := exporter.validateShare()
+			if test.expectedError {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if exporter.ExporterCmdOptions != test.expected {
+				t.Errorf("expected %+v, got %+v", test.expected, exporter.ExporterCmdOptions)
+			}
+		})
+	}
+}
+
+func TestGetShareAddress(t *testing.T) {
+	tests := []struct {
+		protocol    string
+		nodeAddress string
+		expected    string
+	}{
+		{protocol: "nfs", nodeAddress: "10.0.2.123", expected: "10.0.2.123:/pvc-1"},
+		{protocol: "nfs", nodeAddress: "fd00::7", expected: "[fd00::7]:/pvc-1"},
+		{protocol: "smb", nodeAddress: "10.0.2.123", expected: "//10.0.2.123/pvc-1"},
+	}
+
+	for _, test := range tests {
+		if address := getShareAddress(test.protocol, test.nodeAddress, "pvc-1"); address != test.expected {
+			t.Errorf("getShareAddress(%q, %q) = %q, expected %q", test.protocol, test.nodeAddress, address, test.expected)
+		}
+	}
+}
+
+func TestGetNodeAddress(t *testing.T) {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: corev1.NodeStatus{
+			Addresses: []corev1.NodeAddress{
+				{Type: corev1.NodeHostName, Address: "node-1.example.com"},
+				{Type: corev1.NodeExternalIP, Address: "203.0.113.7"},
+				{Type: corev1.NodeInternalIP, Address: "10.0.2.123"},
+			},
+		},
+	}
+	if address := getNodeAddress(node); address != "10.0.2.123" {
+		t.Errorf("expected the internal IP, got %q", address)
+	}
+
+	node.Status.Addresses = nil
+	if address := getNodeAddress(node); address != "node-1" {
+		t.Errorf("expected the node name without addresses, got %q", address)
+	}
+}
+
+func TestNewShareContainerPassword(t *testing.T) {
+	exporter := &Exporter{ExporterCmdOptions: ExporterCmdOptions{Share: "smb", ShareUsername: "longhorn", ShareSecret: "share"}}
+	container := exporter.newShareContainer()
+
+	var password *corev1.EnvVar
+	for i := range container.Env {
+		if container.Env[i].Name == consts.EnvSharePassword {
+			password = &container.Env[i]
+		}
+	}
+	if password == nil || password.Value != "" || password.ValueFrom == nil || password.ValueFrom.SecretKeyRef == nil {
+		t.Fatalf("expected the password from a secret key, got %+v", password)
+	}
+	if ref := password.ValueFrom.SecretKeyRef; ref.Name != "share" || ref.Key != consts.EnvSharePassword {
+		t.Errorf("unexpected secret key %+v", ref)
+	}
+}
@@ -0,0 +1,222 @@
+package replica
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+)
+
+// validateVolumes validates the options to export a replica of each of the volumes.
+func (remote *Exporter) validateVolumes() error {
+	if remote.ReplicaName != "" {
+		return errors.Errorf("--%s cannot be used with --%s", consts.CmdOptName, consts.CmdOptVolumes)
+	}
+
+	if remote.SnapshotName != "" {
+		return errors.Errorf("--%s cannot be used with --%s, since snapshot names are specific to a volume", consts.CmdOptSnapshot, consts.CmdOptVolumes)
+	}
+
+	if remote.Share != "" {
+		return errors.Errorf("--%s cannot be used with --%s, since the shares of the volumes would be served on the same port of the nodes", consts.CmdOptShare, consts.CmdOptVolumes)
+	}
+
+	if remote.Concurrency < 1 {
+		return errors.Errorf("invalid --%s %d, it must be positive", consts.CmdOptConcurrency, remote.Concurrency)
+	}
+
+	return nil
+}
+
+// getVolumeNames returns the volumes to export, in the given order and without duplicates.
+func (remote *Exporter) getVolumeNames() []string {
+	volumeNames := []string{}
+	seen := map[string]bool{}
+	for _, volumeName := range strings.Split(remote.Volumes, consts.CmdOptSeperator) {
+		volumeName = strings.TrimSpace(volumeName)
+		if volumeName == "" || seen[volumeName] {
+			continue
+		}
+		seen[volumeName] = true
+		volumeNames = append(volumeNames, volumeName)
+	}
+	return volumeNames
+}
+
+// getVolumeAppName returns the app name of the replica exporter of the volume. The volume name is
+// hashed to keep the name within the length of a label value.
+func getVolumeAppName(volumeName string) string {
+	sum := sha256.Sum256([]byte(volumeName))
+	return fmt.Sprintf("%s-%x", consts.AppNameReplicaExporter, sum[:4])
+}
+
+// newVolumeExporter returns the replica exporter exporting the replica of the volume.
+func (remote *Exporter) newVolumeExporter(volumeName, replicaName string) *Exporter {
+	exporter := &Exporter{
+		ExporterCmdOptions: remote.ExporterCmdOptions,
+		kubeClient:         remote.kubeClient,
+		appName:            getVolumeAppName(volumeName),
+		namespace:          remote.namespace,
+		volumeName:         volumeName,
+	}
+	exporter.Volumes = ""
+	exporter.ReplicaName = replicaName
+	return exporter
+}
+
+// exportVolumes exports a replica of each volume, running at most Concurrency replica exporters
+// at the same time. A volume failing to export is reported in its result, so the other volumes
+// are still exported.
+func (remote *Exporter) exportVolumes() (map[string][]*types.VolumeInfo, error) {
+	volumeNames := remote.getVolumeNames()
+
+	logrus.Infof("Finding the replicas of %d volumes", len(volumeNames))
+	replicaNames, err := remote.getVolumeReplicaNames(volumeNames)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get replicas")
+	}
+
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, remote.Concurrency)
+
+	volumes := map[string][]*types.VolumeInfo{}
+	for _, volumeName := range volumeNames {
+		replicaName, ok := replicaNames[volumeName]
+		if !ok {
+			logrus.WithField("volume", volumeName).Warn("Cannot find a replica to export")
+			volumes[volumeName] = []*types.VolumeInfo{
+				{
+					Error: "cannot find a replica of the volume that is healthy and not in use",
+				},
+			}
+			continue
+		}
+
+		wg.Add(1)
+		go func(volumeName, replicaName string) {
+			defer wg.Done()
+
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			log := logrus.WithFields(logrus.Fields{"volume": volumeName, "replica": replicaName})
+			log.Info("Exporting replica")
+
+			volumeInfo, err := remote.newVolumeExporter(volumeName, replicaName).export()
+			if err != nil {
+				log.WithError(err).Warn("Failed to export replica")
+				volumeInfo = &types.VolumeInfo{
+					Error: fmt.Sprintf("failed to export replica %v: %v", replicaName, err),
+				}
+			} else {
+				log.Info("Exported replica")
+			}
+
+			lock.Lock()
+			volumes[volumeName] = append(volumes[volumeName], volumeInfo)
+			lock.Unlock()
+		}(volumeName, replicaName)
+	}
+	wg.Wait()
+
+	return volumes, nil
+}
+
+// getVolumeReplicaNames runs the replica getter, and returns the name of the replica data
+// directory to export of each volume.
+func (remote *Exporter) getVolumeReplicaNames(volumeNames []string) (map[string]string, error) {
+	getter := &Getter{
+		GetterCmdOptions: GetterCmdOptions{
+			GlobalCmdOptions:      remote.GlobalCmdOptions,
+			LonghornDataDirectory: remote.LonghornDataDirectory,
+		},
+	}
+	if err := getter.Init(); err != nil {
+		return nil, err
+	}
+	if err := getter.Cleanup(); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := getter.Cleanup(); err != nil {
+			logrus.WithError(err).Warn("Failed to cleanup replica getter")
+		}
+	}()
+
+	replicaCollection, err := getter.getReplicas()
+	if err != nil {
+		return nil, err
+	}
+
+	replicaNames := map[string]string{}
+	for _, volumeName := range volumeNames {
+		if replicaName := pickVolumeReplica(replicaCollection, volumeName); replicaName != "" {
+			replicaNames[volumeName] = replicaName
+		}
+	}
+	return replicaNames, nil
+}
+
+// pickVolumeReplica returns the replica to export of the volume, among the replicas without
+// error, not in use and with a size. Replicas that are not rebuilding are preferred.
+func pickVolumeReplica(replicaCollection *types.ReplicaCollection, volumeName string) string {
+	type candidate struct {
+		name       string
+		rebuilding bool
+	}
+
+	candidates := []candidate{}
+	for replicaName, replicaInfos := range replicaCollection.Replicas {
+		for _, replicaInfo := range replicaInfos {
+			if replicaInfo.VolumeName != volumeName || replicaInfo.Error != "" {
+				continue
+			}
+			if replicaInfo.IsInUse != nil && *replicaInfo.IsInUse {
+				continue
+			}
+			if replicaInfo.Metadata == nil || replicaInfo.Metadata.Size == 0 || replicaInfo.Metadata.Error != "" {
+				continue
+			}
+			candidates = append(candidates, candidate{name: replicaName, rebuilding: replicaInfo.Metadata.Rebuilding})
+		}
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].rebuilding != candidates[j].rebuilding {
+			return !candidates[i].rebuilding
+		}
+		return candidates[i].name < candidates[j].name
+	})
+	return candidates[0].name
+}
+
+// cleanupVolumes deletes the replica exporter of each volume. A volume failing to stop does not
+// prevent the other volumes from stopping.
+func (remote *Exporter) cleanupVolumes() error {
+	failedVolumeNames := []string{}
+	for _, volumeName := range remote.getVolumeNames() {
+		log := logrus.WithField("volume", volumeName)
+		if err := remote.newVolumeExporter(volumeName, "").cleanup(); err != nil {
+			log.WithError(err).Warn("Failed to stop replica exporter")
+			failedVolumeNames = append(failedVolumeNames, volumeName)
+			continue
+		}
+		log.Info("Stopped replica exporter")
+	}
+
+	if len(failedVolumeNames) != 0 {
+		return errors.Errorf("failed to stop the replica exporter of volumes %v", strings.Join(failedVolumeNames, ", "))
+	}
+	return nil
+}
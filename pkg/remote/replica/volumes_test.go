@@ -0,0 +1,82 @@
+package replica
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/utils/ptr"
+
+	lhmgrutil "github.com/longhorn/longhorn-manager/util"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func TestPickVolumeReplica(t *testing.T) {
+	newReplicaInfo := func(volumeName string, meta *lhmgrutil.VolumeMeta) []*types.ReplicaInfo {
+		return []*types.ReplicaInfo{{VolumeName: volumeName, IsInUse: ptr.To(false), Metadata: meta}}
+	}
+
+	tests := map[string]struct {
+		replicas map[string][]*types.ReplicaInfo
+		expected string
+	}{
+		"first by name": {
+			replicas: map[string][]*types.ReplicaInfo{
+				"pvc-1-b0000000": newReplicaInfo("pvc-1", &lhmgrutil.VolumeMeta{Size: 1024}),
+				"pvc-1-a0000000": newReplicaInfo("pvc-1", &lhmgrutil.VolumeMeta{Size: 1024}),
+				"pvc-2-00000000": newReplicaInfo("pvc-2", &lhmgrutil.VolumeMeta{Size: 1024}),
+			},
+			expected: "pvc-1-a0000000",
+		},
+		"not rebuilding": {
+			replicas: map[string][]*types.ReplicaInfo{
+				"pvc-1-a0000000": newReplicaInfo("pvc-1", &lhmgrutil.VolumeMeta{Size: 1024, Rebuilding: true}),
+				"pvc-1-b0000000": newReplicaInfo("pvc-1", &lhmgrutil.VolumeMeta{Size: 1024}),
+			},
+			expected: "pvc-1-b0000000",
+		},
+		"in use, failed or empty": {
+			replicas: map[string][]*types.ReplicaInfo{
+				"pvc-1-a0000000": {{VolumeName: "pvc-1", IsInUse: ptr.To(true), Metadata: &lhmgrutil.VolumeMeta{Size: 1024}}},
+				"pvc-1-b0000000": {{VolumeName: "pvc-1", Error: "failed to read volume.meta"}},
+				"pvc-1-c0000000": newReplicaInfo("pvc-1", &lhmgrutil.VolumeMeta{}),
+			},
+		},
+		"no replica": {
+			replicas: map[string][]*types.ReplicaInfo{
+				"pvc-2-00000000": newReplicaInfo("pvc-2", &lhmgrutil.VolumeMeta{Size: 1024}),
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			replicaName := pickVolumeReplica(&types.ReplicaCollection{Replicas: test.replicas}, "pvc-1")
+			if replicaName != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, replicaName)
+			}
+		})
+	}
+}
+
+func TestGetVolumeNames(t *testing.T) {
+	exporter := &Exporter{ExporterCmdOptions: ExporterCmdOptions{Volumes: "pvc-1, pvc-2,,pvc-1,pvc-3"}}
+	expected := []string{"pvc-1", "pvc-2", "pvc-3"}
+	if volumeNames := exporter.getVolumeNames(); !reflect.DeepEqual(volumeNames, expected) {
+		t.Errorf("expected %v, got %v", expected, volumeNames)
+	}
+}
+
+func TestGetVolumeAppName(t *testing.T) {
+	appName := getVolumeAppName("pvc-48a6457d-585e-423b-b530-bbc68a5f948a")
+	if len(appName) > 63 {
+		t.Errorf("expected the app name %q to be a valid label value", appName)
+	}
+	if !strings.HasPrefix(appName, "longhorn-replica-exporter-") {
+		t.Errorf("expected the app name %q to start with the replica exporter app name", appName)
+	}
+	if appName == getVolumeAppName("pvc-7b3a1c2e-9f4d-4e0a-8c6b-2d1e5f7a9b3c") {
+		t.Error("expected different app names for different volumes")
+	}
+}
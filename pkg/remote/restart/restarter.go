@@ -0,0 +1,340 @@
+package restart
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	kubeclient "k8s.io/client-go/kubernetes"
+
+	longhorn "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+	lhclient "github.com/longhorn/longhorn-manager/k8s/pkg/client/clientset/versioned"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// Longhorn components that can be restarted.
+const (
+	ComponentCSI             = "csi"
+	ComponentInstanceManager = "instance-manager"
+	ComponentManager         = "manager"
+)
+
+// componentLabelSelectors are the label selectors of the pods of each component.
+var componentLabelSelectors = map[string]string{
+	ComponentCSI:             consts.LonghornLabelSelectorCSI,
+	ComponentInstanceManager: consts.LonghornLabelSelectorInstanceManager,
+	ComponentManager:         consts.LonghornLabelSelectorManager,
+}
+
+// csiPluginAppName is the app label of the CSI plugin DaemonSet pods. The other CSI pods are the
+// sidecar Deployments.
+const csiPluginAppName = "longhorn-csi-plugin"
+
+// restarterPollInterval is the interval between the checks of the pods and the volumes.
+const restarterPollInterval = 2 * time.Second
+
+// Restarter provide functions for rolling restarts of the pods of a Longhorn component.
+type Restarter struct {
+	RestarterCmdOptions
+
+	kubeClient     *kubeclient.Clientset
+	longhornClient *lhclient.Clientset
+
+	pods []corev1.Pod
+}
+
+// RestarterCmdOptions holds the options for the command.
+type RestarterCmdOptions struct {
+	types.GlobalCmdOptions
+
+	LonghornNamespace string
+	Component         string
+	NodeName          string        // Only restart the pods on the node when set.
+	Timeout           time.Duration // Maximum time to wait for each pod, and for the volumes before and after it.
+}
+
+// Validate validates the command options.
+func (remote *Restarter) Validate() error {
+	if _, ok := componentLabelSelectors[remote.Component]; !ok {
+		return errors.Errorf("unsupported component %q (--%s), supported components: %s", remote.Component, consts.CmdOptComponent,
+			strings.Join([]string{ComponentManager, ComponentCSI, ComponentInstanceManager}, ", "))
+	}
+
+	return nil
+}
+
+// Init initializes the Restarter, and lists the pods to restart in the order of the restart.
+func (remote *Restarter) Init() error {
+	kubeClient, err := kubeutils.NewKubeClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.kubeClient = kubeClient
+
+	longhornClient, err := kubeutils.NewLonghornClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.longhornClient = longhornClient
+
+	listOptions := metav1.ListOptions{LabelSelector: componentLabelSelectors[remote.Component]}
+	if remote.NodeName != "" {
+		listOptions.FieldSelector = fields.OneTermEqualSelector("spec.nodeName", remote.NodeName).String()
+	}
+	podList, err := remote.kubeClient.CoreV1().Pods(remote.LonghornNamespace).List(context.Background(), listOptions)
+	if err != nil {
+		return errors.Wrapf(err, "failed to list pods of component %v", remote.Component)
+	}
+	if len(podList.Items) == 0 {
+		return errors.Errorf("no pod of component %v found", remote.Component)
+	}
+
+	remote.pods = sortRestartPods(remote.Component, podList.Items)
+	return nil
+}
+
+// Plan returns the pods to restart, in the order of the restart.
+func (remote *Restarter) Plan() []types.RestartStep {
+	steps := make([]types.RestartStep, 0, len(remote.pods))
+	for _, pod := range remote.pods {
+		steps = append(steps, types.RestartStep{Node: pod.Spec.NodeName, Pod: pod.Name, Owner: getOwner(&pod)})
+	}
+	return steps
+}
+
+// Run restarts the pods one at a time, and waits for each replacement to be ready before the next
+// one. For the instance managers, it also waits for the volumes of the node to be healthy before
+// and after each restart.
+func (remote *Restarter) Run(ctx context.Context) error {
+	for _, pod := range remote.pods {
+		log := logrus.WithFields(logrus.Fields{"node": pod.Spec.NodeName, "pod": pod.Name})
+
+		if remote.Component == ComponentInstanceManager {
+			if err := remote.waitVolumesHealthy(ctx, pod.Spec.NodeName, true); err != nil {
+				return err
+			}
+		}
+
+		readyPods, err := remote.countReadyPods(ctx, &pod)
+		if err != nil {
+			return err
+		}
+
+		log.Info("Restarting pod")
+		if err := remote.kubeClient.CoreV1().Pods(pod.Namespace).Delete(ctx, pod.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to delete pod %v", pod.Name)
+		}
+
+		log.Info("Waiting for the replacement pod to be ready")
+		if err := remote.waitPodReplaced(ctx, &pod, readyPods); err != nil {
+			return err
+		}
+
+		if remote.Component == ComponentInstanceManager {
+			if err := remote.waitVolumesHealthy(ctx, pod.Spec.NodeName, false); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// countReadyPods returns the number of ready pods of the controller of the pod, including the pod.
+func (remote *Restarter) countReadyPods(ctx context.Context, pod *corev1.Pod) (int, error) {
+	pods, err := remote.listSiblingPods(ctx, pod)
+	if err != nil {
+		return 0, err
+	}
+
+	ready := 0
+	for _, sibling := range pods {
+		if isPodReady(&sibling) {
+			ready++
+		}
+	}
+	return ready, nil
+}
+
+// waitPodReplaced waits for the pod to be deleted, and the controller of the pod to have as many
+// ready pods as before the deletion.
+func (remote *Restarter) waitPodReplaced(ctx context.Context, pod *corev1.Pod, readyPods int) error {
+	err := wait.PollUntilContextTimeout(ctx, restarterPollInterval, remote.Timeout, true, func(ctx context.Context) (bool, error) {
+		pods, err := remote.listSiblingPods(ctx, pod)
+		if err != nil {
+			return false, err
+		}
+
+		ready := 0
+		for _, sibling := range pods {
+			if sibling.UID == pod.UID {
+				return false, nil
+			}
+			if isPodReady(&sibling) {
+				ready++
+			}
+		}
+		return ready >= readyPods, nil
+	})
+	if err != nil {
+		return errors.Wrapf(err, "pod %v was not replaced by a ready pod within %v", pod.Name, remote.Timeout)
+	}
+	return nil
+}
+
+// listSiblingPods returns the pods of the controller of the pod.
+func (remote *Restarter) listSiblingPods(ctx context.Context, pod *corev1.Pod) ([]corev1.Pod, error) {
+	podList, err := remote.kubeClient.CoreV1().Pods(pod.Namespace).List(ctx, metav1.ListOptions{LabelSelector: componentLabelSelectors[remote.Component]})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list pods of component %v", remote.Component)
+	}
+
+	ownerUID := getOwnerUID(pod)
+	pods := []corev1.Pod{}
+	for _, sibling := range podList.Items {
+		switch {
+		case ownerUID != "" && getOwnerUID(&sibling) == ownerUID:
+		case ownerUID == "" && sibling.Spec.NodeName == pod.Spec.NodeName:
+			// The instance managers are owned by the Longhorn custom resources instead of a
+			// controller recreating the same pod, so the pods of the node are compared instead.
+		default:
+			continue
+		}
+		pods = append(pods, sibling)
+	}
+	return pods, nil
+}
+
+// waitVolumesHealthy waits for the volumes with a replica on the node to be healthy. Before the
+// restart, it refuses the restart when the engine of an attached volume runs on the node, since
+// restarting the instance manager interrupts its I/O.
+func (remote *Restarter) waitVolumesHealthy(ctx context.Context, nodeName string, beforeRestart bool) error {
+	logged := false
+	var refusal error
+	err := wait.PollUntilContextTimeout(ctx, restarterPollInterval, remote.Timeout, true, func(ctx context.Context) (bool, error) {
+		volumeList, err := remote.longhornClient.LonghornV1beta2().Volumes(remote.LonghornNamespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return false, errors.Wrap(err, "failed to list volumes")
+		}
+		replicaList, err := remote.longhornClient.LonghornV1beta2().Replicas(remote.LonghornNamespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return false, errors.Wrap(err, "failed to list replicas")
+		}
+
+		attached, unhealthy := checkNodeVolumes(nodeName, volumeList.Items, replicaList.Items)
+		if beforeRestart && len(attached) > 0 {
+			refusal = errors.Errorf("volumes %v are attached on node %v, move their workloads to another node or detach them before restarting its instance manager", strings.Join(attached, ", "), nodeName)
+			return false, refusal
+		}
+		if len(unhealthy) > 0 && !logged {
+			logrus.WithField("node", nodeName).Infof("Waiting for volumes %v with a replica on the node to be healthy", strings.Join(unhealthy, ", "))
+			logged = true
+		}
+		return len(unhealthy) == 0, nil
+	})
+	if refusal != nil {
+		return refusal
+	}
+	if err != nil {
+		return errors.Wrapf(err, "volumes with a replica on node %v are not healthy", nodeName)
+	}
+	return nil
+}
+
+// checkNodeVolumes returns the attached volumes whose engine runs on the node, and the attached
+// volumes with a replica on the node that are not healthy, such as the volumes being rebuilt.
+func checkNodeVolumes(nodeName string, volumes []longhorn.Volume, replicas []longhorn.Replica) (attached, unhealthy []string) {
+	volumesWithReplica := map[string]bool{}
+	for _, replica := range replicas {
+		if replica.Spec.NodeID == nodeName {
+			volumesWithReplica[replica.Spec.VolumeName] = true
+		}
+	}
+
+	attached = []string{}
+	unhealthy = []string{}
+	for _, volume := range volumes {
+		if volume.Status.State != longhorn.VolumeStateAttached && volume.Status.State != longhorn.VolumeStateAttaching {
+			continue
+		}
+		if volume.Status.CurrentNodeID == nodeName {
+			attached = append(attached, volume.Name)
+		}
+		if volumesWithReplica[volume.Name] && volume.Status.Robustness != longhorn.VolumeRobustnessHealthy {
+			unhealthy = append(unhealthy, volume.Name)
+		}
+	}
+
+	sort.Strings(attached)
+	sort.Strings(unhealthy)
+	return attached, unhealthy
+}
+
+// sortRestartPods returns the pods in the order of the restart, by node. The CSI plugin pods are
+// restarted before the sidecar pods, so the sidecars connect to the restarted plugin sockets.
+func sortRestartPods(component string, pods []corev1.Pod) []corev1.Pod {
+	sorted := append([]corev1.Pod{}, pods...)
+	isSidecar := func(pod *corev1.Pod) bool {
+		return component == ComponentCSI && pod.Labels["app"] != csiPluginAppName
+	}
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		iSidecar, jSidecar := isSidecar(&sorted[i]), isSidecar(&sorted[j])
+		if iSidecar != jSidecar {
+			return jSidecar
+		}
+		if iSidecar && sorted[i].Labels["app"] != sorted[j].Labels["app"] {
+			return sorted[i].Labels["app"] < sorted[j].Labels["app"]
+		}
+		if sorted[i].Spec.NodeName != sorted[j].Spec.NodeName {
+			return sorted[i].Spec.NodeName < sorted[j].Spec.NodeName
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+	return sorted
+}
+
+// getOwner returns the kind/name of the controller of the pod.
+func getOwner(pod *corev1.Pod) string {
+	for _, owner := range pod.OwnerReferences {
+		if owner.Controller != nil && *owner.Controller {
+			return fmt.Sprintf("%s/%s", owner.Kind, owner.Name)
+		}
+	}
+	return "-"
+}
+
+// getOwnerUID returns the UID of the controller of the pod, when it is a controller recreating the
+// pod.
+func getOwnerUID(pod *corev1.Pod) k8stypes.UID {
+	for _, owner := range pod.OwnerReferences {
+		if owner.Controller != nil && *owner.Controller && (owner.Kind == "DaemonSet" || owner.Kind == "ReplicaSet") {
+			return owner.UID
+		}
+	}
+	return ""
+}
+
+func isPodReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
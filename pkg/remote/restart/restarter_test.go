@@ -0,0 +1,116 @@
+package restart
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	longhorn "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+)
+
+func TestSortRestartPods(t *testing.T) {
+	newPod := func(name, app, nodeName string) corev1.Pod {
+		pod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"app": app}}}
+		pod.Spec.NodeName = nodeName
+		return pod
+	}
+
+	tests := map[string]struct {
+		component string
+		pods      []corev1.Pod
+		expected  []string
+	}{
+		"manager by node": {
+			component: ComponentManager,
+			pods: []corev1.Pod{
+				newPod("longhorn-manager-b", "longhorn-manager", "node-2"),
+				newPod("longhorn-manager-a", "longhorn-manager", "node-1"),
+			},
+			expected: []string{"longhorn-manager-a", "longhorn-manager-b"},
+		},
+		"csi plugins before sidecars": {
+			component: ComponentCSI,
+			pods: []corev1.Pod{
+				newPod("csi-provisioner-a", "csi-provisioner", "node-1"),
+				newPod("longhorn-csi-plugin-b", "longhorn-csi-plugin", "node-2"),
+				newPod("csi-attacher-a", "csi-attacher", "node-2"),
+				newPod("csi-attacher-b", "csi-attacher", "node-1"),
+				newPod("longhorn-csi-plugin-a", "longhorn-csi-plugin", "node-1"),
+			},
+			expected: []string{"longhorn-csi-plugin-a", "longhorn-csi-plugin-b", "csi-attacher-b", "csi-attacher-a", "csi-provisioner-a"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			pods := sortRestartPods(test.component, test.pods)
+			if len(pods) != len(test.expected) {
+				t.Fatalf("expected %v, got %d pods", test.expected, len(pods))
+			}
+			for i := range pods {
+				if pods[i].Name != test.expected[i] {
+					t.Errorf("expected pod %d to be %v, got %v", i, test.expected[i], pods[i].Name)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckNodeVolumes(t *testing.T) {
+	newVolume := func(name string, state longhorn.VolumeState, robustness longhorn.VolumeRobustness, currentNodeID string) longhorn.Volume {
+		volume := longhorn.Volume{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		volume.Status.State = state
+		volume.Status.Robustness = robustness
+		volume.Status.CurrentNodeID = currentNodeID
+		return volume
+	}
+	newReplica := func(volumeName, nodeID string) longhorn.Replica {
+		replica := longhorn.Replica{}
+		replica.Spec.VolumeName = volumeName
+		replica.Spec.NodeID = nodeID
+		return replica
+	}
+
+	tests := map[string]struct {
+		volumes           []longhorn.Volume
+		replicas          []longhorn.Replica
+		expectedAttached  []string
+		expectedUnhealthy []string
+	}{
+		"healthy replica on the node": {
+			volumes:  []longhorn.Volume{newVolume("vol-1", longhorn.VolumeStateAttached, longhorn.VolumeRobustnessHealthy, "node-2")},
+			replicas: []longhorn.Replica{newReplica("vol-1", "node-1"), newReplica("vol-1", "node-2")},
+		},
+		"engine on the node": {
+			volumes:          []longhorn.Volume{newVolume("vol-1", longhorn.VolumeStateAttached, longhorn.VolumeRobustnessHealthy, "node-1")},
+			replicas:         []longhorn.Replica{newReplica("vol-1", "node-2")},
+			expectedAttached: []string{"vol-1"},
+		},
+		"degraded with a replica on the node": {
+			volumes:           []longhorn.Volume{newVolume("vol-1", longhorn.VolumeStateAttached, longhorn.VolumeRobustnessDegraded, "node-2")},
+			replicas:          []longhorn.Replica{newReplica("vol-1", "node-1")},
+			expectedUnhealthy: []string{"vol-1"},
+		},
+		"degraded without a replica on the node": {
+			volumes:  []longhorn.Volume{newVolume("vol-1", longhorn.VolumeStateAttached, longhorn.VolumeRobustnessDegraded, "node-2")},
+			replicas: []longhorn.Replica{newReplica("vol-1", "node-2")},
+		},
+		"detached": {
+			volumes:  []longhorn.Volume{newVolume("vol-1", longhorn.VolumeStateDetached, longhorn.VolumeRobustnessUnknown, "")},
+			replicas: []longhorn.Replica{newReplica("vol-1", "node-1")},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			attached, unhealthy := checkNodeVolumes("node-1", test.volumes, test.replicas)
+			if len(attached) != len(test.expectedAttached) {
+				t.Errorf("expected attached volumes %v, got %v", test.expectedAttached, attached)
+			}
+			if len(unhealthy) != len(test.expectedUnhealthy) {
+				t.Errorf("expected unhealthy volumes %v, got %v", test.expectedUnhealthy, unhealthy)
+			}
+		})
+	}
+}
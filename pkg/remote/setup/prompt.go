@@ -0,0 +1,104 @@
+package setup
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// prompter asks the questions of the wizard on the output, and reads the answers from the input.
+type prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+func newPrompter(in io.Reader, out io.Writer) *prompter {
+	return &prompter{in: bufio.NewReader(in), out: out}
+}
+
+// readLine returns the next line of the input without its line break. It returns io.EOF when the
+// input is closed before an answer.
+func (p *prompter) readLine() (string, error) {
+	line, err := p.in.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// askString asks the question until the answer is valid, and returns it. An empty answer is the
+// default value. The validate function may be nil.
+func (p *prompter) askString(question, defaultValue string, validate func(string) error) (string, error) {
+	for {
+		if defaultValue != "" {
+			fmt.Fprintf(p.out, "%s [%s]: ", question, defaultValue)
+		} else {
+			fmt.Fprintf(p.out, "%s: ", question)
+		}
+
+		answer, err := p.readLine()
+		if err != nil {
+			return "", errors.Wrap(err, "failed to read answer")
+		}
+		if answer == "" {
+			answer = defaultValue
+		}
+
+		if validate != nil {
+			if err := validate(answer); err != nil {
+				fmt.Fprintf(p.out, "Invalid answer: %v\n", err)
+				continue
+			}
+		}
+		return answer, nil
+	}
+}
+
+// askInt asks the question until the answer is an integer between min and max.
+func (p *prompter) askInt(question string, defaultValue, minValue, maxValue int) (int, error) {
+	answer, err := p.askString(question, strconv.Itoa(defaultValue), func(answer string) error {
+		value, err := strconv.Atoi(answer)
+		if err != nil {
+			return errors.Errorf("%q is not a number", answer)
+		}
+		if value < minValue || value > maxValue {
+			return errors.Errorf("%d is not between %d and %d", value, minValue, maxValue)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(answer)
+}
+
+// askYesNo asks the question until the answer is yes or no.
+func (p *prompter) askYesNo(question string, defaultValue bool) (bool, error) {
+	hint := "y/N"
+	if defaultValue {
+		hint = "Y/n"
+	}
+
+	for {
+		fmt.Fprintf(p.out, "%s [%s]: ", question, hint)
+
+		answer, err := p.readLine()
+		if err != nil {
+			return false, errors.Wrap(err, "failed to read answer")
+		}
+
+		switch strings.ToLower(answer) {
+		case "":
+			return defaultValue, nil
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+		fmt.Fprintln(p.out, "Please answer yes or no.")
+	}
+}
@@ -0,0 +1,107 @@
+package setup
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"github.com/pkg/errors"
+)
+
+// backupStoreVersion is the first Longhorn version configuring the default backup target in the
+// defaultBackupStore values instead of the defaultSettings.
+var backupStoreVersion = semver.MustParse("1.8.0")
+
+// backupTargetSchemes are the schemes of the backup target URLs, and whether they require a
+// credential secret.
+var backupTargetSchemes = map[string]bool{
+	"s3":     true,
+	"azblob": true,
+	"cifs":   true,
+	"nfs":    false,
+}
+
+// Answers are the answers to the questions of the wizard.
+type Answers struct {
+	ReplicaCount                 int
+	BackupTarget                 string // Empty when no backup target is configured.
+	BackupTargetCredentialSecret string
+	StorageNetwork               string // NetworkAttachmentDefinition as <namespace>/<name>. Empty for the pod network.
+}
+
+// validateBackupTarget returns an error when the backup target is neither empty nor a URL with a
+// scheme supported by Longhorn.
+func validateBackupTarget(target string) error {
+	if target == "" {
+		return nil
+	}
+
+	parsed, err := url.Parse(target)
+	if err != nil {
+		return errors.Wrapf(err, "invalid backup target %q", target)
+	}
+	if _, ok := backupTargetSchemes[parsed.Scheme]; !ok || parsed.Host == "" {
+		return errors.Errorf("invalid backup target %q, expected s3://<bucket>@<region>/<path>, nfs://<server>:/<path>, cifs://<server>/<share> or azblob://<container>@<endpoint>/<path>", target)
+	}
+	return nil
+}
+
+// backupTargetRequiresSecret returns whether the backup target requires a credential secret.
+func backupTargetRequiresSecret(target string) bool {
+	parsed, err := url.Parse(target)
+	if err != nil {
+		return false
+	}
+	return backupTargetSchemes[parsed.Scheme]
+}
+
+// validateStorageNetwork returns an error when the storage network is neither empty nor a
+// NetworkAttachmentDefinition as <namespace>/<name>.
+func validateStorageNetwork(network string) error {
+	if network == "" {
+		return nil
+	}
+
+	parts := strings.Split(network, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return errors.Errorf("invalid storage network %q, expected <namespace>/<name> of a NetworkAttachmentDefinition", network)
+	}
+	return nil
+}
+
+// newValues returns the Helm values of the Longhorn chart for the answers. The backup target goes
+// in the values of the chart version, the latest one when the version is unknown.
+func newValues(answers *Answers, version *semver.Version) map[string]interface{} {
+	defaultSettings := map[string]interface{}{
+		"defaultReplicaCount": answers.ReplicaCount,
+	}
+	values := map[string]interface{}{
+		"persistence": map[string]interface{}{
+			"defaultClassReplicaCount": answers.ReplicaCount,
+		},
+		"defaultSettings": defaultSettings,
+	}
+
+	if answers.BackupTarget != "" {
+		backupStore := map[string]interface{}{
+			"backupTarget": answers.BackupTarget,
+		}
+		if answers.BackupTargetCredentialSecret != "" {
+			backupStore["backupTargetCredentialSecret"] = answers.BackupTargetCredentialSecret
+		}
+
+		if version != nil && version.LT(backupStoreVersion) {
+			for key, value := range backupStore {
+				defaultSettings[key] = value
+			}
+		} else {
+			values["defaultBackupStore"] = backupStore
+		}
+	}
+
+	if answers.StorageNetwork != "" {
+		defaultSettings["storageNetwork"] = answers.StorageNetwork
+	}
+
+	return values
+}
@@ -0,0 +1,112 @@
+package setup
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/blang/semver/v4"
+
+	"sigs.k8s.io/yaml"
+)
+
+func TestValidateBackupTarget(t *testing.T) {
+	tests := map[string]struct {
+		target        string
+		expectError   bool
+		requireSecret bool
+	}{
+		"empty":           {target: ""},
+		"s3":              {target: "s3://backups@us-east-1/longhorn", requireSecret: true},
+		"nfs":             {target: "nfs://10.0.2.10:/exports/longhorn"},
+		"cifs":            {target: "cifs://fileserver/longhorn", requireSecret: true},
+		"azblob":          {target: "azblob://backups@core.windows.net/", requireSecret: true},
+		"unknown scheme":  {target: "ftp://fileserver/longhorn", expectError: true},
+		"missing host":    {target: "s3:///longhorn", expectError: true},
+		"missing scheme":  {target: "backups@us-east-1", expectError: true},
+		"invalid address": {target: "nfs://[::1", expectError: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := validateBackupTarget(test.target)
+			if test.expectError != (err != nil) {
+				t.Fatalf("expected error %v, got %v", test.expectError, err)
+			}
+			if err == nil && backupTargetRequiresSecret(test.target) != test.requireSecret {
+				t.Errorf("expected requiring a secret %v", test.requireSecret)
+			}
+		})
+	}
+}
+
+func TestValidateStorageNetwork(t *testing.T) {
+	for network, expectError := range map[string]bool{
+		"":                    false,
+		"kube-system/storage": false,
+		"storage":             true,
+		"kube-system/":        true,
+		"a/b/c":               true,
+	} {
+		if err := validateStorageNetwork(network); expectError != (err != nil) {
+			t.Errorf("%q: expected error %v, got %v", network, expectError, err)
+		}
+	}
+}
+
+func TestNewValues(t *testing.T) {
+	tests := map[string]struct {
+		answers  Answers
+		version  string
+		expected string
+	}{
+		"replica count only": {
+			answers: Answers{ReplicaCount: 2},
+			expected: `defaultSettings:
+  defaultReplicaCount: 2
+persistence:
+  defaultClassReplicaCount: 2
+`,
+		},
+		"backup store": {
+			answers: Answers{ReplicaCount: 3, BackupTarget: "s3://backups@us-east-1/", BackupTargetCredentialSecret: "s3-secret", StorageNetwork: "kube-system/storage"},
+			version: "v1.8.1",
+			expected: `defaultBackupStore:
+  backupTarget: s3://backups@us-east-1/
+  backupTargetCredentialSecret: s3-secret
+defaultSettings:
+  defaultReplicaCount: 3
+  storageNetwork: kube-system/storage
+persistence:
+  defaultClassReplicaCount: 3
+`,
+		},
+		"backup target setting": {
+			answers: Answers{ReplicaCount: 3, BackupTarget: "nfs://10.0.2.10:/exports"},
+			version: "v1.7.2",
+			expected: `defaultSettings:
+  backupTarget: nfs://10.0.2.10:/exports
+  defaultReplicaCount: 3
+persistence:
+  defaultClassReplicaCount: 3
+`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var version *semver.Version
+			if test.version != "" {
+				parsed := semver.MustParse(strings.TrimPrefix(test.version, "v"))
+				version = &parsed
+			}
+
+			data, err := yaml.Marshal(newValues(&test.answers, version))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(data) != test.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", test.expected, data)
+			}
+		})
+	}
+}
@@ -0,0 +1,375 @@
+package setup
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/yaml"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeclient "k8s.io/client-go/kubernetes"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/remote/preflight"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+const (
+	// defaultReplicaCount is the default number of replicas of the Longhorn volumes.
+	defaultReplicaCount = 3
+
+	// maxReplicaCount is the maximum number of replicas Longhorn accepts for a volume.
+	maxReplicaCount = 20
+)
+
+// Wizard provide functions for the interactive first-time setup of Longhorn: it runs the preflight
+// check, offers to install the missing dependencies, asks about the replica count, backup target and
+// storage network, and generates, or applies with Helm, the install values.
+type Wizard struct {
+	WizardCmdOptions
+
+	kubeClient *kubeclient.Clientset
+	version    *semver.Version // Version of the Longhorn chart. Nil for the latest one.
+	helmPath   string
+
+	prompter *prompter
+}
+
+// WizardCmdOptions holds the options for the command.
+type WizardCmdOptions struct {
+	types.GlobalCmdOptions
+
+	LonghornNamespace string
+	LonghornVersion   string // Version of the Longhorn chart to install. Defaults to the latest one.
+	OutputFile        string // File to write the values to. Defaults to stdout.
+	Apply             bool   // Install Longhorn with Helm and the generated values.
+	SkipPreflight     bool
+}
+
+// Validate validates the command options.
+func (remote *Wizard) Validate() error {
+	if remote.LonghornVersion != "" {
+		version, err := semver.ParseTolerant(remote.LonghornVersion)
+		if err != nil {
+			return errors.Wrapf(err, "invalid Longhorn version %q (--%s)", remote.LonghornVersion, consts.CmdOptLonghornVersion)
+		}
+		remote.version = &version
+	}
+
+	if remote.Apply {
+		helmPath, err := exec.LookPath(consts.HelmBinary)
+		if err != nil {
+			return errors.Wrapf(err, "--%s requires %s on the PATH", consts.CmdOptApply, consts.HelmBinary)
+		}
+		remote.helmPath = helmPath
+	}
+
+	return nil
+}
+
+// Init initializes the Wizard, asking the questions on stderr and reading the answers from stdin.
+func (remote *Wizard) Init() error {
+	kubeClient, err := kubeutils.NewKubeClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.kubeClient = kubeClient
+
+	if remote.prompter == nil {
+		remote.prompter = newPrompter(os.Stdin, os.Stderr)
+	}
+	return nil
+}
+
+// Run runs the wizard, and returns the generated values as a YAML string.
+func (remote *Wizard) Run() (string, error) {
+	if !remote.SkipPreflight {
+		if err := remote.runPreflight(); err != nil {
+			return "", err
+		}
+	}
+
+	nodeCount, err := remote.countNodes()
+	if err != nil {
+		return "", err
+	}
+
+	remote.printSection("Longhorn settings")
+	answers, err := askQuestions(remote.prompter, nodeCount)
+	if err != nil {
+		return "", err
+	}
+	remote.checkCredentialSecret(answers)
+
+	yamlData, err := yaml.Marshal(newValues(answers, remote.version))
+	if err != nil {
+		return "", errors.Wrap(err, "failed to convert values to YAML")
+	}
+	values := string(yamlData)
+
+	if remote.OutputFile != "" {
+		if err := os.WriteFile(remote.OutputFile, yamlData, 0644); err != nil {
+			return "", errors.Wrapf(err, "failed to write values to %v", remote.OutputFile)
+		}
+		logrus.Infof("Wrote the Longhorn install values to %v", remote.OutputFile)
+	}
+
+	if remote.Apply {
+		if err := remote.install(yamlData); err != nil {
+			return "", err
+		}
+	}
+
+	return values, nil
+}
+
+// Cleanup does nothing, since the preflight check and install clean up after themselves.
+func (remote *Wizard) Cleanup() error {
+	return nil
+}
+
+// runPreflight runs the preflight check until it passes. On failures, it offers to install the
+// missing dependencies and checks again, or to continue anyway.
+func (remote *Wizard) runPreflight() error {
+	for {
+		remote.printSection("Preflight check")
+		nodeCollections, err := remote.checkPreflight()
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(remote.prompter.out, utils.RenderNodeCollections(nodeCollections, false))
+
+		failedNodes := getFailedNodes(nodeCollections)
+		if len(failedNodes) == 0 {
+			fmt.Fprintln(remote.prompter.out, "All nodes passed the preflight check.")
+			return nil
+		}
+
+		fix, err := remote.prompter.askYesNo(fmt.Sprintf("The preflight check failed on %s. Install the missing dependencies on the nodes (packages, kernel modules and services)?", strings.Join(failedNodes, ", ")), true)
+		if err != nil {
+			return err
+		}
+		if !fix {
+			proceed, err := remote.prompter.askYesNo("Continue the setup without fixing the failures?", false)
+			if err != nil {
+				return err
+			}
+			if !proceed {
+				return errors.New("setup aborted")
+			}
+			return nil
+		}
+
+		remote.printSection("Preflight install")
+		nodeCollections, err = remote.installPreflight()
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(remote.prompter.out, utils.RenderNodeCollections(nodeCollections, false))
+		if rebootNodes := preflight.GetRebootRequiredNodes(nodeCollections); len(rebootNodes) > 0 {
+			fmt.Fprintf(remote.prompter.out, "Reboot %s and run '%s %s %s' before checking again.\n", strings.Join(rebootNodes, ", "), consts.CmdLonghornctlRemote, consts.SubCmdInstall, consts.SubCmdPreflight)
+		}
+	}
+}
+
+func (remote *Wizard) checkPreflight() (map[string]*types.LogCollection, error) {
+	checker := &preflight.Checker{
+		CheckerCmdOptions: preflight.CheckerCmdOptions{
+			GlobalCmdOptions: remote.GlobalCmdOptions,
+			LonghornVersion:  remote.LonghornVersion,
+		},
+	}
+
+	logrus.Info("Initializing preflight checker")
+	if err := checker.Init(); err != nil {
+		return nil, errors.Wrap(err, "failed to initialize preflight checker")
+	}
+	defer func() {
+		if err := checker.Cleanup(); err != nil {
+			logrus.WithError(err).Warn("Failed to cleanup preflight checker")
+		}
+	}()
+	if err := checker.Cleanup(); err != nil {
+		return nil, errors.Wrap(err, "failed to cleanup preflight checker")
+	}
+
+	logrus.Info("Running preflight checker")
+	nodeCollections, err := checker.Collect()
+	return nodeCollections, errors.Wrap(err, "failed to run preflight checker")
+}
+
+func (remote *Wizard) installPreflight() (map[string]*types.LogCollection, error) {
+	installer := &preflight.Installer{
+		InstallerCmdOptions: preflight.InstallerCmdOptions{
+			GlobalCmdOptions: remote.GlobalCmdOptions,
+		},
+	}
+
+	logrus.Info("Initializing preflight installer")
+	if err := installer.Init(); err != nil {
+		return nil, errors.Wrap(err, "failed to initialize preflight installer")
+	}
+	defer func() {
+		if err := installer.Cleanup(); err != nil {
+			logrus.WithError(err).Warn("Failed to cleanup preflight installer")
+		}
+	}()
+	if err := installer.Cleanup(); err != nil {
+		return nil, errors.Wrap(err, "failed to cleanup preflight installer")
+	}
+
+	logrus.Info("Running preflight installer")
+	nodeCollections, err := installer.Collect()
+	return nodeCollections, errors.Wrap(err, "failed to run preflight installer")
+}
+
+// countNodes returns the number of nodes selected by the node selector.
+func (remote *Wizard) countNodes() (int, error) {
+	nodeList, err := remote.kubeClient.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{LabelSelector: remote.NodeSelector})
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to list nodes")
+	}
+	return len(nodeList.Items), nil
+}
+
+// checkCredentialSecret warns when the credential secret of the backup target does not exist yet.
+func (remote *Wizard) checkCredentialSecret(answers *Answers) {
+	if answers.BackupTargetCredentialSecret == "" {
+		return
+	}
+
+	_, err := remote.kubeClient.CoreV1().Secrets(remote.LonghornNamespace).Get(context.Background(), answers.BackupTargetCredentialSecret, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		fmt.Fprintf(remote.prompter.out, "Secret %s/%s does not exist yet, create it before using the backup target.\n", remote.LonghornNamespace, answers.BackupTargetCredentialSecret)
+	}
+}
+
+// install installs or upgrades the Longhorn release with Helm and the values, after confirmation.
+func (remote *Wizard) install(values []byte) error {
+	confirmed, err := remote.prompter.askYesNo(fmt.Sprintf("Install Longhorn in namespace %s with these values?", remote.LonghornNamespace), true)
+	if err != nil {
+		return err
+	}
+	if !confirmed {
+		return errors.New("setup aborted")
+	}
+
+	valuesFile := remote.OutputFile
+	if valuesFile == "" {
+		tempFile, err := os.CreateTemp("", "longhorn-values-*.yaml")
+		if err != nil {
+			return errors.Wrap(err, "failed to create values file")
+		}
+		defer os.Remove(tempFile.Name())
+
+		if _, err := tempFile.Write(values); err != nil {
+			tempFile.Close()
+			return errors.Wrap(err, "failed to write values file")
+		}
+		if err := tempFile.Close(); err != nil {
+			return errors.Wrap(err, "failed to write values file")
+		}
+		valuesFile = tempFile.Name()
+	}
+
+	args := remote.helmArgs(valuesFile)
+	logrus.Infof("Running %s %s", consts.HelmBinary, strings.Join(args, " "))
+
+	cmd := exec.Command(remote.helmPath, args...)
+	cmd.Stdout = remote.prompter.out
+	cmd.Stderr = remote.prompter.out
+	if err := cmd.Run(); err != nil {
+		return errors.Wrap(err, "failed to install Longhorn with Helm")
+	}
+	return nil
+}
+
+// helmArgs returns the arguments of the Helm command installing or upgrading the Longhorn release
+// with the values file.
+func (remote *Wizard) helmArgs(valuesFile string) []string {
+	args := []string{
+		"upgrade", "--install", consts.HelmReleaseLonghorn, consts.HelmChartLonghorn,
+		"--repo", consts.HelmRepoLonghorn,
+		"--namespace", remote.LonghornNamespace,
+		"--create-namespace",
+		"--values", valuesFile,
+	}
+	if remote.version != nil {
+		args = append(args, "--version", remote.version.String())
+	}
+	if remote.KubeConfigPath != "" {
+		args = append(args, "--kubeconfig", remote.KubeConfigPath)
+	}
+	return args
+}
+
+func (remote *Wizard) printSection(title string) {
+	fmt.Fprintf(remote.prompter.out, "\n== %s ==\n", title)
+}
+
+// askQuestions asks about the replica count, backup target and storage network. The replica count
+// defaults to the number of nodes, up to 3.
+func askQuestions(p *prompter, nodeCount int) (*Answers, error) {
+	answers := &Answers{}
+
+	replicaCount := defaultReplicaCount
+	if nodeCount > 0 && nodeCount < replicaCount {
+		replicaCount = nodeCount
+	}
+
+	var err error
+	answers.ReplicaCount, err = p.askInt("Default number of replicas of each volume", replicaCount, 1, maxReplicaCount)
+	if err != nil {
+		return nil, err
+	}
+	if nodeCount > 0 && answers.ReplicaCount > nodeCount {
+		fmt.Fprintf(p.out, "Only %d nodes are available, the volumes will be degraded unless replica node soft anti-affinity is enabled.\n", nodeCount)
+	}
+
+	answers.BackupTarget, err = p.askString("Backup target URL, for example s3://<bucket>@<region>/<path> or nfs://<server>:/<path> (leave empty to skip)", "", validateBackupTarget)
+	if err != nil {
+		return nil, err
+	}
+	if backupTargetRequiresSecret(answers.BackupTarget) {
+		answers.BackupTargetCredentialSecret, err = p.askString("Name of the secret with the credentials of the backup target", "", func(answer string) error {
+			if answer == "" {
+				return errors.New("the backup target requires a credential secret")
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	answers.StorageNetwork, err = p.askString("Storage network as <namespace>/<name> of a Multus NetworkAttachmentDefinition (leave empty to use the pod network)", "", validateStorageNetwork)
+	if err != nil {
+		return nil, err
+	}
+
+	return answers, nil
+}
+
+// getFailedNodes returns the sorted names of the nodes with errors.
+func getFailedNodes(nodeCollections map[string]*types.LogCollection) []string {
+	var nodes []string
+	for node, collection := range nodeCollections {
+		if collection != nil && len(collection.Error) > 0 {
+			nodes = append(nodes, node)
+		}
+	}
+	sort.Strings(nodes)
+	return nodes
+}
@@ -0,0 +1,127 @@
+package setup
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func TestAskQuestions(t *testing.T) {
+	tests := map[string]struct {
+		input     string
+		nodeCount int
+		expected  Answers
+		output    []string
+	}{
+		"defaults": {
+			input:     "\n\n\n",
+			nodeCount: 5,
+			expected:  Answers{ReplicaCount: 3},
+			output:    []string{"Default number of replicas of each volume [3]: "},
+		},
+		"replica count capped by the nodes": {
+			input:     "\n\n\n",
+			nodeCount: 2,
+			expected:  Answers{ReplicaCount: 2},
+		},
+		"more replicas than nodes": {
+			input:     "3\n\n\n",
+			nodeCount: 2,
+			expected:  Answers{ReplicaCount: 3},
+			output:    []string{"Only 2 nodes are available"},
+		},
+		"backup target with secret": {
+			input:     "2\ns3://backups@us-east-1/\n\ns3-secret\nkube-system/storage\n",
+			nodeCount: 3,
+			expected:  Answers{ReplicaCount: 2, BackupTarget: "s3://backups@us-east-1/", BackupTargetCredentialSecret: "s3-secret", StorageNetwork: "kube-system/storage"},
+			output:    []string{"the backup target requires a credential secret"},
+		},
+		"invalid answers asked again": {
+			input:     "three\n0\n1\nftp://server\nnfs://server:/exports\nstorage\n\n",
+			nodeCount: 1,
+			expected:  Answers{ReplicaCount: 1, BackupTarget: "nfs://server:/exports"},
+			output:    []string{`"three" is not a number`, "0 is not between 1 and 20", `invalid backup target "ftp://server"`, `invalid storage network "storage"`},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var out bytes.Buffer
+			answers, err := askQuestions(newPrompter(strings.NewReader(test.input), &out), test.nodeCount)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(*answers, test.expected) {
+				t.Errorf("expected %+v, got %+v", test.expected, *answers)
+			}
+			for _, expected := range test.output {
+				if !strings.Contains(out.String(), expected) {
+					t.Errorf("expected output to contain %q, got:\n%s", expected, out.String())
+				}
+			}
+		})
+	}
+}
+
+func TestAskQuestionsClosedInput(t *testing.T) {
+	if _, err := askQuestions(newPrompter(strings.NewReader("3\n"), &bytes.Buffer{}), 3); err == nil {
+		t.Fatal("expected an error when the input is closed")
+	}
+}
+
+func TestAskYesNo(t *testing.T) {
+	tests := map[string]struct {
+		input        string
+		defaultValue bool
+		expected     bool
+	}{
+		"default yes": {input: "\n", defaultValue: true, expected: true},
+		"default no":  {input: "\n", expected: false},
+		"yes":         {input: "Yes\n", expected: true},
+		"no":          {input: "n\n", defaultValue: true, expected: false},
+		"asked again": {input: "maybe\ny\n", expected: true},
+		"no new line": {input: "y", expected: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			answer, err := newPrompter(strings.NewReader(test.input), &bytes.Buffer{}).askYesNo("Continue?", test.defaultValue)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if answer != test.expected {
+				t.Errorf("expected %v, got %v", test.expected, answer)
+			}
+		})
+	}
+}
+
+func TestGetFailedNodes(t *testing.T) {
+	nodeCollections := map[string]*types.LogCollection{
+		"node-3": {Error: []string{"Package open-iscsi is not installed"}},
+		"node-1": {Info: []string{"Service iscsid is running"}, Warn: []string{"Kernel module dm_crypt is not loaded"}},
+		"node-2": {Error: []string{"Service iscsid is not running"}},
+		"node-4": nil,
+	}
+
+	expected := []string{"node-2", "node-3"}
+	if nodes := getFailedNodes(nodeCollections); !reflect.DeepEqual(nodes, expected) {
+		t.Errorf("expected %v, got %v", expected, nodes)
+	}
+}
+
+func TestHelmArgs(t *testing.T) {
+	wizard := &Wizard{WizardCmdOptions: WizardCmdOptions{LonghornNamespace: "storage", LonghornVersion: "v1.7.2"}}
+	wizard.KubeConfigPath = "/root/.kube/config"
+	if err := wizard.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "upgrade --install longhorn longhorn --repo https://charts.longhorn.io --namespace storage --create-namespace --values values.yaml --version 1.7.2 --kubeconfig /root/.kube/config"
+	if args := strings.Join(wizard.helmArgs("values.yaml"), " "); args != expected {
+		t.Errorf("expected %q, got %q", expected, args)
+	}
+}
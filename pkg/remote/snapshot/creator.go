@@ -0,0 +1,412 @@
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	kubeclient "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	longhorn "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+	lhclient "github.com/longhorn/longhorn-manager/k8s/pkg/client/clientset/versioned"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// pollInterval is the interval between the checks of the snapshots.
+const pollInterval = time.Second
+
+// Kinds of the workloads whose volumes can be snapshotted.
+const (
+	kindDaemonSet   = "DaemonSet"
+	kindDeployment  = "Deployment"
+	kindPod         = "Pod"
+	kindStatefulSet = "StatefulSet"
+)
+
+// workloadKinds maps the kinds of the --workload option, and their short names, to the workload kinds.
+var workloadKinds = map[string]string{
+	"deploy":      kindDeployment,
+	"deployment":  kindDeployment,
+	"sts":         kindStatefulSet,
+	"statefulset": kindStatefulSet,
+	"ds":          kindDaemonSet,
+	"daemonset":   kindDaemonSet,
+	"po":          kindPod,
+	"pod":         kindPod,
+}
+
+// Creator provide functions for taking snapshots of Longhorn volumes, optionally quiescing the
+// workload using them: the pre-snapshot hooks of its pods run and the filesystems of the volumes
+// are frozen before the snapshots, then the filesystems are thawed and the post-snapshot hooks run.
+type Creator struct {
+	CreatorCmdOptions
+
+	kubeClient     *kubeclient.Clientset
+	longhornClient *lhclient.Clientset
+	restConfig     *rest.Config
+
+	workload *workload
+	targets  []*target
+}
+
+// CreatorCmdOptions holds the options for the command.
+type CreatorCmdOptions struct {
+	types.GlobalCmdOptions
+
+	LonghornNamespace string
+	Volume            string // Volume to snapshot, without quiescing.
+	Workload          string // Workload whose volumes to snapshot, as <kind>/<namespace>/<name>.
+	Name              string // Name of the snapshot, suffixed with the volume name when there are several volumes.
+	Quiesce           bool
+	Timeout           time.Duration // Maximum time to wait for each hook and for the snapshots.
+}
+
+// workload is a workload given by the --workload option.
+type workload struct {
+	kind      string
+	namespace string
+	name      string
+}
+
+func (w *workload) String() string {
+	return fmt.Sprintf("%s %s/%s", w.kind, w.namespace, w.name)
+}
+
+// target is a volume to snapshot, with the pods of the workload using it.
+type target struct {
+	volume   string
+	pvc      string // Namespace and name of the PVC.
+	pods     []*corev1.Pod
+	block    bool // Used as a block device by the pods, without filesystem to freeze.
+	shared   bool // ReadWriteMany volume, mounted by the pods over NFS from the share manager.
+	snapshot string
+	quiesce  []string
+}
+
+// Validate validates the command options.
+func (remote *Creator) Validate() error {
+	if (remote.Volume == "") == (remote.Workload == "") {
+		return errors.Errorf("exactly one of --%s and --%s is required", consts.CmdOptVolume, consts.CmdOptWorkload)
+	}
+
+	if remote.Quiesce && remote.Workload == "" {
+		return errors.Errorf("--%s requires --%s", consts.CmdOptQuiesce, consts.CmdOptWorkload)
+	}
+
+	if remote.Workload != "" {
+		workload, err := parseWorkload(remote.Workload)
+		if err != nil {
+			return err
+		}
+		remote.workload = workload
+	}
+
+	if remote.Timeout <= 0 {
+		return errors.Errorf("timeout (--%s) must be positive", consts.CmdOptTimeout)
+	}
+
+	return nil
+}
+
+// Init initializes the Creator, and finds the volumes to snapshot.
+func (remote *Creator) Init() error {
+	kubeClient, err := kubeutils.NewKubeClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.kubeClient = kubeClient
+
+	longhornClient, err := kubeutils.NewLonghornClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.longhornClient = longhornClient
+
+	restConfig, err := kubeutils.NewRestConfig("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.restConfig = restConfig
+
+	ctx := context.Background()
+
+	if remote.Volume != "" {
+		if _, err := remote.longhornClient.LonghornV1beta2().Volumes(remote.LonghornNamespace).Get(ctx, remote.Volume, metav1.GetOptions{}); err != nil {
+			return errors.Wrapf(err, "failed to get volume %v", remote.Volume)
+		}
+		remote.targets = []*target{{volume: remote.Volume}}
+	} else {
+		pods, err := remote.getWorkloadPods(ctx)
+		if err != nil {
+			return err
+		}
+
+		volumeList, err := remote.longhornClient.LonghornV1beta2().Volumes(remote.LonghornNamespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return errors.Wrap(err, "failed to list volumes")
+		}
+
+		remote.targets = newTargets(pods, volumeList.Items)
+		if len(remote.targets) == 0 {
+			return errors.Errorf("%v does not use any Longhorn volume", remote.workload)
+		}
+	}
+
+	name := remote.Name
+	if name == "" {
+		name = consts.SnapshotNamePrefix + time.Now().UTC().Format("20060102-150405")
+	}
+	for _, target := range remote.targets {
+		target.snapshot = getSnapshotName(name, target.volume, len(remote.targets))
+	}
+
+	return nil
+}
+
+// getWorkloadPods returns the running pods of the workload.
+func (remote *Creator) getWorkloadPods(ctx context.Context) ([]*corev1.Pod, error) {
+	w := remote.workload
+	apps := remote.kubeClient.AppsV1()
+
+	var selector *metav1.LabelSelector
+	switch w.kind {
+	case kindPod:
+		pod, err := remote.kubeClient.CoreV1().Pods(w.namespace).Get(ctx, w.name, metav1.GetOptions{})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get %v", w)
+		}
+		if pod.Status.Phase != corev1.PodRunning {
+			return nil, errors.Errorf("%v is %v, not running", w, pod.Status.Phase)
+		}
+		return []*corev1.Pod{pod}, nil
+	case kindDeployment:
+		deployment, err := apps.Deployments(w.namespace).Get(ctx, w.name, metav1.GetOptions{})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get %v", w)
+		}
+		selector = deployment.Spec.Selector
+	case kindStatefulSet:
+		statefulSet, err := apps.StatefulSets(w.namespace).Get(ctx, w.name, metav1.GetOptions{})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get %v", w)
+		}
+		selector = statefulSet.Spec.Selector
+	case kindDaemonSet:
+		daemonSet, err := apps.DaemonSets(w.namespace).Get(ctx, w.name, metav1.GetOptions{})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get %v", w)
+		}
+		selector = daemonSet.Spec.Selector
+	}
+
+	labelSelector, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid selector of %v", w)
+	}
+
+	podList, err := remote.kubeClient.CoreV1().Pods(w.namespace).List(ctx, metav1.ListOptions{LabelSelector: labelSelector.String()})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list pods of %v", w)
+	}
+
+	var pods []*corev1.Pod
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if pod.Status.Phase == corev1.PodRunning && pod.DeletionTimestamp == nil {
+			pods = append(pods, pod)
+		}
+	}
+	if len(pods) == 0 {
+		return nil, errors.Errorf("%v has no running pod", w)
+	}
+	return pods, nil
+}
+
+// Run takes the snapshots, quiescing the workload around them when requested, and returns them.
+// The filesystems are thawed and the post-snapshot hooks run even when taking the snapshots fails.
+func (remote *Creator) Run(ctx context.Context) (result []types.CreatedSnapshot, err error) {
+	if remote.Quiesce {
+		var hookedPods []*corev1.Pod
+		hookedPods, err = remote.runPreHooks(ctx)
+		defer func() {
+			if postErr := remote.runPostHooks(hookedPods); postErr != nil && err == nil {
+				err = postErr
+			}
+		}()
+		if err != nil {
+			return nil, err
+		}
+
+		var frozenTargets []*target
+		frozenTargets, err = remote.freeze(ctx)
+		defer func() {
+			if thawErr := remote.thaw(frozenTargets); thawErr != nil && err == nil {
+				err = thawErr
+			}
+		}()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := remote.createSnapshots(ctx); err != nil {
+		return nil, err
+	}
+
+	for _, target := range remote.targets {
+		snapshot := types.CreatedSnapshot{
+			Volume:   target.volume,
+			Snapshot: target.snapshot,
+			PVC:      target.pvc,
+			Quiesce:  target.quiesce,
+		}
+		for _, pod := range target.pods {
+			snapshot.Pods = append(snapshot.Pods, pod.Name)
+		}
+		result = append(result, snapshot)
+	}
+	return result, nil
+}
+
+// createSnapshots creates the snapshots of all the volumes, then waits for them to be taken.
+func (remote *Creator) createSnapshots(ctx context.Context) error {
+	client := remote.longhornClient.LonghornV1beta2().Snapshots(remote.LonghornNamespace)
+
+	for _, target := range remote.targets {
+		logrus.WithFields(logrus.Fields{"volume": target.volume, "snapshot": target.snapshot}).Info("Creating snapshot")
+
+		snapshot := &longhorn.Snapshot{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: target.snapshot,
+			},
+			Spec: longhorn.SnapshotSpec{
+				Volume:         target.volume,
+				CreateSnapshot: true,
+			},
+		}
+		if _, err := client.Create(ctx, snapshot, metav1.CreateOptions{}); err != nil {
+			return errors.Wrapf(err, "failed to create snapshot %v of volume %v", target.snapshot, target.volume)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, remote.Timeout)
+	defer cancel()
+
+	for _, target := range remote.targets {
+		err := wait.PollUntilContextCancel(ctx, pollInterval, true, func(ctx context.Context) (bool, error) {
+			snapshot, err := client.Get(ctx, target.snapshot, metav1.GetOptions{})
+			if err != nil {
+				return false, err
+			}
+
+			if snapshot.Status.Error != "" {
+				return false, errors.New(snapshot.Status.Error)
+			}
+			return snapshot.Status.ReadyToUse, nil
+		})
+		if err != nil {
+			return errors.Wrapf(err, "failed waiting for snapshot %v of volume %v", target.snapshot, target.volume)
+		}
+	}
+	return nil
+}
+
+// Cleanup does nothing, since the snapshots are the result of the Creator.
+func (remote *Creator) Cleanup() error {
+	return nil
+}
+
+// parseWorkload parses a workload given as <kind>/<namespace>/<name>.
+func parseWorkload(value string) (*workload, error) {
+	parts := strings.Split(value, "/")
+	if len(parts) != 3 || parts[1] == "" || parts[2] == "" {
+		return nil, errors.Errorf("invalid --%s %q, expected <kind>/<namespace>/<name>", consts.CmdOptWorkload, value)
+	}
+
+	kind, ok := workloadKinds[strings.ToLower(parts[0])]
+	if !ok {
+		return nil, errors.Errorf("unsupported workload kind %q (--%s), supported kinds: deploy, sts, ds, pod", parts[0], consts.CmdOptWorkload)
+	}
+
+	return &workload{kind: kind, namespace: parts[1], name: parts[2]}, nil
+}
+
+// newTargets returns the Longhorn volumes bound to the PVCs of the pods, sorted by name, with the
+// pods using each of them.
+func newTargets(pods []*corev1.Pod, volumes []longhorn.Volume) []*target {
+	volumesByPVC := map[string]*longhorn.Volume{}
+	for i := range volumes {
+		status := volumes[i].Status.KubernetesStatus
+		if status.PVCName != "" {
+			volumesByPVC[status.Namespace+"/"+status.PVCName] = &volumes[i]
+		}
+	}
+
+	targetsByVolume := map[string]*target{}
+	for _, pod := range pods {
+		for _, podVolume := range pod.Spec.Volumes {
+			if podVolume.PersistentVolumeClaim == nil {
+				continue
+			}
+
+			pvc := pod.Namespace + "/" + podVolume.PersistentVolumeClaim.ClaimName
+			volume := volumesByPVC[pvc]
+			if volume == nil {
+				continue
+			}
+
+			t := targetsByVolume[volume.Name]
+			if t == nil {
+				t = &target{
+					volume: volume.Name,
+					pvc:    pvc,
+					block:  isBlockDevice(pod, podVolume.Name),
+					shared: volume.Spec.AccessMode == longhorn.AccessModeReadWriteMany,
+				}
+				targetsByVolume[volume.Name] = t
+			}
+			t.pods = append(t.pods, pod)
+		}
+	}
+
+	targets := make([]*target, 0, len(targetsByVolume))
+	for _, t := range targetsByVolume {
+		targets = append(targets, t)
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].volume < targets[j].volume })
+	return targets
+}
+
+// isBlockDevice returns whether the volume of the pod is used as a raw block device.
+func isBlockDevice(pod *corev1.Pod, volumeName string) bool {
+	for _, container := range pod.Spec.Containers {
+		for _, device := range container.VolumeDevices {
+			if device.Name == volumeName {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// getSnapshotName returns the name of the snapshot of the volume, suffixed with the volume name
+// when several volumes are snapshotted at once.
+func getSnapshotName(name, volume string, count int) string {
+	if count == 1 {
+		return name
+	}
+	return name + "-" + volume
+}
@@ -0,0 +1,199 @@
+package snapshot
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	longhorn "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func TestParseWorkload(t *testing.T) {
+	tests := map[string]struct {
+		value       string
+		expected    *workload
+		expectError bool
+	}{
+		"deployment":     {value: "deploy/default/nginx", expected: &workload{kind: kindDeployment, namespace: "default", name: "nginx"}},
+		"statefulset":    {value: "StatefulSet/db/postgres", expected: &workload{kind: kindStatefulSet, namespace: "db", name: "postgres"}},
+		"pod":            {value: "pod/db/postgres-0", expected: &workload{kind: kindPod, namespace: "db", name: "postgres-0"}},
+		"unknown kind":   {value: "job/default/backup", expectError: true},
+		"missing name":   {value: "deploy/default", expectError: true},
+		"empty name":     {value: "deploy/default/", expectError: true},
+		"too many parts": {value: "deploy/default/nginx/extra", expectError: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			w, err := parseWorkload(test.value)
+			if test.expectError {
+				if err == nil {
+					t.Fatalf("expected error, got %v", w)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(w, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, w)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := map[string]struct {
+		options     CreatorCmdOptions
+		expectError bool
+	}{
+		"volume":               {options: CreatorCmdOptions{Volume: "vol-1", Timeout: 1}},
+		"quiesced workload":    {options: CreatorCmdOptions{Workload: "sts/db/postgres", Quiesce: true, Timeout: 1}},
+		"neither":              {options: CreatorCmdOptions{Timeout: 1}, expectError: true},
+		"both":                 {options: CreatorCmdOptions{Volume: "vol-1", Workload: "sts/db/postgres", Timeout: 1}, expectError: true},
+		"quiesced volume":      {options: CreatorCmdOptions{Volume: "vol-1", Quiesce: true, Timeout: 1}, expectError: true},
+		"invalid workload":     {options: CreatorCmdOptions{Workload: "postgres", Timeout: 1}, expectError: true},
+		"non-positive timeout": {options: CreatorCmdOptions{Volume: "vol-1"}, expectError: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			creator := &Creator{CreatorCmdOptions: test.options}
+			if err := creator.Validate(); test.expectError != (err != nil) {
+				t.Errorf("expected error %v, got %v", test.expectError, err)
+			}
+		})
+	}
+}
+
+func newTestPod(name string, claims map[string]string, blockVolumes ...string) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "db"},
+		Spec: corev1.PodSpec{
+			NodeName:   "node-1",
+			Containers: []corev1.Container{{Name: "app"}},
+		},
+	}
+	for volumeName, claimName := range claims {
+		pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+			Name: volumeName,
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: claimName},
+			},
+		})
+	}
+	pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{Name: "config", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}})
+	for _, volumeName := range blockVolumes {
+		pod.Spec.Containers[0].VolumeDevices = append(pod.Spec.Containers[0].VolumeDevices, corev1.VolumeDevice{Name: volumeName, DevicePath: "/dev/xvda"})
+	}
+	return pod
+}
+
+func newTestVolume(name, namespace, pvc string, accessMode longhorn.AccessMode) longhorn.Volume {
+	volume := longhorn.Volume{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	volume.Spec.AccessMode = accessMode
+	volume.Status.KubernetesStatus.Namespace = namespace
+	volume.Status.KubernetesStatus.PVCName = pvc
+	return volume
+}
+
+func TestNewTargets(t *testing.T) {
+	pods := []*corev1.Pod{
+		newTestPod("postgres-0", map[string]string{"data": "data-postgres-0", "shared": "shared"}),
+		newTestPod("postgres-1", map[string]string{"data": "data-postgres-1", "shared": "shared", "raw": "raw-postgres-1"}, "raw"),
+	}
+	volumes := []longhorn.Volume{
+		newTestVolume("pvc-2", "db", "data-postgres-1", longhorn.AccessModeReadWriteOnce),
+		newTestVolume("pvc-1", "db", "data-postgres-0", longhorn.AccessModeReadWriteOnce),
+		newTestVolume("pvc-3", "db", "shared", longhorn.AccessModeReadWriteMany),
+		newTestVolume("pvc-4", "db", "raw-postgres-1", longhorn.AccessModeReadWriteOnce),
+		newTestVolume("pvc-5", "other", "data-postgres-0", longhorn.AccessModeReadWriteOnce),
+		newTestVolume("pvc-6", "", "", ""),
+	}
+
+	targets := newTargets(pods, volumes)
+
+	type summary struct {
+		volume, pvc   string
+		pods          int
+		block, shared bool
+	}
+	var got []summary
+	for _, target := range targets {
+		got = append(got, summary{target.volume, target.pvc, len(target.pods), target.block, target.shared})
+	}
+	expected := []summary{
+		{"pvc-1", "db/data-postgres-0", 1, false, false},
+		{"pvc-2", "db/data-postgres-1", 1, false, false},
+		{"pvc-3", "db/shared", 2, false, true},
+		{"pvc-4", "db/raw-postgres-1", 1, true, false},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+}
+
+func TestGetSnapshotName(t *testing.T) {
+	if name := getSnapshotName("nightly", "pvc-1", 1); name != "nightly" {
+		t.Errorf("expected nightly, got %v", name)
+	}
+	if name := getSnapshotName("nightly", "pvc-1", 2); name != "nightly-pvc-1" {
+		t.Errorf("expected nightly-pvc-1, got %v", name)
+	}
+}
+
+func TestRecordQuiesce(t *testing.T) {
+	pod0 := newTestPod("postgres-0", nil)
+	pod1 := newTestPod("postgres-1", nil)
+	creator := &Creator{targets: []*target{
+		{volume: "pvc-1", pods: []*corev1.Pod{pod0}},
+		{volume: "pvc-2", pods: []*corev1.Pod{pod1}},
+		{volume: "pvc-3", pods: []*corev1.Pod{pod1, pod0}},
+	}}
+
+	if pods := creator.getPods(); len(pods) != 2 || pods[0] != pod0 || pods[1] != pod1 {
+		t.Fatalf("expected the pods sorted by name, got %v", pods)
+	}
+
+	creator.recordQuiesce(pod1, types.SnapshotQuiescePreHook)
+	for i, expected := range [][]string{nil, {types.SnapshotQuiescePreHook}, {types.SnapshotQuiescePreHook}} {
+		if !reflect.DeepEqual(creator.targets[i].quiesce, expected) {
+			t.Errorf("volume %v: expected %v, got %v", creator.targets[i].volume, expected, creator.targets[i].quiesce)
+		}
+	}
+}
+
+func TestGetHookContainer(t *testing.T) {
+	pod := newTestPod("postgres-0", nil)
+	pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{Name: "sidecar"})
+	if container := getHookContainer(pod); container != "app" {
+		t.Errorf("expected app, got %v", container)
+	}
+
+	pod.Annotations = map[string]string{consts.AnnotationSnapshotHookContainer: "sidecar"}
+	if container := getHookContainer(pod); container != "sidecar" {
+		t.Errorf("expected sidecar, got %v", container)
+	}
+}
+
+func TestGetFsfreezeCommand(t *testing.T) {
+	command := getFsfreezeCommand("pvc-1", true)
+	if !reflect.DeepEqual(command[:5], []string{"nsenter", "--mount=/host/proc/1/ns/mnt", "--", "sh", "-c"}) {
+		t.Errorf("unexpected command %v", command)
+	}
+	for _, expected := range []string{"/dev/mapper/pvc-1 /dev/longhorn/pvc-1", `fsfreeze --freeze "$target"`} {
+		if !strings.Contains(command[5], expected) {
+			t.Errorf("expected script to contain %q, got:\n%s", expected, command[5])
+		}
+	}
+
+	if script := getFsfreezeCommand("pvc-1", false)[5]; !strings.Contains(script, `fsfreeze --unfreeze "$target"`) {
+		t.Errorf("expected script to thaw, got:\n%s", script)
+	}
+}
@@ -0,0 +1,489 @@
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	kubeclient "k8s.io/client-go/kubernetes"
+
+	lhmgrtypes "github.com/longhorn/longhorn-manager/types"
+
+	longhorn "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+	lhclient "github.com/longhorn/longhorn-manager/k8s/pkg/client/clientset/versioned"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// Sources of the CSI snapshots.
+const (
+	SourceSnapshot = "snapshot"
+	SourceBackup   = "backup"
+)
+
+// Deletion policies of the VolumeSnapshotContents.
+const (
+	DeletionPolicyDelete = "Delete"
+	DeletionPolicyRetain = "Retain"
+)
+
+var (
+	// csiSnapshotGroupVersion is the API of the CSI snapshot custom resources.
+	csiSnapshotGroupVersion = schema.GroupVersion{Group: "snapshot.storage.k8s.io", Version: "v1"}
+
+	volumeSnapshotResource        = csiSnapshotGroupVersion.WithResource("volumesnapshots")
+	volumeSnapshotContentResource = csiSnapshotGroupVersion.WithResource("volumesnapshotcontents")
+)
+
+// csiClients holds the clients of the CSI snapshot bridging commands.
+type csiClients struct {
+	kubeClient     *kubeclient.Clientset
+	longhornClient *lhclient.Clientset
+	dynamicClient  *dynamic.DynamicClient
+}
+
+// init creates the clients, and ensures the CSI snapshot custom resources are served.
+func (c *csiClients) init(kubeConfigPath string) error {
+	kubeClient, err := kubeutils.NewKubeClient("", kubeConfigPath)
+	if err != nil {
+		return err
+	}
+	c.kubeClient = kubeClient
+
+	longhornClient, err := kubeutils.NewLonghornClient("", kubeConfigPath)
+	if err != nil {
+		return err
+	}
+	c.longhornClient = longhornClient
+
+	config, err := kubeutils.NewRestConfig("", kubeConfigPath)
+	if err != nil {
+		return err
+	}
+	c.dynamicClient, err = dynamic.NewForConfig(config)
+	if err != nil {
+		return errors.Wrap(err, "failed to create dynamic client")
+	}
+
+	resources, err := c.kubeClient.Discovery().ServerResourcesForGroupVersion(csiSnapshotGroupVersion.String())
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "the CSI snapshot custom resources (%v) are not installed", csiSnapshotGroupVersion)
+		}
+		return errors.Wrapf(err, "failed to discover %v", csiSnapshotGroupVersion)
+	}
+	served := map[string]bool{}
+	for _, resource := range resources.APIResources {
+		served[resource.Name] = true
+	}
+	for _, resource := range []string{volumeSnapshotResource.Resource, volumeSnapshotContentResource.Resource} {
+		if !served[resource] {
+			return errors.Errorf("the CSI snapshot custom resource %v is not installed", resource)
+		}
+	}
+	return nil
+}
+
+// annotateSource records the VolumeSnapshot on the Longhorn snapshot or backup.
+func (c *csiClients) annotateSource(ctx context.Context, namespace string, link *types.CSISnapshotLink) error {
+	client := c.longhornClient.LonghornV1beta2()
+
+	var object metav1.Object
+	var update func() error
+	switch link.Source {
+	case SourceSnapshot:
+		snapshot, err := client.Snapshots(namespace).Get(ctx, link.Name, metav1.GetOptions{})
+		if err != nil {
+			return errors.Wrapf(err, "failed to get snapshot %v", link.Name)
+		}
+		object = snapshot
+		update = func() error {
+			_, err := client.Snapshots(namespace).Update(ctx, snapshot, metav1.UpdateOptions{})
+			return err
+		}
+	case SourceBackup:
+		backup, err := client.Backups(namespace).Get(ctx, link.Name, metav1.GetOptions{})
+		if err != nil {
+			return errors.Wrapf(err, "failed to get backup %v", link.Name)
+		}
+		object = backup
+		update = func() error {
+			_, err := client.Backups(namespace).Update(ctx, backup, metav1.UpdateOptions{})
+			return err
+		}
+	}
+
+	annotations := object.GetAnnotations()
+	if annotations[consts.AnnotationVolumeSnapshot] == link.VolumeSnapshot {
+		return nil
+	}
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[consts.AnnotationVolumeSnapshot] = link.VolumeSnapshot
+	object.SetAnnotations(annotations)
+
+	return errors.Wrapf(update(), "failed to annotate %v %v", link.Source, link.Name)
+}
+
+// Promoter provide functions for exposing a Longhorn snapshot or backup to the CSI tooling, with a
+// pre-provisioned VolumeSnapshotContent referring to it by its snapshot handle, and a VolumeSnapshot
+// bound to the content.
+type Promoter struct {
+	PromoterCmdOptions
+	csiClients
+
+	link *types.CSISnapshotLink
+}
+
+// PromoterCmdOptions holds the options for the command.
+type PromoterCmdOptions struct {
+	types.GlobalCmdOptions
+
+	LonghornNamespace   string
+	Name                string // Name of the Longhorn snapshot or backup.
+	Backup              bool   // The name is the one of a backup.
+	VolumeSnapshot      string // VolumeSnapshot to create, as <namespace>/<name>. Defaults to the name in the namespace of the PVC of the volume.
+	VolumeSnapshotClass string
+	DeletionPolicy      string
+}
+
+// Validate validates the command options.
+func (remote *Promoter) Validate() error {
+	if remote.Name == "" {
+		return errors.New("name of the snapshot or backup is required")
+	}
+
+	if remote.VolumeSnapshot != "" {
+		if _, err := parseNamespacedName(remote.VolumeSnapshot); err != nil {
+			return errors.Wrapf(err, "invalid --%s", consts.CmdOptVolumeSnapshot)
+		}
+	}
+
+	switch remote.DeletionPolicy {
+	case DeletionPolicyRetain, DeletionPolicyDelete:
+	default:
+		return errors.Errorf("invalid --%s %q, expected %s or %s", consts.CmdOptDeletionPolicy, remote.DeletionPolicy, DeletionPolicyRetain, DeletionPolicyDelete)
+	}
+
+	return nil
+}
+
+// Init initializes the Promoter, and ensures the snapshot or backup is ready to be used.
+func (remote *Promoter) Init() error {
+	if err := remote.csiClients.init(remote.KubeConfigPath); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	client := remote.longhornClient.LonghornV1beta2()
+
+	link := &types.CSISnapshotLink{
+		Name:           remote.Name,
+		DeletionPolicy: remote.DeletionPolicy,
+	}
+	handleType := consts.CSISnapshotTypeSnapshot
+	if remote.Backup {
+		backup, err := client.Backups(remote.LonghornNamespace).Get(ctx, remote.Name, metav1.GetOptions{})
+		if err != nil {
+			return errors.Wrapf(err, "failed to get backup %v", remote.Name)
+		}
+		if backup.Status.State != longhorn.BackupStateCompleted {
+			return errors.Errorf("backup %v is %v, not completed", remote.Name, backup.Status.State)
+		}
+		link.Source = SourceBackup
+		link.Volume = backup.Status.VolumeName
+		handleType = consts.CSISnapshotTypeBackup
+	} else {
+		snapshot, err := client.Snapshots(remote.LonghornNamespace).Get(ctx, remote.Name, metav1.GetOptions{})
+		if err != nil {
+			return errors.Wrapf(err, "failed to get snapshot %v", remote.Name)
+		}
+		if !snapshot.Status.ReadyToUse {
+			return errors.Errorf("snapshot %v is not ready to use", remote.Name)
+		}
+		link.Source = SourceSnapshot
+		link.Volume = snapshot.Spec.Volume
+	}
+	link.SnapshotHandle = getSnapshotHandle(handleType, link.Volume, remote.Name)
+	link.VolumeSnapshotContent = fmt.Sprintf("longhorn-%s-%s", handleType, remote.Name)
+
+	link.VolumeSnapshot = remote.VolumeSnapshot
+	if link.VolumeSnapshot == "" {
+		volume, err := client.Volumes(remote.LonghornNamespace).Get(ctx, link.Volume, metav1.GetOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to get volume %v", link.Volume)
+		}
+		if volume == nil || volume.Status.KubernetesStatus.Namespace == "" {
+			return errors.Errorf("volume %v has no PVC, use --%s to set the namespace of the VolumeSnapshot", link.Volume, consts.CmdOptVolumeSnapshot)
+		}
+		link.VolumeSnapshot = volume.Status.KubernetesStatus.Namespace + "/" + remote.Name
+	}
+
+	remote.link = link
+	return nil
+}
+
+// Run creates the VolumeSnapshotContent and the VolumeSnapshot, and records the VolumeSnapshot on
+// the Longhorn snapshot or backup.
+func (remote *Promoter) Run(ctx context.Context) (*types.CSISnapshotLink, error) {
+	link := remote.link
+	volumeSnapshot, _ := parseNamespacedName(link.VolumeSnapshot)
+
+	logrus.WithFields(logrus.Fields{"handle": link.SnapshotHandle, "name": link.VolumeSnapshotContent}).Info("Creating VolumeSnapshotContent")
+	content := newVolumeSnapshotContent(link, volumeSnapshot, remote.VolumeSnapshotClass)
+	if _, err := remote.dynamicClient.Resource(volumeSnapshotContentResource).Create(ctx, content, metav1.CreateOptions{}); err != nil {
+		return nil, errors.Wrapf(err, "failed to create VolumeSnapshotContent %v", link.VolumeSnapshotContent)
+	}
+
+	logrus.WithField("name", link.VolumeSnapshot).Info("Creating VolumeSnapshot")
+	snapshot := newVolumeSnapshot(link, volumeSnapshot, remote.VolumeSnapshotClass)
+	if _, err := remote.dynamicClient.Resource(volumeSnapshotResource).Namespace(volumeSnapshot.Namespace).Create(ctx, snapshot, metav1.CreateOptions{}); err != nil {
+		return nil, errors.Wrapf(err, "failed to create VolumeSnapshot %v", link.VolumeSnapshot)
+	}
+
+	if err := remote.annotateSource(ctx, remote.LonghornNamespace, link); err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+// Cleanup does nothing, since the CSI objects are the result of the Promoter.
+func (remote *Promoter) Cleanup() error {
+	return nil
+}
+
+// Importer provide functions for bringing a CSI VolumeSnapshot of the Longhorn CSI driver back to
+// the Longhorn snapshot or backup it refers to: it records the VolumeSnapshot on the Longhorn
+// object, and optionally retains the Longhorn object when the CSI objects are deleted.
+type Importer struct {
+	ImporterCmdOptions
+	csiClients
+}
+
+// ImporterCmdOptions holds the options for the command.
+type ImporterCmdOptions struct {
+	types.GlobalCmdOptions
+
+	LonghornNamespace string
+	VolumeSnapshot    string // VolumeSnapshot to import, as <namespace>/<name>.
+	Retain            bool   // Set the deletion policy of the VolumeSnapshotContent to Retain.
+}
+
+// Validate validates the command options.
+func (remote *Importer) Validate() error {
+	_, err := parseNamespacedName(remote.VolumeSnapshot)
+	return errors.Wrap(err, "invalid VolumeSnapshot")
+}
+
+// Init initializes the Importer.
+func (remote *Importer) Init() error {
+	return remote.csiClients.init(remote.KubeConfigPath)
+}
+
+// Run links the VolumeSnapshot to the Longhorn snapshot or backup of its snapshot handle.
+func (remote *Importer) Run(ctx context.Context) (*types.CSISnapshotLink, error) {
+	name, _ := parseNamespacedName(remote.VolumeSnapshot)
+
+	snapshot, err := remote.dynamicClient.Resource(volumeSnapshotResource).Namespace(name.Namespace).Get(ctx, name.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get VolumeSnapshot %v", remote.VolumeSnapshot)
+	}
+	contentName, _, _ := unstructured.NestedString(snapshot.Object, "status", "boundVolumeSnapshotContentName")
+	if contentName == "" {
+		contentName, _, _ = unstructured.NestedString(snapshot.Object, "spec", "source", "volumeSnapshotContentName")
+	}
+	if contentName == "" {
+		return nil, errors.Errorf("VolumeSnapshot %v is not bound to a VolumeSnapshotContent", remote.VolumeSnapshot)
+	}
+
+	content, err := remote.dynamicClient.Resource(volumeSnapshotContentResource).Get(ctx, contentName, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get VolumeSnapshotContent %v", contentName)
+	}
+
+	link, err := newLinkFromContent(content)
+	if err != nil {
+		return nil, err
+	}
+	link.VolumeSnapshot = remote.VolumeSnapshot
+
+	if err := remote.checkSource(ctx, link); err != nil {
+		return nil, err
+	}
+
+	if remote.Retain && link.DeletionPolicy != DeletionPolicyRetain {
+		logrus.WithField("name", contentName).Info("Retaining the Longhorn object on deletion of VolumeSnapshotContent")
+		patch := []byte(fmt.Sprintf(`{"spec":{"deletionPolicy":%q}}`, DeletionPolicyRetain))
+		if _, err := remote.dynamicClient.Resource(volumeSnapshotContentResource).Patch(ctx, contentName, k8stypes.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+			return nil, errors.Wrapf(err, "failed to set the deletion policy of VolumeSnapshotContent %v", contentName)
+		}
+		link.DeletionPolicy = DeletionPolicyRetain
+	}
+
+	if err := remote.annotateSource(ctx, remote.LonghornNamespace, link); err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+// checkSource ensures the Longhorn snapshot or backup of the link exists, and belongs to its volume.
+func (remote *Importer) checkSource(ctx context.Context, link *types.CSISnapshotLink) error {
+	client := remote.longhornClient.LonghornV1beta2()
+
+	var volume string
+	switch link.Source {
+	case SourceSnapshot:
+		snapshot, err := client.Snapshots(remote.LonghornNamespace).Get(ctx, link.Name, metav1.GetOptions{})
+		if err != nil {
+			return errors.Wrapf(err, "failed to get snapshot %v of VolumeSnapshot %v", link.Name, link.VolumeSnapshot)
+		}
+		volume = snapshot.Spec.Volume
+	case SourceBackup:
+		backup, err := client.Backups(remote.LonghornNamespace).Get(ctx, link.Name, metav1.GetOptions{})
+		if err != nil {
+			return errors.Wrapf(err, "failed to get backup %v of VolumeSnapshot %v", link.Name, link.VolumeSnapshot)
+		}
+		volume = backup.Status.VolumeName
+	}
+
+	if volume != link.Volume {
+		return errors.Errorf("%v %v belongs to volume %v, not to volume %v of the snapshot handle %v", link.Source, link.Name, volume, link.Volume, link.SnapshotHandle)
+	}
+	return nil
+}
+
+// Cleanup does nothing, since the Importer does not create any resource.
+func (remote *Importer) Cleanup() error {
+	return nil
+}
+
+// newLinkFromContent returns the link of the VolumeSnapshotContent of the Longhorn CSI driver, from
+// its snapshot handle.
+func newLinkFromContent(content *unstructured.Unstructured) (*types.CSISnapshotLink, error) {
+	driver, _, _ := unstructured.NestedString(content.Object, "spec", "driver")
+	if driver != lhmgrtypes.LonghornDriverName {
+		return nil, errors.Errorf("VolumeSnapshotContent %v is provisioned by %v, not by %v", content.GetName(), driver, lhmgrtypes.LonghornDriverName)
+	}
+
+	handle, _, _ := unstructured.NestedString(content.Object, "status", "snapshotHandle")
+	if handle == "" {
+		handle, _, _ = unstructured.NestedString(content.Object, "spec", "source", "snapshotHandle")
+	}
+	if handle == "" {
+		return nil, errors.Errorf("VolumeSnapshotContent %v has no snapshot handle yet", content.GetName())
+	}
+
+	source, volume, name, err := parseSnapshotHandle(handle)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid snapshot handle of VolumeSnapshotContent %v", content.GetName())
+	}
+
+	deletionPolicy, _, _ := unstructured.NestedString(content.Object, "spec", "deletionPolicy")
+	return &types.CSISnapshotLink{
+		Source:                source,
+		Name:                  name,
+		Volume:                volume,
+		SnapshotHandle:        handle,
+		VolumeSnapshotContent: content.GetName(),
+		DeletionPolicy:        deletionPolicy,
+	}, nil
+}
+
+// newVolumeSnapshotContent prepares the pre-provisioned VolumeSnapshotContent of the link, bound to
+// the VolumeSnapshot.
+func newVolumeSnapshotContent(link *types.CSISnapshotLink, volumeSnapshot k8stypes.NamespacedName, class string) *unstructured.Unstructured {
+	spec := map[string]interface{}{
+		"deletionPolicy": link.DeletionPolicy,
+		"driver":         lhmgrtypes.LonghornDriverName,
+		"source": map[string]interface{}{
+			"snapshotHandle": link.SnapshotHandle,
+		},
+		"volumeSnapshotRef": map[string]interface{}{
+			"name":      volumeSnapshot.Name,
+			"namespace": volumeSnapshot.Namespace,
+		},
+	}
+	if class != "" {
+		spec["volumeSnapshotClassName"] = class
+	}
+
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": csiSnapshotGroupVersion.String(),
+		"kind":       "VolumeSnapshotContent",
+		"metadata": map[string]interface{}{
+			"name": link.VolumeSnapshotContent,
+		},
+		"spec": spec,
+	}}
+}
+
+// newVolumeSnapshot prepares the VolumeSnapshot bound to the pre-provisioned VolumeSnapshotContent
+// of the link.
+func newVolumeSnapshot(link *types.CSISnapshotLink, volumeSnapshot k8stypes.NamespacedName, class string) *unstructured.Unstructured {
+	spec := map[string]interface{}{
+		"source": map[string]interface{}{
+			"volumeSnapshotContentName": link.VolumeSnapshotContent,
+		},
+	}
+	if class != "" {
+		spec["volumeSnapshotClassName"] = class
+	}
+
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": csiSnapshotGroupVersion.String(),
+		"kind":       "VolumeSnapshot",
+		"metadata": map[string]interface{}{
+			"name":      volumeSnapshot.Name,
+			"namespace": volumeSnapshot.Namespace,
+		},
+		"spec": spec,
+	}}
+}
+
+// getSnapshotHandle returns the snapshot handle of the Longhorn CSI driver for the snapshot or
+// backup of the volume.
+func getSnapshotHandle(handleType, volume, name string) string {
+	return fmt.Sprintf("%s://%s/%s", handleType, volume, name)
+}
+
+// parseSnapshotHandle returns the source, volume and name of the snapshot or backup of a snapshot
+// handle of the Longhorn CSI driver.
+func parseSnapshotHandle(handle string) (source, volume, name string, err error) {
+	handleType, path, found := strings.Cut(handle, "://")
+	parts := strings.Split(path, "/")
+	if !found || len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", "", errors.Errorf("invalid snapshot handle %q, expected <type>://<volume>/<name>", handle)
+	}
+
+	switch handleType {
+	case consts.CSISnapshotTypeSnapshot:
+		source = SourceSnapshot
+	case consts.CSISnapshotTypeBackup, consts.CSISnapshotTypeLegacyBackup:
+		source = SourceBackup
+	default:
+		return "", "", "", errors.Errorf("unsupported type %q of snapshot handle %q", handleType, handle)
+	}
+	return source, parts[0], parts[1], nil
+}
+
+// parseNamespacedName parses a name given as <namespace>/<name>.
+func parseNamespacedName(value string) (k8stypes.NamespacedName, error) {
+	namespace, name, found := strings.Cut(value, "/")
+	if !found || namespace == "" || name == "" || strings.Contains(name, "/") {
+		return k8stypes.NamespacedName{}, errors.Errorf("%q is not <namespace>/<name>", value)
+	}
+	return k8stypes.NamespacedName{Namespace: namespace, Name: name}, nil
+}
@@ -0,0 +1,160 @@
+package snapshot
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func TestParseSnapshotHandle(t *testing.T) {
+	tests := map[string]struct {
+		handle         string
+		expectedSource string
+		expectedVolume string
+		expectedName   string
+		expectError    bool
+	}{
+		"snapshot":       {handle: "snap://pvc-1/nightly", expectedSource: SourceSnapshot, expectedVolume: "pvc-1", expectedName: "nightly"},
+		"backup":         {handle: "bak://pvc-1/backup-1", expectedSource: SourceBackup, expectedVolume: "pvc-1", expectedName: "backup-1"},
+		"legacy backup":  {handle: "bs://pvc-1/backup-1", expectedSource: SourceBackup, expectedVolume: "pvc-1", expectedName: "backup-1"},
+		"unknown type":   {handle: "img://pvc-1/nightly", expectError: true},
+		"missing scheme": {handle: "pvc-1/nightly", expectError: true},
+		"missing name":   {handle: "snap://pvc-1", expectError: true},
+		"empty volume":   {handle: "snap:///nightly", expectError: true},
+		"too many parts": {handle: "snap://pvc-1/nightly/extra", expectError: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			source, volume, snapshotName, err := parseSnapshotHandle(test.handle)
+			if test.expectError {
+				if err == nil {
+					t.Fatalf("expected error, got %v %v %v", source, volume, snapshotName)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if source != test.expectedSource || volume != test.expectedVolume || snapshotName != test.expectedName {
+				t.Errorf("expected %v %v %v, got %v %v %v", test.expectedSource, test.expectedVolume, test.expectedName, source, volume, snapshotName)
+			}
+		})
+	}
+}
+
+func TestParseNamespacedName(t *testing.T) {
+	tests := map[string]struct {
+		value       string
+		expected    k8stypes.NamespacedName
+		expectError bool
+	}{
+		"valid":             {value: "db/nightly", expected: k8stypes.NamespacedName{Namespace: "db", Name: "nightly"}},
+		"missing name":      {value: "db/", expectError: true},
+		"missing namespace": {value: "/nightly", expectError: true},
+		"no separator":      {value: "nightly", expectError: true},
+		"too many parts":    {value: "db/nightly/extra", expectError: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := parseNamespacedName(test.value)
+			if test.expectError != (err != nil) {
+				t.Fatalf("expected error %v, got %v", test.expectError, err)
+			}
+			if got != test.expected {
+				t.Errorf("expected %v, got %v", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestPromoterValidate(t *testing.T) {
+	tests := map[string]struct {
+		options     PromoterCmdOptions
+		expectError bool
+	}{
+		"default":                 {options: PromoterCmdOptions{Name: "nightly", DeletionPolicy: DeletionPolicyRetain}},
+		"volume snapshot":         {options: PromoterCmdOptions{Name: "nightly", VolumeSnapshot: "db/nightly", DeletionPolicy: DeletionPolicyDelete}},
+		"missing name":            {options: PromoterCmdOptions{DeletionPolicy: DeletionPolicyRetain}, expectError: true},
+		"invalid volume snapshot": {options: PromoterCmdOptions{Name: "nightly", VolumeSnapshot: "nightly", DeletionPolicy: DeletionPolicyRetain}, expectError: true},
+		"invalid deletion policy": {options: PromoterCmdOptions{Name: "nightly", DeletionPolicy: "Keep"}, expectError: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			promoter := &Promoter{PromoterCmdOptions: test.options}
+			if err := promoter.Validate(); test.expectError != (err != nil) {
+				t.Errorf("expected error %v, got %v", test.expectError, err)
+			}
+		})
+	}
+}
+
+func TestNewVolumeSnapshotContent(t *testing.T) {
+	link := &types.CSISnapshotLink{
+		SnapshotHandle:        "snap://pvc-1/nightly",
+		VolumeSnapshotContent: "longhorn-snap-nightly",
+		DeletionPolicy:        DeletionPolicyRetain,
+	}
+	volumeSnapshot := k8stypes.NamespacedName{Namespace: "db", Name: "nightly"}
+
+	content := newVolumeSnapshotContent(link, volumeSnapshot, "longhorn-snapshot")
+	for path, expected := range map[string][]string{
+		"snap://pvc-1/nightly": {"spec", "source", "snapshotHandle"},
+		"driver.longhorn.io":   {"spec", "driver"},
+		"Retain":               {"spec", "deletionPolicy"},
+		"longhorn-snapshot":    {"spec", "volumeSnapshotClassName"},
+		"db":                   {"spec", "volumeSnapshotRef", "namespace"},
+		"nightly":              {"spec", "volumeSnapshotRef", "name"},
+	} {
+		if value, _, _ := unstructured.NestedString(content.Object, expected...); value != path {
+			t.Errorf("expected %v at %v, got %v", path, expected, value)
+		}
+	}
+
+	snapshot := newVolumeSnapshot(link, volumeSnapshot, "")
+	if value, _, _ := unstructured.NestedString(snapshot.Object, "spec", "source", "volumeSnapshotContentName"); value != "longhorn-snap-nightly" {
+		t.Errorf("expected the VolumeSnapshot bound to longhorn-snap-nightly, got %v", value)
+	}
+	if _, found, _ := unstructured.NestedString(snapshot.Object, "spec", "volumeSnapshotClassName"); found {
+		t.Errorf("expected no VolumeSnapshotClass")
+	}
+
+	// The promoted content is imported back to the same link.
+	if err := unstructured.SetNestedField(content.Object, "snap://pvc-1/nightly", "status", "snapshotHandle"); err != nil {
+		t.Fatal(err)
+	}
+	imported, err := newLinkFromContent(content)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := &types.CSISnapshotLink{
+		Source:                SourceSnapshot,
+		Name:                  "nightly",
+		Volume:                "pvc-1",
+		SnapshotHandle:        "snap://pvc-1/nightly",
+		VolumeSnapshotContent: "longhorn-snap-nightly",
+		DeletionPolicy:        DeletionPolicyRetain,
+	}
+	if !reflect.DeepEqual(imported, expected) {
+		t.Errorf("expected %+v, got %+v", expected, imported)
+	}
+}
+
+func TestNewLinkFromContentOtherDriver(t *testing.T) {
+	content := &unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "snapcontent-1"},
+		"spec": map[string]interface{}{
+			"driver": "ebs.csi.aws.com",
+			"source": map[string]interface{}{"snapshotHandle": "snap-0123"},
+		},
+	}}
+	if _, err := newLinkFromContent(content); err == nil {
+		t.Errorf("expected error for a content of another driver")
+	}
+}
@@ -0,0 +1,225 @@
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// fsfreezeScript freezes or thaws the filesystem of the Longhorn volume in the mount namespace of
+// the host. The filesystem of an encrypted volume is on its device mapper device. Freezing a
+// filesystem freezes all its mount points, so the first one found is enough.
+const fsfreezeScript = `for device in /dev/mapper/%[1]s /dev/longhorn/%[1]s; do
+  target=$(findmnt --noheadings --first-only --output TARGET --source "$device") && break
+done
+if [ -z "$target" ]; then
+  echo "volume %[1]s is not mounted on the node" >&2
+  exit 1
+fi
+fsfreeze %[2]s "$target"`
+
+// runPreHooks runs the pre-snapshot hooks of the pods of the workload, and returns the pods whose
+// post-snapshot hooks must run: the ones processed before a failure.
+func (remote *Creator) runPreHooks(ctx context.Context) ([]*corev1.Pod, error) {
+	var hookedPods []*corev1.Pod
+	for _, pod := range remote.getPods() {
+		hookedPods = append(hookedPods, pod)
+
+		ran, err := remote.runHook(ctx, pod, consts.AnnotationSnapshotPreHook)
+		if err != nil {
+			// The pre-snapshot hook of the pod may have partially run, so its post-snapshot hook
+			// still runs.
+			return hookedPods, err
+		}
+		if ran {
+			remote.recordQuiesce(pod, types.SnapshotQuiescePreHook)
+		}
+	}
+	return hookedPods, nil
+}
+
+// runPostHooks runs the post-snapshot hooks of the pods, and returns the first error after trying
+// all of them.
+func (remote *Creator) runPostHooks(pods []*corev1.Pod) error {
+	var firstErr error
+	for _, pod := range pods {
+		ran, err := remote.runHook(context.Background(), pod, consts.AnnotationSnapshotPostHook)
+		if err != nil {
+			logrus.WithError(err).Errorf("Failed to run the post-snapshot hook of pod %v/%v", pod.Namespace, pod.Name)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if ran {
+			remote.recordQuiesce(pod, types.SnapshotQuiescePostHook)
+		}
+	}
+	return firstErr
+}
+
+// runHook runs the command of the hook annotation of the pod, and returns whether the pod has the
+// hook.
+func (remote *Creator) runHook(ctx context.Context, pod *corev1.Pod, annotation string) (bool, error) {
+	command := pod.Annotations[annotation]
+	if command == "" {
+		return false, nil
+	}
+
+	container := getHookContainer(pod)
+	logrus.WithFields(logrus.Fields{"pod": pod.Namespace + "/" + pod.Name, "container": container}).Infof("Running %v", annotation)
+
+	ctx, cancel := context.WithTimeout(ctx, remote.Timeout)
+	defer cancel()
+
+	stdout, stderr, err := kubeutils.ExecPodContainer(ctx, remote.restConfig, remote.kubeClient, pod.Namespace, pod.Name, container, []string{"/bin/sh", "-c", command})
+	if stdout != "" {
+		logrus.Debugf("Output of %v: %s", annotation, stdout)
+	}
+	if err != nil {
+		return true, errors.Wrapf(err, "failed to run %v of pod %v/%v: %s", annotation, pod.Namespace, pod.Name, strings.TrimSpace(stderr))
+	}
+	return true, nil
+}
+
+// freeze freezes the filesystems of the volumes, and returns the frozen ones. The filesystems of
+// the block devices and the ReadWriteMany volumes are not frozen.
+func (remote *Creator) freeze(ctx context.Context) ([]*target, error) {
+	var frozenTargets []*target
+	for _, target := range remote.targets {
+		log := logrus.WithField("volume", target.volume)
+		switch {
+		case target.block:
+			log.Warn("Skipping the filesystem freeze of the volume used as a block device")
+			continue
+		case target.shared:
+			log.Warn("Skipping the filesystem freeze of the ReadWriteMany volume")
+			continue
+		}
+
+		log.Info("Freezing the filesystem of the volume")
+		if err := remote.runFsfreeze(ctx, target, true); err != nil {
+			return frozenTargets, err
+		}
+		frozenTargets = append(frozenTargets, target)
+		target.quiesce = append(target.quiesce, types.SnapshotQuiesceFsfreeze)
+	}
+	return frozenTargets, nil
+}
+
+// thaw thaws the filesystems of the volumes, and returns the first error after trying all of them.
+func (remote *Creator) thaw(targets []*target) error {
+	var firstErr error
+	for _, target := range targets {
+		logrus.WithField("volume", target.volume).Info("Thawing the filesystem of the volume")
+		if err := remote.runFsfreeze(context.Background(), target, false); err != nil {
+			logrus.WithError(err).Errorf("Failed to thaw the filesystem of volume %v, run 'fsfreeze --unfreeze' on its mount point on node %v", target.volume, target.pods[0].Spec.NodeName)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// runFsfreeze freezes or thaws the filesystem of the volume from the instance manager of the node
+// of the pods using it, which is privileged and has access to the processes of the host.
+func (remote *Creator) runFsfreeze(ctx context.Context, target *target, freeze bool) error {
+	nodeName := target.pods[0].Spec.NodeName
+	instanceManager, err := remote.getInstanceManagerPod(ctx, nodeName)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, remote.Timeout)
+	defer cancel()
+
+	_, stderr, err := kubeutils.ExecPodContainer(ctx, remote.restConfig, remote.kubeClient, instanceManager.Namespace, instanceManager.Name, instanceManager.Spec.Containers[0].Name, getFsfreezeCommand(target.volume, freeze))
+	if err != nil {
+		action := "thaw"
+		if freeze {
+			action = "freeze"
+		}
+		return errors.Wrapf(err, "failed to %v the filesystem of volume %v on node %v: %s", action, target.volume, nodeName, strings.TrimSpace(stderr))
+	}
+	return nil
+}
+
+// getInstanceManagerPod returns a running instance manager pod of the node.
+func (remote *Creator) getInstanceManagerPod(ctx context.Context, nodeName string) (*corev1.Pod, error) {
+	podList, err := remote.kubeClient.CoreV1().Pods(remote.LonghornNamespace).List(ctx, metav1.ListOptions{
+		LabelSelector: consts.LonghornLabelSelectorInstanceManager,
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", nodeName).String(),
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list instance manager pods of node %v", nodeName)
+	}
+
+	sort.Slice(podList.Items, func(i, j int) bool { return podList.Items[i].Name < podList.Items[j].Name })
+	for i := range podList.Items {
+		if podList.Items[i].Status.Phase == corev1.PodRunning {
+			return &podList.Items[i], nil
+		}
+	}
+	return nil, errors.Errorf("no running instance manager pod on node %v", nodeName)
+}
+
+// getPods returns the pods of the workload using the volumes, sorted by name.
+func (remote *Creator) getPods() []*corev1.Pod {
+	podsByName := map[string]*corev1.Pod{}
+	for _, target := range remote.targets {
+		for _, pod := range target.pods {
+			podsByName[pod.Name] = pod
+		}
+	}
+
+	pods := make([]*corev1.Pod, 0, len(podsByName))
+	for _, pod := range podsByName {
+		pods = append(pods, pod)
+	}
+	sort.Slice(pods, func(i, j int) bool { return pods[i].Name < pods[j].Name })
+	return pods
+}
+
+// recordQuiesce records the quiesce step on the volumes used by the pod.
+func (remote *Creator) recordQuiesce(pod *corev1.Pod, step string) {
+	for _, target := range remote.targets {
+		for _, targetPod := range target.pods {
+			if targetPod.Name == pod.Name {
+				target.quiesce = append(target.quiesce, step)
+				break
+			}
+		}
+	}
+}
+
+// getHookContainer returns the container running the hooks of the pod.
+func getHookContainer(pod *corev1.Pod) string {
+	if container := pod.Annotations[consts.AnnotationSnapshotHookContainer]; container != "" {
+		return container
+	}
+	return pod.Spec.Containers[0].Name
+}
+
+// getFsfreezeCommand returns the command freezing or thawing the filesystem of the volume in the
+// mount namespace of the host, from an instance manager pod.
+func getFsfreezeCommand(volume string, freeze bool) []string {
+	option := "--unfreeze"
+	if freeze {
+		option = "--freeze"
+	}
+	return []string{"nsenter", "--mount=/host/proc/1/ns/mnt", "--", "sh", "-c", fmt.Sprintf(fsfreezeScript, volume, option)}
+}
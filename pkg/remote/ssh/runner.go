@@ -0,0 +1,280 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	sigsyaml "sigs.k8s.io/yaml"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+)
+
+const (
+	sshBinary = "ssh"
+	scpBinary = "scp"
+
+	// sshExitCodeConnectionError is the exit code of ssh when the connection to the host fails.
+	sshExitCodeConnectionError = 255
+)
+
+// Runner runs longhornctl-local on hosts over SSH with the ssh and scp clients.
+type Runner struct {
+	Hosts []types.SSHHost
+
+	// LocalBinary is the longhornctl-local binary uploaded to the hosts.
+	// When empty, longhornctl-local is expected on the PATH of the hosts.
+	LocalBinary string
+
+	// MaxParallel is the maximum number of hosts to run on at the same time.
+	// Runs on all hosts at once when not positive.
+	MaxParallel int
+}
+
+// NewRunner returns a Runner for the hosts listed in the hosts file.
+func NewRunner(options *types.SSHCmdOptions, maxParallel int) (*Runner, error) {
+	data, err := os.ReadFile(options.SSHHostsFile)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read SSH hosts file %v", options.SSHHostsFile)
+	}
+
+	hostList, err := ParseHosts(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.SSHLocalBinary != "" {
+		if _, err := os.Stat(options.SSHLocalBinary); err != nil {
+			return nil, errors.Wrapf(err, "failed to find %v binary", consts.CmdLonghornctlLocal)
+		}
+	}
+
+	for _, binary := range []string{sshBinary, scpBinary} {
+		if _, err := exec.LookPath(binary); err != nil {
+			return nil, errors.Wrapf(err, "failed to find %v client", binary)
+		}
+	}
+
+	return &Runner{
+		Hosts:       hostList.Hosts,
+		LocalBinary: options.SSHLocalBinary,
+		MaxParallel: maxParallel,
+	}, nil
+}
+
+// ParseHosts parses and validates the SSH hosts YAML.
+func ParseHosts(data []byte) (*types.SSHHostList, error) {
+	hostList := &types.SSHHostList{}
+	if err := sigsyaml.UnmarshalStrict(data, hostList); err != nil {
+		return nil, errors.Wrap(err, "failed to parse SSH hosts")
+	}
+
+	if len(hostList.Hosts) == 0 {
+		return nil, errors.New("no SSH hosts defined")
+	}
+
+	names := map[string]bool{}
+	for i := range hostList.Hosts {
+		host := &hostList.Hosts[i]
+		if host.Address == "" {
+			return nil, errors.Errorf("SSH host #%d has no address", i)
+		}
+
+		if host.Name == "" {
+			host.Name = host.Address
+		}
+
+		if names[host.Name] {
+			return nil, errors.Errorf("SSH host %v is defined more than once", host.Name)
+		}
+		names[host.Name] = true
+	}
+
+	return hostList, nil
+}
+
+// Run runs longhornctl-local with the arguments on every host, and returns the
+// result of each host keyed by the host name. The files are uploaded to the host
+// first, and passed to longhornctl-local as the value of the option they are keyed by.
+//
+// A failure on a host is reported as an error in the result of the host, so the
+// other hosts still complete.
+func (runner *Runner) Run(args []string, files map[string]string) map[string]*types.LogCollection {
+	parallel := runner.MaxParallel
+	if parallel <= 0 || parallel > len(runner.Hosts) {
+		parallel = len(runner.Hosts)
+	}
+
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, parallel)
+
+	nodeCollections := map[string]*types.LogCollection{}
+	for _, host := range runner.Hosts {
+		wg.Add(1)
+		go func(host types.SSHHost) {
+			defer wg.Done()
+
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			log := logrus.WithField("host", host.Name)
+			log.Info("Running on host over SSH")
+
+			collection, err := runner.runOnHost(log, host, args, files)
+			if err != nil {
+				log.WithError(err).Warn("Failed to run on host over SSH")
+				collection = &types.LogCollection{
+					Error: []string{fmt.Sprintf("Failed to run on host over SSH: %v", err)},
+				}
+			}
+
+			lock.Lock()
+			nodeCollections[host.Name] = collection
+			lock.Unlock()
+		}(host)
+	}
+	wg.Wait()
+
+	return nodeCollections
+}
+
+func (runner *Runner) runOnHost(log *logrus.Entry, host types.SSHHost, args []string, files map[string]string) (*types.LogCollection, error) {
+	tempDir, err := runner.ssh(host, []string{"mktemp", "-d", "/tmp/" + consts.CmdLonghornctlLocal + ".XXXXXX"})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create temporary directory")
+	}
+	tempDir = strings.TrimSpace(tempDir)
+	defer func() {
+		if _, err := runner.ssh(host, []string{"rm", "-rf", tempDir}); err != nil {
+			log.WithError(err).Warnf("Failed to remove temporary directory %v", tempDir)
+		}
+	}()
+
+	binary := consts.CmdLonghornctlLocal
+	if runner.LocalBinary != "" {
+		binary = filepath.Join(tempDir, consts.CmdLonghornctlLocal)
+		if err := runner.scp(host, runner.LocalBinary, binary); err != nil {
+			return nil, errors.Wrapf(err, "failed to upload %v", runner.LocalBinary)
+		}
+
+		if _, err := runner.ssh(host, []string{"chmod", "+x", binary}); err != nil {
+			return nil, errors.Wrapf(err, "failed to make %v executable", binary)
+		}
+	}
+
+	command := []string{binary}
+	command = append(command, args...)
+	for option, localPath := range files {
+		remotePath := filepath.Join(tempDir, filepath.Base(localPath))
+		if err := runner.scp(host, localPath, remotePath); err != nil {
+			return nil, errors.Wrapf(err, "failed to upload %v", localPath)
+		}
+		command = append(command, fmt.Sprintf("--%s=%s", option, remotePath))
+	}
+
+	if host.Sudo {
+		command = append([]string{"sudo", "-n"}, command...)
+	}
+
+	log.Debugf("Executing command: %v", strings.Join(command, " "))
+	output, err := runner.ssh(host, command)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseResult(output)
+}
+
+// ParseResult returns the result printed by longhornctl-local to stdout.
+func ParseResult(output string) (*types.LogCollection, error) {
+	index := strings.LastIndex(output, consts.LocalResultHeader)
+	if index < 0 {
+		return nil, errors.Errorf("no result found in output: %s", strings.TrimSpace(output))
+	}
+
+	var nodeCollection types.NodeCollection
+	if err := json.Unmarshal([]byte(output[index+len(consts.LocalResultHeader):]), &nodeCollection); err != nil {
+		return nil, errors.Wrap(err, "failed to decode result")
+	}
+
+	if nodeCollection.Log == nil {
+		return &types.LogCollection{}, nil
+	}
+	return nodeCollection.Log, nil
+}
+
+// ssh runs the command on the host and returns its stdout. The arguments are quoted
+// for the remote shell.
+func (runner *Runner) ssh(host types.SSHHost, command []string) (string, error) {
+	args := commonArgs(host)
+	if host.Port != 0 {
+		args = append(args, "-p", strconv.Itoa(host.Port))
+	}
+	args = append(args, destination(host), "--")
+	for _, arg := range command {
+		args = append(args, shellQuote(arg))
+	}
+
+	output, err := execute(sshBinary, args)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == sshExitCodeConnectionError {
+		return "", &types.NodeUnreachableError{Node: host.Name, Err: err}
+	}
+	return output, err
+}
+
+// scp copies the local file to the path on the host.
+func (runner *Runner) scp(host types.SSHHost, localPath, remotePath string) error {
+	args := commonArgs(host)
+	if host.Port != 0 {
+		args = append(args, "-P", strconv.Itoa(host.Port))
+	}
+	args = append(args, "-q", localPath, destination(host)+":"+remotePath)
+
+	_, err := execute(scpBinary, args)
+	return err
+}
+
+func commonArgs(host types.SSHHost) []string {
+	args := []string{"-o", "BatchMode=yes", "-o", "ConnectTimeout=10"}
+	if host.IdentityFile != "" {
+		args = append(args, "-i", host.IdentityFile)
+	}
+	return args
+}
+
+func destination(host types.SSHHost) string {
+	if host.User == "" {
+		return host.Address
+	}
+	return host.User + "@" + host.Address
+}
+
+func execute(binary string, args []string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(context.Background(), binary, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", errors.Wrapf(err, "%s", strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// shellQuote quotes the argument for a POSIX shell.
+func shellQuote(arg string) string {
+	return "'" + strings.ReplaceAll(arg, "'", `'"'"'`) + "'"
+}
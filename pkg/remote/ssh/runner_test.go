@@ -0,0 +1,134 @@
+package ssh
+
+import (
+	"testing"
+)
+
+func TestParseHosts(t *testing.T) {
+	tests := []struct {
+		name          string
+		data          string
+		expectedNames []string
+		expectedError bool
+	}{
+		{
+			name: "name defaults to address",
+			data: `hosts:
+- address: 10.0.0.1
+- name: node-2
+  address: 10.0.0.2
+  port: 2222
+  user: root`,
+			expectedNames: []string{"10.0.0.1", "node-2"},
+		},
+		{
+			name:          "no hosts",
+			data:          `hosts: []`,
+			expectedError: true,
+		},
+		{
+			name: "missing address",
+			data: `hosts:
+- name: node-1`,
+			expectedError: true,
+		},
+		{
+			name: "duplicate name",
+			data: `hosts:
+- address: 10.0.0.1
+- address: 10.0.0.1`,
+			expectedError: true,
+		},
+		{
+			name: "unknown field",
+			data: `hosts:
+- address: 10.0.0.1
+  password: secret`,
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hostList, err := ParseHosts([]byte(test.data))
+			if test.expectedError {
+				if err == nil {
+					t.Fatalf("expected error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(hostList.Hosts) != len(test.expectedNames) {
+				t.Fatalf("expected %d hosts, got %d", len(test.expectedNames), len(hostList.Hosts))
+			}
+			for i, host := range hostList.Hosts {
+				if host.Name != test.expectedNames[i] {
+					t.Errorf("expected host #%d name %q, got %q", i, test.expectedNames[i], host.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestParseResult(t *testing.T) {
+	tests := []struct {
+		name          string
+		output        string
+		expectedError []string
+		expectedInfo  []string
+		expectError   bool
+	}{
+		{
+			name:         "result",
+			output:       "Result: \n{\"log\":{\"info\":[\"Service iscsid is running\"]}}\n",
+			expectedInfo: []string{"Service iscsid is running"},
+		},
+		{
+			name:          "result after other output",
+			output:        "Updating packages\nResult: \n{\"log\":{\"error\":[\"Package nfs-client is not installed\"]}}\n",
+			expectedError: []string{"Package nfs-client is not installed"},
+		},
+		{
+			name:        "no result",
+			output:      "command not found\n",
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			collection, err := ParseResult(test.output)
+			if test.expectError {
+				if err == nil {
+					t.Fatalf("expected error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(collection.Error) != len(test.expectedError) || len(collection.Info) != len(test.expectedInfo) {
+				t.Fatalf("unexpected result: %+v", collection)
+			}
+		})
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := map[string]string{
+		"plain":       "'plain'",
+		"with space":  "'with space'",
+		"it's":        `'it'"'"'s'`,
+		"--opt=$HOME": "'--opt=$HOME'",
+	}
+
+	for arg, expected := range tests {
+		if quoted := shellQuote(arg); quoted != expected {
+			t.Errorf("shellQuote(%q) = %q, expected %q", arg, quoted, expected)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package storageclass
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	kubeclient "k8s.io/client-go/kubernetes"
+
+	lhmgrtypes "github.com/longhorn/longhorn-manager/types"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// Annotations marking the default StorageClass. The beta annotation is still honored by Kubernetes.
+const (
+	AnnotationIsDefaultClass     = "storageclass.kubernetes.io/is-default-class"
+	AnnotationBetaIsDefaultClass = "storageclass.beta.kubernetes.io/is-default-class"
+)
+
+// Kinds of the checked objects, used in the keys of the result.
+const (
+	KindPersistentVolumeClaim = "PersistentVolumeClaim"
+	KindStorageClass          = "StorageClass"
+)
+
+// Checker provide functions for checking the default StorageClass of the cluster and the Longhorn
+// StorageClass the workloads request.
+type Checker struct {
+	CheckerCmdOptions
+
+	kubeClient *kubeclient.Clientset
+
+	storageClasses []storagev1.StorageClass
+}
+
+// CheckerCmdOptions holds the options for the command.
+type CheckerCmdOptions struct {
+	types.GlobalCmdOptions
+
+	StorageClass string // StorageClass expected by the workloads, and set as default by the fix.
+}
+
+// Validate validates the command options.
+func (remote *Checker) Validate() error {
+	if remote.StorageClass == "" {
+		return errors.Errorf("storage class (--%s) is required", consts.CmdOptStorageClass)
+	}
+	return nil
+}
+
+// Init initializes the Checker.
+func (remote *Checker) Init() error {
+	kubeClient, err := kubeutils.NewKubeClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.kubeClient = kubeClient
+	return nil
+}
+
+// Collect checks the StorageClasses and the pending PVCs, and returns the result of each object
+// keyed by its kind and name.
+func (remote *Checker) Collect() (map[string]*types.LogCollection, error) {
+	ctx := context.Background()
+
+	storageClasses, err := remote.kubeClient.StorageV1().StorageClasses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list StorageClasses")
+	}
+	remote.storageClasses = storageClasses.Items
+
+	pvcs, err := remote.kubeClient.CoreV1().PersistentVolumeClaims(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list PVCs")
+	}
+
+	return checkStorageClasses(remote.storageClasses, pvcs.Items, remote.StorageClass), nil
+}
+
+// DefaultChanges returns the changes setting the StorageClass as the only default StorageClass, as
+// "kind/name: change". It is empty when the StorageClass is already the only default.
+func (remote *Checker) DefaultChanges() ([]string, error) {
+	changes, err := getDefaultChanges(remote.storageClasses, remote.StorageClass)
+	if err != nil {
+		return nil, err
+	}
+
+	descriptions := make([]string, 0, len(changes))
+	for _, name := range sortedKeys(changes) {
+		action := "unset as default"
+		if changes[name] {
+			action = "set as default"
+		}
+		descriptions = append(descriptions, fmt.Sprintf("%v/%v: %v", KindStorageClass, name, action))
+	}
+	return descriptions, nil
+}
+
+// SetDefault sets the StorageClass as the only default StorageClass, by patching the default
+// annotations of the StorageClasses.
+func (remote *Checker) SetDefault() error {
+	changes, err := getDefaultChanges(remote.storageClasses, remote.StorageClass)
+	if err != nil {
+		return err
+	}
+
+	// Unset the other defaults first, so there is never more than one default.
+	names := sortedKeys(changes)
+	sort.SliceStable(names, func(i, j int) bool { return !changes[names[i]] && changes[names[j]] })
+
+	for _, name := range names {
+		patch, err := newDefaultAnnotationPatch(changes[name])
+		if err != nil {
+			return err
+		}
+		if _, err := remote.kubeClient.StorageV1().StorageClasses().Patch(context.Background(), name, k8stypes.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+			return errors.Wrapf(err, "failed to patch the default annotation of StorageClass %v", name)
+		}
+	}
+	return nil
+}
+
+// Cleanup does nothing, since the Checker does not create any resource.
+func (remote *Checker) Cleanup() error {
+	return nil
+}
+
+// checkStorageClasses returns the messages about the default StorageClasses, the expected
+// StorageClass, and the pending PVCs whose StorageClass does not exist or is not set without a
+// default StorageClass.
+func checkStorageClasses(storageClasses []storagev1.StorageClass, pvcs []corev1.PersistentVolumeClaim, expected string) map[string]*types.LogCollection {
+	collections := map[string]*types.LogCollection{}
+
+	existing := map[string]bool{}
+	var defaults []string
+	for _, storageClass := range storageClasses {
+		existing[storageClass.Name] = true
+		if IsDefaultStorageClass(&storageClass) {
+			defaults = append(defaults, storageClass.Name)
+		}
+	}
+	sort.Strings(defaults)
+
+	requested := map[string]int{} // Pending PVCs, by the StorageClass they request.
+	for _, pvc := range pvcs {
+		if pvc.Status.Phase != corev1.ClaimPending {
+			continue
+		}
+
+		key := fmt.Sprintf("%v/%v/%v", KindPersistentVolumeClaim, pvc.Namespace, pvc.Name)
+		switch {
+		case pvc.Spec.StorageClassName == nil && len(defaults) == 0:
+			collections[key] = &types.LogCollection{
+				Error: []string{"Pending without a StorageClass, and there is no default StorageClass"},
+			}
+		case pvc.Spec.StorageClassName != nil && *pvc.Spec.StorageClassName != "" && !existing[*pvc.Spec.StorageClassName]:
+			requested[*pvc.Spec.StorageClassName]++
+			collections[key] = &types.LogCollection{
+				Error: []string{fmt.Sprintf("Pending, StorageClass %v does not exist", *pvc.Spec.StorageClassName)},
+			}
+		}
+	}
+
+	for _, storageClass := range storageClasses {
+		collection := &types.LogCollection{}
+		collections[KindStorageClass+"/"+storageClass.Name] = collection
+
+		if storageClass.Name == expected && storageClass.Provisioner != lhmgrtypes.LonghornDriverName {
+			collection.Warn = append(collection.Warn, fmt.Sprintf("Provisioned by %v instead of %v", storageClass.Provisioner, lhmgrtypes.LonghornDriverName))
+		} else {
+			collection.Info = append(collection.Info, fmt.Sprintf("Provisioned by %v", storageClass.Provisioner))
+		}
+
+		if !IsDefaultStorageClass(&storageClass) {
+			continue
+		}
+		if len(defaults) > 1 {
+			collection.Error = append(collection.Error, fmt.Sprintf("One of the %d default StorageClasses (%v), the PVCs without a StorageClass get the most recently created one, or are rejected by Kubernetes before v1.26",
+				len(defaults), strings.Join(defaults, ", ")))
+			continue
+		}
+		collection.Info = append(collection.Info, "Default StorageClass of the PVCs without a StorageClass")
+	}
+
+	expectedKey := KindStorageClass + "/" + expected
+	if !existing[expected] {
+		collection := &types.LogCollection{}
+		collections[expectedKey] = collection
+		if requested[expected] > 0 {
+			collection.Error = append(collection.Error, fmt.Sprintf("Does not exist, the %d PVCs requesting it stay pending. Recreate it with the Longhorn deployment manifest or Helm chart", requested[expected]))
+		} else {
+			collection.Warn = append(collection.Warn, "Does not exist. Recreate it with the Longhorn deployment manifest or Helm chart")
+		}
+	}
+	if len(defaults) == 0 {
+		collections[expectedKey].Warn = append(collections[expectedKey].Warn, fmt.Sprintf("No default StorageClass, the PVCs without a StorageClass stay pending. Set %v as default with --%v", expected, consts.CmdOptFix))
+	}
+
+	return collections
+}
+
+// getDefaultChanges returns the StorageClasses whose default annotation must change for the
+// expected StorageClass to be the only default, with whether they become the default.
+func getDefaultChanges(storageClasses []storagev1.StorageClass, expected string) (map[string]bool, error) {
+	changes := map[string]bool{}
+	found := false
+	for _, storageClass := range storageClasses {
+		isDefault := IsDefaultStorageClass(&storageClass)
+		if storageClass.Name == expected {
+			found = true
+			if !isDefault {
+				changes[storageClass.Name] = true
+			}
+			continue
+		}
+		if isDefault {
+			changes[storageClass.Name] = false
+		}
+	}
+	if !found {
+		return nil, errors.Errorf("StorageClass %v does not exist, it cannot be set as default", expected)
+	}
+	return changes, nil
+}
+
+// newDefaultAnnotationPatch returns the merge patch of the default annotations. The beta
+// annotation is removed, so it does not contradict the annotation.
+func newDefaultAnnotationPatch(isDefault bool) ([]byte, error) {
+	patch := map[string]any{
+		"metadata": map[string]any{
+			"annotations": map[string]any{
+				AnnotationIsDefaultClass:     fmt.Sprint(isDefault),
+				AnnotationBetaIsDefaultClass: nil,
+			},
+		},
+	}
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to convert the default annotation patch to JSON")
+	}
+	return data, nil
+}
+
+// IsDefaultStorageClass returns true if the StorageClass is annotated as default.
+func IsDefaultStorageClass(storageClass *storagev1.StorageClass) bool {
+	return storageClass.Annotations[AnnotationIsDefaultClass] == "true" || storageClass.Annotations[AnnotationBetaIsDefaultClass] == "true"
+}
+
+func sortedKeys(changes map[string]bool) []string {
+	names := make([]string, 0, len(changes))
+	for name := range changes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
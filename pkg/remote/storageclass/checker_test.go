@@ -0,0 +1,178 @@
+package storageclass
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+func newStorageClass(name, provisioner string, annotations map[string]string) storagev1.StorageClass {
+	return storagev1.StorageClass{
+		ObjectMeta:  metav1.ObjectMeta{Name: name, Annotations: annotations},
+		Provisioner: provisioner,
+	}
+}
+
+func newPendingPVC(name string, storageClassName *string) corev1.PersistentVolumeClaim {
+	return corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+		Spec:       corev1.PersistentVolumeClaimSpec{StorageClassName: storageClassName},
+		Status:     corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimPending},
+	}
+}
+
+func TestCheckStorageClasses(t *testing.T) {
+	isDefault := map[string]string{AnnotationIsDefaultClass: "true"}
+	isBetaDefault := map[string]string{AnnotationBetaIsDefaultClass: "true"}
+
+	tests := map[string]struct {
+		storageClasses []storagev1.StorageClass
+		pvcs           []corev1.PersistentVolumeClaim
+		expectedError  map[string]string
+		expectedWarn   map[string]string
+	}{
+		"longhorn is the only default": {
+			storageClasses: []storagev1.StorageClass{
+				newStorageClass("longhorn", "driver.longhorn.io", isDefault),
+				newStorageClass("local-path", "rancher.io/local-path", nil),
+			},
+			pvcs: []corev1.PersistentVolumeClaim{newPendingPVC("data", nil)},
+		},
+		"multiple defaults": {
+			storageClasses: []storagev1.StorageClass{
+				newStorageClass("longhorn", "driver.longhorn.io", isDefault),
+				newStorageClass("local-path", "rancher.io/local-path", isBetaDefault),
+			},
+			expectedError: map[string]string{
+				"StorageClass/longhorn":   "One of the 2 default StorageClasses (local-path, longhorn)",
+				"StorageClass/local-path": "One of the 2 default StorageClasses (local-path, longhorn)",
+			},
+		},
+		"no default": {
+			storageClasses: []storagev1.StorageClass{newStorageClass("longhorn", "driver.longhorn.io", nil)},
+			pvcs:           []corev1.PersistentVolumeClaim{newPendingPVC("data", nil)},
+			expectedError: map[string]string{
+				"PersistentVolumeClaim/default/data": "there is no default StorageClass",
+			},
+			expectedWarn: map[string]string{
+				"StorageClass/longhorn": "No default StorageClass",
+			},
+		},
+		"missing longhorn requested by pvcs": {
+			storageClasses: []storagev1.StorageClass{newStorageClass("local-path", "rancher.io/local-path", isDefault)},
+			pvcs: []corev1.PersistentVolumeClaim{
+				newPendingPVC("data-0", ptr.To("longhorn")),
+				newPendingPVC("data-1", ptr.To("longhorn")),
+				newPendingPVC("static", ptr.To("")),
+			},
+			expectedError: map[string]string{
+				"PersistentVolumeClaim/default/data-0": "StorageClass longhorn does not exist",
+				"PersistentVolumeClaim/default/data-1": "StorageClass longhorn does not exist",
+				"StorageClass/longhorn":                "the 2 PVCs requesting it stay pending",
+			},
+		},
+		"missing longhorn": {
+			storageClasses: []storagev1.StorageClass{newStorageClass("local-path", "rancher.io/local-path", isDefault)},
+			expectedWarn: map[string]string{
+				"StorageClass/longhorn": "Does not exist",
+			},
+		},
+		"longhorn not provisioned by longhorn": {
+			storageClasses: []storagev1.StorageClass{newStorageClass("longhorn", "rancher.io/local-path", isDefault)},
+			expectedWarn: map[string]string{
+				"StorageClass/longhorn": "Provisioned by rancher.io/local-path instead of driver.longhorn.io",
+			},
+		},
+	}
+
+	for name, test := range tests {
+		collections := checkStorageClasses(test.storageClasses, test.pvcs, "longhorn")
+		for key, collection := range collections {
+			for _, check := range []struct {
+				expected string
+				messages []string
+			}{
+				{test.expectedError[key], collection.Error},
+				{test.expectedWarn[key], collection.Warn},
+			} {
+				if check.expected == "" {
+					if len(check.messages) > 0 {
+						t.Errorf("%v: %v: unexpected messages %v", name, key, check.messages)
+					}
+					continue
+				}
+				if len(check.messages) != 1 || !strings.Contains(check.messages[0], check.expected) {
+					t.Errorf("%v: %v: expected a message containing %q, got %v", name, key, check.expected, check.messages)
+				}
+			}
+		}
+		for _, expected := range []map[string]string{test.expectedError, test.expectedWarn} {
+			for key := range expected {
+				if _, ok := collections[key]; !ok {
+					t.Errorf("%v: expected a result for %v", name, key)
+				}
+			}
+		}
+	}
+}
+
+func TestGetDefaultChanges(t *testing.T) {
+	tests := map[string]struct {
+		storageClasses []storagev1.StorageClass
+		expected       map[string]bool
+		expectedError  bool
+	}{
+		"already the only default": {
+			storageClasses: []storagev1.StorageClass{
+				newStorageClass("longhorn", "driver.longhorn.io", map[string]string{AnnotationIsDefaultClass: "true"}),
+				newStorageClass("local-path", "rancher.io/local-path", map[string]string{AnnotationIsDefaultClass: "false"}),
+			},
+			expected: map[string]bool{},
+		},
+		"other defaults": {
+			storageClasses: []storagev1.StorageClass{
+				newStorageClass("longhorn", "driver.longhorn.io", nil),
+				newStorageClass("local-path", "rancher.io/local-path", map[string]string{AnnotationIsDefaultClass: "true"}),
+				newStorageClass("standard", "kubernetes.io/gce-pd", map[string]string{AnnotationBetaIsDefaultClass: "true"}),
+			},
+			expected: map[string]bool{"longhorn": true, "local-path": false, "standard": false},
+		},
+		"missing": {
+			storageClasses: []storagev1.StorageClass{newStorageClass("local-path", "rancher.io/local-path", nil)},
+			expectedError:  true,
+		},
+	}
+
+	for name, test := range tests {
+		changes, err := getDefaultChanges(test.storageClasses, "longhorn")
+		if test.expectedError {
+			if err == nil {
+				t.Errorf("%v: expected an error", name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", name, err)
+			continue
+		}
+		if !reflect.DeepEqual(changes, test.expected) {
+			t.Errorf("%v: expected changes %v, got %v", name, test.expected, changes)
+		}
+	}
+}
+
+func TestNewDefaultAnnotationPatch(t *testing.T) {
+	patch, err := newDefaultAnnotationPatch(false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `{"metadata":{"annotations":{"storageclass.beta.kubernetes.io/is-default-class":null,"storageclass.kubernetes.io/is-default-class":"false"}}}`
+	if string(patch) != expected {
+		t.Errorf("expected patch %v, got %v", expected, string(patch))
+	}
+}
@@ -0,0 +1,290 @@
+package top
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	longhorn "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+	lhclient "github.com/longhorn/longhorn-manager/k8s/pkg/client/clientset/versioned"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/remote/event"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+const (
+	// maxEvents is the number of the latest events kept in a snapshot.
+	maxEvents = 100
+
+	// eventsSince is how far back the events of a snapshot go.
+	eventsSince = time.Hour
+)
+
+// Top provide functions for showing the live state of the Longhorn volumes, nodes, replica
+// rebuilds and events in a terminal UI.
+type Top struct {
+	TopCmdOptions
+
+	longhornClient *lhclient.Clientset
+	eventStreamer  *event.Streamer
+}
+
+// TopCmdOptions holds the options for the command.
+type TopCmdOptions struct {
+	types.GlobalCmdOptions
+
+	LonghornNamespace string
+	Interval          time.Duration // Interval between refreshes.
+}
+
+// Snapshot is the state of Longhorn at a point in time, sorted by name. The events are sorted from
+// the latest.
+type Snapshot struct {
+	Time     time.Time
+	Volumes  []VolumeRow
+	Nodes    []NodeRow
+	Rebuilds []RebuildRow
+	Events   []*types.Event
+	Replicas map[string][]ReplicaRow // Keyed by volume name.
+}
+
+// VolumeRow is a volume, with its replicas in read-write mode in its engine.
+type VolumeRow struct {
+	Name             string
+	State            string
+	Robustness       string
+	Size             int64
+	ActualSize       int64
+	Node             string
+	HealthyReplicas  int
+	ExpectedReplicas int
+}
+
+// NodeRow is a node, with the storage of its disks summed up.
+type NodeRow struct {
+	Name             string
+	Ready            bool
+	Schedulable      bool
+	Disks            int
+	Replicas         int
+	StorageMaximum   int64
+	StorageAvailable int64
+	StorageScheduled int64
+}
+
+// RebuildRow is a replica being rebuilt.
+type RebuildRow struct {
+	Volume   string
+	Replica  string
+	Node     string
+	Progress int
+	State    string
+	Error    string
+}
+
+// ReplicaRow is a replica of a volume, with its mode in the engine of the volume.
+type ReplicaRow struct {
+	Name     string
+	Node     string
+	Disk     string
+	State    string
+	Mode     string
+	FailedAt string
+	Rebuild  string // Rebuild progress, when the replica is rebuilding.
+}
+
+// Validate validates the command options.
+func (remote *Top) Validate() error {
+	if remote.Interval <= 0 {
+		return errors.Errorf("interval (--%s) must be positive", consts.CmdOptInterval)
+	}
+	return nil
+}
+
+// Init initializes the Top.
+func (remote *Top) Init() error {
+	longhornClient, err := kubeutils.NewLonghornClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.longhornClient = longhornClient
+
+	remote.eventStreamer = &event.Streamer{
+		StreamerCmdOptions: event.StreamerCmdOptions{
+			GlobalCmdOptions:  remote.GlobalCmdOptions,
+			LonghornNamespace: remote.LonghornNamespace,
+			Kinds:             strings.Join([]string{event.KindVolume, event.KindEngine, event.KindReplica, event.KindNode}, consts.CmdOptSeperator),
+			Since:             eventsSince,
+		},
+	}
+	return remote.eventStreamer.Init()
+}
+
+// Collect returns the current snapshot of the Longhorn volumes, nodes, replica rebuilds and events.
+func (remote *Top) Collect(ctx context.Context) (*Snapshot, error) {
+	client := remote.longhornClient.LonghornV1beta2()
+
+	volumeList, err := client.Volumes(remote.LonghornNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list volumes")
+	}
+	nodeList, err := client.Nodes(remote.LonghornNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list nodes")
+	}
+	replicaList, err := client.Replicas(remote.LonghornNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list replicas")
+	}
+	engineList, err := client.Engines(remote.LonghornNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list engines")
+	}
+
+	var events []*types.Event
+	err = remote.eventStreamer.Run(ctx, func(event *types.Event) {
+		events = append(events, event)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return newSnapshot(volumeList.Items, nodeList.Items, replicaList.Items, engineList.Items, events, time.Now()), nil
+}
+
+// Cleanup does nothing, since the Top does not create any resource.
+func (remote *Top) Cleanup() error {
+	return nil
+}
+
+// newSnapshot returns the snapshot of the Longhorn objects. The rebuild progress of each replica is
+// reported by the engine of its volume, keyed by the replica address.
+func newSnapshot(volumes []longhorn.Volume, nodes []longhorn.Node, replicas []longhorn.Replica, engines []longhorn.Engine, events []*types.Event, now time.Time) *Snapshot {
+	snapshot := &Snapshot{
+		Time:     now,
+		Replicas: map[string][]ReplicaRow{},
+	}
+
+	enginesByVolume := map[string]*longhorn.Engine{}
+	for i := range engines {
+		enginesByVolume[engines[i].Spec.VolumeName] = &engines[i]
+	}
+
+	replicasByNode := map[string]int{}
+	for _, replica := range replicas {
+		volumeName := replica.Spec.VolumeName
+		row := ReplicaRow{
+			Name:     replica.Name,
+			Node:     replica.Spec.NodeID,
+			Disk:     replica.Spec.DiskPath,
+			State:    string(replica.Status.CurrentState),
+			FailedAt: replica.Spec.FailedAt,
+		}
+		replicasByNode[replica.Spec.NodeID]++
+
+		if engine := enginesByVolume[volumeName]; engine != nil {
+			row.Mode = string(engine.Status.ReplicaModeMap[replica.Name])
+
+			if rebuild := getReplicaRebuildStatus(engine, &replica); rebuild != nil && rebuild.IsRebuilding {
+				row.Rebuild = fmt.Sprintf("%d%%", rebuild.Progress)
+				snapshot.Rebuilds = append(snapshot.Rebuilds, RebuildRow{
+					Volume:   volumeName,
+					Replica:  replica.Name,
+					Node:     replica.Spec.NodeID,
+					Progress: rebuild.Progress,
+					State:    rebuild.State,
+					Error:    rebuild.Error,
+				})
+			}
+		}
+		snapshot.Replicas[volumeName] = append(snapshot.Replicas[volumeName], row)
+	}
+	for _, rows := range snapshot.Replicas {
+		sort.Slice(rows, func(i, j int) bool { return rows[i].Name < rows[j].Name })
+	}
+	sort.Slice(snapshot.Rebuilds, func(i, j int) bool {
+		if snapshot.Rebuilds[i].Volume != snapshot.Rebuilds[j].Volume {
+			return snapshot.Rebuilds[i].Volume < snapshot.Rebuilds[j].Volume
+		}
+		return snapshot.Rebuilds[i].Replica < snapshot.Rebuilds[j].Replica
+	})
+
+	for _, volume := range volumes {
+		row := VolumeRow{
+			Name:             volume.Name,
+			State:            string(volume.Status.State),
+			Robustness:       string(volume.Status.Robustness),
+			Size:             volume.Spec.Size,
+			ActualSize:       volume.Status.ActualSize,
+			Node:             volume.Status.CurrentNodeID,
+			ExpectedReplicas: volume.Spec.NumberOfReplicas,
+		}
+		for _, replica := range snapshot.Replicas[volume.Name] {
+			if replica.Mode == string(longhorn.ReplicaModeRW) {
+				row.HealthyReplicas++
+			}
+		}
+		snapshot.Volumes = append(snapshot.Volumes, row)
+	}
+	sort.Slice(snapshot.Volumes, func(i, j int) bool { return snapshot.Volumes[i].Name < snapshot.Volumes[j].Name })
+
+	for _, node := range nodes {
+		row := NodeRow{
+			Name:        node.Name,
+			Ready:       isConditionTrue(node.Status.Conditions, longhorn.NodeConditionTypeReady),
+			Schedulable: node.Spec.AllowScheduling && isConditionTrue(node.Status.Conditions, longhorn.NodeConditionTypeSchedulable),
+			Disks:       len(node.Spec.Disks),
+			Replicas:    replicasByNode[node.Name],
+		}
+		for _, disk := range node.Status.DiskStatus {
+			if disk == nil {
+				continue
+			}
+			row.StorageMaximum += disk.StorageMaximum
+			row.StorageAvailable += disk.StorageAvailable
+			row.StorageScheduled += disk.StorageScheduled
+		}
+		snapshot.Nodes = append(snapshot.Nodes, row)
+	}
+	sort.Slice(snapshot.Nodes, func(i, j int) bool { return snapshot.Nodes[i].Name < snapshot.Nodes[j].Name })
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.After(events[j].Time) })
+	if len(events) > maxEvents {
+		events = events[:maxEvents]
+	}
+	snapshot.Events = events
+
+	return snapshot
+}
+
+// getReplicaRebuildStatus returns the rebuild status of the replica reported by the engine, keyed
+// by the address of the replica on the storage network or the pod network.
+func getReplicaRebuildStatus(engine *longhorn.Engine, replica *longhorn.Replica) *longhorn.RebuildStatus {
+	for _, ip := range []string{replica.Status.StorageIP, replica.Status.IP} {
+		if ip == "" {
+			continue
+		}
+		if status, ok := engine.Status.RebuildStatus[fmt.Sprintf("tcp://%s:%d", ip, replica.Status.Port)]; ok {
+			return status
+		}
+	}
+	return nil
+}
+
+func isConditionTrue(conditions []longhorn.Condition, conditionType string) bool {
+	for _, condition := range conditions {
+		if condition.Type == conditionType {
+			return condition.Status == longhorn.ConditionStatusTrue
+		}
+	}
+	return false
+}
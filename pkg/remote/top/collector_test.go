@@ -0,0 +1,87 @@
+package top
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	longhorn "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func TestNewSnapshot(t *testing.T) {
+	newReplica := func(name, node, storageIP string, port int) longhorn.Replica {
+		replica := longhorn.Replica{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		replica.Spec.VolumeName = "pvc-1"
+		replica.Spec.NodeID = node
+		replica.Status.CurrentState = longhorn.InstanceStateRunning
+		replica.Status.StorageIP = storageIP
+		replica.Status.Port = port
+		return replica
+	}
+
+	volume := longhorn.Volume{ObjectMeta: metav1.ObjectMeta{Name: "pvc-1"}}
+	volume.Spec.NumberOfReplicas = 2
+	volume.Status.State = longhorn.VolumeStateAttached
+	volume.Status.Robustness = longhorn.VolumeRobustnessDegraded
+
+	engine := longhorn.Engine{}
+	engine.Spec.VolumeName = "pvc-1"
+	engine.Status.ReplicaModeMap = map[string]longhorn.ReplicaMode{
+		"pvc-1-r-a": longhorn.ReplicaModeRW,
+		"pvc-1-r-b": longhorn.ReplicaModeWO,
+	}
+	engine.Status.RebuildStatus = map[string]*longhorn.RebuildStatus{
+		"tcp://10.0.0.2:10010": {IsRebuilding: true, Progress: 42, State: "in_progress"},
+	}
+
+	node := longhorn.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}
+	node.Spec.AllowScheduling = true
+	node.Status.Conditions = []longhorn.Condition{
+		{Type: longhorn.NodeConditionTypeReady, Status: longhorn.ConditionStatusTrue},
+		{Type: longhorn.NodeConditionTypeSchedulable, Status: longhorn.ConditionStatusTrue},
+	}
+	node.Status.DiskStatus = map[string]*longhorn.DiskStatus{
+		"disk-1": {StorageMaximum: 100, StorageAvailable: 60, StorageScheduled: 30},
+		"disk-2": {StorageMaximum: 50, StorageAvailable: 10, StorageScheduled: 40},
+	}
+
+	now := time.Now()
+	events := []*types.Event{
+		{Time: now.Add(-time.Minute), Reason: "Degraded"},
+		{Time: now, Reason: "Rebuilding"},
+	}
+
+	snapshot := newSnapshot(
+		[]longhorn.Volume{volume},
+		[]longhorn.Node{node},
+		[]longhorn.Replica{newReplica("pvc-1-r-b", "node-1", "10.0.0.2", 10010), newReplica("pvc-1-r-a", "node-1", "10.0.0.1", 10010)},
+		[]longhorn.Engine{engine},
+		events,
+		now,
+	)
+
+	if len(snapshot.Volumes) != 1 || snapshot.Volumes[0].HealthyReplicas != 1 || snapshot.Volumes[0].ExpectedReplicas != 2 {
+		t.Errorf("expected a volume with 1/2 healthy replicas, got %+v", snapshot.Volumes)
+	}
+
+	if len(snapshot.Rebuilds) != 1 || snapshot.Rebuilds[0].Replica != "pvc-1-r-b" || snapshot.Rebuilds[0].Progress != 42 {
+		t.Errorf("expected the rebuild of pvc-1-r-b at 42%%, got %+v", snapshot.Rebuilds)
+	}
+
+	replicas := snapshot.Replicas["pvc-1"]
+	if len(replicas) != 2 || replicas[0].Name != "pvc-1-r-a" || replicas[1].Rebuild != "42%" || replicas[1].Mode != string(longhorn.ReplicaModeWO) {
+		t.Errorf("unexpected replicas %+v", replicas)
+	}
+
+	expectedNode := NodeRow{Name: "node-1", Ready: true, Schedulable: true, Replicas: 2, StorageMaximum: 150, StorageAvailable: 70, StorageScheduled: 70}
+	if len(snapshot.Nodes) != 1 || snapshot.Nodes[0] != expectedNode {
+		t.Errorf("expected node %+v, got %+v", expectedNode, snapshot.Nodes)
+	}
+
+	if len(snapshot.Events) != 2 || snapshot.Events[0].Reason != "Rebuilding" {
+		t.Errorf("expected the latest event first, got %+v", snapshot.Events)
+	}
+}
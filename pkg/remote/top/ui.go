@@ -0,0 +1,152 @@
+package top
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/term"
+)
+
+const (
+	enterAlternateScreen = "\033[?1049h\033[?25l"
+	leaveAlternateScreen = "\033[?25h\033[?1049l"
+	clearScreen          = "\033[H\033[2J"
+
+	// resizeInterval is the interval the terminal size is checked at, to redraw on resize.
+	resizeInterval = 500 * time.Millisecond
+)
+
+// collectResult is the result of a refresh.
+type collectResult struct {
+	snapshot *Snapshot
+	err      error
+}
+
+// Run shows the terminal UI on stdout, refreshed at the interval, until the user quits or the
+// context is cancelled. The terminal is put in raw mode to read the keys, and restored on return.
+// The logs are discarded while the UI is shown.
+func (remote *Top) Run(ctx context.Context) error {
+	stdin := int(os.Stdin.Fd())
+	stdout := int(os.Stdout.Fd())
+	if !term.IsTerminal(stdin) || !term.IsTerminal(stdout) {
+		return errors.New("stdin and stdout must be a terminal")
+	}
+
+	oldState, err := term.MakeRaw(stdin)
+	if err != nil {
+		return errors.Wrap(err, "failed to set the terminal in raw mode")
+	}
+	defer func() {
+		_ = term.Restore(stdin, oldState)
+	}()
+
+	logOutput := logrus.StandardLogger().Out
+	logrus.SetOutput(io.Discard)
+	defer logrus.SetOutput(logOutput)
+
+	os.Stdout.WriteString(enterAlternateScreen)
+	defer os.Stdout.WriteString(leaveAlternateScreen)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	keys := make(chan []byte)
+	go readKeys(ctx, keys)
+
+	results := make(chan collectResult)
+	go remote.refresh(ctx, results)
+
+	var snapshot *Snapshot
+	v := &view{}
+	width, height := getSize(stdout)
+	draw := func() {
+		lines := v.render(snapshot, remote.LonghornNamespace, width, height)
+		os.Stdout.WriteString(clearScreen + strings.Join(lines, "\r\n"))
+	}
+	draw()
+
+	resizeTicker := time.NewTicker(resizeInterval)
+	defer resizeTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case data := <-keys:
+			for _, key := range parseKeys(data) {
+				if v.handleKey(key, snapshot) {
+					return nil
+				}
+			}
+			draw()
+		case result := <-results:
+			v.err = result.err
+			if result.err == nil {
+				snapshot = result.snapshot
+			}
+			draw()
+		case <-resizeTicker.C:
+			if newWidth, newHeight := getSize(stdout); newWidth != width || newHeight != height {
+				width, height = newWidth, newHeight
+				draw()
+			}
+		}
+	}
+}
+
+// refresh sends a snapshot to the results at each interval, until the context is cancelled.
+func (remote *Top) refresh(ctx context.Context, results chan<- collectResult) {
+	ticker := time.NewTicker(remote.Interval)
+	defer ticker.Stop()
+
+	for {
+		collectCtx, cancel := context.WithTimeout(ctx, remote.Interval+10*time.Second)
+		snapshot, err := remote.Collect(collectCtx)
+		cancel()
+
+		select {
+		case <-ctx.Done():
+			return
+		case results <- collectResult{snapshot: snapshot, err: err}:
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// readKeys sends the bytes read from stdin to the keys, until stdin is closed or the context is
+// cancelled.
+func readKeys(ctx context.Context, keys chan<- []byte) {
+	buffer := make([]byte, 64)
+	for {
+		n, err := os.Stdin.Read(buffer)
+		if err != nil {
+			return
+		}
+
+		data := append([]byte{}, buffer[:n]...)
+		select {
+		case <-ctx.Done():
+			return
+		case keys <- data:
+		}
+	}
+}
+
+// getSize returns the size of the terminal, defaulting to 80x24 when it is unknown.
+func getSize(fd int) (int, int) {
+	width, height, err := term.GetSize(fd)
+	if err != nil || width <= 0 || height <= 0 {
+		return 80, 24
+	}
+	return width, height
+}
@@ -0,0 +1,293 @@
+package top
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+
+	"github.com/longhorn/cli/pkg/utils"
+)
+
+// Tabs of the view.
+const (
+	tabVolumes = iota
+	tabNodes
+	tabRebuilds
+	tabEvents
+	numTabs
+)
+
+var tabNames = [numTabs]string{"Volumes", "Nodes", "Rebuilds", "Events"}
+
+// Keys handled by the view.
+const (
+	keyUp    = "up"
+	keyDown  = "down"
+	keyEnter = "enter"
+	keyEsc   = "esc"
+	keyTab   = "tab"
+	keyQuit  = "quit"
+)
+
+const (
+	reverseVideo = "\033[7m"
+	resetVideo   = "\033[0m"
+
+	// headerLines and footerLines are the lines around the table of the view.
+	headerLines = 2
+	footerLines = 1
+)
+
+// view is the state of the terminal UI: the selected tab, the selected row of each tab, and the
+// volume whose replicas are shown, if any.
+type view struct {
+	tab     int
+	cursors [numTabs]int
+	volume  string
+	err     error // Error of the last refresh, shown until the next successful one.
+}
+
+// parseKeys returns the keys read from the terminal in raw mode. Arrow keys are escape sequences,
+// and an escape alone is the escape key.
+func parseKeys(data []byte) []string {
+	var keys []string
+	for i := 0; i < len(data); i++ {
+		switch data[i] {
+		case 0x1b:
+			if i+2 < len(data) && data[i+1] == '[' {
+				switch data[i+2] {
+				case 'A':
+					keys = append(keys, keyUp)
+				case 'B':
+					keys = append(keys, keyDown)
+				}
+				i += 2
+				continue
+			}
+			keys = append(keys, keyEsc)
+		case '\r', '\n':
+			keys = append(keys, keyEnter)
+		case '\t':
+			keys = append(keys, keyTab)
+		case 0x03, 'q':
+			keys = append(keys, keyQuit)
+		case 'k':
+			keys = append(keys, keyUp)
+		case 'j':
+			keys = append(keys, keyDown)
+		case 0x7f, 'h':
+			keys = append(keys, keyEsc)
+		case '1', '2', '3', '4':
+			keys = append(keys, string(data[i]))
+		}
+	}
+	return keys
+}
+
+// handleKey updates the view for the key, and returns whether the UI should quit. Enter on a
+// volume, or on a rebuild, drills down into the replicas of its volume.
+func (v *view) handleKey(key string, snapshot *Snapshot) bool {
+	switch key {
+	case keyQuit:
+		return true
+	case keyEsc:
+		v.volume = ""
+	case keyTab:
+		v.volume = ""
+		v.tab = (v.tab + 1) % numTabs
+	case "1", "2", "3", "4":
+		v.volume = ""
+		v.tab = int(key[0] - '1')
+	case keyUp:
+		if v.volume == "" && v.cursors[v.tab] > 0 {
+			v.cursors[v.tab]--
+		}
+	case keyDown:
+		if v.volume == "" && v.cursors[v.tab] < v.rowCount(snapshot)-1 {
+			v.cursors[v.tab]++
+		}
+	case keyEnter:
+		if v.volume != "" || snapshot == nil {
+			break
+		}
+		cursor := v.cursors[v.tab]
+		switch {
+		case v.tab == tabVolumes && cursor < len(snapshot.Volumes):
+			v.volume = snapshot.Volumes[cursor].Name
+		case v.tab == tabRebuilds && cursor < len(snapshot.Rebuilds):
+			v.volume = snapshot.Rebuilds[cursor].Volume
+		}
+	}
+	return false
+}
+
+func (v *view) rowCount(snapshot *Snapshot) int {
+	if snapshot == nil {
+		return 0
+	}
+	switch v.tab {
+	case tabVolumes:
+		return len(snapshot.Volumes)
+	case tabNodes:
+		return len(snapshot.Nodes)
+	case tabRebuilds:
+		return len(snapshot.Rebuilds)
+	case tabEvents:
+		return len(snapshot.Events)
+	}
+	return 0
+}
+
+// render returns the screen of the view for the snapshot, fitting the terminal size. The selected
+// row is highlighted, and the rows scroll to keep it visible.
+func (v *view) render(snapshot *Snapshot, namespace string, width, height int) []string {
+	var tabs []string
+	for i, name := range tabNames {
+		label := fmt.Sprintf("[%d] %s", i+1, name)
+		if i == v.tab && v.volume == "" {
+			label = reverseVideo + label + resetVideo
+		}
+		tabs = append(tabs, label)
+	}
+
+	status := "Loading..."
+	if snapshot != nil {
+		status = snapshot.Time.Local().Format(time.TimeOnly)
+	}
+	lines := []string{
+		fmt.Sprintf("longhornctl top - %s - %s", namespace, status),
+		strings.Join(tabs, "  "),
+	}
+
+	var table []string
+	selected := -1
+	if snapshot != nil {
+		if v.volume != "" {
+			table = renderVolumeDetail(snapshot, v.volume)
+		} else {
+			table = v.renderTab(snapshot)
+			if len(table) > 1 {
+				if v.cursors[v.tab] >= len(table)-1 {
+					v.cursors[v.tab] = len(table) - 2
+				}
+				selected = v.cursors[v.tab] + 1
+			}
+		}
+	}
+
+	// Scroll the rows below the table header to keep the selected row visible.
+	visible := height - headerLines - footerLines
+	if visible < 2 {
+		visible = 2
+	}
+	if len(table) > visible {
+		offset := 0
+		if selected >= visible {
+			offset = selected - visible + 1
+		}
+		rows := table[1+offset:]
+		if len(rows) > visible-1 {
+			rows = rows[:visible-1]
+		}
+		if selected > 0 {
+			selected -= offset
+		}
+		table = append([]string{table[0]}, rows...)
+	}
+
+	for i, line := range table {
+		line = truncate(line, width)
+		if i == selected {
+			line = reverseVideo + line + strings.Repeat(" ", max(width-utf8.RuneCountInString(line), 0)) + resetVideo
+		}
+		lines = append(lines, line)
+	}
+
+	for len(lines) < height-1 {
+		lines = append(lines, "")
+	}
+
+	footer := "up/down,j/k: select  enter: replicas  esc: back  tab,1-4: switch  q: quit"
+	if v.err != nil {
+		footer = "Error: " + strings.ReplaceAll(v.err.Error(), "\n", " ")
+	}
+	lines = append(lines, truncate(footer, width))
+
+	return lines
+}
+
+// renderTab returns the table of the selected tab, with its header first.
+func (v *view) renderTab(snapshot *Snapshot) []string {
+	var builder strings.Builder
+	writer := tabwriter.NewWriter(&builder, 0, 0, 2, ' ', 0)
+
+	switch v.tab {
+	case tabVolumes:
+		fmt.Fprintln(writer, "VOLUME\tSTATE\tROBUSTNESS\tREPLICAS\tSIZE\tACTUAL SIZE\tNODE")
+		for _, volume := range snapshot.Volumes {
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%d/%d\t%s\t%s\t%s\n", volume.Name, volume.State, volume.Robustness, volume.HealthyReplicas, volume.ExpectedReplicas, utils.FormatBytes(volume.Size), utils.FormatBytes(volume.ActualSize), orDash(volume.Node))
+		}
+	case tabNodes:
+		fmt.Fprintln(writer, "NODE\tREADY\tSCHEDULABLE\tDISKS\tREPLICAS\tAVAILABLE\tSCHEDULED\tMAXIMUM")
+		for _, node := range snapshot.Nodes {
+			fmt.Fprintf(writer, "%s\t%v\t%v\t%d\t%d\t%s\t%s\t%s\n", node.Name, node.Ready, node.Schedulable, node.Disks, node.Replicas, utils.FormatBytes(node.StorageAvailable), utils.FormatBytes(node.StorageScheduled), utils.FormatBytes(node.StorageMaximum))
+		}
+	case tabRebuilds:
+		fmt.Fprintln(writer, "VOLUME\tREPLICA\tNODE\tPROGRESS\tSTATE\tERROR")
+		for _, rebuild := range snapshot.Rebuilds {
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s %d%%\t%s\t%s\n", rebuild.Volume, rebuild.Replica, rebuild.Node, progressBar(rebuild.Progress), rebuild.Progress, orDash(rebuild.State), orDash(rebuild.Error))
+		}
+	case tabEvents:
+		fmt.Fprintln(writer, "EVENT")
+		for _, event := range snapshot.Events {
+			fmt.Fprintln(writer, utils.RenderEvent(event, false))
+		}
+	}
+	_ = writer.Flush()
+
+	return strings.Split(strings.TrimSuffix(builder.String(), "\n"), "\n")
+}
+
+// renderVolumeDetail returns the replicas of the volume, with its rebuild progress.
+func renderVolumeDetail(snapshot *Snapshot, volumeName string) []string {
+	var builder strings.Builder
+	writer := tabwriter.NewWriter(&builder, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(writer, "VOLUME %s\n", volumeName)
+	for _, volume := range snapshot.Volumes {
+		if volume.Name == volumeName {
+			fmt.Fprintf(writer, "State: %s, robustness: %s, %d/%d healthy replicas, attached to %s\n", volume.State, volume.Robustness, volume.HealthyReplicas, volume.ExpectedReplicas, orDash(volume.Node))
+		}
+	}
+	fmt.Fprintln(writer)
+	fmt.Fprintln(writer, "REPLICA\tNODE\tDISK\tSTATE\tMODE\tREBUILD\tFAILED AT")
+	for _, replica := range snapshot.Replicas[volumeName] {
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", replica.Name, orDash(replica.Node), orDash(replica.Disk), orDash(replica.State), orDash(replica.Mode), orDash(replica.Rebuild), orDash(replica.FailedAt))
+	}
+	_ = writer.Flush()
+
+	return strings.Split(strings.TrimSuffix(builder.String(), "\n"), "\n")
+}
+
+// progressBar returns a bar of ten cells filled up to the percentage.
+func progressBar(percentage int) string {
+	filled := min(max(percentage, 0), 100) / 10
+	return "[" + strings.Repeat("#", filled) + strings.Repeat(".", 10-filled) + "]"
+}
+
+// truncate returns the line cut to the width of the terminal.
+func truncate(line string, width int) string {
+	if width <= 0 || utf8.RuneCountInString(line) <= width {
+		return line
+	}
+	return string([]rune(line)[:width])
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
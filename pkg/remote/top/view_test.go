@@ -0,0 +1,100 @@
+package top
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseKeys(t *testing.T) {
+	tests := map[string]struct {
+		data     string
+		expected []string
+	}{
+		"arrows":         {data: "\x1b[A\x1b[B", expected: []string{keyUp, keyDown}},
+		"escape":         {data: "\x1b", expected: []string{keyEsc}},
+		"vi keys":        {data: "jkh", expected: []string{keyDown, keyUp, keyEsc}},
+		"enter and tab":  {data: "\r\t", expected: []string{keyEnter, keyTab}},
+		"quit":           {data: "\x03", expected: []string{keyQuit}},
+		"tabs":           {data: "24", expected: []string{"2", "4"}},
+		"unknown arrows": {data: "\x1b[C", expected: nil},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if result := parseKeys([]byte(test.data)); !reflect.DeepEqual(result, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestViewHandleKey(t *testing.T) {
+	snapshot := &Snapshot{
+		Volumes:  []VolumeRow{{Name: "pvc-1"}, {Name: "pvc-2"}},
+		Rebuilds: []RebuildRow{{Volume: "pvc-3", Replica: "pvc-3-r-a"}},
+	}
+
+	v := &view{}
+	for _, key := range []string{keyDown, keyDown, keyDown} {
+		v.handleKey(key, snapshot)
+	}
+	if v.cursors[tabVolumes] != 1 {
+		t.Errorf("expected the cursor on the last volume, got %d", v.cursors[tabVolumes])
+	}
+
+	v.handleKey(keyEnter, snapshot)
+	if v.volume != "pvc-2" {
+		t.Errorf("expected the replicas of pvc-2, got %q", v.volume)
+	}
+
+	v.handleKey("3", snapshot)
+	v.handleKey(keyEnter, snapshot)
+	if v.tab != tabRebuilds || v.volume != "pvc-3" {
+		t.Errorf("expected the replicas of the rebuilt volume pvc-3, got tab %d volume %q", v.tab, v.volume)
+	}
+
+	v.handleKey(keyEsc, snapshot)
+	v.handleKey(keyTab, snapshot)
+	if v.tab != tabEvents || v.volume != "" {
+		t.Errorf("expected the events tab, got tab %d volume %q", v.tab, v.volume)
+	}
+
+	if !v.handleKey(keyQuit, snapshot) {
+		t.Error("expected to quit")
+	}
+}
+
+func TestViewRender(t *testing.T) {
+	snapshot := &Snapshot{Time: time.Now()}
+	for i := 0; i < 20; i++ {
+		snapshot.Volumes = append(snapshot.Volumes, VolumeRow{Name: fmt.Sprintf("pvc-%02d", i), State: "attached"})
+	}
+
+	v := &view{}
+	v.cursors[tabVolumes] = 15
+	lines := v.render(snapshot, "longhorn-system", 60, 10)
+
+	if len(lines) != 10 {
+		t.Fatalf("expected 10 lines, got %d:\n%s", len(lines), strings.Join(lines, "\n"))
+	}
+	if !strings.HasPrefix(lines[2], "VOLUME") {
+		t.Errorf("expected the table header to stay visible, got %q", lines[2])
+	}
+	if !strings.HasPrefix(lines[8], reverseVideo+"pvc-15") {
+		t.Errorf("expected the selected volume highlighted on the last table row, got %q", lines[8])
+	}
+	for _, line := range lines {
+		if len(strings.TrimSuffix(strings.TrimPrefix(line, reverseVideo), resetVideo)) > 60 && !strings.Contains(line, "[1]") {
+			t.Errorf("expected the line cut to the width, got %q", line)
+		}
+	}
+
+	v.err = fmt.Errorf("connection refused")
+	lines = v.render(snapshot, "longhorn-system", 60, 10)
+	if lines[len(lines)-1] != "Error: connection refused" {
+		t.Errorf("expected the refresh error in the footer, got %q", lines[len(lines)-1])
+	}
+}
@@ -0,0 +1,244 @@
+package topology
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	longhorn "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+	lhclient "github.com/longhorn/longhorn-manager/k8s/pkg/client/clientset/versioned"
+	lhmgrtypes "github.com/longhorn/longhorn-manager/types"
+
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// Effective anti-affinity of a volume.
+const (
+	AntiAffinityHard = "hard"
+	AntiAffinitySoft = "soft"
+)
+
+// Reporter provide functions for mapping the volumes to the nodes, zones and regions of their replicas.
+type Reporter struct {
+	ReporterCmdOptions
+
+	longhornClient *lhclient.Clientset
+}
+
+// ReporterCmdOptions holds the options for the command.
+type ReporterCmdOptions struct {
+	types.GlobalCmdOptions
+
+	LonghornNamespace string
+	VolumeNames       []string // Volumes to report. Defaults to all volumes.
+}
+
+// antiAffinitySettings holds the anti-affinity settings applying to the volumes that do not
+// override them.
+type antiAffinitySettings struct {
+	nodeSoft bool
+	zoneSoft bool
+}
+
+// Init initializes the Reporter.
+func (remote *Reporter) Init() error {
+	longhornClient, err := kubeutils.NewLonghornClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.longhornClient = longhornClient
+
+	return nil
+}
+
+// Run returns the topology of the volumes, sorted by name.
+func (remote *Reporter) Run() ([]types.TopologyVolume, error) {
+	ctx := context.Background()
+	client := remote.longhornClient.LonghornV1beta2()
+
+	volumeList, err := client.Volumes(remote.LonghornNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list volumes")
+	}
+	replicaList, err := client.Replicas(remote.LonghornNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list replicas")
+	}
+	nodeList, err := client.Nodes(remote.LonghornNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list nodes")
+	}
+
+	settings := antiAffinitySettings{}
+	if settings.nodeSoft, err = remote.getBoolSetting(ctx, lhmgrtypes.SettingNameReplicaSoftAntiAffinity, lhmgrtypes.SettingDefinitionReplicaSoftAntiAffinity); err != nil {
+		return nil, err
+	}
+	if settings.zoneSoft, err = remote.getBoolSetting(ctx, lhmgrtypes.SettingNameReplicaZoneSoftAntiAffinity, lhmgrtypes.SettingDefinitionReplicaZoneSoftAntiAffinity); err != nil {
+		return nil, err
+	}
+
+	nodes := map[string]*longhorn.Node{}
+	zones := map[string]struct{}{}
+	for i := range nodeList.Items {
+		nodes[nodeList.Items[i].Name] = &nodeList.Items[i]
+		zones[nodeList.Items[i].Status.Zone] = struct{}{}
+	}
+
+	replicas := map[string][]longhorn.Replica{}
+	for _, replica := range replicaList.Items {
+		replicas[replica.Spec.VolumeName] = append(replicas[replica.Spec.VolumeName], replica)
+	}
+
+	volumes := map[string]*longhorn.Volume{}
+	for i := range volumeList.Items {
+		volumes[volumeList.Items[i].Name] = &volumeList.Items[i]
+	}
+
+	volumeNames := remote.VolumeNames
+	if len(volumeNames) == 0 {
+		for name := range volumes {
+			volumeNames = append(volumeNames, name)
+		}
+	}
+	sort.Strings(volumeNames)
+
+	result := make([]types.TopologyVolume, 0, len(volumeNames))
+	for _, name := range volumeNames {
+		volume, ok := volumes[name]
+		if !ok {
+			return nil, errors.Errorf("volume %v is not found", name)
+		}
+		result = append(result, newTopologyVolume(volume, replicas[name], nodes, settings, len(zones) > 1))
+	}
+	return result, nil
+}
+
+// getBoolSetting returns the value of the boolean setting, or its default when it is not set.
+func (remote *Reporter) getBoolSetting(ctx context.Context, name lhmgrtypes.SettingName, definition lhmgrtypes.SettingDefinition) (bool, error) {
+	value := definition.Default
+
+	setting, err := remote.longhornClient.LonghornV1beta2().Settings(remote.LonghornNamespace).Get(ctx, string(name), metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return false, errors.Wrapf(err, "failed to get setting %v", name)
+	}
+	if err == nil && setting.Value != "" {
+		value = setting.Value
+	}
+
+	result, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, errors.Wrapf(err, "invalid value %q of setting %v", value, name)
+	}
+	return result, nil
+}
+
+// newTopologyVolume maps the volume to the nodes, zones and regions of its replicas. A volume
+// violates its anti-affinity when its usable replicas share a node or a zone while the
+// anti-affinity is hard. A volume has a single failure domain when it has a single usable replica,
+// when its usable replicas are all on one node, or all in one zone of a cluster with several zones.
+func newTopologyVolume(volume *longhorn.Volume, replicas []longhorn.Replica, nodes map[string]*longhorn.Node, settings antiAffinitySettings, multipleZones bool) types.TopologyVolume {
+	result := types.TopologyVolume{
+		Name:             volume.Name,
+		State:            string(volume.Status.State),
+		Robustness:       string(volume.Status.Robustness),
+		NumberOfReplicas: volume.Spec.NumberOfReplicas,
+		NodeAntiAffinity: getAntiAffinity(string(volume.Spec.ReplicaSoftAntiAffinity), settings.nodeSoft),
+		ZoneAntiAffinity: getAntiAffinity(string(volume.Spec.ReplicaZoneSoftAntiAffinity), settings.zoneSoft),
+		Nodes:            []string{},
+		Zones:            []string{},
+		Regions:          []string{},
+		Replicas:         []types.TopologyReplica{},
+	}
+
+	sort.Slice(replicas, func(i, j int) bool { return replicas[i].Name < replicas[j].Name })
+
+	replicasPerNode := map[string]int{}
+	replicasPerZone := map[string]int{}
+	replicasPerRegion := map[string]int{}
+	usableReplicas := 0
+	for _, replica := range replicas {
+		topologyReplica := types.TopologyReplica{
+			Name:   replica.Name,
+			Node:   replica.Spec.NodeID,
+			Usable: replica.Spec.NodeID != "" && replica.Spec.FailedAt == "" && replica.DeletionTimestamp == nil,
+		}
+		if node, ok := nodes[replica.Spec.NodeID]; ok {
+			topologyReplica.Zone = node.Status.Zone
+			topologyReplica.Region = node.Status.Region
+		}
+		result.Replicas = append(result.Replicas, topologyReplica)
+
+		if !topologyReplica.Usable {
+			continue
+		}
+		usableReplicas++
+		replicasPerNode[topologyReplica.Node]++
+		replicasPerZone[topologyReplica.Zone]++
+		replicasPerRegion[topologyReplica.Region]++
+	}
+
+	result.Nodes = sortedKeys(replicasPerNode)
+	result.Zones = sortedKeys(replicasPerZone)
+	result.Regions = sortedKeys(replicasPerRegion)
+
+	if result.NodeAntiAffinity == AntiAffinityHard {
+		for _, node := range result.Nodes {
+			if replicasPerNode[node] > 1 {
+				result.Violations = append(result.Violations, fmt.Sprintf("%d replicas share node %v while node anti-affinity is hard", replicasPerNode[node], node))
+			}
+		}
+	}
+	if result.ZoneAntiAffinity == AntiAffinityHard {
+		for _, zone := range result.Zones {
+			if replicasPerZone[zone] > 1 {
+				result.Violations = append(result.Violations, fmt.Sprintf("%d replicas share zone %q while zone anti-affinity is hard", replicasPerZone[zone], zone))
+			}
+		}
+	}
+
+	switch {
+	case usableReplicas == 0:
+		result.SingleFailureDomains = append(result.SingleFailureDomains, "No usable replica")
+	case usableReplicas == 1:
+		result.SingleFailureDomains = append(result.SingleFailureDomains, fmt.Sprintf("Single usable replica on node %v", result.Nodes[0]))
+	case len(result.Nodes) == 1:
+		result.SingleFailureDomains = append(result.SingleFailureDomains, fmt.Sprintf("All %d usable replicas are on node %v", usableReplicas, result.Nodes[0]))
+	case len(result.Zones) == 1 && multipleZones:
+		result.SingleFailureDomains = append(result.SingleFailureDomains, fmt.Sprintf("All %d usable replicas are in zone %q", usableReplicas, result.Zones[0]))
+	}
+
+	return result
+}
+
+// getAntiAffinity returns the effective anti-affinity of the volume setting, which overrides the
+// global setting unless it is ignored.
+func getAntiAffinity(volumeSetting string, globalSoft bool) string {
+	switch volumeSetting {
+	case string(longhorn.ReplicaSoftAntiAffinityEnabled):
+		return AntiAffinitySoft
+	case string(longhorn.ReplicaSoftAntiAffinityDisabled):
+		return AntiAffinityHard
+	}
+
+	if globalSoft {
+		return AntiAffinitySoft
+	}
+	return AntiAffinityHard
+}
+
+func sortedKeys(values map[string]int) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,119 @@
+package topology
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	longhorn "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+)
+
+func TestNewTopologyVolume(t *testing.T) {
+	newNode := func(name, zone string) *longhorn.Node {
+		node := &longhorn.Node{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		node.Status.Zone = zone
+		node.Status.Region = "region-1"
+		return node
+	}
+	nodes := map[string]*longhorn.Node{
+		"node-1": newNode("node-1", "zone-a"),
+		"node-2": newNode("node-2", "zone-a"),
+		"node-3": newNode("node-3", "zone-b"),
+	}
+	newReplica := func(name, nodeID, failedAt string) longhorn.Replica {
+		replica := longhorn.Replica{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		replica.Spec.VolumeName = "vol"
+		replica.Spec.NodeID = nodeID
+		replica.Spec.FailedAt = failedAt
+		return replica
+	}
+	newVolume := func(nodeAntiAffinity longhorn.ReplicaSoftAntiAffinity, zoneAntiAffinity longhorn.ReplicaZoneSoftAntiAffinity) *longhorn.Volume {
+		volume := &longhorn.Volume{ObjectMeta: metav1.ObjectMeta{Name: "vol"}}
+		volume.Spec.NumberOfReplicas = 2
+		volume.Spec.ReplicaSoftAntiAffinity = nodeAntiAffinity
+		volume.Spec.ReplicaZoneSoftAntiAffinity = zoneAntiAffinity
+		return volume
+	}
+	settings := antiAffinitySettings{nodeSoft: false, zoneSoft: true}
+
+	tests := map[string]struct {
+		volume                       *longhorn.Volume
+		replicas                     []longhorn.Replica
+		multipleZones                bool
+		expectedZones                []string
+		expectedViolations           int
+		expectedSingleFailureDomains int
+	}{
+		"spread across zones": {
+			volume:        newVolume(longhorn.ReplicaSoftAntiAffinityDefault, longhorn.ReplicaZoneSoftAntiAffinityDefault),
+			replicas:      []longhorn.Replica{newReplica("r-1", "node-1", ""), newReplica("r-2", "node-3", "")},
+			multipleZones: true,
+			expectedZones: []string{"zone-a", "zone-b"},
+		},
+		"single zone of several": {
+			volume:                       newVolume(longhorn.ReplicaSoftAntiAffinityDefault, longhorn.ReplicaZoneSoftAntiAffinityDefault),
+			replicas:                     []longhorn.Replica{newReplica("r-1", "node-1", ""), newReplica("r-2", "node-2", "")},
+			multipleZones:                true,
+			expectedZones:                []string{"zone-a"},
+			expectedSingleFailureDomains: 1,
+		},
+		"single zone of a single zone cluster": {
+			volume:        newVolume(longhorn.ReplicaSoftAntiAffinityDefault, longhorn.ReplicaZoneSoftAntiAffinityDefault),
+			replicas:      []longhorn.Replica{newReplica("r-1", "node-1", ""), newReplica("r-2", "node-2", "")},
+			expectedZones: []string{"zone-a"},
+		},
+		"hard zone anti-affinity violated": {
+			volume:                       newVolume(longhorn.ReplicaSoftAntiAffinityDefault, longhorn.ReplicaZoneSoftAntiAffinityDisabled),
+			replicas:                     []longhorn.Replica{newReplica("r-1", "node-1", ""), newReplica("r-2", "node-2", "")},
+			multipleZones:                true,
+			expectedZones:                []string{"zone-a"},
+			expectedViolations:           1,
+			expectedSingleFailureDomains: 1,
+		},
+		"hard node anti-affinity violated": {
+			volume:                       newVolume(longhorn.ReplicaSoftAntiAffinityDefault, longhorn.ReplicaZoneSoftAntiAffinityDefault),
+			replicas:                     []longhorn.Replica{newReplica("r-1", "node-1", ""), newReplica("r-2", "node-1", "")},
+			expectedZones:                []string{"zone-a"},
+			expectedViolations:           1,
+			expectedSingleFailureDomains: 1,
+		},
+		"soft node anti-affinity": {
+			volume:                       newVolume(longhorn.ReplicaSoftAntiAffinityEnabled, longhorn.ReplicaZoneSoftAntiAffinityDefault),
+			replicas:                     []longhorn.Replica{newReplica("r-1", "node-1", ""), newReplica("r-2", "node-1", "")},
+			expectedZones:                []string{"zone-a"},
+			expectedSingleFailureDomains: 1,
+		},
+		"failed replica not counted": {
+			volume:                       newVolume(longhorn.ReplicaSoftAntiAffinityDefault, longhorn.ReplicaZoneSoftAntiAffinityDefault),
+			replicas:                     []longhorn.Replica{newReplica("r-1", "node-1", ""), newReplica("r-2", "node-3", "2024-07-16T09:00:00Z")},
+			multipleZones:                true,
+			expectedZones:                []string{"zone-a"},
+			expectedSingleFailureDomains: 1,
+		},
+		"no replica": {
+			volume:                       newVolume(longhorn.ReplicaSoftAntiAffinityDefault, longhorn.ReplicaZoneSoftAntiAffinityDefault),
+			expectedZones:                []string{},
+			expectedSingleFailureDomains: 1,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			volume := newTopologyVolume(test.volume, test.replicas, nodes, settings, test.multipleZones)
+			if len(volume.Zones) != len(test.expectedZones) {
+				t.Fatalf("expected zones %v, got %v", test.expectedZones, volume.Zones)
+			}
+			for i := range volume.Zones {
+				if volume.Zones[i] != test.expectedZones[i] {
+					t.Errorf("expected zones %v, got %v", test.expectedZones, volume.Zones)
+				}
+			}
+			if len(volume.Violations) != test.expectedViolations {
+				t.Errorf("expected %d violations, got %v", test.expectedViolations, volume.Violations)
+			}
+			if len(volume.SingleFailureDomains) != test.expectedSingleFailureDomains {
+				t.Errorf("expected %d single failure domains, got %v", test.expectedSingleFailureDomains, volume.SingleFailureDomains)
+			}
+		})
+	}
+}
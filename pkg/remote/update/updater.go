@@ -0,0 +1,273 @@
+package update
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/blang/semver/v4"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/longhorn/cli/meta"
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+)
+
+const httpTimeout = 5 * time.Minute
+
+// Updater provide functions for updating the CLI binary from the GitHub releases.
+type Updater struct {
+	UpdaterCmdOptions
+
+	httpClient *http.Client
+
+	assetName      string // Name of the release binary for the current OS and architecture.
+	executablePath string // Path of the running binary, with symlinks resolved.
+}
+
+// UpdaterCmdOptions holds the options for the command.
+type UpdaterCmdOptions struct {
+	types.GlobalCmdOptions
+
+	Version   string
+	CheckOnly bool
+}
+
+type release struct {
+	TagName string `json:"tag_name"`
+}
+
+// Validate validates the command options.
+func (remote *Updater) Validate() error {
+	if remote.Version == "" {
+		return nil
+	}
+
+	if _, err := semver.ParseTolerant(remote.Version); err != nil {
+		return errors.Wrapf(err, "invalid version %q", remote.Version)
+	}
+	return nil
+}
+
+// Init initializes the Updater.
+func (remote *Updater) Init() error {
+	remote.httpClient = &http.Client{Timeout: httpTimeout}
+	remote.assetName = fmt.Sprintf("%s-%s-%s", consts.CmdLonghornctlRemote, runtime.GOOS, runtime.GOARCH)
+
+	executablePath, err := os.Executable()
+	if err != nil {
+		return errors.Wrap(err, "failed to get the path of the running binary")
+	}
+
+	remote.executablePath, err = filepath.EvalSymlinks(executablePath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to resolve the path of the running binary %v", executablePath)
+	}
+
+	return nil
+}
+
+// Run checks for the target release and, unless only checking, replaces the running
+// binary with the release binary after verifying its published SHA256 checksum.
+func (remote *Updater) Run() (string, error) {
+	targetVersion := remote.Version
+	if targetVersion == "" {
+		latestVersion, err := remote.getLatestVersion()
+		if err != nil {
+			return "", err
+		}
+		targetVersion = latestVersion
+	}
+	if !strings.HasPrefix(targetVersion, "v") {
+		targetVersion = "v" + targetVersion
+	}
+
+	isNewer := IsNewerVersion(targetVersion, meta.Version)
+	if remote.CheckOnly {
+		switch {
+		case isNewer:
+			return fmt.Sprintf("Update available: %s -> %s", versionOrUnknown(meta.Version), targetVersion), nil
+		case IsSameVersion(meta.Version, targetVersion):
+			return fmt.Sprintf("%s %s is up to date", consts.CmdLonghornctlRemote, meta.Version), nil
+		default:
+			return fmt.Sprintf("%s %s is newer than %s, no update available", consts.CmdLonghornctlRemote, meta.Version, targetVersion), nil
+		}
+	}
+
+	// The latest release does not downgrade a newer build, only an explicit version does.
+	if !isNewer && remote.Version == "" {
+		return fmt.Sprintf("%s %s is up to date", consts.CmdLonghornctlRemote, meta.Version), nil
+	}
+
+	logrus.Infof("Downloading %s %s", remote.assetName, targetVersion)
+	if err := remote.replaceExecutable(targetVersion); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("Updated %s from %s to %s", remote.executablePath, versionOrUnknown(meta.Version), targetVersion), nil
+}
+
+// getLatestVersion returns the tag of the latest published release.
+func (remote *Updater) getLatestVersion() (string, error) {
+	body, err := remote.get(consts.ReleaseApiURL + "/latest")
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get the latest release")
+	}
+	defer body.Close()
+
+	var latest release
+	if err := json.NewDecoder(body).Decode(&latest); err != nil {
+		return "", errors.Wrap(err, "failed to decode the latest release")
+	}
+
+	if latest.TagName == "" {
+		return "", errors.New("latest release has no tag")
+	}
+	return latest.TagName, nil
+}
+
+// replaceExecutable downloads the release binary next to the running binary, verifies
+// its checksum, then renames it over the running binary so the replacement is atomic.
+func (remote *Updater) replaceExecutable(version string) (err error) {
+	downloadURL := fmt.Sprintf("%s/%s/%s", consts.ReleaseDownloadURL, version, remote.assetName)
+
+	expectedChecksum, err := remote.getChecksum(downloadURL + ".sha256")
+	if err != nil {
+		return err
+	}
+
+	tempFile, err := os.CreateTemp(filepath.Dir(remote.executablePath), "."+filepath.Base(remote.executablePath)+"-*")
+	if err != nil {
+		return errors.Wrap(err, "failed to create temporary file for the downloaded binary")
+	}
+	defer func() {
+		_ = tempFile.Close()
+		if err != nil {
+			_ = os.Remove(tempFile.Name())
+		}
+	}()
+
+	body, err := remote.get(downloadURL)
+	if err != nil {
+		return errors.Wrapf(err, "failed to download %v", downloadURL)
+	}
+	defer body.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tempFile, hash), body); err != nil {
+		return errors.Wrapf(err, "failed to download %v", downloadURL)
+	}
+
+	checksum := hex.EncodeToString(hash.Sum(nil))
+	if checksum != expectedChecksum {
+		return errors.Errorf("checksum mismatch for %v: expected %v, got %v", downloadURL, expectedChecksum, checksum)
+	}
+	logrus.Debugf("Verified SHA256 checksum %v", checksum)
+
+	if err := tempFile.Chmod(0755); err != nil {
+		return errors.Wrap(err, "failed to make the downloaded binary executable")
+	}
+
+	if err := tempFile.Close(); err != nil {
+		return errors.Wrap(err, "failed to write the downloaded binary")
+	}
+
+	if err := os.Rename(tempFile.Name(), remote.executablePath); err != nil {
+		return errors.Wrapf(err, "failed to replace %v", remote.executablePath)
+	}
+	return nil
+}
+
+// getChecksum downloads the published checksum file and returns the checksum in it.
+func (remote *Updater) getChecksum(checksumURL string) (string, error) {
+	body, err := remote.get(checksumURL)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to download checksum %v", checksumURL)
+	}
+	defer body.Close()
+
+	return ParseChecksum(body)
+}
+
+func (remote *Updater) get(url string) (io.ReadCloser, error) {
+	logrus.Debugf("Requesting %v", url)
+
+	resp, err := remote.httpClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, errors.Errorf("unexpected status %v", resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// ParseChecksum returns the SHA256 checksum from the output of sha256sum, in the
+// format "<checksum> *<file name>" or "<checksum>  <file name>".
+func ParseChecksum(reader io.Reader) (string, error) {
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		checksum := strings.ToLower(fields[0])
+		if _, err := hex.DecodeString(checksum); err != nil || len(checksum) != sha256.Size*2 {
+			return "", errors.Errorf("invalid SHA256 checksum %q", fields[0])
+		}
+		return checksum, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return "", errors.Wrap(err, "failed to read checksum")
+	}
+	return "", errors.New("empty checksum")
+}
+
+// IsSameVersion returns true if both versions are valid semantic versions and equal.
+func IsSameVersion(version, otherVersion string) bool {
+	v, err := semver.ParseTolerant(version)
+	if err != nil {
+		return false
+	}
+
+	other, err := semver.ParseTolerant(otherVersion)
+	if err != nil {
+		return false
+	}
+	return v.Equals(other)
+}
+
+// IsNewerVersion returns true if the version is a valid semantic version greater than the current
+// version, or than a current version that is not a valid semantic version, such as a development build.
+func IsNewerVersion(version, currentVersion string) bool {
+	v, err := semver.ParseTolerant(version)
+	if err != nil {
+		return false
+	}
+
+	current, err := semver.ParseTolerant(currentVersion)
+	if err != nil {
+		return true
+	}
+	return v.GT(current)
+}
+
+func versionOrUnknown(version string) string {
+	if version == "" {
+		return "unknown"
+	}
+	return version
+}
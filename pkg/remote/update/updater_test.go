@@ -0,0 +1,119 @@
+package update
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/longhorn/cli/meta"
+)
+
+func TestParseChecksum(t *testing.T) {
+	checksum := strings.Repeat("ab", 32)
+
+	for _, test := range []struct {
+		input     string
+		output    string
+		expectErr bool
+	}{
+		{
+			input:  checksum + " *longhornctl-linux-amd64\n",
+			output: checksum,
+		},
+		{
+			input:  strings.ToUpper(checksum) + "  longhornctl-linux-amd64",
+			output: checksum,
+		},
+		{
+			input:  "\n" + checksum,
+			output: checksum,
+		},
+		{
+			input:     "not-a-checksum longhornctl-linux-amd64",
+			expectErr: true,
+		},
+		{
+			input:     "",
+			expectErr: true,
+		},
+	} {
+		result, err := ParseChecksum(strings.NewReader(test.input))
+		if test.expectErr {
+			if err == nil {
+				t.Errorf("expected error for input %q", test.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error for input %q: %v", test.input, err)
+		}
+		if result != test.output {
+			t.Errorf("expected: %s, got: %s", test.output, result)
+		}
+	}
+}
+
+func TestIsSameVersion(t *testing.T) {
+	for _, test := range []struct {
+		version      string
+		otherVersion string
+		output       bool
+	}{
+		{version: "v1.7.1", otherVersion: "v1.7.1", output: true},
+		{version: "v1.7.1", otherVersion: "1.7.1", output: true},
+		{version: "v1.7.0", otherVersion: "v1.7.1", output: false},
+		{version: "", otherVersion: "v1.7.1", output: false},
+		{version: "master-head", otherVersion: "v1.7.1", output: false},
+	} {
+		result := IsSameVersion(test.version, test.otherVersion)
+		if result != test.output {
+			t.Errorf("%s vs %s: expected: %v, got: %v", test.version, test.otherVersion, test.output, result)
+		}
+	}
+}
+
+func TestIsNewerVersion(t *testing.T) {
+	for _, test := range []struct {
+		version        string
+		currentVersion string
+		output         bool
+	}{
+		{version: "v1.7.1", currentVersion: "v1.7.0", output: true},
+		{version: "1.8.0", currentVersion: "v1.7.1", output: true},
+		{version: "v1.7.1", currentVersion: "v1.7.1-rc1", output: true},
+		{version: "v1.7.1", currentVersion: "v1.7.1", output: false},
+		{version: "v1.7.0", currentVersion: "v1.7.1", output: false},
+		{version: "v1.6.3", currentVersion: "v1.7.0-dev", output: false},
+		{version: "v1.7.1", currentVersion: "", output: true},
+		{version: "v1.7.1", currentVersion: "master-head", output: true},
+		{version: "latest", currentVersion: "v1.7.1", output: false},
+	} {
+		result := IsNewerVersion(test.version, test.currentVersion)
+		if result != test.output {
+			t.Errorf("%s vs %s: expected: %v, got: %v", test.version, test.currentVersion, test.output, result)
+		}
+	}
+}
+
+func TestRunCheckOnly(t *testing.T) {
+	currentVersion := meta.Version
+	meta.Version = "v1.7.1"
+	defer func() { meta.Version = currentVersion }()
+
+	for _, test := range []struct {
+		version string
+		output  string
+	}{
+		{version: "v1.7.2", output: "Update available: v1.7.1 -> v1.7.2"},
+		{version: "1.7.1", output: "longhornctl v1.7.1 is up to date"},
+		{version: "v1.7.0", output: "longhornctl v1.7.1 is newer than v1.7.0, no update available"},
+	} {
+		updater := &Updater{UpdaterCmdOptions: UpdaterCmdOptions{Version: test.version, CheckOnly: true}}
+		output, err := updater.Run()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", test.version, err)
+		}
+		if output != test.output {
+			t.Errorf("%s: expected: %q, got: %q", test.version, test.output, output)
+		}
+	}
+}
@@ -0,0 +1,416 @@
+package velero
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"github.com/pkg/errors"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+	kubeclient "k8s.io/client-go/kubernetes"
+
+	lhmgrtypes "github.com/longhorn/longhorn-manager/types"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// Kinds of the checked objects, used in the keys of the result.
+const (
+	KindBackupStorageLocation    = "BackupStorageLocation"
+	KindCustomResourceDefinition = "CustomResourceDefinition"
+	KindDeployment               = "Deployment"
+	KindVolumeSnapshotClass      = "VolumeSnapshotClass"
+)
+
+// snapshotControllerDeployment is the name of the deployment of the CSI snapshot controller, as
+// deployed by the external-snapshotter manifests and most distributions.
+const snapshotControllerDeployment = "snapshot-controller"
+
+// veleroCSIPluginVersion is the version of Velero from which the CSI plugin is built into the
+// server, instead of the velero-plugin-for-csi init container.
+var veleroCSIPluginVersion = semver.MustParse("1.14.0")
+
+// Checker provide functions for checking that an existing Velero installation snapshots the
+// Longhorn volumes through the Longhorn CSI driver.
+type Checker struct {
+	CheckerCmdOptions
+
+	kubeClient    *kubeclient.Clientset
+	dynamicClient *dynamic.DynamicClient
+}
+
+// CheckerCmdOptions holds the options for the command.
+type CheckerCmdOptions struct {
+	types.GlobalCmdOptions
+
+	VeleroNamespace string
+}
+
+// Init initializes the Checker.
+func (remote *Checker) Init() error {
+	config, err := kubeutils.NewRestConfig("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+
+	remote.kubeClient, err = kubeutils.NewKubeClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+
+	remote.dynamicClient, err = dynamic.NewForConfig(config)
+	if err != nil {
+		return errors.Wrap(err, "failed to create dynamic client")
+	}
+
+	return nil
+}
+
+// Collect checks the Velero server, its backup storage locations, the CSI snapshot custom
+// resources and controller, and the VolumeSnapshotClasses of the Longhorn CSI driver, and returns
+// the result of each object keyed by its kind and name.
+func (remote *Checker) Collect() (map[string]*types.LogCollection, error) {
+	ctx := context.Background()
+	collections := map[string]*types.LogCollection{}
+
+	veleroInstalled, err := remote.checkServer(ctx, collections)
+	if err != nil {
+		return nil, err
+	}
+
+	csiInstalled, err := remote.checkCSISnapshotResources(collections)
+	if err != nil {
+		return nil, err
+	}
+
+	if veleroInstalled {
+		if err := remote.checkBackupStorageLocations(ctx, collections); err != nil {
+			return nil, err
+		}
+	}
+
+	if csiInstalled {
+		if err := remote.checkSnapshotController(ctx, collections); err != nil {
+			return nil, err
+		}
+		if err := remote.checkVolumeSnapshotClasses(ctx, collections); err != nil {
+			return nil, err
+		}
+	}
+
+	return collections, nil
+}
+
+// Cleanup does nothing, since the Checker does not create any resource.
+func (remote *Checker) Cleanup() error {
+	return nil
+}
+
+// checkServer checks the Velero server deployment, and returns whether it exists.
+func (remote *Checker) checkServer(ctx context.Context, collections map[string]*types.LogCollection) (bool, error) {
+	collection := &types.LogCollection{}
+	collections[KindDeployment+"/"+remote.VeleroNamespace+"/"+consts.VeleroDeployment] = collection
+
+	deployment, err := remote.kubeClient.AppsV1().Deployments(remote.VeleroNamespace).Get(ctx, consts.VeleroDeployment, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		collection.Error = append(collection.Error, fmt.Sprintf("Velero is not installed in namespace %v", remote.VeleroNamespace))
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to get deployment %v/%v", remote.VeleroNamespace, consts.VeleroDeployment)
+	}
+
+	appendMessages(collection, checkServerDeployment(deployment))
+	return true, nil
+}
+
+// checkCSISnapshotResources checks the CSI snapshot custom resources are served, and returns
+// whether they are.
+func (remote *Checker) checkCSISnapshotResources(collections map[string]*types.LogCollection) (bool, error) {
+	collection := &types.LogCollection{}
+	collections[KindCustomResourceDefinition+"/*."+csiSnapshotGroupVersion.Group] = collection
+
+	resources, err := remote.kubeClient.Discovery().ServerResourcesForGroupVersion(csiSnapshotGroupVersion.String())
+	if apierrors.IsNotFound(err) {
+		collection.Error = append(collection.Error, fmt.Sprintf("The CSI snapshot custom resources (%v) are not installed, Velero cannot snapshot the Longhorn volumes", csiSnapshotGroupVersion))
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to discover %v", csiSnapshotGroupVersion)
+	}
+
+	served := map[string]bool{}
+	for _, resource := range resources.APIResources {
+		served[resource.Name] = true
+	}
+
+	installed := true
+	for _, resource := range []string{"volumesnapshots", "volumesnapshotcontents", "volumesnapshotclasses"} {
+		if !served[resource] {
+			collection.Error = append(collection.Error, fmt.Sprintf("The CSI snapshot custom resource %v.%v is not installed", resource, csiSnapshotGroupVersion.Group))
+			installed = false
+		}
+	}
+	if installed {
+		collection.Info = append(collection.Info, fmt.Sprintf("The CSI snapshot custom resources are served as %v", csiSnapshotGroupVersion))
+	}
+	return installed, nil
+}
+
+// checkSnapshotController checks a CSI snapshot controller is running, without which the
+// VolumeSnapshots Velero creates are never taken.
+func (remote *Checker) checkSnapshotController(ctx context.Context, collections map[string]*types.LogCollection) error {
+	deployments, err := remote.kubeClient.AppsV1().Deployments(corev1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return errors.Wrap(err, "failed to list deployments")
+	}
+
+	for _, deployment := range deployments.Items {
+		if deployment.Name != snapshotControllerDeployment {
+			continue
+		}
+
+		collection := &types.LogCollection{}
+		collections[KindDeployment+"/"+deployment.Namespace+"/"+deployment.Name] = collection
+		if deployment.Status.ReadyReplicas == 0 {
+			collection.Error = append(collection.Error, "The CSI snapshot controller has no ready replica, the VolumeSnapshots are not taken")
+		} else {
+			collection.Info = append(collection.Info, fmt.Sprintf("The CSI snapshot controller has %d ready replicas", deployment.Status.ReadyReplicas))
+		}
+		return nil
+	}
+
+	collections[KindDeployment+"/*/"+snapshotControllerDeployment] = &types.LogCollection{
+		Warn: []string{"No CSI snapshot controller deployment found, the VolumeSnapshots are not taken unless it is deployed under another name"},
+	}
+	return nil
+}
+
+// checkBackupStorageLocations checks Velero has an available backup storage location.
+func (remote *Checker) checkBackupStorageLocations(ctx context.Context, collections map[string]*types.LogCollection) error {
+	list, err := remote.dynamicClient.Resource(backupStorageLocationResource).Namespace(remote.VeleroNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			collections[KindBackupStorageLocation+"/*"] = &types.LogCollection{
+				Error: []string{fmt.Sprintf("The Velero custom resources (%v) are not installed", veleroGroupVersion)},
+			}
+			return nil
+		}
+		return errors.Wrap(err, "failed to list backup storage locations")
+	}
+
+	if len(list.Items) == 0 {
+		collections[KindBackupStorageLocation+"/*"] = &types.LogCollection{
+			Error: []string{fmt.Sprintf("No backup storage location in namespace %v, Velero cannot store the backups", remote.VeleroNamespace)},
+		}
+		return nil
+	}
+
+	hasDefault := false
+	for _, location := range list.Items {
+		collection := checkBackupStorageLocation(&location)
+		collections[KindBackupStorageLocation+"/"+location.GetName()] = collection
+
+		if isDefault, _, _ := unstructured.NestedBool(location.Object, "spec", "default"); isDefault {
+			hasDefault = true
+		}
+	}
+	if !hasDefault {
+		collections[KindBackupStorageLocation+"/*"] = &types.LogCollection{
+			Warn: []string{"No default backup storage location, the backups must set --storage-location"},
+		}
+	}
+	return nil
+}
+
+// checkVolumeSnapshotClasses checks exactly one VolumeSnapshotClass of the Longhorn CSI driver is
+// selected by Velero.
+func (remote *Checker) checkVolumeSnapshotClasses(ctx context.Context, collections map[string]*types.LogCollection) error {
+	list, err := remote.dynamicClient.Resource(volumeSnapshotClassResource).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return errors.Wrap(err, "failed to list VolumeSnapshotClasses")
+	}
+
+	var selected []string
+	for _, class := range list.Items {
+		driver, _, _ := unstructured.NestedString(class.Object, "driver")
+		if driver != lhmgrtypes.LonghornDriverName || class.GetLabels()[consts.VeleroLabelCSIVolumeSnapshotClass] != "true" {
+			continue
+		}
+
+		selected = append(selected, class.GetName())
+		collections[KindVolumeSnapshotClass+"/"+class.GetName()] = checkVolumeSnapshotClass(&class)
+	}
+
+	switch len(selected) {
+	case 0:
+		collections[KindVolumeSnapshotClass+"/*"] = &types.LogCollection{
+			Error: []string{fmt.Sprintf("No VolumeSnapshotClass of %v has the label %v=true, Velero cannot snapshot the Longhorn volumes", lhmgrtypes.LonghornDriverName, consts.VeleroLabelCSIVolumeSnapshotClass)},
+		}
+	case 1:
+	default:
+		collections[KindVolumeSnapshotClass+"/*"] = &types.LogCollection{
+			Error: []string{fmt.Sprintf("VolumeSnapshotClasses %v of %v all have the label %v=true, Velero fails to choose one", strings.Join(selected, ", "), lhmgrtypes.LonghornDriverName, consts.VeleroLabelCSIVolumeSnapshotClass)},
+		}
+	}
+	return nil
+}
+
+// checkServerDeployment checks the Velero server is ready and snapshots the volumes through CSI.
+func checkServerDeployment(deployment *appsv1.Deployment) *types.LogCollection {
+	collection := &types.LogCollection{}
+
+	if deployment.Status.ReadyReplicas == 0 {
+		collection.Error = append(collection.Error, "The Velero server has no ready replica")
+	} else {
+		collection.Info = append(collection.Info, fmt.Sprintf("The Velero server has %d ready replicas", deployment.Status.ReadyReplicas))
+	}
+
+	var container *corev1.Container
+	for i := range deployment.Spec.Template.Spec.Containers {
+		if deployment.Spec.Template.Spec.Containers[i].Name == consts.VeleroDeployment {
+			container = &deployment.Spec.Template.Spec.Containers[i]
+		}
+	}
+	if container == nil {
+		collection.Error = append(collection.Error, fmt.Sprintf("The Velero server has no container %v", consts.VeleroDeployment))
+		return collection
+	}
+
+	args := append(append([]string{}, container.Command...), container.Args...)
+	if hasFeature(args, consts.VeleroFeatureCSI) {
+		collection.Info = append(collection.Info, fmt.Sprintf("The feature %v is enabled", consts.VeleroFeatureCSI))
+	} else {
+		collection.Error = append(collection.Error, fmt.Sprintf("The feature %v is not enabled, add --features=%v to the arguments of the Velero server", consts.VeleroFeatureCSI, consts.VeleroFeatureCSI))
+	}
+
+	if hasFlag(args, "--default-volumes-to-fs-backup") {
+		collection.Warn = append(collection.Warn, "The volumes are backed up with the file system backup by default, instead of CSI snapshots")
+	}
+
+	version, err := getImageVersion(container.Image)
+	switch {
+	case err != nil:
+		collection.Warn = append(collection.Warn, fmt.Sprintf("Cannot parse the version of image %v, the CSI plugin is not checked", container.Image))
+	case version.GE(veleroCSIPluginVersion):
+		collection.Info = append(collection.Info, fmt.Sprintf("Velero %v has the CSI plugin built in", version))
+	case hasCSIPlugin(deployment.Spec.Template.Spec.InitContainers):
+		collection.Info = append(collection.Info, "The velero-plugin-for-csi plugin is installed")
+	default:
+		collection.Error = append(collection.Error, fmt.Sprintf("Velero %v requires the velero-plugin-for-csi plugin, which is not installed", version))
+	}
+
+	return collection
+}
+
+// checkBackupStorageLocation checks the backup storage location is available.
+func checkBackupStorageLocation(location *unstructured.Unstructured) *types.LogCollection {
+	collection := &types.LogCollection{}
+
+	phase, _, _ := unstructured.NestedString(location.Object, "status", "phase")
+	if phase == "Available" {
+		collection.Info = append(collection.Info, "The backup storage location is available")
+	} else {
+		message, _, _ := unstructured.NestedString(location.Object, "status", "message")
+		if phase == "" {
+			phase = "unknown"
+		}
+		status := fmt.Sprintf("The backup storage location is %v", strings.ToLower(phase))
+		if message != "" {
+			status += ": " + message
+		}
+		collection.Error = append(collection.Error, status)
+	}
+
+	if accessMode, _, _ := unstructured.NestedString(location.Object, "spec", "accessMode"); accessMode == "ReadOnly" {
+		collection.Warn = append(collection.Warn, "The backup storage location is read-only, no backup can be stored in it")
+	}
+	return collection
+}
+
+// checkVolumeSnapshotClass checks the type of the snapshots taken by a VolumeSnapshotClass of the
+// Longhorn CSI driver.
+func checkVolumeSnapshotClass(class *unstructured.Unstructured) *types.LogCollection {
+	collection := &types.LogCollection{}
+
+	snapshotType, _, _ := unstructured.NestedString(class.Object, "parameters", "type")
+	switch snapshotType {
+	case "", consts.CSISnapshotTypeBackup, consts.CSISnapshotTypeLegacyBackup:
+		// The Longhorn CSI driver takes backups by default.
+		collection.Info = append(collection.Info, "Velero backs up the Longhorn volumes to the Longhorn backup target")
+	case consts.CSISnapshotTypeSnapshot:
+		collection.Warn = append(collection.Warn, "Velero takes Longhorn snapshots, which are lost with the volumes, set the parameter type to "+consts.CSISnapshotTypeBackup+" to back them up to the backup target")
+	default:
+		collection.Error = append(collection.Error, fmt.Sprintf("Unknown snapshot type %q", snapshotType))
+	}
+	return collection
+}
+
+// hasFeature returns whether the feature is in the --features flags of the arguments.
+func hasFeature(args []string, feature string) bool {
+	for i, arg := range args {
+		var value string
+		switch {
+		case strings.HasPrefix(arg, "--features="):
+			value = strings.TrimPrefix(arg, "--features=")
+		case arg == "--features" && i+1 < len(args):
+			value = args[i+1]
+		default:
+			continue
+		}
+
+		for _, enabled := range strings.Split(value, ",") {
+			if strings.TrimSpace(enabled) == feature {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasFlag returns whether the boolean flag is set to true in the arguments.
+func hasFlag(args []string, flag string) bool {
+	for _, arg := range args {
+		if arg == flag || arg == flag+"=true" {
+			return true
+		}
+	}
+	return false
+}
+
+// hasCSIPlugin returns whether the velero-plugin-for-csi plugin is in the init containers.
+func hasCSIPlugin(initContainers []corev1.Container) bool {
+	for _, container := range initContainers {
+		if strings.Contains(container.Image, "velero-plugin-for-csi") {
+			return true
+		}
+	}
+	return false
+}
+
+// getImageVersion returns the version of the tag of the image.
+func getImageVersion(image string) (semver.Version, error) {
+	image, _, _ = strings.Cut(image, "@")
+	index := strings.LastIndex(image, ":")
+	if index < 0 || strings.Contains(image[index:], "/") {
+		return semver.Version{}, errors.Errorf("image %v has no tag", image)
+	}
+	return semver.ParseTolerant(image[index+1:])
+}
+
+// appendMessages appends the messages of the source to the collection.
+func appendMessages(collection, source *types.LogCollection) {
+	collection.Error = append(collection.Error, source.Error...)
+	collection.Warn = append(collection.Warn, source.Warn...)
+	collection.Info = append(collection.Info, source.Info...)
+}
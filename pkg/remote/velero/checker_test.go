@@ -0,0 +1,138 @@
+package velero
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func TestHasFeature(t *testing.T) {
+	tests := map[string]struct {
+		args     []string
+		expected bool
+	}{
+		"equals":         {args: []string{"server", "--features=EnableCSI"}, expected: true},
+		"separate value": {args: []string{"server", "--features", "EnableAPIGroupVersions,EnableCSI"}, expected: true},
+		"other feature":  {args: []string{"server", "--features=EnableAPIGroupVersions"}},
+		"no features":    {args: []string{"server"}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := hasFeature(test.args, "EnableCSI"); got != test.expected {
+				t.Errorf("expected %v, got %v", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestGetImageVersion(t *testing.T) {
+	tests := map[string]struct {
+		image       string
+		expected    string
+		expectError bool
+	}{
+		"tag":             {image: "velero/velero:v1.14.1", expected: "1.14.1"},
+		"registry port":   {image: "registry.example.com:5000/velero/velero:v1.13.0", expected: "1.13.0"},
+		"digest":          {image: "velero/velero:v1.15.0@sha256:0123", expected: "1.15.0"},
+		"no tag":          {image: "registry.example.com:5000/velero/velero", expectError: true},
+		"non-version tag": {image: "velero/velero:main", expectError: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			version, err := getImageVersion(test.image)
+			if test.expectError {
+				if err == nil {
+					t.Fatalf("expected error, got %v", version)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if version.String() != test.expected {
+				t.Errorf("expected %v, got %v", test.expected, version)
+			}
+		})
+	}
+}
+
+func newTestDeployment(image string, args []string, initImages ...string) *appsv1.Deployment {
+	deployment := &appsv1.Deployment{}
+	deployment.Status.ReadyReplicas = 1
+	deployment.Spec.Template.Spec.Containers = []corev1.Container{{Name: "velero", Image: image, Args: args}}
+	for _, initImage := range initImages {
+		deployment.Spec.Template.Spec.InitContainers = append(deployment.Spec.Template.Spec.InitContainers, corev1.Container{Image: initImage})
+	}
+	return deployment
+}
+
+func TestCheckServerDeployment(t *testing.T) {
+	tests := map[string]struct {
+		deployment    *appsv1.Deployment
+		expectedError int
+		expectedWarn  int
+	}{
+		"built-in plugin": {deployment: newTestDeployment("velero/velero:v1.14.1", []string{"server", "--features=EnableCSI"})},
+		"init plugin":     {deployment: newTestDeployment("velero/velero:v1.13.2", []string{"server", "--features=EnableCSI"}, "velero/velero-plugin-for-aws:v1.9.0", "velero/velero-plugin-for-csi:v0.7.0")},
+		"missing plugin":  {deployment: newTestDeployment("velero/velero:v1.13.2", []string{"server", "--features=EnableCSI"}, "velero/velero-plugin-for-aws:v1.9.0"), expectedError: 1},
+		"missing feature": {deployment: newTestDeployment("velero/velero:v1.14.1", []string{"server"}), expectedError: 1},
+		"fs backup":       {deployment: newTestDeployment("velero/velero:v1.14.1", []string{"server", "--features=EnableCSI", "--default-volumes-to-fs-backup"}), expectedWarn: 1},
+		"unknown version": {deployment: newTestDeployment("velero/velero:main", []string{"server", "--features=EnableCSI"}), expectedWarn: 1},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			collection := checkServerDeployment(test.deployment)
+			if len(collection.Error) != test.expectedError || len(collection.Warn) != test.expectedWarn {
+				t.Errorf("expected %d errors and %d warnings, got %+v", test.expectedError, test.expectedWarn, collection)
+			}
+		})
+	}
+}
+
+func TestCheckVolumeSnapshotClass(t *testing.T) {
+	tests := map[string]struct {
+		snapshotType string
+		expected     func(*types.LogCollection) bool
+	}{
+		"default":  {expected: func(c *types.LogCollection) bool { return len(c.Info) == 1 && len(c.Warn) == 0 }},
+		"backup":   {snapshotType: "bak", expected: func(c *types.LogCollection) bool { return len(c.Info) == 1 && len(c.Warn) == 0 }},
+		"snapshot": {snapshotType: "snap", expected: func(c *types.LogCollection) bool { return len(c.Warn) == 1 }},
+		"unknown":  {snapshotType: "image", expected: func(c *types.LogCollection) bool { return len(c.Error) == 1 }},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			class := &unstructured.Unstructured{Object: map[string]interface{}{"driver": "driver.longhorn.io"}}
+			if test.snapshotType != "" {
+				class.Object["parameters"] = map[string]interface{}{"type": test.snapshotType}
+			}
+			if collection := checkVolumeSnapshotClass(class); !test.expected(collection) {
+				t.Errorf("unexpected result %+v", collection)
+			}
+		})
+	}
+}
+
+func TestCheckBackupStorageLocation(t *testing.T) {
+	available := &unstructured.Unstructured{Object: map[string]interface{}{
+		"status": map[string]interface{}{"phase": "Available"},
+	}}
+	if collection := checkBackupStorageLocation(available); len(collection.Info) != 1 || len(collection.Error) != 0 {
+		t.Errorf("expected the location available, got %+v", collection)
+	}
+
+	unavailable := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec":   map[string]interface{}{"accessMode": "ReadOnly"},
+		"status": map[string]interface{}{"phase": "Unavailable", "message": "BackupStorageLocation \"default\" is unavailable: access denied"},
+	}}
+	if collection := checkBackupStorageLocation(unavailable); len(collection.Error) != 1 || len(collection.Warn) != 1 {
+		t.Errorf("expected the location unavailable and read-only, got %+v", collection)
+	}
+}
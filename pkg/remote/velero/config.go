@@ -0,0 +1,361 @@
+package velero
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/yaml"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	kubeclient "k8s.io/client-go/kubernetes"
+
+	lhmgrtypes "github.com/longhorn/longhorn-manager/types"
+
+	lhclient "github.com/longhorn/longhorn-manager/k8s/pkg/client/clientset/versioned"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+var (
+	// veleroGroupVersion is the API of the Velero custom resources.
+	veleroGroupVersion = schema.GroupVersion{Group: "velero.io", Version: "v1"}
+	// csiSnapshotGroupVersion is the API of the CSI snapshot custom resources.
+	csiSnapshotGroupVersion = schema.GroupVersion{Group: "snapshot.storage.k8s.io", Version: "v1"}
+
+	backupStorageLocationResource = veleroGroupVersion.WithResource("backupstoragelocations")
+	volumeSnapshotClassResource   = csiSnapshotGroupVersion.WithResource("volumesnapshotclasses")
+)
+
+// veleroCredentialKey is the key of the credentials file of the AWS plugin of Velero in the
+// credential secret.
+const veleroCredentialKey = "cloud"
+
+// ConfigGenerator provide functions for generating, and optionally applying, the Velero
+// configuration backing up the Longhorn volumes: a BackupStorageLocation beside the backupstore of
+// the Longhorn backup target, and the VolumeSnapshotClass Velero selects for the Longhorn CSI
+// driver.
+type ConfigGenerator struct {
+	ConfigGeneratorCmdOptions
+
+	kubeClient     *kubeclient.Clientset
+	longhornClient *lhclient.Clientset
+	dynamicClient  *dynamic.DynamicClient
+}
+
+// ConfigGeneratorCmdOptions holds the options for the command.
+type ConfigGeneratorCmdOptions struct {
+	types.GlobalCmdOptions
+
+	LonghornNamespace  string
+	VeleroNamespace    string
+	BackupTarget       string // Name of the Longhorn backup target.
+	SnapshotType       string // Type of the snapshots taken by the VolumeSnapshotClass.
+	IncludeCredentials bool   // Generate the credential secret of the BackupStorageLocation.
+	Apply              bool
+}
+
+// Validate validates the command options.
+func (remote *ConfigGenerator) Validate() error {
+	switch remote.SnapshotType {
+	case consts.CSISnapshotTypeSnapshot, consts.CSISnapshotTypeBackup:
+	default:
+		return errors.Errorf("invalid --%s %q, expected %s or %s", consts.CmdOptSnapshotType, remote.SnapshotType, consts.CSISnapshotTypeBackup, consts.CSISnapshotTypeSnapshot)
+	}
+
+	if remote.VeleroNamespace == "" {
+		return errors.Errorf("--%s is required", consts.CmdOptVeleroNamespace)
+	}
+
+	return nil
+}
+
+// Init initializes the ConfigGenerator.
+func (remote *ConfigGenerator) Init() error {
+	config, err := kubeutils.NewRestConfig("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+
+	remote.kubeClient, err = kubeutils.NewKubeClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+
+	remote.longhornClient, err = kubeutils.NewLonghornClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+
+	remote.dynamicClient, err = dynamic.NewForConfig(config)
+	if err != nil {
+		return errors.Wrap(err, "failed to create dynamic client")
+	}
+
+	return nil
+}
+
+// Run returns the Velero configuration as a multi-document YAML string, after applying it when
+// requested.
+func (remote *ConfigGenerator) Run() (string, error) {
+	ctx := context.Background()
+
+	objects, err := remote.newObjects(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var documents []string
+	for _, object := range objects {
+		if remote.Apply {
+			if err := remote.applyObject(ctx, object); err != nil {
+				return "", err
+			}
+		}
+
+		yamlData, err := yaml.Marshal(object)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to convert %T to YAML", object)
+		}
+		documents = append(documents, string(yamlData))
+	}
+
+	return strings.Join(documents, "---\n"), nil
+}
+
+// Cleanup does nothing, since the generated resources are the result of the ConfigGenerator.
+func (remote *ConfigGenerator) Cleanup() error {
+	return nil
+}
+
+// newObjects inspects the Longhorn backup target and VolumeSnapshotClasses, and prepares the
+// Velero configuration.
+func (remote *ConfigGenerator) newObjects(ctx context.Context) ([]runtime.Object, error) {
+	var objects []runtime.Object
+
+	backupTarget, err := remote.longhornClient.LonghornV1beta2().BackupTargets(remote.LonghornNamespace).Get(ctx, remote.BackupTarget, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get backup target %v", remote.BackupTarget)
+	}
+
+	switch {
+	case backupTarget.Spec.BackupTargetURL == "":
+		logrus.Warnf("Backup target %v is not configured, skipping the BackupStorageLocation", remote.BackupTarget)
+	case !strings.HasPrefix(backupTarget.Spec.BackupTargetURL, "s3://"):
+		logrus.Warnf("Backup target %v (%v) is not an S3 bucket, skipping the BackupStorageLocation, configure the object storage of Velero separately", remote.BackupTarget, backupTarget.Spec.BackupTargetURL)
+	default:
+		location, err := parseS3BackupTarget(backupTarget.Spec.BackupTargetURL)
+		if err != nil {
+			return nil, err
+		}
+
+		var secretData map[string][]byte
+		if backupTarget.Spec.CredentialSecret != "" {
+			secret, err := remote.kubeClient.CoreV1().Secrets(remote.LonghornNamespace).Get(ctx, backupTarget.Spec.CredentialSecret, metav1.GetOptions{})
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to get credential secret %v of backup target %v", backupTarget.Spec.CredentialSecret, remote.BackupTarget)
+			}
+			secretData = secret.Data
+		}
+
+		if remote.IncludeCredentials {
+			secret, err := remote.newCredentialSecret(secretData)
+			if err != nil {
+				return nil, err
+			}
+			objects = append(objects, secret)
+		} else {
+			logrus.Warnf("Create secret %v/%v with the AWS credentials file of the bucket as key %q, or use --%s", remote.VeleroNamespace, consts.VeleroCredentialSecret, veleroCredentialKey, consts.CmdOptIncludeCredentials)
+		}
+		objects = append(objects, remote.newBackupStorageLocation(location, secretData))
+	}
+
+	if err := remote.checkVolumeSnapshotClasses(ctx); err != nil {
+		return nil, err
+	}
+	objects = append(objects, remote.newVolumeSnapshotClass())
+
+	return objects, nil
+}
+
+// checkVolumeSnapshotClasses warns about the other VolumeSnapshotClasses of the Longhorn CSI driver
+// selected by Velero, since Velero fails when several are.
+func (remote *ConfigGenerator) checkVolumeSnapshotClasses(ctx context.Context) error {
+	list, err := remote.dynamicClient.Resource(volumeSnapshotClassResource).List(ctx, metav1.ListOptions{
+		LabelSelector: consts.VeleroLabelCSIVolumeSnapshotClass + "=true",
+	})
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to list the VolumeSnapshotClasses selected by Velero")
+		return nil
+	}
+
+	for _, class := range list.Items {
+		driver, _, _ := unstructured.NestedString(class.Object, "driver")
+		if driver == lhmgrtypes.LonghornDriverName && class.GetName() != consts.VeleroVolumeSnapshotClass {
+			logrus.Warnf("VolumeSnapshotClass %v is also selected by Velero for %v, remove its label %v", class.GetName(), lhmgrtypes.LonghornDriverName, consts.VeleroLabelCSIVolumeSnapshotClass)
+		}
+	}
+	return nil
+}
+
+// applyObject applies the object with server-side apply, taking over the fields changed by others.
+func (remote *ConfigGenerator) applyObject(ctx context.Context, object runtime.Object) error {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(object)
+	if err != nil {
+		return errors.Wrapf(err, "failed to convert %T", object)
+	}
+	obj := &unstructured.Unstructured{Object: content}
+
+	var resource dynamic.ResourceInterface
+	switch object.(type) {
+	case *corev1.Secret:
+		resource = remote.dynamicClient.Resource(corev1.SchemeGroupVersion.WithResource("secrets")).Namespace(obj.GetNamespace())
+	default:
+		if obj.GetKind() == "BackupStorageLocation" {
+			resource = remote.dynamicClient.Resource(backupStorageLocationResource).Namespace(obj.GetNamespace())
+		} else {
+			resource = remote.dynamicClient.Resource(volumeSnapshotClassResource)
+		}
+	}
+
+	log := logrus.WithFields(logrus.Fields{
+		"kind":      obj.GetKind(),
+		"namespace": obj.GetNamespace(),
+		"name":      obj.GetName(),
+	})
+	log.Info("Applying resource")
+
+	_, err = resource.Apply(ctx, obj.GetName(), obj, metav1.ApplyOptions{
+		FieldManager: consts.CmdLonghornctlRemote,
+		Force:        true,
+	})
+	return errors.Wrapf(err, "failed to apply %v %v", obj.GetKind(), obj.GetName())
+}
+
+// newBackupStorageLocation prepares the BackupStorageLocation of the AWS plugin of Velero, storing
+// the Velero backups in the velero directory beside the backupstore of the backup target.
+func (remote *ConfigGenerator) newBackupStorageLocation(location *s3Location, secretData map[string][]byte) *unstructured.Unstructured {
+	config := map[string]interface{}{
+		"region": location.region,
+	}
+	if endpoint := strings.TrimSpace(string(secretData[consts.EnvAwsEndpoints])); endpoint != "" {
+		config["s3Url"] = endpoint
+		if !strings.EqualFold(string(secretData[consts.EnvVirtualHostedStyle]), "true") {
+			config["s3ForcePathStyle"] = "true"
+		}
+	}
+
+	objectStorage := map[string]interface{}{
+		"bucket": location.bucket,
+		"prefix": path.Join(location.prefix, "velero"),
+	}
+	if cert := secretData[consts.EnvAwsCert]; len(cert) > 0 {
+		objectStorage["caCert"] = base64.StdEncoding.EncodeToString(cert)
+	}
+
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": veleroGroupVersion.String(),
+		"kind":       "BackupStorageLocation",
+		"metadata": map[string]interface{}{
+			"name":      consts.VeleroBackupStorageLocation,
+			"namespace": remote.VeleroNamespace,
+		},
+		"spec": map[string]interface{}{
+			"provider":      "aws",
+			"objectStorage": objectStorage,
+			"config":        config,
+			"credential": map[string]interface{}{
+				"name": consts.VeleroCredentialSecret,
+				"key":  veleroCredentialKey,
+			},
+		},
+	}}
+}
+
+// newCredentialSecret prepares the secret holding the credentials of the backup target as the AWS
+// credentials file the AWS plugin of Velero reads.
+func (remote *ConfigGenerator) newCredentialSecret(secretData map[string][]byte) (*corev1.Secret, error) {
+	accessKeyID := string(secretData[consts.EnvAwsAccessKeyID])
+	secretAccessKey := string(secretData[consts.EnvAwsSecretAccessKey])
+	if accessKeyID == "" || secretAccessKey == "" {
+		return nil, errors.Errorf("%s and %s are required in the credential secret of backup target %v", consts.EnvAwsAccessKeyID, consts.EnvAwsSecretAccessKey, remote.BackupTarget)
+	}
+
+	credentials := fmt.Sprintf("[default]\naws_access_key_id=%s\naws_secret_access_key=%s\n", accessKeyID, secretAccessKey)
+	if sessionToken := string(secretData[consts.EnvAwsSessionToken]); sessionToken != "" {
+		credentials += fmt.Sprintf("aws_session_token=%s\n", sessionToken)
+	}
+
+	return &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1.SchemeGroupVersion.String(),
+			Kind:       "Secret",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      consts.VeleroCredentialSecret,
+			Namespace: remote.VeleroNamespace,
+		},
+		StringData: map[string]string{
+			veleroCredentialKey: credentials,
+		},
+	}, nil
+}
+
+// newVolumeSnapshotClass prepares the VolumeSnapshotClass Velero selects for the Longhorn CSI
+// driver. Velero retains the snapshots it takes regardless of the deletion policy, and deletes
+// them with the backup.
+func (remote *ConfigGenerator) newVolumeSnapshotClass() *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": csiSnapshotGroupVersion.String(),
+		"kind":       "VolumeSnapshotClass",
+		"metadata": map[string]interface{}{
+			"name": consts.VeleroVolumeSnapshotClass,
+			"labels": map[string]interface{}{
+				consts.VeleroLabelCSIVolumeSnapshotClass: "true",
+			},
+		},
+		"driver":         lhmgrtypes.LonghornDriverName,
+		"deletionPolicy": "Delete",
+		"parameters": map[string]interface{}{
+			"type": remote.SnapshotType,
+		},
+	}}
+}
+
+// s3Location is the bucket of an S3 backup target.
+type s3Location struct {
+	bucket string
+	region string
+	prefix string // Prefix of the directory holding the backupstore directory.
+}
+
+// parseS3BackupTarget parses the URL of an S3 backup target, as s3://<bucket>@<region>/<path>.
+func parseS3BackupTarget(target string) (*s3Location, error) {
+	parsed, err := url.Parse(target)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid backup target URL %q", target)
+	}
+	if parsed.Scheme != "s3" || parsed.User == nil || parsed.User.Username() == "" || parsed.Host == "" {
+		return nil, errors.Errorf("invalid S3 backup target URL %q, expected s3://<bucket>@<region>/<path>", target)
+	}
+
+	return &s3Location{
+		bucket: parsed.User.Username(),
+		region: parsed.Host,
+		prefix: strings.Trim(parsed.Path, "/"),
+	}, nil
+}
@@ -0,0 +1,117 @@
+package velero
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/longhorn/cli/pkg/consts"
+)
+
+func TestParseS3BackupTarget(t *testing.T) {
+	tests := map[string]struct {
+		target      string
+		expected    *s3Location
+		expectError bool
+	}{
+		"with path":      {target: "s3://backups@us-east-1/longhorn/", expected: &s3Location{bucket: "backups", region: "us-east-1", prefix: "longhorn"}},
+		"without path":   {target: "s3://backups@minio/", expected: &s3Location{bucket: "backups", region: "minio"}},
+		"missing bucket": {target: "s3://us-east-1/longhorn", expectError: true},
+		"not s3":         {target: "nfs://nfs.example.com:/export", expectError: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			location, err := parseS3BackupTarget(test.target)
+			if test.expectError != (err != nil) {
+				t.Fatalf("expected error %v, got %v", test.expectError, err)
+			}
+			if !reflect.DeepEqual(location, test.expected) {
+				t.Errorf("expected %+v, got %+v", test.expected, location)
+			}
+		})
+	}
+}
+
+func TestConfigGeneratorValidate(t *testing.T) {
+	tests := map[string]struct {
+		options     ConfigGeneratorCmdOptions
+		expectError bool
+	}{
+		"backup":                {options: ConfigGeneratorCmdOptions{VeleroNamespace: "velero", SnapshotType: consts.CSISnapshotTypeBackup}},
+		"snapshot":              {options: ConfigGeneratorCmdOptions{VeleroNamespace: "velero", SnapshotType: consts.CSISnapshotTypeSnapshot}},
+		"legacy backup":         {options: ConfigGeneratorCmdOptions{VeleroNamespace: "velero", SnapshotType: consts.CSISnapshotTypeLegacyBackup}, expectError: true},
+		"missing namespace":     {options: ConfigGeneratorCmdOptions{SnapshotType: consts.CSISnapshotTypeBackup}, expectError: true},
+		"unknown snapshot type": {options: ConfigGeneratorCmdOptions{VeleroNamespace: "velero", SnapshotType: "image"}, expectError: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			generator := &ConfigGenerator{ConfigGeneratorCmdOptions: test.options}
+			if err := generator.Validate(); test.expectError != (err != nil) {
+				t.Errorf("expected error %v, got %v", test.expectError, err)
+			}
+		})
+	}
+}
+
+func TestNewBackupStorageLocation(t *testing.T) {
+	generator := &ConfigGenerator{ConfigGeneratorCmdOptions: ConfigGeneratorCmdOptions{VeleroNamespace: "velero"}}
+	location := &s3Location{bucket: "backups", region: "us-east-1", prefix: "longhorn"}
+
+	tests := map[string]struct {
+		secretData map[string][]byte
+		expected   map[string]interface{}
+	}{
+		"aws": {
+			expected: map[string]interface{}{"region": "us-east-1"},
+		},
+		"path style endpoint": {
+			secretData: map[string][]byte{consts.EnvAwsEndpoints: []byte("https://minio.example.com:9000")},
+			expected:   map[string]interface{}{"region": "us-east-1", "s3Url": "https://minio.example.com:9000", "s3ForcePathStyle": "true"},
+		},
+		"virtual hosted endpoint": {
+			secretData: map[string][]byte{consts.EnvAwsEndpoints: []byte("https://s3.example.com"), consts.EnvVirtualHostedStyle: []byte("true")},
+			expected:   map[string]interface{}{"region": "us-east-1", "s3Url": "https://s3.example.com"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			object := generator.newBackupStorageLocation(location, test.secretData)
+			if object.GetNamespace() != "velero" || object.GetName() != consts.VeleroBackupStorageLocation {
+				t.Errorf("unexpected BackupStorageLocation %v/%v", object.GetNamespace(), object.GetName())
+			}
+
+			config, _, _ := unstructured.NestedMap(object.Object, "spec", "config")
+			if !reflect.DeepEqual(config, test.expected) {
+				t.Errorf("expected config %v, got %v", test.expected, config)
+			}
+			if prefix, _, _ := unstructured.NestedString(object.Object, "spec", "objectStorage", "prefix"); prefix != "longhorn/velero" {
+				t.Errorf("expected prefix longhorn/velero, got %v", prefix)
+			}
+		})
+	}
+}
+
+func TestNewCredentialSecret(t *testing.T) {
+	generator := &ConfigGenerator{ConfigGeneratorCmdOptions: ConfigGeneratorCmdOptions{VeleroNamespace: "velero"}}
+
+	secret, err := generator.newCredentialSecret(map[string][]byte{
+		consts.EnvAwsAccessKeyID:     []byte("AKIA"),
+		consts.EnvAwsSecretAccessKey: []byte("secret"),
+		consts.EnvAwsSessionToken:    []byte("token"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "[default]\naws_access_key_id=AKIA\naws_secret_access_key=secret\naws_session_token=token\n"
+	if secret.StringData[veleroCredentialKey] != expected {
+		t.Errorf("expected credentials %q, got %q", expected, secret.StringData[veleroCredentialKey])
+	}
+
+	if _, err := generator.newCredentialSecret(map[string][]byte{consts.EnvAwsAccessKeyID: []byte("AKIA")}); err == nil {
+		t.Errorf("expected error without secret access key")
+	}
+}
@@ -0,0 +1,487 @@
+package verify
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	kubeclient "k8s.io/client-go/kubernetes"
+	"k8s.io/utils/ptr"
+
+	longhorn "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+	lhclient "github.com/longhorn/longhorn-manager/k8s/pkg/client/clientset/versioned"
+	lhmgrtypes "github.com/longhorn/longhorn-manager/types"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// Names of the verification steps.
+const (
+	StepWriteData      = "write-data"
+	StepCreateSnapshot = "create-snapshot"
+	StepCreateBackup   = "create-backup"
+	StepReadData       = "read-data"
+)
+
+const (
+	verifyDataFile     = "verify.bin"
+	verifyChecksumFile = "verify.sha256"
+	verifyReadyFile    = "/tmp/ready"
+
+	pollInterval = 2 * time.Second
+)
+
+// InstallationVerifier provide functions for verifying a Longhorn installation with a test volume.
+type InstallationVerifier struct {
+	InstallationVerifierCmdOptions
+
+	kubeClient     *kubeclient.Clientset
+	longhornClient *lhclient.Clientset
+
+	namespace    string
+	appName      string // Name of the PVC, snapshot and backup, and prefix of the pod names.
+	nodeSelector map[string]string
+
+	volumeName string // Name of the Longhorn volume bound to the PVC.
+}
+
+// InstallationVerifierCmdOptions holds the options for the command.
+type InstallationVerifierCmdOptions struct {
+	types.GlobalCmdOptions
+
+	LonghornNamespace string
+	StorageClass      string        // StorageClass of the test PVC.
+	Size              string        // Size of the test PVC.
+	Backup            bool          // Back up the snapshot to the backup target of the volume.
+	Timeout           time.Duration // Maximum time to wait for each step.
+}
+
+// Validate validates the command options.
+func (remote *InstallationVerifier) Validate() error {
+	if remote.LonghornNamespace == "" {
+		return errors.Errorf("Longhorn namespace (--%s) is required", consts.CmdOptLonghornNamespace)
+	}
+
+	if remote.StorageClass == "" {
+		return errors.Errorf("storage class (--%s) is required", consts.CmdOptStorageClass)
+	}
+
+	if _, err := resource.ParseQuantity(remote.Size); err != nil {
+		return errors.Wrapf(err, "invalid --%s %q", consts.CmdOptSize, remote.Size)
+	}
+
+	if remote.Timeout <= 0 {
+		return errors.Errorf("timeout (--%s) must be positive", consts.CmdOptTimeout)
+	}
+
+	return nil
+}
+
+// Init initializes the InstallationVerifier.
+func (remote *InstallationVerifier) Init() error {
+	kubeClient, err := kubeutils.NewKubeClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.kubeClient = kubeClient
+
+	longhornClient, err := kubeutils.NewLonghornClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.longhornClient = longhornClient
+
+	remote.namespace = remote.Namespace
+	if remote.namespace == "" {
+		remote.namespace = consts.LonghornNamespace
+	}
+	remote.appName = consts.AppNameInstallationVerifier
+
+	remote.nodeSelector, err = kubeutils.ParseNodeSelector(remote.NodeSelector)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse %q argument", consts.CmdOptNodeSelector)
+	}
+
+	return nil
+}
+
+// verifyStep is a verification step, skipped when skip is set.
+type verifyStep struct {
+	name string
+	run  func(ctx context.Context) (string, error)
+	skip bool
+}
+
+// Run runs the verification steps in order and returns the report. The steps after
+// a failed step are skipped. An error is only returned when the report cannot be produced.
+func (remote *InstallationVerifier) Run() (*types.VerifyReport, error) {
+	if _, err := kubeutils.CreateNamespace(remote.kubeClient, remote.namespace); err != nil {
+		return nil, err
+	}
+
+	steps := []verifyStep{
+		{name: StepWriteData, run: remote.writeData},
+		{name: StepCreateSnapshot, run: remote.createSnapshot},
+		{name: StepCreateBackup, run: remote.createBackup, skip: !remote.Backup},
+		{name: StepReadData, run: remote.readData},
+	}
+	return runSteps(steps, remote.Timeout), nil
+}
+
+// runSteps runs the steps in order, each within the timeout, and reports whether they all passed.
+// The steps after a failed step are skipped.
+func runSteps(steps []verifyStep, timeout time.Duration) *types.VerifyReport {
+	report := &types.VerifyReport{Passed: true}
+	for _, step := range steps {
+		if step.skip || !report.Passed {
+			report.Steps = append(report.Steps, types.VerifyStep{Name: step.name, Status: types.VerifyStepStatusSkip})
+			continue
+		}
+
+		logrus.Infof("Running step %v", step.name)
+		startTime := time.Now()
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		message, err := step.run(ctx)
+		cancel()
+
+		result := types.VerifyStep{
+			Name:     step.name,
+			Status:   types.VerifyStepStatusPass,
+			Message:  message,
+			Duration: time.Since(startTime).Round(time.Second).String(),
+		}
+		if err != nil {
+			logrus.WithError(err).Errorf("Failed step %v", step.name)
+			result.Status = types.VerifyStepStatusFail
+			result.Message = err.Error()
+			report.Passed = false
+		}
+		report.Steps = append(report.Steps, result)
+	}
+
+	return report
+}
+
+// writeData creates the test PVC and a pod writing random data and its checksum to it.
+// The pod keeps running, so the volume stays attached for the snapshot and the backup.
+func (remote *InstallationVerifier) writeData(ctx context.Context) (string, error) {
+	newPVC := remote.newPersistentVolumeClaim()
+	kubeutils.SetRunMetadata(remote.kubeClient, newPVC)
+	if _, err := remote.kubeClient.CoreV1().PersistentVolumeClaims(remote.namespace).Create(ctx, newPVC, metav1.CreateOptions{}); err != nil {
+		return "", errors.Wrapf(err, "failed to create PVC %v", remote.appName)
+	}
+
+	script := fmt.Sprintf("cd %[1]s && dd if=/dev/urandom of=%[2]s bs=1M count=16 && sha256sum %[2]s > %[3]s && sync && touch %[4]s && sleep infinity",
+		consts.VolumeMountVerifyDataDirectory, verifyDataFile, verifyChecksumFile, verifyReadyFile)
+	pod := remote.newPod(remote.writerPodName(), script)
+	pod.Spec.Containers[0].ReadinessProbe = &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{
+			Exec: &corev1.ExecAction{Command: []string{"test", "-f", verifyReadyFile}},
+		},
+		PeriodSeconds: 2,
+	}
+	if err := kubeutils.SetPodOptions(&pod.Spec, &remote.GlobalCmdOptions); err != nil {
+		return "", err
+	}
+
+	kubeutils.SetRunMetadata(remote.kubeClient, pod)
+	if _, err := remote.kubeClient.CoreV1().Pods(remote.namespace).Create(ctx, pod, metav1.CreateOptions{}); err != nil {
+		return "", errors.Wrapf(err, "failed to create pod %v", pod.Name)
+	}
+
+	err := wait.PollUntilContextCancel(ctx, pollInterval, true, func(ctx context.Context) (bool, error) {
+		pod, err := remote.kubeClient.CoreV1().Pods(remote.namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+
+		if pod.Status.Phase == corev1.PodFailed {
+			return false, errors.Errorf("pod %v failed writing the data", pod.Name)
+		}
+		return isPodReady(pod), nil
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed waiting for pod %v to write the data", pod.Name)
+	}
+
+	pvc, err := remote.kubeClient.CoreV1().PersistentVolumeClaims(remote.namespace).Get(ctx, remote.appName, metav1.GetOptions{})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get PVC %v", remote.appName)
+	}
+	remote.volumeName = pvc.Spec.VolumeName
+
+	return fmt.Sprintf("Wrote 16MiB to volume %v", remote.volumeName), nil
+}
+
+// createSnapshot takes a snapshot of the attached test volume.
+func (remote *InstallationVerifier) createSnapshot(ctx context.Context) (string, error) {
+	snapshot := &longhorn.Snapshot{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: remote.appName,
+		},
+		Spec: longhorn.SnapshotSpec{
+			Volume:         remote.volumeName,
+			CreateSnapshot: true,
+		},
+	}
+	if _, err := remote.longhornClient.LonghornV1beta2().Snapshots(remote.LonghornNamespace).Create(ctx, snapshot, metav1.CreateOptions{}); err != nil {
+		return "", errors.Wrapf(err, "failed to create snapshot %v", snapshot.Name)
+	}
+
+	err := wait.PollUntilContextCancel(ctx, pollInterval, true, func(ctx context.Context) (bool, error) {
+		snapshot, err := remote.longhornClient.LonghornV1beta2().Snapshots(remote.LonghornNamespace).Get(ctx, remote.appName, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+
+		if snapshot.Status.Error != "" {
+			return false, errors.New(snapshot.Status.Error)
+		}
+		return snapshot.Status.ReadyToUse, nil
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed waiting for snapshot %v to be ready", remote.appName)
+	}
+
+	return fmt.Sprintf("Created snapshot %v", remote.appName), nil
+}
+
+// createBackup backs up the snapshot to the backup target of the test volume.
+func (remote *InstallationVerifier) createBackup(ctx context.Context) (string, error) {
+	volume, err := remote.longhornClient.LonghornV1beta2().Volumes(remote.LonghornNamespace).Get(ctx, remote.volumeName, metav1.GetOptions{})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get volume %v", remote.volumeName)
+	}
+
+	backupTargetName := volume.Spec.BackupTargetName
+	if backupTargetName == "" {
+		backupTargetName = lhmgrtypes.DefaultBackupTargetName
+	}
+
+	backupTarget, err := remote.longhornClient.LonghornV1beta2().BackupTargets(remote.LonghornNamespace).Get(ctx, backupTargetName, metav1.GetOptions{})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get backup target %v", backupTargetName)
+	}
+	if backupTarget.Spec.BackupTargetURL == "" {
+		return "", errors.Errorf("backup target %v is not configured", backupTargetName)
+	}
+	if !backupTarget.Status.Available {
+		return "", errors.Errorf("backup target %v (%v) is not available", backupTargetName, backupTarget.Spec.BackupTargetURL)
+	}
+
+	backup := &longhorn.Backup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   remote.appName,
+			Labels: lhmgrtypes.GetBackupVolumeWithBackupTargetLabels(backupTargetName, remote.volumeName),
+		},
+		Spec: longhorn.BackupSpec{
+			SnapshotName: remote.appName,
+		},
+	}
+	if _, err := remote.longhornClient.LonghornV1beta2().Backups(remote.LonghornNamespace).Create(ctx, backup, metav1.CreateOptions{}); err != nil {
+		return "", errors.Wrapf(err, "failed to create backup %v", backup.Name)
+	}
+
+	err = wait.PollUntilContextCancel(ctx, pollInterval, true, func(ctx context.Context) (bool, error) {
+		backup, err := remote.longhornClient.LonghornV1beta2().Backups(remote.LonghornNamespace).Get(ctx, remote.appName, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+
+		switch backup.Status.State {
+		case longhorn.BackupStateError, longhorn.BackupStateUnknown:
+			return false, errors.Errorf("backup is %v: %v", backup.Status.State, backup.Status.Error)
+		case longhorn.BackupStateCompleted:
+			return true, nil
+		}
+		return false, nil
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed waiting for backup %v to complete", remote.appName)
+	}
+
+	return fmt.Sprintf("Backed up snapshot %v to %v", remote.appName, backupTarget.Spec.BackupTargetURL), nil
+}
+
+// readData deletes the writer pod, so the volume is detached, and verifies the checksum
+// of the data from a new pod, which attaches the volume again.
+func (remote *InstallationVerifier) readData(ctx context.Context) (string, error) {
+	if err := remote.deletePod(ctx, remote.writerPodName()); err != nil {
+		return "", err
+	}
+
+	script := fmt.Sprintf("cd %s && sha256sum -c %s", consts.VolumeMountVerifyDataDirectory, verifyChecksumFile)
+	pod := remote.newPod(remote.readerPodName(), script)
+	if err := kubeutils.SetPodOptions(&pod.Spec, &remote.GlobalCmdOptions); err != nil {
+		return "", err
+	}
+
+	kubeutils.SetRunMetadata(remote.kubeClient, pod)
+	if _, err := remote.kubeClient.CoreV1().Pods(remote.namespace).Create(ctx, pod, metav1.CreateOptions{}); err != nil {
+		return "", errors.Wrapf(err, "failed to create pod %v", pod.Name)
+	}
+
+	err := wait.PollUntilContextCancel(ctx, pollInterval, true, func(ctx context.Context) (bool, error) {
+		pod, err := remote.kubeClient.CoreV1().Pods(remote.namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+
+		switch pod.Status.Phase {
+		case corev1.PodFailed:
+			return false, errors.Errorf("pod %v failed verifying the checksum of the data", pod.Name)
+		case corev1.PodSucceeded:
+			return true, nil
+		}
+		return false, nil
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed waiting for pod %v to read the data", pod.Name)
+	}
+
+	return "Verified the checksum of the data after reattaching the volume", nil
+}
+
+// Cleanup deletes the backup, snapshot, pods and PVC created for the verification.
+// Deleting the PVC deletes the Longhorn volume with the default reclaim policy.
+func (remote *InstallationVerifier) Cleanup() error {
+	ctx := context.Background()
+
+	err := remote.longhornClient.LonghornV1beta2().Backups(remote.LonghornNamespace).Delete(ctx, remote.appName, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to delete backup %v", remote.appName)
+	}
+
+	err = remote.longhornClient.LonghornV1beta2().Snapshots(remote.LonghornNamespace).Delete(ctx, remote.appName, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to delete snapshot %v", remote.appName)
+	}
+
+	for _, podName := range []string{remote.writerPodName(), remote.readerPodName()} {
+		if err := remote.deletePod(ctx, podName); err != nil {
+			return err
+		}
+	}
+
+	err = remote.kubeClient.CoreV1().PersistentVolumeClaims(remote.namespace).Delete(ctx, remote.appName, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to delete PVC %v", remote.appName)
+	}
+
+	return nil
+}
+
+// deletePod deletes the pod and waits for it to be gone, so its volume is detached.
+func (remote *InstallationVerifier) deletePod(ctx context.Context, name string) error {
+	err := remote.kubeClient.CoreV1().Pods(remote.namespace).Delete(ctx, name, metav1.DeleteOptions{GracePeriodSeconds: ptr.To(int64(0))})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to delete pod %v", name)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, remote.Timeout)
+	defer cancel()
+
+	err = wait.PollUntilContextCancel(ctx, pollInterval, true, func(ctx context.Context) (bool, error) {
+		_, err := remote.kubeClient.CoreV1().Pods(remote.namespace).Get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	})
+	return errors.Wrapf(err, "failed waiting for pod %v to be deleted", name)
+}
+
+func (remote *InstallationVerifier) writerPodName() string {
+	return remote.appName + "-writer"
+}
+
+func (remote *InstallationVerifier) readerPodName() string {
+	return remote.appName + "-reader"
+}
+
+func (remote *InstallationVerifier) newPersistentVolumeClaim() *corev1.PersistentVolumeClaim {
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      remote.appName,
+			Namespace: remote.namespace,
+			Labels: map[string]string{
+				"app":                 remote.appName,
+				consts.LabelManagedBy: consts.LabelValueManagedBy,
+			},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			StorageClassName: ptr.To(remote.StorageClass),
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: resource.MustParse(remote.Size),
+				},
+			},
+		},
+	}
+}
+
+// newPod prepares a pod running the shell script with the test PVC mounted.
+func (remote *InstallationVerifier) newPod(name, script string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: remote.namespace,
+			Labels: map[string]string{
+				"app":                 remote.appName,
+				consts.LabelManagedBy: consts.LabelValueManagedBy,
+			},
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			Containers: []corev1.Container{
+				{
+					Name:    name,
+					Image:   remote.Image,
+					Command: []string{"sh", "-c", script},
+					VolumeMounts: []corev1.VolumeMount{
+						{
+							Name:      consts.VolumeMountVerifyDataName,
+							MountPath: consts.VolumeMountVerifyDataDirectory,
+						},
+					},
+				},
+			},
+			Volumes: []corev1.Volume{
+				{
+					Name: consts.VolumeMountVerifyDataName,
+					VolumeSource: corev1.VolumeSource{
+						PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+							ClaimName: remote.appName,
+						},
+					},
+				},
+			},
+			NodeSelector: remote.nodeSelector,
+		},
+	}
+}
+
+func isPodReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
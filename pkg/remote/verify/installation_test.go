@@ -0,0 +1,127 @@
+package verify
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func TestRunSteps(t *testing.T) {
+	pass := func(ctx context.Context) (string, error) { return "passed", nil }
+	fail := func(ctx context.Context) (string, error) { return "", errors.New("failed") }
+	timeout := func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+
+	tests := map[string]struct {
+		steps            []verifyStep
+		expectedPassed   bool
+		expectedStatuses []types.VerifyStepStatus
+		expectedMessages []string
+	}{
+		"all passed": {
+			steps:            []verifyStep{{name: StepWriteData, run: pass}, {name: StepReadData, run: pass}},
+			expectedPassed:   true,
+			expectedStatuses: []types.VerifyStepStatus{types.VerifyStepStatusPass, types.VerifyStepStatusPass},
+			expectedMessages: []string{"passed", "passed"},
+		},
+		"backup skipped": {
+			steps:            []verifyStep{{name: StepCreateSnapshot, run: pass}, {name: StepCreateBackup, run: fail, skip: true}, {name: StepReadData, run: pass}},
+			expectedPassed:   true,
+			expectedStatuses: []types.VerifyStepStatus{types.VerifyStepStatusPass, types.VerifyStepStatusSkip, types.VerifyStepStatusPass},
+			expectedMessages: []string{"passed", "", "passed"},
+		},
+		"failed step skips the next steps": {
+			steps:            []verifyStep{{name: StepWriteData, run: pass}, {name: StepCreateSnapshot, run: fail}, {name: StepReadData, run: pass}},
+			expectedStatuses: []types.VerifyStepStatus{types.VerifyStepStatusPass, types.VerifyStepStatusFail, types.VerifyStepStatusSkip},
+			expectedMessages: []string{"passed", "failed", ""},
+		},
+		"first step failed": {
+			steps:            []verifyStep{{name: StepWriteData, run: fail}, {name: StepReadData, run: pass}},
+			expectedStatuses: []types.VerifyStepStatus{types.VerifyStepStatusFail, types.VerifyStepStatusSkip},
+			expectedMessages: []string{"failed", ""},
+		},
+		"step timed out": {
+			steps:            []verifyStep{{name: StepWriteData, run: timeout}},
+			expectedStatuses: []types.VerifyStepStatus{types.VerifyStepStatusFail},
+			expectedMessages: []string{context.DeadlineExceeded.Error()},
+		},
+	}
+
+	for name, test := range tests {
+		report := runSteps(test.steps, 10*time.Millisecond)
+		if report.Passed != test.expectedPassed {
+			t.Errorf("%v: expected passed %v, got %v", name, test.expectedPassed, report.Passed)
+		}
+
+		var statuses []types.VerifyStepStatus
+		var messages []string
+		for i, step := range report.Steps {
+			if step.Name != test.steps[i].name {
+				t.Errorf("%v: expected step %v, got %v", name, test.steps[i].name, step.Name)
+			}
+			statuses = append(statuses, step.Status)
+			messages = append(messages, step.Message)
+		}
+		if !reflect.DeepEqual(statuses, test.expectedStatuses) {
+			t.Errorf("%v: expected statuses %v, got %v", name, test.expectedStatuses, statuses)
+		}
+		if !reflect.DeepEqual(messages, test.expectedMessages) {
+			t.Errorf("%v: expected messages %q, got %q", name, test.expectedMessages, messages)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := InstallationVerifierCmdOptions{
+		LonghornNamespace: "longhorn-system",
+		StorageClass:      "longhorn",
+		Size:              "1Gi",
+		Timeout:           5 * time.Minute,
+	}
+
+	tests := map[string]struct {
+		modify        func(options *InstallationVerifierCmdOptions)
+		expectedError bool
+	}{
+		"valid":                 {modify: func(options *InstallationVerifierCmdOptions) {}},
+		"no Longhorn namespace": {modify: func(options *InstallationVerifierCmdOptions) { options.LonghornNamespace = "" }, expectedError: true},
+		"no storage class":      {modify: func(options *InstallationVerifierCmdOptions) { options.StorageClass = "" }, expectedError: true},
+		"invalid size":          {modify: func(options *InstallationVerifierCmdOptions) { options.Size = "1 gigabyte" }, expectedError: true},
+		"no timeout":            {modify: func(options *InstallationVerifierCmdOptions) { options.Timeout = 0 }, expectedError: true},
+	}
+
+	for name, test := range tests {
+		verifier := &InstallationVerifier{InstallationVerifierCmdOptions: valid}
+		test.modify(&verifier.InstallationVerifierCmdOptions)
+		if err := verifier.Validate(); (err != nil) != test.expectedError {
+			t.Errorf("%v: expected error %v, got %v", name, test.expectedError, err)
+		}
+	}
+}
+
+func TestIsPodReady(t *testing.T) {
+	tests := map[string]struct {
+		conditions []corev1.PodCondition
+		expected   bool
+	}{
+		"ready":         {conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}, expected: true},
+		"not ready":     {conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionFalse}}},
+		"no conditions": {},
+	}
+
+	for name, test := range tests {
+		pod := &corev1.Pod{Status: corev1.PodStatus{Conditions: test.conditions}}
+		if ready := isPodReady(pod); ready != test.expected {
+			t.Errorf("%v: expected ready %v, got %v", name, test.expected, ready)
+		}
+	}
+}
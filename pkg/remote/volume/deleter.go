@@ -0,0 +1,215 @@
+package volume
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	longhorn "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+	lhclient "github.com/longhorn/longhorn-manager/k8s/pkg/client/clientset/versioned"
+	lhmgrtypes "github.com/longhorn/longhorn-manager/types"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// Deleter provide functions for deleting a Longhorn volume that is no longer used, optionally
+// after backing it up.
+type Deleter struct {
+	DeleterCmdOptions
+
+	longhornClient *lhclient.Clientset
+
+	volume            *longhorn.Volume
+	protectionReasons []string
+	backupName        string // Name of the final snapshot and of its backup.
+}
+
+// DeleterCmdOptions holds the options for the command.
+type DeleterCmdOptions struct {
+	types.GlobalCmdOptions
+
+	LonghornNamespace  string
+	VolumeName         string
+	Force              bool          // Delete the volume even if it is protected.
+	IdleTime           time.Duration // Time since the volume was last used by a pod or bound to a PVC, below which it is protected.
+	BackupBeforeDelete bool          // Back up the volume to its backup target before deleting it.
+	DeleteBackups      bool          // Delete the backups of the volume from the backup targets.
+	Timeout            time.Duration // Maximum time to wait for the final backup, and for the volume to be deleted.
+}
+
+// Validate validates the command options.
+func (remote *Deleter) Validate() error {
+	if remote.VolumeName == "" {
+		return errors.New("Longhorn volume name is required")
+	}
+	if remote.BackupBeforeDelete && remote.DeleteBackups {
+		return errors.Errorf("--%s cannot be used with --%s", consts.CmdOptBackupBeforeDelete, consts.CmdOptDeleteBackups)
+	}
+	if remote.IdleTime < 0 {
+		return errors.Errorf("idle time (--%s) cannot be negative", consts.CmdOptIdleTime)
+	}
+	if remote.Timeout <= 0 {
+		return errors.Errorf("timeout (--%s) must be positive", consts.CmdOptTimeout)
+	}
+	return nil
+}
+
+// Init initializes the Deleter. It fails when the volume is protected from deletion, unless
+// forced.
+func (remote *Deleter) Init() error {
+	longhornClient, err := kubeutils.NewLonghornClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.longhornClient = longhornClient
+
+	remote.volume, err = remote.longhornClient.LonghornV1beta2().Volumes(remote.LonghornNamespace).Get(context.Background(), remote.VolumeName, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to get volume %v", remote.VolumeName)
+	}
+
+	remote.protectionReasons = getDeleteProtectionReasons(remote.volume, time.Now(), remote.IdleTime)
+	if len(remote.protectionReasons) > 0 && !remote.Force {
+		return errors.Errorf("volume %v is protected from deletion, it is %v. Use --%v to delete it anyway",
+			remote.VolumeName, strings.Join(remote.protectionReasons, ", and "), consts.CmdOptForce)
+	}
+
+	remote.backupName = fmt.Sprintf("%sfinal-%s-%s", consts.SnapshotNamePrefix, time.Now().UTC().Format("20060102-150405"), remote.VolumeName)
+	return nil
+}
+
+// ProtectionReasons returns the reasons the volume is protected from deletion, overridden by
+// --force.
+func (remote *Deleter) ProtectionReasons() []string {
+	return remote.protectionReasons
+}
+
+// Run backs up the volume when requested, deletes its backups when requested, then deletes the
+// volume and waits for it to be gone. The snapshots of the volume are deleted with its replicas.
+func (remote *Deleter) Run() error {
+	ctx, cancel := context.WithTimeout(context.Background(), remote.Timeout)
+	defer cancel()
+
+	if remote.BackupBeforeDelete {
+		if err := remote.backup(ctx); err != nil {
+			return errors.Wrapf(err, "failed to back up volume %v before deleting it, it is not deleted", remote.VolumeName)
+		}
+	}
+
+	if remote.DeleteBackups {
+		if err := remote.deleteBackupVolumes(ctx); err != nil {
+			return err
+		}
+	}
+
+	logrus.Infof("Deleting volume %v", remote.VolumeName)
+	volumes := remote.longhornClient.LonghornV1beta2().Volumes(remote.LonghornNamespace)
+	if err := volumes.Delete(ctx, remote.VolumeName, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to delete volume %v", remote.VolumeName)
+	}
+
+	err := wait.PollUntilContextCancel(ctx, 2*time.Second, true, func(ctx context.Context) (bool, error) {
+		_, err := volumes.Get(ctx, remote.VolumeName, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	})
+	return errors.Wrapf(err, "failed waiting for volume %v to be deleted", remote.VolumeName)
+}
+
+// BackupName returns the name of the backup created by --backup-before-delete.
+func (remote *Deleter) BackupName() string {
+	return remote.backupName
+}
+
+// Cleanup does nothing, since the Deleter does not create any resource but the final backup.
+func (remote *Deleter) Cleanup() error {
+	return nil
+}
+
+// backup takes the final snapshot of the volume, and backs it up to the backup target of the volume.
+func (remote *Deleter) backup(ctx context.Context) error {
+	backupTargetName := remote.volume.Spec.BackupTargetName
+	if backupTargetName == "" {
+		backupTargetName = lhmgrtypes.DefaultBackupTargetName
+	}
+
+	_, err := kubeutils.CreateBackup(ctx, remote.longhornClient, remote.LonghornNamespace, remote.VolumeName, backupTargetName, remote.backupName)
+	return err
+}
+
+// deleteBackupVolumes deletes the backup volumes of the volume on all the backup targets, which
+// makes Longhorn delete their backups from the backup targets.
+func (remote *Deleter) deleteBackupVolumes(ctx context.Context) error {
+	backupVolumes := remote.longhornClient.LonghornV1beta2().BackupVolumes(remote.LonghornNamespace)
+	list, err := backupVolumes.List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(lhmgrtypes.GetBackupVolumeLabels(remote.VolumeName)).String(),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to list backup volumes of volume %v", remote.VolumeName)
+	}
+
+	for _, backupVolume := range list.Items {
+		logrus.WithFields(logrus.Fields{"volume": remote.VolumeName, "backupVolume": backupVolume.Name}).Info("Deleting backups")
+		if err := backupVolumes.Delete(ctx, backupVolume.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to delete backup volume %v", backupVolume.Name)
+		}
+	}
+	return nil
+}
+
+// getDeleteProtectionReasons returns the reasons the volume is protected from deletion at the
+// time: it is bound to a PVC, attached, or was used by a pod or bound to a PVC within the idle
+// time.
+func getDeleteProtectionReasons(volume *longhorn.Volume, now time.Time, idleTime time.Duration) []string {
+	var reasons []string
+
+	kubernetesStatus := volume.Status.KubernetesStatus
+	if kubernetesStatus.PVCName != "" && kubernetesStatus.LastPVCRefAt == "" {
+		reasons = append(reasons, fmt.Sprintf("bound to PVC %v/%v", kubernetesStatus.Namespace, kubernetesStatus.PVCName))
+	}
+
+	switch volume.Status.State {
+	case longhorn.VolumeStateAttached, longhorn.VolumeStateAttaching:
+		reasons = append(reasons, fmt.Sprintf("%v to node %v", volume.Status.State, volume.Status.CurrentNodeID))
+	}
+
+	if len(kubernetesStatus.WorkloadsStatus) > 0 && kubernetesStatus.LastPodRefAt == "" {
+		pods := make([]string, 0, len(kubernetesStatus.WorkloadsStatus))
+		for _, workload := range kubernetesStatus.WorkloadsStatus {
+			pods = append(pods, workload.PodName)
+		}
+		reasons = append(reasons, fmt.Sprintf("used by pods %v", strings.Join(pods, ", ")))
+	}
+
+	for _, activity := range []struct {
+		description string
+		at          string
+	}{
+		{"used by a pod", kubernetesStatus.LastPodRefAt},
+		{"bound to a PVC", kubernetesStatus.LastPVCRefAt},
+	} {
+		at, err := time.Parse(time.RFC3339, activity.at)
+		if err != nil {
+			continue
+		}
+		if now.Sub(at) < idleTime {
+			reasons = append(reasons, fmt.Sprintf("%v at %v, within the idle time of %v", activity.description, activity.at, idleTime))
+		}
+	}
+
+	return reasons
+}
@@ -0,0 +1,85 @@
+package volume
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	longhorn "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+)
+
+func TestGetDeleteProtectionReasons(t *testing.T) {
+	now := time.Date(2024, 7, 16, 12, 0, 0, 0, time.UTC)
+	detached := longhorn.VolumeStatus{State: longhorn.VolumeStateDetached}
+
+	tests := map[string]struct {
+		status   longhorn.VolumeStatus
+		expected []string
+	}{
+		"unused": {
+			status: detached,
+		},
+		"released long ago": {
+			status: longhorn.VolumeStatus{
+				State: longhorn.VolumeStateDetached,
+				KubernetesStatus: longhorn.KubernetesStatus{
+					Namespace:    "default",
+					PVCName:      "data",
+					LastPVCRefAt: "2024-07-01T12:00:00Z",
+					LastPodRefAt: "2024-07-01T11:00:00Z",
+				},
+			},
+		},
+		"bound and used": {
+			status: longhorn.VolumeStatus{
+				State:         longhorn.VolumeStateAttached,
+				CurrentNodeID: "node-1",
+				KubernetesStatus: longhorn.KubernetesStatus{
+					Namespace:       "default",
+					PVCName:         "data",
+					WorkloadsStatus: []longhorn.WorkloadStatus{{PodName: "db-0"}, {PodName: "db-1"}},
+				},
+			},
+			expected: []string{"bound to PVC default/data", "attached to node node-1", "used by pods db-0, db-1"},
+		},
+		"used recently": {
+			status: longhorn.VolumeStatus{
+				State: longhorn.VolumeStateDetached,
+				KubernetesStatus: longhorn.KubernetesStatus{
+					Namespace:    "default",
+					PVCName:      "data",
+					LastPVCRefAt: "2024-07-16T10:00:00Z",
+					LastPodRefAt: "2024-07-15T10:00:00Z",
+				},
+			},
+			expected: []string{"bound to a PVC at 2024-07-16T10:00:00Z, within the idle time of 24h0m0s"},
+		},
+	}
+
+	for name, test := range tests {
+		reasons := getDeleteProtectionReasons(&longhorn.Volume{Status: test.status}, now, 24*time.Hour)
+		if !reflect.DeepEqual(reasons, test.expected) {
+			t.Errorf("%v: expected reasons %q, got %q", name, test.expected, reasons)
+		}
+	}
+}
+
+func TestDeleterValidate(t *testing.T) {
+	tests := map[string]struct {
+		options       DeleterCmdOptions
+		expectedError bool
+	}{
+		"valid":                     {options: DeleterCmdOptions{VolumeName: "vol", Timeout: time.Hour}},
+		"no volume":                 {options: DeleterCmdOptions{Timeout: time.Hour}, expectedError: true},
+		"backup and delete backups": {options: DeleterCmdOptions{VolumeName: "vol", Timeout: time.Hour, BackupBeforeDelete: true, DeleteBackups: true}, expectedError: true},
+		"negative idle time":        {options: DeleterCmdOptions{VolumeName: "vol", Timeout: time.Hour, IdleTime: -time.Hour}, expectedError: true},
+		"no timeout":                {options: DeleterCmdOptions{VolumeName: "vol"}, expectedError: true},
+	}
+
+	for name, test := range tests {
+		deleter := &Deleter{DeleterCmdOptions: test.options}
+		if err := deleter.Validate(); (err != nil) != test.expectedError {
+			t.Errorf("%v: expected error %v, got %v", name, test.expectedError, err)
+		}
+	}
+}
@@ -0,0 +1,453 @@
+package volume
+
+import (
+	"bufio"
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	kubeclient "k8s.io/client-go/kubernetes"
+	"k8s.io/utils/ptr"
+
+	longhorn "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+	lhclient "github.com/longhorn/longhorn-manager/k8s/pkg/client/clientset/versioned"
+	lhmgrtypes "github.com/longhorn/longhorn-manager/types"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// waitTimeout is the maximum time to wait for the maintenance attachment of the volume, and for
+// the pod to start.
+const waitTimeout = 5 * time.Minute
+
+// Rekeyer provide functions for rotating the encryption key of an encrypted volume.
+type Rekeyer struct {
+	RekeyerCmdOptions
+
+	kubeClient     *kubeclient.Clientset
+	longhornClient *lhclient.Clientset
+
+	appName   string // Name of the pod, of the secret holding the keys, and ID of the attachment ticket.
+	namespace string
+	ticketID  string
+	nodeName  string
+
+	secretNamespace string // Secret of the PV holding the current key.
+	secretName      string
+	currentKey      []byte
+	newKey          []byte
+}
+
+// RekeyerCmdOptions holds the options for the command.
+type RekeyerCmdOptions struct {
+	types.GlobalCmdOptions
+
+	LonghornNamespace string
+	VolumeName        string
+	NewSecret         string // Secret holding the new key, as namespace/name.
+	NodeID            string // Node the volume is attached to during the rotation.
+	KeyOnly           bool   // Only replace the passphrase, without re-encrypting the data.
+}
+
+// Validate validates the command options.
+func (remote *Rekeyer) Validate() error {
+	if remote.VolumeName == "" {
+		return errors.New("Longhorn volume name is required")
+	}
+
+	if _, _, err := parseSecretReference(remote.NewSecret); err != nil {
+		return errors.Wrapf(err, "invalid --%s", consts.CmdOptNewSecret)
+	}
+
+	return nil
+}
+
+// Init initializes the Rekeyer. It ensures that the volume is encrypted, detached, and the only
+// user of the secret of its PV, and reads the new key.
+func (remote *Rekeyer) Init() error {
+	kubeClient, err := kubeutils.NewKubeClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.kubeClient = kubeClient
+
+	longhornClient, err := kubeutils.NewLonghornClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.longhornClient = longhornClient
+
+	remote.namespace = remote.Namespace
+	if remote.namespace == "" {
+		remote.namespace = consts.LonghornNamespace
+	}
+	remote.appName = consts.AppNameVolumeRekeyer
+	remote.ticketID = longhorn.GetAttachmentTicketID(longhorn.AttacherTypeLonghornAPI, remote.appName)
+
+	ctx := context.Background()
+
+	volume, err := remote.longhornClient.LonghornV1beta2().Volumes(remote.LonghornNamespace).Get(ctx, remote.VolumeName, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to get volume %v", remote.VolumeName)
+	}
+	if !volume.Spec.Encrypted {
+		return errors.Errorf("volume %v is not encrypted", remote.VolumeName)
+	}
+	if volume.Spec.Frontend != longhorn.VolumeFrontendBlockDev {
+		return errors.Errorf("volume %v has frontend %v, only the %v frontend is supported", remote.VolumeName, volume.Spec.Frontend, longhorn.VolumeFrontendBlockDev)
+	}
+	if volume.Status.State != longhorn.VolumeStateDetached {
+		return errors.Errorf("volume %v is %v, it must be detached to be rekeyed", remote.VolumeName, volume.Status.State)
+	}
+	if volume.Status.KubernetesStatus.PVName == "" {
+		return errors.Errorf("volume %v has no PV, its key cannot be found", remote.VolumeName)
+	}
+
+	pv, err := remote.kubeClient.CoreV1().PersistentVolumes().Get(ctx, volume.Status.KubernetesStatus.PVName, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to get PV %v", volume.Status.KubernetesStatus.PVName)
+	}
+	secretRef := getVolumeSecretReference(pv)
+	if secretRef == nil {
+		return errors.Errorf("PV %v does not reference the secret of the key", pv.Name)
+	}
+	remote.secretNamespace, remote.secretName = secretRef.Namespace, secretRef.Name
+
+	newSecretNamespace, newSecretName, _ := parseSecretReference(remote.NewSecret)
+	if newSecretNamespace == remote.secretNamespace && newSecretName == remote.secretName {
+		return errors.Errorf("secret %v is already the secret of PV %v", remote.NewSecret, pv.Name)
+	}
+
+	pvs, err := remote.kubeClient.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return errors.Wrap(err, "failed to list PVs")
+	}
+	for i := range pvs.Items {
+		ref := getVolumeSecretReference(&pvs.Items[i])
+		if pvs.Items[i].Name != pv.Name && ref != nil && ref.Namespace == remote.secretNamespace && ref.Name == remote.secretName {
+			return errors.Errorf("secret %v/%v is shared with PV %v, rotating the key of a shared secret is not supported", remote.secretNamespace, remote.secretName, pvs.Items[i].Name)
+		}
+	}
+
+	remote.currentKey, err = remote.getKey(ctx, remote.secretNamespace, remote.secretName)
+	if err != nil {
+		return err
+	}
+	remote.newKey, err = remote.getKey(ctx, newSecretNamespace, newSecretName)
+	if err != nil {
+		return err
+	}
+	if string(remote.currentKey) == string(remote.newKey) && remote.KeyOnly {
+		return errors.Errorf("secret %v holds the current key of volume %v", remote.NewSecret, remote.VolumeName)
+	}
+
+	remote.nodeName = remote.NodeID
+	if remote.nodeName == "" {
+		remote.nodeName, err = remote.getReplicaNode(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Run attaches the volume to the node for maintenance, and runs the rotation in a pod on the node
+// while passing its progress to the handler. The secret of the PV is then updated with the new
+// key. An interrupted rotation is resumed by running it again.
+func (remote *Rekeyer) Run(ctx context.Context, handler func(line string)) error {
+	if _, err := kubeutils.CreateNamespace(remote.kubeClient, remote.namespace); err != nil {
+		return err
+	}
+
+	keySecret := remote.newSecret()
+	kubeutils.SetRunMetadata(remote.kubeClient, keySecret)
+	if _, err := remote.kubeClient.CoreV1().Secrets(remote.namespace).Create(ctx, keySecret, metav1.CreateOptions{}); err != nil {
+		return errors.Wrapf(err, "failed to create secret %v", remote.appName)
+	}
+
+	logrus.Infof("Attaching volume %v to node %v for maintenance", remote.VolumeName, remote.nodeName)
+	if err := remote.attach(ctx); err != nil {
+		return err
+	}
+
+	pod, err := remote.newPod()
+	if err != nil {
+		return err
+	}
+	kubeutils.SetRunMetadata(remote.kubeClient, pod)
+	kubeutils.LogManifest(pod)
+	if _, err := remote.kubeClient.CoreV1().Pods(remote.namespace).Create(ctx, pod, metav1.CreateOptions{}); err != nil {
+		return errors.Wrapf(err, "failed to create pod %v", remote.appName)
+	}
+
+	if err := remote.streamLog(ctx, handler); err != nil {
+		return err
+	}
+	if err := kubeutils.WaitForPodCompleted(ctx, remote.kubeClient, remote.namespace, remote.appName); err != nil {
+		return errors.Wrap(err, "failed to rotate the key of the volume")
+	}
+
+	logrus.Infof("Updating secret %v/%v with the new key", remote.secretNamespace, remote.secretName)
+	secret, err := remote.kubeClient.CoreV1().Secrets(remote.secretNamespace).Get(ctx, remote.secretName, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to get secret %v/%v", remote.secretNamespace, remote.secretName)
+	}
+	secret.Data[lhmgrtypes.CryptoKeyValue] = remote.newKey
+	delete(secret.StringData, lhmgrtypes.CryptoKeyValue)
+	if _, err := remote.kubeClient.CoreV1().Secrets(remote.secretNamespace).Update(ctx, secret, metav1.UpdateOptions{}); err != nil {
+		return errors.Wrapf(err, "failed to update secret %v/%v, run the command again to update it", remote.secretNamespace, remote.secretName)
+	}
+
+	return nil
+}
+
+// Cleanup deletes the pod and the secret created for the rotation, and removes the maintenance
+// attachment ticket, so the volume is detached.
+func (remote *Rekeyer) Cleanup() error {
+	ctx := context.Background()
+
+	if err := kubeutils.DeletePod(ctx, remote.kubeClient, remote.namespace, remote.appName); err != nil {
+		return err
+	}
+
+	err := remote.kubeClient.CoreV1().Secrets(remote.namespace).Delete(ctx, remote.appName, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to delete secret %v", remote.appName)
+	}
+
+	volumeAttachments := remote.longhornClient.LonghornV1beta2().VolumeAttachments(remote.LonghornNamespace)
+	volumeAttachment, err := volumeAttachments.Get(ctx, remote.VolumeName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to get volume attachment %v", remote.VolumeName)
+	}
+	if _, ok := volumeAttachment.Spec.AttachmentTickets[remote.ticketID]; !ok {
+		return nil
+	}
+	delete(volumeAttachment.Spec.AttachmentTickets, remote.ticketID)
+	if _, err := volumeAttachments.Update(ctx, volumeAttachment, metav1.UpdateOptions{}); err != nil {
+		return errors.Wrapf(err, "failed to remove attachment ticket %v of volume %v", remote.ticketID, remote.VolumeName)
+	}
+	return nil
+}
+
+// attach adds the maintenance attachment ticket of the volume, and waits for it to be satisfied.
+func (remote *Rekeyer) attach(ctx context.Context) error {
+	volumeAttachments := remote.longhornClient.LonghornV1beta2().VolumeAttachments(remote.LonghornNamespace)
+	volumeAttachment, err := volumeAttachments.Get(ctx, remote.VolumeName, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to get volume attachment %v", remote.VolumeName)
+	}
+
+	for ticketID := range volumeAttachment.Spec.AttachmentTickets {
+		if ticketID != remote.ticketID {
+			return errors.Errorf("volume %v is requested by attachment ticket %v, it must not be used during the rotation", remote.VolumeName, ticketID)
+		}
+	}
+
+	if volumeAttachment.Spec.AttachmentTickets == nil {
+		volumeAttachment.Spec.AttachmentTickets = map[string]*longhorn.AttachmentTicket{}
+	}
+	volumeAttachment.Spec.AttachmentTickets[remote.ticketID] = &longhorn.AttachmentTicket{
+		ID:     remote.ticketID,
+		Type:   longhorn.AttacherTypeLonghornAPI,
+		NodeID: remote.nodeName,
+		Parameters: map[string]string{
+			longhorn.AttachmentParameterDisableFrontend: longhorn.FalseValue,
+		},
+	}
+	if _, err := volumeAttachments.Update(ctx, volumeAttachment, metav1.UpdateOptions{}); err != nil {
+		return errors.Wrapf(err, "failed to add attachment ticket %v to volume %v", remote.ticketID, remote.VolumeName)
+	}
+
+	attachCtx, cancel := context.WithTimeout(ctx, waitTimeout)
+	defer cancel()
+
+	err = wait.PollUntilContextCancel(attachCtx, 2*time.Second, true, func(ctx context.Context) (bool, error) {
+		volumeAttachment, err := volumeAttachments.Get(ctx, remote.VolumeName, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		return longhorn.IsAttachmentTicketSatisfied(remote.ticketID, volumeAttachment), nil
+	})
+	return errors.Wrapf(err, "failed waiting for volume %v to be attached to node %v", remote.VolumeName, remote.nodeName)
+}
+
+// streamLog waits for the container of the pod to start, and passes each line of its log to the
+// handler until it exits.
+func (remote *Rekeyer) streamLog(ctx context.Context, handler func(line string)) error {
+	startCtx, cancel := context.WithTimeout(ctx, waitTimeout)
+	defer cancel()
+
+	err := wait.PollUntilContextCancel(startCtx, 2*time.Second, true, func(ctx context.Context) (bool, error) {
+		pod, err := remote.kubeClient.CoreV1().Pods(remote.namespace).Get(ctx, remote.appName, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		return pod.Status.Phase != corev1.PodPending, nil
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed waiting for pod %v to start", remote.appName)
+	}
+
+	stream, err := remote.kubeClient.CoreV1().Pods(remote.namespace).GetLogs(remote.appName, &corev1.PodLogOptions{
+		Container: consts.ContainerName,
+		Follow:    true,
+	}).Stream(ctx)
+	if err != nil {
+		return errors.Wrapf(err, "failed to stream the log of pod %v", remote.appName)
+	}
+	defer stream.Close()
+
+	scanner := bufio.NewScanner(stream)
+	for scanner.Scan() {
+		handler(scanner.Text())
+	}
+	return scanner.Err()
+}
+
+// getKey returns the key of the secret.
+func (remote *Rekeyer) getKey(ctx context.Context, namespace, name string) ([]byte, error) {
+	secret, err := remote.kubeClient.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get secret %v/%v", namespace, name)
+	}
+
+	key := secret.Data[lhmgrtypes.CryptoKeyValue]
+	if len(key) == 0 {
+		return nil, errors.Errorf("secret %v/%v has no %v", namespace, name, lhmgrtypes.CryptoKeyValue)
+	}
+	if provider, ok := secret.Data[lhmgrtypes.CryptoKeyProvider]; ok && string(provider) != "secret" {
+		return nil, errors.Errorf("secret %v/%v has key provider %q, only the secret provider is supported", namespace, name, provider)
+	}
+	if strings.ContainsAny(string(key), "\n\r") {
+		return nil, errors.Errorf("%v of secret %v/%v contains a line break", lhmgrtypes.CryptoKeyValue, namespace, name)
+	}
+	return key, nil
+}
+
+// getReplicaNode returns the node of a replica of the volume that is not failed, so the rotation
+// reads and writes the data locally.
+func (remote *Rekeyer) getReplicaNode(ctx context.Context) (string, error) {
+	replicas, err := remote.longhornClient.LonghornV1beta2().Replicas(remote.LonghornNamespace).List(ctx, metav1.ListOptions{
+		LabelSelector: metav1.FormatLabelSelector(&metav1.LabelSelector{MatchLabels: lhmgrtypes.GetVolumeLabels(remote.VolumeName)}),
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to list replicas of volume %v", remote.VolumeName)
+	}
+
+	for _, replica := range replicas.Items {
+		if replica.Spec.NodeID != "" && replica.Spec.FailedAt == "" {
+			return replica.Spec.NodeID, nil
+		}
+	}
+	return "", errors.Errorf("volume %v has no healthy replica, specify the node with --%v", remote.VolumeName, consts.CmdOptNode)
+}
+
+// newSecret prepares the secret passing the current and new keys to the pod, in the namespace of the pod.
+func (remote *Rekeyer) newSecret() *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      remote.appName,
+			Namespace: remote.namespace,
+			Labels: map[string]string{
+				"app":                 remote.appName,
+				consts.LabelManagedBy: consts.LabelValueManagedBy,
+			},
+		},
+		Data: map[string][]byte{
+			consts.EnvCryptoKeyValue:    remote.currentKey,
+			consts.EnvNewCryptoKeyValue: remote.newKey,
+		},
+	}
+}
+
+// newPod prepares the pod rotating the key on the node. The pod shares the PID namespace of the
+// host, so cryptsetup runs in the host mount namespace where the device of the volume is.
+func (remote *Rekeyer) newPod() (*corev1.Pod, error) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      remote.appName,
+			Namespace: remote.namespace,
+			Labels: map[string]string{
+				"app":                 remote.appName,
+				consts.LabelManagedBy: consts.LabelValueManagedBy,
+			},
+		},
+		Spec: corev1.PodSpec{
+			HostPID:       true,
+			RestartPolicy: corev1.RestartPolicyNever,
+			Containers: []corev1.Container{
+				{
+					Name:    consts.ContainerName,
+					Image:   remote.Image,
+					Command: []string{consts.CmdLonghornctlLocal, consts.SubCmdVolume, consts.SubCmdRekey},
+					Env: []corev1.EnvVar{
+						{
+							Name:  consts.EnvLogLevel,
+							Value: remote.LogLevel,
+						},
+						{
+							Name:  consts.EnvLonghornVolumeName,
+							Value: remote.VolumeName,
+						},
+						{
+							Name:  consts.EnvKeyOnly,
+							Value: strconv.FormatBool(remote.KeyOnly),
+						},
+					},
+					EnvFrom: []corev1.EnvFromSource{
+						{
+							SecretRef: &corev1.SecretEnvSource{
+								LocalObjectReference: corev1.LocalObjectReference{Name: remote.appName},
+							},
+						},
+					},
+					SecurityContext: kubeutils.NewSecurityContext(remote.Privileged, kubeutils.CapabilitiesHostNamespaces),
+				},
+			},
+			TerminationGracePeriodSeconds: ptr.To(int64(0)),
+		},
+	}
+	kubeutils.SetNodeNameAffinity(&pod.Spec, []string{remote.nodeName})
+
+	if err := kubeutils.SetPodOptions(&pod.Spec, &remote.GlobalCmdOptions); err != nil {
+		return nil, err
+	}
+	return pod, nil
+}
+
+// parseSecretReference parses a secret reference in the namespace/name format.
+func parseSecretReference(ref string) (string, string, error) {
+	namespace, name, found := strings.Cut(ref, "/")
+	if !found || namespace == "" || name == "" || strings.Contains(name, "/") {
+		return "", "", errors.Errorf("secret %q is not in the namespace/name format", ref)
+	}
+	return namespace, name, nil
+}
+
+// getVolumeSecretReference returns the secret the CSI driver reads the key of the PV from when
+// staging it, or when publishing it for the PVs without a stage secret.
+func getVolumeSecretReference(pv *corev1.PersistentVolume) *corev1.SecretReference {
+	if pv.Spec.CSI == nil || pv.Spec.CSI.Driver != lhmgrtypes.LonghornDriverName {
+		return nil
+	}
+	if pv.Spec.CSI.NodeStageSecretRef != nil {
+		return pv.Spec.CSI.NodeStageSecretRef
+	}
+	return pv.Spec.CSI.NodePublishSecretRef
+}
@@ -0,0 +1,78 @@
+package volume
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestParseSecretReference(t *testing.T) {
+	tests := map[string]struct {
+		ref               string
+		expectedNamespace string
+		expectedName      string
+		expectedError     bool
+	}{
+		"namespace and name": {
+			ref:               "default/crypto",
+			expectedNamespace: "default",
+			expectedName:      "crypto",
+		},
+		"name only":       {ref: "crypto", expectedError: true},
+		"empty namespace": {ref: "/crypto", expectedError: true},
+		"empty name":      {ref: "default/", expectedError: true},
+		"extra segment":   {ref: "default/crypto/key", expectedError: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			namespace, secretName, err := parseSecretReference(test.ref)
+			if test.expectedError {
+				if err == nil {
+					t.Fatalf("expected error for %q", test.ref)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if namespace != test.expectedNamespace || secretName != test.expectedName {
+				t.Errorf("expected %v/%v, got %v/%v", test.expectedNamespace, test.expectedName, namespace, secretName)
+			}
+		})
+	}
+}
+
+func TestGetVolumeSecretReference(t *testing.T) {
+	stageRef := &corev1.SecretReference{Namespace: "default", Name: "stage"}
+	publishRef := &corev1.SecretReference{Namespace: "default", Name: "publish"}
+
+	tests := map[string]struct {
+		csi      *corev1.CSIPersistentVolumeSource
+		expected *corev1.SecretReference
+	}{
+		"stage secret": {
+			csi:      &corev1.CSIPersistentVolumeSource{Driver: "driver.longhorn.io", NodeStageSecretRef: stageRef, NodePublishSecretRef: publishRef},
+			expected: stageRef,
+		},
+		"publish secret": {
+			csi:      &corev1.CSIPersistentVolumeSource{Driver: "driver.longhorn.io", NodePublishSecretRef: publishRef},
+			expected: publishRef,
+		},
+		"other driver": {
+			csi: &corev1.CSIPersistentVolumeSource{Driver: "ebs.csi.aws.com", NodeStageSecretRef: stageRef},
+		},
+		"not CSI": {},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			pv := &corev1.PersistentVolume{}
+			pv.Spec.CSI = test.csi
+
+			if ref := getVolumeSecretReference(pv); ref != test.expected {
+				t.Errorf("expected %v, got %v", test.expected, ref)
+			}
+		})
+	}
+}
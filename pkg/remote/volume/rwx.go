@@ -0,0 +1,469 @@
+package volume
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/utils/ptr"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeclient "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	commonkube "github.com/longhorn/go-common-libs/kubernetes"
+	longhorn "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+	lhclient "github.com/longhorn/longhorn-manager/k8s/pkg/client/clientset/versioned"
+	lhmgrtypes "github.com/longhorn/longhorn-manager/types"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// shareManagerExportDirectory is the directory where the share manager mounts the volumes it exports.
+const shareManagerExportDirectory = "/export"
+
+// RwxChecker provide functions for diagnosing the share manager and the NFS client mounts of a
+// ReadWriteMany volume.
+type RwxChecker struct {
+	RwxCheckerCmdOptions
+
+	kubeClient     *kubeclient.Clientset
+	longhornClient *lhclient.Clientset
+	restConfig     *rest.Config
+
+	namespace string
+	appName   string // App name of the DaemonSet.
+}
+
+// RwxCheckerCmdOptions holds the options for the command.
+type RwxCheckerCmdOptions struct {
+	types.GlobalCmdOptions
+
+	LonghornNamespace string
+	VolumeName        string
+}
+
+// Validate validates the command options.
+func (remote *RwxChecker) Validate() error {
+	if remote.VolumeName == "" {
+		return errors.Errorf("Longhorn volume name (--%s) is required", consts.CmdOptVolume)
+	}
+
+	return nil
+}
+
+// Init initializes the RwxChecker.
+func (remote *RwxChecker) Init() error {
+	kubeClient, err := kubeutils.NewKubeClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.kubeClient = kubeClient
+
+	longhornClient, err := kubeutils.NewLonghornClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.longhornClient = longhornClient
+
+	restConfig, err := kubeutils.NewRestConfig("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.restConfig = restConfig
+
+	remote.namespace = remote.Namespace
+	if remote.namespace == "" {
+		remote.namespace = consts.LonghornNamespace
+	}
+	remote.appName = consts.AppNameRwxChecker
+
+	return nil
+}
+
+// Collect checks the share manager of the volume, then creates a DaemonSet on the nodes running
+// the workload pods of the volume to check their NFS mounts, and returns the result keyed by the
+// kind and name of the checked object.
+func (remote *RwxChecker) Collect() (map[string]*types.LogCollection, error) {
+	ctx := context.Background()
+
+	volume, err := remote.longhornClient.LonghornV1beta2().Volumes(remote.LonghornNamespace).Get(ctx, remote.VolumeName, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get volume %v", remote.VolumeName)
+	}
+	if volume.Spec.AccessMode != longhorn.AccessModeReadWriteMany {
+		return nil, errors.Errorf("volume %v has access mode %v, only ReadWriteMany volumes are served by a share manager", remote.VolumeName, volume.Spec.AccessMode)
+	}
+
+	collections := map[string]*types.LogCollection{}
+
+	shareManagerLog := &types.LogCollection{}
+	collections["ShareManager/"+remote.VolumeName] = shareManagerLog
+	endpoint := remote.checkShareManager(ctx, shareManagerLog)
+
+	nodeNames, err := remote.getWorkloadNodeNames(ctx, volume)
+	if err != nil {
+		return nil, err
+	}
+	if len(nodeNames) == 0 {
+		shareManagerLog.Info = append(shareManagerLog.Info, "No running workload pod uses the volume, the client mounts are not checked")
+		return collections, nil
+	}
+
+	nodeCollections, err := remote.collectNodeCollections(endpoint, nodeNames)
+	if err != nil {
+		return nil, err
+	}
+	for _, nodeName := range nodeNames {
+		log, ok := nodeCollections[nodeName]
+		if !ok {
+			log = &types.LogCollection{Error: []string{"No result was collected from the node"}}
+		}
+		collections["Node/"+nodeName] = log
+	}
+
+	return collections, nil
+}
+
+// Cleanup deletes the DaemonSet created for checking the NFS mounts.
+func (remote *RwxChecker) Cleanup() error {
+	return commonkube.DeleteDaemonSet(remote.kubeClient, remote.namespace, remote.appName)
+}
+
+// checkShareManager checks the share manager, its pod, service and NFS export, and returns the NFS
+// endpoint of the volume, or an empty string when the share manager has none.
+func (remote *RwxChecker) checkShareManager(ctx context.Context, log *types.LogCollection) string {
+	shareManager, err := remote.longhornClient.LonghornV1beta2().ShareManagers(remote.LonghornNamespace).Get(ctx, remote.VolumeName, metav1.GetOptions{})
+	if err != nil {
+		log.Error = append(log.Error, fmt.Sprintf("Failed to get share manager: %v", err))
+		return ""
+	}
+
+	switch {
+	case shareManager.Status.State != longhorn.ShareManagerStateRunning:
+		log.Error = append(log.Error, fmt.Sprintf("Share manager is %v instead of running", shareManager.Status.State))
+	case shareManager.Status.Endpoint == "":
+		log.Error = append(log.Error, "Share manager is running without NFS endpoint")
+	default:
+		log.Info = append(log.Info, fmt.Sprintf("Share manager is running with endpoint %v on node %v", shareManager.Status.Endpoint, shareManager.Status.OwnerID))
+	}
+
+	podName := lhmgrtypes.GetShareManagerPodNameFromShareManagerName(shareManager.Name)
+	pod, err := remote.kubeClient.CoreV1().Pods(remote.LonghornNamespace).Get(ctx, podName, metav1.GetOptions{})
+	if err != nil {
+		log.Error = append(log.Error, fmt.Sprintf("Failed to get share manager pod %v: %v", podName, err))
+		return shareManager.Status.Endpoint
+	}
+	if !checkShareManagerPod(log, pod) {
+		return shareManager.Status.Endpoint
+	}
+
+	remote.checkShareManagerService(ctx, log, shareManager, pod)
+
+	stdout, stderr, err := kubeutils.ExecPodContainer(ctx, remote.restConfig, remote.kubeClient, pod.Namespace, pod.Name, pod.Spec.Containers[0].Name, []string{"cat", "/proc/mounts"})
+	if err != nil {
+		log.Error = append(log.Error, fmt.Sprintf("Failed to read mounts of share manager pod %v: %v %v", podName, err, strings.TrimSpace(stderr)))
+		return shareManager.Status.Endpoint
+	}
+	checkShareManagerExport(log, stdout, remote.VolumeName)
+
+	return shareManager.Status.Endpoint
+}
+
+// checkShareManagerService checks the service of the share manager points to its pod, and matches
+// the NFS endpoint.
+func (remote *RwxChecker) checkShareManagerService(ctx context.Context, log *types.LogCollection, shareManager *longhorn.ShareManager, pod *corev1.Pod) {
+	service, err := remote.kubeClient.CoreV1().Services(remote.LonghornNamespace).Get(ctx, shareManager.Name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			log.Error = append(log.Error, fmt.Sprintf("Service %v/%v of the share manager does not exist", remote.LonghornNamespace, shareManager.Name))
+			return
+		}
+		log.Error = append(log.Error, fmt.Sprintf("Failed to get service of the share manager: %v", err))
+		return
+	}
+
+	if endpointURL, err := url.Parse(shareManager.Status.Endpoint); err == nil && endpointURL.Hostname() != "" {
+		host := endpointURL.Hostname()
+		if host != service.Spec.ClusterIP && !strings.HasPrefix(host, service.Name+".") {
+			log.Warn = append(log.Warn, fmt.Sprintf("Endpoint host %v does not match service %v/%v with cluster IP %v", host, service.Namespace, service.Name, service.Spec.ClusterIP))
+		}
+	}
+
+	endpointSlices, err := remote.kubeClient.DiscoveryV1().EndpointSlices(service.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: discoveryv1.LabelServiceName + "=" + service.Name,
+	})
+	if err != nil {
+		log.Error = append(log.Error, fmt.Sprintf("Failed to list endpoints of service %v/%v: %v", service.Namespace, service.Name, err))
+		return
+	}
+
+	for _, endpointSlice := range endpointSlices.Items {
+		for _, endpoint := range endpointSlice.Endpoints {
+			for _, address := range endpoint.Addresses {
+				if address == pod.Status.PodIP {
+					log.Info = append(log.Info, fmt.Sprintf("Service %v/%v routes to share manager pod IP %v", service.Namespace, service.Name, address))
+					return
+				}
+			}
+		}
+	}
+	log.Error = append(log.Error, fmt.Sprintf("Service %v/%v has no endpoint for share manager pod IP %v", service.Namespace, service.Name, pod.Status.PodIP))
+}
+
+// getWorkloadNodeNames returns the sorted names of the nodes running the workload pods of the volume.
+func (remote *RwxChecker) getWorkloadNodeNames(ctx context.Context, volume *longhorn.Volume) ([]string, error) {
+	nodeNames := map[string]struct{}{}
+	for _, workload := range volume.Status.KubernetesStatus.WorkloadsStatus {
+		if workload.PodName == "" || volume.Status.KubernetesStatus.Namespace == "" {
+			continue
+		}
+
+		pod, err := remote.kubeClient.CoreV1().Pods(volume.Status.KubernetesStatus.Namespace).Get(ctx, workload.PodName, metav1.GetOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, errors.Wrapf(err, "failed to get workload pod %v/%v", volume.Status.KubernetesStatus.Namespace, workload.PodName)
+		}
+		if pod.Spec.NodeName == "" || pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		nodeNames[pod.Spec.NodeName] = struct{}{}
+	}
+
+	result := make([]string, 0, len(nodeNames))
+	for nodeName := range nodeNames {
+		result = append(result, nodeName)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// collectNodeCollections creates the DaemonSet checking the NFS mounts on the nodes, waits for it
+// to complete, and returns the result of each node keyed by the node name.
+func (remote *RwxChecker) collectNodeCollections(endpoint string, nodeNames []string) (map[string]*types.LogCollection, error) {
+	if _, err := kubeutils.CreateNamespace(remote.kubeClient, remote.namespace); err != nil {
+		return nil, err
+	}
+
+	newDaemonSet := remote.newDaemonSet(endpoint)
+	if err := kubeutils.SetPodOptions(&newDaemonSet.Spec.Template.Spec, &remote.GlobalCmdOptions); err != nil {
+		return nil, err
+	}
+	kubeutils.SetNodeNameAffinity(&newDaemonSet.Spec.Template.Spec, nodeNames)
+
+	kubeutils.SetRunMetadata(remote.kubeClient, newDaemonSet)
+	kubeutils.LogManifest(newDaemonSet)
+	daemonSet, err := kubeutils.CreateDaemonSet(remote.kubeClient, newDaemonSet, remote.MinNodes)
+	if err != nil {
+		return nil, err
+	}
+
+	err = kubeutils.MonitorDaemonSetContainer(remote.kubeClient, daemonSet, consts.ContainerNameInit, kubeutils.WaitForDaemonSetContainersExit, ptr.To(consts.ContainerConditionMaxTolerationShort))
+	if err != nil {
+		return nil, err
+	}
+	err = kubeutils.MonitorDaemonSetContainer(remote.kubeClient, daemonSet, consts.ContainerNameOutput, kubeutils.WaitForDaemonSetContainersExit, ptr.To(consts.ContainerConditionMaxTolerationShort))
+	if err != nil {
+		return nil, err
+	}
+
+	podCollections, err := kubeutils.GetDaemonSetPodCollections(remote.kubeClient, daemonSet, consts.ContainerNameOutput, false, false, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	nodeCollections := map[string]*types.LogCollection{}
+	for _, collection := range podCollections.Pods {
+		var nodeCollection types.NodeCollection
+		if err := json.Unmarshal([]byte(collection.Log), &nodeCollection); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse result of node %v", collection.Node)
+		}
+		if nodeCollection.Log != nil {
+			nodeCollections[collection.Node] = nodeCollection.Log
+		}
+	}
+
+	return nodeCollections, nil
+}
+
+// newDaemonSet prepares a DaemonSet reading the NFS mounts and kernel modules from the host.
+func (remote *RwxChecker) newDaemonSet(endpoint string) *appsv1.DaemonSet {
+	outputFilePath := filepath.Join(consts.VolumeMountSharedDirectory, consts.FileNameOutputJSON)
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      remote.appName,
+			Namespace: remote.namespace,
+			Labels: map[string]string{
+				"app":                 remote.appName,
+				consts.LabelManagedBy: consts.LabelValueManagedBy,
+			},
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app": remote.appName,
+				},
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						"app":                 remote.appName,
+						consts.LabelManagedBy: consts.LabelValueManagedBy,
+					},
+				},
+				Spec: corev1.PodSpec{
+					InitContainers: []corev1.Container{
+						{
+							Name:    consts.ContainerNameInit,
+							Image:   remote.Image,
+							Command: []string{consts.CmdLonghornctlLocal, consts.SubCmdCheck, consts.SubCmdRwx},
+							Env: []corev1.EnvVar{
+								{
+									Name:  consts.EnvLogLevel,
+									Value: remote.LogLevel,
+								},
+								{
+									Name:  consts.EnvOutputFilePath,
+									Value: outputFilePath,
+								},
+								{
+									Name:  consts.EnvLonghornVolumeName,
+									Value: remote.VolumeName,
+								},
+								{
+									Name:  consts.EnvLonghornShareEndpoint,
+									Value: endpoint,
+								},
+							},
+							SecurityContext: kubeutils.NewSecurityContext(remote.Privileged),
+							VolumeMounts: []corev1.VolumeMount{
+								{
+									Name:      consts.VolumeMountHostName,
+									MountPath: consts.VolumeMountHostDirectory,
+									ReadOnly:  true,
+								},
+								{
+									Name:      consts.VolumeMountSharedName,
+									MountPath: consts.VolumeMountSharedDirectory,
+								},
+							},
+						},
+						{
+							Name:    consts.ContainerNameOutput,
+							Image:   remote.Image,
+							Command: []string{"cat", outputFilePath},
+							Env:     []corev1.EnvVar{},
+							VolumeMounts: []corev1.VolumeMount{
+								{
+									Name:      consts.VolumeMountSharedName,
+									MountPath: consts.VolumeMountSharedDirectory,
+								},
+							},
+						},
+					},
+					Containers: []corev1.Container{
+						{
+							Name:  consts.ContainerNamePause,
+							Image: consts.ImagePause,
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: consts.VolumeMountHostName,
+							VolumeSource: corev1.VolumeSource{
+								HostPath: &corev1.HostPathVolumeSource{
+									Path: "/",
+								},
+							},
+						},
+						{
+							Name: consts.VolumeMountSharedName,
+							VolumeSource: corev1.VolumeSource{
+								EmptyDir: &corev1.EmptyDirVolumeSource{},
+							},
+						},
+					},
+				},
+			},
+			UpdateStrategy: appsv1.DaemonSetUpdateStrategy{
+				Type: appsv1.RollingUpdateDaemonSetStrategyType,
+			},
+		},
+	}
+}
+
+// checkShareManagerPod checks the share manager pod is running and ready, and returns whether
+// its NFS export can be inspected.
+func checkShareManagerPod(log *types.LogCollection, pod *corev1.Pod) bool {
+	if pod.DeletionTimestamp != nil {
+		log.Error = append(log.Error, fmt.Sprintf("Share manager pod %v is being deleted", pod.Name))
+		return false
+	}
+	if pod.Status.Phase != corev1.PodRunning {
+		log.Error = append(log.Error, fmt.Sprintf("Share manager pod %v is %v instead of running", pod.Name, pod.Status.Phase))
+		return false
+	}
+
+	ready := false
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			ready = condition.Status == corev1.ConditionTrue
+		}
+	}
+	if !ready {
+		log.Error = append(log.Error, fmt.Sprintf("Share manager pod %v on node %v is not ready", pod.Name, pod.Spec.NodeName))
+	} else {
+		log.Info = append(log.Info, fmt.Sprintf("Share manager pod %v is running on node %v", pod.Name, pod.Spec.NodeName))
+	}
+
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.RestartCount == 0 {
+			continue
+		}
+		message := fmt.Sprintf("Container %v of share manager pod %v restarted %d times", status.Name, pod.Name, status.RestartCount)
+		if status.LastTerminationState.Terminated != nil {
+			message += fmt.Sprintf(", last terminated with %v (exit code %d)", status.LastTerminationState.Terminated.Reason, status.LastTerminationState.Terminated.ExitCode)
+		}
+		log.Warn = append(log.Warn, message)
+	}
+
+	return true
+}
+
+// checkShareManagerExport checks the mounts of the share manager pod, in the /proc/mounts format,
+// include the volume mounted read-write in the export directory.
+func checkShareManagerExport(log *types.LogCollection, mounts, volumeName string) {
+	exportPath := filepath.Join(shareManagerExportDirectory, volumeName)
+	for _, line := range strings.Split(mounts, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 || fields[1] != exportPath {
+			continue
+		}
+
+		for _, option := range strings.Split(fields[3], ",") {
+			if option == "ro" {
+				log.Error = append(log.Error, fmt.Sprintf("Export %v is mounted read-only from %v, the filesystem may have been remounted after an error", exportPath, fields[0]))
+				return
+			}
+		}
+		log.Info = append(log.Info, fmt.Sprintf("Export %v is mounted from %v (%v)", exportPath, fields[0], fields[2]))
+		return
+	}
+
+	log.Error = append(log.Error, fmt.Sprintf("Export %v is not mounted in the share manager pod", exportPath))
+}
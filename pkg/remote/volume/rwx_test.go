@@ -0,0 +1,92 @@
+package volume
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func TestCheckShareManagerExport(t *testing.T) {
+	tests := map[string]struct {
+		mounts         string
+		expectedErrors int
+	}{
+		"mounted read-write": {
+			mounts: "overlay / overlay rw,relatime 0 0\n" +
+				"/dev/longhorn/vol /export/vol ext4 rw,relatime 0 0\n",
+		},
+		"mounted read-only": {
+			mounts:         "/dev/longhorn/vol /export/vol ext4 ro,relatime 0 0\n",
+			expectedErrors: 1,
+		},
+		"other volume mounted": {
+			mounts:         "/dev/longhorn/vol-2 /export/vol-2 ext4 rw,relatime 0 0\n",
+			expectedErrors: 1,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			log := &types.LogCollection{}
+			checkShareManagerExport(log, test.mounts, "vol")
+			if len(log.Error) != test.expectedErrors {
+				t.Errorf("expected %d errors, got %v", test.expectedErrors, log.Error)
+			}
+		})
+	}
+}
+
+func TestCheckShareManagerPod(t *testing.T) {
+	newPod := func(phase corev1.PodPhase, ready corev1.ConditionStatus, restarts int32) *corev1.Pod {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "share-manager-vol"}}
+		pod.Status.Phase = phase
+		pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: ready}}
+		pod.Status.ContainerStatuses = []corev1.ContainerStatus{{Name: "share-manager", RestartCount: restarts}}
+		return pod
+	}
+
+	tests := map[string]struct {
+		pod              *corev1.Pod
+		expectedUsable   bool
+		expectedErrors   int
+		expectedWarnings int
+	}{
+		"running and ready": {
+			pod:            newPod(corev1.PodRunning, corev1.ConditionTrue, 0),
+			expectedUsable: true,
+		},
+		"restarted": {
+			pod:              newPod(corev1.PodRunning, corev1.ConditionTrue, 3),
+			expectedUsable:   true,
+			expectedWarnings: 1,
+		},
+		"not ready": {
+			pod:            newPod(corev1.PodRunning, corev1.ConditionFalse, 0),
+			expectedUsable: true,
+			expectedErrors: 1,
+		},
+		"pending": {
+			pod:            newPod(corev1.PodPending, corev1.ConditionFalse, 0),
+			expectedErrors: 1,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			log := &types.LogCollection{}
+			usable := checkShareManagerPod(log, test.pod)
+			if usable != test.expectedUsable {
+				t.Errorf("expected usable %v, got %v", test.expectedUsable, usable)
+			}
+			if len(log.Error) != test.expectedErrors {
+				t.Errorf("expected %d errors, got %v", test.expectedErrors, log.Error)
+			}
+			if len(log.Warn) != test.expectedWarnings {
+				t.Errorf("expected %d warnings, got %v", test.expectedWarnings, log.Warn)
+			}
+		})
+	}
+}
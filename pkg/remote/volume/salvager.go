@@ -0,0 +1,259 @@
+package volume
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	longhorn "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+	lhclient "github.com/longhorn/longhorn-manager/k8s/pkg/client/clientset/versioned"
+	lhmgrtypes "github.com/longhorn/longhorn-manager/types"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// Fields of the Longhorn custom resources modified by the salvage.
+const (
+	fieldReplicaFailedAt   = "spec.failedAt"
+	fieldEngineDesireState = "spec.desireState"
+)
+
+// Salvager provide functions for salvaging a faulted volume from one of its failed replicas.
+type Salvager struct {
+	SalvagerCmdOptions
+
+	longhornClient *lhclient.Clientset
+
+	changes  []types.VolumeFieldChange
+	warnings []string
+}
+
+// SalvagerCmdOptions holds the options for the command.
+type SalvagerCmdOptions struct {
+	types.GlobalCmdOptions
+
+	LonghornNamespace string
+	VolumeName        string
+	ReplicaName       string
+}
+
+// Validate validates the command options.
+func (remote *Salvager) Validate() error {
+	if remote.VolumeName == "" {
+		return errors.New("Longhorn volume name is required")
+	}
+
+	if remote.ReplicaName == "" {
+		return errors.Errorf("Replica name (--%s) is required", consts.CmdOptReplica)
+	}
+
+	return nil
+}
+
+// Init initializes the Salvager, and plans the changes of the salvage. It fails when a safety
+// check does not pass.
+func (remote *Salvager) Init() error {
+	longhornClient, err := kubeutils.NewLonghornClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.longhornClient = longhornClient
+
+	ctx := context.Background()
+	lhV1beta2 := remote.longhornClient.LonghornV1beta2()
+
+	volume, err := lhV1beta2.Volumes(remote.LonghornNamespace).Get(ctx, remote.VolumeName, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to get volume %v", remote.VolumeName)
+	}
+
+	listOptions := metav1.ListOptions{
+		LabelSelector: metav1.FormatLabelSelector(&metav1.LabelSelector{MatchLabels: lhmgrtypes.GetVolumeLabels(remote.VolumeName)}),
+	}
+	replicas, err := lhV1beta2.Replicas(remote.LonghornNamespace).List(ctx, listOptions)
+	if err != nil {
+		return errors.Wrapf(err, "failed to list replicas of volume %v", remote.VolumeName)
+	}
+	engines, err := lhV1beta2.Engines(remote.LonghornNamespace).List(ctx, listOptions)
+	if err != nil {
+		return errors.Wrapf(err, "failed to list engines of volume %v", remote.VolumeName)
+	}
+
+	var node *longhorn.Node
+	for i := range replicas.Items {
+		if replicas.Items[i].Name != remote.ReplicaName || replicas.Items[i].Spec.NodeID == "" {
+			continue
+		}
+		node, err = lhV1beta2.Nodes(remote.LonghornNamespace).Get(ctx, replicas.Items[i].Spec.NodeID, metav1.GetOptions{})
+		if err != nil {
+			return errors.Wrapf(err, "failed to get node %v", replicas.Items[i].Spec.NodeID)
+		}
+	}
+
+	remote.changes, remote.warnings, err = planSalvage(volume, replicas.Items, engines.Items, node, remote.ReplicaName)
+	return err
+}
+
+// Changes returns the changes of the Longhorn custom resources the salvage applies.
+func (remote *Salvager) Changes() []types.VolumeFieldChange {
+	return remote.changes
+}
+
+// Warnings returns the concerns about the chosen replica that do not prevent the salvage.
+func (remote *Salvager) Warnings() []string {
+	return remote.warnings
+}
+
+// Run applies the planned changes. Each custom resource is read again, and the salvage stops
+// when a field no longer has the planned value, since Longhorn changed the resource meanwhile.
+func (remote *Salvager) Run() error {
+	ctx := context.Background()
+	lhV1beta2 := remote.longhornClient.LonghornV1beta2()
+
+	for _, change := range remote.changes {
+		switch change.Kind {
+		case "Replica":
+			replica, err := lhV1beta2.Replicas(remote.LonghornNamespace).Get(ctx, change.Name, metav1.GetOptions{})
+			if err != nil {
+				return errors.Wrapf(err, "failed to get replica %v", change.Name)
+			}
+			if replica.Spec.FailedAt != change.From {
+				return errors.Errorf("%v of replica %v changed to %q since the salvage was planned", change.Field, change.Name, replica.Spec.FailedAt)
+			}
+			replica.Spec.FailedAt = change.To
+			if _, err := lhV1beta2.Replicas(remote.LonghornNamespace).Update(ctx, replica, metav1.UpdateOptions{}); err != nil {
+				return errors.Wrapf(err, "failed to update replica %v", change.Name)
+			}
+
+		case "Engine":
+			engine, err := lhV1beta2.Engines(remote.LonghornNamespace).Get(ctx, change.Name, metav1.GetOptions{})
+			if err != nil {
+				return errors.Wrapf(err, "failed to get engine %v", change.Name)
+			}
+			if string(engine.Spec.DesireState) != change.From {
+				return errors.Errorf("%v of engine %v changed to %q since the salvage was planned", change.Field, change.Name, engine.Spec.DesireState)
+			}
+			engine.Spec.DesireState = longhorn.InstanceState(change.To)
+			if _, err := lhV1beta2.Engines(remote.LonghornNamespace).Update(ctx, engine, metav1.UpdateOptions{}); err != nil {
+				return errors.Wrapf(err, "failed to update engine %v", change.Name)
+			}
+
+		default:
+			return errors.Errorf("unknown kind %v", change.Kind)
+		}
+	}
+
+	return nil
+}
+
+// planSalvage checks that the volume can be salvaged from the replica, and returns the changes
+// of the documented manual salvage: the replica is marked as usable by clearing its failure
+// time, and the active engine is stopped when it is still desired running, so the volume
+// controller starts it again with the replica at the next attachment. The warnings report the
+// other replicas that may hold more recent data.
+func planSalvage(volume *longhorn.Volume, replicas []longhorn.Replica, engines []longhorn.Engine, node *longhorn.Node, replicaName string) ([]types.VolumeFieldChange, []string, error) {
+	if volume.Status.State != longhorn.VolumeStateDetached {
+		return nil, nil, errors.Errorf("volume %v is %v, it must be detached to be salvaged", volume.Name, volume.Status.State)
+	}
+	if volume.Status.Robustness != longhorn.VolumeRobustnessFaulted {
+		return nil, nil, errors.Errorf("volume %v is %v, only a faulted volume can be salvaged", volume.Name, volume.Status.Robustness)
+	}
+
+	var replica *longhorn.Replica
+	for i := range replicas {
+		if replicas[i].Name == replicaName {
+			replica = &replicas[i]
+		}
+	}
+	if replica == nil || replica.Spec.VolumeName != volume.Name {
+		return nil, nil, errors.Errorf("replica %v does not belong to volume %v", replicaName, volume.Name)
+	}
+	if replica.DeletionTimestamp != nil {
+		return nil, nil, errors.Errorf("replica %v is being deleted", replica.Name)
+	}
+	if replica.Spec.FailedAt == "" {
+		return nil, nil, errors.Errorf("replica %v is not failed", replica.Name)
+	}
+	if replica.Spec.HealthyAt == "" {
+		return nil, nil, errors.Errorf("replica %v never completed a rebuild, its data is incomplete", replica.Name)
+	}
+
+	if node == nil {
+		return nil, nil, errors.Errorf("replica %v is not scheduled to a node", replica.Name)
+	}
+	if !isConditionTrue(node.Status.Conditions, longhorn.NodeConditionTypeReady) {
+		return nil, nil, errors.Errorf("node %v of replica %v is not ready", node.Name, replica.Name)
+	}
+	diskFound := false
+	for diskName, disk := range node.Status.DiskStatus {
+		if disk == nil || disk.DiskUUID != replica.Spec.DiskID {
+			continue
+		}
+		diskFound = true
+		if !isConditionTrue(disk.Conditions, longhorn.DiskConditionTypeReady) {
+			return nil, nil, errors.Errorf("disk %v of replica %v on node %v is not ready", diskName, replica.Name, node.Name)
+		}
+	}
+	if !diskFound {
+		return nil, nil, errors.Errorf("disk %v of replica %v is not found on node %v", replica.Spec.DiskID, replica.Name, node.Name)
+	}
+
+	warnings := []string{}
+	for _, other := range replicas {
+		if other.Name == replica.Name || other.Spec.VolumeName != volume.Name {
+			continue
+		}
+		if other.Spec.FailedAt == "" {
+			warnings = append(warnings, fmt.Sprintf("Replica %v is not failed, Longhorn may use it instead", other.Name))
+			continue
+		}
+		if other.Spec.HealthyAt != "" && other.Spec.LastHealthyAt > replica.Spec.LastHealthyAt {
+			warnings = append(warnings, fmt.Sprintf("Replica %v was healthy more recently (%v) than replica %v (%v), it may hold more recent data", other.Name, other.Spec.LastHealthyAt, replica.Name, replica.Spec.LastHealthyAt))
+		}
+	}
+	sort.Strings(warnings)
+
+	changes := []types.VolumeFieldChange{
+		{
+			Kind:  "Replica",
+			Name:  replica.Name,
+			Field: fieldReplicaFailedAt,
+			From:  replica.Spec.FailedAt,
+			To:    "",
+		},
+	}
+
+	for _, engine := range engines {
+		if !engine.Spec.Active || engine.Spec.VolumeName != volume.Name {
+			continue
+		}
+		if engine.Spec.DesireState == longhorn.InstanceStateStopped {
+			continue
+		}
+		changes = append(changes, types.VolumeFieldChange{
+			Kind:  "Engine",
+			Name:  engine.Name,
+			Field: fieldEngineDesireState,
+			From:  string(engine.Spec.DesireState),
+			To:    string(longhorn.InstanceStateStopped),
+		})
+	}
+
+	return changes, warnings, nil
+}
+
+func isConditionTrue(conditions []longhorn.Condition, conditionType string) bool {
+	for _, condition := range conditions {
+		if condition.Type == conditionType {
+			return condition.Status == longhorn.ConditionStatusTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,145 @@
+package volume
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	longhorn "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+)
+
+func TestPlanSalvage(t *testing.T) {
+	newVolume := func(state longhorn.VolumeState, robustness longhorn.VolumeRobustness) *longhorn.Volume {
+		volume := &longhorn.Volume{ObjectMeta: metav1.ObjectMeta{Name: "vol"}}
+		volume.Status.State = state
+		volume.Status.Robustness = robustness
+		return volume
+	}
+	newReplica := func(name, healthyAt, lastHealthyAt, failedAt string) longhorn.Replica {
+		replica := longhorn.Replica{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		replica.Spec.VolumeName = "vol"
+		replica.Spec.NodeID = "node-1"
+		replica.Spec.DiskID = "disk-uuid"
+		replica.Spec.HealthyAt = healthyAt
+		replica.Spec.LastHealthyAt = lastHealthyAt
+		replica.Spec.FailedAt = failedAt
+		return replica
+	}
+	newEngine := func(desireState longhorn.InstanceState) longhorn.Engine {
+		engine := longhorn.Engine{ObjectMeta: metav1.ObjectMeta{Name: "vol-e-0"}}
+		engine.Spec.VolumeName = "vol"
+		engine.Spec.Active = true
+		engine.Spec.DesireState = desireState
+		return engine
+	}
+	newNode := func(nodeReady, diskReady longhorn.ConditionStatus) *longhorn.Node {
+		node := &longhorn.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}
+		node.Status.Conditions = []longhorn.Condition{{Type: longhorn.NodeConditionTypeReady, Status: nodeReady}}
+		node.Status.DiskStatus = map[string]*longhorn.DiskStatus{
+			"default-disk": {
+				DiskUUID:   "disk-uuid",
+				Conditions: []longhorn.Condition{{Type: longhorn.DiskConditionTypeReady, Status: diskReady}},
+			},
+		}
+		return node
+	}
+
+	tests := map[string]struct {
+		volume           *longhorn.Volume
+		replicas         []longhorn.Replica
+		engines          []longhorn.Engine
+		node             *longhorn.Node
+		expectedError    bool
+		expectedChanges  int
+		expectedWarnings int
+	}{
+		"salvage failed replica": {
+			volume: newVolume(longhorn.VolumeStateDetached, longhorn.VolumeRobustnessFaulted),
+			replicas: []longhorn.Replica{
+				newReplica("r-1", "2024-07-01T00:00:00Z", "2024-07-10T00:00:00Z", "2024-07-11T00:00:00Z"),
+				newReplica("r-2", "2024-07-01T00:00:00Z", "2024-07-09T00:00:00Z", "2024-07-11T00:00:00Z"),
+			},
+			engines:         []longhorn.Engine{newEngine(longhorn.InstanceStateStopped)},
+			node:            newNode(longhorn.ConditionStatusTrue, longhorn.ConditionStatusTrue),
+			expectedChanges: 1,
+		},
+		"restart engine and warn about more recent replica": {
+			volume: newVolume(longhorn.VolumeStateDetached, longhorn.VolumeRobustnessFaulted),
+			replicas: []longhorn.Replica{
+				newReplica("r-1", "2024-07-01T00:00:00Z", "2024-07-09T00:00:00Z", "2024-07-11T00:00:00Z"),
+				newReplica("r-2", "2024-07-01T00:00:00Z", "2024-07-10T00:00:00Z", "2024-07-11T00:00:00Z"),
+			},
+			engines:          []longhorn.Engine{newEngine(longhorn.InstanceStateRunning)},
+			node:             newNode(longhorn.ConditionStatusTrue, longhorn.ConditionStatusTrue),
+			expectedChanges:  2,
+			expectedWarnings: 1,
+		},
+		"volume not faulted": {
+			volume:        newVolume(longhorn.VolumeStateDetached, longhorn.VolumeRobustnessUnknown),
+			replicas:      []longhorn.Replica{newReplica("r-1", "2024-07-01T00:00:00Z", "2024-07-10T00:00:00Z", "2024-07-11T00:00:00Z")},
+			node:          newNode(longhorn.ConditionStatusTrue, longhorn.ConditionStatusTrue),
+			expectedError: true,
+		},
+		"volume attached": {
+			volume:        newVolume(longhorn.VolumeStateAttached, longhorn.VolumeRobustnessFaulted),
+			replicas:      []longhorn.Replica{newReplica("r-1", "2024-07-01T00:00:00Z", "2024-07-10T00:00:00Z", "2024-07-11T00:00:00Z")},
+			node:          newNode(longhorn.ConditionStatusTrue, longhorn.ConditionStatusTrue),
+			expectedError: true,
+		},
+		"replica not found": {
+			volume:        newVolume(longhorn.VolumeStateDetached, longhorn.VolumeRobustnessFaulted),
+			replicas:      []longhorn.Replica{newReplica("r-2", "2024-07-01T00:00:00Z", "2024-07-10T00:00:00Z", "2024-07-11T00:00:00Z")},
+			node:          newNode(longhorn.ConditionStatusTrue, longhorn.ConditionStatusTrue),
+			expectedError: true,
+		},
+		"replica not failed": {
+			volume:        newVolume(longhorn.VolumeStateDetached, longhorn.VolumeRobustnessFaulted),
+			replicas:      []longhorn.Replica{newReplica("r-1", "2024-07-01T00:00:00Z", "2024-07-10T00:00:00Z", "")},
+			node:          newNode(longhorn.ConditionStatusTrue, longhorn.ConditionStatusTrue),
+			expectedError: true,
+		},
+		"replica never healthy": {
+			volume:        newVolume(longhorn.VolumeStateDetached, longhorn.VolumeRobustnessFaulted),
+			replicas:      []longhorn.Replica{newReplica("r-1", "", "2024-07-10T00:00:00Z", "2024-07-11T00:00:00Z")},
+			node:          newNode(longhorn.ConditionStatusTrue, longhorn.ConditionStatusTrue),
+			expectedError: true,
+		},
+		"node not ready": {
+			volume:        newVolume(longhorn.VolumeStateDetached, longhorn.VolumeRobustnessFaulted),
+			replicas:      []longhorn.Replica{newReplica("r-1", "2024-07-01T00:00:00Z", "2024-07-10T00:00:00Z", "2024-07-11T00:00:00Z")},
+			node:          newNode(longhorn.ConditionStatusFalse, longhorn.ConditionStatusTrue),
+			expectedError: true,
+		},
+		"disk not ready": {
+			volume:        newVolume(longhorn.VolumeStateDetached, longhorn.VolumeRobustnessFaulted),
+			replicas:      []longhorn.Replica{newReplica("r-1", "2024-07-01T00:00:00Z", "2024-07-10T00:00:00Z", "2024-07-11T00:00:00Z")},
+			node:          newNode(longhorn.ConditionStatusTrue, longhorn.ConditionStatusFalse),
+			expectedError: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			changes, warnings, err := planSalvage(test.volume, test.replicas, test.engines, test.node, "r-1")
+			if test.expectedError {
+				if err == nil {
+					t.Fatalf("expected error, got changes %v", changes)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(changes) != test.expectedChanges {
+				t.Errorf("expected %d changes, got %v", test.expectedChanges, changes)
+			}
+			if changes[0].Kind != "Replica" || changes[0].Name != "r-1" || changes[0].To != "" {
+				t.Errorf("expected failedAt of replica r-1 to be cleared, got %v", changes[0])
+			}
+			if len(warnings) != test.expectedWarnings {
+				t.Errorf("expected %d warnings, got %v", test.expectedWarnings, warnings)
+			}
+		})
+	}
+}
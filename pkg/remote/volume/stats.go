@@ -0,0 +1,304 @@
+package volume
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	kubeclient "k8s.io/client-go/kubernetes"
+
+	longhorn "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+	lhclient "github.com/longhorn/longhorn-manager/k8s/pkg/client/clientset/versioned"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// Metrics of the IO of the volumes, reported by the Longhorn manager from the engines in the
+// instance managers of its node.
+const (
+	metricReadIOPS        = "longhorn_volume_read_iops"
+	metricWriteIOPS       = "longhorn_volume_write_iops"
+	metricReadThroughput  = "longhorn_volume_read_throughput"
+	metricWriteThroughput = "longhorn_volume_write_throughput"
+	metricReadLatency     = "longhorn_volume_read_latency"
+	metricWriteLatency    = "longhorn_volume_write_latency"
+)
+
+// metricLabelRegex matches a label of a metric in the Prometheus text format.
+var metricLabelRegex = regexp.MustCompile(`(\w+)="((?:[^"\\]|\\.)*)"`)
+
+// StatsSampler provide functions for sampling the IO of a volume reported by its engine, without
+// Prometheus.
+type StatsSampler struct {
+	StatsSamplerCmdOptions
+
+	kubeClient     *kubeclient.Clientset
+	longhornClient *lhclient.Clientset
+
+	nodeID     string
+	managerPod *corev1.Pod
+	port       string // Metrics port of the Longhorn manager pod.
+}
+
+// StatsSamplerCmdOptions holds the options for the command.
+type StatsSamplerCmdOptions struct {
+	types.GlobalCmdOptions
+
+	LonghornNamespace string
+	VolumeName        string
+	Interval          time.Duration
+	Count             int
+}
+
+// Validate validates the command options.
+func (remote *StatsSampler) Validate() error {
+	if remote.VolumeName == "" {
+		return errors.New("Longhorn volume name is required")
+	}
+	if remote.Interval <= 0 {
+		return errors.Errorf("interval (--%s) must be positive", consts.CmdOptInterval)
+	}
+	if remote.Count <= 0 {
+		return errors.Errorf("count (--%s) must be positive", consts.CmdOptCount)
+	}
+	return nil
+}
+
+// Init initializes the StatsSampler, and finds the Longhorn manager pod on the node of the engine
+// of the volume, which reports the metrics of the volume.
+func (remote *StatsSampler) Init() error {
+	kubeClient, err := kubeutils.NewKubeClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.kubeClient = kubeClient
+
+	longhornClient, err := kubeutils.NewLonghornClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.longhornClient = longhornClient
+
+	ctx := context.Background()
+	volume, err := remote.longhornClient.LonghornV1beta2().Volumes(remote.LonghornNamespace).Get(ctx, remote.VolumeName, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to get volume %v", remote.VolumeName)
+	}
+	if volume.Status.State != longhorn.VolumeStateAttached || volume.Status.CurrentNodeID == "" {
+		return errors.Errorf("volume %v must be attached to report its IO, it is %v", remote.VolumeName, volume.Status.State)
+	}
+	remote.nodeID = volume.Status.CurrentNodeID
+
+	podList, err := remote.kubeClient.CoreV1().Pods(remote.LonghornNamespace).List(ctx, metav1.ListOptions{
+		LabelSelector: consts.LonghornLabelSelectorManager,
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", remote.nodeID).String(),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to list Longhorn manager pods on node %v", remote.nodeID)
+	}
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if pod.Status.Phase != corev1.PodRunning || pod.DeletionTimestamp != nil {
+			continue
+		}
+		port, err := getMetricsPort(pod)
+		if err != nil {
+			return err
+		}
+		remote.managerPod, remote.port = pod, port
+		return nil
+	}
+	return errors.Errorf("no running Longhorn manager pod on node %v", remote.nodeID)
+}
+
+// Run samples the IO of the volume count times at the interval, calling onSample with each sample,
+// and returns the samples with their summary.
+func (remote *StatsSampler) Run(ctx context.Context, onSample func(types.VolumeIOSample)) (*types.VolumeIOStats, error) {
+	stats := &types.VolumeIOStats{
+		Volume:          remote.VolumeName,
+		Node:            remote.nodeID,
+		IntervalSeconds: remote.Interval.Seconds(),
+		Samples:         []types.VolumeIOSample{},
+	}
+
+	ticker := time.NewTicker(remote.Interval)
+	defer ticker.Stop()
+
+	for i := 0; i < remote.Count; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				stats.Summary = summarizeVolumeIO(stats.Samples)
+				return stats, nil
+			case <-ticker.C:
+			}
+		}
+
+		sample, err := remote.sample(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			return nil, err
+		}
+		stats.Samples = append(stats.Samples, sample)
+		if onSample != nil {
+			onSample(sample)
+		}
+	}
+
+	stats.Summary = summarizeVolumeIO(stats.Samples)
+	return stats, nil
+}
+
+// sample scrapes the metrics of the Longhorn manager through the Kubernetes API server proxy. The
+// manager gets the metrics from the engine at each scrape.
+func (remote *StatsSampler) sample(ctx context.Context) (types.VolumeIOSample, error) {
+	data, err := remote.kubeClient.CoreV1().Pods(remote.managerPod.Namespace).ProxyGet("http", remote.managerPod.Name, remote.port, "/metrics", nil).DoRaw(ctx)
+	if err != nil {
+		return types.VolumeIOSample{}, errors.Wrapf(err, "failed to get the metrics of Longhorn manager pod %v", remote.managerPod.Name)
+	}
+
+	sample, err := parseVolumeIOMetrics(data, remote.VolumeName)
+	if err != nil {
+		return types.VolumeIOSample{}, errors.Wrapf(err, "failed to parse the metrics of Longhorn manager pod %v", remote.managerPod.Name)
+	}
+	sample.Time = time.Now().UTC().Format(time.RFC3339)
+	return sample, nil
+}
+
+// getMetricsPort returns the number of the metrics port of the Longhorn manager pod, since the
+// API server proxy does not resolve the port names.
+func getMetricsPort(pod *corev1.Pod) (string, error) {
+	for _, container := range pod.Spec.Containers {
+		for _, port := range container.Ports {
+			if port.Name == consts.LonghornManagerMetricsPort {
+				return strconv.Itoa(int(port.ContainerPort)), nil
+			}
+		}
+	}
+	return "", errors.Errorf("Longhorn manager pod %v has no %v port", pod.Name, consts.LonghornManagerMetricsPort)
+}
+
+// parseVolumeIOMetrics returns the IO of the volume from the metrics in the Prometheus text format.
+func parseVolumeIOMetrics(data []byte, volumeName string) (types.VolumeIOSample, error) {
+	sample := types.VolumeIOSample{}
+	fields := map[string]*int64{
+		metricReadIOPS:        &sample.ReadIOPS,
+		metricWriteIOPS:       &sample.WriteIOPS,
+		metricReadThroughput:  &sample.ReadThroughput,
+		metricWriteThroughput: &sample.WriteThroughput,
+		metricReadLatency:     &sample.ReadLatency,
+		metricWriteLatency:    &sample.WriteLatency,
+	}
+
+	found := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name, labels, value, ok := splitMetricLine(line)
+		if !ok {
+			continue
+		}
+		field, ok := fields[name]
+		if !ok || labels["volume"] != volumeName {
+			continue
+		}
+
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return sample, errors.Wrapf(err, "invalid value %q of metric %v", value, name)
+		}
+		*field = int64(parsed)
+		found++
+	}
+	if err := scanner.Err(); err != nil {
+		return sample, err
+	}
+
+	if found == 0 {
+		return sample, errors.Errorf("no IO metric of volume %v is reported", volumeName)
+	}
+	return sample, nil
+}
+
+// splitMetricLine splits a sample line of the Prometheus text format into the metric name, its
+// labels and its value. The optional timestamp is ignored.
+func splitMetricLine(line string) (string, map[string]string, string, bool) {
+	labels := map[string]string{}
+
+	name, rest := line, ""
+	if start := strings.Index(line, "{"); start >= 0 {
+		end := strings.LastIndex(line, "}")
+		if end < start {
+			return "", nil, "", false
+		}
+		name, rest = line[:start], line[end+1:]
+		for _, match := range metricLabelRegex.FindAllStringSubmatch(line[start+1:end], -1) {
+			labels[match[1]] = match[2]
+		}
+	} else if index := strings.IndexAny(line, " \t"); index >= 0 {
+		name, rest = line[:index], line[index:]
+	}
+
+	values := strings.Fields(rest)
+	if len(values) == 0 {
+		return "", nil, "", false
+	}
+	return name, labels, values[0], true
+}
+
+// summarizeVolumeIO returns the minimum, average and maximum of each metric over the samples.
+func summarizeVolumeIO(samples []types.VolumeIOSample) []types.VolumeIOStatsRange {
+	metrics := []struct {
+		name  string
+		value func(types.VolumeIOSample) int64
+	}{
+		{metricReadIOPS, func(s types.VolumeIOSample) int64 { return s.ReadIOPS }},
+		{metricWriteIOPS, func(s types.VolumeIOSample) int64 { return s.WriteIOPS }},
+		{metricReadThroughput, func(s types.VolumeIOSample) int64 { return s.ReadThroughput }},
+		{metricWriteThroughput, func(s types.VolumeIOSample) int64 { return s.WriteThroughput }},
+		{metricReadLatency, func(s types.VolumeIOSample) int64 { return s.ReadLatency }},
+		{metricWriteLatency, func(s types.VolumeIOSample) int64 { return s.WriteLatency }},
+	}
+
+	summary := []types.VolumeIOStatsRange{}
+	if len(samples) == 0 {
+		return summary
+	}
+
+	for _, metric := range metrics {
+		statsRange := types.VolumeIOStatsRange{
+			Metric:  strings.TrimPrefix(metric.name, "longhorn_volume_"),
+			Minimum: metric.value(samples[0]),
+			Maximum: metric.value(samples[0]),
+		}
+		var total int64
+		for _, sample := range samples {
+			value := metric.value(sample)
+			total += value
+			statsRange.Minimum = min(statsRange.Minimum, value)
+			statsRange.Maximum = max(statsRange.Maximum, value)
+		}
+		statsRange.Average = total / int64(len(samples))
+		summary = append(summary, statsRange)
+	}
+	return summary
+}
@@ -0,0 +1,121 @@
+package volume
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func TestStatsSamplerValidate(t *testing.T) {
+	tests := map[string]struct {
+		options       StatsSamplerCmdOptions
+		expectedError bool
+	}{
+		"valid": {
+			options: StatsSamplerCmdOptions{VolumeName: "vol-1", Interval: 5 * time.Second, Count: 12},
+		},
+		"no volume": {
+			options:       StatsSamplerCmdOptions{Interval: 5 * time.Second, Count: 12},
+			expectedError: true,
+		},
+		"no interval": {
+			options:       StatsSamplerCmdOptions{VolumeName: "vol-1", Count: 12},
+			expectedError: true,
+		},
+		"no count": {
+			options:       StatsSamplerCmdOptions{VolumeName: "vol-1", Interval: 5 * time.Second},
+			expectedError: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			sampler := &StatsSampler{StatsSamplerCmdOptions: test.options}
+			err := sampler.Validate()
+			if test.expectedError != (err != nil) {
+				t.Fatalf("expected error %v, got %v", test.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestParseVolumeIOMetrics(t *testing.T) {
+	metrics := `# HELP longhorn_volume_read_iops Read IOPS of this Longhorn volume
+# TYPE longhorn_volume_read_iops gauge
+longhorn_volume_read_iops{node="node-1",pvc="data",pvc_namespace="default",volume="vol-1"} 120
+longhorn_volume_read_iops{node="node-1",pvc="logs",pvc_namespace="default",volume="vol-10"} 999
+longhorn_volume_write_iops{node="node-1",pvc="data",pvc_namespace="default",volume="vol-1"} 340
+longhorn_volume_read_throughput{node="node-1",pvc="data",pvc_namespace="default",volume="vol-1"} 4.9152e+06
+longhorn_volume_write_throughput{node="node-1",pvc="data",pvc_namespace="default",volume="vol-1"} 1392640
+longhorn_volume_read_latency{node="node-1",pvc="data",pvc_namespace="default",volume="vol-1"} 512000
+longhorn_volume_write_latency{node="node-1",pvc="data",pvc_namespace="default",volume="vol-1"} 1.2e+06 1721120000000
+longhorn_volume_actual_size_bytes{node="node-1",pvc="data",pvc_namespace="default",volume="vol-1"} 1.073741824e+09
+longhorn_manager_cpu_usage_millicpu{manager="longhorn-manager-x2k9p",node="node-1"} 12
+`
+
+	tests := map[string]struct {
+		volume        string
+		expected      types.VolumeIOSample
+		expectedError bool
+	}{
+		"volume": {
+			volume: "vol-1",
+			expected: types.VolumeIOSample{
+				ReadIOPS:        120,
+				WriteIOPS:       340,
+				ReadThroughput:  4915200,
+				WriteThroughput: 1392640,
+				ReadLatency:     512000,
+				WriteLatency:    1200000,
+			},
+		},
+		"volume with a name prefix of another": {
+			volume:   "vol-10",
+			expected: types.VolumeIOSample{ReadIOPS: 999},
+		},
+		"volume not reported": {
+			volume:        "vol-2",
+			expectedError: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			sample, err := parseVolumeIOMetrics([]byte(metrics), test.volume)
+			if test.expectedError != (err != nil) {
+				t.Fatalf("expected error %v, got %v", test.expectedError, err)
+			}
+			if err == nil && !reflect.DeepEqual(sample, test.expected) {
+				t.Fatalf("expected %+v, got %+v", test.expected, sample)
+			}
+		})
+	}
+}
+
+func TestSummarizeVolumeIO(t *testing.T) {
+	samples := []types.VolumeIOSample{
+		{ReadIOPS: 100, WriteLatency: 2000},
+		{ReadIOPS: 300, WriteLatency: 1000},
+		{ReadIOPS: 200, WriteLatency: 3000},
+	}
+
+	summary := summarizeVolumeIO(samples)
+	expected := map[string]types.VolumeIOStatsRange{
+		"read_iops":     {Metric: "read_iops", Minimum: 100, Average: 200, Maximum: 300},
+		"write_latency": {Metric: "write_latency", Minimum: 1000, Average: 2000, Maximum: 3000},
+	}
+
+	if len(summary) != 6 {
+		t.Fatalf("expected 6 metrics, got %+v", summary)
+	}
+	for _, statsRange := range summary {
+		if want, ok := expected[statsRange.Metric]; ok && want != statsRange {
+			t.Fatalf("expected %+v, got %+v", want, statsRange)
+		}
+	}
+	if len(summarizeVolumeIO(nil)) != 0 {
+		t.Fatal("expected no summary without samples")
+	}
+}
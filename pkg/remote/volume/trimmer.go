@@ -1,15 +1,24 @@
 package volume
 
 import (
+	"context"
+	"time"
+
 	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 
 	"k8s.io/utils/ptr"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
 	kubeclient "k8s.io/client-go/kubernetes"
 
+	longhorn "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+	lhclient "github.com/longhorn/longhorn-manager/k8s/pkg/client/clientset/versioned"
+
 	commonkube "github.com/longhorn/go-common-libs/kubernetes"
 
 	"github.com/longhorn/cli/pkg/consts"
@@ -18,13 +27,19 @@ import (
 	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
 )
 
+// actualSizeUpdateTimeout is the maximum time to wait for Longhorn to report the actual size of
+// the volume after the trim.
+const actualSizeUpdateTimeout = 30 * time.Second
+
 // Trimmer provide functions for the volume trimmer.
 type Trimmer struct {
 	TrimmerCmdOptions
 
-	kubeClient *kubeclient.Clientset
+	kubeClient     *kubeclient.Clientset
+	longhornClient *lhclient.Clientset
 
-	appName string // App name of the DaemonSet.
+	appName   string // App name of the DaemonSet.
+	namespace string // Namespace of the DaemonSet.
 }
 
 // TrimmerCmdOptions holds the options for the command.
@@ -39,7 +54,7 @@ type TrimmerCmdOptions struct {
 // Validate validates the command options.
 func (remote *Trimmer) Validate() error {
 	if remote.LonghornNamespace == "" {
-		return errors.New("Longhorn namespace  (--namespace) is required")
+		return errors.New("Longhorn namespace (--longhorn-namespace) is required")
 	}
 
 	if remote.VolumeName == "" {
@@ -57,28 +72,243 @@ func (remote *Trimmer) Init() error {
 	}
 	remote.kubeClient = kubeClient
 
+	longhornClient, err := kubeutils.NewLonghornClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	remote.longhornClient = longhornClient
+
+	remote.namespace = remote.Namespace
+	if remote.namespace == "" {
+		remote.namespace = consts.LonghornNamespace
+	}
 	remote.appName = consts.AppNameVolumeTrimmer
 	return nil
 }
 
-// Run creates the DaemonSet for the volume trimmer, and waits for it to complete.
-func (remote *Trimmer) Run() error {
+// Run creates the DaemonSet for the volume trimmer, waits for it to complete, and returns the space
+// reclaimed by the trim. The volumes that are detached or faulted are skipped.
+func (remote *Trimmer) Run() (*types.VolumeTrimResult, error) {
+	ctx := context.Background()
+
+	volume, err := remote.getVolume(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := &types.VolumeTrimResult{
+		Volume:           volume.Name,
+		Node:             volume.Status.CurrentNodeID,
+		AccessMode:       string(volume.Spec.AccessMode),
+		ActualSizeBefore: volume.Status.ActualSize,
+		ActualSizeAfter:  volume.Status.ActualSize,
+	}
+	if reason := getTrimSkipReason(volume); reason != "" {
+		logrus.Infof("Skipping volume %v, it is %v", remote.VolumeName, reason)
+		result.SkippedReason = reason
+		return result, nil
+	}
+
 	nodeSelector, err := kubeutils.ParseNodeSelector(remote.NodeSelector)
 	if err != nil {
-		return errors.Wrapf(err, "failed to parse %q argument", consts.CmdOptNodeSelector)
+		return nil, errors.Wrapf(err, "failed to parse %q argument", consts.CmdOptNodeSelector)
+	}
+
+	if _, err := kubeutils.CreateNamespace(remote.kubeClient, remote.namespace); err != nil {
+		return nil, err
+	}
+
+	if err := remote.createRbac(); err != nil {
+		return nil, err
 	}
+
 	newDaemonSet := remote.newDaemonSet(nodeSelector)
-	daemonSet, err := commonkube.CreateDaemonSet(remote.kubeClient, newDaemonSet)
+	if err := kubeutils.SetPodOptions(&newDaemonSet.Spec.Template.Spec, &remote.GlobalCmdOptions); err != nil {
+		return nil, err
+	}
+	kubeutils.SetRunMetadata(remote.kubeClient, newDaemonSet)
+	kubeutils.LogManifest(newDaemonSet)
+
+	startTime := time.Now()
+	daemonSet, err := kubeutils.CreateDaemonSet(remote.kubeClient, newDaemonSet, remote.MinNodes)
+	if err != nil {
+		return nil, err
+	}
+	if err := kubeutils.MonitorDaemonSetContainer(remote.kubeClient, daemonSet, consts.ContainerNameInit, kubeutils.WaitForDaemonSetContainersExit, ptr.To(consts.ContainerConditionMaxTolerationMedium)); err != nil {
+		return nil, err
+	}
+	duration := time.Since(startTime)
+	result.Trimmed = true
+	result.Duration = duration.Round(time.Millisecond).String()
+	result.DurationSeconds = duration.Seconds()
+
+	result.ActualSizeAfter, err = remote.waitForActualSizeUpdate(ctx, result.ActualSizeBefore)
+	if err != nil {
+		return nil, err
+	}
+	result.ReclaimedBytes = getReclaimedBytes(result.ActualSizeBefore, result.ActualSizeAfter)
+	return result, nil
+}
+
+func (remote *Trimmer) getVolume(ctx context.Context) (*longhorn.Volume, error) {
+	volume, err := remote.longhornClient.LonghornV1beta2().Volumes(remote.LonghornNamespace).Get(ctx, remote.VolumeName, metav1.GetOptions{})
 	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get volume %v", remote.VolumeName)
+	}
+	return volume, nil
+}
+
+// waitForActualSizeUpdate waits for Longhorn to report an actual size of the volume different from
+// the size before the trim, as it is updated from the replicas periodically, and returns it. The
+// size is returned as is when it does not change in time, such as when nothing is reclaimed.
+func (remote *Trimmer) waitForActualSizeUpdate(ctx context.Context, sizeBefore int64) (int64, error) {
+	actualSize := sizeBefore
+
+	waitCtx, cancel := context.WithTimeout(ctx, actualSizeUpdateTimeout)
+	defer cancel()
+
+	err := wait.PollUntilContextCancel(waitCtx, 2*time.Second, true, func(ctx context.Context) (bool, error) {
+		volume, err := remote.getVolume(ctx)
+		if err != nil {
+			return false, err
+		}
+		actualSize = volume.Status.ActualSize
+		return actualSize != sizeBefore, nil
+	})
+	if err != nil && !wait.Interrupted(err) {
+		return 0, err
+	}
+	if actualSize == sizeBefore {
+		logrus.Debugf("Actual size of volume %v is unchanged after %v", remote.VolumeName, actualSizeUpdateTimeout)
+	}
+	return actualSize, nil
+}
+
+// getTrimSkipReason returns the reason the filesystem of the volume cannot be trimmed, or an empty
+// reason.
+func getTrimSkipReason(volume *longhorn.Volume) types.VolumeTrimSkipReason {
+	if volume.Status.Robustness == longhorn.VolumeRobustnessFaulted {
+		return types.VolumeTrimSkipReasonFaulted
+	}
+	if volume.Status.State != longhorn.VolumeStateAttached || volume.Status.CurrentNodeID == "" {
+		return types.VolumeTrimSkipReasonDetached
+	}
+	return ""
+}
+
+// getReclaimedBytes returns the space reclaimed by the trim. Data written to the volume during the
+// trim may grow the volume, which is not counted as negative space reclaimed.
+func getReclaimedBytes(sizeBefore, sizeAfter int64) int64 {
+	if sizeAfter >= sizeBefore {
+		return 0
+	}
+	return sizeBefore - sizeAfter
+}
+
+// createRbac creates the ServiceAccount, ClusterRole and ClusterRoleBinding that allow the volume
+// trimmer to get the volume in the Longhorn namespace and to run the trim in its share manager pod.
+func (remote *Trimmer) createRbac() error {
+	newServiceAccount := remote.newServiceAccount()
+	kubeutils.SetRunMetadata(remote.kubeClient, newServiceAccount)
+	if _, err := commonkube.CreateServiceAccount(remote.kubeClient, newServiceAccount); err != nil {
 		return err
 	}
 
-	return kubeutils.MonitorDaemonSetContainer(remote.kubeClient, daemonSet, consts.ContainerNameInit, kubeutils.WaitForDaemonSetContainersExit, ptr.To(consts.ContainerConditionMaxTolerationMedium))
+	newClusterRole := remote.newClusterRole()
+	kubeutils.SetRunMetadata(remote.kubeClient, newClusterRole)
+	if _, err := commonkube.CreateClusterRole(remote.kubeClient, newClusterRole); err != nil {
+		return err
+	}
+
+	newClusterRoleBinding := remote.newClusterRoleBinding()
+	kubeutils.SetRunMetadata(remote.kubeClient, newClusterRoleBinding)
+	if _, err := commonkube.CreateClusterRoleBinding(remote.kubeClient, newClusterRoleBinding); err != nil {
+		return err
+	}
+
+	return nil
 }
 
-// Cleanup deletes the DaemonSet created for the volume trimmer.
+// Cleanup deletes the DaemonSet and the RBAC created for the volume trimmer.
 func (remote *Trimmer) Cleanup() error {
-	return commonkube.DeleteDaemonSet(remote.kubeClient, remote.LonghornNamespace, remote.appName)
+	if err := commonkube.DeleteDaemonSet(remote.kubeClient, remote.namespace, remote.appName); err != nil {
+		return err
+	}
+
+	if err := commonkube.DeleteClusterRoleBinding(remote.kubeClient, remote.appName); err != nil {
+		return err
+	}
+
+	if err := commonkube.DeleteClusterRole(remote.kubeClient, remote.appName); err != nil {
+		return err
+	}
+
+	return commonkube.DeleteServiceAccount(remote.kubeClient, remote.namespace, remote.appName)
+}
+
+func (remote *Trimmer) newServiceAccount() *corev1.ServiceAccount {
+	return &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      remote.appName,
+			Namespace: remote.namespace,
+			Labels: map[string]string{
+				"app":                 remote.appName,
+				consts.LabelManagedBy: consts.LabelValueManagedBy,
+			},
+		},
+	}
+}
+
+func (remote *Trimmer) newClusterRole() *rbacv1.ClusterRole {
+	return &rbacv1.ClusterRole{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: remote.appName,
+			Labels: map[string]string{
+				"app":                 remote.appName,
+				consts.LabelManagedBy: consts.LabelValueManagedBy,
+			},
+		},
+		Rules: []rbacv1.PolicyRule{
+			{
+				APIGroups: []string{"longhorn.io"},
+				Resources: []string{"volumes", "sharemanagers"},
+				Verbs:     []string{"get"},
+			},
+			{
+				APIGroups: []string{""},
+				Resources: []string{"pods"},
+				Verbs:     []string{"get"},
+			},
+			{
+				APIGroups: []string{""},
+				Resources: []string{"pods/exec"},
+				Verbs:     []string{"create"},
+			},
+		},
+	}
+}
+
+func (remote *Trimmer) newClusterRoleBinding() *rbacv1.ClusterRoleBinding {
+	return &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: remote.appName,
+			Labels: map[string]string{
+				"app":                 remote.appName,
+				consts.LabelManagedBy: consts.LabelValueManagedBy,
+			},
+		},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "ClusterRole",
+			Name:     remote.appName,
+		},
+		Subjects: []rbacv1.Subject{
+			{
+				Kind:      "ServiceAccount",
+				Name:      remote.appName,
+				Namespace: remote.namespace,
+			},
+		},
+	}
 }
 
 // NewDaemonSet prepares the DaemonSet for the volume trimmer.
@@ -86,9 +316,10 @@ func (remote *Trimmer) newDaemonSet(nodeSelector map[string]string) *appsv1.Daem
 	return &appsv1.DaemonSet{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      remote.appName,
-			Namespace: remote.LonghornNamespace,
+			Namespace: remote.namespace,
 			Labels: map[string]string{
-				"app": remote.appName,
+				"app":                 remote.appName,
+				consts.LabelManagedBy: consts.LabelValueManagedBy,
 			},
 		},
 		Spec: appsv1.DaemonSetSpec{
@@ -100,11 +331,12 @@ func (remote *Trimmer) newDaemonSet(nodeSelector map[string]string) *appsv1.Daem
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
 					Labels: map[string]string{
-						"app": remote.appName,
+						"app":                 remote.appName,
+						consts.LabelManagedBy: consts.LabelValueManagedBy,
 					},
 				},
 				Spec: corev1.PodSpec{
-					ServiceAccountName: consts.LonghornServiceAccountName,
+					ServiceAccountName: remote.appName,
 					InitContainers: []corev1.Container{
 						{
 							Name:    consts.ContainerNameInit,
@@ -132,13 +364,13 @@ func (remote *Trimmer) newDaemonSet(nodeSelector map[string]string) *appsv1.Daem
 									Value: remote.LonghornNamespace,
 								},
 							},
-							SecurityContext: &corev1.SecurityContext{
-								Privileged: ptr.To(true),
-							},
+							SecurityContext: kubeutils.NewSecurityContext(remote.Privileged, kubeutils.CapabilitiesHostNamespaces),
 							VolumeMounts: []corev1.VolumeMount{
 								{
 									Name:      consts.VolumeMountHostName,
 									MountPath: consts.VolumeMountHostDirectory,
+									// The trim runs in the host mount namespace, so the host root is only read.
+									ReadOnly: !remote.Privileged,
 								},
 							},
 						},
@@ -0,0 +1,62 @@
+package volume
+
+import (
+	"testing"
+
+	longhorn "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func TestGetTrimSkipReason(t *testing.T) {
+	tests := map[string]struct {
+		status   longhorn.VolumeStatus
+		expected types.VolumeTrimSkipReason
+	}{
+		"attached": {
+			status: longhorn.VolumeStatus{State: longhorn.VolumeStateAttached, Robustness: longhorn.VolumeRobustnessHealthy, CurrentNodeID: "node-1"},
+		},
+		"degraded": {
+			status: longhorn.VolumeStatus{State: longhorn.VolumeStateAttached, Robustness: longhorn.VolumeRobustnessDegraded, CurrentNodeID: "node-1"},
+		},
+		"detached": {
+			status:   longhorn.VolumeStatus{State: longhorn.VolumeStateDetached, Robustness: longhorn.VolumeRobustnessUnknown},
+			expected: types.VolumeTrimSkipReasonDetached,
+		},
+		"attaching": {
+			status:   longhorn.VolumeStatus{State: longhorn.VolumeStateAttaching},
+			expected: types.VolumeTrimSkipReasonDetached,
+		},
+		"faulted": {
+			status:   longhorn.VolumeStatus{State: longhorn.VolumeStateAttached, Robustness: longhorn.VolumeRobustnessFaulted, CurrentNodeID: "node-1"},
+			expected: types.VolumeTrimSkipReasonFaulted,
+		},
+		"faulted and detached": {
+			status:   longhorn.VolumeStatus{State: longhorn.VolumeStateDetached, Robustness: longhorn.VolumeRobustnessFaulted},
+			expected: types.VolumeTrimSkipReasonFaulted,
+		},
+	}
+
+	for name, test := range tests {
+		reason := getTrimSkipReason(&longhorn.Volume{Status: test.status})
+		if reason != test.expected {
+			t.Errorf("%v: expected reason %q, got %q", name, test.expected, reason)
+		}
+	}
+}
+
+func TestGetReclaimedBytes(t *testing.T) {
+	tests := map[string]struct {
+		before, after, expected int64
+	}{
+		"reclaimed": {before: 3 << 30, after: 1 << 30, expected: 2 << 30},
+		"unchanged": {before: 1 << 30, after: 1 << 30},
+		"grown":     {before: 1 << 30, after: 2 << 30},
+	}
+
+	for name, test := range tests {
+		if reclaimed := getReclaimedBytes(test.before, test.after); reclaimed != test.expected {
+			t.Errorf("%v: expected %v bytes reclaimed, got %v", name, test.expected, reclaimed)
+		}
+	}
+}
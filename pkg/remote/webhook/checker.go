@@ -0,0 +1,402 @@
+package webhook
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	kubeclient "k8s.io/client-go/kubernetes"
+
+	lhmgrtypes "github.com/longhorn/longhorn-manager/types"
+
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// certificateExpiryWarning is how long before its expiry a certificate is reported.
+const certificateExpiryWarning = 30 * 24 * time.Hour
+
+const longhornAPIGroup = "longhorn.io"
+
+var customResourceDefinitionResource = schema.GroupVersionResource{
+	Group:    "apiextensions.k8s.io",
+	Version:  "v1",
+	Resource: "customresourcedefinitions",
+}
+
+// Kinds of the checked objects, used in the keys of the result.
+const (
+	KindCustomResourceDefinition       = "CustomResourceDefinition"
+	KindMutatingWebhookConfiguration   = "MutatingWebhookConfiguration"
+	KindSecret                         = "Secret"
+	KindValidatingWebhookConfiguration = "ValidatingWebhookConfiguration"
+)
+
+// customResourceDefinition is the part of a CustomResourceDefinition used for the check.
+type customResourceDefinition struct {
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              struct {
+		Group      string `json:"group"`
+		Conversion *struct {
+			Strategy string `json:"strategy"`
+			Webhook  *struct {
+				ClientConfig *admissionregistrationv1.WebhookClientConfig `json:"clientConfig"`
+			} `json:"webhook"`
+		} `json:"conversion"`
+	} `json:"spec"`
+}
+
+// Checker provide functions for checking the admission and conversion webhooks of Longhorn.
+type Checker struct {
+	CheckerCmdOptions
+
+	kubeClient    *kubeclient.Clientset
+	dynamicClient *dynamic.DynamicClient
+
+	// Admission webhook configurations whose service no longer exists, keyed by kind.
+	staleConfigurations map[string][]string
+}
+
+// CheckerCmdOptions holds the options for the command.
+type CheckerCmdOptions struct {
+	types.GlobalCmdOptions
+
+	LonghornNamespace string // Namespace where Longhorn is deployed.
+}
+
+// Init initializes the Checker.
+func (remote *Checker) Init() error {
+	config, err := kubeutils.NewRestConfig("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+
+	remote.kubeClient, err = kubeutils.NewKubeClient("", remote.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+
+	remote.dynamicClient, err = dynamic.NewForConfig(config)
+	if err != nil {
+		return errors.Wrap(err, "failed to create dynamic client")
+	}
+
+	return nil
+}
+
+// Collect checks the Longhorn webhook configurations, the conversion webhook of the Longhorn
+// CustomResourceDefinitions and the webhook certificate, and returns the result of each
+// object keyed by its kind and name.
+func (remote *Checker) Collect() (map[string]*types.LogCollection, error) {
+	ctx := context.Background()
+	collections := map[string]*types.LogCollection{}
+	remote.staleConfigurations = map[string][]string{}
+
+	validatingConfigurations, err := remote.kubeClient.AdmissionregistrationV1().ValidatingWebhookConfigurations().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list validating webhook configurations")
+	}
+	for _, configuration := range validatingConfigurations.Items {
+		var clientConfigs []admissionregistrationv1.WebhookClientConfig
+		for _, webhook := range configuration.Webhooks {
+			clientConfigs = append(clientConfigs, webhook.ClientConfig)
+		}
+		if err := remote.checkConfiguration(ctx, KindValidatingWebhookConfiguration, configuration.Name, lhmgrtypes.ValidatingWebhookName, clientConfigs, collections); err != nil {
+			return nil, err
+		}
+	}
+
+	mutatingConfigurations, err := remote.kubeClient.AdmissionregistrationV1().MutatingWebhookConfigurations().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list mutating webhook configurations")
+	}
+	for _, configuration := range mutatingConfigurations.Items {
+		var clientConfigs []admissionregistrationv1.WebhookClientConfig
+		for _, webhook := range configuration.Webhooks {
+			clientConfigs = append(clientConfigs, webhook.ClientConfig)
+		}
+		if err := remote.checkConfiguration(ctx, KindMutatingWebhookConfiguration, configuration.Name, lhmgrtypes.MutatingWebhookName, clientConfigs, collections); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := remote.checkCustomResourceDefinitions(ctx, collections); err != nil {
+		return nil, err
+	}
+
+	if err := remote.checkCertificateSecret(ctx, collections); err != nil {
+		return nil, err
+	}
+
+	return collections, nil
+}
+
+// StaleConfigurations returns the admission webhook configurations whose service no longer
+// exists, as "kind/name". They are usually left by a failed uninstallation, and reject or
+// fail the requests to the Longhorn resources.
+func (remote *Checker) StaleConfigurations() []string {
+	var stale []string
+	for _, kind := range []string{KindValidatingWebhookConfiguration, KindMutatingWebhookConfiguration} {
+		for _, name := range remote.staleConfigurations[kind] {
+			stale = append(stale, kind+"/"+name)
+		}
+	}
+	return stale
+}
+
+// DeleteStaleConfigurations deletes the admission webhook configurations whose service no longer exists.
+func (remote *Checker) DeleteStaleConfigurations() error {
+	ctx := context.Background()
+
+	for _, name := range remote.staleConfigurations[KindValidatingWebhookConfiguration] {
+		err := remote.kubeClient.AdmissionregistrationV1().ValidatingWebhookConfigurations().Delete(ctx, name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to delete validating webhook configuration %v", name)
+		}
+	}
+
+	for _, name := range remote.staleConfigurations[KindMutatingWebhookConfiguration] {
+		err := remote.kubeClient.AdmissionregistrationV1().MutatingWebhookConfigurations().Delete(ctx, name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to delete mutating webhook configuration %v", name)
+		}
+	}
+
+	return nil
+}
+
+// Cleanup does nothing, since the Checker does not create any resource.
+func (remote *Checker) Cleanup() error {
+	return nil
+}
+
+// checkConfiguration checks the webhooks of an admission webhook configuration of Longhorn, which
+// is either named as created by Longhorn or calls the admission webhook service of Longhorn.
+func (remote *Checker) checkConfiguration(ctx context.Context, kind, name, longhornName string, clientConfigs []admissionregistrationv1.WebhookClientConfig, collections map[string]*types.LogCollection) error {
+	isLonghorn := name == longhornName
+	for _, clientConfig := range clientConfigs {
+		if clientConfig.Service != nil && clientConfig.Service.Name == lhmgrtypes.AdmissionWebhookServiceName {
+			isLonghorn = true
+		}
+	}
+	if !isLonghorn {
+		return nil
+	}
+
+	collection := &types.LogCollection{}
+	collections[kind+"/"+name] = collection
+
+	stale := false
+	for _, clientConfig := range uniqueClientConfigs(clientConfigs) {
+		serviceExists, err := remote.checkClientConfig(ctx, &clientConfig, collection)
+		if err != nil {
+			return err
+		}
+		if !serviceExists {
+			stale = true
+		}
+	}
+
+	if stale {
+		remote.staleConfigurations[kind] = append(remote.staleConfigurations[kind], name)
+	}
+	return nil
+}
+
+// checkCustomResourceDefinitions checks the conversion webhook of the Longhorn CustomResourceDefinitions.
+// They share the same client config, so the result is reported once for all of them.
+func (remote *Checker) checkCustomResourceDefinitions(ctx context.Context, collections map[string]*types.LogCollection) error {
+	list, err := remote.dynamicClient.Resource(customResourceDefinitionResource).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return errors.Wrap(err, "failed to list CustomResourceDefinitions")
+	}
+
+	var names []string
+	var clientConfigs []admissionregistrationv1.WebhookClientConfig
+	for _, item := range list.Items {
+		var crd customResourceDefinition
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(item.Object, &crd); err != nil {
+			return errors.Wrapf(err, "failed to convert CustomResourceDefinition %v", item.GetName())
+		}
+
+		conversion := crd.Spec.Conversion
+		if crd.Spec.Group != longhornAPIGroup || conversion == nil || conversion.Strategy != "Webhook" || conversion.Webhook == nil || conversion.Webhook.ClientConfig == nil {
+			continue
+		}
+
+		names = append(names, crd.Name)
+		clientConfigs = append(clientConfigs, *conversion.Webhook.ClientConfig)
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	collection := &types.LogCollection{
+		Info: []string{fmt.Sprintf("%d Longhorn CustomResourceDefinitions use a conversion webhook", len(names))},
+	}
+	collections[KindCustomResourceDefinition+"/*."+longhornAPIGroup] = collection
+
+	for _, clientConfig := range uniqueClientConfigs(clientConfigs) {
+		if _, err := remote.checkClientConfig(ctx, &clientConfig, collection); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkCertificateSecret checks the expiry of the serving certificate of the Longhorn webhooks.
+func (remote *Checker) checkCertificateSecret(ctx context.Context, collections map[string]*types.LogCollection) error {
+	secret, err := remote.kubeClient.CoreV1().Secrets(remote.LonghornNamespace).Get(ctx, lhmgrtypes.CertName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to get secret %v", lhmgrtypes.CertName)
+	}
+
+	collection := &types.LogCollection{}
+	collections[KindSecret+"/"+lhmgrtypes.CertName] = collection
+	appendMessages(collection, checkCertificates("serving certificate", secret.Data["tls.crt"], time.Now()))
+	return nil
+}
+
+// checkClientConfig checks that the service of the webhook exists and has ready endpoints, and the
+// expiry of the CA bundle. It returns false when the service or its namespace no longer exists.
+func (remote *Checker) checkClientConfig(ctx context.Context, clientConfig *admissionregistrationv1.WebhookClientConfig, collection *types.LogCollection) (bool, error) {
+	appendMessages(collection, checkCertificates("CA bundle", clientConfig.CABundle, time.Now()))
+
+	if clientConfig.Service == nil {
+		if clientConfig.URL != nil {
+			collection.Info = append(collection.Info, fmt.Sprintf("Webhook calls URL %v, the endpoint is not checked", *clientConfig.URL))
+		}
+		return true, nil
+	}
+
+	service := clientConfig.Service
+	serviceName := service.Namespace + "/" + service.Name
+
+	_, err := remote.kubeClient.CoreV1().Services(service.Namespace).Get(ctx, service.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		collection.Error = append(collection.Error, fmt.Sprintf("Service %v does not exist, the configuration is stale", serviceName))
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to get service %v", serviceName)
+	}
+
+	endpointSlices, err := remote.kubeClient.DiscoveryV1().EndpointSlices(service.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: discoveryv1.LabelServiceName + "=" + service.Name,
+	})
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to list endpoints of service %v", serviceName)
+	}
+
+	readyEndpoints := countReadyEndpoints(endpointSlices.Items)
+	if readyEndpoints == 0 {
+		collection.Error = append(collection.Error, fmt.Sprintf("Service %v has no ready endpoint, requests to the webhook fail", serviceName))
+	} else {
+		collection.Info = append(collection.Info, fmt.Sprintf("Service %v has %d ready endpoints", serviceName, readyEndpoints))
+	}
+	return true, nil
+}
+
+// uniqueClientConfigs returns the client configs without duplicates, in order.
+func uniqueClientConfigs(clientConfigs []admissionregistrationv1.WebhookClientConfig) []admissionregistrationv1.WebhookClientConfig {
+	seen := map[string]bool{}
+	var unique []admissionregistrationv1.WebhookClientConfig
+	for _, clientConfig := range clientConfigs {
+		key := string(clientConfig.CABundle)
+		if clientConfig.Service != nil {
+			key += clientConfig.Service.Namespace + "/" + clientConfig.Service.Name
+		}
+		if clientConfig.URL != nil {
+			key += *clientConfig.URL
+		}
+
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, clientConfig)
+	}
+	return unique
+}
+
+// countReadyEndpoints returns the number of ready endpoints in the EndpointSlices. An endpoint
+// without ready condition is considered ready, as specified by the EndpointSlice API.
+func countReadyEndpoints(endpointSlices []discoveryv1.EndpointSlice) int {
+	count := 0
+	for _, endpointSlice := range endpointSlices {
+		for _, endpoint := range endpointSlice.Endpoints {
+			if endpoint.Conditions.Ready == nil || *endpoint.Conditions.Ready {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// checkCertificates returns the messages about the expiry of the PEM certificates at the time.
+func checkCertificates(description string, data []byte, now time.Time) *types.LogCollection {
+	collection := &types.LogCollection{}
+	if len(data) == 0 {
+		collection.Warn = append(collection.Warn, fmt.Sprintf("No %v", description))
+		return collection
+	}
+
+	found := false
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			collection.Error = append(collection.Error, fmt.Sprintf("Failed to parse %v: %v", description, err))
+			continue
+		}
+		found = true
+
+		subject := certificate.Subject.CommonName
+		expiry := certificate.NotAfter.UTC().Format(time.RFC3339)
+		switch {
+		case now.After(certificate.NotAfter):
+			collection.Error = append(collection.Error, fmt.Sprintf("Certificate %q of the %v expired at %v", subject, description, expiry))
+		case now.Before(certificate.NotBefore):
+			collection.Error = append(collection.Error, fmt.Sprintf("Certificate %q of the %v is not valid before %v", subject, description, certificate.NotBefore.UTC().Format(time.RFC3339)))
+		case certificate.NotAfter.Sub(now) < certificateExpiryWarning:
+			collection.Warn = append(collection.Warn, fmt.Sprintf("Certificate %q of the %v expires at %v", subject, description, expiry))
+		default:
+			collection.Info = append(collection.Info, fmt.Sprintf("Certificate %q of the %v is valid until %v", subject, description, expiry))
+		}
+	}
+
+	if !found && len(collection.Error) == 0 {
+		collection.Error = append(collection.Error, fmt.Sprintf("No certificate found in the %v", description))
+	}
+	return collection
+}
+
+// appendMessages appends the messages of the source to the collection.
+func appendMessages(collection, source *types.LogCollection) {
+	collection.Error = append(collection.Error, source.Error...)
+	collection.Warn = append(collection.Warn, source.Warn...)
+	collection.Info = append(collection.Info, source.Info...)
+}
@@ -0,0 +1,115 @@
+package webhook
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/utils/ptr"
+)
+
+func newCertificatePEM(t *testing.T, notBefore, notAfter time.Time) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "longhorn-webhook-ca"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestCheckCertificates(t *testing.T) {
+	now := time.Date(2024, 7, 16, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	tests := []struct {
+		name          string
+		data          []byte
+		expectedError string
+		expectedWarn  string
+		expectedInfo  string
+	}{
+		{"valid", newCertificatePEM(t, now.Add(-day), now.Add(365*day)), "", "", "is valid until 2025-07-16"},
+		{"expiring", newCertificatePEM(t, now.Add(-day), now.Add(7*day)), "", "expires at 2024-07-23", ""},
+		{"expired", newCertificatePEM(t, now.Add(-365*day), now.Add(-day)), "expired at 2024-07-15", "", ""},
+		{"not yet valid", newCertificatePEM(t, now.Add(day), now.Add(365*day)), "is not valid before", "", ""},
+		{"empty", nil, "", "No CA bundle", ""},
+		{"garbage", []byte("not a certificate"), "No certificate found", "", ""},
+	}
+
+	for _, test := range tests {
+		collection := checkCertificates("CA bundle", test.data, now)
+		for _, check := range []struct {
+			expected string
+			messages []string
+		}{
+			{test.expectedError, collection.Error},
+			{test.expectedWarn, collection.Warn},
+			{test.expectedInfo, collection.Info},
+		} {
+			if check.expected == "" {
+				if len(check.messages) > 0 {
+					t.Errorf("%s: unexpected messages %v", test.name, check.messages)
+				}
+				continue
+			}
+			if len(check.messages) != 1 || !strings.Contains(check.messages[0], check.expected) {
+				t.Errorf("%s: expected a message containing %q, got %v", test.name, check.expected, check.messages)
+			}
+		}
+	}
+}
+
+func TestCountReadyEndpoints(t *testing.T) {
+	endpointSlices := []discoveryv1.EndpointSlice{
+		{
+			Endpoints: []discoveryv1.Endpoint{
+				{Conditions: discoveryv1.EndpointConditions{Ready: ptr.To(true)}},
+				{Conditions: discoveryv1.EndpointConditions{Ready: ptr.To(false)}},
+			},
+		},
+		{
+			Endpoints: []discoveryv1.Endpoint{
+				{Conditions: discoveryv1.EndpointConditions{}},
+			},
+		},
+	}
+
+	if count := countReadyEndpoints(endpointSlices); count != 2 {
+		t.Errorf("expected 2 ready endpoints, got %d", count)
+	}
+}
+
+func TestUniqueClientConfigs(t *testing.T) {
+	service := &admissionregistrationv1.ServiceReference{Namespace: "longhorn-system", Name: "longhorn-admission-webhook"}
+	otherService := &admissionregistrationv1.ServiceReference{Namespace: "longhorn-system", Name: "longhorn-conversion-webhook"}
+
+	clientConfigs := []admissionregistrationv1.WebhookClientConfig{
+		{Service: service, CABundle: []byte("ca")},
+		{Service: service, CABundle: []byte("ca")},
+		{Service: otherService, CABundle: []byte("ca")},
+		{Service: service, CABundle: []byte("other-ca")},
+	}
+
+	if unique := uniqueClientConfigs(clientConfigs); len(unique) != 3 {
+		t.Errorf("expected 3 unique client configs, got %d", len(unique))
+	}
+}
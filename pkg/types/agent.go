@@ -0,0 +1,34 @@
+package types
+
+import "time"
+
+// AgentPhase is the phase of the command run by the node agent.
+type AgentPhase string
+
+const (
+	AgentPhaseRunning   AgentPhase = "Running"
+	AgentPhaseSucceeded AgentPhase = "Succeeded"
+	AgentPhaseFailed    AgentPhase = "Failed"
+)
+
+// AgentStatus is the status of the command run by the node agent.
+type AgentStatus struct {
+	Node           string     `json:"node,omitempty"`
+	Phase          AgentPhase `json:"phase"`
+	Command        []string   `json:"command"`
+	StartTime      time.Time  `json:"startTime"`
+	CompletionTime *time.Time `json:"completionTime,omitempty"`
+	ExitCode       int        `json:"exitCode"`
+	Error          string     `json:"error,omitempty"`
+	LastSequence   int64      `json:"lastSequence"` // Sequence of the latest progress event, 0 without any.
+}
+
+// AgentProgress is a progress event of the command run by the node agent, parsed from a line of its
+// JSON logs. Lines that are not JSON logs only have the message.
+type AgentProgress struct {
+	Sequence int64          `json:"sequence"` // Sequence of the event, starting from 1.
+	Time     time.Time      `json:"time"`
+	Level    string         `json:"level,omitempty"`
+	Message  string         `json:"message"`
+	Fields   map[string]any `json:"fields,omitempty"`
+}
@@ -0,0 +1,17 @@
+package types
+
+// AirgapBundleManifest describes the content of an air-gap bundle, and what the nodes without
+// internet access need to complete the preflight install from it.
+type AirgapBundleManifest struct {
+	Version         string `json:"version" yaml:"version"` // Version of longhornctl generating the bundle.
+	OperatingSystem string `json:"operatingSystem" yaml:"operatingSystem"`
+	PackageManager  string `json:"packageManager" yaml:"packageManager"`
+	Platform        string `json:"platform" yaml:"platform"`
+
+	Packages []string `json:"packages" yaml:"packages"` // Packages the preflight install installs from the package source.
+	Modules  []string `json:"modules" yaml:"modules"`   // Kernel modules the preflight install loads. They are provided by the kernel of the nodes, not by the bundle.
+	Services []string `json:"services" yaml:"services"` // Services the preflight install starts.
+
+	Image        string `json:"image" yaml:"image"`                                   // Utility image running longhornctl-local on the nodes.
+	ImageArchive string `json:"imageArchive,omitempty" yaml:"imageArchive,omitempty"` // Path of the docker-archive tarball of the image in the bundle. Empty when the image is not included.
+}
@@ -0,0 +1,39 @@
+package types
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type ApiJobStatus string
+
+const (
+	ApiJobStatusPending   = ApiJobStatus("pending")
+	ApiJobStatusRunning   = ApiJobStatus("running")
+	ApiJobStatusSucceeded = ApiJobStatus("succeeded")
+	ApiJobStatusFailed    = ApiJobStatus("failed")
+)
+
+// ApiJobRequest is the request body for submitting an operation to the API server.
+type ApiJobRequest struct {
+	Operation string          `json:"operation"`
+	Options   json.RawMessage `json:"options,omitempty"`
+}
+
+// ApiJob holds the state of an operation submitted to the API server.
+type ApiJob struct {
+	ID          string       `json:"id"`
+	Operation   string       `json:"operation"`
+	Status      ApiJobStatus `json:"status"`
+	Error       string       `json:"error,omitempty"`
+	CreatedAt   time.Time    `json:"createdAt"`
+	StartedAt   *time.Time   `json:"startedAt,omitempty"`
+	CompletedAt *time.Time   `json:"completedAt,omitempty"`
+}
+
+// ApiJobResult holds the result of a completed operation.
+type ApiJobResult struct {
+	ID     string       `json:"id"`
+	Status ApiJobStatus `json:"status"`
+	Result string       `json:"result"`
+}
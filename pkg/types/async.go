@@ -0,0 +1,17 @@
+package types
+
+import "time"
+
+// AsyncRun is a longhornctl command submitted with --no-wait to a Job in the cluster, recorded in
+// a ConfigMap named after its run ID. Its status is the status of the Job.
+type AsyncRun struct {
+	ID          string     `json:"id" yaml:"id"`
+	Command     string     `json:"command" yaml:"command"`
+	Namespace   string     `json:"namespace" yaml:"namespace"`
+	Job         string     `json:"job" yaml:"job"`
+	Status      string     `json:"status" yaml:"status"` // Pending, Running, Succeeded or Failed.
+	Message     string     `json:"message,omitempty" yaml:"message,omitempty"`
+	SubmittedAt time.Time  `json:"submittedAt" yaml:"submittedAt"`
+	CompletedAt *time.Time `json:"completedAt,omitempty" yaml:"completedAt,omitempty"`
+	Result      string     `json:"result,omitempty" yaml:"result,omitempty"` // ConfigMap the Job writes the structured result of the command to.
+}
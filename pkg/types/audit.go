@@ -0,0 +1,14 @@
+package types
+
+import "time"
+
+// AuditRecord describes a CLI operation modifying the cluster or the nodes.
+type AuditRecord struct {
+	User      string    // The Kubernetes user running the operation.
+	HostUser  string    // The local user and host running the CLI.
+	Command   string    // The command path, for example "longhornctl install preflight".
+	Flags     []string  // The flags set on the command line.
+	Args      []string  // The positional arguments, such as the volume name or the command of node exec.
+	StartTime time.Time // The time the operation started.
+	Error     error     // The error of the operation, if it failed.
+}
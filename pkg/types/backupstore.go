@@ -0,0 +1,29 @@
+package types
+
+// BackupStoreReport holds the consistency of the backed up volumes of a backupstore, and the space
+// pruning their orphaned blocks, dangling backups and stale locks reclaims.
+type BackupStoreReport struct {
+	Target           string              `json:"target" yaml:"target"`
+	Volumes          []BackupStoreVolume `json:"volumes" yaml:"volumes"`
+	ReclaimableBytes int64               `json:"reclaimableBytes" yaml:"reclaimableBytes"`
+	Pruned           bool                `json:"pruned" yaml:"pruned"`
+}
+
+// BackupStoreVolume is a backed up volume of a backupstore. The orphaned blocks are not referenced
+// by any completed backup, the missing blocks are referenced by completed backups but not found,
+// the dangling backups are unreadable or were left incomplete by a crashed backup, and the stale
+// locks were not refreshed by their holder for a long time. The orphaned blocks of a busy volume,
+// with a backup in progress or a lock held, are not reclaimable.
+type BackupStoreVolume struct {
+	Name             string   `json:"name" yaml:"name"`
+	Backups          int      `json:"backups" yaml:"backups"` // Completed backups.
+	Blocks           int      `json:"blocks" yaml:"blocks"`
+	OrphanedBlocks   int      `json:"orphanedBlocks" yaml:"orphanedBlocks"`
+	OrphanedBytes    int64    `json:"orphanedBytes" yaml:"orphanedBytes"`
+	MissingBlocks    int      `json:"missingBlocks" yaml:"missingBlocks"`
+	DanglingBackups  []string `json:"danglingBackups,omitempty" yaml:"danglingBackups,omitempty"`
+	StaleLocks       []string `json:"staleLocks,omitempty" yaml:"staleLocks,omitempty"`
+	Busy             bool     `json:"busy" yaml:"busy"`
+	ReclaimableBytes int64    `json:"reclaimableBytes" yaml:"reclaimableBytes"`
+	Issues           []string `json:"issues,omitempty" yaml:"issues,omitempty"`
+}
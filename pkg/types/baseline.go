@@ -0,0 +1,59 @@
+package types
+
+// Baseline is the recorded prerequisite state of the nodes, keyed by the node name, signed so that
+// changes to the document are detected when the nodes are checked against it.
+type Baseline struct {
+	CreatedAt string                        `json:"createdAt" yaml:"createdAt"` // RFC 3339 time of the recording.
+	Nodes     map[string]*NodePrerequisites `json:"nodes" yaml:"nodes"`
+	Signature string                        `json:"signature,omitempty" yaml:"signature,omitempty"` // HMAC-SHA256 of the document without the signature.
+}
+
+// NodePrerequisites holds the state of the prerequisites of Longhorn on a node.
+type NodePrerequisites struct {
+	OperatingSystem string `json:"operatingSystem,omitempty" yaml:"operatingSystem,omitempty"` // ID from /etc/os-release.
+	Kernel          string `json:"kernel,omitempty" yaml:"kernel,omitempty"`                   // Kernel release, as uname -r.
+
+	Packages map[string]string `json:"packages,omitempty" yaml:"packages,omitempty"` // installed or not installed, by package name.
+	Modules  map[string]string `json:"modules,omitempty" yaml:"modules,omitempty"`   // loaded or not loaded, by kernel module name.
+	Services map[string]string `json:"services,omitempty" yaml:"services,omitempty"` // Active and unit file states, by systemd unit name.
+	Sysctls  map[string]string `json:"sysctls,omitempty" yaml:"sysctls,omitempty"`   // Value, by sysctl parameter.
+
+	MultipathConfig string `json:"multipathConfig" yaml:"multipathConfig"` // SHA-256 checksum of /etc/multipath.conf, or absent.
+
+	Errors []string `json:"errors,omitempty" yaml:"errors,omitempty"` // Prerequisites that could not be read.
+}
+
+type BaselineDriftCategory string
+
+const (
+	BaselineDriftCategoryNode            = BaselineDriftCategory("node")
+	BaselineDriftCategoryOperatingSystem = BaselineDriftCategory("operatingSystem")
+	BaselineDriftCategoryKernel          = BaselineDriftCategory("kernel")
+	BaselineDriftCategoryPackage         = BaselineDriftCategory("package")
+	BaselineDriftCategoryModule          = BaselineDriftCategory("module")
+	BaselineDriftCategoryService         = BaselineDriftCategory("service")
+	BaselineDriftCategorySysctl          = BaselineDriftCategory("sysctl")
+	BaselineDriftCategoryMultipathConfig = BaselineDriftCategory("multipathConfig")
+)
+
+// BaselineDriftCollection represents the drift of the nodes from the baseline, keyed by the node
+// name. The nodes without drift have no drifts.
+type BaselineDriftCollection struct {
+	CreatedAt string                        `json:"createdAt" yaml:"createdAt"` // Time of the recording of the baseline.
+	Nodes     map[string]*NodeBaselineDrift `json:"nodes" yaml:"nodes"`
+}
+
+// NodeBaselineDrift holds the drift of a node from the baseline.
+type NodeBaselineDrift struct {
+	Drifts []BaselineDrift `json:"drifts,omitempty" yaml:"drifts,omitempty"`
+	Errors []string        `json:"errors,omitempty" yaml:"errors,omitempty"` // Prerequisites that could not be read.
+}
+
+// BaselineDrift is a prerequisite of a node with a state different from the baseline. An empty
+// state means the prerequisite or the node is absent.
+type BaselineDrift struct {
+	Category BaselineDriftCategory `json:"category" yaml:"category"`
+	Name     string                `json:"name,omitempty" yaml:"name,omitempty"`
+	Expected string                `json:"expected" yaml:"expected"`
+	Actual   string                `json:"actual" yaml:"actual"`
+}
@@ -0,0 +1,56 @@
+package types
+
+// VolumeBenchmarkReport holds the fio results on the local disk of the node and on a Longhorn volume.
+type VolumeBenchmarkReport struct {
+	Node     string     `json:"node" yaml:"node"`
+	Profile  string     `json:"profile" yaml:"profile"`
+	Size     string     `json:"size" yaml:"size"`
+	Baseline *FioResult `json:"baseline,omitempty" yaml:"baseline,omitempty"`
+	Longhorn *FioResult `json:"longhorn,omitempty" yaml:"longhorn,omitempty"`
+}
+
+// FioResult holds the summary of a fio run. The bandwidth is in KiB/s and the latency is the mean completion latency in microseconds.
+type FioResult struct {
+	ReadIOPS         float64 `json:"readIOPS" yaml:"readIOPS"`
+	WriteIOPS        float64 `json:"writeIOPS" yaml:"writeIOPS"`
+	ReadBandwidth    float64 `json:"readBandwidth" yaml:"readBandwidth"`
+	WriteBandwidth   float64 `json:"writeBandwidth" yaml:"writeBandwidth"`
+	ReadLatencyUsec  float64 `json:"readLatencyUsec" yaml:"readLatencyUsec"`
+	WriteLatencyUsec float64 `json:"writeLatencyUsec" yaml:"writeLatencyUsec"`
+}
+
+// NetworkBenchmarkReport holds the iperf3 results between each ordered pair of nodes.
+type NetworkBenchmarkReport struct {
+	Nodes    []string                 `json:"nodes" yaml:"nodes"`
+	Networks []string                 `json:"networks" yaml:"networks"`
+	Results  []NetworkBenchmarkResult `json:"results" yaml:"results"`
+}
+
+// NetworkBenchmarkResult holds the summary of an iperf3 run from the source node to the destination node.
+// The throughput is in bits per second and the latency is the mean TCP round-trip time in microseconds.
+type NetworkBenchmarkResult struct {
+	Network     string  `json:"network" yaml:"network"`
+	Source      string  `json:"source" yaml:"source"`
+	Destination string  `json:"destination" yaml:"destination"`
+	Throughput  float64 `json:"throughput" yaml:"throughput"`
+	LatencyUsec float64 `json:"latencyUsec" yaml:"latencyUsec"`
+	Retransmits int     `json:"retransmits" yaml:"retransmits"`
+	Error       string  `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// DiskBenchmarkReport holds the fio latency results on the data path of each node.
+type DiskBenchmarkReport struct {
+	DataPath            string              `json:"dataPath" yaml:"dataPath"`
+	MaxReadLatencyUsec  float64             `json:"maxReadLatencyUsec" yaml:"maxReadLatencyUsec"`
+	MaxWriteLatencyUsec float64             `json:"maxWriteLatencyUsec" yaml:"maxWriteLatencyUsec"`
+	Passed              bool                `json:"passed" yaml:"passed"`
+	Nodes               []DiskBenchmarkNode `json:"nodes" yaml:"nodes"`
+}
+
+// DiskBenchmarkNode is the result of the disk benchmark on a node. It fails when a latency exceeds its threshold.
+type DiskBenchmarkNode struct {
+	Node    string           `json:"node" yaml:"node"`
+	Status  VerifyStepStatus `json:"status" yaml:"status"`
+	Message string           `json:"message,omitempty" yaml:"message,omitempty"`
+	Result  *FioResult       `json:"result,omitempty" yaml:"result,omitempty"`
+}
@@ -0,0 +1,31 @@
+package types
+
+// CapacityReport holds the capacity and the forecast of each disk, and of each node as the total
+// of its disks.
+type CapacityReport struct {
+	GrowthWindow string             `json:"growthWindow,omitempty" yaml:"growthWindow,omitempty"`
+	Disks        []CapacityForecast `json:"disks" yaml:"disks"`
+	Nodes        []CapacityForecast `json:"nodes" yaml:"nodes"`
+}
+
+// CapacityForecast is the capacity of a disk or a node in bytes, and the estimated time until its
+// available storage reaches the threshold. The threshold is the reserved storage, or the minimal
+// available storage of the settings when it is larger. The scheduled percentage is relative to the
+// storage that can be scheduled, the maximum storage without the reserved storage.
+type CapacityForecast struct {
+	Node                string   `json:"node" yaml:"node"`
+	Disk                string   `json:"disk,omitempty" yaml:"disk,omitempty"`
+	Path                string   `json:"path,omitempty" yaml:"path,omitempty"`
+	Maximum             int64    `json:"maximum" yaml:"maximum"`
+	Reserved            int64    `json:"reserved" yaml:"reserved"`
+	Available           int64    `json:"available" yaml:"available"`
+	Used                int64    `json:"used" yaml:"used"`
+	Scheduled           int64    `json:"scheduled" yaml:"scheduled"`
+	Threshold           int64    `json:"threshold" yaml:"threshold"`
+	ScheduledPercentage float64  `json:"scheduledPercentage" yaml:"scheduledPercentage"`
+	OverProvisioned     bool     `json:"overProvisioned" yaml:"overProvisioned"` // Scheduled beyond the over-provisioning percentage setting.
+	GrowthPerDay        *int64   `json:"growthPerDay,omitempty" yaml:"growthPerDay,omitempty"`
+	DaysUntilThreshold  *float64 `json:"daysUntilThreshold,omitempty" yaml:"daysUntilThreshold,omitempty"`
+	ThresholdReachedAt  string   `json:"thresholdReachedAt,omitempty" yaml:"thresholdReachedAt,omitempty"`
+	Message             string   `json:"message,omitempty" yaml:"message,omitempty"`
+}
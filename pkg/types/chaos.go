@@ -0,0 +1,29 @@
+package types
+
+// FailoverReport is the resilience report of a volume to an injected failure.
+type FailoverReport struct {
+	Volume  string `json:"volume" yaml:"volume"`
+	Failure string `json:"failure" yaml:"failure"` // replica, engine or node-network.
+	Target  string `json:"target" yaml:"target"`   // Name of the replica or engine killed, or of the node partitioned.
+	Node    string `json:"node" yaml:"node"`       // Node of the failure.
+
+	InjectedAt string  `json:"injectedAt" yaml:"injectedAt"` // RFC 3339 time of the injection.
+	SLOSeconds float64 `json:"sloSeconds" yaml:"sloSeconds"` // Maximum time for the volume to recover.
+
+	Detected         bool    `json:"detected" yaml:"detected"`                                     // The volume left the healthy state.
+	DetectionSeconds float64 `json:"detectionSeconds,omitempty" yaml:"detectionSeconds,omitempty"` // Time from the injection to the detection.
+	Recovered        bool    `json:"recovered" yaml:"recovered"`                                   // The volume is attached and healthy again.
+	RecoverySeconds  float64 `json:"recoverySeconds,omitempty" yaml:"recoverySeconds,omitempty"`   // Time from the injection to the recovery.
+	Passed           bool    `json:"passed" yaml:"passed"`                                         // The volume recovered within the SLO.
+
+	Timeline []FailoverEvent `json:"timeline" yaml:"timeline"`
+	Message  string          `json:"message,omitempty" yaml:"message,omitempty"`
+}
+
+// FailoverEvent is a change of the state or the robustness of the volume after the injection.
+type FailoverEvent struct {
+	ElapsedSeconds float64 `json:"elapsedSeconds" yaml:"elapsedSeconds"`
+	State          string  `json:"state" yaml:"state"`
+	Robustness     string  `json:"robustness" yaml:"robustness"`
+	Replicas       int     `json:"replicas" yaml:"replicas"` // Running replicas of the volume.
+}
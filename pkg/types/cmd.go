@@ -2,8 +2,30 @@ package types
 
 // GlobalCmdOptions is the common options for all subcommands.
 type GlobalCmdOptions struct {
-	LogLevel       string // The log level for the CLI.
-	KubeConfigPath string // The path to the kubeconfig file.
-	Image          string // The image to use for local interactions.
-	NodeSelector   string // The node selector to choose nodes on which to run DaemonSet pods
+	LogLevel       string  // The log level for the CLI.
+	LogFormat      string  // The log format for the CLI (text or json).
+	LogFile        string  // The file to write the logs to, in addition to stderr.
+	Verbosity      int     // The verbosity level. Overrides the log level when set.
+	Quiet          bool    // Only output the final result to stdout.
+	NoColor        bool    // Disable colored output.
+	Lang           string  // The language of the results and their tables.
+	AssumeYes      bool    // Skip the confirmation prompts of destructive operations.
+	ForceUnlock    bool    // Take over the lock of another operation of the same kind.
+	KubeConfigPath string  // The path to the kubeconfig file.
+	KubeApiQps     float32 // The maximum rate of the requests to the Kubernetes API server, per second.
+	KubeApiBurst   int     // The maximum burst of the requests to the Kubernetes API server.
+	Image          string  // The image to use for local interactions.
+	Namespace      string  // The namespace to deploy the CLI-created resources in.
+	NodeSelector   string  // The node selector to choose nodes on which to run DaemonSet pods
+	MinNodes       int     // The minimum number of schedulable nodes the node selector must match.
+	PodCpu         string  // The CPU requests and limits of the containers of the CLI-created pods.
+	PodMemory      string  // The memory requests and limits of the containers of the CLI-created pods.
+	PriorityClass  string  // The PriorityClass of the CLI-created pods.
+	Privileged     bool    // Run the CLI-created pods privileged, instead of with only the capabilities each operation needs.
+	Proxy          string  // The HTTP(S) proxy for the CLI and the CLI-created pods. Overrides the proxy environment variables.
+	NoProxy        string  // The hosts excluded from the proxy. Overrides the NO_PROXY environment variable.
+	OutputTo       string  // The comma-separated destinations the structured results are written to.
+	NoWait         bool    // Submit the command to a Job in the cluster, and return its run ID without waiting for it.
+	Telemetry      bool    // Opt in to the telemetry.
+	TelemetryURL   string  // The endpoint the telemetry reports are sent to.
 }
@@ -0,0 +1,87 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// NodeUnreachableError is returned when a node cannot be reached, through the port-forward to its
+// node agent or over SSH.
+type NodeUnreachableError struct {
+	Node string
+	Err  error
+}
+
+func (err *NodeUnreachableError) Error() string {
+	return fmt.Sprintf("node %v is unreachable: %v", err.Node, err.Err)
+}
+
+func (err *NodeUnreachableError) Unwrap() error {
+	return err.Err
+}
+
+// PodSchedulingFailedError is returned when a pod created by the CLI cannot be scheduled, such as
+// when the node lacks the resources of the pod or has a taint it does not tolerate.
+type PodSchedulingFailedError struct {
+	Namespace string
+	Pod       string
+	Reason    string // Reason and message of the PodScheduled condition of the pod.
+}
+
+func (err *PodSchedulingFailedError) Error() string {
+	return fmt.Sprintf("pod %v/%v cannot be scheduled: %v", err.Namespace, err.Pod, err.Reason)
+}
+
+// PackageManagerError is returned when the package manager of a node fails, such as when a
+// package cannot be installed.
+type PackageManagerError struct {
+	Operation string // Operation of the package manager, such as "install package nfs-common". Empty when unknown.
+	Err       error
+}
+
+func (err *PackageManagerError) Error() string {
+	if err.Operation == "" {
+		return fmt.Sprintf("package manager failed: %v", err.Err)
+	}
+	return fmt.Sprintf("package manager failed to %v: %v", err.Operation, err.Err)
+}
+
+func (err *PackageManagerError) Unwrap() error {
+	return err.Err
+}
+
+// TimeoutError is returned when an operation does not complete in time.
+type TimeoutError struct {
+	Operation string // Operation that timed out, such as "waiting for the node agents of DaemonSet x".
+	Timeout   time.Duration
+}
+
+func (err *TimeoutError) Error() string {
+	return fmt.Sprintf("timed out %v after %v", err.Operation, err.Timeout)
+}
+
+// IsNodeUnreachable returns true if the error, or an error it wraps, is a NodeUnreachableError.
+func IsNodeUnreachable(err error) bool {
+	var target *NodeUnreachableError
+	return errors.As(err, &target)
+}
+
+// IsPodSchedulingFailed returns true if the error, or an error it wraps, is a
+// PodSchedulingFailedError.
+func IsPodSchedulingFailed(err error) bool {
+	var target *PodSchedulingFailedError
+	return errors.As(err, &target)
+}
+
+// IsPackageManagerError returns true if the error, or an error it wraps, is a PackageManagerError.
+func IsPackageManagerError(err error) bool {
+	var target *PackageManagerError
+	return errors.As(err, &target)
+}
+
+// IsTimeout returns true if the error, or an error it wraps, is a TimeoutError.
+func IsTimeout(err error) bool {
+	var target *TimeoutError
+	return errors.As(err, &target)
+}
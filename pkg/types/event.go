@@ -0,0 +1,14 @@
+package types
+
+import "time"
+
+// Event is a Kubernetes event of a Longhorn object, normalized for display.
+type Event struct {
+	Time    time.Time `json:"time" yaml:"time"`
+	Type    string    `json:"type" yaml:"type"`
+	Kind    string    `json:"kind" yaml:"kind"`
+	Name    string    `json:"name" yaml:"name"`
+	Reason  string    `json:"reason" yaml:"reason"`
+	Message string    `json:"message" yaml:"message"`
+	Count   int32     `json:"count,omitempty" yaml:"count,omitempty"`
+}
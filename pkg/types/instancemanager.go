@@ -0,0 +1,27 @@
+package types
+
+// InstanceManagerInfo holds the information of an instance manager and of the instances it hosts.
+// The CPU request is the one of the pod, and the guaranteed CPU is the one the settings ask for.
+type InstanceManagerInfo struct {
+	Name          string                `json:"name" yaml:"name"`
+	Node          string                `json:"node" yaml:"node"`
+	Type          string                `json:"type" yaml:"type"`
+	DataEngine    string                `json:"dataEngine" yaml:"dataEngine"`
+	State         string                `json:"state" yaml:"state"`
+	Image         string                `json:"image" yaml:"image"`
+	APIVersion    int                   `json:"apiVersion" yaml:"apiVersion"`
+	CPURequest    string                `json:"cpuRequest,omitempty" yaml:"cpuRequest,omitempty"`
+	GuaranteedCPU string                `json:"guaranteedCPU,omitempty" yaml:"guaranteedCPU,omitempty"`
+	Instances     []InstanceProcessInfo `json:"instances,omitempty" yaml:"instances,omitempty"`
+	Processes     string                `json:"processes,omitempty" yaml:"processes,omitempty"`
+}
+
+// InstanceProcessInfo holds the state of an engine or replica instance, as reported by its instance manager.
+type InstanceProcessInfo struct {
+	Name      string `json:"name" yaml:"name"`
+	Type      string `json:"type" yaml:"type"`
+	State     string `json:"state" yaml:"state"`
+	PortStart int32  `json:"portStart,omitempty" yaml:"portStart,omitempty"`
+	PortEnd   int32  `json:"portEnd,omitempty" yaml:"portEnd,omitempty"`
+	Error     string `json:"error,omitempty" yaml:"error,omitempty"`
+}
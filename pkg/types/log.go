@@ -5,4 +5,6 @@ type LogCollection struct {
 	Error []string `json:"error,omitempty" yaml:"error,omitempty"`
 	Info  []string `json:"info,omitempty" yaml:"info,omitempty"`
 	Warn  []string `json:"warn,omitempty" yaml:"warn,omitempty"`
+
+	Skipped []string `json:"skipped,omitempty" yaml:"skipped,omitempty"` // Reasons the node was skipped.
 }
@@ -0,0 +1,80 @@
+package types
+
+// NodeFactsCollection represents the hardware and operating system inventories of the nodes, keyed
+// by the node name.
+type NodeFactsCollection struct {
+	Nodes map[string]*NodeFacts `json:"nodes" yaml:"nodes"`
+}
+
+// NodeFacts holds the hardware and operating system inventory of a node relevant to storage.
+type NodeFacts struct {
+	Kernel          string              `json:"kernel,omitempty" yaml:"kernel,omitempty"` // Kernel release, as uname -r.
+	OperatingSystem NodeOperatingSystem `json:"operatingSystem" yaml:"operatingSystem"`
+	CPU             NodeCPU             `json:"cpu" yaml:"cpu"`
+	Memory          NodeMemory          `json:"memory" yaml:"memory"`
+
+	NUMANodes         []NodeNUMANode         `json:"numaNodes,omitempty" yaml:"numaNodes,omitempty"`
+	Disks             []NodeDisk             `json:"disks,omitempty" yaml:"disks,omitempty"`
+	NetworkInterfaces []NodeNetworkInterface `json:"networkInterfaces,omitempty" yaml:"networkInterfaces,omitempty"`
+	Modules           []NodeModule           `json:"modules,omitempty" yaml:"modules,omitempty"` // Loaded kernel modules.
+
+	Errors []string `json:"errors,omitempty" yaml:"errors,omitempty"` // Facts that could not be collected.
+}
+
+// NodeOperatingSystem holds the operating system of a node, from /etc/os-release.
+type NodeOperatingSystem struct {
+	ID         string `json:"id,omitempty" yaml:"id,omitempty"`
+	VersionID  string `json:"versionID,omitempty" yaml:"versionID,omitempty"`
+	PrettyName string `json:"prettyName,omitempty" yaml:"prettyName,omitempty"`
+}
+
+// NodeCPU holds the CPUs of a node, from /proc/cpuinfo.
+type NodeCPU struct {
+	Architecture string `json:"architecture,omitempty" yaml:"architecture,omitempty"`
+	Model        string `json:"model,omitempty" yaml:"model,omitempty"`
+	LogicalCPUs  int    `json:"logicalCPUs" yaml:"logicalCPUs"`
+	Sockets      int    `json:"sockets,omitempty" yaml:"sockets,omitempty"`
+}
+
+// NodeMemory holds the memory of a node, from /proc/meminfo.
+type NodeMemory struct {
+	TotalBytes        int64 `json:"totalBytes" yaml:"totalBytes"`
+	AvailableBytes    int64 `json:"availableBytes" yaml:"availableBytes"`
+	HugePagesTotal    int64 `json:"hugePagesTotal" yaml:"hugePagesTotal"`
+	HugePageSizeBytes int64 `json:"hugePageSizeBytes,omitempty" yaml:"hugePageSizeBytes,omitempty"`
+}
+
+// NodeNUMANode holds a NUMA node of a node, from /sys/devices/system/node.
+type NodeNUMANode struct {
+	ID         int    `json:"id" yaml:"id"`
+	CPUs       string `json:"cpus" yaml:"cpus"` // CPU list, for example 0-7,16-23.
+	TotalBytes int64  `json:"totalBytes" yaml:"totalBytes"`
+}
+
+// NodeDisk holds a physical disk of a node, from /sys/block.
+type NodeDisk struct {
+	Name       string `json:"name" yaml:"name"`
+	Transport  string `json:"transport,omitempty" yaml:"transport,omitempty"` // nvme, scsi, virtio, mmc or xen.
+	Model      string `json:"model,omitempty" yaml:"model,omitempty"`
+	Serial     string `json:"serial,omitempty" yaml:"serial,omitempty"`
+	Firmware   string `json:"firmware,omitempty" yaml:"firmware,omitempty"`
+	SizeBytes  int64  `json:"sizeBytes" yaml:"sizeBytes"`
+	Rotational bool   `json:"rotational" yaml:"rotational"`
+	Removable  bool   `json:"removable,omitempty" yaml:"removable,omitempty"`
+}
+
+// NodeNetworkInterface holds a network interface of a node, from /sys/class/net.
+type NodeNetworkInterface struct {
+	Name       string `json:"name" yaml:"name"`
+	MACAddress string `json:"macAddress,omitempty" yaml:"macAddress,omitempty"`
+	State      string `json:"state,omitempty" yaml:"state,omitempty"`         // Operational state, for example up or down.
+	SpeedMbps  int    `json:"speedMbps,omitempty" yaml:"speedMbps,omitempty"` // Link speed, unknown for the interfaces down and most virtual ones.
+	MTU        int    `json:"mtu,omitempty" yaml:"mtu,omitempty"`
+	Virtual    bool   `json:"virtual,omitempty" yaml:"virtual,omitempty"`
+}
+
+// NodeModule holds a loaded kernel module of a node, from /proc/modules.
+type NodeModule struct {
+	Name    string `json:"name" yaml:"name"`
+	Version string `json:"version,omitempty" yaml:"version,omitempty"` // Version in /sys/module, only set by the modules declaring one.
+}
@@ -3,4 +3,33 @@ package types
 // NodeCollection represents a collection of nodes.
 type NodeCollection struct {
 	Log *LogCollection `json:"log,omitempty" yaml:"log,omitempty"`
+
+	StaleMounts []StaleMount `json:"staleMounts,omitempty" yaml:"staleMounts,omitempty"`
+}
+
+// StaleMount is a mount of the device of a Longhorn volume that is not attached to the node.
+type StaleMount struct {
+	Volume     string `json:"volume" yaml:"volume"`
+	Source     string `json:"source" yaml:"source"`
+	MountPoint string `json:"mountPoint" yaml:"mountPoint"`
+	PodUID     string `json:"podUID,omitempty" yaml:"podUID,omitempty"` // UID of the pod the volume is published to, empty for the staging mount.
+	Unmounted  bool   `json:"unmounted" yaml:"unmounted"`
+}
+
+// NodeExecResult is the output of a command run in the host namespaces of a node.
+type NodeExecResult struct {
+	Node     string   `json:"node" yaml:"node"`
+	Command  []string `json:"command" yaml:"command"`
+	Stdout   string   `json:"stdout" yaml:"stdout"`
+	Stderr   string   `json:"stderr" yaml:"stderr"`
+	ExitCode int      `json:"exitCode" yaml:"exitCode"`
+}
+
+// NodeCopyResult is a file copied between the local machine and a node.
+type NodeCopyResult struct {
+	Node        string `json:"node" yaml:"node"`
+	Source      string `json:"source" yaml:"source"`
+	Destination string `json:"destination" yaml:"destination"`
+	Size        int64  `json:"size" yaml:"size"` // Size in bytes.
+	SHA256      string `json:"sha256" yaml:"sha256"`
 }
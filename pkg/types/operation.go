@@ -0,0 +1,22 @@
+package types
+
+import "time"
+
+// Operation holds a longhornctl operation running in the cluster, found by the workload it created.
+type Operation struct {
+	Name      string           `json:"name" yaml:"name"`
+	Namespace string           `json:"namespace" yaml:"namespace"`
+	Kind      string           `json:"kind" yaml:"kind"` // Kind of the workload, DaemonSet or Pod.
+	StartTime time.Time        `json:"startTime" yaml:"startTime"`
+	Nodes     []*OperationNode `json:"nodes,omitempty" yaml:"nodes,omitempty"`
+}
+
+// OperationNode holds the state of the pod of an operation on a node.
+type OperationNode struct {
+	Node      string    `json:"node,omitempty" yaml:"node,omitempty"` // Empty until the pod is scheduled.
+	Pod       string    `json:"pod" yaml:"pod"`
+	State     string    `json:"state" yaml:"state"` // Phase of the pod, or the reason its containers are waiting or terminated.
+	Ready     bool      `json:"ready" yaml:"ready"`
+	Restarts  int32     `json:"restarts,omitempty" yaml:"restarts,omitempty"`
+	StartTime time.Time `json:"startTime" yaml:"startTime"`
+}
@@ -0,0 +1,106 @@
+package types
+
+type CustomCheckSeverity string
+
+const (
+	CustomCheckSeverityError = CustomCheckSeverity("error")
+	CustomCheckSeverityWarn  = CustomCheckSeverity("warn")
+	CustomCheckSeverityInfo  = CustomCheckSeverity("info")
+)
+
+// CustomCheckList holds the custom preflight checks defined by the user.
+type CustomCheckList struct {
+	Checks []CustomCheck `json:"checks" yaml:"checks"`
+}
+
+// CustomCheck is a user defined preflight check running a shell command on the node.
+type CustomCheck struct {
+	Name string `json:"name" yaml:"name"`
+
+	// Command is the shell command to run in the host namespaces.
+	Command string `json:"command" yaml:"command"`
+
+	// ExpectedOutput is a regular expression the command output must match.
+	// When empty, the check passes if the command exits successfully.
+	ExpectedOutput string `json:"expectedOutput,omitempty" yaml:"expectedOutput,omitempty"`
+
+	// Severity is the level reported when the check fails. Defaults to error.
+	Severity CustomCheckSeverity `json:"severity,omitempty" yaml:"severity,omitempty"`
+}
+
+// KnownIssueList holds the known kernel, driver and firmware issues affecting Longhorn, matched
+// against the facts of each node by the preflight check.
+type KnownIssueList struct {
+	Issues []KnownIssue `json:"issues" yaml:"issues"`
+}
+
+// KnownIssue is a known bad kernel, driver or firmware combination.
+type KnownIssue struct {
+	ID string `json:"id" yaml:"id"`
+
+	Description string `json:"description" yaml:"description"`
+
+	// Link points to the details of the issue, such as the upstream bug or the fix.
+	Link string `json:"link,omitempty" yaml:"link,omitempty"`
+
+	// Severity is the level reported when the issue matches a node. Defaults to warn.
+	Severity CustomCheckSeverity `json:"severity,omitempty" yaml:"severity,omitempty"`
+
+	Match KnownIssueMatch `json:"match" yaml:"match"`
+}
+
+// KnownIssueMatch holds the regular expressions matching the facts of the nodes affected by a known
+// issue. A node is affected when all the non-empty ones match.
+type KnownIssueMatch struct {
+	Kernel          string `json:"kernel,omitempty" yaml:"kernel,omitempty"`                   // Kernel release, as uname -r.
+	OperatingSystem string `json:"operatingSystem,omitempty" yaml:"operatingSystem,omitempty"` // ID of /etc/os-release.
+
+	Module        string `json:"module,omitempty" yaml:"module,omitempty"`               // Name of a loaded kernel module.
+	ModuleVersion string `json:"moduleVersion,omitempty" yaml:"moduleVersion,omitempty"` // Version of the module, from /sys/module.
+
+	NVMeModel    string `json:"nvmeModel,omitempty" yaml:"nvmeModel,omitempty"`       // Model of an NVMe controller.
+	NVMeFirmware string `json:"nvmeFirmware,omitempty" yaml:"nvmeFirmware,omitempty"` // Firmware revision of the same NVMe controller.
+}
+
+// SeverityPolicy changes the level of the preflight check messages, so the organizations can encode
+// their own risk tolerance.
+type SeverityPolicy struct {
+	Rules []SeverityRule `json:"rules" yaml:"rules"`
+}
+
+// SeverityRule reports the messages matching a regular expression at another level. The first
+// matching rule of the policy applies.
+type SeverityRule struct {
+	// Match is a regular expression matched against the messages, such as "multipath".
+	Match string `json:"match" yaml:"match"`
+
+	// Severity is the level the matching messages are reported at.
+	Severity CustomCheckSeverity `json:"severity" yaml:"severity"`
+}
+
+// PreflightInstallProfiles maps groups of nodes to the options of the preflight install, such as
+// SPDK enabled only on the nodes with NVMe disks. Each node is installed with the first profile
+// matching it, and the nodes matching no profile with the options of the command.
+type PreflightInstallProfiles struct {
+	Profiles []PreflightInstallProfile `json:"profiles" yaml:"profiles"`
+}
+
+// PreflightInstallProfile overrides the options of the preflight install on the nodes matching its
+// node selector. The options left unset keep the value of the command.
+type PreflightInstallProfile struct {
+	Name string `json:"name" yaml:"name"`
+
+	// NodeSelector selects the nodes of the profile, in the format of --node-selector, such as
+	// "storage=nvme".
+	NodeSelector string `json:"nodeSelector" yaml:"nodeSelector"`
+
+	UpdatePackages *bool   `json:"updatePackages,omitempty" yaml:"updatePackages,omitempty"`
+	PackageSource  *string `json:"packageSource,omitempty" yaml:"packageSource,omitempty"`
+	TuneIscsid     *bool   `json:"tuneIscsid,omitempty" yaml:"tuneIscsid,omitempty"`
+	EnableSpdk     *bool   `json:"enableSpdk,omitempty" yaml:"enableSpdk,omitempty"`
+	SpdkOptions    *string `json:"spdkOptions,omitempty" yaml:"spdkOptions,omitempty"`
+	HugePageSize   *int    `json:"hugePageSize,omitempty" yaml:"hugePageSize,omitempty"`
+	HugePageNodes  *string `json:"hugePageNodes,omitempty" yaml:"hugePageNodes,omitempty"`
+	AllowPci       *string `json:"allowPci,omitempty" yaml:"allowPci,omitempty"`
+	DriverOverride *string `json:"driverOverride,omitempty" yaml:"driverOverride,omitempty"`
+}
@@ -0,0 +1,15 @@
+package types
+
+// ProtectionVolume is a volume with the recurring jobs protecting it and its latest backups, with
+// the issues leaving its data unprotected.
+type ProtectionVolume struct {
+	Name            string   `json:"name" yaml:"name"`
+	State           string   `json:"state" yaml:"state"`
+	SnapshotJobs    []string `json:"snapshotJobs" yaml:"snapshotJobs"` // Recurring jobs applying to the volume, by task.
+	BackupJobs      []string `json:"backupJobs" yaml:"backupJobs"`
+	LastBackup      string   `json:"lastBackup,omitempty" yaml:"lastBackup,omitempty"` // Latest completed backup.
+	LastBackupAt    string   `json:"lastBackupAt,omitempty" yaml:"lastBackupAt,omitempty"`
+	FailedBackups   int      `json:"failedBackups" yaml:"failedBackups"` // Failed backups since the latest completed backup.
+	LastBackupError string   `json:"lastBackupError,omitempty" yaml:"lastBackupError,omitempty"`
+	Issues          []string `json:"issues,omitempty" yaml:"issues,omitempty"`
+}
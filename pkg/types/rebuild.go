@@ -0,0 +1,24 @@
+package types
+
+// ReplicaRebuild is an in-progress rebuild of a replica.
+type ReplicaRebuild struct {
+	Volume    string `json:"volume" yaml:"volume"`
+	Replica   string `json:"replica" yaml:"replica"` // Replica being rebuilt.
+	Node      string `json:"node" yaml:"node"`       // Node of the replica being rebuilt.
+	Source    string `json:"source,omitempty" yaml:"source,omitempty"`
+	SizeBytes int64  `json:"sizeBytes" yaml:"sizeBytes"`
+	Progress  int    `json:"progress" yaml:"progress"` // Percentage of the volume rebuilt.
+
+	// Throughput over the sampling interval, 0 when the progress did not change.
+	ThroughputBytesPerSecond int64 `json:"throughputBytesPerSecond" yaml:"throughputBytesPerSecond"`
+	ETASeconds               int64 `json:"etaSeconds,omitempty" yaml:"etaSeconds,omitempty"` // Remaining time at the throughput, 0 when unknown.
+
+	Error string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// RebuildSettingChange is a change of a Longhorn setting throttling the rebuilds.
+type RebuildSettingChange struct {
+	Name     string `json:"name" yaml:"name"`
+	Previous string `json:"previous" yaml:"previous"`
+	Value    string `json:"value" yaml:"value"`
+}
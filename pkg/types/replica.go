@@ -17,7 +17,58 @@ type ReplicaInfo struct {
 	VolumeName        string                `json:"volumeName,omitempty" yaml:"volumeName,omitempty"`
 	Metadata          *lhmgrutil.VolumeMeta `json:"metadata,omitempty" yaml:"metadata,omitempty"`
 	ExportedDirectory string                `json:"exportedDirectory,omitempty" yaml:"exportedDirectory,omitempty"`
+	ExportedShare     string                `json:"exportedShare,omitempty" yaml:"exportedShare,omitempty"` // Network share serving the exported directory, with --share.
 
 	Error string `json:"error,omitempty" yaml:"error,omitempty"`
 	Warn  string `json:"warn,omitempty" yaml:"warn,omitempty"`
 }
+
+// ReplicaMetaCollection represents a collection of replica metadata inspections, keyed by
+// the name of the replica data directory.
+type ReplicaMetaCollection struct {
+	Replicas map[string]*ReplicaMetaInspection `json:"replicas" yaml:"replicas"`
+}
+
+// ReplicaMetaInspection holds the result of the inspection of the metadata files of a replica.
+type ReplicaMetaInspection struct {
+	Node       string `json:"node,omitempty" yaml:"node,omitempty"`
+	Directory  string `json:"directory,omitempty" yaml:"directory,omitempty"`
+	VolumeName string `json:"volumeName,omitempty" yaml:"volumeName,omitempty"`
+
+	Size  int64    `json:"size,omitempty" yaml:"size,omitempty"`   // Volume size in volume.meta, in bytes.
+	Head  string   `json:"head,omitempty" yaml:"head,omitempty"`   // Image file of the volume head.
+	Chain []string `json:"chain,omitempty" yaml:"chain,omitempty"` // Snapshot image files from the parent of the head to the oldest snapshot.
+
+	Issues []*ReplicaMetaIssue `json:"issues,omitempty" yaml:"issues,omitempty"`
+
+	Error string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// ReplicaMetaIssue holds an inconsistency found in the metadata files of a replica.
+type ReplicaMetaIssue struct {
+	Severity ReplicaMetaIssueSeverity `json:"severity" yaml:"severity"`
+	File     string                   `json:"file,omitempty" yaml:"file,omitempty"`
+	Message  string                   `json:"message" yaml:"message"`
+
+	// Repair describes the known-safe fix of the issue. It is empty when the issue must be fixed manually.
+	Repair   string `json:"repair,omitempty" yaml:"repair,omitempty"`
+	Repaired bool   `json:"repaired,omitempty" yaml:"repaired,omitempty"`
+}
+
+type ReplicaMetaIssueSeverity string
+
+const (
+	ReplicaMetaIssueSeverityError = ReplicaMetaIssueSeverity("error")
+	ReplicaMetaIssueSeverityWarn  = ReplicaMetaIssueSeverity("warn")
+)
+
+// ReplicaArchive is the archive of a replica data directory, with the snapshots and metadata files
+// of the replica and the SHA-256 manifest of these files.
+type ReplicaArchive struct {
+	Node    string `json:"node" yaml:"node"`
+	Replica string `json:"replica" yaml:"replica"` // Name of the replica data directory.
+	Format  string `json:"format" yaml:"format"`   // tar.zst or tar.gz.
+	File    string `json:"file" yaml:"file"`       // Local path of the archive.
+	Size    int64  `json:"size" yaml:"size"`       // Size of the archive in bytes.
+	SHA256  string `json:"sha256" yaml:"sha256"`   // Checksum of the archive.
+}
@@ -0,0 +1,8 @@
+package types
+
+// RestartStep is a pod of a Longhorn component to restart, in the order of the rolling restart.
+type RestartStep struct {
+	Node  string `json:"node" yaml:"node"`
+	Pod   string `json:"pod" yaml:"pod"`
+	Owner string `json:"owner" yaml:"owner"` // Kind/name of the controller recreating the pod.
+}
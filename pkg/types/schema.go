@@ -0,0 +1,80 @@
+package types
+
+// ResultSchemaVersion is the version of the schema of the structured outputs. It changes when a
+// field is removed or changes meaning, not when a field is added.
+const ResultSchemaVersion = "v1"
+
+const (
+	ResultKindAsyncRun                 = "AsyncRun"
+	ResultKindBackupStoreReport        = "BackupStoreReport"
+	ResultKindBaselineDriftCollection  = "BaselineDriftCollection"
+	ResultKindCSISnapshotLink          = "CSISnapshotLink"
+	ResultKindCapacityReport           = "CapacityReport"
+	ResultKindDiskBenchmarkReport      = "DiskBenchmarkReport"
+	ResultKindDrVolumeStatusList       = "DrVolumeStatusList"
+	ResultKindEvent                    = "Event"
+	ResultKindFailoverReport           = "FailoverReport"
+	ResultKindInstanceManagerList      = "InstanceManagerList"
+	ResultKindLogCollections           = "LogCollections"
+	ResultKindNetworkBenchmarkReport   = "NetworkBenchmarkReport"
+	ResultKindNodeCopyResult           = "NodeCopyResult"
+	ResultKindNodeExecResult           = "NodeExecResult"
+	ResultKindNodeFactsCollection      = "NodeFactsCollection"
+	ResultKindOperationList            = "OperationList"
+	ResultKindProtectionVolumeList     = "ProtectionVolumeList"
+	ResultKindRebuildSettingChangeList = "RebuildSettingChangeList"
+	ResultKindReplicaRebuildList       = "ReplicaRebuildList"
+	ResultKindReplicaMetaCollection    = "ReplicaMetaCollection"
+	ResultKindSnapshotList             = "SnapshotList"
+	ResultKindTelemetryStatus          = "TelemetryStatus"
+	ResultKindTopologyVolumeList       = "TopologyVolumeList"
+	ResultKindVerifyReport             = "VerifyReport"
+	ResultKindVersionInfo              = "VersionInfo"
+	ResultKindVolumeBenchmarkReport    = "VolumeBenchmarkReport"
+	ResultKindVolumeClusterMigration   = "VolumeClusterMigration"
+	ResultKindVolumeIOStats            = "VolumeIOStats"
+	ResultKindVolumeTrimResult         = "VolumeTrimResult"
+)
+
+// Result wraps a structured output with its schema version and kind, so tooling can validate it
+// against the JSON Schema printed by "longhornctl schema results".
+type Result struct {
+	SchemaVersion string `json:"schemaVersion" yaml:"schemaVersion"`
+	Kind          string `json:"kind" yaml:"kind"`
+	Result        any    `json:"result" yaml:"result"`
+}
+
+// ResultKinds maps each kind of structured output to a value of the type of its result.
+// The preflight and check results are LogCollections keyed by the node name, or by the
+// object checked.
+var ResultKinds = map[string]any{
+	ResultKindAsyncRun:                 AsyncRun{},
+	ResultKindBackupStoreReport:        BackupStoreReport{},
+	ResultKindBaselineDriftCollection:  BaselineDriftCollection{},
+	ResultKindCSISnapshotLink:          CSISnapshotLink{},
+	ResultKindCapacityReport:           CapacityReport{},
+	ResultKindDiskBenchmarkReport:      DiskBenchmarkReport{},
+	ResultKindDrVolumeStatusList:       []DrVolumeStatus{},
+	ResultKindEvent:                    Event{},
+	ResultKindFailoverReport:           FailoverReport{},
+	ResultKindInstanceManagerList:      []InstanceManagerInfo{},
+	ResultKindLogCollections:           map[string]*LogCollection{},
+	ResultKindNetworkBenchmarkReport:   NetworkBenchmarkReport{},
+	ResultKindNodeCopyResult:           NodeCopyResult{},
+	ResultKindNodeExecResult:           NodeExecResult{},
+	ResultKindNodeFactsCollection:      NodeFactsCollection{},
+	ResultKindOperationList:            []Operation{},
+	ResultKindProtectionVolumeList:     []ProtectionVolume{},
+	ResultKindRebuildSettingChangeList: []RebuildSettingChange{},
+	ResultKindReplicaRebuildList:       []ReplicaRebuild{},
+	ResultKindReplicaMetaCollection:    ReplicaMetaCollection{},
+	ResultKindSnapshotList:             []CreatedSnapshot{},
+	ResultKindTelemetryStatus:          TelemetryStatus{},
+	ResultKindTopologyVolumeList:       []TopologyVolume{},
+	ResultKindVerifyReport:             VerifyReport{},
+	ResultKindVersionInfo:              VersionInfo{},
+	ResultKindVolumeBenchmarkReport:    VolumeBenchmarkReport{},
+	ResultKindVolumeClusterMigration:   VolumeClusterMigration{},
+	ResultKindVolumeIOStats:            VolumeIOStats{},
+	ResultKindVolumeTrimResult:         VolumeTrimResult{},
+}
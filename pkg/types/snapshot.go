@@ -0,0 +1,30 @@
+package types
+
+// Quiesce steps of a snapshot, in the order they run.
+const (
+	SnapshotQuiescePreHook  = "pre-hook"
+	SnapshotQuiesceFsfreeze = "fsfreeze"
+	SnapshotQuiescePostHook = "post-hook"
+)
+
+// CreatedSnapshot is a snapshot taken of a volume, with the quiesce steps run on the pods using the
+// volume around it.
+type CreatedSnapshot struct {
+	Volume   string   `json:"volume" yaml:"volume"`
+	Snapshot string   `json:"snapshot" yaml:"snapshot"`
+	PVC      string   `json:"pvc,omitempty" yaml:"pvc,omitempty"`   // Namespace and name of the PVC of the volume.
+	Pods     []string `json:"pods,omitempty" yaml:"pods,omitempty"` // Pods of the workload using the volume.
+	Quiesce  []string `json:"quiesce,omitempty" yaml:"quiesce,omitempty"`
+}
+
+// CSISnapshotLink is a Longhorn snapshot or backup linked to a CSI VolumeSnapshot through a
+// VolumeSnapshotContent with its snapshot handle.
+type CSISnapshotLink struct {
+	Source                string `json:"source" yaml:"source"` // snapshot or backup.
+	Name                  string `json:"name" yaml:"name"`
+	Volume                string `json:"volume" yaml:"volume"`
+	SnapshotHandle        string `json:"snapshotHandle" yaml:"snapshotHandle"`
+	VolumeSnapshot        string `json:"volumeSnapshot" yaml:"volumeSnapshot"` // Namespace and name of the VolumeSnapshot.
+	VolumeSnapshotContent string `json:"volumeSnapshotContent" yaml:"volumeSnapshotContent"`
+	DeletionPolicy        string `json:"deletionPolicy" yaml:"deletionPolicy"`
+}
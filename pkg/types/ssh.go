@@ -0,0 +1,28 @@
+package types
+
+// SSHCmdOptions holds the options to run an operation on hosts over SSH instead of in a DaemonSet.
+type SSHCmdOptions struct {
+	Backend        string // The backend running the operation on the nodes (daemonset or ssh).
+	SSHHostsFile   string // Path to the YAML file listing the hosts to connect to.
+	SSHLocalBinary string // Path to the longhornctl-local binary to upload to the hosts.
+}
+
+// SSHHostList holds the hosts to run the operations on over SSH.
+type SSHHostList struct {
+	Hosts []SSHHost `json:"hosts" yaml:"hosts"`
+}
+
+// SSHHost is a host reachable over SSH. Authentication uses the identity file
+// when set, and the SSH agent and the SSH client configuration otherwise.
+type SSHHost struct {
+	// Name identifies the host in the result. Defaults to the address.
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+
+	Address      string `json:"address" yaml:"address"`
+	Port         int    `json:"port,omitempty" yaml:"port,omitempty"`
+	User         string `json:"user,omitempty" yaml:"user,omitempty"`
+	IdentityFile string `json:"identityFile,omitempty" yaml:"identityFile,omitempty"`
+
+	// Sudo runs the operation with sudo, which must not prompt for a password.
+	Sudo bool `json:"sudo,omitempty" yaml:"sudo,omitempty"`
+}
@@ -0,0 +1,40 @@
+package types
+
+// TelemetryConfig is the telemetry opt-in of the user, persisted in the user config directory.
+type TelemetryConfig struct {
+	Enabled bool `json:"enabled" yaml:"enabled"`
+
+	// Endpoint is the HTTP or HTTPS URL the reports are posted to.
+	Endpoint string `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+
+	// ID is the random identifier generated on opt-in, distinguishing the reports of the same user
+	// without identifying them. It is discarded when the telemetry is disabled.
+	ID string `json:"id,omitempty" yaml:"id,omitempty"`
+
+	// LastReport is the last report sent, kept for transparency.
+	LastReport *TelemetryReport `json:"lastReport,omitempty" yaml:"lastReport,omitempty"`
+}
+
+// TelemetryReport is the anonymized statistics of a run of the CLI. It holds no argument, flag
+// value, name or address.
+type TelemetryReport struct {
+	ID              string  `json:"id" yaml:"id"`
+	Version         string  `json:"version" yaml:"version"`
+	OS              string  `json:"os" yaml:"os"`
+	Arch            string  `json:"arch" yaml:"arch"`
+	Command         string  `json:"command" yaml:"command"` // The command path, for example "longhornctl check preflight".
+	DurationSeconds float64 `json:"durationSeconds" yaml:"durationSeconds"`
+	NodeCount       int     `json:"nodeCount,omitempty" yaml:"nodeCount,omitempty"` // Number of nodes of the cluster, when reachable.
+
+	// FailureCategory is the category of the error of a failed run, empty on success.
+	FailureCategory string `json:"failureCategory,omitempty" yaml:"failureCategory,omitempty"`
+}
+
+// TelemetryStatus is the telemetry opt-in reported by "longhornctl telemetry status".
+type TelemetryStatus struct {
+	TelemetryConfig
+
+	// DoNotTrack is set when the DO_NOT_TRACK environment variable disables the telemetry.
+	DoNotTrack bool   `json:"doNotTrack,omitempty" yaml:"doNotTrack,omitempty"`
+	ConfigFile string `json:"configFile" yaml:"configFile"`
+}
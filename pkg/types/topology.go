@@ -0,0 +1,29 @@
+package types
+
+// TopologyVolume maps a volume to the nodes, zones and regions of its replicas, with the
+// violations of its anti-affinity and its single failure domains.
+type TopologyVolume struct {
+	Name                 string            `json:"name" yaml:"name"`
+	State                string            `json:"state" yaml:"state"`
+	Robustness           string            `json:"robustness" yaml:"robustness"`
+	NumberOfReplicas     int               `json:"numberOfReplicas" yaml:"numberOfReplicas"`
+	NodeAntiAffinity     string            `json:"nodeAntiAffinity" yaml:"nodeAntiAffinity"` // Effective anti-affinity, "hard" or "soft".
+	ZoneAntiAffinity     string            `json:"zoneAntiAffinity" yaml:"zoneAntiAffinity"`
+	Nodes                []string          `json:"nodes" yaml:"nodes"` // Distinct nodes, zones and regions of the usable replicas.
+	Zones                []string          `json:"zones" yaml:"zones"`
+	Regions              []string          `json:"regions" yaml:"regions"`
+	Replicas             []TopologyReplica `json:"replicas" yaml:"replicas"`
+	Violations           []string          `json:"violations,omitempty" yaml:"violations,omitempty"`
+	SingleFailureDomains []string          `json:"singleFailureDomains,omitempty" yaml:"singleFailureDomains,omitempty"`
+}
+
+// TopologyReplica is a replica of a volume with the node, zone and region it is scheduled to.
+// A failed replica or a replica being deleted is not usable, and is not counted in the domains
+// of the volume.
+type TopologyReplica struct {
+	Name   string `json:"name" yaml:"name"`
+	Node   string `json:"node" yaml:"node"`
+	Zone   string `json:"zone" yaml:"zone"`
+	Region string `json:"region" yaml:"region"`
+	Usable bool   `json:"usable" yaml:"usable"`
+}
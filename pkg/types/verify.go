@@ -0,0 +1,23 @@
+package types
+
+type VerifyStepStatus string
+
+const (
+	VerifyStepStatusPass = VerifyStepStatus("pass")
+	VerifyStepStatusFail = VerifyStepStatus("fail")
+	VerifyStepStatusSkip = VerifyStepStatus("skip")
+)
+
+// VerifyReport holds the result of each step of a verification.
+type VerifyReport struct {
+	Passed bool         `json:"passed" yaml:"passed"`
+	Steps  []VerifyStep `json:"steps" yaml:"steps"`
+}
+
+// VerifyStep is the result of a verification step.
+type VerifyStep struct {
+	Name     string           `json:"name" yaml:"name"`
+	Status   VerifyStepStatus `json:"status" yaml:"status"`
+	Message  string           `json:"message,omitempty" yaml:"message,omitempty"`
+	Duration string           `json:"duration,omitempty" yaml:"duration,omitempty"`
+}
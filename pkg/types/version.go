@@ -0,0 +1,51 @@
+package types
+
+// VersionInfo is the version report of the CLI and the Longhorn deployed in the cluster.
+type VersionInfo struct {
+	ClientVersion ClientVersion  `json:"clientVersion" yaml:"clientVersion"`
+	ServerVersion *ServerVersion `json:"serverVersion,omitempty" yaml:"serverVersion,omitempty"`
+	Warnings      []string       `json:"warnings,omitempty" yaml:"warnings,omitempty"`
+}
+
+// ClientVersion is the build information of the CLI.
+type ClientVersion struct {
+	Version   string `json:"version" yaml:"version"`
+	GitCommit string `json:"gitCommit" yaml:"gitCommit"`
+	BuildDate string `json:"buildDate" yaml:"buildDate"`
+}
+
+// ServerVersion is the version of the Longhorn manager in the cluster.
+type ServerVersion struct {
+	LonghornVersion string `json:"longhornVersion" yaml:"longhornVersion"`
+}
+
+// ReleaseCatalog lists the Longhorn releases and the vulnerabilities affecting them, compared
+// against the installed Longhorn version by the version check.
+type ReleaseCatalog struct {
+	Releases []Release `json:"releases" yaml:"releases"`
+}
+
+// Release is a Longhorn release of the catalog.
+type Release struct {
+	Version string `json:"version" yaml:"version"`
+
+	// EndOfLife is set when the minor version of the release no longer receives patch releases.
+	EndOfLife bool `json:"endOfLife,omitempty" yaml:"endOfLife,omitempty"`
+
+	// CVEs are the vulnerabilities affecting the release.
+	CVEs []ReleaseCVE `json:"cves,omitempty" yaml:"cves,omitempty"`
+}
+
+// ReleaseCVE is a vulnerability affecting a release.
+type ReleaseCVE struct {
+	ID string `json:"id" yaml:"id"`
+
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+
+	// Link points to the advisory of the vulnerability.
+	Link string `json:"link,omitempty" yaml:"link,omitempty"`
+
+	// Severity is the CVSS severity of the vulnerability: critical, high, medium or low. The
+	// critical and high ones are reported as errors, the others as warnings.
+	Severity string `json:"severity,omitempty" yaml:"severity,omitempty"`
+}
@@ -8,4 +8,116 @@ type VolumeCollection struct {
 // ReplicaInfo holds information about a replica.
 type VolumeInfo struct {
 	Replicas []*ReplicaInfo `json:"replicas,omitempty" yaml:"replicas,omitempty"`
+
+	Error string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// VolumeFieldChange describes the change of a field of a Longhorn custom resource.
+type VolumeFieldChange struct {
+	Kind  string `json:"kind" yaml:"kind"`
+	Name  string `json:"name" yaml:"name"`
+	Field string `json:"field" yaml:"field"`
+	From  string `json:"from" yaml:"from"`
+	To    string `json:"to" yaml:"to"`
+}
+
+// DrVolumeStatus is the restore status of a disaster recovery volume, a standby volume
+// incrementally restoring the backups of a volume from the backup target.
+type DrVolumeStatus struct {
+	Name               string `json:"name" yaml:"name"`
+	BackupTarget       string `json:"backupTarget" yaml:"backupTarget"`
+	BackupVolume       string `json:"backupVolume" yaml:"backupVolume"`
+	State              string `json:"state" yaml:"state"`
+	Standby            bool   `json:"standby" yaml:"standby"`
+	LastRestoredBackup string `json:"lastRestoredBackup" yaml:"lastRestoredBackup"`
+	LastRestoredAt     string `json:"lastRestoredAt" yaml:"lastRestoredAt"`
+	LatestBackup       string `json:"latestBackup" yaml:"latestBackup"`
+	LatestBackupAt     string `json:"latestBackupAt" yaml:"latestBackupAt"`
+	UpToDate           bool   `json:"upToDate" yaml:"upToDate"`
+	Lag                string `json:"lag" yaml:"lag"`
+	LagSeconds         int64  `json:"lagSeconds" yaml:"lagSeconds"` // -1 when no backup is restored yet.
+	Message            string `json:"message,omitempty" yaml:"message,omitempty"`
+}
+
+// VolumeIOStats is the IO of a volume sampled at an interval.
+type VolumeIOStats struct {
+	Volume          string               `json:"volume" yaml:"volume"`
+	Node            string               `json:"node" yaml:"node"` // Node of the engine of the volume.
+	IntervalSeconds float64              `json:"intervalSeconds" yaml:"intervalSeconds"`
+	Samples         []VolumeIOSample     `json:"samples" yaml:"samples"`
+	Summary         []VolumeIOStatsRange `json:"summary" yaml:"summary"`
+}
+
+// VolumeIOSample is the IO of a volume reported by its engine at a point in time. The throughput is
+// in bytes per second, and the latency in nanoseconds.
+type VolumeIOSample struct {
+	Time            string `json:"time" yaml:"time"`
+	ReadIOPS        int64  `json:"readIOPS" yaml:"readIOPS"`
+	WriteIOPS       int64  `json:"writeIOPS" yaml:"writeIOPS"`
+	ReadThroughput  int64  `json:"readThroughput" yaml:"readThroughput"`
+	WriteThroughput int64  `json:"writeThroughput" yaml:"writeThroughput"`
+	ReadLatency     int64  `json:"readLatency" yaml:"readLatency"`
+	WriteLatency    int64  `json:"writeLatency" yaml:"writeLatency"`
+}
+
+// VolumeIOStatsRange is the minimum, average and maximum of a metric over the samples.
+type VolumeIOStatsRange struct {
+	Metric  string `json:"metric" yaml:"metric"`
+	Minimum int64  `json:"minimum" yaml:"minimum"`
+	Average int64  `json:"average" yaml:"average"`
+	Maximum int64  `json:"maximum" yaml:"maximum"`
+}
+
+type VolumeTrimSkipReason string
+
+const (
+	VolumeTrimSkipReasonDetached = VolumeTrimSkipReason("detached")
+	VolumeTrimSkipReasonFaulted  = VolumeTrimSkipReason("faulted")
+)
+
+// VolumeTrimResult is the space reclaimed by trimming the filesystem of a volume. The actual sizes
+// are the sizes of the data of the volume reported by Longhorn, in bytes, before and after the trim.
+type VolumeTrimResult struct {
+	Volume           string               `json:"volume" yaml:"volume"`
+	Node             string               `json:"node,omitempty" yaml:"node,omitempty"` // Node the volume is attached to.
+	AccessMode       string               `json:"accessMode" yaml:"accessMode"`
+	Trimmed          bool                 `json:"trimmed" yaml:"trimmed"`
+	SkippedReason    VolumeTrimSkipReason `json:"skippedReason,omitempty" yaml:"skippedReason,omitempty"`
+	ActualSizeBefore int64                `json:"actualSizeBefore" yaml:"actualSizeBefore"`
+	ActualSizeAfter  int64                `json:"actualSizeAfter" yaml:"actualSizeAfter"`
+	ReclaimedBytes   int64                `json:"reclaimedBytes" yaml:"reclaimedBytes"`
+	Duration         string               `json:"duration,omitempty" yaml:"duration,omitempty"`
+	DurationSeconds  float64              `json:"durationSeconds" yaml:"durationSeconds"`
+}
+
+// Steps of the migration of a volume to another cluster, in order.
+const (
+	VolumeClusterMigrationStepBackup  = "backup"  // Back up the volume on the source cluster.
+	VolumeClusterMigrationStepRestore = "restore" // Restore the backup, or activate the DR volume, on the target cluster.
+	VolumeClusterMigrationStepPVC     = "pvc"     // Recreate the PV and the PVC of the volume on the target cluster.
+)
+
+// Modes of the migration of a volume to another cluster.
+const (
+	VolumeClusterMigrationModeRestore = "restore" // Restore the final backup into a new volume.
+	VolumeClusterMigrationModeDr      = "dr"      // Activate the existing DR volume of the volume.
+)
+
+// VolumeClusterMigration is the state of the migration of a volume to another cluster. It is saved
+// in the source cluster after each step, so an interrupted migration resumes with the next step.
+type VolumeClusterMigration struct {
+	Volume         string                       `json:"volume" yaml:"volume"`
+	TargetVolume   string                       `json:"targetVolume" yaml:"targetVolume"`
+	Mode           string                       `json:"mode" yaml:"mode"`
+	Backup         string                       `json:"backup,omitempty" yaml:"backup,omitempty"` // Final backup of the volume.
+	SourcePV       string                       `json:"sourcePV,omitempty" yaml:"sourcePV,omitempty"`
+	TargetPVC      string                       `json:"targetPVC,omitempty" yaml:"targetPVC,omitempty"` // Namespace/name, empty when the volume has no PV.
+	StartedAt      string                       `json:"startedAt" yaml:"startedAt"`
+	CompletedSteps []VolumeClusterMigrationStep `json:"completedSteps" yaml:"completedSteps"`
+}
+
+// VolumeClusterMigrationStep is a completed step of the migration of a volume to another cluster.
+type VolumeClusterMigrationStep struct {
+	Name        string `json:"name" yaml:"name"`
+	CompletedAt string `json:"completedAt" yaml:"completedAt"`
 }
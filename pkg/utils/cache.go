@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/longhorn/cli/pkg/consts"
+)
+
+// GetCachedStrings returns the strings cached under the key if they are younger than
+// the TTL. Otherwise, it fetches the strings and caches them in the user cache directory.
+// Caching failures are ignored, since the cache only serves to speed up repeated calls.
+func GetCachedStrings(key string, ttl time.Duration, fetch func() ([]string, error)) ([]string, error) {
+	cacheFile := ""
+	if cacheDir, err := os.UserCacheDir(); err == nil {
+		hash := sha256.Sum256([]byte(key))
+		cacheFile = filepath.Join(cacheDir, consts.CmdLonghornctlRemote, hex.EncodeToString(hash[:8])+".json")
+	}
+
+	if cacheFile != "" {
+		if info, err := os.Stat(cacheFile); err == nil && time.Since(info.ModTime()) < ttl {
+			if data, err := os.ReadFile(cacheFile); err == nil {
+				var values []string
+				if err := json.Unmarshal(data, &values); err == nil {
+					return values, nil
+				}
+			}
+		}
+	}
+
+	values, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+
+	if cacheFile != "" {
+		data, err := json.Marshal(values)
+		if err == nil {
+			err = os.MkdirAll(filepath.Dir(cacheFile), 0700)
+		}
+		if err == nil {
+			err = os.WriteFile(cacheFile, data, 0600)
+		}
+		if err != nil {
+			logrus.WithError(err).Debug("Failed to cache values")
+		}
+	}
+
+	return values, nil
+}
@@ -2,10 +2,12 @@ package utils
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"reflect"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
@@ -18,17 +20,48 @@ import (
 	"github.com/longhorn/cli/pkg/types"
 )
 
+// OutputToUsage is the usage of the --output-to option of the remote commands.
+const OutputToUsage = "Comma-separated list of destinations to write the structured result to, each optionally prefixed with its format (json, yaml): stdout, file://<path>, s3://<bucket>/<key> or configmap://[<namespace>/]<name>. For example stdout,json=file:///tmp/result.json,s3://bucket/key. The result is only printed to stdout when listed. S3 uses the AWS_* credentials, region and endpoint environment variables"
+
+// LangUsage is the usage of the --lang option of the remote commands.
+const LangUsage = "Language of the result messages and tables (en, zh). The logs stay in English"
+
 // SetGlobalOptionsLocal sets global options for local commands.
 func SetGlobalOptionsLocal(cmd *cobra.Command, globalOpts *types.GlobalCmdOptions) {
 	cmd.PersistentFlags().StringVarP(&globalOpts.LogLevel, consts.CmdOptLogLevel, "l", globalOpts.LogLevel, "Log level")
+	cmd.PersistentFlags().CountVarP(&globalOpts.Verbosity, consts.CmdOptVerbosity, "v", "Verbosity level, -v for debug and -vv for trace. Overrides the log level")
+	cmd.PersistentFlags().BoolVar(&globalOpts.Quiet, consts.CmdOptQuiet, globalOpts.Quiet, "Only output the final result to stdout, and errors to stderr")
+	cmd.PersistentFlags().BoolVar(&globalOpts.NoColor, consts.CmdOptNoColor, globalOpts.NoColor, "Disable colored output. Also disabled by the "+consts.EnvNoColor+" environment variable")
+	cmd.PersistentFlags().StringVar(&globalOpts.LogFormat, consts.CmdOptLogFormat, globalOpts.LogFormat, "Log format (text, json)")
+	cmd.PersistentFlags().StringVar(&globalOpts.LogFile, consts.CmdOptLogFile, globalOpts.LogFile, "Write the logs to the file in addition to stderr")
 }
 
 // SetGlobalOptionsRemote sets global options for remote commands.
 func SetGlobalOptionsRemote(cmd *cobra.Command, globalOpts *types.GlobalCmdOptions) {
 	cmd.PersistentFlags().StringVarP(&globalOpts.LogLevel, consts.CmdOptLogLevel, "l", globalOpts.LogLevel, "Log level")
+	cmd.PersistentFlags().CountVarP(&globalOpts.Verbosity, consts.CmdOptVerbosity, "v", "Verbosity level, -v for debug and -vv for trace. Overrides the log level")
+	cmd.PersistentFlags().BoolVar(&globalOpts.Quiet, consts.CmdOptQuiet, globalOpts.Quiet, "Only output the final result to stdout, and errors to stderr")
+	cmd.PersistentFlags().StringVar(&globalOpts.Lang, consts.CmdOptLang, globalOpts.Lang, LangUsage)
+	cmd.PersistentFlags().StringVar(&globalOpts.LogFormat, consts.CmdOptLogFormat, globalOpts.LogFormat, "Log format (text, json)")
+	cmd.PersistentFlags().StringVar(&globalOpts.LogFile, consts.CmdOptLogFile, globalOpts.LogFile, "Write the logs to the file in addition to stderr")
+	cmd.PersistentFlags().BoolVarP(&globalOpts.AssumeYes, consts.CmdOptYes, "y", globalOpts.AssumeYes, "Skip the confirmation prompts of operations modifying the nodes or volumes")
+	cmd.PersistentFlags().BoolVar(&globalOpts.ForceUnlock, consts.CmdOptForceUnlock, globalOpts.ForceUnlock, "Take over the lock of another operation of the same kind in progress, when it is no longer running")
 	cmd.PersistentFlags().StringVar(&globalOpts.KubeConfigPath, consts.CmdOptKubeConfigPath, globalOpts.KubeConfigPath, "Kubernetes config (kubeconfig) path")
+	cmd.PersistentFlags().Float32Var(&globalOpts.KubeApiQps, consts.CmdOptKubeApiQps, globalOpts.KubeApiQps, "Maximum number of requests per second to the Kubernetes API server, shared by all the requests of the CLI")
+	cmd.PersistentFlags().IntVar(&globalOpts.KubeApiBurst, consts.CmdOptKubeApiBurst, globalOpts.KubeApiBurst, "Maximum burst of requests to the Kubernetes API server above the --"+consts.CmdOptKubeApiQps+" rate")
 	cmd.PersistentFlags().StringVar(&globalOpts.Image, consts.CmdOptImage, globalOpts.Image, "Image containing longhornctl-local")
+	cmd.PersistentFlags().StringVar(&globalOpts.Namespace, consts.CmdOptNamespace, globalOpts.Namespace, "Namespace to deploy the resources (DaemonSets, ConfigMaps, RBAC) created by the CLI")
 	cmd.PersistentFlags().StringVar(&globalOpts.NodeSelector, consts.CmdOptNodeSelector, globalOpts.NodeSelector, "Comma-separated list of key=value pairs to match against node labels, selecting the nodes the DaemonSet will run on (e.g. env=prod,zone=us-west).")
+	cmd.PersistentFlags().IntVar(&globalOpts.MinNodes, consts.CmdOptRequireMinNodes, globalOpts.MinNodes, "Fail before creating a DaemonSet when fewer schedulable Linux nodes match --"+consts.CmdOptNodeSelector+", to enforce the coverage expected by automation")
+	cmd.PersistentFlags().StringVar(&globalOpts.PodCpu, consts.CmdOptPodCpu, globalOpts.PodCpu, "CPU requests and limits of the containers of the pods created by the CLI (e.g. 500m)")
+	cmd.PersistentFlags().StringVar(&globalOpts.PodMemory, consts.CmdOptPodMemory, globalOpts.PodMemory, "Memory requests and limits of the containers of the pods created by the CLI (e.g. 256Mi)")
+	cmd.PersistentFlags().StringVar(&globalOpts.PriorityClass, consts.CmdOptPriorityClass, globalOpts.PriorityClass, "PriorityClass of the pods created by the CLI")
+	cmd.PersistentFlags().StringVar(&globalOpts.Proxy, consts.CmdOptProxy, globalOpts.Proxy, "HTTP(S) proxy URL for the CLI and the pods created by the CLI, for example to install packages behind a proxy, without credentials. Overrides the HTTP_PROXY and HTTPS_PROXY environment variables")
+	cmd.PersistentFlags().StringVar(&globalOpts.NoProxy, consts.CmdOptNoProxy, globalOpts.NoProxy, "Comma-separated list of hosts excluded from the proxy. Overrides the NO_PROXY environment variable")
+	cmd.PersistentFlags().StringVar(&globalOpts.OutputTo, consts.CmdOptOutputTo, globalOpts.OutputTo, OutputToUsage)
+	cmd.PersistentFlags().BoolVar(&globalOpts.Telemetry, consts.CmdOptTelemetry, globalOpts.Telemetry, "Opt in to sending anonymized usage statistics to --"+consts.CmdOptTelemetryURL+". The opt-in is persisted, see '"+consts.CmdLonghornctlRemote+" "+consts.SubCmdTelemetry+" --help'")
+	cmd.PersistentFlags().StringVar(&globalOpts.TelemetryURL, consts.CmdOptTelemetryURL, globalOpts.TelemetryURL, "HTTP or HTTPS URL the telemetry reports are sent to. Required on the first opt-in with --"+consts.CmdOptTelemetry)
+	cmd.PersistentFlags().BoolVar(&globalOpts.Privileged, consts.CmdOptPrivileged, globalOpts.Privileged, "Run the pods created by the CLI as privileged. When false, the pods only get the capabilities and host mounts each operation needs. The replica exporter and the Container-Optimized OS installer always run privileged")
 }
 
 // SetFlagHidden adds a option flag to the given command and mark it as hidden.
@@ -43,8 +76,16 @@ func SetFlagHidden(cmd *cobra.Command, option string) {
 }
 
 // SetLog initializes logrus.
-// It sets log level and timestamp format.
-func SetLog(logLevel string) error {
+// It sets log level, format and output, and the timestamp format.
+// The log level of the global options is updated to the effective log level,
+// so it can be passed on to the node agents.
+func SetLog(globalOpts *types.GlobalCmdOptions) error {
+	globalOpts.LogLevel = effectiveLogLevel(globalOpts)
+
+	logLevel := globalOpts.LogLevel
+	logFormat := globalOpts.LogFormat
+	logFile := globalOpts.LogFile
+
 	if err := setLogLevel(logLevel); err != nil {
 		return err
 	}
@@ -52,16 +93,40 @@ func SetLog(logLevel string) error {
 	// The default log formatter shows like this: INFO[0000].
 	// Set it to show full timestamp to give more information.
 	isFullTimestamp := true
-	setLogFormatter(isFullTimestamp)
+	disableColors := globalOpts.NoColor || os.Getenv(consts.EnvNoColor) != ""
+	if err := setLogFormatter(logFormat, isFullTimestamp, disableColors); err != nil {
+		return err
+	}
+
+	if err := setLogOutput(logFile); err != nil {
+		return err
+	}
 
 	logrus.WithFields(logrus.Fields{
-		"level":          logLevel,
+		"log-level":      logLevel,
+		"log-format":     logFormat,
+		"log-file":       logFile,
 		"full-timestamp": isFullTimestamp,
 	}).Trace("Initialized logger")
 
 	return nil
 }
 
+// effectiveLogLevel returns the log level to use. The verbosity takes precedence
+// over the quiet mode, which takes precedence over the log level.
+func effectiveLogLevel(globalOpts *types.GlobalCmdOptions) string {
+	switch {
+	case globalOpts.Verbosity >= 2:
+		return logrus.TraceLevel.String()
+	case globalOpts.Verbosity == 1:
+		return logrus.DebugLevel.String()
+	case globalOpts.Quiet:
+		return logrus.ErrorLevel.String()
+	default:
+		return globalOpts.LogLevel
+	}
+}
+
 func setLogLevel(logLevel string) error {
 	logrusLevel, err := logrus.ParseLevel(logLevel)
 	if err != nil {
@@ -72,17 +137,82 @@ func setLogLevel(logLevel string) error {
 	return nil
 }
 
-func setLogFormatter(isFullTimestamp bool) {
-	logrus.SetFormatter(&logrus.TextFormatter{FullTimestamp: isFullTimestamp})
+func setLogFormatter(logFormat string, isFullTimestamp, disableColors bool) error {
+	switch logFormat {
+	case "", consts.LogFormatText:
+		logrus.SetFormatter(&logrus.TextFormatter{FullTimestamp: isFullTimestamp, DisableColors: disableColors})
+	case consts.LogFormatJSON:
+		logrus.SetFormatter(&logrus.JSONFormatter{})
+	default:
+		return errors.Errorf("unsupported log format %q", logFormat)
+	}
+	return nil
+}
+
+// setLogOutput writes the logs to both stderr and the log file, if given.
+func setLogOutput(logFile string) error {
+	if logFile == "" {
+		return nil
+	}
+
+	_, err := commonio.CreateDirectory(filepath.Dir(logFile), time.Now())
+	if err != nil {
+		return errors.Wrap(err, "failed to create log file directory")
+	}
+
+	file, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return errors.Wrap(err, "failed to open log file")
+	}
+
+	logrus.SetOutput(io.MultiWriter(os.Stderr, file))
+	return nil
+}
+
+var errorHandlers []func(error)
+
+// RegisterErrorHandler registers a handler called by CheckErr with the error before exiting.
+func RegisterErrorHandler(handler func(error)) {
+	errorHandlers = append(errorHandlers, handler)
 }
 
-// CheckErr logs the error and exits with a non-zero code.
+// CheckErr logs the error and exits with the exit code of its category.
 // This is similar to cobra.CheckErr except that it prints with logrus.
 func CheckErr(err error) {
 	if err != nil {
 		logrus.Error(err)
-		os.Exit(1)
+
+		for _, handler := range errorHandlers {
+			handler(err)
+		}
+
+		os.Exit(ExitCode(err))
+	}
+}
+
+// ExitCode returns the exit code of the category of the error, or ExitCodeError when it has
+// no category.
+func ExitCode(err error) int {
+	switch {
+	case types.IsNodeUnreachable(err):
+		return consts.ExitCodeNodeUnreachable
+	case types.IsPodSchedulingFailed(err):
+		return consts.ExitCodePodSchedulingFailed
+	case types.IsPackageManagerError(err):
+		return consts.ExitCodePackageManagerError
+	case types.IsTimeout(err):
+		return consts.ExitCodeTimeout
+	default:
+		return consts.ExitCodeError
+	}
+}
+
+// SplitList returns the items of the comma-separated list, or nil when the list is empty.
+func SplitList(str string) []string {
+	if str == "" {
+		return nil
 	}
+	return strings.Split(str, consts.CmdOptSeperator)
 }
 
 // ConvertStringToTypeOrDefault converts a string to the type of the default value.
@@ -122,9 +252,23 @@ func ConvertStringToTypeOrDefault[T any](str string, defaultValue T) T {
 	return value
 }
 
+// PrintResult outputs the final result of a command. In quiet mode, only the result
+// is written to stdout so it can be piped. Otherwise, it is logged with the message.
+func PrintResult(globalOpts *types.GlobalCmdOptions, message string, result string) {
+	if globalOpts.Quiet {
+		fmt.Print(result)
+		if !strings.HasSuffix(result, "\n") {
+			fmt.Println()
+		}
+		return
+	}
+
+	logrus.Infof("%s:\n%v", message, result)
+}
+
 func HandleResult(resultBytes []byte, outputFile string, logger *logrus.Entry) error {
 	if len(outputFile) == 0 {
-		fmt.Printf("Result: \n%s\n", resultBytes)
+		fmt.Printf("%s%s\n", consts.LocalResultHeader, resultBytes)
 		return nil
 	}
 
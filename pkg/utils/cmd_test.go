@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func TestExitCode(t *testing.T) {
+	for name, test := range map[string]struct {
+		err      error
+		expected int
+	}{
+		"plain":            {err: errors.New("failed"), expected: consts.ExitCodeError},
+		"node unreachable": {err: errors.Wrap(&types.NodeUnreachableError{Node: "node-1", Err: errors.New("connection refused")}, "failed to run"), expected: consts.ExitCodeNodeUnreachable},
+		"pod scheduling":   {err: &types.PodSchedulingFailedError{Namespace: "longhorn-system", Pod: "pod", Reason: "Unschedulable"}, expected: consts.ExitCodePodSchedulingFailed},
+		"package manager":  {err: errors.Wrap(&types.PackageManagerError{Operation: "install package nfs-common", Err: errors.New("exit status 100")}, "failed to install"), expected: consts.ExitCodePackageManagerError},
+		"timeout":          {err: errors.Wrap(&types.TimeoutError{Operation: "waiting for the node agents", Timeout: time.Hour}, "failed"), expected: consts.ExitCodeTimeout},
+	} {
+		if exitCode := ExitCode(test.err); exitCode != test.expected {
+			t.Errorf("%v: exit code %v, expected %v", name, exitCode, test.expected)
+		}
+	}
+}
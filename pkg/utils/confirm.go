@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+)
+
+// Confirm asks the user to confirm a destructive operation described by the summary.
+// It returns nil when the --yes option is set or the user answers yes, and an
+// error when the user declines or stdin is not a terminal to prompt on.
+func Confirm(globalOpts *types.GlobalCmdOptions, summary string) error {
+	if globalOpts.AssumeYes {
+		return nil
+	}
+
+	if !IsTerminal(os.Stdin) {
+		return errors.Errorf("%s\nConfirmation is required, use --%s to proceed in non-interactive mode", summary, consts.CmdOptYes)
+	}
+
+	confirmed, err := promptConfirm(os.Stdin, os.Stderr, summary)
+	if err != nil {
+		return errors.Wrap(err, "failed to read confirmation")
+	}
+
+	if !confirmed {
+		return errors.New("operation aborted")
+	}
+	return nil
+}
+
+func promptConfirm(in io.Reader, out io.Writer, summary string) (bool, error) {
+	fmt.Fprintf(out, "%s\nDo you want to continue? [y/N]: ", summary)
+
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// DescribeNodeSelector returns a short description of the nodes selected by the node selector.
+func DescribeNodeSelector(nodeSelector string) string {
+	if nodeSelector == "" {
+		return "all nodes"
+	}
+	return fmt.Sprintf("nodes matching %q", nodeSelector)
+}
@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+)
+
+type errorReader struct{}
+
+func (errorReader) Read([]byte) (int, error) {
+	return 0, errors.New("read failed")
+}
+
+func TestPromptConfirm(t *testing.T) {
+	for name, test := range map[string]struct {
+		input     string
+		confirmed bool
+	}{
+		"y":            {input: "y\n", confirmed: true},
+		"yes":          {input: "yes\n", confirmed: true},
+		"Y":            {input: "Y\n", confirmed: true},
+		"YES spaces":   {input: "  YES  \n", confirmed: true},
+		"yes at EOF":   {input: "yes", confirmed: true},
+		"empty":        {input: "\n"},
+		"n":            {input: "n\n"},
+		"no":           {input: "no\n"},
+		"garbage":      {input: "sure\n"},
+		"yes and more": {input: "yes please\n"},
+		"EOF":          {input: ""},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var out bytes.Buffer
+			confirmed, err := promptConfirm(strings.NewReader(test.input), &out, "Delete volume pvc-1234.")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if confirmed != test.confirmed {
+				t.Errorf("expected confirmed %v for input %q, got %v", test.confirmed, test.input, confirmed)
+			}
+			if expected := "Delete volume pvc-1234.\nDo you want to continue? [y/N]: "; out.String() != expected {
+				t.Errorf("expected prompt %q, got %q", expected, out.String())
+			}
+		})
+	}
+}
+
+func TestPromptConfirmReadError(t *testing.T) {
+	var out bytes.Buffer
+	confirmed, err := promptConfirm(errorReader{}, &out, "Delete volume pvc-1234.")
+	if err == nil {
+		t.Error("expected an error")
+	}
+	if confirmed {
+		t.Error("expected the operation not to be confirmed")
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %v", err)
+	}
+	defer reader.Close()
+	defer writer.Close()
+
+	// Answer yes on stdin, which must not be read since it is not a terminal.
+	if _, err := writer.WriteString("yes\n"); err != nil {
+		t.Fatalf("failed to write to pipe: %v", err)
+	}
+
+	stdin := os.Stdin
+	os.Stdin = reader
+	defer func() { os.Stdin = stdin }()
+
+	if err := Confirm(&types.GlobalCmdOptions{AssumeYes: true}, "Delete volume pvc-1234."); err != nil {
+		t.Errorf("expected --%s to skip the prompt, got %v", consts.CmdOptYes, err)
+	}
+
+	err = Confirm(&types.GlobalCmdOptions{}, "Delete volume pvc-1234.")
+	if err == nil {
+		t.Fatal("expected an error without a terminal to prompt on")
+	}
+	if !strings.Contains(err.Error(), "--"+consts.CmdOptYes) || !strings.Contains(err.Error(), "Delete volume pvc-1234.") {
+		t.Errorf("expected the error to describe the operation and suggest --%s, got %v", consts.CmdOptYes, err)
+	}
+}
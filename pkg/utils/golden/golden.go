@@ -0,0 +1,39 @@
+// Package golden compares the manifests generated in the tests with the golden files of the
+// testdata directory of the package.
+package golden
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// update rewrites the golden files with the generated output, after an intended change:
+//
+//	go test ./pkg/remote/job/ -update
+var update = flag.Bool("update", false, "update the golden files with the generated output")
+
+// Assert fails the test when the output differs from the golden file testdata/<name>.golden.
+func Assert(t testing.TB, name string, actual []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create %v: %v", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, actual, 0644); err != nil {
+			t.Fatalf("failed to update golden file %v: %v", path, err)
+		}
+		return
+	}
+
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read golden file %v, run the test with -update to create it: %v", path, err)
+	}
+	if string(expected) != string(actual) {
+		t.Errorf("output differs from golden file %v, run the test with -update if the change is intended:\n%s", path, actual)
+	}
+}
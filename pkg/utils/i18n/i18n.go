@@ -0,0 +1,246 @@
+package i18n
+
+import (
+	"embed"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	sigsyaml "sigs.k8s.io/yaml"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+)
+
+const (
+	LanguageEnglish = "en"
+	LanguageChinese = "zh"
+)
+
+// SupportedLanguages are the languages of the --lang option. English is the language of the
+// source strings, so it has no catalog.
+var SupportedLanguages = []string{LanguageEnglish, LanguageChinese}
+
+// catalogFiles holds the message catalog of each language but English, named after the language.
+//
+//go:embed catalog/*.yaml
+var catalogFiles embed.FS
+
+// Catalog holds the translations of the user-facing strings of longhornctl to a language. The
+// English strings are the IDs of the translations, as with gettext.
+type Catalog struct {
+	// Labels are the format strings of the table headers and summaries, translated before
+	// formatting with the same arguments.
+	Labels []CatalogEntry `json:"labels,omitempty" yaml:"labels,omitempty"`
+
+	// Messages are the format strings of the result messages of the nodes, translated after
+	// formatting. The arguments are extracted from the English message, and passed as strings
+	// to the translation, which refers to them with %s or %[n]s.
+	Messages []CatalogEntry `json:"messages,omitempty" yaml:"messages,omitempty"`
+}
+
+// CatalogEntry is the translation of an English format string.
+type CatalogEntry struct {
+	ID   string `json:"id" yaml:"id"`
+	Text string `json:"text" yaml:"text"`
+}
+
+// catalogMessage is a result message translation with the regular expression matching the English
+// messages formatted from its ID.
+type catalogMessage struct {
+	pattern *regexp.Regexp
+	text    string
+}
+
+// verbRegexp matches the verbs of the format strings of the messages.
+var verbRegexp = regexp.MustCompile(`%[vsdq]`)
+
+var (
+	language string
+	labels   map[string]string
+	messages []catalogMessage
+)
+
+// SetLanguage loads the catalog of the language of the --lang option, translating the output of
+// Sprintf and Translate. Regional variants, such as zh_CN.UTF-8 or zh-TW, use the catalog of their
+// language.
+func SetLanguage(lang string) error {
+	normalized := NormalizeLanguage(lang)
+	switch normalized {
+	case "", LanguageEnglish:
+		language, labels, messages = LanguageEnglish, nil, nil
+		return nil
+	case LanguageChinese:
+	default:
+		return errors.Errorf("unsupported language %q (--%s), supported languages: %s", lang, consts.CmdOptLang, strings.Join(SupportedLanguages, ", "))
+	}
+
+	catalog, err := LoadCatalog(normalized)
+	if err != nil {
+		return err
+	}
+
+	compiledMessages, err := compileMessages(catalog.Messages)
+	if err != nil {
+		return errors.Wrapf(err, "invalid %v catalog", normalized)
+	}
+
+	language, messages = normalized, compiledMessages
+	labels = map[string]string{}
+	for _, entry := range catalog.Labels {
+		labels[entry.ID] = entry.Text
+	}
+	return nil
+}
+
+// NormalizeLanguage returns the language of a locale name, such as zh for zh_CN.UTF-8.
+func NormalizeLanguage(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if index := strings.IndexAny(lang, "_-."); index >= 0 {
+		lang = lang[:index]
+	}
+	return lang
+}
+
+// GetLanguage returns the language set by SetLanguage.
+func GetLanguage() string {
+	if language == "" {
+		return LanguageEnglish
+	}
+	return language
+}
+
+// LoadCatalog parses the embedded catalog of the language.
+func LoadCatalog(lang string) (*Catalog, error) {
+	data, err := catalogFiles.ReadFile("catalog/" + lang + ".yaml")
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %v catalog", lang)
+	}
+	return ParseCatalog(data)
+}
+
+// ParseCatalog parses and validates the catalog YAML.
+func ParseCatalog(data []byte) (*Catalog, error) {
+	catalog := &Catalog{}
+	if err := sigsyaml.UnmarshalStrict(data, catalog); err != nil {
+		return nil, errors.Wrap(err, "failed to parse catalog")
+	}
+
+	for _, entries := range [][]CatalogEntry{catalog.Labels, catalog.Messages} {
+		ids := map[string]bool{}
+		for i, entry := range entries {
+			if entry.ID == "" || entry.Text == "" {
+				return nil, errors.Errorf("catalog entry #%d has no id or text", i)
+			}
+			if ids[entry.ID] {
+				return nil, errors.Errorf("catalog entry %q is defined more than once", entry.ID)
+			}
+			ids[entry.ID] = true
+		}
+	}
+
+	if _, err := compileMessages(catalog.Messages); err != nil {
+		return nil, err
+	}
+	return catalog, nil
+}
+
+func compileMessages(entries []CatalogEntry) ([]catalogMessage, error) {
+	compiled := make([]catalogMessage, 0, len(entries))
+	for _, entry := range entries {
+		parts := verbRegexp.Split(entry.ID, -1)
+		verbs := verbRegexp.FindAllString(entry.ID, -1)
+
+		var builder strings.Builder
+		builder.WriteString("^")
+		for i, part := range parts {
+			builder.WriteString(regexp.QuoteMeta(part))
+			if i < len(verbs) {
+				if verbs[i] == "%d" {
+					builder.WriteString(`(-?\d+)`)
+				} else {
+					builder.WriteString(`(.*?)`)
+				}
+			}
+		}
+		builder.WriteString("$")
+
+		pattern, err := regexp.Compile(builder.String())
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid catalog message %q", entry.ID)
+		}
+
+		args := make([]any, len(verbs))
+		for i := range args {
+			args[i] = ""
+		}
+		if translated := fmt.Sprintf(entry.Text, args...); strings.Contains(translated, "%!") {
+			return nil, errors.Errorf("translation of catalog message %q does not use its %d arguments as strings: %q", entry.ID, len(verbs), entry.Text)
+		}
+
+		compiled = append(compiled, catalogMessage{pattern: pattern, text: entry.Text})
+	}
+	return compiled, nil
+}
+
+// Sprintf formats the label with the translation of its format string, when the catalog has one.
+func Sprintf(format string, args ...any) string {
+	if text, ok := labels[format]; ok {
+		format = text
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// Translate returns the translation of the formatted result message, or the message when the
+// catalog has no translation of it.
+func Translate(message string) string {
+	for _, catalogMessage := range messages {
+		match := catalogMessage.pattern.FindStringSubmatch(message)
+		if match == nil {
+			continue
+		}
+
+		args := make([]any, 0, len(match)-1)
+		for _, arg := range match[1:] {
+			args = append(args, arg)
+		}
+		return fmt.Sprintf(catalogMessage.text, args...)
+	}
+	return message
+}
+
+// TranslateCollections returns a copy of the collections with their messages translated. The
+// collections are returned as is in English.
+func TranslateCollections(collections map[string]*types.LogCollection) map[string]*types.LogCollection {
+	if len(messages) == 0 {
+		return collections
+	}
+
+	translateAll := func(list []string) []string {
+		if list == nil {
+			return nil
+		}
+		translated := make([]string, 0, len(list))
+		for _, message := range list {
+			translated = append(translated, Translate(message))
+		}
+		return translated
+	}
+
+	translated := make(map[string]*types.LogCollection, len(collections))
+	for key, collection := range collections {
+		if collection == nil {
+			translated[key] = nil
+			continue
+		}
+		translated[key] = &types.LogCollection{
+			Error:   translateAll(collection.Error),
+			Info:    translateAll(collection.Info),
+			Warn:    translateAll(collection.Warn),
+			Skipped: translateAll(collection.Skipped),
+		}
+	}
+	return translated
+}
@@ -0,0 +1,73 @@
+package i18n
+
+import (
+	"testing"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func TestLoadCatalog(t *testing.T) {
+	for _, lang := range SupportedLanguages {
+		if lang == LanguageEnglish {
+			continue
+		}
+		if _, err := LoadCatalog(lang); err != nil {
+			t.Errorf("%v catalog: unexpected error: %v", lang, err)
+		}
+	}
+
+	tests := map[string]string{
+		"unknown field":     "messages:\n- id: a\n  translation: b\n",
+		"no text":           "messages:\n- id: a\n",
+		"duplicated id":     "labels:\n- id: a\n  text: b\n- id: a\n  text: c\n",
+		"missing argument":  "messages:\n- id: Package %s is installed\n  text: b\n",
+		"integer argument":  "messages:\n- id: Found %d nodes\n  text: '%d'\n",
+		"too many argument": "messages:\n- id: Package is installed\n  text: '%s'\n",
+	}
+	for name, data := range tests {
+		if _, err := ParseCatalog([]byte(data)); err == nil {
+			t.Errorf("%v: expected an error", name)
+		}
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	defer func() { _ = SetLanguage(LanguageEnglish) }()
+
+	if err := SetLanguage("fr_FR.UTF-8"); err == nil {
+		t.Error("expected an error for an unsupported language")
+	}
+
+	if err := SetLanguage("zh_CN.UTF-8"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if GetLanguage() != LanguageChinese {
+		t.Errorf("unexpected language %q", GetLanguage())
+	}
+
+	tests := map[string]string{
+		"Package nfs-common is installed":                                              "已安装软件包 nfs-common",
+		"Image longhornio/longhorn-manager:v1.7.2 is reachable through https://mirror": "可以通过 https://mirror 访问镜像 longhornio/longhorn-manager:v1.7.2",
+		"No known issue matches the node, out of 12 known issues":                      "在 12 个已知问题中，没有与该节点匹配的问题",
+		"Package nvme-cli is missing":                                                  "Package nvme-cli is missing",
+	}
+	for message, expected := range tests {
+		if translated := Translate(message); translated != expected {
+			t.Errorf("Translate(%q) = %q, expected %q", message, translated, expected)
+		}
+	}
+
+	if summary := Sprintf("%d %s, %d errors, %d warnings", 3, Sprintf("nodes"), 1, 0); summary != "3 个节点，1 个错误，0 个警告" {
+		t.Errorf("unexpected summary %q", summary)
+	}
+
+	collections := map[string]*types.LogCollection{"node-1": {Info: []string{"Module iscsi_tcp is loaded"}}}
+	translated := TranslateCollections(collections)
+	if translated["node-1"].Info[0] != "已加载内核模块 iscsi_tcp" || collections["node-1"].Info[0] != "Module iscsi_tcp is loaded" {
+		t.Errorf("unexpected translated collections %+v from %+v", translated["node-1"], collections["node-1"])
+	}
+
+	if err := SetLanguage(""); err != nil || Translate("Package nfs-common is installed") != "Package nfs-common is installed" {
+		t.Errorf("expected English to leave the messages as is, got error %v", err)
+	}
+}
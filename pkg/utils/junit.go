@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"encoding/xml"
+	"sort"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr,omitempty"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+}
+
+// RenderNodeCollectionsJUnit renders the per-node result as a JUnit XML report.
+// Each node is a test suite, and each message of the node is a test case.
+// Errors are reported as failures, and warnings as passed test cases with the
+// warning in the system output. Skipped nodes are reported as skipped test cases.
+func RenderNodeCollectionsJUnit(name string, nodeCollections map[string]*types.LogCollection) (string, error) {
+	nodes := make([]string, 0, len(nodeCollections))
+	for node := range nodeCollections {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+
+	report := junitTestSuites{Name: name}
+	for _, node := range nodes {
+		collection := nodeCollections[node]
+		if collection == nil {
+			continue
+		}
+
+		suite := junitTestSuite{Name: node}
+		for _, message := range collection.Error {
+			suite.TestCases = append(suite.TestCases, junitTestCase{
+				Name:      message,
+				ClassName: node,
+				Failure: &junitFailure{
+					Message: message,
+					Type:    resultStatusError,
+				},
+			})
+			suite.Failures++
+		}
+		for _, message := range collection.Warn {
+			suite.TestCases = append(suite.TestCases, junitTestCase{
+				Name:      message,
+				ClassName: node,
+				SystemOut: resultStatusWarn + ": " + message,
+			})
+		}
+		for _, message := range collection.Info {
+			suite.TestCases = append(suite.TestCases, junitTestCase{
+				Name:      message,
+				ClassName: node,
+			})
+		}
+		for _, message := range collection.Skipped {
+			suite.TestCases = append(suite.TestCases, junitTestCase{
+				Name:      message,
+				ClassName: node,
+				Skipped:   &junitSkipped{Message: message},
+			})
+			suite.Skipped++
+		}
+		suite.Tests = len(suite.TestCases)
+
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Suites = append(report.Suites, suite)
+	}
+
+	xmlData, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	return xml.Header + string(xmlData) + "\n", nil
+}
@@ -0,0 +1,340 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	kubeclient "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+)
+
+const (
+	agentTimeout    = time.Hour
+	agentMaxRetries = 3
+	agentRetryDelay = 2 * time.Second
+)
+
+// NewAgentCommand returns the command of the node agent container running the command.
+func NewAgentCommand(command ...string) []string {
+	return append([]string{consts.CmdLonghornctlLocal, consts.SubCmdAgent, "--"}, command...)
+}
+
+// AgentClient talks to the node agent of a pod over a port-forward.
+type AgentClient struct {
+	forwarder  *PortForwarder // Nil when the agent is reached directly by its address.
+	httpClient *http.Client
+}
+
+// AgentConnector connects to the node agent of the pod.
+type AgentConnector func(pod *corev1.Pod) (*AgentClient, error)
+
+// NewAgentClient port-forwards to the node agent of the pod. Close it once done.
+func NewAgentClient(config *rest.Config, kubeClient *kubeclient.Clientset, pod *corev1.Pod) (*AgentClient, error) {
+	forwarder, err := NewPortForwarder(config, kubeClient, pod.Namespace, pod.Name, consts.AgentPort)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AgentClient{
+		forwarder: forwarder,
+		httpClient: &http.Client{
+			Transport: &http.Transport{
+				DialContext:       forwarder.DialContext,
+				DisableKeepAlives: true,
+			},
+		},
+	}, nil
+}
+
+// NewAgentClientForAddress returns a client of the node agent listening on the address, without
+// a port-forward, such as an in-process agent in the tests.
+func NewAgentClientForAddress(address string) *AgentClient {
+	dialer := &net.Dialer{}
+	return &AgentClient{
+		httpClient: &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+					return dialer.DialContext(ctx, network, address)
+				},
+				DisableKeepAlives: true,
+			},
+		},
+	}
+}
+
+// Close closes the port-forward to the node agent.
+func (client *AgentClient) Close() error {
+	if client.forwarder == nil {
+		return nil
+	}
+	return client.forwarder.Close()
+}
+
+// GetStatus returns the status of the command run by the node agent.
+func (client *AgentClient) GetStatus(ctx context.Context) (*types.AgentStatus, error) {
+	resp, err := client.get(ctx, consts.AgentPathStatus, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	status := &types.AgentStatus{}
+	if err := json.NewDecoder(resp.Body).Decode(status); err != nil {
+		return nil, errors.Wrap(err, "failed to decode agent status")
+	}
+	return status, nil
+}
+
+// FollowProgress passes the progress events after the sequence to the handler until the command
+// completes, and returns the sequence of the last one.
+func (client *AgentClient) FollowProgress(ctx context.Context, after int64, handler func(progress *types.AgentProgress)) (int64, error) {
+	query := url.Values{}
+	query.Set("after", strconv.FormatInt(after, 10))
+	query.Set("follow", "true")
+
+	resp, err := client.get(ctx, consts.AgentPathProgress, query)
+	if err != nil {
+		return after, err
+	}
+	defer resp.Body.Close()
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		progress := &types.AgentProgress{}
+		if err := decoder.Decode(progress); err != nil {
+			if err == io.EOF {
+				return after, nil
+			}
+			return after, errors.Wrap(err, "failed to decode agent progress")
+		}
+
+		handler(progress)
+		after = progress.Sequence
+	}
+}
+
+// GetResult returns the result of the completed command run by the node agent.
+func (client *AgentClient) GetResult(ctx context.Context) ([]byte, error) {
+	resp, err := client.get(ctx, consts.AgentPathResult, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return io.ReadAll(resp.Body)
+}
+
+func (client *AgentClient) get(ctx context.Context, path string, query url.Values) (*http.Response, error) {
+	// The host is ignored by the port-forward dialer.
+	agentURL := url.URL{Scheme: "http", Host: "localhost", Path: path, RawQuery: query.Encode()}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, agentURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.httpClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to request agent %v", path)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, errors.Errorf("agent %v returned %v: %s", path, resp.Status, strings.TrimSpace(string(message)))
+	}
+	return resp, nil
+}
+
+// CollectDaemonSetAgentResults waits for the node agents of the DaemonSet to run, follows the
+// progress of their commands, and returns their results keyed by the node name. It returns an
+// error when the command fails on any node.
+func CollectDaemonSetAgentResults(config *rest.Config, kubeClient *kubeclient.Clientset, daemonSet *appsv1.DaemonSet, maxConditionToleration *int) (map[string][]byte, error) {
+	err := MonitorDaemonSetContainer(kubeClient, daemonSet, consts.ContainerNameAgent, WaitForDaemonSetContainersReady, maxConditionToleration)
+	if err != nil {
+		return nil, err
+	}
+
+	pods, err := ListPods(kubeClient, daemonSet.Namespace, labels.SelectorFromSet(daemonSet.Spec.Selector.MatchLabels))
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeoutCause(context.Background(), agentTimeout, &types.TimeoutError{
+		Operation: fmt.Sprintf("waiting for the node agents of DaemonSet %s", daemonSet.Name),
+		Timeout:   agentTimeout,
+	})
+	defer cancel()
+
+	results, err := CollectAgentResults(ctx, pods, func(pod *corev1.Pod) (*AgentClient, error) {
+		return NewAgentClient(config, kubeClient, pod)
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to run the node agents of DaemonSet %s", daemonSet.Name)
+	}
+	return results, nil
+}
+
+// CollectAgentResults follows the progress of the commands of the node agents of the pods
+// scheduled to a node, and returns their results keyed by the node name. It returns an error
+// when the command fails on any node.
+func CollectAgentResults(ctx context.Context, pods []*corev1.Pod, connect AgentConnector) (map[string][]byte, error) {
+	var (
+		wg      sync.WaitGroup
+		mutex   sync.Mutex
+		results = map[string][]byte{}
+		errs    nodeErrors
+	)
+	for _, pod := range pods {
+		if pod.DeletionTimestamp != nil || pod.Spec.NodeName == "" {
+			continue
+		}
+
+		wg.Add(1)
+		go func(pod *corev1.Pod) {
+			defer wg.Done()
+
+			result, err := collectAgentResult(ctx, connect, pod)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err != nil {
+				errs = append(errs, errors.Wrapf(err, "node %v", pod.Spec.NodeName))
+				return
+			}
+			results[pod.Spec.NodeName] = result
+		}(pod)
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return results, nil
+}
+
+// nodeErrors are the errors of the nodes, keeping their categories for errors.As.
+type nodeErrors []error
+
+func (errs nodeErrors) Error() string {
+	messages := make([]string, 0, len(errs))
+	for _, err := range errs {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (errs nodeErrors) Unwrap() []error {
+	return errs
+}
+
+// collectAgentResult follows the progress of the node agent of the pod, then returns its result.
+// It reconnects from the last progress event when the port-forward breaks.
+func collectAgentResult(ctx context.Context, connect AgentConnector, pod *corev1.Pod) ([]byte, error) {
+	log := logrus.WithFields(logrus.Fields{
+		"pod":  pod.Name,
+		"node": pod.Spec.NodeName,
+	})
+
+	var (
+		after        int64
+		lastError    string
+		attemptError error
+	)
+	handleProgress := func(progress *types.AgentProgress) {
+		entry := log.WithFields(logrus.Fields(progress.Fields))
+		switch progress.Level {
+		case logrus.ErrorLevel.String(), logrus.FatalLevel.String(), logrus.PanicLevel.String():
+			lastError = progress.Message
+			if cause, ok := progress.Fields[logrus.ErrorKey]; ok {
+				lastError = fmt.Sprintf("%v: %v", progress.Message, cause)
+			}
+			entry.Debugf("Node agent error: %s", progress.Message)
+		default:
+			entry.Debugf("Node agent: %s", progress.Message)
+		}
+	}
+
+	for attempt := 0; attempt < agentMaxRetries; attempt++ {
+		if attempt > 0 {
+			log.WithError(attemptError).Debug("Retrying to follow node agent")
+			select {
+			case <-ctx.Done():
+				return nil, context.Cause(ctx)
+			case <-time.After(agentRetryDelay):
+			}
+		}
+
+		result, done, err := func() ([]byte, bool, error) {
+			client, err := connect(pod)
+			if err != nil {
+				return nil, false, err
+			}
+			defer client.Close()
+
+			after, err = client.FollowProgress(ctx, after, handleProgress)
+			if err != nil {
+				return nil, false, err
+			}
+
+			status, err := client.GetStatus(ctx)
+			if err != nil {
+				return nil, false, err
+			}
+
+			switch status.Phase {
+			case types.AgentPhaseRunning:
+				return nil, false, errors.New("progress ended before the command completed")
+			case types.AgentPhaseFailed:
+				return nil, true, newAgentCommandError(status, lastError)
+			}
+
+			result, err := client.GetResult(ctx)
+			if err != nil {
+				return nil, false, err
+			}
+			return result, true, nil
+		}()
+		if done {
+			return result, err
+		}
+		attemptError = err
+
+		if ctx.Err() != nil {
+			return nil, context.Cause(ctx)
+		}
+	}
+	return nil, &types.NodeUnreachableError{Node: pod.Spec.NodeName, Err: attemptError}
+}
+
+// newAgentCommandError returns the error of the command failed on the node agent, in the category
+// of the exit code of longhornctl-local.
+func newAgentCommandError(status *types.AgentStatus, lastError string) error {
+	err := errors.New(status.Error)
+	if lastError != "" {
+		err = errors.Errorf("%v: %v", status.Error, lastError)
+	}
+
+	if status.ExitCode == consts.ExitCodePackageManagerError {
+		return &types.PackageManagerError{Err: err}
+	}
+	return err
+}
@@ -0,0 +1,60 @@
+package kubernetes_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/longhorn/cli/pkg/local/agent/agenttest"
+	"github.com/longhorn/cli/pkg/types"
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+func TestCollectAgentResults(t *testing.T) {
+	cluster := agenttest.NewCluster()
+	cluster.AddNode(t, "node-1", "sh", "-c", `echo '{"level":"info","msg":"Checking"}' >&2; echo '{"node":1}' > "$1"`, "sh", agenttest.OutputFileArg)
+	cluster.AddNode(t, "node-2", "sh", "-c", `echo '{"node":2}' > "$1"`, "sh", agenttest.OutputFileArg)
+
+	results, err := kubeutils.CollectAgentResults(context.Background(), cluster.Pods(), cluster.Connect)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for node, expected := range map[string]string{"node-1": `{"node":1}` + "\n", "node-2": `{"node":2}` + "\n"} {
+		if string(results[node]) != expected {
+			t.Errorf("%v: expected result %q, got %q", node, expected, results[node])
+		}
+	}
+}
+
+func TestCollectAgentResultsErrors(t *testing.T) {
+	for name, tc := range map[string]struct {
+		script   string
+		stop     bool
+		expected func(error) bool
+	}{
+		"package manager error": {
+			script:   `echo '{"level":"fatal","msg":"Failed to install package nfs-common"}' >&2; exit 5`,
+			expected: types.IsPackageManagerError,
+		},
+		"node unreachable": {
+			script:   `exit 0`,
+			stop:     true,
+			expected: types.IsNodeUnreachable,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cluster := agenttest.NewCluster()
+			node := cluster.AddNode(t, "node-1", "sh", "-c", tc.script)
+			if tc.stop {
+				node.Stop()
+			}
+
+			_, err := kubeutils.CollectAgentResults(context.Background(), cluster.Pods(), cluster.Connect)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if !tc.expected(err) {
+				t.Errorf("unexpected error category: %v", err)
+			}
+		})
+	}
+}
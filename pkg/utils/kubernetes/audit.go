@@ -0,0 +1,86 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeclient "k8s.io/client-go/kubernetes"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+)
+
+// GetCurrentUser returns the name of the user the client is authenticated as.
+func GetCurrentUser(kubeClient *kubeclient.Clientset) (string, error) {
+	review, err := kubeClient.AuthenticationV1().SelfSubjectReviews().Create(context.Background(), &authenticationv1.SelfSubjectReview{}, metav1.CreateOptions{})
+	if err != nil {
+		return "", err
+	}
+	return review.Status.UserInfo.Username, nil
+}
+
+// RecordAuditEvent records the CLI operation as an Event of the namespace, so
+// the operations made through the CLI can be listed with:
+//
+//	kubectl -n <namespace> get events --field-selector reason=LonghornctlOperationSucceeded
+func RecordAuditEvent(kubeClient *kubeclient.Clientset, namespace string, record *types.AuditRecord) (*corev1.Event, error) {
+	eventType := corev1.EventTypeNormal
+	reason := consts.EventReasonOperationSucceeded
+	result := "succeeded"
+	if record.Error != nil {
+		eventType = corev1.EventTypeWarning
+		reason = consts.EventReasonOperationFailed
+		result = fmt.Sprintf("failed: %v", record.Error)
+	}
+
+	command := getAuditCommand(record)
+	now := metav1.NewTime(time.Now())
+
+	newEvent := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: consts.CmdLonghornctlRemote + "-",
+			Namespace:    namespace,
+			Annotations: map[string]string{
+				consts.AnnotationAuditUser:      record.User,
+				consts.AnnotationAuditHostUser:  record.HostUser,
+				consts.AnnotationAuditCommand:   command,
+				consts.AnnotationAuditStartTime: record.StartTime.UTC().Format(time.RFC3339),
+			},
+		},
+		InvolvedObject: corev1.ObjectReference{
+			APIVersion: corev1.SchemeGroupVersion.String(),
+			Kind:       "Namespace",
+			Name:       namespace,
+		},
+		Reason:              reason,
+		Message:             fmt.Sprintf("%s (%s) ran %q: %s", record.User, record.HostUser, command, result),
+		Type:                eventType,
+		Source:              corev1.EventSource{Component: consts.CmdLonghornctlRemote},
+		ReportingController: consts.CmdLonghornctlRemote,
+		FirstTimestamp:      metav1.NewTime(record.StartTime),
+		LastTimestamp:       now,
+		Count:               1,
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"kind":      "Event",
+		"namespace": namespace,
+		"reason":    reason,
+	}).Debug("Recording audit event")
+
+	return kubeClient.CoreV1().Events(namespace).Create(context.Background(), newEvent, metav1.CreateOptions{})
+}
+
+// getAuditCommand returns the command line of the operation, with its flags and positional
+// arguments.
+func getAuditCommand(record *types.AuditRecord) string {
+	words := append([]string{record.Command}, record.Flags...)
+	return strings.TrimSpace(strings.Join(append(words, record.Args...), " "))
+}
@@ -0,0 +1,33 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func TestGetAuditCommand(t *testing.T) {
+	for name, test := range map[string]struct {
+		record   *types.AuditRecord
+		expected string
+	}{
+		"flags": {
+			record:   &types.AuditRecord{Command: "longhornctl install preflight", Flags: []string{"--dry-run=true"}},
+			expected: "longhornctl install preflight --dry-run=true",
+		},
+		"volume name": {
+			record:   &types.AuditRecord{Command: "longhornctl volume delete", Flags: []string{"--yes=true"}, Args: []string{"pvc-1234"}},
+			expected: "longhornctl volume delete --yes=true pvc-1234",
+		},
+		"node exec": {
+			record:   &types.AuditRecord{Command: "longhornctl node exec", Args: []string{"node-1", "--", "rm", "-rf", "/var/lib/longhorn/replicas"}},
+			expected: "longhornctl node exec node-1 -- rm -rf /var/lib/longhorn/replicas",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if command := getAuditCommand(test.record); command != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, command)
+			}
+		})
+	}
+}
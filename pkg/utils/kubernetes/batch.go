@@ -0,0 +1,169 @@
+package kubernetes
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	kubeclient "k8s.io/client-go/kubernetes"
+
+	commonkube "github.com/longhorn/go-common-libs/kubernetes"
+
+	"github.com/longhorn/cli/pkg/consts"
+)
+
+const (
+	daemonSetDeletionInterval = 2 * time.Second
+	daemonSetDeletionTimeout  = consts.ContainerConditionMaxTolerationMedium * time.Second
+)
+
+// RunDaemonSetInBatches creates the DaemonSet and calls the run function with it, once
+// ValidateNodeSelector confirms its node selector matches at least minNodes schedulable nodes.
+//
+// When maxParallel is positive, the nodes matching the node selector of the DaemonSet are
+// split into batches of at most maxParallel nodes. The DaemonSet is then created for one
+// batch at a time, restricted to the nodes of the batch with a node affinity, and deleted
+// once the run function returns, before moving on to the next batch.
+func RunDaemonSetInBatches(kubeClient *kubeclient.Clientset, newDaemonSet *appsv1.DaemonSet, minNodes, maxParallel int, run func(daemonSet *appsv1.DaemonSet) error) error {
+	SetRunMetadata(kubeClient, newDaemonSet)
+
+	if maxParallel <= 0 {
+		LogManifest(newDaemonSet)
+		daemonSet, err := CreateDaemonSet(kubeClient, newDaemonSet, minNodes)
+		if err != nil {
+			return err
+		}
+		return run(daemonSet)
+	}
+
+	if _, err := ValidateNodeSelector(kubeClient, newDaemonSet.Spec.Template.Spec.NodeSelector, minNodes); err != nil {
+		return errors.Wrapf(err, "failed to create DaemonSet %v", newDaemonSet.Name)
+	}
+
+	nodeNames, err := ListNodeNames(kubeClient, newDaemonSet.Spec.Template.Spec.NodeSelector)
+	if err != nil {
+		return err
+	}
+
+	return RunDaemonSetOnNodes(kubeClient, newDaemonSet, nodeNames, maxParallel, run)
+}
+
+// RunDaemonSetOnNodes creates the DaemonSet restricted to the nodes with a node affinity, in
+// batches of at most maxParallel nodes when it is positive, and calls the run function with the
+// DaemonSet of each batch. The DaemonSet of each batch but the last is deleted once the run
+// function returns.
+func RunDaemonSetOnNodes(kubeClient *kubeclient.Clientset, newDaemonSet *appsv1.DaemonSet, nodeNames []string, maxParallel int, run func(daemonSet *appsv1.DaemonSet) error) error {
+	SetRunMetadata(kubeClient, newDaemonSet)
+
+	batches := SplitIntoBatches(nodeNames, maxParallel)
+	for i, batch := range batches {
+		logrus.Infof("Running batch %d/%d on nodes: %s", i+1, len(batches), strings.Join(batch, consts.CmdOptSeperator))
+
+		batchDaemonSet := newDaemonSet.DeepCopy()
+		SetNodeNameAffinity(&batchDaemonSet.Spec.Template.Spec, batch)
+
+		LogManifest(batchDaemonSet)
+		daemonSet, err := commonkube.CreateDaemonSet(kubeClient, batchDaemonSet)
+		if err != nil {
+			return err
+		}
+
+		if err := run(daemonSet); err != nil {
+			return errors.Wrapf(err, "failed batch %d/%d", i+1, len(batches))
+		}
+
+		logrus.Infof("Completed batch %d/%d", i+1, len(batches))
+
+		if i == len(batches)-1 {
+			break
+		}
+
+		if err := DeleteDaemonSetAndWait(kubeClient, daemonSet); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ListNodeNames returns the sorted names of the supported nodes matching the node selector.
+func ListNodeNames(kubeClient *kubeclient.Clientset, nodeSelector map[string]string) ([]string, error) {
+	nodes, err := ListNodes(kubeClient, labels.SelectorFromSet(nodeSelector))
+	if err != nil {
+		return nil, err
+	}
+
+	nodeNames := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		if GetUnsupportedNodeReason(node) != "" {
+			continue
+		}
+		nodeNames = append(nodeNames, node.Name)
+	}
+	sort.Strings(nodeNames)
+	return nodeNames, nil
+}
+
+// SplitIntoBatches splits the items into consecutive batches of at most size items.
+func SplitIntoBatches(items []string, size int) [][]string {
+	if size <= 0 {
+		return [][]string{items}
+	}
+
+	batches := [][]string{}
+	for start := 0; start < len(items); start += size {
+		end := min(start+size, len(items))
+		batches = append(batches, items[start:end])
+	}
+	return batches
+}
+
+// SetNodeNameAffinity restricts the pod to the nodes with the given names.
+func SetNodeNameAffinity(podSpec *corev1.PodSpec, nodeNames []string) {
+	terms := getRequiredNodeSelectorTerms(podSpec)
+	for i := range terms {
+		terms[i].MatchFields = []corev1.NodeSelectorRequirement{
+			{
+				Key:      metav1.ObjectNameField,
+				Operator: corev1.NodeSelectorOpIn,
+				Values:   nodeNames,
+			},
+		}
+	}
+}
+
+// DeleteDaemonSetAndWait deletes the DaemonSet and waits for it and its pods to be
+// removed, so a DaemonSet with the same name and labels can be created afterwards.
+func DeleteDaemonSetAndWait(kubeClient *kubeclient.Clientset, daemonSet *appsv1.DaemonSet) error {
+	if err := commonkube.DeleteDaemonSet(kubeClient, daemonSet.Namespace, daemonSet.Name); err != nil {
+		return err
+	}
+
+	selector := labels.SelectorFromSet(labels.Set{"app": daemonSet.Labels["app"]})
+	err := wait.PollUntilContextTimeout(context.Background(), daemonSetDeletionInterval, daemonSetDeletionTimeout, true, func(ctx context.Context) (bool, error) {
+		_, err := kubeClient.AppsV1().DaemonSets(daemonSet.Namespace).Get(ctx, daemonSet.Name, metav1.GetOptions{})
+		if err == nil {
+			return false, nil
+		}
+		if !apierrors.IsNotFound(err) {
+			return false, err
+		}
+
+		pods, err := ListPods(kubeClient, daemonSet.Namespace, selector)
+		if err != nil {
+			return false, err
+		}
+		return len(pods) == 0, nil
+	})
+	return errors.Wrapf(err, "failed to wait for DaemonSet %v to be deleted", daemonSet.Name)
+}
@@ -0,0 +1,40 @@
+package kubernetes
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitIntoBatches(t *testing.T) {
+	for _, test := range []struct {
+		items []string
+		size  int
+		want  [][]string
+	}{
+		{
+			items: []string{"node-1", "node-2", "node-3", "node-4", "node-5"},
+			size:  2,
+			want:  [][]string{{"node-1", "node-2"}, {"node-3", "node-4"}, {"node-5"}},
+		},
+		{
+			items: []string{"node-1", "node-2"},
+			size:  5,
+			want:  [][]string{{"node-1", "node-2"}},
+		},
+		{
+			items: []string{"node-1", "node-2"},
+			size:  0,
+			want:  [][]string{{"node-1", "node-2"}},
+		},
+		{
+			items: []string{},
+			size:  2,
+			want:  [][]string{},
+		},
+	} {
+		got := SplitIntoBatches(test.items, test.size)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("SplitIntoBatches(%v, %d) = %v, want %v", test.items, test.size, got, test.want)
+		}
+	}
+}
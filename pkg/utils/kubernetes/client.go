@@ -1,21 +1,274 @@
 package kubernetes
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"sync"
+	"time"
 
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/flowcontrol"
 
 	kubeclient "k8s.io/client-go/kubernetes"
+
+	lhclient "github.com/longhorn/longhorn-manager/k8s/pkg/client/clientset/versioned"
 )
 
-func NewKubeClient(masterUrl string, kubeconfigPath string) (kubeClient *kubeclient.Clientset, err error) {
-	const kubeConfigHint = `Make sure to either:
+const kubeConfigHint = `Make sure to either:
   - Set the environment variable: export KUBECONFIG=/path/to/config
-  - Or use: --kube-config=/path/to/config`
+  - Or use: --kube-config=/path/to/config
+  - Or run the CLI inside the cluster with a service account`
+
+const (
+	// DefaultClientQPS and DefaultClientBurst are the default rate limits of the requests to the
+	// Kubernetes API server, shared by all the clients of the CLI.
+	DefaultClientQPS   = 50
+	DefaultClientBurst = 100
+
+	informerCacheSyncTimeout = 2 * time.Minute
+)
+
+// ClientFactory creates the clients of the CLI once per kubeconfig and shares them between the
+// operations, so they all go through the same rate limiter. It also keeps the informers caching
+// the nodes and the pods listed repeatedly while waiting for the DaemonSets. It is safe for
+// concurrent use.
+type ClientFactory struct {
+	lock sync.Mutex
+
+	qps   float32
+	burst int
+
+	restConfigs     map[string]*rest.Config
+	kubeClients     map[string]*kubeclient.Clientset
+	longhornClients map[string]*lhclient.Clientset
+
+	informerFactories map[informerKey]informers.SharedInformerFactory
+	stopCh            chan struct{}
+}
+
+// informerKey identifies the informers of a client watching a namespace, or all namespaces.
+type informerKey struct {
+	kubeClient *kubeclient.Clientset
+	namespace  string
+}
+
+var defaultClientFactory = NewClientFactory(DefaultClientQPS, DefaultClientBurst)
+
+// NewClientFactory returns a client factory limiting the requests of its clients to qps per
+// second, with bursts of burst requests.
+func NewClientFactory(qps float32, burst int) *ClientFactory {
+	return &ClientFactory{
+		qps:               qps,
+		burst:             burst,
+		restConfigs:       map[string]*rest.Config{},
+		kubeClients:       map[string]*kubeclient.Clientset{},
+		longhornClients:   map[string]*lhclient.Clientset{},
+		informerFactories: map[informerKey]informers.SharedInformerFactory{},
+		stopCh:            make(chan struct{}),
+	}
+}
+
+// SetClientRateLimits sets the rate limits of the clients created from now on by the default
+// client factory.
+func SetClientRateLimits(qps float32, burst int) error {
+	if qps <= 0 || burst <= 0 {
+		return errors.Errorf("invalid Kubernetes API rate limits, QPS %v and burst %v must be positive", qps, burst)
+	}
+
+	defaultClientFactory.lock.Lock()
+	defer defaultClientFactory.lock.Unlock()
+
+	defaultClientFactory.qps = qps
+	defaultClientFactory.burst = burst
+	return nil
+}
+
+// NewKubeClient returns the Kubernetes client of the kubeconfig, shared by all the callers.
+func NewKubeClient(masterUrl string, kubeconfigPath string) (kubeClient *kubeclient.Clientset, err error) {
+	return defaultClientFactory.KubeClient(masterUrl, kubeconfigPath)
+}
+
+// NewLonghornClient returns the clientset for the Longhorn custom resources, shared by all the callers.
+func NewLonghornClient(masterUrl string, kubeconfigPath string) (*lhclient.Clientset, error) {
+	return defaultClientFactory.LonghornClient(masterUrl, kubeconfigPath)
+}
+
+// NewRestConfig returns the client config from the kubeconfig, or the in-cluster
+// config when no kubeconfig is provided and the CLI runs inside the cluster.
+// The config is a copy sharing the rate limiter of the other clients.
+func NewRestConfig(masterUrl string, kubeconfigPath string) (*rest.Config, error) {
+	return defaultClientFactory.RestConfig(masterUrl, kubeconfigPath)
+}
+
+// KubeClient returns the Kubernetes client of the kubeconfig, creating it on the first call.
+func (factory *ClientFactory) KubeClient(masterUrl string, kubeconfigPath string) (*kubeclient.Clientset, error) {
+	factory.lock.Lock()
+	defer factory.lock.Unlock()
+
+	key := masterUrl + "|" + kubeconfigPath
+	if kubeClient, ok := factory.kubeClients[key]; ok {
+		return kubeClient, nil
+	}
+
+	config, err := factory.getRestConfig(masterUrl, kubeconfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	kubeClient, err := kubeclient.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kubernetes client: %w\n\n%s", err, kubeConfigHint)
+	}
+
+	factory.kubeClients[key] = kubeClient
+	return kubeClient, nil
+}
+
+// LonghornClient returns the clientset for the Longhorn custom resources of the kubeconfig,
+// creating it on the first call.
+func (factory *ClientFactory) LonghornClient(masterUrl string, kubeconfigPath string) (*lhclient.Clientset, error) {
+	factory.lock.Lock()
+	defer factory.lock.Unlock()
+
+	key := masterUrl + "|" + kubeconfigPath
+	if longhornClient, ok := factory.longhornClients[key]; ok {
+		return longhornClient, nil
+	}
+
+	config, err := factory.getRestConfig(masterUrl, kubeconfigPath)
+	if err != nil {
+		return nil, err
+	}
 
+	longhornClient, err := lhclient.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Longhorn client: %w\n\n%s", err, kubeConfigHint)
+	}
+
+	factory.longhornClients[key] = longhornClient
+	return longhornClient, nil
+}
+
+// RestConfig returns a copy of the client config of the kubeconfig, sharing its rate limiter.
+func (factory *ClientFactory) RestConfig(masterUrl string, kubeconfigPath string) (*rest.Config, error) {
+	factory.lock.Lock()
+	defer factory.lock.Unlock()
+
+	config, err := factory.getRestConfig(masterUrl, kubeconfigPath)
+	if err != nil {
+		return nil, err
+	}
+	return rest.CopyConfig(config), nil
+}
+
+// getRestConfig returns the cached client config of the kubeconfig, loading it with a rate
+// limiter on the first call. The caller must hold the lock.
+func (factory *ClientFactory) getRestConfig(masterUrl string, kubeconfigPath string) (*rest.Config, error) {
+	key := masterUrl + "|" + kubeconfigPath
+	if config, ok := factory.restConfigs[key]; ok {
+		return config, nil
+	}
+
+	config, err := loadRestConfig(masterUrl, kubeconfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	config.QPS = factory.qps
+	config.Burst = factory.burst
+	config.RateLimiter = flowcontrol.NewTokenBucketRateLimiter(factory.qps, factory.burst)
+	logrus.Debugf("Limiting the Kubernetes API requests to %v per second with bursts of %v", factory.qps, factory.burst)
+
+	factory.restConfigs[key] = config
+	return config, nil
+}
+
+// ListNodes lists the nodes matching the label selector from the informer cache of the client.
+func ListNodes(kubeClient *kubeclient.Clientset, selector labels.Selector) ([]*corev1.Node, error) {
+	return defaultClientFactory.ListNodes(kubeClient, selector)
+}
+
+// ListPods lists the pods of the namespace matching the label selector from the informer cache
+// of the client.
+func ListPods(kubeClient *kubeclient.Clientset, namespace string, selector labels.Selector) ([]*corev1.Pod, error) {
+	return defaultClientFactory.ListPods(kubeClient, namespace, selector)
+}
+
+// ListNodes lists the nodes matching the label selector from the informer cache of the client,
+// starting the informer on the first call.
+func (factory *ClientFactory) ListNodes(kubeClient *kubeclient.Clientset, selector labels.Selector) ([]*corev1.Node, error) {
+	informerFactory := factory.getInformerFactory(kubeClient, corev1.NamespaceAll)
+	lister := informerFactory.Core().V1().Nodes().Lister()
+	if err := factory.waitForCacheSync(informerFactory); err != nil {
+		return nil, errors.Wrap(err, "failed to list nodes")
+	}
+	return lister.List(selector)
+}
+
+// ListPods lists the pods of the namespace matching the label selector from the informer cache
+// of the client, starting the informer of the namespace on the first call.
+func (factory *ClientFactory) ListPods(kubeClient *kubeclient.Clientset, namespace string, selector labels.Selector) ([]*corev1.Pod, error) {
+	informerFactory := factory.getInformerFactory(kubeClient, namespace)
+	lister := informerFactory.Core().V1().Pods().Lister()
+	if err := factory.waitForCacheSync(informerFactory); err != nil {
+		return nil, errors.Wrapf(err, "failed to list pods in namespace %v", namespace)
+	}
+	return lister.Pods(namespace).List(selector)
+}
+
+func (factory *ClientFactory) getInformerFactory(kubeClient *kubeclient.Clientset, namespace string) informers.SharedInformerFactory {
+	factory.lock.Lock()
+	defer factory.lock.Unlock()
+
+	key := informerKey{kubeClient: kubeClient, namespace: namespace}
+	informerFactory, ok := factory.informerFactories[key]
+	if !ok {
+		informerFactory = informers.NewSharedInformerFactoryWithOptions(kubeClient, 0, informers.WithNamespace(namespace))
+		factory.informerFactories[key] = informerFactory
+	}
+	return informerFactory
+}
+
+// waitForCacheSync starts the informers requested from the informer factory, and waits for their
+// caches to be filled.
+func (factory *ClientFactory) waitForCacheSync(informerFactory informers.SharedInformerFactory) error {
+	informerFactory.Start(factory.stopCh)
+
+	ctx, cancel := context.WithTimeout(context.Background(), informerCacheSyncTimeout)
+	defer cancel()
+
+	for informerType, synced := range informerFactory.WaitForCacheSync(ctx.Done()) {
+		if !synced {
+			return errors.Errorf("timed out waiting for the %v cache to sync", informerType)
+		}
+	}
+	return nil
+}
+
+// loadRestConfig loads the client config from the kubeconfig, or the in-cluster
+// config when no kubeconfig is provided and the CLI runs inside the cluster.
+func loadRestConfig(masterUrl string, kubeconfigPath string) (*rest.Config, error) {
 	if masterUrl == "" && kubeconfigPath == "" {
-		return nil, fmt.Errorf("no kubeconfig path provided.\n\n%s", kubeConfigHint)
+		if !IsInCluster() {
+			return nil, fmt.Errorf("no kubeconfig path provided.\n\n%s", kubeConfigHint)
+		}
+
+		logrus.Debug("No kubeconfig path provided, using in-cluster config")
+
+		config, err := rest.InClusterConfig()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load in-cluster config: %w\n\n%s", err, kubeConfigHint)
+		}
+
+		return config, nil
 	}
 
 	if kubeconfigPath != "" {
@@ -24,15 +277,16 @@ func NewKubeClient(masterUrl string, kubeconfigPath string) (kubeClient *kubecli
 		}
 	}
 
-	kubeconfig, err := clientcmd.BuildConfigFromFlags(masterUrl, kubeconfigPath)
+	config, err := clientcmd.BuildConfigFromFlags(masterUrl, kubeconfigPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load kubeconfig from path '%s': %w\n\n%s", kubeconfigPath, err, kubeConfigHint)
 	}
 
-	kubeClient, err = kubeclient.NewForConfig(kubeconfig)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create Kubernetes client: %w\n\n%s", err, kubeConfigHint)
-	}
+	return config, nil
+}
 
-	return kubeClient, nil
+// IsInCluster returns true if the CLI is running inside a Kubernetes pod.
+// It relies on the service environment variables injected by the kubelet.
+func IsInCluster() bool {
+	return os.Getenv("KUBERNETES_SERVICE_HOST") != "" && os.Getenv("KUBERNETES_SERVICE_PORT") != ""
 }
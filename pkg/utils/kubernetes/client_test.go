@@ -0,0 +1,80 @@
+package kubernetes
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testKubeConfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: test
+  cluster:
+    server: https://127.0.0.1:6443
+contexts:
+- name: test
+  context:
+    cluster: test
+    user: test
+current-context: test
+users:
+- name: test
+  user:
+    token: test
+`
+
+func TestClientFactory(t *testing.T) {
+	kubeConfigPath := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(kubeConfigPath, []byte(testKubeConfig), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	factory := NewClientFactory(10, 20)
+
+	kubeClient, err := factory.KubeClient("", kubeConfigPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if other, _ := factory.KubeClient("", kubeConfigPath); other != kubeClient {
+		t.Errorf("expected the Kubernetes client to be shared")
+	}
+
+	longhornClient, err := factory.LonghornClient("", kubeConfigPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if other, _ := factory.LonghornClient("", kubeConfigPath); other != longhornClient {
+		t.Errorf("expected the Longhorn client to be shared")
+	}
+
+	config, err := factory.RestConfig("", kubeConfigPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	other, _ := factory.RestConfig("", kubeConfigPath)
+	if config == other {
+		t.Errorf("expected a copy of the client config")
+	}
+	if config.RateLimiter == nil || config.RateLimiter != other.RateLimiter {
+		t.Errorf("expected the client configs to share the rate limiter")
+	}
+	if config.QPS != 10 || config.Burst != 20 {
+		t.Errorf("expected QPS 10 and burst 20, got %v and %v", config.QPS, config.Burst)
+	}
+
+	if _, err := factory.KubeClient("", filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Errorf("expected error for missing kubeconfig")
+	}
+}
+
+func TestSetClientRateLimits(t *testing.T) {
+	for _, limits := range []struct {
+		qps   float32
+		burst int
+	}{{0, 100}, {50, 0}, {-1, -1}} {
+		if err := SetClientRateLimits(limits.qps, limits.burst); err == nil {
+			t.Errorf("expected error for QPS %v and burst %v", limits.qps, limits.burst)
+		}
+	}
+}
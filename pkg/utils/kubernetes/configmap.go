@@ -0,0 +1,37 @@
+package kubernetes
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeclient "k8s.io/client-go/kubernetes"
+)
+
+// CreateOrUpdateConfigMap creates the ConfigMap, or replaces the labels, annotations and data of the existing one.
+func CreateOrUpdateConfigMap(kubeClient *kubeclient.Clientset, newConfigMap *corev1.ConfigMap) (*corev1.ConfigMap, error) {
+	log := logrus.WithFields(logrus.Fields{
+		"kind":      "ConfigMap",
+		"namespace": newConfigMap.Namespace,
+		"name":      newConfigMap.Name,
+	})
+	log.Debug("Creating or updating resource")
+
+	configMaps := kubeClient.CoreV1().ConfigMaps(newConfigMap.Namespace)
+
+	configMap, err := configMaps.Get(context.Background(), newConfigMap.Name, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		return configMaps.Create(context.Background(), newConfigMap, metav1.CreateOptions{})
+	}
+
+	configMap.Labels = newConfigMap.Labels
+	configMap.Annotations = newConfigMap.Annotations
+	configMap.Data = newConfigMap.Data
+	return configMaps.Update(context.Background(), configMap, metav1.UpdateOptions{})
+}
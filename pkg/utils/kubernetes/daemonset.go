@@ -12,9 +12,14 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	kubeclient "k8s.io/client-go/kubernetes"
 
+	commonkube "github.com/longhorn/go-common-libs/kubernetes"
+
 	"github.com/longhorn/cli/pkg/types"
 )
 
+// daemonSetContainerTimeout is the time MonitorDaemonSetContainer waits for the condition.
+const daemonSetContainerTimeout = time.Hour
+
 type monitorDaemonSetContainerConditionFunc func(ctx context.Context, logger *logrus.Entry, kubeClient *kubeclient.Clientset, daemonSet *appsv1.DaemonSet, containerName string, maxConditionToleration *int) error
 
 // MonitorDaemonSetContainer monitors the specified container within the given DaemonSet until a certain condition is met.
@@ -34,7 +39,10 @@ func MonitorDaemonSetContainer(kubeClient *kubeclient.Clientset, daemonSet *apps
 		"container": containerName,
 	})
 
-	ctx, cancel := context.WithTimeoutCause(context.Background(), time.Hour, errors.Errorf("timed out waiting for the DaemonSet %s container %s condition", daemonSet.Name, containerName))
+	ctx, cancel := context.WithTimeoutCause(context.Background(), daemonSetContainerTimeout, &types.TimeoutError{
+		Operation: fmt.Sprintf("waiting for the DaemonSet %s container %s condition", daemonSet.Name, containerName),
+		Timeout:   daemonSetContainerTimeout,
+	})
 	defer cancel()
 
 	doneCh := make(chan struct{})
@@ -74,9 +82,30 @@ func MonitorDaemonSetContainer(kubeClient *kubeclient.Clientset, daemonSet *apps
 		}
 		return err
 	case <-doneCh:
+		if !logrus.IsLevelEnabled(logrus.DebugLevel) {
+			return nil
+		}
+
+		// Show what the node agents did, including the commands executed on the nodes.
+		podsLog, err := workload.GetPodsLogByContainer(ctx, log, containerName, false, false, nil)
+		if err != nil {
+			log.WithError(err).Debug("Failed to get DaemonSet pods container logs")
+			return nil
+		}
+
+		if podsLog == nil {
+			return nil
+		}
+
+		for podName, collection := range podsLog.Pods {
+			log.WithFields(logrus.Fields{
+				"pod":  podName,
+				"node": collection.Node,
+			}).Debugf("Pod container logs:\n%s", collection.Log)
+		}
 		return nil
 	case <-ctx.Done():
-		return errors.Wrapf(ctx.Err(), "Timed out waiting for container %s to be running", containerName)
+		return errors.Wrapf(context.Cause(ctx), "failed waiting for container %s to be running", containerName)
 	}
 }
 
@@ -111,6 +140,15 @@ func GetDaemonSetPodCollections(kubeClient *kubeclient.Clientset, daemonSet *app
 	return collections, nil
 }
 
+// CreateDaemonSet creates the DaemonSet once ValidateNodeSelector confirms its node selector
+// matches schedulable nodes, at least --require-min-nodes of them.
+func CreateDaemonSet(kubeClient *kubeclient.Clientset, newDaemonSet *appsv1.DaemonSet, minNodes int) (*appsv1.DaemonSet, error) {
+	if _, err := ValidateNodeSelector(kubeClient, newDaemonSet.Spec.Template.Spec.NodeSelector, minNodes); err != nil {
+		return nil, errors.Wrapf(err, "failed to create DaemonSet %v", newDaemonSet.Name)
+	}
+	return commonkube.CreateDaemonSet(kubeClient, newDaemonSet)
+}
+
 // ParseNodeSelector parses a node selector string (e.g., "key1=value1,key2=value2")
 // and returns it as a map[string]string. This map can be used directly in a DaemonSet spec.
 // It returns an error if the input is malformed (e.g., missing '=' or empty key/value).
@@ -0,0 +1,165 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	kubeclient "k8s.io/client-go/kubernetes"
+
+	"github.com/longhorn/cli/pkg/consts"
+)
+
+const (
+	drainInterval      = 5 * time.Second
+	nodeRebootInterval = 10 * time.Second
+)
+
+// CordonNode marks the node unschedulable, or schedulable again when unschedulable is false.
+func CordonNode(ctx context.Context, kubeClient *kubeclient.Clientset, nodeName string, unschedulable bool) error {
+	patch := fmt.Sprintf(`{"spec":{"unschedulable":%t}}`, unschedulable)
+	_, err := kubeClient.CoreV1().Nodes().Patch(ctx, nodeName, apitypes.StrategicMergePatchType, []byte(patch), metav1.PatchOptions{})
+	if unschedulable {
+		return errors.Wrapf(err, "failed to cordon node %v", nodeName)
+	}
+	return errors.Wrapf(err, "failed to uncordon node %v", nodeName)
+}
+
+// DrainNode evicts the pods of the node, except the DaemonSet and static pods and the pods of
+// longhornctl, and waits for them to be deleted. Like "kubectl drain", it fails without evicting
+// any pod when the node runs pods not managed by a controller, unless force is set. The evictions
+// go through the Eviction API, so they respect the PodDisruptionBudgets. Longhorn protects the
+// instance managers with them while the node has the last healthy replica of a volume, so the
+// drain waits for the replicas to be rebuilt elsewhere, until the context is done.
+func DrainNode(ctx context.Context, kubeClient *kubeclient.Clientset, nodeName string, force bool) error {
+	log := logrus.WithField("node", nodeName)
+
+	err := wait.PollUntilContextCancel(ctx, drainInterval, true, func(ctx context.Context) (bool, error) {
+		podList, err := kubeClient.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+			FieldSelector: fields.OneTermEqualSelector("spec.nodeName", nodeName).String(),
+		})
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to list pods of node %v", nodeName)
+		}
+
+		if !force {
+			var unmanaged []string
+			for i := range podList.Items {
+				pod := &podList.Items[i]
+				if IsEvictablePod(pod) && !isCurrentPod(pod) && IsUnmanagedPod(pod) {
+					unmanaged = append(unmanaged, pod.Namespace+"/"+pod.Name)
+				}
+			}
+			if len(unmanaged) > 0 {
+				return false, errors.Errorf("cannot evict pods not managed by a controller, as they would not be recreated: %v. Set --%s to evict them anyway", strings.Join(unmanaged, ", "), consts.CmdOptForceDrain)
+			}
+		}
+
+		var remaining []string
+		for i := range podList.Items {
+			pod := &podList.Items[i]
+			if !IsEvictablePod(pod) || isCurrentPod(pod) {
+				continue
+			}
+			remaining = append(remaining, pod.Namespace+"/"+pod.Name)
+
+			if pod.DeletionTimestamp != nil {
+				continue
+			}
+
+			err := kubeClient.PolicyV1().Evictions(pod.Namespace).Evict(ctx, &policyv1.Eviction{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      pod.Name,
+					Namespace: pod.Namespace,
+				},
+			})
+			switch {
+			case err == nil:
+				log.Debugf("Evicted pod %v/%v", pod.Namespace, pod.Name)
+			case apierrors.IsNotFound(err):
+			case apierrors.IsTooManyRequests(err):
+				log.WithError(err).Debugf("Eviction of pod %v/%v is blocked by a PodDisruptionBudget", pod.Namespace, pod.Name)
+			default:
+				return false, errors.Wrapf(err, "failed to evict pod %v/%v", pod.Namespace, pod.Name)
+			}
+		}
+
+		if len(remaining) > 0 {
+			log.Infof("Waiting for %d pods to be evicted: %v", len(remaining), strings.Join(remaining, ", "))
+			return false, nil
+		}
+		return true, nil
+	})
+	return errors.Wrapf(err, "failed to drain node %v", nodeName)
+}
+
+// IsEvictablePod returns whether the drain of the node evicts the pod. The DaemonSet pods would
+// be recreated on the node, the static pods are managed by the kubelet, and the completed pods
+// hold nothing to move. The pods of longhornctl are left running, so the drain does not stop the
+// command driving it.
+func IsEvictablePod(pod *corev1.Pod) bool {
+	if _, ok := pod.Annotations[corev1.MirrorPodAnnotationKey]; ok {
+		return false
+	}
+
+	if pod.Labels[consts.LabelManagedBy] == consts.LabelValueManagedBy {
+		return false
+	}
+
+	for _, owner := range pod.OwnerReferences {
+		if owner.Controller != nil && *owner.Controller && owner.Kind == "DaemonSet" {
+			return false
+		}
+	}
+
+	return pod.Status.Phase != corev1.PodSucceeded && pod.Status.Phase != corev1.PodFailed
+}
+
+// IsUnmanagedPod returns whether the pod has no controller, such as a ReplicaSet or a Job, to
+// recreate it once evicted.
+func IsUnmanagedPod(pod *corev1.Pod) bool {
+	return metav1.GetControllerOf(pod) == nil
+}
+
+// isCurrentPod returns whether the pod runs the CLI, when the CLI runs in a pod with its name and
+// namespace in the environment, such as the Jobs of "longhornctl generate job" and --no-wait.
+func isCurrentPod(pod *corev1.Pod) bool {
+	return pod.Name == os.Getenv(consts.EnvPodName) && pod.Namespace == os.Getenv(consts.EnvPodNamespace)
+}
+
+// WaitForNodeRebooted waits until the node reports a boot ID other than the given one, and is
+// ready.
+func WaitForNodeRebooted(ctx context.Context, kubeClient *kubeclient.Clientset, nodeName, bootID string) error {
+	err := wait.PollUntilContextCancel(ctx, nodeRebootInterval, false, func(ctx context.Context) (bool, error) {
+		node, err := kubeClient.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+		if err != nil {
+			// The API server may be unreachable while a control plane node reboots.
+			logrus.WithError(err).Debugf("Failed to get node %v", nodeName)
+			return false, nil
+		}
+		return node.Status.NodeInfo.BootID != bootID && IsNodeReady(node), nil
+	})
+	return errors.Wrapf(err, "failed waiting for node %v to reboot", nodeName)
+}
+
+// IsNodeReady returns whether the node has the Ready condition.
+func IsNodeReady(node *corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
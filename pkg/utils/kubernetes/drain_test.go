@@ -0,0 +1,128 @@
+package kubernetes
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	"github.com/longhorn/cli/pkg/consts"
+)
+
+func TestIsEvictablePod(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		pod       *corev1.Pod
+		evictable bool
+	}{
+		{
+			name:      "running pod",
+			pod:       &corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodRunning}},
+			evictable: true,
+		},
+		{
+			name: "instance manager pod",
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					OwnerReferences: []metav1.OwnerReference{{Kind: "InstanceManager", Controller: ptr.To(true)}},
+				},
+				Status: corev1.PodStatus{Phase: corev1.PodRunning},
+			},
+			evictable: true,
+		},
+		{
+			name: "DaemonSet pod",
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					OwnerReferences: []metav1.OwnerReference{{Kind: "DaemonSet", Controller: ptr.To(true)}},
+				},
+				Status: corev1.PodStatus{Phase: corev1.PodRunning},
+			},
+		},
+		{
+			name: "static pod",
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{corev1.MirrorPodAnnotationKey: "hash"},
+				},
+				Status: corev1.PodStatus{Phase: corev1.PodRunning},
+			},
+		},
+		{
+			name: "longhornctl pod",
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{consts.LabelManagedBy: consts.LabelValueManagedBy},
+				},
+				Status: corev1.PodStatus{Phase: corev1.PodRunning},
+			},
+		},
+		{
+			name: "completed pod",
+			pod:  &corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodSucceeded}},
+		},
+	} {
+		if evictable := IsEvictablePod(test.pod); evictable != test.evictable {
+			t.Errorf("%v: expected evictable %v, got %v", test.name, test.evictable, evictable)
+		}
+	}
+}
+
+func TestIsUnmanagedPod(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		pod       *corev1.Pod
+		unmanaged bool
+	}{
+		{
+			name:      "bare pod",
+			pod:       &corev1.Pod{},
+			unmanaged: true,
+		},
+		{
+			name: "pod with a non-controller owner",
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					OwnerReferences: []metav1.OwnerReference{{Kind: "ConfigMap"}},
+				},
+			},
+			unmanaged: true,
+		},
+		{
+			name: "ReplicaSet pod",
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Controller: ptr.To(true)}},
+				},
+			},
+		},
+		{
+			name: "instance manager pod",
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					OwnerReferences: []metav1.OwnerReference{{Kind: "InstanceManager", Controller: ptr.To(true)}},
+				},
+			},
+		},
+	} {
+		if unmanaged := IsUnmanagedPod(test.pod); unmanaged != test.unmanaged {
+			t.Errorf("%v: expected unmanaged %v, got %v", test.name, test.unmanaged, unmanaged)
+		}
+	}
+}
+
+func TestIsCurrentPod(t *testing.T) {
+	t.Setenv(consts.EnvPodName, "longhornctl-install-x7k2p")
+	t.Setenv(consts.EnvPodNamespace, "longhorn-system")
+
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "longhornctl-install-x7k2p", Namespace: "longhorn-system"}}
+	if !isCurrentPod(pod) {
+		t.Error("expected the current pod")
+	}
+
+	pod.Namespace = "default"
+	if isCurrentPod(pod) {
+		t.Error("expected a pod in another namespace not to be the current pod")
+	}
+}
@@ -0,0 +1,51 @@
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"io"
+
+	"github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	kubeclient "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// ExecPodContainer runs the command in the container of the pod, and returns its stdout and stderr.
+func ExecPodContainer(ctx context.Context, config *rest.Config, kubeClient *kubeclient.Clientset, namespace, name, containerName string, command []string) (string, string, error) {
+	var stdout, stderr bytes.Buffer
+	err := StreamPodContainer(ctx, config, kubeClient, namespace, name, containerName, command, nil, &stdout, &stderr)
+	return stdout.String(), stderr.String(), err
+}
+
+// StreamPodContainer runs the command in the container of the pod, streaming the stdin to the
+// command and its stdout and stderr to the writers. The stdin is not attached when it is nil.
+func StreamPodContainer(ctx context.Context, config *rest.Config, kubeClient *kubeclient.Clientset, namespace, name, containerName string, command []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	request := kubeClient.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(namespace).
+		Name(name).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: containerName,
+			Command:   command,
+			Stdin:     stdin != nil,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+
+	executor, err := remotecommand.NewSPDYExecutor(config, "POST", request.URL())
+	if err != nil {
+		return errors.Wrapf(err, "failed to create executor for pod %v", name)
+	}
+
+	err = executor.StreamWithContext(ctx, remotecommand.StreamOptions{
+		Stdin:  stdin,
+		Stdout: stdout,
+		Stderr: stderr,
+	})
+	return errors.Wrapf(err, "failed to run %v in container %v of pod %v", command, containerName, name)
+}
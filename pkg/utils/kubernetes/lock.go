@@ -0,0 +1,220 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/utils/ptr"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeclient "k8s.io/client-go/kubernetes"
+
+	"github.com/longhorn/cli/pkg/consts"
+)
+
+const (
+	// operationLockDuration is the time the lock of an operation is held without being renewed,
+	// so the lock of a CLI killed before releasing it expires.
+	operationLockDuration = 60 * time.Second
+	// operationLockRenewInterval is the interval between the renewals of the lock while the
+	// operation runs.
+	operationLockRenewInterval = operationLockDuration / 3
+)
+
+// OperationLock is a Lease held by a CLI operation modifying the cluster or the nodes while it
+// runs, so another operation of the same kind is refused instead of fighting over the same
+// resources.
+type OperationLock struct {
+	kubeClient *kubeclient.Clientset
+
+	namespace string
+	name      string
+	holder    string // Identity of the operation holding the lock.
+	command   string // Command of the operation, recorded on the Lease.
+
+	stopCh chan struct{}
+}
+
+// NewOperationLock returns the lock named after the operation in the namespace.
+func NewOperationLock(kubeClient *kubeclient.Clientset, namespace, name, holder, command string) *OperationLock {
+	return &OperationLock{
+		kubeClient: kubeClient,
+		namespace:  namespace,
+		name:       name,
+		holder:     holder,
+		command:    command,
+	}
+}
+
+// Acquire acquires the lock, and renews it in the background until it is released. It fails when
+// another operation holds the lock and renewed it recently, unless force is set to take it over.
+func (lock *OperationLock) Acquire(force bool) error {
+	leases := lock.kubeClient.CoordinationV1().Leases(lock.namespace)
+	now := time.Now()
+
+	lease, err := leases.Get(context.Background(), lock.name, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		lease, err = leases.Create(context.Background(), lock.newLease(now), metav1.CreateOptions{})
+		if apierrors.IsAlreadyExists(err) {
+			return &operationInProgressError{message: fmt.Sprintf("operation %q was started concurrently, retry once it completes", lock.command)}
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to create lease %v", lock.name)
+		}
+	case err != nil:
+		return errors.Wrapf(err, "failed to get lease %v", lock.name)
+	default:
+		if err := checkLeaseHolder(lease, lock.holder, now); err != nil {
+			if !force {
+				return err
+			}
+			logrus.WithError(err).Warn("Taking over the operation lock")
+		}
+
+		newLease := lock.newLease(now)
+		lease.Annotations = newLease.Annotations
+		lease.Spec = newLease.Spec
+		lease, err = leases.Update(context.Background(), lease, metav1.UpdateOptions{})
+		if apierrors.IsConflict(err) {
+			return &operationInProgressError{message: fmt.Sprintf("operation %q was started concurrently, retry once it completes", lock.command)}
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to update lease %v", lock.name)
+		}
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"kind":      "Lease",
+		"namespace": lease.Namespace,
+		"name":      lease.Name,
+	}).Debug("Acquired operation lock")
+
+	lock.stopCh = make(chan struct{})
+	go lock.renew()
+
+	return nil
+}
+
+// Release stops renewing the lock, and deletes the Lease if the operation still holds it.
+func (lock *OperationLock) Release() error {
+	if lock.stopCh != nil {
+		close(lock.stopCh)
+		lock.stopCh = nil
+	}
+
+	leases := lock.kubeClient.CoordinationV1().Leases(lock.namespace)
+	lease, err := leases.Get(context.Background(), lock.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to get lease %v", lock.name)
+	}
+	if ptr.Deref(lease.Spec.HolderIdentity, "") != lock.holder {
+		return nil
+	}
+
+	err = leases.Delete(context.Background(), lock.name, metav1.DeleteOptions{
+		Preconditions: &metav1.Preconditions{ResourceVersion: &lease.ResourceVersion},
+	})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to delete lease %v", lock.name)
+	}
+	return nil
+}
+
+// renew renews the lock until it is released. The operation is not interrupted when the lock is
+// taken over, only warned.
+func (lock *OperationLock) renew() {
+	ticker := time.NewTicker(operationLockRenewInterval)
+	defer ticker.Stop()
+
+	stopCh := lock.stopCh
+	leases := lock.kubeClient.CoordinationV1().Leases(lock.namespace)
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+		}
+
+		lease, err := leases.Get(context.Background(), lock.name, metav1.GetOptions{})
+		if err != nil {
+			logrus.WithError(err).Warn("Failed to renew operation lock")
+			continue
+		}
+		if holder := ptr.Deref(lease.Spec.HolderIdentity, ""); holder != lock.holder {
+			logrus.Warnf("Operation lock was taken over by %v", holder)
+			return
+		}
+
+		lease.Spec.RenewTime = ptr.To(metav1.NewMicroTime(time.Now()))
+		if _, err := leases.Update(context.Background(), lease, metav1.UpdateOptions{}); err != nil {
+			logrus.WithError(err).Warn("Failed to renew operation lock")
+		}
+	}
+}
+
+func (lock *OperationLock) newLease(now time.Time) *coordinationv1.Lease {
+	return &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      lock.name,
+			Namespace: lock.namespace,
+			Annotations: map[string]string{
+				consts.AnnotationAuditCommand: lock.command,
+			},
+		},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       ptr.To(lock.holder),
+			LeaseDurationSeconds: ptr.To(int32(operationLockDuration.Seconds())),
+			AcquireTime:          ptr.To(metav1.NewMicroTime(now)),
+			RenewTime:            ptr.To(metav1.NewMicroTime(now)),
+		},
+	}
+}
+
+// checkLeaseHolder returns an error when the Lease is held by another holder, and did not expire.
+func checkLeaseHolder(lease *coordinationv1.Lease, holder string, now time.Time) error {
+	leaseHolder := ptr.Deref(lease.Spec.HolderIdentity, "")
+	if leaseHolder == "" || leaseHolder == holder {
+		return nil
+	}
+
+	if lease.Spec.RenewTime != nil && lease.Spec.LeaseDurationSeconds != nil {
+		expireTime := lease.Spec.RenewTime.Add(time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second)
+		if now.After(expireTime) {
+			return nil
+		}
+	}
+
+	since := ""
+	if lease.Spec.AcquireTime != nil {
+		since = fmt.Sprintf(" since %s", lease.Spec.AcquireTime.UTC().Format(time.RFC3339))
+	}
+	return &operationInProgressError{
+		message: fmt.Sprintf("operation %q already in progress by %s%s, use --%s if it is no longer running",
+			lease.Annotations[consts.AnnotationAuditCommand], leaseHolder, since, consts.CmdOptForceUnlock),
+	}
+}
+
+// operationInProgressError is returned when another operation holds the lock.
+type operationInProgressError struct {
+	message string
+}
+
+func (err *operationInProgressError) Error() string {
+	return err.message
+}
+
+// IsOperationInProgress returns true if the error is returned because another operation holds the lock.
+func IsOperationInProgress(err error) bool {
+	_, ok := errors.Cause(err).(*operationInProgressError)
+	return ok
+}
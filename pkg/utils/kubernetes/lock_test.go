@@ -0,0 +1,56 @@
+package kubernetes
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/utils/ptr"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCheckLeaseHolder(t *testing.T) {
+	now := time.Date(2024, 7, 16, 17, 30, 0, 0, time.UTC)
+	newLease := func(holder string, renewTime time.Time) *coordinationv1.Lease {
+		return &coordinationv1.Lease{
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       ptr.To(holder),
+				LeaseDurationSeconds: ptr.To(int32(60)),
+				AcquireTime:          ptr.To(metav1.NewMicroTime(renewTime.Add(-time.Hour))),
+				RenewTime:            ptr.To(metav1.NewMicroTime(renewTime)),
+			},
+		}
+	}
+
+	tests := map[string]struct {
+		lease         *coordinationv1.Lease
+		expectedError bool
+	}{
+		"held by another holder": {
+			lease:         newLease("bob (bob@laptop, pid 42)", now.Add(-10*time.Second)),
+			expectedError: true,
+		},
+		"expired": {
+			lease: newLease("bob (bob@laptop, pid 42)", now.Add(-2*time.Minute)),
+		},
+		"held by the holder": {
+			lease: newLease("alice (alice@laptop, pid 7)", now.Add(-10*time.Second)),
+		},
+		"released": {
+			lease: &coordinationv1.Lease{},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := checkLeaseHolder(test.lease, "alice (alice@laptop, pid 7)", now)
+			if test.expectedError != (err != nil) {
+				t.Fatalf("expected error %v, got %v", test.expectedError, err)
+			}
+			if err != nil && !IsOperationInProgress(err) {
+				t.Errorf("expected an operation in progress error, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package kubernetes
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	longhorn "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+	lhclient "github.com/longhorn/longhorn-manager/k8s/pkg/client/clientset/versioned"
+	lhmgrtypes "github.com/longhorn/longhorn-manager/types"
+)
+
+// GetLonghornVersion returns the version of the Longhorn manager running in the namespace.
+func GetLonghornVersion(ctx context.Context, longhornClient *lhclient.Clientset, namespace string) (string, error) {
+	setting, err := longhornClient.LonghornV1beta2().Settings(namespace).Get(ctx, string(lhmgrtypes.SettingNameCurrentLonghornVersion), metav1.GetOptions{})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get setting %v", lhmgrtypes.SettingNameCurrentLonghornVersion)
+	}
+
+	if setting.Value == "" {
+		return "", errors.Errorf("setting %v is empty", lhmgrtypes.SettingNameCurrentLonghornVersion)
+	}
+	return setting.Value, nil
+}
+
+// CreateBackup takes a snapshot of the volume, backs it up to the backup target, and waits for the
+// backup to complete. The snapshot and the backup are both named after the backup, and are reused
+// when they exist, so calling it again resumes an interrupted backup.
+func CreateBackup(ctx context.Context, longhornClient *lhclient.Clientset, namespace, volumeName, backupTargetName, backupName string) (*longhorn.Backup, error) {
+	backupTarget, err := longhornClient.LonghornV1beta2().BackupTargets(namespace).Get(ctx, backupTargetName, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get backup target %v", backupTargetName)
+	}
+	if backupTarget.Spec.BackupTargetURL == "" {
+		return nil, errors.Errorf("backup target %v is not configured", backupTargetName)
+	}
+	if !backupTarget.Status.Available {
+		return nil, errors.Errorf("backup target %v (%v) is not available", backupTargetName, backupTarget.Spec.BackupTargetURL)
+	}
+
+	logrus.WithFields(logrus.Fields{"volume": volumeName, "snapshot": backupName}).Info("Creating snapshot")
+	snapshots := longhornClient.LonghornV1beta2().Snapshots(namespace)
+	snapshot := &longhorn.Snapshot{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: backupName,
+		},
+		Spec: longhorn.SnapshotSpec{
+			Volume:         volumeName,
+			CreateSnapshot: true,
+		},
+	}
+	if _, err := snapshots.Create(ctx, snapshot, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+		return nil, errors.Wrapf(err, "failed to create snapshot %v", backupName)
+	}
+
+	err = wait.PollUntilContextCancel(ctx, 2*time.Second, true, func(ctx context.Context) (bool, error) {
+		snapshot, err := snapshots.Get(ctx, backupName, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+
+		if snapshot.Status.Error != "" {
+			return false, errors.New(snapshot.Status.Error)
+		}
+		return snapshot.Status.ReadyToUse, nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed waiting for snapshot %v to be ready", backupName)
+	}
+
+	logrus.WithFields(logrus.Fields{"volume": volumeName, "backup": backupName}).Infof("Backing up snapshot to %v", backupTarget.Spec.BackupTargetURL)
+	backups := longhornClient.LonghornV1beta2().Backups(namespace)
+	backup := &longhorn.Backup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   backupName,
+			Labels: lhmgrtypes.GetBackupVolumeWithBackupTargetLabels(backupTargetName, volumeName),
+		},
+		Spec: longhorn.BackupSpec{
+			SnapshotName: backupName,
+		},
+	}
+	if _, err := backups.Create(ctx, backup, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+		return nil, errors.Wrapf(err, "failed to create backup %v", backupName)
+	}
+
+	err = wait.PollUntilContextCancel(ctx, 2*time.Second, true, func(ctx context.Context) (bool, error) {
+		backup, err = backups.Get(ctx, backupName, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+
+		switch backup.Status.State {
+		case longhorn.BackupStateError, longhorn.BackupStateUnknown:
+			return false, errors.Errorf("backup is %v: %v", backup.Status.State, backup.Status.Error)
+		case longhorn.BackupStateCompleted:
+			return true, nil
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed waiting for backup %v to complete", backupName)
+	}
+	return backup, nil
+}
@@ -0,0 +1,26 @@
+package kubernetes
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+)
+
+// LogManifest logs the YAML manifest of the object at debug level, before it is
+// created in the cluster.
+func LogManifest(obj runtime.Object) {
+	if !logrus.IsLevelEnabled(logrus.DebugLevel) {
+		return
+	}
+
+	yamlData, err := yaml.Marshal(obj)
+	if err != nil {
+		logrus.WithError(err).Debugf("Failed to convert %T to YAML", obj)
+		return
+	}
+
+	logrus.WithField("kind", fmt.Sprintf("%T", obj)).Debugf("Generated manifest:\n%s", yamlData)
+}
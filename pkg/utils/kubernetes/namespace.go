@@ -0,0 +1,39 @@
+package kubernetes
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeclient "k8s.io/client-go/kubernetes"
+)
+
+// CreateNamespace creates the namespace with the given name.
+// If the namespace already exists, it will be returned.
+func CreateNamespace(kubeClient *kubeclient.Clientset, name string) (*corev1.Namespace, error) {
+	log := logrus.WithFields(logrus.Fields{
+		"kind": "Namespace",
+		"name": name,
+	})
+	log.Debug("Creating resource")
+
+	newNamespace := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+	}
+
+	namespace, err := kubeClient.CoreV1().Namespaces().Create(context.Background(), newNamespace, metav1.CreateOptions{})
+	if err != nil {
+		if apierrors.IsAlreadyExists(err) {
+			log.WithError(err).Debug("Resource already exists")
+			return kubeClient.CoreV1().Namespaces().Get(context.Background(), name, metav1.GetOptions{})
+		}
+		return nil, err
+	}
+
+	return namespace, nil
+}
@@ -0,0 +1,222 @@
+package kubernetes
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	kubeclient "k8s.io/client-go/kubernetes"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+)
+
+const (
+	// nodeLabelType is the label virtual kubelets, such as the ones of the serverless container
+	// platforms, set on their nodes.
+	nodeLabelType          = "type"
+	nodeTypeVirtualKubelet = "virtual-kubelet"
+
+	nodeOSWindows = "windows"
+
+	// nodeGroupNone is the group of the nodes without the label or information of the category.
+	nodeGroupNone = "<none>"
+)
+
+// nodePoolLabels are the labels of the node pools set by the managed platforms and node
+// provisioners, in the order they are looked up.
+var nodePoolLabels = []string{
+	"cloud.google.com/gke-nodepool",
+	"eks.amazonaws.com/nodegroup",
+	"alpha.eksctl.io/nodegroup-name",
+	"karpenter.sh/nodepool",
+	"kubernetes.azure.com/agentpool",
+	"doks.digitalocean.com/node-pool",
+}
+
+// unsupportedNodeRequirements returns the node selector requirements excluding the nodes the
+// pods of longhornctl cannot run on. They match the nodes without the labels as well.
+func unsupportedNodeRequirements() []corev1.NodeSelectorRequirement {
+	return []corev1.NodeSelectorRequirement{
+		{
+			Key:      corev1.LabelOSStable,
+			Operator: corev1.NodeSelectorOpNotIn,
+			Values:   []string{nodeOSWindows},
+		},
+		{
+			Key:      nodeLabelType,
+			Operator: corev1.NodeSelectorOpNotIn,
+			Values:   []string{nodeTypeVirtualKubelet},
+		},
+	}
+}
+
+// GetUnsupportedNodeReason returns why the pods of longhornctl cannot run on the node, or an
+// empty string when they can.
+func GetUnsupportedNodeReason(node *corev1.Node) string {
+	if node.Labels[corev1.LabelOSStable] == nodeOSWindows {
+		return "Windows nodes are not supported"
+	}
+	if node.Labels[nodeLabelType] == nodeTypeVirtualKubelet {
+		return "Virtual kubelet nodes are not supported"
+	}
+	return ""
+}
+
+// GetNodeGroup returns the group of the node in the category of the summary: its zone, node pool
+// or operating system image.
+func GetNodeGroup(node *corev1.Node, category string) string {
+	var group string
+	switch category {
+	case consts.SummarizeByZone:
+		group = node.Labels[corev1.LabelTopologyZone]
+		if group == "" {
+			group = node.Labels[corev1.LabelFailureDomainBetaZone]
+		}
+	case consts.SummarizeByNodePool:
+		for _, label := range nodePoolLabels {
+			if group = node.Labels[label]; group != "" {
+				break
+			}
+		}
+	case consts.SummarizeByOS:
+		group = node.Status.NodeInfo.OSImage
+	}
+
+	if group == "" {
+		return nodeGroupNone
+	}
+	return group
+}
+
+// SetSupportedNodeAffinity excludes the unsupported nodes from the nodes the pod can be scheduled
+// on, so the DaemonSets do not wait for pods that can never run.
+func SetSupportedNodeAffinity(podSpec *corev1.PodSpec) {
+	terms := getRequiredNodeSelectorTerms(podSpec)
+	for i := range terms {
+		for _, requirement := range unsupportedNodeRequirements() {
+			if slices.ContainsFunc(terms[i].MatchExpressions, func(existing corev1.NodeSelectorRequirement) bool {
+				return existing.Key == requirement.Key
+			}) {
+				continue
+			}
+			terms[i].MatchExpressions = append(terms[i].MatchExpressions, requirement)
+		}
+	}
+}
+
+// getRequiredNodeSelectorTerms returns the required node selector terms of the pod, creating a
+// term when there is none.
+func getRequiredNodeSelectorTerms(podSpec *corev1.PodSpec) []corev1.NodeSelectorTerm {
+	if podSpec.Affinity == nil {
+		podSpec.Affinity = &corev1.Affinity{}
+	}
+	if podSpec.Affinity.NodeAffinity == nil {
+		podSpec.Affinity.NodeAffinity = &corev1.NodeAffinity{}
+	}
+	nodeAffinity := podSpec.Affinity.NodeAffinity
+	if nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = &corev1.NodeSelector{}
+	}
+	if len(nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms) == 0 {
+		nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms = []corev1.NodeSelectorTerm{{}}
+	}
+	return nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+}
+
+// AddSkippedNodes adds the unsupported nodes matching the node selector of the DaemonSet to the
+// result, with the reason they are skipped.
+func AddSkippedNodes(kubeClient *kubeclient.Clientset, daemonSet *appsv1.DaemonSet, nodeCollections map[string]*types.LogCollection) error {
+	nodes, err := ListNodes(kubeClient, labels.SelectorFromSet(daemonSet.Spec.Template.Spec.NodeSelector))
+	if err != nil {
+		return err
+	}
+
+	for _, node := range nodes {
+		reason := GetUnsupportedNodeReason(node)
+		if reason == "" {
+			continue
+		}
+
+		name := node.Name
+		if nodeCollections[name] == nil {
+			nodeCollections[name] = &types.LogCollection{}
+		}
+		nodeCollections[name].Skipped = append(nodeCollections[name].Skipped, fmt.Sprintf("%v, skipped running %v on it", reason, daemonSet.Name))
+	}
+	return nil
+}
+
+// ValidateNodeSelector returns the names of the schedulable nodes matching the node selector, listed
+// from the informer cache of the client, and logs them. It returns an error when no node matches,
+// or fewer than minNodes, so the DaemonSets are not created for pods that cannot run anywhere.
+func ValidateNodeSelector(kubeClient *kubeclient.Clientset, nodeSelector map[string]string, minNodes int) ([]string, error) {
+	selector := labels.SelectorFromSet(nodeSelector)
+	nodes, err := ListNodes(kubeClient, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	nodeNames, excludedNodes := getSchedulableNodeNames(nodes)
+	if err := checkSchedulableNodeCount(selector.String(), nodeNames, excludedNodes, minNodes); err != nil {
+		return nil, err
+	}
+
+	logrus.Infof("Matched %d schedulable nodes: %s", len(nodeNames), strings.Join(nodeNames, consts.CmdOptSeperator))
+	return nodeNames, nil
+}
+
+// getSchedulableNodeNames returns the sorted names of the nodes the DaemonSet pods of longhornctl
+// can be scheduled on, and the reason each other node is excluded, keyed by the node name. The
+// cordoned nodes are included, as the DaemonSet controller still schedules pods on them.
+func getSchedulableNodeNames(nodes []*corev1.Node) ([]string, map[string]string) {
+	nodeNames := []string{}
+	excludedNodes := map[string]string{}
+	for _, node := range nodes {
+		if reason := GetUnsupportedNodeReason(node); reason != "" {
+			excludedNodes[node.Name] = reason
+			continue
+		}
+		if node.Spec.Unschedulable {
+			logrus.Debugf("Node %v is cordoned, the DaemonSet pods still run on it", node.Name)
+		}
+		nodeNames = append(nodeNames, node.Name)
+	}
+	sort.Strings(nodeNames)
+	return nodeNames, excludedNodes
+}
+
+// checkSchedulableNodeCount returns an error describing the excluded nodes when there is no
+// schedulable node, or fewer than minNodes.
+func checkSchedulableNodeCount(selector string, nodeNames []string, excludedNodes map[string]string, minNodes int) error {
+	if len(nodeNames) > 0 && len(nodeNames) >= minNodes {
+		return nil
+	}
+
+	target := ""
+	if selector != "" {
+		target = fmt.Sprintf(" matching --%s %s", consts.CmdOptNodeSelector, selector)
+	}
+
+	excluded := make([]string, 0, len(excludedNodes))
+	for node, reason := range excludedNodes {
+		excluded = append(excluded, fmt.Sprintf("%s: %s", node, reason))
+	}
+	sort.Strings(excluded)
+
+	message := fmt.Sprintf("found %d schedulable Linux nodes%s", len(nodeNames), target)
+	if minNodes > 0 {
+		message += fmt.Sprintf(", --%s requires %d", consts.CmdOptRequireMinNodes, minNodes)
+	}
+	if len(excluded) > 0 {
+		message += fmt.Sprintf(" (excluded %s)", strings.Join(excluded, "; "))
+	}
+	return errors.New(message)
+}
@@ -0,0 +1,147 @@
+package kubernetes
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/longhorn/cli/pkg/consts"
+)
+
+func TestGetUnsupportedNodeReason(t *testing.T) {
+	for _, test := range []struct {
+		labels map[string]string
+		reason string
+	}{
+		{
+			labels: map[string]string{corev1.LabelOSStable: "linux"},
+		},
+		{
+			labels: map[string]string{},
+		},
+		{
+			labels: map[string]string{corev1.LabelOSStable: "windows"},
+			reason: "Windows nodes are not supported",
+		},
+		{
+			labels: map[string]string{corev1.LabelOSStable: "linux", "type": "virtual-kubelet"},
+			reason: "Virtual kubelet nodes are not supported",
+		},
+	} {
+		node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node", Labels: test.labels}}
+		if reason := GetUnsupportedNodeReason(node); reason != test.reason {
+			t.Errorf("expected reason %q for labels %v, got %q", test.reason, test.labels, reason)
+		}
+	}
+}
+
+func TestGetNodeGroup(t *testing.T) {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node", Labels: map[string]string{
+		corev1.LabelFailureDomainBetaZone: "us-east-1a",
+		"eks.amazonaws.com/nodegroup":     "storage",
+	}}}
+	node.Status.NodeInfo.OSImage = "Ubuntu 22.04.4 LTS"
+
+	for _, test := range []struct {
+		category string
+		group    string
+	}{
+		{category: consts.SummarizeByZone, group: "us-east-1a"},
+		{category: consts.SummarizeByNodePool, group: "storage"},
+		{category: consts.SummarizeByOS, group: "Ubuntu 22.04.4 LTS"},
+	} {
+		if group := GetNodeGroup(node, test.category); group != test.group {
+			t.Errorf("expected group %q by %v, got %q", test.group, test.category, group)
+		}
+	}
+
+	node.Labels = map[string]string{corev1.LabelTopologyZone: "us-east-1b"}
+	if group := GetNodeGroup(node, consts.SummarizeByZone); group != "us-east-1b" {
+		t.Errorf("expected zone us-east-1b, got %q", group)
+	}
+	if group := GetNodeGroup(node, consts.SummarizeByNodePool); group != "<none>" {
+		t.Errorf("expected no node pool, got %q", group)
+	}
+}
+
+func TestSetSupportedNodeAffinity(t *testing.T) {
+	podSpec := &corev1.PodSpec{}
+	SetNodeNameAffinity(podSpec, []string{"node-a"})
+	SetSupportedNodeAffinity(podSpec)
+	SetSupportedNodeAffinity(podSpec)
+
+	// The batches restrict the pods to their nodes after the pod options are set.
+	SetNodeNameAffinity(podSpec, []string{"node-b"})
+
+	terms := podSpec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	if len(terms) != 1 {
+		t.Fatalf("expected 1 node selector term, got %v", terms)
+	}
+	if !reflect.DeepEqual(terms[0].MatchExpressions, unsupportedNodeRequirements()) {
+		t.Errorf("expected match expressions %v, got %v", unsupportedNodeRequirements(), terms[0].MatchExpressions)
+	}
+	if len(terms[0].MatchFields) != 1 || !reflect.DeepEqual(terms[0].MatchFields[0].Values, []string{"node-b"}) {
+		t.Errorf("expected the pod to be restricted to node-b, got %v", terms[0].MatchFields)
+	}
+}
+
+func TestGetSchedulableNodeNames(t *testing.T) {
+	nodes := []*corev1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-b"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-a"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-c"}, Spec: corev1.NodeSpec{Unschedulable: true}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "win-a", Labels: map[string]string{corev1.LabelOSStable: "windows"}}},
+	}
+
+	nodeNames, excludedNodes := getSchedulableNodeNames(nodes)
+	// The DaemonSet pods run on the cordoned nodes.
+	if !reflect.DeepEqual(nodeNames, []string{"node-a", "node-b", "node-c"}) {
+		t.Errorf("expected nodes [node-a node-b node-c], got %v", nodeNames)
+	}
+	expectedExcludedNodes := map[string]string{"win-a": "Windows nodes are not supported"}
+	if !reflect.DeepEqual(excludedNodes, expectedExcludedNodes) {
+		t.Errorf("expected excluded nodes %v, got %v", expectedExcludedNodes, excludedNodes)
+	}
+}
+
+func TestCheckSchedulableNodeCount(t *testing.T) {
+	for name, test := range map[string]struct {
+		selector      string
+		nodeNames     []string
+		excludedNodes map[string]string
+		minNodes      int
+		expectedError string
+	}{
+		"matched": {
+			nodeNames: []string{"node-a"},
+		},
+		"enough nodes": {
+			nodeNames: []string{"node-a", "node-b"},
+			minNodes:  2,
+		},
+		"no node": {
+			selector:      "env=prod",
+			excludedNodes: map[string]string{"win-a": "Windows nodes are not supported"},
+			expectedError: "found 0 schedulable Linux nodes matching --node-selector env=prod (excluded win-a: Windows nodes are not supported)",
+		},
+		"no node in the cluster": {
+			expectedError: "found 0 schedulable Linux nodes",
+		},
+		"not enough nodes": {
+			selector:      "env=prod",
+			nodeNames:     []string{"node-a"},
+			minNodes:      3,
+			expectedError: "found 1 schedulable Linux nodes matching --node-selector env=prod, --require-min-nodes requires 3",
+		},
+	} {
+		err := checkSchedulableNodeCount(test.selector, test.nodeNames, test.excludedNodes, test.minNodes)
+		switch {
+		case test.expectedError == "" && err != nil:
+			t.Errorf("%v: unexpected error: %v", name, err)
+		case test.expectedError != "" && (err == nil || err.Error() != test.expectedError):
+			t.Errorf("%v: expected error %q, got %v", name, test.expectedError, err)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/utils/ptr"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	kubeclient "k8s.io/client-go/kubernetes"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+)
+
+// SetPodOptions applies the pod resources, priority class and proxy of the global options to the pod spec.
+//
+// The CPU and memory are set as both the requests and limits of every container, so
+// the pods get the Guaranteed QoS class and are the last to be evicted under node pressure.
+func SetPodOptions(podSpec *corev1.PodSpec, globalOpts *types.GlobalCmdOptions) error {
+	resources, err := parsePodResources(globalOpts.PodCpu, globalOpts.PodMemory)
+	if err != nil {
+		return err
+	}
+
+	if len(resources) > 0 {
+		for i := range podSpec.InitContainers {
+			setContainerResources(&podSpec.InitContainers[i], resources)
+		}
+		for i := range podSpec.Containers {
+			setContainerResources(&podSpec.Containers[i], resources)
+		}
+	}
+
+	SetSupportedNodeAffinity(podSpec)
+
+	if globalOpts.PriorityClass != "" {
+		podSpec.PriorityClassName = globalOpts.PriorityClass
+	}
+
+	if proxyEnvVars := newProxyEnvVars(globalOpts); len(proxyEnvVars) > 0 {
+		for i := range podSpec.InitContainers {
+			podSpec.InitContainers[i].Env = append(podSpec.InitContainers[i].Env, proxyEnvVars...)
+		}
+		for i := range podSpec.Containers {
+			podSpec.Containers[i].Env = append(podSpec.Containers[i].Env, proxyEnvVars...)
+		}
+	}
+
+	return nil
+}
+
+// newProxyEnvVars returns the proxy environment variables, in upper and lower case, for the proxy
+// options. With a proxy, the Kubernetes service and the cluster domains are excluded from it,
+// so the pods still reach the Kubernetes API.
+func newProxyEnvVars(globalOpts *types.GlobalCmdOptions) []corev1.EnvVar {
+	values := map[string]string{}
+	if globalOpts.Proxy != "" {
+		values[consts.EnvHttpProxy] = globalOpts.Proxy
+		values[consts.EnvHttpsProxy] = globalOpts.Proxy
+		values[consts.EnvNoProxy] = utils.JoinNoProxy(globalOpts.NoProxy, "$("+consts.EnvKubernetesServiceHost+")", ".svc", ".cluster.local")
+	} else if globalOpts.NoProxy != "" {
+		values[consts.EnvNoProxy] = globalOpts.NoProxy
+	}
+
+	envVars := []corev1.EnvVar{}
+	for _, name := range []string{consts.EnvHttpProxy, consts.EnvHttpsProxy, consts.EnvNoProxy} {
+		if values[name] == "" {
+			continue
+		}
+		envVars = append(envVars,
+			corev1.EnvVar{Name: name, Value: values[name]},
+			corev1.EnvVar{Name: strings.ToLower(name), Value: values[name]},
+		)
+	}
+	return envVars
+}
+
+func parsePodResources(cpu, memory string) (corev1.ResourceList, error) {
+	resources := corev1.ResourceList{}
+
+	if cpu != "" {
+		quantity, err := resource.ParseQuantity(cpu)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid --%s %q", consts.CmdOptPodCpu, cpu)
+		}
+		resources[corev1.ResourceCPU] = quantity
+	}
+
+	if memory != "" {
+		quantity, err := resource.ParseQuantity(memory)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid --%s %q", consts.CmdOptPodMemory, memory)
+		}
+		resources[corev1.ResourceMemory] = quantity
+	}
+
+	return resources, nil
+}
+
+func setContainerResources(container *corev1.Container, resources corev1.ResourceList) {
+	if container.Resources.Requests == nil {
+		container.Resources.Requests = corev1.ResourceList{}
+	}
+	if container.Resources.Limits == nil {
+		container.Resources.Limits = corev1.ResourceList{}
+	}
+
+	for name, quantity := range resources {
+		container.Resources.Requests[name] = quantity
+		container.Resources.Limits[name] = quantity
+	}
+}
+
+// WaitForPodCompleted waits until the pod exits, and returns an error if it failed.
+func WaitForPodCompleted(ctx context.Context, kubeClient *kubeclient.Clientset, namespace, name string) error {
+	return wait.PollUntilContextCancel(ctx, 2*time.Second, true, func(ctx context.Context) (bool, error) {
+		pod, err := kubeClient.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+
+		switch pod.Status.Phase {
+		case corev1.PodFailed:
+			return false, errors.Errorf("pod %v failed", name)
+		case corev1.PodSucceeded:
+			return true, nil
+		}
+		return false, nil
+	})
+}
+
+// GetPodContainerLog returns the complete log of the container.
+func GetPodContainerLog(ctx context.Context, kubeClient *kubeclient.Clientset, namespace, name, containerName string) (string, error) {
+	data, err := kubeClient.CoreV1().Pods(namespace).GetLogs(name, &corev1.PodLogOptions{Container: containerName}).DoRaw(ctx)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get the log of container %v of pod %v", containerName, name)
+	}
+	return string(data), nil
+}
+
+// DeletePod deletes the pod without grace period and waits for it to be gone.
+func DeletePod(ctx context.Context, kubeClient *kubeclient.Clientset, namespace, name string) error {
+	err := kubeClient.CoreV1().Pods(namespace).Delete(ctx, name, metav1.DeleteOptions{GracePeriodSeconds: ptr.To(int64(0))})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to delete pod %v", name)
+	}
+
+	err = wait.PollUntilContextCancel(ctx, time.Second, true, func(ctx context.Context) (bool, error) {
+		_, err := kubeClient.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	})
+	return errors.Wrapf(err, "failed waiting for pod %v to be deleted", name)
+}
+
+// podSchedulingGracePeriod is the time a pod can stay unschedulable before it is reported as
+// failing to be scheduled, leaving time to the terminating pods to release their resources.
+const podSchedulingGracePeriod = time.Minute
+
+// GetPodSchedulingError returns a PodSchedulingFailedError when the scheduler has reported the pod
+// unschedulable for longer than the grace period at the time, or nil otherwise.
+func GetPodSchedulingError(pod *corev1.Pod, now time.Time) error {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type != corev1.PodScheduled || condition.Status != corev1.ConditionFalse || condition.Reason != corev1.PodReasonUnschedulable {
+			continue
+		}
+
+		if now.Sub(condition.LastTransitionTime.Time) < podSchedulingGracePeriod {
+			return nil
+		}
+		return &types.PodSchedulingFailedError{
+			Namespace: pod.Namespace,
+			Pod:       pod.Name,
+			Reason:    fmt.Sprintf("%v: %v", condition.Reason, condition.Message),
+		}
+	}
+	return nil
+}
+
+// WaitForPodRunning waits until the pod is running, and returns it.
+func WaitForPodRunning(ctx context.Context, kubeClient *kubeclient.Clientset, namespace, name string) (*corev1.Pod, error) {
+	var runningPod *corev1.Pod
+	err := wait.PollUntilContextCancel(ctx, 2*time.Second, true, func(ctx context.Context) (bool, error) {
+		pod, err := kubeClient.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+
+		if err := GetPodSchedulingError(pod, time.Now()); err != nil {
+			return false, err
+		}
+
+		switch pod.Status.Phase {
+		case corev1.PodFailed, corev1.PodSucceeded:
+			return false, errors.Errorf("pod %v exited", name)
+		case corev1.PodRunning:
+			if pod.Status.PodIP == "" {
+				return false, nil
+			}
+			runningPod = pod
+			return true, nil
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed waiting for pod %v to be running", name)
+	}
+	return runningPod, nil
+}
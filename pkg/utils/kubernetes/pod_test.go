@@ -0,0 +1,159 @@
+package kubernetes
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func TestSetPodOptions(t *testing.T) {
+	for _, test := range []struct {
+		globalOpts   types.GlobalCmdOptions
+		wantCpu      string
+		wantMemory   string
+		wantErr      bool
+		wantPriority string
+	}{
+		{
+			globalOpts: types.GlobalCmdOptions{},
+		},
+		{
+			globalOpts:   types.GlobalCmdOptions{PodCpu: "500m", PodMemory: "256Mi", PriorityClass: "system-node-critical"},
+			wantCpu:      "500m",
+			wantMemory:   "256Mi",
+			wantPriority: "system-node-critical",
+		},
+		{
+			globalOpts: types.GlobalCmdOptions{PodMemory: "1Gi"},
+			wantMemory: "1Gi",
+		},
+		{
+			globalOpts: types.GlobalCmdOptions{PodCpu: "half"},
+			wantErr:    true,
+		},
+	} {
+		podSpec := &corev1.PodSpec{
+			InitContainers: []corev1.Container{{Name: "init"}},
+			Containers:     []corev1.Container{{Name: "main"}},
+		}
+
+		err := SetPodOptions(podSpec, &test.globalOpts)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("%+v: expected error", test.globalOpts)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%+v: unexpected error: %v", test.globalOpts, err)
+		}
+
+		if podSpec.PriorityClassName != test.wantPriority {
+			t.Errorf("%+v: expected priority class %q, got %q", test.globalOpts, test.wantPriority, podSpec.PriorityClassName)
+		}
+
+		for _, container := range append(podSpec.InitContainers, podSpec.Containers...) {
+			for resourceName, want := range map[corev1.ResourceName]string{corev1.ResourceCPU: test.wantCpu, corev1.ResourceMemory: test.wantMemory} {
+				request, hasRequest := container.Resources.Requests[resourceName]
+				limit, hasLimit := container.Resources.Limits[resourceName]
+				if want == "" {
+					if hasRequest || hasLimit {
+						t.Errorf("%+v: container %s: unexpected %s resources", test.globalOpts, container.Name, resourceName)
+					}
+					continue
+				}
+				if request.String() != want || limit.String() != want {
+					t.Errorf("%+v: container %s: expected %s %s, got request %s and limit %s", test.globalOpts, container.Name, resourceName, want, request.String(), limit.String())
+				}
+			}
+		}
+	}
+}
+
+func TestSetPodOptionsProxy(t *testing.T) {
+	for _, test := range []struct {
+		globalOpts types.GlobalCmdOptions
+		wantEnv    map[string]string
+	}{
+		{
+			globalOpts: types.GlobalCmdOptions{},
+			wantEnv:    map[string]string{},
+		},
+		{
+			globalOpts: types.GlobalCmdOptions{Proxy: "http://proxy:3128", NoProxy: "10.0.0.0/8"},
+			wantEnv: map[string]string{
+				"HTTP_PROXY":  "http://proxy:3128",
+				"https_proxy": "http://proxy:3128",
+				"NO_PROXY":    "10.0.0.0/8,$(KUBERNETES_SERVICE_HOST),.svc,.cluster.local",
+			},
+		},
+		{
+			globalOpts: types.GlobalCmdOptions{NoProxy: "example.com"},
+			wantEnv: map[string]string{
+				"no_proxy": "example.com",
+			},
+		},
+	} {
+		podSpec := &corev1.PodSpec{
+			InitContainers: []corev1.Container{{Name: "init"}},
+			Containers:     []corev1.Container{{Name: "main"}},
+		}
+
+		if err := SetPodOptions(podSpec, &test.globalOpts); err != nil {
+			t.Fatalf("%+v: unexpected error: %v", test.globalOpts, err)
+		}
+
+		for _, container := range append(podSpec.InitContainers, podSpec.Containers...) {
+			env := map[string]string{}
+			for _, envVar := range container.Env {
+				env[envVar.Name] = envVar.Value
+			}
+
+			if len(test.wantEnv) == 0 && len(env) != 0 {
+				t.Errorf("%+v: container %s: unexpected env %v", test.globalOpts, container.Name, env)
+			}
+			for name, want := range test.wantEnv {
+				if env[name] != want {
+					t.Errorf("%+v: container %s: expected %s=%q, got %q", test.globalOpts, container.Name, name, want, env[name])
+				}
+			}
+		}
+	}
+}
+
+func TestGetPodSchedulingError(t *testing.T) {
+	now := time.Now()
+	newPod := func(reason string, since time.Duration) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "longhorn-system", Name: "longhorn-preflight-checker-abcde"},
+			Status: corev1.PodStatus{
+				Conditions: []corev1.PodCondition{{
+					Type:               corev1.PodScheduled,
+					Status:             corev1.ConditionFalse,
+					Reason:             reason,
+					Message:            "0/1 nodes are available: 1 Insufficient memory.",
+					LastTransitionTime: metav1.NewTime(now.Add(-since)),
+				}},
+			},
+		}
+	}
+
+	for name, test := range map[string]struct {
+		pod      *corev1.Pod
+		expected bool
+	}{
+		"scheduled":        {pod: &corev1.Pod{}},
+		"unschedulable":    {pod: newPod(corev1.PodReasonUnschedulable, 2*time.Minute), expected: true},
+		"within grace":     {pod: newPod(corev1.PodReasonUnschedulable, 10*time.Second)},
+		"scheduling gated": {pod: newPod(corev1.PodReasonSchedulingGated, 2*time.Minute)},
+	} {
+		err := GetPodSchedulingError(test.pod, now)
+		if types.IsPodSchedulingFailed(err) != test.expected {
+			t.Errorf("%v: unexpected error %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package kubernetes
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	kubeclient "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/transport/spdy"
+)
+
+const (
+	portForwardProtocolV1Name = "portforward.k8s.io"
+
+	// portForwardErrorTimeout is how long to wait for the error of a forwarded connection once
+	// its data stream ends.
+	portForwardErrorTimeout = time.Second
+)
+
+// PortForwarder opens connections to a port on the localhost of a pod, through the port-forward
+// subresource of the pod, like kubectl port-forward without listening locally.
+type PortForwarder struct {
+	connection httpstream.Connection
+	port       int
+	requestID  atomic.Int64
+}
+
+// NewPortForwarder upgrades a connection to the port-forward subresource of the pod. Close it once
+// done.
+func NewPortForwarder(config *rest.Config, kubeClient *kubeclient.Clientset, namespace, name string, port int) (*PortForwarder, error) {
+	transport, upgrader, err := spdy.RoundTripperFor(config)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create port-forward transport")
+	}
+
+	url := kubeClient.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(namespace).
+		Name(name).
+		SubResource("portforward").
+		URL()
+
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, url)
+	connection, protocol, err := dialer.Dial(portForwardProtocolV1Name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to port-forward to pod %v", name)
+	}
+	if protocol != portForwardProtocolV1Name {
+		_ = connection.Close()
+		return nil, errors.Errorf("unsupported port-forward protocol %q of pod %v", protocol, name)
+	}
+
+	return &PortForwarder{connection: connection, port: port}, nil
+}
+
+// DialContext opens a connection to the port of the pod. The network and address are ignored, so
+// it can be the dialer of an HTTP transport.
+func (forwarder *PortForwarder) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	requestID := strconv.FormatInt(forwarder.requestID.Add(1), 10)
+
+	headers := http.Header{}
+	headers.Set(corev1.StreamType, corev1.StreamTypeError)
+	headers.Set(corev1.PortHeader, strconv.Itoa(forwarder.port))
+	headers.Set(corev1.PortForwardRequestIDHeader, requestID)
+	errorStream, err := forwarder.connection.CreateStream(headers)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create port-forward error stream")
+	}
+	// The error stream is only read.
+	_ = errorStream.Close()
+
+	errCh := make(chan error, 1)
+	go func() {
+		defer close(errCh)
+
+		message, err := io.ReadAll(errorStream)
+		switch {
+		case err != nil:
+			errCh <- errors.Wrapf(err, "failed to read port-forward error stream of port %d", forwarder.port)
+		case len(message) > 0:
+			errCh <- errors.Errorf("failed to forward port %d: %s", forwarder.port, message)
+		}
+	}()
+
+	headers.Set(corev1.StreamType, corev1.StreamTypeData)
+	dataStream, err := forwarder.connection.CreateStream(headers)
+	if err != nil {
+		forwarder.connection.RemoveStreams(errorStream)
+		return nil, errors.Wrap(err, "failed to create port-forward data stream")
+	}
+
+	return &portForwardConn{
+		forwarder:   forwarder,
+		errorStream: errorStream,
+		dataStream:  dataStream,
+		errCh:       errCh,
+	}, nil
+}
+
+// Close closes the connection to the port-forward subresource, and all the connections opened
+// through it.
+func (forwarder *PortForwarder) Close() error {
+	return forwarder.connection.Close()
+}
+
+// portForwardConn is a connection to the port of the pod over a pair of port-forward streams.
+type portForwardConn struct {
+	forwarder   *PortForwarder
+	errorStream httpstream.Stream
+	dataStream  httpstream.Stream
+	errCh       chan error
+}
+
+// Read reads from the data stream. Once it ends, it returns the error of the forwarded
+// connection if any, for example when nothing listens on the port.
+func (conn *portForwardConn) Read(p []byte) (int, error) {
+	n, err := conn.dataStream.Read(p)
+	if err != io.EOF {
+		return n, err
+	}
+
+	select {
+	case forwardErr, ok := <-conn.errCh:
+		if ok && forwardErr != nil {
+			return n, forwardErr
+		}
+	case <-time.After(portForwardErrorTimeout):
+	}
+	return n, io.EOF
+}
+
+func (conn *portForwardConn) Write(p []byte) (int, error) {
+	return conn.dataStream.Write(p)
+}
+
+func (conn *portForwardConn) Close() error {
+	_ = conn.dataStream.Reset()
+	_ = conn.errorStream.Reset()
+	conn.forwarder.connection.RemoveStreams(conn.errorStream, conn.dataStream)
+	return nil
+}
+
+func (conn *portForwardConn) LocalAddr() net.Addr {
+	return portForwardAddr{}
+}
+
+func (conn *portForwardConn) RemoteAddr() net.Addr {
+	return portForwardAddr{port: conn.forwarder.port}
+}
+
+// The deadlines are not supported by the streams, the requests are bound by their context instead.
+func (conn *portForwardConn) SetDeadline(t time.Time) error      { return nil }
+func (conn *portForwardConn) SetReadDeadline(t time.Time) error  { return nil }
+func (conn *portForwardConn) SetWriteDeadline(t time.Time) error { return nil }
+
+type portForwardAddr struct {
+	port int
+}
+
+func (addr portForwardAddr) Network() string {
+	return "portforward"
+}
+
+func (addr portForwardAddr) String() string {
+	return net.JoinHostPort("localhost", strconv.Itoa(addr.port))
+}
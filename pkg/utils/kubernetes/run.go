@@ -0,0 +1,189 @@
+package kubernetes
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/utils/ptr"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeclient "k8s.io/client-go/kubernetes"
+
+	"github.com/longhorn/cli/pkg/consts"
+)
+
+// Run is a run of a longhornctl command creating resources in the cluster. Its resources are
+// labeled with the operation and the run ID, and the namespaced ones are owned by the anchor
+// ConfigMap of the run in their namespace. Deleting the anchor of an abandoned run deletes its
+// resources with it.
+type Run struct {
+	Operation string
+	ID        string
+
+	mutex      sync.Mutex
+	kubeClient *kubeclient.Clientset        // Client the anchors were created with.
+	anchors    map[string]*corev1.ConfigMap // Anchor of the run in each namespace it created resources in.
+}
+
+// currentRun is the run of the command, if it started one.
+var currentRun *Run
+
+// StartRun starts the run of the operation with a new ID. The resources prepared with
+// SetRunMetadata afterward belong to it.
+func StartRun(operation string) (*Run, error) {
+	id := make([]byte, 4)
+	if _, err := rand.Read(id); err != nil {
+		return nil, errors.Wrap(err, "failed to generate run ID")
+	}
+
+	currentRun = &Run{
+		Operation: operation,
+		ID:        hex.EncodeToString(id),
+		anchors:   map[string]*corev1.ConfigMap{},
+	}
+	return currentRun, nil
+}
+
+// GetCurrentRun returns the run of the command, or nil if it did not start one.
+func GetCurrentRun() *Run {
+	return currentRun
+}
+
+// SetRunMetadata labels the resource with the operation and the ID of the run, and makes the
+// anchor of the run in its namespace its owner, creating the anchor on the first resource of the
+// namespace. The pod template of a DaemonSet is labeled too. The resource is only labeled when it
+// is cluster-scoped, already has a controller, or the anchor cannot be created.
+func SetRunMetadata(kubeClient *kubeclient.Clientset, object metav1.Object) {
+	run := currentRun
+	if run == nil {
+		return
+	}
+
+	var anchor *corev1.ConfigMap
+	if object.GetNamespace() != "" && metav1.GetControllerOf(object) == nil {
+		var err error
+		anchor, err = run.getAnchor(kubeClient, object.GetNamespace())
+		if err != nil {
+			logrus.WithError(err).Warnf("Failed to create anchor of run %v, %v/%v is not owned by it", run.ID, object.GetNamespace(), object.GetName())
+		}
+	}
+
+	setRunMetadata(object, run, anchor)
+}
+
+// CompleteRun deletes the anchors of the run once the command completes, orphaning the resources
+// the command leaves in the cluster on purpose, such as the replica exporters. The anchors of a
+// failed or interrupted run are kept, so its resources are removed with them by
+// 'longhornctl cleanup all'.
+func CompleteRun() {
+	run := currentRun
+	if run == nil {
+		return
+	}
+
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	for namespace, anchor := range run.anchors {
+		err := run.kubeClient.CoreV1().ConfigMaps(namespace).Delete(context.Background(), anchor.Name, metav1.DeleteOptions{
+			PropagationPolicy: ptr.To(metav1.DeletePropagationOrphan),
+		})
+		if err != nil && !apierrors.IsNotFound(err) {
+			logrus.WithError(err).Warnf("Failed to delete anchor %v/%v of run %v", namespace, anchor.Name, run.ID)
+		}
+	}
+	run.anchors = map[string]*corev1.ConfigMap{}
+}
+
+// getAnchor returns the anchor of the run in the namespace, creating it if needed.
+func (run *Run) getAnchor(kubeClient *kubeclient.Clientset, namespace string) (*corev1.ConfigMap, error) {
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	if anchor, ok := run.anchors[namespace]; ok {
+		return anchor, nil
+	}
+
+	if _, err := CreateNamespace(kubeClient, namespace); err != nil {
+		return nil, err
+	}
+
+	newAnchor := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      consts.RunAnchorNamePrefix + run.ID,
+			Namespace: namespace,
+			Labels:    run.getLabels(),
+		},
+	}
+	newAnchor.Labels[consts.LabelManagedBy] = consts.LabelValueManagedBy
+
+	anchor, err := kubeClient.CoreV1().ConfigMaps(namespace).Create(context.Background(), newAnchor, metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	run.kubeClient = kubeClient
+	run.anchors[namespace] = anchor
+	return anchor, nil
+}
+
+// getLabels returns the labels identifying the run.
+func (run *Run) getLabels() map[string]string {
+	return map[string]string{
+		consts.LabelOperation: run.Operation,
+		consts.LabelRunID:     run.ID,
+	}
+}
+
+// SetRunLabels labels the resource with the operation and the ID of the run, without making the
+// anchor of the run its owner, for the resources outliving the run on purpose, such as its results.
+// The resource is not labeled when the command did not start a run.
+func SetRunLabels(object metav1.Object) {
+	run := currentRun
+	if run == nil {
+		return
+	}
+
+	object.SetLabels(addRunLabels(object.GetLabels(), run))
+}
+
+// setRunMetadata labels the resource with the run, and adds the anchor to its owners unless the
+// anchor is nil or already owns it.
+func setRunMetadata(object metav1.Object, run *Run, anchor *corev1.ConfigMap) {
+	object.SetLabels(addRunLabels(object.GetLabels(), run))
+	if daemonSet, ok := object.(*appsv1.DaemonSet); ok {
+		daemonSet.Spec.Template.Labels = addRunLabels(daemonSet.Spec.Template.Labels, run)
+	}
+
+	if anchor == nil {
+		return
+	}
+	for _, owner := range object.GetOwnerReferences() {
+		if owner.UID == anchor.UID {
+			return
+		}
+	}
+	object.SetOwnerReferences(append(object.GetOwnerReferences(), metav1.OwnerReference{
+		APIVersion: "v1",
+		Kind:       "ConfigMap",
+		Name:       anchor.Name,
+		UID:        anchor.UID,
+	}))
+}
+
+func addRunLabels(labels map[string]string, run *Run) map[string]string {
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	for key, value := range run.getLabels() {
+		labels[key] = value
+	}
+	return labels
+}
@@ -0,0 +1,49 @@
+package kubernetes
+
+import (
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/longhorn/cli/pkg/consts"
+)
+
+func TestSetRunMetadata(t *testing.T) {
+	run := &Run{Operation: "check-mounts", ID: "0a1b2c3d"}
+	anchor := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: consts.RunAnchorNamePrefix + run.ID, UID: "anchor-uid"}}
+
+	daemonSet := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "longhorn-mount-checker",
+			Namespace: "longhorn-system",
+			Labels:    map[string]string{"app": "longhorn-mount-checker"},
+		},
+	}
+	setRunMetadata(daemonSet, run, anchor)
+	setRunMetadata(daemonSet, run, anchor)
+
+	expectedLabels := map[string]string{
+		"app":                 "longhorn-mount-checker",
+		consts.LabelOperation: "check-mounts",
+		consts.LabelRunID:     "0a1b2c3d",
+	}
+	if !reflect.DeepEqual(daemonSet.Labels, expectedLabels) {
+		t.Fatalf("expected labels %v, got %v", expectedLabels, daemonSet.Labels)
+	}
+	if daemonSet.Spec.Template.Labels[consts.LabelRunID] != run.ID {
+		t.Fatalf("expected pod template to be labeled with run %v, got %v", run.ID, daemonSet.Spec.Template.Labels)
+	}
+	expectedOwners := []metav1.OwnerReference{{APIVersion: "v1", Kind: "ConfigMap", Name: anchor.Name, UID: anchor.UID}}
+	if !reflect.DeepEqual(daemonSet.OwnerReferences, expectedOwners) {
+		t.Fatalf("expected owners %v, got %v", expectedOwners, daemonSet.OwnerReferences)
+	}
+
+	persistentVolume := &corev1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{Name: "longhorn-backup-verifier"}}
+	setRunMetadata(persistentVolume, run, nil)
+	if persistentVolume.Labels[consts.LabelRunID] != run.ID || len(persistentVolume.OwnerReferences) != 0 {
+		t.Fatalf("expected cluster-scoped resource to be labeled only, got labels %v and owners %v", persistentVolume.Labels, persistentVolume.OwnerReferences)
+	}
+}
@@ -16,6 +16,7 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/wait"
@@ -59,15 +60,14 @@ func WaitForDaemonSetContainersExit(ctx context.Context, logger *logrus.Entry, k
 
 func waitForDaemonSetContainers(ctx context.Context, logger *logrus.Entry, kubeClient *kubeclient.Clientset, daemonSet *appsv1.DaemonSet, containerName string, conditionFunc func(pod *corev1.Pod) bool, maxConditionToleration *int) error {
 	isPodScheduled := false
+	podContainerStates := map[string]string{}
 
 	return wait.PollUntilContextCancel(ctx, time.Second, false, func(ctx context.Context) (bool, error) {
 		if maxConditionToleration != nil && *maxConditionToleration < 0 {
 			return true, errors.Errorf("exceeded maximum tolerated condition for DaemonSet container %s", containerName)
 		}
 
-		pods, err := kubeClient.CoreV1().Pods(daemonSet.Namespace).List(ctx, metav1.ListOptions{
-			LabelSelector: fields.SelectorFromSet(daemonSet.Spec.Selector.MatchLabels).String(),
-		})
+		pods, err := ListPods(kubeClient, daemonSet.Namespace, labels.SelectorFromSet(daemonSet.Spec.Selector.MatchLabels))
 		if err != nil {
 			logger.WithError(err).Trace("Failed to list pods")
 			return false, err
@@ -89,17 +89,29 @@ func waitForDaemonSetContainers(ctx context.Context, logger *logrus.Entry, kubeC
 			}
 
 			// Check if pod count is equal to DaemonSet pod count
-			if len(pods.Items) != int(daemonSet.Status.DesiredNumberScheduled) {
+			if len(pods) != int(daemonSet.Status.DesiredNumberScheduled) {
 				return false, nil
 			}
 
 			isPodScheduled = true
 		}
 
-		for _, pod := range pods.Items {
+		for _, pod := range pods {
 			logger.WithField("pod", pod.Name).Trace("Checking pod container condition")
 
-			if commonkube.IsPodContainerInState(&pod, containerName, commonkube.IsContainerWaitingCrashLoopBackOff) {
+			if state := getPodContainerState(pod, containerName); state != podContainerStates[pod.Name] {
+				logger.WithFields(logrus.Fields{
+					"pod":  pod.Name,
+					"node": pod.Spec.NodeName,
+				}).Debugf("Pod container transitioned to %s", state)
+				podContainerStates[pod.Name] = state
+			}
+
+			if err := GetPodSchedulingError(pod, time.Now()); err != nil {
+				return false, err
+			}
+
+			if commonkube.IsPodContainerInState(pod, containerName, commonkube.IsContainerWaitingCrashLoopBackOff) {
 				logger.Debug("Pod container is in crashloopbackoff")
 
 				*maxConditionToleration = -1
@@ -107,7 +119,7 @@ func waitForDaemonSetContainers(ctx context.Context, logger *logrus.Entry, kubeC
 				return false, errors.Errorf("pod container is in crash loop. View the logs using \"kubectl -n %s logs %s -c %s\"", pod.Namespace, pod.Name, containerName)
 			}
 
-			if !conditionFunc(&pod) {
+			if !conditionFunc(pod) {
 				logger.Tracef("Waiting for pod container condition to be met, tolerating %v", *maxConditionToleration)
 
 				if maxConditionToleration != nil {
@@ -122,6 +134,26 @@ func waitForDaemonSetContainers(ctx context.Context, logger *logrus.Entry, kubeC
 	})
 }
 
+// getPodContainerState returns a short description of the state of the pod container.
+func getPodContainerState(pod *corev1.Pod, containerName string) string {
+	statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, status := range statuses {
+		if status.Name != containerName {
+			continue
+		}
+
+		switch {
+		case status.State.Running != nil:
+			return "running"
+		case status.State.Terminated != nil:
+			return fmt.Sprintf("terminated (%s, exit code %d)", status.State.Terminated.Reason, status.State.Terminated.ExitCode)
+		case status.State.Waiting != nil:
+			return fmt.Sprintf("waiting (%s)", status.State.Waiting.Reason)
+		}
+	}
+	return fmt.Sprintf("pending (pod %s)", pod.Status.Phase)
+}
+
 // Workload provide functions for workloads.
 type Workload struct {
 	logger     *logrus.Entry
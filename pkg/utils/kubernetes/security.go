@@ -0,0 +1,58 @@
+package kubernetes
+
+import (
+	corev1 "k8s.io/api/core/v1"
+
+	"k8s.io/utils/ptr"
+)
+
+var (
+	// CapabilitiesHostNamespaces are the capabilities to enter the namespaces of
+	// the host process, used by the node agents to run commands on the host.
+	CapabilitiesHostNamespaces = []corev1.Capability{"SYS_ADMIN", "SYS_CHROOT", "SYS_PTRACE"}
+
+	// CapabilitiesHostRead are the capabilities to read the files and processes of the host.
+	CapabilitiesHostRead = []corev1.Capability{"DAC_READ_SEARCH", "SYS_PTRACE"}
+
+	// CapabilitiesHostWrite is the capability to write the files of the host regardless of their permissions.
+	CapabilitiesHostWrite = []corev1.Capability{"DAC_OVERRIDE"}
+
+	// CapabilitiesKernelModule is the capability to load kernel modules.
+	CapabilitiesKernelModule = []corev1.Capability{"SYS_MODULE"}
+
+	// CapabilitiesNetworkAdmin are the capabilities to change the firewall rules of the host network.
+	CapabilitiesNetworkAdmin = []corev1.Capability{"NET_ADMIN", "NET_RAW"}
+)
+
+// NewSecurityContext returns the security context of a node agent container.
+//
+// When privileged is false, the container runs unprivileged with all capabilities
+// dropped except the given ones, instead of being a blanket privileged container.
+func NewSecurityContext(privileged bool, capabilities ...[]corev1.Capability) *corev1.SecurityContext {
+	if privileged {
+		return &corev1.SecurityContext{
+			Privileged: ptr.To(true),
+		}
+	}
+
+	add := []corev1.Capability{}
+	seen := map[corev1.Capability]bool{}
+	for _, capabilityList := range capabilities {
+		for _, capability := range capabilityList {
+			if seen[capability] {
+				continue
+			}
+			seen[capability] = true
+			add = append(add, capability)
+		}
+	}
+
+	return &corev1.SecurityContext{
+		Privileged:               ptr.To(false),
+		AllowPrivilegeEscalation: ptr.To(false),
+		Capabilities: &corev1.Capabilities{
+			Drop: []corev1.Capability{"ALL"},
+			Add:  add,
+		},
+	}
+}
@@ -10,8 +10,6 @@ import (
 	"regexp"
 	"strings"
 
-	"github.com/longhorn/cli/pkg/consts"
-
 	pkgmgr "github.com/longhorn/cli/pkg/local/preflight/packagemanager"
 )
 
@@ -32,7 +30,8 @@ func GetPackageManagerType(osRelease string) (pkgmgr.PackageManagerType, error)
 	}
 }
 
-func GetOSRelease() (string, error) {
+// GetOSRelease returns the platform of the operating system in the host directory.
+func GetOSRelease(hostDirectory string) (string, error) {
 	// List of possible locations for the os-release file.
 	possiblePaths := []string{
 		filepath.Join("/etc/os-release"),
@@ -43,7 +42,7 @@ func GetOSRelease() (string, error) {
 	var lines []string
 	var err error
 	for _, path := range possiblePaths {
-		hostPath := filepath.Join(consts.VolumeMountHostDirectory, path)
+		hostPath := filepath.Join(hostDirectory, path)
 		if _, err = os.Stat(hostPath); err == nil {
 			lines, err = readFileLines(hostPath)
 			break
@@ -0,0 +1,83 @@
+package output
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+// ConfigMapDataKeyPrefix is the prefix of the key of the result in the ConfigMap, followed by the format.
+const ConfigMapDataKeyPrefix = "result."
+
+// configMapSink stores the result in a ConfigMap, created when it does not exist.
+type configMapSink struct {
+	namespace      string
+	name           string
+	key            string
+	kubeConfigPath string
+}
+
+func newConfigMapSink(destinationURL *url.URL, format string, globalOpts *types.GlobalCmdOptions) (*configMapSink, error) {
+	namespace, name := globalOpts.Namespace, destinationURL.Host
+	if path := strings.Trim(destinationURL.Path, "/"); path != "" {
+		namespace, name = destinationURL.Host, path
+	}
+	if namespace == "" {
+		namespace = consts.LonghornNamespace
+	}
+	if name == "" || strings.Contains(name, "/") {
+		return nil, errors.Errorf("expected %s://[<namespace>/]<name>", schemeConfigMap)
+	}
+
+	return &configMapSink{
+		namespace:      namespace,
+		name:           name,
+		key:            ConfigMapDataKeyPrefix + format,
+		kubeConfigPath: globalOpts.KubeConfigPath,
+	}, nil
+}
+
+func (sink *configMapSink) Write(data []byte) error {
+	kubeClient, err := kubeutils.NewKubeClient("", sink.kubeConfigPath)
+	if err != nil {
+		return err
+	}
+
+	_, err = kubeutils.CreateOrUpdateConfigMap(kubeClient, sink.newConfigMap(data))
+	return err
+}
+
+// newConfigMap returns the ConfigMap holding the result. It is labeled as a record of longhornctl
+// and with the current run, so it can be found, and is only removed by 'longhornctl cleanup all'
+// with --include-state.
+func (sink *configMapSink) newConfigMap(data []byte) *corev1.ConfigMap {
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      sink.name,
+			Namespace: sink.namespace,
+			Labels: map[string]string{
+				consts.LabelManagedBy: consts.LabelValueManagedBy,
+				consts.LabelState:     consts.LabelValueState,
+			},
+		},
+		Data: map[string]string{
+			sink.key: string(data),
+		},
+	}
+	kubeutils.SetRunLabels(configMap)
+	return configMap
+}
+
+func (sink *configMapSink) String() string {
+	return fmt.Sprintf("%s://%s/%s", schemeConfigMap, sink.namespace, sink.name)
+}
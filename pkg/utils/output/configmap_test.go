@@ -0,0 +1,36 @@
+package output
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/longhorn/cli/pkg/consts"
+
+	kubeutils "github.com/longhorn/cli/pkg/utils/kubernetes"
+)
+
+func TestConfigMapSinkNewConfigMap(t *testing.T) {
+	run, err := kubeutils.StartRun("check-preflight")
+	if err != nil {
+		t.Fatalf("failed to start run: %v", err)
+	}
+
+	sink := &configMapSink{namespace: "longhorn-system", name: "longhornctl-async-5f2a9c1e-result", key: ConfigMapDataKeyPrefix + consts.OutputFormatJSON}
+	configMap := sink.newConfigMap([]byte(`{}`))
+
+	expectedLabels := map[string]string{
+		consts.LabelManagedBy: consts.LabelValueManagedBy,
+		consts.LabelState:     consts.LabelValueState,
+		consts.LabelOperation: "check-preflight",
+		consts.LabelRunID:     run.ID,
+	}
+	if !reflect.DeepEqual(configMap.Labels, expectedLabels) {
+		t.Errorf("expected labels %v, got %v", expectedLabels, configMap.Labels)
+	}
+	if len(configMap.OwnerReferences) != 0 {
+		t.Errorf("expected the result not to be owned by the run anchor, got %v", configMap.OwnerReferences)
+	}
+	if configMap.Namespace != "longhorn-system" || configMap.Data["result.json"] != `{}` {
+		t.Errorf("unexpected ConfigMap %v", configMap)
+	}
+}
@@ -0,0 +1,99 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/longhorn/cli/pkg/consts"
+
+	s3utils "github.com/longhorn/cli/pkg/utils/s3"
+)
+
+const (
+	s3DefaultRegion = "us-east-1"
+	s3Timeout       = time.Minute
+)
+
+// s3Sink uploads the result to an object of an S3 or S3-compatible bucket. The credentials, region
+// and endpoint are read from the standard AWS environment variables.
+type s3Sink struct {
+	bucket string
+	key    string
+}
+
+func newS3Sink(destinationURL *url.URL) (*s3Sink, error) {
+	bucket := destinationURL.Host
+	key := strings.TrimPrefix(destinationURL.Path, "/")
+	if bucket == "" || key == "" {
+		return nil, errors.Errorf("expected %s://<bucket>/<key>", schemeS3)
+	}
+
+	if os.Getenv(consts.EnvAwsAccessKeyID) == "" || os.Getenv(consts.EnvAwsSecretAccessKey) == "" {
+		return nil, errors.Errorf("%s and %s are required", consts.EnvAwsAccessKeyID, consts.EnvAwsSecretAccessKey)
+	}
+
+	return &s3Sink{bucket: bucket, key: key}, nil
+}
+
+func (sink *s3Sink) Write(data []byte) error {
+	region := getS3Region()
+	objectURL := getS3ObjectURL(sink.bucket, sink.key, region)
+
+	req, err := http.NewRequest(http.MethodPut, objectURL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = int64(len(data))
+
+	credentials := s3utils.Credentials{
+		AccessKeyID:     os.Getenv(consts.EnvAwsAccessKeyID),
+		SecretAccessKey: os.Getenv(consts.EnvAwsSecretAccessKey),
+		SessionToken:    os.Getenv(consts.EnvAwsSessionToken),
+	}
+	s3utils.SignRequest(req, data, credentials, region, time.Now())
+
+	httpClient := &http.Client{Timeout: s3Timeout}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errors.Errorf("upload returned %v: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+func (sink *s3Sink) String() string {
+	return fmt.Sprintf("%s://%s/%s", schemeS3, sink.bucket, sink.key)
+}
+
+func getS3Region() string {
+	for _, env := range []string{consts.EnvAwsRegion, consts.EnvAwsDefaultRegion} {
+		if region := os.Getenv(env); region != "" {
+			return region
+		}
+	}
+	return s3DefaultRegion
+}
+
+// getS3ObjectURL returns the URL of the object. With a custom endpoint, such as the one of an
+// S3-compatible storage, the bucket is in the path. Otherwise, it is in the host of the AWS endpoint.
+func getS3ObjectURL(bucket, key, region string) string {
+	for _, env := range []string{consts.EnvAwsEndpointURLS3, consts.EnvAwsEndpointURL} {
+		if endpoint := os.Getenv(env); endpoint != "" {
+			return fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(endpoint, "/"), bucket, s3utils.EscapeKey(key))
+		}
+	}
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", bucket, region, s3utils.EscapeKey(key))
+}
@@ -0,0 +1,26 @@
+package output
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetS3ObjectURL(t *testing.T) {
+	t.Setenv("AWS_ENDPOINT_URL_S3", "")
+	t.Setenv("AWS_ENDPOINT_URL", "")
+	if objectURL := getS3ObjectURL("bucket", "runs/a b.json", "eu-west-1"); objectURL != "https://bucket.s3.eu-west-1.amazonaws.com/runs/a%20b.json" {
+		t.Errorf("unexpected URL %v", objectURL)
+	}
+
+	t.Setenv("AWS_ENDPOINT_URL", "http://minio.example.com:9000/")
+	if objectURL := getS3ObjectURL("bucket", "runs/result+1.json", "us-east-1"); objectURL != "http://minio.example.com:9000/bucket/runs/result%2B1.json" {
+		t.Errorf("unexpected URL %v", objectURL)
+	}
+}
+
+func TestNewS3Sink(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	if _, err := ParseTargets("s3://bucket/key", nil); err == nil || !strings.Contains(err.Error(), "AWS_ACCESS_KEY_ID") {
+		t.Errorf("expected missing credentials error, got %v", err)
+	}
+}
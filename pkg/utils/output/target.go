@@ -0,0 +1,180 @@
+package output
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils"
+)
+
+const (
+	schemeFile      = "file"
+	schemeS3        = "s3"
+	schemeConfigMap = "configmap"
+
+	destinationStdout = "stdout"
+)
+
+// Sink is a destination the structured results are written to.
+type Sink interface {
+	Write(data []byte) error
+	String() string
+}
+
+// Target is a sink with the format of the result written to it.
+type Target struct {
+	Format string // Format of the result, json or yaml. Empty for the default output of the command on stdout.
+	Sink   Sink
+}
+
+// SetOutputTargets parses the --output-to option and registers the targets as the writer of the
+// structured results.
+func SetOutputTargets(globalOpts *types.GlobalCmdOptions) error {
+	if strings.TrimSpace(globalOpts.OutputTo) == "" {
+		return nil
+	}
+
+	targets, err := ParseTargets(globalOpts.OutputTo, globalOpts)
+	if err != nil {
+		return err
+	}
+
+	utils.RegisterResultWriter(func(kind string, result any) (bool, error) {
+		return WriteTargets(targets, kind, result)
+	})
+	return nil
+}
+
+// ParseTargets parses the comma-separated targets, each a destination optionally prefixed with
+// its format, for example "stdout,json=file:///tmp/result.json,yaml=s3://bucket/key".
+func ParseTargets(outputTo string, globalOpts *types.GlobalCmdOptions) ([]Target, error) {
+	var targets []Target
+	for _, spec := range strings.Split(outputTo, consts.CmdOptSeperator) {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+
+		target, err := parseTarget(spec, globalOpts)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid destination %q (--%s)", spec, consts.CmdOptOutputTo)
+		}
+		targets = append(targets, target)
+	}
+
+	if len(targets) == 0 {
+		return nil, errors.Errorf("no destination in --%s", consts.CmdOptOutputTo)
+	}
+	return targets, nil
+}
+
+func parseTarget(spec string, globalOpts *types.GlobalCmdOptions) (Target, error) {
+	format, destination := "", spec
+	if prefix, rest, found := strings.Cut(spec, "="); found && !strings.Contains(prefix, "://") {
+		format, destination = prefix, rest
+		if format != consts.OutputFormatJSON && format != consts.OutputFormatYAML {
+			return Target{}, errors.Errorf("unsupported format %q, supported formats: %s, %s", format, consts.OutputFormatJSON, consts.OutputFormatYAML)
+		}
+	}
+
+	if destination == destinationStdout {
+		return Target{Format: format, Sink: &stdoutSink{}}, nil
+	}
+
+	if format == "" {
+		format = consts.OutputFormatJSON
+	}
+
+	destinationURL, err := url.Parse(destination)
+	if err != nil {
+		return Target{}, err
+	}
+
+	switch destinationURL.Scheme {
+	case schemeFile:
+		sink, err := newFileSink(destinationURL)
+		return Target{Format: format, Sink: sink}, err
+
+	case schemeS3:
+		sink, err := newS3Sink(destinationURL)
+		return Target{Format: format, Sink: sink}, err
+
+	case schemeConfigMap:
+		sink, err := newConfigMapSink(destinationURL, format, globalOpts)
+		return Target{Format: format, Sink: sink}, err
+
+	default:
+		return Target{}, errors.Errorf("unsupported destination, expected %s, %s://, %s:// or %s://", destinationStdout, schemeFile, schemeS3, schemeConfigMap)
+	}
+}
+
+// WriteTargets writes the result of the kind to the targets. It returns false when the result
+// must still be printed to stdout as usual, because a target is stdout without a format.
+func WriteTargets(targets []Target, kind string, result any) (bool, error) {
+	handled := true
+	for _, target := range targets {
+		if target.Format == "" {
+			handled = false
+			continue
+		}
+
+		data, err := utils.MarshalResult(target.Format, kind, result)
+		if err != nil {
+			return true, err
+		}
+
+		if err := target.Sink.Write(data); err != nil {
+			return true, errors.Wrapf(err, "failed to write the result to %v", target.Sink)
+		}
+		logrus.Debugf("Wrote the result to %v", target.Sink)
+	}
+	return handled, nil
+}
+
+// stdoutSink prints the result to stdout.
+type stdoutSink struct{}
+
+func (sink *stdoutSink) Write(data []byte) error {
+	_, err := os.Stdout.Write(data)
+	return err
+}
+
+func (sink *stdoutSink) String() string {
+	return destinationStdout
+}
+
+// fileSink writes the result to a local file, creating its directory.
+type fileSink struct {
+	path string
+}
+
+func newFileSink(destinationURL *url.URL) (*fileSink, error) {
+	path := destinationURL.Path
+	if destinationURL.Host != "" {
+		// Relative paths, such as file://results/result.json.
+		path = destinationURL.Host + path
+	}
+	if path == "" {
+		return nil, errors.New("missing file path")
+	}
+	return &fileSink{path: path}, nil
+}
+
+func (sink *fileSink) Write(data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(sink.path), 0755); err != nil {
+		return errors.Wrap(err, "failed to create directory")
+	}
+	return os.WriteFile(sink.path, data, 0644)
+}
+
+func (sink *fileSink) String() string {
+	return fmt.Sprintf("%s://%s", schemeFile, sink.path)
+}
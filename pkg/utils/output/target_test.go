@@ -0,0 +1,79 @@
+package output
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func TestParseTargets(t *testing.T) {
+	globalOpts := &types.GlobalCmdOptions{Namespace: "longhorn-system"}
+	t.Setenv("AWS_ACCESS_KEY_ID", "key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+
+	targets, err := ParseTargets("stdout, yaml=stdout,json=file:///tmp/r.json,s3://bucket/runs/r.json,yaml=configmap://results,configmap://ns/results", globalOpts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []struct {
+		format string
+		sink   string
+	}{
+		{"", "stdout"},
+		{"yaml", "stdout"},
+		{"json", "file:///tmp/r.json"},
+		{"json", "s3://bucket/runs/r.json"},
+		{"yaml", "configmap://longhorn-system/results"},
+		{"json", "configmap://ns/results"},
+	}
+	if len(targets) != len(expected) {
+		t.Fatalf("expected %d targets, got %d", len(expected), len(targets))
+	}
+	for i, target := range targets {
+		if target.Format != expected[i].format || target.Sink.String() != expected[i].sink {
+			t.Errorf("target %d: expected %v=%v, got %v=%v", i, expected[i].format, expected[i].sink, target.Format, target.Sink)
+		}
+	}
+
+	for _, outputTo := range []string{"", "xml=stdout", "http://example.com/r.json", "file://", "s3://bucket", "configmap://ns/a/b"} {
+		if _, err := ParseTargets(outputTo, globalOpts); err == nil {
+			t.Errorf("expected error for %q", outputTo)
+		}
+	}
+}
+
+func TestWriteTargets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results", "result.yaml")
+	targets, err := ParseTargets("yaml=file://"+path, &types.GlobalCmdOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	handled, err := WriteTargets(targets, types.ResultKindLogCollections, map[string]*types.LogCollection{"node-1": {Info: []string{"ok"}}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !handled {
+		t.Errorf("expected the result to be handled without stdout target")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(string(data), "schemaVersion: "+types.ResultSchemaVersion) || !strings.Contains(string(data), "node-1") {
+		t.Errorf("unexpected result file content:\n%s", data)
+	}
+
+	targets, err = ParseTargets("stdout", &types.GlobalCmdOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if handled, _ := WriteTargets(targets, types.ResultKindLogCollections, nil); handled {
+		t.Errorf("expected the result to be printed as usual with the stdout target")
+	}
+}
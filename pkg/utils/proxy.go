@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+)
+
+// SetProxyEnv overrides the proxy environment variables of the CLI with the proxy options, so the
+// Kubernetes client and the other HTTP clients of the CLI use them. When running in a pod, the
+// Kubernetes service host is excluded from the proxy so the in-cluster client keeps working.
+func SetProxyEnv(globalOpts *types.GlobalCmdOptions) error {
+	if globalOpts.Proxy != "" {
+		if err := ValidateProxyURL(globalOpts.Proxy); err != nil {
+			return err
+		}
+
+		setEnvAllCases(consts.EnvHttpProxy, globalOpts.Proxy)
+		setEnvAllCases(consts.EnvHttpsProxy, globalOpts.Proxy)
+	}
+
+	if globalOpts.NoProxy != "" {
+		setEnvAllCases(consts.EnvNoProxy, globalOpts.NoProxy)
+	}
+
+	if globalOpts.Proxy != "" {
+		if serviceHost := os.Getenv(consts.EnvKubernetesServiceHost); serviceHost != "" {
+			setEnvAllCases(consts.EnvNoProxy, JoinNoProxy(os.Getenv(consts.EnvNoProxy), serviceHost))
+		}
+	}
+
+	return nil
+}
+
+// ValidateProxyURL returns an error if the proxy is not an HTTP(S) or SOCKS5 URL, or if it holds
+// credentials, as the proxy is passed in plain text to the pods and recorded in their specs.
+// The errors only hold the proxy with its password redacted.
+func ValidateProxyURL(proxy string) error {
+	proxyURL, err := url.Parse(proxy)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return errors.Wrapf(err, "invalid --%s", consts.CmdOptProxy)
+	}
+
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return errors.Errorf("invalid --%s %q, expected an http, https or socks5 URL", consts.CmdOptProxy, proxyURL.Redacted())
+	}
+
+	if proxyURL.Host == "" {
+		return errors.Errorf("invalid --%s %q, no host", consts.CmdOptProxy, proxyURL.Redacted())
+	}
+
+	if proxyURL.User != nil {
+		return errors.Errorf("invalid --%s %q, credentials in the proxy URL are not supported", consts.CmdOptProxy, proxyURL.Redacted())
+	}
+	return nil
+}
+
+// JoinNoProxy appends the hosts to the comma-separated no proxy list, skipping the ones already in it.
+func JoinNoProxy(noProxy string, hosts ...string) string {
+	entries := []string{}
+	seen := map[string]bool{}
+	for _, entry := range append(strings.Split(noProxy, consts.CmdOptSeperator), hosts...) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" || seen[entry] {
+			continue
+		}
+
+		seen[entry] = true
+		entries = append(entries, entry)
+	}
+	return strings.Join(entries, consts.CmdOptSeperator)
+}
+
+// setEnvAllCases sets the upper and lower case environment variable, as tools differ in the one they read.
+func setEnvAllCases(name, value string) {
+	_ = os.Setenv(name, value)
+	_ = os.Setenv(strings.ToLower(name), value)
+}
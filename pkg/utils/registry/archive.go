@@ -0,0 +1,157 @@
+package registry
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// descriptor is a content descriptor of an image index or manifest.
+type descriptor struct {
+	MediaType string    `json:"mediaType"`
+	Digest    string    `json:"digest"`
+	Size      int64     `json:"size"`
+	Platform  *platform `json:"platform,omitempty"`
+}
+
+// imageManifest is an image index or manifest with its content descriptors.
+type imageManifest struct {
+	MediaType string       `json:"mediaType"`
+	Config    descriptor   `json:"config"`
+	Layers    []descriptor `json:"layers"`
+	Manifests []descriptor `json:"manifests"`
+}
+
+// archiveManifest is an entry of the manifest.json of a docker-archive tarball.
+type archiveManifest struct {
+	Config   string   `json:"Config"`
+	RepoTags []string `json:"RepoTags"`
+	Layers   []string `json:"Layers"`
+}
+
+// SaveImage writes the image of the platform, such as linux/amd64, as a docker-archive tarball,
+// the format of docker save. The tarball can be loaded with docker load, ctr images import, or
+// from the image directory of the air-gap install of K3s and RKE2.
+func SaveImage(httpClient *http.Client, endpoint string, ref ImageReference, image, imagePlatform string, w io.Writer) error {
+	manifest, err := getImageManifest(httpClient, endpoint, ref, imagePlatform)
+	if err != nil {
+		return err
+	}
+
+	tarWriter := tar.NewWriter(w)
+	blobs := append([]descriptor{manifest.Config}, manifest.Layers...)
+	entry := archiveManifest{Config: blobPath(manifest.Config.Digest)}
+	if !strings.Contains(image, "@") {
+		entry.RepoTags = []string{image}
+	}
+	for _, blob := range blobs {
+		if err := writeBlob(httpClient, endpoint, ref, blob, tarWriter); err != nil {
+			return err
+		}
+		if blob.Digest != manifest.Config.Digest {
+			entry.Layers = append(entry.Layers, blobPath(blob.Digest))
+		}
+	}
+
+	data, err := json.Marshal([]archiveManifest{entry})
+	if err != nil {
+		return errors.Wrap(err, "failed to convert the archive manifest to JSON")
+	}
+	if err := writeFile(tarWriter, "manifest.json", data); err != nil {
+		return err
+	}
+	return tarWriter.Close()
+}
+
+// getImageManifest returns the manifest of the image, resolving the index to the manifest of
+// the platform.
+func getImageManifest(httpClient *http.Client, endpoint string, ref ImageReference, imagePlatform string) (*imageManifest, error) {
+	manifest := &imageManifest{}
+	if err := getJSON(httpClient, manifestURL(endpoint, ref), manifestMediaTypes, manifest); err != nil {
+		return nil, errors.Wrap(err, "failed to get the image manifest")
+	}
+	if len(manifest.Manifests) == 0 {
+		if manifest.Config.Digest == "" {
+			return nil, errors.Errorf("unsupported manifest type %q", manifest.MediaType)
+		}
+		return manifest, nil
+	}
+
+	var platforms []string
+	for _, entry := range manifest.Manifests {
+		if entry.Platform == nil || entry.Platform.OS == unknownPlatform {
+			continue
+		}
+		if entry.Platform.String() != imagePlatform {
+			platforms = append(platforms, entry.Platform.String())
+			continue
+		}
+
+		platformRef := ref
+		platformRef.Reference = entry.Digest
+		return getImageManifest(httpClient, endpoint, platformRef, imagePlatform)
+	}
+	return nil, errors.Errorf("image has no %v manifest, available platforms: %v", imagePlatform, strings.Join(platforms, ", "))
+}
+
+// writeBlob downloads the blob to the tarball, verifying its digest.
+func writeBlob(httpClient *http.Client, endpoint string, ref ImageReference, blob descriptor, tarWriter *tar.Writer) error {
+	algorithm, expected, ok := strings.Cut(blob.Digest, ":")
+	if !ok || algorithm != "sha256" {
+		return errors.Errorf("unsupported digest %q", blob.Digest)
+	}
+
+	url := fmt.Sprintf("%s/v2/%s/blobs/%s", endpoint, ref.Repository, blob.Digest)
+	resp, err := request(httpClient, http.MethodGet, url, nil)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get blob %v", blob.Digest)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("blob %v request returned %v", blob.Digest, resp.Status)
+	}
+
+	if err := tarWriter.WriteHeader(&tar.Header{
+		Name:    blobPath(blob.Digest),
+		Mode:    0644,
+		Size:    blob.Size,
+		ModTime: time.Unix(0, 0),
+	}); err != nil {
+		return err
+	}
+
+	hash := sha256.New()
+	if _, err := io.CopyN(io.MultiWriter(tarWriter, hash), resp.Body, blob.Size); err != nil {
+		return errors.Wrapf(err, "failed to download blob %v", blob.Digest)
+	}
+	if actual := hex.EncodeToString(hash.Sum(nil)); actual != expected {
+		return errors.Errorf("blob %v has digest sha256:%v", blob.Digest, actual)
+	}
+	return nil
+}
+
+func writeFile(tarWriter *tar.Writer, name string, data []byte) error {
+	if err := tarWriter.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: time.Unix(0, 0),
+	}); err != nil {
+		return err
+	}
+	_, err := tarWriter.Write(data)
+	return err
+}
+
+func blobPath(digest string) string {
+	return "blobs/" + strings.Replace(digest, ":", "/", 1)
+}
@@ -0,0 +1,264 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	DockerHubRegistry = "docker.io"
+	DockerHubEndpoint = "https://registry-1.docker.io"
+)
+
+const (
+	mediaTypeOCIIndex           = "application/vnd.oci.image.index.v1+json"
+	mediaTypeOCIManifest        = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeDockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	mediaTypeDockerManifest     = "application/vnd.docker.distribution.manifest.v2+json"
+
+	// unknownPlatform is the platform of the attestation manifests BuildKit adds to the indexes.
+	unknownPlatform = "unknown"
+)
+
+// manifestMediaTypes are the manifest types accepted when requesting the images.
+var manifestMediaTypes = []string{
+	mediaTypeOCIIndex,
+	mediaTypeOCIManifest,
+	mediaTypeDockerManifestList,
+	mediaTypeDockerManifest,
+}
+
+var bearerParamRegexp = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// ImageReference is an image split into the parts used by the registry API.
+type ImageReference struct {
+	Registry   string
+	Repository string
+	Reference  string // Tag or digest.
+}
+
+// manifest is the part of an image manifest or index listing its platforms.
+type manifest struct {
+	MediaType string `json:"mediaType"`
+	Config    struct {
+		Digest string `json:"digest"`
+	} `json:"config"`
+	Manifests []struct {
+		Platform *platform `json:"platform"`
+	} `json:"manifests"`
+}
+
+type platform struct {
+	OS           string `json:"os"`
+	Architecture string `json:"architecture"`
+	Variant      string `json:"variant,omitempty"`
+}
+
+// ParseImageReference splits the image into its registry, repository and tag or digest,
+// with the defaults of Docker Hub.
+func ParseImageReference(image string) ImageReference {
+	name, reference := image, "latest"
+	if i := strings.Index(image, "@"); i >= 0 {
+		name, reference = image[:i], image[i+1:]
+	} else if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		name, reference = image[:i], image[i+1:]
+	}
+
+	registry, repository := DockerHubRegistry, name
+	if i := strings.Index(name, "/"); i >= 0 {
+		host := name[:i]
+		if strings.ContainsAny(host, ".:") || host == "localhost" {
+			registry, repository = host, name[i+1:]
+		}
+	}
+
+	if registry == DockerHubRegistry && !strings.Contains(repository, "/") {
+		repository = "library/" + repository
+	}
+
+	return ImageReference{
+		Registry:   registry,
+		Repository: repository,
+		Reference:  reference,
+	}
+}
+
+// GetUpstreamEndpoint returns the endpoint of the registry API of the registry.
+func GetUpstreamEndpoint(registry string) string {
+	if registry == DockerHubRegistry {
+		return DockerHubEndpoint
+	}
+	return "https://" + registry
+}
+
+// CheckManifest requests the image manifest from the registry endpoint, with an anonymous
+// token when the registry asks for one.
+func CheckManifest(httpClient *http.Client, endpoint string, ref ImageReference) error {
+	resp, err := request(httpClient, http.MethodHead, manifestURL(endpoint, ref), manifestMediaTypes)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("manifest request returned %v", resp.Status)
+	}
+	return nil
+}
+
+// GetImagePlatforms returns the sorted platforms of the image, such as linux/amd64 or
+// linux/arm/v7, from its index, or from the configuration of a single-platform image.
+func GetImagePlatforms(httpClient *http.Client, endpoint string, ref ImageReference) ([]string, error) {
+	var imageManifest manifest
+	if err := getJSON(httpClient, manifestURL(endpoint, ref), manifestMediaTypes, &imageManifest); err != nil {
+		return nil, errors.Wrap(err, "failed to get the image manifest")
+	}
+
+	platforms := []string{}
+	switch imageManifest.MediaType {
+	case mediaTypeOCIIndex, mediaTypeDockerManifestList:
+		for _, entry := range imageManifest.Manifests {
+			if entry.Platform == nil || entry.Platform.OS == unknownPlatform {
+				continue
+			}
+			platforms = append(platforms, entry.Platform.String())
+		}
+
+	default:
+		if imageManifest.Config.Digest == "" {
+			return nil, errors.Errorf("unsupported manifest type %q", imageManifest.MediaType)
+		}
+
+		var config platform
+		url := fmt.Sprintf("%s/v2/%s/blobs/%s", endpoint, ref.Repository, imageManifest.Config.Digest)
+		if err := getJSON(httpClient, url, nil, &config); err != nil {
+			return nil, errors.Wrap(err, "failed to get the image configuration")
+		}
+		platforms = append(platforms, config.String())
+	}
+
+	sort.Strings(platforms)
+	return platforms, nil
+}
+
+func (p *platform) String() string {
+	if p.Variant == "" {
+		return p.OS + "/" + p.Architecture
+	}
+	return p.OS + "/" + p.Architecture + "/" + p.Variant
+}
+
+func manifestURL(endpoint string, ref ImageReference) string {
+	return fmt.Sprintf("%s/v2/%s/manifests/%s", endpoint, ref.Repository, ref.Reference)
+}
+
+func getJSON(httpClient *http.Client, url string, accept []string, value any) error {
+	resp, err := request(httpClient, http.MethodGet, url, accept)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("request returned %v", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(value)
+}
+
+// request sends the request, retrying it with an anonymous token when the registry asks for one.
+func request(httpClient *http.Client, method, url string, accept []string) (*http.Response, error) {
+	resp, err := send(httpClient, method, url, accept, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusUnauthorized {
+		return resp, nil
+	}
+	_ = resp.Body.Close()
+
+	token, err := getAnonymousToken(httpClient, resp.Header.Get("WWW-Authenticate"))
+	if err != nil {
+		return nil, err
+	}
+	return send(httpClient, method, url, accept, token)
+}
+
+func send(httpClient *http.Client, method, url string, accept []string, token string) (*http.Response, error) {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(accept) > 0 {
+		req.Header.Set("Accept", strings.Join(accept, ", "))
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return httpClient.Do(req)
+}
+
+// getAnonymousToken requests a token from the realm of the bearer challenge.
+func getAnonymousToken(httpClient *http.Client, challenge string) (string, error) {
+	params := parseBearerChallenge(challenge)
+	if params["realm"] == "" {
+		return "", errors.Errorf("registry requires unsupported authentication %q", challenge)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, params["realm"], nil)
+	if err != nil {
+		return "", err
+	}
+
+	query := req.URL.Query()
+	for _, key := range []string{"service", "scope"} {
+		if params[key] != "" {
+			query.Set(key, params[key])
+		}
+	}
+	req.URL.RawQuery = query.Encode()
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get registry token")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.Errorf("registry token request returned %v", resp.Status)
+	}
+
+	var tokenResponse struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResponse); err != nil {
+		return "", errors.Wrap(err, "failed to decode registry token")
+	}
+
+	if tokenResponse.Token != "" {
+		return tokenResponse.Token, nil
+	}
+	return tokenResponse.AccessToken, nil
+}
+
+// parseBearerChallenge returns the parameters of a "Bearer" WWW-Authenticate header.
+func parseBearerChallenge(challenge string) map[string]string {
+	params := map[string]string{}
+	if !strings.HasPrefix(strings.ToLower(challenge), "bearer ") {
+		return params
+	}
+
+	for _, match := range bearerParamRegexp.FindAllStringSubmatch(challenge, -1) {
+		params[strings.ToLower(match[1])] = match[2]
+	}
+	return params
+}
@@ -0,0 +1,161 @@
+package registry
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestParseImageReference(t *testing.T) {
+	tests := map[string]ImageReference{
+		"nginx":                              {Registry: "docker.io", Repository: "library/nginx", Reference: "latest"},
+		"longhornio/longhorn-manager:v1.7.2": {Registry: "docker.io", Repository: "longhornio/longhorn-manager", Reference: "v1.7.2"},
+		"registry.example.com:5000/longhorn/ui:v1": {Registry: "registry.example.com:5000", Repository: "longhorn/ui", Reference: "v1"},
+		"localhost/app@sha256:abc":                 {Registry: "localhost", Repository: "app", Reference: "sha256:abc"},
+	}
+
+	for image, expected := range tests {
+		if ref := ParseImageReference(image); ref != expected {
+			t.Errorf("ParseImageReference(%q) = %+v, expected %+v", image, ref, expected)
+		}
+	}
+}
+
+func TestParseBearerChallenge(t *testing.T) {
+	params := parseBearerChallenge(`Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/nginx:pull"`)
+	expected := map[string]string{
+		"realm":   "https://auth.docker.io/token",
+		"service": "registry.docker.io",
+		"scope":   "repository:library/nginx:pull",
+	}
+	if !reflect.DeepEqual(params, expected) {
+		t.Errorf("expected %v, got %v", expected, params)
+	}
+
+	if params := parseBearerChallenge(`Basic realm="registry"`); len(params) != 0 {
+		t.Errorf("expected no parameters for basic challenge, got %v", params)
+	}
+}
+
+func TestGetImagePlatforms(t *testing.T) {
+	index := fmt.Sprintf(`{
+  "mediaType": %q,
+  "manifests": [
+    {"platform": {"os": "linux", "architecture": "arm64"}},
+    {"platform": {"os": "linux", "architecture": "amd64"}},
+    {"platform": {"os": "unknown", "architecture": "unknown"}}
+  ]
+}`, mediaTypeOCIIndex)
+	single := fmt.Sprintf(`{"mediaType": %q, "config": {"digest": "sha256:config"}}`, mediaTypeDockerManifest)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/longhorn/multi/manifests/v1":
+			_, _ = w.Write([]byte(index))
+		case "/v2/longhorn/single/manifests/v1":
+			_, _ = w.Write([]byte(single))
+		case "/v2/longhorn/single/blobs/sha256:config":
+			_, _ = w.Write([]byte(`{"os": "linux", "architecture": "arm", "variant": "v7"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := map[string][]string{
+		"multi":  {"linux/amd64", "linux/arm64"},
+		"single": {"linux/arm/v7"},
+	}
+
+	for repository, expected := range tests {
+		platforms, err := GetImagePlatforms(server.Client(), server.URL, ImageReference{Repository: "longhorn/" + repository, Reference: "v1"})
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v", repository, err)
+		}
+		if !reflect.DeepEqual(platforms, expected) {
+			t.Errorf("%v: expected %v, got %v", repository, expected, platforms)
+		}
+	}
+
+	if _, err := GetImagePlatforms(server.Client(), server.URL, ImageReference{Repository: "longhorn/missing", Reference: "v1"}); err == nil {
+		t.Errorf("expected error for missing image")
+	}
+}
+
+func TestSaveImage(t *testing.T) {
+	config := []byte(`{"architecture": "arm64", "os": "linux"}`)
+	layer := []byte("layer")
+	digest := func(data []byte) string {
+		return fmt.Sprintf("sha256:%x", sha256.Sum256(data))
+	}
+
+	manifest := fmt.Sprintf(`{
+  "mediaType": %q,
+  "config": {"digest": %q, "size": %d},
+  "layers": [{"digest": %q, "size": %d}]
+}`, mediaTypeOCIManifest, digest(config), len(config), digest(layer), len(layer))
+	index := fmt.Sprintf(`{
+  "mediaType": %q,
+  "manifests": [
+    {"digest": "sha256:amd64", "platform": {"os": "linux", "architecture": "amd64"}},
+    {"digest": "sha256:arm64", "platform": {"os": "linux", "architecture": "arm64"}}
+  ]
+}`, mediaTypeOCIIndex)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/longhorn/cli/manifests/v1":
+			_, _ = w.Write([]byte(index))
+		case "/v2/longhorn/cli/manifests/sha256:arm64":
+			_, _ = w.Write([]byte(manifest))
+		case "/v2/longhorn/cli/blobs/" + digest(config):
+			_, _ = w.Write(config)
+		case "/v2/longhorn/cli/blobs/" + digest(layer):
+			_, _ = w.Write(layer)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	ref := ImageReference{Repository: "longhorn/cli", Reference: "v1"}
+	var archive bytes.Buffer
+	if err := SaveImage(server.Client(), server.URL, ref, "longhorn/cli:v1", "linux/arm64", &archive); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	files := map[string]string{}
+	tarReader := tar.NewReader(&archive)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		data, _ := io.ReadAll(tarReader)
+		files[header.Name] = string(data)
+	}
+
+	configPath := "blobs/sha256/" + digest(config)[len("sha256:"):]
+	layerPath := "blobs/sha256/" + digest(layer)[len("sha256:"):]
+	expected := map[string]string{
+		configPath:      string(config),
+		layerPath:       string(layer),
+		"manifest.json": fmt.Sprintf(`[{"Config":%q,"RepoTags":["longhorn/cli:v1"],"Layers":[%q]}]`, configPath, layerPath),
+	}
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("expected archive %v, got %v", expected, files)
+	}
+
+	if err := SaveImage(server.Client(), server.URL, ref, "longhorn/cli:v1", "linux/s390x", io.Discard); err == nil {
+		t.Errorf("expected error for missing platform")
+	}
+}
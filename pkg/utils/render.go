@@ -0,0 +1,328 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/term"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils/i18n"
+)
+
+const (
+	colorReset  = "\033[0m"
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+)
+
+const (
+	resultStatusPass  = "PASS"
+	resultStatusWarn  = "WARN"
+	resultStatusError = "ERROR"
+	resultStatusSkip  = "SKIP"
+)
+
+// IsTerminal returns true if the file is a terminal.
+func IsTerminal(file *os.File) bool {
+	return term.IsTerminal(int(file.Fd()))
+}
+
+// IsColorEnabled returns true if the output to the file can be colored.
+// Color is disabled with the --no-color option, the NO_COLOR environment variable,
+// or when the file is not a terminal.
+func IsColorEnabled(globalOpts *types.GlobalCmdOptions, file *os.File) bool {
+	if globalOpts.NoColor || os.Getenv(consts.EnvNoColor) != "" {
+		return false
+	}
+	return IsTerminal(file)
+}
+
+// ValidateOutputFormat returns an error if the output format is not empty and not one of the supported formats.
+func ValidateOutputFormat(outputFormat string, supportedFormats ...string) error {
+	if outputFormat == "" || slices.Contains(supportedFormats, outputFormat) {
+		return nil
+	}
+	return errors.Errorf("unsupported output format %q (--%s), supported formats: %s", outputFormat, consts.CmdOptOutput, strings.Join(supportedFormats, ", "))
+}
+
+// PrintNodeCollections outputs the per-node result of a command in the output format.
+// Without an output format, the result is rendered as an aligned table when stdout
+// is a terminal. Otherwise, it falls back to PrintResult with the result in YAML.
+func PrintNodeCollections(globalOpts *types.GlobalCmdOptions, message, outputFormat string, nodeCollections map[string]*types.LogCollection) error {
+	return PrintCollections(globalOpts, "NODE", "nodes", message, outputFormat, nodeCollections)
+}
+
+// PrintCollections is PrintNodeCollections for results keyed by something else than
+// nodes, named by the header of the first column and the noun of the summary line.
+// The messages are translated to the language of --lang in all the output formats.
+func PrintCollections(globalOpts *types.GlobalCmdOptions, header, noun, message, outputFormat string, nodeCollections map[string]*types.LogCollection) error {
+	nodeCollections = i18n.TranslateCollections(nodeCollections)
+
+	if printed, err := PrintStructuredResult(outputFormat, types.ResultKindLogCollections, nodeCollections); printed || err != nil {
+		return err
+	}
+
+	if outputFormat == consts.OutputFormatJUnit {
+		output, err := RenderNodeCollectionsJUnit(consts.CmdLonghornctlRemote, nodeCollections)
+		if err != nil {
+			return err
+		}
+		fmt.Print(output)
+		return nil
+	}
+
+	if IsReportOutputFormat(outputFormat) {
+		document := NewCollectionsReport(consts.CmdLonghornctlRemote+" report", i18n.Sprintf(message), header, noun, nodeCollections)
+		document.GeneratedAt = time.Now()
+		return PrintReportDocument(outputFormat, document)
+	}
+
+	if len(nodeCollections) == 0 {
+		return nil
+	}
+
+	if !globalOpts.Quiet && IsTerminal(os.Stdout) {
+		fmt.Print(RenderCollections(header, noun, nodeCollections, IsColorEnabled(globalOpts, os.Stdout)))
+		return nil
+	}
+
+	yamlData, err := yaml.Marshal(NewResult(types.ResultKindLogCollections, nodeCollections))
+	if err != nil {
+		return err
+	}
+
+	PrintResult(globalOpts, message, string(yamlData))
+	return nil
+}
+
+// RenderNodeCollections renders the per-node result as a table with a row for
+// each message, followed by a summary line. Errors are listed first on each node.
+func RenderNodeCollections(nodeCollections map[string]*types.LogCollection, color bool) string {
+	return RenderCollections("NODE", "nodes", nodeCollections, color)
+}
+
+// RenderCollections is RenderNodeCollections for results keyed by something else than nodes.
+func RenderCollections(header, noun string, nodeCollections map[string]*types.LogCollection, color bool) string {
+	nodes := make([]string, 0, len(nodeCollections))
+	for node := range nodeCollections {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+
+	colorize := func(status, colorCode string) string {
+		return colorizeText(status, colorCode, color)
+	}
+
+	var builder strings.Builder
+	writer := tabwriter.NewWriter(&builder, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(writer, "%s\t%s\t%s\n", i18n.Sprintf(header), i18n.Sprintf("STATUS"), i18n.Sprintf("MESSAGE"))
+
+	errorCount, warnCount, skipCount := 0, 0, 0
+	for _, node := range nodes {
+		collection := nodeCollections[node]
+		if collection == nil {
+			continue
+		}
+
+		nodeName := node
+		writeRows := func(messages []string, status string) {
+			for _, message := range messages {
+				fmt.Fprintf(writer, "%s\t%s\t%s\n", nodeName, status, message)
+				nodeName = ""
+			}
+		}
+
+		writeRows(collection.Error, colorize(i18n.Sprintf(resultStatusError), colorRed))
+		writeRows(collection.Warn, colorize(i18n.Sprintf(resultStatusWarn), colorYellow))
+		writeRows(collection.Info, colorize(i18n.Sprintf(resultStatusPass), colorGreen))
+		writeRows(collection.Skipped, i18n.Sprintf(resultStatusSkip))
+
+		errorCount += len(collection.Error)
+		warnCount += len(collection.Warn)
+		if len(collection.Skipped) > 0 {
+			skipCount++
+		}
+	}
+	_ = writer.Flush()
+
+	builder.WriteString("\n" + renderSummary(len(nodes), noun, errorCount, warnCount, skipCount, color) + "\n")
+
+	return builder.String()
+}
+
+// PrintSummarizedNodeCollections is PrintNodeCollections summarizing the nodes by their group
+// when the result is rendered as a table: the node counts of each group, followed by the messages
+// of the nodes with errors only. The output formats keep the result of each node.
+func PrintSummarizedNodeCollections(globalOpts *types.GlobalCmdOptions, groupHeader, message, outputFormat string, nodeGroups map[string]string, nodeCollections map[string]*types.LogCollection) error {
+	if outputFormat != "" || globalOpts.Quiet || !IsTerminal(os.Stdout) || len(nodeCollections) == 0 {
+		return PrintNodeCollections(globalOpts, message, outputFormat, nodeCollections)
+	}
+
+	fmt.Print(RenderSummarizedNodeCollections(groupHeader, nodeGroups, i18n.TranslateCollections(nodeCollections), IsColorEnabled(globalOpts, os.Stdout)))
+	return nil
+}
+
+// RenderSummarizedNodeCollections renders the per-node result as a table with the number of nodes
+// passing, with warnings, with errors and skipped in each group, followed by a table with the
+// errors and warnings of the nodes with errors, and the summary line. The nodes missing from the
+// groups are counted in the <unknown> group.
+func RenderSummarizedNodeCollections(groupHeader string, nodeGroups map[string]string, nodeCollections map[string]*types.LogCollection, color bool) string {
+	type groupCounts struct {
+		nodes, pass, warn, error, skip int
+	}
+
+	nodes := make([]string, 0, len(nodeCollections))
+	for node := range nodeCollections {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+
+	getGroup := func(node string) string {
+		if group, ok := nodeGroups[node]; ok {
+			return group
+		}
+		return "<unknown>"
+	}
+
+	counts := map[string]*groupCounts{}
+	var groups, failingNodes []string
+	errorCount, warnCount, skipCount := 0, 0, 0
+	for _, node := range nodes {
+		collection := nodeCollections[node]
+		if collection == nil {
+			continue
+		}
+
+		group := getGroup(node)
+		if counts[group] == nil {
+			counts[group] = &groupCounts{}
+			groups = append(groups, group)
+		}
+		counts[group].nodes++
+
+		switch {
+		case len(collection.Error) > 0:
+			counts[group].error++
+			failingNodes = append(failingNodes, node)
+		case len(collection.Warn) > 0:
+			counts[group].warn++
+		case len(collection.Skipped) > 0:
+			counts[group].skip++
+		default:
+			counts[group].pass++
+		}
+
+		errorCount += len(collection.Error)
+		warnCount += len(collection.Warn)
+		if len(collection.Skipped) > 0 {
+			skipCount++
+		}
+	}
+	sort.Strings(groups)
+
+	var builder strings.Builder
+	writer := tabwriter.NewWriter(&builder, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\n", i18n.Sprintf(groupHeader), i18n.Sprintf("NODES"), i18n.Sprintf(resultStatusPass), i18n.Sprintf(resultStatusWarn), i18n.Sprintf(resultStatusError), i18n.Sprintf(resultStatusSkip))
+	for _, group := range groups {
+		groupCount := counts[group]
+		fmt.Fprintf(writer, "%s\t%d\t%d\t%d\t%d\t%d\n", group, groupCount.nodes, groupCount.pass, groupCount.warn, groupCount.error, groupCount.skip)
+	}
+	_ = writer.Flush()
+
+	if len(failingNodes) > 0 {
+		builder.WriteString("\n")
+		writer = tabwriter.NewWriter(&builder, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", i18n.Sprintf("NODE"), i18n.Sprintf(groupHeader), i18n.Sprintf("STATUS"), i18n.Sprintf("MESSAGE"))
+		for _, node := range failingNodes {
+			nodeName, group := node, getGroup(node)
+			writeRows := func(messages []string, status string) {
+				for _, message := range messages {
+					fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", nodeName, group, status, message)
+					nodeName, group = "", ""
+				}
+			}
+
+			writeRows(nodeCollections[node].Error, colorizeText(i18n.Sprintf(resultStatusError), colorRed, color))
+			writeRows(nodeCollections[node].Warn, colorizeText(i18n.Sprintf(resultStatusWarn), colorYellow, color))
+		}
+		_ = writer.Flush()
+	}
+
+	builder.WriteString("\n" + renderSummary(len(nodes), "nodes", errorCount, warnCount, skipCount, color) + "\n")
+
+	return builder.String()
+}
+
+// renderSummary renders the summary line of a result, colored by its most severe status.
+func renderSummary(count int, noun string, errorCount, warnCount, skipCount int, color bool) string {
+	summary := i18n.Sprintf("%d %s, %d errors, %d warnings", count, i18n.Sprintf(noun), errorCount, warnCount)
+	if skipCount > 0 {
+		summary += i18n.Sprintf(", %d skipped", skipCount)
+	}
+	switch {
+	case errorCount > 0:
+		return colorizeText(summary, colorRed, color)
+	case warnCount > 0:
+		return colorizeText(summary, colorYellow, color)
+	default:
+		return colorizeText(summary, colorGreen, color)
+	}
+}
+
+// colorizeText wraps the text in the color code when color is enabled.
+func colorizeText(text, colorCode string, color bool) string {
+	if !color {
+		return text
+	}
+	return colorCode + text + colorReset
+}
+
+// RenderEvent renders the event as a single line. Warnings are colored in yellow.
+func RenderEvent(event *types.Event, color bool) string {
+	eventType := fmt.Sprintf("%-7s", event.Type)
+	if color && event.Type == corev1.EventTypeWarning {
+		eventType = colorYellow + eventType + colorReset
+	}
+
+	message := strings.ReplaceAll(event.Message, "\n", " ")
+	if event.Count > 1 {
+		message = fmt.Sprintf("%s (x%d)", message, event.Count)
+	}
+
+	return fmt.Sprintf("%s  %s  %s/%s  %s: %s", event.Time.Local().Format(time.RFC3339), eventType, event.Kind, event.Name, event.Reason, message)
+}
+
+// FormatBytes returns the size in binary units with one decimal, such as 1.5GiB.
+func FormatBytes(size int64) string {
+	units := []string{"KiB", "MiB", "GiB", "TiB", "PiB"}
+
+	sign := ""
+	if size < 0 {
+		sign = "-"
+		size = -size
+	}
+	if size < 1024 {
+		return fmt.Sprintf("%s%dB", sign, size)
+	}
+
+	value := float64(size) / 1024
+	unit := 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	return fmt.Sprintf("%s%.1f%s", sign, value, units[unit])
+}
@@ -0,0 +1,173 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func TestRenderNodeCollections(t *testing.T) {
+	for _, test := range []struct {
+		input  map[string]*types.LogCollection
+		output string
+	}{
+		{
+			input: map[string]*types.LogCollection{
+				"node-b": {
+					Info: []string{"Service iscsid is running"},
+				},
+				"node-a": {
+					Error: []string{"Package open-iscsi is not installed"},
+					Warn:  []string{"multipathd.service is running"},
+					Info:  []string{"NFS4 is supported"},
+				},
+			},
+			output: "NODE    STATUS  MESSAGE\n" +
+				"node-a  ERROR   Package open-iscsi is not installed\n" +
+				"        WARN    multipathd.service is running\n" +
+				"        PASS    NFS4 is supported\n" +
+				"node-b  PASS    Service iscsid is running\n" +
+				"\n2 nodes, 1 errors, 1 warnings\n",
+		},
+		{
+			input:  map[string]*types.LogCollection{},
+			output: "NODE  STATUS  MESSAGE\n\n0 nodes, 0 errors, 0 warnings\n",
+		},
+		{
+			input: map[string]*types.LogCollection{
+				"node-a": {
+					Info: []string{"Service iscsid is running"},
+				},
+				"win-a": {
+					Skipped: []string{"Windows nodes are not supported, skipped running longhorn-preflight-checker on it"},
+				},
+			},
+			output: "NODE    STATUS  MESSAGE\n" +
+				"node-a  PASS    Service iscsid is running\n" +
+				"win-a   SKIP    Windows nodes are not supported, skipped running longhorn-preflight-checker on it\n" +
+				"\n2 nodes, 0 errors, 0 warnings, 1 skipped\n",
+		},
+	} {
+		result := RenderNodeCollections(test.input, false)
+		if result != test.output {
+			t.Errorf("expected:\n%s\ngot:\n%s", test.output, result)
+		}
+	}
+}
+
+func TestRenderSummarizedNodeCollections(t *testing.T) {
+	nodeGroups := map[string]string{
+		"node-a": "us-east-1a",
+		"node-b": "us-east-1a",
+		"node-c": "us-east-1b",
+		"win-a":  "us-east-1b",
+	}
+	input := map[string]*types.LogCollection{
+		"node-a": {
+			Error: []string{"Package open-iscsi is not installed"},
+			Warn:  []string{"multipathd.service is running"},
+			Info:  []string{"NFS4 is supported"},
+		},
+		"node-b": {
+			Warn: []string{"multipathd.service is running"},
+			Info: []string{"NFS4 is supported"},
+		},
+		"node-c": {
+			Info: []string{"NFS4 is supported"},
+		},
+		"node-d": {
+			Info: []string{"NFS4 is supported"},
+		},
+		"win-a": {
+			Skipped: []string{"Windows nodes are not supported, skipped running longhorn-preflight-checker on it"},
+		},
+	}
+
+	output := "ZONE        NODES  PASS  WARN  ERROR  SKIP\n" +
+		"<unknown>   1      1     0     0      0\n" +
+		"us-east-1a  2      0     1     1      0\n" +
+		"us-east-1b  2      1     0     0      1\n" +
+		"\n" +
+		"NODE    ZONE        STATUS  MESSAGE\n" +
+		"node-a  us-east-1a  ERROR   Package open-iscsi is not installed\n" +
+		"                    WARN    multipathd.service is running\n" +
+		"\n5 nodes, 1 errors, 2 warnings, 1 skipped\n"
+
+	if result := RenderSummarizedNodeCollections("ZONE", nodeGroups, input, false); result != output {
+		t.Errorf("expected:\n%s\ngot:\n%s", output, result)
+	}
+}
+
+func TestRenderNodeCollectionsJUnit(t *testing.T) {
+	input := map[string]*types.LogCollection{
+		"node-a": {
+			Error: []string{"Package open-iscsi is not installed"},
+			Info:  []string{"NFS4 is supported"},
+		},
+	}
+	output := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites name="longhornctl" tests="2" failures="1">
+  <testsuite name="node-a" tests="2" failures="1">
+    <testcase name="Package open-iscsi is not installed" classname="node-a">
+      <failure message="Package open-iscsi is not installed" type="ERROR"></failure>
+    </testcase>
+    <testcase name="NFS4 is supported" classname="node-a"></testcase>
+  </testsuite>
+</testsuites>
+`
+
+	result, err := RenderNodeCollectionsJUnit("longhornctl", input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != output {
+		t.Errorf("expected:\n%s\ngot:\n%s", output, result)
+	}
+}
+
+func TestRenderEvent(t *testing.T) {
+	eventTime := time.Date(2024, 7, 16, 17, 17, 38, 0, time.Local)
+	event := &types.Event{
+		Time:    eventTime,
+		Type:    "Warning",
+		Kind:    "Replica",
+		Name:    "pvc-1-r-abc",
+		Reason:  "Rebuilding",
+		Message: "Replica is rebuilding\nfrom pvc-1-r-def",
+		Count:   3,
+	}
+
+	expected := eventTime.Format(time.RFC3339) + "  Warning  Replica/pvc-1-r-abc  Rebuilding: Replica is rebuilding from pvc-1-r-def (x3)"
+	if result := RenderEvent(event, false); result != expected {
+		t.Errorf("expected %q, got %q", expected, result)
+	}
+
+	if result := RenderEvent(event, true); !strings.Contains(result, colorYellow+"Warning"+colorReset) {
+		t.Errorf("expected the warning to be colored, got %q", result)
+	}
+
+	event.Type = "Normal"
+	event.Count = 1
+	if result := RenderEvent(event, true); strings.Contains(result, colorYellow) || strings.Contains(result, "(x1)") {
+		t.Errorf("expected a plain normal event, got %q", result)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := map[int64]string{
+		0:                "0B",
+		1023:             "1023B",
+		1536:             "1.5KiB",
+		-3 * 1024 * 1024: "-3.0MiB",
+		5 << 40:          "5.0TiB",
+		12 << 50:         "12.0PiB",
+	}
+
+	for size, expected := range tests {
+		if result := FormatBytes(size); result != expected {
+			t.Errorf("expected %v for %d, got %v", expected, size, result)
+		}
+	}
+}
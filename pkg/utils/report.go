@@ -0,0 +1,255 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+	"github.com/longhorn/cli/pkg/utils/i18n"
+)
+
+// ReportDocument is a result rendered as a document of tables, shareable as a Markdown
+// or a self-contained HTML file, such as an attachment of a change-management ticket.
+type ReportDocument struct {
+	Title       string
+	Description string
+	GeneratedAt time.Time
+	Sections    []ReportSection
+	Notes       []string // Footnotes of the document, following the sections.
+}
+
+// ReportSection is a titled table of a report document. A section without rows
+// is rendered with its empty text instead of the table.
+type ReportSection struct {
+	Title   string
+	Empty   string
+	Headers []string
+	Rows    [][]string
+}
+
+// IsReportOutputFormat returns true if the output format renders a report document.
+func IsReportOutputFormat(outputFormat string) bool {
+	return outputFormat == consts.OutputFormatHTML || outputFormat == consts.OutputFormatMarkdown
+}
+
+// PrintReportDocument outputs the report document in the output format. Without an output format,
+// the sections are printed as aligned tables separated by empty lines, without their titles.
+func PrintReportDocument(outputFormat string, document *ReportDocument) error {
+	switch outputFormat {
+	case consts.OutputFormatHTML:
+		output, err := RenderReportHTML(document)
+		if err != nil {
+			return err
+		}
+		fmt.Print(output)
+	case consts.OutputFormatMarkdown:
+		fmt.Print(RenderReportMarkdown(document))
+	default:
+		fmt.Print(RenderReportText(document))
+	}
+	return nil
+}
+
+// RenderReportText renders the sections of the report document as aligned tables.
+func RenderReportText(document *ReportDocument) string {
+	var builder strings.Builder
+	for i, section := range document.Sections {
+		if i > 0 {
+			builder.WriteString("\n")
+		}
+		writer := tabwriter.NewWriter(&builder, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, strings.Join(section.Headers, "\t"))
+		for _, row := range section.Rows {
+			fmt.Fprintln(writer, strings.Join(row, "\t"))
+		}
+		_ = writer.Flush()
+	}
+	for _, note := range document.Notes {
+		builder.WriteString("\n" + note + "\n")
+	}
+	return builder.String()
+}
+
+// RenderReportMarkdown renders the report document as Markdown with a table for each section.
+func RenderReportMarkdown(document *ReportDocument) string {
+	escape := strings.NewReplacer("\\", "\\\\", "|", "\\|", "<", "&lt;", ">", "&gt;", "\n", "<br>").Replace
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "# %s\n\n", escape(document.Title))
+	if document.Description != "" {
+		fmt.Fprintf(&builder, "%s\n\n", escape(document.Description))
+	}
+	if !document.GeneratedAt.IsZero() {
+		fmt.Fprintf(&builder, "%s\n\n", escape(i18n.Sprintf("Generated at %s", document.GeneratedAt.Format(time.RFC3339))))
+	}
+
+	for _, section := range document.Sections {
+		fmt.Fprintf(&builder, "## %s\n\n", escape(section.Title))
+		if len(section.Rows) == 0 {
+			fmt.Fprintf(&builder, "%s\n\n", escape(section.Empty))
+			continue
+		}
+
+		writeRow := func(cells []string) {
+			builder.WriteString("|")
+			for _, cell := range cells {
+				builder.WriteString(" " + escape(cell) + " |")
+			}
+			builder.WriteString("\n")
+		}
+		writeRow(section.Headers)
+		builder.WriteString(strings.Repeat("| --- ", len(section.Headers)) + "|\n")
+		for _, row := range section.Rows {
+			writeRow(row)
+		}
+		builder.WriteString("\n")
+	}
+
+	for _, note := range document.Notes {
+		fmt.Fprintf(&builder, "%s\n\n", escape(note))
+	}
+	return builder.String()
+}
+
+var reportHTMLTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"statusClass": func(cell string) string {
+		for _, status := range []string{resultStatusPass, resultStatusWarn, resultStatusError, resultStatusSkip} {
+			if cell == i18n.Sprintf(status) {
+				return status
+			}
+		}
+		return ""
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{ .Title }}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+th { background: #f0f0f0; }
+td.PASS { color: #2e7d32; }
+td.WARN { color: #b26a00; }
+td.ERROR { color: #c62828; font-weight: bold; }
+td.SKIP { color: #757575; }
+@media print { body { margin: 0; } h2 { page-break-after: avoid; } tr { page-break-inside: avoid; } }
+</style>
+</head>
+<body>
+<h1>{{ .Title }}</h1>
+{{- if .Description }}
+<p>{{ .Description }}</p>
+{{- end }}
+{{- if .GeneratedAt }}
+<p>{{ .GeneratedAt }}</p>
+{{- end }}
+{{- range .Sections }}
+<h2>{{ .Title }}</h2>
+{{- if .Rows }}
+<table>
+<tr>{{ range .Headers }}<th>{{ . }}</th>{{ end }}</tr>
+{{- range .Rows }}
+<tr>{{ range . }}{{ with statusClass . }}<td class="{{ . }}">{{ else }}<td>{{ end }}{{ . }}</td>{{ end }}</tr>
+{{- end }}
+</table>
+{{- else }}
+<p>{{ .Empty }}</p>
+{{- end }}
+{{- end }}
+{{- range .Notes }}
+<p>{{ . }}</p>
+{{- end }}
+</body>
+</html>
+`))
+
+// RenderReportHTML renders the report document as a self-contained HTML page, with its style
+// inlined so it can be opened or printed without network access.
+func RenderReportHTML(document *ReportDocument) (string, error) {
+	generatedAt := ""
+	if !document.GeneratedAt.IsZero() {
+		generatedAt = i18n.Sprintf("Generated at %s", document.GeneratedAt.Format(time.RFC3339))
+	}
+
+	var buffer bytes.Buffer
+	if err := reportHTMLTemplate.Execute(&buffer, struct {
+		*ReportDocument
+		GeneratedAt string
+	}{document, generatedAt}); err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
+
+// NewCollectionsReport returns the report document of a result keyed by the header of the
+// first column: a summary of the status counts, the messages of each object, and a remediation
+// appendix with the errors and warnings to address, by object.
+func NewCollectionsReport(title, description, header, noun string, collections map[string]*types.LogCollection) *ReportDocument {
+	names := make([]string, 0, len(collections))
+	for name := range collections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	details := ReportSection{
+		Title:   i18n.Sprintf("Details"),
+		Empty:   i18n.Sprintf("No result."),
+		Headers: []string{i18n.Sprintf(header), i18n.Sprintf("STATUS"), i18n.Sprintf("MESSAGE")},
+	}
+	remediation := ReportSection{
+		Title:   i18n.Sprintf("Remediation"),
+		Empty:   i18n.Sprintf("No errors or warnings to address."),
+		Headers: []string{i18n.Sprintf(header), i18n.Sprintf("STATUS"), i18n.Sprintf("MESSAGE")},
+	}
+
+	passCount, warnCount, errorCount, skipCount := 0, 0, 0, 0
+	for _, name := range names {
+		collection := collections[name]
+		if collection == nil {
+			continue
+		}
+
+		switch {
+		case len(collection.Error) > 0:
+			errorCount++
+		case len(collection.Warn) > 0:
+			warnCount++
+		case len(collection.Skipped) > 0:
+			skipCount++
+		default:
+			passCount++
+		}
+
+		addRows := func(section *ReportSection, messages []string, resultStatus string) {
+			for _, message := range messages {
+				section.Rows = append(section.Rows, []string{name, i18n.Sprintf(resultStatus), message})
+			}
+		}
+		addRows(&details, collection.Error, resultStatusError)
+		addRows(&details, collection.Warn, resultStatusWarn)
+		addRows(&details, collection.Info, resultStatusPass)
+		addRows(&details, collection.Skipped, resultStatusSkip)
+		addRows(&remediation, collection.Error, resultStatusError)
+		addRows(&remediation, collection.Warn, resultStatusWarn)
+	}
+
+	summary := ReportSection{
+		Title:   i18n.Sprintf("Summary"),
+		Headers: []string{i18n.Sprintf(strings.ToUpper(noun)), i18n.Sprintf(resultStatusPass), i18n.Sprintf(resultStatusWarn), i18n.Sprintf(resultStatusError), i18n.Sprintf(resultStatusSkip)},
+		Rows:    [][]string{{fmt.Sprint(len(names)), fmt.Sprint(passCount), fmt.Sprint(warnCount), fmt.Sprint(errorCount), fmt.Sprint(skipCount)}},
+	}
+
+	return &ReportDocument{
+		Title:       title,
+		Description: description,
+		Sections:    []ReportSection{summary, details, remediation},
+	}
+}
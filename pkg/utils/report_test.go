@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func TestNewCollectionsReport(t *testing.T) {
+	document := NewCollectionsReport("longhornctl report", "Retrieved preflight checker result", "NODE", "nodes", map[string]*types.LogCollection{
+		"node-b": {Info: []string{"Service iscsid is running"}},
+		"node-a": {
+			Error: []string{"Package open-iscsi is not installed"},
+			Warn:  []string{"multipathd.service is running"},
+			Info:  []string{"NFS4 is supported"},
+		},
+		"win-a": {Skipped: []string{"Windows nodes are not supported"}},
+	})
+
+	if len(document.Sections) != 3 {
+		t.Fatalf("expected summary, details and remediation sections, got %d sections", len(document.Sections))
+	}
+	if summary := strings.Join(document.Sections[0].Rows[0], ","); summary != "3,1,0,1,1" {
+		t.Errorf("expected summary 3,1,0,1,1, got %v", summary)
+	}
+	if rows := len(document.Sections[1].Rows); rows != 5 {
+		t.Errorf("expected 5 detail rows, got %d", rows)
+	}
+
+	expectedRemediation := [][]string{
+		{"node-a", "ERROR", "Package open-iscsi is not installed"},
+		{"node-a", "WARN", "multipathd.service is running"},
+	}
+	if remediation := document.Sections[2].Rows; len(remediation) != len(expectedRemediation) {
+		t.Fatalf("expected remediation %v, got %v", expectedRemediation, remediation)
+	}
+	for i, row := range document.Sections[2].Rows {
+		if strings.Join(row, ",") != strings.Join(expectedRemediation[i], ",") {
+			t.Errorf("expected remediation row %v, got %v", expectedRemediation[i], row)
+		}
+	}
+}
+
+func TestRenderReport(t *testing.T) {
+	document := &ReportDocument{
+		Title: "longhornctl report",
+		Sections: []ReportSection{
+			{Title: "Volumes", Headers: []string{"VOLUME", "ISSUE"}, Rows: [][]string{{"vol|1", "<b>no backup</b>"}}},
+			{Title: "Remediation", Empty: "All volumes are protected.", Headers: []string{"VOLUME", "ISSUE"}},
+		},
+		Notes: []string{"* Note"},
+	}
+
+	text := RenderReportText(document)
+	expectedText := "VOLUME  ISSUE\nvol|1   <b>no backup</b>\n\nVOLUME  ISSUE\n\n* Note\n"
+	if text != expectedText {
+		t.Errorf("expected text:\n%s\ngot:\n%s", expectedText, text)
+	}
+
+	markdown := RenderReportMarkdown(document)
+	for _, expected := range []string{"# longhornctl report\n", "| VOLUME | ISSUE |\n| --- | --- |\n| vol\\|1 | &lt;b&gt;no backup&lt;/b&gt; |\n", "## Remediation\n\nAll volumes are protected.\n", "* Note\n"} {
+		if !strings.Contains(markdown, expected) {
+			t.Errorf("expected markdown to contain %q, got:\n%s", expected, markdown)
+		}
+	}
+
+	html, err := RenderReportHTML(document)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, expected := range []string{"<title>longhornctl report</title>", "<style>", "<td>&lt;b&gt;no backup&lt;/b&gt;</td>", "<p>All volumes are protected.</p>"} {
+		if !strings.Contains(html, expected) {
+			t.Errorf("expected HTML to contain %q, got:\n%s", expected, html)
+		}
+	}
+	if strings.Contains(html, "Generated at") {
+		t.Error("expected no generation time without GeneratedAt")
+	}
+}
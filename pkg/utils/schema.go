@@ -0,0 +1,229 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+)
+
+const jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// NewResult wraps the result of the kind with the current schema version.
+func NewResult(kind string, result any) *types.Result {
+	return &types.Result{
+		SchemaVersion: types.ResultSchemaVersion,
+		Kind:          kind,
+		Result:        result,
+	}
+}
+
+var resultWriters []func(kind string, result any) (bool, error)
+
+// RegisterResultWriter registers a writer called by PrintStructuredResult with each result, before it
+// is printed. When a writer returns true, the result is not printed to stdout.
+func RegisterResultWriter(writer func(kind string, result any) (bool, error)) {
+	resultWriters = append(resultWriters, writer)
+}
+
+// MarshalResult returns the result of the kind in JSON or YAML, wrapped with the schema version.
+func MarshalResult(outputFormat, kind string, result any) ([]byte, error) {
+	switch outputFormat {
+	case consts.OutputFormatJSON:
+		jsonData, err := json.MarshalIndent(NewResult(kind, result), "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(jsonData, '\n'), nil
+
+	case consts.OutputFormatYAML:
+		return yaml.Marshal(NewResult(kind, result))
+
+	default:
+		return nil, errors.Errorf("unsupported structured output format %q", outputFormat)
+	}
+}
+
+// PrintStructuredResult passes the result of the kind to the registered writers, then prints it in
+// JSON or YAML, wrapped with the schema version. It returns false without printing anything when
+// the output format is not JSON or YAML, and true when a writer has handled the result.
+func PrintStructuredResult(outputFormat, kind string, result any) (bool, error) {
+	handled := false
+	for _, writer := range resultWriters {
+		written, err := writer(kind, result)
+		if err != nil {
+			return true, err
+		}
+		handled = handled || written
+	}
+	if handled {
+		return true, nil
+	}
+
+	if outputFormat != consts.OutputFormatJSON && outputFormat != consts.OutputFormatYAML {
+		return false, nil
+	}
+
+	data, err := MarshalResult(outputFormat, kind, result)
+	if err != nil {
+		return true, err
+	}
+	fmt.Print(string(data))
+	return true, nil
+}
+
+// GetResultKinds returns the kinds of the structured outputs in alphabetical order.
+func GetResultKinds() []string {
+	kinds := make([]string, 0, len(types.ResultKinds))
+	for kind := range types.ResultKinds {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// GenerateResultSchema returns the JSON Schema of the structured output of the kind, generated
+// from the JSON tags of the type of its result.
+func GenerateResultSchema(kind string) (map[string]any, error) {
+	result, ok := types.ResultKinds[kind]
+	if !ok {
+		return nil, errors.Errorf("unknown result kind %q", kind)
+	}
+
+	definitions := map[string]any{}
+	resultSchema := generateTypeSchema(reflect.TypeOf(result), definitions)
+
+	schema := map[string]any{
+		"$schema": jsonSchemaDraft,
+		"$id":     fmt.Sprintf("https://longhorn.io/schemas/longhornctl/%s/%s.json", types.ResultSchemaVersion, kind),
+		"title":   kind,
+		"type":    "object",
+		"properties": map[string]any{
+			"schemaVersion": map[string]any{"const": types.ResultSchemaVersion},
+			"kind":          map[string]any{"const": kind},
+			"result":        resultSchema,
+		},
+		"required": []string{"schemaVersion", "kind", "result"},
+	}
+	if len(definitions) > 0 {
+		schema["$defs"] = definitions
+	}
+	return schema, nil
+}
+
+// generateTypeSchema returns the JSON Schema of the type. Named structs are added to the
+// definitions and referenced, so recursive types do not recurse forever. Pointers, slices and
+// maps accept null, since they are encoded as null when nil.
+func generateTypeSchema(t reflect.Type, definitions map[string]any) map[string]any {
+	switch t {
+	case timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case reflect.TypeOf(json.RawMessage{}):
+		return map[string]any{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return map[string]any{"anyOf": []any{generateTypeSchema(t.Elem(), definitions), map[string]any{"type": "null"}}}
+
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+
+	case reflect.String:
+		return map[string]any{"type": "string"}
+
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "contentEncoding": "base64"}
+		}
+		return map[string]any{"type": []string{"array", "null"}, "items": generateTypeSchema(t.Elem(), definitions)}
+
+	case reflect.Map:
+		return map[string]any{"type": []string{"object", "null"}, "additionalProperties": generateTypeSchema(t.Elem(), definitions)}
+
+	case reflect.Struct:
+		if t.Name() == "" {
+			return generateStructSchema(t, definitions)
+		}
+
+		if _, ok := definitions[t.Name()]; !ok {
+			definitions[t.Name()] = map[string]any{} // Placeholder for recursive references.
+			definitions[t.Name()] = generateStructSchema(t, definitions)
+		}
+		return map[string]any{"$ref": "#/$defs/" + t.Name()}
+	}
+
+	// Interfaces can hold any value.
+	return map[string]any{}
+}
+
+// generateStructSchema returns the JSON Schema of the struct from the JSON tags of its exported
+// fields. The fields without omitempty are required, and the embedded structs are inlined.
+func generateStructSchema(t reflect.Type, definitions map[string]any) map[string]any {
+	properties := map[string]any{}
+	required := []string{}
+	addStructProperties(t, definitions, properties, &required)
+
+	schema := map[string]any{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func addStructProperties(t reflect.Type, definitions map[string]any, properties map[string]any, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+
+		// Like encoding/json, the fields of embedded structs are promoted even when the struct is unexported.
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				addStructProperties(embedded, definitions, properties, required)
+				continue
+			}
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = generateTypeSchema(field.Type, definitions)
+
+		if !strings.Contains(options, "omitempty") {
+			*required = append(*required, name)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/longhorn/cli/pkg/types"
+)
+
+type schemaTestEmbedded struct {
+	Embedded string `json:"embedded"`
+}
+
+type schemaTestItem struct {
+	schemaTestEmbedded
+
+	Name     string            `json:"name"`
+	Count    int               `json:"count,omitempty"`
+	Time     time.Time         `json:"time"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Children []schemaTestItem  `json:"children,omitempty"`
+	Ignored  string            `json:"-"`
+	internal string
+}
+
+func TestGenerateTypeSchema(t *testing.T) {
+	definitions := map[string]any{}
+	schema := generateTypeSchema(reflect.TypeOf(schemaTestItem{}), definitions)
+
+	if !reflect.DeepEqual(schema, map[string]any{"$ref": "#/$defs/schemaTestItem"}) {
+		t.Fatalf("unexpected schema %v", schema)
+	}
+
+	expected := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"embedded": map[string]any{"type": "string"},
+			"name":     map[string]any{"type": "string"},
+			"count":    map[string]any{"type": "integer"},
+			"time":     map[string]any{"type": "string", "format": "date-time"},
+			"labels":   map[string]any{"type": []string{"object", "null"}, "additionalProperties": map[string]any{"type": "string"}},
+			"children": map[string]any{"type": []string{"array", "null"}, "items": map[string]any{"$ref": "#/$defs/schemaTestItem"}},
+		},
+		"required": []string{"embedded", "name", "time"},
+	}
+	if !reflect.DeepEqual(definitions["schemaTestItem"], expected) {
+		t.Errorf("expected definition %v, got %v", expected, definitions["schemaTestItem"])
+	}
+}
+
+func TestGenerateResultSchema(t *testing.T) {
+	for _, kind := range GetResultKinds() {
+		schema, err := GenerateResultSchema(kind)
+		if err != nil {
+			t.Errorf("kind %v: unexpected error: %v", kind, err)
+			continue
+		}
+		if _, err := json.Marshal(schema); err != nil {
+			t.Errorf("kind %v: failed to marshal schema: %v", kind, err)
+		}
+	}
+
+	if _, err := GenerateResultSchema("Unknown"); err == nil {
+		t.Errorf("expected error for unknown kind")
+	}
+}
+
+func TestNewResult(t *testing.T) {
+	jsonData, err := json.Marshal(NewResult(types.ResultKindLogCollections, map[string]*types.LogCollection{
+		"node-1": {Info: []string{"ok"}},
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `{"schemaVersion":"` + types.ResultSchemaVersion + `","kind":"LogCollections","result":{"node-1":{"info":["ok"]}}}`
+	if string(jsonData) != expected {
+		t.Errorf("expected %v, got %v", expected, string(jsonData))
+	}
+}
@@ -0,0 +1,177 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+)
+
+const telemetryTimeout = 5 * time.Second
+
+// Failure categories of the telemetry reports.
+const (
+	TelemetryFailureCanceled   = "canceled"
+	TelemetryFailureKubernetes = "kubernetes-api"
+	TelemetryFailureNetwork    = "network"
+	TelemetryFailurePermission = "permission"
+	TelemetryFailureTimeout    = "timeout"
+	TelemetryFailureOther      = "other"
+)
+
+// GetTelemetryConfigFile returns the path to the telemetry opt-in in the user config directory.
+func GetTelemetryConfigFile() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get user config directory")
+	}
+	return filepath.Join(configDir, consts.CmdLonghornctlRemote, "telemetry.json"), nil
+}
+
+// ReadTelemetryConfig returns the telemetry opt-in of the file, disabled when it does not exist.
+func ReadTelemetryConfig(path string) (*types.TelemetryConfig, error) {
+	config := &types.TelemetryConfig{}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read telemetry config %v", path)
+	}
+
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse telemetry config %v", path)
+	}
+	return config, nil
+}
+
+// WriteTelemetryConfig writes the telemetry opt-in to the file, readable by the user only.
+func WriteTelemetryConfig(path string, config *types.TelemetryConfig) error {
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to convert telemetry config to JSON")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return errors.Wrapf(err, "failed to create directory of telemetry config %v", path)
+	}
+	return errors.Wrapf(os.WriteFile(path, data, 0600), "failed to write telemetry config %v", path)
+}
+
+// EnableTelemetry opts in to the telemetry with the endpoint, defaulting to the one of the previous
+// opt-in, and generates the random identifier of the reports on the first opt-in.
+func EnableTelemetry(config *types.TelemetryConfig, endpoint string) error {
+	if endpoint == "" {
+		endpoint = config.Endpoint
+	}
+	if endpoint == "" {
+		return errors.Errorf("--%s is required to enable the telemetry", consts.CmdOptTelemetryURL)
+	}
+	if err := ValidateTelemetryURL(endpoint); err != nil {
+		return err
+	}
+
+	if config.ID == "" {
+		id := make([]byte, 16)
+		if _, err := rand.Read(id); err != nil {
+			return errors.Wrap(err, "failed to generate telemetry identifier")
+		}
+		config.ID = hex.EncodeToString(id)
+	}
+	config.Enabled = true
+	config.Endpoint = endpoint
+	return nil
+}
+
+// DisableTelemetry opts out of the telemetry, and discards the identifier and the last report.
+func DisableTelemetry(config *types.TelemetryConfig) {
+	config.Enabled = false
+	config.ID = ""
+	config.LastReport = nil
+}
+
+// ValidateTelemetryURL returns an error if the telemetry endpoint is not an HTTP or HTTPS URL.
+func ValidateTelemetryURL(endpoint string) error {
+	parsedURL, err := url.Parse(endpoint)
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" {
+		return errors.Errorf("invalid --%s %q, it must be an HTTP or HTTPS URL", consts.CmdOptTelemetryURL, endpoint)
+	}
+	return nil
+}
+
+// IsDoNotTrack returns whether the DO_NOT_TRACK environment variable disables the telemetry.
+func IsDoNotTrack() bool {
+	value := strings.TrimSpace(os.Getenv(consts.EnvDoNotTrack))
+	return value != "" && value != "0" && !strings.EqualFold(value, "false")
+}
+
+// SendTelemetryReport posts the report as JSON to the endpoint.
+func SendTelemetryReport(httpClient *http.Client, endpoint string, report *types.TelemetryReport) error {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return errors.Wrap(err, "failed to convert telemetry report to JSON")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), telemetryTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return errors.Wrapf(err, "failed to create telemetry request to %v", endpoint)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "failed to send telemetry report to %v", endpoint)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.Errorf("failed to send telemetry report to %v: %v", endpoint, resp.Status)
+	}
+	return nil
+}
+
+// GetTelemetryFailureCategory returns the category of the error reported instead of its message,
+// which may hold names and addresses.
+func GetTelemetryFailureCategory(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	var netErr net.Error
+	cause := errors.Cause(err)
+	switch {
+	case errors.Is(err, context.Canceled):
+		return TelemetryFailureCanceled
+	case errors.Is(err, context.DeadlineExceeded) || apierrors.IsTimeout(cause) || apierrors.IsServerTimeout(cause):
+		return TelemetryFailureTimeout
+	case apierrors.IsForbidden(cause) || apierrors.IsUnauthorized(cause):
+		return TelemetryFailurePermission
+	case apierrors.ReasonForError(cause) != "":
+		return TelemetryFailureKubernetes
+	case errors.As(err, &netErr):
+		if netErr.Timeout() {
+			return TelemetryFailureTimeout
+		}
+		return TelemetryFailureNetwork
+	}
+	return TelemetryFailureOther
+}
@@ -0,0 +1,140 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/longhorn/cli/pkg/consts"
+	"github.com/longhorn/cli/pkg/types"
+)
+
+func TestTelemetryConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "longhornctl", "telemetry.json")
+
+	config, err := ReadTelemetryConfig(path)
+	if err != nil {
+		t.Fatalf("ReadTelemetryConfig() of a missing file error = %v", err)
+	}
+	if config.Enabled {
+		t.Fatalf("ReadTelemetryConfig() of a missing file is enabled")
+	}
+
+	if err := EnableTelemetry(config, ""); err == nil {
+		t.Fatalf("EnableTelemetry() without endpoint succeeded")
+	}
+	if err := EnableTelemetry(config, "https://telemetry.example.com/longhornctl"); err != nil {
+		t.Fatalf("EnableTelemetry() error = %v", err)
+	}
+	id := config.ID
+	if len(id) != 32 {
+		t.Fatalf("EnableTelemetry() ID = %q, want 32 hex characters", id)
+	}
+	config.LastReport = &types.TelemetryReport{ID: id, Command: "longhornctl check preflight"}
+
+	if err := WriteTelemetryConfig(path, config); err != nil {
+		t.Fatalf("WriteTelemetryConfig() error = %v", err)
+	}
+	config, err = ReadTelemetryConfig(path)
+	if err != nil {
+		t.Fatalf("ReadTelemetryConfig() error = %v", err)
+	}
+	if !config.Enabled || config.ID != id || config.LastReport == nil {
+		t.Fatalf("ReadTelemetryConfig() = %+v, want the written config", config)
+	}
+
+	// Opting in again keeps the identifier and the endpoint.
+	if err := EnableTelemetry(config, ""); err != nil || config.ID != id {
+		t.Fatalf("EnableTelemetry() again error = %v, ID = %q, want %q", err, config.ID, id)
+	}
+
+	DisableTelemetry(config)
+	if config.Enabled || config.ID != "" || config.LastReport != nil {
+		t.Fatalf("DisableTelemetry() = %+v, want disabled without ID and report", config)
+	}
+}
+
+func TestValidateTelemetryURL(t *testing.T) {
+	for endpoint, wantErr := range map[string]bool{
+		"https://telemetry.example.com/longhornctl": false,
+		"http://127.0.0.1:8080":                     false,
+		"telemetry.example.com":                     true,
+		"ftp://telemetry.example.com":               true,
+		"https://":                                  true,
+	} {
+		err := ValidateTelemetryURL(endpoint)
+		if (err != nil) != wantErr {
+			t.Errorf("ValidateTelemetryURL(%q) error = %v, want error %v", endpoint, err, wantErr)
+		}
+	}
+}
+
+func TestIsDoNotTrack(t *testing.T) {
+	for value, want := range map[string]bool{
+		"":      false,
+		"0":     false,
+		"false": false,
+		"1":     true,
+		"true":  true,
+	} {
+		t.Setenv(consts.EnvDoNotTrack, value)
+		if got := IsDoNotTrack(); got != want {
+			t.Errorf("IsDoNotTrack() with %v=%q = %v, want %v", consts.EnvDoNotTrack, value, got, want)
+		}
+	}
+}
+
+func TestGetTelemetryFailureCategory(t *testing.T) {
+	resource := schema.GroupResource{Resource: "nodes"}
+	for _, test := range []struct {
+		err  error
+		want string
+	}{
+		{err: nil, want: ""},
+		{err: errors.Wrap(context.Canceled, "failed to list nodes"), want: TelemetryFailureCanceled},
+		{err: errors.Wrap(context.DeadlineExceeded, "failed to list nodes"), want: TelemetryFailureTimeout},
+		{err: errors.Wrap(apierrors.NewForbidden(resource, "", errors.New("denied")), "failed to list nodes"), want: TelemetryFailurePermission},
+		{err: errors.Wrap(apierrors.NewNotFound(resource, "node-1"), "failed to get node"), want: TelemetryFailureKubernetes},
+		{err: errors.New("invalid --node-id"), want: TelemetryFailureOther},
+	} {
+		if got := GetTelemetryFailureCategory(test.err); got != test.want {
+			t.Errorf("GetTelemetryFailureCategory(%v) = %q, want %q", test.err, got, test.want)
+		}
+	}
+}
+
+func TestSendTelemetryReport(t *testing.T) {
+	var received types.TelemetryReport
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	report := &types.TelemetryReport{ID: "id", Command: "longhornctl check preflight", NodeCount: 3}
+	if err := SendTelemetryReport(server.Client(), server.URL, report); err != nil {
+		t.Fatalf("SendTelemetryReport() error = %v", err)
+	}
+	if received != *report {
+		t.Fatalf("SendTelemetryReport() sent %+v, want %+v", received, *report)
+	}
+
+	if err := SendTelemetryReport(server.Client(), server.URL+"/missing\x7f", report); err == nil {
+		t.Fatalf("SendTelemetryReport() to an invalid URL succeeded")
+	}
+}
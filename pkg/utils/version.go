@@ -0,0 +1,21 @@
+package utils
+
+import (
+	"github.com/blang/semver/v4"
+	"github.com/pkg/errors"
+)
+
+// IsMinorVersionCompatible returns true if both versions share the same major and minor version.
+func IsMinorVersionCompatible(version, otherVersion string) (bool, error) {
+	v, err := semver.ParseTolerant(version)
+	if err != nil {
+		return false, errors.Wrapf(err, "invalid version %q", version)
+	}
+
+	other, err := semver.ParseTolerant(otherVersion)
+	if err != nil {
+		return false, errors.Wrapf(err, "invalid version %q", otherVersion)
+	}
+
+	return v.Major == other.Major && v.Minor == other.Minor, nil
+}
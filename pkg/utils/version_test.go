@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"testing"
+)
+
+func TestIsMinorVersionCompatible(t *testing.T) {
+	for _, test := range []struct {
+		version      string
+		otherVersion string
+		output       bool
+		expectErr    bool
+	}{
+		{version: "v1.9.0", otherVersion: "v1.9.2", output: true},
+		{version: "v1.9.0", otherVersion: "1.9.0-rc1", output: true},
+		{version: "v1.9.0", otherVersion: "v1.8.2", output: false},
+		{version: "v2.9.0", otherVersion: "v1.9.0", output: false},
+		{version: "master-head", otherVersion: "v1.9.0", expectErr: true},
+		{version: "", otherVersion: "v1.9.0", expectErr: true},
+	} {
+		result, err := IsMinorVersionCompatible(test.version, test.otherVersion)
+		if test.expectErr {
+			if err == nil {
+				t.Errorf("%s vs %s: expected error", test.version, test.otherVersion)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s vs %s: unexpected error: %v", test.version, test.otherVersion, err)
+		}
+		if result != test.output {
+			t.Errorf("%s vs %s: expected: %v, got: %v", test.version, test.otherVersion, test.output, result)
+		}
+	}
+}